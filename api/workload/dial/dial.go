@@ -97,6 +97,9 @@ func parseUDSAddr(u *url.URL) (net.Addr, error) {
 }
 
 func dialer(network string) func(addr string, timeout time.Duration) (net.Conn, error) {
+	if network == "pipe" {
+		return dialPipe
+	}
 	return func(addr string, timeout time.Duration) (net.Conn, error) {
 		return net.DialTimeout(network, addr, timeout)
 	}
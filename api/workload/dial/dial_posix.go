@@ -0,0 +1,13 @@
+// +build !windows
+
+package dial
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+func dialPipe(addr string, timeout time.Duration) (net.Conn, error) {
+	return nil, errors.New("named pipes are only supported on Windows")
+}
@@ -5,7 +5,6 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"net"
 	"os"
 	"strings"
 	"time"
@@ -24,10 +23,6 @@ var (
 	}
 )
 
-const (
-	defaultSocketPath = "/tmp/agent.sock"
-)
-
 type workloadClient struct {
 	workload.SpiffeWorkloadAPIClient
 	timeout time.Duration
@@ -37,10 +32,7 @@ type workloadClientMaker func(ctx context.Context, socketPath string, timeout ti
 
 // newClients is the default client maker
 func newWorkloadClient(ctx context.Context, socketPath string, timeout time.Duration) (*workloadClient, error) {
-	conn, err := workload_dial.Dial(ctx, &net.UnixAddr{
-		Name: socketPath,
-		Net:  "unix",
-	})
+	conn, err := workload_dial.Dial(ctx, workloadAddr(socketPath))
 	if err != nil {
 		return nil, err
 	}
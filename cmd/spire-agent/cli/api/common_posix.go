@@ -0,0 +1,16 @@
+// +build !windows
+
+package api
+
+import "net"
+
+const (
+	defaultSocketPath = "/tmp/agent.sock"
+)
+
+func workloadAddr(socketPath string) net.Addr {
+	return &net.UnixAddr{
+		Name: socketPath,
+		Net:  "unix",
+	}
+}
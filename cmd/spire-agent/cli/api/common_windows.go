@@ -0,0 +1,18 @@
+// +build windows
+
+package api
+
+import "net"
+
+const (
+	defaultSocketPath = `\\.\pipe\spire-agent\public\api`
+)
+
+// workloadAddr returns the address of the named pipe the Workload API is
+// served on
+func workloadAddr(socketPath string) net.Addr {
+	return &net.UnixAddr{
+		Name: socketPath,
+		Net:  "pipe",
+	}
+}
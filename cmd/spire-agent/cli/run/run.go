@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/hashicorp/hcl"
 	"github.com/spiffe/spire/pkg/agent"
@@ -19,14 +20,13 @@ import (
 	"github.com/spiffe/spire/pkg/common/cli"
 	"github.com/spiffe/spire/pkg/common/idutil"
 	"github.com/spiffe/spire/pkg/common/log"
+	"github.com/spiffe/spire/pkg/common/selector"
 	"github.com/spiffe/spire/pkg/common/util"
 )
 
 const (
 	defaultConfigPath = "conf/agent/agent.conf"
 
-	defaultSocketPath = "./spire_api"
-
 	// TODO: Make my defaults sane
 	defaultDataDir  = "."
 	defaultLogLevel = "INFO"
@@ -40,16 +40,23 @@ type runConfig struct {
 }
 
 type agentRunConfig struct {
-	DataDir         string `hcl:"data_dir"`
-	EnableSDS       bool   `hcl:"enable_sds"`
-	LogFile         string `hcl:"log_file"`
-	LogLevel        string `hcl:"log_level"`
-	ServerAddress   string `hcl:"server_address"`
-	ServerPort      int    `hcl:"server_port"`
-	SocketPath      string `hcl:"socket_path"`
-	TrustBundlePath string `hcl:"trust_bundle_path"`
-	TrustDomain     string `hcl:"trust_domain"`
-	JoinToken       string `hcl:"join_token"`
+	DataDir           string `hcl:"data_dir"`
+	EnableSDS         bool   `hcl:"enable_sds"`
+	LogFile           string `hcl:"log_file"`
+	LogLevel          string `hcl:"log_level"`
+	ServerAddress     string `hcl:"server_address"`
+	ServerPort        int    `hcl:"server_port"`
+	SocketPath        string `hcl:"socket_path"`
+	TrustBundlePath   string `hcl:"trust_bundle_path"`
+	TrustDomain       string `hcl:"trust_domain"`
+	JoinToken         string `hcl:"join_token"`
+	ReusableJoinToken bool   `hcl:"reusable_join_token"`
+	ReattestInterval  string `hcl:"reattest_interval"`
+	EBPFAttestation   bool   `hcl:"ebpf_attestation"`
+
+	WorkloadAttestationCacheTTL string `hcl:"workload_attestation_cache_ttl"`
+
+	SelectorHook *selectorHookConfig `hcl:"selector_hook"`
 
 	ConfigPath string
 
@@ -61,6 +68,11 @@ type agentRunConfig struct {
 	Umask            string   `hcl:"umask"`
 }
 
+type selectorHookConfig struct {
+	Command []string `hcl:"command"`
+	Timeout string   `hcl:"timeout"`
+}
+
 type agentConfig struct {
 	agent.Config
 	umask int
@@ -161,6 +173,8 @@ func parseFlags(args []string) (*runConfig, error) {
 	flags.StringVar(&c.AgentConfig.TrustDomain, "trustDomain", "", "The trust domain that this agent belongs to")
 	flags.StringVar(&c.AgentConfig.TrustBundlePath, "trustBundle", "", "Path to the SPIRE server CA bundle")
 	flags.StringVar(&c.AgentConfig.JoinToken, "joinToken", "", "An optional token which has been generated by the SPIRE server")
+	flags.BoolVar(&c.AgentConfig.ReusableJoinToken, "reusableJoinToken", false, "Whether the join token may be used by more than one agent")
+	flags.StringVar(&c.AgentConfig.ReattestInterval, "reattestInterval", "", "How often to re-attest the agent")
 	flags.StringVar(&c.AgentConfig.SocketPath, "socketPath", "", "Location to bind the workload API socket")
 	flags.StringVar(&c.AgentConfig.DataDir, "dataDir", "", "A directory the agent can use for its runtime data")
 	flags.StringVar(&c.AgentConfig.LogFile, "logFile", "", "File to write logs to")
@@ -220,6 +234,18 @@ func mergeConfig(orig *agentConfig, cmd *runConfig) error {
 		orig.JoinToken = cmd.AgentConfig.JoinToken
 	}
 
+	if cmd.AgentConfig.ReusableJoinToken {
+		orig.ReusableJoinToken = cmd.AgentConfig.ReusableJoinToken
+	}
+
+	if cmd.AgentConfig.ReattestInterval != "" {
+		interval, err := time.ParseDuration(cmd.AgentConfig.ReattestInterval)
+		if err != nil {
+			return fmt.Errorf("unable to parse reattest interval %q: %v", cmd.AgentConfig.ReattestInterval, err)
+		}
+		orig.ReattestInterval = interval
+	}
+
 	if cmd.AgentConfig.SocketPath != "" {
 		orig.BindAddress.Name = cmd.AgentConfig.SocketPath
 	}
@@ -232,6 +258,36 @@ func mergeConfig(orig *agentConfig, cmd *runConfig) error {
 		orig.EnableSDS = cmd.AgentConfig.EnableSDS
 	}
 
+	if cmd.AgentConfig.EBPFAttestation {
+		orig.EBPFAttestation = cmd.AgentConfig.EBPFAttestation
+	}
+
+	if cmd.AgentConfig.WorkloadAttestationCacheTTL != "" {
+		ttl, err := time.ParseDuration(cmd.AgentConfig.WorkloadAttestationCacheTTL)
+		if err != nil {
+			return fmt.Errorf("unable to parse workload attestation cache ttl %q: %v", cmd.AgentConfig.WorkloadAttestationCacheTTL, err)
+		}
+		orig.WorkloadAttestationCacheTTL = ttl
+	}
+
+	if hook := cmd.AgentConfig.SelectorHook; hook != nil {
+		if len(hook.Command) == 0 {
+			return errors.New("selector_hook requires a command")
+		}
+		var timeout time.Duration
+		if hook.Timeout != "" {
+			var err error
+			timeout, err = time.ParseDuration(hook.Timeout)
+			if err != nil {
+				return fmt.Errorf("unable to parse selector hook timeout %q: %v", hook.Timeout, err)
+			}
+		}
+		orig.SelectorHook = selector.NewHook(selector.HookConfig{
+			Command: hook.Command,
+			Timeout: timeout,
+		})
+	}
+
 	// Handle log file and level
 	if cmd.AgentConfig.LogFile != "" || cmd.AgentConfig.LogLevel != "" {
 		logLevel := defaultLogLevel
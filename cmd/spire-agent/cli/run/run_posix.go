@@ -0,0 +1,7 @@
+// +build !windows
+
+package run
+
+const (
+	defaultSocketPath = "./spire_api"
+)
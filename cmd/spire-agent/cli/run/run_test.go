@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/hcl/hcl/printer"
 	"github.com/stretchr/testify/assert"
@@ -86,6 +87,11 @@ func TestMergeConfigGood(t *testing.T) {
 		SocketPath:    "/tmp/agent.sock",
 		TrustDomain:   "example.org",
 		Umask:         "077",
+
+		ReattestInterval: "1h",
+		EBPFAttestation:  true,
+
+		WorkloadAttestationCacheTTL: "30s",
 	}
 
 	c := &runConfig{
@@ -103,4 +109,52 @@ func TestMergeConfigGood(t *testing.T) {
 	assert.Equal(t, orig.GlobalConfig().TrustDomain, "example.org")
 	assert.Equal(t, orig.DataDir, ".")
 	assert.Equal(t, orig.umask, 077)
+	assert.Equal(t, orig.ReattestInterval, time.Hour)
+	assert.True(t, orig.EBPFAttestation)
+	assert.Equal(t, orig.WorkloadAttestationCacheTTL, 30*time.Second)
+}
+
+func TestMergeConfigBadReattestInterval(t *testing.T) {
+	c := &runConfig{
+		AgentConfig: agentRunConfig{
+			ReattestInterval: "soon",
+		},
+	}
+
+	err := mergeConfig(newDefaultConfig(), c)
+	require.EqualError(t, err, `unable to parse reattest interval "soon": time: invalid duration "soon"`)
+}
+
+func TestMergeConfigBadWorkloadAttestationCacheTTL(t *testing.T) {
+	c := &runConfig{
+		AgentConfig: agentRunConfig{
+			WorkloadAttestationCacheTTL: "forever",
+		},
+	}
+
+	err := mergeConfig(newDefaultConfig(), c)
+	require.EqualError(t, err, `unable to parse workload attestation cache ttl "forever": time: invalid duration "forever"`)
+}
+
+func TestMergeConfigSelectorHook(t *testing.T) {
+	c := &runConfig{
+		AgentConfig: agentRunConfig{
+			SelectorHook: &selectorHookConfig{
+				Command: []string{"/opt/spire/hook"},
+				Timeout: "2s",
+			},
+		},
+	}
+
+	orig := newDefaultConfig()
+	require.NoError(t, mergeConfig(orig, c))
+	assert.NotNil(t, orig.SelectorHook)
+
+	c.AgentConfig.SelectorHook.Timeout = "soon"
+	err := mergeConfig(newDefaultConfig(), c)
+	require.EqualError(t, err, `unable to parse selector hook timeout "soon": time: invalid duration "soon"`)
+
+	c.AgentConfig.SelectorHook.Command = nil
+	err = mergeConfig(newDefaultConfig(), c)
+	require.EqualError(t, err, "selector_hook requires a command")
 }
@@ -0,0 +1,8 @@
+// +build windows
+
+package run
+
+const (
+	// On Windows the Workload API is served on a named pipe
+	defaultSocketPath = `\\.\pipe\spire-agent\public\api`
+)
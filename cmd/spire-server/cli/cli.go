@@ -6,6 +6,7 @@ import (
 	"github.com/mitchellh/cli"
 	"github.com/spiffe/spire/cmd/spire-server/cli/agent"
 	"github.com/spiffe/spire/cmd/spire-server/cli/bundle"
+	"github.com/spiffe/spire/cmd/spire-server/cli/datastore"
 	"github.com/spiffe/spire/cmd/spire-server/cli/entry"
 	"github.com/spiffe/spire/cmd/spire-server/cli/run"
 	"github.com/spiffe/spire/cmd/spire-server/cli/token"
@@ -43,6 +44,15 @@ func Run(args []string) int {
 		"experimental bundle set": func() (cli.Command, error) {
 			return bundle.NewExperimentalSetCommand(), nil
 		},
+		"datastore backup": func() (cli.Command, error) {
+			return &datastore.BackupCLI{}, nil
+		},
+		"datastore restore": func() (cli.Command, error) {
+			return &datastore.RestoreCLI{}, nil
+		},
+		"datastore migrate": func() (cli.Command, error) {
+			return &datastore.MigrateCLI{}, nil
+		},
 		"entry create": func() (cli.Command, error) {
 			return &entry.CreateCLI{}, nil
 		},
@@ -61,6 +71,12 @@ func Run(args []string) int {
 		"token generate": func() (cli.Command, error) {
 			return &token.GenerateCLI{}, nil
 		},
+		"token list": func() (cli.Command, error) {
+			return &token.ListCLI{}, nil
+		},
+		"token revoke": func() (cli.Command, error) {
+			return &token.RevokeCLI{}, nil
+		},
 	}
 
 	exitStatus, err := c.Run()
@@ -0,0 +1,187 @@
+package datastore
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/server/datastore"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// backupVersion is the version of the backup format. It is bumped
+	// whenever a change is made that older versions cannot restore.
+	backupVersion = 1
+
+	// listPageSize is the number of records requested per page when
+	// reading paginated listings
+	listPageSize = 1000
+)
+
+// backup is the portable representation of the datastore contents. It does
+// not depend on the DataStore plugin the records were read from.
+type backup struct {
+	Version             int                         `json:"version"`
+	Bundles             []*common.Bundle            `json:"bundles"`
+	RegistrationEntries []*common.RegistrationEntry `json:"registration_entries"`
+	AttestedNodes       []*common.AttestedNode      `json:"attested_nodes"`
+	NodeSelectors       []*datastore.NodeSelectors  `json:"node_selectors"`
+	JoinTokens          []*datastore.JoinToken      `json:"join_tokens"`
+}
+
+// BackupConfig holds configuration for BackupCLI
+type BackupConfig struct {
+	// Path to the server configuration file
+	ConfigPath string
+
+	// Path the backup is written to
+	Path string
+}
+
+// Validate will perform a basic validation on config fields
+func (c *BackupConfig) Validate() error {
+	if c.Path == "" {
+		return errors.New("a backup path is required")
+	}
+	return nil
+}
+
+// BackupCLI command for dumping the datastore contents to a file
+type BackupCLI struct {
+	dataStore datastore.DataStore
+}
+
+func (BackupCLI) Synopsis() string {
+	return "Writes the datastore contents to a backup file"
+}
+
+func (c BackupCLI) Help() string {
+	_, err := c.parseConfig([]string{"-h"})
+	return err.Error()
+}
+
+// Run will write the datastore contents to the backup file
+func (c *BackupCLI) Run(args []string) int {
+	ctx := context.Background()
+
+	config, err := c.parseConfig(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err = config.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if c.dataStore == nil {
+		ds, unload, err := loadDataStore(ctx, config.ConfigPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading datastore: %v\n", err)
+			return 1
+		}
+		defer unload()
+		c.dataStore = ds
+	}
+
+	b, err := readBackup(ctx, c.dataStore)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading datastore: %v\n", err)
+		return 1
+	}
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding backup: %v\n", err)
+		return 1
+	}
+
+	// the backup holds join tokens, so it is only readable by the owner
+	if err := ioutil.WriteFile(config.Path, data, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing backup: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Backed up %d bundles, %d registration entries, %d attested nodes and %d join tokens to %s\n",
+		len(b.Bundles), len(b.RegistrationEntries), len(b.AttestedNodes), len(b.JoinTokens), config.Path)
+	return 0
+}
+
+func (BackupCLI) parseConfig(args []string) (*BackupConfig, error) {
+	f := flag.NewFlagSet("datastore backup", flag.ContinueOnError)
+	c := &BackupConfig{}
+
+	f.StringVar(&c.ConfigPath, "config", defaultConfigPath, "Path to the SPIRE server configuration file")
+	f.StringVar(&c.Path, "path", "", "Path to write the backup to")
+
+	return c, f.Parse(args)
+}
+
+func readBackup(ctx context.Context, ds datastore.DataStore) (*backup, error) {
+	b := &backup{
+		Version: backupVersion,
+	}
+
+	bundles, err := ds.ListBundles(ctx, &datastore.ListBundlesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list bundles: %v", err)
+	}
+	b.Bundles = bundles.Bundles
+
+	pagination := &datastore.Pagination{PageSize: listPageSize}
+	for {
+		resp, err := ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{
+			Pagination: pagination,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list registration entries: %v", err)
+		}
+		if len(resp.Entries) == 0 {
+			break
+		}
+		b.RegistrationEntries = append(b.RegistrationEntries, resp.Entries...)
+		pagination = resp.Pagination
+	}
+
+	pagination = &datastore.Pagination{PageSize: listPageSize}
+	for {
+		resp, err := ds.ListAttestedNodes(ctx, &datastore.ListAttestedNodesRequest{
+			Pagination: pagination,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list attested nodes: %v", err)
+		}
+		if len(resp.Nodes) == 0 {
+			break
+		}
+		b.AttestedNodes = append(b.AttestedNodes, resp.Nodes...)
+		pagination = resp.Pagination
+	}
+
+	for _, node := range b.AttestedNodes {
+		resp, err := ds.GetNodeSelectors(ctx, &datastore.GetNodeSelectorsRequest{
+			SpiffeId: node.SpiffeId,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get selectors for %q: %v", node.SpiffeId, err)
+		}
+		if resp.Selectors != nil && len(resp.Selectors.Selectors) > 0 {
+			b.NodeSelectors = append(b.NodeSelectors, resp.Selectors)
+		}
+	}
+
+	tokens, err := ds.ListJoinTokens(ctx, &datastore.ListJoinTokensRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list join tokens: %v", err)
+	}
+	b.JoinTokens = tokens.JoinTokens
+
+	return b, nil
+}
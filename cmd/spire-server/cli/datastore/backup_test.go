@@ -0,0 +1,199 @@
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/spiffe/spire/test/fakes/fakedatastore"
+	"github.com/stretchr/testify/suite"
+)
+
+type BackupTestSuite struct {
+	suite.Suite
+	dir string
+	ctx context.Context
+}
+
+func TestBackupTestSuite(t *testing.T) {
+	suite.Run(t, new(BackupTestSuite))
+}
+
+func (s *BackupTestSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "datastore-backup-test")
+	s.Require().NoError(err)
+	s.ctx = context.Background()
+}
+
+func (s *BackupTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *BackupTestSuite) TestBackupAndRestore() {
+	src := fakedatastore.New()
+	s.populate(src)
+
+	path := filepath.Join(s.dir, "backup.json")
+	backupCLI := &BackupCLI{dataStore: src}
+	s.Require().Equal(0, backupCLI.Run([]string{"-path", path}))
+
+	info, err := os.Stat(path)
+	s.Require().NoError(err)
+	s.Require().Equal(os.FileMode(0600), info.Mode().Perm())
+
+	dst := fakedatastore.New()
+	restoreCLI := &RestoreCLI{dataStore: dst}
+	s.Require().Equal(0, restoreCLI.Run([]string{"-path", path}))
+
+	expected, err := readBackup(s.ctx, src)
+	s.Require().NoError(err)
+	actual, err := readBackup(s.ctx, dst)
+	s.Require().NoError(err)
+
+	// entries are given new IDs when restored
+	for _, entries := range [][]*common.RegistrationEntry{expected.RegistrationEntries, actual.RegistrationEntries} {
+		for _, entry := range entries {
+			entry.EntryId = ""
+		}
+	}
+
+	s.Require().ElementsMatch(expected.Bundles, actual.Bundles)
+	s.Require().ElementsMatch(expected.RegistrationEntries, actual.RegistrationEntries)
+	s.Require().ElementsMatch(expected.AttestedNodes, actual.AttestedNodes)
+	s.Require().ElementsMatch(expected.NodeSelectors, actual.NodeSelectors)
+	s.Require().ElementsMatch(expected.JoinTokens, actual.JoinTokens)
+}
+
+func (s *BackupTestSuite) TestRestoreRequiresEmptyDataStore() {
+	path := filepath.Join(s.dir, "backup.json")
+	backupCLI := &BackupCLI{dataStore: fakedatastore.New()}
+	s.Require().Equal(0, backupCLI.Run([]string{"-path", path}))
+
+	dst := fakedatastore.New()
+	_, err := dst.CreateJoinToken(s.ctx, &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "existing", Expiry: 1000},
+	})
+	s.Require().NoError(err)
+
+	s.Require().EqualError(checkEmpty(s.ctx, dst), "the datastore is not empty; backups can only be restored into an empty datastore")
+	restoreCLI := &RestoreCLI{dataStore: dst}
+	s.Require().Equal(1, restoreCLI.Run([]string{"-path", path}))
+}
+
+func (s *BackupTestSuite) TestRestoreRejectsUnsupportedVersion() {
+	path := filepath.Join(s.dir, "backup.json")
+	s.Require().NoError(ioutil.WriteFile(path, []byte(`{"version": 2}`), 0600))
+
+	dst := fakedatastore.New()
+	restoreCLI := &RestoreCLI{dataStore: dst}
+	s.Require().Equal(1, restoreCLI.Run([]string{"-path", path}))
+
+	s.Require().NoError(checkEmpty(s.ctx, dst))
+}
+
+func (s *BackupTestSuite) TestRequiresPath() {
+	s.Require().Equal(1, (&BackupCLI{dataStore: fakedatastore.New()}).Run(nil))
+	s.Require().Equal(1, (&RestoreCLI{dataStore: fakedatastore.New()}).Run(nil))
+}
+
+func (s *BackupTestSuite) TestLoadDataStoreFromConfig() {
+	configPath := filepath.Join(s.dir, "server.conf")
+	config := fmt.Sprintf(`
+server {
+	trust_domain = "example.org"
+}
+
+plugins {
+	DataStore "sql" {
+		plugin_data {
+			database_type = "sqlite3"
+			connection_string = %q
+		}
+	}
+}
+`, filepath.Join(s.dir, "datastore.sqlite3"))
+	s.Require().NoError(ioutil.WriteFile(configPath, []byte(config), 0600))
+
+	ds, unload, err := loadDataStore(s.ctx, configPath)
+	s.Require().NoError(err)
+	defer unload()
+
+	s.populate(ds)
+	b, err := readBackup(s.ctx, ds)
+	s.Require().NoError(err)
+	s.Require().Len(b.Bundles, 1)
+	s.Require().Len(b.RegistrationEntries, 2)
+	s.Require().Len(b.AttestedNodes, 1)
+	s.Require().Len(b.NodeSelectors, 1)
+	s.Require().Len(b.JoinTokens, 1)
+}
+
+func (s *BackupTestSuite) TestLoadDataStoreMissingConfig() {
+	_, _, err := loadDataStore(s.ctx, filepath.Join(s.dir, "missing.conf"))
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "unable to read server configuration")
+}
+
+func (s *BackupTestSuite) populate(ds datastore.DataStore) {
+	_, err := ds.CreateBundle(s.ctx, &datastore.CreateBundleRequest{
+		Bundle: &common.Bundle{
+			TrustDomainId: "spiffe://otherdomain.org",
+			RootCas:       []*common.Certificate{{DerBytes: []byte("root")}},
+			JwtSigningKeys: []*common.PublicKey{
+				{PkixBytes: []byte("key"), Kid: "kid", NotAfter: 1000},
+			},
+		},
+	})
+	s.Require().NoError(err)
+
+	for _, entry := range []*common.RegistrationEntry{
+		{
+			ParentId:  "spiffe://example.org/node",
+			SpiffeId:  "spiffe://example.org/workload1",
+			Selectors: []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+			Ttl:       60,
+		},
+		{
+			ParentId:      "spiffe://example.org/node",
+			SpiffeId:      "spiffe://example.org/workload2",
+			Selectors:     []*common.Selector{{Type: "unix", Value: "uid:1001"}},
+			FederatesWith: []string{"spiffe://otherdomain.org"},
+		},
+	} {
+		_, err := ds.CreateRegistrationEntry(s.ctx, &datastore.CreateRegistrationEntryRequest{
+			Entry: entry,
+		})
+		s.Require().NoError(err)
+	}
+
+	_, err = ds.CreateAttestedNode(s.ctx, &datastore.CreateAttestedNodeRequest{
+		Node: &common.AttestedNode{
+			SpiffeId:            "spiffe://example.org/spire/agent/join_token/foo",
+			AttestationDataType: "join_token",
+			CertSerialNumber:    "1234",
+			CertNotAfter:        5000,
+		},
+	})
+	s.Require().NoError(err)
+
+	_, err = ds.SetNodeSelectors(s.ctx, &datastore.SetNodeSelectorsRequest{
+		Selectors: &datastore.NodeSelectors{
+			SpiffeId:  "spiffe://example.org/spire/agent/join_token/foo",
+			Selectors: []*common.Selector{{Type: "foo", Value: "bar"}},
+		},
+	})
+	s.Require().NoError(err)
+
+	_, err = ds.CreateJoinToken(s.ctx, &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "token", Expiry: 9999999999, MaxUses: 3},
+	})
+	s.Require().NoError(err)
+	_, err = ds.UseJoinToken(s.ctx, &datastore.UseJoinTokenRequest{Token: "token"})
+	s.Require().NoError(err)
+}
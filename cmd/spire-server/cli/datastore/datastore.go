@@ -0,0 +1,65 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hashicorp/hcl"
+	"github.com/sirupsen/logrus"
+	common_catalog "github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/server/catalog"
+	"github.com/spiffe/spire/proto/server/datastore"
+)
+
+const (
+	defaultConfigPath = "conf/server/server.conf"
+)
+
+// serverConfig holds the parts of the server configuration file needed to
+// load the DataStore plugin
+type serverConfig struct {
+	Server struct {
+		TrustDomain string `hcl:"trust_domain"`
+	} `hcl:"server"`
+	PluginConfigs common_catalog.PluginConfigMap `hcl:"plugins"`
+}
+
+// loadDataStore loads the DataStore plugin configured in the server
+// configuration file. The returned function unloads it.
+func loadDataStore(ctx context.Context, configPath string) (datastore.DataStore, func(), error) {
+	config, err := loadServerConfig(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log := logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+
+	return catalog.LoadDataStore(ctx, &catalog.Config{
+		GlobalConfig: &common_catalog.GlobalConfig{
+			TrustDomain: config.Server.TrustDomain,
+		},
+		PluginConfigs: config.PluginConfigs,
+		Log:           log,
+	})
+}
+
+// loadServerConfig reads the server configuration file
+func loadServerConfig(configPath string) (*serverConfig, error) {
+	if configPath == "" {
+		return nil, errors.New("a server configuration file is required")
+	}
+
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read server configuration: %v", err)
+	}
+
+	config := new(serverConfig)
+	if err := hcl.Decode(config, string(data)); err != nil {
+		return nil, fmt.Errorf("unable to parse server configuration: %v", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,151 @@
+package datastore
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/hcl/hcl/printer"
+	"github.com/spiffe/spire/pkg/server/catalog"
+	"github.com/spiffe/spire/pkg/server/plugin/datastore/sql"
+)
+
+// MigrateConfig holds configuration for MigrateCLI
+type MigrateConfig struct {
+	// Path to the server configuration file
+	ConfigPath string
+
+	// Print the pending migrations without applying them
+	DryRun bool
+
+	// Fail unless the schema is at the version expected by the server
+	Verify bool
+}
+
+// Validate will perform a basic validation on config fields
+func (c *MigrateConfig) Validate() error {
+	if c.DryRun && c.Verify {
+		return errors.New("-dryRun and -verify cannot be combined")
+	}
+	return nil
+}
+
+// MigrateCLI command for migrating the datastore schema while the server is
+// not running
+type MigrateCLI struct{}
+
+func (MigrateCLI) Synopsis() string {
+	return "Migrates the datastore schema to the version expected by the server"
+}
+
+func (c MigrateCLI) Help() string {
+	_, err := c.parseConfig([]string{"-h"})
+	return err.Error()
+}
+
+// Run will print, verify or apply the pending schema migrations
+func (c *MigrateCLI) Run(args []string) int {
+	config, err := c.parseConfig(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err = config.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	pluginConfig, err := loadSQLPluginConfig(config.ConfigPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading datastore configuration: %v\n", err)
+		return 1
+	}
+
+	if config.DryRun || config.Verify {
+		status, err := sql.GetSchemaStatus(pluginConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading schema: %v\n", err)
+			return 1
+		}
+		printSchemaStatus(status)
+
+		if config.Verify && !status.Compatible() {
+			fmt.Fprintln(os.Stderr, "Schema is not compatible with this server")
+			return 1
+		}
+		return 0
+	}
+
+	status, err := sql.MigrateSchema(pluginConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error migrating schema: %v\n", err)
+		return 1
+	}
+
+	if len(status.Pending) == 0 {
+		fmt.Printf("Schema is up to date at version %d\n", status.CodeVersion)
+		return 0
+	}
+	fmt.Println("Applied migrations:")
+	for _, migration := range status.Pending {
+		fmt.Printf("  %s\n", migration)
+	}
+	fmt.Printf("Schema is at version %d\n", status.CodeVersion)
+	return 0
+}
+
+func (MigrateCLI) parseConfig(args []string) (*MigrateConfig, error) {
+	f := flag.NewFlagSet("datastore migrate", flag.ContinueOnError)
+	c := &MigrateConfig{}
+
+	f.StringVar(&c.ConfigPath, "config", defaultConfigPath, "Path to the SPIRE server configuration file")
+	f.BoolVar(&c.DryRun, "dryRun", false, "Print the pending migrations without applying them")
+	f.BoolVar(&c.Verify, "verify", false, "Exit with an error unless the schema is at the version expected by the server")
+
+	return c, f.Parse(args)
+}
+
+func printSchemaStatus(status *sql.SchemaStatus) {
+	if status.Initialized {
+		fmt.Printf("Schema version: %d (expected: %d)\n", status.Version, status.CodeVersion)
+	} else {
+		fmt.Printf("Schema version: not initialized (expected: %d)\n", status.CodeVersion)
+	}
+
+	if len(status.Pending) == 0 {
+		fmt.Println("No pending migrations")
+		return
+	}
+	fmt.Println("Pending migrations:")
+	for _, migration := range status.Pending {
+		fmt.Printf("  %s\n", migration)
+	}
+}
+
+// loadSQLPluginConfig returns the plugin data of the "sql" DataStore plugin,
+// the only DataStore with a versioned schema
+func loadSQLPluginConfig(configPath string) (string, error) {
+	config, err := loadServerConfig(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	for name, pluginConfig := range config.PluginConfigs[catalog.DataStoreType] {
+		if !pluginConfig.IsEnabled() {
+			continue
+		}
+		if name != "sql" {
+			return "", fmt.Errorf("the %q DataStore plugin does not have a schema to migrate", name)
+		}
+
+		var data bytes.Buffer
+		if err := printer.DefaultConfig.Fprint(&data, pluginConfig.PluginData); err != nil {
+			return "", fmt.Errorf("unable to read plugin data: %v", err)
+		}
+		return data.String(), nil
+	}
+	return "", errors.New("no DataStore plugin is configured")
+}
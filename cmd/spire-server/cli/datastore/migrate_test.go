@@ -0,0 +1,91 @@
+package datastore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/server/plugin/datastore/sql"
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrateTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestMigrateTestSuite(t *testing.T) {
+	suite.Run(t, new(MigrateTestSuite))
+}
+
+func (s *MigrateTestSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "datastore-migrate-test")
+	s.Require().NoError(err)
+}
+
+func (s *MigrateTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *MigrateTestSuite) TestMigrate() {
+	configPath := s.writeConfig("sql", fmt.Sprintf(`
+			database_type = "sqlite3"
+			connection_string = %q
+`, filepath.Join(s.dir, "datastore.sqlite3")))
+
+	// the dry run and verification do not modify the schema
+	s.Require().Equal(0, s.run("-config", configPath, "-dryRun"))
+	s.Require().Equal(1, s.run("-config", configPath, "-verify"))
+
+	s.Require().Equal(0, s.run("-config", configPath))
+	s.Require().Equal(0, s.run("-config", configPath, "-verify"))
+
+	pluginConfig, err := loadSQLPluginConfig(configPath)
+	s.Require().NoError(err)
+	status, err := sql.GetSchemaStatus(pluginConfig)
+	s.Require().NoError(err)
+	s.Require().True(status.Compatible())
+	s.Require().Empty(status.Pending)
+
+	// migrating an up to date schema is a no-op
+	s.Require().Equal(0, s.run("-config", configPath))
+}
+
+func (s *MigrateTestSuite) TestRequiresSQLPlugin() {
+	configPath := s.writeConfig("etcd", `
+			endpoints = ["http://127.0.0.1:2379"]
+`)
+
+	_, err := loadSQLPluginConfig(configPath)
+	s.Require().EqualError(err, `the "etcd" DataStore plugin does not have a schema to migrate`)
+	s.Require().Equal(1, s.run("-config", configPath))
+}
+
+func (s *MigrateTestSuite) TestDryRunAndVerifyCannotBeCombined() {
+	s.Require().Equal(1, s.run("-dryRun", "-verify"))
+}
+
+func (s *MigrateTestSuite) run(args ...string) int {
+	c := &MigrateCLI{}
+	return c.Run(args)
+}
+
+func (s *MigrateTestSuite) writeConfig(pluginName, pluginData string) string {
+	configPath := filepath.Join(s.dir, "server.conf")
+	config := fmt.Sprintf(`
+server {
+	trust_domain = "example.org"
+}
+
+plugins {
+	DataStore %q {
+		plugin_data {%s		}
+	}
+}
+`, pluginName, pluginData)
+	s.Require().NoError(ioutil.WriteFile(configPath, []byte(config), 0600))
+	return configPath
+}
@@ -0,0 +1,222 @@
+package datastore
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/server/datastore"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// restoreBatchSize is the number of registration entries created per
+	// batch when restoring a backup
+	restoreBatchSize = 500
+)
+
+// RestoreConfig holds configuration for RestoreCLI
+type RestoreConfig struct {
+	// Path to the server configuration file
+	ConfigPath string
+
+	// Path the backup is read from
+	Path string
+}
+
+// Validate will perform a basic validation on config fields
+func (c *RestoreConfig) Validate() error {
+	if c.Path == "" {
+		return errors.New("a backup path is required")
+	}
+	return nil
+}
+
+// RestoreCLI command for loading a backup file into an empty datastore
+type RestoreCLI struct {
+	dataStore datastore.DataStore
+}
+
+func (RestoreCLI) Synopsis() string {
+	return "Restores the datastore contents from a backup file"
+}
+
+func (c RestoreCLI) Help() string {
+	_, err := c.parseConfig([]string{"-h"})
+	return err.Error()
+}
+
+// Run will load the backup file into the datastore
+func (c *RestoreCLI) Run(args []string) int {
+	ctx := context.Background()
+
+	config, err := c.parseConfig(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err = config.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	data, err := ioutil.ReadFile(config.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading backup: %v\n", err)
+		return 1
+	}
+
+	b := new(backup)
+	if err := json.Unmarshal(data, b); err != nil {
+		fmt.Fprintf(os.Stderr, "Error decoding backup: %v\n", err)
+		return 1
+	}
+	if b.Version != backupVersion {
+		fmt.Fprintf(os.Stderr, "Unsupported backup version %d; expected %d\n", b.Version, backupVersion)
+		return 1
+	}
+
+	if c.dataStore == nil {
+		ds, unload, err := loadDataStore(ctx, config.ConfigPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading datastore: %v\n", err)
+			return 1
+		}
+		defer unload()
+		c.dataStore = ds
+	}
+
+	if err := checkEmpty(ctx, c.dataStore); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := writeBackup(ctx, c.dataStore, b); err != nil {
+		fmt.Fprintf(os.Stderr, "Error restoring datastore: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Restored %d bundles, %d registration entries, %d attested nodes and %d join tokens from %s\n",
+		len(b.Bundles), len(b.RegistrationEntries), len(b.AttestedNodes), len(b.JoinTokens), config.Path)
+	return 0
+}
+
+func (RestoreCLI) parseConfig(args []string) (*RestoreConfig, error) {
+	f := flag.NewFlagSet("datastore restore", flag.ContinueOnError)
+	c := &RestoreConfig{}
+
+	f.StringVar(&c.ConfigPath, "config", defaultConfigPath, "Path to the SPIRE server configuration file")
+	f.StringVar(&c.Path, "path", "", "Path to read the backup from")
+
+	return c, f.Parse(args)
+}
+
+// checkEmpty makes sure the datastore holds no records, so that a restore
+// never merges a backup with existing data
+func checkEmpty(ctx context.Context, ds datastore.DataStore) error {
+	bundles, err := ds.ListBundles(ctx, &datastore.ListBundlesRequest{})
+	if err != nil {
+		return fmt.Errorf("unable to list bundles: %v", err)
+	}
+
+	entries, err := ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{
+		Pagination: &datastore.Pagination{PageSize: 1},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to list registration entries: %v", err)
+	}
+
+	nodes, err := ds.ListAttestedNodes(ctx, &datastore.ListAttestedNodesRequest{
+		Pagination: &datastore.Pagination{PageSize: 1},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to list attested nodes: %v", err)
+	}
+
+	tokens, err := ds.ListJoinTokens(ctx, &datastore.ListJoinTokensRequest{})
+	if err != nil {
+		return fmt.Errorf("unable to list join tokens: %v", err)
+	}
+
+	if len(bundles.Bundles) > 0 || len(entries.Entries) > 0 || len(nodes.Nodes) > 0 || len(tokens.JoinTokens) > 0 {
+		return errors.New("the datastore is not empty; backups can only be restored into an empty datastore")
+	}
+	return nil
+}
+
+func writeBackup(ctx context.Context, ds datastore.DataStore, b *backup) error {
+	for _, bundle := range b.Bundles {
+		if _, err := ds.CreateBundle(ctx, &datastore.CreateBundleRequest{
+			Bundle: bundle,
+		}); err != nil {
+			return fmt.Errorf("unable to create bundle %q: %v", bundle.TrustDomainId, err)
+		}
+	}
+
+	// entry IDs are assigned by the datastore, so the restored entries are
+	// given new ones
+	entries := make([]*common.RegistrationEntry, 0, len(b.RegistrationEntries))
+	for _, entry := range b.RegistrationEntries {
+		entry = proto.Clone(entry).(*common.RegistrationEntry)
+		entry.EntryId = ""
+		entries = append(entries, entry)
+	}
+	for len(entries) > 0 {
+		n := len(entries)
+		if n > restoreBatchSize {
+			n = restoreBatchSize
+		}
+		if _, err := ds.BatchCreateRegistrationEntries(ctx, &datastore.BatchCreateRegistrationEntriesRequest{
+			Entries: entries[:n],
+		}); err != nil {
+			return fmt.Errorf("unable to create registration entries: %v", err)
+		}
+		entries = entries[n:]
+	}
+
+	for _, node := range b.AttestedNodes {
+		if _, err := ds.CreateAttestedNode(ctx, &datastore.CreateAttestedNodeRequest{
+			Node: node,
+		}); err != nil {
+			return fmt.Errorf("unable to create attested node %q: %v", node.SpiffeId, err)
+		}
+	}
+
+	for _, selectors := range b.NodeSelectors {
+		if _, err := ds.SetNodeSelectors(ctx, &datastore.SetNodeSelectorsRequest{
+			Selectors: selectors,
+		}); err != nil {
+			return fmt.Errorf("unable to set selectors for %q: %v", selectors.SpiffeId, err)
+		}
+	}
+
+	for _, token := range b.JoinTokens {
+		if _, err := ds.CreateJoinToken(ctx, &datastore.CreateJoinTokenRequest{
+			JoinToken: &datastore.JoinToken{
+				Token:   token.Token,
+				Expiry:  token.Expiry,
+				MaxUses: token.MaxUses,
+			},
+		}); err != nil {
+			return fmt.Errorf("unable to create join token: %v", err)
+		}
+
+		// the use count can only be changed by using the token
+		for i := int32(0); i < token.Uses; i++ {
+			if _, err := ds.UseJoinToken(ctx, &datastore.UseJoinTokenRequest{
+				Token: token.Token,
+			}); err != nil {
+				return fmt.Errorf("unable to restore join token uses: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -42,6 +42,9 @@ type CreateConfig struct {
 
 	// Whether or not the entry represents a node or group of nodes
 	Node bool
+
+	// Time, in seconds since the Unix epoch, the entry expires at
+	EntryExpiry int64
 }
 
 // Perform basic validation, even on fields that we
@@ -76,6 +79,10 @@ func (rc *CreateConfig) Validate() (err error) {
 		return errors.New("a TTL is required")
 	}
 
+	if rc.EntryExpiry < 0 {
+		return errors.New("an entry expiry cannot be negative")
+	}
+
 	// make sure all SPIFFE ID's are well formed
 	rc.SpiffeID, err = idutil.NormalizeSpiffeID(rc.SpiffeID, idutil.AllowAny())
 	if err != nil {
@@ -99,6 +106,10 @@ func (rc *CreateConfig) Validate() (err error) {
 	return nil
 }
 
+// createBatchSize is the number of entries sent to the server per
+// CreateEntries call when creating entries from a data file.
+const createBatchSize = 500
+
 type CreateCLI struct{}
 
 func (CreateCLI) Synopsis() string {
@@ -153,10 +164,11 @@ func (c CreateCLI) Run(args []string) int {
 // parseConfig builds a registration entry from the given config
 func (c CreateCLI) parseConfig(config *CreateConfig) ([]*common.RegistrationEntry, error) {
 	e := &common.RegistrationEntry{
-		ParentId:   config.ParentID,
-		SpiffeId:   config.SpiffeID,
-		Ttl:        int32(config.Ttl),
-		Downstream: config.Downstream,
+		ParentId:    config.ParentID,
+		SpiffeId:    config.SpiffeID,
+		Ttl:         int32(config.Ttl),
+		Downstream:  config.Downstream,
+		EntryExpiry: config.EntryExpiry,
 	}
 
 	// If the node flag is set, then set the Parent ID to the server's expected SPIFFE ID
@@ -201,16 +213,38 @@ func (CreateCLI) parseFile(path string) ([]*common.RegistrationEntry, error) {
 }
 
 func (CreateCLI) registerEntries(ctx context.Context, c registration.RegistrationClient, entries []*common.RegistrationEntry) error {
-	for _, e := range entries {
-		id, err := c.CreateEntry(ctx, e)
+	if len(entries) == 1 {
+		id, err := c.CreateEntry(ctx, entries[0])
 		if err != nil {
 			fmt.Println("FAILED to create the following entry:")
-			printEntry(e)
+			printEntry(entries[0])
+			return err
+		}
+
+		entries[0].EntryId = id.Id
+		printEntry(entries[0])
+		return nil
+	}
+
+	// Entries are sent in batches so that large imports don't take a round
+	// trip per entry. Batches that were already created stay created.
+	for start := 0; start < len(entries); start += createBatchSize {
+		end := start + createBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		resp, err := c.CreateEntries(ctx, &registration.CreateEntriesRequest{
+			Entries: entries[start:end],
+		})
+		if err != nil {
+			fmt.Printf("FAILED to create entries %d to %d\n", start+1, end)
 			return err
 		}
 
-		e.EntryId = id.Id
-		printEntry(e)
+		for _, e := range resp.Entries {
+			printEntry(e)
+		}
 	}
 
 	return nil
@@ -224,6 +258,7 @@ func (CreateCLI) newConfig(args []string) (*CreateConfig, error) {
 	f.StringVar(&c.ParentID, "parentID", "", "The SPIFFE ID of this record's parent")
 	f.StringVar(&c.SpiffeID, "spiffeID", "", "The SPIFFE ID that this record represents")
 	f.IntVar(&c.Ttl, "ttl", 3600, "A TTL, in seconds, for any SVID issued as a result of this record")
+	f.Int64Var(&c.EntryExpiry, "entryExpiry", 0, "An expiry, in seconds since the Unix epoch, after which the entry is pruned by the server (optional)")
 
 	f.StringVar(&c.Path, "data", "", "Path to a file containing registration JSON (optional)")
 
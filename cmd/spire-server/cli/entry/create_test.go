@@ -1,13 +1,18 @@
 package entry
 
 import (
+	"fmt"
 	"path"
 	"testing"
 
+	"github.com/golang/mock/gomock"
+	"github.com/spiffe/spire/proto/api/registration"
 	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/test/mock/proto/api/registration"
 	"github.com/spiffe/spire/test/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 
 	cmdutil "github.com/spiffe/spire/cmd/spire-server/util"
 )
@@ -22,6 +27,7 @@ func TestCreateParseConfig(t *testing.T) {
 		Selectors:           StringsFlag{"unix:uid:1000", "unix:gid:1000"},
 		FederatesWith:       StringsFlag{"spiffe://domain1.test", "spiffe://domain2.test"},
 		Admin:               true,
+		EntryExpiry:         1552410266,
 	}
 
 	entries, err := CreateCLI{}.parseConfig(c)
@@ -39,7 +45,8 @@ func TestCreateParseConfig(t *testing.T) {
 			"spiffe://domain1.test",
 			"spiffe://domain2.test",
 		},
-		Admin: true,
+		Admin:       true,
+		EntryExpiry: 1552410266,
 	}
 
 	expectedEntries := []*common.RegistrationEntry{expectedEntry}
@@ -119,3 +126,30 @@ func TestRegisterParseSelector(t *testing.T) {
 	_, err = parseSelector(str)
 	assert.NotNil(t, err)
 }
+
+func TestRegisterEntriesInBatches(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mock_registration.NewMockRegistrationClient(mockCtrl)
+
+	entries := make([]*common.RegistrationEntry, createBatchSize+1)
+	for i := range entries {
+		entries[i] = &common.RegistrationEntry{
+			ParentId:  "spiffe://example.org/foo",
+			SpiffeId:  fmt.Sprintf("spiffe://example.org/bar%d", i),
+			Selectors: []*common.Selector{{Type: "unix", Value: "uid:1111"}},
+		}
+	}
+
+	var batches [][]*common.RegistrationEntry
+	client.EXPECT().CreateEntries(gomock.Any(), gomock.Any()).Times(2).DoAndReturn(
+		func(ctx context.Context, req *registration.CreateEntriesRequest) (*registration.CreateEntriesResponse, error) {
+			batches = append(batches, req.Entries)
+			return &registration.CreateEntriesResponse{Entries: req.Entries}, nil
+		})
+
+	require.NoError(t, CreateCLI{}.registerEntries(context.Background(), client, entries))
+	require.Len(t, batches, 2)
+	assert.Equal(t, entries[:createBatchSize], batches[0])
+	assert.Equal(t, entries[createBatchSize:], batches[1])
+}
@@ -40,6 +40,9 @@ type UpdateConfig struct {
 
 	// Whether or not the registration entry is for an "admin" workload
 	Admin bool
+
+	// Time, in seconds since the Unix epoch, the entry expires at
+	EntryExpiry int64
 }
 
 // Perform basic validation, even on fields that we
@@ -74,6 +77,10 @@ func (rc *UpdateConfig) Validate() (err error) {
 		return errors.New("a TTL is required")
 	}
 
+	if rc.EntryExpiry < 0 {
+		return errors.New("an entry expiry cannot be negative")
+	}
+
 	// make sure all SPIFFE ID's are well formed
 	rc.SpiffeID, err = idutil.NormalizeSpiffeID(rc.SpiffeID, idutil.AllowAny())
 	if err != nil {
@@ -147,11 +154,12 @@ func (c UpdateCLI) Run(args []string) int {
 // parseConfig builds a registration entry from the given config
 func (c UpdateCLI) parseConfig(config *UpdateConfig) ([]*common.RegistrationEntry, error) {
 	e := &common.RegistrationEntry{
-		EntryId:    config.EntryID,
-		ParentId:   config.ParentID,
-		SpiffeId:   config.SpiffeID,
-		Ttl:        int32(config.Ttl),
-		Downstream: config.Downstream,
+		EntryId:     config.EntryID,
+		ParentId:    config.ParentID,
+		SpiffeId:    config.SpiffeID,
+		Ttl:         int32(config.Ttl),
+		Downstream:  config.Downstream,
+		EntryExpiry: config.EntryExpiry,
 	}
 
 	selectors := []*common.Selector{}
@@ -210,6 +218,7 @@ func (UpdateCLI) newConfig(args []string) (*UpdateConfig, error) {
 	f.StringVar(&c.ParentID, "parentID", "", "The SPIFFE ID of this record's parent")
 	f.StringVar(&c.SpiffeID, "spiffeID", "", "The SPIFFE ID that this record represents")
 	f.IntVar(&c.Ttl, "ttl", 3600, "A TTL, in seconds, for any SVID issued as a result of this record")
+	f.Int64Var(&c.EntryExpiry, "entryExpiry", 0, "An expiry, in seconds since the Unix epoch, after which the entry is pruned by the server (optional)")
 
 	f.StringVar(&c.Path, "data", "", "Path to a file containing registration JSON (optional)")
 
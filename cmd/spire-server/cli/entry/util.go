@@ -3,6 +3,7 @@ package entry
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spiffe/spire/proto/common"
 )
@@ -71,6 +72,10 @@ func printEntry(e *common.RegistrationEntry) {
 		fmt.Printf("TTL           : %d\n", e.Ttl)
 	}
 
+	if e.EntryExpiry != 0 {
+		fmt.Printf("Entry Expiry  : %s\n", time.Unix(e.EntryExpiry, 0).UTC())
+	}
+
 	for _, s := range e.Selectors {
 		fmt.Printf("Selector      : %s:%s\n", s.Type, s.Value)
 	}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/hcl"
@@ -18,8 +19,10 @@ import (
 	"github.com/spiffe/spire/pkg/common/cli"
 	"github.com/spiffe/spire/pkg/common/idutil"
 	"github.com/spiffe/spire/pkg/common/log"
+	"github.com/spiffe/spire/pkg/common/selector"
 	"github.com/spiffe/spire/pkg/common/util"
 	"github.com/spiffe/spire/pkg/server"
+	"github.com/spiffe/spire/proto/server/keymanager"
 )
 
 const (
@@ -37,17 +40,23 @@ type runConfig struct {
 }
 
 type serverRunConfig struct {
-	BindAddress         string           `hcl:"bind_address"`
-	BindPort            int              `hcl:"bind_port"`
-	CASubject           *caSubjectConfig `hcl:"ca_subject"`
-	CATTL               string           `hcl:"ca_ttl"`
-	DataDir             string           `hcl:"data_dir"`
-	LogFile             string           `hcl:"log_file"`
-	LogLevel            string           `hcl:"log_level"`
-	RegistrationUDSPath string           `hcl:"registration_uds_path"`
-	SVIDTTL             string           `hcl:"svid_ttl"`
-	TrustDomain         string           `hcl:"trust_domain"`
-	UpstreamBundle      bool             `hcl:"upstream_bundle"`
+	BindAddress          string           `hcl:"bind_address"`
+	BindPort             int              `hcl:"bind_port"`
+	CAKeyType            string           `hcl:"ca_key_type"`
+	CASubject            *caSubjectConfig `hcl:"ca_subject"`
+	CATTL                string           `hcl:"ca_ttl"`
+	ChangeEventRetention string           `hcl:"change_event_retention"`
+	DataDir              string           `hcl:"data_dir"`
+	JWTKeyType           string           `hcl:"jwt_key_type"`
+	LogFile              string           `hcl:"log_file"`
+	LogLevel             string           `hcl:"log_level"`
+	RegistrationUDSPath  string           `hcl:"registration_uds_path"`
+	SVIDTTL              string           `hcl:"svid_ttl"`
+	TrustDomain          string           `hcl:"trust_domain"`
+	UpstreamBundle       bool             `hcl:"upstream_bundle"`
+
+	SelectorHook *selectorHookConfig `hcl:"selector_hook"`
+	Pruning      *pruningConfig      `hcl:"pruning"`
 
 	ConfigPath string
 
@@ -65,6 +74,17 @@ type caSubjectConfig struct {
 	CommonName   string   `hcl:"common_name"`
 }
 
+type selectorHookConfig struct {
+	Command []string `hcl:"command"`
+	Timeout string   `hcl:"timeout"`
+}
+
+type pruningConfig struct {
+	Interval            string `hcl:"interval"`
+	StaleAgentThreshold string `hcl:"stale_agent_threshold"`
+	DryRun              bool   `hcl:"dry_run"`
+}
+
 type serverConfig struct {
 	server.Config
 	umask int
@@ -74,13 +94,13 @@ type serverConfig struct {
 type RunCLI struct {
 }
 
-//Help prints the server cmd usage
+// Help prints the server cmd usage
 func (*RunCLI) Help() string {
 	_, err := parseFlags([]string{"-h"})
 	return err.Error()
 }
 
-//Run the SPIFFE Server
+// Run the SPIFFE Server
 func (*RunCLI) Run(args []string) int {
 	cliConfig, err := parseFlags(args)
 	if err != nil {
@@ -129,7 +149,7 @@ func (*RunCLI) Run(args []string) int {
 	return 0
 }
 
-//Synopsis of the command
+// Synopsis of the command
 func (*RunCLI) Synopsis() string {
 	return "Runs the server"
 }
@@ -283,6 +303,26 @@ func mergeConfig(orig *serverConfig, cmd *runConfig) error {
 		orig.CATTL = ttl
 	}
 
+	if cmd.Server.CAKeyType != "" {
+		keyType, err := keyTypeFromString(cmd.Server.CAKeyType)
+		if err != nil {
+			return fmt.Errorf("error parsing ca_key_type: %v", err)
+		}
+		orig.CAKeyType = keyType
+	}
+
+	if cmd.Server.JWTKeyType != "" {
+		keyType, err := keyTypeFromString(cmd.Server.JWTKeyType)
+		if err != nil {
+			return fmt.Errorf("error parsing jwt_key_type: %v", err)
+		}
+		// JWT-SVIDs are signed with ES256 or EdDSA
+		if keyType == keymanager.KeyType_EC_P384 {
+			return fmt.Errorf("jwt_key_type %q is not supported", cmd.Server.JWTKeyType)
+		}
+		orig.JWTKeyType = keyType
+	}
+
 	if subject := cmd.Server.CASubject; subject != nil {
 		orig.CASubject = pkix.Name{
 			Organization: subject.Organization,
@@ -291,6 +331,58 @@ func mergeConfig(orig *serverConfig, cmd *runConfig) error {
 		}
 	}
 
+	if hook := cmd.Server.SelectorHook; hook != nil {
+		if len(hook.Command) == 0 {
+			return errors.New("selector_hook requires a command")
+		}
+		var timeout time.Duration
+		if hook.Timeout != "" {
+			var err error
+			timeout, err = time.ParseDuration(hook.Timeout)
+			if err != nil {
+				return fmt.Errorf("unable to parse selector hook timeout %q: %v", hook.Timeout, err)
+			}
+		}
+		orig.SelectorHook = selector.NewHook(selector.HookConfig{
+			Command: hook.Command,
+			Timeout: timeout,
+		})
+	}
+
+	if pruning := cmd.Server.Pruning; pruning != nil {
+		orig.Pruning = &server.PruningConfig{
+			DryRun: pruning.DryRun,
+		}
+		if pruning.Interval != "" {
+			interval, err := time.ParseDuration(pruning.Interval)
+			if err != nil {
+				return fmt.Errorf("unable to parse pruning interval %q: %v", pruning.Interval, err)
+			}
+			if interval <= 0 {
+				return fmt.Errorf("pruning interval %q must be positive", pruning.Interval)
+			}
+			orig.Pruning.Interval = interval
+		}
+		if pruning.StaleAgentThreshold != "" {
+			threshold, err := time.ParseDuration(pruning.StaleAgentThreshold)
+			if err != nil {
+				return fmt.Errorf("unable to parse stale agent threshold %q: %v", pruning.StaleAgentThreshold, err)
+			}
+			orig.Pruning.StaleAgentThreshold = threshold
+		}
+	}
+
+	if cmd.Server.ChangeEventRetention != "" {
+		retention, err := time.ParseDuration(cmd.Server.ChangeEventRetention)
+		if err != nil {
+			return fmt.Errorf("unable to parse change event retention %q: %v", cmd.Server.ChangeEventRetention, err)
+		}
+		if retention <= 0 {
+			return fmt.Errorf("change event retention %q must be positive", cmd.Server.ChangeEventRetention)
+		}
+		orig.ChangeEventRetention = retention
+	}
+
 	return nil
 }
 
@@ -329,3 +421,16 @@ func newDefaultConfig() *serverConfig {
 		umask: -1,
 	}
 }
+
+func keyTypeFromString(s string) (keymanager.KeyType, error) {
+	switch strings.ToLower(s) {
+	case "ec-p256":
+		return keymanager.KeyType_EC_P256, nil
+	case "ec-p384":
+		return keymanager.KeyType_EC_P384, nil
+	case "ed25519":
+		return keymanager.KeyType_ED25519, nil
+	default:
+		return keymanager.KeyType_UNSPECIFIED_KEY_TYPE, fmt.Errorf("key type %q is unknown; must be ec-p256, ec-p384 or ed25519", s)
+	}
+}
@@ -3,8 +3,10 @@ package run
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/hcl/hcl/printer"
+	"github.com/spiffe/spire/proto/server/keymanager"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -99,3 +101,91 @@ func TestMergeConfigGood(t *testing.T) {
 	assert.Equal(t, orig.GlobalConfig().TrustDomain, "example.org")
 	assert.Equal(t, orig.umask, 0077)
 }
+
+func TestMergeConfigKeyTypes(t *testing.T) {
+	c := &runConfig{
+		Server: serverRunConfig{
+			CAKeyType:  "ed25519",
+			JWTKeyType: "ed25519",
+		},
+	}
+	orig := newDefaultConfig()
+	require.NoError(t, mergeConfig(orig, c))
+	assert.Equal(t, keymanager.KeyType_ED25519, orig.CAKeyType)
+	assert.Equal(t, keymanager.KeyType_ED25519, orig.JWTKeyType)
+
+	c.Server.CAKeyType = "rsa-2048"
+	err := mergeConfig(newDefaultConfig(), c)
+	require.EqualError(t, err, `error parsing ca_key_type: key type "rsa-2048" is unknown; must be ec-p256, ec-p384 or ed25519`)
+
+	c.Server.CAKeyType = ""
+	c.Server.JWTKeyType = "ec-p384"
+	err = mergeConfig(newDefaultConfig(), c)
+	require.EqualError(t, err, `jwt_key_type "ec-p384" is not supported`)
+}
+
+func TestMergeConfigSelectorHook(t *testing.T) {
+	c := &runConfig{
+		Server: serverRunConfig{
+			SelectorHook: &selectorHookConfig{
+				Command: []string{"/opt/spire/hook"},
+			},
+		},
+	}
+
+	orig := newDefaultConfig()
+	require.NoError(t, mergeConfig(orig, c))
+	assert.NotNil(t, orig.SelectorHook)
+
+	c.Server.SelectorHook.Command = nil
+	err := mergeConfig(newDefaultConfig(), c)
+	require.EqualError(t, err, "selector_hook requires a command")
+}
+
+func TestMergeConfigPruning(t *testing.T) {
+	orig := newDefaultConfig()
+	require.NoError(t, mergeConfig(orig, &runConfig{}))
+	assert.Nil(t, orig.Pruning)
+
+	c := &runConfig{
+		Server: serverRunConfig{
+			Pruning: &pruningConfig{
+				Interval:            "10m",
+				StaleAgentThreshold: "24h",
+				DryRun:              true,
+			},
+		},
+	}
+	orig = newDefaultConfig()
+	require.NoError(t, mergeConfig(orig, c))
+	require.NotNil(t, orig.Pruning)
+	assert.Equal(t, 10*time.Minute, orig.Pruning.Interval)
+	assert.Equal(t, 24*time.Hour, orig.Pruning.StaleAgentThreshold)
+	assert.True(t, orig.Pruning.DryRun)
+
+	c.Server.Pruning.Interval = "0s"
+	err := mergeConfig(newDefaultConfig(), c)
+	require.EqualError(t, err, `pruning interval "0s" must be positive`)
+
+	c.Server.Pruning.Interval = ""
+	c.Server.Pruning.StaleAgentThreshold = "forever"
+	err = mergeConfig(newDefaultConfig(), c)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to parse stale agent threshold")
+}
+
+func TestMergeConfigChangeEventRetention(t *testing.T) {
+	c := &runConfig{
+		Server: serverRunConfig{
+			ChangeEventRetention: "6h",
+		},
+	}
+	orig := newDefaultConfig()
+	require.NoError(t, mergeConfig(orig, c))
+	assert.Nil(t, orig.Pruning)
+	assert.Equal(t, 6*time.Hour, orig.ChangeEventRetention)
+
+	c.Server.ChangeEventRetention = "-1h"
+	err := mergeConfig(newDefaultConfig(), c)
+	require.EqualError(t, err, `change event retention "-1h" must be positive`)
+}
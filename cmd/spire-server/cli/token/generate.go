@@ -25,6 +25,9 @@ type GenerateConfig struct {
 
 	// Token TTL in seconds
 	TTL int
+
+	// Number of times the token can be used
+	MaxUses int
 }
 
 func (GenerateCLI) Synopsis() string {
@@ -45,13 +48,20 @@ func (g GenerateCLI) Run(args []string) int {
 		return 1
 	}
 
+	// agents sharing a reusable token are each given a unique ID under the
+	// token, so a single vanity record cannot be parented to all of them
+	if config.MaxUses > 1 && config.SpiffeID != "" {
+		fmt.Println("A SPIFFE ID cannot be assigned to a token with more than one use")
+		return 1
+	}
+
 	c, err := util.NewRegistrationClient(config.RegistrationUDSPath)
 	if err != nil {
 		fmt.Println(err.Error())
 		return 1
 	}
 
-	token, err := g.createToken(ctx, c, config.TTL)
+	token, err := g.createToken(ctx, c, config.TTL, config.MaxUses)
 	if err != nil {
 		fmt.Println(err.Error())
 		return 1
@@ -73,9 +83,10 @@ func (g GenerateCLI) Run(args []string) int {
 }
 
 // createToken calls the registration API and creates a new token
-// with the given TTL. It returns the raw token and an error, if any
-func (GenerateCLI) createToken(ctx context.Context, c registration.RegistrationClient, ttl int) (string, error) {
-	req := &registration.JoinToken{Ttl: int32(ttl)}
+// with the given TTL and maximum number of uses. It returns the raw
+// token and an error, if any
+func (GenerateCLI) createToken(ctx context.Context, c registration.RegistrationClient, ttl, maxUses int) (string, error) {
+	req := &registration.JoinToken{Ttl: int32(ttl), MaxUses: int32(maxUses)}
 	resp, err := c.CreateJoinToken(ctx, req)
 	if err != nil {
 		return "", err
@@ -119,6 +130,7 @@ func (GenerateCLI) newConfig(args []string) (GenerateConfig, error) {
 	c := GenerateConfig{}
 
 	flags.IntVar(&c.TTL, "ttl", 600, "Token TTL in seconds")
+	flags.IntVar(&c.MaxUses, "maxUses", 1, "Number of agents that can attest using the token")
 	flags.StringVar(&c.SpiffeID, "spiffeID", "", "Additional SPIFFE ID to assign the token owner (optional)")
 	flags.StringVar(&c.RegistrationUDSPath, "registrationUDSPath", util.DefaultSocketPath, "Registration API UDS path")
 
@@ -25,11 +25,30 @@ func TestCreateToken(t *testing.T) {
 	resp := &registration.JoinToken{Token: "foobar", Ttl: 60}
 
 	c.EXPECT().CreateJoinToken(gomock.Any(), req).Return(resp, nil)
-	token, err := GenerateCLI{}.createToken(ctx, c, 60)
+	token, err := GenerateCLI{}.createToken(ctx, c, 60, 0)
 	require.NoError(t, err)
 	assert.Equal(t, "foobar", token)
 }
 
+func TestCreateReusableToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := mock_registration.NewMockRegistrationClient(ctrl)
+	req := &registration.JoinToken{Ttl: 60, MaxUses: 5}
+	resp := &registration.JoinToken{Token: "foobar", Ttl: 60, MaxUses: 5}
+
+	c.EXPECT().CreateJoinToken(gomock.Any(), req).Return(resp, nil)
+	token, err := GenerateCLI{}.createToken(ctx, c, 60, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", token)
+}
+
+func TestRunRejectsSpiffeIDForReusableToken(t *testing.T) {
+	ret := GenerateCLI{}.Run([]string{"-maxUses", "2", "-spiffeID", "spiffe://example.org/VanityID"})
+	assert.Equal(t, 1, ret)
+}
+
 func TestCreateVanityRecord(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -0,0 +1,102 @@
+package token
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spiffe/spire/cmd/spire-server/util"
+	"github.com/spiffe/spire/proto/api/registration"
+
+	"golang.org/x/net/context"
+)
+
+//ListConfig holds configuration for ListCLI
+type ListConfig struct {
+	// Socket path of registration API
+	RegistrationUDSPath string
+}
+
+// Validate will perform a basic validation on config fields
+func (c *ListConfig) Validate() (err error) {
+	if c.RegistrationUDSPath == "" {
+		return errors.New("a socket path for registration api is required")
+	}
+	return nil
+}
+
+//ListCLI command for listing outstanding join tokens
+type ListCLI struct {
+	registrationClient registration.RegistrationClient
+	tokenList          []*registration.JoinToken
+}
+
+func (ListCLI) Synopsis() string {
+	return "Lists outstanding join tokens"
+}
+
+func (c ListCLI) Help() string {
+	_, err := c.parseConfig([]string{"-h"})
+	return err.Error()
+}
+
+//Run will list outstanding join tokens
+func (c *ListCLI) Run(args []string) int {
+	ctx := context.Background()
+
+	config, err := c.parseConfig(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err = config.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if c.registrationClient == nil {
+		c.registrationClient, err = util.NewRegistrationClient(config.RegistrationUDSPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error establishing connection to the Registration API: %v \n", err)
+			return 1
+		}
+	}
+
+	listResponse, err := c.registrationClient.ListJoinTokens(ctx, &registration.ListJoinTokensRequest{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing join tokens: %v \n", err)
+		return 1
+	}
+	c.tokenList = listResponse.JoinTokens
+	c.printJoinTokens()
+	return 0
+}
+
+func (ListCLI) parseConfig(args []string) (*ListConfig, error) {
+	f := flag.NewFlagSet("token list", flag.ContinueOnError)
+	c := &ListConfig{}
+
+	f.StringVar(&c.RegistrationUDSPath, "registrationUDSPath", util.DefaultSocketPath, "Registration API UDS path")
+
+	return c, f.Parse(args)
+}
+
+func (c ListCLI) printJoinTokens() {
+	msg := fmt.Sprintf("Found %d join ", len(c.tokenList))
+	msg = util.Pluralizer(msg, "token", "tokens", len(c.tokenList))
+	fmt.Print(msg + ":\n\n")
+
+	for _, token := range c.tokenList {
+		maxUses := token.MaxUses
+		if maxUses < 1 {
+			maxUses = 1
+		}
+		fmt.Printf("Token             : %s\n", token.Token)
+		fmt.Printf("Uses              : %d/%d\n", token.Uses, maxUses)
+		fmt.Printf("Expiration time   : %s\n", time.Unix(token.Expiry, 0))
+		fmt.Println()
+	}
+}
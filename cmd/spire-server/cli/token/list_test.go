@@ -0,0 +1,61 @@
+package token
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spiffe/spire/proto/api/registration"
+	"github.com/spiffe/spire/test/mock/proto/api/registration"
+	"github.com/stretchr/testify/suite"
+)
+
+type ListTestSuite struct {
+	suite.Suite
+	cli        *ListCLI
+	mockClient *mock_registration.MockRegistrationClient
+	mockCtrl   *gomock.Controller
+}
+
+func (s *ListTestSuite) SetupTest() {
+	s.mockCtrl = gomock.NewController(s.T())
+	s.mockClient = mock_registration.NewMockRegistrationClient(s.mockCtrl)
+	s.cli = &ListCLI{
+		registrationClient: s.mockClient,
+	}
+}
+
+func (s *ListTestSuite) TearDownTest() {
+	s.mockCtrl.Finish()
+}
+
+func TestListTestSuite(t *testing.T) {
+	suite.Run(t, new(ListTestSuite))
+}
+
+func (s *ListTestSuite) TestRun() {
+	req := &registration.ListJoinTokensRequest{}
+	resp := &registration.ListJoinTokensResponse{
+		JoinTokens: []*registration.JoinToken{
+			{Token: "foobar", MaxUses: 3, Uses: 1, Expiry: 1000},
+		},
+	}
+	s.mockClient.EXPECT().ListJoinTokens(gomock.Any(), req).Return(resp, nil)
+	s.Require().Equal(0, s.cli.Run([]string{}))
+	s.Assert().Equal(resp.JoinTokens, s.cli.tokenList)
+}
+
+func (s *ListTestSuite) TestRunWithNoTokens() {
+	req := &registration.ListJoinTokensRequest{}
+	resp := &registration.ListJoinTokensResponse{}
+	s.mockClient.EXPECT().ListJoinTokens(gomock.Any(), req).Return(resp, nil)
+	s.Require().Equal(0, s.cli.Run([]string{}))
+	s.Assert().Equal(resp.JoinTokens, s.cli.tokenList)
+}
+
+func (s *ListTestSuite) TestRunExitsWithNonZeroCodeOnFailure() {
+	req := &registration.ListJoinTokensRequest{}
+	s.mockClient.EXPECT().ListJoinTokens(gomock.Any(), req).Return(nil, errors.New("Some error"))
+	s.Require().Equal(1, s.cli.Run([]string{}))
+	s.Assert().Nil(s.cli.tokenList)
+}
@@ -0,0 +1,55 @@
+package token
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spiffe/spire/proto/api/registration"
+	"github.com/spiffe/spire/test/mock/proto/api/registration"
+	"github.com/stretchr/testify/suite"
+)
+
+type RevokeTestSuite struct {
+	suite.Suite
+	cli        *RevokeCLI
+	mockClient *mock_registration.MockRegistrationClient
+	mockCtrl   *gomock.Controller
+}
+
+func (s *RevokeTestSuite) SetupTest() {
+	s.mockCtrl = gomock.NewController(s.T())
+	s.mockClient = mock_registration.NewMockRegistrationClient(s.mockCtrl)
+	s.cli = &RevokeCLI{
+		registrationClient: s.mockClient,
+	}
+}
+
+func (s *RevokeTestSuite) TearDownTest() {
+	s.mockCtrl.Finish()
+}
+
+func TestRevokeTestSuite(t *testing.T) {
+	suite.Run(t, new(RevokeTestSuite))
+}
+
+func (s *RevokeTestSuite) TestRun() {
+	req := &registration.RevokeJoinTokenRequest{Token: "foobar"}
+	resp := &registration.RevokeJoinTokenResponse{
+		JoinToken: &registration.JoinToken{Token: "foobar"},
+	}
+
+	s.mockClient.EXPECT().RevokeJoinToken(gomock.Any(), req).Return(resp, nil)
+	s.Require().Equal(0, s.cli.Run([]string{"-token", "foobar"}))
+}
+
+func (s *RevokeTestSuite) TestRunExitsWithNonZeroCodeOnError() {
+	req := &registration.RevokeJoinTokenRequest{Token: "foobar"}
+
+	s.mockClient.EXPECT().RevokeJoinToken(gomock.Any(), req).Return(nil, errors.New("Some error"))
+	s.Require().Equal(1, s.cli.Run([]string{"-token", "foobar"}))
+}
+
+func (s *RevokeTestSuite) TestRunRequiresToken() {
+	s.Require().Equal(1, s.cli.Run([]string{}))
+}
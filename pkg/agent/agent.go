@@ -2,16 +2,22 @@ package agent
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
 	"fmt"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"os/signal"
 	"path"
 	"runtime"
 	"sync"
 
 	attestor "github.com/spiffe/spire/pkg/agent/attestor/node"
+	workload_attestor "github.com/spiffe/spire/pkg/agent/attestor/workload"
 	"github.com/spiffe/spire/pkg/agent/catalog"
+	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/agent/common/ebpf"
 	"github.com/spiffe/spire/pkg/agent/endpoints"
 	"github.com/spiffe/spire/pkg/agent/manager"
 	"github.com/spiffe/spire/pkg/common/profiling"
@@ -42,6 +48,11 @@ func (a *Agent) Run(ctx context.Context) error {
 		defer stopProfiling()
 	}
 
+	if a.c.EBPFAttestation {
+		stopTracking := a.setupEBPFTracking()
+		defer stopTracking()
+	}
+
 	metrics := telemetry.NewMetrics(&telemetry.MetricsConfig{
 		Logger:      a.c.Log.WithField("subsystem_name", "telemetry").Writer(),
 		ServiceName: "spire_agent",
@@ -59,12 +70,13 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
-	as, err := a.attest(ctx, cat, metrics)
+	nodeAttestor := a.newAttestor(cat, metrics)
+	as, err := nodeAttestor.Attest(ctx)
 	if err != nil {
 		return err
 	}
 
-	manager, err := a.newManager(ctx, cat, metrics, as)
+	manager, err := a.newManager(ctx, cat, metrics, nodeAttestor, as)
 	if err != nil {
 		return err
 	}
@@ -81,6 +93,23 @@ func (a *Agent) Run(ctx context.Context) error {
 	return err
 }
 
+// setupEBPFTracking attaches the eBPF program tracking the cgroups of
+// workloads. If it cannot be attached, workload attestors keep reading
+// cgroups from procfs.
+func (a *Agent) setupEBPFTracking() (stop func()) {
+	tracker, err := ebpf.New()
+	if err != nil {
+		a.c.Log.Warnf("eBPF attestation unavailable; falling back to procfs: %v", err)
+		return func() {}
+	}
+	a.c.Log.Info("Tracking workload cgroups with eBPF")
+	cgroups.SetTracker(tracker)
+	return func() {
+		cgroups.SetTracker(nil)
+		tracker.Close()
+	}
+}
+
 func (a *Agent) setupProfiling(ctx context.Context) (stop func()) {
 	var wg sync.WaitGroup
 	ctx, cancel := context.WithCancel(ctx)
@@ -135,22 +164,23 @@ func (a *Agent) setupProfiling(ctx context.Context) (stop func()) {
 	}
 }
 
-func (a *Agent) attest(ctx context.Context, cat catalog.Catalog, metrics telemetry.Metrics) (*attestor.AttestationResult, error) {
+func (a *Agent) newAttestor(cat catalog.Catalog, metrics telemetry.Metrics) attestor.Attestor {
 	config := attestor.Config{
-		Catalog:         cat,
-		Metrics:         metrics,
-		JoinToken:       a.c.JoinToken,
-		TrustDomain:     a.c.TrustDomain,
-		TrustBundle:     a.c.TrustBundle,
-		BundleCachePath: a.bundleCachePath(),
-		SVIDCachePath:   a.agentSVIDPath(),
-		Log:             a.c.Log.WithField("subsystem_name", "attestor"),
-		ServerAddress:   a.c.ServerAddress,
+		Catalog:           cat,
+		Metrics:           metrics,
+		JoinToken:         a.c.JoinToken,
+		ReusableJoinToken: a.c.ReusableJoinToken,
+		TrustDomain:       a.c.TrustDomain,
+		TrustBundle:       a.c.TrustBundle,
+		BundleCachePath:   a.bundleCachePath(),
+		SVIDCachePath:     a.agentSVIDPath(),
+		Log:               a.c.Log.WithField("subsystem_name", "attestor"),
+		ServerAddress:     a.c.ServerAddress,
 	}
-	return attestor.New(&config).Attest(ctx)
+	return attestor.New(&config)
 }
 
-func (a *Agent) newManager(ctx context.Context, cat catalog.Catalog, metrics telemetry.Metrics, as *attestor.AttestationResult) (manager.Manager, error) {
+func (a *Agent) newManager(ctx context.Context, cat catalog.Catalog, metrics telemetry.Metrics, nodeAttestor attestor.Attestor, as *attestor.AttestationResult) (manager.Manager, error) {
 	config := &manager.Config{
 		SVID:            as.SVID,
 		SVIDKey:         as.Key,
@@ -164,6 +194,19 @@ func (a *Agent) newManager(ctx context.Context, cat catalog.Catalog, metrics tel
 		SVIDCachePath:   a.agentSVIDPath(),
 	}
 
+	// join tokens cannot be used to attest again
+	if a.c.JoinToken == "" {
+		config.Reattestor = func(ctx context.Context, svid []*x509.Certificate, key *ecdsa.PrivateKey) ([]*x509.Certificate, *ecdsa.PrivateKey, error) {
+			as, err := nodeAttestor.Reattest(ctx, svid, key)
+			if err != nil {
+				return nil, nil, err
+			}
+			return as.SVID, as.Key, nil
+		}
+		config.ReattestInterval = a.c.ReattestInterval
+		config.ReattestCh = a.reattestOnSignal(ctx)
+	}
+
 	mgr, err := manager.New(config)
 	if err != nil {
 		return nil, err
@@ -176,6 +219,34 @@ func (a *Agent) newManager(ctx context.Context, cat catalog.Catalog, metrics tel
 	return mgr, nil
 }
 
+// reattestOnSignal returns a channel that receives a value each time the
+// agent is sent SIGUSR1, requesting an on-demand re-attestation. It returns
+// nil on platforms without a re-attestation signal.
+func (a *Agent) reattestOnSignal(ctx context.Context) <-chan struct{} {
+	if len(reattestSignals) == 0 {
+		return nil
+	}
+	reattestCh := make(chan struct{}, 1)
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, reattestSignals...)
+	go func() {
+		defer signal.Stop(signalCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signalCh:
+				a.c.Log.Info("Received re-attestation request")
+				select {
+				case reattestCh <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return reattestCh
+}
+
 func (a *Agent) newEndpoints(ctx context.Context, cat catalog.Catalog, metrics telemetry.Metrics, mgr manager.Manager) endpoints.Server {
 	config := &endpoints.Config{
 		BindAddr:  a.c.BindAddress,
@@ -184,6 +255,11 @@ func (a *Agent) newEndpoints(ctx context.Context, cat catalog.Catalog, metrics t
 		Log:       a.c.Log.WithField("subsystem_name", "endpoints"),
 		Metrics:   metrics,
 		EnableSDS: a.c.EnableSDS,
+
+		SelectorHook: a.c.SelectorHook,
+	}
+	if a.c.WorkloadAttestationCacheTTL > 0 {
+		config.AttestationCache = workload_attestor.NewCache(a.c.WorkloadAttestationCacheTTL)
 	}
 
 	return endpoints.New(config)
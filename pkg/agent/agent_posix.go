@@ -0,0 +1,11 @@
+// +build !windows
+
+package agent
+
+import (
+	"os"
+	"syscall"
+)
+
+// reattestSignals are the signals requesting an on-demand re-attestation
+var reattestSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,10 @@
+// +build windows
+
+package agent
+
+import "os"
+
+// reattestSignals are the signals requesting an on-demand re-attestation.
+// Windows has no equivalent of SIGUSR1, so re-attestation only happens on
+// the configured interval.
+var reattestSignals []os.Signal
@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"path"
 
+	"github.com/gofrs/uuid"
 	"github.com/sirupsen/logrus"
 	spiffe_tls "github.com/spiffe/go-spiffe/tls"
 	"github.com/spiffe/spire/pkg/agent/catalog"
@@ -35,19 +36,24 @@ type AttestationResult struct {
 
 type Attestor interface {
 	Attest(ctx context.Context) (*AttestationResult, error)
+
+	// Reattest performs node attestation again, authenticating to the server
+	// with the current agent SVID and key. The agent ID must not change.
+	Reattest(ctx context.Context, svid []*x509.Certificate, key *ecdsa.PrivateKey) (*AttestationResult, error)
 }
 
 type Config struct {
-	Catalog         catalog.Catalog
-	Metrics         telemetry.Metrics
-	JoinToken       string
-	TrustDomain     url.URL
-	TrustBundle     []*x509.Certificate
-	BundleCachePath string
-	SVIDCachePath   string
-	Log             logrus.FieldLogger
-	ServerAddress   string
-	NodeClient      node.NodeClient
+	Catalog           catalog.Catalog
+	Metrics           telemetry.Metrics
+	JoinToken         string
+	ReusableJoinToken bool
+	TrustDomain       url.URL
+	TrustBundle       []*x509.Certificate
+	BundleCachePath   string
+	SVIDCachePath     string
+	Log               logrus.FieldLogger
+	ServerAddress     string
+	NodeClient        node.NodeClient
 }
 
 type attestor struct {
@@ -71,7 +77,7 @@ func (a *attestor) Attest(ctx context.Context) (res *AttestationResult, err erro
 	}
 
 	if svid == nil {
-		svid, bundle, err = a.newSVID(ctx, key, bundle)
+		svid, bundle, err = a.newSVID(ctx, key, bundle, nil, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -79,6 +85,39 @@ func (a *attestor) Attest(ctx context.Context) (res *AttestationResult, err erro
 	return &AttestationResult{Bundle: bundle, SVID: svid, Key: key}, nil
 }
 
+func (a *attestor) Reattest(ctx context.Context, currentSVID []*x509.Certificate, currentKey *ecdsa.PrivateKey) (res *AttestationResult, err error) {
+	defer telemetry.CountCall(a.c.Metrics, "node", "reattest")(&err)
+
+	// join tokens are consumed by the initial attestation
+	if a.c.JoinToken != "" {
+		return nil, errors.New("re-attestation is not supported with join tokens")
+	}
+
+	bundle, err := a.loadBundle()
+	if err != nil {
+		return nil, err
+	}
+
+	mgrs := a.c.Catalog.KeyManagers()
+	if len(mgrs) > 1 {
+		return nil, errors.New("more than one key manager configured")
+	}
+	gResp, err := mgrs[0].GenerateKeyPair(ctx, &keymanager.GenerateKeyPairRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("generate key pair: %v", err)
+	}
+	key, err := x509.ParseECPrivateKey(gResp.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parse key from keymanager: %v", err)
+	}
+
+	svid, bundle, err := a.newSVID(ctx, key, bundle, currentSVID, currentKey)
+	if err != nil {
+		return nil, err
+	}
+	return &AttestationResult{Bundle: bundle, SVID: svid, Key: key}, nil
+}
+
 func (a *attestor) loadSVID(ctx context.Context) ([]*x509.Certificate, *ecdsa.PrivateKey, error) {
 	mgrs := a.c.Catalog.KeyManagers()
 	if len(mgrs) > 1 {
@@ -153,6 +192,13 @@ func (a *attestor) fetchAttestationData(
 			Host:   a.c.TrustDomain.Host,
 			Path:   path.Join("spire", "agent", "join_token", a.c.JoinToken),
 		}
+		if a.c.ReusableJoinToken {
+			u, err := uuid.NewV4()
+			if err != nil {
+				return nil, fmt.Errorf("generating agent ID suffix: %v", err)
+			}
+			id.Path = path.Join(id.Path, u.String())
+		}
 
 		return &nodeattestor.FetchAttestationDataResponse{
 			AttestationData: data,
@@ -191,8 +237,10 @@ func (a *attestor) readSVIDFromDisk() []*x509.Certificate {
 }
 
 // newSVID obtains an agent svid for the given private key by performing node attesatation. The bundle is
-// necessary in order to validate the SPIRE server we are attesting to. Returns the SVID and an updated bundle.
-func (a *attestor) newSVID(ctx context.Context, key *ecdsa.PrivateKey, bundle *bundleutil.Bundle) (newSVID []*x509.Certificate, newBundle *bundleutil.Bundle, err error) {
+// necessary in order to validate the SPIRE server we are attesting to. When re-attesting, the current SVID
+// and key are presented to the server and the attested agent ID must match the current one. Returns the SVID
+// and an updated bundle.
+func (a *attestor) newSVID(ctx context.Context, key *ecdsa.PrivateKey, bundle *bundleutil.Bundle, currentSVID []*x509.Certificate, currentKey *ecdsa.PrivateKey) (newSVID []*x509.Certificate, newBundle *bundleutil.Bundle, err error) {
 	counter := telemetry.StartCall(a.c.Metrics, "node", "attestor", "new_svid")
 	defer counter.Done(&err)
 
@@ -218,16 +266,25 @@ func (a *attestor) newSVID(ctx context.Context, key *ecdsa.PrivateKey, bundle *b
 
 	counter.AddLabel("type", attestorName)
 
-	conn, err := a.serverConn(ctx, bundle.RootCAs())
+	var currentID string
+	if currentSVID != nil {
+		currentID, err = getSpiffeIDFromSVID(currentSVID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	conn, err := a.serverConn(ctx, bundle.RootCAs(), currentSVID, currentKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("create attestation client: %v", err)
 	}
 	defer conn.Close()
-	if a.c.NodeClient == nil {
-		a.c.NodeClient = node.NewNodeClient(conn)
+	nodeClient := a.c.NodeClient
+	if nodeClient == nil {
+		nodeClient = node.NewNodeClient(conn)
 	}
 
-	attestStream, err := a.c.NodeClient.Attest(ctx)
+	attestStream, err := nodeClient.Attest(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("opening stream for attestation: %v", err)
 	}
@@ -241,6 +298,10 @@ func (a *attestor) newSVID(ctx context.Context, key *ecdsa.PrivateKey, bundle *b
 			return nil, nil, err
 		}
 
+		if currentID != "" && currentID != data.SpiffeId {
+			return nil, nil, fmt.Errorf("attested agent ID %q does not match current agent ID %q", data.SpiffeId, currentID)
+		}
+
 		// (re)generate the SVID if the spiffeid changes.
 		if spiffeID != data.SpiffeId {
 			csr, err = util.MakeCSR(key, data.SpiffeId)
@@ -292,17 +353,17 @@ func (a *attestor) newSVID(ctx context.Context, key *ecdsa.PrivateKey, bundle *b
 	return svid, bundle, nil
 }
 
-func (a *attestor) serverConn(ctx context.Context, bundle []*x509.Certificate) (*grpc.ClientConn, error) {
+func (a *attestor) serverConn(ctx context.Context, bundle []*x509.Certificate, svid []*x509.Certificate, key *ecdsa.PrivateKey) (*grpc.ClientConn, error) {
 	config := grpcutil.GRPCDialerConfig{
 		Log:      grpcutil.LoggerFromFieldLogger(a.c.Log),
-		CredFunc: a.serverCredFunc(bundle),
+		CredFunc: a.serverCredFunc(bundle, svid, key),
 	}
 
 	dialer := grpcutil.NewGRPCDialer(config)
 	return dialer.Dial(ctx, a.c.ServerAddress)
 }
 
-func (a *attestor) serverCredFunc(bundle []*x509.Certificate) func() (credentials.TransportCredentials, error) {
+func (a *attestor) serverCredFunc(bundle []*x509.Certificate, svid []*x509.Certificate, key *ecdsa.PrivateKey) func() (credentials.TransportCredentials, error) {
 	pool := x509.NewCertPool()
 	for _, c := range bundle {
 		pool.AddCert(c)
@@ -313,8 +374,16 @@ func (a *attestor) serverCredFunc(bundle []*x509.Certificate) func() (credential
 		TrustRoots: pool,
 	}
 
-	// Explicitly not mTLS since we don't have an SVID yet
-	tlsConfig := spiffePeer.NewTLSConfig([]tls.Certificate{})
+	// Only mTLS when re-attesting since otherwise we don't have an SVID yet
+	var certs []tls.Certificate
+	if svid != nil {
+		cert := tls.Certificate{PrivateKey: key}
+		for _, c := range svid {
+			cert.Certificate = append(cert.Certificate, c.Raw)
+		}
+		certs = append(certs, cert)
+	}
+	tlsConfig := spiffePeer.NewTLSConfig(certs)
 	credFunc := func() (credentials.TransportCredentials, error) { return credentials.NewTLS(tlsConfig), nil }
 	return credFunc
 }
@@ -354,6 +423,13 @@ func (a *attestor) parseAttestationResponse(id string, r *node.AttestResponse) (
 	return svid, bundle, nil
 }
 
+func getSpiffeIDFromSVID(svid []*x509.Certificate) (string, error) {
+	if len(svid) == 0 || len(svid[0].URIs) != 1 {
+		return "", errors.New("current agent SVID must have exactly one URI SAN")
+	}
+	return svid[0].URIs[0].String(), nil
+}
+
 func (a *attestor) serverID() *url.URL {
 	return &url.URL{
 		Scheme: "spiffe",
@@ -2,6 +2,7 @@ package attestor
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"crypto/x509"
 	"io"
 	"io/ioutil"
@@ -159,6 +160,74 @@ func (s *NodeAttestorTestSuite) TestAttestJoinToken() {
 	s.Assert().Equal([]*x509.Certificate{svid}, as.SVID)
 }
 
+func (s *NodeAttestorTestSuite) TestFetchAttestationDataReusableJoinToken() {
+	s.config.JoinToken = "foobar"
+	s.config.ReusableJoinToken = true
+
+	a := s.attestor.(*attestor)
+	data1, err := a.fetchAttestationData(nil, nil)
+	s.Require().NoError(err)
+	s.Require().Equal(&common.AttestationData{Type: "join_token", Data: []byte("foobar")}, data1.AttestationData)
+	s.Require().Regexp("^spiffe://example.com/spire/agent/join_token/foobar/[^/]+$", data1.SpiffeId)
+
+	// each agent sharing the token is given a distinct ID
+	data2, err := a.fetchAttestationData(nil, nil)
+	s.Require().NoError(err)
+	s.Require().NotEqual(data1.SpiffeId, data2.SpiffeId)
+}
+
+func (s *NodeAttestorTestSuite) TestReattest() {
+	s.linkBundle()
+	s.setCatalog(true)
+	s.setGenerateKeyPairResponse()
+	s.setFetchAttestationDataResponse(nil)
+	s.setAttestResponse(nil)
+
+	currentSVID, currentKey := s.makeCurrentSVID("spiffe://example.com/spire/agent/join_token/foobar")
+	as, err := s.attestor.Reattest(ctx, currentSVID, currentKey)
+	s.Require().NoError(err)
+
+	svid, key, err := util.LoadSVIDFixture()
+	s.Require().NoError(err)
+
+	s.Assert().Equal(key, as.Key)
+	s.Assert().Equal([]*x509.Certificate{svid}, as.SVID)
+}
+
+func (s *NodeAttestorTestSuite) TestReattestWithDifferentAgentID() {
+	s.linkBundle()
+	s.setCatalog(true)
+	s.setGenerateKeyPairResponse()
+
+	stream := mock_nodeattestor.NewMockFetchAttestationData_Stream(s.ctrl)
+	stream.EXPECT().Recv().Return(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{Type: "test"},
+		SpiffeId:        "spiffe://example.com/spire/agent/test/other",
+	}, nil)
+	s.nodeAttestor.EXPECT().FetchAttestationData(gomock.Any()).Return(stream, nil)
+	s.nodeClient.EXPECT().Attest(gomock.Any()).Return(mock_node.NewMockNode_AttestClient(s.ctrl), nil)
+
+	currentSVID, currentKey := s.makeCurrentSVID("spiffe://example.com/spire/agent/test/id")
+	_, err := s.attestor.Reattest(ctx, currentSVID, currentKey)
+	s.Require().EqualError(err, `attested agent ID "spiffe://example.com/spire/agent/test/other" does not match current agent ID "spiffe://example.com/spire/agent/test/id"`)
+}
+
+func (s *NodeAttestorTestSuite) TestReattestWithJoinToken() {
+	s.config.JoinToken = "foobar"
+
+	currentSVID, currentKey := s.makeCurrentSVID("spiffe://example.com/spire/agent/join_token/foobar")
+	_, err := s.attestor.Reattest(ctx, currentSVID, currentKey)
+	s.Require().EqualError(err, "re-attestation is not supported with join tokens")
+}
+
+func (s *NodeAttestorTestSuite) makeCurrentSVID(spiffeID string) ([]*x509.Certificate, *ecdsa.PrivateKey) {
+	temp, err := util.NewSVIDTemplate(spiffeID)
+	s.Require().NoError(err)
+	cert, key, err := util.SelfSign(temp)
+	s.Require().NoError(err)
+	return []*x509.Certificate{cert}, key
+}
+
 func (s *NodeAttestorTestSuite) linkAgentSVIDPath() {
 	err := os.Symlink(
 		path.Join(util.ProjectRoot(), "test/fixture/certs/agent_svid.der"),
@@ -0,0 +1,114 @@
+package attestor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spiffe/spire/proto/common"
+)
+
+// Cache holds the selectors of attested processes, so that repeated Workload
+// API calls from the same process do not run the workload attestors again.
+// Entries are keyed by PID and are only used while the process keeps the
+// identity it had when attested (its start time, executable, user and group
+// IDs and cgroups), so they are invalidated when the process exits, when its
+// PID is reused, when it execs another program, when it changes users or
+// groups, or when it moves to another cgroup. Since the properties of a workload, such as the
+// labels of its pod, may change while it runs, entries also expire after a
+// TTL. A nil Cache caches nothing.
+type Cache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[int32]cacheEntry
+	nextSweep time.Time
+
+	// hooks for tests
+	hooks struct {
+		now      func() time.Time
+		identify func(pid int32) (string, error)
+	}
+}
+
+type cacheEntry struct {
+	identity  string
+	selectors []*common.Selector
+	expiresAt time.Time
+}
+
+// NewCache returns a Cache whose entries expire after the given TTL. On
+// platforms where the identity of a process cannot be determined, nothing
+// is cached.
+func NewCache(ttl time.Duration) *Cache {
+	c := &Cache{
+		ttl:     ttl,
+		entries: make(map[int32]cacheEntry),
+	}
+	c.hooks.now = time.Now
+	c.hooks.identify = processIdentity
+	return c
+}
+
+// lookup returns the cached selectors of the process. It also returns the
+// current identity of the process, to be passed to store once the process
+// is attested. An empty identity means the process cannot be cached.
+func (c *Cache) lookup(pid int32) ([]*common.Selector, string, bool) {
+	if c == nil {
+		return nil, "", false
+	}
+	identity, err := c.hooks.identify(pid)
+	if err != nil {
+		return nil, "", false
+	}
+
+	now := c.hooks.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+
+	entry, ok := c.entries[pid]
+	if !ok || entry.identity != identity || !now.Before(entry.expiresAt) {
+		return nil, identity, false
+	}
+	return entry.selectors, identity, true
+}
+
+// store caches the selectors of the process with the identity returned by
+// lookup. The selectors are not cached if the identity of the process changed
+// while it was attested, since they may describe either identity.
+func (c *Cache) store(pid int32, identity string, selectors []*common.Selector) {
+	if c == nil || identity == "" {
+		return
+	}
+	if current, err := c.hooks.identify(pid); err != nil || current != identity {
+		return
+	}
+
+	now := c.hooks.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[pid] = cacheEntry{
+		identity:  identity,
+		selectors: selectors,
+		expiresAt: now.Add(c.ttl),
+	}
+}
+
+// sweep drops the entries which have expired or whose process has exited.
+// It runs at most once per TTL. Must be called with the lock held.
+func (c *Cache) sweep(now time.Time) {
+	if now.Before(c.nextSweep) {
+		return
+	}
+	c.nextSweep = now.Add(c.ttl)
+
+	for pid, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, pid)
+			continue
+		}
+		if identity, err := c.hooks.identify(pid); err != nil || identity != entry.identity {
+			delete(c.entries, pid)
+		}
+	}
+}
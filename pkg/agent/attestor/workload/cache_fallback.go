@@ -0,0 +1,11 @@
+// +build !linux
+
+package attestor
+
+import (
+	"errors"
+)
+
+func processIdentity(pid int32) (string, error) {
+	return "", errors.New("process identity is not supported on this platform")
+}
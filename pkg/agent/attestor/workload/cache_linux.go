@@ -0,0 +1,67 @@
+// +build linux
+
+package attestor
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// processIdentity returns the start time of the process, which tells it
+// apart from later processes reusing its PID, followed by the device and
+// inode of its executable, which change when it execs another program, its
+// real, effective, saved and filesystem UIDs and GIDs, which change when it
+// switches users, and its cgroups
+func processIdentity(pid int32) (string, error) {
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", err
+	}
+
+	// the command name may contain spaces and parentheses, so the fields
+	// are counted from the end of it. The start time is the 22nd field.
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return "", fmt.Errorf("malformed stat for PID %d", pid)
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 20 {
+		return "", fmt.Errorf("malformed stat for PID %d", pid)
+	}
+	startTime := fields[19]
+
+	// stat follows the link to the executable, which requires the same
+	// permissions as attaching to the process with ptrace
+	exe, err := os.Stat(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return "", err
+	}
+	exeStat, ok := exe.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", fmt.Errorf("unable to stat executable of PID %d", pid)
+	}
+
+	status, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return "", err
+	}
+	var ids []string
+	for _, line := range strings.Split(string(status), "\n") {
+		if strings.HasPrefix(line, "Uid:") || strings.HasPrefix(line, "Gid:") {
+			ids = append(ids, strings.Join(strings.Fields(line), " "))
+		}
+	}
+	if len(ids) != 2 {
+		return "", fmt.Errorf("malformed status for PID %d", pid)
+	}
+
+	cgroups, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\n%d:%d\n%s\n%s\n%s", startTime, exeStat.Dev, exeStat.Ino, ids[0], ids[1], cgroups), nil
+}
@@ -0,0 +1,41 @@
+// +build linux
+
+package attestor
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessIdentity(t *testing.T) {
+	identity, err := processIdentity(int32(os.Getpid()))
+	require.NoError(t, err)
+	require.NotEmpty(t, identity)
+
+	again, err := processIdentity(int32(os.Getpid()))
+	require.NoError(t, err)
+	require.Equal(t, identity, again)
+
+	_, err = processIdentity(-1)
+	require.Error(t, err)
+}
+
+func TestProcessIdentityIncludesExecutableAndIDs(t *testing.T) {
+	identity, err := processIdentity(int32(os.Getpid()))
+	require.NoError(t, err)
+
+	exe, err := os.Stat("/proc/self/exe")
+	require.NoError(t, err)
+	exeStat := exe.Sys().(*syscall.Stat_t)
+	require.Contains(t, identity, fmt.Sprintf("\n%d:%d\n", exeStat.Dev, exeStat.Ino))
+
+	uid := strconv.Itoa(os.Getuid())
+	gid := strconv.Itoa(os.Getgid())
+	require.Contains(t, identity, fmt.Sprintf("\nUid: %s %s ", uid, strconv.Itoa(os.Geteuid())))
+	require.Contains(t, identity, fmt.Sprintf("\nGid: %s %s ", gid, strconv.Itoa(os.Getegid())))
+}
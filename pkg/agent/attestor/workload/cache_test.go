@@ -0,0 +1,112 @@
+package attestor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/proto/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	identities := map[int32]string{1: "100", 2: "200"}
+
+	cache := NewCache(time.Minute)
+	cache.hooks.now = func() time.Time { return now }
+	cache.hooks.identify = func(pid int32) (string, error) {
+		if identity, ok := identities[pid]; ok {
+			return identity, nil
+		}
+		return "", errors.New("no such process")
+	}
+
+	sel1 := []*common.Selector{{Type: "foo", Value: "bar"}}
+	sel2 := []*common.Selector{{Type: "bat", Value: "baz"}}
+
+	_, identity, ok := cache.lookup(1)
+	require.False(t, ok)
+	require.Equal(t, "100", identity)
+	cache.store(1, identity, sel1)
+
+	_, identity, ok = cache.lookup(2)
+	require.False(t, ok)
+	cache.store(2, identity, sel2)
+
+	selectors, _, ok := cache.lookup(1)
+	require.True(t, ok)
+	require.Equal(t, sel1, selectors)
+
+	// processes that cannot be identified are not cached
+	_, identity, ok = cache.lookup(3)
+	require.False(t, ok)
+	require.Empty(t, identity)
+	cache.store(3, identity, sel1)
+	require.Len(t, cache.entries, 2)
+
+	// the entry of a process whose identity changed is not used
+	identities[1] = "101"
+	_, identity, ok = cache.lookup(1)
+	require.False(t, ok)
+	require.Equal(t, "101", identity)
+
+	// selectors are not cached if the identity of the process changed while
+	// it was attested
+	identities[1] = "102"
+	cache.store(1, identity, sel2)
+	_, identity, ok = cache.lookup(1)
+	require.False(t, ok)
+	require.Equal(t, "102", identity)
+
+	// entries expire after the TTL
+	now = now.Add(30 * time.Second)
+	_, _, ok = cache.lookup(2)
+	require.True(t, ok)
+	now = now.Add(30 * time.Second)
+	_, _, ok = cache.lookup(2)
+	require.False(t, ok)
+}
+
+func TestCacheSweep(t *testing.T) {
+	now := time.Now()
+	identities := map[int32]string{1: "100", 2: "200"}
+
+	cache := NewCache(time.Minute)
+	cache.hooks.now = func() time.Time { return now }
+	cache.hooks.identify = func(pid int32) (string, error) {
+		if identity, ok := identities[pid]; ok {
+			return identity, nil
+		}
+		return "", errors.New("no such process")
+	}
+
+	// the first lookup sweeps the empty cache
+	_, _, ok := cache.lookup(1)
+	require.False(t, ok)
+
+	now = now.Add(30 * time.Second)
+	for pid, identity := range identities {
+		cache.store(pid, identity, nil)
+	}
+	require.Len(t, cache.entries, 2)
+
+	// the entries of exited processes are dropped at most once per TTL
+	delete(identities, 2)
+	_, _, ok = cache.lookup(1)
+	require.True(t, ok)
+	require.Len(t, cache.entries, 2)
+
+	now = now.Add(30 * time.Second)
+	_, _, ok = cache.lookup(1)
+	require.True(t, ok)
+	require.Len(t, cache.entries, 1)
+}
+
+func TestNilCache(t *testing.T) {
+	var cache *Cache
+	_, identity, ok := cache.lookup(1)
+	require.False(t, ok)
+	require.Empty(t, identity)
+	cache.store(1, "100", nil)
+}
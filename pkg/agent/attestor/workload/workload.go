@@ -7,6 +7,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/pkg/agent/catalog"
+	"github.com/spiffe/spire/pkg/common/selector"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/proto/agent/workloadattestor"
 	"github.com/spiffe/spire/proto/common"
@@ -32,6 +33,12 @@ type Config struct {
 	Catalog catalog.Catalog
 	L       logrus.FieldLogger
 	M       telemetry.Metrics
+
+	// SelectorHook, if set, post-processes the selectors of the workload
+	SelectorHook *selector.Hook
+
+	// Cache, if set, holds the selectors of previously attested processes
+	Cache *Cache
 }
 
 const (
@@ -42,10 +49,19 @@ const (
 
 // Attest invokes all workload attestor plugins against the provided PID. If an error
 // is encountered, it is logged and selectors from the failing plugin are discarded.
+// The selectors of processes found in the cache are returned without invoking the
+// plugins, and only complete results are cached.
 func (wla *attestor) Attest(ctx context.Context, pid int32) []*common.Selector {
 	tLabels := []telemetry.Label{{workloadPid, string(pid)}}
 	defer wla.c.M.MeasureSinceWithLabels([]string{workloadApi, workloadAttDur}, time.Now(), tLabels)
 
+	cached, identity, ok := wla.c.Cache.lookup(pid)
+	if ok {
+		wla.c.M.IncrCounterWithLabels([]string{workloadApi, "attestation_cache_hit"}, 1, tLabels)
+		wla.c.L.Debugf("PID %v has cached selectors %v", pid, cached)
+		return cached
+	}
+
 	plugins := wla.c.Catalog.WorkloadAttestors()
 	sChan := make(chan []*common.Selector)
 	errChan := make(chan error)
@@ -62,15 +78,30 @@ func (wla *attestor) Attest(ctx context.Context, pid int32) []*common.Selector {
 
 	// Collect the results
 	selectors := []*common.Selector{}
+	complete := true
 	for i := 0; i < len(plugins); i++ {
 		select {
 		case s := <-sChan:
 			selectors = append(selectors, s...)
 		case err := <-errChan:
 			wla.c.L.Errorf("Failed to collect all selectors for PID %v: %v", pid, err)
+			complete = false
 		}
 	}
 
+	// selectors are discarded if the hook fails, since it may be relied on
+	// to filter out selectors
+	selectors, err := wla.c.SelectorHook.Process(ctx, selector.HookInput{Kind: selector.HookKindWorkload, PID: pid}, selectors)
+	if err != nil {
+		wla.c.L.Errorf("Discarding selectors for PID %v: %v", pid, err)
+		selectors = []*common.Selector{}
+		complete = false
+	}
+
+	if complete {
+		wla.c.Cache.store(pid, identity, selectors)
+	}
+
 	wla.c.M.AddSampleWithLabels([]string{workloadApi, "discovered_selectors"}, float32(len(selectors)), tLabels)
 	wla.c.L.Debugf("PID %v attested to have selectors %v", pid, selectors)
 	return selectors
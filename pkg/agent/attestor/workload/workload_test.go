@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/sirupsen/logrus/hooks/test"
@@ -94,3 +95,37 @@ func (s *WorkloadAttestorTestSuite) TestInvokeAttestor() {
 	s.Require().EqualError(err, `workload attestor "foo" failed: i'm an error`)
 	s.Require().Nil(result)
 }
+
+func (s *WorkloadAttestorTestSuite) TestAttestWorkloadCached() {
+	identities := map[int32]string{1: "100"}
+	cache := NewCache(time.Minute)
+	cache.hooks.identify = func(pid int32) (string, error) {
+		if identity, ok := identities[pid]; ok {
+			return identity, nil
+		}
+		return "", errors.New("no such process")
+	}
+	s.attestor.c.Cache = cache
+
+	sel1 := []*common.Selector{{Type: "foo", Value: "bar"}}
+	sel2 := []*common.Selector{{Type: "bat", Value: "baz"}}
+	expected := selector.NewSetFromRaw([]*common.Selector{sel1[0], sel2[0]})
+
+	// incomplete results are not cached
+	s.attestor1.EXPECT().Attest(gomock.Any(), gomock.Any()).Return(nil, errors.New("i'm an error"))
+	s.attestor2.EXPECT().Attest(gomock.Any(), gomock.Any()).Return(&workloadattestor.AttestResponse{Selectors: sel2}, nil)
+	s.Assert().Equal(sel2, s.attestor.Attest(ctx, 1))
+
+	// the plugins are only invoked once for the same process
+	s.attestor1.EXPECT().Attest(gomock.Any(), gomock.Any()).Return(&workloadattestor.AttestResponse{Selectors: sel1}, nil)
+	s.attestor2.EXPECT().Attest(gomock.Any(), gomock.Any()).Return(&workloadattestor.AttestResponse{Selectors: sel2}, nil)
+	s.Assert().Equal(expected, selector.NewSetFromRaw(s.attestor.Attest(ctx, 1)))
+	s.Assert().Equal(expected, selector.NewSetFromRaw(s.attestor.Attest(ctx, 1)))
+
+	// a process reusing the PID is attested again
+	identities[1] = "200"
+	s.attestor1.EXPECT().Attest(gomock.Any(), gomock.Any()).Return(&workloadattestor.AttestResponse{Selectors: sel1}, nil)
+	s.attestor2.EXPECT().Attest(gomock.Any(), gomock.Any()).Return(&workloadattestor.AttestResponse{}, nil)
+	s.Assert().Equal(sel1, s.attestor.Attest(ctx, 1))
+	s.Assert().Equal(sel1, s.attestor.Attest(ctx, 1))
+}
@@ -8,15 +8,35 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/pkg/agent/plugin/keymanager/disk"
 	"github.com/spiffe/spire/pkg/agent/plugin/keymanager/memory"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/alibaba"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/aws"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/azure"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/digitalocean"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/equinix"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/gcp"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/github"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/ibmcloud"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/jointoken"
 	k8s_na "github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/k8s"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/nitro"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/nomad"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/oci"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/oidc"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/openstack"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/sevsnp"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/sgx"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/tpmdevid"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/vsphere"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/x509pop"
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/containerd"
 	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/docker"
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/ecs"
 	k8s_wa "github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/k8s"
+	nomad_wa "github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/nomad"
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/podman"
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/systemd"
 	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/unix"
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/windows"
 	"github.com/spiffe/spire/proto/agent/keymanager"
 	"github.com/spiffe/spire/proto/agent/nodeattestor"
 	"github.com/spiffe/spire/proto/agent/workloadattestor"
@@ -50,17 +70,37 @@ var (
 			"memory": keymanager.NewBuiltIn(memory.New()),
 		},
 		NodeAttestorType: {
-			"aws_iid":    nodeattestor.NewBuiltIn(aws.NewIID()),
-			"join_token": nodeattestor.NewBuiltIn(jointoken.New()),
-			"gcp_iit":    nodeattestor.NewBuiltIn(gcp.NewIITAttestorPlugin()),
-			"x509pop":    nodeattestor.NewBuiltIn(x509pop.New()),
-			"azure_msi":  nodeattestor.NewBuiltIn(azure.NewMSIAttestorPlugin()),
-			"k8s_sat":    nodeattestor.NewBuiltIn(k8s_na.NewSATAttestorPlugin()),
+			"aws_iid":                nodeattestor.NewBuiltIn(aws.NewIID()),
+			"join_token":             nodeattestor.NewBuiltIn(jointoken.New()),
+			"gcp_iit":                nodeattestor.NewBuiltIn(gcp.NewIITAttestorPlugin()),
+			"x509pop":                nodeattestor.NewBuiltIn(x509pop.New()),
+			"azure_msi":              nodeattestor.NewBuiltIn(azure.NewMSIAttestorPlugin()),
+			"k8s_sat":                nodeattestor.NewBuiltIn(k8s_na.NewSATAttestorPlugin()),
+			"github_oidc":            nodeattestor.NewBuiltIn(github.NewOIDCAttestorPlugin()),
+			"aws_nitro":              nodeattestor.NewBuiltIn(nitro.New()),
+			"sgx_dcap":               nodeattestor.NewBuiltIn(sgx.New()),
+			"tpm_devid":              nodeattestor.NewBuiltIn(tpmdevid.New()),
+			"sev_snp":                nodeattestor.NewBuiltIn(sevsnp.New()),
+			"openstack":              nodeattestor.NewBuiltIn(openstack.New()),
+			"digitalocean_droplet":   nodeattestor.NewBuiltIn(digitalocean.New()),
+			"equinix_metal":          nodeattestor.NewBuiltIn(equinix.New()),
+			"vsphere":                nodeattestor.NewBuiltIn(vsphere.New()),
+			"oci_instance_principal": nodeattestor.NewBuiltIn(oci.New()),
+			"ibmcloud_vpc":           nodeattestor.NewBuiltIn(ibmcloud.New()),
+			"alibaba_ecs":            nodeattestor.NewBuiltIn(alibaba.New()),
+			"oidc":                   nodeattestor.NewBuiltIn(oidc.New()),
+			"nomad":                  nodeattestor.NewBuiltIn(nomad.New()),
 		},
 		WorkloadAttestorType: {
-			"k8s":    workloadattestor.NewBuiltIn(k8s_wa.New()),
-			"unix":   workloadattestor.NewBuiltIn(unix.New()),
-			"docker": workloadattestor.NewBuiltIn(docker.New()),
+			"k8s":        workloadattestor.NewBuiltIn(k8s_wa.New()),
+			"unix":       workloadattestor.NewBuiltIn(unix.New()),
+			"docker":     workloadattestor.NewBuiltIn(docker.New()),
+			"systemd":    workloadattestor.NewBuiltIn(systemd.New()),
+			"windows":    workloadattestor.NewBuiltIn(windows.New()),
+			"containerd": workloadattestor.NewBuiltIn(containerd.New()),
+			"podman":     workloadattestor.NewBuiltIn(podman.New()),
+			"ecs":        workloadattestor.NewBuiltIn(ecs.New()),
+			"nomad":      workloadattestor.NewBuiltIn(nomad_wa.New()),
 		},
 	}
 )
@@ -25,10 +25,17 @@ type Cgroup struct {
 // The expected cgroup format is "hierarchy-ID:controller-list:cgroup-path", and
 // this function will return an error if every cgroup does not meet that format.
 //
+// If a Tracker is set and knows the process, its unified hierarchy entry is
+// returned without reading procfs.
+//
 // For more information, see:
 //  - http://man7.org/linux/man-pages/man7/cgroups.7.html
 //  - https://www.kernel.org/doc/Documentation/cgroup-v2.txt
 func GetCgroups(pid int32, fs FileSystem) ([]Cgroup, error) {
+	if cgroups, ok := lookupTracker(pid); ok {
+		return cgroups, nil
+	}
+
 	path := fmt.Sprintf("/proc/%v/cgroup", pid)
 	file, err := fs.Open(path)
 	if err != nil {
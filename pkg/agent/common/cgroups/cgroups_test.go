@@ -55,3 +55,30 @@ func TestCgroupsBadFormat(t *testing.T) {
 	require.Contains(t, err.Error(), `cgroup entry contains 2 colons, but expected at least 2 colons: "11:hugetlb"`)
 	require.Nil(t, cgroups)
 }
+
+func TestCgroupsFromTracker(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	SetTracker(fakeTracker{123: "/system.slice/nginx.service"})
+	defer SetTracker(nil)
+
+	// tracked processes are not looked up in procfs
+	mockFileSystem := filesystem_mock.NewMockfileSystem(mockCtrl)
+	cgroups, err := GetCgroups(123, mockFileSystem)
+	require.NoError(t, err)
+	require.Equal(t, []Cgroup{{"0", "", "/system.slice/nginx.service"}}, cgroups)
+
+	// untracked processes are
+	mockFileSystem.EXPECT().Open("/proc/456/cgroup").Return(os.Open(cgSimple))
+	cgroups, err = GetCgroups(456, mockFileSystem)
+	require.NoError(t, err)
+	require.Equal(t, expectSimpleCgroup, cgroups)
+}
+
+type fakeTracker map[int32]string
+
+func (t fakeTracker) Lookup(pid int32) (string, bool) {
+	groupPath, ok := t[pid]
+	return groupPath, ok
+}
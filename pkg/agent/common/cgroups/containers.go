@@ -0,0 +1,87 @@
+package cgroups
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Container runtimes, as identified from cgroup paths
+const (
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+	RuntimeCRIO       = "crio"
+	RuntimePodman     = "podman"
+)
+
+var (
+	// containerScopeRE matches a path element naming the cgroup of a
+	// container. Runtimes place containers either in a cgroup named after
+	// the container ID (cgroupfs driver) or in a systemd scope unit with a
+	// runtime specific prefix (systemd driver). The scopes of container
+	// monitors (e.g. "crio-conmon-<id>.scope") do not match.
+	containerScopeRE = regexp.MustCompile(`^(?:(docker|cri-containerd|crio|libpod)-)?([[:xdigit:]]{64})(?:\.scope)?$`)
+
+	scopeRuntimes = map[string]string{
+		"docker":         RuntimeDocker,
+		"cri-containerd": RuntimeContainerd,
+		"crio":           RuntimeCRIO,
+		"libpod":         RuntimePodman,
+	}
+)
+
+// Container identifies a container found in a cgroup path
+type Container struct {
+	ID string
+
+	// Runtime is the container runtime, if it can be told from the path
+	Runtime string
+}
+
+// FindContainer returns the container a cgroup path belongs to. It handles
+// the layouts of the cgroupfs and systemd cgroup drivers, on both cgroups v1
+// and the unified v2 hierarchy. When containers are nested (e.g. Kind nodes
+// or rootless runtimes inside a container), the innermost container is
+// returned. For example, each of these paths belongs to container <id>:
+//  /docker/<id>
+//  /system.slice/docker-<id>.scope
+//  /user.slice/user-1000.slice/user@1000.service/user.slice/docker-<id>.scope
+//  /kubepods/burstable/pod<uid>/<id>
+//  /kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod<uid>.slice/cri-containerd-<id>.scope
+//  /docker/<node id>/kubepods/besteffort/pod<uid>/<id>
+func FindContainer(groupPath string) (Container, bool) {
+	elems := strings.Split(groupPath, "/")
+	// processes may be in a child cgroup of the container (e.g. systemd
+	// running in a container), so the whole path is searched, starting
+	// with the innermost element
+	for i := len(elems) - 1; i >= 0; i-- {
+		m := containerScopeRE.FindStringSubmatch(elems[i])
+		if m == nil {
+			continue
+		}
+		container := Container{
+			ID:      m[2],
+			Runtime: scopeRuntimes[m[1]],
+		}
+		// with the cgroupfs driver, docker places containers under a
+		// "docker" cgroup
+		if container.Runtime == "" && i > 0 && elems[i-1] == "docker" {
+			container.Runtime = RuntimeDocker
+		}
+		return container, true
+	}
+	return Container{}, false
+}
+
+// FindPodContainer returns the container of a Kubernetes pod a cgroup path
+// belongs to. The container must be found under the "kubepods" hierarchy
+// created by the kubelet (e.g. "/kubepods", "/kubepods.slice" or, in Kind,
+// "/kubelet.slice/kubelet-kubepods.slice").
+func FindPodContainer(groupPath string) (Container, bool) {
+	elems := strings.Split(groupPath, "/")
+	for i, elem := range elems {
+		if strings.HasPrefix(elem, "kubepods") || strings.Contains(elem, "-kubepods") {
+			return FindContainer(strings.Join(elems[i+1:], "/"))
+		}
+	}
+	return Container{}, false
+}
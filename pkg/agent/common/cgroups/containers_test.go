@@ -0,0 +1,173 @@
+package cgroups
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testContainerID = "9bca8d63d5fa610783847915bcff0ecac1273e5b4bed3f6fa1b07350e0135961"
+	testNodeID      = "6469646e742065787065637420616e796f6e6520746f20726561642074686973"
+)
+
+func TestFindContainer(t *testing.T) {
+	testCases := []struct {
+		name      string
+		groupPath string
+		container *Container
+	}{
+		{
+			name:      "docker cgroupfs",
+			groupPath: "/docker/" + testContainerID,
+			container: &Container{ID: testContainerID, Runtime: RuntimeDocker},
+		},
+		{
+			name:      "docker systemd",
+			groupPath: "/system.slice/docker-" + testContainerID + ".scope",
+			container: &Container{ID: testContainerID, Runtime: RuntimeDocker},
+		},
+		{
+			name:      "rootless docker",
+			groupPath: "/user.slice/user-1000.slice/user@1000.service/user.slice/docker-" + testContainerID + ".scope",
+			container: &Container{ID: testContainerID, Runtime: RuntimeDocker},
+		},
+		{
+			name:      "nested docker",
+			groupPath: "/docker/" + testNodeID + "/docker/" + testContainerID,
+			container: &Container{ID: testContainerID, Runtime: RuntimeDocker},
+		},
+		{
+			name:      "child cgroup of container",
+			groupPath: "/system.slice/docker-" + testContainerID + ".scope/init.scope",
+			container: &Container{ID: testContainerID, Runtime: RuntimeDocker},
+		},
+		{
+			name:      "podman",
+			groupPath: "/machine.slice/libpod-" + testContainerID + ".scope",
+			container: &Container{ID: testContainerID, Runtime: RuntimePodman},
+		},
+		{
+			name:      "podman conmon",
+			groupPath: "/machine.slice/libpod-conmon-" + testContainerID + ".scope",
+		},
+		{
+			name:      "kubernetes cgroupfs burstable",
+			groupPath: "/kubepods/burstable/pod2c48913c-b29f-11e7-9350-020968147796/" + testContainerID,
+			container: &Container{ID: testContainerID},
+		},
+		{
+			name:      "kubernetes cgroupfs guaranteed",
+			groupPath: "/kubepods/pod2c48913c-b29f-11e7-9350-020968147796/" + testContainerID,
+			container: &Container{ID: testContainerID},
+		},
+		{
+			name:      "kubernetes systemd docker",
+			groupPath: "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod2c48913c_b29f_11e7_9350_020968147796.slice/docker-" + testContainerID + ".scope",
+			container: &Container{ID: testContainerID, Runtime: RuntimeDocker},
+		},
+		{
+			name:      "kubernetes systemd containerd",
+			groupPath: "/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod2c48913c_b29f_11e7_9350_020968147796.slice/cri-containerd-" + testContainerID + ".scope",
+			container: &Container{ID: testContainerID, Runtime: RuntimeContainerd},
+		},
+		{
+			name:      "kubernetes systemd cri-o",
+			groupPath: "/kubepods.slice/kubepods-pod2c48913c_b29f_11e7_9350_020968147796.slice/crio-" + testContainerID + ".scope",
+			container: &Container{ID: testContainerID, Runtime: RuntimeCRIO},
+		},
+		{
+			name:      "kubernetes cri-o conmon",
+			groupPath: "/kubepods.slice/kubepods-pod2c48913c_b29f_11e7_9350_020968147796.slice/crio-conmon-" + testContainerID + ".scope",
+		},
+		{
+			name:      "kind cgroupfs",
+			groupPath: "/docker/" + testNodeID + "/kubepods/besteffort/pod2c48913c-b29f-11e7-9350-020968147796/" + testContainerID,
+			container: &Container{ID: testContainerID},
+		},
+		{
+			name:      "kind systemd",
+			groupPath: "/system.slice/docker-" + testNodeID + ".scope/kubelet.slice/kubelet-kubepods.slice/kubelet-kubepods-besteffort.slice/kubelet-kubepods-besteffort-pod2c48913c_b29f_11e7_9350_020968147796.slice/cri-containerd-" + testContainerID + ".scope",
+			container: &Container{ID: testContainerID, Runtime: RuntimeContainerd},
+		},
+		{
+			name:      "not a container",
+			groupPath: "/user.slice/user-1000.slice/session-2.scope",
+		},
+		{
+			name:      "root",
+			groupPath: "/",
+		},
+		{
+			name:      "short id",
+			groupPath: "/docker/6469646e7420",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			container, ok := FindContainer(testCase.groupPath)
+			if testCase.container == nil {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, *testCase.container, container)
+		})
+	}
+}
+
+func TestFindPodContainer(t *testing.T) {
+	testCases := []struct {
+		name      string
+		groupPath string
+		expectID  string
+	}{
+		{
+			name:      "cgroupfs",
+			groupPath: "/kubepods/burstable/pod2c48913c-b29f-11e7-9350-020968147796/" + testContainerID,
+			expectID:  testContainerID,
+		},
+		{
+			name:      "systemd",
+			groupPath: "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod2c48913c_b29f_11e7_9350_020968147796.slice/cri-containerd-" + testContainerID + ".scope",
+			expectID:  testContainerID,
+		},
+		{
+			name:      "kind cgroupfs",
+			groupPath: "/docker/" + testNodeID + "/kubepods/besteffort/pod2c48913c-b29f-11e7-9350-020968147796/" + testContainerID,
+			expectID:  testContainerID,
+		},
+		{
+			name:      "kind systemd",
+			groupPath: "/system.slice/docker-" + testNodeID + ".scope/kubelet.slice/kubelet-kubepods.slice/kubelet-kubepods-besteffort.slice/kubelet-kubepods-besteffort-pod2c48913c_b29f_11e7_9350_020968147796.slice/cri-containerd-" + testContainerID + ".scope",
+			expectID:  testContainerID,
+		},
+		{
+			name:      "kind node",
+			groupPath: "/system.slice/docker-" + testNodeID + ".scope/kubelet.slice",
+		},
+		{
+			name:      "pod without container",
+			groupPath: "/kubepods/burstable/pod2c48913c-b29f-11e7-9350-020968147796",
+		},
+		{
+			name:      "docker container outside of a pod",
+			groupPath: "/docker/" + testContainerID,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			container, ok := FindPodContainer(testCase.groupPath)
+			if testCase.expectID == "" {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, testCase.expectID, container.ID)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package cgroups
+
+import "sync"
+
+// Tracker provides the cgroup v2 path of processes without reading procfs
+// (e.g. from the eBPF tracker in pkg/agent/common/ebpf).
+type Tracker interface {
+	// Lookup returns the path of the process in the unified hierarchy, if
+	// known
+	Lookup(pid int32) (string, bool)
+}
+
+var (
+	trackerMu sync.RWMutex
+	tracker   Tracker
+)
+
+// SetTracker sets the tracker GetCgroups consults before procfs. Processes
+// unknown to the tracker are still looked up in procfs. A nil tracker
+// disables it.
+func SetTracker(t Tracker) {
+	trackerMu.Lock()
+	tracker = t
+	trackerMu.Unlock()
+}
+
+func lookupTracker(pid int32) ([]Cgroup, bool) {
+	trackerMu.RLock()
+	t := tracker
+	trackerMu.RUnlock()
+	if t == nil {
+		return nil, false
+	}
+
+	groupPath, ok := t.Lookup(pid)
+	if !ok {
+		return nil, false
+	}
+	return []Cgroup{{HierarchyID: "0", GroupPath: groupPath}}, true
+}
@@ -0,0 +1,219 @@
+package ebpf
+
+import (
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// A minimal interface to the bpf(2) syscall, implementing only what is
+// needed to load and attach the tracking program and read its map.
+
+const (
+	// bpf(2) commands
+	bpfMapCreate     = 0
+	bpfMapLookupElem = 1
+	bpfProgLoad      = 5
+	bpfLinkCreate    = 28
+
+	bpfMapTypeLRUHash         = 9
+	bpfProgTypeCgroupSockAddr = 18
+	bpfCgroupUnixConnect      = 49
+
+	// helper functions callable from programs
+	helperMapUpdateElem      = 2
+	helperGetCurrentPIDTGID  = 14
+	helperGetCurrentCgroupID = 80
+)
+
+type mapCreateAttr struct {
+	mapType    uint32
+	keySize    uint32
+	valueSize  uint32
+	maxEntries uint32
+	mapFlags   uint32
+}
+
+type mapElemAttr struct {
+	mapFD uint32
+	_     uint32
+	key   uint64
+	value uint64
+	flags uint64
+}
+
+type progLoadAttr struct {
+	progType           uint32
+	insnCnt            uint32
+	insns              uint64
+	license            uint64
+	logLevel           uint32
+	logSize            uint32
+	logBuf             uint64
+	kernVersion        uint32
+	progFlags          uint32
+	progName           [16]byte
+	progIfindex        uint32
+	expectedAttachType uint32
+}
+
+type linkCreateAttr struct {
+	progFD     uint32
+	targetFD   uint32
+	attachType uint32
+	flags      uint32
+}
+
+func bpf(cmd int, attr unsafe.Pointer, size uintptr) (int, error) {
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+func createMap(mapType, keySize, valueSize, maxEntries uint32) (int, error) {
+	attr := mapCreateAttr{
+		mapType:    mapType,
+		keySize:    keySize,
+		valueSize:  valueSize,
+		maxEntries: maxEntries,
+	}
+	return bpf(bpfMapCreate, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+}
+
+// lookupElem copies the value of key into value. It returns false if the
+// key is not in the map.
+func lookupElem(mapFD int, key, value unsafe.Pointer) (bool, error) {
+	attr := mapElemAttr{
+		mapFD: uint32(mapFD),
+		key:   uint64(uintptr(key)),
+		value: uint64(uintptr(value)),
+	}
+	_, err := bpf(bpfMapLookupElem, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	switch err {
+	case nil:
+		return true, nil
+	case unix.ENOENT:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func loadProgram(progType, expectedAttachType uint32, insns []instruction) (int, error) {
+	license := []byte("GPL\x00")
+	attr := progLoadAttr{
+		progType:           progType,
+		insnCnt:            uint32(len(insns)),
+		insns:              uint64(uintptr(unsafe.Pointer(&insns[0]))),
+		license:            uint64(uintptr(unsafe.Pointer(&license[0]))),
+		expectedAttachType: expectedAttachType,
+	}
+	copy(attr.progName[:], "spire_track")
+	fd, err := bpf(bpfProgLoad, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(license)
+	return fd, err
+}
+
+// createLink attaches the program to the target. The program is detached
+// when the link is closed, including when the process exits.
+func createLink(progFD, targetFD int, attachType uint32) (int, error) {
+	attr := linkCreateAttr{
+		progFD:     uint32(progFD),
+		targetFD:   uint32(targetFD),
+		attachType: attachType,
+	}
+	return bpf(bpfLinkCreate, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+}
+
+// instruction is a single eBPF instruction
+type instruction struct {
+	code uint8
+	regs uint8 // dst in the low nibble, src in the high nibble
+	off  int16
+	imm  int32
+}
+
+const (
+	regR0 = 0
+	regR1 = 1
+	regR2 = 2
+	regR3 = 3
+	regR4 = 4
+	regFP = 10
+
+	pseudoMapFD = 1
+)
+
+func call(helper int32) instruction {
+	return instruction{code: 0x85, imm: helper}
+}
+
+func exit() instruction {
+	return instruction{code: 0x95}
+}
+
+func movImm(dst uint8, imm int32) instruction {
+	return instruction{code: 0xb7, regs: dst, imm: imm}
+}
+
+func movReg(dst, src uint8) instruction {
+	return instruction{code: 0xbf, regs: dst | src<<4}
+}
+
+func addImm(dst uint8, imm int32) instruction {
+	return instruction{code: 0x07, regs: dst, imm: imm}
+}
+
+func rshImm(dst uint8, imm int32) instruction {
+	return instruction{code: 0x77, regs: dst, imm: imm}
+}
+
+// storeW and storeDW store the 32 and 64 bit value of src at dst+off
+func storeW(dst uint8, off int16, src uint8) instruction {
+	return instruction{code: 0x63, regs: dst | src<<4, off: off}
+}
+
+func storeDW(dst uint8, off int16, src uint8) instruction {
+	return instruction{code: 0x7b, regs: dst | src<<4, off: off}
+}
+
+// loadMapFD loads a reference to the map into dst. It takes two
+// instruction slots.
+func loadMapFD(dst uint8, mapFD int) []instruction {
+	return []instruction{
+		{code: 0x18, regs: dst | pseudoMapFD<<4, imm: int32(mapFD)},
+		{},
+	}
+}
+
+// trackingProgram records the cgroup ID of the process connecting a unix
+// socket in the map, keyed by PID. The connection is always allowed.
+func trackingProgram(mapFD int) []instruction {
+	insns := []instruction{
+		// key = bpf_get_current_pid_tgid() >> 32
+		call(helperGetCurrentPIDTGID),
+		rshImm(regR0, 32),
+		storeW(regFP, -4, regR0),
+		// value = bpf_get_current_cgroup_id()
+		call(helperGetCurrentCgroupID),
+		storeDW(regFP, -16, regR0),
+	}
+	// bpf_map_update_elem(map, &key, &value, BPF_ANY)
+	insns = append(insns, loadMapFD(regR1, mapFD)...)
+	insns = append(insns,
+		movReg(regR2, regFP),
+		addImm(regR2, -4),
+		movReg(regR3, regFP),
+		addImm(regR3, -16),
+		movImm(regR4, 0),
+		call(helperMapUpdateElem),
+		// allow the connection
+		movImm(regR0, 1),
+		exit(),
+	)
+	return insns
+}
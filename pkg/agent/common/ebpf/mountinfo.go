@@ -0,0 +1,71 @@
+// Package ebpf tracks the cgroups of processes connecting to unix sockets
+// with an eBPF program, so that workload attestors do not need to read them
+// from procfs after the fact.
+package ebpf
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// findUnifiedMount returns the mount point of the cgroup v2 hierarchy, and
+// the root of the hierarchy it exposes, from the contents of
+// /proc/self/mountinfo. Hosts that also mount cgroup v1 hierarchies are
+// rejected, since container runtimes on those hosts place containers through
+// the v1 controllers and the unified hierarchy does not reflect them.
+func findUnifiedMount(r io.Reader) (mountPoint, root string, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// 30 25 0:26 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:4 - cgroup2 cgroup2 rw
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || sep+1 >= len(fields) {
+			continue
+		}
+		switch fields[sep+1] {
+		case "cgroup":
+			return "", "", errors.New("cgroup v1 hierarchies are mounted; a cgroup v2 only host is required")
+		case "cgroup2":
+			if mountPoint == "" {
+				root = unescapeMountinfo(fields[3])
+				mountPoint = unescapeMountinfo(fields[4])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+	if mountPoint == "" {
+		return "", "", errors.New("cgroup v2 hierarchy is not mounted")
+	}
+	return mountPoint, root, nil
+}
+
+// unescapeMountinfo decodes the octal escapes (e.g. "\040" for a space)
+// mountinfo uses in paths
+func unescapeMountinfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
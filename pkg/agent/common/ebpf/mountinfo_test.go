@@ -0,0 +1,61 @@
+package ebpf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindUnifiedMount(t *testing.T) {
+	testCases := []struct {
+		name       string
+		mountinfo  string
+		mountPoint string
+		root       string
+		err        string
+	}{
+		{
+			name: "unified",
+			mountinfo: `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+30 25 0:26 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:4 - cgroup2 cgroup2 rw,nsdelegate
+`,
+			mountPoint: "/sys/fs/cgroup",
+			root:       "/",
+		},
+		{
+			name: "unified with escaped mount point",
+			mountinfo: `30 25 0:26 /kubepods /mnt/cgroup\040root rw,relatime - cgroup2 cgroup2 rw
+`,
+			mountPoint: "/mnt/cgroup root",
+			root:       "/kubepods",
+		},
+		{
+			name: "hybrid",
+			mountinfo: `30 25 0:26 / /sys/fs/cgroup/unified rw,relatime shared:4 - cgroup2 cgroup2 rw
+31 25 0:27 / /sys/fs/cgroup/systemd rw,relatime shared:5 - cgroup cgroup rw,name=systemd
+`,
+			err: "cgroup v1 hierarchies are mounted; a cgroup v2 only host is required",
+		},
+		{
+			name: "not mounted",
+			mountinfo: `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+`,
+			err: "cgroup v2 hierarchy is not mounted",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			mountPoint, root, err := findUnifiedMount(strings.NewReader(testCase.mountinfo))
+			if testCase.err != "" {
+				require.EqualError(t, err, testCase.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.mountPoint, mountPoint)
+			require.Equal(t, testCase.root, root)
+		})
+	}
+}
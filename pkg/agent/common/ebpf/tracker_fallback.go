@@ -0,0 +1,20 @@
+// +build !linux
+
+package ebpf
+
+import "errors"
+
+// Tracker is only available on Linux
+type Tracker struct{}
+
+func New() (*Tracker, error) {
+	return nil, errors.New("eBPF tracking is only supported on Linux")
+}
+
+func (t *Tracker) Lookup(pid int32) (string, bool) {
+	return "", false
+}
+
+func (t *Tracker) Close() error {
+	return nil
+}
@@ -0,0 +1,180 @@
+package ebpf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// maxTrackedProcesses bounds the map of tracked processes. The least
+	// recently connected processes are evicted first.
+	maxTrackedProcesses = 65536
+)
+
+// Tracker records the cgroup of processes as they connect to unix sockets,
+// using an eBPF program attached to the root of the cgroup v2 hierarchy.
+// Requires Linux 6.7 or later, a cgroup v2 only host, and the agent to run
+// in the host PID namespace.
+type Tracker struct {
+	mountPoint string
+	root       string
+
+	mapFD  int
+	progFD int
+	linkFD int
+
+	mu sync.Mutex
+	// paths caches the path of cgroups by ID
+	paths map[uint64]string
+}
+
+// New loads and attaches the tracking program
+func New() (*Tracker, error) {
+	mountinfo, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	mountPoint, root, err := findUnifiedMount(mountinfo)
+	mountinfo.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tracker{
+		mountPoint: mountPoint,
+		root:       root,
+		mapFD:      -1,
+		progFD:     -1,
+		linkFD:     -1,
+	}
+	if err := t.attach(); err != nil {
+		t.Close()
+		return nil, err
+	}
+	if err := t.selfTest(); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Tracker) attach() (err error) {
+	t.mapFD, err = createMap(bpfMapTypeLRUHash, 4, 8, maxTrackedProcesses)
+	if err != nil {
+		return fmt.Errorf("creating map: %v", err)
+	}
+	t.progFD, err = loadProgram(bpfProgTypeCgroupSockAddr, bpfCgroupUnixConnect, trackingProgram(t.mapFD))
+	if err != nil {
+		return fmt.Errorf("loading program: %v", err)
+	}
+
+	cgroup, err := os.Open(t.mountPoint)
+	if err != nil {
+		return err
+	}
+	defer cgroup.Close()
+	t.linkFD, err = createLink(t.progFD, int(cgroup.Fd()), bpfCgroupUnixConnect)
+	if err != nil {
+		return fmt.Errorf("attaching program: %v", err)
+	}
+	return nil
+}
+
+// selfTest connects to a unix socket and expects the agent to be tracked.
+// This fails if the agent does not share the PID namespace the program
+// records PIDs in.
+func (t *Tracker) selfTest() error {
+	dir, err := ioutil.TempDir("", "spire-ebpf-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "test.sock"))
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		return err
+	}
+	conn.Close()
+
+	if _, ok := t.lookupCgroupID(int32(os.Getpid())); !ok {
+		return fmt.Errorf("agent connection was not tracked; the agent must run in the host PID namespace")
+	}
+	return nil
+}
+
+// Lookup returns the path, in the unified hierarchy, of the cgroup the
+// process was in when it last connected to a unix socket.
+func (t *Tracker) Lookup(pid int32) (string, bool) {
+	id, ok := t.lookupCgroupID(pid)
+	if !ok {
+		return "", false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if groupPath, ok := t.paths[id]; ok {
+		return groupPath, true
+	}
+
+	// the cgroup was created since the hierarchy was last walked
+	t.paths = walkCgroups(t.mountPoint, t.root)
+	groupPath, ok := t.paths[id]
+	return groupPath, ok
+}
+
+// Close detaches the tracking program
+func (t *Tracker) Close() error {
+	for _, fd := range []int{t.linkFD, t.progFD, t.mapFD} {
+		if fd >= 0 {
+			syscall.Close(fd)
+		}
+	}
+	return nil
+}
+
+func (t *Tracker) lookupCgroupID(pid int32) (uint64, bool) {
+	key := uint32(pid)
+	var id uint64
+	ok, err := lookupElem(t.mapFD, unsafe.Pointer(&key), unsafe.Pointer(&id))
+	if err != nil || !ok {
+		return 0, false
+	}
+	return id, true
+}
+
+// walkCgroups returns the paths of the cgroups under mountPoint, relative to
+// the hierarchy root, keyed by cgroup ID. The ID of a cgroup is the inode
+// number of its directory.
+func walkCgroups(mountPoint, root string) map[uint64]string {
+	paths := make(map[uint64]string)
+	filepath.Walk(mountPoint, func(p string, info os.FileInfo, err error) error {
+		// cgroups may be removed during the walk
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+		rel, err := filepath.Rel(mountPoint, p)
+		if err != nil {
+			return nil
+		}
+		paths[stat.Ino] = path.Join(root, "/", filepath.ToSlash(rel))
+		return nil
+	})
+	return paths
+}
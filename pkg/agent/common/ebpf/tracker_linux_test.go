@@ -0,0 +1,67 @@
+package ebpf
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkCgroups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ebpf-walk-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "system.slice", "nginx.service"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cgroup.procs"), nil, 0644))
+
+	paths := walkCgroups(dir, "/")
+	require.Len(t, paths, 3)
+	require.Equal(t, "/", paths[inode(t, dir)])
+	require.Equal(t, "/system.slice", paths[inode(t, filepath.Join(dir, "system.slice"))])
+	require.Equal(t, "/system.slice/nginx.service", paths[inode(t, filepath.Join(dir, "system.slice", "nginx.service"))])
+
+	// paths are relative to the root of the hierarchy
+	paths = walkCgroups(dir, "/kubepods")
+	require.Equal(t, "/kubepods/system.slice", paths[inode(t, filepath.Join(dir, "system.slice"))])
+}
+
+func TestTracker(t *testing.T) {
+	tracker, err := New()
+	if err != nil {
+		t.Skipf("eBPF tracking unavailable: %v", err)
+	}
+	defer tracker.Close()
+
+	dir, err := ioutil.TempDir("", "ebpf-tracker-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "test.sock"))
+	require.NoError(t, err)
+	defer listener.Close()
+	conn, err := net.Dial("unix", listener.Addr().String())
+	require.NoError(t, err)
+	conn.Close()
+
+	groupPath, ok := tracker.Lookup(int32(os.Getpid()))
+	require.True(t, ok)
+	require.Equal(t, currentCgroup(t), groupPath)
+}
+
+func inode(t *testing.T, path string) uint64 {
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return info.Sys().(*syscall.Stat_t).Ino
+}
+
+func currentCgroup(t *testing.T) string {
+	data, err := ioutil.ReadFile("/proc/self/cgroup")
+	require.NoError(t, err)
+	// the unified hierarchy is the only one on hosts the tracker supports
+	return string(data[3 : len(data)-1])
+}
@@ -0,0 +1,233 @@
+// Package sandbox detects workloads running in sandboxed container runtimes,
+// such as gVisor and Kata Containers, for the container workload attestors.
+package sandbox
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TypeGVisor is the type of workloads running under gVisor
+	TypeGVisor = "gvisor"
+
+	// TypeKata is the type of workloads running in Kata Containers
+	TypeKata = "kata"
+
+	// gvisorSandboxName is the name the gVisor Sentry runs under. Workloads
+	// running under gVisor reach the agent through the Sentry, which is the
+	// process seen on the other end of the Workload API connection.
+	gvisorSandboxName = "runsc-sandbox"
+
+	// versionTimeout bounds how long the runtime binary may take to report
+	// its version
+	versionTimeout = 5 * time.Second
+)
+
+// runtimeBinaries are the binaries reporting the version of each sandbox
+var runtimeBinaries = map[string]string{
+	TypeGVisor: "runsc",
+	TypeKata:   "kata-runtime",
+}
+
+// Sandbox describes the sandbox a workload runs in
+type Sandbox struct {
+	// Type is the sandbox type (e.g. "gvisor")
+	Type string
+
+	// Version is the version of the sandbox runtime, if it could be
+	// determined
+	Version string
+}
+
+// SelectorValues returns the values of the "runtime" selectors of the
+// sandbox, e.g. "runtime:gvisor" and, when the version is known,
+// "runtime:gvisor:release-20240311.0". Attestors emit them under their own
+// selector type.
+func (s Sandbox) SelectorValues() []string {
+	values := []string{"runtime:" + s.Type}
+	if s.Version != "" {
+		values = append(values, fmt.Sprintf("runtime:%s:%s", s.Type, s.Version))
+	}
+	return values
+}
+
+// Detector detects whether workloads run in a sandbox
+type Detector interface {
+	// Detect returns the sandbox the process runs in. The runtime is the
+	// name of the runtime the container runs with, as reported by the
+	// container engine (e.g. the OCI runtime, containerd shim or Kubernetes
+	// runtime class), or empty if unknown. It returns false if the process
+	// is not sandboxed.
+	Detect(ctx context.Context, pid int32, runtime string) (Sandbox, bool)
+}
+
+// TypeFromRuntime returns the sandbox type of the named container runtime,
+// or an empty string if the runtime is not known to be sandboxed. Names
+// are matched loosely, e.g. "runsc", "io.containerd.runsc.v1" and "gvisor"
+// are all gVisor, and "kata-qemu" and "io.containerd.kata.v2" are Kata.
+func TypeFromRuntime(runtime string) string {
+	runtime = strings.ToLower(runtime)
+	switch {
+	case strings.Contains(runtime, "runsc"), strings.Contains(runtime, "gvisor"):
+		return TypeGVisor
+	case strings.Contains(runtime, "kata"):
+		return TypeKata
+	default:
+		return ""
+	}
+}
+
+type cachedVersion struct {
+	modTime time.Time
+	version string
+}
+
+type detector struct {
+	mu       sync.Mutex
+	versions map[string]cachedVersion
+
+	// hooks for tests
+	hooks struct {
+		readlink   func(name string) (string, error)
+		readFile   func(name string) ([]byte, error)
+		stat       func(name string) (os.FileInfo, error)
+		lookPath   func(file string) (string, error)
+		runVersion func(ctx context.Context, path string) ([]byte, error)
+	}
+}
+
+// NewDetector returns a Detector inspecting the processes of the host and
+// running the sandbox runtime binaries to learn their version. Versions are
+// cached until the binary changes.
+func NewDetector() Detector {
+	d := &detector{
+		versions: make(map[string]cachedVersion),
+	}
+	d.hooks.readlink = os.Readlink
+	d.hooks.readFile = ioutil.ReadFile
+	d.hooks.stat = os.Stat
+	d.hooks.lookPath = exec.LookPath
+	d.hooks.runVersion = runVersion
+	return d
+}
+
+func (d *detector) Detect(ctx context.Context, pid int32, runtime string) (Sandbox, bool) {
+	// the runtime binary is looked up in PATH unless the process is the
+	// gVisor Sentry, whose executable is the runtime itself
+	var binary string
+	sandboxType := TypeFromRuntime(runtime)
+	if exe, ok := d.gvisorSentry(pid); ok {
+		sandboxType = TypeGVisor
+		binary = exe
+	}
+	if sandboxType == "" {
+		return Sandbox{}, false
+	}
+
+	if binary == "" {
+		binary, _ = d.hooks.lookPath(runtimeBinaries[sandboxType])
+	}
+	return Sandbox{
+		Type:    sandboxType,
+		Version: d.getVersion(ctx, binary),
+	}, true
+}
+
+// gvisorSentry returns the path of the executable of the process if it is
+// the gVisor Sentry
+func (d *detector) gvisorSentry(pid int32) (string, bool) {
+	cmdline, err := d.hooks.readFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return "", false
+	}
+	argv0 := strings.SplitN(string(cmdline), "\x00", 2)[0]
+	if filepath.Base(argv0) != gvisorSandboxName {
+		return "", false
+	}
+
+	// the executable may have been replaced or deleted since the sandbox
+	// started, in which case the version of the one in PATH is used
+	exe, err := d.hooks.readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil || strings.HasSuffix(exe, " (deleted)") {
+		return "", true
+	}
+	return exe, true
+}
+
+// getVersion returns the version reported by the runtime binary, or an
+// empty string if it cannot be determined
+func (d *detector) getVersion(ctx context.Context, binary string) string {
+	if binary == "" {
+		return ""
+	}
+	info, err := d.hooks.stat(binary)
+	if err != nil {
+		return ""
+	}
+
+	d.mu.Lock()
+	cached, ok := d.versions[binary]
+	d.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.version
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+	out, err := d.hooks.runVersion(ctx, binary)
+	if err != nil {
+		return ""
+	}
+	version := parseVersion(string(out))
+	if version == "" {
+		return ""
+	}
+
+	d.mu.Lock()
+	d.versions[binary] = cachedVersion{
+		modTime: info.ModTime(),
+		version: version,
+	}
+	d.mu.Unlock()
+	return version
+}
+
+// parseVersion extracts the version from the output of "<runtime> --version"
+// of the supported runtimes. Examples:
+//
+//	runsc version release-20240311.0
+//	kata-runtime  : 3.2.0
+func parseVersion(out string) string {
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	if !scanner.Scan() {
+		return ""
+	}
+	line := scanner.Text()
+
+	if i := strings.Index(line, " version "); i >= 0 {
+		line = line[i+len(" version "):]
+	} else if i := strings.Index(line, ":"); i >= 0 {
+		line = line[i+1:]
+	} else {
+		return ""
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+func runVersion(ctx context.Context, path string) ([]byte, error) {
+	return exec.CommandContext(ctx, path, "--version").Output()
+}
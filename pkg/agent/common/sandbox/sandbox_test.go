@@ -0,0 +1,144 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeFromRuntime(t *testing.T) {
+	for runtime, expected := range map[string]string{
+		"":                       "",
+		"runc":                   "",
+		"io.containerd.runc.v2":  "",
+		"crun":                   "",
+		"runsc":                  TypeGVisor,
+		"io.containerd.runsc.v1": TypeGVisor,
+		"gVisor":                 TypeGVisor,
+		"kata":                   TypeKata,
+		"kata-qemu":              TypeKata,
+		"io.containerd.kata.v2":  TypeKata,
+	} {
+		require.Equal(t, expected, TypeFromRuntime(runtime), "runtime %q", runtime)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	for out, expected := range map[string]string{
+		"runsc version release-20240311.0\nspec: 1.1.0-rc.1\n":            "release-20240311.0",
+		"kata-runtime  : 3.2.0\n   commit   : abc\n   OCI specs: 1.0.2\n": "3.2.0",
+		"":                 "",
+		"garbage\n":        "",
+		"runsc version \n": "",
+	} {
+		require.Equal(t, expected, parseVersion(out), "output %q", out)
+	}
+}
+
+func TestSelectorValues(t *testing.T) {
+	require.Equal(t, []string{"runtime:kata"}, Sandbox{Type: TypeKata}.SelectorValues())
+	require.Equal(t, []string{"runtime:gvisor", "runtime:gvisor:release-20240311.0"},
+		Sandbox{Type: TypeGVisor, Version: "release-20240311.0"}.SelectorValues())
+}
+
+func TestDetect(t *testing.T) {
+	ctx := context.Background()
+	modTime := time.Now()
+	versionRuns := 0
+
+	d := NewDetector().(*detector)
+	d.hooks.readFile = func(name string) ([]byte, error) {
+		switch name {
+		case "/proc/1/cmdline":
+			return []byte("/usr/bin/nginx\x00-g\x00"), nil
+		case "/proc/2/cmdline":
+			return []byte("runsc-sandbox\x00--root=/run/runsc\x00"), nil
+		case "/proc/3/cmdline":
+			return []byte("runsc-sandbox\x00"), nil
+		}
+		return nil, errors.New("no such process")
+	}
+	d.hooks.readlink = func(name string) (string, error) {
+		switch name {
+		case "/proc/2/exe":
+			return "/usr/local/bin/runsc", nil
+		case "/proc/3/exe":
+			return "/usr/local/bin/runsc (deleted)", nil
+		}
+		return "", errors.New("no such process")
+	}
+	d.hooks.lookPath = func(file string) (string, error) {
+		switch file {
+		case "runsc":
+			return "/usr/bin/runsc", nil
+		case "kata-runtime":
+			return "/usr/bin/kata-runtime", nil
+		}
+		return "", errors.New("not found")
+	}
+	d.hooks.stat = func(name string) (os.FileInfo, error) {
+		return fakeFileInfo{modTime: modTime}, nil
+	}
+	d.hooks.runVersion = func(ctx context.Context, path string) ([]byte, error) {
+		versionRuns++
+		switch path {
+		case "/usr/local/bin/runsc":
+			return []byte("runsc version release-20240311.0\n"), nil
+		case "/usr/bin/runsc":
+			return []byte("runsc version release-20230101.0\n"), nil
+		case "/usr/bin/kata-runtime":
+			return []byte("kata-runtime  : 3.2.0\n"), nil
+		}
+		return nil, errors.New("exec failed")
+	}
+
+	// plain containers are not sandboxed
+	_, ok := d.Detect(ctx, 1, "runc")
+	require.False(t, ok)
+	_, ok = d.Detect(ctx, 99, "")
+	require.False(t, ok)
+
+	// the gVisor Sentry is detected regardless of the runtime name, with the
+	// version of its own executable
+	sb, ok := d.Detect(ctx, 2, "")
+	require.True(t, ok)
+	require.Equal(t, Sandbox{Type: TypeGVisor, Version: "release-20240311.0"}, sb)
+	require.Equal(t, 1, versionRuns)
+
+	// versions are cached until the binary changes
+	_, _ = d.Detect(ctx, 2, "")
+	require.Equal(t, 1, versionRuns)
+	modTime = modTime.Add(time.Second)
+	_, _ = d.Detect(ctx, 2, "")
+	require.Equal(t, 2, versionRuns)
+
+	// a deleted executable falls back to the runtime in PATH
+	sb, ok = d.Detect(ctx, 3, "")
+	require.True(t, ok)
+	require.Equal(t, Sandbox{Type: TypeGVisor, Version: "release-20230101.0"}, sb)
+
+	sb, ok = d.Detect(ctx, 1, "io.containerd.kata.v2")
+	require.True(t, ok)
+	require.Equal(t, Sandbox{Type: TypeKata, Version: "3.2.0"}, sb)
+
+	// the version is omitted when the runtime binary is unavailable
+	d.hooks.lookPath = func(file string) (string, error) {
+		return "", errors.New("not found")
+	}
+	sb, ok = d.Detect(ctx, 1, "kata-fc")
+	require.True(t, ok)
+	require.Equal(t, Sandbox{Type: TypeKata}, sb)
+}
+
+type fakeFileInfo struct {
+	os.FileInfo
+	modTime time.Time
+}
+
+func (fi fakeFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
@@ -4,9 +4,11 @@ import (
 	"crypto/x509"
 	"net"
 	"net/url"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/selector"
 )
 
 type Config struct {
@@ -19,6 +21,17 @@ type Config struct {
 	// If true, enables an Envoy SecretDiscoveryService server
 	EnableSDS bool
 
+	// If true, the cgroups of workloads are tracked with eBPF instead of
+	// being read from procfs
+	EBPFAttestation bool
+
+	// If set, post-processes the selectors of workloads
+	SelectorHook *selector.Hook
+
+	// How long the selectors of attested workloads are cached. Zero
+	// disables the cache.
+	WorkloadAttestationCacheTTL time.Duration
+
 	// Configurations for agent plugins
 	PluginConfigs catalog.PluginConfigMap
 
@@ -34,6 +47,14 @@ type Config struct {
 	// Join token to use for attestation, if needed
 	JoinToken string
 
+	// If true, the join token may be used by other agents and a unique
+	// suffix is added to the agent ID
+	ReusableJoinToken bool
+
+	// How often the agent re-runs node attestation. Zero disables periodic
+	// re-attestation.
+	ReattestInterval time.Duration
+
 	// If true enables profiling.
 	ProfilingEnabled bool
 
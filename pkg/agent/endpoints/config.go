@@ -4,14 +4,18 @@ import (
 	"net"
 
 	"github.com/sirupsen/logrus"
+	attestor "github.com/spiffe/spire/pkg/agent/attestor/workload"
 	"github.com/spiffe/spire/pkg/agent/catalog"
 	"github.com/spiffe/spire/pkg/agent/manager"
+	"github.com/spiffe/spire/pkg/common/selector"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 
 	"google.golang.org/grpc"
 )
 
 type Config struct {
+	// BindAddr is the address the Workload API is served on. On Windows,
+	// its name is the path of a named pipe.
 	BindAddr *net.UnixAddr
 
 	GRPCHook func(*grpc.Server) error
@@ -24,6 +28,12 @@ type Config struct {
 
 	// If true, an SDS server will be served over the UDS socket
 	EnableSDS bool
+
+	// If set, post-processes the selectors of workloads
+	SelectorHook *selector.Hook
+
+	// If set, holds the selectors of attested workloads
+	AttestationCache *attestor.Cache
 }
 
 func New(c *Config) *endpoints {
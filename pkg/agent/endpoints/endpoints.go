@@ -3,8 +3,6 @@ package endpoints
 import (
 	"context"
 	"fmt"
-	"net"
-	"os"
 
 	sds_v2 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
 	attestor "github.com/spiffe/spire/pkg/agent/attestor/workload"
@@ -33,7 +31,7 @@ func (e *endpoints) ListenAndServe(ctx context.Context) error {
 		e.registerSecretDiscoveryService(server)
 	}
 
-	l, err := e.createUDSListener()
+	l, err := e.createListener()
 	if err != nil {
 		return err
 	}
@@ -68,6 +66,9 @@ func (e *endpoints) registerWorkloadAPI(server *grpc.Server) {
 		Catalog: e.c.Catalog,
 		L:       e.c.Log.WithField("subsystem_name", "workload_api"),
 		M:       e.c.Metrics,
+
+		SelectorHook:     e.c.SelectorHook,
+		AttestationCache: e.c.AttestationCache,
 	}
 
 	workload_pb.RegisterSpiffeWorkloadAPIServer(server, w)
@@ -75,9 +76,11 @@ func (e *endpoints) registerWorkloadAPI(server *grpc.Server) {
 
 func (e *endpoints) registerSecretDiscoveryService(server *grpc.Server) {
 	attestor := attestor.New(&attestor.Config{
-		Catalog: e.c.Catalog,
-		L:       e.c.Log,
-		M:       e.c.Metrics,
+		Catalog:      e.c.Catalog,
+		L:            e.c.Log,
+		M:            e.c.Metrics,
+		SelectorHook: e.c.SelectorHook,
+		Cache:        e.c.AttestationCache,
 	})
 
 	h := sds.NewHandler(sds.HandlerConfig{
@@ -88,15 +91,3 @@ func (e *endpoints) registerSecretDiscoveryService(server *grpc.Server) {
 	})
 	sds_v2.RegisterSecretDiscoveryServiceServer(server, h)
 }
-
-func (e *endpoints) createUDSListener() (net.Listener, error) {
-	os.Remove(e.c.BindAddr.String())
-
-	l, err := net.Listen(e.c.BindAddr.Network(), e.c.BindAddr.String())
-	if err != nil {
-		return nil, fmt.Errorf("create UDS listener: %s", err)
-	}
-
-	os.Chmod(e.c.BindAddr.String(), os.ModePerm)
-	return l, nil
-}
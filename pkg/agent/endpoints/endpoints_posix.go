@@ -0,0 +1,21 @@
+// +build !windows
+
+package endpoints
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+func (e *endpoints) createListener() (net.Listener, error) {
+	os.Remove(e.c.BindAddr.String())
+
+	l, err := net.Listen(e.c.BindAddr.Network(), e.c.BindAddr.String())
+	if err != nil {
+		return nil, fmt.Errorf("create UDS listener: %s", err)
+	}
+
+	os.Chmod(e.c.BindAddr.String(), os.ModePerm)
+	return l, nil
+}
@@ -0,0 +1,29 @@
+// +build windows
+
+package endpoints
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+const (
+	// pipeSecurityDescriptor grants full access to SYSTEM and the builtin
+	// administrators, so the agent can create pipe instances, and read/write
+	// access to everyone else, the equivalent of the world accessible socket
+	// used on other platforms.
+	pipeSecurityDescriptor = "D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;GRGW;;;WD)"
+)
+
+// createListener listens on the named pipe given by the bind address
+func (e *endpoints) createListener() (net.Listener, error) {
+	l, err := winio.ListenPipe(e.c.BindAddr.String(), &winio.PipeConfig{
+		SecurityDescriptor: pipeSecurityDescriptor,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create named pipe listener: %s", err)
+	}
+	return l, nil
+}
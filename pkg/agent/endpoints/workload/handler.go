@@ -20,6 +20,7 @@ import (
 	"github.com/spiffe/spire/pkg/common/auth"
 	"github.com/spiffe/spire/pkg/common/bundleutil"
 	"github.com/spiffe/spire/pkg/common/jwtsvid"
+	"github.com/spiffe/spire/pkg/common/selector"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/pkg/common/x509util"
 	"github.com/spiffe/spire/proto/api/workload"
@@ -42,6 +43,12 @@ type Handler struct {
 	Catalog catalog.Catalog
 	L       logrus.FieldLogger
 	M       telemetry.Metrics
+
+	// SelectorHook, if set, post-processes the selectors of workloads
+	SelectorHook *selector.Hook
+
+	// AttestationCache, if set, holds the selectors of attested workloads
+	AttestationCache *attestor.Cache
 }
 
 func (h *Handler) FetchJWTSVID(ctx context.Context, req *workload.JWTSVIDRequest) (*workload.JWTSVIDResponse, error) {
@@ -296,9 +303,11 @@ func (h *Handler) startCall(ctx context.Context) (int32, []*common.Selector, tel
 	metrics.IncrCounter([]string{workloadApi, "connections"}, 1)
 
 	config := attestor.Config{
-		Catalog: h.Catalog,
-		L:       h.L,
-		M:       metrics,
+		Catalog:      h.Catalog,
+		L:            h.L,
+		M:            metrics,
+		SelectorHook: h.SelectorHook,
+		Cache:        h.AttestationCache,
 	}
 
 	selectors := attestor.New(&config).Attest(ctx, pid)
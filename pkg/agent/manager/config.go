@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/x509"
 	"fmt"
@@ -30,6 +31,12 @@ type Config struct {
 	BundleCachePath  string
 	SyncInterval     time.Duration
 	RotationInterval time.Duration
+
+	// Reattestor, if set, is used to re-attest the agent, either every
+	// ReattestInterval or when requested through ReattestCh.
+	Reattestor       func(ctx context.Context, svid []*x509.Certificate, key *ecdsa.PrivateKey) ([]*x509.Certificate, *ecdsa.PrivateKey, error)
+	ReattestInterval time.Duration
+	ReattestCh       <-chan struct{}
 }
 
 // New creates a cache manager based on c's configuration
@@ -60,6 +67,10 @@ func New(c *Config) (*manager, error) {
 		ServerAddr:   c.ServerAddr,
 		TrustDomain:  c.TrustDomain,
 		Interval:     c.RotationInterval,
+
+		Reattestor:       c.Reattestor,
+		ReattestInterval: c.ReattestInterval,
+		ReattestCh:       c.ReattestCh,
 	}
 	svidRotator, client := svid.NewRotator(rotCfg)
 
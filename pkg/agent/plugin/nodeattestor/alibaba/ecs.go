@@ -0,0 +1,116 @@
+package alibaba
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/alibaba"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+var (
+	ecsError = errs.Class("alibaba-ecs")
+)
+
+type ECSAttestorConfig struct {
+	trustDomain string
+	MetadataURL string `hcl:"metadata_url"`
+}
+
+type ECSAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *ECSAttestorConfig
+
+	hooks struct {
+		fetchAttestationData func(ctx context.Context, metadataURL string) (*alibaba.AttestationData, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*ECSAttestorPlugin)(nil)
+
+func New() *ECSAttestorPlugin {
+	p := &ECSAttestorPlugin{}
+	p.hooks.fetchAttestationData = func(ctx context.Context, metadataURL string) (*alibaba.AttestationData, error) {
+		return alibaba.FetchAttestationData(ctx, http.DefaultClient, metadataURL)
+	}
+	return p
+}
+
+func (p *ECSAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	attestationData, err := p.hooks.fetchAttestationData(stream.Context(), config.MetadataURL)
+	if err != nil {
+		return ecsError.New("unable to fetch attestation data: %v", err)
+	}
+
+	// the document is verified by the server; it is only read here to
+	// build the agent ID
+	doc := new(alibaba.InstanceIdentityDocument)
+	if err := json.Unmarshal([]byte(attestationData.Document), doc); err != nil {
+		return ecsError.New("unable to unmarshal identity document: %v", err)
+	}
+
+	data, err := json.Marshal(attestationData)
+	if err != nil {
+		return ecsError.Wrap(err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: alibaba.PluginName,
+			Data: data,
+		},
+		SpiffeId: alibaba.AgentID(config.trustDomain, doc),
+	})
+}
+
+func (p *ECSAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(ECSAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, ecsError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, ecsError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, ecsError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.MetadataURL == "" {
+		config.MetadataURL = alibaba.DefaultMetadataURL
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *ECSAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *ECSAttestorPlugin) getConfig() (*ECSAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, ecsError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *ECSAttestorPlugin) setConfig(config *ECSAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
@@ -0,0 +1,157 @@
+package alibaba
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/common/plugin/alibaba"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testDocument = `{"account-id":"ACCOUNT","instance-id":"i-INSTANCE","region-id":"cn-hangzhou"}`
+)
+
+func TestECSAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(ECSAttestorSuite))
+}
+
+type ECSAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+
+	expectedURL     string
+	attestationData *alibaba.AttestationData
+	fetchErr        error
+}
+
+func (s *ECSAttestorSuite) SetupTest() {
+	s.expectedURL = alibaba.DefaultMetadataURL
+	s.attestationData = &alibaba.AttestationData{
+		Document:  testDocument,
+		Signature: "SIGNATURE",
+		RAMRole:   "ROLE",
+	}
+	s.fetchErr = nil
+
+	s.newAttestor()
+
+	_, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{
+			TrustDomain: "example.org",
+		},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *ECSAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	s.requireFetchError("alibaba-ecs: not configured")
+}
+
+func (s *ECSAttestorSuite) TestFetchAttestationDataFailedToFetch() {
+	s.fetchErr = errors.New("FAILED")
+	s.requireFetchError("alibaba-ecs: unable to fetch attestation data: FAILED")
+}
+
+func (s *ECSAttestorSuite) TestFetchAttestationDataMalformedDocument() {
+	s.attestationData.Document = "{"
+	s.requireFetchError("alibaba-ecs: unable to unmarshal identity document")
+}
+
+func (s *ECSAttestorSuite) TestFetchAttestationDataSuccess() {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+
+	// assert attestation data
+	s.Require().Equal("spiffe://example.org/spire/agent/alibaba_ecs/ACCOUNT/cn-hangzhou/i-INSTANCE", resp.SpiffeId)
+	s.Require().NotNil(resp.AttestationData)
+	s.Require().Equal("alibaba_ecs", resp.AttestationData.Type)
+	document, err := json.Marshal(testDocument)
+	s.Require().NoError(err)
+	s.Require().JSONEq(fmt.Sprintf(`{"document": %s, "signature": "SIGNATURE", "ram_role": "ROLE"}`, document), string(resp.AttestationData.Data))
+
+	// node attestor should return EOF now
+	_, err = stream.Recv()
+	s.Require().Equal(io.EOF, err)
+}
+
+func (s *ECSAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "alibaba-ecs: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "alibaba-ecs: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "alibaba-ecs: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// success with a custom URL
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `metadata_url = "http://metadata.example.org"`,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+
+	s.expectedURL = "http://metadata.example.org"
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Require().NoError(err)
+}
+
+func (s *ECSAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *ECSAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.fetchAttestationData = func(ctx context.Context, metadataURL string) (*alibaba.AttestationData, error) {
+		if metadataURL != s.expectedURL {
+			return nil, fmt.Errorf("expected metadata URL %s; got %s", s.expectedURL, metadataURL)
+		}
+		if s.fetchErr != nil {
+			return nil, s.fetchErr
+		}
+		return s.attestationData, nil
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *ECSAttestorSuite) requireFetchError(contains string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *ECSAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
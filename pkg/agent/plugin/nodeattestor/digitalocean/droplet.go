@@ -0,0 +1,112 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/digitalocean"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+var (
+	dropletError = errs.Class("digitalocean-droplet")
+)
+
+type DropletAttestorConfig struct {
+	trustDomain string
+
+	// MetadataURL is the URL of the droplet ID served by the metadata
+	// service
+	MetadataURL string `hcl:"metadata_url"`
+}
+
+type DropletAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *DropletAttestorConfig
+
+	hooks struct {
+		fetchDropletID func(context.Context, digitalocean.HTTPClient, string) (int64, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*DropletAttestorPlugin)(nil)
+
+func New() *DropletAttestorPlugin {
+	p := &DropletAttestorPlugin{}
+	p.hooks.fetchDropletID = digitalocean.FetchDropletID
+	return p
+}
+
+func (p *DropletAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	dropletID, err := p.hooks.fetchDropletID(stream.Context(), http.DefaultClient, config.MetadataURL)
+	if err != nil {
+		return dropletError.New("unable to fetch droplet ID: %v", err)
+	}
+
+	data, err := json.Marshal(digitalocean.AttestationData{
+		DropletID: dropletID,
+	})
+	if err != nil {
+		return dropletError.Wrap(err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: digitalocean.PluginName,
+			Data: data,
+		},
+		SpiffeId: digitalocean.AgentID(config.trustDomain, dropletID),
+	})
+}
+
+func (p *DropletAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(DropletAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, dropletError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, dropletError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, dropletError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.MetadataURL == "" {
+		config.MetadataURL = digitalocean.DefaultMetadataURL
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *DropletAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *DropletAttestorPlugin) getConfig() (*DropletAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, dropletError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *DropletAttestorPlugin) setConfig(config *DropletAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
@@ -0,0 +1,142 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/common/plugin/digitalocean"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestDropletAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(DropletAttestorSuite))
+}
+
+type DropletAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+
+	expectedURL string
+	dropletID   int64
+	dropletErr  error
+}
+
+func (s *DropletAttestorSuite) SetupTest() {
+	s.expectedURL = digitalocean.DefaultMetadataURL
+	s.dropletID = 12345
+	s.dropletErr = nil
+
+	s.newAttestor()
+
+	_, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{
+			TrustDomain: "example.org",
+		},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *DropletAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	s.requireFetchError("digitalocean-droplet: not configured")
+}
+
+func (s *DropletAttestorSuite) TestFetchAttestationDataFailedToObtainDropletID() {
+	s.dropletErr = errors.New("FAILED")
+	s.requireFetchError("digitalocean-droplet: unable to fetch droplet ID: FAILED")
+}
+
+func (s *DropletAttestorSuite) TestFetchAttestationDataSuccess() {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+
+	// assert attestation data
+	s.Require().Equal("spiffe://example.org/spire/agent/digitalocean_droplet/12345", resp.SpiffeId)
+	s.Require().NotNil(resp.AttestationData)
+	s.Require().Equal("digitalocean_droplet", resp.AttestationData.Type)
+	s.Require().JSONEq(`{"droplet_id": 12345}`, string(resp.AttestationData.Data))
+
+	// node attestor should return EOF now
+	_, err = stream.Recv()
+	s.Require().Equal(io.EOF, err)
+}
+
+func (s *DropletAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "digitalocean-droplet: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "digitalocean-droplet: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "digitalocean-droplet: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// success with a custom metadata URL
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `metadata_url = "http://metadata.example.org/metadata/v1/id"`,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+
+	s.expectedURL = "http://metadata.example.org/metadata/v1/id"
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Require().NoError(err)
+}
+
+func (s *DropletAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *DropletAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.fetchDropletID = func(ctx context.Context, httpClient digitalocean.HTTPClient, metadataURL string) (int64, error) {
+		if httpClient != http.DefaultClient {
+			return 0, errors.New("unexpected http client")
+		}
+		if metadataURL != s.expectedURL {
+			return 0, fmt.Errorf("expected metadata URL %s; got %s", s.expectedURL, metadataURL)
+		}
+		return s.dropletID, s.dropletErr
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *DropletAttestorSuite) requireFetchError(contains string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *DropletAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
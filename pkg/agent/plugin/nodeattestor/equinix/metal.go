@@ -0,0 +1,112 @@
+package equinix
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/equinix"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+var (
+	metalError = errs.Class("equinix-metal")
+)
+
+type MetalAttestorConfig struct {
+	trustDomain string
+
+	// MetadataURL is the URL of the device metadata served by the metadata
+	// service
+	MetadataURL string `hcl:"metadata_url"`
+}
+
+type MetalAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *MetalAttestorConfig
+
+	hooks struct {
+		fetchDeviceID func(context.Context, equinix.HTTPClient, string) (string, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*MetalAttestorPlugin)(nil)
+
+func New() *MetalAttestorPlugin {
+	p := &MetalAttestorPlugin{}
+	p.hooks.fetchDeviceID = equinix.FetchDeviceID
+	return p
+}
+
+func (p *MetalAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	deviceID, err := p.hooks.fetchDeviceID(stream.Context(), http.DefaultClient, config.MetadataURL)
+	if err != nil {
+		return metalError.New("unable to fetch device ID: %v", err)
+	}
+
+	data, err := json.Marshal(equinix.AttestationData{
+		DeviceID: deviceID,
+	})
+	if err != nil {
+		return metalError.Wrap(err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: equinix.PluginName,
+			Data: data,
+		},
+		SpiffeId: equinix.AgentID(config.trustDomain, deviceID),
+	})
+}
+
+func (p *MetalAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(MetalAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, metalError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, metalError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, metalError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.MetadataURL == "" {
+		config.MetadataURL = equinix.DefaultMetadataURL
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *MetalAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *MetalAttestorPlugin) getConfig() (*MetalAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, metalError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *MetalAttestorPlugin) setConfig(config *MetalAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
@@ -0,0 +1,142 @@
+package equinix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/common/plugin/equinix"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMetalAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(MetalAttestorSuite))
+}
+
+type MetalAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+
+	expectedURL string
+	deviceID    string
+	deviceErr   error
+}
+
+func (s *MetalAttestorSuite) SetupTest() {
+	s.expectedURL = equinix.DefaultMetadataURL
+	s.deviceID = "DEVICEID"
+	s.deviceErr = nil
+
+	s.newAttestor()
+
+	_, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{
+			TrustDomain: "example.org",
+		},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *MetalAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	s.requireFetchError("equinix-metal: not configured")
+}
+
+func (s *MetalAttestorSuite) TestFetchAttestationDataFailedToObtainDeviceID() {
+	s.deviceErr = errors.New("FAILED")
+	s.requireFetchError("equinix-metal: unable to fetch device ID: FAILED")
+}
+
+func (s *MetalAttestorSuite) TestFetchAttestationDataSuccess() {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+
+	// assert attestation data
+	s.Require().Equal("spiffe://example.org/spire/agent/equinix_metal/DEVICEID", resp.SpiffeId)
+	s.Require().NotNil(resp.AttestationData)
+	s.Require().Equal("equinix_metal", resp.AttestationData.Type)
+	s.Require().JSONEq(`{"device_id": "DEVICEID"}`, string(resp.AttestationData.Data))
+
+	// node attestor should return EOF now
+	_, err = stream.Recv()
+	s.Require().Equal(io.EOF, err)
+}
+
+func (s *MetalAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "equinix-metal: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "equinix-metal: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "equinix-metal: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// success with a custom metadata URL
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `metadata_url = "https://metadata.example.org/metadata"`,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+
+	s.expectedURL = "https://metadata.example.org/metadata"
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Require().NoError(err)
+}
+
+func (s *MetalAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *MetalAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.fetchDeviceID = func(ctx context.Context, httpClient equinix.HTTPClient, metadataURL string) (string, error) {
+		if httpClient != http.DefaultClient {
+			return "", errors.New("unexpected http client")
+		}
+		if metadataURL != s.expectedURL {
+			return "", fmt.Errorf("expected metadata URL %s; got %s", s.expectedURL, metadataURL)
+		}
+		return s.deviceID, s.deviceErr
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *MetalAttestorSuite) requireFetchError(contains string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *MetalAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
@@ -0,0 +1,160 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/github"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	pluginName = "github_oidc"
+
+	// Environment variables set by GitHub Actions for jobs granted the
+	// id-token: write permission
+	requestURLEnv   = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	requestTokenEnv = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+)
+
+var (
+	oidcError = errs.Class("github-oidc")
+)
+
+type OIDCAttestorConfig struct {
+	trustDomain string
+
+	// Audience requested for the OIDC token. It must match the audience
+	// the server is configured to accept.
+	Audience string `hcl:"audience"`
+}
+
+type OIDCAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *OIDCAttestorConfig
+
+	hooks struct {
+		getenv         func(string) string
+		fetchOIDCToken func(context.Context, github.HTTPClient, string, string, string) (string, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*OIDCAttestorPlugin)(nil)
+
+func NewOIDCAttestorPlugin() *OIDCAttestorPlugin {
+	p := &OIDCAttestorPlugin{}
+	p.hooks.getenv = os.Getenv
+	p.hooks.fetchOIDCToken = github.FetchOIDCToken
+	return p
+}
+
+func (p *OIDCAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	requestURL := p.hooks.getenv(requestURLEnv)
+	requestToken := p.hooks.getenv(requestTokenEnv)
+	if requestURL == "" || requestToken == "" {
+		return oidcError.New("%s and %s must be set; is the job granted the id-token: write permission?", requestURLEnv, requestTokenEnv)
+	}
+
+	// Obtain an OIDC token from the GitHub Actions token endpoint
+	token, err := p.hooks.fetchOIDCToken(stream.Context(), http.DefaultClient, requestURL, requestToken, config.Audience)
+	if err != nil {
+		return oidcError.New("unable to fetch token: %v", err)
+	}
+
+	claims, err := getUnverifiedOIDCTokenClaims(token)
+	if err != nil {
+		return oidcError.Wrap(err)
+	}
+
+	data, err := json.Marshal(github.OIDCAttestationData{
+		Token: token,
+	})
+	if err != nil {
+		return oidcError.Wrap(err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: pluginName,
+			Data: data,
+		},
+		SpiffeId: claims.AgentID(config.trustDomain),
+	})
+}
+
+func (p *OIDCAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(OIDCAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, oidcError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, oidcError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, oidcError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.Audience == "" {
+		config.Audience = github.DefaultAudience
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *OIDCAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *OIDCAttestorPlugin) getConfig() (*OIDCAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, oidcError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *OIDCAttestorPlugin) setConfig(config *OIDCAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func getUnverifiedOIDCTokenClaims(rawToken string) (*github.OIDCTokenClaims, error) {
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return nil, oidcError.New("unable to parse token: %v", err)
+	}
+
+	claims := new(github.OIDCTokenClaims)
+	if err := token.UnsafeClaimsWithoutVerification(claims); err != nil {
+		return nil, oidcError.New("unable to parse token claims: %v", err)
+	}
+
+	switch {
+	case claims.Repository == "":
+		return nil, oidcError.New("token missing repository claim")
+	case claims.RunID == "":
+		return nil, oidcError.New("token missing run ID claim")
+	case claims.ID == "":
+		return nil, oidcError.New("token missing token ID claim")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,205 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/common/plugin/github"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestOIDCAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(OIDCAttestorSuite))
+}
+
+type OIDCAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+
+	env              map[string]string
+	expectedAudience string
+	token            string
+	tokenErr         error
+}
+
+func (s *OIDCAttestorSuite) SetupTest() {
+	s.env = map[string]string{
+		"ACTIONS_ID_TOKEN_REQUEST_URL":   "https://example.org/token",
+		"ACTIONS_ID_TOKEN_REQUEST_TOKEN": "REQUESTTOKEN",
+	}
+	s.expectedAudience = github.DefaultAudience
+	s.token = ""
+	s.tokenErr = nil
+
+	s.newAttestor()
+
+	_, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{
+			TrustDomain: "example.org",
+		},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	s.requireFetchError("github-oidc: not configured")
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataMissingEnvironment() {
+	delete(s.env, "ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	s.requireFetchError("github-oidc: ACTIONS_ID_TOKEN_REQUEST_URL and ACTIONS_ID_TOKEN_REQUEST_TOKEN must be set")
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataFailedToObtainToken() {
+	s.tokenErr = errors.New("FAILED")
+	s.requireFetchError("github-oidc: unable to fetch token: FAILED")
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataTokenMalformed() {
+	s.token = ""
+	s.requireFetchError("github-oidc: unable to parse token")
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataTokenHasBadClaims() {
+	s.token = "e30.f32.baadf00d"
+	s.requireFetchError("github-oidc: unable to parse token claims")
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataTokenMissingClaims() {
+	s.token = s.makeToken("", "42", "TOKENID")
+	s.requireFetchError("github-oidc: token missing repository claim")
+
+	s.token = s.makeToken("octo-org/octo-repo", "", "TOKENID")
+	s.requireFetchError("github-oidc: token missing run ID claim")
+
+	s.token = s.makeToken("octo-org/octo-repo", "42", "")
+	s.requireFetchError("github-oidc: token missing token ID claim")
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataSuccess() {
+	s.token = s.makeToken("octo-org/octo-repo", "42", "TOKENID")
+
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+
+	// assert attestation data
+	s.Require().Equal("spiffe://example.org/spire/agent/github_oidc/octo-org/octo-repo/42/TOKENID", resp.SpiffeId)
+	s.Require().NotNil(resp.AttestationData)
+	s.Require().Equal("github_oidc", resp.AttestationData.Type)
+	s.Require().JSONEq(fmt.Sprintf(`{"token": %q}`, s.token), string(resp.AttestationData.Data))
+
+	// node attestor should return EOF now
+	_, err = stream.Recv()
+	s.Require().Equal(io.EOF, err)
+}
+
+func (s *OIDCAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "github-oidc: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.requireErrorContains(err, "github-oidc: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "github-oidc: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// success with a custom audience
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `audience = "AUDIENCE"`,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+
+	s.expectedAudience = "AUDIENCE"
+	s.token = s.makeToken("octo-org/octo-repo", "42", "TOKENID")
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Require().NoError(err)
+}
+
+func (s *OIDCAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *OIDCAttestorSuite) newAttestor() {
+	attestor := NewOIDCAttestorPlugin()
+	attestor.hooks.getenv = func(key string) string {
+		return s.env[key]
+	}
+	attestor.hooks.fetchOIDCToken = func(ctx context.Context, httpClient github.HTTPClient, requestURL, requestToken, audience string) (string, error) {
+		if httpClient != http.DefaultClient {
+			return "", errors.New("unexpected http client")
+		}
+		if requestURL != "https://example.org/token" {
+			return "", fmt.Errorf("unexpected request URL %s", requestURL)
+		}
+		if requestToken != "REQUESTTOKEN" {
+			return "", fmt.Errorf("unexpected request token %s", requestToken)
+		}
+		if audience != s.expectedAudience {
+			return "", fmt.Errorf("expected audience %s; got %s", s.expectedAudience, audience)
+		}
+		return s.token, s.tokenErr
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *OIDCAttestorSuite) requireFetchError(contains string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *OIDCAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func (s *OIDCAttestorSuite) makeToken(repository, runID, tokenID string) string {
+	claims := github.OIDCTokenClaims{
+		Claims: jwt.Claims{
+			ID: tokenID,
+		},
+		Repository: repository,
+		RunID:      runID,
+	}
+
+	signingKey := jose.SigningKey{Algorithm: jose.HS256, Key: []byte("KEY")}
+	signer, err := jose.NewSigner(signingKey, nil)
+	s.Require().NoError(err)
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	s.Require().NoError(err)
+	return token
+}
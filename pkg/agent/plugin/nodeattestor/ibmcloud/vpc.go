@@ -0,0 +1,142 @@
+package ibmcloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/ibmcloud"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	vpcError = errs.Class("ibmcloud-vpc")
+)
+
+type VPCAttestorConfig struct {
+	trustDomain string
+	MetadataURL string `hcl:"metadata_url"`
+
+	// TrustedProfileID is the ID of the trusted profile to obtain the IAM
+	// token for. Only required when more than one trusted profile is
+	// linked to the instance.
+	TrustedProfileID string `hcl:"trusted_profile_id"`
+}
+
+type VPCAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *VPCAttestorConfig
+
+	hooks struct {
+		fetchIAMToken func(ctx context.Context, metadataURL, trustedProfileID string) (string, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*VPCAttestorPlugin)(nil)
+
+func New() *VPCAttestorPlugin {
+	p := &VPCAttestorPlugin{}
+	p.hooks.fetchIAMToken = func(ctx context.Context, metadataURL, trustedProfileID string) (string, error) {
+		return ibmcloud.FetchIAMToken(ctx, http.DefaultClient, metadataURL, trustedProfileID)
+	}
+	return p
+}
+
+func (p *VPCAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	token, err := p.hooks.fetchIAMToken(stream.Context(), config.MetadataURL, config.TrustedProfileID)
+	if err != nil {
+		return vpcError.New("unable to fetch IAM token: %v", err)
+	}
+
+	crn, err := getUnverifiedInstanceCRN(token)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(ibmcloud.AttestationData{
+		Token: token,
+	})
+	if err != nil {
+		return vpcError.Wrap(err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: ibmcloud.PluginName,
+			Data: data,
+		},
+		SpiffeId: ibmcloud.AgentID(config.trustDomain, crn),
+	})
+}
+
+func (p *VPCAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(VPCAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, vpcError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, vpcError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, vpcError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.MetadataURL == "" {
+		config.MetadataURL = ibmcloud.DefaultMetadataURL
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *VPCAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *VPCAttestorPlugin) getConfig() (*VPCAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, vpcError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *VPCAttestorPlugin) setConfig(config *VPCAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+// getUnverifiedInstanceCRN reads the instance CRN from the IAM token to
+// build the agent ID. The token is verified by the server.
+func getUnverifiedInstanceCRN(token string) (*ibmcloud.InstanceCRN, error) {
+	tok, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, vpcError.New("unable to parse IAM token: %v", err)
+	}
+
+	claims := new(ibmcloud.IAMClaims)
+	if err := tok.UnsafeClaimsWithoutVerification(claims); err != nil {
+		return nil, vpcError.New("unable to get IAM token claims: %v", err)
+	}
+
+	crn, err := ibmcloud.ParseInstanceCRN(claims.Authn.Sub)
+	if err != nil {
+		return nil, vpcError.Wrap(err)
+	}
+	return crn, nil
+}
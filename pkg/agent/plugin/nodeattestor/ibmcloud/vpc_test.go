@@ -0,0 +1,182 @@
+package ibmcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/common/plugin/ibmcloud"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestVPCAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(VPCAttestorSuite))
+}
+
+type VPCAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+
+	expectedURL       string
+	expectedProfileID string
+	token             string
+	tokenErr          error
+}
+
+func (s *VPCAttestorSuite) SetupTest() {
+	s.expectedURL = ibmcloud.DefaultMetadataURL
+	s.expectedProfileID = ""
+	s.token = ""
+	s.tokenErr = nil
+
+	s.newAttestor()
+
+	_, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{
+			TrustDomain: "example.org",
+		},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *VPCAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	s.requireFetchError("ibmcloud-vpc: not configured")
+}
+
+func (s *VPCAttestorSuite) TestFetchAttestationDataFailedToObtainToken() {
+	s.tokenErr = errors.New("FAILED")
+	s.requireFetchError("ibmcloud-vpc: unable to fetch IAM token: FAILED")
+}
+
+func (s *VPCAttestorSuite) TestFetchAttestationDataTokenMalformed() {
+	s.token = ""
+	s.requireFetchError("ibmcloud-vpc: unable to parse IAM token")
+}
+
+func (s *VPCAttestorSuite) TestFetchAttestationDataTokenHasBadClaims() {
+	s.token = "e30.f32.baadf00d"
+	s.requireFetchError("ibmcloud-vpc: unable to get IAM token claims")
+}
+
+func (s *VPCAttestorSuite) TestFetchAttestationDataTokenNotForInstance() {
+	s.token = s.makeToken("crn:v1:bluemix:public:iam-identity::a/ACCOUNT::serviceid:ServiceId-1234")
+	s.requireFetchError("is not a VPC instance")
+}
+
+func (s *VPCAttestorSuite) TestFetchAttestationDataSuccess() {
+	s.token = s.makeToken("crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::instance:0717_INSTANCE")
+
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+
+	// assert attestation data
+	s.Require().Equal("spiffe://example.org/spire/agent/ibmcloud_vpc/ACCOUNT/0717_INSTANCE", resp.SpiffeId)
+	s.Require().NotNil(resp.AttestationData)
+	s.Require().Equal("ibmcloud_vpc", resp.AttestationData.Type)
+	s.Require().JSONEq(fmt.Sprintf(`{"token": %q}`, s.token), string(resp.AttestationData.Data))
+
+	// node attestor should return EOF now
+	_, err = stream.Recv()
+	s.Require().Equal(io.EOF, err)
+}
+
+func (s *VPCAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "ibmcloud-vpc: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "ibmcloud-vpc: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "ibmcloud-vpc: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// success with a custom URL and trusted profile
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `
+		metadata_url = "http://metadata.example.org"
+		trusted_profile_id = "Profile-1234"
+		`,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+
+	s.expectedURL = "http://metadata.example.org"
+	s.expectedProfileID = "Profile-1234"
+	s.token = s.makeToken("crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::instance:0717_INSTANCE")
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Require().NoError(err)
+}
+
+func (s *VPCAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *VPCAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.fetchIAMToken = func(ctx context.Context, metadataURL, trustedProfileID string) (string, error) {
+		if metadataURL != s.expectedURL {
+			return "", fmt.Errorf("expected metadata URL %s; got %s", s.expectedURL, metadataURL)
+		}
+		if trustedProfileID != s.expectedProfileID {
+			return "", fmt.Errorf("expected trusted profile %s; got %s", s.expectedProfileID, trustedProfileID)
+		}
+		return s.token, s.tokenErr
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *VPCAttestorSuite) requireFetchError(contains string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *VPCAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func (s *VPCAttestorSuite) makeToken(sub string) string {
+	claims := new(ibmcloud.IAMClaims)
+	claims.Account.BSS = "ACCOUNT"
+	claims.Authn.Sub = sub
+	claims.Authn.SubType = ibmcloud.SubTypeComputeResource
+
+	signingKey := jose.SigningKey{Algorithm: jose.HS256, Key: []byte("KEY")}
+	signer, err := jose.NewSigner(signingKey, nil)
+	s.Require().NoError(err)
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	s.Require().NoError(err)
+	return token
+}
@@ -0,0 +1,147 @@
+package nitro
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/nitro"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+const (
+	defaultNSMDevicePath = "/dev/nsm"
+)
+
+var (
+	nitroError = errs.Class("aws-nitro")
+)
+
+type NitroAttestorConfig struct {
+	trustDomain string
+
+	// NSMDevicePath is the path to the Nitro Secure Module device inside
+	// the enclave
+	NSMDevicePath string `hcl:"nsm_device_path"`
+}
+
+type NitroAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *NitroAttestorConfig
+
+	hooks struct {
+		callNSM nsmCallFn
+	}
+}
+
+var _ nodeattestor.Plugin = (*NitroAttestorPlugin)(nil)
+
+func New() *NitroAttestorPlugin {
+	p := &NitroAttestorPlugin{}
+	p.hooks.callNSM = callNSM
+	return p
+}
+
+func (p *NitroAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	moduleID, err := describeNSM(p.hooks.callNSM, config.NSMDevicePath)
+	if err != nil {
+		return nitroError.New("unable to describe NSM: %v", err)
+	}
+
+	data, err := json.Marshal(nitro.AttestationData{
+		ModuleID: moduleID,
+	})
+	if err != nil {
+		return nitroError.Wrap(err)
+	}
+
+	spiffeID := nitro.AgentID(config.trustDomain, moduleID)
+	if err := stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: nitro.PluginName,
+			Data: data,
+		},
+		SpiffeId: spiffeID,
+	}); err != nil {
+		return err
+	}
+
+	// receive the challenge and answer with an attestation document
+	// embedding the nonce
+	resp, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	challenge := new(nitro.Challenge)
+	if err := json.Unmarshal(resp.Challenge, challenge); err != nil {
+		return nitroError.New("unable to unmarshal challenge: %v", err)
+	}
+
+	document, err := requestAttestation(p.hooks.callNSM, config.NSMDevicePath, challenge.Nonce)
+	if err != nil {
+		return nitroError.New("unable to obtain attestation document: %v", err)
+	}
+
+	responseBytes, err := json.Marshal(nitro.Response{
+		Document: document,
+	})
+	if err != nil {
+		return nitroError.New("unable to marshal challenge response: %v", err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		SpiffeId: spiffeID,
+		Response: responseBytes,
+	})
+}
+
+func (p *NitroAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(NitroAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, nitroError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, nitroError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, nitroError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.NSMDevicePath == "" {
+		config.NSMDevicePath = defaultNSMDevicePath
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *NitroAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *NitroAttestorPlugin) getConfig() (*NitroAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, nitroError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *NitroAttestorPlugin) setConfig(config *NitroAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
@@ -0,0 +1,229 @@
+package nitro
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/plugin/nitro"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/test/fakes/fakenitro"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestNitroAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(NitroAttestorSuite))
+}
+
+type NitroAttestorSuite struct {
+	suite.Suite
+
+	attestor   *nodeattestor.BuiltIn
+	hypervisor *fakenitro.Hypervisor
+
+	devicePath string
+	nsmErr     error
+	responses  map[string]interface{}
+}
+
+func (s *NitroAttestorSuite) SetupTest() {
+	s.hypervisor = fakenitro.New(s.T())
+	s.devicePath = "/dev/nsm"
+	s.nsmErr = nil
+	s.responses = map[string]interface{}{
+		"DescribeNSM": map[string]interface{}{
+			"DescribeNSM": map[string]interface{}{
+				"module_id": "i-1234-enc5678",
+			},
+		},
+	}
+
+	s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *NitroAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().EqualError(err, "aws-nitro: not configured")
+}
+
+func (s *NitroAttestorSuite) TestFetchAttestationDataDescribeFails() {
+	s.nsmErr = errors.New("oh no")
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().EqualError(err, "aws-nitro: unable to describe NSM: DescribeNSM request failed: oh no")
+
+	s.nsmErr = nil
+	s.responses["DescribeNSM"] = map[string]interface{}{"Error": "InternalError"}
+	stream = s.fetchAttestationData()
+	_, err = stream.Recv()
+	s.Require().EqualError(err, "aws-nitro: unable to describe NSM: DescribeNSM request failed: InternalError")
+
+	s.responses["DescribeNSM"] = map[string]interface{}{"DescribeNSM": map[string]interface{}{}}
+	stream = s.fetchAttestationData()
+	_, err = stream.Recv()
+	s.Require().EqualError(err, "aws-nitro: unable to describe NSM: DescribeNSM response missing module_id")
+}
+
+func (s *NitroAttestorSuite) TestFetchAttestationDataBadChallenge() {
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().NoError(err)
+
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: []byte("{"),
+	}))
+	_, err = stream.Recv()
+	s.requireErrorContains(err, "aws-nitro: unable to unmarshal challenge")
+}
+
+func (s *NitroAttestorSuite) TestFetchAttestationDataAttestationFails() {
+	s.responses["Attestation"] = map[string]interface{}{"Error": "InvalidArgument"}
+
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().NoError(err)
+
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: s.marshal(nitro.Challenge{Nonce: []byte("NONCE")}),
+	}))
+	_, err = stream.Recv()
+	s.Require().EqualError(err, "aws-nitro: unable to obtain attestation document: Attestation request failed: InvalidArgument")
+}
+
+func (s *NitroAttestorSuite) TestFetchAttestationDataSuccess() {
+	s.configureAttestor(`nsm_device_path = "/dev/othernsm"`)
+	s.devicePath = "/dev/othernsm"
+
+	stream := s.fetchAttestationData()
+
+	// the module ID is sent first
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().Equal("spiffe://example.org/spire/agent/aws_nitro/i-1234-enc5678", resp.SpiffeId)
+	s.Require().Equal("aws_nitro", resp.AttestationData.Type)
+	s.Require().JSONEq(`{"module_id": "i-1234-enc5678"}`, string(resp.AttestationData.Data))
+
+	// the challenge is answered with an attestation document holding the
+	// nonce
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: s.marshal(nitro.Challenge{Nonce: []byte("NONCE")}),
+	}))
+	resp, err = stream.Recv()
+	s.Require().NoError(err)
+	s.Require().Equal("spiffe://example.org/spire/agent/aws_nitro/i-1234-enc5678", resp.SpiffeId)
+
+	response := new(nitro.Response)
+	s.Require().NoError(json.Unmarshal(resp.Response, response))
+	doc, err := nitro.VerifyAttestationDocument(response.Document, s.hypervisor.Roots(), time.Now())
+	s.Require().NoError(err)
+	s.Require().Equal("i-1234-enc5678", doc.ModuleID)
+	s.Require().Equal([]byte("NONCE"), doc.Nonce)
+}
+
+func (s *NitroAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "aws-nitro: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "aws-nitro: global configuration is required")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "aws-nitro: global configuration missing trust domain")
+	s.Require().Nil(resp)
+}
+
+func (s *NitroAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *NitroAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.callNSM = s.callNSM
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *NitroAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: config,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+// callNSM fakes the NSM device, answering DescribeNSM and Attestation
+// requests
+func (s *NitroAttestorSuite) callNSM(devicePath string, request []byte) ([]byte, error) {
+	if devicePath != s.devicePath {
+		return nil, errors.New("unexpected device path " + devicePath)
+	}
+	if s.nsmErr != nil {
+		return nil, s.nsmErr
+	}
+
+	v, err := nitro.DecodeCBOR(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response interface{}
+	switch v := v.(type) {
+	case string:
+		response = s.responses[v]
+	case map[interface{}]interface{}:
+		attestation, ok := v["Attestation"].(map[interface{}]interface{})
+		if !ok {
+			return nil, errors.New("unexpected request")
+		}
+		if resp, ok := s.responses["Attestation"]; ok {
+			response = resp
+			break
+		}
+		nonce, _ := attestation["nonce"].([]byte)
+		response = map[string]interface{}{
+			"Attestation": map[string]interface{}{
+				"document": s.hypervisor.Attest(fakenitro.Document{
+					ModuleID:  "i-1234-enc5678",
+					Timestamp: time.Now(),
+					PCRs:      map[int][]byte{0: make([]byte, 48)},
+					Nonce:     nonce,
+				}),
+			},
+		}
+	}
+	if response == nil {
+		return nil, errors.New("unexpected request")
+	}
+	return nitro.EncodeCBOR(response)
+}
+
+func (s *NitroAttestorSuite) fetchAttestationData() nodeattestor.FetchAttestationData_Stream {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	return stream
+}
+
+func (s *NitroAttestorSuite) marshal(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	s.Require().NoError(err)
+	return data
+}
+
+func (s *NitroAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
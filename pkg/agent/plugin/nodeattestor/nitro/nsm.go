@@ -0,0 +1,85 @@
+package nitro
+
+import (
+	"github.com/spiffe/spire/pkg/common/plugin/nitro"
+	"github.com/zeebo/errs"
+)
+
+// NSM requests and responses are CBOR encoded maps keyed by the operation
+// name (or "Error" for failed requests).
+
+// nsmCallFn sends a raw request to the Nitro Secure Module device and returns
+// the raw response
+type nsmCallFn func(devicePath string, request []byte) ([]byte, error)
+
+func describeNSM(call nsmCallFn, devicePath string) (string, error) {
+	resp, err := doNSMRequest(call, devicePath, "DescribeNSM", "DescribeNSM")
+	if err != nil {
+		return "", err
+	}
+	moduleID, ok := resp["module_id"].(string)
+	if !ok || moduleID == "" {
+		return "", errs.New("DescribeNSM response missing module_id")
+	}
+	return moduleID, nil
+}
+
+func requestAttestation(call nsmCallFn, devicePath string, nonce []byte) ([]byte, error) {
+	resp, err := doNSMRequest(call, devicePath, "Attestation", map[string]interface{}{
+		"Attestation": map[string]interface{}{
+			"nonce":      nonce,
+			"user_data":  nil,
+			"public_key": nil,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch document := resp["document"].(type) {
+	case []byte:
+		return document, nil
+	case []interface{}:
+		// some NSM library versions encode the document as an array of
+		// integers instead of a byte string
+		out := make([]byte, 0, len(document))
+		for _, b := range document {
+			n, ok := b.(uint64)
+			if !ok || n > 0xff {
+				return nil, errs.New("malformed Attestation response document")
+			}
+			out = append(out, byte(n))
+		}
+		return out, nil
+	default:
+		return nil, errs.New("Attestation response missing document")
+	}
+}
+
+func doNSMRequest(call nsmCallFn, devicePath, operation string, request interface{}) (map[interface{}]interface{}, error) {
+	requestBytes, err := nitro.EncodeCBOR(request)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBytes, err := call(devicePath, requestBytes)
+	if err != nil {
+		return nil, errs.New("%s request failed: %v", operation, err)
+	}
+
+	v, err := nitro.DecodeCBOR(responseBytes)
+	if err != nil {
+		return nil, errs.New("unable to decode %s response: %v", operation, err)
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errs.New("malformed %s response", operation)
+	}
+	if nsmErr, ok := m["Error"]; ok {
+		return nil, errs.New("%s request failed: %v", operation, nsmErr)
+	}
+	resp, ok := m[operation].(map[interface{}]interface{})
+	if !ok {
+		return nil, errs.New("malformed %s response", operation)
+	}
+	return resp, nil
+}
@@ -0,0 +1,11 @@
+// +build !linux
+
+package nitro
+
+import (
+	"github.com/zeebo/errs"
+)
+
+func callNSM(devicePath string, request []byte) ([]byte, error) {
+	return nil, errs.New("the Nitro Secure Module is only available on Linux")
+}
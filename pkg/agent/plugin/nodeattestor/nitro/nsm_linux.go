@@ -0,0 +1,50 @@
+// +build linux
+
+package nitro
+
+import (
+	"os"
+	"unsafe"
+
+	"github.com/zeebo/errs"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// nsmIoctlRequest is _IOWR(0x0A, 0, struct nsm_message)
+	nsmIoctlRequest = 0xC0200A00
+
+	// nsmResponseMaxSize is the largest response the NSM driver produces
+	nsmResponseMaxSize = 0x3000
+)
+
+// nsmMessage mirrors the driver's struct holding the request and response
+// buffers
+type nsmMessage struct {
+	request  unix.Iovec
+	response unix.Iovec
+}
+
+func callNSM(devicePath string, request []byte) ([]byte, error) {
+	f, err := os.OpenFile(devicePath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer f.Close()
+
+	if len(request) == 0 {
+		return nil, errs.New("empty request")
+	}
+	response := make([]byte, nsmResponseMaxSize)
+	msg := nsmMessage{}
+	msg.request.Base = &request[0]
+	msg.request.SetLen(len(request))
+	msg.response.Base = &response[0]
+	msg.response.SetLen(len(response))
+
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), nsmIoctlRequest, uintptr(unsafe.Pointer(&msg))); errno != 0 {
+		return nil, errs.New("ioctl failed: %v", errno)
+	}
+
+	return response[:msg.response.Len], nil
+}
@@ -0,0 +1,178 @@
+package nomad
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/nomad"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// tokenFileName is the name of the workload identity file Nomad writes
+	// into the task secrets directory when the identity has file = true
+	tokenFileName = "nomad_token"
+)
+
+var (
+	nomadError = errs.Class("nomad")
+)
+
+type NomadAttestorConfig struct {
+	trustDomain string
+
+	// Cluster is the name of the cluster, as configured on the server
+	Cluster string `hcl:"cluster"`
+
+	// TokenPath is the path of a file containing the workload identity
+	// token. If unset, the token is taken from the NOMAD_TOKEN environment
+	// variable or from the task secrets directory.
+	TokenPath string `hcl:"token_path"`
+}
+
+type NomadAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *NomadAttestorConfig
+
+	hooks struct {
+		getenv   func(key string) string
+		readFile func(path string) ([]byte, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*NomadAttestorPlugin)(nil)
+
+func New() *NomadAttestorPlugin {
+	p := &NomadAttestorPlugin{}
+	p.hooks.getenv = os.Getenv
+	p.hooks.readFile = ioutil.ReadFile
+	return p
+}
+
+func (p *NomadAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	token, err := p.loadToken(config)
+	if err != nil {
+		return err
+	}
+
+	allocationID, err := getUnverifiedAllocationID(token)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(nomad.AttestationData{
+		Cluster: config.Cluster,
+		Token:   token,
+	})
+	if err != nil {
+		return nomadError.Wrap(err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: nomad.PluginName,
+			Data: data,
+		},
+		SpiffeId: nomad.AgentID(config.trustDomain, config.Cluster, allocationID),
+	})
+}
+
+func (p *NomadAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(NomadAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, nomadError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, nomadError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, nomadError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.Cluster == "" {
+		return nil, nomadError.New("configuration missing cluster")
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *NomadAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *NomadAttestorPlugin) getConfig() (*NomadAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, nomadError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *NomadAttestorPlugin) setConfig(config *NomadAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+// loadToken reads the workload identity token from the configured path or,
+// failing that, from where Nomad exposes it to the task.
+func (p *NomadAttestorPlugin) loadToken(config *NomadAttestorConfig) (string, error) {
+	tokenPath := config.TokenPath
+	if tokenPath == "" {
+		if token := strings.TrimSpace(p.hooks.getenv("NOMAD_TOKEN")); token != "" {
+			return token, nil
+		}
+		secretsDir := p.hooks.getenv("NOMAD_SECRETS_DIR")
+		if secretsDir == "" {
+			return "", nomadError.New("unable to locate workload identity token: NOMAD_TOKEN and NOMAD_SECRETS_DIR are not set")
+		}
+		tokenPath = filepath.Join(secretsDir, tokenFileName)
+	}
+
+	out, err := p.hooks.readFile(tokenPath)
+	if err != nil {
+		return "", nomadError.New("unable to read token file: %v", err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", nomadError.New("token is empty")
+	}
+	return token, nil
+}
+
+// getUnverifiedAllocationID reads the allocation ID from the token to build
+// the agent ID. The token is verified by the server.
+func getUnverifiedAllocationID(rawToken string) (string, error) {
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return "", nomadError.New("unable to parse token: %v", err)
+	}
+
+	claims := new(nomad.WorkloadIdentityClaims)
+	if err := token.UnsafeClaimsWithoutVerification(claims); err != nil {
+		return "", nomadError.New("unable to parse token claims: %v", err)
+	}
+	if claims.AllocationID == "" {
+		return "", nomadError.New("token missing allocation ID claim")
+	}
+	return claims.AllocationID, nil
+}
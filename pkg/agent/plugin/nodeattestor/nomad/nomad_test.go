@@ -0,0 +1,201 @@
+package nomad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/common/plugin/nomad"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestNomadAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(NomadAttestorSuite))
+}
+
+type NomadAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+
+	env    map[string]string
+	output []byte
+	err    error
+	path   string
+}
+
+func (s *NomadAttestorSuite) SetupTest() {
+	s.env = map[string]string{
+		"NOMAD_SECRETS_DIR": "/secrets",
+	}
+	s.output = []byte(s.makeToken("ALLOCATION") + "\n")
+	s.err = nil
+	s.path = ""
+
+	s.newAttestor()
+	s.configure(`cluster = "FOO"`)
+}
+
+func (s *NomadAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	s.requireFetchError("nomad: not configured")
+}
+
+func (s *NomadAttestorSuite) TestFetchAttestationDataNoTokenLocation() {
+	s.env = nil
+	s.requireFetchError("nomad: unable to locate workload identity token")
+}
+
+func (s *NomadAttestorSuite) TestFetchAttestationDataFailedToReadFile() {
+	s.err = errors.New("FAILED")
+	s.requireFetchError("nomad: unable to read token file: FAILED")
+}
+
+func (s *NomadAttestorSuite) TestFetchAttestationDataEmptyToken() {
+	s.output = []byte("\n")
+	s.requireFetchError("nomad: token is empty")
+}
+
+func (s *NomadAttestorSuite) TestFetchAttestationDataMalformedToken() {
+	s.output = []byte("blah")
+	s.requireFetchError("nomad: unable to parse token")
+}
+
+func (s *NomadAttestorSuite) TestFetchAttestationDataMissingAllocationID() {
+	s.output = []byte(s.makeToken(""))
+	s.requireFetchError("nomad: token missing allocation ID claim")
+}
+
+func (s *NomadAttestorSuite) TestFetchAttestationDataFromSecretsDir() {
+	s.requireFetchSuccess(s.makeToken("ALLOCATION"))
+	s.Require().Equal("/secrets/nomad_token", s.path)
+}
+
+func (s *NomadAttestorSuite) TestFetchAttestationDataFromEnvironment() {
+	token := s.makeToken("ALLOCATION")
+	s.env["NOMAD_TOKEN"] = token
+	s.err = errors.New("file should not be read")
+	s.requireFetchSuccess(token)
+}
+
+func (s *NomadAttestorSuite) TestFetchAttestationDataFromTokenPath() {
+	s.configure(`
+	cluster = "FOO"
+	token_path = "/custom/token"
+	`)
+	s.env["NOMAD_TOKEN"] = "ignored"
+	s.requireFetchSuccess(s.makeToken("ALLOCATION"))
+	s.Require().Equal("/custom/token", s.path)
+}
+
+func (s *NomadAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "nomad: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "nomad: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "nomad: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// missing cluster
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "nomad: configuration missing cluster")
+	s.Require().Nil(resp)
+}
+
+func (s *NomadAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *NomadAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.getenv = func(key string) string {
+		return s.env[key]
+	}
+	attestor.hooks.readFile = func(path string) ([]byte, error) {
+		s.path = path
+		return s.output, s.err
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *NomadAttestorSuite) configure(config string) {
+	_, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: config,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{
+			TrustDomain: "example.org",
+		},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *NomadAttestorSuite) requireFetchSuccess(token string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+
+	// assert attestation data
+	s.Require().Equal("spiffe://example.org/spire/agent/nomad/FOO/ALLOCATION", resp.SpiffeId)
+	s.Require().NotNil(resp.AttestationData)
+	s.Require().Equal("nomad", resp.AttestationData.Type)
+	s.Require().JSONEq(fmt.Sprintf(`{"cluster": "FOO", "token": %q}`, token), string(resp.AttestationData.Data))
+
+	// node attestor should return EOF now
+	_, err = stream.Recv()
+	s.Require().Equal(io.EOF, err)
+}
+
+func (s *NomadAttestorSuite) requireFetchError(contains string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *NomadAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func (s *NomadAttestorSuite) makeToken(allocationID string) string {
+	claims := &nomad.WorkloadIdentityClaims{
+		Namespace:    "default",
+		JobID:        "spire-agent",
+		AllocationID: allocationID,
+		Task:         "agent",
+	}
+
+	signingKey := jose.SigningKey{Algorithm: jose.HS256, Key: []byte("KEY")}
+	signer, err := jose.NewSigner(signingKey, nil)
+	s.Require().NoError(err)
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	s.Require().NoError(err)
+	return token
+}
@@ -0,0 +1,166 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/oci"
+	"github.com/spiffe/spire/pkg/common/plugin/x509pop"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+var (
+	ociError = errs.Class("oci-instance-principal")
+)
+
+type InstanceAttestorConfig struct {
+	trustDomain string
+	MetadataURL string `hcl:"metadata_url"`
+}
+
+type InstanceAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *InstanceAttestorConfig
+
+	hooks struct {
+		fetchInstanceCredentials func(ctx context.Context, metadataURL string) (*oci.InstanceCredentials, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*InstanceAttestorPlugin)(nil)
+
+func New() *InstanceAttestorPlugin {
+	p := &InstanceAttestorPlugin{}
+	p.hooks.fetchInstanceCredentials = func(ctx context.Context, metadataURL string) (*oci.InstanceCredentials, error) {
+		return oci.FetchInstanceCredentials(ctx, http.DefaultClient, metadataURL)
+	}
+	return p
+}
+
+func (p *InstanceAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	creds, err := p.hooks.fetchInstanceCredentials(stream.Context(), config.MetadataURL)
+	if err != nil {
+		return ociError.New("unable to fetch instance principal credentials: %v", err)
+	}
+
+	leaf, err := pemutil.ParseCertificate(creds.Certificate)
+	if err != nil {
+		return ociError.New("unable to parse instance certificate: %v", err)
+	}
+	intermediates, err := pemutil.ParseCertificates(creds.Intermediate)
+	if err != nil {
+		return ociError.New("unable to parse intermediate certificates: %v", err)
+	}
+	privateKey, err := pemutil.ParsePrivateKey(creds.PrivateKey)
+	if err != nil {
+		return ociError.New("unable to parse instance private key: %v", err)
+	}
+
+	identity, err := oci.ParseInstanceIdentity(leaf)
+	if err != nil {
+		return ociError.Wrap(err)
+	}
+
+	certificates := [][]byte{leaf.Raw}
+	for _, intermediate := range intermediates {
+		certificates = append(certificates, intermediate.Raw)
+	}
+
+	data, err := json.Marshal(oci.AttestationData{
+		Certificates: certificates,
+		Region:       creds.Region,
+	})
+	if err != nil {
+		return ociError.Wrap(err)
+	}
+
+	spiffeID := oci.AgentID(config.trustDomain, identity)
+	if err := stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: oci.PluginName,
+			Data: data,
+		},
+		SpiffeId: spiffeID,
+	}); err != nil {
+		return err
+	}
+
+	// prove possession of the instance principal private key
+	resp, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	challenge := new(x509pop.Challenge)
+	if err := json.Unmarshal(resp.Challenge, challenge); err != nil {
+		return ociError.New("unable to unmarshal challenge: %v", err)
+	}
+
+	response, err := x509pop.CalculateResponse(privateKey, challenge)
+	if err != nil {
+		return ociError.New("unable to calculate challenge response: %v", err)
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		return ociError.New("unable to marshal challenge response: %v", err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		SpiffeId: spiffeID,
+		Response: responseBytes,
+	})
+}
+
+func (p *InstanceAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(InstanceAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, ociError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, ociError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, ociError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.MetadataURL == "" {
+		config.MetadataURL = oci.DefaultMetadataURL
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *InstanceAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *InstanceAttestorPlugin) getConfig() (*InstanceAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, ociError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *InstanceAttestorPlugin) setConfig(config *InstanceAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
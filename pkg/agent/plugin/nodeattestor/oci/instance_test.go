@@ -0,0 +1,200 @@
+package oci
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/oci"
+	"github.com/spiffe/spire/pkg/common/plugin/x509pop"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/test/fakes/fakeoci"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestInstanceAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(InstanceAttestorSuite))
+}
+
+type InstanceAttestorSuite struct {
+	suite.Suite
+
+	ca       *fakeoci.CA
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	creds    *oci.InstanceCredentials
+	fetchErr error
+	attestor *nodeattestor.BuiltIn
+}
+
+func (s *InstanceAttestorSuite) SetupTest() {
+	s.ca = fakeoci.New(s.T())
+	s.cert, s.key = s.ca.IssueInstanceCertificate(
+		"opc-instance:ocid1.instance.oc1.iad.instance",
+		"opc-compartment:ocid1.compartment.oc1..compartment",
+		"opc-tenant:ocid1.tenancy.oc1..tenancy",
+	)
+	keyBytes, err := pemutil.EncodePKCS8PrivateKey(s.key)
+	s.Require().NoError(err)
+	s.creds = &oci.InstanceCredentials{
+		Certificate:  pemutil.EncodeCertificate(s.cert),
+		Intermediate: pemutil.EncodeCertificate(s.ca.Intermediate),
+		PrivateKey:   keyBytes,
+		Region:       "us-ashburn-1",
+	}
+	s.fetchErr = nil
+
+	s.newAttestor()
+	s.configureAttestor()
+}
+
+func (s *InstanceAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	s.requireFetchError("oci-instance-principal: not configured")
+}
+
+func (s *InstanceAttestorSuite) TestFetchAttestationDataFetchFailure() {
+	s.fetchErr = errors.New("oh no")
+	s.requireFetchError("oci-instance-principal: unable to fetch instance principal credentials: oh no")
+}
+
+func (s *InstanceAttestorSuite) TestFetchAttestationDataMalformedCredentials() {
+	s.creds.Certificate = []byte("blah")
+	s.requireFetchError("oci-instance-principal: unable to parse instance certificate")
+
+	s.creds.Certificate = pemutil.EncodeCertificate(s.cert)
+	s.creds.PrivateKey = []byte("blah")
+	s.requireFetchError("oci-instance-principal: unable to parse instance private key")
+}
+
+func (s *InstanceAttestorSuite) TestFetchAttestationDataIncompleteIdentity() {
+	cert, _ := s.ca.IssueInstanceCertificate("opc-instance:ocid1.instance.oc1.iad.instance")
+	s.creds.Certificate = pemutil.EncodeCertificate(cert)
+	s.requireFetchError("oci-instance-principal: certificate missing compartment ID")
+}
+
+func (s *InstanceAttestorSuite) TestFetchAttestationDataBadChallenge() {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+
+	_, err = stream.Recv()
+	s.Require().NoError(err)
+
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: []byte("{"),
+	}))
+
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, "oci-instance-principal: unable to unmarshal challenge")
+	s.Require().Nil(resp)
+}
+
+func (s *InstanceAttestorSuite) TestFetchAttestationDataSuccess() {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+
+	spiffeID := "spiffe://example.org/spire/agent/oci_instance_principal/ocid1.tenancy.oc1..tenancy/ocid1.instance.oc1.iad.instance"
+
+	// first response has the spiffeid and attestation data
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().Equal(spiffeID, resp.SpiffeId)
+	s.Require().Equal("oci_instance_principal", resp.AttestationData.Type)
+	expected, err := json.Marshal(oci.AttestationData{
+		Certificates: [][]byte{s.cert.Raw, s.ca.Intermediate.Raw},
+		Region:       "us-ashburn-1",
+	})
+	s.Require().NoError(err)
+	s.Require().JSONEq(string(expected), string(resp.AttestationData.Data))
+
+	// send a challenge
+	challenge, err := x509pop.GenerateChallenge(s.cert)
+	s.Require().NoError(err)
+	challengeBytes, err := json.Marshal(challenge)
+	s.Require().NoError(err)
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: challengeBytes,
+	}))
+
+	// verify the response
+	resp, err = stream.Recv()
+	s.Require().NoError(err)
+	s.Require().Equal(spiffeID, resp.SpiffeId)
+	s.Require().Nil(resp.AttestationData)
+	response := new(x509pop.Response)
+	s.Require().NoError(json.Unmarshal(resp.Response, response))
+	s.Require().NoError(x509pop.VerifyChallengeResponse(s.cert.PublicKey, challenge, response))
+
+	// node attestor should return EOF now
+	_, err = stream.Recv()
+	s.Require().Equal(io.EOF, err)
+}
+
+func (s *InstanceAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "oci-instance-principal: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "oci-instance-principal: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "oci-instance-principal: global configuration missing trust domain")
+	s.Require().Nil(resp)
+}
+
+func (s *InstanceAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *InstanceAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.fetchInstanceCredentials = func(ctx context.Context, metadataURL string) (*oci.InstanceCredentials, error) {
+		if metadataURL != "http://169.254.169.254/opc/v2" {
+			return nil, fmt.Errorf("unexpected metadata URL %q", metadataURL)
+		}
+		if s.fetchErr != nil {
+			return nil, s.fetchErr
+		}
+		return s.creds, nil
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *InstanceAttestorSuite) configureAttestor() {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *InstanceAttestorSuite) requireFetchError(contains string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *InstanceAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
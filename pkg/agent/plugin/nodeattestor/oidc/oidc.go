@@ -0,0 +1,170 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/oidc"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	oidcError = errs.Class("oidc")
+)
+
+type OIDCAttestorConfig struct {
+	trustDomain string
+
+	// TokenPath is the path of a file containing the token. The file is
+	// read on every attestation, so it may be refreshed by the platform.
+	TokenPath string `hcl:"token_path"`
+
+	// TokenCommand is a command, and its arguments, that prints the token
+	// to stdout.
+	TokenCommand []string `hcl:"token_command"`
+}
+
+type OIDCAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *OIDCAttestorConfig
+
+	hooks struct {
+		readFile   func(path string) ([]byte, error)
+		runCommand func(ctx context.Context, args []string) ([]byte, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*OIDCAttestorPlugin)(nil)
+
+func New() *OIDCAttestorPlugin {
+	p := &OIDCAttestorPlugin{}
+	p.hooks.readFile = ioutil.ReadFile
+	p.hooks.runCommand = func(ctx context.Context, args []string) ([]byte, error) {
+		return exec.CommandContext(ctx, args[0], args[1:]...).Output()
+	}
+	return p
+}
+
+func (p *OIDCAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	token, err := p.fetchToken(stream.Context(), config)
+	if err != nil {
+		return err
+	}
+
+	agentID, err := getUnverifiedAgentID(config.trustDomain, token)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(oidc.AttestationData{
+		Token: token,
+	})
+	if err != nil {
+		return oidcError.Wrap(err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: oidc.PluginName,
+			Data: data,
+		},
+		SpiffeId: agentID,
+	})
+}
+
+func (p *OIDCAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(OIDCAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, oidcError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, oidcError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, oidcError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if (config.TokenPath == "") == (len(config.TokenCommand) == 0) {
+		return nil, oidcError.New("exactly one of token_path or token_command is required")
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *OIDCAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *OIDCAttestorPlugin) getConfig() (*OIDCAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, oidcError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *OIDCAttestorPlugin) setConfig(config *OIDCAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func (p *OIDCAttestorPlugin) fetchToken(ctx context.Context, config *OIDCAttestorConfig) (string, error) {
+	var out []byte
+	var err error
+	if config.TokenPath != "" {
+		out, err = p.hooks.readFile(config.TokenPath)
+		if err != nil {
+			return "", oidcError.New("unable to read token file: %v", err)
+		}
+	} else {
+		out, err = p.hooks.runCommand(ctx, config.TokenCommand)
+		if err != nil {
+			return "", oidcError.New("unable to run token command: %v", err)
+		}
+	}
+
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", oidcError.New("token is empty")
+	}
+	return token, nil
+}
+
+// getUnverifiedAgentID builds the agent ID from the token claims. The token
+// is verified by the server.
+func getUnverifiedAgentID(trustDomain, rawToken string) (string, error) {
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return "", oidcError.New("unable to parse token: %v", err)
+	}
+
+	claims := new(jwt.Claims)
+	if err := token.UnsafeClaimsWithoutVerification(claims); err != nil {
+		return "", oidcError.New("unable to parse token claims: %v", err)
+	}
+
+	agentID, err := oidc.AgentID(trustDomain, claims.Subject, claims.ID)
+	if err != nil {
+		return "", oidcError.Wrap(err)
+	}
+	return agentID, nil
+}
@@ -0,0 +1,195 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestOIDCAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(OIDCAttestorSuite))
+}
+
+type OIDCAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+
+	output []byte
+	err    error
+	path   string
+	args   []string
+}
+
+func (s *OIDCAttestorSuite) SetupTest() {
+	s.output = []byte(s.makeToken("SUBJECT", "TOKENID") + "\n")
+	s.err = nil
+	s.path = ""
+	s.args = nil
+
+	s.newAttestor()
+	s.configure(`token_path = "/var/run/secrets/token"`)
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	s.requireFetchError("oidc: not configured")
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataFailedToReadFile() {
+	s.err = errors.New("FAILED")
+	s.requireFetchError("oidc: unable to read token file: FAILED")
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataFailedToRunCommand() {
+	s.configure(`token_command = ["print-token", "--audience", "spire-server"]`)
+	s.err = errors.New("FAILED")
+	s.requireFetchError("oidc: unable to run token command: FAILED")
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataEmptyToken() {
+	s.output = []byte("\n")
+	s.requireFetchError("oidc: token is empty")
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataMalformedToken() {
+	s.output = []byte("blah")
+	s.requireFetchError("oidc: unable to parse token")
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataBadSubject() {
+	s.output = []byte(s.makeToken("../../server", ""))
+	s.requireFetchError(`oidc: subject "../../server" cannot be used in an agent ID`)
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataFromFile() {
+	s.requireFetchSuccess()
+	s.Require().Equal("/var/run/secrets/token", s.path)
+}
+
+func (s *OIDCAttestorSuite) TestFetchAttestationDataFromCommand() {
+	s.configure(`token_command = ["print-token", "--audience", "spire-server"]`)
+	s.requireFetchSuccess()
+	s.Require().Equal([]string{"print-token", "--audience", "spire-server"}, s.args)
+}
+
+func (s *OIDCAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "oidc: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "oidc: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "oidc: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// neither token source
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "oidc: exactly one of token_path or token_command is required")
+	s.Require().Nil(resp)
+
+	// both token sources
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `
+		token_path = "/var/run/secrets/token"
+		token_command = ["print-token"]
+		`,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "oidc: exactly one of token_path or token_command is required")
+	s.Require().Nil(resp)
+}
+
+func (s *OIDCAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *OIDCAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.readFile = func(path string) ([]byte, error) {
+		s.path = path
+		return s.output, s.err
+	}
+	attestor.hooks.runCommand = func(ctx context.Context, args []string) ([]byte, error) {
+		s.args = args
+		return s.output, s.err
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *OIDCAttestorSuite) configure(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: config,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *OIDCAttestorSuite) requireFetchSuccess() {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+
+	// assert attestation data
+	s.Require().Equal("spiffe://example.org/spire/agent/oidc/SUBJECT/TOKENID", resp.SpiffeId)
+	s.Require().NotNil(resp.AttestationData)
+	s.Require().Equal("oidc", resp.AttestationData.Type)
+	s.Require().JSONEq(fmt.Sprintf(`{"token": %q}`, s.makeToken("SUBJECT", "TOKENID")), string(resp.AttestationData.Data))
+
+	// node attestor should return EOF now
+	_, err = stream.Recv()
+	s.Require().Equal(io.EOF, err)
+}
+
+func (s *OIDCAttestorSuite) requireFetchError(contains string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *OIDCAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func (s *OIDCAttestorSuite) makeToken(subject, tokenID string) string {
+	signingKey := jose.SigningKey{Algorithm: jose.HS256, Key: []byte("KEY")}
+	signer, err := jose.NewSigner(signingKey, nil)
+	s.Require().NoError(err)
+
+	token, err := jwt.Signed(signer).Claims(jwt.Claims{
+		Subject: subject,
+		ID:      tokenID,
+	}).CompactSerialize()
+	s.Require().NoError(err)
+	return token
+}
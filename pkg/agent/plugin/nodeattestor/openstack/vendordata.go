@@ -0,0 +1,145 @@
+package openstack
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/openstack"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	openstackError = errs.Class("openstack")
+)
+
+type VendordataAttestorConfig struct {
+	trustDomain string
+
+	// VendordataURL is the URL of the dynamic vendordata served by the
+	// metadata service
+	VendordataURL string `hcl:"vendordata_url"`
+
+	// VendordataTarget is the name of the Nova dynamic vendordata target
+	// that provides the signed token
+	VendordataTarget string `hcl:"vendordata_target"`
+}
+
+type VendordataAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *VendordataAttestorConfig
+
+	hooks struct {
+		fetchVendordataToken func(ctx context.Context, vendordataURL, target string) (string, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*VendordataAttestorPlugin)(nil)
+
+func New() *VendordataAttestorPlugin {
+	p := &VendordataAttestorPlugin{}
+	p.hooks.fetchVendordataToken = openstack.FetchVendordataToken
+	return p
+}
+
+func (p *VendordataAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	token, err := p.hooks.fetchVendordataToken(stream.Context(), config.VendordataURL, config.VendordataTarget)
+	if err != nil {
+		return openstackError.New("unable to fetch vendordata token: %v", err)
+	}
+
+	claims, err := getUnverifiedVendordataClaims(token)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(openstack.AttestationData{
+		Token: token,
+	})
+	if err != nil {
+		return openstackError.Wrap(err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: openstack.PluginName,
+			Data: data,
+		},
+		SpiffeId: claims.AgentID(config.trustDomain),
+	})
+}
+
+func (p *VendordataAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(VendordataAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, openstackError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, openstackError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, openstackError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.VendordataURL == "" {
+		config.VendordataURL = openstack.DefaultVendordataURL
+	}
+	if config.VendordataTarget == "" {
+		config.VendordataTarget = openstack.DefaultVendordataTarget
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *VendordataAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *VendordataAttestorPlugin) getConfig() (*VendordataAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, openstackError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *VendordataAttestorPlugin) setConfig(config *VendordataAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func getUnverifiedVendordataClaims(rawToken string) (*openstack.VendordataClaims, error) {
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return nil, openstackError.New("unable to parse token: %v", err)
+	}
+
+	claims := new(openstack.VendordataClaims)
+	if err := token.UnsafeClaimsWithoutVerification(claims); err != nil {
+		return nil, openstackError.New("unable to parse token claims: %v", err)
+	}
+
+	switch {
+	case claims.ProjectID == "":
+		return nil, openstackError.New("token missing project ID claim")
+	case claims.InstanceID == "":
+		return nil, openstackError.New("token missing instance ID claim")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,186 @@
+package openstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/common/plugin/openstack"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestVendordataAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(VendordataAttestorSuite))
+}
+
+type VendordataAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+
+	expectedURL    string
+	expectedTarget string
+	token          string
+	tokenErr       error
+}
+
+func (s *VendordataAttestorSuite) SetupTest() {
+	s.expectedURL = openstack.DefaultVendordataURL
+	s.expectedTarget = openstack.DefaultVendordataTarget
+	s.token = ""
+	s.tokenErr = nil
+
+	s.newAttestor()
+
+	_, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{
+			TrustDomain: "example.org",
+		},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *VendordataAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	s.requireFetchError("openstack: not configured")
+}
+
+func (s *VendordataAttestorSuite) TestFetchAttestationDataFailedToObtainToken() {
+	s.tokenErr = errors.New("FAILED")
+	s.requireFetchError("openstack: unable to fetch vendordata token: FAILED")
+}
+
+func (s *VendordataAttestorSuite) TestFetchAttestationDataTokenMalformed() {
+	s.token = ""
+	s.requireFetchError("openstack: unable to parse token")
+}
+
+func (s *VendordataAttestorSuite) TestFetchAttestationDataTokenHasBadClaims() {
+	s.token = "e30.f32.baadf00d"
+	s.requireFetchError("openstack: unable to parse token claims")
+}
+
+func (s *VendordataAttestorSuite) TestFetchAttestationDataTokenMissingClaims() {
+	s.token = s.makeToken("", "INSTANCEID")
+	s.requireFetchError("openstack: token missing project ID claim")
+
+	s.token = s.makeToken("PROJECTID", "")
+	s.requireFetchError("openstack: token missing instance ID claim")
+}
+
+func (s *VendordataAttestorSuite) TestFetchAttestationDataSuccess() {
+	s.token = s.makeToken("PROJECTID", "INSTANCEID")
+
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+
+	// assert attestation data
+	s.Require().Equal("spiffe://example.org/spire/agent/openstack/PROJECTID/INSTANCEID", resp.SpiffeId)
+	s.Require().NotNil(resp.AttestationData)
+	s.Require().Equal("openstack", resp.AttestationData.Type)
+	s.Require().JSONEq(fmt.Sprintf(`{"token": %q}`, s.token), string(resp.AttestationData.Data))
+
+	// node attestor should return EOF now
+	_, err = stream.Recv()
+	s.Require().Equal(io.EOF, err)
+}
+
+func (s *VendordataAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "openstack: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "openstack: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "openstack: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// success with a custom URL and target
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `
+		vendordata_url = "http://metadata.example.org/openstack/latest/vendor_data2.json"
+		vendordata_target = "TARGET"
+		`,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+
+	s.expectedURL = "http://metadata.example.org/openstack/latest/vendor_data2.json"
+	s.expectedTarget = "TARGET"
+	s.token = s.makeToken("PROJECTID", "INSTANCEID")
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Require().NoError(err)
+}
+
+func (s *VendordataAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *VendordataAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.fetchVendordataToken = func(ctx context.Context, vendordataURL, target string) (string, error) {
+		if vendordataURL != s.expectedURL {
+			return "", fmt.Errorf("expected vendordata URL %s; got %s", s.expectedURL, vendordataURL)
+		}
+		if target != s.expectedTarget {
+			return "", fmt.Errorf("expected target %s; got %s", s.expectedTarget, target)
+		}
+		return s.token, s.tokenErr
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *VendordataAttestorSuite) requireFetchError(contains string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *VendordataAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func (s *VendordataAttestorSuite) makeToken(projectID, instanceID string) string {
+	claims := openstack.VendordataClaims{
+		ProjectID:  projectID,
+		InstanceID: instanceID,
+		Flavor:     "m1.small",
+	}
+
+	signingKey := jose.SigningKey{Algorithm: jose.HS256, Key: []byte("KEY")}
+	signer, err := jose.NewSigner(signingKey, nil)
+	s.Require().NoError(err)
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	s.Require().NoError(err)
+	return token
+}
@@ -0,0 +1,71 @@
+package sevsnp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	certTableEntrySize = 24
+)
+
+var (
+	// GUIDs identifying the certificates in the host provided certificate
+	// table, as defined by the GHCB specification
+	guidVCEK = mustDecodeGUID("63da758de6644564adc5f4b93be8accd")
+	guidASK  = mustDecodeGUID("4ab7b379bbac4fe4a02f05aef327c782")
+)
+
+// parseCertTable returns the DER encoded VCEK and ASK certificates held by
+// the certificate table, in that order. The table is a list of entries, each
+// holding a GUID followed by the offset and length of the certificate,
+// terminated by an all-zero entry.
+func parseCertTable(table []byte) ([][]byte, error) {
+	var vcek, ask []byte
+	for off := 0; ; off += certTableEntrySize {
+		if off+certTableEntrySize > len(table) {
+			if off == 0 {
+				// no table was provided
+				return nil, nil
+			}
+			return nil, errs.New("certificate table is not terminated")
+		}
+		entry := table[off : off+certTableEntrySize]
+		guid := entry[:16]
+		certOff := binary.LittleEndian.Uint32(entry[16:])
+		certLen := binary.LittleEndian.Uint32(entry[20:])
+		if bytes.Equal(entry, make([]byte, certTableEntrySize)) {
+			break
+		}
+		if uint64(certOff)+uint64(certLen) > uint64(len(table)) {
+			return nil, errs.New("certificate table entry out of bounds")
+		}
+		cert := table[certOff : certOff+certLen]
+		switch {
+		case bytes.Equal(guid, guidVCEK):
+			vcek = cert
+		case bytes.Equal(guid, guidASK):
+			ask = cert
+		}
+	}
+
+	if vcek == nil {
+		return nil, nil
+	}
+	certs := [][]byte{vcek}
+	if ask != nil {
+		certs = append(certs, ask)
+	}
+	return certs, nil
+}
+
+func mustDecodeGUID(s string) []byte {
+	guid, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return guid
+}
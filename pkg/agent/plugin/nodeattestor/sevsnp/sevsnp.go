@@ -0,0 +1,214 @@
+package sevsnp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/sevsnp"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+const (
+	defaultTSMReportPath = "/sys/kernel/config/tsm/report"
+)
+
+var (
+	sevsnpError = errs.Class("sev-snp")
+)
+
+type SEVSNPAttestorConfig struct {
+	trustDomain string
+
+	// TSMReportPath is the configfs-tsm directory used to request reports
+	TSMReportPath string `hcl:"tsm_report_path"`
+
+	// CertChainPath, if set, is the path to the PEM encoded VCEK
+	// certificate followed by the ASK. Otherwise the certificates are taken
+	// from those provided by the host alongside the report.
+	CertChainPath string `hcl:"cert_chain_path"`
+}
+
+type SEVSNPAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *SEVSNPAttestorConfig
+
+	hooks struct {
+		getReport func(tsmReportPath string, reportData [64]byte) (report, auxBlob []byte, err error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*SEVSNPAttestorPlugin)(nil)
+
+func New() *SEVSNPAttestorPlugin {
+	p := &SEVSNPAttestorPlugin{}
+	p.hooks.getReport = getReport
+	return p
+}
+
+func (p *SEVSNPAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	// The agent ID is derived from the report ID assigned to the guest at
+	// launch. Obtain a report up front to learn it.
+	reportBytes, _, err := p.hooks.getReport(config.TSMReportPath, [64]byte{})
+	if err != nil {
+		return sevsnpError.New("unable to obtain report: %v", err)
+	}
+	report, err := sevsnp.ParseReport(reportBytes)
+	if err != nil {
+		return sevsnpError.New("unable to parse report: %v", err)
+	}
+	spiffeID := sevsnp.AgentID(config.trustDomain, report)
+
+	data, err := json.Marshal(sevsnp.AttestationData{})
+	if err != nil {
+		return sevsnpError.Wrap(err)
+	}
+
+	if err := stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: sevsnp.PluginName,
+			Data: data,
+		},
+		SpiffeId: spiffeID,
+	}); err != nil {
+		return err
+	}
+
+	// receive the challenge and answer with a report binding the nonce
+	resp, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	challenge := new(sevsnp.Challenge)
+	if err := json.Unmarshal(resp.Challenge, challenge); err != nil {
+		return sevsnpError.New("unable to unmarshal challenge: %v", err)
+	}
+
+	reportBytes, auxBlob, err := p.hooks.getReport(config.TSMReportPath, sevsnp.ReportDataForNonce(challenge.Nonce))
+	if err != nil {
+		return sevsnpError.New("unable to obtain report: %v", err)
+	}
+
+	certificates, err := loadCertificates(config, auxBlob)
+	if err != nil {
+		return err
+	}
+
+	responseBytes, err := json.Marshal(sevsnp.Response{
+		Report:       reportBytes,
+		Certificates: certificates,
+	})
+	if err != nil {
+		return sevsnpError.New("unable to marshal challenge response: %v", err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		SpiffeId: spiffeID,
+		Response: responseBytes,
+	})
+}
+
+func (p *SEVSNPAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(SEVSNPAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, sevsnpError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, sevsnpError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, sevsnpError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.TSMReportPath == "" {
+		config.TSMReportPath = defaultTSMReportPath
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *SEVSNPAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *SEVSNPAttestorPlugin) getConfig() (*SEVSNPAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, sevsnpError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *SEVSNPAttestorPlugin) setConfig(config *SEVSNPAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+// loadCertificates returns the DER encoded VCEK and ASK certificates, either
+// from the configured certificate chain or from the certificate table
+// provided by the host
+func loadCertificates(config *SEVSNPAttestorConfig, auxBlob []byte) ([][]byte, error) {
+	if config.CertChainPath != "" {
+		certs, err := pemutil.LoadCertificates(config.CertChainPath)
+		if err != nil {
+			return nil, sevsnpError.New("unable to load certificate chain: %v", err)
+		}
+		var certificates [][]byte
+		for _, cert := range certs {
+			certificates = append(certificates, cert.Raw)
+		}
+		return certificates, nil
+	}
+
+	certificates, err := parseCertTable(auxBlob)
+	if err != nil {
+		return nil, sevsnpError.New("unable to parse certificate table: %v", err)
+	}
+	if len(certificates) == 0 {
+		return nil, sevsnpError.New("host did not provide a VCEK certificate; configure cert_chain_path")
+	}
+	return certificates, nil
+}
+
+// getReport requests a report through configfs-tsm. A report entry is
+// created, the report data written to inblob and the report read back from
+// outblob. The host provided certificate table, if any, is read from auxblob.
+func getReport(tsmReportPath string, reportData [64]byte) ([]byte, []byte, error) {
+	entry, err := ioutil.TempDir(tsmReportPath, "spire-")
+	if err != nil {
+		return nil, nil, errs.Wrap(err)
+	}
+	defer os.Remove(entry)
+
+	if err := ioutil.WriteFile(filepath.Join(entry, "inblob"), reportData[:], 0); err != nil {
+		return nil, nil, errs.Wrap(err)
+	}
+	report, err := ioutil.ReadFile(filepath.Join(entry, "outblob"))
+	if err != nil {
+		return nil, nil, errs.Wrap(err)
+	}
+	auxBlob, err := ioutil.ReadFile(filepath.Join(entry, "auxblob"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, errs.Wrap(err)
+	}
+	return report, auxBlob, nil
+}
@@ -0,0 +1,275 @@
+package sevsnp
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/sevsnp"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/test/fakes/fakesevsnp"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	reportID = bytes.Repeat([]byte{0x02}, 32)
+)
+
+func TestSEVSNPAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(SEVSNPAttestorSuite))
+}
+
+type SEVSNPAttestorSuite struct {
+	suite.Suite
+
+	dir      string
+	attestor *nodeattestor.BuiltIn
+	sp       *fakesevsnp.SecureProcessor
+
+	tsmReportPath string
+	reportErr     error
+	report        []byte
+	auxBlob       []byte
+}
+
+func (s *SEVSNPAttestorSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "sevsnp-test")
+	s.Require().NoError(err)
+
+	s.sp = fakesevsnp.New(s.T())
+	s.tsmReportPath = "/sys/kernel/config/tsm/report"
+	s.reportErr = nil
+	s.report = nil
+	s.auxBlob = makeCertTable(s.sp.VCEK.Raw, s.sp.ASK.Raw)
+
+	s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *SEVSNPAttestorSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *SEVSNPAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().EqualError(err, "sev-snp: not configured")
+}
+
+func (s *SEVSNPAttestorSuite) TestFetchAttestationDataReportFails() {
+	s.reportErr = errors.New("oh no")
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().EqualError(err, "sev-snp: unable to obtain report: oh no")
+
+	s.reportErr = nil
+	s.report = []byte("blah")
+	stream = s.fetchAttestationData()
+	_, err = stream.Recv()
+	s.Require().EqualError(err, "sev-snp: unable to parse report: unexpected report size 4")
+}
+
+func (s *SEVSNPAttestorSuite) TestFetchAttestationDataBadChallenge() {
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().NoError(err)
+
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: []byte("{"),
+	}))
+	_, err = stream.Recv()
+	s.requireErrorContains(err, "sev-snp: unable to unmarshal challenge")
+}
+
+func (s *SEVSNPAttestorSuite) TestFetchAttestationDataMissingCertificates() {
+	s.auxBlob = nil
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().NoError(err)
+
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: s.marshal(sevsnp.Challenge{Nonce: []byte("NONCE")}),
+	}))
+	_, err = stream.Recv()
+	s.Require().EqualError(err, "sev-snp: host did not provide a VCEK certificate; configure cert_chain_path")
+}
+
+func (s *SEVSNPAttestorSuite) TestFetchAttestationDataSuccess() {
+	s.configureAttestor(`tsm_report_path = "/other/report"`)
+	s.tsmReportPath = "/other/report"
+
+	s.requireFetchAttestationDataSuccess()
+}
+
+func (s *SEVSNPAttestorSuite) TestFetchAttestationDataWithCertChainPath() {
+	certChainPath := filepath.Join(s.dir, "vcek.pem")
+	s.Require().NoError(ioutil.WriteFile(certChainPath, pemutil.EncodeCertificates([]*x509.Certificate{s.sp.VCEK, s.sp.ASK}), 0644))
+	s.configureAttestor(fmt.Sprintf("cert_chain_path = %q", certChainPath))
+	s.auxBlob = nil
+
+	s.requireFetchAttestationDataSuccess()
+}
+
+func (s *SEVSNPAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "sev-snp: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "sev-snp: global configuration is required")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "sev-snp: global configuration missing trust domain")
+	s.Require().Nil(resp)
+}
+
+func (s *SEVSNPAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *SEVSNPAttestorSuite) requireFetchAttestationDataSuccess() {
+	stream := s.fetchAttestationData()
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	spiffeID := fmt.Sprintf("spiffe://example.org/spire/agent/sev_snp/%x", reportID)
+	s.Require().Equal(spiffeID, resp.SpiffeId)
+	s.Require().Equal("sev_snp", resp.AttestationData.Type)
+	s.Require().JSONEq(`{}`, string(resp.AttestationData.Data))
+
+	// the challenge is answered with a report binding the nonce
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: s.marshal(sevsnp.Challenge{Nonce: []byte("NONCE")}),
+	}))
+	resp, err = stream.Recv()
+	s.Require().NoError(err)
+	s.Require().Equal(spiffeID, resp.SpiffeId)
+
+	response := new(sevsnp.Response)
+	s.Require().NoError(json.Unmarshal(resp.Response, response))
+	report, err := sevsnp.VerifyReport(response.Report, response.Certificates, s.sp.Roots(), time.Now())
+	s.Require().NoError(err)
+	s.Require().Equal(sevsnp.ReportDataForNonce([]byte("NONCE")), report.ReportData)
+}
+
+func (s *SEVSNPAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.getReport = func(tsmReportPath string, reportData [64]byte) ([]byte, []byte, error) {
+		if tsmReportPath != s.tsmReportPath {
+			return nil, nil, errors.New("unexpected report path " + tsmReportPath)
+		}
+		if s.reportErr != nil {
+			return nil, nil, s.reportErr
+		}
+		if s.report != nil {
+			return s.report, s.auxBlob, nil
+		}
+		report := sevsnp.Report{ReportData: reportData}
+		copy(report.ReportID[:], reportID)
+		return s.sp.Report(report), s.auxBlob, nil
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *SEVSNPAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: config,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *SEVSNPAttestorSuite) fetchAttestationData() nodeattestor.FetchAttestationData_Stream {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	return stream
+}
+
+func (s *SEVSNPAttestorSuite) marshal(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	s.Require().NoError(err)
+	return data
+}
+
+func (s *SEVSNPAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func TestParseCertTable(t *testing.T) {
+	// no table
+	certs, err := parseCertTable(nil)
+	require.NoError(t, err)
+	require.Nil(t, certs)
+
+	// VCEK and ASK, with an unrecognized entry
+	table := makeCertTable([]byte("VCEK"), []byte("ASK"))
+	certs, err = parseCertTable(table)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("VCEK"), []byte("ASK")}, certs)
+
+	// unterminated table
+	unterminated := make([]byte, certTableEntrySize)
+	copy(unterminated, guidVCEK)
+	binary.LittleEndian.PutUint32(unterminated[20:], certTableEntrySize)
+	_, err = parseCertTable(unterminated)
+	require.EqualError(t, err, "certificate table is not terminated")
+
+	// entry out of bounds
+	binary.LittleEndian.PutUint32(table[20:], 1000)
+	_, err = parseCertTable(table)
+	require.EqualError(t, err, "certificate table entry out of bounds")
+}
+
+func TestGetReport(t *testing.T) {
+	_, _, err := getReport("/does/not/exist", [64]byte{})
+	require.Error(t, err)
+}
+
+// makeCertTable builds a certificate table holding the VCEK, the ASK and an
+// entry with an unrecognized GUID
+func makeCertTable(vcek, ask []byte) []byte {
+	other := bytes.Repeat([]byte{0xaa}, 16)
+	entries := []struct {
+		guid []byte
+		cert []byte
+	}{
+		{guidVCEK, vcek},
+		{other, []byte("OTHER")},
+		{guidASK, ask},
+	}
+
+	off := (len(entries) + 1) * certTableEntrySize
+	header := new(bytes.Buffer)
+	body := new(bytes.Buffer)
+	for _, entry := range entries {
+		header.Write(entry.guid)
+		binary.Write(header, binary.LittleEndian, uint32(off+body.Len()))
+		binary.Write(header, binary.LittleEndian, uint32(len(entry.cert)))
+		body.Write(entry.cert)
+	}
+	header.Write(make([]byte, certTableEntrySize))
+	return append(header.Bytes(), body.Bytes()...)
+}
@@ -0,0 +1,174 @@
+package sgx
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/sgx"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+const (
+	defaultAttestationPath = "/dev/attestation"
+)
+
+var (
+	sgxError = errs.Class("sgx-dcap")
+)
+
+type SGXAttestorConfig struct {
+	trustDomain string
+
+	// AttestationPath is the directory of the attestation pseudo-files
+	// exposed to the enclave by the library OS
+	AttestationPath string `hcl:"attestation_path"`
+}
+
+type SGXAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *SGXAttestorConfig
+
+	hooks struct {
+		getQuote func(attestationPath string, reportData [64]byte) ([]byte, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*SGXAttestorPlugin)(nil)
+
+func New() *SGXAttestorPlugin {
+	p := &SGXAttestorPlugin{}
+	p.hooks.getQuote = getQuote
+	return p
+}
+
+func (p *SGXAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	// The agent ID is derived from the attestation key of the platform's
+	// quoting enclave. Obtain a quote up front to learn it.
+	quote, err := p.fetchQuote(config, [64]byte{})
+	if err != nil {
+		return err
+	}
+	spiffeID := sgx.AgentID(config.trustDomain, quote)
+
+	data, err := json.Marshal(sgx.AttestationData{})
+	if err != nil {
+		return sgxError.Wrap(err)
+	}
+
+	if err := stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: sgx.PluginName,
+			Data: data,
+		},
+		SpiffeId: spiffeID,
+	}); err != nil {
+		return err
+	}
+
+	// receive the challenge and answer with a quote binding the nonce
+	resp, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	challenge := new(sgx.Challenge)
+	if err := json.Unmarshal(resp.Challenge, challenge); err != nil {
+		return sgxError.New("unable to unmarshal challenge: %v", err)
+	}
+
+	quoteBytes, err := p.hooks.getQuote(config.AttestationPath, sgx.ReportDataForNonce(challenge.Nonce))
+	if err != nil {
+		return sgxError.New("unable to obtain quote: %v", err)
+	}
+
+	responseBytes, err := json.Marshal(sgx.Response{
+		Quote: quoteBytes,
+	})
+	if err != nil {
+		return sgxError.New("unable to marshal challenge response: %v", err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		SpiffeId: spiffeID,
+		Response: responseBytes,
+	})
+}
+
+func (p *SGXAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(SGXAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, sgxError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, sgxError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, sgxError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.AttestationPath == "" {
+		config.AttestationPath = defaultAttestationPath
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *SGXAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *SGXAttestorPlugin) getConfig() (*SGXAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, sgxError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *SGXAttestorPlugin) setConfig(config *SGXAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func (p *SGXAttestorPlugin) fetchQuote(config *SGXAttestorConfig, reportData [64]byte) (*sgx.Quote, error) {
+	quoteBytes, err := p.hooks.getQuote(config.AttestationPath, reportData)
+	if err != nil {
+		return nil, sgxError.New("unable to obtain quote: %v", err)
+	}
+	quote, err := sgx.ParseQuote(quoteBytes)
+	if err != nil {
+		return nil, sgxError.New("unable to parse quote: %v", err)
+	}
+	return quote, nil
+}
+
+// getQuote obtains a quote through the attestation pseudo-files: the report
+// data is written to user_report_data and the quote for it read back from
+// quote.
+func getQuote(attestationPath string, reportData [64]byte) ([]byte, error) {
+	if err := ioutil.WriteFile(filepath.Join(attestationPath, "user_report_data"), reportData[:], 0); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	quote, err := ioutil.ReadFile(filepath.Join(attestationPath, "quote"))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return quote, nil
+}
@@ -0,0 +1,192 @@
+package sgx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/plugin/sgx"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/test/fakes/fakesgx"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestSGXAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(SGXAttestorSuite))
+}
+
+type SGXAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+	qe       *fakesgx.QuotingEnclave
+
+	attestationPath string
+	quoteErr        error
+	quote           []byte
+}
+
+func (s *SGXAttestorSuite) SetupTest() {
+	s.qe = fakesgx.New(s.T())
+	s.attestationPath = "/dev/attestation"
+	s.quoteErr = nil
+	s.quote = nil
+
+	s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *SGXAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().EqualError(err, "sgx-dcap: not configured")
+}
+
+func (s *SGXAttestorSuite) TestFetchAttestationDataQuoteFails() {
+	s.quoteErr = errors.New("oh no")
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().EqualError(err, "sgx-dcap: unable to obtain quote: oh no")
+
+	s.quoteErr = nil
+	s.quote = []byte("blah")
+	stream = s.fetchAttestationData()
+	_, err = stream.Recv()
+	s.Require().EqualError(err, "sgx-dcap: unable to parse quote: quote too short")
+}
+
+func (s *SGXAttestorSuite) TestFetchAttestationDataBadChallenge() {
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().NoError(err)
+
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: []byte("{"),
+	}))
+	_, err = stream.Recv()
+	s.requireErrorContains(err, "sgx-dcap: unable to unmarshal challenge")
+}
+
+func (s *SGXAttestorSuite) TestFetchAttestationDataSuccess() {
+	s.configureAttestor(`attestation_path = "/dev/otherattestation"`)
+	s.attestationPath = "/dev/otherattestation"
+
+	stream := s.fetchAttestationData()
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	spiffeID := "spiffe://example.org/spire/agent/sgx_dcap/" + sgx.AttestationKeyFingerprint(s.qe.AttestationKey())
+	s.Require().Equal(spiffeID, resp.SpiffeId)
+	s.Require().Equal("sgx_dcap", resp.AttestationData.Type)
+	s.Require().JSONEq(`{}`, string(resp.AttestationData.Data))
+
+	// the challenge is answered with a quote binding the nonce
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: s.marshal(sgx.Challenge{Nonce: []byte("NONCE")}),
+	}))
+	resp, err = stream.Recv()
+	s.Require().NoError(err)
+	s.Require().Equal(spiffeID, resp.SpiffeId)
+
+	response := new(sgx.Response)
+	s.Require().NoError(json.Unmarshal(resp.Response, response))
+	quote, err := sgx.VerifyQuote(response.Quote, s.qe.Roots(), time.Now())
+	s.Require().NoError(err)
+	s.Require().Equal(sgx.ReportDataForNonce([]byte("NONCE")), quote.Report.ReportData)
+}
+
+func (s *SGXAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "sgx-dcap: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "sgx-dcap: global configuration is required")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "sgx-dcap: global configuration missing trust domain")
+	s.Require().Nil(resp)
+}
+
+func (s *SGXAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *SGXAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.getQuote = func(attestationPath string, reportData [64]byte) ([]byte, error) {
+		if attestationPath != s.attestationPath {
+			return nil, errors.New("unexpected attestation path " + attestationPath)
+		}
+		if s.quoteErr != nil {
+			return nil, s.quoteErr
+		}
+		if s.quote != nil {
+			return s.quote, nil
+		}
+		return s.qe.Quote(sgx.ReportBody{ReportData: reportData}), nil
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *SGXAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: config,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *SGXAttestorSuite) fetchAttestationData() nodeattestor.FetchAttestationData_Stream {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	return stream
+}
+
+func (s *SGXAttestorSuite) marshal(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	s.Require().NoError(err)
+	return data
+}
+
+func (s *SGXAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func TestGetQuote(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sgx-attestation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// missing pseudo-files
+	_, err = getQuote(filepath.Join(dir, "missing"), [64]byte{})
+	require.Error(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "quote"), []byte("QUOTE"), 0644))
+
+	reportData := sgx.ReportDataForNonce([]byte("NONCE"))
+	quote, err := getQuote(dir, reportData)
+	require.NoError(t, err)
+	require.Equal(t, []byte("QUOTE"), quote)
+
+	written, err := ioutil.ReadFile(filepath.Join(dir, "user_report_data"))
+	require.NoError(t, err)
+	require.Equal(t, reportData[:], written)
+}
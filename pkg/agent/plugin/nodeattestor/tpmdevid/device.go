@@ -0,0 +1,74 @@
+package tpmdevid
+
+import (
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/zeebo/errs"
+)
+
+// device is the set of TPM operations the attestor needs
+type device interface {
+	// Quote quotes the PCRs in the selection with the persistent key at the
+	// given handle, including the nonce as qualifying data.
+	Quote(handle tpmutil.Handle, password string, nonce []byte, sel tpm2.PCRSelection) ([]byte, *tpm2.Signature, error)
+
+	// ReadPCRs returns the values of the PCRs in the selection
+	ReadPCRs(sel tpm2.PCRSelection) (map[int][]byte, error)
+
+	Close() error
+}
+
+// tpmDevice implements device for a TPM 2.0 character device (or resource
+// manager).
+type tpmDevice struct {
+	rw io.ReadWriteCloser
+}
+
+func openDevice(path string) (device, error) {
+	rw, err := openTPM(path)
+	if err != nil {
+		return nil, errs.New("unable to open TPM at %q: %v", path, err)
+	}
+	return &tpmDevice{rw: rw}, nil
+}
+
+func (d *tpmDevice) Quote(handle tpmutil.Handle, password string, nonce []byte, sel tpm2.PCRSelection) ([]byte, *tpm2.Signature, error) {
+	// the null scheme selects the signing scheme of the key
+	quote, sig, err := tpm2.Quote(d.rw, handle, password, "", nonce, sel, tpm2.AlgNull)
+	if err != nil {
+		return nil, nil, errs.New("unable to quote PCRs with handle %#x: %v", uint32(handle), err)
+	}
+	return quote, sig, nil
+}
+
+func (d *tpmDevice) ReadPCRs(sel tpm2.PCRSelection) (map[int][]byte, error) {
+	values := make(map[int][]byte)
+	remaining := sel.PCRs
+	// the TPM may return fewer PCRs than were selected, so keep reading
+	// until all of them have been returned
+	for len(remaining) > 0 {
+		read, err := tpm2.ReadPCRs(d.rw, tpm2.PCRSelection{Hash: sel.Hash, PCRs: remaining})
+		if err != nil {
+			return nil, errs.New("unable to read PCRs: %v", err)
+		}
+		if len(read) == 0 {
+			return nil, errs.New("TPM did not return PCRs %v", remaining)
+		}
+		var next []int
+		for _, pcr := range remaining {
+			if value, ok := read[pcr]; ok {
+				values[pcr] = value
+			} else {
+				next = append(next, pcr)
+			}
+		}
+		remaining = next
+	}
+	return values, nil
+}
+
+func (d *tpmDevice) Close() error {
+	return d.rw.Close()
+}
@@ -0,0 +1,15 @@
+// +build windows
+
+package tpmdevid
+
+import (
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// openTPM opens the TPM through the TPM Base Services. There is no device
+// path on Windows, so the configured path is ignored.
+func openTPM(path string) (io.ReadWriteCloser, error) {
+	return tpm2.OpenTPM()
+}
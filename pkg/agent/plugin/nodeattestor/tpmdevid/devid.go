@@ -0,0 +1,222 @@
+package tpmdevid
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/tpmdevid"
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+const (
+	defaultDevicePath = "/dev/tpmrm0"
+)
+
+var (
+	devidError = errs.Class("tpm-devid")
+
+	// defaultPCRs are the PCRs holding the firmware and boot loader
+	// measurements
+	defaultPCRs = []int{0, 1, 2, 3, 4, 5, 6, 7}
+)
+
+type DevIDAttestorConfig struct {
+	// DevicePath is the path to the TPM device or resource manager
+	DevicePath string `hcl:"device_path"`
+
+	// DevIDCertPath is the path to the PEM encoded DevID certificate,
+	// optionally followed by intermediates
+	DevIDCertPath string `hcl:"devid_cert_path"`
+
+	// DevIDHandle is the persistent handle of the DevID key
+	DevIDHandle int64 `hcl:"devid_handle"`
+
+	// DevIDPassword is the authorization value of the DevID key
+	DevIDPassword string `hcl:"devid_password"`
+
+	// PCRs are the SHA-256 PCRs quoted during attestation
+	PCRs []int `hcl:"pcrs"`
+}
+
+type devidAttestorConfig struct {
+	*DevIDAttestorConfig
+
+	spiffeID     string
+	certificates [][]byte
+}
+
+type DevIDAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *devidAttestorConfig
+
+	hooks struct {
+		openDevice func(path string) (device, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*DevIDAttestorPlugin)(nil)
+
+func New() *DevIDAttestorPlugin {
+	p := &DevIDAttestorPlugin{}
+	p.hooks.openDevice = openDevice
+	return p
+}
+
+func (p *DevIDAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(tpmdevid.AttestationData{
+		Certificates: config.certificates,
+	})
+	if err != nil {
+		return devidError.New("unable to marshal attestation data: %v", err)
+	}
+
+	if err := stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: tpmdevid.PluginName,
+			Data: data,
+		},
+		SpiffeId: config.spiffeID,
+	}); err != nil {
+		return err
+	}
+
+	// receive the challenge and answer with a quote including the nonce
+	resp, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	challenge := new(tpmdevid.Challenge)
+	if err := json.Unmarshal(resp.Challenge, challenge); err != nil {
+		return devidError.New("unable to unmarshal challenge: %v", err)
+	}
+
+	response, err := p.quote(config, challenge.Nonce)
+	if err != nil {
+		return err
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		return devidError.New("unable to marshal challenge response: %v", err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		SpiffeId: config.spiffeID,
+		Response: responseBytes,
+	})
+}
+
+func (p *DevIDAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(DevIDAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, devidError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, devidError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, devidError.New("global configuration missing trust domain")
+	}
+
+	if hclConfig.DevicePath == "" {
+		hclConfig.DevicePath = defaultDevicePath
+	}
+	if hclConfig.DevIDCertPath == "" {
+		return nil, devidError.New("devid_cert_path is required")
+	}
+	if hclConfig.DevIDHandle < int64(tpm2.PersistentFirst) || hclConfig.DevIDHandle > 0x81FFFFFF {
+		return nil, devidError.New("devid_handle %#x is not a persistent handle", hclConfig.DevIDHandle)
+	}
+	if len(hclConfig.PCRs) == 0 {
+		hclConfig.PCRs = defaultPCRs
+	}
+	for _, pcr := range hclConfig.PCRs {
+		if pcr < 0 || pcr > 23 {
+			return nil, devidError.New("invalid PCR index %d", pcr)
+		}
+	}
+
+	certs, err := util.LoadCertificates(hclConfig.DevIDCertPath)
+	if err != nil {
+		return nil, devidError.New("unable to load DevID certificate: %v", err)
+	}
+	var certificates [][]byte
+	for _, cert := range certs {
+		certificates = append(certificates, cert.Raw)
+	}
+
+	p.setConfig(&devidAttestorConfig{
+		DevIDAttestorConfig: hclConfig,
+		spiffeID:            tpmdevid.AgentID(req.GlobalConfig.TrustDomain, certs[0]),
+		certificates:        certificates,
+	})
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *DevIDAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *DevIDAttestorPlugin) getConfig() (*devidAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, devidError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *DevIDAttestorPlugin) setConfig(config *devidAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+// quote quotes the configured PCRs with the DevID key and reads their values
+func (p *DevIDAttestorPlugin) quote(config *devidAttestorConfig, nonce []byte) (*tpmdevid.Response, error) {
+	d, err := p.hooks.openDevice(config.DevicePath)
+	if err != nil {
+		return nil, devidError.Wrap(err)
+	}
+	defer d.Close()
+
+	sel := tpm2.PCRSelection{
+		Hash: tpmdevid.PCRBank,
+		PCRs: config.PCRs,
+	}
+
+	quote, sig, err := d.Quote(tpmutil.Handle(config.DevIDHandle), config.DevIDPassword, nonce, sel)
+	if err != nil {
+		return nil, devidError.Wrap(err)
+	}
+	signature, err := tpmdevid.EncodeSignature(sig)
+	if err != nil {
+		return nil, devidError.New("unable to encode quote signature: %v", err)
+	}
+
+	pcrs, err := d.ReadPCRs(sel)
+	if err != nil {
+		return nil, devidError.Wrap(err)
+	}
+
+	return &tpmdevid.Response{
+		Quote:     quote,
+		Signature: signature,
+		PCRs:      pcrs,
+	}, nil
+}
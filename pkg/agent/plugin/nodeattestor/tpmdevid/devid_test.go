@@ -0,0 +1,281 @@
+package tpmdevid
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/tpmdevid"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/test/fakes/fakedevid"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestDevIDAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(DevIDAttestorSuite))
+}
+
+type DevIDAttestorSuite struct {
+	suite.Suite
+
+	dir      string
+	certPath string
+	tpm      *fakedevid.TPM
+	device   *fakeDevice
+	attestor *nodeattestor.BuiltIn
+}
+
+func (s *DevIDAttestorSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "tpmdevid-test")
+	s.Require().NoError(err)
+
+	s.tpm = fakedevid.New(s.T())
+	s.certPath = filepath.Join(s.dir, "devid.pem")
+	s.Require().NoError(ioutil.WriteFile(s.certPath, pemutil.EncodeCertificates([]*x509.Certificate{s.tpm.DevID, s.tpm.Intermediate}), 0644))
+
+	s.device = &fakeDevice{tpm: s.tpm}
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *DevIDAttestorSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *DevIDAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	stream, err := s.newAttestor().FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Require().EqualError(err, "tpm-devid: not configured")
+}
+
+func (s *DevIDAttestorSuite) TestFetchAttestationDataBadChallenge() {
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().NoError(err)
+
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: []byte("{"),
+	}))
+	_, err = stream.Recv()
+	s.requireErrorContains(err, "tpm-devid: unable to unmarshal challenge")
+}
+
+func (s *DevIDAttestorSuite) TestFetchAttestationDataDeviceFailures() {
+	s.device.openErr = errors.New("open failed")
+	s.requireChallengeError("tpm-devid: open failed")
+	s.device.openErr = nil
+
+	s.device.quoteErr = errors.New("quote failed")
+	s.requireChallengeError("tpm-devid: quote failed")
+	s.device.quoteErr = nil
+
+	s.device.readErr = errors.New("read failed")
+	s.requireChallengeError("tpm-devid: read failed")
+}
+
+func (s *DevIDAttestorSuite) TestFetchAttestationDataSuccess() {
+	s.configureAttestor(`
+	device_path = "/dev/tpm0"
+	devid_password = "secret"
+	pcrs = [0, 7]
+	`)
+
+	stream := s.fetchAttestationData()
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	spiffeID := "spiffe://example.org/spire/agent/tpm_devid/" + tpmdevid.Fingerprint(s.tpm.DevID)
+	s.Require().Equal(spiffeID, resp.SpiffeId)
+	s.Require().Equal("tpm_devid", resp.AttestationData.Type)
+	attestationData := new(tpmdevid.AttestationData)
+	s.Require().NoError(json.Unmarshal(resp.AttestationData.Data, attestationData))
+	s.Require().Equal(s.tpm.Certificates(), attestationData.Certificates)
+
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: s.marshal(tpmdevid.Challenge{Nonce: []byte("NONCE")}),
+	}))
+	resp, err = stream.Recv()
+	s.Require().NoError(err)
+	s.Require().Equal(spiffeID, resp.SpiffeId)
+
+	response := new(tpmdevid.Response)
+	s.Require().NoError(json.Unmarshal(resp.Response, response))
+	s.Require().NoError(tpmdevid.VerifyQuote(s.tpm.DevID.PublicKey, response, []byte("NONCE")))
+	s.Require().Len(response.PCRs, 2)
+
+	s.Require().Equal("/dev/tpm0", s.device.path)
+	s.Require().Equal(tpmutil.Handle(0x81010002), s.device.handle)
+	s.Require().Equal("secret", s.device.password)
+	s.Require().True(s.device.closed)
+}
+
+func (s *DevIDAttestorSuite) TestFetchAttestationDataDefaultPCRs() {
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().NoError(err)
+
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: s.marshal(tpmdevid.Challenge{Nonce: []byte("NONCE")}),
+	}))
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+
+	response := new(tpmdevid.Response)
+	s.Require().NoError(json.Unmarshal(resp.Response, response))
+	s.Require().Len(response.PCRs, 8)
+	s.Require().Equal("/dev/tpmrm0", s.device.path)
+}
+
+func (s *DevIDAttestorSuite) TestConfigure() {
+	configure := func(config string, globalConfig *plugin.ConfigureRequest_GlobalConfig) error {
+		resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+			Configuration: config,
+			GlobalConfig:  globalConfig,
+		})
+		if err != nil {
+			s.Require().Nil(resp)
+		}
+		return err
+	}
+	globalConfig := &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"}
+	base := fmt.Sprintf("devid_cert_path = %q\ndevid_handle = 0x81010002\n", s.certPath)
+
+	err := configure("blah", globalConfig)
+	s.requireErrorContains(err, "tpm-devid: unable to decode configuration")
+
+	err = configure(base, nil)
+	s.Require().EqualError(err, "tpm-devid: global configuration is required")
+
+	err = configure(base, &plugin.ConfigureRequest_GlobalConfig{})
+	s.Require().EqualError(err, "tpm-devid: global configuration missing trust domain")
+
+	err = configure("devid_handle = 0x81010002", globalConfig)
+	s.Require().EqualError(err, "tpm-devid: devid_cert_path is required")
+
+	err = configure(fmt.Sprintf("devid_cert_path = %q", s.certPath), globalConfig)
+	s.Require().EqualError(err, "tpm-devid: devid_handle 0x0 is not a persistent handle")
+
+	err = configure(base+"pcrs = [24]", globalConfig)
+	s.Require().EqualError(err, "tpm-devid: invalid PCR index 24")
+
+	err = configure(`devid_cert_path = "blah"
+	devid_handle = 0x81010002`, globalConfig)
+	s.requireErrorContains(err, "tpm-devid: unable to load DevID certificate")
+
+	emptyPath := filepath.Join(s.dir, "empty.pem")
+	s.Require().NoError(ioutil.WriteFile(emptyPath, nil, 0644))
+	err = configure(fmt.Sprintf("devid_cert_path = %q\ndevid_handle = 0x81010002", emptyPath), globalConfig)
+	s.Require().EqualError(err, "tpm-devid: unable to load DevID certificate: no certificates found in file")
+
+	err = configure(base, globalConfig)
+	s.Require().NoError(err)
+}
+
+func (s *DevIDAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *DevIDAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.openDevice = func(path string) (device, error) {
+		if s.device.openErr != nil {
+			return nil, s.device.openErr
+		}
+		s.device.path = path
+		return s.device, nil
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *DevIDAttestorSuite) configureAttestor(extra string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("devid_cert_path = %q\ndevid_handle = 0x81010002\n%s", s.certPath, extra),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *DevIDAttestorSuite) fetchAttestationData() nodeattestor.FetchAttestationData_Stream {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	return stream
+}
+
+func (s *DevIDAttestorSuite) requireChallengeError(contains string) {
+	stream := s.fetchAttestationData()
+	_, err := stream.Recv()
+	s.Require().NoError(err)
+
+	s.Require().NoError(stream.Send(&nodeattestor.FetchAttestationDataRequest{
+		Challenge: s.marshal(tpmdevid.Challenge{Nonce: []byte("NONCE")}),
+	}))
+	_, err = stream.Recv()
+	s.requireErrorContains(err, contains)
+}
+
+func (s *DevIDAttestorSuite) marshal(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	s.Require().NoError(err)
+	return data
+}
+
+func (s *DevIDAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+type fakeDevice struct {
+	tpm *fakedevid.TPM
+
+	openErr  error
+	quoteErr error
+	readErr  error
+
+	path     string
+	handle   tpmutil.Handle
+	password string
+	closed   bool
+}
+
+func (d *fakeDevice) Quote(handle tpmutil.Handle, password string, nonce []byte, sel tpm2.PCRSelection) ([]byte, *tpm2.Signature, error) {
+	if d.quoteErr != nil {
+		return nil, nil, d.quoteErr
+	}
+	d.handle = handle
+	d.password = password
+	pcrs, _ := d.ReadPCRs(sel)
+	quote, sig := d.tpm.Quote(nonce, pcrs)
+	return quote, sig, nil
+}
+
+func (d *fakeDevice) ReadPCRs(sel tpm2.PCRSelection) (map[int][]byte, error) {
+	if d.readErr != nil {
+		return nil, d.readErr
+	}
+	pcrs := make(map[int][]byte)
+	for _, pcr := range sel.PCRs {
+		pcrs[pcr] = bytes.Repeat([]byte{byte(pcr)}, 32)
+	}
+	return pcrs, nil
+}
+
+func (d *fakeDevice) Close() error {
+	d.closed = true
+	return nil
+}
@@ -0,0 +1,153 @@
+package vsphere
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os/exec"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/vsphere"
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+const (
+	// productUUIDPath exposes the BIOS UUID of the virtual machine. It is
+	// only readable by root.
+	productUUIDPath = "/sys/class/dmi/id/product_uuid"
+
+	defaultRPCToolPath = "vmware-rpctool"
+)
+
+var (
+	vsphereError = errs.Class("vsphere")
+)
+
+type VMAttestorConfig struct {
+	trustDomain string
+
+	// GuestinfoKey, if set, is the guestinfo key (e.g. guestinfo.spire.uuid)
+	// holding the BIOS UUID of the virtual machine. It is read using
+	// VMware Tools instead of reading the UUID from the DMI tables.
+	GuestinfoKey string `hcl:"guestinfo_key"`
+
+	// RPCToolPath is the path to the vmware-rpctool binary used to read
+	// the guestinfo key
+	RPCToolPath string `hcl:"rpctool_path"`
+}
+
+type VMAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *VMAttestorConfig
+
+	hooks struct {
+		readFile     func(string) ([]byte, error)
+		getGuestinfo func(rpcToolPath, key string) ([]byte, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*VMAttestorPlugin)(nil)
+
+func New() *VMAttestorPlugin {
+	p := &VMAttestorPlugin{}
+	p.hooks.readFile = ioutil.ReadFile
+	p.hooks.getGuestinfo = getGuestinfo
+	return p
+}
+
+func (p *VMAttestorPlugin) FetchAttestationData(stream nodeattestor.FetchAttestationData_PluginStream) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	var rawUUID []byte
+	if config.GuestinfoKey != "" {
+		rawUUID, err = p.hooks.getGuestinfo(config.RPCToolPath, config.GuestinfoKey)
+		if err != nil {
+			return vsphereError.New("unable to read guestinfo key %q: %v", config.GuestinfoKey, err)
+		}
+	} else {
+		rawUUID, err = p.hooks.readFile(productUUIDPath)
+		if err != nil {
+			return vsphereError.New("unable to read BIOS UUID: %v", err)
+		}
+	}
+
+	biosUUID, err := vsphere.NormalizeUUID(string(rawUUID))
+	if err != nil {
+		return vsphereError.New("invalid BIOS UUID: %v", err)
+	}
+
+	data, err := json.Marshal(vsphere.AttestationData{
+		BIOSUUID: biosUUID,
+	})
+	if err != nil {
+		return vsphereError.Wrap(err)
+	}
+
+	return stream.Send(&nodeattestor.FetchAttestationDataResponse{
+		AttestationData: &common.AttestationData{
+			Type: vsphere.PluginName,
+			Data: data,
+		},
+		SpiffeId: vsphere.AgentID(config.trustDomain, biosUUID),
+	})
+}
+
+func (p *VMAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(VMAttestorConfig)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, vsphereError.New("unable to decode configuration: %v", err)
+	}
+
+	if req.GlobalConfig == nil {
+		return nil, vsphereError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, vsphereError.New("global configuration missing trust domain")
+	}
+	config.trustDomain = req.GlobalConfig.TrustDomain
+
+	if config.RPCToolPath == "" {
+		config.RPCToolPath = defaultRPCToolPath
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *VMAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *VMAttestorPlugin) getConfig() (*VMAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, vsphereError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *VMAttestorPlugin) setConfig(config *VMAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+// getGuestinfo reads the guestinfo key through the VMware Tools backdoor
+func getGuestinfo(rpcToolPath, key string) ([]byte, error) {
+	out, err := exec.Command(rpcToolPath, "info-get "+key).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, errs.New("%v: %s", err, exitErr.Stderr)
+		}
+		return nil, errs.Wrap(err)
+	}
+	return out, nil
+}
@@ -0,0 +1,173 @@
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/spiffe/spire/proto/agent/nodeattestor"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestVMAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(VMAttestorSuite))
+}
+
+type VMAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+
+	productUUID  string
+	guestinfo    map[string]string
+	readErr      error
+	guestinfoErr error
+}
+
+func (s *VMAttestorSuite) SetupTest() {
+	s.productUUID = "4210B1A2-3C4D-5E6F-7A8B-9C0D1E2F3A4B\n"
+	s.guestinfo = map[string]string{
+		"guestinfo.spire.uuid": "5210b1a2-3c4d-5e6f-7a8b-9c0d1e2f3a4b",
+	}
+	s.readErr = nil
+	s.guestinfoErr = nil
+
+	s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *VMAttestorSuite) TestFetchAttestationDataNotConfigured() {
+	s.newAttestor()
+	s.requireFetchError("vsphere: not configured")
+}
+
+func (s *VMAttestorSuite) TestFetchAttestationDataFailedToReadUUID() {
+	s.readErr = errors.New("permission denied")
+	s.requireFetchError("vsphere: unable to read BIOS UUID: permission denied")
+}
+
+func (s *VMAttestorSuite) TestFetchAttestationDataMalformedUUID() {
+	s.productUUID = "blah"
+	s.requireFetchError(`vsphere: invalid BIOS UUID: malformed UUID "blah"`)
+}
+
+func (s *VMAttestorSuite) TestFetchAttestationDataFailedToReadGuestinfo() {
+	s.configureAttestor(`guestinfo_key = "guestinfo.spire.uuid"`)
+	s.guestinfoErr = errors.New("no value found")
+	s.requireFetchError(`vsphere: unable to read guestinfo key "guestinfo.spire.uuid": no value found`)
+}
+
+func (s *VMAttestorSuite) TestFetchAttestationDataSuccess() {
+	s.requireFetchSuccess("4210b1a2-3c4d-5e6f-7a8b-9c0d1e2f3a4b")
+}
+
+func (s *VMAttestorSuite) TestFetchAttestationDataFromGuestinfo() {
+	s.configureAttestor(`guestinfo_key = "guestinfo.spire.uuid"`)
+	s.requireFetchSuccess("5210b1a2-3c4d-5e6f-7a8b-9c0d1e2f3a4b")
+}
+
+func (s *VMAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{},
+	})
+	s.requireErrorContains(err, "vsphere: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "vsphere: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "vsphere: global configuration missing trust domain")
+	s.Require().Nil(resp)
+}
+
+func (s *VMAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *VMAttestorSuite) requireFetchSuccess(biosUUID string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+
+	// assert attestation data
+	s.Require().Equal("spiffe://example.org/spire/agent/vsphere/"+biosUUID, resp.SpiffeId)
+	s.Require().NotNil(resp.AttestationData)
+	s.Require().Equal("vsphere", resp.AttestationData.Type)
+	s.Require().JSONEq(fmt.Sprintf(`{"bios_uuid": %q}`, biosUUID), string(resp.AttestationData.Data))
+
+	// node attestor should return EOF now
+	_, err = stream.Recv()
+	s.Require().Equal(io.EOF, err)
+}
+
+func (s *VMAttestorSuite) newAttestor() {
+	attestor := New()
+	attestor.hooks.readFile = func(path string) ([]byte, error) {
+		if path != "/sys/class/dmi/id/product_uuid" {
+			return nil, fmt.Errorf("unexpected path %q", path)
+		}
+		if s.readErr != nil {
+			return nil, s.readErr
+		}
+		return []byte(s.productUUID), nil
+	}
+	attestor.hooks.getGuestinfo = func(rpcToolPath, key string) ([]byte, error) {
+		if rpcToolPath != "vmware-rpctool" {
+			return nil, fmt.Errorf("unexpected rpctool path %q", rpcToolPath)
+		}
+		if s.guestinfoErr != nil {
+			return nil, s.guestinfoErr
+		}
+		return []byte(s.guestinfo[key]), nil
+	}
+	s.attestor = nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *VMAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: config,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *VMAttestorSuite) requireFetchError(contains string) {
+	stream, err := s.attestor.FetchAttestationData(context.Background())
+	s.Require().NoError(err)
+	s.Require().NotNil(stream)
+
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *VMAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func TestGetGuestinfo(t *testing.T) {
+	_, err := getGuestinfo("/does/not/exist", "guestinfo.spire.uuid")
+	require.Error(t, err)
+
+	// echo stands in for vmware-rpctool
+	out, err := getGuestinfo("echo", "guestinfo.spire.uuid")
+	require.NoError(t, err)
+	require.Equal(t, "info-get guestinfo.spire.uuid\n", string(out))
+}
@@ -0,0 +1,148 @@
+package containerd
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// The messages below mirror the subset of the containerd containers and
+// images services (github.com/containerd/containerd/api/services) used by
+// the attestor. Fields not listed here are skipped when unmarshaling.
+
+const (
+	getContainerMethod = "/containerd.services.containers.v1.Containers/Get"
+	getImageMethod     = "/containerd.services.images.v1.Images/Get"
+
+	// namespaceHeader is the gRPC metadata key selecting the containerd
+	// namespace of a request
+	namespaceHeader = "containerd-namespace"
+)
+
+type container struct {
+	ID      string            `protobuf:"bytes,1,opt,name=id,proto3"`
+	Labels  map[string]string `protobuf:"bytes,2,rep,name=labels,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Image   string            `protobuf:"bytes,3,opt,name=image,proto3"`
+	Runtime *containerRuntime `protobuf:"bytes,4,opt,name=runtime"`
+}
+
+func (m *container) Reset()         { *m = container{} }
+func (m *container) String() string { return proto.CompactTextString(m) }
+func (*container) ProtoMessage()    {}
+
+type containerRuntime struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3"`
+}
+
+func (m *containerRuntime) Reset()         { *m = containerRuntime{} }
+func (m *containerRuntime) String() string { return proto.CompactTextString(m) }
+func (*containerRuntime) ProtoMessage()    {}
+
+type getContainerRequest struct {
+	ID string `protobuf:"bytes,1,opt,name=id,proto3"`
+}
+
+func (m *getContainerRequest) Reset()         { *m = getContainerRequest{} }
+func (m *getContainerRequest) String() string { return proto.CompactTextString(m) }
+func (*getContainerRequest) ProtoMessage()    {}
+
+type getContainerResponse struct {
+	Container *container `protobuf:"bytes,1,opt,name=container"`
+}
+
+func (m *getContainerResponse) Reset()         { *m = getContainerResponse{} }
+func (m *getContainerResponse) String() string { return proto.CompactTextString(m) }
+func (*getContainerResponse) ProtoMessage()    {}
+
+type descriptor struct {
+	MediaType string `protobuf:"bytes,1,opt,name=media_type,proto3"`
+	Digest    string `protobuf:"bytes,2,opt,name=digest,proto3"`
+	Size      int64  `protobuf:"varint,3,opt,name=size,proto3"`
+}
+
+func (m *descriptor) Reset()         { *m = descriptor{} }
+func (m *descriptor) String() string { return proto.CompactTextString(m) }
+func (*descriptor) ProtoMessage()    {}
+
+type image struct {
+	Name   string      `protobuf:"bytes,1,opt,name=name,proto3"`
+	Target *descriptor `protobuf:"bytes,3,opt,name=target"`
+}
+
+func (m *image) Reset()         { *m = image{} }
+func (m *image) String() string { return proto.CompactTextString(m) }
+func (*image) ProtoMessage()    {}
+
+type getImageRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3"`
+}
+
+func (m *getImageRequest) Reset()         { *m = getImageRequest{} }
+func (m *getImageRequest) String() string { return proto.CompactTextString(m) }
+func (*getImageRequest) ProtoMessage()    {}
+
+type getImageResponse struct {
+	Image *image `protobuf:"bytes,1,opt,name=image"`
+}
+
+func (m *getImageResponse) Reset()         { *m = getImageResponse{} }
+func (m *getImageResponse) String() string { return proto.CompactTextString(m) }
+func (*getImageResponse) ProtoMessage()    {}
+
+// containerdClient is the subset of the containerd API used by the attestor
+type containerdClient interface {
+	GetContainer(ctx context.Context, namespace, id string) (*container, error)
+	GetImage(ctx context.Context, namespace, name string) (*image, error)
+	Close() error
+}
+
+type grpcClient struct {
+	conn *grpc.ClientConn
+}
+
+// dialContainerd connects to the containerd API on the given unix socket
+func dialContainerd(ctx context.Context, socketPath string) (containerdClient, error) {
+	conn, err := grpc.DialContext(ctx, socketPath,
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", addr, timeout)
+		}))
+	if err != nil {
+		return nil, err
+	}
+	return &grpcClient{conn: conn}, nil
+}
+
+func (c *grpcClient) GetContainer(ctx context.Context, namespace, id string) (*container, error) {
+	resp := new(getContainerResponse)
+	if err := c.conn.Invoke(withNamespace(ctx, namespace), getContainerMethod, &getContainerRequest{ID: id}, resp); err != nil {
+		return nil, err
+	}
+	if resp.Container == nil {
+		return nil, errEmptyResponse
+	}
+	return resp.Container, nil
+}
+
+func (c *grpcClient) GetImage(ctx context.Context, namespace, name string) (*image, error) {
+	resp := new(getImageResponse)
+	if err := c.conn.Invoke(withNamespace(ctx, namespace), getImageMethod, &getImageRequest{Name: name}, resp); err != nil {
+		return nil, err
+	}
+	if resp.Image == nil {
+		return nil, errEmptyResponse
+	}
+	return resp.Image, nil
+}
+
+func (c *grpcClient) Close() error {
+	return c.conn.Close()
+}
+
+func withNamespace(ctx context.Context, namespace string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, namespaceHeader, namespace)
+}
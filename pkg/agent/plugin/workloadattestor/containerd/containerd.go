@@ -0,0 +1,209 @@
+package containerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/agent/common/sandbox"
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	selectorType = "containerd"
+
+	defaultSocketPath = "/run/containerd/containerd.sock"
+
+	// defaultNamespace is the namespace used by the containerd CRI plugin
+	defaultNamespace = "k8s.io"
+)
+
+var (
+	containerdErr = errs.Class("containerd")
+
+	errEmptyResponse = errors.New("empty response")
+
+	// containerIDRE matches the container ID at the end of the cgroup path
+	// of a container process. The ID may be prefixed (e.g.
+	// "cri-containerd-<id>.scope" with the systemd cgroup driver).
+	containerIDRE = regexp.MustCompile(`[/-]([[:xdigit:]]{64})(?:\.scope)?$`)
+)
+
+type Configuration struct {
+	// SocketPath is the path to the containerd API socket (default:
+	// "/run/containerd/containerd.sock")
+	SocketPath string `hcl:"socket_path"`
+
+	// Namespace is the containerd namespace containers are looked up in
+	// (default: "k8s.io")
+	Namespace string `hcl:"namespace"`
+}
+
+type ContainerdPlugin struct {
+	mu     sync.Mutex
+	config *Configuration
+
+	// hooks for tests
+	hooks struct {
+		fs      cgroups.FileSystem
+		dial    func(ctx context.Context, socketPath string) (containerdClient, error)
+		sandbox sandbox.Detector
+	}
+}
+
+func New() *ContainerdPlugin {
+	p := &ContainerdPlugin{}
+	p.hooks.fs = cgroups.OSFileSystem{}
+	p.hooks.dial = dialContainerd
+	p.hooks.sandbox = sandbox.NewDetector()
+	return p
+}
+
+func (p *ContainerdPlugin) Attest(ctx context.Context, req *workloadattestor.AttestRequest) (*workloadattestor.AttestResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	containerID, err := p.getContainerID(req.Pid)
+	if err != nil {
+		return nil, err
+	}
+
+	// processes outside of a container have no containerd selectors
+	if containerID == "" {
+		return &workloadattestor.AttestResponse{}, nil
+	}
+
+	client, err := p.hooks.dial(ctx, config.SocketPath)
+	if err != nil {
+		return nil, containerdErr.New("unable to connect to containerd: %v", err)
+	}
+	defer client.Close()
+
+	c, err := client.GetContainer(ctx, config.Namespace, containerID)
+	switch {
+	case status.Code(err) == codes.NotFound:
+		// the container is managed by another runtime or lives in another
+		// namespace
+		return &workloadattestor.AttestResponse{}, nil
+	case err != nil:
+		return nil, containerdErr.New("unable to get container %q: %v", containerID, err)
+	}
+
+	selectors := []*common.Selector{
+		makeSelector("namespace", config.Namespace),
+	}
+	if c.Image != "" {
+		selectors = append(selectors, makeSelector("image", c.Image))
+
+		img, err := client.GetImage(ctx, config.Namespace, c.Image)
+		switch {
+		case status.Code(err) == codes.NotFound:
+			// the image may have been removed since the container was
+			// created, leaving no digest to attest
+		case err != nil:
+			return nil, containerdErr.New("unable to get image %q: %v", c.Image, err)
+		case img.Target != nil && img.Target.Digest != "":
+			selectors = append(selectors, makeSelector("image_digest", img.Target.Digest))
+		}
+	}
+
+	labels := make([]string, 0, len(c.Labels))
+	for label := range c.Labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		selectors = append(selectors, makeSelector("label", fmt.Sprintf("%s:%s", label, c.Labels[label])))
+	}
+
+	var runtime string
+	if c.Runtime != nil {
+		runtime = c.Runtime.Name
+	}
+	if sb, ok := p.hooks.sandbox.Detect(ctx, req.Pid, runtime); ok {
+		for _, value := range sb.SelectorValues() {
+			selectors = append(selectors, &common.Selector{
+				Type:  selectorType,
+				Value: value,
+			})
+		}
+	}
+
+	return &workloadattestor.AttestResponse{
+		Selectors: selectors,
+	}, nil
+}
+
+func (p *ContainerdPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(Configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, containerdErr.Wrap(err)
+	}
+	if config.SocketPath == "" {
+		config.SocketPath = defaultSocketPath
+	}
+	if config.Namespace == "" {
+		config.Namespace = defaultNamespace
+	}
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *ContainerdPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *ContainerdPlugin) getConfig() (*Configuration, error) {
+	p.mu.Lock()
+	config := p.config
+	p.mu.Unlock()
+	if config == nil {
+		return nil, containerdErr.New("not configured")
+	}
+	return config, nil
+}
+
+func (p *ContainerdPlugin) setConfig(config *Configuration) {
+	p.mu.Lock()
+	p.config = config
+	p.mu.Unlock()
+}
+
+// getContainerID returns the ID of the container the process runs in, taken
+// from its cgroup paths. It returns an empty string if the process does not
+// run in a container.
+func (p *ContainerdPlugin) getContainerID(pid int32) (string, error) {
+	cgroupList, err := cgroups.GetCgroups(pid, p.hooks.fs)
+	if err != nil {
+		return "", containerdErr.New("unable to get cgroups: %v", err)
+	}
+
+	for _, cgroup := range cgroupList {
+		// Example entries:
+		// "11:memory:/kubepods/besteffort/pod2c48913c-b29f-11e7-9350-020968147796/9bca8d63d5fa610783847915bcff0ecac1273e5b4bed3f6fa1b07350e0135961"
+		// "0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod2c48913c.slice/cri-containerd-9bca8d63d5fa610783847915bcff0ecac1273e5b4bed3f6fa1b07350e0135961.scope"
+		if m := containerIDRE.FindStringSubmatch(cgroup.GroupPath); m != nil {
+			return m[1], nil
+		}
+	}
+	return "", nil
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  selectorType,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
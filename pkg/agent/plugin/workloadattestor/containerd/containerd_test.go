@@ -0,0 +1,285 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/agent/common/sandbox"
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	containerID = "9bca8d63d5fa610783847915bcff0ecac1273e5b4bed3f6fa1b07350e0135961"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestContainerdPlugin(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	dir        string
+	socketPath string
+	server     *grpc.Server
+	p          *workloadattestor.BuiltIn
+
+	// containers and images served by the fake containerd, keyed by
+	// namespace and then by ID or name
+	containers map[string]map[string]*container
+	images     map[string]map[string]*image
+}
+
+func (s *Suite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "containerd-workload-attestor-test-")
+	s.Require().NoError(err)
+
+	s.containers = map[string]map[string]*container{
+		"k8s.io": {
+			containerID: {
+				ID:    containerID,
+				Image: "docker.io/library/nginx:1.15",
+				Labels: map[string]string{
+					"io.kubernetes.pod.namespace":  "default",
+					"io.kubernetes.container.name": "nginx",
+				},
+			},
+		},
+		"default": {
+			containerID: {
+				ID:      containerID,
+				Image:   "docker.io/library/removed:latest",
+				Runtime: &containerRuntime{Name: "io.containerd.kata.v2"},
+			},
+		},
+	}
+	s.images = map[string]map[string]*image{
+		"k8s.io": {
+			"docker.io/library/nginx:1.15": {
+				Name: "docker.io/library/nginx:1.15",
+				Target: &descriptor{
+					MediaType: "application/vnd.docker.distribution.manifest.list.v2+json",
+					Digest:    "sha256:e3456c851a152494c3e4ff5fcc26f240206abac0c9d794affb40e0714846c451",
+				},
+			},
+		},
+	}
+
+	s.socketPath = filepath.Join(s.dir, "containerd.sock")
+	listener, err := net.Listen("unix", s.socketPath)
+	s.Require().NoError(err)
+	s.server = grpc.NewServer()
+	s.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "containerd.services.containers.v1.Containers",
+		HandlerType: (*interface{})(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Get", Handler: s.getContainer}},
+	}, s)
+	s.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "containerd.services.images.v1.Images",
+		HandlerType: (*interface{})(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Get", Handler: s.getImage}},
+	}, s)
+	go s.server.Serve(listener)
+
+	p := New()
+	p.hooks.fs = fakeFS{dir: s.dir}
+	p.hooks.sandbox = fakeSandboxDetector{}
+	s.p = workloadattestor.NewBuiltIn(p)
+	s.configure("")
+}
+
+func (s *Suite) TearDownTest() {
+	s.server.Stop()
+	os.RemoveAll(s.dir)
+}
+
+func (s *Suite) TestAttest() {
+	testCases := []struct {
+		name      string
+		cgroups   string
+		config    string
+		err       string
+		selectors []string
+	}{
+		{
+			name:    "cgroupfs driver",
+			cgroups: "11:memory:/kubepods/besteffort/pod2c48913c-b29f-11e7-9350-020968147796/" + containerID + "\n",
+			selectors: []string{
+				"namespace:k8s.io",
+				"image:docker.io/library/nginx:1.15",
+				"image_digest:sha256:e3456c851a152494c3e4ff5fcc26f240206abac0c9d794affb40e0714846c451",
+				"label:io.kubernetes.container.name:nginx",
+				"label:io.kubernetes.pod.namespace:default",
+			},
+		},
+		{
+			name:    "systemd driver",
+			cgroups: "0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod2c48913c.slice/cri-containerd-" + containerID + ".scope\n",
+			selectors: []string{
+				"namespace:k8s.io",
+				"image:docker.io/library/nginx:1.15",
+				"image_digest:sha256:e3456c851a152494c3e4ff5fcc26f240206abac0c9d794affb40e0714846c451",
+				"label:io.kubernetes.container.name:nginx",
+				"label:io.kubernetes.pod.namespace:default",
+			},
+		},
+		{
+			name:    "image removed",
+			cgroups: "0::/default/" + containerID + "\n",
+			config:  `namespace = "default"`,
+			selectors: []string{
+				"namespace:default",
+				"image:docker.io/library/removed:latest",
+				"runtime:kata",
+				"runtime:kata:3.2.0",
+			},
+		},
+		{
+			name:    "not in a container",
+			cgroups: "0::/user.slice/user-1000.slice/session-1.scope\n",
+		},
+		{
+			name:    "container in another namespace",
+			cgroups: "0::/default/" + containerID + "\n",
+			config:  `namespace = "moby"`,
+		},
+		{
+			name:    "malformed cgroups",
+			cgroups: "garbage\n",
+			err:     `containerd: unable to get cgroups: cgroup entry contains 1 colons, but expected at least 2 colons: "garbage"`,
+		},
+		{
+			name:    "containerd unavailable",
+			cgroups: "0::/default/" + containerID + "\n",
+			config:  `socket_path = "` + filepath.Join(os.TempDir(), "does-not-exist.sock") + `"`,
+			err:     `containerd: unable to get container "` + containerID + `": rpc error: code = Unavailable`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		s.T().Run(testCase.name, func(t *testing.T) {
+			s.configure(testCase.config)
+			s.writeCgroups(123, testCase.cgroups)
+
+			resp, err := s.p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 123})
+			if testCase.err != "" {
+				s.Require().Error(err)
+				s.Require().Contains(err.Error(), testCase.err)
+				s.Require().Nil(resp)
+				return
+			}
+
+			s.Require().NoError(err)
+			s.Require().NotNil(resp)
+			var selectors []string
+			for _, selector := range resp.Selectors {
+				s.Require().Equal("containerd", selector.Type)
+				selectors = append(selectors, selector.Value)
+			}
+			s.Require().Equal(testCase.selectors, selectors)
+		})
+	}
+}
+
+func (s *Suite) TestAttestNotConfigured() {
+	p := workloadattestor.NewBuiltIn(New())
+	resp, err := p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 123})
+	s.Require().EqualError(err, "containerd: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestConfigure() {
+	resp, err := s.p.Configure(ctx, &spi.ConfigureRequest{Configuration: "blah"})
+	s.Require().Error(err)
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestGetPluginInfo() {
+	resp, err := s.p.GetPluginInfo(ctx, &spi.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+}
+
+func (s *Suite) configure(config string) {
+	// point at the fake containerd unless the test overrides the path
+	if !strings.Contains(config, "socket_path") {
+		config += fmt.Sprintf("\nsocket_path = %q", s.socketPath)
+	}
+	_, err := s.p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: config,
+	})
+	s.Require().NoError(err)
+}
+
+func (s *Suite) writeCgroups(pid int32, cgroups string) {
+	dir := filepath.Join(s.dir, "proc", fmt.Sprint(pid))
+	s.Require().NoError(os.MkdirAll(dir, 0755))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "cgroup"), []byte(cgroups), 0644))
+}
+
+func (s *Suite) getContainer(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(getContainerRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	c, ok := s.containers[namespaceFromContext(ctx)][req.ID]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "container %q in namespace %q: not found", req.ID, namespaceFromContext(ctx))
+	}
+	return &getContainerResponse{Container: c}, nil
+}
+
+func (s *Suite) getImage(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(getImageRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	img, ok := s.images[namespaceFromContext(ctx)][req.Name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "image %q: not found", req.Name)
+	}
+	return &getImageResponse{Image: img}, nil
+}
+
+func namespaceFromContext(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md[namespaceHeader]; len(values) == 1 {
+		return values[0]
+	}
+	return ""
+}
+
+// fakeFS opens files relative to a directory
+type fakeFS struct {
+	dir string
+}
+
+func (fs fakeFS) Open(name string) (*os.File, error) {
+	return os.Open(filepath.Join(fs.dir, name))
+}
+
+type fakeSandboxDetector struct{}
+
+func (fakeSandboxDetector) Detect(ctx context.Context, pid int32, runtime string) (sandbox.Sandbox, bool) {
+	if runtime != "io.containerd.kata.v2" {
+		return sandbox.Sandbox{}, false
+	}
+	return sandbox.Sandbox{Type: sandbox.TypeKata, Version: "3.2.0"}, true
+}
@@ -12,6 +12,7 @@ import (
 	dockerclient "github.com/docker/docker/client"
 	"github.com/hashicorp/hcl"
 	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/agent/common/sandbox"
 	"github.com/spiffe/spire/proto/agent/workloadattestor"
 	"github.com/spiffe/spire/proto/common"
 	spi "github.com/spiffe/spire/proto/common/plugin"
@@ -36,6 +37,7 @@ type dockerPlugin struct {
 	cgroupPrefix         string
 	cgroupContainerIndex int
 	fs                   cgroups.FileSystem
+	sandbox              sandbox.Detector
 	mtx                  *sync.RWMutex
 }
 
@@ -80,6 +82,19 @@ func (p *dockerPlugin) Attest(ctx context.Context, req *workloadattestor.AttestR
 		break
 	}
 
+	// Hosts using the systemd cgroup driver, or rootless docker, place
+	// containers in scopes (e.g. "0::/system.slice/docker-<id>.scope") that
+	// do not match the default prefix. A custom prefix disables this lookup.
+	if !hasDockerEntries && p.cgroupPrefix == defaultCgroupPrefix {
+		for _, cgroup := range cgroupList {
+			if container, ok := cgroups.FindContainer(cgroup.GroupPath); ok && container.Runtime == cgroups.RuntimeDocker {
+				hasDockerEntries = true
+				containerID = container.ID
+				break
+			}
+		}
+	}
+
 	// Not a docker workload. Since it is expected that non-docker workloads will call the
 	// workload API, it is fine to return a response without any selectors.
 	if !hasDockerEntries {
@@ -94,8 +109,23 @@ func (p *dockerPlugin) Attest(ctx context.Context, req *workloadattestor.AttestR
 		return nil, err
 	}
 
+	selectors := getSelectorsFromConfig(container.Config)
+
+	var runtime string
+	if container.ContainerJSONBase != nil && container.HostConfig != nil {
+		runtime = container.HostConfig.Runtime
+	}
+	if sb, ok := p.sandbox.Detect(ctx, req.Pid, runtime); ok {
+		for _, value := range sb.SelectorValues() {
+			selectors = append(selectors, &common.Selector{
+				Type:  selectorType,
+				Value: value,
+			})
+		}
+	}
+
 	return &workloadattestor.AttestResponse{
-		Selectors: getSelectorsFromConfig(container.Config),
+		Selectors: selectors,
 	}, nil
 }
 
@@ -155,7 +185,8 @@ func (*dockerPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (
 
 func New() *dockerPlugin {
 	return &dockerPlugin{
-		mtx: &sync.RWMutex{},
-		fs:  cgroups.OSFileSystem{},
+		mtx:     &sync.RWMutex{},
+		fs:      cgroups.OSFileSystem{},
+		sandbox: sandbox.NewDetector(),
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	dockerclient "github.com/docker/docker/client"
 	gomock "github.com/golang/mock/gomock"
+	"github.com/spiffe/spire/pkg/agent/common/sandbox"
 	"github.com/spiffe/spire/proto/agent/workloadattestor"
 	spi "github.com/spiffe/spire/proto/common/plugin"
 	filesystem_mock "github.com/spiffe/spire/test/mock/common/filesystem"
@@ -161,6 +162,74 @@ func TestDockerCgroupFormatErrors(t *testing.T) {
 	}
 }
 
+func TestDockerSystemdCgroups(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockDocker := NewMockDockerClient(mockCtrl)
+	mockFS := filesystem_mock.NewMockfileSystem(mockCtrl)
+
+	p := New()
+	p.docker = mockDocker
+	p.fs = mockFS
+	p.cgroupContainerIndex = 1
+	p.cgroupPrefix = "/docker"
+
+	cgroupFile, cleanup := newTestFile(t, "0::/system.slice/docker-6469646e742065787065637420616e796f6e6520746f20726561642074686973.scope")
+	defer cleanup()
+	ctx := context.Background()
+	container := types.ContainerJSON{
+		Config: &container.Config{
+			Image: "my-docker-image",
+		},
+	}
+	mockFS.EXPECT().Open("/proc/123/cgroup").Return(os.Open(cgroupFile))
+	mockDocker.EXPECT().ContainerInspect(ctx, "6469646e742065787065637420616e796f6e6520746f20726561642074686973").Return(container, nil)
+
+	res, err := p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 123})
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	require.Len(t, res.Selectors, 1)
+	require.Equal(t, "image_id:my-docker-image", res.Selectors[0].Value)
+}
+
+func TestDockerSandboxRuntime(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockDocker := NewMockDockerClient(mockCtrl)
+	mockFS := filesystem_mock.NewMockfileSystem(mockCtrl)
+
+	p := New()
+	p.docker = mockDocker
+	p.fs = mockFS
+	p.sandbox = fakeSandboxDetector{}
+	p.cgroupContainerIndex = 1
+	p.cgroupPrefix = "/docker"
+
+	cgroupFile, cleanup := newTestFile(t, "10:devices:/docker/6469646e742065787065637420616e796f6e6520746f20726561642074686973")
+	defer cleanup()
+	ctx := context.Background()
+	container := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			HostConfig: &container.HostConfig{
+				Runtime: "runsc",
+			},
+		},
+		Config: &container.Config{
+			Image: "my-docker-image",
+		},
+	}
+	mockFS.EXPECT().Open("/proc/123/cgroup").Return(os.Open(cgroupFile))
+	mockDocker.EXPECT().ContainerInspect(ctx, "6469646e742065787065637420616e796f6e6520746f20726561642074686973").Return(container, nil)
+
+	res, err := p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 123})
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	require.Len(t, res.Selectors, 3)
+	require.Equal(t, "image_id:my-docker-image", res.Selectors[0].Value)
+	require.Equal(t, "runtime:gvisor", res.Selectors[1].Value)
+	require.Equal(t, "runtime:gvisor:release-20240311.0", res.Selectors[2].Value)
+}
+
 func TestCgroupFileNotFound(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -231,3 +300,12 @@ func TestDockerConfigDefault(t *testing.T) {
 	require.Equal(t, "/docker", p.cgroupPrefix)
 	require.Equal(t, 1, p.cgroupContainerIndex)
 }
+
+type fakeSandboxDetector struct{}
+
+func (fakeSandboxDetector) Detect(ctx context.Context, pid int32, runtime string) (sandbox.Sandbox, bool) {
+	if runtime != "runsc" {
+		return sandbox.Sandbox{}, false
+	}
+	return sandbox.Sandbox{Type: sandbox.TypeGVisor, Version: "release-20240311.0"}, true
+}
@@ -0,0 +1,267 @@
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+const (
+	selectorType = "ecs"
+
+	// metadataURIEnvV4 is set by the ECS agent in the environment of every
+	// container of a task to the task metadata endpoint of the container
+	metadataURIEnvV4 = "ECS_CONTAINER_METADATA_URI_V4"
+
+	defaultMetadataTimeout = 5 * time.Second
+)
+
+var (
+	ecsErr = errs.Class("ecs")
+
+	// containerIDRE matches the cgroup path of a container in an ECS task,
+	// e.g. "/ecs/<task id>/<container id>"
+	containerIDRE = regexp.MustCompile(`^/ecs/([^/]+)/([[:xdigit:]]{64})$`)
+)
+
+// dockerClient is the subset of the docker client used by the attestor
+type dockerClient interface {
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+}
+
+// taskMetadata is the subset of the task metadata (version 4) used by the
+// attestor
+type taskMetadata struct {
+	Cluster     string            `json:"Cluster"`
+	TaskARN     string            `json:"TaskARN"`
+	Family      string            `json:"Family"`
+	Revision    string            `json:"Revision"`
+	ServiceName string            `json:"ServiceName"`
+	LaunchType  string            `json:"LaunchType"`
+	TaskTags    map[string]string `json:"TaskTags"`
+}
+
+type Configuration struct {
+	// DockerSocketPath is the location of the docker daemon socket (default:
+	// "unix:///var/run/docker.sock")
+	DockerSocketPath string `hcl:"docker_socket_path"`
+
+	// DockerVersion is the API version of the docker daemon (default: "1.40")
+	DockerVersion string `hcl:"docker_version"`
+}
+
+type ECSPlugin struct {
+	mu     sync.Mutex
+	config *Configuration
+	docker dockerClient
+
+	// hooks for tests
+	hooks struct {
+		fs              cgroups.FileSystem
+		newDockerClient func(config *Configuration) (dockerClient, error)
+		httpClient      *http.Client
+	}
+}
+
+func New() *ECSPlugin {
+	p := &ECSPlugin{}
+	p.hooks.fs = cgroups.OSFileSystem{}
+	p.hooks.newDockerClient = newDockerClient
+	p.hooks.httpClient = &http.Client{Timeout: defaultMetadataTimeout}
+	return p
+}
+
+func (p *ECSPlugin) Attest(ctx context.Context, req *workloadattestor.AttestRequest) (*workloadattestor.AttestResponse, error) {
+	docker, err := p.getDockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	taskID, containerID, err := p.getContainerID(req.Pid)
+	if err != nil {
+		return nil, err
+	}
+
+	// processes outside of an ECS task have no ecs selectors
+	if containerID == "" {
+		return &workloadattestor.AttestResponse{}, nil
+	}
+
+	// The metadata endpoint is taken from the container configuration set
+	// by the ECS agent, not from the process environment, which the
+	// workload controls.
+	container, err := docker.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, ecsErr.New("unable to inspect container %q: %v", containerID, err)
+	}
+	var metadataURI string
+	if container.Config != nil {
+		metadataURI = lookupEnv(container.Config.Env, metadataURIEnvV4)
+	}
+	if metadataURI == "" {
+		return nil, ecsErr.New("container %q has no task metadata endpoint", containerID)
+	}
+
+	task, err := p.getTaskMetadata(ctx, metadataURI)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(task.TaskARN, "/"+taskID) {
+		return nil, ecsErr.New("task metadata for %q does not match task %q", task.TaskARN, taskID)
+	}
+
+	selectors := []*common.Selector{
+		makeSelector("cluster", clusterName(task.Cluster)),
+		makeSelector("task_family", task.Family),
+		makeSelector("task_revision", task.Revision),
+	}
+	if task.ServiceName != "" {
+		selectors = append(selectors, makeSelector("service", task.ServiceName))
+	}
+	if task.LaunchType != "" {
+		selectors = append(selectors, makeSelector("launch_type", task.LaunchType))
+	}
+
+	tags := make([]string, 0, len(task.TaskTags))
+	for tag := range task.TaskTags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		selectors = append(selectors, makeSelector("tag", fmt.Sprintf("%s:%s", tag, task.TaskTags[tag])))
+	}
+
+	return &workloadattestor.AttestResponse{
+		Selectors: selectors,
+	}, nil
+}
+
+func (p *ECSPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(Configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, ecsErr.Wrap(err)
+	}
+
+	docker, err := p.hooks.newDockerClient(config)
+	if err != nil {
+		return nil, ecsErr.New("unable to create docker client: %v", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	p.docker = docker
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *ECSPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *ECSPlugin) getDockerClient() (dockerClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config == nil {
+		return nil, ecsErr.New("not configured")
+	}
+	return p.docker, nil
+}
+
+// getContainerID returns the IDs of the ECS task and container the process
+// runs in, taken from its cgroup paths. It returns empty strings if the
+// process does not run in an ECS task.
+func (p *ECSPlugin) getContainerID(pid int32) (string, string, error) {
+	cgroupList, err := cgroups.GetCgroups(pid, p.hooks.fs)
+	if err != nil {
+		return "", "", ecsErr.New("unable to get cgroups: %v", err)
+	}
+
+	for _, cgroup := range cgroupList {
+		// Example entry:
+		// "9:memory:/ecs/8f1ad7b16b0a4ea2a3d0e4a5b8b5bb4c/9bca8d63d5fa610783847915bcff0ecac1273e5b4bed3f6fa1b07350e0135961"
+		if m := containerIDRE.FindStringSubmatch(cgroup.GroupPath); m != nil {
+			return m[1], m[2], nil
+		}
+	}
+	return "", "", nil
+}
+
+// getTaskMetadata fetches the metadata of the task, including its tags, from
+// the task metadata endpoint of one of its containers
+func (p *ECSPlugin) getTaskMetadata(ctx context.Context, metadataURI string) (*taskMetadata, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(metadataURI, "/")+"/taskWithTags", nil)
+	if err != nil {
+		return nil, ecsErr.New("invalid task metadata endpoint %q: %v", metadataURI, err)
+	}
+	resp, err := p.hooks.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, ecsErr.New("unable to get task metadata: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, ecsErr.New("unexpected task metadata status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	task := new(taskMetadata)
+	if err := json.NewDecoder(resp.Body).Decode(task); err != nil {
+		return nil, ecsErr.New("unable to decode task metadata: %v", err)
+	}
+	return task, nil
+}
+
+func newDockerClient(config *Configuration) (dockerClient, error) {
+	var opts []func(*dockerclient.Client) error
+	if config.DockerSocketPath != "" {
+		opts = append(opts, dockerclient.WithHost(config.DockerSocketPath))
+	}
+	if config.DockerVersion != "" {
+		opts = append(opts, dockerclient.WithVersion(config.DockerVersion))
+	}
+	return dockerclient.NewClientWithOpts(opts...)
+}
+
+// lookupEnv returns the value of the variable in a list of KEY=value pairs
+func lookupEnv(env []string, key string) string {
+	for _, kv := range env {
+		if strings.HasPrefix(kv, key+"=") {
+			return kv[len(key)+1:]
+		}
+	}
+	return ""
+}
+
+// clusterName returns the name of the cluster from its ARN, e.g.
+// "arn:aws:ecs:us-west-2:111122223333:cluster/default". Cluster names are
+// returned unchanged.
+func clusterName(cluster string) string {
+	if i := strings.LastIndex(cluster, ":cluster/"); i >= 0 {
+		return cluster[i+len(":cluster/"):]
+	}
+	return cluster
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  selectorType,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
@@ -0,0 +1,228 @@
+package ecs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	webID       = "9bca8d63d5fa610783847915bcff0ecac1273e5b4bed3f6fa1b07350e0135961"
+	batchID     = "6469646e742065787065637420616e796f6e6520746f20726561642074686973"
+	noEnvID     = "2235ebefd9babe0dde4df4e7c49708e24fb31fb851edea55c0ee29a18273cdf4"
+	brokenID    = "c2b2c1b5e8f2f1e3d4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7"
+	missingID   = "0000000000000000000000000000000000000000000000000000000000000000"
+	webTaskPath = "/v4/web-container"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestECSPlugin(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	dir    string
+	server *httptest.Server
+	p      *workloadattestor.BuiltIn
+}
+
+func (s *Suite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "ecs-workload-attestor-test-")
+	s.Require().NoError(err)
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case webTaskPath + "/taskWithTags":
+			w.Write([]byte(`{
+				"Cluster": "arn:aws:ecs:us-west-2:111122223333:cluster/production",
+				"TaskARN": "arn:aws:ecs:us-west-2:111122223333:task/production/8f1ad7b16b0a4ea2a3d0e4a5b8b5bb4c",
+				"Family": "web",
+				"Revision": "7",
+				"ServiceName": "web-frontend",
+				"LaunchType": "EC2",
+				"TaskTags": {"team": "storefront", "env": "prod"}
+			}`))
+		case "/v4/batch-container/taskWithTags":
+			w.Write([]byte(`{
+				"Cluster": "default",
+				"TaskARN": "arn:aws:ecs:us-west-2:111122223333:task/1c4f6e0e2a3d4b5c8f7a6b5c4d3e2f1a",
+				"Family": "nightly-report",
+				"Revision": "1",
+				"LaunchType": "EXTERNAL"
+			}`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+
+	containers := map[string]types.ContainerJSON{
+		webID:    makeContainer("ECS_CONTAINER_METADATA_URI_V4=" + s.server.URL + webTaskPath),
+		batchID:  makeContainer("HOME=/root", "ECS_CONTAINER_METADATA_URI_V4="+s.server.URL+"/v4/batch-container"),
+		noEnvID:  makeContainer("HOME=/root"),
+		brokenID: makeContainer("ECS_CONTAINER_METADATA_URI_V4=" + s.server.URL + "/v4/gone"),
+	}
+
+	p := New()
+	p.hooks.fs = fakeFS{dir: s.dir}
+	p.hooks.newDockerClient = func(config *Configuration) (dockerClient, error) {
+		return fakeDocker(containers), nil
+	}
+	s.p = workloadattestor.NewBuiltIn(p)
+
+	_, err = s.p.Configure(ctx, &spi.ConfigureRequest{})
+	s.Require().NoError(err)
+}
+
+func (s *Suite) TearDownTest() {
+	s.server.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *Suite) TestAttest() {
+	testCases := []struct {
+		name      string
+		cgroups   string
+		err       string
+		selectors []string
+	}{
+		{
+			name:    "service task on EC2",
+			cgroups: "9:memory:/ecs/8f1ad7b16b0a4ea2a3d0e4a5b8b5bb4c/" + webID + "\n",
+			selectors: []string{
+				"cluster:production",
+				"task_family:web",
+				"task_revision:7",
+				"service:web-frontend",
+				"launch_type:EC2",
+				"tag:env:prod",
+				"tag:team:storefront",
+			},
+		},
+		{
+			name:    "standalone task without tags",
+			cgroups: "9:memory:/ecs/1c4f6e0e2a3d4b5c8f7a6b5c4d3e2f1a/" + batchID + "\n",
+			selectors: []string{
+				"cluster:default",
+				"task_family:nightly-report",
+				"task_revision:1",
+				"launch_type:EXTERNAL",
+			},
+		},
+		{
+			name:    "task metadata of another task",
+			cgroups: "9:memory:/ecs/1c4f6e0e2a3d4b5c8f7a6b5c4d3e2f1a/" + webID + "\n",
+			err:     `ecs: task metadata for "arn:aws:ecs:us-west-2:111122223333:task/production/8f1ad7b16b0a4ea2a3d0e4a5b8b5bb4c" does not match task "1c4f6e0e2a3d4b5c8f7a6b5c4d3e2f1a"`,
+		},
+		{
+			name:    "not in an ECS task",
+			cgroups: "9:memory:/docker/" + webID + "\n",
+		},
+		{
+			name:    "container not found",
+			cgroups: "9:memory:/ecs/8f1ad7b16b0a4ea2a3d0e4a5b8b5bb4c/" + missingID + "\n",
+			err:     `ecs: unable to inspect container "` + missingID + `": no such container`,
+		},
+		{
+			name:    "container without metadata endpoint",
+			cgroups: "9:memory:/ecs/8f1ad7b16b0a4ea2a3d0e4a5b8b5bb4c/" + noEnvID + "\n",
+			err:     `ecs: container "` + noEnvID + `" has no task metadata endpoint`,
+		},
+		{
+			name:    "task metadata unavailable",
+			cgroups: "9:memory:/ecs/8f1ad7b16b0a4ea2a3d0e4a5b8b5bb4c/" + brokenID + "\n",
+			err:     "ecs: unexpected task metadata status code 404: not found",
+		},
+	}
+
+	for _, testCase := range testCases {
+		s.T().Run(testCase.name, func(t *testing.T) {
+			s.writeCgroups(123, testCase.cgroups)
+
+			resp, err := s.p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 123})
+			if testCase.err != "" {
+				s.Require().EqualError(err, testCase.err)
+				s.Require().Nil(resp)
+				return
+			}
+
+			s.Require().NoError(err)
+			s.Require().NotNil(resp)
+			var selectors []string
+			for _, selector := range resp.Selectors {
+				s.Require().Equal("ecs", selector.Type)
+				selectors = append(selectors, selector.Value)
+			}
+			s.Require().Equal(testCase.selectors, selectors)
+		})
+	}
+}
+
+func (s *Suite) TestAttestNotConfigured() {
+	p := workloadattestor.NewBuiltIn(New())
+	resp, err := p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 123})
+	s.Require().EqualError(err, "ecs: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestConfigure() {
+	resp, err := s.p.Configure(ctx, &spi.ConfigureRequest{Configuration: "blah"})
+	s.Require().Error(err)
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestGetPluginInfo() {
+	resp, err := s.p.GetPluginInfo(ctx, &spi.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+}
+
+func (s *Suite) writeCgroups(pid int32, cgroups string) {
+	dir := filepath.Join(s.dir, "proc", fmt.Sprint(pid))
+	s.Require().NoError(os.MkdirAll(dir, 0755))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "cgroup"), []byte(cgroups), 0644))
+}
+
+func makeContainer(env ...string) types.ContainerJSON {
+	return types.ContainerJSON{
+		Config: &container.Config{
+			Env: env,
+		},
+	}
+}
+
+type fakeDocker map[string]types.ContainerJSON
+
+func (d fakeDocker) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	container, ok := d[containerID]
+	if !ok {
+		return types.ContainerJSON{}, errors.New("no such container")
+	}
+	return container, nil
+}
+
+// fakeFS opens files relative to a directory
+type fakeFS struct {
+	dir string
+}
+
+func (fs fakeFS) Open(name string) (*os.File, error) {
+	return os.Open(filepath.Join(fs.dir, name))
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/hashicorp/hcl"
 	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/agent/common/sandbox"
 	"github.com/spiffe/spire/proto/agent/workloadattestor"
 	"github.com/spiffe/spire/proto/common"
 	spi "github.com/spiffe/spire/proto/common/plugin"
@@ -40,13 +41,28 @@ type k8sPlugin struct {
 	pollRetryInterval   time.Duration
 	httpClient          httpClient
 	fs                  cgroups.FileSystem
+	sandbox             sandbox.Detector
 	mtx                 *sync.RWMutex
+
+	// labelAllowlist restricts the pod labels producing selectors. If nil,
+	// all labels do.
+	labelAllowlist map[string]bool
+	// annotationAllowlist holds the pod annotations producing selectors
+	annotationAllowlist map[string]bool
 }
 
 type k8sPluginConfig struct {
 	KubeletReadOnlyPort int    `hcl:"kubelet_read_only_port"`
 	MaxPollAttempts     int    `hcl:"max_poll_attempts"`
 	PollRetryInterval   string `hcl:"poll_retry_interval"`
+
+	// PodLabelAllowlist, if set, restricts the pod labels producing
+	// selectors to those with the listed keys
+	PodLabelAllowlist []string `hcl:"pod_label_allowlist"`
+
+	// PodAnnotationAllowlist lists the keys of the pod annotations producing
+	// selectors
+	PodAnnotationAllowlist []string `hcl:"pod_annotation_allowlist"`
 }
 
 type podInfo struct {
@@ -55,10 +71,12 @@ type podInfo struct {
 		UID             string            `json:"uid"`
 		Namespace       string            `json:"namespace"`
 		Labels          map[string]string `json:"labels"`
+		Annotations     map[string]string `json:"annotations"`
 		OwnerReferences []struct {
-			Kind string `json:"kind"`
-			UID  string `json:"uid"`
-			Name string `json:"name"`
+			Kind       string `json:"kind"`
+			UID        string `json:"uid"`
+			Name       string `json:"name"`
+			Controller bool   `json:"controller"`
 		} `json:"ownerReferences"`
 	} `json:"metadata"`
 	Spec struct {
@@ -68,7 +86,17 @@ type podInfo struct {
 			Name  string `json:"name"`
 			Image string `json:"image"`
 		} `json:"containers"`
-		NodeName string `json:"nodeName"`
+		NodeName         string `json:"nodeName"`
+		RuntimeClassName string `json:"runtimeClassName"`
+		Volumes          []struct {
+			Projected *struct {
+				Sources []struct {
+					ServiceAccountToken *struct {
+						Audience string `json:"audience"`
+					} `json:"serviceAccountToken"`
+				} `json:"sources"`
+			} `json:"projected"`
+		} `json:"volumes"`
 	} `json:"spec"`
 	Status podStatus `json:"status"`
 }
@@ -96,28 +124,18 @@ func (p *k8sPlugin) Attest(ctx context.Context, req *workloadattestor.AttestRequ
 	p.mtx.RLock()
 	defer p.mtx.RUnlock()
 
-	cgroups, err := cgroups.GetCgroups(req.Pid, p.fs)
+	cgroupList, err := cgroups.GetCgroups(req.Pid, p.fs)
 	if err != nil {
 		return nil, k8sErr.Wrap(err)
 	}
 
 	var containerID string
-	for _, cgroup := range cgroups {
-		// We are only interested in kube pods entries. Example entry:
+	for _, cgroup := range cgroupList {
+		// We are only interested in kube pods entries. Example entries:
 		// 11:hugetlb:/kubepods/burstable/pod2c48913c-b29f-11e7-9350-020968147796/9bca8d63d5fa610783847915bcff0ecac1273e5b4bed3f6fa1b07350e0135961
-		if len(cgroup.GroupPath) < 9 {
-			continue
-		}
-
-		substring := cgroup.GroupPath[:9]
-		if substring == "/kubepods" {
-			parts := strings.Split(cgroup.GroupPath, "/")
-
-			if len(parts) < 5 {
-				log.Printf("Kube pod entry found, but without container id: %v", substring)
-				continue
-			}
-			containerID = parts[4]
+		// 0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod2c48913c_b29f_11e7_9350_020968147796.slice/docker-9bca8d63d5fa610783847915bcff0ecac1273e5b4bed3f6fa1b07350e0135961.scope
+		if container, ok := cgroups.FindPodContainer(cgroup.GroupPath); ok {
+			containerID = container.ID
 			break
 		}
 	}
@@ -141,8 +159,17 @@ func (p *k8sPlugin) Attest(ctx context.Context, req *workloadattestor.AttestRequ
 			status, lookup := lookUpContainerInPod(containerID, item.Status)
 			switch lookup {
 			case containerInPod:
+				selectors := p.getSelectorsFromPodInfo(item, status)
+				// the handler of the runtime class is not known to the
+				// kubelet, so the class is expected to be named after it
+				// (e.g. "gvisor" or "kata-qemu")
+				if sb, ok := p.sandbox.Detect(ctx, req.Pid, item.Spec.RuntimeClassName); ok {
+					for _, value := range sb.SelectorValues() {
+						selectors = append(selectors, makeSelector("%s", value))
+					}
+				}
 				return &workloadattestor.AttestResponse{
-					Selectors: getSelectorsFromPodInfo(item, status),
+					Selectors: selectors,
 				}, nil
 			case containerMaybeInPod:
 				notAllContainersReady = true
@@ -234,7 +261,7 @@ func lookUpContainerInPod(containerID string, status podStatus) (*containerStatu
 	return nil, containerNotInPod
 }
 
-func getSelectorsFromPodInfo(info *podInfo, status *containerStatus) []*common.Selector {
+func (p *k8sPlugin) getSelectorsFromPodInfo(info *podInfo, status *containerStatus) []*common.Selector {
 	selectors := []*common.Selector{
 		makeSelector("sa:%s", info.Spec.ServiceAccountName),
 		makeSelector("ns:%s", info.Metadata.Namespace),
@@ -245,11 +272,41 @@ func getSelectorsFromPodInfo(info *podInfo, status *containerStatus) []*common.S
 	}
 
 	for k, v := range info.Metadata.Labels {
-		selectors = append(selectors, makeSelector("pod-label:%s:%s", k, v))
+		if p.labelAllowlist == nil || p.labelAllowlist[k] {
+			selectors = append(selectors, makeSelector("pod-label:%s:%s", k, v))
+		}
+	}
+	for k, v := range info.Metadata.Annotations {
+		if p.annotationAllowlist[k] {
+			selectors = append(selectors, makeSelector("pod-annotation:%s:%s", k, v))
+		}
 	}
 	for _, ownerReference := range info.Metadata.OwnerReferences {
 		selectors = append(selectors, makeSelector("pod-owner:%s:%s", ownerReference.Kind, ownerReference.Name))
 		selectors = append(selectors, makeSelector("pod-owner-uid:%s:%s", ownerReference.Kind, ownerReference.UID))
+		if !ownerReference.Controller {
+			continue
+		}
+		switch ownerReference.Kind {
+		case "ReplicaSet":
+			// the ReplicaSets of a Deployment are named after it, with the
+			// hash of the pod template as suffix
+			if hash := info.Metadata.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(ownerReference.Name, "-"+hash) {
+				selectors = append(selectors, makeSelector("pod-deployment:%s", strings.TrimSuffix(ownerReference.Name, "-"+hash)))
+			}
+		case "StatefulSet":
+			selectors = append(selectors, makeSelector("pod-statefulset:%s", ownerReference.Name))
+		}
+	}
+	for _, volume := range info.Spec.Volumes {
+		if volume.Projected == nil {
+			continue
+		}
+		for _, source := range volume.Projected.Sources {
+			if source.ServiceAccountToken != nil && source.ServiceAccountToken.Audience != "" {
+				selectors = append(selectors, makeSelector("sa-token-audience:%s", source.ServiceAccountToken.Audience))
+			}
+		}
 	}
 
 	return selectors
@@ -293,6 +350,11 @@ func (p *k8sPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*
 	p.kubeletReadOnlyPort = config.KubeletReadOnlyPort
 	p.pollRetryInterval = pollRetryInterval
 	p.maxPollAttempts = config.MaxPollAttempts
+	p.labelAllowlist = nil
+	if len(config.PodLabelAllowlist) > 0 {
+		p.labelAllowlist = makeAllowlist(config.PodLabelAllowlist)
+	}
+	p.annotationAllowlist = makeAllowlist(config.PodAnnotationAllowlist)
 	return &spi.ConfigureResponse{}, nil
 }
 
@@ -300,10 +362,19 @@ func (*k8sPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*sp
 	return &spi.GetPluginInfoResponse{}, nil
 }
 
+func makeAllowlist(keys []string) map[string]bool {
+	allowlist := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		allowlist[key] = true
+	}
+	return allowlist
+}
+
 func New() *k8sPlugin {
 	return &k8sPlugin{
 		mtx:        &sync.RWMutex{},
 		httpClient: &http.Client{},
 		fs:         cgroups.OSFileSystem{},
+		sandbox:    sandbox.NewDetector(),
 	}
 }
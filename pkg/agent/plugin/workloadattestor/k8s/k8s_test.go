@@ -11,6 +11,7 @@ import (
 	"time"
 
 	mock "github.com/golang/mock/gomock"
+	"github.com/spiffe/spire/pkg/agent/common/sandbox"
 	"github.com/spiffe/spire/pkg/common/util"
 	"github.com/spiffe/spire/proto/agent/workloadattestor"
 	"github.com/spiffe/spire/proto/common"
@@ -26,7 +27,9 @@ const (
 	invalidConfig             = `{"kubelet_read_only_port":"invalid"}`
 	podListFilePath           = "../../../../../test/fixture/workloadattestor/k8s/pod_list.json"
 	podListNotRunningFilePath = "../../../../../test/fixture/workloadattestor/k8s/pod_list_not_running.json"
+	podListDeploymentFilePath = "../../../../../test/fixture/workloadattestor/k8s/pod_list_deployment.json"
 	cgPidInPodFilePath        = "../../../../../test/fixture/workloadattestor/k8s/cgroups_pid_in_pod.txt"
+	cgPidInPodV2FilePath      = "../../../../../test/fixture/workloadattestor/k8s/cgroups_pid_in_pod_v2.txt"
 	cgInitPidInPodFilePath    = "../../../../../test/fixture/workloadattestor/k8s/cgroups_init_pid_in_pod.txt"
 	cgPidNotInPodFilePath     = "../../../../../test/fixture/workloadattestor/k8s/cgroups_pid_not_in_pod.txt"
 )
@@ -57,6 +60,7 @@ func (s *K8sAttestorSuite) SetupTest() {
 	p := New()
 	p.fs = s.fs
 	p.httpClient = s.httpClient
+	p.sandbox = fakeSandboxDetector{}
 
 	s.p = workloadattestor.NewBuiltIn(p)
 	s.configure(time.Millisecond)
@@ -107,6 +111,17 @@ func (s *K8sAttestorSuite) TestAttestWithPidInPod() {
 	s.Require().NotEmpty(resp.Selectors)
 }
 
+func (s *K8sAttestorSuite) TestAttestWithPidInPodCgroupsV2() {
+	s.addPodListResponse(podListFilePath)
+	s.addCgroupsResponse(cgPidInPodV2FilePath)
+
+	resp, err := s.p.Attest(context.Background(), &workloadattestor.AttestRequest{
+		Pid: int32(pid),
+	})
+	s.Require().NoError(err)
+	s.Require().NotEmpty(resp.Selectors)
+}
+
 func (s *K8sAttestorSuite) TestAttestWithInitPidInPod() {
 	s.addPodListResponse(podListFilePath)
 	s.addCgroupsResponse(cgInitPidInPodFilePath)
@@ -145,6 +160,42 @@ func (s *K8sAttestorSuite) TestAttestWithPidInPodAfterRetry() {
 	}, resp.Selectors)
 }
 
+func (s *K8sAttestorSuite) TestAttestWithDeploymentPod() {
+	_, err := s.p.Configure(context.Background(), &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+			kubelet_read_only_port = %d
+			pod_label_allowlist = ["k8s-app"]
+			pod_annotation_allowlist = ["example.org/tier", "example.org/missing"]
+		`, kubeletReadOnlyPort),
+	})
+	s.Require().NoError(err)
+	s.addPodListResponse(podListDeploymentFilePath)
+	s.addCgroupsResponse(cgPidInPodFilePath)
+
+	resp, err := s.p.Attest(context.Background(), &workloadattestor.AttestRequest{
+		Pid: int32(pid),
+	})
+	s.Require().NoError(err)
+
+	util.SortSelectors(resp.Selectors)
+	s.Require().Equal([]*common.Selector{
+		{Type: "k8s", Value: "container-image:localhost/spiffe/blog:latest"},
+		{Type: "k8s", Value: "container-name:blog"},
+		{Type: "k8s", Value: "node-name:k8s-node-1"},
+		{Type: "k8s", Value: "ns:default"},
+		{Type: "k8s", Value: "pod-annotation:example.org/tier:frontend"},
+		{Type: "k8s", Value: "pod-deployment:blog"},
+		{Type: "k8s", Value: "pod-label:k8s-app:blog"},
+		{Type: "k8s", Value: "pod-owner-uid:ReplicaSet:2c401175-b29f-11e7-9350-020968147796"},
+		{Type: "k8s", Value: "pod-owner:ReplicaSet:blog-5d8f9b7c6d"},
+		{Type: "k8s", Value: "pod-uid:2c48913c-b29f-11e7-9350-020968147796"},
+		{Type: "k8s", Value: "runtime:gvisor"},
+		{Type: "k8s", Value: "runtime:gvisor:release-20240311.0"},
+		{Type: "k8s", Value: "sa-token-audience:spire-server"},
+		{Type: "k8s", Value: "sa:default"},
+	}, resp.Selectors)
+}
+
 func (s *K8sAttestorSuite) TestAttestWithPidNotInPodCancelsEarly() {
 	// Configure the poll interval really far out to make sure cancellation is
 	// the cause for return.
@@ -213,3 +264,25 @@ func (s *K8sAttestorSuite) TestGetPluginInfo() {
 	s.NoError(err)
 	s.Equal(&spi.GetPluginInfoResponse{}, resp)
 }
+
+func (s *K8sAttestorSuite) TestStatefulSetSelectors() {
+	info := new(podInfo)
+	info.Metadata.OwnerReferences = append(info.Metadata.OwnerReferences, struct {
+		Kind       string `json:"kind"`
+		UID        string `json:"uid"`
+		Name       string `json:"name"`
+		Controller bool   `json:"controller"`
+	}{Kind: "StatefulSet", UID: "uid", Name: "db", Controller: true})
+
+	selectors := New().getSelectorsFromPodInfo(info, &containerStatus{})
+	s.Require().Contains(selectors, &common.Selector{Type: "k8s", Value: "pod-statefulset:db"})
+}
+
+type fakeSandboxDetector struct{}
+
+func (fakeSandboxDetector) Detect(ctx context.Context, pid int32, runtime string) (sandbox.Sandbox, bool) {
+	if runtime != "gvisor" {
+		return sandbox.Sandbox{}, false
+	}
+	return sandbox.Sandbox{Type: sandbox.TypeGVisor, Version: "release-20240311.0"}, true
+}
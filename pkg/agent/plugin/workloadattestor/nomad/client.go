@@ -0,0 +1,93 @@
+package nomad
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/zeebo/errs"
+)
+
+// allocation holds the allocation details returned by the Nomad API that
+// are relevant to workload attestation
+type allocation struct {
+	ID           string `json:"ID"`
+	Namespace    string `json:"Namespace"`
+	JobID        string `json:"JobID"`
+	TaskGroup    string `json:"TaskGroup"`
+	ClientStatus string `json:"ClientStatus"`
+}
+
+// apiClient is the subset of the Nomad HTTP API used by the attestor
+type apiClient interface {
+	GetAllocation(ctx context.Context, allocationID string) (*allocation, error)
+}
+
+// nomadClient implements apiClient using the Nomad HTTP API
+type nomadClient struct {
+	httpClient *http.Client
+	address    string
+	token      string
+}
+
+func newNomadClient(address, token string, roots *x509.CertPool) apiClient {
+	httpClient := http.DefaultClient
+	if roots != nil {
+		httpClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					RootCAs: roots,
+				},
+			},
+		}
+	}
+	return &nomadClient{
+		httpClient: httpClient,
+		address:    address,
+		token:      token,
+	}
+}
+
+func (c *nomadClient) GetAllocation(ctx context.Context, allocationID string) (*allocation, error) {
+	alloc := new(allocation)
+	if err := c.get(ctx, "/v1/allocation/"+url.PathEscape(allocationID), alloc); err != nil {
+		return nil, err
+	}
+	return alloc, nil
+}
+
+func (c *nomadClient) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequest("GET", c.address+path, nil)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	if c.token != "" {
+		req.Header.Set("X-Nomad-Token", c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errs.New("unable to decode response: %v", err)
+	}
+	return nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
@@ -0,0 +1,170 @@
+package nomad
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+const (
+	selectorType = "nomad"
+
+	defaultAddress = "http://127.0.0.1:4646"
+
+	allocationStatusRunning = "running"
+)
+
+var (
+	nomadErr = errs.Class("nomad")
+
+	// taskCgroupRE matches the cgroup of a task run by one of the isolating
+	// Nomad task drivers (e.g. exec or java), capturing the allocation ID
+	// and task name. Examples:
+	// "/nomad/5c3b2bbe-4a2b-e0a4-ae6c-3a5d1ee0a4a1-web" (cgroups v1)
+	// "/nomad.slice/share.slice/5c3b2bbe-4a2b-e0a4-ae6c-3a5d1ee0a4a1.web.scope" (cgroups v2)
+	taskCgroupRE = regexp.MustCompile(`^/nomad(?:\.slice)?/(?:[^/]+\.slice/)?([[:xdigit:]]{8}-[[:xdigit:]]{4}-[[:xdigit:]]{4}-[[:xdigit:]]{4}-[[:xdigit:]]{12})[.-]([^/]+?)(?:\.scope)?$`)
+)
+
+type Configuration struct {
+	// Address is the address of the Nomad HTTP API (default:
+	// "http://127.0.0.1:4646")
+	Address string `hcl:"address"`
+
+	// Token is the ACL token used to read allocations. It needs the
+	// read-job capability in the namespaces of the workloads.
+	Token string `hcl:"token"`
+
+	// CACertPath is the path to the CA certificates used to verify the
+	// Nomad API server certificate. Defaults to the system roots.
+	CACertPath string `hcl:"ca_cert_path"`
+}
+
+type NomadPlugin struct {
+	mu     sync.Mutex
+	config *Configuration
+	client apiClient
+
+	// hooks for tests
+	hooks struct {
+		fs        cgroups.FileSystem
+		newClient func(address, token string, roots *x509.CertPool) apiClient
+	}
+}
+
+func New() *NomadPlugin {
+	p := &NomadPlugin{}
+	p.hooks.fs = cgroups.OSFileSystem{}
+	p.hooks.newClient = newNomadClient
+	return p
+}
+
+func (p *NomadPlugin) Attest(ctx context.Context, req *workloadattestor.AttestRequest) (*workloadattestor.AttestResponse, error) {
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	allocationID, task, err := p.getTask(req.Pid)
+	if err != nil {
+		return nil, err
+	}
+
+	// processes outside of a Nomad task have no nomad selectors
+	if allocationID == "" {
+		return &workloadattestor.AttestResponse{}, nil
+	}
+
+	alloc, err := client.GetAllocation(ctx, allocationID)
+	if err != nil {
+		return nil, nomadErr.New("unable to get allocation %q: %v", allocationID, err)
+	}
+	if alloc.ClientStatus != allocationStatusRunning {
+		return nil, nomadErr.New("allocation %q is not running: status is %q", allocationID, alloc.ClientStatus)
+	}
+
+	return &workloadattestor.AttestResponse{
+		Selectors: []*common.Selector{
+			makeSelector("namespace", alloc.Namespace),
+			makeSelector("job_id", alloc.JobID),
+			makeSelector("task_group", alloc.TaskGroup),
+			makeSelector("task", task),
+		},
+	}, nil
+}
+
+func (p *NomadPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(Configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, nomadErr.New("unable to decode configuration: %v", err)
+	}
+	if config.Address == "" {
+		config.Address = defaultAddress
+	}
+
+	var roots *x509.CertPool
+	if config.CACertPath != "" {
+		certs, err := pemutil.LoadCertificates(config.CACertPath)
+		if err != nil {
+			return nil, nomadErr.New("failed to load CA certificates from %q: %v", config.CACertPath, err)
+		}
+		roots = x509.NewCertPool()
+		for _, cert := range certs {
+			roots.AddCert(cert)
+		}
+	}
+
+	client := p.hooks.newClient(config.Address, config.Token, roots)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	p.client = client
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *NomadPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *NomadPlugin) getClient() (apiClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config == nil {
+		return nil, nomadErr.New("not configured")
+	}
+	return p.client, nil
+}
+
+// getTask returns the allocation ID and task name of the Nomad task the
+// process runs in, taken from its cgroup paths. It returns empty strings if
+// the process does not run in a Nomad task.
+func (p *NomadPlugin) getTask(pid int32) (string, string, error) {
+	cgroupList, err := cgroups.GetCgroups(pid, p.hooks.fs)
+	if err != nil {
+		return "", "", nomadErr.New("unable to get cgroups: %v", err)
+	}
+
+	for _, cgroup := range cgroupList {
+		if m := taskCgroupRE.FindStringSubmatch(cgroup.GroupPath); m != nil {
+			return m[1], m[2], nil
+		}
+	}
+	return "", "", nil
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  selectorType,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
@@ -0,0 +1,197 @@
+package nomad
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	runningID  = "5c3b2bbe-4a2b-e0a4-ae6c-3a5d1ee0a4a1"
+	completeID = "9d1e6b9c-1f0a-7c55-2b7e-0c1f44b4f0e3"
+	missingID  = "00000000-0000-0000-0000-000000000000"
+	token      = "8f0f3a3d-4a0e-3e59-0a14-1b8f1f1c2b6a"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestNomadPlugin(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	dir    string
+	server *httptest.Server
+	p      *workloadattestor.BuiltIn
+}
+
+func (s *Suite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "nomad-workload-attestor-test-")
+	s.Require().NoError(err)
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Nomad-Token") != token {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		switch req.URL.Path {
+		case "/v1/allocation/" + runningID:
+			fmt.Fprintf(w, `{"ID": %q, "Namespace": "payments", "JobID": "api", "TaskGroup": "servers", "ClientStatus": "running"}`, runningID)
+		case "/v1/allocation/" + completeID:
+			fmt.Fprintf(w, `{"ID": %q, "Namespace": "default", "JobID": "batch", "TaskGroup": "workers", "ClientStatus": "complete"}`, completeID)
+		default:
+			http.Error(w, "alloc not found", http.StatusNotFound)
+		}
+	}))
+
+	p := New()
+	p.hooks.fs = fakeFS{dir: s.dir}
+	s.p = workloadattestor.NewBuiltIn(p)
+	s.configure(token)
+}
+
+func (s *Suite) TearDownTest() {
+	s.server.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *Suite) TestAttest() {
+	testCases := []struct {
+		name      string
+		cgroups   string
+		token     string
+		err       string
+		selectors []string
+	}{
+		{
+			name:    "cgroups v1",
+			cgroups: "7:cpuset:/nomad/" + runningID + "-web\n",
+			selectors: []string{
+				"namespace:payments",
+				"job_id:api",
+				"task_group:servers",
+				"task:web",
+			},
+		},
+		{
+			name:    "cgroups v2",
+			cgroups: "0::/nomad.slice/share.slice/" + runningID + ".web-sidecar.scope\n",
+			selectors: []string{
+				"namespace:payments",
+				"job_id:api",
+				"task_group:servers",
+				"task:web-sidecar",
+			},
+		},
+		{
+			name:    "not in a task",
+			cgroups: "0::/system.slice/nomad.service\n",
+		},
+		{
+			name:    "allocation not running",
+			cgroups: "0::/nomad.slice/" + completeID + ".report.scope\n",
+			err:     `nomad: allocation "` + completeID + `" is not running: status is "complete"`,
+		},
+		{
+			name:    "allocation not found",
+			cgroups: "0::/nomad.slice/" + missingID + ".web.scope\n",
+			err:     `nomad: unable to get allocation "` + missingID + `": unexpected status code 404: alloc not found` + "\n",
+		},
+		{
+			name:    "permission denied",
+			cgroups: "7:cpuset:/nomad/" + runningID + "-web\n",
+			token:   "wrong",
+			err:     `nomad: unable to get allocation "` + runningID + `": unexpected status code 403: Permission denied` + "\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		s.T().Run(testCase.name, func(t *testing.T) {
+			if testCase.token != "" {
+				s.configure(testCase.token)
+				defer s.configure(token)
+			}
+			s.writeCgroups(123, testCase.cgroups)
+
+			resp, err := s.p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 123})
+			if testCase.err != "" {
+				s.Require().EqualError(err, testCase.err)
+				s.Require().Nil(resp)
+				return
+			}
+
+			s.Require().NoError(err)
+			s.Require().NotNil(resp)
+			var selectors []string
+			for _, selector := range resp.Selectors {
+				s.Require().Equal("nomad", selector.Type)
+				selectors = append(selectors, selector.Value)
+			}
+			s.Require().Equal(testCase.selectors, selectors)
+		})
+	}
+}
+
+func (s *Suite) TestAttestNotConfigured() {
+	p := workloadattestor.NewBuiltIn(New())
+	resp, err := p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 123})
+	s.Require().EqualError(err, "nomad: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.p.Configure(ctx, &spi.ConfigureRequest{Configuration: "blah"})
+	s.Require().Error(err)
+	s.Require().Nil(resp)
+
+	// missing CA certificates
+	resp, err = s.p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf("ca_cert_path = %q", filepath.Join(s.dir, "missing.pem")),
+	})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "nomad: failed to load CA certificates from")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestGetPluginInfo() {
+	resp, err := s.p.GetPluginInfo(ctx, &spi.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+}
+
+func (s *Suite) configure(token string) {
+	_, err := s.p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf("address = %q\ntoken = %q", s.server.URL, token),
+	})
+	s.Require().NoError(err)
+}
+
+func (s *Suite) writeCgroups(pid int32, cgroups string) {
+	dir := filepath.Join(s.dir, "proc", fmt.Sprint(pid))
+	s.Require().NoError(os.MkdirAll(dir, 0755))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "cgroup"), []byte(cgroups), 0644))
+}
+
+// fakeFS opens files relative to a directory
+type fakeFS struct {
+	dir string
+}
+
+func (fs fakeFS) Open(name string) (*os.File, error) {
+	return os.Open(filepath.Join(fs.dir, name))
+}
@@ -0,0 +1,120 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// apiVersion is the version of the libpod REST API used by the client
+const apiVersion = "v1.0.0"
+
+var (
+	// errNotFound is returned when the container or pod does not exist
+	errNotFound = errors.New("not found")
+)
+
+// containerInfo is the subset of the libpod container inspect data used by
+// the attestor
+type containerInfo struct {
+	ID         string `json:"Id"`
+	Image      string `json:"Image"`
+	ImageName  string `json:"ImageName"`
+	Pod        string `json:"Pod"`
+	OCIRuntime string `json:"OCIRuntime"`
+	Config     struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// podInfo is the subset of the libpod pod inspect data used by the attestor
+type podInfo struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+}
+
+// podmanClient is the subset of the libpod API used by the attestor
+type podmanClient interface {
+	InspectContainer(ctx context.Context, id string) (*containerInfo, error)
+	InspectPod(ctx context.Context, id string) (*podInfo, error)
+}
+
+type httpClient struct {
+	client *http.Client
+}
+
+// newClient returns a client for the libpod API served on the given unix
+// socket
+func newClient(socketPath string) podmanClient {
+	return &httpClient{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+func (c *httpClient) InspectContainer(ctx context.Context, id string) (*containerInfo, error) {
+	info := new(containerInfo)
+	if err := c.get(ctx, "/containers/"+url.PathEscape(id)+"/json", info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (c *httpClient) InspectPod(ctx context.Context, id string) (*podInfo, error) {
+	info := new(podInfo)
+	if err := c.get(ctx, "/pods/"+url.PathEscape(id)+"/json", info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (c *httpClient) get(ctx context.Context, path string, out interface{}) error {
+	// the host is ignored since connections are made to the socket
+	req, err := http.NewRequest("GET", "http://d/"+apiVersion+"/libpod"+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return errNotFound
+	default:
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, readErrorMessage(resp.Body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("unable to decode response: %v", err)
+	}
+	return nil
+}
+
+// readErrorMessage returns the message of a libpod error response, falling
+// back to the raw body
+func readErrorMessage(r io.Reader) string {
+	body, _ := ioutil.ReadAll(io.LimitReader(r, 4096))
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Message != "" {
+		return apiErr.Message
+	}
+	return string(body)
+}
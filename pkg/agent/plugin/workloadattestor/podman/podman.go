@@ -0,0 +1,198 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/agent/common/sandbox"
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+const (
+	selectorType = "podman"
+
+	defaultSocketPath         = "/run/podman/podman.sock"
+	defaultRootlessSocketPath = "/run/user/{uid}/podman/podman.sock"
+)
+
+var (
+	podmanErr = errs.Class("podman")
+
+	// containerIDRE matches the container ID at the end of the cgroup path
+	// of a container process, e.g. "libpod-<id>.scope" with the systemd
+	// cgroup manager or "libpod_parent/libpod-<id>" with cgroupfs. The
+	// "libpod-conmon-<id>" cgroup of the container monitor does not match.
+	containerIDRE = regexp.MustCompile(`/libpod-([[:xdigit:]]{64})(?:\.scope)?(?:/container)?$`)
+
+	// rootlessUIDRE matches the user service of rootless containers, e.g.
+	// "/user.slice/user-1000.slice/user@1000.service/..."
+	rootlessUIDRE = regexp.MustCompile(`/user@(\d+)\.service/`)
+)
+
+type Configuration struct {
+	// SocketPath is the path to the socket of the rootful Podman service
+	// (default: "/run/podman/podman.sock")
+	SocketPath string `hcl:"socket_path"`
+
+	// RootlessSocketPath is the path to the socket of the Podman service of
+	// a user running rootless containers. "{uid}" is replaced with the ID of
+	// the user (default: "/run/user/{uid}/podman/podman.sock").
+	RootlessSocketPath string `hcl:"rootless_socket_path"`
+}
+
+type PodmanPlugin struct {
+	mu     sync.Mutex
+	config *Configuration
+
+	// hooks for tests
+	hooks struct {
+		fs        cgroups.FileSystem
+		newClient func(socketPath string) podmanClient
+		sandbox   sandbox.Detector
+	}
+}
+
+func New() *PodmanPlugin {
+	p := &PodmanPlugin{}
+	p.hooks.fs = cgroups.OSFileSystem{}
+	p.hooks.newClient = newClient
+	p.hooks.sandbox = sandbox.NewDetector()
+	return p
+}
+
+func (p *PodmanPlugin) Attest(ctx context.Context, req *workloadattestor.AttestRequest) (*workloadattestor.AttestResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	containerID, socketPath, err := p.findContainer(config, req.Pid)
+	if err != nil {
+		return nil, err
+	}
+
+	// processes outside of a Podman container have no podman selectors
+	if containerID == "" {
+		return &workloadattestor.AttestResponse{}, nil
+	}
+
+	client := p.hooks.newClient(socketPath)
+	container, err := client.InspectContainer(ctx, containerID)
+	if err != nil {
+		return nil, podmanErr.New("unable to inspect container %q: %v", containerID, err)
+	}
+
+	var selectors []*common.Selector
+	if container.ImageName != "" {
+		selectors = append(selectors, makeSelector("image", container.ImageName))
+	}
+	if container.Image != "" {
+		selectors = append(selectors, makeSelector("image_id", container.Image))
+	}
+
+	labels := make([]string, 0, len(container.Config.Labels))
+	for label := range container.Config.Labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		selectors = append(selectors, makeSelector("label", fmt.Sprintf("%s:%s", label, container.Config.Labels[label])))
+	}
+
+	if container.Pod != "" {
+		pod, err := client.InspectPod(ctx, container.Pod)
+		if err != nil {
+			return nil, podmanErr.New("unable to inspect pod %q: %v", container.Pod, err)
+		}
+		selectors = append(selectors, makeSelector("pod_name", pod.Name))
+	}
+
+	if sb, ok := p.hooks.sandbox.Detect(ctx, req.Pid, container.OCIRuntime); ok {
+		for _, value := range sb.SelectorValues() {
+			selectors = append(selectors, &common.Selector{
+				Type:  selectorType,
+				Value: value,
+			})
+		}
+	}
+
+	return &workloadattestor.AttestResponse{
+		Selectors: selectors,
+	}, nil
+}
+
+func (p *PodmanPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(Configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, podmanErr.Wrap(err)
+	}
+	if config.SocketPath == "" {
+		config.SocketPath = defaultSocketPath
+	}
+	if config.RootlessSocketPath == "" {
+		config.RootlessSocketPath = defaultRootlessSocketPath
+	}
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *PodmanPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *PodmanPlugin) getConfig() (*Configuration, error) {
+	p.mu.Lock()
+	config := p.config
+	p.mu.Unlock()
+	if config == nil {
+		return nil, podmanErr.New("not configured")
+	}
+	return config, nil
+}
+
+func (p *PodmanPlugin) setConfig(config *Configuration) {
+	p.mu.Lock()
+	p.config = config
+	p.mu.Unlock()
+}
+
+// findContainer returns the ID of the Podman container the process runs in,
+// along with the socket of the Podman service managing it, taken from the
+// cgroup paths of the process. It returns an empty ID if the process does
+// not run in a Podman container.
+func (p *PodmanPlugin) findContainer(config *Configuration, pid int32) (string, string, error) {
+	cgroupList, err := cgroups.GetCgroups(pid, p.hooks.fs)
+	if err != nil {
+		return "", "", podmanErr.New("unable to get cgroups: %v", err)
+	}
+
+	for _, cgroup := range cgroupList {
+		m := containerIDRE.FindStringSubmatch(cgroup.GroupPath)
+		if m == nil {
+			continue
+		}
+		// containers of rootless users are managed by the Podman service
+		// of the user
+		if uid := rootlessUIDRE.FindStringSubmatch(cgroup.GroupPath); uid != nil {
+			return m[1], strings.Replace(config.RootlessSocketPath, "{uid}", uid[1], -1), nil
+		}
+		return m[1], config.SocketPath, nil
+	}
+	return "", "", nil
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  selectorType,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
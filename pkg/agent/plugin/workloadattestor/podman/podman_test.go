@@ -0,0 +1,248 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/agent/common/sandbox"
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	webID      = "5fc5a1d1a2c5a3ecb9a4e5c4d97b5ca0c7b3cd5ae5f5c0b6a0f4c5a3ecb9a4e5"
+	dbID       = "8a33e8b9e1e5a0c4c0b5ff3f16a0e48f1d3f7a7e26d7b0f52d5e8e45b1a0c9d2"
+	rootlessID = "c2b2c1b5e8f2f1e3d4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7"
+	missingID  = "0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestPodmanPlugin(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	dir     string
+	servers []*http.Server
+	p       *workloadattestor.BuiltIn
+}
+
+func (s *Suite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "podman-workload-attestor-test-")
+	s.Require().NoError(err)
+
+	s.serve(filepath.Join(s.dir, "podman.sock"), map[string]string{
+		"/v1.0.0/libpod/containers/" + webID + "/json": `{
+			"Id": "` + webID + `",
+			"Image": "sha256:9a3c2d1f",
+			"ImageName": "registry.access.redhat.com/ubi8/nginx-118:latest",
+			"Pod": "f2a1",
+			"Config": {"Labels": {"tier": "web", "app": "store"}}
+		}`,
+		"/v1.0.0/libpod/pods/f2a1/json": `{"Id": "f2a1", "Name": "store"}`,
+		"/v1.0.0/libpod/containers/" + dbID + "/json": `{
+			"Id": "` + dbID + `",
+			"Image": "sha256:7b1e",
+			"ImageName": "docker.io/library/postgres:12",
+			"Pod": "dead",
+			"Config": {}
+		}`,
+	})
+	s.serve(filepath.Join(s.dir, "user", "1000", "podman.sock"), map[string]string{
+		"/v1.0.0/libpod/containers/" + rootlessID + "/json": `{
+			"Id": "` + rootlessID + `",
+			"Image": "sha256:4c5d",
+			"ImageName": "docker.io/library/redis:6",
+			"OCIRuntime": "runsc",
+			"Config": {"Labels": null}
+		}`,
+	})
+
+	p := New()
+	p.hooks.fs = fakeFS{dir: s.dir}
+	p.hooks.sandbox = fakeSandboxDetector{}
+	s.p = workloadattestor.NewBuiltIn(p)
+	s.configure()
+}
+
+func (s *Suite) TearDownTest() {
+	for _, server := range s.servers {
+		server.Close()
+	}
+	os.RemoveAll(s.dir)
+}
+
+func (s *Suite) TestAttest() {
+	testCases := []struct {
+		name      string
+		cgroups   string
+		err       string
+		selectors []string
+	}{
+		{
+			name:    "rootful container in a pod",
+			cgroups: "0::/machine.slice/libpod-" + webID + ".scope/container\n",
+			selectors: []string{
+				"image:registry.access.redhat.com/ubi8/nginx-118:latest",
+				"image_id:sha256:9a3c2d1f",
+				"label:app:store",
+				"label:tier:web",
+				"pod_name:store",
+			},
+		},
+		{
+			name:    "rootful container with cgroupfs",
+			cgroups: "11:memory:/libpod_parent/libpod-" + webID + "\n",
+			selectors: []string{
+				"image:registry.access.redhat.com/ubi8/nginx-118:latest",
+				"image_id:sha256:9a3c2d1f",
+				"label:app:store",
+				"label:tier:web",
+				"pod_name:store",
+			},
+		},
+		{
+			name:    "rootless container",
+			cgroups: "0::/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + rootlessID + ".scope\n",
+			selectors: []string{
+				"image:docker.io/library/redis:6",
+				"image_id:sha256:4c5d",
+				"runtime:gvisor",
+				"runtime:gvisor:release-20240311.0",
+			},
+		},
+		{
+			name:    "container monitor",
+			cgroups: "0::/machine.slice/libpod-conmon-" + webID + ".scope\n",
+		},
+		{
+			name:    "not in a container",
+			cgroups: "0::/user.slice/user-1000.slice/session-2.scope\n",
+		},
+		{
+			name:    "container not found",
+			cgroups: "0::/machine.slice/libpod-" + missingID + ".scope\n",
+			err:     `podman: unable to inspect container "` + missingID + `": not found`,
+		},
+		{
+			name:    "pod not found",
+			cgroups: "0::/machine.slice/libpod-" + dbID + ".scope\n",
+			err:     `podman: unable to inspect pod "dead": not found`,
+		},
+		{
+			name:    "podman service not running",
+			cgroups: "0::/user.slice/user-1001.slice/user@1001.service/user.slice/libpod-" + rootlessID + ".scope\n",
+			err:     `podman: unable to inspect container "` + rootlessID + `": Get`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		s.T().Run(testCase.name, func(t *testing.T) {
+			s.writeCgroups(123, testCase.cgroups)
+
+			resp, err := s.p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 123})
+			if testCase.err != "" {
+				s.Require().Error(err)
+				s.Require().True(strings.HasPrefix(err.Error(), testCase.err), "unexpected error: %v", err)
+				s.Require().Nil(resp)
+				return
+			}
+
+			s.Require().NoError(err)
+			s.Require().NotNil(resp)
+			var selectors []string
+			for _, selector := range resp.Selectors {
+				s.Require().Equal("podman", selector.Type)
+				selectors = append(selectors, selector.Value)
+			}
+			s.Require().Equal(testCase.selectors, selectors)
+		})
+	}
+}
+
+func (s *Suite) TestAttestNotConfigured() {
+	p := workloadattestor.NewBuiltIn(New())
+	resp, err := p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 123})
+	s.Require().EqualError(err, "podman: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestConfigure() {
+	resp, err := s.p.Configure(ctx, &spi.ConfigureRequest{Configuration: "blah"})
+	s.Require().Error(err)
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestGetPluginInfo() {
+	resp, err := s.p.GetPluginInfo(ctx, &spi.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+}
+
+func (s *Suite) configure() {
+	_, err := s.p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf("socket_path = %q\nrootless_socket_path = %q",
+			filepath.Join(s.dir, "podman.sock"),
+			filepath.Join(s.dir, "user", "{uid}", "podman.sock")),
+	})
+	s.Require().NoError(err)
+}
+
+func (s *Suite) writeCgroups(pid int32, cgroups string) {
+	dir := filepath.Join(s.dir, "proc", fmt.Sprint(pid))
+	s.Require().NoError(os.MkdirAll(dir, 0755))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "cgroup"), []byte(cgroups), 0644))
+}
+
+// serve serves the responses, keyed by path, on a fake libpod API socket
+func (s *Suite) serve(socketPath string, responses map[string]string) {
+	s.Require().NoError(os.MkdirAll(filepath.Dir(socketPath), 0755))
+	listener, err := net.Listen("unix", socketPath)
+	s.Require().NoError(err)
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			response, ok := responses[req.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprintf(w, `{"cause": "no such object", "message": "no such object: %s", "response": 404}`, req.URL.Path)
+				return
+			}
+			w.Write([]byte(response))
+		}),
+	}
+	go server.Serve(listener)
+	s.servers = append(s.servers, server)
+}
+
+// fakeFS opens files relative to a directory
+type fakeFS struct {
+	dir string
+}
+
+func (fs fakeFS) Open(name string) (*os.File, error) {
+	return os.Open(filepath.Join(fs.dir, name))
+}
+
+type fakeSandboxDetector struct{}
+
+func (fakeSandboxDetector) Detect(ctx context.Context, pid int32, runtime string) (sandbox.Sandbox, bool) {
+	if runtime != "runsc" {
+		return sandbox.Sandbox{}, false
+	}
+	return sandbox.Sandbox{Type: sandbox.TypeGVisor, Version: "release-20240311.0"}, true
+}
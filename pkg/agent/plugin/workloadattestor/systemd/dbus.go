@@ -0,0 +1,442 @@
+package systemd
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A minimal D-Bus client, implementing only what is needed to query systemd
+// for the unit a process belongs to: EXTERNAL authentication over a unix
+// socket and method calls with basic argument and return types.
+
+const (
+	dbusTimeout = 5 * time.Second
+
+	// D-Bus message types
+	msgMethodCall   = 1
+	msgMethodReturn = 2
+	msgError        = 3
+
+	// D-Bus header field codes
+	fieldPath        = 1
+	fieldInterface   = 2
+	fieldMember      = 3
+	fieldErrorName   = 4
+	fieldReplySerial = 5
+	fieldDestination = 6
+	fieldSignature   = 8
+
+	// limits the size of messages read from the bus
+	maxMessageSize = 1 << 20
+)
+
+// dbusError is an error reply received from the bus
+type dbusError struct {
+	Name    string
+	Message string
+}
+
+func (e *dbusError) Error() string {
+	if e.Message == "" {
+		return e.Name
+	}
+	return fmt.Sprintf("%s: %s", e.Name, e.Message)
+}
+
+type dbusConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	serial uint32
+}
+
+// dialDBus connects and authenticates to the bus listening on the given unix
+// socket path.
+func dialDBus(ctx context.Context, socketPath string) (*dbusConn, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(dbusTimeout)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &dbusConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+	}
+	if err := c.auth(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// the bus requires Hello to be the first call on a connection
+	if _, err := c.call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "Hello", "", nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *dbusConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *dbusConn) auth() error {
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := io.WriteString(c.conn, "\x00AUTH EXTERNAL "+uid+"\r\n"); err != nil {
+		return err
+	}
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("authentication rejected: %s", strings.TrimSpace(line))
+	}
+	_, err = io.WriteString(c.conn, "BEGIN\r\n")
+	return err
+}
+
+// getUnitByPID returns the object path of the systemd unit the process
+// belongs to
+func (c *dbusConn) getUnitByPID(pid uint32) (string, error) {
+	e := new(encoder)
+	e.uint32(pid)
+	reply, err := c.call("org.freedesktop.systemd1", "/org/freedesktop/systemd1", "org.freedesktop.systemd1.Manager", "GetUnitByPID", "u", e.buf)
+	if err != nil {
+		return "", err
+	}
+	return reply.stringBody("o")
+}
+
+// getStringProperty returns a string property of the object
+func (c *dbusConn) getStringProperty(path, iface, name string) (string, error) {
+	e := new(encoder)
+	e.string(iface)
+	e.string(name)
+	reply, err := c.call("org.freedesktop.systemd1", path, "org.freedesktop.DBus.Properties", "Get", "ss", e.buf)
+	if err != nil {
+		return "", err
+	}
+	v, err := reply.variantBody()
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("property %s.%s is not a string", iface, name)
+	}
+	return s, nil
+}
+
+func (c *dbusConn) call(dest, path, iface, member, signature string, body []byte) (*dbusMessage, error) {
+	c.serial++
+	serial := c.serial
+
+	e := new(encoder)
+	e.byte('l')
+	e.byte(msgMethodCall)
+	e.byte(0)
+	e.byte(1)
+	e.uint32(uint32(len(body)))
+	e.uint32(serial)
+	e.headerFields(func() {
+		e.headerField(fieldPath, "o", path)
+		e.headerField(fieldDestination, "s", dest)
+		e.headerField(fieldInterface, "s", iface)
+		e.headerField(fieldMember, "s", member)
+		if signature != "" {
+			e.headerField(fieldSignature, "g", signature)
+		}
+	})
+	e.align(8)
+	e.buf = append(e.buf, body...)
+
+	if _, err := c.conn.Write(e.buf); err != nil {
+		return nil, err
+	}
+
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		// skip signals and anything else not replying to this call
+		if msg.replySerial != serial {
+			continue
+		}
+		switch msg.typ {
+		case msgMethodReturn:
+			return msg, nil
+		case msgError:
+			dErr := &dbusError{Name: msg.errorName}
+			// the first argument of an error, if any, is the message
+			if strings.HasPrefix(msg.signature, "s") {
+				d := &decoder{order: msg.order, buf: msg.body}
+				dErr.Message = d.string()
+			}
+			return nil, dErr
+		}
+	}
+}
+
+type dbusMessage struct {
+	order       binary.ByteOrder
+	typ         byte
+	serial      uint32
+	path        string
+	member      string
+	replySerial uint32
+	errorName   string
+	signature   string
+	body        []byte
+}
+
+func (c *dbusConn) readMessage() (*dbusMessage, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(c.r, fixed); err != nil {
+		return nil, err
+	}
+
+	var order binary.ByteOrder
+	switch fixed[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid message endianness %q", fixed[0])
+	}
+
+	bodyLen := order.Uint32(fixed[4:8])
+	fieldsLen := order.Uint32(fixed[12:16])
+	if bodyLen > maxMessageSize || fieldsLen > maxMessageSize {
+		return nil, errors.New("message too large")
+	}
+
+	// the header is padded to a multiple of 8 bytes
+	headerLen := 16 + int(fieldsLen)
+	headerLen += (8 - headerLen%8) % 8
+	header := make([]byte, headerLen)
+	copy(header, fixed)
+	if _, err := io.ReadFull(c.r, header[16:]); err != nil {
+		return nil, err
+	}
+
+	msg := &dbusMessage{
+		order:  order,
+		typ:    fixed[1],
+		serial: order.Uint32(fixed[8:12]),
+		body:   make([]byte, bodyLen),
+	}
+
+	d := &decoder{order: order, buf: header[:16+fieldsLen], pos: 16}
+	for d.err == nil && d.pos < len(d.buf) {
+		d.align(8)
+		code := d.byte()
+		value := d.variant()
+		switch code {
+		case fieldPath:
+			msg.path, _ = value.(string)
+		case fieldMember:
+			msg.member, _ = value.(string)
+		case fieldReplySerial:
+			msg.replySerial, _ = value.(uint32)
+		case fieldErrorName:
+			msg.errorName, _ = value.(string)
+		case fieldSignature:
+			msg.signature, _ = value.(string)
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("malformed message header: %v", d.err)
+	}
+
+	if _, err := io.ReadFull(c.r, msg.body); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (m *dbusMessage) stringBody(signature string) (string, error) {
+	if m.signature != signature {
+		return "", fmt.Errorf("unexpected reply signature %q", m.signature)
+	}
+	d := &decoder{order: m.order, buf: m.body}
+	v := d.value(signature)
+	if d.err != nil {
+		return "", fmt.Errorf("malformed reply: %v", d.err)
+	}
+	return v.(string), nil
+}
+
+func (m *dbusMessage) variantBody() (interface{}, error) {
+	if m.signature != "v" {
+		return nil, fmt.Errorf("unexpected reply signature %q", m.signature)
+	}
+	d := &decoder{order: m.order, buf: m.body}
+	v := d.variant()
+	if d.err != nil {
+		return nil, fmt.Errorf("malformed reply: %v", d.err)
+	}
+	return v, nil
+}
+
+// encoder marshals little endian D-Bus values
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *encoder) byte(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.align(4)
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *encoder) string(s string) {
+	e.uint32(uint32(len(s)))
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, 0)
+}
+
+func (e *encoder) signature(s string) {
+	e.buf = append(e.buf, byte(len(s)))
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, 0)
+}
+
+// headerFields encodes the array of header field structs written by fn
+func (e *encoder) headerFields(fn func()) {
+	e.uint32(0)
+	lenOffset := len(e.buf) - 4
+	e.align(8)
+	start := len(e.buf)
+	fn()
+	binary.LittleEndian.PutUint32(e.buf[lenOffset:], uint32(len(e.buf)-start))
+}
+
+func (e *encoder) headerField(code byte, signature, value string) {
+	e.align(8)
+	e.byte(code)
+	e.signature(signature)
+	if signature == "g" {
+		e.signature(value)
+	} else {
+		e.string(value)
+	}
+}
+
+// decoder unmarshals the subset of D-Bus types found in systemd replies
+type decoder struct {
+	order binary.ByteOrder
+	buf   []byte
+	pos   int
+	err   error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.pos+n > len(d.buf) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *decoder) align(n int) {
+	if pad := (n - d.pos%n) % n; pad > 0 {
+		d.next(pad)
+	}
+}
+
+func (d *decoder) byte() byte {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) uint32() uint32 {
+	d.align(4)
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return d.order.Uint32(b)
+}
+
+func (d *decoder) string() string {
+	n := d.uint32()
+	b := d.next(int(n) + 1)
+	if b == nil {
+		return ""
+	}
+	return string(b[:n])
+}
+
+func (d *decoder) signature() string {
+	n := d.byte()
+	b := d.next(int(n) + 1)
+	if b == nil {
+		return ""
+	}
+	return string(b[:n])
+}
+
+func (d *decoder) variant() interface{} {
+	return d.value(d.signature())
+}
+
+func (d *decoder) value(signature string) interface{} {
+	if d.err != nil {
+		return nil
+	}
+	switch signature {
+	case "y":
+		return d.byte()
+	case "u":
+		return d.uint32()
+	case "s", "o":
+		return d.string()
+	case "g":
+		return d.signature()
+	case "v":
+		return d.variant()
+	default:
+		d.err = fmt.Errorf("unsupported signature %q", signature)
+		return nil
+	}
+}
@@ -0,0 +1,201 @@
+package systemd
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUnitInfo(t *testing.T) {
+	socketPath, done := startFakeSystemd(t)
+	defer done()
+
+	unit, err := getUnitInfo(ctx, socketPath, 1234)
+	require.NoError(t, err)
+	require.Equal(t, &unitInfo{
+		ID:           "nginx.service",
+		Slice:        "system.slice",
+		FragmentPath: "/lib/systemd/system/nginx.service",
+	}, unit)
+}
+
+func TestGetUnitInfoNoUnitForPID(t *testing.T) {
+	socketPath, done := startFakeSystemd(t)
+	defer done()
+
+	unit, err := getUnitInfo(ctx, socketPath, 4321)
+	require.NoError(t, err)
+	require.Nil(t, unit)
+}
+
+func TestGetUnitInfoUnknownProperty(t *testing.T) {
+	socketPath, done := startFakeSystemd(t)
+	defer done()
+
+	_, err := getUnitInfo(ctx, socketPath, 5678)
+	require.EqualError(t, err, "org.freedesktop.DBus.Error.UnknownProperty: Unknown property FragmentPath")
+}
+
+func TestGetUnitInfoAuthenticationRejected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systemd-dbus-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "bus.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("REJECTED EXTERNAL\r\n"))
+	}()
+
+	_, err = getUnitInfo(ctx, socketPath, 1234)
+	require.EqualError(t, err, "authentication rejected: REJECTED EXTERNAL")
+}
+
+// fakeUnits maps unit object paths to their properties
+var fakeUnits = map[string]map[string]string{
+	"/org/freedesktop/systemd1/unit/nginx_2eservice": {
+		"org.freedesktop.systemd1.Unit.Id":           "nginx.service",
+		"org.freedesktop.systemd1.Unit.FragmentPath": "/lib/systemd/system/nginx.service",
+		"org.freedesktop.systemd1.Service.Slice":     "system.slice",
+	},
+	"/org/freedesktop/systemd1/unit/broken_2eservice": {
+		"org.freedesktop.systemd1.Unit.Id": "broken.service",
+	},
+}
+
+var fakeUnitPIDs = map[uint32]string{
+	1234: "/org/freedesktop/systemd1/unit/nginx_2eservice",
+	5678: "/org/freedesktop/systemd1/unit/broken_2eservice",
+}
+
+// startFakeSystemd serves a single connection on a unix socket, answering
+// the calls made by getUnitInfo like the bus and systemd would.
+func startFakeSystemd(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "systemd-dbus-test-")
+	require.NoError(t, err)
+
+	socketPath := filepath.Join(dir, "bus.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serveFakeSystemd(t, conn)
+	}()
+
+	return socketPath, func() {
+		listener.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func serveFakeSystemd(t *testing.T, conn net.Conn) {
+	c := &dbusConn{conn: conn, r: bufio.NewReader(conn)}
+
+	line, err := c.r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "\x00AUTH EXTERNAL ") {
+		t.Errorf("unexpected auth request %q: %v", line, err)
+		return
+	}
+	conn.Write([]byte("OK 0123456789abcdef\r\n"))
+	if line, err := c.r.ReadString('\n'); err != nil || line != "BEGIN\r\n" {
+		t.Errorf("unexpected auth begin %q: %v", line, err)
+		return
+	}
+
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			return
+		}
+		d := &decoder{order: msg.order, buf: msg.body}
+		switch msg.member {
+		case "Hello":
+			e := new(encoder)
+			e.string(":1.42")
+			writeFakeReply(conn, msg.serial, "", "s", e.buf)
+			// signals are interleaved with replies
+			writeFakeMessage(conn, 4, nil, "", "", nil)
+		case "GetUnitByPID":
+			path, ok := fakeUnitPIDs[d.uint32()]
+			if !ok {
+				e := new(encoder)
+				e.string("PID has no unit")
+				writeFakeReply(conn, msg.serial, errNoUnitForPID, "s", e.buf)
+				continue
+			}
+			e := new(encoder)
+			e.string(path)
+			writeFakeReply(conn, msg.serial, "", "o", e.buf)
+		case "Get":
+			iface, name := d.string(), d.string()
+			value, ok := fakeUnits[msg.path][iface+"."+name]
+			if !ok {
+				e := new(encoder)
+				e.string("Unknown property " + name)
+				writeFakeReply(conn, msg.serial, "org.freedesktop.DBus.Error.UnknownProperty", "s", e.buf)
+				continue
+			}
+			e := new(encoder)
+			e.signature("s")
+			e.string(value)
+			writeFakeReply(conn, msg.serial, "", "v", e.buf)
+		default:
+			writeFakeReply(conn, msg.serial, "org.freedesktop.DBus.Error.UnknownMethod", "", nil)
+		}
+	}
+}
+
+func writeFakeReply(conn net.Conn, replySerial uint32, errorName, signature string, body []byte) {
+	typ := byte(msgMethodReturn)
+	if errorName != "" {
+		typ = msgError
+	}
+	writeFakeMessage(conn, typ, &replySerial, errorName, signature, body)
+}
+
+func writeFakeMessage(conn net.Conn, typ byte, replySerial *uint32, errorName, signature string, body []byte) {
+	e := new(encoder)
+	e.byte('l')
+	e.byte(typ)
+	e.byte(0)
+	e.byte(1)
+	e.uint32(uint32(len(body)))
+	e.uint32(1)
+	e.headerFields(func() {
+		if replySerial != nil {
+			e.align(8)
+			e.byte(fieldReplySerial)
+			e.signature("u")
+			e.uint32(*replySerial)
+		}
+		if errorName != "" {
+			e.headerField(fieldErrorName, "s", errorName)
+		}
+		if signature != "" {
+			e.headerField(fieldSignature, "g", signature)
+		}
+	})
+	e.align(8)
+	e.buf = append(e.buf, body...)
+	conn.Write(e.buf)
+}
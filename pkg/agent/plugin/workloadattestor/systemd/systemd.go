@@ -0,0 +1,213 @@
+package systemd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+const (
+	selectorType = "systemd"
+
+	defaultDBusSocketPath = "/run/dbus/system_bus_socket"
+
+	errNoUnitForPID = "org.freedesktop.systemd1.NoUnitForPID"
+)
+
+var (
+	systemdErr = errs.Class("systemd")
+)
+
+// unitInfo holds the properties of the systemd unit a process belongs to
+type unitInfo struct {
+	ID           string
+	Slice        string
+	FragmentPath string
+}
+
+type Configuration struct {
+	// DBusSocketPath is the path to the D-Bus system bus socket (default:
+	// "/run/dbus/system_bus_socket")
+	DBusSocketPath string `hcl:"dbus_socket_path"`
+
+	// HashUnitFile, if true, produces a selector with the SHA256 digest of
+	// the unit file
+	HashUnitFile bool `hcl:"hash_unit_file"`
+}
+
+type SystemdPlugin struct {
+	mu     sync.Mutex
+	config *Configuration
+
+	// hooks for tests
+	hooks struct {
+		getUnitInfo func(ctx context.Context, socketPath string, pid int32) (*unitInfo, error)
+	}
+}
+
+func New() *SystemdPlugin {
+	p := &SystemdPlugin{}
+	p.hooks.getUnitInfo = getUnitInfo
+	return p
+}
+
+func (p *SystemdPlugin) Attest(ctx context.Context, req *workloadattestor.AttestRequest) (*workloadattestor.AttestResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := p.hooks.getUnitInfo(ctx, config.DBusSocketPath, req.Pid)
+	if err != nil {
+		return nil, systemdErr.New("unit lookup: %v", err)
+	}
+
+	// processes outside of any unit have no systemd selectors
+	if unit == nil {
+		return &workloadattestor.AttestResponse{}, nil
+	}
+
+	selectors := []*common.Selector{
+		makeSelector("unit", unit.ID),
+	}
+	if unit.Slice != "" {
+		selectors = append(selectors, makeSelector("slice", unit.Slice))
+	}
+	if unit.FragmentPath != "" {
+		selectors = append(selectors, makeSelector("fragment_path", unit.FragmentPath))
+		if config.HashUnitFile {
+			digest, err := getSHA256Digest(unit.FragmentPath)
+			if err != nil {
+				return nil, err
+			}
+			selectors = append(selectors, makeSelector("fragment_sha256", digest))
+		}
+	}
+
+	return &workloadattestor.AttestResponse{
+		Selectors: selectors,
+	}, nil
+}
+
+func (p *SystemdPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(Configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, systemdErr.Wrap(err)
+	}
+	if config.DBusSocketPath == "" {
+		config.DBusSocketPath = defaultDBusSocketPath
+	}
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *SystemdPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *SystemdPlugin) getConfig() (*Configuration, error) {
+	p.mu.Lock()
+	config := p.config
+	p.mu.Unlock()
+	if config == nil {
+		return nil, systemdErr.New("not configured")
+	}
+	return config, nil
+}
+
+func (p *SystemdPlugin) setConfig(config *Configuration) {
+	p.mu.Lock()
+	p.config = config
+	p.mu.Unlock()
+}
+
+// getUnitInfo asks systemd over D-Bus for the unit the process belongs to.
+// It returns nil if the process does not belong to a unit.
+func getUnitInfo(ctx context.Context, socketPath string, pid int32) (*unitInfo, error) {
+	conn, err := dialDBus(ctx, socketPath)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	unitPath, err := conn.getUnitByPID(uint32(pid))
+	if dErr, ok := err.(*dbusError); ok && dErr.Name == errNoUnitForPID {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	unit := new(unitInfo)
+	unit.ID, err = conn.getStringProperty(unitPath, "org.freedesktop.systemd1.Unit", "Id")
+	if err != nil {
+		return nil, err
+	}
+	unit.FragmentPath, err = conn.getStringProperty(unitPath, "org.freedesktop.systemd1.Unit", "FragmentPath")
+	if err != nil {
+		return nil, err
+	}
+
+	// the slice is a property of the interface specific to the unit type
+	if iface, ok := unitTypeInterface(unit.ID); ok {
+		unit.Slice, err = conn.getStringProperty(unitPath, iface, "Slice")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return unit, nil
+}
+
+// unitTypeInterface returns the D-Bus interface of the unit type, for unit
+// types placed in a slice
+func unitTypeInterface(unitID string) (string, bool) {
+	switch path.Ext(unitID) {
+	case ".service":
+		return "org.freedesktop.systemd1.Service", true
+	case ".scope":
+		return "org.freedesktop.systemd1.Scope", true
+	case ".socket":
+		return "org.freedesktop.systemd1.Socket", true
+	case ".mount":
+		return "org.freedesktop.systemd1.Mount", true
+	case ".swap":
+		return "org.freedesktop.systemd1.Swap", true
+	case ".slice":
+		return "org.freedesktop.systemd1.Slice", true
+	default:
+		return "", false
+	}
+}
+
+func getSHA256Digest(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", systemdErr.New("SHA256 digest: %v", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", systemdErr.New("SHA256 digest: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  selectorType,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
@@ -0,0 +1,189 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestSystemdPlugin(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	dir   string
+	p     *workloadattestor.BuiltIn
+	units map[int32]*unitInfo
+
+	socketPath string
+}
+
+func (s *Suite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "systemd-workload-attestor-test-")
+	s.Require().NoError(err)
+
+	s.units = map[int32]*unitInfo{
+		1: {
+			ID:           "nginx.service",
+			Slice:        "system.slice",
+			FragmentPath: filepath.Join(s.dir, "nginx.service"),
+		},
+		2: {
+			ID:    "session-1.scope",
+			Slice: "user-1000.slice",
+		},
+		3: {
+			ID:           "missing.service",
+			Slice:        "system.slice",
+			FragmentPath: filepath.Join(s.dir, "missing.service"),
+		},
+	}
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, "nginx.service"), []byte("[Service]\nExecStart=/usr/sbin/nginx\n"), 0644))
+
+	p := New()
+	p.hooks.getUnitInfo = func(ctx context.Context, socketPath string, pid int32) (*unitInfo, error) {
+		s.socketPath = socketPath
+		if pid == 4 {
+			return nil, errors.New("connection refused")
+		}
+		return s.units[pid], nil
+	}
+	s.p = workloadattestor.NewBuiltIn(p)
+	s.configure("")
+}
+
+func (s *Suite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *Suite) TestAttest() {
+	testCases := []struct {
+		name      string
+		pid       int32
+		err       string
+		selectors []string
+		config    string
+	}{
+		{
+			name: "service",
+			pid:  1,
+			selectors: []string{
+				"unit:nginx.service",
+				"slice:system.slice",
+				fmt.Sprintf("fragment_path:%s", filepath.Join(s.dir, "nginx.service")),
+			},
+		},
+		{
+			name: "service with unit file hash",
+			pid:  1,
+			selectors: []string{
+				"unit:nginx.service",
+				"slice:system.slice",
+				fmt.Sprintf("fragment_path:%s", filepath.Join(s.dir, "nginx.service")),
+				"fragment_sha256:8fe842c958035dbbf2417850016ba06d0564481cddf90eba8077e1473ea52e1e",
+			},
+			config: "hash_unit_file = true",
+		},
+		{
+			name: "scope without unit file",
+			pid:  2,
+			selectors: []string{
+				"unit:session-1.scope",
+				"slice:user-1000.slice",
+			},
+			config: "hash_unit_file = true",
+		},
+		{
+			name:   "unit file cannot be hashed",
+			pid:    3,
+			err:    "systemd: SHA256 digest: open " + filepath.Join(s.dir, "missing.service") + ": no such file or directory",
+			config: "hash_unit_file = true",
+		},
+		{
+			name: "unit lookup fails",
+			pid:  4,
+			err:  "systemd: unit lookup: connection refused",
+		},
+		{
+			name: "process without unit",
+			pid:  5,
+		},
+	}
+
+	for _, testCase := range testCases {
+		s.T().Run(testCase.name, func(t *testing.T) {
+			s.configure(testCase.config)
+			resp, err := s.p.Attest(ctx, &workloadattestor.AttestRequest{
+				Pid: testCase.pid,
+			})
+			if testCase.err != "" {
+				s.Require().EqualError(err, testCase.err)
+				s.Require().Nil(resp)
+				return
+			}
+
+			s.Require().NoError(err)
+			s.Require().NotNil(resp)
+			var selectors []string
+			for _, selector := range resp.Selectors {
+				s.Require().Equal("systemd", selector.Type)
+				selectors = append(selectors, selector.Value)
+			}
+			s.Require().Equal(testCase.selectors, selectors)
+		})
+	}
+}
+
+func (s *Suite) TestAttestNotConfigured() {
+	p := workloadattestor.NewBuiltIn(New())
+	resp, err := p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 1})
+	s.Require().EqualError(err, "systemd: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.p.Configure(ctx, &spi.ConfigureRequest{Configuration: "blah"})
+	s.Require().Error(err)
+	s.Require().Nil(resp)
+
+	// default socket path
+	s.configure("")
+	_, err = s.p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 1})
+	s.Require().NoError(err)
+	s.Require().Equal("/run/dbus/system_bus_socket", s.socketPath)
+
+	// custom socket path
+	s.configure(`dbus_socket_path = "/var/run/dbus/system_bus_socket"`)
+	_, err = s.p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 1})
+	s.Require().NoError(err)
+	s.Require().Equal("/var/run/dbus/system_bus_socket", s.socketPath)
+}
+
+func (s *Suite) TestGetPluginInfo() {
+	resp, err := s.p.GetPluginInfo(ctx, &spi.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+}
+
+func (s *Suite) configure(config string) {
+	_, err := s.p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: config,
+	})
+	s.Require().NoError(err)
+}
@@ -0,0 +1,97 @@
+package unix
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// securityContext describes the confinement of a process by a Linux
+// Security Module. Only one of SELinux or AppArmor is set.
+type securityContext struct {
+	// SELinux is the SELinux context (e.g. "system_u:system_r:httpd_t:s0")
+	SELinux string
+
+	// AppArmorProfile is the AppArmor profile (e.g. "/usr/sbin/nginx" or
+	// "unconfined") and AppArmorMode its mode (e.g. "enforce"), if any
+	AppArmorProfile string
+	AppArmorMode    string
+}
+
+// readProcSecurityContext reads the security context of the process from
+// procfs, relative to root. It returns nil if neither SELinux nor AppArmor
+// is enabled.
+func readProcSecurityContext(root string, pid int32) (*securityContext, error) {
+	procAttr := filepath.Join(root, "proc", fmt.Sprint(pid), "attr")
+
+	// with LSM stacking, each module has its own attribute directory
+	if data, err := ioutil.ReadFile(filepath.Join(procAttr, "apparmor", "current")); err == nil {
+		return parseAppArmorContext(data), nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var parse func([]byte) *securityContext
+	switch {
+	case isAppArmorEnabled(root):
+		parse = parseAppArmorContext
+	case isSELinuxEnabled(root):
+		parse = parseSELinuxContext
+	default:
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(procAttr, "current"))
+	if err != nil {
+		return nil, err
+	}
+	return parse(data), nil
+}
+
+func isAppArmorEnabled(root string) bool {
+	data, err := ioutil.ReadFile(filepath.Join(root, "sys", "module", "apparmor", "parameters", "enabled"))
+	return err == nil && strings.TrimSpace(string(data)) == "Y"
+}
+
+func isSELinuxEnabled(root string) bool {
+	_, err := os.Stat(filepath.Join(root, "sys", "fs", "selinux", "enforce"))
+	return err == nil
+}
+
+func parseSELinuxContext(data []byte) *securityContext {
+	return &securityContext{
+		SELinux: trimAttr(data),
+	}
+}
+
+// parseAppArmorContext parses contexts in the "profile (mode)" form, or just
+// "unconfined"
+func parseAppArmorContext(data []byte) *securityContext {
+	context := trimAttr(data)
+	sc := &securityContext{AppArmorProfile: context}
+	if strings.HasSuffix(context, ")") {
+		if i := strings.LastIndex(context, " ("); i >= 0 {
+			sc.AppArmorProfile = context[:i]
+			sc.AppArmorMode = context[i+2 : len(context)-1]
+		}
+	}
+	return sc
+}
+
+// trimAttr trims the NUL terminator and newline of attribute values
+func trimAttr(data []byte) string {
+	return strings.TrimSpace(string(bytes.TrimRight(data, "\x00")))
+}
+
+// selinuxType returns the type of an SELinux context
+// (user:role:type[:level])
+func selinuxType(context string) string {
+	parts := strings.SplitN(context, ":", 4)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
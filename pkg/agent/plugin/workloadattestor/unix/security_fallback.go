@@ -0,0 +1,6 @@
+// +build !linux
+
+package unix
+
+// security modules are only available on Linux
+var readSecurityContext func(pid int32) (*securityContext, error)
@@ -0,0 +1,5 @@
+package unix
+
+func readSecurityContext(pid int32) (*securityContext, error) {
+	return readProcSecurityContext("/", pid)
+}
@@ -0,0 +1,96 @@
+package unix
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadProcSecurityContext(t *testing.T) {
+	testCases := []struct {
+		name    string
+		files   map[string]string
+		context *securityContext
+		err     string
+	}{
+		{
+			name: "no security module",
+			files: map[string]string{
+				"proc/123/attr/current": "",
+			},
+		},
+		{
+			name: "selinux",
+			files: map[string]string{
+				"sys/fs/selinux/enforce": "1",
+				"proc/123/attr/current":  "system_u:system_r:httpd_t:s0\x00",
+			},
+			context: &securityContext{SELinux: "system_u:system_r:httpd_t:s0"},
+		},
+		{
+			name: "apparmor",
+			files: map[string]string{
+				"sys/module/apparmor/parameters/enabled": "Y\n",
+				"proc/123/attr/current":                  "/usr/sbin/nginx (enforce)\n",
+			},
+			context: &securityContext{AppArmorProfile: "/usr/sbin/nginx", AppArmorMode: "enforce"},
+		},
+		{
+			name: "apparmor unconfined",
+			files: map[string]string{
+				"sys/module/apparmor/parameters/enabled": "Y\n",
+				"proc/123/attr/current":                  "unconfined\n",
+			},
+			context: &securityContext{AppArmorProfile: "unconfined"},
+		},
+		{
+			name: "apparmor disabled",
+			files: map[string]string{
+				"sys/module/apparmor/parameters/enabled": "N\n",
+				"proc/123/attr/current":                  "",
+			},
+		},
+		{
+			name: "apparmor stacked",
+			files: map[string]string{
+				"proc/123/attr/apparmor/current": "docker-default (enforce)\n",
+				"proc/123/attr/current":          "",
+			},
+			context: &securityContext{AppArmorProfile: "docker-default", AppArmorMode: "enforce"},
+		},
+		{
+			name: "process gone",
+			files: map[string]string{
+				"sys/fs/selinux/enforce": "1",
+			},
+			err: "no such file or directory",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			root, err := ioutil.TempDir("", "unix-security-test-")
+			require.NoError(t, err)
+			defer os.RemoveAll(root)
+
+			for name, data := range testCase.files {
+				path := filepath.Join(root, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				require.NoError(t, ioutil.WriteFile(path, []byte(data), 0644))
+			}
+
+			context, err := readProcSecurityContext(root, 123)
+			if testCase.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), testCase.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.context, context)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package unix
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+)
+
+const codesignPath = "/usr/bin/codesign"
+
+// getSignature verifies the code signature of the binary with codesign(1)
+// and, if valid, returns the signing identifier and team.
+func getSignature(ctx context.Context, path string) (*signatureInfo, error) {
+	// codesign exits with status 1 when the binary is unsigned or the
+	// signature is invalid
+	err := exec.CommandContext(ctx, codesignPath, "--verify", "--strict", path).Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return &signatureInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// details are written to stderr
+	stderr := new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, codesignPath, "--display", "--verbose=2", path)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	info := &signatureInfo{Signed: true}
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "Identifier":
+			info.Identifier = kv[1]
+		case "TeamIdentifier":
+			// ad-hoc and platform binaries have no team
+			if kv[1] != "not set" {
+				info.TeamID = kv[1]
+			}
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,8 @@
+// +build !darwin
+
+package unix
+
+import "context"
+
+// code signatures of binaries are not verified on this platform
+var getSignature func(ctx context.Context, path string) (*signatureInfo, error)
@@ -32,9 +32,26 @@ type processInfo interface {
 	Exe() (string, error)
 }
 
+// signatureInfo describes the code signature of a workload binary
+type signatureInfo struct {
+	// Signed is true if the binary has a valid signature
+	Signed bool
+
+	Identifier string
+	TeamID     string
+}
+
 type Configuration struct {
 	DiscoverWorkloadPath bool  `hcl:"discover_workload_path"`
 	WorkloadSizeLimit    int64 `hcl:"workload_size_limit"`
+
+	// DiscoverWorkloadSignature, if true, verifies the code signature of the
+	// workload binary. Requires DiscoverWorkloadPath.
+	DiscoverWorkloadSignature bool `hcl:"discover_workload_signature"`
+
+	// DiscoverSecurityContext, if true, produces selectors for the SELinux
+	// context or AppArmor profile of the workload
+	DiscoverSecurityContext bool `hcl:"discover_security_context"`
 }
 
 type UnixPlugin struct {
@@ -46,6 +63,9 @@ type UnixPlugin struct {
 		newProcess      func(pid int32) (processInfo, error)
 		lookupUserById  func(id string) (*user.User, error)
 		lookupGroupById func(id string) (*user.Group, error)
+		getSignature    func(ctx context.Context, path string) (*signatureInfo, error)
+
+		readSecurityContext func(pid int32) (*securityContext, error)
 	}
 }
 
@@ -54,6 +74,8 @@ func New() *UnixPlugin {
 	p.hooks.newProcess = func(pid int32) (processInfo, error) { return process.NewProcess(pid) }
 	p.hooks.lookupUserById = user.LookupId
 	p.hooks.lookupGroupById = user.LookupGroupId
+	p.hooks.getSignature = getSignature
+	p.hooks.readSecurityContext = readSecurityContext
 	return p
 }
 
@@ -93,6 +115,7 @@ func (p *UnixPlugin) Attest(ctx context.Context, req *workloadattestor.AttestReq
 	// available.
 	var processPath string
 	var sha256Digest string
+	var signature *signatureInfo
 	if config.DiscoverWorkloadPath {
 		processPath, err = p.getPath(proc)
 		if err != nil {
@@ -102,6 +125,20 @@ func (p *UnixPlugin) Attest(ctx context.Context, req *workloadattestor.AttestReq
 		if err != nil {
 			return nil, err
 		}
+		if config.DiscoverWorkloadSignature {
+			signature, err = p.hooks.getSignature(ctx, processPath)
+			if err != nil {
+				return nil, unixErr.New("signature verification: %v", err)
+			}
+		}
+	}
+
+	var security *securityContext
+	if config.DiscoverSecurityContext {
+		security, err = p.hooks.readSecurityContext(req.Pid)
+		if err != nil {
+			return nil, unixErr.New("security context lookup: %v", err)
+		}
 	}
 
 	selectors := []*common.Selector{
@@ -116,6 +153,18 @@ func (p *UnixPlugin) Attest(ctx context.Context, req *workloadattestor.AttestReq
 	if sha256Digest != "" {
 		selectors = append(selectors, makeSelector("sha256", sha256Digest))
 	}
+	if signature != nil {
+		selectors = append(selectors, makeSelector("signed", fmt.Sprint(signature.Signed)))
+		if signature.Identifier != "" {
+			selectors = append(selectors, makeSelector("signing_identifier", signature.Identifier))
+		}
+		if signature.TeamID != "" {
+			selectors = append(selectors, makeSelector("signing_team_id", signature.TeamID))
+		}
+	}
+	if security != nil {
+		selectors = append(selectors, securitySelectors(security)...)
+	}
 
 	return &workloadattestor.AttestResponse{
 		Selectors: selectors,
@@ -127,6 +176,17 @@ func (p *UnixPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (
 	if err := hcl.Decode(config, req.Configuration); err != nil {
 		return nil, unixErr.Wrap(err)
 	}
+	if config.DiscoverWorkloadSignature {
+		if !config.DiscoverWorkloadPath {
+			return nil, unixErr.New("discover_workload_signature requires discover_workload_path")
+		}
+		if p.hooks.getSignature == nil {
+			return nil, unixErr.New("discover_workload_signature is not supported on this platform")
+		}
+	}
+	if config.DiscoverSecurityContext && p.hooks.readSecurityContext == nil {
+		return nil, unixErr.New("discover_security_context is not supported on this platform")
+	}
 	p.setConfig(config)
 	return &spi.ConfigureResponse{}, nil
 }
@@ -232,6 +292,23 @@ func getSHA256Digest(path string, limit int64) (string, error) {
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+func securitySelectors(security *securityContext) []*common.Selector {
+	var selectors []*common.Selector
+	if security.SELinux != "" {
+		selectors = append(selectors, makeSelector("selinux_context", security.SELinux))
+		if typ := selinuxType(security.SELinux); typ != "" {
+			selectors = append(selectors, makeSelector("selinux_type", typ))
+		}
+	}
+	if security.AppArmorProfile != "" {
+		selectors = append(selectors, makeSelector("apparmor_profile", security.AppArmorProfile))
+		if security.AppArmorMode != "" {
+			selectors = append(selectors, makeSelector("apparmor_mode", security.AppArmorMode))
+		}
+	}
+	return selectors
+}
+
 func makeSelector(kind, value string) *common.Selector {
 	return &common.Selector{
 		Type:  selectorType,
@@ -2,6 +2,7 @@ package unix
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -42,6 +43,10 @@ func (s *Suite) SetupTest() {
 	}
 	p.hooks.lookupUserById = fakeLookupUserById
 	p.hooks.lookupGroupById = fakeLookupGroupById
+	p.hooks.getSignature = func(ctx context.Context, path string) (*signatureInfo, error) {
+		return fakeGetSignature(s.dir, path)
+	}
+	p.hooks.readSecurityContext = fakeReadSecurityContext
 	s.p = workloadattestor.NewBuiltIn(p)
 	s.configure("")
 }
@@ -139,10 +144,91 @@ func (s *Suite) TestAttest() {
 				"sha256:3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7",
 			},
 		},
+		{
+			name:   "signed process binary",
+			pid:    12,
+			config: "discover_workload_path = true\ndiscover_workload_signature = true",
+			selectors: []string{
+				"uid:1000",
+				"user:u1000",
+				"gid:2000",
+				"group:g2000",
+				fmt.Sprintf("path:%s", filepath.Join(s.dir, "exe")),
+				"sha256:3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7",
+				"signed:true",
+				"signing_identifier:com.example.exe",
+				"signing_team_id:ABCDE12345",
+			},
+		},
+		{
+			name:   "unsigned process binary",
+			pid:    13,
+			config: "discover_workload_path = true\ndiscover_workload_signature = true",
+			selectors: []string{
+				"uid:1000",
+				"user:u1000",
+				"gid:2000",
+				"group:g2000",
+				fmt.Sprintf("path:%s", filepath.Join(s.dir, "unsigned-exe")),
+				"sha256:3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7",
+				"signed:false",
+			},
+		},
+		{
+			name:   "fail to verify process binary signature",
+			pid:    14,
+			config: "discover_workload_path = true\ndiscover_workload_signature = true",
+			err:    "unix: signature verification: codesign failed",
+		},
+		{
+			name:   "selinux context",
+			pid:    15,
+			config: "discover_security_context = true",
+			selectors: []string{
+				"uid:1000",
+				"user:u1000",
+				"gid:2000",
+				"group:g2000",
+				"selinux_context:system_u:system_r:httpd_t:s0",
+				"selinux_type:httpd_t",
+			},
+		},
+		{
+			name:   "apparmor profile",
+			pid:    16,
+			config: "discover_security_context = true",
+			selectors: []string{
+				"uid:1000",
+				"user:u1000",
+				"gid:2000",
+				"group:g2000",
+				"apparmor_profile:/usr/sbin/nginx",
+				"apparmor_mode:enforce",
+			},
+		},
+		{
+			name:   "fail to read security context",
+			pid:    17,
+			config: "discover_security_context = true",
+			err:    "unix: security context lookup: unable to read attr for PID 17",
+		},
+		{
+			name:   "no security module",
+			pid:    7,
+			config: "discover_security_context = true",
+			selectors: []string{
+				"uid:1000",
+				"user:u1000",
+				"gid:2000",
+				"group:g2000",
+			},
+		},
 	}
 
 	// prepare the "exe" for hashing
 	s.writeFile("exe", []byte("data"))
+	s.writeFile("unsigned-exe", []byte("data"))
+	s.writeFile("broken-exe", []byte("data"))
 
 	for _, testCase := range testCases {
 		s.T().Run(testCase.name, func(t *testing.T) {
@@ -172,6 +258,33 @@ func (s *Suite) TestConfigure() {
 	resp, err := s.p.Configure(ctx, &spi.ConfigureRequest{})
 	s.NoError(err)
 	s.Equal(&spi.ConfigureResponse{}, resp)
+
+	// signature verification requires the workload path
+	resp, err = s.p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: "discover_workload_signature = true",
+	})
+	s.EqualError(err, "unix: discover_workload_signature requires discover_workload_path")
+	s.Nil(resp)
+}
+
+func (s *Suite) TestConfigureSecurityContextUnsupported() {
+	p := New()
+	p.hooks.readSecurityContext = nil
+	resp, err := workloadattestor.NewBuiltIn(p).Configure(ctx, &spi.ConfigureRequest{
+		Configuration: "discover_security_context = true",
+	})
+	s.EqualError(err, "unix: discover_security_context is not supported on this platform")
+	s.Nil(resp)
+}
+
+func (s *Suite) TestConfigureSignatureUnsupported() {
+	p := New()
+	p.hooks.getSignature = nil
+	resp, err := workloadattestor.NewBuiltIn(p).Configure(ctx, &spi.ConfigureRequest{
+		Configuration: "discover_workload_path = true\ndiscover_workload_signature = true",
+	})
+	s.EqualError(err, "unix: discover_workload_signature is not supported on this platform")
+	s.Nil(resp)
 }
 
 func (s *Suite) TestGetPluginInfo() {
@@ -204,7 +317,7 @@ func (p fakeProcess) Uids() ([]int32, error) {
 		return nil, fmt.Errorf("unable to get UIDs for PID %d", p.pid)
 	case 3:
 		return []int32{1999}, nil
-	case 4, 5, 6, 7, 9, 10, 11, 12:
+	case 4, 5, 6, 7, 9, 10, 11, 12, 13, 14, 15, 16, 17:
 		return []int32{1000}, nil
 	case 8:
 		return []int32{1000, 1100}, nil
@@ -221,7 +334,7 @@ func (p fakeProcess) Gids() ([]int32, error) {
 		return nil, fmt.Errorf("unable to get GIDs for PID %d", p.pid)
 	case 6:
 		return []int32{2999}, nil
-	case 7, 9, 10, 11, 12:
+	case 7, 9, 10, 11, 12, 13, 14, 15, 16, 17:
 		return []int32{2000}, nil
 	case 8:
 		return []int32{2000, 2100}, nil
@@ -238,6 +351,10 @@ func (p fakeProcess) Exe() (string, error) {
 		return filepath.Join(p.dir, "unreadable-exe"), nil
 	case 11, 12:
 		return filepath.Join(p.dir, "exe"), nil
+	case 13:
+		return filepath.Join(p.dir, "unsigned-exe"), nil
+	case 14:
+		return filepath.Join(p.dir, "broken-exe"), nil
 	default:
 		return "", fmt.Errorf("unhandled exe test case %d", p.pid)
 	}
@@ -268,3 +385,27 @@ func fakeLookupGroupById(gid string) (*user.Group, error) {
 		return nil, fmt.Errorf("no group with GID %s", gid)
 	}
 }
+
+func fakeGetSignature(dir, path string) (*signatureInfo, error) {
+	switch path {
+	case filepath.Join(dir, "exe"):
+		return &signatureInfo{Signed: true, Identifier: "com.example.exe", TeamID: "ABCDE12345"}, nil
+	case filepath.Join(dir, "unsigned-exe"):
+		return &signatureInfo{}, nil
+	default:
+		return nil, errors.New("codesign failed")
+	}
+}
+
+func fakeReadSecurityContext(pid int32) (*securityContext, error) {
+	switch pid {
+	case 15:
+		return &securityContext{SELinux: "system_u:system_r:httpd_t:s0"}, nil
+	case 16:
+		return &securityContext{AppArmorProfile: "/usr/sbin/nginx", AppArmorMode: "enforce"}, nil
+	case 17:
+		return nil, fmt.Errorf("unable to read attr for PID %d", pid)
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,158 @@
+package windows
+
+import (
+	"bytes"
+	"crypto/x509"
+	"debug/pe"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Authenticode signatures are PKCS#7 SignedData structures stored in the
+// security directory of the PE file. Only what is needed to find the
+// certificate of the signer is implemented here; verifying the signature is
+// left to the operating system.
+
+const (
+	// index of the security directory in the optional header
+	imageDirectoryEntrySecurity = 4
+
+	// WIN_CERTIFICATE type of PKCS#7 SignedData
+	winCertTypePKCSSignedData = 0x0002
+
+	// size of the WIN_CERTIFICATE header
+	winCertificateHeaderLen = 8
+)
+
+var (
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+	errNotSigned = errors.New("binary is not signed")
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// signerInfo holds the leading fields of the SignerInfo structure, which are
+// the only ones needed to identify the signer
+type signerInfo struct {
+	Version               int
+	IssuerAndSerialNumber issuerAndSerialNumber
+}
+
+// readSigner returns the certificate of the signer of the Authenticode
+// signature embedded in the PE file
+func readSigner(path string) (*x509.Certificate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pf, err := pe.NewFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var dir pe.DataDirectory
+	switch oh := pf.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		if oh.NumberOfRvaAndSizes > imageDirectoryEntrySecurity {
+			dir = oh.DataDirectory[imageDirectoryEntrySecurity]
+		}
+	case *pe.OptionalHeader64:
+		if oh.NumberOfRvaAndSizes > imageDirectoryEntrySecurity {
+			dir = oh.DataDirectory[imageDirectoryEntrySecurity]
+		}
+	}
+	if dir.Size == 0 {
+		return nil, errNotSigned
+	}
+
+	// unlike the other directories, the address of the security directory
+	// is a file offset
+	data := make([]byte, dir.Size)
+	if _, err := f.ReadAt(data, int64(dir.VirtualAddress)); err != nil {
+		return nil, fmt.Errorf("unable to read security directory: %v", err)
+	}
+
+	signature, err := findPKCS7Signature(data)
+	if err != nil {
+		return nil, err
+	}
+	return parseSigner(signature)
+}
+
+// findPKCS7Signature returns the first PKCS#7 signature in the list of
+// WIN_CERTIFICATE structures making up the security directory
+func findPKCS7Signature(data []byte) ([]byte, error) {
+	for len(data) >= winCertificateHeaderLen {
+		length := binary.LittleEndian.Uint32(data[0:4])
+		certType := binary.LittleEndian.Uint16(data[6:8])
+		if length < winCertificateHeaderLen || uint64(length) > uint64(len(data)) {
+			return nil, errors.New("malformed security directory")
+		}
+		if certType == winCertTypePKCSSignedData {
+			return data[winCertificateHeaderLen:length], nil
+		}
+		// entries are aligned on 8 byte boundaries
+		next := (uint64(length) + 7) &^ 7
+		if next > uint64(len(data)) {
+			break
+		}
+		data = data[next:]
+	}
+	return nil, errNotSigned
+}
+
+// parseSigner returns the certificate of the signer from the certificates
+// carried in the DER encoded PKCS#7 SignedData
+func parseSigner(der []byte) (*x509.Certificate, error) {
+	info := new(contentInfo)
+	if _, err := asn1.Unmarshal(der, info); err != nil {
+		return nil, fmt.Errorf("malformed signature: %v", err)
+	}
+	if !info.ContentType.Equal(oidSignedData) {
+		return nil, errors.New("signature is not signed data")
+	}
+
+	sd := new(signedData)
+	if _, err := asn1.Unmarshal(info.Content.Bytes, sd); err != nil {
+		return nil, fmt.Errorf("malformed signed data: %v", err)
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("expected one signer; got %d", len(sd.SignerInfos))
+	}
+	signer := sd.SignerInfos[0].IssuerAndSerialNumber
+
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("malformed certificates: %v", err)
+	}
+	for _, cert := range certs {
+		if bytes.Equal(cert.RawIssuer, signer.Issuer.FullBytes) && cert.SerialNumber.Cmp(signer.SerialNumber) == 0 {
+			return cert, nil
+		}
+	}
+	return nil, errors.New("signer certificate not found")
+}
@@ -0,0 +1,185 @@
+package windows
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"debug/pe"
+	"encoding/asn1"
+	"encoding/binary"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "windows-authenticode-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	signer := createCertificate(t, "Example Corp", 2)
+	other := createCertificate(t, "Example Intermediate", 1)
+
+	signed := filepath.Join(dir, "signed.exe")
+	require.NoError(t, ioutil.WriteFile(signed, buildPE(t, buildSignature(t, signer, other, signer)), 0644))
+	cert, err := readSigner(signed)
+	require.NoError(t, err)
+	require.Equal(t, "Example Corp", cert.Subject.CommonName)
+
+	unsigned := filepath.Join(dir, "unsigned.exe")
+	require.NoError(t, ioutil.WriteFile(unsigned, buildPE(t, nil), 0644))
+	_, err = readSigner(unsigned)
+	require.Equal(t, errNotSigned, err)
+
+	notPE := filepath.Join(dir, "script.ps1")
+	require.NoError(t, ioutil.WriteFile(notPE, []byte("Write-Host hello"), 0644))
+	_, err = readSigner(notPE)
+	require.Error(t, err)
+}
+
+func TestFindPKCS7Signature(t *testing.T) {
+	// an entry of another type is skipped
+	data := winCertificate(0x0001, []byte("x509"))
+	data = append(data, winCertificate(winCertTypePKCSSignedData, []byte("pkcs7"))...)
+	signature, err := findPKCS7Signature(data)
+	require.NoError(t, err)
+	require.Equal(t, []byte("pkcs7"), signature)
+
+	_, err = findPKCS7Signature(winCertificate(0x0001, []byte("x509")))
+	require.Equal(t, errNotSigned, err)
+
+	malformed := winCertificate(winCertTypePKCSSignedData, []byte("pkcs7"))
+	binary.LittleEndian.PutUint32(malformed, 1000)
+	_, err = findPKCS7Signature(malformed)
+	require.EqualError(t, err, "malformed security directory")
+}
+
+func TestParseSigner(t *testing.T) {
+	signer := createCertificate(t, "Example Corp", 2)
+	other := createCertificate(t, "Example Intermediate", 1)
+
+	cert, err := parseSigner(buildSignature(t, signer, other, signer))
+	require.NoError(t, err)
+	require.Equal(t, signer.Raw, cert.Raw)
+
+	_, err = parseSigner(buildSignature(t, signer, other))
+	require.EqualError(t, err, "signer certificate not found")
+
+	_, err = parseSigner([]byte("garbage"))
+	require.Error(t, err)
+}
+
+func createCertificate(t *testing.T, commonName string, serial int64) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+	return cert
+}
+
+// buildSignature returns a PKCS#7 SignedData carrying the certificates,
+// signed by signer. Only the fields read by parseSigner are meaningful.
+func buildSignature(t *testing.T, signer *x509.Certificate, certs ...*x509.Certificate) []byte {
+	var certsDER []byte
+	for _, cert := range certs {
+		certsDER = append(certsDER, cert.Raw...)
+	}
+
+	type testSignedData struct {
+		Version          int
+		DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+		ContentInfo      contentInfo
+		Certificates     asn1.RawValue
+		SignerInfos      []signerInfo `asn1:"set"`
+	}
+
+	sdDER, err := asn1.Marshal(testSignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{},
+		ContentInfo:      contentInfo{ContentType: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 4}},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certsDER},
+		SignerInfos: []signerInfo{
+			{
+				Version: 1,
+				IssuerAndSerialNumber: issuerAndSerialNumber{
+					Issuer:       asn1.RawValue{FullBytes: signer.RawIssuer},
+					SerialNumber: signer.SerialNumber,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	der, err := asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sdDER},
+	})
+	require.NoError(t, err)
+	return der
+}
+
+func winCertificate(certType uint16, content []byte) []byte {
+	buf := make([]byte, winCertificateHeaderLen, winCertificateHeaderLen+len(content)+7)
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(winCertificateHeaderLen+len(content)))
+	binary.LittleEndian.PutUint16(buf[4:6], 0x0200)
+	binary.LittleEndian.PutUint16(buf[6:8], certType)
+	buf = append(buf, content...)
+	for len(buf)%8 != 0 {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+// buildPE returns a minimal PE32 image without sections, with the
+// signature, if any, in the security directory
+func buildPE(t *testing.T, signature []byte) []byte {
+	const peOffset = 0x40
+
+	buf := new(bytes.Buffer)
+	dosHeader := make([]byte, peOffset)
+	copy(dosHeader, "MZ")
+	binary.LittleEndian.PutUint32(dosHeader[0x3c:], peOffset)
+	buf.Write(dosHeader)
+	buf.WriteString("PE\x00\x00")
+
+	optionalHeader := pe.OptionalHeader32{
+		Magic:               0x10b,
+		NumberOfRvaAndSizes: 16,
+	}
+	fileHeader := pe.FileHeader{
+		Machine:              pe.IMAGE_FILE_MACHINE_I386,
+		SizeOfOptionalHeader: uint16(binary.Size(optionalHeader)),
+	}
+
+	var securityDirectory []byte
+	if signature != nil {
+		securityDirectory = winCertificate(winCertTypePKCSSignedData, signature)
+		offset := buf.Len() + binary.Size(fileHeader) + binary.Size(optionalHeader)
+		optionalHeader.DataDirectory[imageDirectoryEntrySecurity] = pe.DataDirectory{
+			VirtualAddress: uint32(offset),
+			Size:           uint32(len(securityDirectory)),
+		}
+	}
+
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, fileHeader))
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, optionalHeader))
+	buf.Write(securityDirectory)
+	return buf.Bytes()
+}
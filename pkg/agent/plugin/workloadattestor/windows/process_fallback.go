@@ -0,0 +1,15 @@
+// +build !windows
+
+package windows
+
+import "errors"
+
+var errUnsupportedPlatform = errors.New("unsupported platform")
+
+func getProcessInfo(pid int32) (*processInfo, error) {
+	return nil, errUnsupportedPlatform
+}
+
+func getPublisher(path string) (string, error) {
+	return "", errUnsupportedPlatform
+}
@@ -0,0 +1,209 @@
+// +build windows
+
+package windows
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// access rights and attributes not defined by golang.org/x/sys/windows
+	processQueryLimitedInformation = 0x1000
+	seGroupEnabled                 = 0x00000004
+	seGroupLogonID                 = 0xC0000000
+
+	// WinVerifyTrust parameters
+	wtdUINone                = 2
+	wtdRevokeNone            = 0
+	wtdChoiceFile            = 1
+	wtdStateActionIgnore     = 0
+	wtdRevocationCheckNone   = 0x00000010
+	trustENoSignature        = 0x800B0100
+	trustESubjectFormUnknown = 0x800B0003
+
+	maxPathLength = 32768
+)
+
+var (
+	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
+	modwintrust = windows.NewLazySystemDLL("wintrust.dll")
+
+	procQueryFullProcessImageNameW = modkernel32.NewProc("QueryFullProcessImageNameW")
+	procWinVerifyTrust             = modwintrust.NewProc("WinVerifyTrust")
+
+	// WINTRUST_ACTION_GENERIC_VERIFY_V2 verifies Authenticode signatures
+	wintrustActionGenericVerifyV2 = windows.GUID{
+		Data1: 0xaac56b,
+		Data2: 0xcd44,
+		Data3: 0x11d0,
+		Data4: [8]byte{0x8c, 0xc2, 0x00, 0xc0, 0x4f, 0xc2, 0x95, 0xee},
+	}
+)
+
+// wintrustFileInfo is the WINTRUST_FILE_INFO structure
+type wintrustFileInfo struct {
+	cbStruct       uint32
+	pcwszFilePath  *uint16
+	hFile          windows.Handle
+	pgKnownSubject *windows.GUID
+}
+
+// wintrustData is the WINTRUST_DATA structure
+type wintrustData struct {
+	cbStruct            uint32
+	pPolicyCallbackData uintptr
+	pSIPClientData      uintptr
+	dwUIChoice          uint32
+	fdwRevocationChecks uint32
+	dwUnionChoice       uint32
+	pFile               *wintrustFileInfo
+	dwStateAction       uint32
+	hWVTStateData       windows.Handle
+	pwszURLReference    *uint16
+	dwProvFlags         uint32
+	dwUIContext         uint32
+	pSignatureSettings  uintptr
+}
+
+// getProcessInfo reads the user and groups from the access token of the
+// process, along with the path of its binary
+func getProcessInfo(pid int32) (*processInfo, error) {
+	h, err := windows.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open process: %v", err)
+	}
+	defer windows.CloseHandle(h)
+
+	var token windows.Token
+	if err := windows.OpenProcessToken(h, windows.TOKEN_QUERY, &token); err != nil {
+		return nil, fmt.Errorf("unable to open process token: %v", err)
+	}
+	defer token.Close()
+
+	info := new(processInfo)
+
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get token user: %v", err)
+	}
+	info.User, err = lookupAccount(user.User.Sid)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := token.GetTokenGroups()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get token groups: %v", err)
+	}
+	for _, group := range tokenGroups(groups) {
+		// disabled groups are not used for access checks and the logon SID
+		// is unique to the logon session
+		if group.Attributes&seGroupEnabled == 0 || group.Attributes&seGroupLogonID == seGroupLogonID {
+			continue
+		}
+		account, err := lookupAccount(group.Sid)
+		if err != nil {
+			return nil, err
+		}
+		info.Groups = append(info.Groups, account)
+	}
+
+	info.Path, err = queryFullProcessImageName(h)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get process image name: %v", err)
+	}
+
+	return info, nil
+}
+
+func tokenGroups(groups *windows.Tokengroups) []windows.SIDAndAttributes {
+	n := groups.GroupCount
+	return (*[1 << 20]windows.SIDAndAttributes)(unsafe.Pointer(&groups.Groups[0]))[:n:n]
+}
+
+func lookupAccount(sid *windows.SID) (account, error) {
+	sidString, err := sid.String()
+	if err != nil {
+		return account{}, fmt.Errorf("unable to convert SID to string: %v", err)
+	}
+
+	// not every SID maps to an account name (e.g. capability SIDs)
+	var name string
+	if accountName, domain, _, err := sid.LookupAccount(""); err == nil {
+		name = accountName
+		if domain != "" {
+			name = domain + `\` + accountName
+		}
+	}
+
+	return account{
+		SID:  sidString,
+		Name: name,
+	}, nil
+}
+
+func queryFullProcessImageName(h windows.Handle) (string, error) {
+	buf := make([]uint16, maxPathLength)
+	size := uint32(len(buf))
+	r1, _, err := procQueryFullProcessImageNameW.Call(uintptr(h), 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+	if r1 == 0 {
+		return "", err
+	}
+	return windows.UTF16ToString(buf[:size]), nil
+}
+
+// getPublisher returns the common name of the signer of the binary. It
+// returns an empty string if the binary does not carry an embedded
+// Authenticode signature.
+func getPublisher(path string) (string, error) {
+	switch err := verifyTrust(path); err {
+	case nil:
+	case errNotSigned:
+		return "", nil
+	default:
+		return "", err
+	}
+
+	cert, err := readSigner(path)
+	if err != nil {
+		return "", err
+	}
+	return cert.Subject.CommonName, nil
+}
+
+// verifyTrust verifies the Authenticode signature embedded in the file
+// chains to a trusted root
+func verifyTrust(path string) error {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	fileInfo := &wintrustFileInfo{
+		pcwszFilePath: pathPtr,
+	}
+	fileInfo.cbStruct = uint32(unsafe.Sizeof(*fileInfo))
+
+	data := &wintrustData{
+		dwUIChoice:          wtdUINone,
+		fdwRevocationChecks: wtdRevokeNone,
+		dwUnionChoice:       wtdChoiceFile,
+		pFile:               fileInfo,
+		dwStateAction:       wtdStateActionIgnore,
+		dwProvFlags:         wtdRevocationCheckNone,
+	}
+	data.cbStruct = uint32(unsafe.Sizeof(*data))
+
+	r1, _, _ := procWinVerifyTrust.Call(0, uintptr(unsafe.Pointer(&wintrustActionGenericVerifyV2)), uintptr(unsafe.Pointer(data)))
+	switch status := uint32(r1); status {
+	case 0:
+		return nil
+	case trustENoSignature, trustESubjectFormUnknown:
+		return errNotSigned
+	default:
+		return fmt.Errorf("signature verification failed: %#x", status)
+	}
+}
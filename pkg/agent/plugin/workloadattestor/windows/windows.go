@@ -0,0 +1,146 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/zeebo/errs"
+)
+
+const (
+	selectorType = "windows"
+)
+
+var (
+	windowsErr = errs.Class("windows")
+)
+
+// account is a security principal identified by its SID
+type account struct {
+	SID string
+	// Name is the DOMAIN\name of the account. It is empty if the SID
+	// could not be mapped to a name.
+	Name string
+}
+
+// processInfo holds the security context of a process, taken from its
+// access token
+type processInfo struct {
+	User   account
+	Groups []account
+	Path   string
+}
+
+type Configuration struct {
+	// DiscoverWorkloadPath, if true, produces a selector with the path of
+	// the workload binary
+	DiscoverWorkloadPath bool `hcl:"discover_workload_path"`
+
+	// DiscoverPublisher, if true, produces a selector with the publisher of
+	// the workload binary, if it carries a trusted Authenticode signature
+	DiscoverPublisher bool `hcl:"discover_publisher"`
+}
+
+type WindowsPlugin struct {
+	mu     sync.Mutex
+	config *Configuration
+
+	// hooks for tests
+	hooks struct {
+		getProcessInfo func(pid int32) (*processInfo, error)
+		getPublisher   func(path string) (string, error)
+	}
+}
+
+func New() *WindowsPlugin {
+	p := &WindowsPlugin{}
+	p.hooks.getProcessInfo = getProcessInfo
+	p.hooks.getPublisher = getPublisher
+	return p
+}
+
+func (p *WindowsPlugin) Attest(ctx context.Context, req *workloadattestor.AttestRequest) (*workloadattestor.AttestResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	proc, err := p.hooks.getProcessInfo(req.Pid)
+	if err != nil {
+		return nil, windowsErr.New("process lookup: %v", err)
+	}
+
+	selectors := []*common.Selector{
+		makeSelector("user_sid", proc.User.SID),
+	}
+	if proc.User.Name != "" {
+		selectors = append(selectors, makeSelector("user_name", proc.User.Name))
+	}
+	for _, group := range proc.Groups {
+		selectors = append(selectors, makeSelector("group_sid", group.SID))
+		if group.Name != "" {
+			selectors = append(selectors, makeSelector("group_name", group.Name))
+		}
+	}
+
+	if config.DiscoverWorkloadPath {
+		selectors = append(selectors, makeSelector("path", proc.Path))
+	}
+
+	// verifying the signature requires reading the whole binary, so it is
+	// behind a config flag
+	if config.DiscoverPublisher {
+		publisher, err := p.hooks.getPublisher(proc.Path)
+		if err != nil {
+			return nil, windowsErr.New("publisher lookup: %v", err)
+		}
+		if publisher != "" {
+			selectors = append(selectors, makeSelector("publisher", publisher))
+		}
+	}
+
+	return &workloadattestor.AttestResponse{
+		Selectors: selectors,
+	}, nil
+}
+
+func (p *WindowsPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(Configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, windowsErr.Wrap(err)
+	}
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *WindowsPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *WindowsPlugin) getConfig() (*Configuration, error) {
+	p.mu.Lock()
+	config := p.config
+	p.mu.Unlock()
+	if config == nil {
+		return nil, windowsErr.New("not configured")
+	}
+	return config, nil
+}
+
+func (p *WindowsPlugin) setConfig(config *Configuration) {
+	p.mu.Lock()
+	p.config = config
+	p.mu.Unlock()
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  selectorType,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
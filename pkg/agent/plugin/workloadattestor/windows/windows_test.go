@@ -0,0 +1,172 @@
+package windows
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestWindowsPlugin(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	p         *workloadattestor.BuiltIn
+	processes map[int32]*processInfo
+}
+
+func (s *Suite) SetupTest() {
+	s.processes = map[int32]*processInfo{
+		1: {
+			User: account{SID: "S-1-5-21-1-2-3-1001", Name: `CORP\svc-web`},
+			Groups: []account{
+				{SID: "S-1-5-32-545", Name: `BUILTIN\Users`},
+				{SID: "S-1-15-3-1"},
+			},
+			Path: `C:\Program Files\Web\web.exe`,
+		},
+		2: {
+			User: account{SID: "S-1-5-18", Name: `NT AUTHORITY\SYSTEM`},
+			Path: `C:\Tools\unsigned.exe`,
+		},
+		3: {
+			User: account{SID: "S-1-5-18", Name: `NT AUTHORITY\SYSTEM`},
+			Path: `C:\Tools\tampered.exe`,
+		},
+	}
+
+	p := New()
+	p.hooks.getProcessInfo = func(pid int32) (*processInfo, error) {
+		proc, ok := s.processes[pid]
+		if !ok {
+			return nil, errors.New("The parameter is incorrect.")
+		}
+		return proc, nil
+	}
+	p.hooks.getPublisher = func(path string) (string, error) {
+		switch path {
+		case `C:\Program Files\Web\web.exe`:
+			return "Example Corp", nil
+		case `C:\Tools\tampered.exe`:
+			return "", errors.New("signature verification failed: 0x80096010")
+		}
+		return "", nil
+	}
+	s.p = workloadattestor.NewBuiltIn(p)
+	s.configure("")
+}
+
+func (s *Suite) TestAttest() {
+	testCases := []struct {
+		name      string
+		pid       int32
+		err       string
+		selectors []string
+		config    string
+	}{
+		{
+			name: "user and groups",
+			pid:  1,
+			selectors: []string{
+				"user_sid:S-1-5-21-1-2-3-1001",
+				`user_name:CORP\svc-web`,
+				"group_sid:S-1-5-32-545",
+				`group_name:BUILTIN\Users`,
+				"group_sid:S-1-15-3-1",
+			},
+		},
+		{
+			name: "workload path and publisher",
+			pid:  1,
+			selectors: []string{
+				"user_sid:S-1-5-21-1-2-3-1001",
+				`user_name:CORP\svc-web`,
+				"group_sid:S-1-5-32-545",
+				`group_name:BUILTIN\Users`,
+				"group_sid:S-1-15-3-1",
+				`path:C:\Program Files\Web\web.exe`,
+				"publisher:Example Corp",
+			},
+			config: "discover_workload_path = true\ndiscover_publisher = true",
+		},
+		{
+			name: "unsigned binary",
+			pid:  2,
+			selectors: []string{
+				"user_sid:S-1-5-18",
+				`user_name:NT AUTHORITY\SYSTEM`,
+			},
+			config: "discover_publisher = true",
+		},
+		{
+			name:   "signature verification fails",
+			pid:    3,
+			err:    "windows: publisher lookup: signature verification failed: 0x80096010",
+			config: "discover_publisher = true",
+		},
+		{
+			name: "process lookup fails",
+			pid:  4,
+			err:  "windows: process lookup: The parameter is incorrect.",
+		},
+	}
+
+	for _, testCase := range testCases {
+		s.T().Run(testCase.name, func(t *testing.T) {
+			s.configure(testCase.config)
+			resp, err := s.p.Attest(ctx, &workloadattestor.AttestRequest{
+				Pid: testCase.pid,
+			})
+			if testCase.err != "" {
+				s.Require().EqualError(err, testCase.err)
+				s.Require().Nil(resp)
+				return
+			}
+
+			s.Require().NoError(err)
+			s.Require().NotNil(resp)
+			var selectors []string
+			for _, selector := range resp.Selectors {
+				s.Require().Equal("windows", selector.Type)
+				selectors = append(selectors, selector.Value)
+			}
+			s.Require().Equal(testCase.selectors, selectors)
+		})
+	}
+}
+
+func (s *Suite) TestAttestNotConfigured() {
+	p := workloadattestor.NewBuiltIn(New())
+	resp, err := p.Attest(ctx, &workloadattestor.AttestRequest{Pid: 1})
+	s.Require().EqualError(err, "windows: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestConfigure() {
+	resp, err := s.p.Configure(ctx, &spi.ConfigureRequest{Configuration: "blah"})
+	s.Require().Error(err)
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestGetPluginInfo() {
+	resp, err := s.p.GetPluginInfo(ctx, &spi.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+}
+
+func (s *Suite) configure(config string) {
+	_, err := s.p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: config,
+	})
+	s.Require().NoError(err)
+}
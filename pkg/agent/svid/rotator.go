@@ -45,6 +45,13 @@ func (r *rotator) Run(ctx context.Context) error {
 	t := time.NewTicker(r.c.Interval)
 	defer t.Stop()
 
+	var reattestTick <-chan time.Time
+	if r.c.Reattestor != nil && r.c.ReattestInterval > 0 {
+		rt := time.NewTicker(r.c.ReattestInterval)
+		defer rt.Stop()
+		reattestTick = rt.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -57,6 +64,14 @@ func (r *rotator) Run(ctx context.Context) error {
 					r.c.Log.Errorf("Could not rotate agent SVID: %v", err)
 				}
 			}
+		case <-reattestTick:
+			if err := r.reattest(ctx); err != nil {
+				r.c.Log.Errorf("Could not re-attest agent: %v", err)
+			}
+		case <-r.c.ReattestCh:
+			if err := r.reattest(ctx); err != nil {
+				r.c.Log.Errorf("Could not re-attest agent: %v", err)
+			}
 		case <-r.c.BundleStream.Changes():
 			r.bsm.Lock()
 			r.c.BundleStream.Next()
@@ -138,6 +153,41 @@ func (r *rotator) rotateSVID(ctx context.Context) (err error) {
 	return nil
 }
 
+// reattest performs node attestation again and replaces the agent SVID with
+// the freshly attested one.
+func (r *rotator) reattest(ctx context.Context) (err error) {
+	if r.c.Reattestor == nil {
+		return errors.New("re-attestation is not available")
+	}
+
+	counter := telemetry.StartCall(r.c.Metrics, "agent_svid", "reattest")
+	defer counter.Done(&err)
+
+	counter.AddLabel("spiffe_id", r.c.SpiffeID)
+	r.c.Log.Debug("Re-attesting agent")
+
+	s := r.State()
+	certs, key, err := r.c.Reattestor(ctx, s.SVID, s.Key)
+	if err != nil {
+		return err
+	}
+
+	if err := r.storeKey(ctx, key); err != nil {
+		return err
+	}
+
+	// The previous SVID is no longer valid on the server, so release the
+	// client to reconnect with the new one.
+	r.client.Release()
+
+	r.state.Update(State{
+		SVID: certs,
+		Key:  key,
+	})
+	r.c.Log.Info("Agent re-attested")
+	return nil
+}
+
 func (r *rotator) newKey(ctx context.Context) (*ecdsa.PrivateKey, error) {
 	mgrs := r.c.Catalog.KeyManagers()
 	if len(mgrs) > 1 {
@@ -1,6 +1,7 @@
 package svid
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/x509"
 	"net/url"
@@ -32,6 +33,18 @@ type RotatorConfig struct {
 
 	// How long to wait between expiry checks
 	Interval time.Duration
+
+	// Reattestor performs node attestation again using the current SVID and
+	// key, returning the freshly attested SVID and key. If nil, the agent
+	// is never re-attested.
+	Reattestor func(ctx context.Context, svid []*x509.Certificate, key *ecdsa.PrivateKey) ([]*x509.Certificate, *ecdsa.PrivateKey, error)
+
+	// How long to wait between re-attestations. Zero disables periodic
+	// re-attestation.
+	ReattestInterval time.Duration
+
+	// ReattestCh receives on-demand re-attestation requests
+	ReattestCh <-chan struct{}
 }
 
 func NewRotator(c *RotatorConfig) (*rotator, client.Client) {
@@ -2,7 +2,9 @@ package svid
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"crypto/x509"
+	"errors"
 	"net/url"
 	"testing"
 	"time"
@@ -197,6 +199,104 @@ func (s *RotatorTestSuite) TestRotateSVID() {
 	s.Assert().Equal(state.Key, storedKey)
 }
 
+func (s *RotatorTestSuite) TestReattest() {
+	cert, key, err := util.LoadSVIDFixture()
+	s.Require().NoError(err)
+	s.r.state = observer.NewProperty(State{
+		SVID: []*x509.Certificate{cert},
+		Key:  key,
+	})
+
+	temp, err := util.NewSVIDTemplate("spiffe://example.org/spire/agent/1234")
+	s.Require().NoError(err)
+	newCert, newKey, err := util.SelfSign(temp)
+	s.Require().NoError(err)
+	s.r.c.Reattestor = func(ctx context.Context, svid []*x509.Certificate, k *ecdsa.PrivateKey) ([]*x509.Certificate, *ecdsa.PrivateKey, error) {
+		// the current SVID and key authenticate the re-attestation
+		s.Require().Equal([]*x509.Certificate{cert}, svid)
+		s.Require().Equal(key, k)
+		return []*x509.Certificate{newCert}, newKey, nil
+	}
+	s.client.EXPECT().Release()
+
+	stream := s.r.Subscribe()
+	s.Require().NoError(s.r.reattest(context.Background()))
+	s.Require().True(stream.HasNext())
+
+	state := stream.Next().(State)
+	s.Require().Equal([]*x509.Certificate{newCert}, state.SVID)
+	s.Require().Equal(newKey, state.Key)
+
+	// keymanager data matches state
+	mgr := s.r.c.Catalog.KeyManagers()[0]
+	kresp, err := mgr.FetchPrivateKey(context.Background(), &keymanager.FetchPrivateKeyRequest{})
+	s.Require().NoError(err)
+	storedKey, err := x509.ParseECPrivateKey(kresp.PrivateKey)
+	s.Require().NoError(err)
+	s.Assert().Equal(newKey, storedKey)
+}
+
+func (s *RotatorTestSuite) TestReattestFailure() {
+	cert, key, err := util.LoadSVIDFixture()
+	s.Require().NoError(err)
+	s.r.state = observer.NewProperty(State{
+		SVID: []*x509.Certificate{cert},
+		Key:  key,
+	})
+
+	s.r.c.Reattestor = func(context.Context, []*x509.Certificate, *ecdsa.PrivateKey) ([]*x509.Certificate, *ecdsa.PrivateKey, error) {
+		return nil, nil, errors.New("attestation failed")
+	}
+
+	stream := s.r.Subscribe()
+	s.Require().EqualError(s.r.reattest(context.Background()), "attestation failed")
+	s.Require().False(stream.HasNext())
+}
+
+func (s *RotatorTestSuite) TestReattestNotAvailable() {
+	s.Require().EqualError(s.r.reattest(context.Background()), "re-attestation is not available")
+}
+
+func (s *RotatorTestSuite) TestRunReattestsOnDemand() {
+	cert, key, err := util.LoadSVIDFixture()
+	s.Require().NoError(err)
+	s.r.state = observer.NewProperty(State{
+		SVID: []*x509.Certificate{cert},
+		Key:  key,
+	})
+
+	temp, err := util.NewSVIDTemplate("spiffe://example.org/spire/agent/1234")
+	s.Require().NoError(err)
+	newCert, newKey, err := util.SelfSign(temp)
+	s.Require().NoError(err)
+	s.r.c.Reattestor = func(context.Context, []*x509.Certificate, *ecdsa.PrivateKey) ([]*x509.Certificate, *ecdsa.PrivateKey, error) {
+		return []*x509.Certificate{newCert}, newKey, nil
+	}
+	reattestCh := make(chan struct{}, 1)
+	s.r.c.ReattestCh = reattestCh
+	s.client.EXPECT().Release().Times(2)
+
+	stream := s.r.Subscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t := new(tomb.Tomb)
+	t.Go(func() error {
+		return s.r.Run(ctx)
+	})
+
+	reattestCh <- struct{}{}
+	select {
+	case <-time.NewTimer(5 * time.Second).C:
+		s.T().Error("re-attestation timeout reached")
+	case <-stream.Changes():
+		state := stream.Next().(State)
+		s.Require().Equal([]*x509.Certificate{newCert}, state.SVID)
+	}
+
+	cancel()
+	s.Require().NoError(t.Wait())
+}
+
 // expectSVIDRotation sets the appropriate expectations for an SVID rotation, and returns
 // the the provided certificate to the client.Client caller.
 func (s *RotatorTestSuite) expectSVIDRotation(cert *x509.Certificate) {
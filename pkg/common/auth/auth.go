@@ -1,8 +1,8 @@
 /*
 The auth package handles GRPC transport "security" for the workload API. It
 does so by implementing the GRPC credential interface, the function of which
-is dependent on the underlying transport method. Currently, only UNIX domain
-sockets are supported.
+is dependent on the underlying transport method. Currently, UNIX domain
+sockets and, on Windows, named pipes are supported.
 
 In the context of the Workload API, we are looking to retrieve the PID of the
 caller. To do this, two steps are required: 1) use one of the types provided
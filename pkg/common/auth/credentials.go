@@ -26,6 +26,8 @@ func (c *grpcCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.
 	switch conn.RemoteAddr().Network() {
 	case "unix":
 		info = FromUDSConn(conn)
+	case "pipe":
+		info = FromPipeConn(conn)
 	default:
 		info = CallerInfo{Err: ErrUnsupportedTransport}
 	}
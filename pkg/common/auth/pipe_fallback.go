@@ -0,0 +1,11 @@
+// +build !windows
+
+package auth
+
+import "net"
+
+func FromPipeConn(conn net.Conn) CallerInfo {
+	var info CallerInfo
+	info.Err = ErrUnsupportedPlatform
+	return info
+}
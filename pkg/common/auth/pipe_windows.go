@@ -0,0 +1,56 @@
+// +build windows
+
+package auth
+
+import (
+	"net"
+	"reflect"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+	procGetNamedPipeClientProcessId = modkernel32.NewProc("GetNamedPipeClientProcessId")
+)
+
+func FromPipeConn(conn net.Conn) CallerInfo {
+	var info CallerInfo
+
+	handle, ok := pipeHandle(conn)
+	if !ok {
+		info.Err = ErrInvalidConnection
+		return info
+	}
+
+	var pid uint32
+	r1, _, err := procGetNamedPipeClientProcessId.Call(handle, uintptr(unsafe.Pointer(&pid)))
+	if r1 == 0 {
+		info.Err = err
+		return info
+	}
+
+	info.Addr = conn.RemoteAddr()
+	info.PID = int32(pid)
+	return info
+}
+
+// pipeHandle returns the handle of a named pipe connection accepted by a
+// go-winio listener. The version of go-winio in use does not expose the
+// handle, so it is read from the unexported field of the embedded file.
+func pipeHandle(conn net.Conn) (uintptr, bool) {
+	v := reflect.ValueOf(conn)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return 0, false
+	}
+	if _, ok := v.Elem().Type().FieldByName("handle"); !ok {
+		return 0, false
+	}
+	handle := v.Elem().FieldByName("handle")
+	if handle.Kind() != reflect.Uintptr {
+		return 0, false
+	}
+	return uintptr(handle.Uint()), true
+}
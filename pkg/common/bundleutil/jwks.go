@@ -1,12 +1,14 @@
 package bundleutil
 
 import (
+	"crypto/ed25519"
 	"crypto/x509"
 	"encoding/json"
 
 	"github.com/spiffe/spire/pkg/common/idutil"
 	"github.com/spiffe/spire/proto/common"
 	"github.com/zeebo/errs"
+	xed25519 "golang.org/x/crypto/ed25519"
 	jose "gopkg.in/square/go-jose.v2"
 )
 
@@ -27,7 +29,7 @@ func JWTJWKSFromBundle(bundle *Bundle) *jose.JSONWebKeySet {
 	jwks := new(jose.JSONWebKeySet)
 	for keyID, jwtSigningKey := range bundle.JWTSigningKeys() {
 		jwks.Keys = append(jwks.Keys, jose.JSONWebKey{
-			Key:   jwtSigningKey,
+			Key:   toJOSEKey(jwtSigningKey),
 			KeyID: keyID,
 			// TODO: fill in with proper use value when it is known
 			Use: jwtUse,
@@ -56,7 +58,7 @@ func JWKSFromBundle(bundle *Bundle) *JWKS {
 	}
 	for _, rootCA := range bundle.RootCAs() {
 		jwks.Keys = append(jwks.Keys, jose.JSONWebKey{
-			Key:          rootCA.PublicKey,
+			Key:          toJOSEKey(rootCA.PublicKey),
 			Certificates: []*x509.Certificate{rootCA},
 			// TODO: fill in with proper use value when it is known
 			Use: x509Use,
@@ -64,7 +66,7 @@ func JWKSFromBundle(bundle *Bundle) *JWKS {
 	}
 	for keyID, jwtSigningKey := range bundle.JWTSigningKeys() {
 		jwks.Keys = append(jwks.Keys, jose.JSONWebKey{
-			Key:   jwtSigningKey,
+			Key:   toJOSEKey(jwtSigningKey),
 			KeyID: keyID,
 			// TODO: fill in with proper use value when it is known
 			Use: jwtUse,
@@ -101,7 +103,7 @@ func BundleFromJWKS(jwks *JWKS) (*Bundle, error) {
 			if key.KeyID == "" {
 				return nil, errs.New("expected key ID in JWT key entry %d", i)
 			}
-			if err := bundle.AppendJWTSigningKey(key.KeyID, key.Key); err != nil {
+			if err := bundle.AppendJWTSigningKey(key.KeyID, fromJOSEKey(key.Key)); err != nil {
 				return nil, errs.New("failed to add JWT key entry %d: %v", i, err)
 			}
 		default:
@@ -111,3 +113,20 @@ func BundleFromJWKS(jwks *JWKS) (*Bundle, error) {
 
 	return bundle, nil
 }
+
+// toJOSEKey converts a key to the type expected by go-jose, which only
+// understands the Ed25519 key types from golang.org/x/crypto.
+func toJOSEKey(key interface{}) interface{} {
+	if key, ok := key.(ed25519.PublicKey); ok {
+		return xed25519.PublicKey(key)
+	}
+	return key
+}
+
+// fromJOSEKey is the inverse of toJOSEKey
+func fromJOSEKey(key interface{}) interface{} {
+	if key, ok := key.(xed25519.PublicKey); ok {
+		return ed25519.PublicKey(key)
+	}
+	return key
+}
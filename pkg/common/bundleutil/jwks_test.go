@@ -1,6 +1,8 @@
 package bundleutil
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/json"
 	"testing"
 
@@ -131,3 +133,20 @@ func TestBundleFromJWKSSuccess(t *testing.T) {
 	require.NoError(t, err)
 	require.JSONEq(t, jwksIn, string(jwksOut))
 }
+
+func TestJWKSEd25519RoundTrip(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bundle := New("spiffe://domain1.test")
+	require.NoError(t, bundle.AppendJWTSigningKey("KID", publicKey))
+
+	jwksBytes, err := json.Marshal(JWKSFromBundle(bundle))
+	require.NoError(t, err)
+	require.Contains(t, string(jwksBytes), `"kty":"OKP"`)
+	require.Contains(t, string(jwksBytes), `"crv":"Ed25519"`)
+
+	bundle, err = BundleFromJWKSBytes(jwksBytes)
+	require.NoError(t, err)
+	require.Equal(t, publicKey, bundle.JWTSigningKeys()["KID"])
+}
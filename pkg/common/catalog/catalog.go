@@ -57,6 +57,12 @@ type catalog struct {
 	m *sync.RWMutex
 }
 
+// NeedsLogger is implemented by builtin plugins that log. They are handed a
+// logger carrying the plugin type and name before they are configured.
+type NeedsLogger interface {
+	SetLogger(logrus.FieldLogger)
+}
+
 // BuiltinPluginMap organizes builtin plugin sets, accessed by
 // [plugin type][plugin name]
 type BuiltinPluginMap map[string]map[string]Plugin
@@ -210,6 +216,12 @@ func (c *catalog) startPlugins() error {
 
 		builtin := c.builtins(p.Config.PluginType, p.Config.PluginName)
 		if builtin != nil {
+			if b, ok := builtin.(NeedsLogger); ok {
+				b.SetLogger(c.l.WithFields(logrus.Fields{
+					"plugin_type": pluginType,
+					"plugin_name": pluginName,
+				}))
+			}
 			p.Plugin = builtin
 			continue
 		}
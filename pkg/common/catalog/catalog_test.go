@@ -1,6 +1,7 @@
 package catalog
 
 import (
+	"context"
 	"net/rpc"
 	"os/exec"
 	"testing"
@@ -8,8 +9,10 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/hcl"
+	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spiffe/spire/pkg/common/log"
+	pb "github.com/spiffe/spire/proto/common/plugin"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -20,6 +23,19 @@ type testPlugin struct{}
 func (testPlugin) Server(_ *plugin.MuxBroker) (interface{}, error)                { return nil, nil }
 func (testPlugin) Client(_ *plugin.MuxBroker, _ *rpc.Client) (interface{}, error) { return nil, nil }
 
+// loggingPlugin is a builtin plugin that logs
+type loggingPlugin struct {
+	log logrus.FieldLogger
+}
+
+func (p *loggingPlugin) SetLogger(log logrus.FieldLogger) { p.log = log }
+func (p *loggingPlugin) Configure(context.Context, *pb.ConfigureRequest) (*pb.ConfigureResponse, error) {
+	return &pb.ConfigureResponse{}, nil
+}
+func (p *loggingPlugin) GetPluginInfo(context.Context, *pb.GetPluginInfoRequest) (*pb.GetPluginInfoResponse, error) {
+	return &pb.GetPluginInfoResponse{}, nil
+}
+
 type CatalogTestSuite struct {
 	suite.Suite
 
@@ -103,6 +119,24 @@ func (c *CatalogTestSuite) TestNewPluginConfig() {
 	}
 }
 
+func (c *CatalogTestSuite) TestStartPluginsHandsLoggerToBuiltins() {
+	builtin := new(loggingPlugin)
+	c.catalog.builtinPlugins = BuiltinPluginMap{"NodeAttestor": {"join_token": builtin}}
+
+	c.Require().NoError(c.catalog.loadConfigs())
+	c.Require().NoError(c.catalog.startPlugins())
+	c.Require().Equal(builtin, c.catalog.plugins[0].Plugin)
+
+	c.Require().NotNil(builtin.log)
+	builtin.log.Info("hello")
+	entry := c.logHook.LastEntry()
+	c.Require().Equal("hello", entry.Message)
+	c.Require().Equal(logrus.Fields{
+		"plugin_type": "NodeAttestor",
+		"plugin_name": "join_token",
+	}, entry.Data)
+}
+
 func TestCatalog(t *testing.T) {
 	suite.Run(t, new(CatalogTestSuite))
 }
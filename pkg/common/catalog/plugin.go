@@ -23,6 +23,10 @@ type PluginConfig struct {
 	PluginData string `hcl:"plugin_data"`
 	PluginType string
 	Enabled    bool `hcl:"enabled"`
+
+	// KeyPurposes restricts the keys a server KeyManager is used for. It
+	// does not apply to other plugin types.
+	KeyPurposes []string `hcl:"key_purposes"`
 }
 
 // HclPluginConfig serves as an intermediary struct. We pass this to the
@@ -37,6 +41,8 @@ type HclPluginConfig struct {
 	PluginData ast.Node `hcl:"plugin_data"`
 	PluginType string
 	Enabled    *bool `hcl:"enabled"`
+
+	KeyPurposes []string `hcl:"key_purposes"`
 }
 
 func (c HclPluginConfig) IsEnabled() bool {
@@ -68,6 +74,7 @@ func parsePluginConfig(hclPluginConfig HclPluginConfig) (PluginConfig, error) {
 		PluginChecksum: hclPluginConfig.PluginChecksum,
 		PluginType:     hclPluginConfig.PluginType,
 		Enabled:        hclPluginConfig.IsEnabled(),
+		KeyPurposes:    hclPluginConfig.KeyPurposes,
 
 		// Handle PluginData as opaque string. This gets fed
 		// to the plugin, whos job it is to parse it.
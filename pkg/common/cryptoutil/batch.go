@@ -0,0 +1,153 @@
+package cryptoutil
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/spiffe/spire/proto/server/keymanager"
+)
+
+// SignBatch calls sign concurrently for each of n items, passing a signer for
+// the given key manager key. The signatures requested through the signers
+// are collected and made with a single SignDataBatch call, which amortizes
+// the cost of a key manager round trip over the batch. Each signer can make
+// at most one signature. The first error returned by sign, in item order, is
+// returned.
+func SignBatch(ctx context.Context, km keymanager.KeyManager, keyId string, publicKey crypto.PublicKey, n int, sign func(i int, signer crypto.Signer) error) error {
+	b := &batchSigner{
+		ctx:         ctx,
+		km:          km,
+		keyId:       keyId,
+		publicKey:   publicKey,
+		outstanding: n,
+	}
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			signer := &batchItemSigner{b: b}
+			errs[i] = sign(i, signer)
+			if !signer.used {
+				b.release()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type signResult struct {
+	signature []byte
+	err       error
+}
+
+type pendingSignature struct {
+	req    *keymanager.SignDataRequest
+	result chan signResult
+}
+
+type batchSigner struct {
+	ctx       context.Context
+	km        keymanager.KeyManager
+	keyId     string
+	publicKey crypto.PublicKey
+
+	mu          sync.Mutex
+	outstanding int
+	pending     []*pendingSignature
+}
+
+// enqueue adds a signature to the batch. The batch is sent once every item
+// has either requested a signature or finished without one.
+func (b *batchSigner) enqueue(req *keymanager.SignDataRequest) <-chan signResult {
+	p := &pendingSignature{
+		req:    req,
+		result: make(chan signResult, 1),
+	}
+	b.mu.Lock()
+	b.pending = append(b.pending, p)
+	b.outstanding--
+	flush := b.outstanding == 0
+	b.mu.Unlock()
+
+	if flush {
+		b.flush()
+	}
+	return p.result
+}
+
+// release accounts for an item that finished without requesting a signature
+func (b *batchSigner) release() {
+	b.mu.Lock()
+	b.outstanding--
+	flush := b.outstanding == 0
+	b.mu.Unlock()
+
+	if flush {
+		b.flush()
+	}
+}
+
+func (b *batchSigner) flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	req := &keymanager.SignDataBatchRequest{
+		Requests: make([]*keymanager.SignDataRequest, 0, len(pending)),
+	}
+	for _, p := range pending {
+		req.Requests = append(req.Requests, p.req)
+	}
+
+	resp, err := b.km.SignDataBatch(b.ctx, req)
+	if err == nil && len(resp.Responses) != len(pending) {
+		err = fmt.Errorf("expected %d signatures in batch response; got %d", len(pending), len(resp.Responses))
+	}
+	for i, p := range pending {
+		switch {
+		case err != nil:
+			p.result <- signResult{err: err}
+		case len(resp.Responses[i].Signature) == 0:
+			p.result <- signResult{err: errors.New("response missing signature data")}
+		default:
+			p.result <- signResult{signature: resp.Responses[i].Signature}
+		}
+	}
+}
+
+type batchItemSigner struct {
+	b    *batchSigner
+	used bool
+}
+
+func (s *batchItemSigner) Public() crypto.PublicKey {
+	return s.b.publicKey
+}
+
+func (s *batchItemSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if s.used {
+		return nil, errors.New("batch signer can only be used for one signature")
+	}
+	s.used = true
+	result := <-s.b.enqueue(makeSignDataRequest(s.b.keyId, digest, opts))
+	return result.signature, result.err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"errors"
@@ -12,6 +13,10 @@ import (
 	"github.com/spiffe/spire/proto/server/keymanager"
 )
 
+func Ed25519PublicKeyEqual(a, b ed25519.PublicKey) bool {
+	return a.Equal(b)
+}
+
 func RSAPublicKeyEqual(a, b *rsa.PublicKey) bool {
 	return a.E == b.E && a.N.Cmp(b.N) == 0
 }
@@ -53,6 +58,9 @@ func PublicKeyEqual(a, b crypto.PublicKey) (bool, error) {
 	case *ecdsa.PublicKey:
 		ecdsaPublicKey, ok := b.(*ecdsa.PublicKey)
 		return ok && ECDSAPublicKeyEqual(a, ecdsaPublicKey), nil
+	case ed25519.PublicKey:
+		ed25519PublicKey, ok := b.(ed25519.PublicKey)
+		return ok && Ed25519PublicKeyEqual(a, ed25519PublicKey), nil
 	default:
 		return false, fmt.Errorf("unsupported public key type %T", a)
 	}
@@ -66,6 +74,9 @@ func KeyMatches(privateKey crypto.PrivateKey, publicKey crypto.PublicKey) (bool,
 	case *ecdsa.PrivateKey:
 		ecdsaPublicKey, ok := publicKey.(*ecdsa.PublicKey)
 		return ok && ECDSAKeyMatches(privateKey, ecdsaPublicKey), nil
+	case ed25519.PrivateKey:
+		ed25519PublicKey, ok := publicKey.(ed25519.PublicKey)
+		return ok && Ed25519PublicKeyEqual(privateKey.Public().(ed25519.PublicKey), ed25519PublicKey), nil
 	default:
 		return false, fmt.Errorf("unsupported private key type %T", privateKey)
 	}
@@ -33,8 +33,26 @@ func (s *KeyManagerSigner) Public() crypto.PublicKey {
 }
 
 func (s *KeyManagerSigner) SignContext(ctx context.Context, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	resp, err := s.km.SignData(ctx, makeSignDataRequest(s.keyId, digest, opts))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Signature) == 0 {
+		return nil, fmt.Errorf("response missing signature data")
+	}
+	return resp.Signature, nil
+}
+
+func (s *KeyManagerSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	// rand is purposefully ignored since it can't be communicated between
+	// the plugin boundary. The crypto.Signer interface implies this is ok
+	// when it says "possibly using entropy from rand".
+	return s.SignContext(context.Background(), digest, opts)
+}
+
+func makeSignDataRequest(keyId string, digest []byte, opts crypto.SignerOpts) *keymanager.SignDataRequest {
 	req := &keymanager.SignDataRequest{
-		KeyId: s.keyId,
+		KeyId: keyId,
 		Data:  digest,
 	}
 	switch opts := opts.(type) {
@@ -50,22 +68,7 @@ func (s *KeyManagerSigner) SignContext(ctx context.Context, digest []byte, opts
 			HashAlgorithm: keymanager.HashAlgorithm(opts.HashFunc()),
 		}
 	}
-
-	resp, err := s.km.SignData(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-	if len(resp.Signature) == 0 {
-		return nil, fmt.Errorf("response missing signature data")
-	}
-	return resp.Signature, nil
-}
-
-func (s *KeyManagerSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
-	// rand is purposefully ignored since it can't be communicated between
-	// the plugin boundary. The crypto.Signer interface implies this is ok
-	// when it says "possibly using entropy from rand".
-	return s.SignContext(context.Background(), digest, opts)
+	return req
 }
 
 func GenerateKeyRaw(ctx context.Context, km keymanager.KeyManager, keyId string, keyType keymanager.KeyType) ([]byte, error) {
@@ -3,8 +3,10 @@ package jwtsvid
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/asn1"
+	"fmt"
 	"math/big"
 
 	jwt "github.com/dgrijalva/jwt-go"
@@ -14,8 +16,29 @@ var (
 	signingMethodES256 = &signingMethodECDSA{
 		SigningMethodECDSA: jwt.SigningMethodES256,
 	}
+
+	signingMethodEdDSA = &signingMethodEd25519{}
 )
 
+func init() {
+	jwt.RegisterSigningMethod(signingMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return signingMethodEdDSA
+	})
+}
+
+// signingMethodForKey returns the signing method used to sign tokens with the
+// given public key
+func signingMethodForKey(publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return signingMethodES256, nil
+	case ed25519.PublicKey:
+		return signingMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", publicKey)
+	}
+}
+
 // signingMethodECDSA is a copy of the implementation of the JWT package
 // modified to accomodate both an *ecdsa.PrivateKey and a crypto.Signer based
 // key. It can be thrown away as soon as
@@ -85,3 +108,49 @@ func (m *signingMethodECDSA) Sign(signingString string, key interface{}) (string
 
 	return jwt.EncodeSegment(out), nil
 }
+
+// signingMethodEd25519 implements the "EdDSA" algorithm (RFC 8037) for
+// Ed25519 keys, which the JWT package does not support. Like
+// signingMethodECDSA, it signs with any crypto.Signer based key.
+type signingMethodEd25519 struct{}
+
+func (m *signingMethodEd25519) Alg() string {
+	return "EdDSA"
+}
+
+func (m *signingMethodEd25519) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	signatureBytes, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), signatureBytes) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+func (m *signingMethodEd25519) Sign(signingString string, key interface{}) (string, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	// make sure the signer is for Ed25519
+	if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	// Ed25519 signs the message itself instead of a digest
+	signatureBytes, err := signer.Sign(rand.Reader, []byte(signingString), crypto.Hash(0))
+	if err != nil {
+		return "", err
+	}
+
+	return jwt.EncodeSegment(signatureBytes), nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
 	"crypto/x509"
 	"encoding/pem"
 	"testing"
@@ -83,6 +85,33 @@ func (s *TokenSuite) TestSignAndValidateWithAudienceList() {
 	s.Require().NotEmpty(claims)
 }
 
+func (s *TokenSuite) TestSignAndValidateEd25519() {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	s.Require().NoError(err)
+	bundle := NewKeyStore(map[string]map[string]crypto.PublicKey{
+		"spiffe://example.org": {
+			"kid": publicKey,
+		},
+	})
+
+	token, err := SignToken(fakeSpiffeID, fakeAudience, time.Now().Add(time.Hour), privateKey, "kid")
+	s.Require().NoError(err)
+	s.Require().NotEmpty(token)
+
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	s.Require().NoError(err)
+	s.Require().Equal("EdDSA", parsed.Header["alg"])
+
+	spiffeID, claims, err := ValidateToken(ctx, token, bundle, fakeAudience[0:1])
+	s.Require().NoError(err)
+	s.Require().Equal(fakeSpiffeID, spiffeID)
+	s.Require().NotEmpty(claims)
+
+	// the token does not validate against an EC key with the same id
+	_, _, err = ValidateToken(ctx, token, s.bundle, fakeAudience[0:1])
+	s.Require().EqualError(err, "key is of invalid type")
+}
+
 func (s *TokenSuite) TestSignWithNoExpiration() {
 	_, err := SignToken(fakeSpiffeID, fakeAudience, time.Time{}, s.key, "kid")
 	s.Require().EqualError(err, "expiration is required")
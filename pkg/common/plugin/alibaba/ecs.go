@@ -0,0 +1,141 @@
+package alibaba
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	PluginName = "alibaba_ecs"
+
+	// DefaultMetadataURL is the base URL of the ECS instance metadata
+	// service
+	DefaultMetadataURL = "http://100.100.100.200"
+
+	// metadataTokenTTL is the lifetime, in seconds, requested for the
+	// metadata access token used in security hardening mode
+	metadataTokenTTL = "60"
+
+	maxMetadataSize = 64 * 1024
+)
+
+// AttestationData is sent by the agent to the server. Document is the raw
+// instance identity document and Signature the base64 encoded PKCS#7
+// signature over it. RAMRole is the RAM role attached to the instance, as
+// reported by the metadata service, if any.
+type AttestationData struct {
+	Document  string `json:"document"`
+	Signature string `json:"signature"`
+	RAMRole   string `json:"ram_role,omitempty"`
+}
+
+// InstanceIdentityDocument holds the fields of the instance identity
+// document relevant to attestation
+type InstanceIdentityDocument struct {
+	AccountID    string `json:"account-id"`
+	InstanceID   string `json:"instance-id"`
+	RegionID     string `json:"region-id"`
+	ZoneID       string `json:"zone-id"`
+	ImageID      string `json:"image-id"`
+	InstanceType string `json:"instance-type"`
+}
+
+func AgentID(trustDomain string, doc *InstanceIdentityDocument) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, doc.AccountID, doc.RegionID, doc.InstanceID),
+	}
+	return u.String()
+}
+
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type HTTPClientFunc func(*http.Request) (*http.Response, error)
+
+func (fn HTTPClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// FetchAttestationData fetches the instance identity document, its
+// signature and the attached RAM role from the metadata service. A
+// metadata access token is obtained first so that instances enforcing
+// security hardening mode are supported.
+func FetchAttestationData(ctx context.Context, cl HTTPClient, metadataURL string) (*AttestationData, error) {
+	token, err := fetchMetadata(ctx, cl, "PUT", metadataURL+"/latest/api/token", "")
+	if err != nil {
+		return nil, errs.New("unable to obtain metadata token: %v", err)
+	}
+
+	document, err := fetchMetadata(ctx, cl, "GET", metadataURL+"/latest/dynamic/instance-identity/document", token)
+	if err != nil {
+		return nil, errs.New("unable to fetch instance identity document: %v", err)
+	}
+
+	signature, err := fetchMetadata(ctx, cl, "GET", metadataURL+"/latest/dynamic/instance-identity/pkcs7", token)
+	if err != nil {
+		return nil, errs.New("unable to fetch instance identity signature: %v", err)
+	}
+
+	// instances without a RAM role return an empty listing or not found
+	ramRole, err := fetchMetadata(ctx, cl, "GET", metadataURL+"/latest/meta-data/ram/security-credentials/", token)
+	if err != nil && err != errNotFound {
+		return nil, errs.New("unable to fetch RAM role: %v", err)
+	}
+
+	return &AttestationData{
+		Document:  document,
+		Signature: strings.TrimSpace(signature),
+		RAMRole:   strings.TrimSpace(ramRole),
+	}, nil
+}
+
+var errNotFound = errors.New("not found")
+
+func fetchMetadata(ctx context.Context, cl HTTPClient, method, metadataURL, token string) (string, error) {
+	req, err := http.NewRequest(method, metadataURL, nil)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	if token != "" {
+		req.Header.Set("X-aliyun-ecs-metadata-token", token)
+	} else {
+		req.Header.Set("X-aliyun-ecs-metadata-token-ttl-seconds", metadataTokenTTL)
+	}
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", errNotFound
+	default:
+		return "", errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	return string(body), nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
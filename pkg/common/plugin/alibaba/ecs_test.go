@@ -0,0 +1,81 @@
+package alibaba
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentID(t *testing.T) {
+	require.Equal(t, "spiffe://example.org/spire/agent/alibaba_ecs/ACCOUNT/cn-hangzhou/i-INSTANCE", AgentID("example.org", &InstanceIdentityDocument{
+		AccountID:  "ACCOUNT",
+		RegionID:   "cn-hangzhou",
+		InstanceID: "i-INSTANCE",
+	}))
+}
+
+func TestFetchAttestationData(t *testing.T) {
+	ramRole := "ROLE\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == "PUT" && req.URL.Path == "/latest/api/token" {
+			if req.Header.Get("X-aliyun-ecs-metadata-token-ttl-seconds") == "" {
+				http.Error(w, "missing ttl", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, "TOKEN")
+			return
+		}
+		if req.Method != "GET" || req.Header.Get("X-aliyun-ecs-metadata-token") != "TOKEN" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		switch req.URL.Path {
+		case "/latest/dynamic/instance-identity/document":
+			fmt.Fprint(w, `{"instance-id":"i-INSTANCE"}`)
+		case "/latest/dynamic/instance-identity/pkcs7":
+			fmt.Fprint(w, "SIGNATURE\n")
+		case "/latest/meta-data/ram/security-credentials/":
+			if ramRole == "" {
+				http.NotFound(w, req)
+				return
+			}
+			fmt.Fprint(w, ramRole)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := http.DefaultClient
+
+	data, err := FetchAttestationData(ctx, client, server.URL)
+	require.NoError(t, err)
+	require.Equal(t, &AttestationData{
+		Document:  `{"instance-id":"i-INSTANCE"}`,
+		Signature: "SIGNATURE",
+		RAMRole:   "ROLE",
+	}, data)
+
+	// no RAM role attached
+	ramRole = ""
+	data, err = FetchAttestationData(ctx, client, server.URL)
+	require.NoError(t, err)
+	require.Equal(t, "", data.RAMRole)
+
+	_, err = FetchAttestationData(ctx, HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		req.Method = "GET"
+		return client.Do(req)
+	}), server.URL)
+	require.EqualError(t, err, "unable to obtain metadata token: unexpected status code 403: forbidden\n")
+
+	_, err = FetchAttestationData(ctx, HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		req.Header.Del("X-aliyun-ecs-metadata-token")
+		return client.Do(req)
+	}), server.URL)
+	require.EqualError(t, err, "unable to fetch instance identity document: unexpected status code 403: forbidden\n")
+}
@@ -0,0 +1,157 @@
+package alibaba
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+
+	"github.com/zeebo/errs"
+)
+
+// The instance identity signature is a detached PKCS#7 SignedData
+// structure over the identity document. Only what is needed to verify
+// RSA signatures from a known certificate is implemented here.
+
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidDigestSHA1    = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidDigestSHA256  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+// VerifyPKCS7 verifies the DER encoded detached PKCS#7 signature over
+// content was made by one of the given certificates
+func VerifyPKCS7(signature, content []byte, certs []*x509.Certificate) error {
+	info := new(contentInfo)
+	if rest, err := asn1.Unmarshal(signature, info); err != nil {
+		return errs.New("malformed signature: %v", err)
+	} else if len(rest) > 0 {
+		return errs.New("malformed signature: trailing data")
+	}
+	if !info.ContentType.Equal(oidSignedData) {
+		return errs.New("signature is not signed data")
+	}
+
+	sd := new(signedData)
+	if _, err := asn1.Unmarshal(info.Content.Bytes, sd); err != nil {
+		return errs.New("malformed signed data: %v", err)
+	}
+	if len(sd.SignerInfos) != 1 {
+		return errs.New("expected one signer; got %d", len(sd.SignerInfos))
+	}
+	signer := sd.SignerInfos[0]
+
+	hash, err := hashFromOID(signer.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	h := hash.New()
+	h.Write(content)
+	digest := h.Sum(nil)
+
+	// Without authenticated attributes the signature is over the content
+	// itself. Otherwise it is over the attributes, which must include the
+	// digest of the content.
+	if len(signer.AuthenticatedAttributes.FullBytes) > 0 {
+		// the attributes are signed with their universal SET tag instead
+		// of the implicit tag used in the signer info
+		signed := append([]byte(nil), signer.AuthenticatedAttributes.FullBytes...)
+		signed[0] = 0x31
+
+		var attributes []attribute
+		if _, err := asn1.UnmarshalWithParams(signed, &attributes, "set"); err != nil {
+			return errs.New("malformed authenticated attributes: %v", err)
+		}
+		messageDigest, err := getMessageDigest(attributes)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(messageDigest, digest) {
+			return errs.New("message digest mismatch")
+		}
+
+		h = hash.New()
+		h.Write(signed)
+		digest = h.Sum(nil)
+	}
+
+	for _, cert := range certs {
+		if cert.SerialNumber.Cmp(signer.IssuerAndSerialNumber.SerialNumber) != 0 ||
+			!bytes.Equal(cert.RawIssuer, signer.IssuerAndSerialNumber.Issuer.FullBytes) {
+			continue
+		}
+		publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return errs.New("signing certificate does not have an RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(publicKey, hash, digest, signer.EncryptedDigest); err != nil {
+			return errs.New("signature verification failed: %v", err)
+		}
+		return nil
+	}
+	return errs.New("signing certificate not found")
+}
+
+func hashFromOID(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidDigestSHA1):
+		return crypto.SHA1, nil
+	case oid.Equal(oidDigestSHA256):
+		return crypto.SHA256, nil
+	default:
+		return 0, errs.New("unsupported digest algorithm %s", oid)
+	}
+}
+
+func getMessageDigest(attributes []attribute) ([]byte, error) {
+	for _, attr := range attributes {
+		if !attr.Type.Equal(oidMessageDigest) {
+			continue
+		}
+		var messageDigest []byte
+		if _, err := asn1.Unmarshal(attr.Value.Bytes, &messageDigest); err != nil {
+			return nil, errs.New("malformed message digest: %v", err)
+		}
+		return messageDigest, nil
+	}
+	return nil, errs.New("authenticated attributes missing message digest")
+}
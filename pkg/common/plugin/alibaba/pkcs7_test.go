@@ -0,0 +1,42 @@
+package alibaba
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+
+	"github.com/spiffe/spire/test/fakes/fakealibaba"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPKCS7(t *testing.T) {
+	signer := fakealibaba.New(t)
+	other := fakealibaba.New(t)
+
+	content := []byte(`{"instance-id":"i-INSTANCE"}`)
+	signature, err := base64.StdEncoding.DecodeString(signer.Sign(content))
+	require.NoError(t, err)
+
+	// success
+	require.NoError(t, VerifyPKCS7(signature, content, []*x509.Certificate{other.Certificate, signer.Certificate}))
+
+	// content was tampered with
+	err = VerifyPKCS7(signature, []byte(`{"instance-id":"i-OTHER"}`), []*x509.Certificate{signer.Certificate})
+	require.EqualError(t, err, "message digest mismatch")
+
+	// signed by an unknown certificate
+	err = VerifyPKCS7(signature, content, []*x509.Certificate{other.Certificate})
+	require.EqualError(t, err, "signing certificate not found")
+
+	// malformed
+	err = VerifyPKCS7([]byte("blah"), content, []*x509.Certificate{signer.Certificate})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "malformed signature")
+
+	// signature bytes corrupted
+	corrupted := append([]byte(nil), signature...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	err = VerifyPKCS7(corrupted, content, []*x509.Certificate{signer.Certificate})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "signature verification failed")
+}
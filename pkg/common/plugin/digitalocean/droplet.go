@@ -0,0 +1,83 @@
+package digitalocean
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	PluginName = "digitalocean_droplet"
+
+	// DefaultMetadataURL is the location of the droplet ID served by the
+	// droplet metadata service
+	DefaultMetadataURL = "http://169.254.169.254/metadata/v1/id"
+
+	// maxIDLength bounds the size of the metadata response
+	maxIDLength = 32
+)
+
+type AttestationData struct {
+	DropletID int64 `json:"droplet_id"`
+}
+
+func AgentID(trustDomain string, dropletID int64) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, strconv.FormatInt(dropletID, 10)),
+	}
+	return u.String()
+}
+
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type HTTPClientFunc func(*http.Request) (*http.Response, error)
+
+func (fn HTTPClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// FetchDropletID fetches the ID of the droplet from the metadata service
+func FetchDropletID(ctx context.Context, cl HTTPClient, metadataURL string) (int64, error) {
+	req, err := http.NewRequest("GET", metadataURL, nil)
+	if err != nil {
+		return 0, errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return 0, errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxIDLength))
+	if err != nil {
+		return 0, errs.Wrap(err)
+	}
+
+	dropletID, err := strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+	if err != nil {
+		return 0, errs.New("malformed droplet ID: %v", err)
+	}
+	return dropletID, nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
@@ -0,0 +1,53 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentID(t *testing.T) {
+	require.Equal(t, "spiffe://example.org/spire/agent/digitalocean_droplet/12345", AgentID("example.org", 12345))
+}
+
+func TestFetchDropletID(t *testing.T) {
+	ctx := context.Background()
+
+	// unexpected status
+	dropletID, err := FetchDropletID(ctx, fakeMetadataHTTPClient(http.StatusNotFound, "ERROR"), "http://169.254.169.254/metadata/v1/id")
+	require.EqualError(t, err, "unexpected status code 404: ERROR")
+	require.Zero(t, dropletID)
+
+	// malformed response
+	dropletID, err = FetchDropletID(ctx, fakeMetadataHTTPClient(http.StatusOK, "blah"), "http://169.254.169.254/metadata/v1/id")
+	require.Contains(t, err.Error(), "malformed droplet ID")
+	require.Zero(t, dropletID)
+
+	// success
+	dropletID, err = FetchDropletID(ctx, fakeMetadataHTTPClient(http.StatusOK, "12345\n"), "http://169.254.169.254/metadata/v1/id")
+	require.NoError(t, err)
+	require.Equal(t, int64(12345), dropletID)
+}
+
+func fakeMetadataHTTPClient(statusCode int, body string) HTTPClient {
+	return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		// assert the expected request values
+		if req.Method != "GET" {
+			return nil, fmt.Errorf("unexpected method %q", req.Method)
+		}
+		if req.URL.String() != "http://169.254.169.254/metadata/v1/id" {
+			return nil, fmt.Errorf("unexpected URL %q", req.URL)
+		}
+
+		// return the response
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+}
@@ -0,0 +1,79 @@
+package equinix
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	PluginName = "equinix_metal"
+
+	// DefaultMetadataURL is the location of the device metadata served by the
+	// Equinix Metal metadata service
+	DefaultMetadataURL = "https://metadata.platformequinix.com/metadata"
+)
+
+type AttestationData struct {
+	DeviceID string `json:"device_id"`
+}
+
+func AgentID(trustDomain string, deviceID string) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, deviceID),
+	}
+	return u.String()
+}
+
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type HTTPClientFunc func(*http.Request) (*http.Response, error)
+
+func (fn HTTPClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// FetchDeviceID fetches the ID of the device from the metadata service
+func FetchDeviceID(ctx context.Context, cl HTTPClient, metadataURL string) (string, error) {
+	req, err := http.NewRequest("GET", metadataURL, nil)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	metadata := struct {
+		ID string `json:"id"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return "", errs.New("unable to decode metadata: %v", err)
+	}
+	if metadata.ID == "" {
+		return "", errs.New("metadata missing device ID")
+	}
+	return metadata.ID, nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
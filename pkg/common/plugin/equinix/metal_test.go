@@ -0,0 +1,58 @@
+package equinix
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentID(t *testing.T) {
+	require.Equal(t, "spiffe://example.org/spire/agent/equinix_metal/DEVICEID", AgentID("example.org", "DEVICEID"))
+}
+
+func TestFetchDeviceID(t *testing.T) {
+	ctx := context.Background()
+
+	// unexpected status
+	deviceID, err := FetchDeviceID(ctx, fakeMetadataHTTPClient(http.StatusNotFound, "ERROR"), DefaultMetadataURL)
+	require.EqualError(t, err, "unexpected status code 404: ERROR")
+	require.Empty(t, deviceID)
+
+	// malformed response
+	deviceID, err = FetchDeviceID(ctx, fakeMetadataHTTPClient(http.StatusOK, "{"), DefaultMetadataURL)
+	require.EqualError(t, err, "unable to decode metadata: unexpected EOF")
+	require.Empty(t, deviceID)
+
+	// no device ID
+	deviceID, err = FetchDeviceID(ctx, fakeMetadataHTTPClient(http.StatusOK, "{}"), DefaultMetadataURL)
+	require.EqualError(t, err, "metadata missing device ID")
+	require.Empty(t, deviceID)
+
+	// success
+	deviceID, err = FetchDeviceID(ctx, fakeMetadataHTTPClient(http.StatusOK, `{"id": "DEVICEID", "hostname": "node-1"}`), DefaultMetadataURL)
+	require.NoError(t, err)
+	require.Equal(t, "DEVICEID", deviceID)
+}
+
+func fakeMetadataHTTPClient(statusCode int, body string) HTTPClient {
+	return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		// assert the expected request values
+		if req.Method != "GET" {
+			return nil, fmt.Errorf("unexpected method %q", req.Method)
+		}
+		if req.URL.String() != DefaultMetadataURL {
+			return nil, fmt.Errorf("unexpected URL %q", req.URL)
+		}
+
+		// return the response
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+}
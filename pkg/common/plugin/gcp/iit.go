@@ -34,6 +34,19 @@ type ComputeEngine struct {
 	InstanceID                string `json:"instance_id"`
 	InstanceName              string `json:"instance_name"`
 	InstanceCreationTimestamp int64  `json:"instance_creation_timestamp"`
+
+	// InstanceConfidentiality is 1 when the instance is a Confidential VM
+	InstanceConfidentiality int `json:"instance_confidentiality"`
+
+	// ShieldedInstance holds the Shielded VM integrity monitoring state. It
+	// is only present for Shielded VM instances.
+	ShieldedInstance *ShieldedInstance `json:"shielded_instance,omitempty"`
+}
+
+type ShieldedInstance struct {
+	SecureBoot         bool `json:"secure_boot"`
+	VTPM               bool `json:"vtpm"`
+	IntegrityValidated bool `json:"integrity_validated"`
 }
 
 type agentPathTemplateData struct {
@@ -0,0 +1,181 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/zeebo/errs"
+)
+
+const (
+	// DefaultMetadataTokenURL is the metadata server endpoint serving access
+	// tokens for the default service account of the instance
+	DefaultMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+	defaultGoogleTokenURL = "https://oauth2.googleapis.com/token"
+
+	// tokens are refreshed this long before they actually expire
+	tokenExpiryDelta = time.Minute
+)
+
+// TokenSource provides OAuth2 access tokens for the Google Cloud APIs
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+// cachingTokenSource caches the access token obtained by the fetch function
+// until shortly before it expires.
+type cachingTokenSource struct {
+	fetch func(ctx context.Context) (*tokenResponse, error)
+	now   func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func (s *cachingTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.now().Before(s.expiry) {
+		return s.token, nil
+	}
+
+	resp, err := s.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", errs.New("token response missing access token")
+	}
+
+	s.token = resp.AccessToken
+	s.expiry = s.now().Add(time.Duration(resp.ExpiresIn)*time.Second - tokenExpiryDelta)
+	return s.token, nil
+}
+
+// NewMetadataTokenSource returns a token source that obtains access tokens
+// for the default service account from the GCE metadata server.
+func NewMetadataTokenSource(tokenURL string) TokenSource {
+	return &cachingTokenSource{
+		now: time.Now,
+		fetch: func(ctx context.Context) (*tokenResponse, error) {
+			req, err := http.NewRequest("GET", tokenURL, nil)
+			if err != nil {
+				return nil, errs.Wrap(err)
+			}
+			req = req.WithContext(ctx)
+			req.Header.Set("Metadata-Flavor", "Google")
+			return doTokenRequest(req)
+		},
+	}
+}
+
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// NewServiceAccountTokenSource returns a token source that exchanges a JWT
+// assertion signed by the service account key in the given JSON key file
+// for an access token with the given scope.
+func NewServiceAccountTokenSource(path, scope string) (TokenSource, error) {
+	keyBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errs.New("unable to read service account file: %v", err)
+	}
+
+	key := new(serviceAccountKey)
+	if err := json.Unmarshal(keyBytes, key); err != nil {
+		return nil, errs.New("unable to decode service account file: %v", err)
+	}
+	if key.Type != "service_account" {
+		return nil, errs.New("unexpected credentials type %q in service account file", key.Type)
+	}
+	if key.ClientEmail == "" {
+		return nil, errs.New("service account file missing client_email")
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(key.PrivateKey))
+	if err != nil {
+		return nil, errs.New("unable to parse service account private key: %v", err)
+	}
+
+	tokenURL := key.TokenURI
+	if tokenURL == "" {
+		tokenURL = defaultGoogleTokenURL
+	}
+
+	s := &cachingTokenSource{
+		now: time.Now,
+	}
+	s.fetch = func(ctx context.Context) (*tokenResponse, error) {
+		now := s.now()
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":   key.ClientEmail,
+			"scope": scope,
+			"aud":   tokenURL,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		})
+		if key.PrivateKeyID != "" {
+			token.Header["kid"] = key.PrivateKeyID
+		}
+		assertion, err := token.SignedString(privateKey)
+		if err != nil {
+			return nil, errs.New("unable to sign token assertion: %v", err)
+		}
+
+		form := url.Values{}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+		req, err := http.NewRequest("POST", tokenURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return doTokenRequest(req)
+	}
+	return s, nil
+}
+
+func doTokenRequest(req *http.Request) (*tokenResponse, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	token := new(tokenResponse)
+	if err := json.Unmarshal(body, token); err != nil {
+		return nil, errs.New("unable to decode token response: %v", err)
+	}
+	return token, nil
+}
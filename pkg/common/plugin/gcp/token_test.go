@@ -0,0 +1,38 @@
+package gcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataTokenSourceCachesToken(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		require.Equal(t, "Google", req.Header.Get("Metadata-Flavor"))
+		w.Write([]byte(`{"access_token":"TOKEN","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	tokens := NewMetadataTokenSource(server.URL).(*cachingTokenSource)
+	tokens.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		token, err := tokens.Token(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "TOKEN", token)
+	}
+	require.Equal(t, 1, requests)
+
+	// the token is refreshed once it is about to expire
+	now = now.Add(time.Hour - tokenExpiryDelta)
+	_, err := tokens.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+}
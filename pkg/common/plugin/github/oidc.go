@@ -0,0 +1,108 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// DefaultOIDCIssuer is the issuer of OIDC tokens handed out to GitHub
+	// Actions workflow runs
+	DefaultOIDCIssuer = "https://token.actions.githubusercontent.com"
+
+	// DefaultAudience is the default audience requested for the OIDC token.
+	// The server rejects tokens intended for a different audience.
+	DefaultAudience = "spire-server"
+)
+
+type OIDCAttestationData struct {
+	Token string `json:"token"`
+}
+
+// OIDCTokenClaims are the claims of a GitHub Actions OIDC token that are
+// relevant to node attestation
+type OIDCTokenClaims struct {
+	jwt.Claims
+	Repository      string `json:"repository,omitempty"`
+	RepositoryOwner string `json:"repository_owner,omitempty"`
+	Ref             string `json:"ref,omitempty"`
+	Environment     string `json:"environment,omitempty"`
+	Workflow        string `json:"workflow,omitempty"`
+	RunID           string `json:"run_id,omitempty"`
+}
+
+// AgentID returns the agent SPIFFE ID for the workflow run. Every job in a
+// run obtains its own token, so the token ID is used to keep the agent IDs
+// of concurrently running jobs distinct.
+func (c *OIDCTokenClaims) AgentID(trustDomain string) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", "github_oidc", c.Repository, c.RunID, c.ID),
+	}
+	return u.String()
+}
+
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type HTTPClientFunc func(*http.Request) (*http.Response, error)
+
+func (fn HTTPClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// FetchOIDCToken requests an OIDC token for the given audience from the
+// GitHub Actions token endpoint. The request URL and bearer token are
+// provided to the job through the ACTIONS_ID_TOKEN_REQUEST_URL and
+// ACTIONS_ID_TOKEN_REQUEST_TOKEN environment variables.
+func FetchOIDCToken(ctx context.Context, cl HTTPClient, requestURL, requestToken, audience string) (string, error) {
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+	req.Header.Set("Accept", "application/json")
+
+	q := req.URL.Query()
+	q.Set("audience", audience)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	r := struct {
+		Value string `json:"value"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", errs.New("unable to decode response: %v", err)
+	}
+
+	if r.Value == "" {
+		return "", errs.New("response missing token")
+	}
+
+	return r.Value, nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
@@ -0,0 +1,81 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestOIDCTokenClaims(t *testing.T) {
+	claims := OIDCTokenClaims{
+		Claims: jwt.Claims{
+			ID: "TOKENID",
+		},
+		Repository: "octo-org/octo-repo",
+		RunID:      "42",
+	}
+	require.Equal(t, "spiffe://example.org/spire/agent/github_oidc/octo-org/octo-repo/42/TOKENID", claims.AgentID("example.org"))
+}
+
+func TestFetchOIDCToken(t *testing.T) {
+	ctx := context.Background()
+
+	// unexpected status
+	token, err := FetchOIDCToken(ctx, fakeTokenHTTPClient(http.StatusForbidden, "ERROR"), "https://example.org/token?api-version=2.0", "REQUESTTOKEN", "AUDIENCE")
+	require.EqualError(t, err, "unexpected status code 403: ERROR")
+	require.Empty(t, token)
+
+	// empty response
+	token, err = FetchOIDCToken(ctx, fakeTokenHTTPClient(http.StatusOK, ""), "https://example.org/token?api-version=2.0", "REQUESTTOKEN", "AUDIENCE")
+	require.EqualError(t, err, "unable to decode response: EOF")
+	require.Empty(t, token)
+
+	// malformed response
+	token, err = FetchOIDCToken(ctx, fakeTokenHTTPClient(http.StatusOK, "{"), "https://example.org/token?api-version=2.0", "REQUESTTOKEN", "AUDIENCE")
+	require.EqualError(t, err, "unable to decode response: unexpected EOF")
+	require.Empty(t, token)
+
+	// no token
+	token, err = FetchOIDCToken(ctx, fakeTokenHTTPClient(http.StatusOK, "{}"), "https://example.org/token?api-version=2.0", "REQUESTTOKEN", "AUDIENCE")
+	require.EqualError(t, err, "response missing token")
+	require.Empty(t, token)
+
+	// success
+	token, err = FetchOIDCToken(ctx, fakeTokenHTTPClient(http.StatusOK, `{"value": "ASDF"}`), "https://example.org/token?api-version=2.0", "REQUESTTOKEN", "AUDIENCE")
+	require.NoError(t, err)
+	require.Equal(t, "ASDF", token)
+}
+
+func fakeTokenHTTPClient(statusCode int, body string) HTTPClient {
+	return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		// assert the expected request values
+		if req.Method != "GET" {
+			return nil, fmt.Errorf("unexpected method %q", req.Method)
+		}
+		if req.URL.Host != "example.org" || req.URL.Path != "/token" {
+			return nil, fmt.Errorf("unexpected URL %q", req.URL)
+		}
+		// existing query parameters on the request URL must be preserved
+		if v := req.URL.Query().Get("api-version"); v != "2.0" {
+			return nil, fmt.Errorf("unexpected api version %q", v)
+		}
+		if v := req.URL.Query().Get("audience"); v != "AUDIENCE" {
+			return nil, fmt.Errorf("unexpected audience %q", v)
+		}
+		if v := req.Header.Get("Authorization"); v != "Bearer REQUESTTOKEN" {
+			return nil, fmt.Errorf("unexpected authorization header %q", v)
+		}
+
+		// return the response
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+}
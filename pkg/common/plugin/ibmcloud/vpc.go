@@ -0,0 +1,193 @@
+package ibmcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	PluginName = "ibmcloud_vpc"
+
+	// DefaultMetadataURL is the base URL of the VPC instance metadata
+	// service
+	DefaultMetadataURL = "http://169.254.169.254"
+
+	// SubTypeComputeResource is the subject type of IAM tokens issued to
+	// compute resources through a trusted profile
+	SubTypeComputeResource = "ComputeResource"
+
+	metadataAPIVersion = "2022-03-01"
+
+	// identityTokenLifetime is the lifetime, in seconds, requested for the
+	// instance identity token. It is only used to obtain the IAM token.
+	identityTokenLifetime = 300
+)
+
+var (
+	// reZone matches VPC zone names (e.g. us-south-1)
+	reZone = regexp.MustCompile(`^([a-z]+-[a-z]+)-[0-9]+$`)
+)
+
+type AttestationData struct {
+	// Token is the IAM access token obtained by the instance for its
+	// trusted profile
+	Token string `json:"token"`
+}
+
+// IAMClaims are the claims of an IAM access token issued to a compute
+// resource. Authn identifies the compute resource that authenticated.
+type IAMClaims struct {
+	jwt.Claims
+	Account struct {
+		BSS string `json:"bss"`
+	} `json:"account"`
+	Authn struct {
+		Sub     string `json:"sub"`
+		SubType string `json:"sub_type"`
+	} `json:"authn"`
+}
+
+// InstanceCRN holds the parts of the cloud resource name of a VPC instance
+// relevant to attestation
+type InstanceCRN struct {
+	CRN        string
+	AccountID  string
+	Zone       string
+	InstanceID string
+}
+
+// ParseInstanceCRN parses the CRN of a VPC instance, which has the form
+// crn:v1:bluemix:public:is:<zone>:a/<account>::instance:<instance id>
+func ParseInstanceCRN(crn string) (*InstanceCRN, error) {
+	parts := strings.Split(crn, ":")
+	if len(parts) != 10 || parts[0] != "crn" || parts[1] != "v1" {
+		return nil, errs.New("malformed CRN %q", crn)
+	}
+	if parts[4] != "is" || parts[8] != "instance" {
+		return nil, errs.New("CRN %q is not a VPC instance", crn)
+	}
+	if !strings.HasPrefix(parts[6], "a/") || parts[6] == "a/" {
+		return nil, errs.New("CRN %q missing account", crn)
+	}
+	if !reZone.MatchString(parts[5]) {
+		return nil, errs.New("CRN %q has invalid zone %q", crn, parts[5])
+	}
+	if parts[9] == "" {
+		return nil, errs.New("CRN %q missing instance ID", crn)
+	}
+	return &InstanceCRN{
+		CRN:        crn,
+		AccountID:  strings.TrimPrefix(parts[6], "a/"),
+		Zone:       parts[5],
+		InstanceID: parts[9],
+	}, nil
+}
+
+// Region returns the region containing the zone of the instance
+func (c *InstanceCRN) Region() string {
+	return reZone.FindStringSubmatch(c.Zone)[1]
+}
+
+func AgentID(trustDomain string, crn *InstanceCRN) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, crn.AccountID, crn.InstanceID),
+	}
+	return u.String()
+}
+
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type HTTPClientFunc func(*http.Request) (*http.Response, error)
+
+func (fn HTTPClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// FetchIAMToken obtains an instance identity token from the metadata
+// service and exchanges it for an IAM access token for the trusted profile
+// linked to the instance. The trusted profile ID is only required when
+// more than one profile is linked.
+func FetchIAMToken(ctx context.Context, cl HTTPClient, metadataURL, trustedProfileID string) (string, error) {
+	identityToken, err := callMetadata(ctx, cl, "PUT", metadataURL+"/instance_identity/v1/token", "", map[string]interface{}{
+		"expires_in": identityTokenLifetime,
+	})
+	if err != nil {
+		return "", errs.New("unable to obtain instance identity token: %v", err)
+	}
+
+	var body interface{}
+	if trustedProfileID != "" {
+		body = map[string]interface{}{
+			"trusted_profile": map[string]string{"id": trustedProfileID},
+		}
+	}
+	iamToken, err := callMetadata(ctx, cl, "POST", metadataURL+"/instance_identity/v1/iam_token", identityToken, body)
+	if err != nil {
+		return "", errs.New("unable to obtain IAM token: %v", err)
+	}
+	return iamToken, nil
+}
+
+func callMetadata(ctx context.Context, cl HTTPClient, method, metadataURL, bearer string, body interface{}) (string, error) {
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		bodyBytes, err = json.Marshal(body)
+		if err != nil {
+			return "", errs.Wrap(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, metadataURL+"?version="+metadataAPIVersion, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	} else {
+		req.Header.Set("Metadata-Flavor", "ibm")
+	}
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	r := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", errs.New("unable to decode response: %v", err)
+	}
+	if r.AccessToken == "" {
+		return "", errs.New("response missing access token")
+	}
+	return r.AccessToken, nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
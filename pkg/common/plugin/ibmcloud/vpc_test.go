@@ -0,0 +1,99 @@
+package ibmcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInstanceCRN(t *testing.T) {
+	crn, err := ParseInstanceCRN("crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::instance:0717_INSTANCE")
+	require.NoError(t, err)
+	require.Equal(t, &InstanceCRN{
+		CRN:        "crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::instance:0717_INSTANCE",
+		AccountID:  "ACCOUNT",
+		Zone:       "us-south-1",
+		InstanceID: "0717_INSTANCE",
+	}, crn)
+	require.Equal(t, "us-south", crn.Region())
+	require.Equal(t, "spiffe://example.org/spire/agent/ibmcloud_vpc/ACCOUNT/0717_INSTANCE", AgentID("example.org", crn))
+
+	for crn, expected := range map[string]string{
+		"blah": `malformed CRN "blah"`,
+		"crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::instance":          `malformed CRN "crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::instance"`,
+		"crn:v1:bluemix:public:cos:us-south-1:a/ACCOUNT::instance:ID":      `CRN "crn:v1:bluemix:public:cos:us-south-1:a/ACCOUNT::instance:ID" is not a VPC instance`,
+		"crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::vpc:ID":            `CRN "crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::vpc:ID" is not a VPC instance`,
+		"crn:v1:bluemix:public:is:us-south-1:o/ORG::instance:ID":           `CRN "crn:v1:bluemix:public:is:us-south-1:o/ORG::instance:ID" missing account`,
+		"crn:v1:bluemix:public:is:evil.example.com:a/ACCOUNT::instance:ID": `CRN "crn:v1:bluemix:public:is:evil.example.com:a/ACCOUNT::instance:ID" has invalid zone "evil.example.com"`,
+		"crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::instance:":         `CRN "crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::instance:" missing instance ID`,
+	} {
+		_, err := ParseInstanceCRN(crn)
+		require.EqualError(t, err, expected)
+	}
+}
+
+func TestFetchIAMToken(t *testing.T) {
+	var iamTokenBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("version") != "2022-03-01" {
+			http.Error(w, "bad version", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case req.Method == "PUT" && req.URL.Path == "/instance_identity/v1/token":
+			if req.Header.Get("Metadata-Flavor") != "ibm" {
+				http.Error(w, "missing metadata flavor", http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{"access_token": "IDENTITY"}`)
+		case req.Method == "POST" && req.URL.Path == "/instance_identity/v1/iam_token":
+			if req.Header.Get("Authorization") != "Bearer IDENTITY" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			iamTokenBody = nil
+			if err := json.NewDecoder(req.Body).Decode(&iamTokenBody); err != nil && err != io.EOF {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"access_token": "IAM"}`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := http.DefaultClient
+
+	token, err := FetchIAMToken(ctx, client, server.URL, "")
+	require.NoError(t, err)
+	require.Equal(t, "IAM", token)
+	require.Nil(t, iamTokenBody)
+
+	token, err = FetchIAMToken(ctx, client, server.URL, "Profile-1234")
+	require.NoError(t, err)
+	require.Equal(t, "IAM", token)
+	require.Equal(t, map[string]interface{}{
+		"trusted_profile": map[string]interface{}{"id": "Profile-1234"},
+	}, iamTokenBody)
+
+	_, err = FetchIAMToken(ctx, client, server.URL+"/blah", "")
+	require.EqualError(t, err, "unable to obtain instance identity token: unexpected status code 404: 404 page not found\n")
+
+	_, err = FetchIAMToken(ctx, HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == "POST" {
+			req.Header.Set("Authorization", "Bearer BAD")
+		}
+		return client.Do(req)
+	}), server.URL, "")
+	require.EqualError(t, err, "unable to obtain IAM token: unexpected status code 401: unauthorized\n")
+}
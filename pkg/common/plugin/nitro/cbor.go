@@ -0,0 +1,310 @@
+package nitro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/zeebo/errs"
+)
+
+// Attestation documents and NSM messages are CBOR encoded. Only the subset
+// of CBOR used by them is supported: definite length items, integers, byte
+// and text strings, arrays, maps, tags and the simple values false, true and
+// null. Decoded values are represented as uint64 (unsigned integers), int64
+// (negative integers), []byte, string, []interface{},
+// map[interface{}]interface{}, bool and nil. Tags are dropped and
+// the tagged value is returned.
+
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborTag      = 6
+	cborSimple   = 7
+
+	// maxCBORDepth bounds the nesting of arrays, maps and tags
+	maxCBORDepth = 16
+)
+
+// EncodeCBOR encodes a value as CBOR. Map keys are sorted by their encoding
+// so the output is deterministic.
+func EncodeCBOR(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := encodeCBOR(buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeCBOR(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(cborSimple<<5 | 22)
+	case bool:
+		if v {
+			buf.WriteByte(cborSimple<<5 | 21)
+		} else {
+			buf.WriteByte(cborSimple<<5 | 20)
+		}
+	case int:
+		encodeCBORInt(buf, int64(v))
+	case int64:
+		encodeCBORInt(buf, v)
+	case uint64:
+		writeCBORHead(buf, cborUnsigned, v)
+	case []byte:
+		writeCBORHead(buf, cborBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeCBORHead(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		writeCBORHead(buf, cborArray, uint64(len(v)))
+		for _, elem := range v {
+			if err := encodeCBOR(buf, elem); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for key, value := range v {
+			m[key] = value
+		}
+		return encodeCBOR(buf, m)
+	case map[interface{}]interface{}:
+		type entry struct {
+			key   []byte
+			value interface{}
+		}
+		entries := make([]entry, 0, len(v))
+		for key, value := range v {
+			keyBytes, err := EncodeCBOR(key)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry{key: keyBytes, value: value})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+		writeCBORHead(buf, cborMap, uint64(len(entries)))
+		for _, e := range entries {
+			buf.Write(e.key)
+			if err := encodeCBOR(buf, e.value); err != nil {
+				return err
+			}
+		}
+	default:
+		return errs.New("unsupported CBOR value type %T", v)
+	}
+	return nil
+}
+
+func encodeCBORInt(buf *bytes.Buffer, v int64) {
+	if v < 0 {
+		writeCBORHead(buf, cborNegative, uint64(-1-v))
+		return
+	}
+	writeCBORHead(buf, cborUnsigned, uint64(v))
+}
+
+func writeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// DecodeCBOR decodes a single CBOR item. Trailing data is an error.
+func DecodeCBOR(data []byte) (interface{}, error) {
+	v, rest, err := decodeCBOR(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errs.New("%d bytes of trailing CBOR data", len(rest))
+	}
+	return v, nil
+}
+
+func decodeCBOR(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, errs.New("CBOR nesting exceeds the maximum depth of %d", maxCBORDepth)
+	}
+	if len(data) == 0 {
+		return nil, nil, errs.New("truncated CBOR item")
+	}
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+
+	// simple values do not carry an argument
+	if major == cborSimple {
+		return decodeCBORSimple(info, data)
+	}
+
+	n, data, err := readCBORArgument(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		return n, data, nil
+	case cborNegative:
+		if n > math.MaxInt64 {
+			return nil, nil, errs.New("CBOR negative integer overflows int64")
+		}
+		return -1 - int64(n), data, nil
+	case cborBytes, cborText:
+		if n > uint64(len(data)) {
+			return nil, nil, errs.New("truncated CBOR string")
+		}
+		value := data[:n]
+		if major == cborText {
+			return string(value), data[n:], nil
+		}
+		return append([]byte(nil), value...), data[n:], nil
+	case cborArray:
+		// every element takes at least one byte
+		if n > uint64(len(data)) {
+			return nil, nil, errs.New("truncated CBOR array")
+		}
+		array := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var elem interface{}
+			elem, data, err = decodeCBOR(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			array = append(array, elem)
+		}
+		return array, data, nil
+	case cborMap:
+		// every entry takes at least two bytes
+		if n > uint64(len(data))/2 {
+			return nil, nil, errs.New("truncated CBOR map")
+		}
+		m := make(map[interface{}]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			var key, value interface{}
+			key, data, err = decodeCBOR(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case uint64, int64, string:
+			default:
+				return nil, nil, errs.New("unsupported CBOR map key type %T", key)
+			}
+			value, data, err = decodeCBOR(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, data, nil
+	default: // cborTag
+		return decodeCBOR(data, depth+1)
+	}
+}
+
+func readCBORArgument(info byte, data []byte) (uint64, []byte, error) {
+	var size int
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, nil, errs.New("unsupported CBOR additional information %d", info)
+	}
+	if len(data) < size {
+		return 0, nil, errs.New("truncated CBOR argument")
+	}
+	var n uint64
+	for _, b := range data[:size] {
+		n = n<<8 | uint64(b)
+	}
+	return n, data[size:], nil
+}
+
+func decodeCBORSimple(info byte, data []byte) (interface{}, []byte, error) {
+	switch info {
+	case 20:
+		return false, data, nil
+	case 21:
+		return true, data, nil
+	case 22, 23:
+		return nil, data, nil
+	default:
+		return nil, nil, errs.New("unsupported CBOR simple value %d", info)
+	}
+}
+
+// cborMapEntries is a helper for reading fields out of a decoded CBOR map
+type cborMapEntries map[interface{}]interface{}
+
+func (m cborMapEntries) bytes(key interface{}) ([]byte, error) {
+	switch v := m[key].(type) {
+	case []byte:
+		return v, nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, errs.New("%s: expected byte string; got %T", describeCBORKey(key), v)
+	}
+}
+
+func (m cborMapEntries) text(key interface{}) (string, error) {
+	switch v := m[key].(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	default:
+		return "", errs.New("%s: expected text string; got %T", describeCBORKey(key), v)
+	}
+}
+
+func (m cborMapEntries) uint(key interface{}) (uint64, error) {
+	switch v := m[key].(type) {
+	case uint64:
+		return v, nil
+	case nil:
+		return 0, nil
+	default:
+		return 0, errs.New("%s: expected unsigned integer; got %T", describeCBORKey(key), v)
+	}
+}
+
+func describeCBORKey(key interface{}) string {
+	if s, ok := key.(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
+}
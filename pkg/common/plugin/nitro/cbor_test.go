@@ -0,0 +1,78 @@
+package nitro
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCBOREncoding(t *testing.T) {
+	// vectors from RFC 7049 appendix A
+	vectors := []struct {
+		value   interface{}
+		encoded string
+	}{
+		{value: uint64(0), encoded: "00"},
+		{value: uint64(23), encoded: "17"},
+		{value: uint64(24), encoded: "1818"},
+		{value: uint64(1000), encoded: "1903e8"},
+		{value: uint64(1000000), encoded: "1a000f4240"},
+		{value: uint64(1000000000000), encoded: "1b000000e8d4a51000"},
+		{value: int64(-1), encoded: "20"},
+		{value: int64(-1000), encoded: "3903e7"},
+		{value: false, encoded: "f4"},
+		{value: true, encoded: "f5"},
+		{value: nil, encoded: "f6"},
+		{value: []byte{1, 2, 3, 4}, encoded: "4401020304"},
+		{value: "IETF", encoded: "6449455446"},
+		{value: []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}}, encoded: "8201820203"},
+		{value: map[interface{}]interface{}{"a": uint64(1), "b": []interface{}{uint64(2), uint64(3)}}, encoded: "a26161016162820203"},
+	}
+
+	for _, vector := range vectors {
+		encoded, err := EncodeCBOR(vector.value)
+		require.NoError(t, err)
+		require.Equal(t, vector.encoded, hex.EncodeToString(encoded))
+
+		decoded, err := DecodeCBOR(encoded)
+		require.NoError(t, err)
+		require.Equal(t, vector.value, decoded)
+	}
+
+	// int and int64 values pick the unsigned or negative major type
+	encoded, err := EncodeCBOR(map[string]interface{}{"x": -35, "y": int64(1)})
+	require.NoError(t, err)
+	require.Equal(t, "a261783822617901", hex.EncodeToString(encoded))
+
+	// tags are dropped
+	decoded, err := DecodeCBOR([]byte{0xd2, 0x01})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), decoded)
+}
+
+func TestCBORDecodingErrors(t *testing.T) {
+	vectors := []struct {
+		encoded string
+		err     string
+	}{
+		{encoded: "", err: "truncated CBOR item"},
+		{encoded: "0000", err: "1 bytes of trailing CBOR data"},
+		{encoded: "19", err: "truncated CBOR argument"},
+		{encoded: "1f", err: "unsupported CBOR additional information 31"},
+		{encoded: "3bffffffffffffffff", err: "CBOR negative integer overflows int64"},
+		{encoded: "4401", err: "truncated CBOR string"},
+		{encoded: "9a00010000", err: "truncated CBOR array"},
+		{encoded: "a1", err: "truncated CBOR map"},
+		{encoded: "a14000", err: "unsupported CBOR map key type []uint8"},
+		{encoded: "f818", err: "unsupported CBOR simple value 24"},
+		{encoded: "818181818181818181818181818181818181", err: "CBOR nesting exceeds the maximum depth of 16"},
+	}
+
+	for _, vector := range vectors {
+		encoded, err := hex.DecodeString(vector.encoded)
+		require.NoError(t, err)
+		_, err = DecodeCBOR(encoded)
+		require.EqualError(t, err, vector.err, "decoding %q", vector.encoded)
+	}
+}
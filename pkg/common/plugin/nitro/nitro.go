@@ -0,0 +1,279 @@
+package nitro
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	PluginName = "aws_nitro"
+
+	// coseAlgorithmES384 is the COSE algorithm identifier for ECDSA with
+	// SHA-384, which the Nitro hypervisor uses to sign attestation documents
+	coseAlgorithmES384 = -35
+
+	// coseHeaderAlgorithm is the COSE header label of the algorithm
+	coseHeaderAlgorithm = 1
+
+	nonceSize = 32
+
+	// maxPCRs is the number of PCRs the Nitro Secure Module provides
+	maxPCRs = 32
+)
+
+// AttestationData is sent by the agent to begin attestation
+type AttestationData struct {
+	// ModuleID is the ID of the enclave the agent is running in
+	ModuleID string `json:"module_id"`
+}
+
+// Challenge is sent by the server. The nonce must be embedded in the
+// attestation document to prove it is fresh.
+type Challenge struct {
+	Nonce []byte `json:"nonce"`
+}
+
+// Response is sent by the agent in response to the challenge
+type Response struct {
+	// Document is the COSE_Sign1 encoded attestation document
+	Document []byte `json:"document"`
+}
+
+// AttestationDocument is the payload of an attestation document produced by
+// the Nitro Secure Module
+type AttestationDocument struct {
+	ModuleID    string
+	Digest      string
+	Timestamp   time.Time
+	PCRs        map[int][]byte
+	Certificate *x509.Certificate
+	CABundle    []*x509.Certificate
+	PublicKey   []byte
+	UserData    []byte
+	Nonce       []byte
+}
+
+func AgentID(trustDomain, moduleID string) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, moduleID),
+	}
+	return u.String()
+}
+
+func GenerateChallenge() (*Challenge, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return &Challenge{Nonce: nonce}, nil
+}
+
+// VerifyAttestationDocument parses a COSE_Sign1 encoded attestation document,
+// verifies the signing certificate chains back to one of the roots and that
+// the document is signed by it.
+func VerifyAttestationDocument(data []byte, roots *x509.CertPool, now time.Time) (*AttestationDocument, error) {
+	protected, payload, signature, err := parseCOSESign1(data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := parseAttestationDocument(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// The CA bundle starts with the root certificate. The root is not
+	// trusted from the document; the configured roots are used instead.
+	intermediates := x509.NewCertPool()
+	for _, cert := range doc.CABundle {
+		intermediates.AddCert(cert)
+	}
+	if _, err := doc.Certificate.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         roots,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, errs.New("certificate verification failed: %v", err)
+	}
+
+	publicKey, ok := doc.Certificate.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errs.New("unexpected certificate public key type %T", doc.Certificate.PublicKey)
+	}
+
+	toBeSigned, err := sigStructure(protected, payload)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyES384(publicKey, toBeSigned, signature) {
+		return nil, errs.New("signature verification failed")
+	}
+
+	return doc, nil
+}
+
+// SelectorValues returns selector values for the non-zero PCRs in the
+// document. Unused PCRs are all zeroes and are omitted.
+func (d *AttestationDocument) SelectorValues() []string {
+	var values []string
+	for i := 0; i < maxPCRs; i++ {
+		pcr, ok := d.PCRs[i]
+		if !ok || isZero(pcr) {
+			continue
+		}
+		values = append(values, fmt.Sprintf("pcr%d:%s", i, hex.EncodeToString(pcr)))
+	}
+	return values
+}
+
+func parseCOSESign1(data []byte) (protected, payload, signature []byte, err error) {
+	v, err := DecodeCBOR(data)
+	if err != nil {
+		return nil, nil, nil, errs.New("unable to decode COSE_Sign1: %v", err)
+	}
+	array, ok := v.([]interface{})
+	if !ok || len(array) != 4 {
+		return nil, nil, nil, errs.New("malformed COSE_Sign1: expected array of four items")
+	}
+	protected, ok1 := array[0].([]byte)
+	payload, ok2 := array[2].([]byte)
+	signature, ok3 := array[3].([]byte)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, nil, nil, errs.New("malformed COSE_Sign1: expected byte strings")
+	}
+
+	headers, err := DecodeCBOR(protected)
+	if err != nil {
+		return nil, nil, nil, errs.New("unable to decode COSE protected headers: %v", err)
+	}
+	headerMap, ok := headers.(map[interface{}]interface{})
+	if !ok {
+		return nil, nil, nil, errs.New("malformed COSE protected headers")
+	}
+	if alg := headerMap[uint64(coseHeaderAlgorithm)]; alg != int64(coseAlgorithmES384) {
+		return nil, nil, nil, errs.New("unsupported COSE algorithm %v", alg)
+	}
+
+	return protected, payload, signature, nil
+}
+
+func parseAttestationDocument(payload []byte) (*AttestationDocument, error) {
+	v, err := DecodeCBOR(payload)
+	if err != nil {
+		return nil, errs.New("unable to decode attestation document: %v", err)
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errs.New("malformed attestation document: expected map")
+	}
+	entries := cborMapEntries(m)
+
+	doc := &AttestationDocument{
+		PCRs: make(map[int][]byte),
+	}
+	if doc.ModuleID, err = entries.text("module_id"); err != nil {
+		return nil, errs.New("malformed attestation document: %v", err)
+	}
+	if doc.ModuleID == "" {
+		return nil, errs.New("attestation document missing module_id")
+	}
+	if doc.Digest, err = entries.text("digest"); err != nil {
+		return nil, errs.New("malformed attestation document: %v", err)
+	}
+	if doc.Digest != "SHA384" {
+		return nil, errs.New("unsupported attestation document digest %q", doc.Digest)
+	}
+	timestamp, err := entries.uint("timestamp")
+	if err != nil {
+		return nil, errs.New("malformed attestation document: %v", err)
+	}
+	doc.Timestamp = time.Unix(0, int64(timestamp)*int64(time.Millisecond)).UTC()
+
+	pcrs, ok := m["pcrs"].(map[interface{}]interface{})
+	if !ok {
+		return nil, errs.New("attestation document missing pcrs")
+	}
+	for index, value := range pcrs {
+		i, ok1 := index.(uint64)
+		pcr, ok2 := value.([]byte)
+		if !ok1 || !ok2 || i >= maxPCRs {
+			return nil, errs.New("malformed attestation document: invalid PCR entry")
+		}
+		doc.PCRs[int(i)] = pcr
+	}
+
+	certDER, err := entries.bytes("certificate")
+	if err != nil {
+		return nil, errs.New("malformed attestation document: %v", err)
+	}
+	if doc.Certificate, err = x509.ParseCertificate(certDER); err != nil {
+		return nil, errs.New("unable to parse attestation document certificate: %v", err)
+	}
+	cabundle, ok := m["cabundle"].([]interface{})
+	if !ok {
+		return nil, errs.New("attestation document missing cabundle")
+	}
+	for i, value := range cabundle {
+		der, ok := value.([]byte)
+		if !ok {
+			return nil, errs.New("malformed attestation document: cabundle entry %d is not a byte string", i)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, errs.New("unable to parse attestation document cabundle entry %d: %v", i, err)
+		}
+		doc.CABundle = append(doc.CABundle, cert)
+	}
+
+	if doc.PublicKey, err = entries.bytes("public_key"); err != nil {
+		return nil, errs.New("malformed attestation document: %v", err)
+	}
+	if doc.UserData, err = entries.bytes("user_data"); err != nil {
+		return nil, errs.New("malformed attestation document: %v", err)
+	}
+	if doc.Nonce, err = entries.bytes("nonce"); err != nil {
+		return nil, errs.New("malformed attestation document: %v", err)
+	}
+
+	return doc, nil
+}
+
+// sigStructure builds the COSE Sig_structure signed for a COSE_Sign1
+// message with no external additional authenticated data
+func sigStructure(protected, payload []byte) ([]byte, error) {
+	return EncodeCBOR([]interface{}{
+		"Signature1",
+		protected,
+		[]byte{},
+		payload,
+	})
+}
+
+func verifyES384(publicKey *ecdsa.PublicKey, toBeSigned, signature []byte) bool {
+	// COSE ECDSA signatures are the fixed size concatenation of r and s
+	if len(signature) != 96 {
+		return false
+	}
+	digest := sha512.Sum384(toBeSigned)
+	r := new(big.Int).SetBytes(signature[:48])
+	s := new(big.Int).SetBytes(signature[48:])
+	return ecdsa.Verify(publicKey, digest[:], r, s)
+}
+
+func isZero(b []byte) bool {
+	return len(bytes.Trim(b, "\x00")) == 0
+}
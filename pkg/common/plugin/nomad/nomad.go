@@ -0,0 +1,49 @@
+package nomad
+
+import (
+	"net/url"
+	"path"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	PluginName = "nomad"
+
+	// DefaultAudience is the audience of the default Nomad workload
+	// identity
+	DefaultAudience = "nomad.io"
+)
+
+type AttestationData struct {
+	Cluster string `json:"cluster"`
+	Token   string `json:"token"`
+}
+
+// WorkloadIdentityClaims represents claims in a Nomad workload identity
+// token, for example:
+// {
+//   "aud": "nomad.io",
+//   "nomad_allocation_id": "5c3b2bbe-4a2b-e0a4-ae6c-3a5d1ee0a4a1",
+//   "nomad_job_id": "spire-agent",
+//   "nomad_namespace": "default",
+//   "nomad_task": "agent",
+//   "sub": "global:default:spire-agent:agent:agent:default"
+// }
+type WorkloadIdentityClaims struct {
+	jwt.Claims
+	Namespace    string `json:"nomad_namespace"`
+	JobID        string `json:"nomad_job_id"`
+	AllocationID string `json:"nomad_allocation_id"`
+	Task         string `json:"nomad_task"`
+}
+
+// AgentID returns the agent SPIFFE ID for the allocation the agent runs in
+func AgentID(trustDomain, cluster, allocationID string) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, cluster, allocationID),
+	}
+	return u.String()
+}
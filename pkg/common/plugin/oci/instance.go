@@ -0,0 +1,171 @@
+package oci
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	PluginName = "oci_instance_principal"
+
+	// DefaultMetadataURL is the base URL of the v2 instance metadata service
+	DefaultMetadataURL = "http://169.254.169.254/opc/v2"
+
+	// prefixes of the organizational units identifying the instance in the
+	// instance principal certificate
+	ouInstance    = "opc-instance:"
+	ouCompartment = "opc-compartment:"
+	ouTenant      = "opc-tenant:"
+
+	// maxMetadataSize bounds the size of the metadata responses
+	maxMetadataSize = 64 * 1024
+)
+
+// AttestationData is sent by the agent to begin attestation. Certificates
+// holds the DER encoded instance principal certificate followed by the
+// intermediate. Region is the canonical name of the region the instance
+// runs in, as reported by the metadata service.
+type AttestationData struct {
+	Certificates [][]byte `json:"certificates"`
+	Region       string   `json:"region"`
+}
+
+// InstanceIdentity holds the identity of the instance as asserted by the
+// instance principal certificate
+type InstanceIdentity struct {
+	InstanceID    string
+	CompartmentID string
+	TenancyID     string
+}
+
+// ParseInstanceIdentity extracts the instance identity from the
+// organizational units of the instance principal certificate
+func ParseInstanceIdentity(cert *x509.Certificate) (*InstanceIdentity, error) {
+	identity := new(InstanceIdentity)
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		switch {
+		case strings.HasPrefix(ou, ouInstance):
+			identity.InstanceID = strings.TrimPrefix(ou, ouInstance)
+		case strings.HasPrefix(ou, ouCompartment):
+			identity.CompartmentID = strings.TrimPrefix(ou, ouCompartment)
+		case strings.HasPrefix(ou, ouTenant):
+			identity.TenancyID = strings.TrimPrefix(ou, ouTenant)
+		}
+	}
+
+	switch {
+	case identity.InstanceID == "":
+		return nil, errs.New("certificate missing instance ID")
+	case identity.CompartmentID == "":
+		return nil, errs.New("certificate missing compartment ID")
+	case identity.TenancyID == "":
+		return nil, errs.New("certificate missing tenancy ID")
+	}
+	return identity, nil
+}
+
+func AgentID(trustDomain string, identity *InstanceIdentity) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, identity.TenancyID, identity.InstanceID),
+	}
+	return u.String()
+}
+
+// InstanceCredentials holds the PEM encoded instance principal
+// credentials served by the metadata service
+type InstanceCredentials struct {
+	Certificate  []byte
+	Intermediate []byte
+	PrivateKey   []byte
+	Region       string
+}
+
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type HTTPClientFunc func(*http.Request) (*http.Response, error)
+
+func (fn HTTPClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// FetchInstanceCredentials fetches the instance principal certificate,
+// intermediate and private key, along with the region, from the metadata
+// service
+func FetchInstanceCredentials(ctx context.Context, cl HTTPClient, metadataURL string) (*InstanceCredentials, error) {
+	creds := new(InstanceCredentials)
+	for _, item := range []struct {
+		path string
+		out  *[]byte
+	}{
+		{"/identity/cert.pem", &creds.Certificate},
+		{"/identity/intermediate.pem", &creds.Intermediate},
+		{"/identity/key.pem", &creds.PrivateKey},
+	} {
+		body, err := fetchMetadata(ctx, cl, metadataURL+item.path)
+		if err != nil {
+			return nil, err
+		}
+		*item.out = body
+	}
+
+	body, err := fetchMetadata(ctx, cl, metadataURL+"/instance/")
+	if err != nil {
+		return nil, err
+	}
+	instance := struct {
+		CanonicalRegionName string `json:"canonicalRegionName"`
+	}{}
+	if err := json.Unmarshal(body, &instance); err != nil {
+		return nil, errs.New("unable to decode instance metadata: %v", err)
+	}
+	if instance.CanonicalRegionName == "" {
+		return nil, errs.New("instance metadata missing region")
+	}
+	creds.Region = instance.CanonicalRegionName
+
+	return creds, nil
+}
+
+func fetchMetadata(ctx context.Context, cl HTTPClient, metadataURL string) ([]byte, error) {
+	req, err := http.NewRequest("GET", metadataURL, nil)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	// required by the v2 metadata service
+	req.Header.Set("Authorization", "Bearer Oracle")
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errs.New("unexpected status code %d from %s: %s", resp.StatusCode, req.URL.Path, tryRead(resp.Body))
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return body, nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
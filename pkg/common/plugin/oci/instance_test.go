@@ -0,0 +1,95 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spiffe/spire/test/fakes/fakeoci"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInstanceIdentity(t *testing.T) {
+	ca := fakeoci.New(t)
+
+	cert, _ := ca.IssueInstanceCertificate(
+		"opc-certtype:instance",
+		"opc-instance:INSTANCE",
+		"opc-compartment:COMPARTMENT",
+		"opc-tenant:TENANCY",
+	)
+	identity, err := ParseInstanceIdentity(cert)
+	require.NoError(t, err)
+	require.Equal(t, &InstanceIdentity{
+		InstanceID:    "INSTANCE",
+		CompartmentID: "COMPARTMENT",
+		TenancyID:     "TENANCY",
+	}, identity)
+	require.Equal(t, "spiffe://example.org/spire/agent/oci_instance_principal/TENANCY/INSTANCE", AgentID("example.org", identity))
+
+	cert, _ = ca.IssueInstanceCertificate("opc-compartment:COMPARTMENT", "opc-tenant:TENANCY")
+	_, err = ParseInstanceIdentity(cert)
+	require.EqualError(t, err, "certificate missing instance ID")
+
+	cert, _ = ca.IssueInstanceCertificate("opc-instance:INSTANCE", "opc-tenant:TENANCY")
+	_, err = ParseInstanceIdentity(cert)
+	require.EqualError(t, err, "certificate missing compartment ID")
+
+	cert, _ = ca.IssueInstanceCertificate("opc-instance:INSTANCE", "opc-compartment:COMPARTMENT")
+	_, err = ParseInstanceIdentity(cert)
+	require.EqualError(t, err, "certificate missing tenancy ID")
+}
+
+func TestFetchInstanceCredentials(t *testing.T) {
+	instance := `{"canonicalRegionName": "us-ashburn-1"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer Oracle" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		switch req.URL.Path {
+		case "/opc/v2/identity/cert.pem":
+			fmt.Fprint(w, "CERT")
+		case "/opc/v2/identity/intermediate.pem":
+			fmt.Fprint(w, "INTERMEDIATE")
+		case "/opc/v2/identity/key.pem":
+			fmt.Fprint(w, "KEY")
+		case "/opc/v2/instance/":
+			fmt.Fprint(w, instance)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := http.DefaultClient
+
+	creds, err := FetchInstanceCredentials(ctx, client, server.URL+"/opc/v2")
+	require.NoError(t, err)
+	require.Equal(t, &InstanceCredentials{
+		Certificate:  []byte("CERT"),
+		Intermediate: []byte("INTERMEDIATE"),
+		PrivateKey:   []byte("KEY"),
+		Region:       "us-ashburn-1",
+	}, creds)
+
+	instance = `{}`
+	_, err = FetchInstanceCredentials(ctx, client, server.URL+"/opc/v2")
+	require.EqualError(t, err, "instance metadata missing region")
+
+	instance = `{`
+	_, err = FetchInstanceCredentials(ctx, client, server.URL+"/opc/v2")
+	require.EqualError(t, err, "unable to decode instance metadata: unexpected end of JSON input")
+
+	_, err = FetchInstanceCredentials(ctx, client, server.URL+"/opc/v1")
+	require.EqualError(t, err, "unexpected status code 404 from /opc/v1/identity/cert.pem: 404 page not found\n")
+
+	_, err = FetchInstanceCredentials(ctx, HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		req.Header.Del("Authorization")
+		return client.Do(req)
+	}), server.URL+"/opc/v2")
+	require.EqualError(t, err, "unexpected status code 403 from /opc/v2/identity/cert.pem: forbidden\n")
+}
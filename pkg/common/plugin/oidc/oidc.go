@@ -0,0 +1,76 @@
+package oidc
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	PluginName = "oidc"
+)
+
+type AttestationData struct {
+	Token string `json:"token"`
+}
+
+// AgentID returns the agent SPIFFE ID for the token subject. When the
+// token has an ID, it is appended so that agents presenting distinct
+// tokens for the same subject (e.g. concurrent CI jobs) get distinct IDs,
+// while a replayed token maps to the ID that has already attested.
+func AgentID(trustDomain, subject, tokenID string) (string, error) {
+	// subjects may contain slashes, but must not otherwise change the
+	// path when it is cleaned
+	if subject == "" || path.Clean("/"+subject) != "/"+subject {
+		return "", errs.New("subject %q cannot be used in an agent ID", subject)
+	}
+	if strings.Contains(tokenID, "/") || tokenID == "." || tokenID == ".." {
+		return "", errs.New("token ID %q cannot be used in an agent ID", tokenID)
+	}
+
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, subject, tokenID),
+	}
+	return u.String(), nil
+}
+
+// ClaimValues returns the string forms of a claim value. Strings, numbers
+// and booleans produce a single value and arrays of those produce one value
+// per element. Other types, like objects, produce no values.
+func ClaimValues(claim interface{}) []string {
+	switch v := claim.(type) {
+	case []interface{}:
+		var values []string
+		for _, elem := range v {
+			if s, ok := scalarValue(elem); ok {
+				values = append(values, s)
+			}
+		}
+		sort.Strings(values)
+		return values
+	default:
+		if s, ok := scalarValue(v); ok {
+			return []string{s}
+		}
+		return nil
+	}
+}
+
+func scalarValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,43 @@
+package oidc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentID(t *testing.T) {
+	for _, tt := range []struct {
+		subject  string
+		tokenID  string
+		expected string
+	}{
+		{subject: "SUBJECT", tokenID: "TOKENID", expected: "spiffe://example.org/spire/agent/oidc/SUBJECT/TOKENID"},
+		{subject: "SUBJECT", expected: "spiffe://example.org/spire/agent/oidc/SUBJECT"},
+		{subject: "repo:octo-org/octo-repo:ref:refs/heads/main", expected: "spiffe://example.org/spire/agent/oidc/repo:octo-org/octo-repo:ref:refs/heads/main"},
+	} {
+		id, err := AgentID("example.org", tt.subject, tt.tokenID)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, id)
+	}
+
+	for _, subject := range []string{"", "../../server", "a//b", "a/", "/a", "a/./b"} {
+		_, err := AgentID("example.org", subject, "")
+		require.EqualError(t, err, fmt.Sprintf("subject %q cannot be used in an agent ID", subject))
+	}
+	for _, tokenID := range []string{"a/b", ".."} {
+		_, err := AgentID("example.org", "SUBJECT", tokenID)
+		require.EqualError(t, err, fmt.Sprintf("token ID %q cannot be used in an agent ID", tokenID))
+	}
+}
+
+func TestClaimValues(t *testing.T) {
+	require.Equal(t, []string{"foo"}, ClaimValues("foo"))
+	require.Equal(t, []string{"42"}, ClaimValues(float64(42)))
+	require.Equal(t, []string{"1.5"}, ClaimValues(1.5))
+	require.Equal(t, []string{"true"}, ClaimValues(true))
+	require.Equal(t, []string{"a", "b", "false"}, ClaimValues([]interface{}{"b", "a", false, map[string]interface{}{}}))
+	require.Nil(t, ClaimValues(map[string]interface{}{"a": "b"}))
+	require.Nil(t, ClaimValues(nil))
+}
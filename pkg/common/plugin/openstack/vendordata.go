@@ -0,0 +1,95 @@
+package openstack
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	PluginName = "openstack"
+
+	// DefaultVendordataURL is the location of the dynamic vendordata served
+	// by the Nova metadata service
+	DefaultVendordataURL = "http://169.254.169.254/openstack/latest/vendor_data2.json"
+
+	// DefaultVendordataTarget is the default name of the Nova dynamic
+	// vendordata target that provides the signed token
+	DefaultVendordataTarget = "spire"
+
+	// DefaultAudience is the default audience of the vendordata token. The
+	// server rejects tokens intended for a different audience.
+	DefaultAudience = "spire-server"
+)
+
+type AttestationData struct {
+	Token string `json:"token"`
+}
+
+// VendordataClaims are the claims of the token signed by the vendordata
+// service. Nova passes the instance details to the service when the
+// instance requests its vendordata.
+type VendordataClaims struct {
+	jwt.Claims
+	ProjectID  string `json:"project_id,omitempty"`
+	InstanceID string `json:"instance_id,omitempty"`
+	Flavor     string `json:"flavor,omitempty"`
+}
+
+func (c *VendordataClaims) AgentID(trustDomain string) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, c.ProjectID, c.InstanceID),
+	}
+	return u.String()
+}
+
+// FetchVendordataToken fetches the dynamic vendordata from the metadata
+// service and returns the token provided by the given target. Nova keys the
+// response of each vendordata service by its target name.
+func FetchVendordataToken(ctx context.Context, vendordataURL, target string) (string, error) {
+	req, err := http.NewRequest("GET", vendordataURL, nil)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	vendordata := map[string]struct {
+		Token string `json:"token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&vendordata); err != nil {
+		return "", errs.New("unable to decode vendordata: %v", err)
+	}
+
+	entry, ok := vendordata[target]
+	if !ok {
+		return "", errs.New("vendordata missing target %q", target)
+	}
+	if entry.Token == "" {
+		return "", errs.New("vendordata target %q missing token", target)
+	}
+	return entry.Token, nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
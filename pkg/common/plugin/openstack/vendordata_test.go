@@ -0,0 +1,57 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVendordataClaims(t *testing.T) {
+	claims := VendordataClaims{
+		ProjectID:  "PROJECTID",
+		InstanceID: "INSTANCEID",
+	}
+	require.Equal(t, "spiffe://example.org/spire/agent/openstack/PROJECTID/INSTANCEID", claims.AgentID("example.org"))
+}
+
+func TestFetchVendordataToken(t *testing.T) {
+	ctx := context.Background()
+
+	// unexpected status
+	token, err := FetchVendordataToken(ctx, vendordataServer(t, http.StatusNotFound, "ERROR"), "spire")
+	require.EqualError(t, err, "unexpected status code 404: ERROR")
+	require.Empty(t, token)
+
+	// malformed response
+	token, err = FetchVendordataToken(ctx, vendordataServer(t, http.StatusOK, "{"), "spire")
+	require.EqualError(t, err, "unable to decode vendordata: unexpected EOF")
+	require.Empty(t, token)
+
+	// missing target
+	token, err = FetchVendordataToken(ctx, vendordataServer(t, http.StatusOK, `{"other": {"token": "ASDF"}}`), "spire")
+	require.EqualError(t, err, `vendordata missing target "spire"`)
+	require.Empty(t, token)
+
+	// missing token
+	token, err = FetchVendordataToken(ctx, vendordataServer(t, http.StatusOK, `{"spire": {}}`), "spire")
+	require.EqualError(t, err, `vendordata target "spire" missing token`)
+	require.Empty(t, token)
+
+	// success
+	token, err = FetchVendordataToken(ctx, vendordataServer(t, http.StatusOK, `{"spire": {"token": "ASDF"}}`), "spire")
+	require.NoError(t, err)
+	require.Equal(t, "ASDF", token)
+}
+
+func vendordataServer(t *testing.T, statusCode int, body string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(statusCode)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
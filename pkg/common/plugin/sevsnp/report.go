@@ -0,0 +1,265 @@
+package sevsnp
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	PluginName = "sev_snp"
+
+	// MinReportVersion is the oldest supported attestation report version
+	MinReportVersion = 2
+
+	// SignatureAlgoECDSAP384SHA384 identifies reports signed with ECDSA
+	// P-384 over a SHA-384 digest
+	SignatureAlgoECDSAP384SHA384 = 1
+
+	// ReportSize is the size of an attestation report, including the
+	// signature
+	ReportSize = 0x4A0
+
+	// signedSize is the size of the portion of the report covered by the
+	// signature
+	signedSize = 0x2A0
+
+	// signatureComponentSize is the size of each little endian signature
+	// component (R and S) in the report
+	signatureComponentSize = 72
+
+	// policyDebug is the guest policy bit allowing the hypervisor to debug
+	// the guest, which exposes guest memory
+	policyDebug = 1 << 19
+
+	nonceSize = 32
+)
+
+var (
+	// oidHardwareID is the VCEK certificate extension holding the chip ID
+	// the certificate was issued for
+	oidHardwareID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 4}
+)
+
+// AttestationData is sent by the agent to begin attestation. The report is
+// only provided in response to the challenge.
+type AttestationData struct{}
+
+// Challenge is sent by the server. The report data must bind the nonce to
+// prove the report is fresh.
+type Challenge struct {
+	Nonce []byte `json:"nonce"`
+}
+
+// Response is sent by the agent in response to the challenge. Certificates
+// holds the DER encoded VCEK certificate followed by any intermediates.
+type Response struct {
+	Report       []byte   `json:"report"`
+	Certificates [][]byte `json:"certificates"`
+}
+
+// Report is an SEV-SNP attestation report produced by the AMD secure
+// processor
+type Report struct {
+	Version         uint32
+	GuestSVN        uint32
+	Policy          uint64
+	FamilyID        [16]byte
+	ImageID         [16]byte
+	VMPL            uint32
+	SignatureAlgo   uint32
+	CurrentTCB      uint64
+	PlatformInfo    uint64
+	ReportData      [64]byte
+	Measurement     [48]byte
+	HostData        [32]byte
+	IDKeyDigest     [48]byte
+	AuthorKeyDigest [48]byte
+	ReportID        [32]byte
+	ReportedTCB     uint64
+	ChipID          [64]byte
+
+	signedData []byte
+	signature  []byte
+}
+
+// Debug returns true if the guest policy allows the guest to be debugged
+func (r *Report) Debug() bool {
+	return r.Policy&policyDebug != 0
+}
+
+// SelectorValues returns the selector values describing the guest
+func (r *Report) SelectorValues() []string {
+	return []string{
+		fmt.Sprintf("measurement:%x", r.Measurement),
+		fmt.Sprintf("policy:%016x", r.Policy),
+		fmt.Sprintf("chip_id:%x", r.ChipID),
+		fmt.Sprintf("guest_svn:%d", r.GuestSVN),
+		fmt.Sprintf("vmpl:%d", r.VMPL),
+	}
+}
+
+// AgentID returns the agent ID for the guest. The report ID is generated
+// by the secure processor when the guest is launched and is unique to it.
+func AgentID(trustDomain string, report *Report) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, hex.EncodeToString(report.ReportID[:])),
+	}
+	return u.String()
+}
+
+// ReportDataForNonce returns the report data a guest uses to bind the nonce
+// to its report
+func ReportDataForNonce(nonce []byte) [64]byte {
+	return sha512.Sum512(nonce)
+}
+
+func GenerateChallenge() (*Challenge, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return &Challenge{Nonce: nonce}, nil
+}
+
+// ParseReport parses an SEV-SNP attestation report without verifying it
+func ParseReport(data []byte) (*Report, error) {
+	if len(data) != ReportSize {
+		return nil, errs.New("unexpected report size %d", len(data))
+	}
+
+	le := binary.LittleEndian
+	r := &Report{
+		Version:       le.Uint32(data[0x00:]),
+		GuestSVN:      le.Uint32(data[0x04:]),
+		Policy:        le.Uint64(data[0x08:]),
+		VMPL:          le.Uint32(data[0x30:]),
+		SignatureAlgo: le.Uint32(data[0x34:]),
+		CurrentTCB:    le.Uint64(data[0x38:]),
+		PlatformInfo:  le.Uint64(data[0x40:]),
+		ReportedTCB:   le.Uint64(data[0x180:]),
+		signedData:    data[:signedSize],
+		signature:     data[signedSize:],
+	}
+	copy(r.FamilyID[:], data[0x10:])
+	copy(r.ImageID[:], data[0x20:])
+	copy(r.ReportData[:], data[0x50:])
+	copy(r.Measurement[:], data[0x90:])
+	copy(r.HostData[:], data[0xC0:])
+	copy(r.IDKeyDigest[:], data[0xE0:])
+	copy(r.AuthorKeyDigest[:], data[0x110:])
+	copy(r.ReportID[:], data[0x140:])
+	copy(r.ChipID[:], data[0x1A0:])
+
+	if r.Version < MinReportVersion {
+		return nil, errs.New("unsupported report version %d", r.Version)
+	}
+	if r.SignatureAlgo != SignatureAlgoECDSAP384SHA384 {
+		return nil, errs.New("unsupported signature algorithm %d", r.SignatureAlgo)
+	}
+	return r, nil
+}
+
+// VerifyReport parses an SEV-SNP attestation report and verifies that:
+// - the VCEK certificate chains back to one of the roots
+// - the VCEK certificate was issued for the chip that produced the report
+// - the report is signed by the VCEK
+//
+// The certificates are DER encoded, starting with the VCEK. Checking the
+// reported TCB against AMD's TCB and revocation information is not
+// performed.
+func VerifyReport(data []byte, certificates [][]byte, roots *x509.CertPool, now time.Time) (*Report, error) {
+	r, err := ParseReport(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(certificates) == 0 {
+		return nil, errs.New("missing VCEK certificate")
+	}
+	var certs []*x509.Certificate
+	for _, der := range certificates {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, errs.New("unable to parse certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	vcek := certs[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := vcek.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         roots,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, errs.New("VCEK certificate verification failed: %v", err)
+	}
+
+	if hwID, ok := hardwareID(vcek); ok && !bytes.Equal(hwID, r.ChipID[:]) {
+		return nil, errs.New("VCEK certificate was not issued for chip %x", r.ChipID)
+	}
+
+	vcekKey, ok := vcek.PublicKey.(*ecdsa.PublicKey)
+	if !ok || vcekKey.Curve != elliptic.P384() {
+		return nil, errs.New("VCEK certificate does not have an ECDSA P-384 key")
+	}
+	if !verifySignature(vcekKey, r.signedData, r.signature) {
+		return nil, errs.New("report signature verification failed")
+	}
+
+	return r, nil
+}
+
+// hardwareID returns the chip ID held by the VCEK certificate, if present.
+// The extension value is an OCTET STRING, though some certificates carry the
+// raw bytes.
+func hardwareID(cert *x509.Certificate) ([]byte, bool) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidHardwareID) {
+			continue
+		}
+		var hwID []byte
+		if rest, err := asn1.Unmarshal(ext.Value, &hwID); err == nil && len(rest) == 0 {
+			return hwID, true
+		}
+		return ext.Value, true
+	}
+	return nil, false
+}
+
+// verifySignature verifies the report signature, whose R and S components
+// are little endian, over the SHA-384 digest of data
+func verifySignature(key *ecdsa.PublicKey, data, signature []byte) bool {
+	digest := sha512.Sum384(data)
+	r := new(big.Int).SetBytes(reverse(signature[:signatureComponentSize]))
+	s := new(big.Int).SetBytes(reverse(signature[signatureComponentSize : 2*signatureComponentSize]))
+	return ecdsa.Verify(key, digest[:], r, s)
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package sevsnp
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReport(t *testing.T) {
+	_, err := ParseReport(nil)
+	require.EqualError(t, err, "unexpected report size 0")
+
+	data := make([]byte, ReportSize)
+	binary.LittleEndian.PutUint32(data[0x00:], 1)
+	_, err = ParseReport(data)
+	require.EqualError(t, err, "unsupported report version 1")
+
+	binary.LittleEndian.PutUint32(data[0x00:], MinReportVersion)
+	binary.LittleEndian.PutUint32(data[0x34:], 2)
+	_, err = ParseReport(data)
+	require.EqualError(t, err, "unsupported signature algorithm 2")
+
+	binary.LittleEndian.PutUint32(data[0x34:], SignatureAlgoECDSAP384SHA384)
+	binary.LittleEndian.PutUint32(data[0x04:], 3)
+	binary.LittleEndian.PutUint64(data[0x08:], 0xB0000)
+	binary.LittleEndian.PutUint32(data[0x30:], 1)
+	data[0x90] = 0x01
+	data[0x140] = 0x02
+	data[0x1A0] = 0x03
+	report, err := ParseReport(data)
+	require.NoError(t, err)
+	require.True(t, report.Debug())
+	require.Equal(t, []string{
+		"measurement:010000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		"policy:00000000000b0000",
+		"chip_id:03000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		"guest_svn:3",
+		"vmpl:1",
+	}, report.SelectorValues())
+	require.Equal(t, "spiffe://example.org/spire/agent/sev_snp/0200000000000000000000000000000000000000000000000000000000000000", AgentID("example.org", report))
+}
+
+func TestReportDataForNonce(t *testing.T) {
+	require.Equal(t, sha512.Sum512([]byte("NONCE")), ReportDataForNonce([]byte("NONCE")))
+}
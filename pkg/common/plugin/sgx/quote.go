@@ -0,0 +1,331 @@
+package sgx
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	PluginName = "sgx_dcap"
+
+	// QuoteVersion is the only supported DCAP quote version
+	QuoteVersion = 3
+
+	// AttestationKeyTypeECDSAP256 identifies quotes signed with an ECDSA
+	// P-256 attestation key
+	AttestationKeyTypeECDSAP256 = 2
+
+	// CertificationDataTypePCKCertChain identifies certification data
+	// holding the PEM encoded PCK certificate chain
+	CertificationDataTypePCKCertChain = 5
+
+	HeaderSize     = 48
+	ReportBodySize = 384
+	SignatureSize  = 64
+	PublicKeySize  = 64
+
+	nonceSize = 32
+
+	// attributeDebug is set in the first attributes byte for enclaves
+	// launched in debug mode, whose memory can be inspected by the host
+	attributeDebug = 0x02
+)
+
+// AttestationData is sent by the agent to begin attestation. The quote is
+// only provided in response to the challenge.
+type AttestationData struct{}
+
+// Challenge is sent by the server. The quote's report data must bind the
+// nonce to prove the quote is fresh.
+type Challenge struct {
+	Nonce []byte `json:"nonce"`
+}
+
+// Response is sent by the agent in response to the challenge
+type Response struct {
+	Quote []byte `json:"quote"`
+}
+
+// ReportBody is the enclave report embedded in a quote
+type ReportBody struct {
+	CPUSVN     [16]byte
+	MiscSelect uint32
+	Attributes [16]byte
+	MRENCLAVE  [32]byte
+	MRSIGNER   [32]byte
+	ISVProdID  uint16
+	ISVSVN     uint16
+	ReportData [64]byte
+}
+
+// Debug returns true if the enclave was launched in debug mode
+func (r *ReportBody) Debug() bool {
+	return r.Attributes[0]&attributeDebug != 0
+}
+
+// Quote is an SGX ECDSA quote produced by the DCAP quoting enclave (QE)
+type Quote struct {
+	Version            uint16
+	AttestationKeyType uint16
+	Report             ReportBody
+
+	Signature         []byte
+	AttestationKey    []byte
+	QEReport          ReportBody
+	QEReportSignature []byte
+	QEAuthData        []byte
+	PCKCertificates   []*x509.Certificate
+
+	signedData   []byte
+	qeReportData []byte
+}
+
+func AgentID(trustDomain string, quote *Quote) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, AttestationKeyFingerprint(quote.AttestationKey)),
+	}
+	return u.String()
+}
+
+// AttestationKeyFingerprint identifies the platform by the quoting
+// enclave's attestation key
+func AttestationKeyFingerprint(attestationKey []byte) string {
+	sum := sha256.Sum256(attestationKey)
+	return hex.EncodeToString(sum[:])
+}
+
+// ReportDataForNonce returns the report data an enclave uses to bind the
+// nonce to its report
+func ReportDataForNonce(nonce []byte) [64]byte {
+	var reportData [64]byte
+	sum := sha256.Sum256(nonce)
+	copy(reportData[:], sum[:])
+	return reportData
+}
+
+func GenerateChallenge() (*Challenge, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return &Challenge{Nonce: nonce}, nil
+}
+
+// SelectorValues returns the selector values describing the enclave
+func (r *ReportBody) SelectorValues() []string {
+	return []string{
+		fmt.Sprintf("mrenclave:%x", r.MRENCLAVE),
+		fmt.Sprintf("mrsigner:%x", r.MRSIGNER),
+		fmt.Sprintf("isv_prod_id:%d", r.ISVProdID),
+		fmt.Sprintf("isv_svn:%d", r.ISVSVN),
+	}
+}
+
+// ParseQuote parses an SGX ECDSA quote without verifying it
+func ParseQuote(data []byte) (*Quote, error) {
+	r := &quoteReader{data: data}
+
+	q := new(Quote)
+	header := r.next(HeaderSize)
+	reportBody := r.next(ReportBodySize)
+	signatureDataLen := r.uint32()
+	if r.err != nil {
+		return nil, errs.New("quote too short")
+	}
+	q.Version = binary.LittleEndian.Uint16(header[0:])
+	q.AttestationKeyType = binary.LittleEndian.Uint16(header[2:])
+	if q.Version != QuoteVersion {
+		return nil, errs.New("unsupported quote version %d", q.Version)
+	}
+	if q.AttestationKeyType != AttestationKeyTypeECDSAP256 {
+		return nil, errs.New("unsupported attestation key type %d", q.AttestationKeyType)
+	}
+	q.Report = parseReportBody(reportBody)
+	q.signedData = data[:HeaderSize+ReportBodySize]
+
+	if int(signatureDataLen) != len(r.data)-r.off {
+		return nil, errs.New("quote signature data length %d does not match remaining %d bytes", signatureDataLen, len(r.data)-r.off)
+	}
+
+	q.Signature = r.next(SignatureSize)
+	q.AttestationKey = r.next(PublicKeySize)
+	q.qeReportData = r.next(ReportBodySize)
+	q.QEReportSignature = r.next(SignatureSize)
+	q.QEAuthData = r.next(int(r.uint16()))
+	certificationDataType := r.uint16()
+	certificationData := r.next(int(r.uint32()))
+	if r.err != nil {
+		return nil, errs.New("quote signature data truncated")
+	}
+	q.QEReport = parseReportBody(q.qeReportData)
+
+	if certificationDataType != CertificationDataTypePCKCertChain {
+		return nil, errs.New("unsupported certification data type %d", certificationDataType)
+	}
+	certs, err := parsePEMCertificates(certificationData)
+	if err != nil {
+		return nil, errs.New("unable to parse PCK certificate chain: %v", err)
+	}
+	if len(certs) == 0 {
+		return nil, errs.New("quote missing PCK certificate chain")
+	}
+	q.PCKCertificates = certs
+
+	return q, nil
+}
+
+// VerifyQuote parses an SGX ECDSA quote and verifies that:
+// - the PCK certificate chains back to one of the roots
+// - the QE report is signed by the PCK certificate key
+// - the QE report binds the attestation key
+// - the enclave report is signed by the attestation key
+//
+// TCB level evaluation against Intel's collateral (TCB info, QE identity and
+// revocation lists) is not performed.
+func VerifyQuote(data []byte, roots *x509.CertPool, now time.Time) (*Quote, error) {
+	q, err := ParseQuote(data)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := q.PCKCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range q.PCKCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         roots,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, errs.New("PCK certificate verification failed: %v", err)
+	}
+
+	pckKey, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errs.New("unexpected PCK certificate public key type %T", leaf.PublicKey)
+	}
+	if !verifySignature(pckKey, q.qeReportData, q.QEReportSignature) {
+		return nil, errs.New("QE report signature verification failed")
+	}
+
+	// the QE report data holds the hash of the attestation key and QE
+	// authentication data, followed by zeroes
+	h := sha256.New()
+	h.Write(q.AttestationKey)
+	h.Write(q.QEAuthData)
+	var expected [64]byte
+	copy(expected[:], h.Sum(nil))
+	if q.QEReport.ReportData != expected {
+		return nil, errs.New("QE report does not bind the attestation key")
+	}
+
+	attestationKey, err := parseP256PublicKey(q.AttestationKey)
+	if err != nil {
+		return nil, err
+	}
+	if !verifySignature(attestationKey, q.signedData, q.Signature) {
+		return nil, errs.New("quote signature verification failed")
+	}
+
+	return q, nil
+}
+
+func parseReportBody(b []byte) ReportBody {
+	var r ReportBody
+	copy(r.CPUSVN[:], b[0:16])
+	r.MiscSelect = binary.LittleEndian.Uint32(b[16:])
+	copy(r.Attributes[:], b[48:64])
+	copy(r.MRENCLAVE[:], b[64:96])
+	copy(r.MRSIGNER[:], b[128:160])
+	r.ISVProdID = binary.LittleEndian.Uint16(b[256:])
+	r.ISVSVN = binary.LittleEndian.Uint16(b[258:])
+	copy(r.ReportData[:], b[320:384])
+	return r
+}
+
+func parseP256PublicKey(b []byte) (*ecdsa.PublicKey, error) {
+	key := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(b[:32]),
+		Y:     new(big.Int).SetBytes(b[32:]),
+	}
+	if !key.Curve.IsOnCurve(key.X, key.Y) {
+		return nil, errs.New("attestation key is not on the P-256 curve")
+	}
+	return key, nil
+}
+
+// verifySignature verifies a raw r||s ECDSA P-256 signature over the SHA-256
+// digest of data
+func verifySignature(key *ecdsa.PublicKey, data, signature []byte) bool {
+	digest := sha256.Sum256(data)
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	return ecdsa.Verify(key, digest[:], r, s)
+}
+
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	// the certification data may be NUL terminated
+	data = bytes.TrimRight(data, "\x00")
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// quoteReader reads little endian fields, recording the first short read
+type quoteReader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (r *quoteReader) next(n int) []byte {
+	if r.err != nil || n > len(r.data)-r.off {
+		r.err = errs.New("short read")
+		return make([]byte, n)
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *quoteReader) uint16() uint16 {
+	return binary.LittleEndian.Uint16(r.next(2))
+}
+
+func (r *quoteReader) uint32() uint32 {
+	return binary.LittleEndian.Uint32(r.next(4))
+}
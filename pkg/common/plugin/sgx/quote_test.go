@@ -0,0 +1,82 @@
+package sgx
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportDataForNonce(t *testing.T) {
+	sum := sha256.Sum256([]byte("NONCE"))
+	reportData := ReportDataForNonce([]byte("NONCE"))
+	require.Equal(t, sum[:], reportData[:32])
+	require.Equal(t, make([]byte, 32), reportData[32:])
+}
+
+func TestAgentID(t *testing.T) {
+	quote := &Quote{AttestationKey: []byte("KEY")}
+	sum := sha256.Sum256([]byte("KEY"))
+	require.Equal(t, "spiffe://example.org/spire/agent/sgx_dcap/"+hex.EncodeToString(sum[:]), AgentID("example.org", quote))
+}
+
+func TestReportBody(t *testing.T) {
+	var b [ReportBodySize]byte
+	b[48] = attributeDebug
+	b[64] = 0x01  // MRENCLAVE
+	b[128] = 0x02 // MRSIGNER
+	binary.LittleEndian.PutUint16(b[256:], 7)
+	binary.LittleEndian.PutUint16(b[258:], 3)
+
+	report := parseReportBody(b[:])
+	require.True(t, report.Debug())
+	require.Equal(t, []string{
+		"mrenclave:0100000000000000000000000000000000000000000000000000000000000000",
+		"mrsigner:0200000000000000000000000000000000000000000000000000000000000000",
+		"isv_prod_id:7",
+		"isv_svn:3",
+	}, report.SelectorValues())
+}
+
+func TestParseQuoteErrors(t *testing.T) {
+	header := func(version, keyType uint16) []byte {
+		b := make([]byte, HeaderSize+ReportBodySize+4)
+		binary.LittleEndian.PutUint16(b[0:], version)
+		binary.LittleEndian.PutUint16(b[2:], keyType)
+		return b
+	}
+
+	_, err := ParseQuote(nil)
+	require.EqualError(t, err, "quote too short")
+
+	_, err = ParseQuote(header(4, AttestationKeyTypeECDSAP256))
+	require.EqualError(t, err, "unsupported quote version 4")
+
+	_, err = ParseQuote(header(QuoteVersion, 3))
+	require.EqualError(t, err, "unsupported attestation key type 3")
+
+	quote := header(QuoteVersion, AttestationKeyTypeECDSAP256)
+	binary.LittleEndian.PutUint32(quote[HeaderSize+ReportBodySize:], 10)
+	_, err = ParseQuote(quote)
+	require.EqualError(t, err, "quote signature data length 10 does not match remaining 0 bytes")
+
+	quote = append(quote, make([]byte, 10)...)
+	_, err = ParseQuote(quote)
+	require.EqualError(t, err, "quote signature data truncated")
+
+	// signature data with an unsupported certification data type
+	signatureData := make([]byte, SignatureSize+PublicKeySize+ReportBodySize+SignatureSize+2+2+4)
+	binary.LittleEndian.PutUint16(signatureData[len(signatureData)-6:], 1)
+	quote = header(QuoteVersion, AttestationKeyTypeECDSAP256)
+	binary.LittleEndian.PutUint32(quote[HeaderSize+ReportBodySize:], uint32(len(signatureData)))
+	quote = append(quote, signatureData...)
+	_, err = ParseQuote(quote)
+	require.EqualError(t, err, "unsupported certification data type 1")
+
+	// no certificates in the certification data
+	binary.LittleEndian.PutUint16(quote[len(quote)-6:], CertificationDataTypePCKCertChain)
+	_, err = ParseQuote(quote)
+	require.EqualError(t, err, "quote missing PCK certificate chain")
+}
@@ -0,0 +1,206 @@
+package tpmdevid
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/zeebo/errs"
+)
+
+const (
+	PluginName = "tpm_devid"
+
+	// PCRBank is the PCR bank quoted by the agent
+	PCRBank = tpm2.AlgSHA256
+
+	// generatedValue is the magic value prefixing structures created by the
+	// TPM (TPM_GENERATED_VALUE)
+	generatedValue = 0xff544347
+
+	nonceSize = 32
+)
+
+var (
+	signatureHashes = map[tpm2.Algorithm]crypto.Hash{
+		tpm2.AlgSHA256: crypto.SHA256,
+		tpm2.AlgSHA384: crypto.SHA384,
+		tpm2.AlgSHA512: crypto.SHA512,
+	}
+)
+
+// AttestationData is sent by the agent to begin attestation. Certificates
+// holds the DER encoded DevID certificate followed by any intermediates.
+type AttestationData struct {
+	Certificates [][]byte `json:"certificates"`
+}
+
+// Challenge is sent by the server. The agent must return a quote, signed by
+// the DevID key, that includes the nonce.
+type Challenge struct {
+	Nonce []byte `json:"nonce"`
+}
+
+// Response is sent by the agent in response to the challenge. Quote is the
+// TPMS_ATTEST structure and Signature the TPMT_SIGNATURE over it, produced by
+// TPM2_Quote with the DevID key. PCRs holds the values of the quoted PCRs.
+type Response struct {
+	Quote     []byte         `json:"quote"`
+	Signature []byte         `json:"signature"`
+	PCRs      map[int][]byte `json:"pcrs"`
+}
+
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func AgentID(trustDomain string, cert *x509.Certificate) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, Fingerprint(cert)),
+	}
+	return u.String()
+}
+
+func GenerateChallenge() (*Challenge, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return &Challenge{Nonce: nonce}, nil
+}
+
+// EncodeSignature encodes the signature as a TPMT_SIGNATURE
+func EncodeSignature(sig *tpm2.Signature) ([]byte, error) {
+	switch {
+	case sig.RSA != nil:
+		return tpmutil.Pack(sig.Alg, sig.RSA.HashAlg, sig.RSA.Signature)
+	case sig.ECC != nil:
+		return tpmutil.Pack(sig.Alg, sig.ECC.HashAlg, tpmutil.U16Bytes(sig.ECC.R.Bytes()), tpmutil.U16Bytes(sig.ECC.S.Bytes()))
+	default:
+		return nil, errs.New("unsupported signature algorithm %#x", uint16(sig.Alg))
+	}
+}
+
+// VerifyQuote verifies that the quote in the response is signed by the
+// public key, includes the nonce, and covers the reported PCR values.
+func VerifyQuote(publicKey crypto.PublicKey, response *Response, nonce []byte) error {
+	sig, err := tpm2.DecodeSignature(bytes.NewBuffer(response.Signature))
+	if err != nil {
+		return errs.New("unable to decode signature: %v", err)
+	}
+	if err := verifySignature(publicKey, response.Quote, sig); err != nil {
+		return err
+	}
+
+	attest, err := tpm2.DecodeAttestationData(response.Quote)
+	if err != nil {
+		return errs.New("unable to decode quote: %v", err)
+	}
+	if attest.Magic != generatedValue {
+		return errs.New("quote was not generated by a TPM")
+	}
+	if attest.Type != tpm2.TagAttestQuote {
+		return errs.New("unexpected attestation type %#x", uint16(attest.Type))
+	}
+	if !bytes.Equal(attest.ExtraData, nonce) {
+		return errs.New("quote does not include the challenge nonce")
+	}
+
+	sel := attest.AttestedQuoteInfo.PCRSelection
+	if sel.Hash != PCRBank {
+		return errs.New("unexpected PCR bank %#x", uint16(sel.Hash))
+	}
+	if len(sel.PCRs) != len(response.PCRs) {
+		return errs.New("quoted PCRs do not match reported PCRs")
+	}
+	pcrs := append([]int(nil), sel.PCRs...)
+	sort.Ints(pcrs)
+	h := sha256.New()
+	for _, pcr := range pcrs {
+		value, ok := response.PCRs[pcr]
+		if !ok {
+			return errs.New("quoted PCRs do not match reported PCRs")
+		}
+		h.Write(value)
+	}
+	if !bytes.Equal(h.Sum(nil), attest.AttestedQuoteInfo.PCRDigest) {
+		return errs.New("PCR values do not match quote digest")
+	}
+	return nil
+}
+
+// PCRSelectorValues returns selector values for the PCRs, in index order
+func PCRSelectorValues(pcrs map[int][]byte) []string {
+	var indices []int
+	for pcr := range pcrs {
+		indices = append(indices, pcr)
+	}
+	sort.Ints(indices)
+
+	var values []string
+	for _, pcr := range indices {
+		values = append(values, fmt.Sprintf("pcr:%d:%x", pcr, pcrs[pcr]))
+	}
+	return values
+}
+
+func verifySignature(publicKey crypto.PublicKey, data []byte, sig *tpm2.Signature) error {
+	var hashAlg tpm2.Algorithm
+	switch {
+	case sig.RSA != nil:
+		hashAlg = sig.RSA.HashAlg
+	case sig.ECC != nil:
+		hashAlg = sig.ECC.HashAlg
+	}
+	hash, ok := signatureHashes[hashAlg]
+	if !ok {
+		return errs.New("unsupported signature hash algorithm %#x", uint16(hashAlg))
+	}
+	h := hash.New()
+	h.Write(data)
+	digest := h.Sum(nil)
+
+	var err error
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if sig.RSA == nil {
+			return errs.New("expected RSA signature for RSA key")
+		}
+		switch sig.Alg {
+		case tpm2.AlgRSASSA:
+			err = rsa.VerifyPKCS1v15(key, hash, digest, sig.RSA.Signature)
+		case tpm2.AlgRSAPSS:
+			err = rsa.VerifyPSS(key, hash, digest, sig.RSA.Signature, nil)
+		default:
+			return errs.New("unsupported RSA signature scheme %#x", uint16(sig.Alg))
+		}
+	case *ecdsa.PublicKey:
+		if sig.ECC == nil {
+			return errs.New("expected ECDSA signature for ECDSA key")
+		}
+		if !ecdsa.Verify(key, digest, sig.ECC.R, sig.ECC.S) {
+			err = errs.New("ECDSA verification failed")
+		}
+	default:
+		return errs.New("unsupported DevID public key type %T", publicKey)
+	}
+	if err != nil {
+		return errs.New("quote signature verification failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package tpmdevid
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spiffe/spire/test/fakes/fakedevid"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	nonce = []byte("NONCE")
+	pcrs  = map[int][]byte{
+		0: bytes.Repeat([]byte{0x00}, 32),
+		7: bytes.Repeat([]byte{0x07}, 32),
+	}
+)
+
+func TestVerifyQuote(t *testing.T) {
+	tpm := fakedevid.New(t)
+
+	makeResponse := func(quote []byte, sig *tpm2.Signature, pcrs map[int][]byte) *Response {
+		signature, err := EncodeSignature(sig)
+		require.NoError(t, err)
+		return &Response{
+			Quote:     quote,
+			Signature: signature,
+			PCRs:      pcrs,
+		}
+	}
+
+	// success
+	quote, sig := tpm.Quote(nonce, pcrs)
+	require.NoError(t, VerifyQuote(tpm.DevID.PublicKey, makeResponse(quote, sig, pcrs), nonce))
+
+	// malformed signature
+	err := VerifyQuote(tpm.DevID.PublicKey, &Response{Quote: quote}, nonce)
+	require.Contains(t, err.Error(), "unable to decode signature")
+
+	// signed by another key
+	other := fakedevid.New(t)
+	err = VerifyQuote(tpm.DevID.PublicKey, makeResponse(quote, other.Sign(quote), pcrs), nonce)
+	require.EqualError(t, err, "quote signature verification failed: ECDSA verification failed")
+
+	// not generated by the TPM
+	forged := fakedevid.MarshalQuote(0, tpm2.TagAttestQuote, nonce, tpm2.AlgSHA256, pcrs)
+	err = VerifyQuote(tpm.DevID.PublicKey, makeResponse(forged, tpm.Sign(forged), pcrs), nonce)
+	require.EqualError(t, err, "quote was not generated by a TPM")
+
+	// not a quote
+	forged = fakedevid.MarshalQuote(0xff544347, tpm2.TagAttestCertify, nonce, tpm2.AlgSHA256, pcrs)
+	err = VerifyQuote(tpm.DevID.PublicKey, makeResponse(forged, tpm.Sign(forged), pcrs), nonce)
+	require.Contains(t, err.Error(), "unable to decode quote")
+
+	// wrong nonce
+	err = VerifyQuote(tpm.DevID.PublicKey, makeResponse(quote, sig, pcrs), []byte("OTHER"))
+	require.EqualError(t, err, "quote does not include the challenge nonce")
+
+	// wrong PCR bank
+	forged = fakedevid.MarshalQuote(0xff544347, tpm2.TagAttestQuote, nonce, tpm2.AlgSHA1, pcrs)
+	err = VerifyQuote(tpm.DevID.PublicKey, makeResponse(forged, tpm.Sign(forged), pcrs), nonce)
+	require.EqualError(t, err, "unexpected PCR bank 0x4")
+
+	// reported PCRs do not match the quoted selection
+	err = VerifyQuote(tpm.DevID.PublicKey, makeResponse(quote, sig, map[int][]byte{0: pcrs[0]}), nonce)
+	require.EqualError(t, err, "quoted PCRs do not match reported PCRs")
+	err = VerifyQuote(tpm.DevID.PublicKey, makeResponse(quote, sig, map[int][]byte{0: pcrs[0], 1: pcrs[7]}), nonce)
+	require.EqualError(t, err, "quoted PCRs do not match reported PCRs")
+
+	// reported PCR values do not match the quoted digest
+	err = VerifyQuote(tpm.DevID.PublicKey, makeResponse(quote, sig, map[int][]byte{0: pcrs[7], 7: pcrs[0]}), nonce)
+	require.EqualError(t, err, "PCR values do not match quote digest")
+}
+
+func TestPCRSelectorValues(t *testing.T) {
+	require.Equal(t, []string{
+		"pcr:0:0000000000000000000000000000000000000000000000000000000000000000",
+		"pcr:7:0707070707070707070707070707070707070707070707070707070707070707",
+	}, PCRSelectorValues(pcrs))
+}
+
+func TestAgentID(t *testing.T) {
+	tpm := fakedevid.New(t)
+	sum := sha1.Sum(tpm.DevID.Raw)
+	require.Equal(t, "spiffe://example.org/spire/agent/tpm_devid/"+hex.EncodeToString(sum[:]), AgentID("example.org", tpm.DevID))
+}
@@ -0,0 +1,43 @@
+package vsphere
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	PluginName = "vsphere"
+)
+
+var (
+	reUUID = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+)
+
+type AttestationData struct {
+	// BIOSUUID is the BIOS UUID of the virtual machine, as presented to the
+	// guest
+	BIOSUUID string `json:"bios_uuid"`
+}
+
+func AgentID(trustDomain string, biosUUID string) string {
+	u := url.URL{
+		Scheme: "spiffe",
+		Host:   trustDomain,
+		Path:   path.Join("spire", "agent", PluginName, biosUUID),
+	}
+	return u.String()
+}
+
+// NormalizeUUID lowercases the UUID and makes sure it is well formed. The
+// guest and vCenter do not agree on the case of the UUID.
+func NormalizeUUID(s string) (string, error) {
+	uuid := strings.ToLower(strings.TrimSpace(s))
+	if !reUUID.MatchString(uuid) {
+		return "", errs.New("malformed UUID %q", s)
+	}
+	return uuid, nil
+}
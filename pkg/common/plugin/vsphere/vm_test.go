@@ -0,0 +1,23 @@
+package vsphere
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentID(t *testing.T) {
+	require.Equal(t, "spiffe://example.org/spire/agent/vsphere/4210b1a2-3c4d-5e6f-7a8b-9c0d1e2f3a4b", AgentID("example.org", "4210b1a2-3c4d-5e6f-7a8b-9c0d1e2f3a4b"))
+}
+
+func TestNormalizeUUID(t *testing.T) {
+	uuid, err := NormalizeUUID(" 4210B1A2-3C4D-5E6F-7A8B-9C0D1E2F3A4B\n")
+	require.NoError(t, err)
+	require.Equal(t, "4210b1a2-3c4d-5e6f-7a8b-9c0d1e2f3a4b", uuid)
+
+	_, err = NormalizeUUID("blah")
+	require.EqualError(t, err, `malformed UUID "blah"`)
+
+	_, err = NormalizeUUID("4210b1a2-3c4d-5e6f-7a8b-9c0d1e2f3a4b/../x")
+	require.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package selector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spiffe/spire/proto/common"
+)
+
+const (
+	// DefaultHookTimeout is how long the hook program may run when no
+	// timeout is configured
+	DefaultHookTimeout = 5 * time.Second
+
+	// HookKindWorkload and HookKindNode tell the hook program which kind of
+	// selectors it is processing
+	HookKindWorkload = "workload"
+	HookKindNode     = "node"
+
+	// limits how much of the program's standard error is reported
+	maxHookStderr = 1024
+)
+
+// HookConfig configures a Hook
+type HookConfig struct {
+	// Command is the program to run, followed by its arguments
+	Command []string
+
+	// Timeout is how long the program may run (default: DefaultHookTimeout)
+	Timeout time.Duration
+}
+
+// Hook post-processes the selectors produced by attestors with an external
+// program, before they are used to match registration entries. The program
+// is run for every attestation. It reads a JSON document describing the
+// attestation (see HookInput) on standard input and writes a JSON document
+// holding the selectors to use instead (see HookOutput) on standard output.
+// It may filter, rename or add selectors.
+type Hook struct {
+	command []string
+	timeout time.Duration
+}
+
+// HookInput is the document written to the hook program
+type HookInput struct {
+	Kind string `json:"kind"`
+
+	// PID is the process ID of the workload, for workload selectors
+	PID int32 `json:"pid,omitempty"`
+
+	// SpiffeID and AttestationType identify the agent and how it attested,
+	// for node selectors
+	SpiffeID        string `json:"spiffe_id,omitempty"`
+	AttestationType string `json:"attestation_type,omitempty"`
+
+	Selectors []HookSelector `json:"selectors"`
+}
+
+// HookOutput is the document read from the hook program
+type HookOutput struct {
+	Selectors []HookSelector `json:"selectors"`
+}
+
+type HookSelector struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func NewHook(config HookConfig) *Hook {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	return &Hook{
+		command: config.Command,
+		timeout: timeout,
+	}
+}
+
+// Process runs the hook program with the selectors of the attestation
+// described by input and returns the selectors it produced. A nil Hook
+// returns the selectors unchanged.
+func (h *Hook) Process(ctx context.Context, input HookInput, selectors []*common.Selector) ([]*common.Selector, error) {
+	if h == nil {
+		return selectors, nil
+	}
+	if len(h.command) == 0 {
+		return nil, errors.New("selector hook: no command")
+	}
+
+	input.Selectors = make([]HookSelector, 0, len(selectors))
+	for _, s := range selectors {
+		input.Selectors = append(input.Selectors, HookSelector{Type: s.Type, Value: s.Value})
+	}
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("selector hook: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, h.command[0], h.command[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("selector hook: timed out after %s", h.timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > maxHookStderr {
+			msg = msg[:maxHookStderr]
+		}
+		if msg != "" {
+			return nil, fmt.Errorf("selector hook: %v: %s", err, msg)
+		}
+		return nil, fmt.Errorf("selector hook: %v", err)
+	}
+
+	output := new(HookOutput)
+	if err := json.Unmarshal(stdout.Bytes(), output); err != nil {
+		return nil, fmt.Errorf("selector hook: malformed output: %v", err)
+	}
+
+	processed := make([]*common.Selector, 0, len(output.Selectors))
+	for _, s := range output.Selectors {
+		if s.Type == "" || s.Value == "" {
+			return nil, fmt.Errorf("selector hook: selector %q:%q is missing a type or value", s.Type, s.Value)
+		}
+		processed = append(processed, &common.Selector{Type: s.Type, Value: s.Value})
+	}
+	return processed, nil
+}
@@ -0,0 +1,124 @@
+package selector
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/proto/common"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	hookSelectors = []*common.Selector{
+		{Type: "unix", Value: "uid:1000"},
+		{Type: "k8s", Value: "ns:default"},
+	}
+)
+
+func TestHookNil(t *testing.T) {
+	var h *Hook
+	selectors, err := h.Process(context.Background(), HookInput{Kind: HookKindWorkload}, hookSelectors)
+	require.NoError(t, err)
+	require.Equal(t, hookSelectors, selectors)
+}
+
+func TestHookInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selector-hook-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	inputPath := filepath.Join(dir, "input.json")
+
+	// the program echoes the selectors back, keeping a copy of its input
+	h := NewHook(HookConfig{Command: []string{"tee", inputPath}})
+	selectors, err := h.Process(context.Background(), HookInput{
+		Kind:            HookKindNode,
+		SpiffeID:        "spiffe://example.org/spire/agent/join_token/abc",
+		AttestationType: "join_token",
+	}, hookSelectors)
+	require.NoError(t, err)
+	require.Equal(t, hookSelectors, selectors)
+
+	input, err := ioutil.ReadFile(inputPath)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"kind": "node",
+		"spiffe_id": "spiffe://example.org/spire/agent/join_token/abc",
+		"attestation_type": "join_token",
+		"selectors": [
+			{"type": "unix", "value": "uid:1000"},
+			{"type": "k8s", "value": "ns:default"}
+		]
+	}`, string(input))
+}
+
+func TestHookProcess(t *testing.T) {
+	testCases := []struct {
+		name      string
+		command   []string
+		timeout   time.Duration
+		selectors []*common.Selector
+		err       string
+	}{
+		{
+			name:    "rename",
+			command: []string{"sed", `s/"unix"/"posix"/`},
+			selectors: []*common.Selector{
+				{Type: "posix", Value: "uid:1000"},
+				{Type: "k8s", Value: "ns:default"},
+			},
+		},
+		{
+			name:      "filter all",
+			command:   []string{"sh", "-c", `cat >/dev/null; echo '{"selectors": []}'`},
+			selectors: []*common.Selector{},
+		},
+		{
+			name:    "program fails",
+			command: []string{"sh", "-c", "echo denied >&2; exit 3"},
+			err:     "selector hook: exit status 3: denied",
+		},
+		{
+			name:    "program not found",
+			command: []string{"/does/not/exist"},
+			err:     "selector hook: fork/exec /does/not/exist: no such file or directory",
+		},
+		{
+			name:    "program times out",
+			command: []string{"sleep", "5"},
+			timeout: 10 * time.Millisecond,
+			err:     "selector hook: timed out after 10ms",
+		},
+		{
+			name:    "malformed output",
+			command: []string{"echo", "nope"},
+			err:     "selector hook: malformed output: invalid character 'o' in literal null (expecting 'u')",
+		},
+		{
+			name:    "selector without value",
+			command: []string{"echo", `{"selectors": [{"type": "unix"}]}`},
+			err:     `selector hook: selector "unix":"" is missing a type or value`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			h := NewHook(HookConfig{
+				Command: testCase.command,
+				Timeout: testCase.timeout,
+			})
+			selectors, err := h.Process(context.Background(), HookInput{Kind: HookKindWorkload, PID: 123}, hookSelectors)
+			if testCase.err != "" {
+				require.EqualError(t, err, testCase.err)
+				require.Nil(t, selectors)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.selectors, selectors)
+		})
+	}
+}
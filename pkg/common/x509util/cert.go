@@ -1,6 +1,7 @@
 package x509util
 
 import (
+	"bytes"
 	"context"
 	"crypto"
 	"crypto/rand"
@@ -43,9 +44,69 @@ func CreateCertificate(ctx context.Context, km keymanager.KeyManager, template,
 	return cert, nil
 }
 
+// CreateCertificates creates a certificate for each template, signed by the
+// parent key in a single key manager batch. The public key of each
+// certificate is taken from its template.
+func CreateCertificates(ctx context.Context, km keymanager.KeyManager, templates []*x509.Certificate, parent *x509.Certificate, parentKeyId string) ([]*x509.Certificate, error) {
+	parentPublicKey := parent.PublicKey
+	if parentPublicKey == nil {
+		var err error
+		parentPublicKey, err = cryptoutil.GetPublicKey(ctx, km, parentKeyId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	certs := make([]*x509.Certificate, len(templates))
+	err := cryptoutil.SignBatch(ctx, km, parentKeyId, parentPublicKey, len(templates), func(i int, signer crypto.Signer) error {
+		certDER, err := x509.CreateCertificate(rand.Reader, templates[i], parent, templates[i].PublicKey, signer)
+		if err != nil {
+			return err
+		}
+		certs[i], err = x509.ParseCertificate(certDER)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
 func DERFromCertificates(certs []*x509.Certificate) (derBytes []byte) {
 	for _, cert := range certs {
 		derBytes = append(derBytes, cert.Raw...)
 	}
 	return derBytes
 }
+
+// IsSelfSigned returns true if the certificate is signed by its own key
+func IsSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// SplitRoots separates the self-signed certificates of a chain from the rest,
+// preserving their order.
+func SplitRoots(chain []*x509.Certificate) (intermediates, roots []*x509.Certificate) {
+	for _, cert := range chain {
+		if IsSelfSigned(cert) {
+			roots = append(roots, cert)
+		} else {
+			intermediates = append(intermediates, cert)
+		}
+	}
+	return intermediates, roots
+}
+
+// AppendUniqueCertificates appends the certificates not already present
+func AppendUniqueCertificates(certs []*x509.Certificate, more ...*x509.Certificate) []*x509.Certificate {
+next:
+	for _, cert := range more {
+		for _, existing := range certs {
+			if existing.Equal(cert) {
+				continue next
+			}
+		}
+		certs = append(certs, cert)
+	}
+	return certs
+}
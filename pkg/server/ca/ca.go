@@ -35,8 +35,17 @@ type serverCAConfig struct {
 	CASubject   pkix.Name
 }
 
+// X509SVIDParams are the parameters for signing one of a batch of X509-SVIDs
+type X509SVIDParams struct {
+	CSR []byte
+	TTL time.Duration
+}
+
 type ServerCA interface {
 	SignX509SVID(ctx context.Context, csrDER []byte, ttl time.Duration) ([]*x509.Certificate, error)
+	// SignX509SVIDs signs a batch of X509-SVIDs with a single key manager
+	// call, returning the certificate chains in the same order as params
+	SignX509SVIDs(ctx context.Context, params []X509SVIDParams) ([][]*x509.Certificate, error)
 	SignX509CASVID(ctx context.Context, csrDER []byte, ttl time.Duration) ([]*x509.Certificate, error)
 	SignJWTSVID(ctx context.Context, jsr *node.JSR) (string, error)
 }
@@ -79,6 +88,49 @@ func (ca *serverCA) SignX509SVID(ctx context.Context, csrDER []byte, ttl time.Du
 		return nil, errors.New("no X509-SVID keypair available")
 	}
 
+	template, err := ca.x509SVIDTemplate(kp, csrDER, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	km := catalog.KeyManagerFor(ca.c.Catalog.KeyManagers(), catalog.KeyPurposeX509CA)
+	cert, err := x509util.CreateCertificate(ctx, km, template, kp.x509CA.chain[0], kp.X509CAKeyID(), template.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return ca.x509SVIDChain(kp, cert), nil
+}
+
+func (ca *serverCA) SignX509SVIDs(ctx context.Context, params []X509SVIDParams) ([][]*x509.Certificate, error) {
+	kp := ca.getKeypairSet()
+	if kp == nil || kp.x509CA == nil || len(kp.x509CA.chain) < 1 {
+		return nil, errors.New("no X509-SVID keypair available")
+	}
+
+	templates := make([]*x509.Certificate, 0, len(params))
+	for _, p := range params {
+		template, err := ca.x509SVIDTemplate(kp, p.CSR, p.TTL)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	km := catalog.KeyManagerFor(ca.c.Catalog.KeyManagers(), catalog.KeyPurposeX509CA)
+	certs, err := x509util.CreateCertificates(ctx, km, templates, kp.x509CA.chain[0], kp.X509CAKeyID())
+	if err != nil {
+		return nil, err
+	}
+
+	svids := make([][]*x509.Certificate, 0, len(certs))
+	for _, cert := range certs {
+		svids = append(svids, ca.x509SVIDChain(kp, cert))
+	}
+	return svids, nil
+}
+
+func (ca *serverCA) x509SVIDTemplate(kp *keypairSet, csrDER []byte, ttl time.Duration) (*x509.Certificate, error) {
 	now := ca.hooks.now()
 	if ttl <= 0 {
 		ttl = ca.c.DefaultTTL
@@ -91,17 +143,12 @@ func (ca *serverCA) SignX509SVID(ctx context.Context, csrDER []byte, ttl time.Du
 
 	serialNumber := big.NewInt(atomic.AddInt64(&ca.x509sn, 1))
 
-	template, err := CreateX509SVIDTemplate(csrDER, ca.c.TrustDomain.Host, notBefore, notAfter, serialNumber)
-	if err != nil {
-		return nil, err
-	}
-
-	km := ca.c.Catalog.KeyManagers()[0]
-	cert, err := x509util.CreateCertificate(ctx, km, template, kp.x509CA.chain[0], kp.X509CAKeyID(), template.PublicKey)
-	if err != nil {
-		return nil, err
-	}
+	return CreateX509SVIDTemplate(csrDER, ca.c.TrustDomain.Host, notBefore, notAfter, serialNumber)
+}
 
+// x509SVIDChain records a newly signed X509-SVID and returns its certificate
+// chain
+func (ca *serverCA) x509SVIDChain(kp *keypairSet, cert *x509.Certificate) []*x509.Certificate {
 	spiffeID := cert.URIs[0].String()
 	ca.c.Log.Debugf("Signed x509 SVID %q (expires %s)", spiffeID, cert.NotAfter.Format(time.RFC3339))
 	ca.c.Metrics.IncrCounterWithLabels([]string{"ca", "sign", "x509_svid"}, 1, []telemetry.Label{
@@ -115,7 +162,7 @@ func (ca *serverCA) SignX509SVID(ctx context.Context, csrDER []byte, ttl time.Du
 	// cert all the way back to the signing root of the keypair. if an
 	// upstream ca was used, and upstream_bundle is true, this will include
 	// the upstream certificates, otherwise the root will be the server CA.
-	return append([]*x509.Certificate{cert}, kp.x509CA.chain...), nil
+	return append([]*x509.Certificate{cert}, kp.x509CA.chain...)
 }
 
 func (ca *serverCA) SignX509CASVID(ctx context.Context, csrDER []byte, ttl time.Duration) ([]*x509.Certificate, error) {
@@ -145,7 +192,7 @@ func (ca *serverCA) SignX509CASVID(ctx context.Context, csrDER []byte, ttl time.
 	// replace template subject to use configured ca subject
 	template.Subject = ca.c.CASubject
 
-	km := ca.c.Catalog.KeyManagers()[0]
+	km := catalog.KeyManagerFor(ca.c.Catalog.KeyManagers(), catalog.KeyPurposeX509CA)
 	cert, err := x509util.CreateCertificate(ctx, km, template, kp.x509CA.chain[0], kp.X509CAKeyID(), template.PublicKey)
 	if err != nil {
 		return nil, err
@@ -186,7 +233,7 @@ func (ca *serverCA) SignJWTSVID(ctx context.Context, jsr *node.JSR) (string, err
 		expiresAt = kp.jwtSigningKey.notAfter
 	}
 
-	km := ca.c.Catalog.KeyManagers()[0]
+	km := catalog.KeyManagerFor(ca.c.Catalog.KeyManagers(), catalog.KeyPurposeJWT)
 	signer := cryptoutil.NewKeyManagerSigner(km, kp.JWTSignerKeyID(), kp.jwtSigningKey.publicKey)
 	token, err := jwtsvid.SignToken(jsr.SpiffeId, jsr.Audience, expiresAt, signer, kp.jwtSigningKey.Kid)
 	if err != nil {
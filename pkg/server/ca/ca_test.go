@@ -1,12 +1,14 @@
 package ca
 
 import (
+	"context"
 	"crypto"
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"math/big"
 	"net/url"
+	"sync"
 	"testing"
 	"time"
 
@@ -39,9 +41,11 @@ func TestCA(t *testing.T) {
 type CATestSuite struct {
 	suite.Suite
 
-	signer crypto.Signer
-	now    time.Time
-	ca     *serverCA
+	signer  crypto.Signer
+	now     time.Time
+	km      *memory.KeyManager
+	catalog *fakeservercatalog.Catalog
+	ca      *serverCA
 }
 
 func (s *CATestSuite) SetupTest() {
@@ -51,6 +55,7 @@ func (s *CATestSuite) SetupTest() {
 	s.now = time.Now().Truncate(time.Second).UTC()
 
 	km := memory.New()
+	s.km = km
 	x509CASigner, err := cryptoutil.GenerateKeyAndSigner(ctx, km, "x509-CA-FOO", keymanager.KeyType_EC_P256)
 	s.Require().NoError(err)
 
@@ -69,6 +74,7 @@ func (s *CATestSuite) SetupTest() {
 
 	catalog := fakeservercatalog.New()
 	catalog.SetKeyManagers(km)
+	s.catalog = catalog
 
 	logger, err := log.NewLogger("DEBUG", "")
 	s.Require().NoError(err)
@@ -138,6 +144,40 @@ func (s *CATestSuite) TestSignX509SVIDUsesTTLIfSpecified() {
 	s.Require().Equal(s.now.Add(time.Minute+time.Second), svid[0].NotAfter)
 }
 
+func (s *CATestSuite) TestSignX509SVIDs() {
+	km := &countingKeyManager{KeyManager: s.km}
+	s.catalog.SetKeyManagers(km)
+
+	svids, err := s.ca.SignX509SVIDs(ctx, []X509SVIDParams{
+		{CSR: s.generateCSR("example.org")},
+		{CSR: s.generateCSR("example.org"), TTL: time.Minute + time.Second},
+		{CSR: s.generateCSR("example.org"), TTL: time.Hour},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(svids, 3)
+
+	// the SVIDs are signed with a single batch call
+	s.Require().Equal(0, km.signDataCalls)
+	s.Require().Equal(1, km.signDataBatchCalls)
+
+	kp := s.ca.getKeypairSet()
+	for _, svid := range svids {
+		s.Require().Len(svid, 2)
+		s.Require().NoError(svid[0].CheckSignatureFrom(kp.x509CA.chain[0]))
+	}
+	s.Require().Equal(s.now.Add(time.Minute), svids[0][0].NotAfter)
+	s.Require().Equal(s.now.Add(time.Minute+time.Second), svids[1][0].NotAfter)
+	s.Require().Equal(s.now.Add(10*time.Minute), svids[2][0].NotAfter)
+}
+
+func (s *CATestSuite) TestSignX509SVIDsValidatesCSRs() {
+	_, err := s.ca.SignX509SVIDs(ctx, []X509SVIDParams{
+		{CSR: s.generateCSR("example.org")},
+		{CSR: s.generateCSR("foo.com")},
+	})
+	s.Require().EqualError(err, `"spiffe://foo.com" does not belong to trust domain "example.org"`)
+}
+
 func (s *CATestSuite) TestSignX509SVIDCapsTTLToKeypairTTL() {
 	svid, err := s.ca.SignX509SVID(ctx, s.generateCSR("example.org"), time.Hour)
 	s.Require().NoError(err)
@@ -280,3 +320,26 @@ func (s *CATestSuite) generateJSR(trustDomain string, ttl time.Duration) *node.J
 func makeSpiffeID(trustDomain string) *url.URL {
 	return &url.URL{Scheme: "spiffe", Host: trustDomain}
 }
+
+// countingKeyManager counts the signing calls made to a key manager
+type countingKeyManager struct {
+	keymanager.KeyManager
+
+	mu                 sync.Mutex
+	signDataCalls      int
+	signDataBatchCalls int
+}
+
+func (km *countingKeyManager) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error) {
+	km.mu.Lock()
+	km.signDataCalls++
+	km.mu.Unlock()
+	return km.KeyManager.SignData(ctx, req)
+}
+
+func (km *countingKeyManager) SignDataBatch(ctx context.Context, req *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	km.mu.Lock()
+	km.signDataBatchCalls++
+	km.mu.Unlock()
+	return km.KeyManager.SignDataBatch(ctx, req)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -34,10 +35,12 @@ import (
 )
 
 const (
-	DefaultSVIDTTL  = time.Hour
-	DefaultCATTL    = 24 * time.Hour
-	backdate        = time.Second * 10
-	safetyThreshold = 24 * time.Hour
+	DefaultSVIDTTL    = time.Hour
+	DefaultCATTL      = 24 * time.Hour
+	DefaultCAKeyType  = keymanager.KeyType_EC_P384
+	DefaultJWTKeyType = keymanager.KeyType_EC_P256
+	backdate          = time.Second * 10
+	safetyThreshold   = 24 * time.Hour
 )
 
 type ManagerConfig struct {
@@ -47,6 +50,8 @@ type ManagerConfig struct {
 	SVIDTTL        time.Duration
 	CATTL          time.Duration
 	CASubject      pkix.Name
+	CAKeyType      keymanager.KeyType
+	JWTKeyType     keymanager.KeyType
 	CertsPath      string
 	Log            logrus.FieldLogger
 	Metrics        telemetry.Metrics
@@ -126,6 +131,12 @@ func NewManager(c *ManagerConfig) *manager {
 	if c.CATTL <= 0 {
 		c.CATTL = DefaultCATTL
 	}
+	if c.CAKeyType == keymanager.KeyType_UNSPECIFIED_KEY_TYPE {
+		c.CAKeyType = DefaultCAKeyType
+	}
+	if c.JWTKeyType == keymanager.KeyType_UNSPECIFIED_KEY_TYPE {
+		c.JWTKeyType = DefaultJWTKeyType
+	}
 
 	m := &manager{
 		c: c,
@@ -345,8 +356,8 @@ func (m *manager) prepareKeypairSet(ctx context.Context, kps *keypairSet) (err e
 	notBefore := now.Add(-backdate)
 	notAfter := now.Add(m.c.CATTL)
 
-	km := m.c.Catalog.KeyManagers()[0]
-	x509CASigner, err := cryptoutil.GenerateKeyAndSigner(ctx, km, kps.X509CAKeyID(), keymanager.KeyType_EC_P384)
+	x509CAKM := catalog.KeyManagerFor(m.c.Catalog.KeyManagers(), catalog.KeyPurposeX509CA)
+	x509CASigner, err := cryptoutil.GenerateKeyAndSigner(ctx, x509CAKM, kps.X509CAKeyID(), m.c.CAKeyType)
 	if err != nil {
 		return err
 	}
@@ -385,7 +396,8 @@ func (m *manager) prepareKeypairSet(ctx context.Context, kps *keypairSet) (err e
 		trustBundle = certChainWithRoot
 	}
 
-	jwtSigningKeyPKIX, err := cryptoutil.GenerateKeyRaw(ctx, km, kps.JWTSignerKeyID(), keymanager.KeyType_EC_P256)
+	jwtKM := catalog.KeyManagerFor(m.c.Catalog.KeyManagers(), catalog.KeyPurposeJWT)
+	jwtSigningKeyPKIX, err := cryptoutil.GenerateKeyRaw(ctx, jwtKM, kps.JWTSignerKeyID(), m.c.JWTKeyType)
 	if err != nil {
 		return err
 	}
@@ -422,8 +434,12 @@ func (m *manager) loadKeypairSets(ctx context.Context) error {
 		return nil
 	}
 
-	km := m.c.Catalog.KeyManagers()[0]
-	keys, err := loadKeyManagerKeys(ctx, km)
+	// the X509 CA and JWT signing keys may live in different key managers
+	x509CAKeys, err := loadKeyManagerKeys(ctx, catalog.KeyManagerFor(m.c.Catalog.KeyManagers(), catalog.KeyPurposeX509CA))
+	if err != nil {
+		return err
+	}
+	jwtKeys, err := loadKeyManagerKeys(ctx, catalog.KeyManagerFor(m.c.Catalog.KeyManagers(), catalog.KeyPurposeJWT))
 	if err != nil {
 		return err
 	}
@@ -453,10 +469,10 @@ func (m *manager) loadKeypairSets(ctx context.Context) error {
 		kps.Reset()
 
 		x509CA := x509CAs[kps.X509CAKeyID()]
-		x509CAKMKey := keys[kps.X509CAKeyID()]
+		x509CAKMKey := x509CAKeys[kps.X509CAKeyID()]
 		x509CAOK := x509CA != nil && x509CAKMKey != nil && certMatchesKey(x509CA.cert(), x509CAKMKey)
 		jwtSigningKey := publicKeys[kps.JWTSignerKeyID()]
-		jwtSigningKMKey := keys[kps.JWTSignerKeyID()]
+		jwtSigningKMKey := jwtKeys[kps.JWTSignerKeyID()]
 		jwtSigningKeyOK := jwtSigningKey != nil && jwtSigningKMKey != nil && publicKeyEqual(jwtSigningKey.publicKey, jwtSigningKMKey)
 
 		if !(x509CAOK && jwtSigningKeyOK) {
@@ -672,9 +688,14 @@ func GenerateServerCACSR(signer crypto.Signer, trustDomain string, subject pkix.
 		Host:   trustDomain,
 	}
 
+	signatureAlgorithm := x509.ECDSAWithSHA256
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		signatureAlgorithm = x509.PureEd25519
+	}
+
 	template := x509.CertificateRequest{
 		Subject:            subject,
-		SignatureAlgorithm: x509.ECDSAWithSHA256,
+		SignatureAlgorithm: signatureAlgorithm,
 		URIs:               []*url.URL{spiffeID},
 	}
 
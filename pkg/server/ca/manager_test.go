@@ -2,6 +2,11 @@ package ca
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/x509"
 	"io/ioutil"
 	"net/url"
@@ -12,11 +17,14 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/spiffe/spire/pkg/common/jwtsvid"
 	"github.com/spiffe/spire/pkg/common/log"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager/memory"
+	"github.com/spiffe/spire/proto/api/node"
 	"github.com/spiffe/spire/proto/common"
 	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/spiffe/spire/proto/server/keymanager"
 	"github.com/spiffe/spire/test/fakes/fakedatastore"
 	"github.com/spiffe/spire/test/fakes/fakeservercatalog"
 	"github.com/spiffe/spire/test/fakes/fakeupstreamca"
@@ -209,6 +217,70 @@ func (m *ManagerTestSuite) TestSelfSigning() {
 	m.requireBundleJWTSigningKeys(a.jwtSigningKey)
 }
 
+func (m *ManagerTestSuite) TestSelfSigningWithEd25519() {
+	m.m.c.CAKeyType = keymanager.KeyType_ED25519
+	m.m.c.JWTKeyType = keymanager.KeyType_ED25519
+	m.Require().NoError(m.m.Initialize(ctx))
+
+	a := m.m.getCurrentKeypairSet()
+	m.Require().Equal(x509.PureEd25519, a.x509CA.cert().SignatureAlgorithm)
+	m.Require().IsType(ed25519.PublicKey{}, a.x509CA.cert().PublicKey)
+	m.Require().IsType(ed25519.PublicKey{}, a.jwtSigningKey.publicKey)
+	m.requireBundleRootCAs(a.x509CA.cert())
+	m.requireBundleJWTSigningKeys(a.jwtSigningKey)
+
+	token, err := m.m.CA().SignJWTSVID(ctx, &node.JSR{
+		SpiffeId: "spiffe://example.org/workload",
+		Audience: []string{"AUDIENCE"},
+	})
+	m.Require().NoError(err)
+
+	keyStore := jwtsvid.NewKeyStore(map[string]map[string]crypto.PublicKey{
+		"spiffe://example.org": {
+			a.jwtSigningKey.Kid: a.jwtSigningKey.publicKey,
+		},
+	})
+	spiffeID, _, err := jwtsvid.ValidateToken(ctx, token, keyStore, []string{"AUDIENCE"})
+	m.Require().NoError(err)
+	m.Require().Equal("spiffe://example.org/workload", spiffeID)
+}
+
+func (m *ManagerTestSuite) TestKeyManagerPerKeyPurpose() {
+	x509CAKeyManager := memory.New()
+	jwtKeyManager := memory.New()
+	m.catalog.SetKeyManagers()
+	m.catalog.AddKeyManagerWithPurposes(x509CAKeyManager, "x509_ca")
+	m.catalog.AddKeyManagerWithPurposes(jwtKeyManager, "jwt")
+
+	m.Require().NoError(m.m.Initialize(ctx))
+	current1 := m.m.getCurrentKeypairSet()
+	m.requireValidKeypairSet(current1)
+	m.requireKeyIDs(x509CAKeyManager, "x509-CA-A")
+	m.requireKeyIDs(jwtKeyManager, "JWT-Signer-A")
+
+	// keys from both key managers are used when signing
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	m.Require().NoError(err)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		URIs: []*url.URL{makeSpiffeID("example.org")},
+	}, key)
+	m.Require().NoError(err)
+	svid, err := m.m.CA().SignX509SVID(ctx, csr, 0)
+	m.Require().NoError(err)
+	m.Require().NoError(svid[0].CheckSignatureFrom(current1.x509CA.cert()))
+	_, err = m.m.CA().SignJWTSVID(ctx, &node.JSR{
+		SpiffeId: "spiffe://example.org/workload",
+		Audience: []string{"AUDIENCE"},
+	})
+	m.Require().NoError(err)
+
+	// "reload" the manager and assert the keypairs are loaded from both
+	m.newManager()
+	m.Require().NoError(m.m.Initialize(ctx))
+	current2 := m.m.getCurrentKeypairSet()
+	m.requireKeypairSetKeysEqual(current1, current2)
+}
+
 func (m *ManagerTestSuite) TestUpstreamSigning() {
 	upstreamCA := fakeupstreamca.New(m.T(), fakeupstreamca.Config{
 		TrustDomain: "example.org",
@@ -387,6 +459,16 @@ func (m *ManagerTestSuite) TestPrune() {
 	m.requireBundleJWTSigningKeys(b.jwtSigningKey)
 }
 
+func (m *ManagerTestSuite) requireKeyIDs(km keymanager.KeyManager, expectedIDs ...string) {
+	resp, err := km.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	m.Require().NoError(err)
+	var ids []string
+	for _, publicKey := range resp.PublicKeys {
+		ids = append(ids, publicKey.Id)
+	}
+	m.Require().ElementsMatch(expectedIDs, ids)
+}
+
 func (m *ManagerTestSuite) requireBundleRootCAs(expectedCerts ...*x509.Certificate) {
 	var expected []*common.Certificate
 	for _, expectedCert := range expectedCerts {
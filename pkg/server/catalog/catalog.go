@@ -3,15 +3,32 @@ package catalog
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/server/plugin/datastore/dynamodb"
+	"github.com/spiffe/spire/pkg/server/plugin/datastore/etcd"
 	"github.com/spiffe/spire/pkg/server/plugin/datastore/sql"
+	alibaba_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/alibaba"
 	aws_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/aws"
 	azure_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/azure"
+	digitalocean_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/digitalocean"
+	equinix_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/equinix"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/gcp"
+	github_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/github"
+	ibmcloud_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/ibmcloud"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/jointoken"
 	k8s_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/k8s"
+	nitro_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/nitro"
+	nomad_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/nomad"
+	oci_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/oci"
+	oidc_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/oidc"
+	openstack_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/openstack"
+	sevsnp_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/sevsnp"
+	sgx_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/sgx"
+	tpmdevid_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/tpmdevid"
+	vsphere_na "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/vsphere"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/x509pop"
 	aws_nr "github.com/spiffe/spire/pkg/server/plugin/noderesolver/aws"
 	azure_nr "github.com/spiffe/spire/pkg/server/plugin/noderesolver/azure"
@@ -24,9 +41,20 @@ import (
 
 	goplugin "github.com/hashicorp/go-plugin"
 	common "github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	keymanager_azurekeyvault "github.com/spiffe/spire/pkg/server/plugin/keymanager/azurekeyvault"
 	keymanager_disk "github.com/spiffe/spire/pkg/server/plugin/keymanager/disk"
+	keymanager_gcpkms "github.com/spiffe/spire/pkg/server/plugin/keymanager/gcpkms"
+	keymanager_kmip "github.com/spiffe/spire/pkg/server/plugin/keymanager/kmip"
 	keymanager_memory "github.com/spiffe/spire/pkg/server/plugin/keymanager/memory"
+	keymanager_pkcs11 "github.com/spiffe/spire/pkg/server/plugin/keymanager/pkcs11"
+	keymanager_remotesigner "github.com/spiffe/spire/pkg/server/plugin/keymanager/remotesigner"
+	keymanager_tpm "github.com/spiffe/spire/pkg/server/plugin/keymanager/tpm"
+	upstreamca_acme "github.com/spiffe/spire/pkg/server/plugin/upstreamca/acme"
 	upstreamca_disk "github.com/spiffe/spire/pkg/server/plugin/upstreamca/disk"
+	upstreamca_ejbca "github.com/spiffe/spire/pkg/server/plugin/upstreamca/ejbca"
+	upstreamca_gcpcas "github.com/spiffe/spire/pkg/server/plugin/upstreamca/gcpcas"
+	upstreamca_k8scsr "github.com/spiffe/spire/pkg/server/plugin/upstreamca/k8scsr"
 )
 
 const (
@@ -37,6 +65,15 @@ const (
 	KeyManagerType   = "KeyManager"
 )
 
+// Key purposes a KeyManager can be restricted to with the key_purposes
+// plugin configuration option
+const (
+	KeyPurposeX509CA = "x509_ca"
+	KeyPurposeJWT    = "jwt"
+)
+
+var keyPurposes = []string{KeyPurposeX509CA, KeyPurposeJWT}
+
 type Catalog interface {
 	DataStores() []*ManagedDataStore
 	NodeAttestors() []*ManagedNodeAttestor
@@ -56,15 +93,31 @@ var (
 
 	builtinPlugins = common.BuiltinPluginMap{
 		DataStoreType: {
-			"sql": datastore.NewBuiltIn(sql.New()),
+			"sql":      datastore.NewBuiltIn(sql.New()),
+			"dynamodb": datastore.NewBuiltIn(dynamodb.New()),
+			"etcd":     datastore.NewBuiltIn(etcd.New()),
 		},
 		NodeAttestorType: {
-			"aws_iid":    nodeattestor.NewBuiltIn(aws_na.NewIID()),
-			"join_token": nodeattestor.NewBuiltIn(jointoken.New()),
-			"gcp_iit":    nodeattestor.NewBuiltIn(gcp.NewIITAttestorPlugin()),
-			"x509pop":    nodeattestor.NewBuiltIn(x509pop.New()),
-			"azure_msi":  nodeattestor.NewBuiltIn(azure_na.NewMSIAttestorPlugin()),
-			"k8s_sat":    nodeattestor.NewBuiltIn(k8s_na.NewSATAttestorPlugin()),
+			"aws_iid":                nodeattestor.NewBuiltIn(aws_na.NewIID()),
+			"join_token":             nodeattestor.NewBuiltIn(jointoken.New()),
+			"gcp_iit":                nodeattestor.NewBuiltIn(gcp.NewIITAttestorPlugin()),
+			"x509pop":                nodeattestor.NewBuiltIn(x509pop.New()),
+			"azure_msi":              nodeattestor.NewBuiltIn(azure_na.NewMSIAttestorPlugin()),
+			"k8s_sat":                nodeattestor.NewBuiltIn(k8s_na.NewSATAttestorPlugin()),
+			"github_oidc":            nodeattestor.NewBuiltIn(github_na.NewOIDCAttestorPlugin()),
+			"aws_nitro":              nodeattestor.NewBuiltIn(nitro_na.New()),
+			"sgx_dcap":               nodeattestor.NewBuiltIn(sgx_na.New()),
+			"tpm_devid":              nodeattestor.NewBuiltIn(tpmdevid_na.New()),
+			"sev_snp":                nodeattestor.NewBuiltIn(sevsnp_na.New()),
+			"openstack":              nodeattestor.NewBuiltIn(openstack_na.New()),
+			"digitalocean_droplet":   nodeattestor.NewBuiltIn(digitalocean_na.New()),
+			"equinix_metal":          nodeattestor.NewBuiltIn(equinix_na.New()),
+			"vsphere":                nodeattestor.NewBuiltIn(vsphere_na.New()),
+			"oci_instance_principal": nodeattestor.NewBuiltIn(oci_na.New()),
+			"ibmcloud_vpc":           nodeattestor.NewBuiltIn(ibmcloud_na.New()),
+			"alibaba_ecs":            nodeattestor.NewBuiltIn(alibaba_na.New()),
+			"oidc":                   nodeattestor.NewBuiltIn(oidc_na.New()),
+			"nomad":                  nodeattestor.NewBuiltIn(nomad_na.New()),
 		},
 		NodeResolverType: {
 			"noop":      noderesolver.NewBuiltIn(noop.New()),
@@ -72,11 +125,21 @@ var (
 			"azure_msi": noderesolver.NewBuiltIn(azure_nr.NewMSIResolverPlugin()),
 		},
 		UpstreamCAType: {
-			"disk": upstreamca.NewBuiltIn(upstreamca_disk.New()),
+			"acme":    upstreamca.NewBuiltIn(upstreamca_acme.New()),
+			"disk":    upstreamca.NewBuiltIn(upstreamca_disk.New()),
+			"ejbca":   upstreamca.NewBuiltIn(upstreamca_ejbca.New()),
+			"gcp_cas": upstreamca.NewBuiltIn(upstreamca_gcpcas.New()),
+			"k8s_csr": upstreamca.NewBuiltIn(upstreamca_k8scsr.New()),
 		},
 		KeyManagerType: {
-			"disk":   keymanager.NewBuiltIn(keymanager_disk.New()),
-			"memory": keymanager.NewBuiltIn(keymanager_memory.New()),
+			"azure_key_vault": keymanager.NewBuiltIn(keymanager_azurekeyvault.New()),
+			"disk":            keymanager.NewBuiltIn(keymanager_disk.New()),
+			"gcpkms":          keymanager.NewBuiltIn(keymanager_gcpkms.New()),
+			"kmip":            keymanager.NewBuiltIn(keymanager_kmip.New()),
+			"memory":          keymanager.NewBuiltIn(keymanager_memory.New()),
+			"pkcs11":          keymanager.NewBuiltIn(keymanager_pkcs11.New()),
+			"remote_signer":   keymanager.NewBuiltIn(keymanager_remotesigner.New()),
+			"tpm":             keymanager.NewBuiltIn(keymanager_tpm.New()),
 		},
 	}
 )
@@ -85,12 +148,16 @@ type Config struct {
 	GlobalConfig  *common.GlobalConfig
 	PluginConfigs common.PluginConfigMap
 	Log           logrus.FieldLogger
+
+	// If set, the DataStore operations are instrumented with these metrics
+	Metrics telemetry.Metrics
 }
 
 type ServerCatalog struct {
-	com common.Catalog
-	m   sync.RWMutex
-	log logrus.FieldLogger
+	com     common.Catalog
+	m       sync.RWMutex
+	log     logrus.FieldLogger
+	metrics telemetry.Metrics
 
 	dataStorePlugins    []*ManagedDataStore
 	nodeAttestorPlugins []*ManagedNodeAttestor
@@ -109,11 +176,45 @@ func New(c *Config) *ServerCatalog {
 	}
 
 	return &ServerCatalog{
-		log: c.Log,
-		com: common.New(commonConfig),
+		log:     c.Log,
+		metrics: c.Metrics,
+		com:     common.New(commonConfig),
 	}
 }
 
+// LoadDataStore loads and configures only the DataStore plugin found in the
+// plugin configurations. It is meant for tooling that accesses the datastore
+// without running the server. The returned function stops the plugin.
+func LoadDataStore(ctx context.Context, c *Config) (datastore.DataStore, func(), error) {
+	com := common.New(&common.Config{
+		GlobalConfig: c.GlobalConfig,
+		PluginConfigs: common.PluginConfigMap{
+			DataStoreType: c.PluginConfigs[DataStoreType],
+		},
+		SupportedPlugins: supportedPlugins,
+		BuiltinPlugins:   builtinPlugins,
+		Log:              c.Log,
+	})
+	if err := com.Run(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	for _, p := range com.Plugins() {
+		if !p.Config.Enabled || p.Config.PluginType != DataStoreType {
+			continue
+		}
+		ds, ok := p.Plugin.(datastore.DataStore)
+		if !ok {
+			com.Stop()
+			return nil, nil, fmt.Errorf("Plugin %s does not adhere to DataStore interface", p.Config.PluginName)
+		}
+		return ds, com.Stop, nil
+	}
+
+	com.Stop()
+	return nil, nil, fmt.Errorf("At least one plugin of type %s is required", DataStoreType)
+}
+
 func (c *ServerCatalog) Run(ctx context.Context) error {
 	c.m.Lock()
 	defer c.m.Unlock()
@@ -202,6 +303,9 @@ func (c *ServerCatalog) categorize() error {
 			if !ok {
 				return fmt.Errorf("Plugin %s does not adhere to DataStore interface", p.Config.PluginName)
 			}
+			if c.metrics != nil {
+				pl = newMetricsDataStore(pl, c.metrics)
+			}
 			c.dataStorePlugins = append(c.dataStorePlugins, NewManagedDataStore(pl, p.Config))
 		case NodeAttestorType:
 			pl, ok := p.Plugin.(nodeattestor.NodeAttestor)
@@ -226,6 +330,10 @@ func (c *ServerCatalog) categorize() error {
 			if !ok {
 				return fmt.Errorf("Plugin %s does not adhere to KeyManager interface", p.Config.PluginName)
 			}
+			pl = newBatchFallbackKeyManager(pl, c.log.WithFields(logrus.Fields{
+				"plugin_type": p.Config.PluginType,
+				"plugin_name": p.Config.PluginName,
+			}))
 			c.keyManagerPlugins = append(c.keyManagerPlugins, NewManagedKeyManager(pl, p.Config))
 
 		default:
@@ -245,7 +353,7 @@ func (c *ServerCatalog) categorize() error {
 		}
 	}
 
-	return nil
+	return validateKeyPurposes(c.keyManagerPlugins)
 }
 
 func (c *ServerCatalog) reset() {
@@ -255,3 +363,68 @@ func (c *ServerCatalog) reset() {
 	c.upstreamCAPlugins = nil
 	c.keyManagerPlugins = nil
 }
+
+// KeyManagerFor returns the KeyManager used for keys of the given purpose.
+// KeyManagers configured with key_purposes are used only for those purposes,
+// while a KeyManager without key_purposes is used for any purpose not claimed
+// by another KeyManager. It returns nil if no KeyManager is suitable.
+func KeyManagerFor(keyManagers []*ManagedKeyManager, purpose string) *ManagedKeyManager {
+	var fallback *ManagedKeyManager
+	for _, km := range keyManagers {
+		purposes := km.Config().KeyPurposes
+		if len(purposes) == 0 {
+			if fallback == nil {
+				fallback = km
+			}
+			continue
+		}
+		if hasKeyPurpose(purposes, purpose) {
+			return km
+		}
+	}
+	return fallback
+}
+
+// validateKeyPurposes ensures that each key purpose is served by exactly one
+// KeyManager
+func validateKeyPurposes(keyManagers []*ManagedKeyManager) error {
+	claimedBy := make(map[string]string)
+	var unrestricted []string
+	for _, km := range keyManagers {
+		config := km.Config()
+		if len(config.KeyPurposes) == 0 {
+			unrestricted = append(unrestricted, config.PluginName)
+			continue
+		}
+		for _, purpose := range config.KeyPurposes {
+			if !hasKeyPurpose(keyPurposes, purpose) {
+				return fmt.Errorf("KeyManager %s has unknown key purpose %q", config.PluginName, purpose)
+			}
+			if other, ok := claimedBy[purpose]; ok {
+				return fmt.Errorf("KeyManagers %s and %s both claim key purpose %q", other, config.PluginName, purpose)
+			}
+			claimedBy[purpose] = config.PluginName
+		}
+	}
+
+	if len(unrestricted) > 1 {
+		return fmt.Errorf("Only one KeyManager may omit key_purposes; found %s", strings.Join(unrestricted, ", "))
+	}
+	if len(unrestricted) == 0 {
+		for _, purpose := range keyPurposes {
+			if _, ok := claimedBy[purpose]; !ok {
+				return fmt.Errorf("No KeyManager configured for key purpose %q", purpose)
+			}
+		}
+	}
+	return nil
+}
+
+func hasKeyPurpose(purposes []string, purpose string) bool {
+	for _, p := range purposes {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package catalog
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -17,6 +18,7 @@ import (
 	"github.com/spiffe/spire/test/mock/proto/server/nodeattestor"
 	"github.com/spiffe/spire/test/mock/proto/server/noderesolver"
 	"github.com/spiffe/spire/test/mock/proto/server/upstreamca"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -133,6 +135,81 @@ func (c *ServerCatalogTestSuite) TestCategorize() {
 	c.Assert().Nil(err)
 }
 
+func (c *ServerCatalogTestSuite) TestCategorizeKeyPurposes() {
+	comCatalog := mock_catalog.NewMockCatalog(c.ctrl)
+	c.catalog.com = comCatalog
+
+	for _, tt := range []struct {
+		purposes [][]string
+		err      string
+	}{
+		{
+			purposes: [][]string{nil},
+		},
+		{
+			purposes: [][]string{{"x509_ca"}, nil},
+		},
+		{
+			purposes: [][]string{{"x509_ca"}, {"jwt"}},
+		},
+		{
+			purposes: [][]string{{"x509_ca", "jwt"}},
+		},
+		{
+			purposes: [][]string{{"x509"}, nil},
+			err:      `KeyManager keymanager1 has unknown key purpose "x509"`,
+		},
+		{
+			purposes: [][]string{{"jwt"}, {"x509_ca", "jwt"}},
+			err:      `KeyManagers keymanager1 and keymanager2 both claim key purpose "jwt"`,
+		},
+		{
+			purposes: [][]string{nil, nil},
+			err:      "Only one KeyManager may omit key_purposes; found keymanager1, keymanager2",
+		},
+		{
+			purposes: [][]string{{"x509_ca"}},
+			err:      `No KeyManager configured for key purpose "jwt"`,
+		},
+	} {
+		withKeyManagers := append([]*common_catalog.ManagedPlugin(nil), plugins[:len(plugins)-1]...)
+		for i, purposes := range tt.purposes {
+			withKeyManagers = append(withKeyManagers, &common_catalog.ManagedPlugin{
+				Plugin: keymanager.NewBuiltIn(&mock_keymanager.MockPlugin{}),
+				Config: common_catalog.PluginConfig{
+					Enabled:     true,
+					PluginName:  fmt.Sprintf("keymanager%d", i+1),
+					PluginType:  KeyManagerType,
+					KeyPurposes: purposes,
+				},
+			})
+		}
+		comCatalog.EXPECT().Plugins().Return(withKeyManagers)
+
+		err := c.catalog.categorize()
+		if tt.err != "" {
+			c.Require().EqualError(err, tt.err)
+		} else {
+			c.Require().NoError(err)
+		}
+	}
+}
+
+func TestKeyManagerFor(t *testing.T) {
+	x509CA := NewManagedKeyManager(nil, common_catalog.PluginConfig{
+		PluginName:  "x509_ca",
+		KeyPurposes: []string{KeyPurposeX509CA},
+	})
+	fallback := NewManagedKeyManager(nil, common_catalog.PluginConfig{
+		PluginName: "fallback",
+	})
+
+	keyManagers := []*ManagedKeyManager{fallback, x509CA}
+	require.Equal(t, x509CA, KeyManagerFor(keyManagers, KeyPurposeX509CA))
+	require.Equal(t, fallback, KeyManagerFor(keyManagers, KeyPurposeJWT))
+	require.Nil(t, KeyManagerFor([]*ManagedKeyManager{x509CA}, KeyPurposeJWT))
+}
+
 func TestCatalog(t *testing.T) {
 	suite.Run(t, new(ServerCatalogTestSuite))
 }
@@ -0,0 +1,226 @@
+package catalog
+
+import (
+	"context"
+
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/proto/server/datastore"
+)
+
+// metricsDataStore wraps a DataStore, emitting the count and latency of each
+// operation, along with the number of records returned by reads.
+type metricsDataStore struct {
+	ds      datastore.DataStore
+	metrics telemetry.Metrics
+}
+
+func newMetricsDataStore(ds datastore.DataStore, metrics telemetry.Metrics) datastore.DataStore {
+	return metricsDataStore{
+		ds:      ds,
+		metrics: metrics,
+	}
+}
+
+func (w metricsDataStore) CreateBundle(ctx context.Context, req *datastore.CreateBundleRequest) (_ *datastore.CreateBundleResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "bundle", "create")(&err)
+	return w.ds.CreateBundle(ctx, req)
+}
+
+func (w metricsDataStore) FetchBundle(ctx context.Context, req *datastore.FetchBundleRequest) (resp *datastore.FetchBundleResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "bundle", "fetch")(&err)
+	resp, err = w.ds.FetchBundle(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "bundle", "fetch", "rows"}, boolRows(resp.Bundle != nil))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) ListBundles(ctx context.Context, req *datastore.ListBundlesRequest) (resp *datastore.ListBundlesResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "bundle", "list")(&err)
+	resp, err = w.ds.ListBundles(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "bundle", "list", "rows"}, len(resp.Bundles))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) UpdateBundle(ctx context.Context, req *datastore.UpdateBundleRequest) (_ *datastore.UpdateBundleResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "bundle", "update")(&err)
+	return w.ds.UpdateBundle(ctx, req)
+}
+
+func (w metricsDataStore) AppendBundle(ctx context.Context, req *datastore.AppendBundleRequest) (_ *datastore.AppendBundleResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "bundle", "append")(&err)
+	return w.ds.AppendBundle(ctx, req)
+}
+
+func (w metricsDataStore) DeleteBundle(ctx context.Context, req *datastore.DeleteBundleRequest) (_ *datastore.DeleteBundleResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "bundle", "delete")(&err)
+	return w.ds.DeleteBundle(ctx, req)
+}
+
+func (w metricsDataStore) CreateAttestedNode(ctx context.Context, req *datastore.CreateAttestedNodeRequest) (_ *datastore.CreateAttestedNodeResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "node", "create")(&err)
+	return w.ds.CreateAttestedNode(ctx, req)
+}
+
+func (w metricsDataStore) FetchAttestedNode(ctx context.Context, req *datastore.FetchAttestedNodeRequest) (resp *datastore.FetchAttestedNodeResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "node", "fetch")(&err)
+	resp, err = w.ds.FetchAttestedNode(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "node", "fetch", "rows"}, boolRows(resp.Node != nil))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) ListAttestedNodes(ctx context.Context, req *datastore.ListAttestedNodesRequest) (resp *datastore.ListAttestedNodesResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "node", "list")(&err)
+	resp, err = w.ds.ListAttestedNodes(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "node", "list", "rows"}, len(resp.Nodes))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) UpdateAttestedNode(ctx context.Context, req *datastore.UpdateAttestedNodeRequest) (_ *datastore.UpdateAttestedNodeResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "node", "update")(&err)
+	return w.ds.UpdateAttestedNode(ctx, req)
+}
+
+func (w metricsDataStore) DeleteAttestedNode(ctx context.Context, req *datastore.DeleteAttestedNodeRequest) (_ *datastore.DeleteAttestedNodeResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "node", "delete")(&err)
+	return w.ds.DeleteAttestedNode(ctx, req)
+}
+
+func (w metricsDataStore) SetNodeSelectors(ctx context.Context, req *datastore.SetNodeSelectorsRequest) (_ *datastore.SetNodeSelectorsResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "node_selectors", "set")(&err)
+	return w.ds.SetNodeSelectors(ctx, req)
+}
+
+func (w metricsDataStore) GetNodeSelectors(ctx context.Context, req *datastore.GetNodeSelectorsRequest) (_ *datastore.GetNodeSelectorsResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "node_selectors", "get")(&err)
+	return w.ds.GetNodeSelectors(ctx, req)
+}
+
+func (w metricsDataStore) CreateRegistrationEntry(ctx context.Context, req *datastore.CreateRegistrationEntryRequest) (_ *datastore.CreateRegistrationEntryResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "registration_entry", "create")(&err)
+	return w.ds.CreateRegistrationEntry(ctx, req)
+}
+
+func (w metricsDataStore) FetchRegistrationEntry(ctx context.Context, req *datastore.FetchRegistrationEntryRequest) (resp *datastore.FetchRegistrationEntryResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "registration_entry", "fetch")(&err)
+	resp, err = w.ds.FetchRegistrationEntry(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "registration_entry", "fetch", "rows"}, boolRows(resp.Entry != nil))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) ListRegistrationEntries(ctx context.Context, req *datastore.ListRegistrationEntriesRequest) (resp *datastore.ListRegistrationEntriesResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "registration_entry", "list")(&err)
+	resp, err = w.ds.ListRegistrationEntries(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "registration_entry", "list", "rows"}, len(resp.Entries))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) UpdateRegistrationEntry(ctx context.Context, req *datastore.UpdateRegistrationEntryRequest) (_ *datastore.UpdateRegistrationEntryResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "registration_entry", "update")(&err)
+	return w.ds.UpdateRegistrationEntry(ctx, req)
+}
+
+func (w metricsDataStore) DeleteRegistrationEntry(ctx context.Context, req *datastore.DeleteRegistrationEntryRequest) (_ *datastore.DeleteRegistrationEntryResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "registration_entry", "delete")(&err)
+	return w.ds.DeleteRegistrationEntry(ctx, req)
+}
+
+func (w metricsDataStore) BatchCreateRegistrationEntries(ctx context.Context, req *datastore.BatchCreateRegistrationEntriesRequest) (resp *datastore.BatchCreateRegistrationEntriesResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "registration_entry", "batch_create")(&err)
+	resp, err = w.ds.BatchCreateRegistrationEntries(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "registration_entry", "batch_create", "rows"}, len(resp.Entries))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) BatchUpdateRegistrationEntries(ctx context.Context, req *datastore.BatchUpdateRegistrationEntriesRequest) (resp *datastore.BatchUpdateRegistrationEntriesResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "registration_entry", "batch_update")(&err)
+	resp, err = w.ds.BatchUpdateRegistrationEntries(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "registration_entry", "batch_update", "rows"}, len(resp.Entries))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) BatchDeleteRegistrationEntries(ctx context.Context, req *datastore.BatchDeleteRegistrationEntriesRequest) (resp *datastore.BatchDeleteRegistrationEntriesResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "registration_entry", "batch_delete")(&err)
+	resp, err = w.ds.BatchDeleteRegistrationEntries(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "registration_entry", "batch_delete", "rows"}, len(resp.Entries))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) CreateJoinToken(ctx context.Context, req *datastore.CreateJoinTokenRequest) (_ *datastore.CreateJoinTokenResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "join_token", "create")(&err)
+	return w.ds.CreateJoinToken(ctx, req)
+}
+
+func (w metricsDataStore) FetchJoinToken(ctx context.Context, req *datastore.FetchJoinTokenRequest) (resp *datastore.FetchJoinTokenResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "join_token", "fetch")(&err)
+	resp, err = w.ds.FetchJoinToken(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "join_token", "fetch", "rows"}, boolRows(resp.JoinToken != nil))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) ListJoinTokens(ctx context.Context, req *datastore.ListJoinTokensRequest) (resp *datastore.ListJoinTokensResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "join_token", "list")(&err)
+	resp, err = w.ds.ListJoinTokens(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "join_token", "list", "rows"}, len(resp.JoinTokens))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) UseJoinToken(ctx context.Context, req *datastore.UseJoinTokenRequest) (_ *datastore.UseJoinTokenResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "join_token", "use")(&err)
+	return w.ds.UseJoinToken(ctx, req)
+}
+
+func (w metricsDataStore) DeleteJoinToken(ctx context.Context, req *datastore.DeleteJoinTokenRequest) (_ *datastore.DeleteJoinTokenResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "join_token", "delete")(&err)
+	return w.ds.DeleteJoinToken(ctx, req)
+}
+
+func (w metricsDataStore) PruneJoinTokens(ctx context.Context, req *datastore.PruneJoinTokensRequest) (_ *datastore.PruneJoinTokensResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "join_token", "prune")(&err)
+	return w.ds.PruneJoinTokens(ctx, req)
+}
+
+func (w metricsDataStore) ListChangeEvents(ctx context.Context, req *datastore.ListChangeEventsRequest) (resp *datastore.ListChangeEventsResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "change_event", "list")(&err)
+	resp, err = w.ds.ListChangeEvents(ctx, req)
+	if err == nil {
+		w.addRows([]string{"datastore", "change_event", "list", "rows"}, len(resp.Events))
+	}
+	return resp, err
+}
+
+func (w metricsDataStore) PruneChangeEvents(ctx context.Context, req *datastore.PruneChangeEventsRequest) (_ *datastore.PruneChangeEventsResponse, err error) {
+	defer telemetry.CountCall(w.metrics, "datastore", "change_event", "prune")(&err)
+	return w.ds.PruneChangeEvents(ctx, req)
+}
+
+func (w metricsDataStore) addRows(key []string, rows int) {
+	w.metrics.AddSample(key, float32(rows))
+}
+
+func boolRows(found bool) int {
+	if found {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package catalog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/spiffe/spire/test/fakes/fakedatastore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsDataStore(t *testing.T) {
+	metrics := newFakeMetrics()
+	ds := newMetricsDataStore(fakedatastore.New(), metrics)
+	ctx := context.Background()
+
+	for _, spiffeID := range []string{"spiffe://example.org/foo", "spiffe://example.org/bar"} {
+		_, err := ds.CreateRegistrationEntry(ctx, &datastore.CreateRegistrationEntryRequest{
+			Entry: &common.RegistrationEntry{
+				ParentId:  "spiffe://example.org/node",
+				SpiffeId:  spiffeID,
+				Selectors: []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	resp, err := ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Entries, 2)
+
+	_, err = ds.FetchBundle(ctx, &datastore.FetchBundleRequest{
+		TrustDomainId: "spiffe://otherdomain.org",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]float32{
+		"datastore.registration_entry.create": 2,
+		"datastore.registration_entry.list":   1,
+		"datastore.bundle.fetch":              1,
+	}, metrics.counters)
+	require.Equal(t, []string{
+		"datastore.registration_entry.create",
+		"datastore.registration_entry.create",
+		"datastore.registration_entry.list",
+		"datastore.bundle.fetch",
+	}, metrics.timers)
+	require.Equal(t, map[string][]float32{
+		"datastore.registration_entry.list.rows": {2},
+		"datastore.bundle.fetch.rows":            {0},
+	}, metrics.samples)
+}
+
+type fakeMetrics struct {
+	telemetry.Blackhole
+
+	counters map[string]float32
+	timers   []string
+	samples  map[string][]float32
+}
+
+func newFakeMetrics() *fakeMetrics {
+	return &fakeMetrics{
+		counters: make(map[string]float32),
+		samples:  make(map[string][]float32),
+	}
+}
+
+func (m *fakeMetrics) IncrCounterWithLabels(key []string, val float32, labels []telemetry.Label) {
+	m.counters[strings.Join(key, ".")] += val
+}
+
+func (m *fakeMetrics) MeasureSince(key []string, start time.Time) {
+	m.timers = append(m.timers, strings.Join(key, "."))
+}
+
+func (m *fakeMetrics) AddSample(key []string, val float32) {
+	k := strings.Join(key, ".")
+	m.samples[k] = append(m.samples[k], val)
+}
@@ -0,0 +1,44 @@
+package catalog
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/base"
+	"github.com/spiffe/spire/proto/server/keymanager"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// batchFallbackKeyManager wraps a KeyManager, signing each request of a batch
+// with SignData when the plugin does not implement SignDataBatch, as external
+// plugins built before the RPC was added don't. The plugin is only asked to
+// sign a batch until it answers that it cannot.
+type batchFallbackKeyManager struct {
+	keymanager.KeyManager
+	log logrus.FieldLogger
+
+	// set once the plugin answered that SignDataBatch is unimplemented
+	unimplemented int32
+}
+
+func newBatchFallbackKeyManager(km keymanager.KeyManager, log logrus.FieldLogger) keymanager.KeyManager {
+	return &batchFallbackKeyManager{
+		KeyManager: km,
+		log:        log,
+	}
+}
+
+func (w *batchFallbackKeyManager) SignDataBatch(ctx context.Context, req *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	if atomic.LoadInt32(&w.unimplemented) == 0 {
+		resp, err := w.KeyManager.SignDataBatch(ctx, req)
+		if status.Code(err) != codes.Unimplemented {
+			return resp, err
+		}
+		if atomic.CompareAndSwapInt32(&w.unimplemented, 0, 1) {
+			w.log.Warn("KeyManager does not implement SignDataBatch; signing each request with SignData")
+		}
+	}
+	return base.SignDataEach(ctx, req, w.KeyManager.SignData)
+}
@@ -0,0 +1,109 @@
+package catalog
+
+import (
+	"context"
+	"crypto/sha256"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/memory"
+	"github.com/spiffe/spire/proto/server/keymanager"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestBatchFallbackKeyManager(t *testing.T) {
+	ctx := context.Background()
+
+	// serve a key manager that predates SignDataBatch
+	signer := &countingSigner{KeyManager: memory.New()}
+	server := grpc.NewServer()
+	server.RegisterService(&legacyKeyManagerServiceDesc, signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	client, err := keymanager.GRPCPlugin{}.GRPCClient(conn)
+	require.NoError(t, err)
+
+	log, hook := test.NewNullLogger()
+	km := newBatchFallbackKeyManager(client.(keymanager.KeyManager), log)
+
+	_, err = signer.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("DATA"))
+	req := &keymanager.SignDataBatchRequest{
+		Requests: []*keymanager.SignDataRequest{
+			{
+				KeyId:      "KEY",
+				Data:       digest[:],
+				SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{HashAlgorithm: keymanager.HashAlgorithm_SHA256},
+			},
+			{
+				KeyId:      "KEY",
+				Data:       digest[:],
+				SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{HashAlgorithm: keymanager.HashAlgorithm_SHA256},
+			},
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := km.SignDataBatch(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 2)
+		for _, signResp := range resp.Responses {
+			require.NotEmpty(t, signResp.Signature)
+		}
+	}
+	require.Equal(t, int32(4), atomic.LoadInt32(&signer.signed))
+
+	// the fallback is only logged when the plugin first answers that the
+	// RPC is unimplemented
+	require.Len(t, hook.AllEntries(), 1)
+	require.Equal(t, "KeyManager does not implement SignDataBatch; signing each request with SignData", hook.LastEntry().Message)
+}
+
+type countingSigner struct {
+	keymanager.KeyManager
+	signed int32
+}
+
+func (s *countingSigner) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error) {
+	atomic.AddInt32(&s.signed, 1)
+	return s.KeyManager.SignData(ctx, req)
+}
+
+type legacyKeyManagerServer interface {
+	SignData(context.Context, *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error)
+}
+
+// legacyKeyManagerServiceDesc describes the KeyManager service of a plugin
+// without SignDataBatch. Only SignData is served since it is all the batch
+// fallback needs.
+var legacyKeyManagerServiceDesc = grpc.ServiceDesc{
+	ServiceName: "spire.server.keymanager.KeyManager",
+	HandlerType: (*legacyKeyManagerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SignData",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := new(keymanager.SignDataRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return srv.(legacyKeyManagerServer).SignData(ctx, req)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
@@ -0,0 +1,256 @@
+package changefeed
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/proto/server/datastore"
+)
+
+const (
+	// pageSize is the number of events read per datastore call
+	pageSize = 1000
+
+	// maxRetryInterval caps the time between attempts to poll the datastore
+	// after it fails
+	maxRetryInterval = time.Minute
+)
+
+// Handler is told about the changes made to registration entries and bundles
+type Handler interface {
+	// Sync is called once the feed follows the changes, and again whenever
+	// changes may have been missed. The handler must reload whatever it
+	// keeps of the entries and bundles.
+	Sync()
+
+	// Unsync is called when the feed can no longer follow the changes, e.g.
+	// because the datastore cannot be reached. The handler must not rely on
+	// the feed until Sync is called again.
+	Unsync()
+
+	// Changed is called once for each change event, in the order the events
+	// become visible, which is not necessarily the order of their IDs.
+	Changed(event *datastore.ChangeEvent)
+}
+
+// Handlers returns a handler that hands everything over to each of the given
+// handlers in turn
+func Handlers(handlers ...Handler) Handler {
+	return multiHandler(handlers)
+}
+
+type multiHandler []Handler
+
+func (m multiHandler) Sync() {
+	for _, h := range m {
+		h.Sync()
+	}
+}
+
+func (m multiHandler) Unsync() {
+	for _, h := range m {
+		h.Unsync()
+	}
+}
+
+func (m multiHandler) Changed(event *datastore.ChangeEvent) {
+	for _, h := range m {
+		h.Changed(event)
+	}
+}
+
+type Config struct {
+	DataStore datastore.DataStore
+	Log       logrus.FieldLogger
+	Handler   Handler
+
+	// How often the datastore is polled for events. Defaults to one second.
+	PollInterval time.Duration
+
+	// How long after an event becomes visible an event with a lower ID may
+	// still show up, i.e. how long the transactions recording events take
+	// at most to commit. Defaults to one minute.
+	SettleTime time.Duration
+}
+
+// Feed polls the datastore for change events and hands them to the handler.
+//
+// Event IDs are assigned when a change is made but the event only becomes
+// visible once the transaction making the change commits, so events can show
+// up after events with greater IDs. The feed keeps asking for the events
+// following the last settled one, i.e. the greatest ID it has seen for longer
+// than the settle time, and skips the ones it has already handed over.
+type Feed struct {
+	c *Config
+
+	synced  bool
+	failing bool
+
+	// settled is the ID up to which every event has been handed over or is
+	// reflected in what the handler loaded on sync
+	settled uint64
+
+	// seen holds when the events following settled were first seen
+	seen map[uint64]time.Time
+
+	hooks struct {
+		now   func() time.Time
+		after func(time.Duration) <-chan time.Time
+	}
+}
+
+func New(c *Config) *Feed {
+	if c.PollInterval == 0 {
+		c.PollInterval = time.Second
+	}
+	if c.SettleTime == 0 {
+		c.SettleTime = time.Minute
+	}
+
+	f := &Feed{
+		c: c,
+	}
+	f.hooks.now = time.Now
+	f.hooks.after = time.After
+	return f
+}
+
+// Run follows the change events until the context is canceled
+func (f *Feed) Run(ctx context.Context) error {
+	interval := f.c.PollInterval
+	for {
+		if err := f.poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			f.unsync()
+
+			// the datastore may not support change events at all, so only
+			// the first of consecutive failures is worth a warning
+			if !f.failing {
+				f.c.Log.Warnf("Unable to poll change events: %v", err)
+			} else {
+				f.c.Log.Debugf("Unable to poll change events: %v", err)
+			}
+			f.failing = true
+
+			if interval *= 2; interval > maxRetryInterval {
+				interval = maxRetryInterval
+			}
+		} else {
+			f.failing = false
+			interval = f.c.PollInterval
+		}
+
+		select {
+		case <-f.hooks.after(interval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (f *Feed) poll(ctx context.Context) error {
+	if !f.synced {
+		return f.sync(ctx)
+	}
+
+	now := f.hooks.now()
+	after := f.settled
+	for {
+		resp, err := f.list(ctx, after)
+		if err != nil {
+			return err
+		}
+		if resp.ResyncRequired {
+			f.c.Log.Info("Change events have been missed; resyncing")
+			return f.sync(ctx)
+		}
+
+		for _, event := range resp.Events {
+			after = event.Id
+			if _, ok := f.seen[event.Id]; ok {
+				continue
+			}
+			f.seen[event.Id] = now
+			f.c.Handler.Changed(event)
+		}
+		if len(resp.Events) < pageSize {
+			break
+		}
+	}
+
+	for id, seenAt := range f.seen {
+		if id > f.settled && now.Sub(seenAt) >= f.c.SettleTime {
+			f.settled = id
+		}
+	}
+	for id := range f.seen {
+		if id <= f.settled {
+			delete(f.seen, id)
+		}
+	}
+	return nil
+}
+
+// sync starts following the events from the ones already in the datastore.
+// Events created within the settle time are not settled yet, since events
+// with lower IDs may still show up, and are marked as seen instead. The
+// handler reloads after they were listed, so it doesn't need to be told
+// about them.
+func (f *Feed) sync(ctx context.Context) error {
+	f.unsync()
+
+	now := f.hooks.now()
+	settleBefore := now.Add(-f.c.SettleTime).Unix()
+
+	var events []*datastore.ChangeEvent
+	var after uint64
+	for {
+		resp, err := f.list(ctx, after)
+		if err != nil {
+			return err
+		}
+		for _, event := range resp.Events {
+			after = event.Id
+			events = append(events, event)
+		}
+		if len(resp.Events) < pageSize {
+			break
+		}
+	}
+
+	var settled uint64
+	for _, event := range events {
+		if event.CreatedAt < settleBefore {
+			settled = event.Id
+		}
+	}
+	seen := make(map[uint64]time.Time)
+	for _, event := range events {
+		if event.Id > settled {
+			seen[event.Id] = now
+		}
+	}
+
+	f.settled = settled
+	f.seen = seen
+	f.synced = true
+	f.c.Handler.Sync()
+	return nil
+}
+
+func (f *Feed) unsync() {
+	if f.synced {
+		f.synced = false
+		f.c.Handler.Unsync()
+	}
+}
+
+func (f *Feed) list(ctx context.Context, after uint64) (*datastore.ListChangeEventsResponse, error) {
+	return f.c.DataStore.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{
+		AfterId: after,
+		Limit:   pageSize,
+	})
+}
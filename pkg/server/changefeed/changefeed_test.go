@@ -0,0 +1,231 @@
+package changefeed
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestFeed(t *testing.T) {
+	suite.Run(t, new(FeedTestSuite))
+}
+
+type FeedTestSuite struct {
+	suite.Suite
+
+	ds      *fakeDataStore
+	handler *fakeHandler
+	now     time.Time
+	feed    *Feed
+}
+
+func (s *FeedTestSuite) SetupTest() {
+	s.ds = &fakeDataStore{}
+	s.handler = &fakeHandler{}
+	s.now = time.Unix(1000000, 0)
+
+	log, _ := test.NewNullLogger()
+	s.feed = New(&Config{
+		DataStore: s.ds,
+		Log:       log,
+		Handler:   s.handler,
+	})
+	s.feed.hooks.now = func() time.Time {
+		return s.now
+	}
+}
+
+func (s *FeedTestSuite) TestSyncSkipsExistingEvents() {
+	s.ds.add(1, s.now.Add(-time.Hour))
+	s.ds.add(2, s.now)
+
+	s.poll()
+	s.Require().Equal(1, s.handler.syncs)
+	s.Require().Empty(s.handler.changed)
+
+	s.ds.add(3, s.now)
+	s.poll()
+	s.Require().Equal([]uint64{3}, s.handler.changed)
+	s.Require().Equal(1, s.handler.syncs)
+}
+
+func (s *FeedTestSuite) TestEventsAreHandedOverOnce() {
+	s.poll()
+
+	s.ds.add(1, s.now)
+	s.ds.add(2, s.now)
+	s.poll()
+	s.poll()
+	s.Require().Equal([]uint64{1, 2}, s.handler.changed)
+
+	s.advance(time.Hour)
+	s.ds.add(3, s.now)
+	s.poll()
+	s.poll()
+	s.Require().Equal([]uint64{1, 2, 3}, s.handler.changed)
+	s.Require().Equal(uint64(2), s.feed.settled)
+}
+
+func (s *FeedTestSuite) TestEventsShowingUpOutOfOrder() {
+	s.poll()
+
+	// event 2 commits before event 1
+	s.ds.add(2, s.now)
+	s.poll()
+	s.Require().Equal([]uint64{2}, s.handler.changed)
+
+	s.advance(30 * time.Second)
+	s.ds.add(1, s.now)
+	s.poll()
+	s.Require().Equal([]uint64{2, 1}, s.handler.changed)
+}
+
+func (s *FeedTestSuite) TestEventsShowingUpOutOfOrderAfterSync() {
+	s.ds.add(2, s.now)
+	s.poll()
+	s.Require().Equal(1, s.handler.syncs)
+
+	s.advance(30 * time.Second)
+	s.ds.add(1, s.now)
+	s.poll()
+	s.Require().Equal([]uint64{1}, s.handler.changed)
+}
+
+func (s *FeedTestSuite) TestEventsAreSettledAfterSettleTime() {
+	s.poll()
+
+	s.ds.add(2, s.now)
+	s.poll()
+	s.advance(time.Minute)
+	s.poll()
+	s.Require().Equal(uint64(2), s.feed.settled)
+
+	// an event behind a settled one is assumed to have been rolled back
+	s.ds.add(1, s.now)
+	s.poll()
+	s.Require().Equal([]uint64{2}, s.handler.changed)
+}
+
+func (s *FeedTestSuite) TestResyncWhenEventsWereMissed() {
+	s.ds.add(1, s.now.Add(-time.Hour))
+	s.poll()
+
+	s.ds.resyncRequired = true
+	s.poll()
+	s.Require().Equal(2, s.handler.syncs)
+	s.Require().Equal(1, s.handler.unsyncs)
+}
+
+func (s *FeedTestSuite) TestUnsyncOnFailure() {
+	s.poll()
+
+	s.ds.err = errors.New("oh no")
+	s.Require().Error(s.feed.poll(context.Background()))
+	s.feed.unsync()
+	s.Require().Equal(1, s.handler.unsyncs)
+
+	s.ds.err = nil
+	s.poll()
+	s.Require().Equal(2, s.handler.syncs)
+}
+
+func (s *FeedTestSuite) TestPaging() {
+	s.poll()
+
+	for id := uint64(1); id <= pageSize+1; id++ {
+		s.ds.add(id, s.now)
+	}
+	s.poll()
+	s.Require().Len(s.handler.changed, pageSize+1)
+}
+
+func (s *FeedTestSuite) poll() {
+	s.Require().NoError(s.feed.poll(context.Background()))
+}
+
+func (s *FeedTestSuite) advance(d time.Duration) {
+	s.now = s.now.Add(d)
+}
+
+type fakeDataStore struct {
+	datastore.DataStore
+
+	events         []*datastore.ChangeEvent
+	resyncRequired bool
+	err            error
+}
+
+func (ds *fakeDataStore) add(id uint64, createdAt time.Time) {
+	ds.events = append(ds.events, &datastore.ChangeEvent{
+		Id:        id,
+		Kind:      datastore.ChangeEvent_BUNDLE,
+		Key:       "spiffe://example.org",
+		CreatedAt: createdAt.Unix(),
+	})
+	sort.Slice(ds.events, func(i, j int) bool {
+		return ds.events[i].Id < ds.events[j].Id
+	})
+}
+
+func (ds *fakeDataStore) ListChangeEvents(ctx context.Context, req *datastore.ListChangeEventsRequest) (*datastore.ListChangeEventsResponse, error) {
+	if ds.err != nil {
+		return nil, ds.err
+	}
+
+	resp := new(datastore.ListChangeEventsResponse)
+	if req.AfterId > 0 && ds.resyncRequired {
+		ds.resyncRequired = false
+		resp.ResyncRequired = true
+		return resp, nil
+	}
+	for _, event := range ds.events {
+		if event.Id <= req.AfterId {
+			continue
+		}
+		if len(resp.Events) == int(req.Limit) {
+			break
+		}
+		resp.Events = append(resp.Events, event)
+	}
+	return resp, nil
+}
+
+func TestHandlers(t *testing.T) {
+	a, b := new(fakeHandler), new(fakeHandler)
+	h := Handlers(a, b)
+
+	h.Sync()
+	h.Changed(&datastore.ChangeEvent{Id: 1})
+	h.Unsync()
+
+	for _, handler := range []*fakeHandler{a, b} {
+		require.Equal(t, 1, handler.syncs)
+		require.Equal(t, 1, handler.unsyncs)
+		require.Equal(t, []uint64{1}, handler.changed)
+	}
+}
+
+type fakeHandler struct {
+	syncs   int
+	unsyncs int
+	changed []uint64
+}
+
+func (h *fakeHandler) Sync() {
+	h.syncs++
+}
+
+func (h *fakeHandler) Unsync() {
+	h.unsyncs++
+}
+
+func (h *fakeHandler) Changed(event *datastore.ChangeEvent) {
+	h.changed = append(h.changed, event.Id)
+}
@@ -0,0 +1,90 @@
+package endpoints
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/spiffe/spire/pkg/server/changefeed"
+	datastore_pb "github.com/spiffe/spire/proto/server/datastore"
+)
+
+// bundleCache caches the bundle of the trust domain, which is needed for every
+// TLS handshake with the server. The change feed tells it when the bundle
+// changes. The datastore is read every time while the feed is not in sync.
+type bundleCache struct {
+	ds            datastore_pb.DataStore
+	trustDomainID string
+
+	mu     sync.Mutex
+	synced bool
+	bundle *datastore_pb.Bundle
+
+	// generation is bumped whenever the cached bundle is dropped, so that a
+	// fetch racing with a change doesn't cache the bundle it read before
+	generation uint64
+}
+
+var _ changefeed.Handler = (*bundleCache)(nil)
+
+func newBundleCache(ds datastore_pb.DataStore, trustDomainID string) *bundleCache {
+	return &bundleCache{
+		ds:            ds,
+		trustDomainID: trustDomainID,
+	}
+}
+
+// FetchBundle returns the bundle of the trust domain, or nil if there is none
+func (c *bundleCache) FetchBundle(ctx context.Context) (*datastore_pb.Bundle, error) {
+	c.mu.Lock()
+	bundle := c.bundle
+	generation := c.generation
+	c.mu.Unlock()
+	if bundle != nil {
+		return bundle, nil
+	}
+
+	resp, err := c.ds.FetchBundle(ctx, &datastore_pb.FetchBundleRequest{
+		TrustDomainId: c.trustDomainID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.synced && c.generation == generation {
+		c.bundle = resp.Bundle
+	}
+	c.mu.Unlock()
+	return resp.Bundle, nil
+}
+
+func (c *bundleCache) Sync() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.synced = true
+	c.drop()
+}
+
+func (c *bundleCache) Unsync() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.synced = false
+	c.drop()
+}
+
+func (c *bundleCache) Changed(event *datastore_pb.ChangeEvent) {
+	if event.Kind != datastore_pb.ChangeEvent_BUNDLE || event.Key != c.trustDomainID {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.drop()
+}
+
+// drop drops the cached bundle. Must be called with the lock held.
+func (c *bundleCache) drop() {
+	c.bundle = nil
+	c.generation++
+}
@@ -0,0 +1,70 @@
+package endpoints
+
+import (
+	"testing"
+
+	"github.com/spiffe/spire/proto/common"
+	datastore_pb "github.com/spiffe/spire/proto/server/datastore"
+	"github.com/spiffe/spire/test/fakes/fakedatastore"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestBundleCache(t *testing.T) {
+	ctx := context.Background()
+	ds := fakedatastore.New()
+	cache := newBundleCache(ds, "spiffe://example.org")
+
+	setBundle := func(der string) {
+		bundle := &datastore_pb.Bundle{
+			TrustDomainId: "spiffe://example.org",
+			RootCas:       []*common.Certificate{{DerBytes: []byte(der)}},
+		}
+		_, err := ds.UpdateBundle(ctx, &datastore_pb.UpdateBundleRequest{
+			Bundle: bundle,
+		})
+		require.NoError(t, err)
+	}
+	fetchBundle := func() string {
+		bundle, err := cache.FetchBundle(ctx)
+		require.NoError(t, err)
+		return string(bundle.RootCas[0].DerBytes)
+	}
+
+	_, err := ds.CreateBundle(ctx, &datastore_pb.CreateBundleRequest{
+		Bundle: &datastore_pb.Bundle{
+			TrustDomainId: "spiffe://example.org",
+		},
+	})
+	require.NoError(t, err)
+
+	// the datastore is read while the cache is not synced
+	setBundle("A")
+	require.Equal(t, "A", fetchBundle())
+	setBundle("B")
+	require.Equal(t, "B", fetchBundle())
+
+	// the bundle is cached until it changes
+	cache.Sync()
+	require.Equal(t, "B", fetchBundle())
+	setBundle("C")
+	require.Equal(t, "B", fetchBundle())
+
+	// changes to other bundles are ignored
+	cache.Changed(&datastore_pb.ChangeEvent{
+		Kind: datastore_pb.ChangeEvent_BUNDLE,
+		Key:  "spiffe://otherdomain.org",
+	})
+	require.Equal(t, "B", fetchBundle())
+
+	cache.Changed(&datastore_pb.ChangeEvent{
+		Kind: datastore_pb.ChangeEvent_BUNDLE,
+		Key:  "spiffe://example.org",
+	})
+	require.Equal(t, "C", fetchBundle())
+
+	// the cached bundle is dropped when the cache is unsynced
+	cache.Unsync()
+	setBundle("D")
+	require.Equal(t, "D", fetchBundle())
+}
@@ -7,9 +7,11 @@ import (
 
 	observer "github.com/imkira/go-observer"
 	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/selector"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/pkg/server/ca"
 	"github.com/spiffe/spire/pkg/server/catalog"
+	"github.com/spiffe/spire/pkg/server/util/regentryutil"
 
 	"google.golang.org/grpc"
 )
@@ -36,11 +38,16 @@ type Config struct {
 
 	Log     logrus.FieldLogger
 	Metrics telemetry.Metrics
+
+	// If set, post-processes the selectors of agents
+	SelectorHook *selector.Hook
 }
 
 func New(c *Config) *endpoints {
 	return &endpoints{
-		c:   c,
-		mtx: new(sync.RWMutex),
+		c:       c,
+		mtx:     new(sync.RWMutex),
+		bundles: newBundleCache(c.Catalog.DataStores()[0], c.TrustDomain.String()),
+		entries: regentryutil.NewCache(c.Catalog.DataStores()[0]),
 	}
 }
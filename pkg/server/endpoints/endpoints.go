@@ -16,12 +16,13 @@ import (
 
 	"github.com/spiffe/spire/pkg/common/auth"
 	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/pkg/server/changefeed"
 	"github.com/spiffe/spire/pkg/server/endpoints/node"
 	"github.com/spiffe/spire/pkg/server/endpoints/registration"
 	"github.com/spiffe/spire/pkg/server/svid"
+	"github.com/spiffe/spire/pkg/server/util/regentryutil"
 	node_pb "github.com/spiffe/spire/proto/api/node"
 	registration_pb "github.com/spiffe/spire/proto/api/registration"
-	datastore_pb "github.com/spiffe/spire/proto/server/datastore"
 )
 
 // Server manages gRPC and HTTP endpoint lifecycle
@@ -38,6 +39,9 @@ type endpoints struct {
 
 	svid    []*x509.Certificate
 	svidKey *ecdsa.PrivateKey
+
+	bundles *bundleCache
+	entries *regentryutil.Cache
 }
 
 // ListenAndServe starts all maintenance routines and endpoints, then blocks
@@ -62,6 +66,7 @@ func (e *endpoints) ListenAndServe(ctx context.Context) error {
 			return e.runUDSServer(ctx, udsServer)
 		},
 		e.runSVIDObserver,
+		e.runChangeFeed,
 	)
 	if err == context.Canceled {
 		err = nil
@@ -96,6 +101,9 @@ func (e *endpoints) registerNodeAPI(tcpServer *grpc.Server) {
 		Catalog:     e.c.Catalog,
 		TrustDomain: e.c.TrustDomain,
 		ServerCA:    e.c.ServerCA,
+
+		SelectorHook: e.c.SelectorHook,
+		EntryCache:   e.entries,
 	})
 	node_pb.RegisterNodeServer(tcpServer, n)
 }
@@ -194,6 +202,17 @@ func (e *endpoints) runSVIDObserver(ctx context.Context) error {
 	}
 }
 
+// runChangeFeed keeps the bundle and registration entry caches up to date
+// with the changes made to the bundles and entries
+func (e *endpoints) runChangeFeed(ctx context.Context) error {
+	feed := changefeed.New(&changefeed.Config{
+		DataStore: e.c.Catalog.DataStores()[0],
+		Log:       e.c.Log.WithField("subsystem_name", "change_feed"),
+		Handler:   changefeed.Handlers(e.bundles, e.entries),
+	})
+	return feed.Run(ctx)
+}
+
 // getTLSConfig returns a TLS Config hook for the gRPC server
 func (e *endpoints) getTLSConfig(ctx context.Context) func(*tls.ClientHelloInfo) (*tls.Config, error) {
 	return func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
@@ -220,20 +239,16 @@ func (e *endpoints) getTLSConfig(ctx context.Context) func(*tls.ClientHelloInfo)
 // getCerts queries the datastore and returns a TLS serving certificate(s) plus
 // the current CA root bundle.
 func (e *endpoints) getCerts(ctx context.Context) ([]tls.Certificate, *x509.CertPool, error) {
-	ds := e.c.Catalog.DataStores()[0]
-
-	resp, err := ds.FetchBundle(ctx, &datastore_pb.FetchBundleRequest{
-		TrustDomainId: e.c.TrustDomain.String(),
-	})
+	bundle, err := e.bundles.FetchBundle(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get bundle from datastore: %v", err)
 	}
-	if resp.Bundle == nil {
+	if bundle == nil {
 		return nil, nil, errors.New("bundle not found")
 	}
 
 	var caCerts []*x509.Certificate
-	for _, rootCA := range resp.Bundle.RootCas {
+	for _, rootCA := range bundle.RootCas {
 		rootCACerts, err := x509.ParseCertificates(rootCA.DerBytes)
 		if err != nil {
 			return nil, nil, fmt.Errorf("parse bundle: %v", err)
@@ -15,6 +15,7 @@ import (
 	"github.com/spiffe/spire/pkg/common/bundleutil"
 	"github.com/spiffe/spire/pkg/common/idutil"
 	"github.com/spiffe/spire/pkg/common/jwtsvid"
+	"github.com/spiffe/spire/pkg/common/selector"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/pkg/server/ca"
 	"github.com/spiffe/spire/pkg/server/catalog"
@@ -37,6 +38,13 @@ type HandlerConfig struct {
 	Catalog     catalog.Catalog
 	ServerCA    ca.ServerCA
 	TrustDomain url.URL
+
+	// SelectorHook, if set, post-processes the selectors of agents
+	SelectorHook *selector.Hook
+
+	// EntryCache, if set, serves the registration entries of agents when
+	// slightly out of date entries are tolerated
+	EntryCache *regentryutil.Cache
 }
 
 type Handler struct {
@@ -101,6 +109,11 @@ func (h *Handler) Attest(stream node.Node_AttestServer) (err error) {
 		return errors.New("failed to determine if agent has already attested")
 	}
 
+	// An agent presenting its current SVID is re-attesting. It has proven
+	// to be the agent that attested before, so the attestor does not need to
+	// treat the attestation as a replay.
+	reattesting := attestedBefore && h.isReattesting(ctx, agentID)
+
 	// Pick the right node attestor
 	var attestStream nodeattestor.Attest_Stream
 	if request.AttestationData.Type != "join_token" {
@@ -121,7 +134,7 @@ func (h *Handler) Attest(stream node.Node_AttestServer) (err error) {
 		}
 	}
 
-	attestResponse, err := h.doAttestChallengeResponse(ctx, stream, attestStream, request, attestedBefore)
+	attestResponse, err := h.doAttestChallengeResponse(ctx, stream, attestStream, request, agentID, attestedBefore && !reattesting)
 	if err != nil {
 		return err
 	}
@@ -174,7 +187,11 @@ func (h *Handler) Attest(stream node.Node_AttestServer) (err error) {
 
 	p, ok := peer.FromContext(ctx)
 	if ok {
-		h.c.Log.Infof("Node attestation request from %v completed using strategy %v", p.Addr, request.AttestationData.Type)
+		if reattesting {
+			h.c.Log.Infof("Node re-attestation request from %v completed using strategy %v", p.Addr, request.AttestationData.Type)
+		} else {
+			h.c.Log.Infof("Node attestation request from %v completed using strategy %v", p.Addr, request.AttestationData.Type)
+		}
 	}
 
 	if err := stream.Send(response); err != nil {
@@ -218,7 +235,10 @@ func (h *Handler) FetchX509SVID(server node.Node_FetchX509SVIDServer) (err error
 			return err
 		}
 
-		regEntries, err := regentryutil.FetchRegistrationEntries(ctx, h.c.Catalog.DataStores()[0], agentID)
+		// the entries authorize the CSRs, so they may only be slightly out
+		// of date when there is nothing to sign
+		tolerateStale := len(request.Csrs) == 0
+		regEntries, err := h.fetchEntries(ctx, agentID, tolerateStale)
 		if err != nil {
 			h.c.Log.Error(err)
 			return errors.New("failed to fetch agent registration entries")
@@ -230,7 +250,7 @@ func (h *Handler) FetchX509SVID(server node.Node_FetchX509SVIDServer) (err error
 			return errors.New("failed to sign CSRs")
 		}
 
-		bundles, err := h.getBundlesForEntries(ctx, regEntries)
+		bundles, err := h.getBundlesForEntries(ctx, regEntries, tolerateStale)
 		if err != nil {
 			h.c.Log.Error(err)
 			return err
@@ -381,6 +401,26 @@ func (h *Handler) isAttested(ctx context.Context, baseSpiffeID string) (bool, er
 	return false, nil
 }
 
+// isReattesting returns true if the peer presented a valid SVID for the given
+// agent ID.
+func (h *Handler) isReattesting(ctx context.Context, agentID string) bool {
+	peerCert, err := getPeerCertificateFromRequestContext(ctx)
+	if err != nil {
+		return false
+	}
+
+	peerID, err := getSpiffeIDFromCert(peerCert)
+	if err != nil || peerID != agentID {
+		return false
+	}
+
+	if err := h.validateAgentSVID(ctx, peerCert); err != nil {
+		h.c.Log.Warnf("Agent %q presented an invalid SVID for re-attestation: %v", agentID, err)
+		return false
+	}
+	return true
+}
+
 func (h *Handler) validateAgentSVID(ctx context.Context, cert *x509.Certificate) error {
 	dataStore := h.c.Catalog.DataStores()[0]
 
@@ -419,10 +459,10 @@ func (h *Handler) validateAgentSVID(ctx context.Context, cert *x509.Certificate)
 func (h *Handler) doAttestChallengeResponse(ctx context.Context,
 	nodeStream node.Node_AttestServer,
 	attestStream nodeattestor.Attest_Stream,
-	request *node.AttestRequest, attestedBefore bool) (*nodeattestor.AttestResponse, error) {
+	request *node.AttestRequest, agentID string, attestedBefore bool) (*nodeattestor.AttestResponse, error) {
 	// challenge/response loop
 	for {
-		response, err := h.attest(ctx, attestStream, request, attestedBefore)
+		response, err := h.attest(ctx, attestStream, request, agentID, attestedBefore)
 		if err != nil {
 			h.c.Log.Error(err)
 			return nil, fmt.Errorf("failed to attest: %v", err)
@@ -448,11 +488,11 @@ func (h *Handler) doAttestChallengeResponse(ctx context.Context,
 
 func (h *Handler) attest(ctx context.Context,
 	attestStream nodeattestor.Attest_Stream,
-	nodeRequest *node.AttestRequest, attestedBefore bool) (
+	nodeRequest *node.AttestRequest, agentID string, attestedBefore bool) (
 	response *nodeattestor.AttestResponse, err error) {
 
 	if attestStream == nil {
-		return h.attestToken(ctx, nodeRequest.AttestationData, attestedBefore)
+		return h.attestToken(ctx, nodeRequest.AttestationData, agentID, attestedBefore)
 	}
 
 	attestRequest := &nodeattestor.AttestRequest{
@@ -468,7 +508,7 @@ func (h *Handler) attest(ctx context.Context,
 }
 
 func (h *Handler) attestToken(ctx context.Context,
-	attestationData *common.AttestationData, attestedBefore bool) (
+	attestationData *common.AttestationData, agentID string, attestedBefore bool) (
 	response *nodeattestor.AttestResponse, err error) {
 
 	if attestedBefore {
@@ -476,9 +516,12 @@ func (h *Handler) attestToken(ctx context.Context,
 	}
 
 	tokenValue := string(attestationData.Data)
+	if tokenValue == "" {
+		return nil, errors.New("invalid join token")
+	}
 
 	ds := h.c.Catalog.DataStores()[0]
-	resp, err := ds.FetchJoinToken(ctx, &datastore.FetchJoinTokenRequest{
+	resp, err := ds.UseJoinToken(ctx, &datastore.UseJoinTokenRequest{
 		Token: tokenValue,
 	})
 	if err != nil {
@@ -489,17 +532,6 @@ func (h *Handler) attestToken(ctx context.Context,
 	}
 	t := resp.JoinToken
 
-	if t.Token == "" {
-		return nil, errors.New("invalid join token")
-	}
-
-	_, err = ds.DeleteJoinToken(ctx, &datastore.DeleteJoinTokenRequest{
-		Token: tokenValue,
-	})
-	if err != nil {
-		return nil, err
-	}
-
 	if time.Unix(t.Expiry, 0).Before(h.hooks.now()) {
 		return nil, errors.New("join token expired")
 	}
@@ -510,9 +542,21 @@ func (h *Handler) attestToken(ctx context.Context,
 		Host:   h.c.TrustDomain.Host,
 		Path:   path.Join("spire", "agent", "join_token", t.Token),
 	}
+
+	// Agents attesting with a reusable token append a unique suffix to the
+	// token path so that each one is assigned its own ID. A single use token
+	// names exactly one agent, so the suffix is rejected.
+	baseID := id.String()
+	if suffix := strings.TrimPrefix(agentID, baseID+"/"); suffix != agentID && suffix != "" && !strings.Contains(suffix, "/") {
+		if t.MaxUses <= 1 {
+			return nil, errors.New("agent ID suffix is only allowed with reusable join tokens")
+		}
+		baseID = agentID
+	}
+
 	return &nodeattestor.AttestResponse{
 		Valid:        true,
-		BaseSPIFFEID: id.String(),
+		BaseSPIFFEID: baseID,
 	}, nil
 }
 
@@ -587,8 +631,17 @@ func (h *Handler) updateNodeSelectors(ctx context.Context,
 
 	selectors = append(selectors, attestResponse.Selectors...)
 
+	selectors, err := h.c.SelectorHook.Process(ctx, selector.HookInput{
+		Kind:            selector.HookKindNode,
+		SpiffeID:        baseSpiffeID,
+		AttestationType: attestationType,
+	}, selectors)
+	if err != nil {
+		return err
+	}
+
 	dataStore := h.c.Catalog.DataStores()[0]
-	_, err := dataStore.SetNodeSelectors(ctx, &datastore.SetNodeSelectorsRequest{
+	_, err = dataStore.SetNodeSelectors(ctx, &datastore.SetNodeSelectorsRequest{
 		Selectors: &datastore.NodeSelectors{
 			SpiffeId:  baseSpiffeID,
 			Selectors: selectors,
@@ -613,7 +666,7 @@ func (h *Handler) getAttestResponse(ctx context.Context,
 		return nil, err
 	}
 
-	bundles, err := h.getBundlesForEntries(ctx, regEntries)
+	bundles, err := h.getBundlesForEntries(ctx, regEntries, false)
 	if err != nil {
 		return nil, err
 	}
@@ -669,6 +722,10 @@ func (h *Handler) signCSRs(ctx context.Context,
 
 	dataStore := h.c.Catalog.DataStores()[0]
 	svids = make(map[string]*node.X509SVID)
+	// workload SVIDs are signed together after all CSRs are validated so the
+	// key manager can sign them in one batch
+	var workloadIDs []string
+	var workloadParams []ca.X509SVIDParams
 	//iterate the CSRs and sign them
 	for _, csr := range csrs {
 		spiffeID, err := getSpiffeIDFromCSR(csr, idutil.AllowAny())
@@ -721,33 +778,40 @@ func (h *Handler) signCSRs(ctx context.Context,
 			svids[spiffeID] = svid
 		} else {
 			h.c.Log.Debugf("Signing SVID for %v on request by %v", spiffeID, callerID)
-			svid, err := h.buildSVID(ctx, spiffeID, regEntriesMap, csr)
+			params, err := h.buildSVIDParams(spiffeID, regEntriesMap, csr)
 			if err != nil {
 				return nil, err
 			}
-			svids[spiffeID] = svid
+			workloadIDs = append(workloadIDs, spiffeID)
+			workloadParams = append(workloadParams, params)
+		}
+	}
+
+	if len(workloadParams) > 0 {
+		workloadSVIDs, err := h.c.ServerCA.SignX509SVIDs(ctx, workloadParams)
+		if err != nil {
+			return nil, err
+		}
+		for i, svid := range workloadSVIDs {
+			svids[workloadIDs[i]] = makeX509SVID(svid)
 		}
 	}
 
 	return svids, nil
 }
 
-func (h *Handler) buildSVID(ctx context.Context,
-	spiffeID string, regEntries map[string]*common.RegistrationEntry, csr []byte) (
-	*node.X509SVID, error) {
-
+func (h *Handler) buildSVIDParams(spiffeID string, regEntries map[string]*common.RegistrationEntry, csr []byte) (ca.X509SVIDParams, error) {
 	//TODO: Validate that other fields are not populated https://github.com/spiffe/spire/issues/161
 	//validate that is present in the registration entries, otherwise we shouldn't sign
 	entry, ok := regEntries[spiffeID]
 	if !ok {
-		return nil, fmt.Errorf("not entitled to sign CSR for %q", spiffeID)
+		return ca.X509SVIDParams{}, fmt.Errorf("not entitled to sign CSR for %q", spiffeID)
 	}
 
-	svid, err := h.c.ServerCA.SignX509SVID(ctx, csr, time.Duration(entry.Ttl)*time.Second)
-	if err != nil {
-		return nil, err
-	}
-	return makeX509SVID(svid), nil
+	return ca.X509SVIDParams{
+		CSR: csr,
+		TTL: time.Duration(entry.Ttl) * time.Second,
+	}, nil
 }
 
 func (h *Handler) buildBaseSVID(ctx context.Context, csr []byte) (*node.X509SVID, *x509.Certificate, error) {
@@ -768,10 +832,10 @@ func (h *Handler) buildCASVID(ctx context.Context, csr []byte, ttl int32) (*node
 	return makeX509SVID(svid), nil
 }
 
-func (h *Handler) getBundlesForEntries(ctx context.Context, regEntries []*common.RegistrationEntry) (map[string]*common.Bundle, error) {
+func (h *Handler) getBundlesForEntries(ctx context.Context, regEntries []*common.RegistrationEntry, tolerateStale bool) (map[string]*common.Bundle, error) {
 	bundles := make(map[string]*common.Bundle)
 
-	ourBundle, err := h.getBundle(ctx, h.c.TrustDomain.String())
+	ourBundle, err := h.getBundle(ctx, h.c.TrustDomain.String(), tolerateStale)
 	if err != nil {
 		return nil, err
 	}
@@ -782,7 +846,7 @@ func (h *Handler) getBundlesForEntries(ctx context.Context, regEntries []*common
 			if bundles[trustDomainId] != nil {
 				continue
 			}
-			bundle, err := h.getBundle(ctx, trustDomainId)
+			bundle, err := h.getBundle(ctx, trustDomainId, tolerateStale)
 			if err != nil {
 				return nil, err
 			}
@@ -792,12 +856,27 @@ func (h *Handler) getBundlesForEntries(ctx context.Context, regEntries []*common
 	return bundles, nil
 }
 
+// fetchEntries fetches the registration entries of the agent, from the entry
+// cache when slightly out of date entries are tolerated
+func (h *Handler) fetchEntries(ctx context.Context, agentID string, tolerateStale bool) ([]*common.RegistrationEntry, error) {
+	ds := h.c.Catalog.DataStores()[0]
+	switch {
+	case !tolerateStale:
+		return regentryutil.FetchRegistrationEntries(ctx, ds, agentID)
+	case h.c.EntryCache != nil:
+		return h.c.EntryCache.FetchRegistrationEntries(ctx, agentID)
+	default:
+		return regentryutil.FetchStaleRegistrationEntries(ctx, ds, agentID)
+	}
+}
+
 // getBundle fetches a bundle from the datastore, by trust domain
-func (h *Handler) getBundle(ctx context.Context, trustDomainId string) (*common.Bundle, error) {
+func (h *Handler) getBundle(ctx context.Context, trustDomainId string, tolerateStale bool) (*common.Bundle, error) {
 	ds := h.c.Catalog.DataStores()[0]
 
 	resp, err := ds.FetchBundle(ctx, &datastore.FetchBundleRequest{
 		TrustDomainId: trustDomainId,
+		TolerateStale: tolerateStale,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch bundle: %v", err)
@@ -20,6 +20,7 @@ import (
 	"github.com/spiffe/spire/pkg/common/pemutil"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/pkg/server/util/regentryutil"
 	"github.com/spiffe/spire/proto/api/node"
 	"github.com/spiffe/spire/proto/common"
 	"github.com/spiffe/spire/proto/server/datastore"
@@ -341,6 +342,47 @@ func (s *HandlerSuite) TestAttestReattestation() {
 	s.Equal("", attestedNode.AttestationDataType)
 }
 
+func (s *HandlerSuite) TestAttestReattestationWithAgentSVID() {
+	s.addAttestor("test", fakeservernodeattestor.Config{
+		Data:      map[string]string{"data": "id"},
+		Selectors: map[string][]string{"id": {"label:new"}},
+	})
+
+	s.attestAgent()
+
+	// The agent presents its current SVID, so the attestor is not told the
+	// agent attested before.
+	upd := s.requireAttestSuccessWithClient(s.attestedClient, &node.AttestRequest{
+		AttestationData: makeAttestationData("test", "data"),
+		Csr:             s.makeCSR(agentID),
+	})
+	svidChain := s.assertSVIDsInUpdate(upd, agentID)[0]
+
+	// The attested node entry now tracks the new SVID and the selectors
+	// have been refreshed.
+	attestedNode := s.fetchAttestedNode(agentID)
+	s.Require().NotNil(attestedNode)
+	s.Equal(svidChain[0].SerialNumber.String(), attestedNode.CertSerialNumber)
+	s.Equal([]*common.Selector{{Type: "test", Value: "label:new"}}, s.getNodeSelectors(agentID))
+	s.assertLastLogMessageContains("Node re-attestation request")
+}
+
+func (s *HandlerSuite) TestAttestReattestationWithStaleAgentSVID() {
+	s.addAttestor("test", fakeservernodeattestor.Config{
+		Data: map[string]string{"data": "id"},
+	})
+
+	s.attestAgent()
+	s.updateAttestedNode(agentID, "NEWSERIAL", s.now)
+
+	// The SVID no longer matches the attested node, so this is treated as
+	// a regular attestation of an already attested agent.
+	s.requireAttestFailureWithClient(s.attestedClient, &node.AttestRequest{
+		AttestationData: makeAttestationData("test", "data"),
+		Csr:             s.makeCSR(agentID),
+	}, codes.Unknown, "reattestation is not permitted")
+}
+
 func (s *HandlerSuite) TestAttestChallengeResponseSuccess() {
 	// Make sure reattestation is allowed by the attestor
 	s.addAttestor("test", fakeservernodeattestor.Config{
@@ -398,6 +440,53 @@ func (s *HandlerSuite) TestAttestWithValidJoinToken() {
 	s.Nil(s.fetchJoinToken("TOKEN"))
 }
 
+func (s *HandlerSuite) TestAttestWithReusableJoinToken() {
+	s.createReusableJoinToken("TOKEN", s.now.Add(time.Second), 2)
+
+	// agents using a reusable token are assigned unique IDs
+	s.requireAttestSuccess(&node.AttestRequest{
+		AttestationData: makeAttestationData("join_token", "TOKEN"),
+		Csr:             s.makeCSR("spiffe://example.org/spire/agent/join_token/TOKEN/one"),
+	})
+	s.Require().NotNil(s.fetchJoinToken("TOKEN"))
+	s.Equal(int32(1), s.fetchJoinToken("TOKEN").Uses)
+
+	s.requireAttestSuccess(&node.AttestRequest{
+		AttestationData: makeAttestationData("join_token", "TOKEN"),
+		Csr:             s.makeCSR("spiffe://example.org/spire/agent/join_token/TOKEN/two"),
+	})
+
+	// join token should be removed once it has been used up
+	s.Nil(s.fetchJoinToken("TOKEN"))
+	s.requireAttestFailure(&node.AttestRequest{
+		AttestationData: makeAttestationData("join_token", "TOKEN"),
+		Csr:             s.makeCSR("spiffe://example.org/spire/agent/join_token/TOKEN/three"),
+	}, codes.Unknown, "failed to attest: no such token")
+}
+
+func (s *HandlerSuite) TestAttestWithSingleUseJoinTokenAndSuffix() {
+	s.createJoinToken("TOKEN", s.now.Add(time.Second))
+
+	s.requireAttestFailure(&node.AttestRequest{
+		AttestationData: makeAttestationData("join_token", "TOKEN"),
+		Csr:             s.makeCSR("spiffe://example.org/spire/agent/join_token/TOKEN/one"),
+	}, codes.Unknown, "failed to attest: agent ID suffix is only allowed with reusable join tokens")
+}
+
+func (s *HandlerSuite) TestAttestWithJoinTokenAndUnexpectedID() {
+	s.createReusableJoinToken("TOKEN", s.now.Add(time.Second), 2)
+
+	s.requireAttestFailure(&node.AttestRequest{
+		AttestationData: makeAttestationData("join_token", "TOKEN"),
+		Csr:             s.makeCSR("spiffe://example.org/spire/agent/join_token/TOKEN/one/two"),
+	}, codes.Unknown, "attestor returned unexpected response")
+
+	s.requireAttestFailure(&node.AttestRequest{
+		AttestationData: makeAttestationData("join_token", "TOKEN"),
+		Csr:             s.makeCSR("spiffe://example.org/spire/agent/join_token/OTHER"),
+	}, codes.Unknown, "attestor returned unexpected response")
+}
+
 func (s *HandlerSuite) TestAttestWithOnlyAttestorSelectors() {
 	// configure the attestor to return selectors
 	s.addAttestor("test", fakeservernodeattestor.Config{
@@ -516,6 +605,55 @@ func (s *HandlerSuite) TestFetchX509SVIDWithNoCSRs() {
 	s.Empty(upd.Svids)
 }
 
+func (s *HandlerSuite) TestFetchX509SVIDToleratesStaleDataWithoutCSRs() {
+	s.attestAgent()
+
+	s.createRegistrationEntry(&common.RegistrationEntry{
+		ParentId: agentID,
+		SpiffeId: workloadID,
+	})
+	ds := &staleReadsDataStore{DataStore: s.ds}
+	s.catalog.SetDataStores(ds)
+
+	// a sync without CSRs only reads data that may be slightly out of date
+	s.requireFetchX509SVIDSuccess(&node.FetchX509SVIDRequest{})
+	s.Equal([]bool{true}, ds.takeReads())
+
+	// the entries authorize the CSRs, so they are read from up-to-date data
+	s.requireFetchX509SVIDSuccess(&node.FetchX509SVIDRequest{
+		Csrs: s.makeCSRs(workloadID),
+	})
+	s.Equal([]bool{false}, ds.takeReads())
+}
+
+func (s *HandlerSuite) TestFetchX509SVIDReadsEntryCacheWithoutCSRs() {
+	s.attestAgent()
+
+	s.createRegistrationEntry(&common.RegistrationEntry{
+		ParentId: agentID,
+		SpiffeId: workloadID,
+	})
+	ds := &staleReadsDataStore{DataStore: s.ds}
+	s.catalog.SetDataStores(ds)
+	cache := regentryutil.NewCache(ds)
+	cache.Sync()
+	s.handler.c.EntryCache = cache
+
+	// the cache loads the entries once, and serves them after that
+	for i := 0; i < 2; i++ {
+		upd := s.requireFetchX509SVIDSuccess(&node.FetchX509SVIDRequest{})
+		s.Require().Len(upd.RegistrationEntries, 1)
+		s.Equal(workloadID, upd.RegistrationEntries[0].SpiffeId)
+	}
+	s.Equal(1, ds.takeEntryLists())
+
+	// the entries authorize the CSRs, so they are not read from the cache
+	s.requireFetchX509SVIDSuccess(&node.FetchX509SVIDRequest{
+		Csrs: s.makeCSRs(workloadID),
+	})
+	s.NotZero(ds.takeEntryLists())
+}
+
 func (s *HandlerSuite) TestFetchX509SVIDWithMalformedCSR() {
 	s.attestAgent()
 
@@ -816,6 +954,17 @@ func (s *HandlerSuite) createJoinToken(token string, expiresAt time.Time) {
 	s.Require().NoError(err)
 }
 
+func (s *HandlerSuite) createReusableJoinToken(token string, expiresAt time.Time, maxUses int32) {
+	_, err := s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{
+			Token:   token,
+			Expiry:  expiresAt.Unix(),
+			MaxUses: maxUses,
+		},
+	})
+	s.Require().NoError(err)
+}
+
 func (s *HandlerSuite) fetchJoinToken(token string) *datastore.JoinToken {
 	resp, err := s.ds.FetchJoinToken(context.Background(), &datastore.FetchJoinTokenRequest{
 		Token: token,
@@ -874,7 +1023,11 @@ func (s *HandlerSuite) createRegistrationEntry(entry *common.RegistrationEntry)
 }
 
 func (s *HandlerSuite) requireAttestSuccess(req *node.AttestRequest, responses ...string) *node.X509SVIDUpdate {
-	stream, err := s.unattestedClient.Attest(context.Background())
+	return s.requireAttestSuccessWithClient(s.unattestedClient, req, responses...)
+}
+
+func (s *HandlerSuite) requireAttestSuccessWithClient(client node.NodeClient, req *node.AttestRequest, responses ...string) *node.X509SVIDUpdate {
+	stream, err := client.Attest(context.Background())
 	s.Require().NoError(err)
 	s.Require().NoError(stream.Send(req))
 	for _, response := range responses {
@@ -897,7 +1050,11 @@ func (s *HandlerSuite) requireAttestSuccess(req *node.AttestRequest, responses .
 }
 
 func (s *HandlerSuite) requireAttestFailure(req *node.AttestRequest, errorCode codes.Code, errorContains string) {
-	stream, err := s.unattestedClient.Attest(context.Background())
+	s.requireAttestFailureWithClient(s.unattestedClient, req, errorCode, errorContains)
+}
+
+func (s *HandlerSuite) requireAttestFailureWithClient(client node.NodeClient, req *node.AttestRequest, errorCode codes.Code, errorContains string) {
+	stream, err := client.Attest(context.Background())
 	s.Require().NoError(err)
 	s.Require().NoError(stream.Send(req))
 	stream.CloseSend()
@@ -1126,3 +1283,65 @@ func withPeerCert(ctx context.Context, certChain []*x509.Certificate) context.Co
 		},
 	})
 }
+
+// staleReadsDataStore records whether the reads that can be answered from
+// stale data tolerated it
+type staleReadsDataStore struct {
+	*fakedatastore.DataStore
+
+	mu         sync.Mutex
+	reads      map[bool]bool
+	entryLists int
+}
+
+func (ds *staleReadsDataStore) FetchBundle(ctx context.Context, req *datastore.FetchBundleRequest) (*datastore.FetchBundleResponse, error) {
+	ds.recordRead(req.TolerateStale)
+	return ds.DataStore.FetchBundle(ctx, req)
+}
+
+func (ds *staleReadsDataStore) GetNodeSelectors(ctx context.Context, req *datastore.GetNodeSelectorsRequest) (*datastore.GetNodeSelectorsResponse, error) {
+	ds.recordRead(req.TolerateStale)
+	return ds.DataStore.GetNodeSelectors(ctx, req)
+}
+
+func (ds *staleReadsDataStore) ListRegistrationEntries(ctx context.Context, req *datastore.ListRegistrationEntriesRequest) (*datastore.ListRegistrationEntriesResponse, error) {
+	ds.recordRead(req.TolerateStale)
+	ds.mu.Lock()
+	ds.entryLists++
+	ds.mu.Unlock()
+	return ds.DataStore.ListRegistrationEntries(ctx, req)
+}
+
+// takeEntryLists returns the number of times registration entries were listed
+// since the last call
+func (ds *staleReadsDataStore) takeEntryLists() int {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	lists := ds.entryLists
+	ds.entryLists = 0
+	return lists
+}
+
+func (ds *staleReadsDataStore) recordRead(tolerateStale bool) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if ds.reads == nil {
+		ds.reads = make(map[bool]bool)
+	}
+	ds.reads[tolerateStale] = true
+}
+
+// takeReads returns the distinct TolerateStale values of the reads since the
+// last call
+func (ds *staleReadsDataStore) takeReads() []bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	var reads []bool
+	for _, tolerateStale := range []bool{false, true} {
+		if ds.reads[tolerateStale] {
+			reads = append(reads, tolerateStale)
+		}
+	}
+	ds.reads = nil
+	return reads
+}
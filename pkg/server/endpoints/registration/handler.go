@@ -27,6 +27,10 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// maxBatchSize is the maximum number of entries accepted by the batch
+// entry operations.
+const maxBatchSize = 1000
+
 //Service is used to register SPIFFE IDs, and the attestation logic that should
 //be performed on a workload before those IDs can be issued.
 type Handler struct {
@@ -147,6 +151,36 @@ func (h *Handler) FetchEntries(
 	}, nil
 }
 
+// ListEntries returns a page of registration entries
+func (h *Handler) ListEntries(
+	ctx context.Context, request *registration.ListEntriesRequest) (
+	response *registration.ListEntriesResponse, err error) {
+
+	counter, err := h.startCall(ctx, "registration_api", "entry", "list")
+	if err != nil {
+		return nil, err
+	}
+	defer counter.Done(&err)
+
+	pagination, err := paginationToDataStore(request.Pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := h.getDataStore()
+	listResponse, err := ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{
+		Pagination: pagination,
+	})
+	if err != nil {
+		h.Log.Error(err)
+		return nil, errors.New("Error trying to list entries")
+	}
+	return &registration.ListEntriesResponse{
+		Entries:    listResponse.Entries,
+		Pagination: paginationFromDataStore(request.Pagination, listResponse.Pagination, len(listResponse.Entries)),
+	}, nil
+}
+
 func (h *Handler) UpdateEntry(
 	ctx context.Context, request *registration.UpdateEntryRequest) (
 	response *common.RegistrationEntry, err error) {
@@ -181,6 +215,135 @@ func (h *Handler) UpdateEntry(
 	return resp.Entry, nil
 }
 
+//Creates several entries at once. No entry is created if any of them
+//is invalid or already exists.
+func (h *Handler) CreateEntries(
+	ctx context.Context, request *registration.CreateEntriesRequest) (
+	response *registration.CreateEntriesResponse, err error) {
+
+	counter, err := h.startCall(ctx, "registration_api", "entry", "batch_create")
+	if err != nil {
+		return nil, err
+	}
+	defer counter.Done(&err)
+
+	if err := checkBatchSize(len(request.Entries)); err != nil {
+		h.Log.Error(err)
+		return nil, err
+	}
+
+	entries := make([]*common.RegistrationEntry, 0, len(request.Entries))
+	for i, entry := range request.Entries {
+		entry, err = h.prepareRegistrationEntry(entry, false)
+		if err != nil {
+			err = fmt.Errorf("entry %d: %v", i, err)
+			h.Log.Error(err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if i, j, ok := findDuplicateEntries(entries); ok {
+		err = fmt.Errorf("entry %d: Entry is a duplicate of entry %d", j, i)
+		h.Log.Error(err)
+		return nil, err
+	}
+
+	ds := h.getDataStore()
+
+	for i, entry := range entries {
+		unique, err := h.isEntryUnique(ctx, ds, entry)
+		if err != nil {
+			h.Log.Error(err)
+			return nil, errors.New("Error trying to create entries")
+		}
+		if !unique {
+			err = fmt.Errorf("entry %d: Entry already exists", i)
+			h.Log.Error(err)
+			return nil, err
+		}
+	}
+
+	resp, err := ds.BatchCreateRegistrationEntries(ctx,
+		&datastore.BatchCreateRegistrationEntriesRequest{Entries: entries},
+	)
+	if err != nil {
+		h.Log.Error(err)
+		return nil, errors.New("Error trying to create entries")
+	}
+
+	return &registration.CreateEntriesResponse{Entries: resp.Entries}, nil
+}
+
+//Updates several entries at once.
+func (h *Handler) UpdateEntries(
+	ctx context.Context, request *registration.UpdateEntriesRequest) (
+	response *registration.UpdateEntriesResponse, err error) {
+
+	counter, err := h.startCall(ctx, "registration_api", "entry", "batch_update")
+	if err != nil {
+		return nil, err
+	}
+	defer counter.Done(&err)
+
+	if err := checkBatchSize(len(request.Entries)); err != nil {
+		h.Log.Error(err)
+		return nil, err
+	}
+
+	entries := make([]*common.RegistrationEntry, 0, len(request.Entries))
+	for i, entry := range request.Entries {
+		entry, err = h.prepareRegistrationEntry(entry, true)
+		if err != nil {
+			err = fmt.Errorf("entry %d: %v", i, err)
+			h.Log.Error(err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	ds := h.getDataStore()
+	resp, err := ds.BatchUpdateRegistrationEntries(ctx, &datastore.BatchUpdateRegistrationEntriesRequest{
+		Entries: entries,
+	})
+	if err != nil {
+		h.Log.Error(err)
+		return nil, fmt.Errorf("Failed to update registration entries: %v", err)
+	}
+
+	h.Metrics.IncrCounter([]string{"registration_api", "entry", "updated"}, float32(len(resp.Entries)))
+
+	return &registration.UpdateEntriesResponse{Entries: resp.Entries}, nil
+}
+
+//Deletes several entries at once.
+func (h *Handler) DeleteEntries(
+	ctx context.Context, request *registration.DeleteEntriesRequest) (
+	response *registration.DeleteEntriesResponse, err error) {
+
+	counter, err := h.startCall(ctx, "registration_api", "entry", "batch_delete")
+	if err != nil {
+		return nil, err
+	}
+	defer counter.Done(&err)
+
+	if err := checkBatchSize(len(request.Ids)); err != nil {
+		h.Log.Error(err)
+		return nil, err
+	}
+
+	ds := h.getDataStore()
+	resp, err := ds.BatchDeleteRegistrationEntries(ctx, &datastore.BatchDeleteRegistrationEntriesRequest{
+		EntryIds: request.Ids,
+	})
+	if err != nil {
+		h.Log.Error(err)
+		return nil, err
+	}
+
+	return &registration.DeleteEntriesResponse{Entries: resp.Entries}, nil
+}
+
 //Returns all the Entries associated with the ParentID value
 func (h *Handler) ListByParentID(
 	ctx context.Context, request *registration.ParentID) (
@@ -481,6 +644,9 @@ func (h *Handler) CreateJoinToken(
 	if request.Ttl < 1 {
 		return nil, errors.New("Ttl is required, you must provide one")
 	}
+	if request.MaxUses < 0 {
+		return nil, errors.New("MaxUses cannot be negative")
+	}
 
 	// Generate a token if one wasn't specified
 	if request.Token == "" {
@@ -496,8 +662,9 @@ func (h *Handler) CreateJoinToken(
 
 	_, err = ds.CreateJoinToken(ctx, &datastore.CreateJoinTokenRequest{
 		JoinToken: &datastore.JoinToken{
-			Token:  request.Token,
-			Expiry: expiry,
+			Token:   request.Token,
+			Expiry:  expiry,
+			MaxUses: request.MaxUses,
 		},
 	})
 	if err != nil {
@@ -508,6 +675,69 @@ func (h *Handler) CreateJoinToken(
 	return request, nil
 }
 
+// ListJoinTokens returns the outstanding join tokens
+func (h *Handler) ListJoinTokens(
+	ctx context.Context, request *registration.ListJoinTokensRequest) (
+	response *registration.ListJoinTokensResponse, err error) {
+
+	counter, err := h.startCall(ctx, "registration_api", "join_token", "list")
+	if err != nil {
+		return nil, err
+	}
+	defer counter.Done(&err)
+
+	ds := h.getDataStore()
+	resp, err := ds.ListJoinTokens(ctx, &datastore.ListJoinTokensRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	response = &registration.ListJoinTokensResponse{}
+	for _, joinToken := range resp.JoinTokens {
+		response.JoinTokens = append(response.JoinTokens, joinTokenFromDataStore(joinToken))
+	}
+	return response, nil
+}
+
+// RevokeJoinToken deletes an outstanding join token so it can no longer be
+// used to attest
+func (h *Handler) RevokeJoinToken(
+	ctx context.Context, request *registration.RevokeJoinTokenRequest) (
+	response *registration.RevokeJoinTokenResponse, err error) {
+
+	counter, err := h.startCall(ctx, "registration_api", "join_token", "revoke")
+	if err != nil {
+		return nil, err
+	}
+	defer counter.Done(&err)
+
+	if request.Token == "" {
+		return nil, errors.New("token is required")
+	}
+
+	ds := h.getDataStore()
+	fetchResp, err := ds.FetchJoinToken(ctx, &datastore.FetchJoinTokenRequest{
+		Token: request.Token,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if fetchResp.JoinToken == nil {
+		return nil, status.Error(codes.NotFound, "no such token")
+	}
+
+	deleteResp, err := ds.DeleteJoinToken(ctx, &datastore.DeleteJoinTokenRequest{
+		Token: request.Token,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &registration.RevokeJoinTokenResponse{
+		JoinToken: joinTokenFromDataStore(deleteResp.JoinToken),
+	}, nil
+}
+
 // FetchBundle retrieves the CA bundle.
 func (h *Handler) FetchBundle(
 	ctx context.Context, request *common.Empty) (
@@ -553,13 +783,23 @@ func (h *Handler) EvictAgent(ctx context.Context, evictRequest *registration.Evi
 
 //ListAgents returns the list of attested nodes
 func (h *Handler) ListAgents(ctx context.Context, listReq *registration.ListAgentsRequest) (*registration.ListAgentsResponse, error) {
+	pagination, err := paginationToDataStore(listReq.Pagination)
+	if err != nil {
+		return nil, err
+	}
+
 	dataStore := h.Catalog.DataStores()[0]
-	req := &datastore.ListAttestedNodesRequest{}
+	req := &datastore.ListAttestedNodesRequest{
+		Pagination: pagination,
+	}
 	resp, err := dataStore.ListAttestedNodes(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	return &registration.ListAgentsResponse{Nodes: resp.Nodes}, nil
+	return &registration.ListAgentsResponse{
+		Nodes:      resp.Nodes,
+		Pagination: paginationFromDataStore(listReq.Pagination, resp.Pagination, len(resp.Nodes)),
+	}, nil
 }
 
 func (h *Handler) deleteAttestedNode(ctx context.Context, agentID string) (*common.AttestedNode, error) {
@@ -629,6 +869,10 @@ func (h *Handler) prepareRegistrationEntry(entry *common.RegistrationEntry, forU
 		return nil, err
 	}
 
+	if entry.EntryExpiry < 0 {
+		return nil, errors.New("entry expiry cannot be negative")
+	}
+
 	return entry, nil
 }
 
@@ -656,6 +900,15 @@ func cloneRegistrationEntry(entry *common.RegistrationEntry) *common.Registratio
 	return proto.Clone(entry).(*common.RegistrationEntry)
 }
 
+func joinTokenFromDataStore(joinToken *datastore.JoinToken) *registration.JoinToken {
+	return &registration.JoinToken{
+		Token:   joinToken.Token,
+		MaxUses: joinToken.MaxUses,
+		Uses:    joinToken.Uses,
+		Expiry:  joinToken.Expiry,
+	}
+}
+
 func convertDeleteBundleMode(in registration.DeleteFederatedBundleRequest_Mode) (datastore.DeleteBundleRequest_Mode, error) {
 	switch in {
 	case registration.DeleteFederatedBundleRequest_RESTRICT:
@@ -740,3 +993,58 @@ func getCallerID(ctx context.Context) string {
 	callerID, _ := ctx.Value(callerIDKey{}).(string)
 	return callerID
 }
+
+// paginationToDataStore converts the pagination of a listing request into the
+// datastore one. Listings are not paginated if the page size is zero.
+func checkBatchSize(size int) error {
+	if size > maxBatchSize {
+		return fmt.Errorf("batch of %d entries exceeds the maximum of %d", size, maxBatchSize)
+	}
+	return nil
+}
+
+// findDuplicateEntries returns the indices of the first two entries in the
+// batch that share the same SPIFFE ID, parent ID and selectors.
+func findDuplicateEntries(entries []*common.RegistrationEntry) (int, int, bool) {
+	seen := make(map[string][]int)
+	for j, entry := range entries {
+		key := entry.SpiffeId + "|" + entry.ParentId
+		entrySelSet := selector.NewSetFromRaw(entry.Selectors)
+		for _, i := range seen[key] {
+			if selector.NewSetFromRaw(entries[i].Selectors).Equal(entrySelSet) {
+				return i, j, true
+			}
+		}
+		seen[key] = append(seen[key], j)
+	}
+	return 0, 0, false
+}
+
+func paginationToDataStore(p *registration.Pagination) (*datastore.Pagination, error) {
+	if p == nil || p.PageSize == 0 {
+		return nil, nil
+	}
+	if p.PageSize < 0 {
+		return nil, fmt.Errorf("invalid page size %d", p.PageSize)
+	}
+	return &datastore.Pagination{
+		Token:    p.Token,
+		PageSize: p.PageSize,
+	}, nil
+}
+
+// paginationFromDataStore returns the pagination to request the page after
+// the one listed. A page with fewer items than the page size is the last one,
+// which is signaled by an empty token.
+func paginationFromDataStore(req *registration.Pagination, p *datastore.Pagination, count int) *registration.Pagination {
+	if req == nil || req.PageSize == 0 {
+		return nil
+	}
+	next := &registration.Pagination{
+		PageSize: req.PageSize,
+	}
+	if p != nil && count == int(req.PageSize) {
+		next.Token = p.Token
+	}
+	return next
+}
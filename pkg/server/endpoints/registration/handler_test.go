@@ -401,6 +401,16 @@ func (s *HandlerSuite) TestCreateEntry() {
 			Entry: &common.RegistrationEntry{ParentId: "spiffe://example.org/parent", SpiffeId: "FOO"},
 			Err:   `"FOO" is not a valid workload SPIFFE ID`,
 		},
+		{
+			Name: "Entry expiry is negative",
+			Entry: &common.RegistrationEntry{
+				ParentId:    "spiffe://example.org/parent",
+				SpiffeId:    "spiffe://example.org/child",
+				Selectors:   []*common.Selector{{Type: "B", Value: "b"}},
+				EntryExpiry: -1,
+			},
+			Err: "entry expiry cannot be negative",
+		},
 		{
 			Name: "Success",
 			Entry: &common.RegistrationEntry{
@@ -536,6 +546,139 @@ func (s *HandlerSuite) TestDeleteEntry() {
 	}
 }
 
+func (s *HandlerSuite) TestCreateEntries() {
+	existing := s.createRegistrationEntry(&common.RegistrationEntry{
+		ParentId:  "spiffe://example.org/parent",
+		SpiffeId:  "spiffe://example.org/existing",
+		Selectors: []*common.Selector{{Type: "A", Value: "a"}},
+	})
+
+	newEntry := func(name string) *common.RegistrationEntry {
+		return &common.RegistrationEntry{
+			ParentId:  "spiffe://example.org/parent",
+			SpiffeId:  "spiffe://example.org/" + name,
+			Selectors: []*common.Selector{{Type: "B", Value: "b"}},
+		}
+	}
+
+	tooMany := make([]*common.RegistrationEntry, maxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = newEntry("foo")
+	}
+
+	testCases := []struct {
+		Name    string
+		Entries []*common.RegistrationEntry
+		Err     string
+	}{
+		{
+			Name:    "Entry is malformed",
+			Entries: []*common.RegistrationEntry{newEntry("one"), {ParentId: "FOO"}},
+			Err:     `entry 1: "FOO" is not a valid SPIFFE ID`,
+		},
+		{
+			Name:    "Entry is duplicated in the batch",
+			Entries: []*common.RegistrationEntry{newEntry("one"), newEntry("two"), newEntry("one")},
+			Err:     "entry 2: Entry is a duplicate of entry 0",
+		},
+		{
+			Name: "Entry already exists",
+			Entries: []*common.RegistrationEntry{newEntry("one"), {
+				ParentId:  existing.ParentId,
+				SpiffeId:  existing.SpiffeId,
+				Selectors: existing.Selectors,
+			}},
+			Err: "entry 1: Entry already exists",
+		},
+		{
+			Name:    "Too many entries",
+			Entries: tooMany,
+			Err:     "batch of 1001 entries exceeds the maximum of 1000",
+		},
+		{
+			Name:    "Success",
+			Entries: []*common.RegistrationEntry{newEntry("one"), newEntry("two")},
+		},
+	}
+
+	for _, testCase := range testCases {
+		s.T().Run(testCase.Name, func(t *testing.T) {
+			resp, err := s.handler.CreateEntries(context.Background(), &registration.CreateEntriesRequest{
+				Entries: testCase.Entries,
+			})
+			if testCase.Err != "" {
+				requireErrorContains(t, err, testCase.Err)
+
+				// nothing should have been created
+				entries, err := s.ds.ListRegistrationEntries(context.Background(), &datastore.ListRegistrationEntriesRequest{})
+				require.NoError(t, err)
+				require.Len(t, entries.Entries, 1)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, resp.Entries, len(testCase.Entries))
+			for i, entry := range resp.Entries {
+				require.NotEmpty(t, entry.EntryId)
+				require.Equal(t, testCase.Entries[i].SpiffeId, entry.SpiffeId)
+			}
+		})
+	}
+}
+
+func (s *HandlerSuite) TestUpdateEntries() {
+	one := s.createRegistrationEntry(&common.RegistrationEntry{
+		ParentId:  "spiffe://example.org/parent",
+		SpiffeId:  "spiffe://example.org/one",
+		Selectors: []*common.Selector{{Type: "A", Value: "a"}},
+	})
+	two := s.createRegistrationEntry(&common.RegistrationEntry{
+		ParentId:  "spiffe://example.org/parent",
+		SpiffeId:  "spiffe://example.org/two",
+		Selectors: []*common.Selector{{Type: "A", Value: "a"}},
+	})
+
+	// malformed entry
+	_, err := s.handler.UpdateEntries(context.Background(), &registration.UpdateEntriesRequest{
+		Entries: []*common.RegistrationEntry{one, {EntryId: two.EntryId, ParentId: "FOO"}},
+	})
+	s.requireErrorContains(err, `entry 1: "FOO" is not a valid SPIFFE ID`)
+
+	one.Ttl = 10
+	two.Ttl = 20
+	resp, err := s.handler.UpdateEntries(context.Background(), &registration.UpdateEntriesRequest{
+		Entries: []*common.RegistrationEntry{one, two},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(resp.Entries, 2)
+	s.Require().Equal(int32(10), resp.Entries[0].Ttl)
+	s.Require().Equal(int32(20), resp.Entries[1].Ttl)
+}
+
+func (s *HandlerSuite) TestDeleteEntries() {
+	one := s.createRegistrationEntry(&common.RegistrationEntry{
+		ParentId:  "spiffe://example.org/parent",
+		SpiffeId:  "spiffe://example.org/one",
+		Selectors: []*common.Selector{{Type: "A", Value: "a"}},
+	})
+	two := s.createRegistrationEntry(&common.RegistrationEntry{
+		ParentId:  "spiffe://example.org/parent",
+		SpiffeId:  "spiffe://example.org/two",
+		Selectors: []*common.Selector{{Type: "A", Value: "a"}},
+	})
+
+	resp, err := s.handler.DeleteEntries(context.Background(), &registration.DeleteEntriesRequest{
+		Ids: []string{two.EntryId, one.EntryId},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(resp.Entries, 2)
+	s.Require().Equal(two.EntryId, resp.Entries[0].EntryId)
+	s.Require().Equal(one.EntryId, resp.Entries[1].EntryId)
+
+	entries, err := s.ds.ListRegistrationEntries(context.Background(), &datastore.ListRegistrationEntriesRequest{})
+	s.Require().NoError(err)
+	s.Require().Empty(entries.Entries)
+}
+
 func (s *HandlerSuite) TestFetchEntry() {
 	entry := s.createRegistrationEntry(&common.RegistrationEntry{
 		ParentId:  "spiffe://example.org/foo",
@@ -604,6 +747,59 @@ func (s *HandlerSuite) TestFetchEntries() {
 	s.Require().True(proto.Equal(entry2, resp.Entries[1]))
 }
 
+func (s *HandlerSuite) TestListEntries() {
+	for _, spiffeID := range []string{"bar", "baz", "qux"} {
+		s.createRegistrationEntry(&common.RegistrationEntry{
+			ParentId:  "spiffe://example.org/foo",
+			SpiffeId:  "spiffe://example.org/" + spiffeID,
+			Selectors: []*common.Selector{{Type: "A", Value: "a"}},
+		})
+	}
+
+	// Not paginated
+	resp, err := s.handler.ListEntries(context.Background(), &registration.ListEntriesRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(resp.Entries, 3)
+	s.Require().Nil(resp.Pagination)
+
+	// Paginated, with an entry listed in the first page deleted before
+	// listing the second one
+	var listed []*common.RegistrationEntry
+	pagination := &registration.Pagination{PageSize: 2}
+	for pages := 0; ; pages++ {
+		s.Require().True(pages < 3, "too many pages")
+		resp, err := s.handler.ListEntries(context.Background(), &registration.ListEntriesRequest{
+			Pagination: pagination,
+		})
+		s.Require().NoError(err)
+		s.Require().True(len(resp.Entries) <= 2)
+		listed = append(listed, resp.Entries...)
+		if resp.Pagination.Token == "" {
+			break
+		}
+		pagination = resp.Pagination
+
+		if pages == 0 {
+			_, err := s.ds.DeleteRegistrationEntry(context.Background(), &datastore.DeleteRegistrationEntryRequest{
+				EntryId: resp.Entries[0].EntryId,
+			})
+			s.Require().NoError(err)
+		}
+	}
+	s.Require().Len(listed, 3)
+	ids := make(map[string]bool)
+	for _, entry := range listed {
+		ids[entry.EntryId] = true
+	}
+	s.Require().Len(ids, 3)
+
+	// Invalid page size
+	_, err = s.handler.ListEntries(context.Background(), &registration.ListEntriesRequest{
+		Pagination: &registration.Pagination{PageSize: -1},
+	})
+	s.Require().EqualError(err, "rpc error: code = Unknown desc = invalid page size -1")
+}
+
 func (s *HandlerSuite) TestListByParentId() {
 	entry1 := s.createRegistrationEntry(&common.RegistrationEntry{
 		ParentId:  "spiffe://example.org/foo",
@@ -759,6 +955,55 @@ func (s *HandlerSuite) TestCreateJoinToken() {
 	resp, err = s.handler.CreateJoinToken(context.Background(), &registration.JoinToken{Token: "foo", Ttl: 1})
 	s.requireErrorContains(err, "Failed to register token")
 	s.Require().Nil(resp)
+
+	// Negative max uses
+	resp, err = s.handler.CreateJoinToken(context.Background(), &registration.JoinToken{Token: "bar", Ttl: 1, MaxUses: -1})
+	s.requireErrorContains(err, "MaxUses cannot be negative")
+	s.Require().Nil(resp)
+
+	// Reusable token
+	resp, err = s.handler.CreateJoinToken(context.Background(), &registration.JoinToken{Token: "bar", Ttl: 1, MaxUses: 3})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &registration.JoinToken{Token: "bar", Ttl: 1, MaxUses: 3})
+}
+
+func (s *HandlerSuite) TestListJoinTokens() {
+	// No tokens
+	resp, err := s.handler.ListJoinTokens(context.Background(), &registration.ListJoinTokensRequest{})
+	s.Require().NoError(err)
+	s.Require().Empty(resp.JoinTokens)
+
+	s.createJoinToken(&datastore.JoinToken{Token: "bar", Expiry: 2, MaxUses: 3, Uses: 1})
+	s.createJoinToken(&datastore.JoinToken{Token: "foo", Expiry: 1})
+
+	resp, err = s.handler.ListJoinTokens(context.Background(), &registration.ListJoinTokensRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal([]*registration.JoinToken{
+		{Token: "bar", Expiry: 2, MaxUses: 3, Uses: 1},
+		{Token: "foo", Expiry: 1},
+	}, resp.JoinTokens)
+}
+
+func (s *HandlerSuite) TestRevokeJoinToken() {
+	// Missing token
+	resp, err := s.handler.RevokeJoinToken(context.Background(), &registration.RevokeJoinTokenRequest{})
+	s.requireErrorContains(err, "token is required")
+	s.Require().Nil(resp)
+
+	// Unknown token
+	resp, err = s.handler.RevokeJoinToken(context.Background(), &registration.RevokeJoinTokenRequest{Token: "foo"})
+	s.requireErrorContains(err, "no such token")
+	s.Require().Nil(resp)
+
+	// Success
+	s.createJoinToken(&datastore.JoinToken{Token: "foo", Expiry: 1, MaxUses: 2})
+	resp, err = s.handler.RevokeJoinToken(context.Background(), &registration.RevokeJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal(&registration.JoinToken{Token: "foo", Expiry: 1, MaxUses: 2}, resp.JoinToken)
+
+	fetchResp, err := s.ds.FetchJoinToken(context.Background(), &datastore.FetchJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Nil(fetchResp.JoinToken)
 }
 
 func (s *HandlerSuite) TestFetchBundle() {
@@ -828,6 +1073,30 @@ func (s *HandlerSuite) TestListAgents() {
 	s.Equal(listResponse.Nodes, expectedNodeList)
 }
 
+func (s *HandlerSuite) TestListAgentsWithPagination() {
+	ctx := context.Background()
+	spiffeID1 := "spiffe://example.org/spire/agent/join_token/token_a"
+	spiffeID2 := "spiffe://example.org/spire/agent/join_token/token_b"
+	spiffeID3 := "spiffe://example.org/spire/agent/join_token/token_c"
+	s.createAttestedNode(spiffeID1)
+	s.createAttestedNode(spiffeID2)
+	s.createAttestedNode(spiffeID3)
+
+	listResponse, err := s.handler.ListAgents(ctx, &registration.ListAgentsRequest{
+		Pagination: &registration.Pagination{PageSize: 2},
+	})
+	s.Require().NoError(err)
+	s.Equal([]*common.AttestedNode{{SpiffeId: spiffeID1}, {SpiffeId: spiffeID2}}, listResponse.Nodes)
+	s.Equal(&registration.Pagination{Token: spiffeID2, PageSize: 2}, listResponse.Pagination)
+
+	listResponse, err = s.handler.ListAgents(ctx, &registration.ListAgentsRequest{
+		Pagination: listResponse.Pagination,
+	})
+	s.Require().NoError(err)
+	s.Equal([]*common.AttestedNode{{SpiffeId: spiffeID3}}, listResponse.Nodes)
+	s.Equal(&registration.Pagination{PageSize: 2}, listResponse.Pagination)
+}
+
 func (s *HandlerSuite) TestListWithNoAgents() {
 	// Creating attested nodes list
 	ctx := context.Background()
@@ -951,6 +1220,13 @@ func (s *HandlerSuite) createBundle(bundle *datastore.Bundle) {
 	s.Require().NoError(err)
 }
 
+func (s *HandlerSuite) createJoinToken(joinToken *datastore.JoinToken) {
+	_, err := s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: joinToken,
+	})
+	s.Require().NoError(err)
+}
+
 func (s *HandlerSuite) createRegistrationEntry(entry *common.RegistrationEntry) *common.RegistrationEntry {
 	resp, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
 		Entry: entry,
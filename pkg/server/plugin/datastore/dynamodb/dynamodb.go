@@ -0,0 +1,1247 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/gofrs/uuid"
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/pkg/common/idutil"
+	"github.com/spiffe/spire/pkg/common/selector"
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/zeebo/errs"
+)
+
+var (
+	pluginInfo = spi.GetPluginInfoResponse{
+		Description: "",
+		DateCreated: "",
+		Version:     "",
+		Author:      "",
+		Company:     "",
+	}
+
+	dynamoError = errs.Class("datastore-dynamodb")
+)
+
+type configuration struct {
+	TableName       string `hcl:"table_name" json:"table_name"`
+	Region          string `hcl:"region" json:"region"`
+	Endpoint        string `hcl:"endpoint" json:"endpoint"`
+	AccessKeyID     string `hcl:"access_key_id" json:"access_key_id"`
+	SecretAccessKey string `hcl:"secret_access_key" json:"secret_access_key"`
+}
+
+type dynamoPlugin struct {
+	mu    sync.RWMutex
+	table *table
+
+	hooks struct {
+		newClient func(config *configuration) (dynamoClient, error)
+	}
+}
+
+var _ datastore.Plugin = (*dynamoPlugin)(nil)
+
+func newPlugin() *dynamoPlugin {
+	p := &dynamoPlugin{}
+	p.hooks.newClient = newDynamoClient
+	return p
+}
+
+// New creates a new dynamodb plugin struct. Configure must be called
+// in order to use the table.
+func New() datastore.Plugin {
+	return newPlugin()
+}
+
+// CreateBundle stores the given bundle
+func (ds *dynamoPlugin) CreateBundle(ctx context.Context, req *datastore.CreateBundleRequest) (*datastore.CreateBundleResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := bundleToItem(req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	switch err := t.put(ctx, it); {
+	case err == errConflict:
+		return nil, dynamoError.New("bundle %q already exists", it.key)
+	case err != nil:
+		return nil, err
+	}
+
+	return &datastore.CreateBundleResponse{
+		Bundle: req.Bundle,
+	}, nil
+}
+
+// UpdateBundle updates an existing bundle with the given CAs. Overwrites any
+// existing certificates.
+func (ds *dynamoPlugin) UpdateBundle(ctx context.Context, req *datastore.UpdateBundleRequest) (*datastore.UpdateBundleResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	newItem, err := bundleToItem(req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := withRetries(ctx, func() error {
+		it, err := t.get(ctx, kindBundle, newItem.key)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			return errNotFound()
+		}
+		it.data = newItem.data
+		return t.put(ctx, it)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.UpdateBundleResponse{
+		Bundle: req.Bundle,
+	}, nil
+}
+
+// AppendBundle adds the CAs and keys of the given bundle to the existing
+// one, creating it if it does not exist
+func (ds *dynamoPlugin) AppendBundle(ctx context.Context, req *datastore.AppendBundleRequest) (*datastore.AppendBundleResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	newItem, err := bundleToItem(req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle *datastore.Bundle
+	if err := withRetries(ctx, func() error {
+		it, err := t.get(ctx, kindBundle, newItem.key)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			bundle = req.Bundle
+			newItem.version = 0
+			return t.put(ctx, newItem)
+		}
+
+		existing := new(datastore.Bundle)
+		if err := unmarshalItem(it, existing); err != nil {
+			return err
+		}
+
+		var changed bool
+		bundle, changed = bundleutil.MergeBundles(existing, req.Bundle)
+		if !changed {
+			return nil
+		}
+		if it.data, err = proto.Marshal(bundle); err != nil {
+			return dynamoError.Wrap(err)
+		}
+		return t.put(ctx, it)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.AppendBundleResponse{
+		Bundle: bundle,
+	}, nil
+}
+
+// DeleteBundle deletes the bundle with the given trust domain. Registration
+// entries federated with it are handled according to the request mode.
+func (ds *dynamoPlugin) DeleteBundle(ctx context.Context, req *datastore.DeleteBundleRequest) (*datastore.DeleteBundleResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := t.get(ctx, kindBundle, req.TrustDomainId)
+	if err != nil {
+		return nil, err
+	}
+	if it == nil {
+		return nil, errNotFound()
+	}
+
+	entries, err := listEntries(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	var federatedEntries []*common.RegistrationEntry
+	for _, entry := range entries {
+		if federatesWith(entry, req.TrustDomainId) {
+			federatedEntries = append(federatedEntries, entry)
+		}
+	}
+
+	if len(federatedEntries) > 0 {
+		switch req.Mode {
+		case datastore.DeleteBundleRequest_DELETE:
+			for _, entry := range federatedEntries {
+				if _, err := deleteEntry(ctx, t, entry.EntryId); err != nil {
+					return nil, err
+				}
+			}
+		case datastore.DeleteBundleRequest_DISSOCIATE:
+			for _, entry := range federatedEntries {
+				if err := dissociateEntry(ctx, t, entry.EntryId, req.TrustDomainId); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, dynamoError.New("cannot delete bundle; federated with %d registration entries", len(federatedEntries))
+		}
+	}
+
+	bundle := new(datastore.Bundle)
+	if err := withRetries(ctx, func() error {
+		it, err := t.get(ctx, kindBundle, req.TrustDomainId)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			return errNotFound()
+		}
+		if err := unmarshalItem(it, bundle); err != nil {
+			return err
+		}
+		return t.delete(ctx, it)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.DeleteBundleResponse{
+		Bundle: bundle,
+	}, nil
+}
+
+// FetchBundle returns the bundle matching the specified Trust Domain.
+func (ds *dynamoPlugin) FetchBundle(ctx context.Context, req *datastore.FetchBundleRequest) (*datastore.FetchBundleResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := t.get(ctx, kindBundle, req.TrustDomainId)
+	if err != nil {
+		return nil, err
+	}
+	if it == nil {
+		return &datastore.FetchBundleResponse{}, nil
+	}
+
+	bundle := new(datastore.Bundle)
+	if err := unmarshalItem(it, bundle); err != nil {
+		return nil, err
+	}
+
+	return &datastore.FetchBundleResponse{
+		Bundle: bundle,
+	}, nil
+}
+
+// ListBundles can be used to fetch all existing bundles.
+func (ds *dynamoPlugin) ListBundles(ctx context.Context, req *datastore.ListBundlesRequest) (*datastore.ListBundlesResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := t.list(ctx, kindBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &datastore.ListBundlesResponse{}
+	for _, it := range items {
+		bundle := new(datastore.Bundle)
+		if err := unmarshalItem(it, bundle); err != nil {
+			return nil, err
+		}
+		resp.Bundles = append(resp.Bundles, bundle)
+	}
+	return resp, nil
+}
+
+// CreateAttestedNode stores the given attested node
+func (ds *dynamoPlugin) CreateAttestedNode(ctx context.Context,
+	req *datastore.CreateAttestedNodeRequest) (*datastore.CreateAttestedNodeResponse, error) {
+
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Node == nil {
+		return nil, dynamoError.New("invalid request: missing attested node")
+	}
+
+	node := &datastore.AttestedNode{
+		SpiffeId:            req.Node.SpiffeId,
+		AttestationDataType: req.Node.AttestationDataType,
+		CertSerialNumber:    req.Node.CertSerialNumber,
+		CertNotAfter:        req.Node.CertNotAfter,
+	}
+	it, err := newItem(kindNode, node.SpiffeId, node)
+	if err != nil {
+		return nil, err
+	}
+
+	switch err := t.put(ctx, it); {
+	case err == errConflict:
+		return nil, dynamoError.New("attested node %q already exists", node.SpiffeId)
+	case err != nil:
+		return nil, err
+	}
+
+	return &datastore.CreateAttestedNodeResponse{
+		Node: node,
+	}, nil
+}
+
+// FetchAttestedNode fetches an existing attested node by SPIFFE ID
+func (ds *dynamoPlugin) FetchAttestedNode(ctx context.Context,
+	req *datastore.FetchAttestedNodeRequest) (*datastore.FetchAttestedNodeResponse, error) {
+
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := t.get(ctx, kindNode, req.SpiffeId)
+	if err != nil {
+		return nil, err
+	}
+	if it == nil {
+		return &datastore.FetchAttestedNodeResponse{}, nil
+	}
+
+	node := new(datastore.AttestedNode)
+	if err := unmarshalItem(it, node); err != nil {
+		return nil, err
+	}
+
+	return &datastore.FetchAttestedNodeResponse{
+		Node: node,
+	}, nil
+}
+
+// ListAttestedNodes lists all attested nodes (pagination available)
+func (ds *dynamoPlugin) ListAttestedNodes(ctx context.Context,
+	req *datastore.ListAttestedNodesRequest) (*datastore.ListAttestedNodesResponse, error) {
+
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := t.list(ctx, kindNode)
+	if err != nil {
+		return nil, err
+	}
+
+	p := req.Pagination
+	resp := &datastore.ListAttestedNodesResponse{
+		Pagination: p,
+	}
+	for _, it := range items {
+		if !inPage(p, it.key, len(resp.Nodes)) {
+			continue
+		}
+
+		node := new(datastore.AttestedNode)
+		if err := unmarshalItem(it, node); err != nil {
+			return nil, err
+		}
+		if req.ByExpiresBefore != nil && node.CertNotAfter >= req.ByExpiresBefore.Value {
+			continue
+		}
+
+		resp.Nodes = append(resp.Nodes, node)
+		updatePaginationToken(p, node.SpiffeId)
+	}
+	return resp, nil
+}
+
+// UpdateAttestedNode updates the certificate serial number and expiration of
+// the given attested node
+func (ds *dynamoPlugin) UpdateAttestedNode(ctx context.Context,
+	req *datastore.UpdateAttestedNodeRequest) (*datastore.UpdateAttestedNodeResponse, error) {
+
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(datastore.AttestedNode)
+	if err := withRetries(ctx, func() error {
+		it, err := t.get(ctx, kindNode, req.SpiffeId)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			return errNotFound()
+		}
+		if err := unmarshalItem(it, node); err != nil {
+			return err
+		}
+
+		node.CertSerialNumber = req.CertSerialNumber
+		node.CertNotAfter = req.CertNotAfter
+		if it.data, err = proto.Marshal(node); err != nil {
+			return dynamoError.Wrap(err)
+		}
+		return t.put(ctx, it)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.UpdateAttestedNodeResponse{
+		Node: node,
+	}, nil
+}
+
+// DeleteAttestedNode deletes the given attested node
+func (ds *dynamoPlugin) DeleteAttestedNode(ctx context.Context,
+	req *datastore.DeleteAttestedNodeRequest) (*datastore.DeleteAttestedNodeResponse, error) {
+
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(datastore.AttestedNode)
+	if err := withRetries(ctx, func() error {
+		it, err := t.get(ctx, kindNode, req.SpiffeId)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			return errNotFound()
+		}
+		if err := unmarshalItem(it, node); err != nil {
+			return err
+		}
+		if req.ByCertSerialNumber != nil && node.CertSerialNumber != req.ByCertSerialNumber.Value {
+			node = nil
+			return nil
+		}
+		// the delete fails if the node changed since it was read
+		return t.delete(ctx, it)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.DeleteAttestedNodeResponse{
+		Node: node,
+	}, nil
+}
+
+// SetNodeSelectors replaces the selectors of the given node
+func (ds *dynamoPlugin) SetNodeSelectors(ctx context.Context, req *datastore.SetNodeSelectorsRequest) (*datastore.SetNodeSelectorsResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Selectors == nil {
+		return nil, errors.New("invalid request: missing selectors")
+	}
+
+	data, err := proto.Marshal(req.Selectors)
+	if err != nil {
+		return nil, dynamoError.Wrap(err)
+	}
+
+	if err := withRetries(ctx, func() error {
+		it, err := t.get(ctx, kindNodeSelectors, req.Selectors.SpiffeId)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			it = &item{kind: kindNodeSelectors, key: req.Selectors.SpiffeId}
+		}
+		it.data = data
+		return t.put(ctx, it)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.SetNodeSelectorsResponse{}, nil
+}
+
+// GetNodeSelectors gets node (agent) selectors by SPIFFE ID
+func (ds *dynamoPlugin) GetNodeSelectors(ctx context.Context,
+	req *datastore.GetNodeSelectorsRequest) (*datastore.GetNodeSelectorsResponse, error) {
+
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := t.get(ctx, kindNodeSelectors, req.SpiffeId)
+	if err != nil {
+		return nil, err
+	}
+
+	selectors := &datastore.NodeSelectors{
+		SpiffeId: req.SpiffeId,
+	}
+	if it != nil {
+		if err := unmarshalItem(it, selectors); err != nil {
+			return nil, err
+		}
+	}
+
+	return &datastore.GetNodeSelectorsResponse{
+		Selectors: selectors,
+	}, nil
+}
+
+// CreateRegistrationEntry stores the given registration entry under a new
+// entry ID
+func (ds *dynamoPlugin) CreateRegistrationEntry(ctx context.Context,
+	req *datastore.CreateRegistrationEntryRequest) (*datastore.CreateRegistrationEntryResponse, error) {
+
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Entry == nil {
+		return nil, dynamoError.New("invalid request: missing registered entry")
+	}
+
+	if err := validateRegistrationEntry(req.Entry); err != nil {
+		return nil, err
+	}
+
+	if err := checkFederatedBundles(ctx, t, req.Entry.FederatesWith); err != nil {
+		return nil, err
+	}
+
+	entryID, err := newRegistrationEntryID()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := proto.Clone(req.Entry).(*common.RegistrationEntry)
+	entry.EntryId = entryID
+	it, err := newItem(kindEntry, entryID, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	// the entry ID is random, so a conflict is next to impossible, but the
+	// condition guarantees an existing entry is never overwritten
+	switch err := t.put(ctx, it); {
+	case err == errConflict:
+		return nil, dynamoError.New("registration entry %q already exists", entryID)
+	case err != nil:
+		return nil, err
+	}
+
+	return &datastore.CreateRegistrationEntryResponse{
+		Entry: entry,
+	}, nil
+}
+
+// FetchRegistrationEntry fetches an existing registration entry by entry ID
+func (ds *dynamoPlugin) FetchRegistrationEntry(ctx context.Context,
+	req *datastore.FetchRegistrationEntryRequest) (*datastore.FetchRegistrationEntryResponse, error) {
+
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := t.get(ctx, kindEntry, req.EntryId)
+	if err != nil {
+		return nil, err
+	}
+	if it == nil {
+		return &datastore.FetchRegistrationEntryResponse{}, nil
+	}
+
+	entry := new(common.RegistrationEntry)
+	if err := unmarshalItem(it, entry); err != nil {
+		return nil, err
+	}
+
+	return &datastore.FetchRegistrationEntryResponse{
+		Entry: entry,
+	}, nil
+}
+
+// ListRegistrationEntries lists all registration entries (pagination available)
+func (ds *dynamoPlugin) ListRegistrationEntries(ctx context.Context,
+	req *datastore.ListRegistrationEntriesRequest) (*datastore.ListRegistrationEntriesResponse, error) {
+
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	var bySelectors selector.Set
+	if req.BySelectors != nil && len(req.BySelectors.Selectors) > 0 {
+		switch req.BySelectors.Match {
+		case datastore.BySelectors_MATCH_SUBSET, datastore.BySelectors_MATCH_EXACT:
+		default:
+			return nil, fmt.Errorf("unhandled match behavior %q", req.BySelectors.Match)
+		}
+		bySelectors = selector.NewSetFromRaw(req.BySelectors.Selectors)
+	}
+
+	entries, err := listEntries(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	p := req.Pagination
+	resp := &datastore.ListRegistrationEntriesResponse{
+		Pagination: p,
+	}
+	for _, entry := range entries {
+		if !inPage(p, entry.EntryId, len(resp.Entries)) {
+			continue
+		}
+		if req.ByParentId != nil && entry.ParentId != req.ByParentId.Value {
+			continue
+		}
+		if req.BySpiffeId != nil && entry.SpiffeId != req.BySpiffeId.Value {
+			continue
+		}
+		if req.ByExpiresBefore != nil && (entry.EntryExpiry == 0 || entry.EntryExpiry >= req.ByExpiresBefore.Value) {
+			continue
+		}
+		if bySelectors != nil {
+			entrySelectors := selector.NewSetFromRaw(entry.Selectors)
+			switch req.BySelectors.Match {
+			case datastore.BySelectors_MATCH_SUBSET:
+				if entrySelectors.Size() == 0 || !bySelectors.IncludesSet(entrySelectors) {
+					continue
+				}
+			case datastore.BySelectors_MATCH_EXACT:
+				if !bySelectors.Equal(entrySelectors) {
+					continue
+				}
+			}
+		}
+
+		resp.Entries = append(resp.Entries, entry)
+		updatePaginationToken(p, entry.EntryId)
+	}
+
+	util.SortRegistrationEntries(resp.Entries)
+	return resp, nil
+}
+
+// UpdateRegistrationEntry updates an existing registration entry
+func (ds *dynamoPlugin) UpdateRegistrationEntry(ctx context.Context,
+	req *datastore.UpdateRegistrationEntryRequest) (*datastore.UpdateRegistrationEntryResponse, error) {
+
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Entry == nil {
+		return nil, dynamoError.New("no registration entry provided")
+	}
+
+	if err := validateRegistrationEntry(req.Entry); err != nil {
+		return nil, err
+	}
+
+	if err := checkFederatedBundles(ctx, t, req.Entry.FederatesWith); err != nil {
+		return nil, err
+	}
+
+	data, err := proto.Marshal(req.Entry)
+	if err != nil {
+		return nil, dynamoError.Wrap(err)
+	}
+
+	if err := withRetries(ctx, func() error {
+		it, err := t.get(ctx, kindEntry, req.Entry.EntryId)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			return errNotFound()
+		}
+		it.data = data
+		return t.put(ctx, it)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.UpdateRegistrationEntryResponse{
+		Entry: req.Entry,
+	}, nil
+}
+
+// DeleteRegistrationEntry deletes the given registration entry
+func (ds *dynamoPlugin) DeleteRegistrationEntry(ctx context.Context,
+	req *datastore.DeleteRegistrationEntryRequest) (*datastore.DeleteRegistrationEntryResponse, error) {
+
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := deleteEntry(ctx, t, req.EntryId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &datastore.DeleteRegistrationEntryResponse{
+		Entry: entry,
+	}, nil
+}
+
+// BatchCreateRegistrationEntries creates the registration entries one at a
+// time, since DynamoDB has no transactions spanning that many items. The
+// entries are validated up front, but if creating one fails, the entries
+// before it remain created.
+func (ds *dynamoPlugin) BatchCreateRegistrationEntries(ctx context.Context,
+	req *datastore.BatchCreateRegistrationEntriesRequest) (*datastore.BatchCreateRegistrationEntriesResponse, error) {
+
+	for _, entry := range req.Entries {
+		if entry == nil {
+			return nil, dynamoError.New("invalid request: missing registered entry")
+		}
+		if err := validateRegistrationEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &datastore.BatchCreateRegistrationEntriesResponse{
+		Entries: make([]*common.RegistrationEntry, 0, len(req.Entries)),
+	}
+	for _, entry := range req.Entries {
+		createResp, err := ds.CreateRegistrationEntry(ctx, &datastore.CreateRegistrationEntryRequest{
+			Entry: entry,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, createResp.Entry)
+	}
+	return resp, nil
+}
+
+// BatchUpdateRegistrationEntries updates the registration entries one at a
+// time. If updating one fails, the entries before it remain updated.
+func (ds *dynamoPlugin) BatchUpdateRegistrationEntries(ctx context.Context,
+	req *datastore.BatchUpdateRegistrationEntriesRequest) (*datastore.BatchUpdateRegistrationEntriesResponse, error) {
+
+	for _, entry := range req.Entries {
+		if entry == nil {
+			return nil, dynamoError.New("no registration entry provided")
+		}
+		if err := validateRegistrationEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &datastore.BatchUpdateRegistrationEntriesResponse{
+		Entries: make([]*common.RegistrationEntry, 0, len(req.Entries)),
+	}
+	for _, entry := range req.Entries {
+		updateResp, err := ds.UpdateRegistrationEntry(ctx, &datastore.UpdateRegistrationEntryRequest{
+			Entry: entry,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, updateResp.Entry)
+	}
+	return resp, nil
+}
+
+// BatchDeleteRegistrationEntries deletes the registration entries one at a
+// time. If deleting one fails, the entries before it remain deleted.
+func (ds *dynamoPlugin) BatchDeleteRegistrationEntries(ctx context.Context,
+	req *datastore.BatchDeleteRegistrationEntriesRequest) (*datastore.BatchDeleteRegistrationEntriesResponse, error) {
+
+	resp := &datastore.BatchDeleteRegistrationEntriesResponse{
+		Entries: make([]*common.RegistrationEntry, 0, len(req.EntryIds)),
+	}
+	for _, entryID := range req.EntryIds {
+		deleteResp, err := ds.DeleteRegistrationEntry(ctx, &datastore.DeleteRegistrationEntryRequest{
+			EntryId: entryID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, deleteResp.Entry)
+	}
+	return resp, nil
+}
+
+// CreateJoinToken takes a Token message and stores it. DynamoDB deletes it
+// some time after it expires.
+func (ds *dynamoPlugin) CreateJoinToken(ctx context.Context, req *datastore.CreateJoinTokenRequest) (*datastore.CreateJoinTokenResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.JoinToken == nil || req.JoinToken.Token == "" || req.JoinToken.Expiry == 0 {
+		return nil, errors.New("token and expiry are required")
+	}
+
+	if req.JoinToken.MaxUses < 0 {
+		return nil, errors.New("max uses cannot be negative")
+	}
+
+	token := &datastore.JoinToken{
+		Token:   req.JoinToken.Token,
+		Expiry:  req.JoinToken.Expiry,
+		MaxUses: req.JoinToken.MaxUses,
+	}
+	it, err := newItem(kindJoinToken, token.Token, token)
+	if err != nil {
+		return nil, err
+	}
+	it.expiresAt = token.Expiry
+
+	switch err := t.put(ctx, it); {
+	case err == errConflict:
+		// the token is a secret, so it is left out of the error
+		return nil, dynamoError.New("join token already exists")
+	case err != nil:
+		return nil, err
+	}
+
+	return &datastore.CreateJoinTokenResponse{
+		JoinToken: req.JoinToken,
+	}, nil
+}
+
+// FetchJoinToken fetches a token by its value. Expired tokens are returned
+// until they are pruned or deleted by DynamoDB.
+func (ds *dynamoPlugin) FetchJoinToken(ctx context.Context, req *datastore.FetchJoinTokenRequest) (*datastore.FetchJoinTokenResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := t.get(ctx, kindJoinToken, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if it == nil {
+		return &datastore.FetchJoinTokenResponse{}, nil
+	}
+
+	token := new(datastore.JoinToken)
+	if err := unmarshalItem(it, token); err != nil {
+		return nil, err
+	}
+
+	return &datastore.FetchJoinTokenResponse{
+		JoinToken: token,
+	}, nil
+}
+
+// DeleteJoinToken deletes the matching join token
+func (ds *dynamoPlugin) DeleteJoinToken(ctx context.Context, req *datastore.DeleteJoinTokenRequest) (*datastore.DeleteJoinTokenResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	token := new(datastore.JoinToken)
+	if err := withRetries(ctx, func() error {
+		it, err := t.get(ctx, kindJoinToken, req.Token)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			return errNotFound()
+		}
+		if err := unmarshalItem(it, token); err != nil {
+			return err
+		}
+		return t.delete(ctx, it)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.DeleteJoinTokenResponse{
+		JoinToken: token,
+	}, nil
+}
+
+// ListJoinTokens lists all join tokens
+func (ds *dynamoPlugin) ListJoinTokens(ctx context.Context, req *datastore.ListJoinTokensRequest) (*datastore.ListJoinTokensResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := t.list(ctx, kindJoinToken)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &datastore.ListJoinTokensResponse{
+		JoinTokens: make([]*datastore.JoinToken, 0, len(items)),
+	}
+	for _, it := range items {
+		token := new(datastore.JoinToken)
+		if err := unmarshalItem(it, token); err != nil {
+			return nil, err
+		}
+		resp.JoinTokens = append(resp.JoinTokens, token)
+	}
+	return resp, nil
+}
+
+// UseJoinToken records a use of the join token, deleting it once it has
+// been used the maximum number of times
+func (ds *dynamoPlugin) UseJoinToken(ctx context.Context, req *datastore.UseJoinTokenRequest) (*datastore.UseJoinTokenResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	var token *datastore.JoinToken
+	if err := withRetries(ctx, func() error {
+		token = nil
+		it, err := t.get(ctx, kindJoinToken, req.Token)
+		if err != nil || it == nil {
+			return err
+		}
+
+		token = new(datastore.JoinToken)
+		if err := unmarshalItem(it, token); err != nil {
+			return err
+		}
+
+		// a token without a max use count can only be used once
+		maxUses := token.MaxUses
+		if maxUses < 1 {
+			maxUses = 1
+		}
+
+		if token.Uses+1 >= maxUses {
+			return t.delete(ctx, it)
+		}
+
+		used := proto.Clone(token).(*datastore.JoinToken)
+		used.Uses++
+		if it.data, err = proto.Marshal(used); err != nil {
+			return dynamoError.Wrap(err)
+		}
+		return t.put(ctx, it)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.UseJoinTokenResponse{
+		JoinToken: token,
+	}, nil
+}
+
+// PruneJoinTokens deletes all join tokens expiring before the given time.
+// Expired tokens are also deleted by DynamoDB, but only eventually.
+func (ds *dynamoPlugin) PruneJoinTokens(ctx context.Context, req *datastore.PruneJoinTokensRequest) (*datastore.PruneJoinTokensResponse, error) {
+	t, err := ds.getTable()
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := t.list(ctx, kindJoinToken)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, listed := range items {
+		if listed.expiresAt > req.ExpiresBefore {
+			continue
+		}
+		if err := withRetries(ctx, func() error {
+			it, err := t.get(ctx, kindJoinToken, listed.key)
+			if err != nil || it == nil {
+				return err
+			}
+			return t.delete(ctx, it)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &datastore.PruneJoinTokensResponse{}, nil
+}
+
+// ListChangeEvents is not supported by this datastore
+func (ds *dynamoPlugin) ListChangeEvents(ctx context.Context, req *datastore.ListChangeEventsRequest) (*datastore.ListChangeEventsResponse, error) {
+	return nil, dynamoError.New("change events are not supported")
+}
+
+// PruneChangeEvents does nothing since this datastore records no change
+// events
+func (ds *dynamoPlugin) PruneChangeEvents(ctx context.Context, req *datastore.PruneChangeEventsRequest) (*datastore.PruneChangeEventsResponse, error) {
+	return &datastore.PruneChangeEventsResponse{}, nil
+}
+
+func (ds *dynamoPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	// Parse HCL config payload into config struct
+	config := &configuration{}
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, dynamoError.New("unable to decode configuration: %v", err)
+	}
+
+	if config.TableName == "" {
+		return nil, dynamoError.New("table_name must be set")
+	}
+
+	switch {
+	case config.AccessKeyID != "" && config.SecretAccessKey == "":
+		return nil, dynamoError.New("configuration missing secret access key")
+	case config.AccessKeyID == "" && config.SecretAccessKey != "":
+		return nil, dynamoError.New("configuration missing access key id")
+	}
+
+	client, err := ds.hooks.newClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.table = &table{
+		client: client,
+		name:   config.TableName,
+	}
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (*dynamoPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &pluginInfo, nil
+}
+
+func (ds *dynamoPlugin) getTable() (*table, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	if ds.table == nil {
+		return nil, dynamoError.New("not configured")
+	}
+	return ds.table, nil
+}
+
+func listEntries(ctx context.Context, t *table) ([]*common.RegistrationEntry, error) {
+	items, err := t.list(ctx, kindEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*common.RegistrationEntry, 0, len(items))
+	for _, it := range items {
+		entry := new(common.RegistrationEntry)
+		if err := unmarshalItem(it, entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func deleteEntry(ctx context.Context, t *table, entryID string) (*common.RegistrationEntry, error) {
+	entry := new(common.RegistrationEntry)
+	if err := withRetries(ctx, func() error {
+		it, err := t.get(ctx, kindEntry, entryID)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			return errNotFound()
+		}
+		if err := unmarshalItem(it, entry); err != nil {
+			return err
+		}
+		return t.delete(ctx, it)
+	}); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// dissociateEntry removes the trust domain from the trust domains the
+// registration entry is federated with
+func dissociateEntry(ctx context.Context, t *table, entryID, trustDomainID string) error {
+	return withRetries(ctx, func() error {
+		it, err := t.get(ctx, kindEntry, entryID)
+		if err != nil || it == nil {
+			return err
+		}
+
+		entry := new(common.RegistrationEntry)
+		if err := unmarshalItem(it, entry); err != nil {
+			return err
+		}
+
+		var ids []string
+		for _, id := range entry.FederatesWith {
+			if id != trustDomainID {
+				ids = append(ids, id)
+			}
+		}
+		entry.FederatesWith = ids
+
+		if it.data, err = proto.Marshal(entry); err != nil {
+			return dynamoError.Wrap(err)
+		}
+		return t.put(ctx, it)
+	})
+}
+
+func federatesWith(entry *common.RegistrationEntry, trustDomainID string) bool {
+	for _, id := range entry.FederatesWith {
+		if id == trustDomainID {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFederatedBundles makes sure there is a bundle for each of the trust
+// domains
+func checkFederatedBundles(ctx context.Context, t *table, ids []string) error {
+	for _, id := range ids {
+		it, err := t.get(ctx, kindBundle, id)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			return fmt.Errorf("unable to find federated bundle %q", id)
+		}
+	}
+	return nil
+}
+
+// inPage returns true if the object with the given key belongs in the page
+// being listed, given the number of objects already in it. Objects are
+// paginated in key order and the token is the key of the last object of the
+// previous page.
+func inPage(p *datastore.Pagination, key string, count int) bool {
+	if p == nil || p.PageSize <= 0 {
+		return true
+	}
+	return key > p.Token && count < int(p.PageSize)
+}
+
+// updatePaginationToken sets the token to the key of the last object added to
+// the page
+func updatePaginationToken(p *datastore.Pagination, key string) {
+	if p != nil && p.PageSize > 0 {
+		p.Token = key
+	}
+}
+
+func validateRegistrationEntry(entry *common.RegistrationEntry) error {
+	if entry.Selectors == nil || len(entry.Selectors) == 0 {
+		return dynamoError.New("invalid registration entry: missing selector list")
+	}
+
+	if len(entry.SpiffeId) == 0 {
+		return dynamoError.New("invalid registration entry: missing SPIFFE ID")
+	}
+
+	if entry.Ttl < 0 {
+		return dynamoError.New("invalid registration entry: TTL is not set")
+	}
+
+	return nil
+}
+
+// bundleToItem converts the given bundle to an item keyed by its normalized
+// trust domain ID
+func bundleToItem(pb *datastore.Bundle) (*item, error) {
+	if pb == nil {
+		return nil, dynamoError.New("missing bundle in request")
+	}
+	id, err := idutil.NormalizeSpiffeID(pb.TrustDomainId, idutil.AllowAnyTrustDomain())
+	if err != nil {
+		return nil, dynamoError.Wrap(err)
+	}
+	return newItem(kindBundle, id, pb)
+}
+
+func newItem(kind, key string, msg proto.Message) (*item, error) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, dynamoError.Wrap(err)
+	}
+	return &item{
+		kind: kind,
+		key:  key,
+		data: data,
+	}, nil
+}
+
+func unmarshalItem(it *item, msg proto.Message) error {
+	if err := proto.Unmarshal(it.data, msg); err != nil {
+		return dynamoError.New("unable to unmarshal item %q: %v", itemKey(it.kind, it.key), err)
+	}
+	return nil
+}
+
+func errNotFound() error {
+	return dynamoError.New("record not found")
+}
+
+func newRegistrationEntryID() (string, error) {
+	u, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func newDynamoClient(config *configuration) (dynamoClient, error) {
+	conf := aws.NewConfig()
+	if config.AccessKeyID != "" {
+		conf.Credentials = credentials.NewStaticCredentials(config.AccessKeyID, config.SecretAccessKey, "")
+	}
+	if config.Region != "" {
+		conf.Region = aws.String(config.Region)
+	}
+	if config.Endpoint != "" {
+		conf.Endpoint = aws.String(config.Endpoint)
+	}
+
+	sess, err := session.NewSession(conf)
+	if err != nil {
+		return nil, dynamoError.Wrap(err)
+	}
+	return dynamodb.New(sess), nil
+}
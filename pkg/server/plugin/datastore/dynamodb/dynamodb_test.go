@@ -0,0 +1,811 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestDynamoDB(t *testing.T) {
+	suite.Run(t, new(DynamoDBSuite))
+}
+
+type DynamoDBSuite struct {
+	suite.Suite
+
+	client *fakeClient
+	ds     *dynamoPlugin
+}
+
+func (s *DynamoDBSuite) SetupTest() {
+	s.client = newFakeClient()
+	s.ds = newPlugin()
+	s.ds.hooks.newClient = func(config *configuration) (dynamoClient, error) {
+		return s.client, nil
+	}
+	_, err := s.ds.Configure(context.Background(), &spi.ConfigureRequest{
+		Configuration: `table_name = "spire"`,
+	})
+	s.Require().NoError(err)
+}
+
+func (s *DynamoDBSuite) TestConfigure() {
+	ds := newPlugin()
+	_, err := ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{})
+	s.Require().EqualError(err, "datastore-dynamodb: not configured")
+
+	var config *configuration
+	ds.hooks.newClient = func(c *configuration) (dynamoClient, error) {
+		config = c
+		return s.client, nil
+	}
+
+	_, err = ds.Configure(context.Background(), &spi.ConfigureRequest{})
+	s.Require().EqualError(err, "datastore-dynamodb: table_name must be set")
+
+	_, err = ds.Configure(context.Background(), &spi.ConfigureRequest{
+		Configuration: `table_name = "spire" access_key_id = "ACCESSKEYID"`,
+	})
+	s.Require().EqualError(err, "datastore-dynamodb: configuration missing secret access key")
+
+	_, err = ds.Configure(context.Background(), &spi.ConfigureRequest{
+		Configuration: `
+			table_name = "spire"
+			region = "us-west-2"
+			endpoint = "http://localhost:8000"
+		`,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&configuration{
+		TableName: "spire",
+		Region:    "us-west-2",
+		Endpoint:  "http://localhost:8000",
+	}, config)
+}
+
+func (s *DynamoDBSuite) TestBundleCRUD() {
+	bundle := &datastore.Bundle{
+		TrustDomainId: "spiffe://foo",
+		RootCas:       []*common.Certificate{{DerBytes: []byte("cert1")}},
+	}
+
+	// fetch non-existent
+	fresp, err := s.ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().NoError(err)
+	s.Require().Nil(fresp.Bundle)
+
+	// create
+	_, err = s.ds.CreateBundle(context.Background(), &datastore.CreateBundleRequest{Bundle: bundle})
+	s.Require().NoError(err)
+
+	// create again fails
+	_, err = s.ds.CreateBundle(context.Background(), &datastore.CreateBundleRequest{Bundle: bundle})
+	s.Require().EqualError(err, `datastore-dynamodb: bundle "spiffe://foo" already exists`)
+
+	// fetch
+	fresp, err = s.ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().NoError(err)
+	s.requireProtoEqual(bundle, fresp.Bundle)
+
+	// append
+	aresp, err := s.ds.AppendBundle(context.Background(), &datastore.AppendBundleRequest{
+		Bundle: &datastore.Bundle{
+			TrustDomainId: "spiffe://foo",
+			RootCas:       []*common.Certificate{{DerBytes: []byte("cert2")}},
+		},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(aresp.Bundle.RootCas, 2)
+
+	// append creates missing bundles
+	_, err = s.ds.AppendBundle(context.Background(), &datastore.AppendBundleRequest{
+		Bundle: &datastore.Bundle{TrustDomainId: "spiffe://bar"},
+	})
+	s.Require().NoError(err)
+
+	// update
+	_, err = s.ds.UpdateBundle(context.Background(), &datastore.UpdateBundleRequest{Bundle: bundle})
+	s.Require().NoError(err)
+	_, err = s.ds.UpdateBundle(context.Background(), &datastore.UpdateBundleRequest{
+		Bundle: &datastore.Bundle{TrustDomainId: "spiffe://baz"},
+	})
+	s.Require().EqualError(err, "datastore-dynamodb: record not found")
+
+	// list
+	lresp, err := s.ds.ListBundles(context.Background(), &datastore.ListBundlesRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(lresp.Bundles, 2)
+	s.Require().Equal("spiffe://bar", lresp.Bundles[0].TrustDomainId)
+	s.requireProtoEqual(bundle, lresp.Bundles[1])
+
+	// delete
+	dresp, err := s.ds.DeleteBundle(context.Background(), &datastore.DeleteBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().NoError(err)
+	s.requireProtoEqual(bundle, dresp.Bundle)
+	_, err = s.ds.DeleteBundle(context.Background(), &datastore.DeleteBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().EqualError(err, "datastore-dynamodb: record not found")
+}
+
+func (s *DynamoDBSuite) TestCreateBundleNormalizesTrustDomain() {
+	_, err := s.ds.CreateBundle(context.Background(), &datastore.CreateBundleRequest{
+		Bundle: &datastore.Bundle{TrustDomainId: "spiffe://FOO"},
+	})
+	s.Require().NoError(err)
+
+	fresp, err := s.ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().NoError(err)
+	s.Require().NotNil(fresp.Bundle)
+}
+
+func (s *DynamoDBSuite) TestDeleteBundleModes() {
+	s.createBundle("spiffe://otherdomain.org")
+
+	createEntry := func() *common.RegistrationEntry {
+		resp, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
+			Entry: &common.RegistrationEntry{
+				SpiffeId:      "spiffe://example.org/foo",
+				Selectors:     []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+				FederatesWith: []string{"spiffe://otherdomain.org"},
+			},
+		})
+		s.Require().NoError(err)
+		return resp.Entry
+	}
+
+	// restrict
+	entry := createEntry()
+	_, err := s.ds.DeleteBundle(context.Background(), &datastore.DeleteBundleRequest{
+		TrustDomainId: "spiffe://otherdomain.org",
+	})
+	s.Require().EqualError(err, "datastore-dynamodb: cannot delete bundle; federated with 1 registration entries")
+
+	// dissociate
+	_, err = s.ds.DeleteBundle(context.Background(), &datastore.DeleteBundleRequest{
+		TrustDomainId: "spiffe://otherdomain.org",
+		Mode:          datastore.DeleteBundleRequest_DISSOCIATE,
+	})
+	s.Require().NoError(err)
+	fresp, err := s.ds.FetchRegistrationEntry(context.Background(), &datastore.FetchRegistrationEntryRequest{EntryId: entry.EntryId})
+	s.Require().NoError(err)
+	s.Require().NotNil(fresp.Entry)
+	s.Require().Empty(fresp.Entry.FederatesWith)
+
+	// delete
+	s.createBundle("spiffe://otherdomain.org")
+	entry = createEntry()
+	_, err = s.ds.DeleteBundle(context.Background(), &datastore.DeleteBundleRequest{
+		TrustDomainId: "spiffe://otherdomain.org",
+		Mode:          datastore.DeleteBundleRequest_DELETE,
+	})
+	s.Require().NoError(err)
+	fresp, err = s.ds.FetchRegistrationEntry(context.Background(), &datastore.FetchRegistrationEntryRequest{EntryId: entry.EntryId})
+	s.Require().NoError(err)
+	s.Require().Nil(fresp.Entry)
+}
+
+func (s *DynamoDBSuite) TestAttestedNodeCRUD() {
+	node := &datastore.AttestedNode{
+		SpiffeId:            "spiffe://example.org/spire/agent/foo",
+		AttestationDataType: "aws-tag",
+		CertSerialNumber:    "1234",
+		CertNotAfter:        1000,
+	}
+
+	_, err := s.ds.CreateAttestedNode(context.Background(), &datastore.CreateAttestedNodeRequest{})
+	s.Require().EqualError(err, "datastore-dynamodb: invalid request: missing attested node")
+
+	_, err = s.ds.CreateAttestedNode(context.Background(), &datastore.CreateAttestedNodeRequest{Node: node})
+	s.Require().NoError(err)
+	_, err = s.ds.CreateAttestedNode(context.Background(), &datastore.CreateAttestedNodeRequest{Node: node})
+	s.Require().EqualError(err, `datastore-dynamodb: attested node "spiffe://example.org/spire/agent/foo" already exists`)
+
+	fresp, err := s.ds.FetchAttestedNode(context.Background(), &datastore.FetchAttestedNodeRequest{SpiffeId: node.SpiffeId})
+	s.Require().NoError(err)
+	s.requireProtoEqual(node, fresp.Node)
+
+	uresp, err := s.ds.UpdateAttestedNode(context.Background(), &datastore.UpdateAttestedNodeRequest{
+		SpiffeId:         node.SpiffeId,
+		CertSerialNumber: "5678",
+		CertNotAfter:     2000,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal("aws-tag", uresp.Node.AttestationDataType)
+	s.Require().Equal("5678", uresp.Node.CertSerialNumber)
+	s.Require().Equal(int64(2000), uresp.Node.CertNotAfter)
+
+	// the serial number changed with the update
+	dresp, err := s.ds.DeleteAttestedNode(context.Background(), &datastore.DeleteAttestedNodeRequest{
+		SpiffeId:           node.SpiffeId,
+		ByCertSerialNumber: &wrappers.StringValue{Value: node.CertSerialNumber},
+	})
+	s.Require().NoError(err)
+	s.Require().Nil(dresp.Node)
+
+	dresp, err = s.ds.DeleteAttestedNode(context.Background(), &datastore.DeleteAttestedNodeRequest{
+		SpiffeId:           node.SpiffeId,
+		ByCertSerialNumber: &wrappers.StringValue{Value: "5678"},
+	})
+	s.Require().NoError(err)
+	s.requireProtoEqual(uresp.Node, dresp.Node)
+
+	fresp, err = s.ds.FetchAttestedNode(context.Background(), &datastore.FetchAttestedNodeRequest{SpiffeId: node.SpiffeId})
+	s.Require().NoError(err)
+	s.Require().Nil(fresp.Node)
+
+	_, err = s.ds.UpdateAttestedNode(context.Background(), &datastore.UpdateAttestedNodeRequest{SpiffeId: node.SpiffeId})
+	s.Require().EqualError(err, "datastore-dynamodb: record not found")
+	_, err = s.ds.DeleteAttestedNode(context.Background(), &datastore.DeleteAttestedNodeRequest{SpiffeId: node.SpiffeId})
+	s.Require().EqualError(err, "datastore-dynamodb: record not found")
+}
+
+func (s *DynamoDBSuite) TestListAttestedNodes() {
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		_, err := s.ds.CreateAttestedNode(context.Background(), &datastore.CreateAttestedNodeRequest{
+			Node: &datastore.AttestedNode{
+				SpiffeId:     "spiffe://example.org/spire/agent/" + id,
+				CertNotAfter: int64(i),
+			},
+		})
+		s.Require().NoError(err)
+	}
+
+	resp, err := s.ds.ListAttestedNodes(context.Background(), &datastore.ListAttestedNodesRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(resp.Nodes, 5)
+
+	resp, err = s.ds.ListAttestedNodes(context.Background(), &datastore.ListAttestedNodesRequest{
+		ByExpiresBefore: &wrappers.Int64Value{Value: 2},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"spiffe://example.org/spire/agent/a", "spiffe://example.org/spire/agent/b"}, nodeIDs(resp.Nodes))
+
+	p := &datastore.Pagination{PageSize: 2}
+	var pages [][]string
+	for {
+		resp, err = s.ds.ListAttestedNodes(context.Background(), &datastore.ListAttestedNodesRequest{Pagination: p})
+		s.Require().NoError(err)
+		if len(resp.Nodes) == 0 {
+			break
+		}
+		pages = append(pages, nodeIDs(resp.Nodes))
+		p = resp.Pagination
+	}
+	s.Require().Equal([][]string{
+		{"spiffe://example.org/spire/agent/a", "spiffe://example.org/spire/agent/b"},
+		{"spiffe://example.org/spire/agent/c", "spiffe://example.org/spire/agent/d"},
+		{"spiffe://example.org/spire/agent/e"},
+	}, pages)
+}
+
+func (s *DynamoDBSuite) TestNodeSelectors() {
+	resp, err := s.ds.GetNodeSelectors(context.Background(), &datastore.GetNodeSelectorsRequest{SpiffeId: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal("foo", resp.Selectors.SpiffeId)
+	s.Require().Empty(resp.Selectors.Selectors)
+
+	_, err = s.ds.SetNodeSelectors(context.Background(), &datastore.SetNodeSelectorsRequest{})
+	s.Require().EqualError(err, "invalid request: missing selectors")
+
+	for _, selectors := range [][]*common.Selector{
+		{{Type: "FOO1", Value: "1"}},
+		{{Type: "FOO2", Value: "2"}, {Type: "FOO3", Value: "3"}},
+	} {
+		_, err = s.ds.SetNodeSelectors(context.Background(), &datastore.SetNodeSelectorsRequest{
+			Selectors: &datastore.NodeSelectors{SpiffeId: "foo", Selectors: selectors},
+		})
+		s.Require().NoError(err)
+
+		resp, err = s.ds.GetNodeSelectors(context.Background(), &datastore.GetNodeSelectorsRequest{SpiffeId: "foo"})
+		s.Require().NoError(err)
+		s.requireProtoEqual(&datastore.NodeSelectors{SpiffeId: "foo", Selectors: selectors}, resp.Selectors)
+	}
+}
+
+func (s *DynamoDBSuite) TestRegistrationEntryCRUD() {
+	_, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{})
+	s.Require().EqualError(err, "datastore-dynamodb: invalid request: missing registered entry")
+
+	_, err = s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
+		Entry: &common.RegistrationEntry{SpiffeId: "spiffe://example.org/foo"},
+	})
+	s.Require().EqualError(err, "datastore-dynamodb: invalid registration entry: missing selector list")
+
+	entry := &common.RegistrationEntry{
+		SpiffeId:      "spiffe://example.org/foo",
+		ParentId:      "spiffe://example.org/spire/agent/foo",
+		Selectors:     []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+		FederatesWith: []string{"spiffe://otherdomain.org"},
+		Ttl:           60,
+	}
+	_, err = s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{Entry: entry})
+	s.Require().EqualError(err, `unable to find federated bundle "spiffe://otherdomain.org"`)
+
+	s.createBundle("spiffe://otherdomain.org")
+	cresp, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{Entry: entry})
+	s.Require().NoError(err)
+	s.Require().NotEmpty(cresp.Entry.EntryId)
+	s.Require().Empty(entry.EntryId, "request entry should not be modified")
+
+	fresp, err := s.ds.FetchRegistrationEntry(context.Background(), &datastore.FetchRegistrationEntryRequest{EntryId: cresp.Entry.EntryId})
+	s.Require().NoError(err)
+	s.requireProtoEqual(cresp.Entry, fresp.Entry)
+
+	updated := proto.Clone(cresp.Entry).(*common.RegistrationEntry)
+	updated.Ttl = 120
+	updated.FederatesWith = nil
+	_, err = s.ds.UpdateRegistrationEntry(context.Background(), &datastore.UpdateRegistrationEntryRequest{Entry: updated})
+	s.Require().NoError(err)
+
+	fresp, err = s.ds.FetchRegistrationEntry(context.Background(), &datastore.FetchRegistrationEntryRequest{EntryId: cresp.Entry.EntryId})
+	s.Require().NoError(err)
+	s.requireProtoEqual(updated, fresp.Entry)
+
+	dresp, err := s.ds.DeleteRegistrationEntry(context.Background(), &datastore.DeleteRegistrationEntryRequest{EntryId: cresp.Entry.EntryId})
+	s.Require().NoError(err)
+	s.requireProtoEqual(updated, dresp.Entry)
+
+	_, err = s.ds.UpdateRegistrationEntry(context.Background(), &datastore.UpdateRegistrationEntryRequest{Entry: updated})
+	s.Require().EqualError(err, "datastore-dynamodb: record not found")
+	_, err = s.ds.DeleteRegistrationEntry(context.Background(), &datastore.DeleteRegistrationEntryRequest{EntryId: cresp.Entry.EntryId})
+	s.Require().EqualError(err, "datastore-dynamodb: record not found")
+}
+
+func (s *DynamoDBSuite) TestBatchRegistrationEntries() {
+	foo := &common.RegistrationEntry{
+		SpiffeId:  "spiffe://example.org/foo",
+		ParentId:  "spiffe://example.org/spire/agent/foo",
+		Selectors: []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+	}
+	bar := &common.RegistrationEntry{
+		SpiffeId:  "spiffe://example.org/bar",
+		ParentId:  "spiffe://example.org/spire/agent/foo",
+		Selectors: []*common.Selector{{Type: "unix", Value: "uid:1001"}},
+	}
+
+	// entries are validated before any is created
+	_, err := s.ds.BatchCreateRegistrationEntries(context.Background(), &datastore.BatchCreateRegistrationEntriesRequest{
+		Entries: []*common.RegistrationEntry{foo, {SpiffeId: "spiffe://example.org/baz"}},
+	})
+	s.Require().EqualError(err, "datastore-dynamodb: invalid registration entry: missing selector list")
+	lresp, err := s.ds.ListRegistrationEntries(context.Background(), &datastore.ListRegistrationEntriesRequest{})
+	s.Require().NoError(err)
+	s.Require().Empty(lresp.Entries)
+
+	cresp, err := s.ds.BatchCreateRegistrationEntries(context.Background(), &datastore.BatchCreateRegistrationEntriesRequest{
+		Entries: []*common.RegistrationEntry{foo, bar},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(cresp.Entries, 2)
+	s.Require().Equal(foo.SpiffeId, cresp.Entries[0].SpiffeId)
+	s.Require().Equal(bar.SpiffeId, cresp.Entries[1].SpiffeId)
+
+	var updated []*common.RegistrationEntry
+	var ids []string
+	for _, entry := range cresp.Entries {
+		entry = proto.Clone(entry).(*common.RegistrationEntry)
+		entry.Ttl = 120
+		updated = append(updated, entry)
+		ids = append(ids, entry.EntryId)
+	}
+	uresp, err := s.ds.BatchUpdateRegistrationEntries(context.Background(), &datastore.BatchUpdateRegistrationEntriesRequest{
+		Entries: updated,
+	})
+	s.Require().NoError(err)
+	s.Require().Len(uresp.Entries, 2)
+	for i, entry := range updated {
+		fresp, err := s.ds.FetchRegistrationEntry(context.Background(), &datastore.FetchRegistrationEntryRequest{EntryId: entry.EntryId})
+		s.Require().NoError(err)
+		s.requireProtoEqual(entry, fresp.Entry)
+		s.requireProtoEqual(entry, uresp.Entries[i])
+	}
+
+	dresp, err := s.ds.BatchDeleteRegistrationEntries(context.Background(), &datastore.BatchDeleteRegistrationEntriesRequest{
+		EntryIds: ids,
+	})
+	s.Require().NoError(err)
+	s.Require().Len(dresp.Entries, 2)
+	s.requireProtoEqual(updated[0], dresp.Entries[0])
+	s.requireProtoEqual(updated[1], dresp.Entries[1])
+
+	_, err = s.ds.BatchDeleteRegistrationEntries(context.Background(), &datastore.BatchDeleteRegistrationEntriesRequest{
+		EntryIds: ids,
+	})
+	s.Require().EqualError(err, "datastore-dynamodb: record not found")
+}
+
+func (s *DynamoDBSuite) TestListRegistrationEntries() {
+	a := &common.Selector{Type: "a", Value: "1"}
+	b := &common.Selector{Type: "b", Value: "2"}
+	c := &common.Selector{Type: "c", Value: "3"}
+
+	createEntry := func(spiffeID, parentID string, selectors ...*common.Selector) string {
+		resp, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
+			Entry: &common.RegistrationEntry{
+				SpiffeId:  spiffeID,
+				ParentId:  parentID,
+				Selectors: selectors,
+			},
+		})
+		s.Require().NoError(err)
+		return resp.Entry.SpiffeId
+	}
+	createEntry("spiffe://example.org/a", "spiffe://example.org/p1", a)
+	createEntry("spiffe://example.org/ab", "spiffe://example.org/p1", a, b)
+	createEntry("spiffe://example.org/abc", "spiffe://example.org/p2", a, b, c)
+	createEntry("spiffe://example.org/c", "spiffe://example.org/p2", c)
+
+	list := func(req *datastore.ListRegistrationEntriesRequest) []string {
+		resp, err := s.ds.ListRegistrationEntries(context.Background(), req)
+		s.Require().NoError(err)
+		var ids []string
+		for _, entry := range resp.Entries {
+			ids = append(ids, entry.SpiffeId)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/a",
+		"spiffe://example.org/ab",
+		"spiffe://example.org/abc",
+		"spiffe://example.org/c",
+	}, list(&datastore.ListRegistrationEntriesRequest{}))
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/a",
+		"spiffe://example.org/ab",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		ByParentId: &wrappers.StringValue{Value: "spiffe://example.org/p1"},
+	}))
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/c",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		BySpiffeId: &wrappers.StringValue{Value: "spiffe://example.org/c"},
+	}))
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/ab",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		BySelectors: &datastore.BySelectors{
+			Selectors: []*common.Selector{a, b},
+			Match:     datastore.BySelectors_MATCH_EXACT,
+		},
+	}))
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/a",
+		"spiffe://example.org/ab",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		BySelectors: &datastore.BySelectors{
+			Selectors: []*common.Selector{a, b},
+			Match:     datastore.BySelectors_MATCH_SUBSET,
+		},
+	}))
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/c",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		ByParentId: &wrappers.StringValue{Value: "spiffe://example.org/p2"},
+		BySelectors: &datastore.BySelectors{
+			Selectors: []*common.Selector{a, c},
+			Match:     datastore.BySelectors_MATCH_SUBSET,
+		},
+	}))
+
+	// paginate through all of the entries
+	var ids []string
+	p := &datastore.Pagination{PageSize: 3}
+	for {
+		resp, err := s.ds.ListRegistrationEntries(context.Background(), &datastore.ListRegistrationEntriesRequest{Pagination: p})
+		s.Require().NoError(err)
+		if len(resp.Entries) == 0 {
+			break
+		}
+		s.Require().True(len(resp.Entries) <= 3)
+		for _, entry := range resp.Entries {
+			ids = append(ids, entry.SpiffeId)
+		}
+		p = resp.Pagination
+	}
+	sort.Strings(ids)
+	s.Require().Equal([]string{
+		"spiffe://example.org/a",
+		"spiffe://example.org/ab",
+		"spiffe://example.org/abc",
+		"spiffe://example.org/c",
+	}, ids)
+
+	// only entries with an expiry before the given time are listed
+	_, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
+		Entry: &common.RegistrationEntry{
+			SpiffeId:    "spiffe://example.org/expired",
+			ParentId:    "spiffe://example.org/p1",
+			Selectors:   []*common.Selector{a},
+			EntryExpiry: 1000,
+		},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{
+		"spiffe://example.org/expired",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		ByExpiresBefore: &wrappers.Int64Value{Value: 2000},
+	}))
+	s.Require().Empty(list(&datastore.ListRegistrationEntriesRequest{
+		ByExpiresBefore: &wrappers.Int64Value{Value: 1000},
+	}))
+}
+
+func (s *DynamoDBSuite) TestJoinTokens() {
+	_, err := s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "foo"},
+	})
+	s.Require().EqualError(err, "token and expiry are required")
+
+	_, err = s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "foo", Expiry: 1000, MaxUses: -1},
+	})
+	s.Require().EqualError(err, "max uses cannot be negative")
+
+	for _, token := range []*datastore.JoinToken{
+		{Token: "foo", Expiry: 1000, MaxUses: 2},
+		{Token: "bar", Expiry: 2000},
+	} {
+		_, err = s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{JoinToken: token})
+		s.Require().NoError(err)
+	}
+	_, err = s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "foo", Expiry: 1000},
+	})
+	s.Require().EqualError(err, "datastore-dynamodb: join token already exists")
+
+	// DynamoDB deletes the tokens once they expire
+	s.Require().Equal(int64(1000), s.client.ttl("TOKEN#foo"))
+
+	lresp, err := s.ds.ListJoinTokens(context.Background(), &datastore.ListJoinTokensRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(lresp.JoinTokens, 2)
+	s.Require().Equal("bar", lresp.JoinTokens[0].Token)
+	s.Require().Equal("foo", lresp.JoinTokens[1].Token)
+
+	// tokens are deleted once used the maximum number of times
+	uresp, err := s.ds.UseJoinToken(context.Background(), &datastore.UseJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal(int32(0), uresp.JoinToken.Uses)
+	fresp, err := s.ds.FetchJoinToken(context.Background(), &datastore.FetchJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal(int32(1), fresp.JoinToken.Uses)
+
+	uresp, err = s.ds.UseJoinToken(context.Background(), &datastore.UseJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal(int32(1), uresp.JoinToken.Uses)
+	fresp, err = s.ds.FetchJoinToken(context.Background(), &datastore.FetchJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Nil(fresp.JoinToken)
+
+	uresp, err = s.ds.UseJoinToken(context.Background(), &datastore.UseJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Nil(uresp.JoinToken)
+
+	// prune
+	_, err = s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "baz", Expiry: 3000},
+	})
+	s.Require().NoError(err)
+	_, err = s.ds.PruneJoinTokens(context.Background(), &datastore.PruneJoinTokensRequest{ExpiresBefore: 2000})
+	s.Require().NoError(err)
+	lresp, err = s.ds.ListJoinTokens(context.Background(), &datastore.ListJoinTokensRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(lresp.JoinTokens, 1)
+	s.Require().Equal("baz", lresp.JoinTokens[0].Token)
+
+	// delete
+	dresp, err := s.ds.DeleteJoinToken(context.Background(), &datastore.DeleteJoinTokenRequest{Token: "baz"})
+	s.Require().NoError(err)
+	s.Require().Equal("baz", dresp.JoinToken.Token)
+	_, err = s.ds.DeleteJoinToken(context.Background(), &datastore.DeleteJoinTokenRequest{Token: "baz"})
+	s.Require().EqualError(err, "datastore-dynamodb: record not found")
+}
+
+func (s *DynamoDBSuite) TestConcurrentUpdatesAreRetried() {
+	_, err := s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "foo", Expiry: 1000, MaxUses: 3},
+	})
+	s.Require().NoError(err)
+
+	// the token is used by someone else between the read and the write of
+	// the first attempt
+	s.client.beforeWrite = func() {
+		s.client.beforeWrite = nil
+		_, err := s.ds.UseJoinToken(context.Background(), &datastore.UseJoinTokenRequest{Token: "foo"})
+		s.Require().NoError(err)
+	}
+
+	resp, err := s.ds.UseJoinToken(context.Background(), &datastore.UseJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal(int32(1), resp.JoinToken.Uses)
+
+	fresp, err := s.ds.FetchJoinToken(context.Background(), &datastore.FetchJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal(int32(2), fresp.JoinToken.Uses)
+}
+
+func (s *DynamoDBSuite) TestTableErrors() {
+	s.client.err = errors.New("oh no")
+	_, err := s.ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().EqualError(err, "datastore-dynamodb: oh no")
+	_, err = s.ds.ListBundles(context.Background(), &datastore.ListBundlesRequest{})
+	s.Require().EqualError(err, "datastore-dynamodb: oh no")
+}
+
+func (s *DynamoDBSuite) createBundle(trustDomainID string) {
+	_, err := s.ds.CreateBundle(context.Background(), &datastore.CreateBundleRequest{
+		Bundle: &datastore.Bundle{TrustDomainId: trustDomainID},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *DynamoDBSuite) requireProtoEqual(expected, actual proto.Message) {
+	if !proto.Equal(expected, actual) {
+		s.Require().Equal(expected, actual)
+	}
+}
+
+func nodeIDs(nodes []*datastore.AttestedNode) []string {
+	var ids []string
+	for _, node := range nodes {
+		ids = append(ids, node.SpiffeId)
+	}
+	return ids
+}
+
+// fakeClient is an in-memory table which understands the expressions used
+// by the plugin
+type fakeClient struct {
+	mu    sync.Mutex
+	items map[string]map[string]*dynamodb.AttributeValue
+
+	// err, when set, is returned by all operations
+	err error
+
+	// beforeWrite, when set, is called before conditional writes are
+	// evaluated, without the lock held
+	beforeWrite func()
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		items: make(map[string]map[string]*dynamodb.AttributeValue),
+	}
+}
+
+func (c *fakeClient) GetItemWithContext(ctx aws.Context, in *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &dynamodb.GetItemOutput{
+		Item: c.items[aws.StringValue(in.Key[attrKey].S)],
+	}, nil
+}
+
+func (c *fakeClient) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	c.callBeforeWrite()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	pk := aws.StringValue(in.Item[attrKey].S)
+	if err := c.checkCondition(pk, in.ConditionExpression, in.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+	c.items[pk] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (c *fakeClient) DeleteItemWithContext(ctx aws.Context, in *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	c.callBeforeWrite()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	pk := aws.StringValue(in.Key[attrKey].S)
+	if err := c.checkCondition(pk, in.ConditionExpression, in.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+	delete(c.items, pk)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (c *fakeClient) QueryWithContext(ctx aws.Context, in *dynamodb.QueryInput, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	if aws.StringValue(in.IndexName) != kindIndex || aws.StringValue(in.KeyConditionExpression) != "#kind = :kind" {
+		return nil, errors.New("unsupported query")
+	}
+	kind := aws.StringValue(in.ExpressionAttributeValues[":kind"].S)
+
+	var pks []string
+	for pk, attrs := range c.items {
+		if aws.StringValue(attrs[attrKind].S) == kind {
+			pks = append(pks, pk)
+		}
+	}
+	sort.Strings(pks)
+
+	// return pages of two items to exercise the pagination of queries
+	var start string
+	if in.ExclusiveStartKey != nil {
+		start = aws.StringValue(in.ExclusiveStartKey[attrKey].S)
+	}
+	out := &dynamodb.QueryOutput{}
+	for _, pk := range pks {
+		if pk <= start {
+			continue
+		}
+		if len(out.Items) == 2 {
+			out.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{
+				attrKey:  out.Items[1][attrKey],
+				attrKind: out.Items[1][attrKind],
+			}
+			break
+		}
+		out.Items = append(out.Items, c.items[pk])
+	}
+	return out, nil
+}
+
+func (c *fakeClient) checkCondition(pk string, condition *string, values map[string]*dynamodb.AttributeValue) error {
+	existing, exists := c.items[pk]
+	var ok bool
+	switch aws.StringValue(condition) {
+	case "attribute_not_exists(#pk)":
+		ok = !exists
+	case "#version = :version":
+		ok = exists && aws.StringValue(existing[attrVersion].N) == aws.StringValue(values[":version"].N)
+	default:
+		return errors.New("unsupported condition")
+	}
+	if !ok {
+		return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	return nil
+}
+
+func (c *fakeClient) callBeforeWrite() {
+	c.mu.Lock()
+	beforeWrite := c.beforeWrite
+	c.mu.Unlock()
+	if beforeWrite != nil {
+		beforeWrite()
+	}
+}
+
+func (c *fakeClient) ttl(pk string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, _ := numberAttrValue(c.items[pk][attrTTL])
+	return n
+}
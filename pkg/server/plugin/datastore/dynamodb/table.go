@@ -0,0 +1,277 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// The plugin stores every object in a single table. Items are keyed by a
+// string partition key made of the kind of the object and its natural key,
+// e.g. "ENTRY#<entry id>". The kind is also stored in its own attribute,
+// which is the partition key of a global secondary index used to list all
+// the objects of a kind.
+const (
+	attrKey     = "pk"
+	attrKind    = "kind"
+	attrVersion = "version"
+	attrData    = "data"
+	attrTTL     = "ttl"
+
+	kindIndex = "kind-index"
+
+	kindBundle        = "BUNDLE"
+	kindNode          = "NODE"
+	kindNodeSelectors = "NODESEL"
+	kindEntry         = "ENTRY"
+	kindJoinToken     = "TOKEN"
+)
+
+const (
+	// maxUpdateAttempts is how many times a read-modify-write operation is
+	// attempted before giving up on conflicting concurrent writes
+	maxUpdateAttempts = 5
+
+	// updateRetryBackoff is how long to wait before the first retry. It
+	// doubles on each subsequent retry.
+	updateRetryBackoff = 10 * time.Millisecond
+)
+
+var (
+	// errConflict is returned when a conditional write fails because the
+	// item was created, changed or deleted by someone else
+	errConflict = errors.New("conflicting write")
+)
+
+type dynamoClient interface {
+	GetItemWithContext(aws.Context, *dynamodb.GetItemInput, ...request.Option) (*dynamodb.GetItemOutput, error)
+	PutItemWithContext(aws.Context, *dynamodb.PutItemInput, ...request.Option) (*dynamodb.PutItemOutput, error)
+	DeleteItemWithContext(aws.Context, *dynamodb.DeleteItemInput, ...request.Option) (*dynamodb.DeleteItemOutput, error)
+	QueryWithContext(aws.Context, *dynamodb.QueryInput, ...request.Option) (*dynamodb.QueryOutput, error)
+}
+
+// item is an object stored in the table
+type item struct {
+	kind string
+	key  string
+
+	// version is incremented on every write and used to detect concurrent
+	// writes. Zero means the item has not been stored yet.
+	version int64
+
+	// data is the object marshaled as protobuf
+	data []byte
+
+	// expiresAt, when non-zero, is the time in seconds since the epoch after
+	// which DynamoDB deletes the item
+	expiresAt int64
+}
+
+func itemKey(kind, key string) string {
+	return kind + "#" + key
+}
+
+type table struct {
+	client dynamoClient
+	name   string
+}
+
+// get returns the item with the given key or nil if it does not exist
+func (t *table) get(ctx context.Context, kind, key string) (*item, error) {
+	out, err := t.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(t.name),
+		Key:            keyAttrs(kind, key),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, dynamoError.Wrap(err)
+	}
+	if len(out.Item) == 0 {
+		return nil, nil
+	}
+	return attrsToItem(out.Item)
+}
+
+// put stores the item. If the item has not been stored yet it fails with
+// errConflict if another item with the same key exists, otherwise it fails
+// with errConflict if the stored item has changed since it was read. The
+// version of the item is incremented on success.
+func (t *table) put(ctx context.Context, it *item) error {
+	in := &dynamodb.PutItemInput{
+		TableName: aws.String(t.name),
+	}
+	if it.version == 0 {
+		in.ConditionExpression = aws.String("attribute_not_exists(#pk)")
+		in.ExpressionAttributeNames = map[string]*string{"#pk": aws.String(attrKey)}
+	} else {
+		in.ConditionExpression = aws.String("#version = :version")
+		in.ExpressionAttributeNames = map[string]*string{"#version": aws.String(attrVersion)}
+		in.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":version": numberAttr(it.version)}
+	}
+
+	next := *it
+	next.version++
+	in.Item = itemToAttrs(&next)
+
+	if _, err := t.client.PutItemWithContext(ctx, in); err != nil {
+		return conditionalError(err)
+	}
+	it.version = next.version
+	return nil
+}
+
+// delete removes the item. It fails with errConflict if the stored item has
+// changed since it was read.
+func (t *table) delete(ctx context.Context, it *item) error {
+	_, err := t.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(t.name),
+		Key:                       keyAttrs(it.kind, it.key),
+		ConditionExpression:       aws.String("#version = :version"),
+		ExpressionAttributeNames:  map[string]*string{"#version": aws.String(attrVersion)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":version": numberAttr(it.version)},
+	})
+	return conditionalError(err)
+}
+
+// list returns all the items of the given kind sorted by key. The index is
+// eventually consistent, so items written very recently may be missing or
+// stale.
+func (t *table) list(ctx context.Context, kind string) ([]*item, error) {
+	in := &dynamodb.QueryInput{
+		TableName:                 aws.String(t.name),
+		IndexName:                 aws.String(kindIndex),
+		KeyConditionExpression:    aws.String("#kind = :kind"),
+		ExpressionAttributeNames:  map[string]*string{"#kind": aws.String(attrKind)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":kind": {S: aws.String(kind)}},
+	}
+
+	var items []*item
+	for {
+		out, err := t.client.QueryWithContext(ctx, in)
+		if err != nil {
+			return nil, dynamoError.Wrap(err)
+		}
+		for _, attrs := range out.Items {
+			it, err := attrsToItem(attrs)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, it)
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		in.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].key < items[j].key
+	})
+	return items, nil
+}
+
+// withRetries runs the read-modify-write operation until it does not fail
+// with errConflict, up to maxUpdateAttempts times
+func withRetries(ctx context.Context, op func() error) error {
+	backoff := updateRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err != errConflict {
+			return err
+		}
+		if attempt >= maxUpdateAttempts {
+			return dynamoError.New("giving up after %d attempts: %v", attempt, err)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return dynamoError.Wrap(ctx.Err())
+		}
+		backoff *= 2
+	}
+}
+
+func conditionalError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return errConflict
+	}
+	return dynamoError.Wrap(err)
+}
+
+func keyAttrs(kind, key string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		attrKey: {S: aws.String(itemKey(kind, key))},
+	}
+}
+
+func itemToAttrs(it *item) map[string]*dynamodb.AttributeValue {
+	attrs := map[string]*dynamodb.AttributeValue{
+		attrKey:     {S: aws.String(itemKey(it.kind, it.key))},
+		attrKind:    {S: aws.String(it.kind)},
+		attrVersion: numberAttr(it.version),
+		attrData:    {B: it.data},
+	}
+	if it.expiresAt != 0 {
+		attrs[attrTTL] = numberAttr(it.expiresAt)
+	}
+	return attrs
+}
+
+func attrsToItem(attrs map[string]*dynamodb.AttributeValue) (*item, error) {
+	kind := stringAttrValue(attrs[attrKind])
+	pk := stringAttrValue(attrs[attrKey])
+	if kind == "" || len(pk) <= len(kind)+1 || pk[:len(kind)+1] != kind+"#" {
+		return nil, dynamoError.New("malformed item %q", pk)
+	}
+
+	version, err := numberAttrValue(attrs[attrVersion])
+	if err != nil {
+		return nil, dynamoError.New("malformed version of item %q: %v", pk, err)
+	}
+	expiresAt, err := numberAttrValue(attrs[attrTTL])
+	if err != nil {
+		return nil, dynamoError.New("malformed ttl of item %q: %v", pk, err)
+	}
+
+	var data []byte
+	if attrs[attrData] != nil {
+		data = attrs[attrData].B
+	}
+
+	return &item{
+		kind:      kind,
+		key:       pk[len(kind)+1:],
+		version:   version,
+		data:      data,
+		expiresAt: expiresAt,
+	}, nil
+}
+
+func numberAttr(n int64) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(n, 10))}
+}
+
+func numberAttrValue(attr *dynamodb.AttributeValue) (int64, error) {
+	if attr == nil || attr.N == nil {
+		return 0, nil
+	}
+	return strconv.ParseInt(*attr.N, 10, 64)
+}
+
+func stringAttrValue(attr *dynamodb.AttributeValue) string {
+	if attr == nil {
+		return ""
+	}
+	return aws.StringValue(attr.S)
+}
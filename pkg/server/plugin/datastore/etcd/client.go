@@ -0,0 +1,401 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// client talks to etcd through the JSON gateway of its v3 API, which is
+// served on the same port as the gRPC API. See
+// https://etcd.io/docs/latest/dev-guide/api_grpc_gateway/.
+type client struct {
+	endpoints []string
+	username  string
+	password  string
+	http      *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+func newClient(endpoints []string, username, password string, tlsConfig *tls.Config) *client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &client{
+		endpoints: endpoints,
+		username:  username,
+		password:  password,
+		http:      &http.Client{Transport: transport},
+	}
+}
+
+type keyValue struct {
+	Key            []byte      `json:"key"`
+	Value          []byte      `json:"value"`
+	CreateRevision int64String `json:"create_revision"`
+	ModRevision    int64String `json:"mod_revision"`
+}
+
+type responseHeader struct {
+	Revision int64String `json:"revision"`
+}
+
+type rangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+}
+
+type rangeResponse struct {
+	Header responseHeader `json:"header"`
+	Kvs    []*keyValue    `json:"kvs"`
+}
+
+type compare struct {
+	Key            []byte       `json:"key"`
+	Target         string       `json:"target"`
+	Result         string       `json:"result"`
+	CreateRevision *int64String `json:"create_revision,omitempty"`
+	ModRevision    *int64String `json:"mod_revision,omitempty"`
+}
+
+type requestOp struct {
+	RequestPut         *putRequest         `json:"request_put,omitempty"`
+	RequestDeleteRange *deleteRangeRequest `json:"request_delete_range,omitempty"`
+}
+
+type putRequest struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type deleteRangeRequest struct {
+	Key []byte `json:"key"`
+}
+
+type txnRequest struct {
+	Compare []compare   `json:"compare"`
+	Success []requestOp `json:"success"`
+}
+
+type txnResponse struct {
+	Header    responseHeader `json:"header"`
+	Succeeded bool           `json:"succeeded"`
+}
+
+type watchCreateRequest struct {
+	Key           []byte      `json:"key"`
+	RangeEnd      []byte      `json:"range_end,omitempty"`
+	StartRevision int64String `json:"start_revision"`
+}
+
+type watchRequest struct {
+	CreateRequest *watchCreateRequest `json:"create_request"`
+}
+
+type event struct {
+	// Type is empty for puts, since it is the default value
+	Type string    `json:"type"`
+	Kv   *keyValue `json:"kv"`
+}
+
+type watchResponse struct {
+	Result struct {
+		Header          responseHeader `json:"header"`
+		Created         bool           `json:"created"`
+		Canceled        bool           `json:"canceled"`
+		CompactRevision int64String    `json:"compact_revision"`
+		CancelReason    string         `json:"cancel_reason"`
+		Events          []*event       `json:"events"`
+	} `json:"result"`
+	Error *gatewayError `json:"error"`
+}
+
+type gatewayError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+func (e *gatewayError) Error() string {
+	return e.Message
+}
+
+// get returns the key value or nil if the key does not exist
+func (c *client) get(ctx context.Context, key string) (*keyValue, error) {
+	resp := new(rangeResponse)
+	if err := c.call(ctx, "/v3/kv/range", &rangeRequest{Key: []byte(key)}, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	return resp.Kvs[0], nil
+}
+
+// list returns the key values with the given prefix, sorted by key, and the
+// revision of the store they were read at
+func (c *client) list(ctx context.Context, prefix string) ([]*keyValue, int64, error) {
+	resp := new(rangeResponse)
+	if err := c.call(ctx, "/v3/kv/range", &rangeRequest{
+		Key:      []byte(prefix),
+		RangeEnd: prefixEnd(prefix),
+	}, resp); err != nil {
+		return nil, 0, err
+	}
+	return resp.Kvs, int64(resp.Header.Revision), nil
+}
+
+// create stores the value if the key does not exist. It returns the revision
+// of the store after the write.
+func (c *client) create(ctx context.Context, key string, value []byte) (int64, error) {
+	zero := int64String(0)
+	return c.txn(ctx, compare{
+		Key:            []byte(key),
+		Target:         "CREATE",
+		Result:         "EQUAL",
+		CreateRevision: &zero,
+	}, requestOp{RequestPut: &putRequest{Key: []byte(key), Value: value}})
+}
+
+// update stores the value if the key has not been modified since the given
+// revision. It returns the revision of the store after the write.
+func (c *client) update(ctx context.Context, key string, value []byte, modRevision int64) (int64, error) {
+	rev := int64String(modRevision)
+	return c.txn(ctx, compare{
+		Key:         []byte(key),
+		Target:      "MOD",
+		Result:      "EQUAL",
+		ModRevision: &rev,
+	}, requestOp{RequestPut: &putRequest{Key: []byte(key), Value: value}})
+}
+
+// delete deletes the key if it has not been modified since the given
+// revision. It returns the revision of the store after the write.
+func (c *client) delete(ctx context.Context, key string, modRevision int64) (int64, error) {
+	rev := int64String(modRevision)
+	return c.txn(ctx, compare{
+		Key:         []byte(key),
+		Target:      "MOD",
+		Result:      "EQUAL",
+		ModRevision: &rev,
+	}, requestOp{RequestDeleteRange: &deleteRangeRequest{Key: []byte(key)}})
+}
+
+func (c *client) txn(ctx context.Context, cmp compare, op requestOp) (int64, error) {
+	resp := new(txnResponse)
+	if err := c.call(ctx, "/v3/kv/txn", &txnRequest{
+		Compare: []compare{cmp},
+		Success: []requestOp{op},
+	}, resp); err != nil {
+		return 0, err
+	}
+	if !resp.Succeeded {
+		return 0, errConflict
+	}
+	return int64(resp.Header.Revision), nil
+}
+
+// watch calls fn with the events on keys with the given prefix from the
+// given revision on, along with the revision of the last event, until the
+// context is done or the watch fails
+func (c *client) watch(ctx context.Context, prefix string, startRevision int64, fn func(revision int64, events []*event)) error {
+	body, err := c.stream(ctx, "/v3/watch", &watchRequest{
+		CreateRequest: &watchCreateRequest{
+			Key:           []byte(prefix),
+			RangeEnd:      prefixEnd(prefix),
+			StartRevision: int64String(startRevision),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	decoder := json.NewDecoder(body)
+	for {
+		resp := new(watchResponse)
+		if err := decoder.Decode(resp); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return etcdError.New("watch stream failed: %v", err)
+		}
+		switch {
+		case resp.Error != nil:
+			return etcdError.New("watch failed: %v", resp.Error)
+		case resp.Result.CompactRevision > 0:
+			return etcdError.New("watch failed: revision %d has been compacted", resp.Result.CompactRevision)
+		case resp.Result.Canceled:
+			return etcdError.New("watch canceled: %s", resp.Result.CancelReason)
+		}
+		if len(resp.Result.Events) == 0 {
+			continue
+		}
+
+		// the header holds the current revision of the store, which may be
+		// ahead of the events still to be sent, so the revision the changes
+		// have been applied up to is taken from the last event
+		last := resp.Result.Events[len(resp.Result.Events)-1]
+		if last.Kv == nil {
+			continue
+		}
+		fn(int64(last.Kv.ModRevision), resp.Result.Events)
+	}
+}
+
+// call sends the request to the first endpoint that can be reached and
+// decodes the response
+func (c *client) call(ctx context.Context, path string, req, resp interface{}) error {
+	body, err := c.stream(ctx, path, req)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(resp); err != nil {
+		return etcdError.New("unable to decode response: %v", err)
+	}
+	return nil
+}
+
+func (c *client) stream(ctx context.Context, path string, req interface{}) (io.ReadCloser, error) {
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, etcdError.Wrap(err)
+	}
+
+	token, err := c.getToken(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.post(ctx, path, reqBody, token)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && c.username != "" {
+		// the token has expired, authenticate again
+		resp.Body.Close()
+		if token, err = c.getToken(ctx, true); err != nil {
+			return nil, err
+		}
+		resp, err = c.post(ctx, path, reqBody, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp.Body, nil
+}
+
+// post sends the request to each endpoint in turn until one can be reached
+func (c *client) post(ctx context.Context, path string, body []byte, token string) (*http.Response, error) {
+	var errors []string
+	for _, endpoint := range c.endpoints {
+		req, err := http.NewRequest("POST", strings.TrimSuffix(endpoint, "/")+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, etcdError.Wrap(err)
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+
+		resp, err := c.http.Do(req)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, etcdError.Wrap(ctx.Err())
+		}
+		errors = append(errors, err.Error())
+	}
+	return nil, etcdError.New("unable to reach etcd: %s", strings.Join(errors, "; "))
+}
+
+func (c *client) getToken(ctx context.Context, renew bool) (string, error) {
+	if c.username == "" {
+		return "", nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && !renew {
+		return c.token, nil
+	}
+
+	reqBody, err := json.Marshal(map[string]string{
+		"name":     c.username,
+		"password": c.password,
+	})
+	if err != nil {
+		return "", etcdError.Wrap(err)
+	}
+	resp, err := c.post(ctx, "/v3/auth/authenticate", reqBody, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", etcdError.New("unable to authenticate: %v", responseError(resp))
+	}
+
+	authResp := new(struct {
+		Token string `json:"token"`
+	})
+	if err := json.NewDecoder(resp.Body).Decode(authResp); err != nil {
+		return "", etcdError.New("unable to decode authentication response: %v", err)
+	}
+	c.token = authResp.Token
+	return c.token, nil
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	gwErr := new(gatewayError)
+	if err := json.Unmarshal(body, gwErr); err == nil && gwErr.Message != "" {
+		return etcdError.New("request failed (%d): %s", resp.StatusCode, gwErr.Message)
+	}
+	return etcdError.New("request failed (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// prefixEnd returns the end of the range of keys with the given prefix
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// the prefix is all 0xff bytes, so the range extends to the end
+	return []byte{0}
+}
+
+// int64String is an int64 that is encoded as a JSON string, as the gateway
+// does for 64-bit integers. It is also decoded from JSON numbers.
+type int64String int64
+
+func (i int64String) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(i), 10))
+}
+
+func (i *int64String) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s", data)
+	}
+	*i = int64String(n)
+	return nil
+}
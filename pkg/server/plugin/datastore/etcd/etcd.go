@@ -0,0 +1,1220 @@
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/pkg/common/idutil"
+	"github.com/spiffe/spire/pkg/common/selector"
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/zeebo/errs"
+)
+
+const (
+	defaultPrefix = "/spire"
+)
+
+var (
+	pluginInfo = spi.GetPluginInfoResponse{
+		Description: "",
+		DateCreated: "",
+		Version:     "",
+		Author:      "",
+		Company:     "",
+	}
+
+	etcdError = errs.Class("datastore-etcd")
+)
+
+type configuration struct {
+	Endpoints    []string `hcl:"endpoints" json:"endpoints"`
+	Prefix       string   `hcl:"prefix" json:"prefix"`
+	Username     string   `hcl:"username" json:"username"`
+	Password     string   `hcl:"password" json:"password"`
+	CABundlePath string   `hcl:"ca_bundle_path" json:"ca_bundle_path"`
+	CertPath     string   `hcl:"cert_path" json:"cert_path"`
+	KeyPath      string   `hcl:"key_path" json:"key_path"`
+}
+
+type etcdPlugin struct {
+	mu      sync.RWMutex
+	store   *store
+	entries *entryCache
+
+	// stopWatch stops keeping the entry cache of the current configuration
+	// up to date
+	stopWatch context.CancelFunc
+}
+
+var _ datastore.Plugin = (*etcdPlugin)(nil)
+
+func newPlugin() *etcdPlugin {
+	return &etcdPlugin{}
+}
+
+// New creates a new etcd plugin struct. Configure must be called
+// in order to connect to etcd.
+func New() datastore.Plugin {
+	return newPlugin()
+}
+
+// CreateBundle stores the given bundle
+func (ds *etcdPlugin) CreateBundle(ctx context.Context, req *datastore.CreateBundleRequest) (*datastore.CreateBundleResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := normalizeTrustDomain(req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	switch err := s.create(ctx, kindBundle, id, req.Bundle); {
+	case err == errConflict:
+		return nil, etcdError.New("bundle %q already exists", id)
+	case err != nil:
+		return nil, err
+	}
+
+	return &datastore.CreateBundleResponse{
+		Bundle: req.Bundle,
+	}, nil
+}
+
+// UpdateBundle updates an existing bundle with the given CAs. Overwrites any
+// existing certificates.
+func (ds *etcdPlugin) UpdateBundle(ctx context.Context, req *datastore.UpdateBundleRequest) (*datastore.UpdateBundleResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := normalizeTrustDomain(req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := withRetries(ctx, func() error {
+		kv, err := s.get(ctx, kindBundle, id)
+		if err != nil {
+			return err
+		}
+		if kv == nil {
+			return errNotFound()
+		}
+		return s.update(ctx, kv, req.Bundle)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.UpdateBundleResponse{
+		Bundle: req.Bundle,
+	}, nil
+}
+
+// AppendBundle adds the CAs and keys of the given bundle to the existing
+// one, creating it if it does not exist
+func (ds *etcdPlugin) AppendBundle(ctx context.Context, req *datastore.AppendBundleRequest) (*datastore.AppendBundleResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := normalizeTrustDomain(req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle *datastore.Bundle
+	if err := withRetries(ctx, func() error {
+		kv, err := s.get(ctx, kindBundle, id)
+		if err != nil {
+			return err
+		}
+		if kv == nil {
+			bundle = req.Bundle
+			return s.create(ctx, kindBundle, id, bundle)
+		}
+
+		existing := new(datastore.Bundle)
+		if err := unmarshalValue(kv, existing); err != nil {
+			return err
+		}
+
+		var changed bool
+		bundle, changed = bundleutil.MergeBundles(existing, req.Bundle)
+		if !changed {
+			return nil
+		}
+		return s.update(ctx, kv, bundle)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.AppendBundleResponse{
+		Bundle: bundle,
+	}, nil
+}
+
+// DeleteBundle deletes the bundle with the given trust domain. Registration
+// entries federated with it are handled according to the request mode.
+func (ds *etcdPlugin) DeleteBundle(ctx context.Context, req *datastore.DeleteBundleRequest) (*datastore.DeleteBundleResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := s.get(ctx, kindBundle, req.TrustDomainId)
+	if err != nil {
+		return nil, err
+	}
+	if kv == nil {
+		return nil, errNotFound()
+	}
+
+	entries, err := listEntries(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	var federatedEntries []*common.RegistrationEntry
+	for _, entry := range entries {
+		if federatesWith(entry, req.TrustDomainId) {
+			federatedEntries = append(federatedEntries, entry)
+		}
+	}
+
+	if len(federatedEntries) > 0 {
+		switch req.Mode {
+		case datastore.DeleteBundleRequest_DELETE:
+			for _, entry := range federatedEntries {
+				if _, err := deleteEntry(ctx, s, entry.EntryId); err != nil {
+					return nil, err
+				}
+			}
+		case datastore.DeleteBundleRequest_DISSOCIATE:
+			for _, entry := range federatedEntries {
+				if err := dissociateEntry(ctx, s, entry.EntryId, req.TrustDomainId); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, etcdError.New("cannot delete bundle; federated with %d registration entries", len(federatedEntries))
+		}
+	}
+
+	bundle := new(datastore.Bundle)
+	if err := withRetries(ctx, func() error {
+		kv, err := s.get(ctx, kindBundle, req.TrustDomainId)
+		if err != nil {
+			return err
+		}
+		if kv == nil {
+			return errNotFound()
+		}
+		if err := unmarshalValue(kv, bundle); err != nil {
+			return err
+		}
+		return s.delete(ctx, kv)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.DeleteBundleResponse{
+		Bundle: bundle,
+	}, nil
+}
+
+// FetchBundle returns the bundle matching the specified Trust Domain.
+func (ds *etcdPlugin) FetchBundle(ctx context.Context, req *datastore.FetchBundleRequest) (*datastore.FetchBundleResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := s.get(ctx, kindBundle, req.TrustDomainId)
+	if err != nil {
+		return nil, err
+	}
+	if kv == nil {
+		return &datastore.FetchBundleResponse{}, nil
+	}
+
+	bundle := new(datastore.Bundle)
+	if err := unmarshalValue(kv, bundle); err != nil {
+		return nil, err
+	}
+
+	return &datastore.FetchBundleResponse{
+		Bundle: bundle,
+	}, nil
+}
+
+// ListBundles can be used to fetch all existing bundles.
+func (ds *etcdPlugin) ListBundles(ctx context.Context, req *datastore.ListBundlesRequest) (*datastore.ListBundlesResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := s.list(ctx, kindBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &datastore.ListBundlesResponse{}
+	for _, kv := range kvs {
+		bundle := new(datastore.Bundle)
+		if err := unmarshalValue(kv, bundle); err != nil {
+			return nil, err
+		}
+		resp.Bundles = append(resp.Bundles, bundle)
+	}
+	return resp, nil
+}
+
+// CreateAttestedNode stores the given attested node
+func (ds *etcdPlugin) CreateAttestedNode(ctx context.Context,
+	req *datastore.CreateAttestedNodeRequest) (*datastore.CreateAttestedNodeResponse, error) {
+
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Node == nil {
+		return nil, etcdError.New("invalid request: missing attested node")
+	}
+
+	node := &datastore.AttestedNode{
+		SpiffeId:            req.Node.SpiffeId,
+		AttestationDataType: req.Node.AttestationDataType,
+		CertSerialNumber:    req.Node.CertSerialNumber,
+		CertNotAfter:        req.Node.CertNotAfter,
+	}
+
+	switch err := s.create(ctx, kindNode, node.SpiffeId, node); {
+	case err == errConflict:
+		return nil, etcdError.New("attested node %q already exists", node.SpiffeId)
+	case err != nil:
+		return nil, err
+	}
+
+	return &datastore.CreateAttestedNodeResponse{
+		Node: node,
+	}, nil
+}
+
+// FetchAttestedNode fetches an existing attested node by SPIFFE ID
+func (ds *etcdPlugin) FetchAttestedNode(ctx context.Context,
+	req *datastore.FetchAttestedNodeRequest) (*datastore.FetchAttestedNodeResponse, error) {
+
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := s.get(ctx, kindNode, req.SpiffeId)
+	if err != nil {
+		return nil, err
+	}
+	if kv == nil {
+		return &datastore.FetchAttestedNodeResponse{}, nil
+	}
+
+	node := new(datastore.AttestedNode)
+	if err := unmarshalValue(kv, node); err != nil {
+		return nil, err
+	}
+
+	return &datastore.FetchAttestedNodeResponse{
+		Node: node,
+	}, nil
+}
+
+// ListAttestedNodes lists all attested nodes (pagination available)
+func (ds *etcdPlugin) ListAttestedNodes(ctx context.Context,
+	req *datastore.ListAttestedNodesRequest) (*datastore.ListAttestedNodesResponse, error) {
+
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := s.list(ctx, kindNode)
+	if err != nil {
+		return nil, err
+	}
+
+	p := req.Pagination
+	resp := &datastore.ListAttestedNodesResponse{
+		Pagination: p,
+	}
+	for _, kv := range kvs {
+		if !inPage(p, s.id(kindNode, kv), len(resp.Nodes)) {
+			continue
+		}
+
+		node := new(datastore.AttestedNode)
+		if err := unmarshalValue(kv, node); err != nil {
+			return nil, err
+		}
+		if req.ByExpiresBefore != nil && node.CertNotAfter >= req.ByExpiresBefore.Value {
+			continue
+		}
+
+		resp.Nodes = append(resp.Nodes, node)
+		updatePaginationToken(p, node.SpiffeId)
+	}
+	return resp, nil
+}
+
+// UpdateAttestedNode updates the certificate serial number and expiration of
+// the given attested node
+func (ds *etcdPlugin) UpdateAttestedNode(ctx context.Context,
+	req *datastore.UpdateAttestedNodeRequest) (*datastore.UpdateAttestedNodeResponse, error) {
+
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(datastore.AttestedNode)
+	if err := withRetries(ctx, func() error {
+		kv, err := s.get(ctx, kindNode, req.SpiffeId)
+		if err != nil {
+			return err
+		}
+		if kv == nil {
+			return errNotFound()
+		}
+		if err := unmarshalValue(kv, node); err != nil {
+			return err
+		}
+
+		node.CertSerialNumber = req.CertSerialNumber
+		node.CertNotAfter = req.CertNotAfter
+		return s.update(ctx, kv, node)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.UpdateAttestedNodeResponse{
+		Node: node,
+	}, nil
+}
+
+// DeleteAttestedNode deletes the given attested node
+func (ds *etcdPlugin) DeleteAttestedNode(ctx context.Context,
+	req *datastore.DeleteAttestedNodeRequest) (*datastore.DeleteAttestedNodeResponse, error) {
+
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(datastore.AttestedNode)
+	if err := withRetries(ctx, func() error {
+		kv, err := s.get(ctx, kindNode, req.SpiffeId)
+		if err != nil {
+			return err
+		}
+		if kv == nil {
+			return errNotFound()
+		}
+		if err := unmarshalValue(kv, node); err != nil {
+			return err
+		}
+		if req.ByCertSerialNumber != nil && node.CertSerialNumber != req.ByCertSerialNumber.Value {
+			node = nil
+			return nil
+		}
+		// the delete fails if the node changed since it was read
+		return s.delete(ctx, kv)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.DeleteAttestedNodeResponse{
+		Node: node,
+	}, nil
+}
+
+// SetNodeSelectors replaces the selectors of the given node
+func (ds *etcdPlugin) SetNodeSelectors(ctx context.Context, req *datastore.SetNodeSelectorsRequest) (*datastore.SetNodeSelectorsResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Selectors == nil {
+		return nil, errors.New("invalid request: missing selectors")
+	}
+
+	if err := withRetries(ctx, func() error {
+		kv, err := s.get(ctx, kindNodeSelectors, req.Selectors.SpiffeId)
+		if err != nil {
+			return err
+		}
+		if kv == nil {
+			return s.create(ctx, kindNodeSelectors, req.Selectors.SpiffeId, req.Selectors)
+		}
+		return s.update(ctx, kv, req.Selectors)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.SetNodeSelectorsResponse{}, nil
+}
+
+// GetNodeSelectors gets node (agent) selectors by SPIFFE ID
+func (ds *etcdPlugin) GetNodeSelectors(ctx context.Context,
+	req *datastore.GetNodeSelectorsRequest) (*datastore.GetNodeSelectorsResponse, error) {
+
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := s.get(ctx, kindNodeSelectors, req.SpiffeId)
+	if err != nil {
+		return nil, err
+	}
+
+	selectors := &datastore.NodeSelectors{
+		SpiffeId: req.SpiffeId,
+	}
+	if kv != nil {
+		if err := unmarshalValue(kv, selectors); err != nil {
+			return nil, err
+		}
+	}
+
+	return &datastore.GetNodeSelectorsResponse{
+		Selectors: selectors,
+	}, nil
+}
+
+// CreateRegistrationEntry stores the given registration entry under a new
+// entry ID
+func (ds *etcdPlugin) CreateRegistrationEntry(ctx context.Context,
+	req *datastore.CreateRegistrationEntryRequest) (*datastore.CreateRegistrationEntryResponse, error) {
+
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Entry == nil {
+		return nil, etcdError.New("invalid request: missing registered entry")
+	}
+
+	if err := validateRegistrationEntry(req.Entry); err != nil {
+		return nil, err
+	}
+
+	if err := checkFederatedBundles(ctx, s, req.Entry.FederatesWith); err != nil {
+		return nil, err
+	}
+
+	entryID, err := newRegistrationEntryID()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := proto.Clone(req.Entry).(*common.RegistrationEntry)
+	entry.EntryId = entryID
+
+	switch err := s.create(ctx, kindEntry, entryID, entry); {
+	case err == errConflict:
+		return nil, etcdError.New("registration entry %q already exists", entryID)
+	case err != nil:
+		return nil, err
+	}
+
+	return &datastore.CreateRegistrationEntryResponse{
+		Entry: entry,
+	}, nil
+}
+
+// FetchRegistrationEntry fetches an existing registration entry by entry ID
+func (ds *etcdPlugin) FetchRegistrationEntry(ctx context.Context,
+	req *datastore.FetchRegistrationEntryRequest) (*datastore.FetchRegistrationEntryResponse, error) {
+
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := s.get(ctx, kindEntry, req.EntryId)
+	if err != nil {
+		return nil, err
+	}
+	if kv == nil {
+		return &datastore.FetchRegistrationEntryResponse{}, nil
+	}
+
+	entry := new(common.RegistrationEntry)
+	if err := unmarshalValue(kv, entry); err != nil {
+		return nil, err
+	}
+
+	return &datastore.FetchRegistrationEntryResponse{
+		Entry: entry,
+	}, nil
+}
+
+// ListRegistrationEntries lists all registration entries (pagination
+// available). Entries are served from the entry cache when it is in sync.
+func (ds *etcdPlugin) ListRegistrationEntries(ctx context.Context,
+	req *datastore.ListRegistrationEntriesRequest) (*datastore.ListRegistrationEntriesResponse, error) {
+
+	s, cache, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	var bySelectors selector.Set
+	if req.BySelectors != nil && len(req.BySelectors.Selectors) > 0 {
+		switch req.BySelectors.Match {
+		case datastore.BySelectors_MATCH_SUBSET, datastore.BySelectors_MATCH_EXACT:
+		default:
+			return nil, fmt.Errorf("unhandled match behavior %q", req.BySelectors.Match)
+		}
+		bySelectors = selector.NewSetFromRaw(req.BySelectors.Selectors)
+	}
+
+	entries, ok := cache.snapshot(ctx, s.lastEntryWrite())
+	if !ok {
+		entries, err = listEntries(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := req.Pagination
+	resp := &datastore.ListRegistrationEntriesResponse{
+		Pagination: p,
+	}
+	for _, entry := range entries {
+		if !inPage(p, entry.EntryId, len(resp.Entries)) {
+			continue
+		}
+		if req.ByParentId != nil && entry.ParentId != req.ByParentId.Value {
+			continue
+		}
+		if req.BySpiffeId != nil && entry.SpiffeId != req.BySpiffeId.Value {
+			continue
+		}
+		if req.ByExpiresBefore != nil && (entry.EntryExpiry == 0 || entry.EntryExpiry >= req.ByExpiresBefore.Value) {
+			continue
+		}
+		if bySelectors != nil {
+			entrySelectors := selector.NewSetFromRaw(entry.Selectors)
+			switch req.BySelectors.Match {
+			case datastore.BySelectors_MATCH_SUBSET:
+				if entrySelectors.Size() == 0 || !bySelectors.IncludesSet(entrySelectors) {
+					continue
+				}
+			case datastore.BySelectors_MATCH_EXACT:
+				if !bySelectors.Equal(entrySelectors) {
+					continue
+				}
+			}
+		}
+
+		// cached entries are shared between listings
+		resp.Entries = append(resp.Entries, proto.Clone(entry).(*common.RegistrationEntry))
+		updatePaginationToken(p, entry.EntryId)
+	}
+
+	util.SortRegistrationEntries(resp.Entries)
+	return resp, nil
+}
+
+// UpdateRegistrationEntry updates an existing registration entry
+func (ds *etcdPlugin) UpdateRegistrationEntry(ctx context.Context,
+	req *datastore.UpdateRegistrationEntryRequest) (*datastore.UpdateRegistrationEntryResponse, error) {
+
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Entry == nil {
+		return nil, etcdError.New("no registration entry provided")
+	}
+
+	if err := validateRegistrationEntry(req.Entry); err != nil {
+		return nil, err
+	}
+
+	if err := checkFederatedBundles(ctx, s, req.Entry.FederatesWith); err != nil {
+		return nil, err
+	}
+
+	if err := withRetries(ctx, func() error {
+		kv, err := s.get(ctx, kindEntry, req.Entry.EntryId)
+		if err != nil {
+			return err
+		}
+		if kv == nil {
+			return errNotFound()
+		}
+		return s.update(ctx, kv, req.Entry)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.UpdateRegistrationEntryResponse{
+		Entry: req.Entry,
+	}, nil
+}
+
+// DeleteRegistrationEntry deletes the given registration entry
+func (ds *etcdPlugin) DeleteRegistrationEntry(ctx context.Context,
+	req *datastore.DeleteRegistrationEntryRequest) (*datastore.DeleteRegistrationEntryResponse, error) {
+
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := deleteEntry(ctx, s, req.EntryId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &datastore.DeleteRegistrationEntryResponse{
+		Entry: entry,
+	}, nil
+}
+
+// BatchCreateRegistrationEntries creates the registration entries one at a
+// time, since etcd has no transactions spanning that many items. The
+// entries are validated up front, but if creating one fails, the entries
+// before it remain created.
+func (ds *etcdPlugin) BatchCreateRegistrationEntries(ctx context.Context,
+	req *datastore.BatchCreateRegistrationEntriesRequest) (*datastore.BatchCreateRegistrationEntriesResponse, error) {
+
+	for _, entry := range req.Entries {
+		if entry == nil {
+			return nil, etcdError.New("invalid request: missing registered entry")
+		}
+		if err := validateRegistrationEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &datastore.BatchCreateRegistrationEntriesResponse{
+		Entries: make([]*common.RegistrationEntry, 0, len(req.Entries)),
+	}
+	for _, entry := range req.Entries {
+		createResp, err := ds.CreateRegistrationEntry(ctx, &datastore.CreateRegistrationEntryRequest{
+			Entry: entry,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, createResp.Entry)
+	}
+	return resp, nil
+}
+
+// BatchUpdateRegistrationEntries updates the registration entries one at a
+// time. If updating one fails, the entries before it remain updated.
+func (ds *etcdPlugin) BatchUpdateRegistrationEntries(ctx context.Context,
+	req *datastore.BatchUpdateRegistrationEntriesRequest) (*datastore.BatchUpdateRegistrationEntriesResponse, error) {
+
+	for _, entry := range req.Entries {
+		if entry == nil {
+			return nil, etcdError.New("no registration entry provided")
+		}
+		if err := validateRegistrationEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &datastore.BatchUpdateRegistrationEntriesResponse{
+		Entries: make([]*common.RegistrationEntry, 0, len(req.Entries)),
+	}
+	for _, entry := range req.Entries {
+		updateResp, err := ds.UpdateRegistrationEntry(ctx, &datastore.UpdateRegistrationEntryRequest{
+			Entry: entry,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, updateResp.Entry)
+	}
+	return resp, nil
+}
+
+// BatchDeleteRegistrationEntries deletes the registration entries one at a
+// time. If deleting one fails, the entries before it remain deleted.
+func (ds *etcdPlugin) BatchDeleteRegistrationEntries(ctx context.Context,
+	req *datastore.BatchDeleteRegistrationEntriesRequest) (*datastore.BatchDeleteRegistrationEntriesResponse, error) {
+
+	resp := &datastore.BatchDeleteRegistrationEntriesResponse{
+		Entries: make([]*common.RegistrationEntry, 0, len(req.EntryIds)),
+	}
+	for _, entryID := range req.EntryIds {
+		deleteResp, err := ds.DeleteRegistrationEntry(ctx, &datastore.DeleteRegistrationEntryRequest{
+			EntryId: entryID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, deleteResp.Entry)
+	}
+	return resp, nil
+}
+
+// CreateJoinToken takes a Token message and stores it
+func (ds *etcdPlugin) CreateJoinToken(ctx context.Context, req *datastore.CreateJoinTokenRequest) (*datastore.CreateJoinTokenResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.JoinToken == nil || req.JoinToken.Token == "" || req.JoinToken.Expiry == 0 {
+		return nil, errors.New("token and expiry are required")
+	}
+
+	if req.JoinToken.MaxUses < 0 {
+		return nil, errors.New("max uses cannot be negative")
+	}
+
+	token := &datastore.JoinToken{
+		Token:   req.JoinToken.Token,
+		Expiry:  req.JoinToken.Expiry,
+		MaxUses: req.JoinToken.MaxUses,
+	}
+
+	switch err := s.create(ctx, kindJoinToken, token.Token, token); {
+	case err == errConflict:
+		// the token is a secret, so it is left out of the error
+		return nil, etcdError.New("join token already exists")
+	case err != nil:
+		return nil, err
+	}
+
+	return &datastore.CreateJoinTokenResponse{
+		JoinToken: req.JoinToken,
+	}, nil
+}
+
+// FetchJoinToken fetches a token by its value
+func (ds *etcdPlugin) FetchJoinToken(ctx context.Context, req *datastore.FetchJoinTokenRequest) (*datastore.FetchJoinTokenResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := s.get(ctx, kindJoinToken, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if kv == nil {
+		return &datastore.FetchJoinTokenResponse{}, nil
+	}
+
+	token := new(datastore.JoinToken)
+	if err := unmarshalValue(kv, token); err != nil {
+		return nil, err
+	}
+
+	return &datastore.FetchJoinTokenResponse{
+		JoinToken: token,
+	}, nil
+}
+
+// DeleteJoinToken deletes the matching join token
+func (ds *etcdPlugin) DeleteJoinToken(ctx context.Context, req *datastore.DeleteJoinTokenRequest) (*datastore.DeleteJoinTokenResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	token := new(datastore.JoinToken)
+	if err := withRetries(ctx, func() error {
+		kv, err := s.get(ctx, kindJoinToken, req.Token)
+		if err != nil {
+			return err
+		}
+		if kv == nil {
+			return errNotFound()
+		}
+		if err := unmarshalValue(kv, token); err != nil {
+			return err
+		}
+		return s.delete(ctx, kv)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.DeleteJoinTokenResponse{
+		JoinToken: token,
+	}, nil
+}
+
+// ListJoinTokens lists all join tokens
+func (ds *etcdPlugin) ListJoinTokens(ctx context.Context, req *datastore.ListJoinTokensRequest) (*datastore.ListJoinTokensResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := s.list(ctx, kindJoinToken)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &datastore.ListJoinTokensResponse{
+		JoinTokens: make([]*datastore.JoinToken, 0, len(kvs)),
+	}
+	for _, kv := range kvs {
+		token := new(datastore.JoinToken)
+		if err := unmarshalValue(kv, token); err != nil {
+			return nil, err
+		}
+		resp.JoinTokens = append(resp.JoinTokens, token)
+	}
+	return resp, nil
+}
+
+// UseJoinToken records a use of the join token, deleting it once it has
+// been used the maximum number of times
+func (ds *etcdPlugin) UseJoinToken(ctx context.Context, req *datastore.UseJoinTokenRequest) (*datastore.UseJoinTokenResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	var token *datastore.JoinToken
+	if err := withRetries(ctx, func() error {
+		token = nil
+		kv, err := s.get(ctx, kindJoinToken, req.Token)
+		if err != nil || kv == nil {
+			return err
+		}
+
+		token = new(datastore.JoinToken)
+		if err := unmarshalValue(kv, token); err != nil {
+			return err
+		}
+
+		// a token without a max use count can only be used once
+		maxUses := token.MaxUses
+		if maxUses < 1 {
+			maxUses = 1
+		}
+
+		if token.Uses+1 >= maxUses {
+			return s.delete(ctx, kv)
+		}
+
+		used := proto.Clone(token).(*datastore.JoinToken)
+		used.Uses++
+		return s.update(ctx, kv, used)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &datastore.UseJoinTokenResponse{
+		JoinToken: token,
+	}, nil
+}
+
+// PruneJoinTokens deletes all join tokens expiring before the given time
+func (ds *etcdPlugin) PruneJoinTokens(ctx context.Context, req *datastore.PruneJoinTokensRequest) (*datastore.PruneJoinTokensResponse, error) {
+	s, _, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := s.list(ctx, kindJoinToken)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, kv := range kvs {
+		token := new(datastore.JoinToken)
+		if err := unmarshalValue(kv, token); err != nil {
+			return nil, err
+		}
+		if token.Expiry > req.ExpiresBefore {
+			continue
+		}
+		if err := withRetries(ctx, func() error {
+			kv, err := s.get(ctx, kindJoinToken, token.Token)
+			if err != nil || kv == nil {
+				return err
+			}
+			return s.delete(ctx, kv)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &datastore.PruneJoinTokensResponse{}, nil
+}
+
+// ListChangeEvents lists the registration entry and bundle change events
+// following the given event. Events are the changes seen by the watch of the
+// entry cache, identified by their revision, and are kept in memory.
+func (ds *etcdPlugin) ListChangeEvents(ctx context.Context, req *datastore.ListChangeEventsRequest) (*datastore.ListChangeEventsResponse, error) {
+	if req.Limit < 0 {
+		return nil, etcdError.New("invalid limit %d", req.Limit)
+	}
+
+	_, cache, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, ok := cache.listChangeEvents(req.AfterId, req.Limit)
+	if !ok {
+		return nil, etcdError.New("change events are unavailable until the entry cache is in sync")
+	}
+	return resp, nil
+}
+
+// PruneChangeEvents drops the change events created before the given time
+func (ds *etcdPlugin) PruneChangeEvents(ctx context.Context, req *datastore.PruneChangeEventsRequest) (*datastore.PruneChangeEventsResponse, error) {
+	_, cache, err := ds.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	cache.pruneChangeEvents(req.CreatedBefore)
+	return &datastore.PruneChangeEventsResponse{}, nil
+}
+
+func (ds *etcdPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	// Parse HCL config payload into config struct
+	config := &configuration{}
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, etcdError.New("unable to decode configuration: %v", err)
+	}
+
+	if len(config.Endpoints) == 0 {
+		return nil, etcdError.New("endpoints must be set")
+	}
+	for _, endpoint := range config.Endpoints {
+		if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+			return nil, etcdError.New("endpoint %q must be an http:// or https:// URL", endpoint)
+		}
+	}
+	if config.Prefix == "" {
+		config.Prefix = defaultPrefix
+	}
+	config.Prefix = strings.TrimSuffix(config.Prefix, "/")
+
+	tlsConfig := new(tls.Config)
+	if config.CABundlePath != "" {
+		roots, err := util.LoadCertPool(config.CABundlePath)
+		if err != nil {
+			return nil, etcdError.New("unable to load CA bundle: %v", err)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	if config.CertPath != "" || config.KeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertPath, config.KeyPath)
+		if err != nil {
+			return nil, etcdError.New("unable to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	s := &store{
+		client:         newClient(config.Endpoints, config.Username, config.Password, tlsConfig),
+		prefix:         config.Prefix,
+		entriesWritten: new(int64),
+	}
+	cache := newEntryCache()
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	go cache.run(watchCtx, s)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if ds.stopWatch != nil {
+		ds.stopWatch()
+	}
+	ds.store = s
+	ds.entries = cache
+	ds.stopWatch = stopWatch
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (*etcdPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &pluginInfo, nil
+}
+
+func (ds *etcdPlugin) getStore() (*store, *entryCache, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	if ds.store == nil {
+		return nil, nil, etcdError.New("not configured")
+	}
+	return ds.store, ds.entries, nil
+}
+
+func listEntries(ctx context.Context, s *store) ([]*common.RegistrationEntry, error) {
+	kvs, err := s.list(ctx, kindEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*common.RegistrationEntry, 0, len(kvs))
+	for _, kv := range kvs {
+		entry := new(common.RegistrationEntry)
+		if err := unmarshalValue(kv, entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func deleteEntry(ctx context.Context, s *store, entryID string) (*common.RegistrationEntry, error) {
+	entry := new(common.RegistrationEntry)
+	if err := withRetries(ctx, func() error {
+		kv, err := s.get(ctx, kindEntry, entryID)
+		if err != nil {
+			return err
+		}
+		if kv == nil {
+			return errNotFound()
+		}
+		if err := unmarshalValue(kv, entry); err != nil {
+			return err
+		}
+		return s.delete(ctx, kv)
+	}); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// dissociateEntry removes the trust domain from the trust domains the
+// registration entry is federated with
+func dissociateEntry(ctx context.Context, s *store, entryID, trustDomainID string) error {
+	return withRetries(ctx, func() error {
+		kv, err := s.get(ctx, kindEntry, entryID)
+		if err != nil || kv == nil {
+			return err
+		}
+
+		entry := new(common.RegistrationEntry)
+		if err := unmarshalValue(kv, entry); err != nil {
+			return err
+		}
+
+		var ids []string
+		for _, id := range entry.FederatesWith {
+			if id != trustDomainID {
+				ids = append(ids, id)
+			}
+		}
+		entry.FederatesWith = ids
+		return s.update(ctx, kv, entry)
+	})
+}
+
+func federatesWith(entry *common.RegistrationEntry, trustDomainID string) bool {
+	for _, id := range entry.FederatesWith {
+		if id == trustDomainID {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFederatedBundles makes sure there is a bundle for each of the trust
+// domains
+func checkFederatedBundles(ctx context.Context, s *store, ids []string) error {
+	for _, id := range ids {
+		kv, err := s.get(ctx, kindBundle, id)
+		if err != nil {
+			return err
+		}
+		if kv == nil {
+			return fmt.Errorf("unable to find federated bundle %q", id)
+		}
+	}
+	return nil
+}
+
+// inPage returns true if the object with the given key belongs in the page
+// being listed, given the number of objects already in it. Objects are
+// paginated in key order and the token is the key of the last object of the
+// previous page.
+func inPage(p *datastore.Pagination, key string, count int) bool {
+	if p == nil || p.PageSize <= 0 {
+		return true
+	}
+	return key > p.Token && count < int(p.PageSize)
+}
+
+// updatePaginationToken sets the token to the key of the last object added to
+// the page
+func updatePaginationToken(p *datastore.Pagination, key string) {
+	if p != nil && p.PageSize > 0 {
+		p.Token = key
+	}
+}
+
+func validateRegistrationEntry(entry *common.RegistrationEntry) error {
+	if entry.Selectors == nil || len(entry.Selectors) == 0 {
+		return etcdError.New("invalid registration entry: missing selector list")
+	}
+
+	if len(entry.SpiffeId) == 0 {
+		return etcdError.New("invalid registration entry: missing SPIFFE ID")
+	}
+
+	if entry.Ttl < 0 {
+		return etcdError.New("invalid registration entry: TTL is not set")
+	}
+
+	return nil
+}
+
+// normalizeTrustDomain returns the normalized trust domain ID of the bundle,
+// which bundles are stored under
+func normalizeTrustDomain(pb *datastore.Bundle) (string, error) {
+	if pb == nil {
+		return "", etcdError.New("missing bundle in request")
+	}
+	id, err := idutil.NormalizeSpiffeID(pb.TrustDomainId, idutil.AllowAnyTrustDomain())
+	if err != nil {
+		return "", etcdError.Wrap(err)
+	}
+	return id, nil
+}
+
+func errNotFound() error {
+	return etcdError.New("record not found")
+}
+
+func newRegistrationEntryID() (string, error) {
+	u, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,864 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestEtcd(t *testing.T) {
+	suite.Run(t, new(EtcdSuite))
+}
+
+type EtcdSuite struct {
+	suite.Suite
+
+	etcd *fakeEtcd
+	ds   *etcdPlugin
+}
+
+func (s *EtcdSuite) SetupTest() {
+	s.etcd = newFakeEtcd()
+	s.ds = s.newPlugin()
+}
+
+func (s *EtcdSuite) TearDownTest() {
+	s.ds.stopWatch()
+	s.etcd.Close()
+}
+
+func (s *EtcdSuite) newPlugin() *etcdPlugin {
+	ds := newPlugin()
+	_, err := ds.Configure(context.Background(), &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`endpoints = [%q]`, s.etcd.URL),
+	})
+	s.Require().NoError(err)
+	return ds
+}
+
+func (s *EtcdSuite) TestConfigure() {
+	ds := newPlugin()
+	_, err := ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{})
+	s.Require().EqualError(err, "datastore-etcd: not configured")
+
+	_, err = ds.Configure(context.Background(), &spi.ConfigureRequest{})
+	s.Require().EqualError(err, "datastore-etcd: endpoints must be set")
+
+	_, err = ds.Configure(context.Background(), &spi.ConfigureRequest{
+		Configuration: `endpoints = ["localhost:2379"]`,
+	})
+	s.Require().EqualError(err, `datastore-etcd: endpoint "localhost:2379" must be an http:// or https:// URL`)
+
+	_, err = ds.Configure(context.Background(), &spi.ConfigureRequest{
+		Configuration: `endpoints = ["https://localhost:2379"] cert_path = "/does/not/exist"`,
+	})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "datastore-etcd: unable to load client certificate")
+
+	// objects are stored under the prefix
+	_, err = ds.Configure(context.Background(), &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+			endpoints = ["http://127.0.0.1:1", %q]
+			prefix = "/foo/"
+		`, s.etcd.URL),
+	})
+	s.Require().NoError(err)
+	defer func() { ds.stopWatch() }()
+	_, err = ds.CreateBundle(context.Background(), &datastore.CreateBundleRequest{
+		Bundle: &datastore.Bundle{TrustDomainId: "spiffe://example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Contains(s.etcd.kvs, "/foo/bundles/spiffe://example.org")
+}
+
+func (s *EtcdSuite) TestAuthentication() {
+	s.etcd.users = map[string]string{"spire": "secret"}
+
+	ds := newPlugin()
+	_, err := ds.Configure(context.Background(), &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`endpoints = [%q] username = "spire" password = "wrong"`, s.etcd.URL),
+	})
+	s.Require().NoError(err)
+	defer func() { ds.stopWatch() }()
+	_, err = ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{TrustDomainId: "spiffe://example.org"})
+	s.Require().EqualError(err, "datastore-etcd: unable to authenticate: datastore-etcd: request failed (400): etcdserver: authentication failed, invalid user ID or password")
+
+	_, err = ds.Configure(context.Background(), &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`endpoints = [%q] username = "spire" password = "secret"`, s.etcd.URL),
+	})
+	s.Require().NoError(err)
+	_, err = ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{TrustDomainId: "spiffe://example.org"})
+	s.Require().NoError(err)
+
+	// expired tokens are renewed
+	s.etcd.expireTokens()
+	_, err = ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{TrustDomainId: "spiffe://example.org"})
+	s.Require().NoError(err)
+}
+
+func (s *EtcdSuite) TestBundleCRUD() {
+	bundle := &datastore.Bundle{
+		TrustDomainId: "spiffe://foo",
+		RootCas:       []*common.Certificate{{DerBytes: []byte("cert1")}},
+	}
+
+	// fetch non-existent
+	fresp, err := s.ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().NoError(err)
+	s.Require().Nil(fresp.Bundle)
+
+	// create
+	_, err = s.ds.CreateBundle(context.Background(), &datastore.CreateBundleRequest{Bundle: bundle})
+	s.Require().NoError(err)
+
+	// create again fails
+	_, err = s.ds.CreateBundle(context.Background(), &datastore.CreateBundleRequest{Bundle: bundle})
+	s.Require().EqualError(err, `datastore-etcd: bundle "spiffe://foo" already exists`)
+
+	// fetch
+	fresp, err = s.ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().NoError(err)
+	s.requireProtoEqual(bundle, fresp.Bundle)
+
+	// append
+	aresp, err := s.ds.AppendBundle(context.Background(), &datastore.AppendBundleRequest{
+		Bundle: &datastore.Bundle{
+			TrustDomainId: "spiffe://foo",
+			RootCas:       []*common.Certificate{{DerBytes: []byte("cert2")}},
+		},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(aresp.Bundle.RootCas, 2)
+
+	// append creates missing bundles
+	_, err = s.ds.AppendBundle(context.Background(), &datastore.AppendBundleRequest{
+		Bundle: &datastore.Bundle{TrustDomainId: "spiffe://bar"},
+	})
+	s.Require().NoError(err)
+
+	// update
+	_, err = s.ds.UpdateBundle(context.Background(), &datastore.UpdateBundleRequest{Bundle: bundle})
+	s.Require().NoError(err)
+	_, err = s.ds.UpdateBundle(context.Background(), &datastore.UpdateBundleRequest{
+		Bundle: &datastore.Bundle{TrustDomainId: "spiffe://baz"},
+	})
+	s.Require().EqualError(err, "datastore-etcd: record not found")
+
+	// list
+	lresp, err := s.ds.ListBundles(context.Background(), &datastore.ListBundlesRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(lresp.Bundles, 2)
+	s.Require().Equal("spiffe://bar", lresp.Bundles[0].TrustDomainId)
+	s.requireProtoEqual(bundle, lresp.Bundles[1])
+
+	// delete
+	dresp, err := s.ds.DeleteBundle(context.Background(), &datastore.DeleteBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().NoError(err)
+	s.requireProtoEqual(bundle, dresp.Bundle)
+	_, err = s.ds.DeleteBundle(context.Background(), &datastore.DeleteBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().EqualError(err, "datastore-etcd: record not found")
+}
+
+func (s *EtcdSuite) TestCreateBundleNormalizesTrustDomain() {
+	_, err := s.ds.CreateBundle(context.Background(), &datastore.CreateBundleRequest{
+		Bundle: &datastore.Bundle{TrustDomainId: "spiffe://FOO"},
+	})
+	s.Require().NoError(err)
+
+	fresp, err := s.ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().NoError(err)
+	s.Require().NotNil(fresp.Bundle)
+}
+
+func (s *EtcdSuite) TestDeleteBundleModes() {
+	s.createBundle("spiffe://otherdomain.org")
+
+	createEntry := func() *common.RegistrationEntry {
+		resp, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
+			Entry: &common.RegistrationEntry{
+				SpiffeId:      "spiffe://example.org/foo",
+				Selectors:     []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+				FederatesWith: []string{"spiffe://otherdomain.org"},
+			},
+		})
+		s.Require().NoError(err)
+		return resp.Entry
+	}
+
+	// restrict
+	entry := createEntry()
+	_, err := s.ds.DeleteBundle(context.Background(), &datastore.DeleteBundleRequest{
+		TrustDomainId: "spiffe://otherdomain.org",
+	})
+	s.Require().EqualError(err, "datastore-etcd: cannot delete bundle; federated with 1 registration entries")
+
+	// dissociate
+	_, err = s.ds.DeleteBundle(context.Background(), &datastore.DeleteBundleRequest{
+		TrustDomainId: "spiffe://otherdomain.org",
+		Mode:          datastore.DeleteBundleRequest_DISSOCIATE,
+	})
+	s.Require().NoError(err)
+	fresp, err := s.ds.FetchRegistrationEntry(context.Background(), &datastore.FetchRegistrationEntryRequest{EntryId: entry.EntryId})
+	s.Require().NoError(err)
+	s.Require().NotNil(fresp.Entry)
+	s.Require().Empty(fresp.Entry.FederatesWith)
+
+	// delete
+	s.createBundle("spiffe://otherdomain.org")
+	entry = createEntry()
+	_, err = s.ds.DeleteBundle(context.Background(), &datastore.DeleteBundleRequest{
+		TrustDomainId: "spiffe://otherdomain.org",
+		Mode:          datastore.DeleteBundleRequest_DELETE,
+	})
+	s.Require().NoError(err)
+	fresp, err = s.ds.FetchRegistrationEntry(context.Background(), &datastore.FetchRegistrationEntryRequest{EntryId: entry.EntryId})
+	s.Require().NoError(err)
+	s.Require().Nil(fresp.Entry)
+}
+
+func (s *EtcdSuite) TestAttestedNodeCRUD() {
+	node := &datastore.AttestedNode{
+		SpiffeId:            "spiffe://example.org/spire/agent/foo",
+		AttestationDataType: "aws-tag",
+		CertSerialNumber:    "1234",
+		CertNotAfter:        1000,
+	}
+
+	_, err := s.ds.CreateAttestedNode(context.Background(), &datastore.CreateAttestedNodeRequest{})
+	s.Require().EqualError(err, "datastore-etcd: invalid request: missing attested node")
+
+	_, err = s.ds.CreateAttestedNode(context.Background(), &datastore.CreateAttestedNodeRequest{Node: node})
+	s.Require().NoError(err)
+	_, err = s.ds.CreateAttestedNode(context.Background(), &datastore.CreateAttestedNodeRequest{Node: node})
+	s.Require().EqualError(err, `datastore-etcd: attested node "spiffe://example.org/spire/agent/foo" already exists`)
+
+	fresp, err := s.ds.FetchAttestedNode(context.Background(), &datastore.FetchAttestedNodeRequest{SpiffeId: node.SpiffeId})
+	s.Require().NoError(err)
+	s.requireProtoEqual(node, fresp.Node)
+
+	uresp, err := s.ds.UpdateAttestedNode(context.Background(), &datastore.UpdateAttestedNodeRequest{
+		SpiffeId:         node.SpiffeId,
+		CertSerialNumber: "5678",
+		CertNotAfter:     2000,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal("aws-tag", uresp.Node.AttestationDataType)
+	s.Require().Equal("5678", uresp.Node.CertSerialNumber)
+	s.Require().Equal(int64(2000), uresp.Node.CertNotAfter)
+
+	// the serial number changed with the update
+	dresp, err := s.ds.DeleteAttestedNode(context.Background(), &datastore.DeleteAttestedNodeRequest{
+		SpiffeId:           node.SpiffeId,
+		ByCertSerialNumber: &wrappers.StringValue{Value: node.CertSerialNumber},
+	})
+	s.Require().NoError(err)
+	s.Require().Nil(dresp.Node)
+
+	dresp, err = s.ds.DeleteAttestedNode(context.Background(), &datastore.DeleteAttestedNodeRequest{
+		SpiffeId:           node.SpiffeId,
+		ByCertSerialNumber: &wrappers.StringValue{Value: "5678"},
+	})
+	s.Require().NoError(err)
+	s.requireProtoEqual(uresp.Node, dresp.Node)
+
+	fresp, err = s.ds.FetchAttestedNode(context.Background(), &datastore.FetchAttestedNodeRequest{SpiffeId: node.SpiffeId})
+	s.Require().NoError(err)
+	s.Require().Nil(fresp.Node)
+
+	_, err = s.ds.UpdateAttestedNode(context.Background(), &datastore.UpdateAttestedNodeRequest{SpiffeId: node.SpiffeId})
+	s.Require().EqualError(err, "datastore-etcd: record not found")
+	_, err = s.ds.DeleteAttestedNode(context.Background(), &datastore.DeleteAttestedNodeRequest{SpiffeId: node.SpiffeId})
+	s.Require().EqualError(err, "datastore-etcd: record not found")
+}
+
+func (s *EtcdSuite) TestListAttestedNodes() {
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		_, err := s.ds.CreateAttestedNode(context.Background(), &datastore.CreateAttestedNodeRequest{
+			Node: &datastore.AttestedNode{
+				SpiffeId:     "spiffe://example.org/spire/agent/" + id,
+				CertNotAfter: int64(i),
+			},
+		})
+		s.Require().NoError(err)
+	}
+
+	resp, err := s.ds.ListAttestedNodes(context.Background(), &datastore.ListAttestedNodesRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(resp.Nodes, 5)
+
+	resp, err = s.ds.ListAttestedNodes(context.Background(), &datastore.ListAttestedNodesRequest{
+		ByExpiresBefore: &wrappers.Int64Value{Value: 2},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"spiffe://example.org/spire/agent/a", "spiffe://example.org/spire/agent/b"}, nodeIDs(resp.Nodes))
+
+	p := &datastore.Pagination{PageSize: 2}
+	var pages [][]string
+	for {
+		resp, err = s.ds.ListAttestedNodes(context.Background(), &datastore.ListAttestedNodesRequest{Pagination: p})
+		s.Require().NoError(err)
+		if len(resp.Nodes) == 0 {
+			break
+		}
+		pages = append(pages, nodeIDs(resp.Nodes))
+		p = resp.Pagination
+	}
+	s.Require().Equal([][]string{
+		{"spiffe://example.org/spire/agent/a", "spiffe://example.org/spire/agent/b"},
+		{"spiffe://example.org/spire/agent/c", "spiffe://example.org/spire/agent/d"},
+		{"spiffe://example.org/spire/agent/e"},
+	}, pages)
+}
+
+func (s *EtcdSuite) TestNodeSelectors() {
+	resp, err := s.ds.GetNodeSelectors(context.Background(), &datastore.GetNodeSelectorsRequest{SpiffeId: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal("foo", resp.Selectors.SpiffeId)
+	s.Require().Empty(resp.Selectors.Selectors)
+
+	_, err = s.ds.SetNodeSelectors(context.Background(), &datastore.SetNodeSelectorsRequest{})
+	s.Require().EqualError(err, "invalid request: missing selectors")
+
+	for _, selectors := range [][]*common.Selector{
+		{{Type: "FOO1", Value: "1"}},
+		{{Type: "FOO2", Value: "2"}, {Type: "FOO3", Value: "3"}},
+	} {
+		_, err = s.ds.SetNodeSelectors(context.Background(), &datastore.SetNodeSelectorsRequest{
+			Selectors: &datastore.NodeSelectors{SpiffeId: "foo", Selectors: selectors},
+		})
+		s.Require().NoError(err)
+
+		resp, err = s.ds.GetNodeSelectors(context.Background(), &datastore.GetNodeSelectorsRequest{SpiffeId: "foo"})
+		s.Require().NoError(err)
+		s.requireProtoEqual(&datastore.NodeSelectors{SpiffeId: "foo", Selectors: selectors}, resp.Selectors)
+	}
+}
+
+func (s *EtcdSuite) TestRegistrationEntryCRUD() {
+	_, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{})
+	s.Require().EqualError(err, "datastore-etcd: invalid request: missing registered entry")
+
+	_, err = s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
+		Entry: &common.RegistrationEntry{SpiffeId: "spiffe://example.org/foo"},
+	})
+	s.Require().EqualError(err, "datastore-etcd: invalid registration entry: missing selector list")
+
+	entry := &common.RegistrationEntry{
+		SpiffeId:      "spiffe://example.org/foo",
+		ParentId:      "spiffe://example.org/spire/agent/foo",
+		Selectors:     []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+		FederatesWith: []string{"spiffe://otherdomain.org"},
+		Ttl:           60,
+	}
+	_, err = s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{Entry: entry})
+	s.Require().EqualError(err, `unable to find federated bundle "spiffe://otherdomain.org"`)
+
+	s.createBundle("spiffe://otherdomain.org")
+	cresp, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{Entry: entry})
+	s.Require().NoError(err)
+	s.Require().NotEmpty(cresp.Entry.EntryId)
+	s.Require().Empty(entry.EntryId, "request entry should not be modified")
+
+	fresp, err := s.ds.FetchRegistrationEntry(context.Background(), &datastore.FetchRegistrationEntryRequest{EntryId: cresp.Entry.EntryId})
+	s.Require().NoError(err)
+	s.requireProtoEqual(cresp.Entry, fresp.Entry)
+
+	updated := proto.Clone(cresp.Entry).(*common.RegistrationEntry)
+	updated.Ttl = 120
+	updated.FederatesWith = nil
+	_, err = s.ds.UpdateRegistrationEntry(context.Background(), &datastore.UpdateRegistrationEntryRequest{Entry: updated})
+	s.Require().NoError(err)
+
+	fresp, err = s.ds.FetchRegistrationEntry(context.Background(), &datastore.FetchRegistrationEntryRequest{EntryId: cresp.Entry.EntryId})
+	s.Require().NoError(err)
+	s.requireProtoEqual(updated, fresp.Entry)
+
+	dresp, err := s.ds.DeleteRegistrationEntry(context.Background(), &datastore.DeleteRegistrationEntryRequest{EntryId: cresp.Entry.EntryId})
+	s.Require().NoError(err)
+	s.requireProtoEqual(updated, dresp.Entry)
+
+	_, err = s.ds.UpdateRegistrationEntry(context.Background(), &datastore.UpdateRegistrationEntryRequest{Entry: updated})
+	s.Require().EqualError(err, "datastore-etcd: record not found")
+	_, err = s.ds.DeleteRegistrationEntry(context.Background(), &datastore.DeleteRegistrationEntryRequest{EntryId: cresp.Entry.EntryId})
+	s.Require().EqualError(err, "datastore-etcd: record not found")
+}
+
+func (s *EtcdSuite) TestBatchRegistrationEntries() {
+	foo := &common.RegistrationEntry{
+		SpiffeId:  "spiffe://example.org/foo",
+		ParentId:  "spiffe://example.org/spire/agent/foo",
+		Selectors: []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+	}
+	bar := &common.RegistrationEntry{
+		SpiffeId:  "spiffe://example.org/bar",
+		ParentId:  "spiffe://example.org/spire/agent/foo",
+		Selectors: []*common.Selector{{Type: "unix", Value: "uid:1001"}},
+	}
+
+	// entries are validated before any is created
+	_, err := s.ds.BatchCreateRegistrationEntries(context.Background(), &datastore.BatchCreateRegistrationEntriesRequest{
+		Entries: []*common.RegistrationEntry{foo, {SpiffeId: "spiffe://example.org/baz"}},
+	})
+	s.Require().EqualError(err, "datastore-etcd: invalid registration entry: missing selector list")
+	lresp, err := s.ds.ListRegistrationEntries(context.Background(), &datastore.ListRegistrationEntriesRequest{})
+	s.Require().NoError(err)
+	s.Require().Empty(lresp.Entries)
+
+	cresp, err := s.ds.BatchCreateRegistrationEntries(context.Background(), &datastore.BatchCreateRegistrationEntriesRequest{
+		Entries: []*common.RegistrationEntry{foo, bar},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(cresp.Entries, 2)
+	s.Require().Equal(foo.SpiffeId, cresp.Entries[0].SpiffeId)
+	s.Require().Equal(bar.SpiffeId, cresp.Entries[1].SpiffeId)
+
+	var updated []*common.RegistrationEntry
+	var ids []string
+	for _, entry := range cresp.Entries {
+		entry = proto.Clone(entry).(*common.RegistrationEntry)
+		entry.Ttl = 120
+		updated = append(updated, entry)
+		ids = append(ids, entry.EntryId)
+	}
+	uresp, err := s.ds.BatchUpdateRegistrationEntries(context.Background(), &datastore.BatchUpdateRegistrationEntriesRequest{
+		Entries: updated,
+	})
+	s.Require().NoError(err)
+	s.Require().Len(uresp.Entries, 2)
+	for i, entry := range updated {
+		fresp, err := s.ds.FetchRegistrationEntry(context.Background(), &datastore.FetchRegistrationEntryRequest{EntryId: entry.EntryId})
+		s.Require().NoError(err)
+		s.requireProtoEqual(entry, fresp.Entry)
+		s.requireProtoEqual(entry, uresp.Entries[i])
+	}
+
+	dresp, err := s.ds.BatchDeleteRegistrationEntries(context.Background(), &datastore.BatchDeleteRegistrationEntriesRequest{
+		EntryIds: ids,
+	})
+	s.Require().NoError(err)
+	s.Require().Len(dresp.Entries, 2)
+	s.requireProtoEqual(updated[0], dresp.Entries[0])
+	s.requireProtoEqual(updated[1], dresp.Entries[1])
+
+	_, err = s.ds.BatchDeleteRegistrationEntries(context.Background(), &datastore.BatchDeleteRegistrationEntriesRequest{
+		EntryIds: ids,
+	})
+	s.Require().EqualError(err, "datastore-etcd: record not found")
+}
+
+func (s *EtcdSuite) TestListRegistrationEntries() {
+	a := &common.Selector{Type: "a", Value: "1"}
+	b := &common.Selector{Type: "b", Value: "2"}
+	c := &common.Selector{Type: "c", Value: "3"}
+
+	createEntry := func(spiffeID, parentID string, selectors ...*common.Selector) string {
+		resp, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
+			Entry: &common.RegistrationEntry{
+				SpiffeId:  spiffeID,
+				ParentId:  parentID,
+				Selectors: selectors,
+			},
+		})
+		s.Require().NoError(err)
+		return resp.Entry.SpiffeId
+	}
+	createEntry("spiffe://example.org/a", "spiffe://example.org/p1", a)
+	createEntry("spiffe://example.org/ab", "spiffe://example.org/p1", a, b)
+	createEntry("spiffe://example.org/abc", "spiffe://example.org/p2", a, b, c)
+	createEntry("spiffe://example.org/c", "spiffe://example.org/p2", c)
+
+	list := func(req *datastore.ListRegistrationEntriesRequest) []string {
+		resp, err := s.ds.ListRegistrationEntries(context.Background(), req)
+		s.Require().NoError(err)
+		var ids []string
+		for _, entry := range resp.Entries {
+			ids = append(ids, entry.SpiffeId)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/a",
+		"spiffe://example.org/ab",
+		"spiffe://example.org/abc",
+		"spiffe://example.org/c",
+	}, list(&datastore.ListRegistrationEntriesRequest{}))
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/a",
+		"spiffe://example.org/ab",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		ByParentId: &wrappers.StringValue{Value: "spiffe://example.org/p1"},
+	}))
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/c",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		BySpiffeId: &wrappers.StringValue{Value: "spiffe://example.org/c"},
+	}))
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/ab",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		BySelectors: &datastore.BySelectors{
+			Selectors: []*common.Selector{a, b},
+			Match:     datastore.BySelectors_MATCH_EXACT,
+		},
+	}))
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/a",
+		"spiffe://example.org/ab",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		BySelectors: &datastore.BySelectors{
+			Selectors: []*common.Selector{a, b},
+			Match:     datastore.BySelectors_MATCH_SUBSET,
+		},
+	}))
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/c",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		ByParentId: &wrappers.StringValue{Value: "spiffe://example.org/p2"},
+		BySelectors: &datastore.BySelectors{
+			Selectors: []*common.Selector{a, c},
+			Match:     datastore.BySelectors_MATCH_SUBSET,
+		},
+	}))
+
+	// paginate through all of the entries
+	var ids []string
+	p := &datastore.Pagination{PageSize: 3}
+	for {
+		resp, err := s.ds.ListRegistrationEntries(context.Background(), &datastore.ListRegistrationEntriesRequest{Pagination: p})
+		s.Require().NoError(err)
+		if len(resp.Entries) == 0 {
+			break
+		}
+		s.Require().True(len(resp.Entries) <= 3)
+		for _, entry := range resp.Entries {
+			ids = append(ids, entry.SpiffeId)
+		}
+		p = resp.Pagination
+	}
+	sort.Strings(ids)
+	s.Require().Equal([]string{
+		"spiffe://example.org/a",
+		"spiffe://example.org/ab",
+		"spiffe://example.org/abc",
+		"spiffe://example.org/c",
+	}, ids)
+
+	// only entries with an expiry before the given time are listed
+	_, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
+		Entry: &common.RegistrationEntry{
+			SpiffeId:    "spiffe://example.org/expired",
+			ParentId:    "spiffe://example.org/p1",
+			Selectors:   []*common.Selector{a},
+			EntryExpiry: 1000,
+		},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{
+		"spiffe://example.org/expired",
+	}, list(&datastore.ListRegistrationEntriesRequest{
+		ByExpiresBefore: &wrappers.Int64Value{Value: 2000},
+	}))
+	s.Require().Empty(list(&datastore.ListRegistrationEntriesRequest{
+		ByExpiresBefore: &wrappers.Int64Value{Value: 1000},
+	}))
+}
+
+func (s *EtcdSuite) TestJoinTokens() {
+	_, err := s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "foo"},
+	})
+	s.Require().EqualError(err, "token and expiry are required")
+
+	_, err = s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "foo", Expiry: 1000, MaxUses: -1},
+	})
+	s.Require().EqualError(err, "max uses cannot be negative")
+
+	for _, token := range []*datastore.JoinToken{
+		{Token: "foo", Expiry: 1000, MaxUses: 2},
+		{Token: "bar", Expiry: 2000},
+	} {
+		_, err = s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{JoinToken: token})
+		s.Require().NoError(err)
+	}
+	_, err = s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "foo", Expiry: 1000},
+	})
+	s.Require().EqualError(err, "datastore-etcd: join token already exists")
+
+	lresp, err := s.ds.ListJoinTokens(context.Background(), &datastore.ListJoinTokensRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(lresp.JoinTokens, 2)
+	s.Require().Equal("bar", lresp.JoinTokens[0].Token)
+	s.Require().Equal("foo", lresp.JoinTokens[1].Token)
+
+	// tokens are deleted once used the maximum number of times
+	uresp, err := s.ds.UseJoinToken(context.Background(), &datastore.UseJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal(int32(0), uresp.JoinToken.Uses)
+	fresp, err := s.ds.FetchJoinToken(context.Background(), &datastore.FetchJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal(int32(1), fresp.JoinToken.Uses)
+
+	uresp, err = s.ds.UseJoinToken(context.Background(), &datastore.UseJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal(int32(1), uresp.JoinToken.Uses)
+	fresp, err = s.ds.FetchJoinToken(context.Background(), &datastore.FetchJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Nil(fresp.JoinToken)
+
+	uresp, err = s.ds.UseJoinToken(context.Background(), &datastore.UseJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Nil(uresp.JoinToken)
+
+	// prune
+	_, err = s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "baz", Expiry: 3000},
+	})
+	s.Require().NoError(err)
+	_, err = s.ds.PruneJoinTokens(context.Background(), &datastore.PruneJoinTokensRequest{ExpiresBefore: 2000})
+	s.Require().NoError(err)
+	lresp, err = s.ds.ListJoinTokens(context.Background(), &datastore.ListJoinTokensRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(lresp.JoinTokens, 1)
+	s.Require().Equal("baz", lresp.JoinTokens[0].Token)
+
+	// delete
+	dresp, err := s.ds.DeleteJoinToken(context.Background(), &datastore.DeleteJoinTokenRequest{Token: "baz"})
+	s.Require().NoError(err)
+	s.Require().Equal("baz", dresp.JoinToken.Token)
+	_, err = s.ds.DeleteJoinToken(context.Background(), &datastore.DeleteJoinTokenRequest{Token: "baz"})
+	s.Require().EqualError(err, "datastore-etcd: record not found")
+}
+
+func (s *EtcdSuite) TestConcurrentUpdatesAreRetried() {
+	_, err := s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "foo", Expiry: 1000, MaxUses: 3},
+	})
+	s.Require().NoError(err)
+
+	// the token is used by someone else between the read and the write of
+	// the first attempt
+	s.etcd.beforeTxn = func() {
+		s.etcd.beforeTxn = nil
+		_, err := s.ds.UseJoinToken(context.Background(), &datastore.UseJoinTokenRequest{Token: "foo"})
+		s.Require().NoError(err)
+	}
+
+	resp, err := s.ds.UseJoinToken(context.Background(), &datastore.UseJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal(int32(1), resp.JoinToken.Uses)
+
+	fresp, err := s.ds.FetchJoinToken(context.Background(), &datastore.FetchJoinTokenRequest{Token: "foo"})
+	s.Require().NoError(err)
+	s.Require().Equal(int32(2), fresp.JoinToken.Uses)
+}
+
+func (s *EtcdSuite) TestEtcdErrors() {
+	s.ds.stopWatch()
+	s.etcd.Close()
+	_, err := s.ds.FetchBundle(context.Background(), &datastore.FetchBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "datastore-etcd: unable to reach etcd: ")
+}
+
+func (s *EtcdSuite) TestListRegistrationEntriesFromCache() {
+	entryPrefix := "/spire/entries/"
+	create := func(ds *etcdPlugin, spiffeID string) {
+		_, err := ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
+			Entry: &common.RegistrationEntry{
+				SpiffeId:  spiffeID,
+				Selectors: []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+			},
+		})
+		s.Require().NoError(err)
+	}
+	list := func() []string {
+		resp, err := s.ds.ListRegistrationEntries(context.Background(), &datastore.ListRegistrationEntriesRequest{})
+		s.Require().NoError(err)
+		var ids []string
+		for _, entry := range resp.Entries {
+			ids = append(ids, entry.SpiffeId)
+		}
+		return ids
+	}
+
+	// wait for the caches of this server and of another one to be loaded
+	other := s.newPlugin()
+	defer func() { other.stopWatch() }()
+	for _, ds := range []*etcdPlugin{s.ds, other} {
+		cache := ds.entries
+		s.eventually(func() bool {
+			_, ok := cache.snapshot(context.Background(), 0)
+			return ok
+		})
+	}
+	loads := s.etcd.rangeCount(entryPrefix)
+
+	// the entries written by the plugin are listed right away
+	create(s.ds, "spiffe://example.org/foo")
+	s.Require().Equal([]string{"spiffe://example.org/foo"}, list())
+
+	// the entries written by other servers are listed once the watch
+	// delivers them
+	create(other, "spiffe://example.org/bar")
+	s.eventually(func() bool {
+		return len(list()) == 2
+	})
+
+	// none of the listings read the entries from etcd
+	s.Require().Equal(loads, s.etcd.rangeCount(entryPrefix))
+
+	// malformed entries are dropped
+	s.etcd.put(entryPrefix+"malformed", []byte("garbage"))
+	s.Require().Len(list(), 2)
+}
+
+func (s *EtcdSuite) TestListRegistrationEntriesWithoutCache() {
+	// the cache does not catch up with writes without a working watch
+	s.etcd.failWatches = true
+	ds := s.newPlugin()
+	defer func() { ds.stopWatch() }()
+
+	_, err := ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
+		Entry: &common.RegistrationEntry{
+			SpiffeId:  "spiffe://example.org/foo",
+			Selectors: []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+		},
+	})
+	s.Require().NoError(err)
+
+	resp, err := ds.ListRegistrationEntries(context.Background(), &datastore.ListRegistrationEntriesRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(resp.Entries, 1)
+}
+
+func (s *EtcdSuite) TestChangeEvents() {
+	ctx := context.Background()
+
+	// events are only available once the entry cache is loaded
+	s.eventually(func() bool {
+		_, err := s.ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{})
+		return err == nil
+	})
+
+	s.createBundle("spiffe://otherdomain.org")
+	resp, err := s.ds.CreateRegistrationEntry(ctx, &datastore.CreateRegistrationEntryRequest{
+		Entry: &common.RegistrationEntry{
+			SpiffeId:  "spiffe://example.org/foo",
+			Selectors: []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+		},
+	})
+	s.Require().NoError(err)
+	entry := resp.Entry
+	entry.Ttl = 60
+	_, err = s.ds.UpdateRegistrationEntry(ctx, &datastore.UpdateRegistrationEntryRequest{Entry: entry})
+	s.Require().NoError(err)
+	_, err = s.ds.DeleteRegistrationEntry(ctx, &datastore.DeleteRegistrationEntryRequest{EntryId: entry.EntryId})
+	s.Require().NoError(err)
+
+	// changes to other records are not events
+	_, err = s.ds.CreateJoinToken(ctx, &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "foobar", Expiry: time.Now().Add(time.Hour).Unix()},
+	})
+	s.Require().NoError(err)
+
+	var events []*datastore.ChangeEvent
+	s.eventually(func() bool {
+		resp, err := s.ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{})
+		s.Require().NoError(err)
+		events = resp.Events
+		return len(events) == 4
+	})
+
+	type change struct {
+		Kind datastore.ChangeEvent_Kind
+		Op   datastore.ChangeEvent_Op
+		Key  string
+	}
+	var changes []change
+	for i, event := range events {
+		changes = append(changes, change{Kind: event.Kind, Op: event.Op, Key: event.Key})
+		if i > 0 {
+			s.Require().True(event.Id > events[i-1].Id)
+		}
+	}
+	s.Require().Equal([]change{
+		{datastore.ChangeEvent_BUNDLE, datastore.ChangeEvent_CREATED, "spiffe://otherdomain.org"},
+		{datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_CREATED, entry.EntryId},
+		{datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_UPDATED, entry.EntryId},
+		{datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_DELETED, entry.EntryId},
+	}, changes)
+
+	// events are listed after the given one
+	listResp, err := s.ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{
+		AfterId: events[1].Id,
+		Limit:   1,
+	})
+	s.Require().NoError(err)
+	s.Require().Len(listResp.Events, 1)
+	s.Require().Equal(events[2].Id, listResp.Events[0].Id)
+	s.Require().False(listResp.ResyncRequired)
+
+	// pruned events require a resync, but the most recent one is kept
+	_, err = s.ds.PruneChangeEvents(ctx, &datastore.PruneChangeEventsRequest{
+		CreatedBefore: time.Now().Add(time.Hour).Unix(),
+	})
+	s.Require().NoError(err)
+	listResp, err = s.ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{
+		AfterId: events[1].Id,
+	})
+	s.Require().NoError(err)
+	s.Require().True(listResp.ResyncRequired)
+	listResp, err = s.ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(listResp.Events, 1)
+	s.Require().Equal(events[3].Id, listResp.Events[0].Id)
+}
+
+func (s *EtcdSuite) createBundle(trustDomainID string) {
+	_, err := s.ds.CreateBundle(context.Background(), &datastore.CreateBundleRequest{
+		Bundle: &datastore.Bundle{TrustDomainId: trustDomainID},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *EtcdSuite) eventually(condition func() bool) {
+	deadline := time.Now().Add(time.Minute)
+	for !condition() {
+		if time.Now().After(deadline) {
+			s.FailNow("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *EtcdSuite) requireProtoEqual(expected, actual proto.Message) {
+	if !proto.Equal(expected, actual) {
+		s.Require().Equal(expected, actual)
+	}
+}
+
+func nodeIDs(nodes []*datastore.AttestedNode) []string {
+	var ids []string
+	for _, node := range nodes {
+		ids = append(ids, node.SpiffeId)
+	}
+	return ids
+}
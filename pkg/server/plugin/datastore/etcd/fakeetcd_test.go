@@ -0,0 +1,297 @@
+package etcd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+)
+
+// fakeEtcd serves an in-memory key value store through the subset of the
+// etcd v3 JSON gateway used by the plugin
+type fakeEtcd struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	kvs      map[string]*keyValue
+	revision int64
+	history  []historyEvent
+	updated  chan struct{}
+
+	// ranges counts the range requests per key
+	ranges map[string]int
+
+	// users maps the names of the users to their passwords. When set,
+	// requests must be authenticated.
+	users  map[string]string
+	tokens map[string]bool
+
+	// beforeTxn, when set, is called before transactions are evaluated,
+	// without the lock held
+	beforeTxn func()
+
+	// failWatches makes watches fail right after they are created
+	failWatches bool
+}
+
+type historyEvent struct {
+	revision int64
+	event    *event
+}
+
+func newFakeEtcd() *fakeEtcd {
+	f := &fakeEtcd{
+		kvs:     make(map[string]*keyValue),
+		updated: make(chan struct{}),
+		ranges:  make(map[string]int),
+		tokens:  make(map[string]bool),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/kv/range", f.authenticated(f.handleRange))
+	mux.HandleFunc("/v3/kv/txn", f.authenticated(f.handleTxn))
+	mux.HandleFunc("/v3/watch", f.authenticated(f.handleWatch))
+	mux.HandleFunc("/v3/auth/authenticate", f.handleAuthenticate)
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+func (f *fakeEtcd) put(key string, value []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.apply(&requestOp{RequestPut: &putRequest{Key: []byte(key), Value: value}})
+}
+
+func (f *fakeEtcd) rangeCount(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ranges[key]
+}
+
+// expireTokens invalidates the authentication tokens handed out so far
+func (f *fakeEtcd) expireTokens() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens = make(map[string]bool)
+}
+
+func (f *fakeEtcd) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		ok := f.users == nil || f.tokens[r.Header.Get("Authorization")]
+		f.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "etcdserver: invalid auth token")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (f *fakeEtcd) handleAuthenticate(w http.ResponseWriter, r *http.Request) {
+	req := new(struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	})
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if password, ok := f.users[req.Name]; !ok || password != req.Password {
+		writeError(w, http.StatusBadRequest, "etcdserver: authentication failed, invalid user ID or password")
+		return
+	}
+	token := req.Name + "." + string(rune('a'+len(f.tokens)))
+	f.tokens[token] = true
+	writeJSON(w, map[string]string{"token": token})
+}
+
+func (f *fakeEtcd) handleRange(w http.ResponseWriter, r *http.Request) {
+	req := new(rangeRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ranges[string(req.Key)]++
+
+	resp := &rangeResponse{
+		Header: responseHeader{Revision: int64String(f.revision)},
+	}
+	for key, kv := range f.kvs {
+		if inRange([]byte(key), req.Key, req.RangeEnd) {
+			resp.Kvs = append(resp.Kvs, kv)
+		}
+	}
+	sort.Slice(resp.Kvs, func(i, j int) bool {
+		return bytes.Compare(resp.Kvs[i].Key, resp.Kvs[j].Key) < 0
+	})
+	writeJSON(w, resp)
+}
+
+func (f *fakeEtcd) handleTxn(w http.ResponseWriter, r *http.Request) {
+	req := new(txnRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	beforeTxn := f.beforeTxn
+	f.mu.Unlock()
+	if beforeTxn != nil {
+		beforeTxn()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	succeeded := true
+	for _, cmp := range req.Compare {
+		var createRevision, modRevision int64String
+		if kv, ok := f.kvs[string(cmp.Key)]; ok {
+			createRevision = kv.CreateRevision
+			modRevision = kv.ModRevision
+		}
+		switch {
+		case cmp.Result != "EQUAL":
+			writeError(w, http.StatusBadRequest, "unsupported compare result")
+			return
+		case cmp.Target == "CREATE" && cmp.CreateRevision != nil:
+			succeeded = succeeded && createRevision == *cmp.CreateRevision
+		case cmp.Target == "MOD" && cmp.ModRevision != nil:
+			succeeded = succeeded && modRevision == *cmp.ModRevision
+		default:
+			writeError(w, http.StatusBadRequest, "unsupported compare")
+			return
+		}
+	}
+
+	if succeeded {
+		for i := range req.Success {
+			f.apply(&req.Success[i])
+		}
+	}
+	writeJSON(w, &txnResponse{
+		Header:    responseHeader{Revision: int64String(f.revision)},
+		Succeeded: succeeded,
+	})
+}
+
+// apply applies the operation at a new revision. Must be called with the lock
+// held.
+func (f *fakeEtcd) apply(op *requestOp) {
+	f.revision++
+	switch {
+	case op.RequestPut != nil:
+		key := string(op.RequestPut.Key)
+		kv := &keyValue{
+			Key:            op.RequestPut.Key,
+			Value:          op.RequestPut.Value,
+			CreateRevision: int64String(f.revision),
+			ModRevision:    int64String(f.revision),
+		}
+		if existing, ok := f.kvs[key]; ok {
+			kv.CreateRevision = existing.CreateRevision
+		}
+		f.kvs[key] = kv
+		f.history = append(f.history, historyEvent{revision: f.revision, event: &event{Kv: kv}})
+	case op.RequestDeleteRange != nil:
+		key := string(op.RequestDeleteRange.Key)
+		if _, ok := f.kvs[key]; !ok {
+			return
+		}
+		delete(f.kvs, key)
+		f.history = append(f.history, historyEvent{revision: f.revision, event: &event{
+			Type: "DELETE",
+			Kv:   &keyValue{Key: []byte(key), ModRevision: int64String(f.revision)},
+		}})
+	}
+	close(f.updated)
+	f.updated = make(chan struct{})
+}
+
+func (f *fakeEtcd) handleWatch(w http.ResponseWriter, r *http.Request) {
+	req := new(watchRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil || req.CreateRequest == nil {
+		writeError(w, http.StatusBadRequest, "invalid watch request")
+		return
+	}
+	create := req.CreateRequest
+
+	flusher := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	send := func(resp *watchResponse) bool {
+		if err := encoder.Encode(resp); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	f.mu.Lock()
+	created := new(watchResponse)
+	created.Result.Created = true
+	created.Result.Header.Revision = int64String(f.revision)
+	failWatches := f.failWatches
+	f.mu.Unlock()
+	if !send(created) {
+		return
+	}
+	if failWatches {
+		canceled := new(watchResponse)
+		canceled.Result.Canceled = true
+		canceled.Result.CancelReason = "watch failed"
+		send(canceled)
+		return
+	}
+
+	next := int64(create.StartRevision)
+	for {
+		f.mu.Lock()
+		resp := new(watchResponse)
+		resp.Result.Header.Revision = int64String(f.revision)
+		for _, h := range f.history {
+			if h.revision >= next && inRange(h.event.Kv.Key, create.Key, create.RangeEnd) {
+				resp.Result.Events = append(resp.Result.Events, h.event)
+			}
+		}
+		next = f.revision + 1
+		updated := f.updated
+		f.mu.Unlock()
+
+		if len(resp.Result.Events) > 0 && !send(resp) {
+			return
+		}
+
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func inRange(key, start, end []byte) bool {
+	if len(end) == 0 {
+		return bytes.Equal(key, start)
+	}
+	return bytes.Compare(key, start) >= 0 && (bytes.Equal(end, []byte{0}) || bytes.Compare(key, end) < 0)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v) //nolint: errcheck
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&gatewayError{Message: message, Code: status}) //nolint: errcheck
+}
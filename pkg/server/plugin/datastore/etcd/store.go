@@ -0,0 +1,161 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Objects are stored protobuf-encoded under keys made of the configured
+// prefix, the kind of the object and its natural key, e.g.
+// "/spire/entries/<entry id>".
+const (
+	kindBundle        = "bundles"
+	kindNode          = "nodes"
+	kindNodeSelectors = "nodeselectors"
+	kindEntry         = "entries"
+	kindJoinToken     = "tokens"
+)
+
+const (
+	// maxUpdateAttempts is how many times a read-modify-write operation is
+	// attempted before giving up on conflicting concurrent writes
+	maxUpdateAttempts = 5
+
+	// updateRetryBackoff is how long to wait before the first retry. It
+	// doubles on each subsequent retry.
+	updateRetryBackoff = 10 * time.Millisecond
+)
+
+var (
+	// errConflict is returned when a transaction fails because the key was
+	// created, changed or deleted by someone else
+	errConflict = errors.New("conflicting write")
+)
+
+type store struct {
+	client *client
+	prefix string
+
+	// entriesWritten is the highest revision of the store at which the
+	// plugin wrote a registration entry
+	entriesWritten *int64
+}
+
+func (s *store) key(kind, id string) string {
+	return s.prefix + "/" + kind + "/" + id
+}
+
+func (s *store) kindPrefix(kind string) string {
+	return s.prefix + "/" + kind + "/"
+}
+
+// id returns the natural key of the object stored under the key
+func (s *store) id(kind string, kv *keyValue) string {
+	return strings.TrimPrefix(string(kv.Key), s.kindPrefix(kind))
+}
+
+func (s *store) get(ctx context.Context, kind, id string) (*keyValue, error) {
+	return s.client.get(ctx, s.key(kind, id))
+}
+
+// list returns all the objects of the given kind sorted by key
+func (s *store) list(ctx context.Context, kind string) ([]*keyValue, error) {
+	kvs, _, err := s.client.list(ctx, s.kindPrefix(kind))
+	return kvs, err
+}
+
+// create stores the object if there is none with the same key, otherwise it
+// fails with errConflict
+func (s *store) create(ctx context.Context, kind, id string, msg proto.Message) error {
+	value, err := proto.Marshal(msg)
+	if err != nil {
+		return etcdError.Wrap(err)
+	}
+	key := s.key(kind, id)
+	rev, err := s.client.create(ctx, key, value)
+	if err != nil {
+		return err
+	}
+	s.noteWrite(key, rev)
+	return nil
+}
+
+// update replaces the object read as kv. It fails with errConflict if the
+// object has changed since.
+func (s *store) update(ctx context.Context, kv *keyValue, msg proto.Message) error {
+	value, err := proto.Marshal(msg)
+	if err != nil {
+		return etcdError.Wrap(err)
+	}
+	rev, err := s.client.update(ctx, string(kv.Key), value, int64(kv.ModRevision))
+	if err != nil {
+		return err
+	}
+	s.noteWrite(string(kv.Key), rev)
+	return nil
+}
+
+// delete deletes the object read as kv. It fails with errConflict if the
+// object has changed since.
+func (s *store) delete(ctx context.Context, kv *keyValue) error {
+	rev, err := s.client.delete(ctx, string(kv.Key), int64(kv.ModRevision))
+	if err != nil {
+		return err
+	}
+	s.noteWrite(string(kv.Key), rev)
+	return nil
+}
+
+// noteWrite records the revision of writes to registration entries, so that
+// listings wait for the entry cache to reflect them
+func (s *store) noteWrite(key string, rev int64) {
+	if !strings.HasPrefix(key, s.kindPrefix(kindEntry)) {
+		return
+	}
+	for {
+		written := atomic.LoadInt64(s.entriesWritten)
+		if rev <= written || atomic.CompareAndSwapInt64(s.entriesWritten, written, rev) {
+			return
+		}
+	}
+}
+
+// lastEntryWrite returns the revision of the last write to registration
+// entries made by the plugin
+func (s *store) lastEntryWrite() int64 {
+	return atomic.LoadInt64(s.entriesWritten)
+}
+
+// withRetries runs the read-modify-write operation until it does not fail
+// with errConflict, up to maxUpdateAttempts times
+func withRetries(ctx context.Context, op func() error) error {
+	backoff := updateRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err != errConflict {
+			return err
+		}
+		if attempt >= maxUpdateAttempts {
+			return etcdError.New("giving up after %d attempts: %v", attempt, err)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return etcdError.Wrap(ctx.Err())
+		}
+		backoff *= 2
+	}
+}
+
+func unmarshalValue(kv *keyValue, msg proto.Message) error {
+	if err := proto.Unmarshal(kv.Value, msg); err != nil {
+		return etcdError.New("unable to unmarshal %q: %v", kv.Key, err)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package etcd
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/server/datastore"
+)
+
+const (
+	// cacheWaitTimeout is how long listings wait for the entry cache to
+	// catch up with the writes of the plugin before reading from etcd
+	cacheWaitTimeout = time.Second
+
+	// maxWatchRetryInterval caps the time between attempts to resync the
+	// entry cache after its watch fails
+	maxWatchRetryInterval = 30 * time.Second
+
+	// maxChangeEvents is the number of change events kept in memory
+	maxChangeEvents = 10000
+)
+
+// entryCache holds the registration entries in memory. It is loaded from
+// etcd and then kept up to date by watching the store for changes, so
+// listing entries, which the server does constantly to serve agents, does
+// not read them all from etcd every time.
+//
+// The changes to entries and bundles seen by the watch are also kept as
+// change events, identified by the revision of the change.
+type entryCache struct {
+	mu       sync.Mutex
+	entries  map[string]*common.RegistrationEntry
+	revision int64
+	synced   bool
+
+	// events holds the change events following eventsFrom, the revision
+	// the cache was loaded at or of the last event dropped since
+	events     []*datastore.ChangeEvent
+	eventsFrom int64
+
+	// updated is closed and replaced whenever the cache changes
+	updated chan struct{}
+
+	// retryInterval is the initial time between attempts to resync the
+	// cache after its watch fails
+	retryInterval time.Duration
+}
+
+func newEntryCache() *entryCache {
+	return &entryCache{
+		updated:       make(chan struct{}),
+		retryInterval: time.Second,
+	}
+}
+
+// run keeps the cache in sync with the entries in the store until the
+// context is done
+func (c *entryCache) run(ctx context.Context, s *store) {
+	interval := c.retryInterval
+	for {
+		synced := c.sync(ctx, s)
+		c.unsync()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if synced {
+			interval = c.retryInterval
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+		if interval *= 2; interval > maxWatchRetryInterval {
+			interval = maxWatchRetryInterval
+		}
+	}
+}
+
+// sync loads the entries and applies the changes to them until the watch
+// fails. It returns true if the cache was loaded.
+func (c *entryCache) sync(ctx context.Context, s *store) bool {
+	kvs, revision, err := s.client.list(ctx, s.kindPrefix(kindEntry))
+	if err != nil {
+		return false
+	}
+
+	entries := make(map[string]*common.RegistrationEntry, len(kvs))
+	for _, kv := range kvs {
+		entry := new(common.RegistrationEntry)
+		if err := unmarshalValue(kv, entry); err != nil {
+			return false
+		}
+		entries[string(kv.Key)] = entry
+	}
+
+	c.mu.Lock()
+	c.entries = entries
+	c.revision = revision
+	c.synced = true
+	c.events = nil
+	c.eventsFrom = revision
+	c.notify()
+	c.mu.Unlock()
+
+	// the watch returns an error once the cache falls out of sync
+	s.client.watch(ctx, s.prefix+"/", revision+1, func(revision int64, events []*event) { //nolint: errcheck
+		c.apply(s, revision, events)
+	})
+	return true
+}
+
+// apply applies the changes to the entries up to the given revision
+func (c *entryCache) apply(s *store, revision int64, events []*event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.synced {
+		return
+	}
+
+	now := time.Now().Unix()
+	for _, ev := range events {
+		if ev.Kv == nil {
+			continue
+		}
+		if changeEvent := toChangeEvent(s, ev); changeEvent != nil {
+			changeEvent.CreatedAt = now
+			c.addChangeEvent(changeEvent)
+		}
+
+		key := string(ev.Kv.Key)
+		if !strings.HasPrefix(key, s.kindPrefix(kindEntry)) {
+			continue
+		}
+		if ev.Type == "DELETE" {
+			delete(c.entries, key)
+			continue
+		}
+
+		entry := new(common.RegistrationEntry)
+		if err := unmarshalValue(ev.Kv, entry); err != nil {
+			// drop the entry rather than serving a stale one. Listings
+			// will not notice, but the entry is also malformed in etcd.
+			delete(c.entries, key)
+			continue
+		}
+		c.entries[key] = entry
+	}
+
+	if revision > c.revision {
+		c.revision = revision
+	}
+	c.notify()
+}
+
+func (c *entryCache) unsync() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+	c.synced = false
+	c.events = nil
+	c.notify()
+}
+
+// addChangeEvent keeps the change event, dropping the oldest one if there
+// are too many. Must be called with the lock held.
+func (c *entryCache) addChangeEvent(event *datastore.ChangeEvent) {
+	if len(c.events) == maxChangeEvents {
+		c.eventsFrom = int64(c.events[0].Id)
+		c.events = c.events[1:]
+	}
+	c.events = append(c.events, event)
+}
+
+// listChangeEvents returns the change events following afterID. Events can
+// only be listed while the cache is in sync, since the events from before the
+// cache was last loaded are not known.
+func (c *entryCache) listChangeEvents(afterID uint64, limit int32) (*datastore.ListChangeEventsResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.synced {
+		return nil, false
+	}
+
+	resp := &datastore.ListChangeEventsResponse{
+		LatestId: uint64(c.revision),
+	}
+	if afterID > 0 && (afterID < uint64(c.eventsFrom) || afterID > uint64(c.revision)) {
+		resp.ResyncRequired = true
+		return resp, true
+	}
+
+	for _, event := range c.events {
+		if event.Id <= afterID {
+			continue
+		}
+		if limit > 0 && len(resp.Events) == int(limit) {
+			break
+		}
+		resp.Events = append(resp.Events, proto.Clone(event).(*datastore.ChangeEvent))
+	}
+	return resp, true
+}
+
+// pruneChangeEvents drops the change events created before the given time,
+// except for the most recent one
+func (c *entryCache) pruneChangeEvents(createdBefore int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.events) > 1 && c.events[0].CreatedAt < createdBefore {
+		c.eventsFrom = int64(c.events[0].Id)
+		c.events = c.events[1:]
+	}
+}
+
+// snapshot returns the cached entries sorted by entry ID once the cache has
+// caught up with the given revision. It returns false if the cache is not in
+// sync or does not catch up in time, in which case the entries must be read
+// from etcd.
+func (c *entryCache) snapshot(ctx context.Context, revision int64) ([]*common.RegistrationEntry, bool) {
+	timeout := time.NewTimer(cacheWaitTimeout)
+	defer timeout.Stop()
+
+	for {
+		c.mu.Lock()
+		if !c.synced {
+			c.mu.Unlock()
+			return nil, false
+		}
+		if c.revision >= revision {
+			entries := make([]*common.RegistrationEntry, 0, len(c.entries))
+			for _, entry := range c.entries {
+				entries = append(entries, entry)
+			}
+			c.mu.Unlock()
+
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].EntryId < entries[j].EntryId
+			})
+			return entries, true
+		}
+		updated := c.updated
+		c.mu.Unlock()
+
+		select {
+		case <-updated:
+		case <-timeout.C:
+			return nil, false
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// notify wakes up the listings waiting for the cache. Must be called with the
+// lock held.
+func (c *entryCache) notify() {
+	close(c.updated)
+	c.updated = make(chan struct{})
+}
+
+// toChangeEvent returns the change event for the watch event, or nil if the
+// key is neither a registration entry nor a bundle
+func toChangeEvent(s *store, ev *event) *datastore.ChangeEvent {
+	changeEvent := &datastore.ChangeEvent{
+		Id: uint64(ev.Kv.ModRevision),
+	}
+
+	key := string(ev.Kv.Key)
+	switch {
+	case strings.HasPrefix(key, s.kindPrefix(kindEntry)):
+		changeEvent.Kind = datastore.ChangeEvent_REGISTRATION_ENTRY
+		changeEvent.Key = s.id(kindEntry, ev.Kv)
+	case strings.HasPrefix(key, s.kindPrefix(kindBundle)):
+		changeEvent.Kind = datastore.ChangeEvent_BUNDLE
+		changeEvent.Key = s.id(kindBundle, ev.Kv)
+	default:
+		return nil
+	}
+
+	switch {
+	case ev.Type == "DELETE":
+		changeEvent.Op = datastore.ChangeEvent_DELETED
+	case ev.Kv.CreateRevision == ev.Kv.ModRevision:
+		changeEvent.Op = datastore.ChangeEvent_CREATED
+	default:
+		changeEvent.Op = datastore.ChangeEvent_UPDATED
+	}
+	return changeEvent
+}
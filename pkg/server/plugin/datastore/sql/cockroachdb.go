@@ -0,0 +1,63 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/lib/pq"
+	"github.com/zeebo/errs"
+)
+
+const (
+	// maxTxAttempts is how many times a CockroachDB transaction is attempted
+	// before giving up on serialization conflicts
+	maxTxAttempts = 5
+
+	// txRetryBackoff is how long to wait before the first retry. It doubles
+	// on each subsequent retry.
+	txRetryBackoff = 10 * time.Millisecond
+
+	// serializationFailure is the SQLSTATE of transactions aborted due to
+	// conflicts with concurrent transactions
+	serializationFailure = "40001"
+)
+
+// cockroachDB connects to CockroachDB, which speaks the PostgreSQL wire
+// protocol and is driven through the PostgreSQL dialect.
+type cockroachDB struct{}
+
+func (c cockroachDB) connect(connectionString string) (*gorm.DB, error) {
+	db, err := gorm.Open("postgres", connectionString)
+	if err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+	return db, nil
+}
+
+// withTxRetries runs the transaction until it does not fail with a
+// serialization conflict, up to maxTxAttempts times. CockroachDB runs all
+// transactions with serializable isolation and aborts those that conflict
+// with concurrent ones, expecting clients to retry them.
+func withTxRetries(ctx context.Context, runTx func() error) error {
+	backoff := txRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := runTx()
+		if err == nil || attempt >= maxTxAttempts || !isSerializationFailure(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+func isSerializationFailure(err error) bool {
+	pqErr, ok := errs.Unwrap(err).(*pq.Error)
+	return ok && pqErr.Code == serializationFailure
+}
@@ -0,0 +1,63 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTxRetries(t *testing.T) {
+	conflict := sqlError.Wrap(&pq.Error{Code: serializationFailure, Message: "restart transaction"})
+
+	// conflicts are retried until the transaction succeeds
+	attempts := 0
+	err := withTxRetries(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return conflict
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+
+	// other errors are not retried
+	attempts = 0
+	err = withTxRetries(context.Background(), func() error {
+		attempts++
+		return sqlError.Wrap(&pq.Error{Code: "23505", Message: "duplicate key value"})
+	})
+	require.EqualError(t, err, "datastore-sql: pq: duplicate key value")
+	require.Equal(t, 1, attempts)
+
+	// conflicts are given up on after maxTxAttempts
+	attempts = 0
+	err = withTxRetries(context.Background(), func() error {
+		attempts++
+		return conflict
+	})
+	require.Equal(t, conflict, err)
+	require.Equal(t, maxTxAttempts, attempts)
+
+	// retries stop when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	err = withTxRetries(ctx, func() error {
+		attempts++
+		return conflict
+	})
+	require.Equal(t, conflict, err)
+	require.Equal(t, 1, attempts)
+}
+
+func TestIsSerializationFailure(t *testing.T) {
+	require.True(t, isSerializationFailure(&pq.Error{Code: serializationFailure}))
+	require.True(t, isSerializationFailure(sqlError.Wrap(&pq.Error{Code: serializationFailure})))
+	require.False(t, isSerializationFailure(&pq.Error{Code: "23505"}))
+	require.False(t, isSerializationFailure(errors.New("oh no")))
+	require.False(t, isSerializationFailure(nil))
+}
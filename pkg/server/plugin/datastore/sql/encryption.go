@@ -0,0 +1,144 @@
+package sql
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// keySize is the size of the data-encryption key, in bytes
+	keySize = 32
+
+	// digestPrefix marks column values that hold a digest of the secret
+	// instead of the secret itself
+	digestPrefix = "hmac-sha256:"
+)
+
+// fieldCipher encrypts the values of sensitive columns before they are
+// written to the database. Values that are looked up by equality (e.g. join
+// tokens) are stored as a keyed digest, alongside a sealed copy of the value
+// that can be decrypted when the value needs to be returned.
+type fieldCipher struct {
+	aead      cipher.AEAD
+	digestKey []byte
+}
+
+// loadFieldCipher loads the base64 encoded data-encryption key from the
+// given file.
+func loadFieldCipher(path string) (*fieldCipher, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, sqlError.New("unable to read encryption key: %v", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, sqlError.New("unable to decode encryption key: %v", err)
+	}
+
+	return newFieldCipher(key)
+}
+
+func newFieldCipher(key []byte) (*fieldCipher, error) {
+	if len(key) != keySize {
+		return nil, sqlError.New("encryption key must be %d bytes; got %d", keySize, len(key))
+	}
+
+	// separate keys are derived for encryption and for digests so that the
+	// same key is never used with two different algorithms
+	block, err := aes.NewCipher(deriveKey(key, "encryption"))
+	if err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+
+	return &fieldCipher{
+		aead:      aead,
+		digestKey: deriveKey(key, "digest"),
+	}, nil
+}
+
+// lookupValue returns the column value used to look up the given secret. If
+// no cipher is configured the secret is stored as is.
+func (c *fieldCipher) lookupValue(secret string) string {
+	if c == nil {
+		return secret
+	}
+	mac := hmac.New(sha256.New, c.digestKey)
+	mac.Write([]byte(secret))
+	return digestPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// seal encrypts the given secret. If no cipher is configured, nothing is
+// returned and the secret is stored as is.
+func (c *fieldCipher) seal(secret string) ([]byte, error) {
+	if c == nil {
+		return nil, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+	return c.aead.Seal(nonce, nonce, []byte(secret), nil), nil
+}
+
+// open returns the secret stored in a column, decrypting the sealed copy if
+// there is one.
+func (c *fieldCipher) open(value string, sealed []byte) (string, error) {
+	if len(sealed) == 0 {
+		return value, nil
+	}
+	if c == nil {
+		return "", sqlError.New("value is encrypted but no encryption key is configured")
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", sqlError.New("encrypted value is too short")
+	}
+	secret, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", sqlError.New("unable to decrypt value: %v", err)
+	}
+	return string(secret), nil
+}
+
+// encryptJoinTokens encrypts the join tokens that were stored before an
+// encryption key was configured.
+func encryptJoinTokens(tx *gorm.DB, c *fieldCipher) error {
+	var models []JoinToken
+	if err := tx.Where("sealed_token IS NULL").Find(&models).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+
+	for _, model := range models {
+		sealed, err := c.seal(model.Token)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&model).Updates(map[string]interface{}{
+			"token":        c.lookupValue(model.Token),
+			"sealed_token": sealed,
+		}).Error; err != nil {
+			return sqlError.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
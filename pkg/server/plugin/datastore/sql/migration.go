@@ -13,9 +13,24 @@ import (
 
 const (
 	// version of the database in the code
-	codeVersion = 6
+	codeVersion = 10
 )
 
+// migrationDescriptions describes the migration to each version, as printed
+// by `spire-server datastore migrate`
+var migrationDescriptions = map[int]string{
+	1:  "drop soft-deleted records",
+	2:  "create the federated registration entries table",
+	3:  "normalize SPIFFE IDs",
+	4:  "store bundles as a single blob",
+	5:  "add the registration entry admin flag",
+	6:  "add the registration entry downstream flag",
+	7:  "add join token usage limits",
+	8:  "create the change events table",
+	9:  "add encrypted join tokens",
+	10: "add the registration entry expiry",
+}
+
 func migrateDB(db *gorm.DB) (err error) {
 	isNew := !db.HasTable(&Bundle{})
 	if err := db.Error; err != nil {
@@ -75,7 +90,7 @@ func initDB(db *gorm.DB) (err error) {
 
 	if err := tx.AutoMigrate(&Bundle{}, &AttestedNode{},
 		&NodeSelector{}, &RegisteredEntry{}, &JoinToken{},
-		&Selector{}, &Migration{}).Error; err != nil {
+		&Selector{}, &Migration{}, &ChangeEvent{}).Error; err != nil {
 		tx.Rollback()
 		return sqlError.Wrap(err)
 	}
@@ -112,6 +127,14 @@ func migrateVersion(tx *gorm.DB, version int) (versionOut int, err error) {
 		err = migrateToV5(tx)
 	case 5:
 		err = migrateToV6(tx)
+	case 6:
+		err = migrateToV7(tx)
+	case 7:
+		err = migrateToV8(tx)
+	case 8:
+		err = migrateToV9(tx)
+	case 9:
+		err = migrateToV10(tx)
 	default:
 		err = sqlError.New("no migration support for version %d", version)
 	}
@@ -286,6 +309,34 @@ func migrateToV6(tx *gorm.DB) error {
 	return nil
 }
 
+func migrateToV7(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&JoinToken{}).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+	return nil
+}
+
+func migrateToV8(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&ChangeEvent{}).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+	return nil
+}
+
+func migrateToV9(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&JoinToken{}).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+	return nil
+}
+
+func migrateToV10(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&RegisteredEntry{}).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+	return nil
+}
+
 type V3_Bundle struct {
 	Model
 
@@ -56,6 +56,10 @@ type RegisteredEntry struct {
 	FederatesWith []Bundle `gorm:"many2many:federated_registration_entries;"`
 	Admin         bool
 	Downstream    bool
+
+	// EntryExpiry is the UNIX time the entry expires at, or zero if it
+	// never expires
+	EntryExpiry int64 `gorm:"index"`
 }
 
 // Keep time simple and easily comparable with UNIX time
@@ -64,6 +68,15 @@ type JoinToken struct {
 
 	Token  string `gorm:"unique_index"`
 	Expiry int64
+
+	// SealedToken holds the encrypted token when an encryption key is
+	// configured. Token then holds a digest of the token, used for lookups.
+	SealedToken []byte
+
+	// MaxUses is the number of times the token can be used. Zero means the
+	// token can only be used once.
+	MaxUses int32
+	Uses    int32
 }
 
 type Selector struct {
@@ -74,6 +87,17 @@ type Selector struct {
 	Value             string `gorm:"unique_index:idx_selector_entry"`
 }
 
+// ChangeEvent records a change to a registration entry or a bundle. Events
+// are never updated, so there is no UpdatedAt.
+type ChangeEvent struct {
+	ID        uint      `gorm:"primary_key"`
+	CreatedAt time.Time `gorm:"index"`
+
+	Kind int32
+	Op   int32
+	Key  string
+}
+
 type Migration struct {
 	Model
 
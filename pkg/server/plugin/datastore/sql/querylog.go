@@ -0,0 +1,69 @@
+package sql
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// sqlLogger logs every statement when log_sql is set, as gorm does by
+// default
+var sqlLogger = gorm.Logger{LogWriter: log.New(os.Stdout, "\r\n", 0)}
+
+// queryLogger receives the statements executed by gorm. It logs the
+// statements that took longer than the slow query threshold and, when
+// log_sql is set, every statement as gorm does by default.
+type queryLogger struct {
+	// slowQueryThreshold is the duration after which a statement is logged
+	// as slow. Slow statements are not logged if zero.
+	slowQueryThreshold time.Duration
+
+	// logSQL logs every statement, along with its bound parameters
+	logSQL bool
+
+	// log receives the slow statements
+	log logrus.FieldLogger
+}
+
+func newQueryLogger(config *configuration, slowQueryThreshold time.Duration) *queryLogger {
+	return &queryLogger{
+		slowQueryThreshold: slowQueryThreshold,
+		logSQL:             config.LogSQL,
+		log:                logrus.StandardLogger(),
+	}
+}
+
+// configure installs the logger on the given database
+func (l *queryLogger) configure(db *gorm.DB) {
+	db.SetLogger(l)
+	db.LogMode(l.logSQL || l.slowQueryThreshold > 0)
+}
+
+// Print implements the gorm logger interface. Statements are passed as
+// ("sql", source, duration, statement, bound parameters, rows affected).
+func (l *queryLogger) Print(values ...interface{}) {
+	if l.logSQL {
+		sqlLogger.Print(values...)
+	}
+
+	if l.slowQueryThreshold <= 0 || len(values) < 6 || values[0] != "sql" {
+		return
+	}
+	duration, ok := values[2].(time.Duration)
+	if !ok || duration < l.slowQueryThreshold {
+		return
+	}
+
+	// the statement holds placeholders for the bound parameters, which are
+	// never logged since they may hold secrets like join tokens
+	statement, _ := values[3].(string)
+	rows, _ := values[5].(int64)
+	l.log.WithFields(logrus.Fields{
+		"duration": duration,
+		"rows":     rows,
+		"source":   values[1],
+	}).Warnf("Slow query: %s", statement)
+}
@@ -0,0 +1,73 @@
+package sql
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLoggerLogsSlowQueries(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	l := &queryLogger{
+		slowQueryThreshold: time.Second,
+		log:                log,
+	}
+
+	l.Print("sql", "sql.go:100", 500*time.Millisecond, "SELECT * FROM join_tokens WHERE token = ?", []interface{}{"fast"}, int64(1))
+	require.Empty(t, hook.AllEntries())
+
+	l.Print("sql", "sql.go:100", 2*time.Second, "SELECT * FROM join_tokens WHERE token = ?", []interface{}{"secret"}, int64(1))
+	require.Len(t, hook.AllEntries(), 1)
+	entry := hook.LastEntry()
+	require.Equal(t, logrus.WarnLevel, entry.Level)
+	require.Equal(t, "Slow query: SELECT * FROM join_tokens WHERE token = ?", entry.Message)
+	require.Equal(t, logrus.Fields{
+		"duration": 2 * time.Second,
+		"rows":     int64(1),
+		"source":   "sql.go:100",
+	}, entry.Data)
+
+	// other gorm messages are not logged
+	l.Print("log", "sql.go:100", "record not found")
+	require.Len(t, hook.AllEntries(), 1)
+}
+
+func TestQueryLoggerDisabled(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	l := &queryLogger{
+		log: log,
+	}
+
+	l.Print("sql", "sql.go:100", time.Hour, "SELECT 1", []interface{}{}, int64(1))
+	require.Empty(t, hook.AllEntries())
+}
+
+func TestQueryLoggerConfiguresLogMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire-datastore-sql-querylog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := openDB("sqlite3", filepath.Join(dir, "db.sqlite3"), true)
+	require.NoError(t, err)
+	defer db.Close()
+
+	log, hook := test.NewNullLogger()
+	l := &queryLogger{
+		slowQueryThreshold: time.Nanosecond,
+		log:                log,
+	}
+	l.configure(db)
+
+	var count int
+	require.NoError(t, db.Model(&JoinToken{}).Where("token = ?", "secret").Count(&count).Error)
+	require.NotEmpty(t, hook.AllEntries())
+	for _, entry := range hook.AllEntries() {
+		require.NotContains(t, entry.Message, "secret")
+	}
+}
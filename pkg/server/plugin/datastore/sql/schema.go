@@ -0,0 +1,123 @@
+package sql
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SchemaStatus describes the schema of a database relative to the version
+// the plugin expects.
+type SchemaStatus struct {
+	// Initialized is false if the database has no schema yet
+	Initialized bool
+
+	// Version is the schema version of the database
+	Version int
+
+	// CodeVersion is the schema version the plugin expects
+	CodeVersion int
+
+	// Pending describes, in order, the migrations needed to bring the
+	// database to the code version
+	Pending []string
+}
+
+// Compatible returns true if the plugin can use the database without
+// migrating it.
+func (s *SchemaStatus) Compatible() bool {
+	return s.Initialized && s.Version == s.CodeVersion
+}
+
+// GetSchemaStatus connects to the database configured in the plugin
+// configuration and returns the status of its schema. The database is not
+// modified.
+func GetSchemaStatus(pluginConfig string) (*SchemaStatus, error) {
+	db, err := connectSchemaDB(pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return getSchemaStatus(db)
+}
+
+// MigrateSchema connects to the database configured in the plugin
+// configuration and migrates its schema to the code version. The status of
+// the schema before the migration is returned.
+func MigrateSchema(pluginConfig string) (*SchemaStatus, error) {
+	db, err := connectSchemaDB(pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	status, err := getSchemaStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrateDB(db); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func connectSchemaDB(pluginConfig string) (*gorm.DB, error) {
+	config, err := parseConfiguration(pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return connectDB(config.DatabaseType, config.ConnectionString)
+}
+
+// verifyDB fails if the schema of the database is not at the code version
+func verifyDB(db *gorm.DB) error {
+	status, err := getSchemaStatus(db)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !status.Initialized:
+		return sqlError.New("database schema is not initialized and auto-migration is disabled; run `spire-server datastore migrate`")
+	case status.Version < status.CodeVersion:
+		return sqlError.New("database schema version %d is behind the code version %d and auto-migration is disabled; run `spire-server datastore migrate`", status.Version, status.CodeVersion)
+	case status.Version > status.CodeVersion:
+		return sqlError.New("database schema version %d is ahead of the code version %d", status.Version, status.CodeVersion)
+	}
+	return nil
+}
+
+func getSchemaStatus(db *gorm.DB) (*SchemaStatus, error) {
+	status := &SchemaStatus{
+		CodeVersion: codeVersion,
+	}
+
+	status.Initialized = db.HasTable(&Bundle{})
+	if err := db.Error; err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+
+	if !status.Initialized {
+		status.Pending = []string{fmt.Sprintf("v%d: initialize the schema", codeVersion)}
+		return status, nil
+	}
+
+	// databases created before migrations were tracked are at version zero
+	if db.HasTable(&Migration{}) {
+		migration := new(Migration)
+		switch err := db.First(migration).Error; {
+		case err == nil:
+			status.Version = migration.Version
+		case !gorm.IsRecordNotFoundError(err):
+			return nil, sqlError.Wrap(err)
+		}
+	}
+
+	for version := status.Version + 1; version <= codeVersion; version++ {
+		status.Pending = append(status.Pending, fmt.Sprintf("v%d: %s", version, migrationDescriptions[version]))
+	}
+	return status, nil
+}
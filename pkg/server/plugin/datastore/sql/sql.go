@@ -41,6 +41,23 @@ type configuration struct {
 	DatabaseType     string `hcl:"database_type" json:"database_type"`
 	ConnectionString string `hcl:"connection_string" json:"connection_string"`
 
+	// ROConnectionString is the connection string of a read-only replica of
+	// the database, used for the queries that tolerate stale reads
+	ROConnectionString string `hcl:"ro_connection_string" json:"ro_connection_string"`
+
+	// EncryptionKeyFile is the path to a file holding the base64 encoded key
+	// used to encrypt sensitive columns
+	EncryptionKeyFile string `hcl:"encryption_key_file" json:"encryption_key_file"`
+
+	// SlowQueryThreshold is the duration after which a statement is logged
+	// as slow. Slow statements are not logged if unset.
+	SlowQueryThreshold string `hcl:"slow_query_threshold" json:"slow_query_threshold"`
+
+	// DisableAutoMigration prevents the plugin from migrating the schema.
+	// The plugin then fails to configure unless the schema has already been
+	// migrated to the current version with `spire-server datastore migrate`.
+	DisableAutoMigration bool `hcl:"disable_auto_migration" json:"disable_auto_migration"`
+
 	// Undocumented flags
 	LogSQL bool `hcl:"log_sql" json:"log_sql"`
 }
@@ -58,6 +75,12 @@ type sqlDB struct {
 type sqlPlugin struct {
 	mu sync.Mutex
 	db *sqlDB
+
+	// roDb is the read-only replica, if one is configured
+	roDb *sqlDB
+
+	// cipher encrypts sensitive columns, if an encryption key is configured
+	cipher *fieldCipher
 }
 
 func newPlugin() *sqlPlugin {
@@ -116,7 +139,7 @@ func (ds *sqlPlugin) DeleteBundle(ctx context.Context, req *datastore.DeleteBund
 
 // FetchBundle returns the bundle matching the specified Trust Domain.
 func (ds *sqlPlugin) FetchBundle(ctx context.Context, req *datastore.FetchBundleRequest) (resp *datastore.FetchBundleResponse, err error) {
-	if err := ds.withReadTx(ctx, func(tx *gorm.DB) (err error) {
+	if err := ds.withReplicaTx(ctx, req.TolerateStale, func(tx *gorm.DB) (err error) {
 		resp, err = fetchBundle(tx, req)
 		return err
 	}); err != nil {
@@ -209,7 +232,7 @@ func (ds *sqlPlugin) SetNodeSelectors(ctx context.Context, req *datastore.SetNod
 func (ds *sqlPlugin) GetNodeSelectors(ctx context.Context,
 	req *datastore.GetNodeSelectorsRequest) (resp *datastore.GetNodeSelectorsResponse, err error) {
 
-	if err := ds.withReadTx(ctx, func(tx *gorm.DB) (err error) {
+	if err := ds.withReplicaTx(ctx, req.TolerateStale, func(tx *gorm.DB) (err error) {
 		resp, err = getNodeSelectors(tx, req)
 		return err
 	}); err != nil {
@@ -245,7 +268,7 @@ func (ds *sqlPlugin) FetchRegistrationEntry(ctx context.Context,
 func (ds *sqlPlugin) ListRegistrationEntries(ctx context.Context,
 	req *datastore.ListRegistrationEntriesRequest) (resp *datastore.ListRegistrationEntriesResponse, err error) {
 
-	if err := ds.withReadTx(ctx, func(tx *gorm.DB) (err error) {
+	if err := ds.withReplicaTx(ctx, req.TolerateStale, func(tx *gorm.DB) (err error) {
 		resp, err = listRegistrationEntries(tx, req)
 		return err
 	}); err != nil {
@@ -278,10 +301,53 @@ func (ds *sqlPlugin) DeleteRegistrationEntry(ctx context.Context,
 	return resp, nil
 }
 
+// BatchCreateRegistrationEntries creates the registration entries in a single
+// transaction
+func (ds *sqlPlugin) BatchCreateRegistrationEntries(ctx context.Context,
+	req *datastore.BatchCreateRegistrationEntriesRequest) (resp *datastore.BatchCreateRegistrationEntriesResponse, err error) {
+
+	if err := ds.withWriteTx(ctx, func(tx *gorm.DB) (err error) {
+		resp, err = batchCreateRegistrationEntries(tx, req)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// BatchUpdateRegistrationEntries updates the registration entries in a single
+// transaction
+func (ds *sqlPlugin) BatchUpdateRegistrationEntries(ctx context.Context,
+	req *datastore.BatchUpdateRegistrationEntriesRequest) (resp *datastore.BatchUpdateRegistrationEntriesResponse, err error) {
+
+	if err := ds.withWriteTx(ctx, func(tx *gorm.DB) (err error) {
+		resp, err = batchUpdateRegistrationEntries(tx, req)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// BatchDeleteRegistrationEntries deletes the registration entries in a single
+// transaction
+func (ds *sqlPlugin) BatchDeleteRegistrationEntries(ctx context.Context,
+	req *datastore.BatchDeleteRegistrationEntriesRequest) (resp *datastore.BatchDeleteRegistrationEntriesResponse, err error) {
+
+	if err := ds.withWriteTx(ctx, func(tx *gorm.DB) (err error) {
+		resp, err = batchDeleteRegistrationEntries(tx, req)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // CreateJoinToken takes a Token message and stores it
 func (ds *sqlPlugin) CreateJoinToken(ctx context.Context, req *datastore.CreateJoinTokenRequest) (resp *datastore.CreateJoinTokenResponse, err error) {
+	c := ds.getCipher()
 	if err := ds.withWriteTx(ctx, func(tx *gorm.DB) (err error) {
-		resp, err = createJoinToken(tx, req)
+		resp, err = createJoinToken(tx, c, req)
 		return err
 	}); err != nil {
 		return nil, err
@@ -292,8 +358,9 @@ func (ds *sqlPlugin) CreateJoinToken(ctx context.Context, req *datastore.CreateJ
 // FetchJoinToken takes a Token message and returns one, populating the fields
 // we have knowledge of
 func (ds *sqlPlugin) FetchJoinToken(ctx context.Context, req *datastore.FetchJoinTokenRequest) (resp *datastore.FetchJoinTokenResponse, err error) {
+	c := ds.getCipher()
 	if err := ds.withReadTx(ctx, func(tx *gorm.DB) (err error) {
-		resp, err = fetchJoinToken(tx, req)
+		resp, err = fetchJoinToken(tx, c, req)
 		return err
 	}); err != nil {
 		return nil, err
@@ -302,8 +369,34 @@ func (ds *sqlPlugin) FetchJoinToken(ctx context.Context, req *datastore.FetchJoi
 }
 
 func (ds *sqlPlugin) DeleteJoinToken(ctx context.Context, req *datastore.DeleteJoinTokenRequest) (resp *datastore.DeleteJoinTokenResponse, err error) {
+	c := ds.getCipher()
 	if err := ds.withWriteTx(ctx, func(tx *gorm.DB) (err error) {
-		resp, err = deleteJoinToken(tx, req)
+		resp, err = deleteJoinToken(tx, c, req)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListJoinTokens lists all join tokens
+func (ds *sqlPlugin) ListJoinTokens(ctx context.Context, req *datastore.ListJoinTokensRequest) (resp *datastore.ListJoinTokensResponse, err error) {
+	c := ds.getCipher()
+	if err := ds.withReadTx(ctx, func(tx *gorm.DB) (err error) {
+		resp, err = listJoinTokens(tx, c, req)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UseJoinToken records a use of the given token, deleting it once it has
+// been used as many times as it allows
+func (ds *sqlPlugin) UseJoinToken(ctx context.Context, req *datastore.UseJoinTokenRequest) (resp *datastore.UseJoinTokenResponse, err error) {
+	c := ds.getCipher()
+	if err := ds.withWriteTx(ctx, func(tx *gorm.DB) (err error) {
+		resp, err = useJoinToken(tx, c, req)
 		return err
 	}); err != nil {
 		return nil, err
@@ -323,20 +416,43 @@ func (ds *sqlPlugin) PruneJoinTokens(ctx context.Context, req *datastore.PruneJo
 	return resp, nil
 }
 
-func (ds *sqlPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
-	// Parse HCL config payload into config struct
-	config := &configuration{}
-	if err := hcl.Decode(config, req.Configuration); err != nil {
+// ListChangeEvents lists the registration entry and bundle change events
+// following the given event
+func (ds *sqlPlugin) ListChangeEvents(ctx context.Context, req *datastore.ListChangeEventsRequest) (resp *datastore.ListChangeEventsResponse, err error) {
+	if err = ds.withReadTx(ctx, func(tx *gorm.DB) (err error) {
+		resp, err = listChangeEvents(tx, req)
+		return err
+	}); err != nil {
 		return nil, err
 	}
+	return resp, nil
+}
 
-	if config.DatabaseType == "" {
-		return nil, errors.New("database_type must be set")
+// PruneChangeEvents deletes change events created before the given time
+func (ds *sqlPlugin) PruneChangeEvents(ctx context.Context, req *datastore.PruneChangeEventsRequest) (resp *datastore.PruneChangeEventsResponse, err error) {
+	if err = ds.withWriteTx(ctx, func(tx *gorm.DB) (err error) {
+		resp, err = pruneChangeEvents(tx, req)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (ds *sqlPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config, err := parseConfiguration(req.Configuration)
+	if err != nil {
+		return nil, err
 	}
 
-	if config.ConnectionString == "" {
-		return nil, errors.New("connection_string must be set")
+	var slowQueryThreshold time.Duration
+	if config.SlowQueryThreshold != "" {
+		slowQueryThreshold, err = time.ParseDuration(config.SlowQueryThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse slow_query_threshold %q: %v", config.SlowQueryThreshold, err)
+		}
 	}
+	queryLog := newQueryLogger(config, slowQueryThreshold)
 
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
@@ -345,7 +461,7 @@ func (ds *sqlPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (
 		config.ConnectionString != ds.db.connectionString ||
 		config.DatabaseType != ds.db.databaseType {
 
-		db, err := openDB(config.DatabaseType, config.ConnectionString)
+		db, err := openDB(config.DatabaseType, config.ConnectionString, !config.DisableAutoMigration)
 		if err != nil {
 			return nil, err
 		}
@@ -361,11 +477,103 @@ func (ds *sqlPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (
 		}
 	}
 
-	ds.db.LogMode(config.LogSQL)
+	queryLog.configure(ds.db.DB)
+
+	if err := ds.configureReplica(config, queryLog); err != nil {
+		return nil, err
+	}
+
+	if err := ds.configureEncryption(ctx, config); err != nil {
+		return nil, err
+	}
 
 	return &spi.ConfigureResponse{}, nil
 }
 
+// configureReplica opens the read-only replica, if configured, and closes the
+// one previously configured, if any. Must be called with the lock held.
+func (ds *sqlPlugin) configureReplica(config *configuration, queryLog *queryLogger) error {
+	if config.ROConnectionString == "" {
+		if ds.roDb != nil {
+			ds.roDb.Close()
+			ds.roDb = nil
+		}
+		return nil
+	}
+
+	if ds.roDb == nil ||
+		config.ROConnectionString != ds.roDb.connectionString ||
+		config.DatabaseType != ds.roDb.databaseType {
+
+		// the schema of the replica is migrated through replication, since
+		// the replica cannot be written to
+		db, err := connectDB(config.DatabaseType, config.ROConnectionString)
+		if err != nil {
+			return err
+		}
+
+		if ds.roDb != nil {
+			ds.roDb.Close()
+		}
+
+		ds.roDb = &sqlDB{
+			DB:               db,
+			databaseType:     config.DatabaseType,
+			connectionString: config.ROConnectionString,
+		}
+	}
+
+	queryLog.configure(ds.roDb.DB)
+	return nil
+}
+
+// configureEncryption loads the encryption key, if one is configured, and
+// encrypts the secrets that were stored before it was. It fails if the
+// secrets already encrypted cannot be decrypted with the configured key, or
+// without one, rather than failing each request that reads them.
+func (ds *sqlPlugin) configureEncryption(ctx context.Context, config *configuration) error {
+	sealed := new(JoinToken)
+	result := ds.db.Where("sealed_token IS NOT NULL").First(sealed)
+	if result.RecordNotFound() {
+		sealed = nil
+	} else if result.Error != nil {
+		return sqlError.Wrap(result.Error)
+	}
+
+	if config.EncryptionKeyFile == "" {
+		if sealed != nil {
+			return sqlError.New("join tokens are encrypted but encryption_key_file is not set")
+		}
+		ds.cipher = nil
+		return nil
+	}
+
+	c, err := loadFieldCipher(config.EncryptionKeyFile)
+	if err != nil {
+		return err
+	}
+	if sealed != nil {
+		if _, err := c.open(sealed.Token, sealed.SealedToken); err != nil {
+			return sqlError.New("join tokens are encrypted with another key than the one in encryption_key_file")
+		}
+	}
+
+	if err := withDBTx(ctx, ds.db, func(tx *gorm.DB) error {
+		return encryptJoinTokens(tx, c)
+	}, false); err != nil {
+		return err
+	}
+
+	ds.cipher = c
+	return nil
+}
+
+func (ds *sqlPlugin) getCipher() *fieldCipher {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.cipher
+}
+
 func (sqlPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
 	return &pluginInfo, nil
 }
@@ -378,11 +586,33 @@ func (ds *sqlPlugin) withReadTx(ctx context.Context, op func(tx *gorm.DB) error)
 	return ds.withTx(ctx, op, true)
 }
 
+// withReplicaTx runs the read-only operation against the read-only replica,
+// if one is configured and the request tolerates reading data that is
+// slightly out of date, and against the primary database otherwise. If the
+// operation fails on the replica, e.g. because it cannot be reached, it is
+// run against the primary database instead.
+func (ds *sqlPlugin) withReplicaTx(ctx context.Context, tolerateStale bool, op func(tx *gorm.DB) error) error {
+	ds.mu.Lock()
+	roDb := ds.roDb
+	ds.mu.Unlock()
+
+	if roDb != nil && tolerateStale {
+		if err := withDBTx(ctx, roDb, op, true); err == nil {
+			return nil
+		}
+	}
+	return ds.withReadTx(ctx, op)
+}
+
 func (ds *sqlPlugin) withTx(ctx context.Context, op func(tx *gorm.DB) error, readOnly bool) error {
 	ds.mu.Lock()
 	db := ds.db
 	ds.mu.Unlock()
 
+	return withDBTx(ctx, db, op, readOnly)
+}
+
+func withDBTx(ctx context.Context, db *sqlDB, op func(tx *gorm.DB) error, readOnly bool) error {
 	if db.databaseType == "sqlite3" && !readOnly {
 		// sqlite3 can only have one writer at a time. since we're in WAL mode,
 		// there can be concurrent reads and writes, so no lock is necessary
@@ -391,6 +621,15 @@ func (ds *sqlPlugin) withTx(ctx context.Context, op func(tx *gorm.DB) error, rea
 		defer db.opMu.Unlock()
 	}
 
+	if db.databaseType == "cockroachdb" {
+		return withTxRetries(ctx, func() error {
+			return runTx(db, op, readOnly)
+		})
+	}
+	return runTx(db, op, readOnly)
+}
+
+func runTx(db *sqlDB, op func(tx *gorm.DB) error, readOnly bool) error {
 	// TODO: as soon as GORM supports it, attach the context
 	// https://github.com/jinzhu/gorm/issues/1231
 	tx := db.Begin()
@@ -412,7 +651,47 @@ func (ds *sqlPlugin) withTx(ctx context.Context, op func(tx *gorm.DB) error, rea
 	return sqlError.Wrap(tx.Commit().Error)
 }
 
-func openDB(databaseType, connectionString string) (*gorm.DB, error) {
+// parseConfiguration parses the HCL configuration of the plugin
+func parseConfiguration(data string) (*configuration, error) {
+	config := &configuration{}
+	if err := hcl.Decode(config, data); err != nil {
+		return nil, err
+	}
+
+	if config.DatabaseType == "" {
+		return nil, errors.New("database_type must be set")
+	}
+
+	if config.ConnectionString == "" {
+		return nil, errors.New("connection_string must be set")
+	}
+
+	return config, nil
+}
+
+// openDB connects to the database and, if autoMigrate is set, migrates its
+// schema to the code version. Otherwise the schema must already be at the
+// code version.
+func openDB(databaseType, connectionString string, autoMigrate bool) (*gorm.DB, error) {
+	db, err := connectDB(databaseType, connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	if autoMigrate {
+		err = migrateDB(db)
+	} else {
+		err = verifyDB(db)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func connectDB(databaseType, connectionString string) (*gorm.DB, error) {
 	var db *gorm.DB
 	var err error
 
@@ -421,18 +700,14 @@ func openDB(databaseType, connectionString string) (*gorm.DB, error) {
 		db, err = sqlite{}.connect(connectionString)
 	case "postgres":
 		db, err = postgres{}.connect(connectionString)
+	case "cockroachdb":
+		db, err = cockroachDB{}.connect(connectionString)
 	default:
 		return nil, sqlError.New("unsupported database_type: %v", databaseType)
 	}
 	if err != nil {
 		return nil, err
 	}
-
-	if err := migrateDB(db); err != nil {
-		db.Close()
-		return nil, err
-	}
-
 	return db, nil
 }
 
@@ -446,6 +721,10 @@ func createBundle(tx *gorm.DB, req *datastore.CreateBundleRequest) (*datastore.C
 		return nil, sqlError.Wrap(err)
 	}
 
+	if err := recordChangeEvent(tx, datastore.ChangeEvent_BUNDLE, datastore.ChangeEvent_CREATED, model.TrustDomain); err != nil {
+		return nil, err
+	}
+
 	return &datastore.CreateBundleResponse{
 		Bundle: req.Bundle,
 	}, nil
@@ -467,6 +746,10 @@ func updateBundle(tx *gorm.DB, req *datastore.UpdateBundleRequest) (*datastore.U
 		return nil, sqlError.Wrap(err)
 	}
 
+	if err := recordChangeEvent(tx, datastore.ChangeEvent_BUNDLE, datastore.ChangeEvent_UPDATED, model.TrustDomain); err != nil {
+		return nil, err
+	}
+
 	return &datastore.UpdateBundleResponse{
 		Bundle: req.Bundle,
 	}, nil
@@ -509,6 +792,9 @@ func appendBundle(tx *gorm.DB, req *datastore.AppendBundleRequest) (*datastore.A
 		if err := tx.Save(model).Error; err != nil {
 			return nil, sqlError.Wrap(err)
 		}
+		if err := recordChangeEvent(tx, datastore.ChangeEvent_BUNDLE, datastore.ChangeEvent_UPDATED, model.TrustDomain); err != nil {
+			return nil, err
+		}
 	}
 
 	return &datastore.AppendBundleResponse{
@@ -530,6 +816,16 @@ func deleteBundle(tx *gorm.DB, req *datastore.DeleteBundleRequest) (*datastore.D
 	}
 
 	if entriesCount > 0 {
+		// Record the changes to the associated entries before they are
+		// deleted or dissociated
+		var entryIDs []string
+		if err := tx.Table("registered_entries").
+			Joins("INNER JOIN federated_registration_entries ON federated_registration_entries.registered_entry_id = registered_entries.id").
+			Where("federated_registration_entries.bundle_id = ?", model.ID).
+			Pluck("registered_entries.entry_id", &entryIDs).Error; err != nil {
+			return nil, sqlError.Wrap(err)
+		}
+
 		switch req.Mode {
 		case datastore.DeleteBundleRequest_DELETE:
 			// TODO: figure out how to do this gracefully with GORM.
@@ -546,10 +842,16 @@ func deleteBundle(tx *gorm.DB, req *datastore.DeleteBundleRequest) (*datastore.D
 					federated_registration_entries.bundle_id = ?)`), model.ID).Error; err != nil {
 				return nil, sqlError.Wrap(err)
 			}
+			if err := recordChangeEvents(tx, datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_DELETED, entryIDs); err != nil {
+				return nil, err
+			}
 		case datastore.DeleteBundleRequest_DISSOCIATE:
 			if err := entriesAssociation.Clear().Error; err != nil {
 				return nil, sqlError.Wrap(err)
 			}
+			if err := recordChangeEvents(tx, datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_UPDATED, entryIDs); err != nil {
+				return nil, err
+			}
 		default:
 			return nil, sqlError.New("cannot delete bundle; federated with %d registration entries", entriesCount)
 		}
@@ -559,6 +861,10 @@ func deleteBundle(tx *gorm.DB, req *datastore.DeleteBundleRequest) (*datastore.D
 		return nil, sqlError.Wrap(err)
 	}
 
+	if err := recordChangeEvent(tx, datastore.ChangeEvent_BUNDLE, datastore.ChangeEvent_DELETED, model.TrustDomain); err != nil {
+		return nil, err
+	}
+
 	bundle, err := modelToBundle(model)
 	if err != nil {
 		return nil, err
@@ -708,7 +1014,17 @@ func deleteAttestedNode(tx *gorm.DB, req *datastore.DeleteAttestedNodeRequest) (
 		return nil, sqlError.Wrap(err)
 	}
 
-	if err := tx.Delete(&model).Error; err != nil {
+	if req.ByCertSerialNumber != nil {
+		// the serial number is part of the statement so that a node renewed
+		// by a concurrent transaction is not deleted
+		db := tx.Where("serial_number = ?", req.ByCertSerialNumber.Value).Delete(&model)
+		if db.Error != nil {
+			return nil, sqlError.Wrap(db.Error)
+		}
+		if db.RowsAffected == 0 {
+			return &datastore.DeleteAttestedNodeResponse{}, nil
+		}
+	} else if err := tx.Delete(&model).Error; err != nil {
 		return nil, sqlError.Wrap(err)
 	}
 
@@ -778,12 +1094,13 @@ func createRegistrationEntry(tx *gorm.DB,
 	}
 
 	newRegisteredEntry := RegisteredEntry{
-		EntryID:    entryID,
-		SpiffeID:   req.Entry.SpiffeId,
-		ParentID:   req.Entry.ParentId,
-		TTL:        req.Entry.Ttl,
-		Admin:      req.Entry.Admin,
-		Downstream: req.Entry.Downstream,
+		EntryID:     entryID,
+		SpiffeID:    req.Entry.SpiffeId,
+		ParentID:    req.Entry.ParentId,
+		TTL:         req.Entry.Ttl,
+		Admin:       req.Entry.Admin,
+		Downstream:  req.Entry.Downstream,
+		EntryExpiry: req.Entry.EntryExpiry,
 	}
 
 	if err := tx.Create(&newRegisteredEntry).Error; err != nil {
@@ -810,6 +1127,10 @@ func createRegistrationEntry(tx *gorm.DB,
 		}
 	}
 
+	if err := recordChangeEvent(tx, datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_CREATED, entryID); err != nil {
+		return nil, err
+	}
+
 	entry, err := modelToEntry(tx, newRegisteredEntry)
 	if err != nil {
 		return nil, err
@@ -871,6 +1192,9 @@ func listRegistrationEntries(tx *gorm.DB,
 	if req.BySpiffeId != nil {
 		entryTx = entryTx.Where("spiffe_id = ?", req.BySpiffeId.Value)
 	}
+	if req.ByExpiresBefore != nil {
+		entryTx = entryTx.Where("entry_expiry > 0 AND entry_expiry < ?", req.ByExpiresBefore.Value)
+	}
 
 	if len(selectorsList) == 0 {
 		// no selectors to filter against.
@@ -891,7 +1215,9 @@ func listRegistrationEntries(tx *gorm.DB,
 		}, nil
 	}
 
-	modelsSet := make(map[uint]RegisteredEntry)
+	// collect the ids of the entries matching any of the selector sets, so
+	// they can be paginated over with a single query
+	entryIDSet := make(map[uint]bool)
 	for _, selectors := range selectorsList {
 		refCount := make(map[uint]int)
 		for _, s := range selectors {
@@ -916,29 +1242,41 @@ func listRegistrationEntries(tx *gorm.DB,
 			continue
 		}
 
-		// fetch the entries in the id set, filtered by any parent/spiffe id filters
-		// applied globally
-		db := entryTx.Where(entryIDs)
-		var models []RegisteredEntry
-		models, p, err = findRegisteredEntries(db, req.Pagination)
-		if err != nil {
-			return nil, err
+		// exclude entries that have selectors besides the ones in the set
+		var counts []struct {
+			RegisteredEntryID uint
+			Count             int
 		}
-
-		for _, model := range models {
-			var count int
-			if err := tx.Model(&Selector{}).Where("registered_entry_id = ?", model.ID).Count(&count).Error; err != nil {
-				return nil, sqlError.Wrap(err)
-			}
-			if count == len(selectors) {
-				modelsSet[model.ID] = model
+		if err := tx.Model(&Selector{}).
+			Select("registered_entry_id, count(*) as count").
+			Where("registered_entry_id IN (?)", entryIDs).
+			Group("registered_entry_id").
+			Scan(&counts).Error; err != nil {
+			return nil, sqlError.Wrap(err)
+		}
+		for _, c := range counts {
+			if c.Count == len(selectors) {
+				entryIDSet[c.RegisteredEntryID] = true
 			}
 		}
 	}
 
-	models := make([]RegisteredEntry, 0, len(modelsSet))
-	for _, model := range modelsSet {
-		models = append(models, model)
+	if len(entryIDSet) == 0 {
+		return &datastore.ListRegistrationEntriesResponse{
+			Pagination: req.Pagination,
+		}, nil
+	}
+
+	entryIDs := make([]uint, 0, len(entryIDSet))
+	for id := range entryIDSet {
+		entryIDs = append(entryIDs, id)
+	}
+
+	// fetch the entries in the id set, filtered by any parent/spiffe id filters
+	// applied globally
+	models, p, err := findRegisteredEntries(entryTx.Where(entryIDs), req.Pagination)
+	if err != nil {
+		return nil, err
 	}
 
 	entries, err := modelsToEntries(tx, models)
@@ -1038,6 +1376,7 @@ func updateRegistrationEntry(tx *gorm.DB,
 	entry.Selectors = selectors
 	entry.Admin = req.Entry.Admin
 	entry.Downstream = req.Entry.Downstream
+	entry.EntryExpiry = req.Entry.EntryExpiry
 	if err := tx.Save(&entry).Error; err != nil {
 		return nil, sqlError.Wrap(err)
 	}
@@ -1051,6 +1390,10 @@ func updateRegistrationEntry(tx *gorm.DB,
 		return nil, err
 	}
 
+	if err := recordChangeEvent(tx, datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_UPDATED, entry.EntryID); err != nil {
+		return nil, err
+	}
+
 	req.Entry.EntryId = entry.EntryID
 	return &datastore.UpdateRegistrationEntryResponse{
 		Entry: req.Entry,
@@ -1078,19 +1421,88 @@ func deleteRegistrationEntry(tx *gorm.DB,
 		return nil, sqlError.Wrap(err)
 	}
 
+	if err := recordChangeEvent(tx, datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_DELETED, entry.EntryID); err != nil {
+		return nil, err
+	}
+
 	return &datastore.DeleteRegistrationEntryResponse{
 		Entry: respEntry,
 	}, nil
 }
 
-func createJoinToken(tx *gorm.DB, req *datastore.CreateJoinTokenRequest) (*datastore.CreateJoinTokenResponse, error) {
+func batchCreateRegistrationEntries(tx *gorm.DB,
+	req *datastore.BatchCreateRegistrationEntriesRequest) (*datastore.BatchCreateRegistrationEntriesResponse, error) {
+
+	resp := &datastore.BatchCreateRegistrationEntriesResponse{
+		Entries: make([]*common.RegistrationEntry, 0, len(req.Entries)),
+	}
+	for _, entry := range req.Entries {
+		createResp, err := createRegistrationEntry(tx, &datastore.CreateRegistrationEntryRequest{
+			Entry: entry,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, createResp.Entry)
+	}
+	return resp, nil
+}
+
+func batchUpdateRegistrationEntries(tx *gorm.DB,
+	req *datastore.BatchUpdateRegistrationEntriesRequest) (*datastore.BatchUpdateRegistrationEntriesResponse, error) {
+
+	resp := &datastore.BatchUpdateRegistrationEntriesResponse{
+		Entries: make([]*common.RegistrationEntry, 0, len(req.Entries)),
+	}
+	for _, entry := range req.Entries {
+		updateResp, err := updateRegistrationEntry(tx, &datastore.UpdateRegistrationEntryRequest{
+			Entry: entry,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, updateResp.Entry)
+	}
+	return resp, nil
+}
+
+func batchDeleteRegistrationEntries(tx *gorm.DB,
+	req *datastore.BatchDeleteRegistrationEntriesRequest) (*datastore.BatchDeleteRegistrationEntriesResponse, error) {
+
+	resp := &datastore.BatchDeleteRegistrationEntriesResponse{
+		Entries: make([]*common.RegistrationEntry, 0, len(req.EntryIds)),
+	}
+	for _, entryID := range req.EntryIds {
+		deleteResp, err := deleteRegistrationEntry(tx, &datastore.DeleteRegistrationEntryRequest{
+			EntryId: entryID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, deleteResp.Entry)
+	}
+	return resp, nil
+}
+
+func createJoinToken(tx *gorm.DB, c *fieldCipher, req *datastore.CreateJoinTokenRequest) (*datastore.CreateJoinTokenResponse, error) {
 	if req.JoinToken == nil || req.JoinToken.Token == "" || req.JoinToken.Expiry == 0 {
 		return nil, errors.New("token and expiry are required")
 	}
 
+	if req.JoinToken.MaxUses < 0 {
+		return nil, errors.New("max uses cannot be negative")
+	}
+
+	sealed, err := c.seal(req.JoinToken.Token)
+	if err != nil {
+		return nil, err
+	}
+
 	t := JoinToken{
-		Token:  req.JoinToken.Token,
-		Expiry: req.JoinToken.Expiry,
+		Token:       c.lookupValue(req.JoinToken.Token),
+		SealedToken: sealed,
+		Expiry:      req.JoinToken.Expiry,
+		MaxUses:     req.JoinToken.MaxUses,
 	}
 
 	if err := tx.Create(&t).Error; err != nil {
@@ -1102,35 +1514,108 @@ func createJoinToken(tx *gorm.DB, req *datastore.CreateJoinTokenRequest) (*datas
 	}, nil
 }
 
-func fetchJoinToken(tx *gorm.DB, req *datastore.FetchJoinTokenRequest) (*datastore.FetchJoinTokenResponse, error) {
+func fetchJoinToken(tx *gorm.DB, c *fieldCipher, req *datastore.FetchJoinTokenRequest) (*datastore.FetchJoinTokenResponse, error) {
 	var model JoinToken
-	err := tx.Find(&model, "token = ?", req.Token).Error
+	err := tx.Find(&model, "token = ?", c.lookupValue(req.Token)).Error
 	if err == gorm.ErrRecordNotFound {
 		return &datastore.FetchJoinTokenResponse{}, nil
 	} else if err != nil {
 		return nil, sqlError.Wrap(err)
 	}
 
+	joinToken, err := modelToJoinToken(c, model)
+	if err != nil {
+		return nil, err
+	}
+
 	return &datastore.FetchJoinTokenResponse{
-		JoinToken: modelToJoinToken(model),
+		JoinToken: joinToken,
 	}, nil
 }
 
-func deleteJoinToken(tx *gorm.DB, req *datastore.DeleteJoinTokenRequest) (*datastore.DeleteJoinTokenResponse, error) {
+func deleteJoinToken(tx *gorm.DB, c *fieldCipher, req *datastore.DeleteJoinTokenRequest) (*datastore.DeleteJoinTokenResponse, error) {
 	var model JoinToken
-	if err := tx.Find(&model, "token = ?", req.Token).Error; err != nil {
+	if err := tx.Find(&model, "token = ?", c.lookupValue(req.Token)).Error; err != nil {
 		return nil, sqlError.Wrap(err)
 	}
 
+	joinToken, err := modelToJoinToken(c, model)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := tx.Delete(&model).Error; err != nil {
 		return nil, sqlError.Wrap(err)
 	}
 
 	return &datastore.DeleteJoinTokenResponse{
-		JoinToken: modelToJoinToken(model),
+		JoinToken: joinToken,
 	}, nil
 }
 
+func listJoinTokens(tx *gorm.DB, c *fieldCipher, req *datastore.ListJoinTokensRequest) (*datastore.ListJoinTokensResponse, error) {
+	var models []JoinToken
+	if err := tx.Order("id ASC").Find(&models).Error; err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+
+	resp := &datastore.ListJoinTokensResponse{
+		JoinTokens: make([]*datastore.JoinToken, 0, len(models)),
+	}
+	for _, model := range models {
+		joinToken, err := modelToJoinToken(c, model)
+		if err != nil {
+			return nil, err
+		}
+		resp.JoinTokens = append(resp.JoinTokens, joinToken)
+	}
+	return resp, nil
+}
+
+func useJoinToken(tx *gorm.DB, c *fieldCipher, req *datastore.UseJoinTokenRequest) (*datastore.UseJoinTokenResponse, error) {
+	var model JoinToken
+	err := tx.Find(&model, "token = ?", c.lookupValue(req.Token)).Error
+	if err == gorm.ErrRecordNotFound {
+		return &datastore.UseJoinTokenResponse{}, nil
+	} else if err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+
+	joinToken, err := modelToJoinToken(c, model)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &datastore.UseJoinTokenResponse{
+		JoinToken: joinToken,
+	}
+
+	// a token without a max use count can only be used once
+	maxUses := model.MaxUses
+	if maxUses < 1 {
+		maxUses = 1
+	}
+
+	// The use is counted by a conditional update rather than from the count
+	// read above, so that concurrent uses cannot go over the maximum: once
+	// the token is used up, the update matches no row.
+	result := tx.Model(&JoinToken{}).
+		Where("token = ? AND uses < ?", model.Token, maxUses).
+		Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return nil, sqlError.Wrap(result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return &datastore.UseJoinTokenResponse{}, nil
+	}
+
+	if err := tx.Where("token = ? AND uses >= ?", model.Token, maxUses).
+		Delete(&JoinToken{}).Error; err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+	return resp, nil
+}
+
 func pruneJoinTokens(tx *gorm.DB, req *datastore.PruneJoinTokensRequest) (*datastore.PruneJoinTokensResponse, error) {
 	if err := tx.Where("expiry <= ?", req.ExpiresBefore).Delete(&JoinToken{}).Error; err != nil {
 		return nil, sqlError.Wrap(err)
@@ -1139,6 +1624,95 @@ func pruneJoinTokens(tx *gorm.DB, req *datastore.PruneJoinTokensRequest) (*datas
 	return &datastore.PruneJoinTokensResponse{}, nil
 }
 
+func listChangeEvents(tx *gorm.DB, req *datastore.ListChangeEventsRequest) (*datastore.ListChangeEventsResponse, error) {
+	if req.Limit < 0 {
+		return nil, sqlError.New("invalid limit %d", req.Limit)
+	}
+
+	var bounds struct {
+		Oldest *uint64
+		Latest *uint64
+	}
+	if err := tx.Model(&ChangeEvent{}).
+		Select("MIN(id) AS oldest, MAX(id) AS latest").
+		Scan(&bounds).Error; err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+
+	resp := new(datastore.ListChangeEventsResponse)
+	if bounds.Latest == nil {
+		return resp, nil
+	}
+	resp.LatestId = *bounds.Latest
+
+	// Events following after_id are missing if they were pruned, or if the
+	// database no longer knows about after_id (e.g. it was restored from an
+	// older backup).
+	if req.AfterId > 0 && (req.AfterId+1 < *bounds.Oldest || req.AfterId > *bounds.Latest) {
+		resp.ResyncRequired = true
+		return resp, nil
+	}
+
+	eventsTx := tx.Where("id > ?", req.AfterId).Order("id")
+	if req.Limit > 0 {
+		eventsTx = eventsTx.Limit(req.Limit)
+	}
+
+	var models []ChangeEvent
+	if err := eventsTx.Find(&models).Error; err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+
+	for _, model := range models {
+		resp.Events = append(resp.Events, modelToChangeEvent(model))
+	}
+	return resp, nil
+}
+
+func pruneChangeEvents(tx *gorm.DB, req *datastore.PruneChangeEventsRequest) (*datastore.PruneChangeEventsResponse, error) {
+	// The most recent event is kept so that consumers can tell whether the
+	// events they haven't seen yet were pruned.
+	latest := new(ChangeEvent)
+	result := tx.Order("id DESC").First(latest)
+	if result.RecordNotFound() {
+		return &datastore.PruneChangeEventsResponse{}, nil
+	} else if result.Error != nil {
+		return nil, sqlError.Wrap(result.Error)
+	}
+
+	if err := tx.Where("created_at < ? AND id < ?", time.Unix(req.CreatedBefore, 0), latest.ID).
+		Delete(&ChangeEvent{}).Error; err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+
+	return &datastore.PruneChangeEventsResponse{}, nil
+}
+
+// recordChangeEvent records a change to a registration entry or bundle in
+// the same transaction as the change itself.
+func recordChangeEvent(tx *gorm.DB, kind datastore.ChangeEvent_Kind, op datastore.ChangeEvent_Op, key string) error {
+	// The ID comes from the autoincrement column. No lock is taken, so
+	// concurrent transactions may commit out of ID order and consumers have
+	// to watch for events showing up behind ones they have already seen.
+	if err := tx.Create(&ChangeEvent{
+		Kind: int32(kind),
+		Op:   int32(op),
+		Key:  key,
+	}).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+	return nil
+}
+
+func recordChangeEvents(tx *gorm.DB, kind datastore.ChangeEvent_Kind, op datastore.ChangeEvent_Op, keys []string) error {
+	for _, key := range keys {
+		if err := recordChangeEvent(tx, kind, op, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // modelToBundle converts the given bundle model to a Protobuf bundle message. It will also
 // include any embedded CACert models.
 func modelToBundle(model *Bundle) (*datastore.Bundle, error) {
@@ -1241,6 +1815,7 @@ func modelToEntry(tx *gorm.DB, model RegisteredEntry) (*common.RegistrationEntry
 		FederatesWith: federatesWith,
 		Admin:         model.Admin,
 		Downstream:    model.Downstream,
+		EntryExpiry:   model.EntryExpiry,
 	}, nil
 }
 
@@ -1261,11 +1836,28 @@ func modelToAttestedNode(model AttestedNode) *datastore.AttestedNode {
 	}
 }
 
-func modelToJoinToken(model JoinToken) *datastore.JoinToken {
-	return &datastore.JoinToken{
-		Token:  model.Token,
-		Expiry: model.Expiry,
+func modelToChangeEvent(model ChangeEvent) *datastore.ChangeEvent {
+	return &datastore.ChangeEvent{
+		Id:        uint64(model.ID),
+		Kind:      datastore.ChangeEvent_Kind(model.Kind),
+		Op:        datastore.ChangeEvent_Op(model.Op),
+		Key:       model.Key,
+		CreatedAt: model.CreatedAt.Unix(),
+	}
+}
+
+func modelToJoinToken(c *fieldCipher, model JoinToken) (*datastore.JoinToken, error) {
+	token, err := c.open(model.Token, model.SealedToken)
+	if err != nil {
+		return nil, err
 	}
+
+	return &datastore.JoinToken{
+		Token:   token,
+		Expiry:  model.Expiry,
+		MaxUses: model.MaxUses,
+		Uses:    model.Uses,
+	}, nil
 }
 
 func makeFederatesWith(tx *gorm.DB, ids []string) ([]*Bundle, error) {
@@ -1,13 +1,17 @@
 package sql
 
 import (
+	"bytes"
 	"context"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -103,6 +107,126 @@ func (s *PluginSuite) TestInvalidPluginConfiguration() {
 	s.Require().EqualError(err, "datastore-sql: unsupported database_type: wrong")
 }
 
+func (s *PluginSuite) TestInvalidSlowQueryThreshold() {
+	_, err := s.ds.Configure(context.Background(), &spi.ConfigureRequest{
+		Configuration: `
+		database_type = "sqlite3"
+		connection_string = ":memory:"
+		slow_query_threshold = "soon"
+		`,
+	})
+	s.Require().EqualError(err, `unable to parse slow_query_threshold "soon": time: invalid duration "soon"`)
+}
+
+func (s *PluginSuite) TestReadReplica() {
+	primaryPath := filepath.Join(s.dir, "replica-primary.sqlite3")
+	replicaPath := filepath.Join(s.dir, "replica.sqlite3")
+
+	// the replica is not written to by the plugin, so set up its schema
+	// beforehand
+	replica, err := openDB("sqlite3", replicaPath, true)
+	s.Require().NoError(err)
+	defer replica.Close()
+
+	p := newPlugin()
+	_, err = p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+		database_type = "sqlite3"
+		connection_string = "%s"
+		ro_connection_string = "%s"
+		`, primaryPath, replicaPath),
+	})
+	s.Require().NoError(err)
+
+	bundle := bundleutil.BundleProtoFromRootCA("spiffe://foo", s.cert)
+	_, err = p.CreateBundle(ctx, &datastore.CreateBundleRequest{Bundle: bundle})
+	s.Require().NoError(err)
+
+	// reads that tolerate staleness are served by the replica, which has not
+	// received the bundle
+	fetchResp, err := p.FetchBundle(ctx, &datastore.FetchBundleRequest{
+		TrustDomainId: "spiffe://foo",
+		TolerateStale: true,
+	})
+	s.Require().NoError(err)
+	s.Require().Nil(fetchResp.Bundle)
+
+	// other reads are served by the primary
+	fetchResp, err = p.FetchBundle(ctx, &datastore.FetchBundleRequest{TrustDomainId: "spiffe://foo"})
+	s.Require().NoError(err)
+	s.Require().True(proto.Equal(bundle, fetchResp.Bundle))
+
+	listResp, err := p.ListBundles(ctx, &datastore.ListBundlesRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(listResp.Bundles, 1)
+
+	// once "replicated", the bundle is served by the replica
+	model, err := bundleToModel(bundle)
+	s.Require().NoError(err)
+	s.Require().NoError(replica.Create(model).Error)
+
+	fetchResp, err = p.FetchBundle(ctx, &datastore.FetchBundleRequest{
+		TrustDomainId: "spiffe://foo",
+		TolerateStale: true,
+	})
+	s.Require().NoError(err)
+	s.Require().True(proto.Equal(bundle, fetchResp.Bundle))
+
+	// the replica is not used when it is no longer configured
+	_, err = p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+		database_type = "sqlite3"
+		connection_string = "%s"
+		`, primaryPath),
+	})
+	s.Require().NoError(err)
+	s.Require().Nil(p.roDb)
+}
+
+func (s *PluginSuite) TestReadReplicaFallback() {
+	primaryPath := filepath.Join(s.dir, "fallback-primary.sqlite3")
+
+	// queries fail on a replica without the schema, so they are run against
+	// the primary instead
+	replicaPath := filepath.Join(s.dir, "fallback-replica.sqlite3")
+
+	p := newPlugin()
+	_, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+		database_type = "sqlite3"
+		connection_string = "%s"
+		ro_connection_string = "%s"
+		`, primaryPath, replicaPath),
+	})
+	s.Require().NoError(err)
+
+	bundle := bundleutil.BundleProtoFromRootCA("spiffe://foo", s.cert)
+	_, err = p.CreateBundle(ctx, &datastore.CreateBundleRequest{Bundle: bundle})
+	s.Require().NoError(err)
+
+	fetchResp, err := p.FetchBundle(ctx, &datastore.FetchBundleRequest{
+		TrustDomainId: "spiffe://foo",
+		TolerateStale: true,
+	})
+	s.Require().NoError(err)
+	s.Require().True(proto.Equal(bundle, fetchResp.Bundle))
+
+	createResp, err := p.CreateRegistrationEntry(ctx, &datastore.CreateRegistrationEntryRequest{
+		Entry: &common.RegistrationEntry{
+			ParentId:  "spiffe://example.org/node",
+			SpiffeId:  "spiffe://example.org/workload",
+			Selectors: []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+		},
+	})
+	s.Require().NoError(err)
+
+	listResp, err := p.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{
+		TolerateStale: true,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]*common.RegistrationEntry{createResp.Entry}, listResp.Entries)
+}
+
 func (s *PluginSuite) TestBundleCRUD() {
 	bundle := bundleutil.BundleProtoFromRootCA("spiffe://foo", s.cert)
 
@@ -487,6 +611,40 @@ func (s *PluginSuite) TestDeleteAttestedNode() {
 	s.Nil(fresp.Node)
 }
 
+func (s *PluginSuite) TestDeleteAttestedNodeBySerialNumber() {
+	entry := &datastore.AttestedNode{
+		SpiffeId:            "foo",
+		AttestationDataType: "aws-tag",
+		CertSerialNumber:    "badcafe",
+		CertNotAfter:        time.Now().Add(time.Hour).Unix(),
+	}
+
+	_, err := s.ds.CreateAttestedNode(ctx, &datastore.CreateAttestedNodeRequest{Node: entry})
+	s.Require().NoError(err)
+
+	dresp, err := s.ds.DeleteAttestedNode(ctx, &datastore.DeleteAttestedNodeRequest{
+		SpiffeId:           entry.SpiffeId,
+		ByCertSerialNumber: &wrappers.StringValue{Value: "deadbeef"},
+	})
+	s.Require().NoError(err)
+	s.Nil(dresp.Node)
+
+	fresp, err := s.ds.FetchAttestedNode(ctx, &datastore.FetchAttestedNodeRequest{SpiffeId: entry.SpiffeId})
+	s.Require().NoError(err)
+	s.Equal(entry, fresp.Node)
+
+	dresp, err = s.ds.DeleteAttestedNode(ctx, &datastore.DeleteAttestedNodeRequest{
+		SpiffeId:           entry.SpiffeId,
+		ByCertSerialNumber: &wrappers.StringValue{Value: "badcafe"},
+	})
+	s.Require().NoError(err)
+	s.Equal(entry, dresp.Node)
+
+	fresp, err = s.ds.FetchAttestedNode(ctx, &datastore.FetchAttestedNodeRequest{SpiffeId: entry.SpiffeId})
+	s.Require().NoError(err)
+	s.Nil(fresp.Node)
+}
+
 func (s *PluginSuite) TestNodeSelectors() {
 	foo1 := []*common.Selector{
 		{Type: "FOO1", Value: "1"},
@@ -816,6 +974,46 @@ func (s *PluginSuite) TestFetchRegistrationEntriesWithPagination() {
 	s.Require().Error(err, "could not parse token 'invalid int'")
 }
 
+func (s *PluginSuite) TestFetchRegistrationEntriesBySelectorSubsetWithPagination() {
+	entry1 := s.createRegistrationEntry(&common.RegistrationEntry{
+		Selectors: []*common.Selector{{Type: "a", Value: "1"}},
+		SpiffeId:  "spiffe://example.org/foo",
+		ParentId:  "spiffe://example.org/bar",
+	})
+	entry2 := s.createRegistrationEntry(&common.RegistrationEntry{
+		Selectors: []*common.Selector{{Type: "b", Value: "2"}},
+		SpiffeId:  "spiffe://example.org/baz",
+		ParentId:  "spiffe://example.org/bar",
+	})
+	s.createRegistrationEntry(&common.RegistrationEntry{
+		Selectors: []*common.Selector{{Type: "a", Value: "1"}, {Type: "c", Value: "3"}},
+		SpiffeId:  "spiffe://example.org/qux",
+		ParentId:  "spiffe://example.org/bar",
+	})
+
+	// entries matching different subsets of the selectors are paginated over
+	// together
+	var entries []*common.RegistrationEntry
+	pagination := &datastore.Pagination{PageSize: 1}
+	for {
+		resp, err := s.ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{
+			BySelectors: &datastore.BySelectors{
+				Selectors: []*common.Selector{{Type: "a", Value: "1"}, {Type: "b", Value: "2"}},
+				Match:     datastore.BySelectors_MATCH_SUBSET,
+			},
+			Pagination: pagination,
+		})
+		s.Require().NoError(err)
+		if len(resp.Entries) == 0 {
+			break
+		}
+		s.Require().Len(resp.Entries, 1)
+		entries = append(entries, resp.Entries...)
+		pagination = resp.Pagination
+	}
+	s.Require().Equal([]*common.RegistrationEntry{entry1, entry2}, entries)
+}
+
 func (s *PluginSuite) TestUpdateRegistrationEntry() {
 	entry := s.createRegistrationEntry(&common.RegistrationEntry{
 		Selectors: []*common.Selector{
@@ -874,6 +1072,169 @@ func (s *PluginSuite) TestDeleteRegistrationEntry() {
 	s.Require().Equal(entry1, delRes.Entry)
 }
 
+func (s *PluginSuite) TestBatchRegistrationEntries() {
+	foo := &common.RegistrationEntry{
+		Selectors: []*common.Selector{{Type: "Type1", Value: "Value1"}},
+		SpiffeId:  "spiffe://example.org/foo",
+		ParentId:  "spiffe://example.org/bar",
+		Ttl:       1,
+	}
+	baz := &common.RegistrationEntry{
+		Selectors: []*common.Selector{{Type: "Type2", Value: "Value2"}},
+		SpiffeId:  "spiffe://example.org/baz",
+		ParentId:  "spiffe://example.org/bar",
+		Ttl:       2,
+	}
+
+	createResp, err := s.ds.BatchCreateRegistrationEntries(ctx, &datastore.BatchCreateRegistrationEntriesRequest{
+		Entries: []*common.RegistrationEntry{foo, baz},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(createResp.Entries, 2)
+	s.Require().Equal("spiffe://example.org/foo", createResp.Entries[0].SpiffeId)
+	s.Require().Equal("spiffe://example.org/baz", createResp.Entries[1].SpiffeId)
+	s.Require().Equal(createResp.Entries[0], s.fetchRegistrationEntry(createResp.Entries[0].EntryId))
+	s.Require().Equal(createResp.Entries[1], s.fetchRegistrationEntry(createResp.Entries[1].EntryId))
+
+	updated := make([]*common.RegistrationEntry, 0, 2)
+	for _, entry := range createResp.Entries {
+		entry = proto.Clone(entry).(*common.RegistrationEntry)
+		entry.Ttl = 10
+		updated = append(updated, entry)
+	}
+	updateResp, err := s.ds.BatchUpdateRegistrationEntries(ctx, &datastore.BatchUpdateRegistrationEntriesRequest{
+		Entries: updated,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(updated, updateResp.Entries)
+	s.Require().Equal(updated[0], s.fetchRegistrationEntry(updated[0].EntryId))
+	s.Require().Equal(updated[1], s.fetchRegistrationEntry(updated[1].EntryId))
+
+	deleteResp, err := s.ds.BatchDeleteRegistrationEntries(ctx, &datastore.BatchDeleteRegistrationEntriesRequest{
+		EntryIds: []string{updated[0].EntryId, updated[1].EntryId},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(updated, deleteResp.Entries)
+	listResp, err := s.ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{})
+	s.Require().NoError(err)
+	s.Require().Empty(listResp.Entries)
+}
+
+func (s *PluginSuite) TestBatchRegistrationEntriesAreAtomic() {
+	valid := &common.RegistrationEntry{
+		Selectors: []*common.Selector{{Type: "Type1", Value: "Value1"}},
+		SpiffeId:  "spiffe://example.org/foo",
+		ParentId:  "spiffe://example.org/bar",
+	}
+	invalid := &common.RegistrationEntry{
+		SpiffeId: "spiffe://example.org/baz",
+		ParentId: "spiffe://example.org/bar",
+	}
+
+	// none of the entries are created if one is invalid
+	_, err := s.ds.BatchCreateRegistrationEntries(ctx, &datastore.BatchCreateRegistrationEntriesRequest{
+		Entries: []*common.RegistrationEntry{valid, invalid},
+	})
+	s.Require().EqualError(err, "datastore-sql: invalid registration entry: missing selector list")
+
+	listResp, err := s.ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{})
+	s.Require().NoError(err)
+	s.Require().Empty(listResp.Entries)
+
+	// none of the entries are deleted if one does not exist
+	entry := s.createRegistrationEntry(valid)
+	_, err = s.ds.BatchDeleteRegistrationEntries(ctx, &datastore.BatchDeleteRegistrationEntriesRequest{
+		EntryIds: []string{entry.EntryId, "missing"},
+	})
+	s.Require().EqualError(err, "datastore-sql: record not found")
+	s.Require().Equal(entry, s.fetchRegistrationEntry(entry.EntryId))
+}
+
+func (s *PluginSuite) TestChangeEvents() {
+	// no events yet
+	resp, err := s.ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{})
+	s.Require().NoError(err)
+	s.Require().Empty(resp.Events)
+	s.Require().Equal(uint64(0), resp.LatestId)
+
+	s.createBundle("spiffe://otherdomain.org")
+	entry := s.createRegistrationEntry(makeFederatedRegistrationEntry())
+	entry.Ttl = 10
+	_, err = s.ds.UpdateRegistrationEntry(ctx, &datastore.UpdateRegistrationEntryRequest{
+		Entry: entry,
+	})
+	s.Require().NoError(err)
+	_, err = s.ds.DeleteBundle(ctx, &datastore.DeleteBundleRequest{
+		TrustDomainId: "spiffe://otherdomain.org",
+		Mode:          datastore.DeleteBundleRequest_DELETE,
+	})
+	s.Require().NoError(err)
+
+	type change struct {
+		Kind datastore.ChangeEvent_Kind
+		Op   datastore.ChangeEvent_Op
+		Key  string
+	}
+	expected := []change{
+		{datastore.ChangeEvent_BUNDLE, datastore.ChangeEvent_CREATED, "spiffe://otherdomain.org"},
+		{datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_CREATED, entry.EntryId},
+		{datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_UPDATED, entry.EntryId},
+		{datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_DELETED, entry.EntryId},
+		{datastore.ChangeEvent_BUNDLE, datastore.ChangeEvent_DELETED, "spiffe://otherdomain.org"},
+	}
+
+	resp, err = s.ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{})
+	s.Require().NoError(err)
+	s.Require().False(resp.ResyncRequired)
+	var actual []change
+	for _, event := range resp.Events {
+		actual = append(actual, change{Kind: event.Kind, Op: event.Op, Key: event.Key})
+	}
+	s.Require().Equal(expected, actual)
+	s.Require().Equal(resp.Events[4].Id, resp.LatestId)
+	events := resp.Events
+
+	// page through the events following the first one
+	resp, err = s.ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{
+		AfterId: events[0].Id,
+		Limit:   2,
+	})
+	s.Require().NoError(err)
+	s.Require().Len(resp.Events, 2)
+	s.Require().Equal(events[1].Id, resp.Events[0].Id)
+	s.Require().Equal(events[2].Id, resp.Events[1].Id)
+
+	// caught up
+	resp, err = s.ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{
+		AfterId: events[4].Id,
+	})
+	s.Require().NoError(err)
+	s.Require().Empty(resp.Events)
+	s.Require().False(resp.ResyncRequired)
+
+	// pruning keeps the most recent event, and consumers that fell behind
+	// are told to resync
+	_, err = s.ds.PruneChangeEvents(ctx, &datastore.PruneChangeEventsRequest{
+		CreatedBefore: time.Now().Add(time.Minute).Unix(),
+	})
+	s.Require().NoError(err)
+
+	resp, err = s.ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{
+		AfterId: events[0].Id,
+	})
+	s.Require().NoError(err)
+	s.Require().True(resp.ResyncRequired)
+	s.Require().Empty(resp.Events)
+
+	resp, err = s.ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{
+		AfterId: events[3].Id,
+	})
+	s.Require().NoError(err)
+	s.Require().False(resp.ResyncRequired)
+	s.Require().Len(resp.Events, 1)
+	s.Require().Equal(events[4].Id, resp.Events[0].Id)
+}
+
 func (s *PluginSuite) TestListParentIDEntries() {
 	allEntries := testutil.GetRegistrationEntries("entries.json")
 	tests := []struct {
@@ -917,6 +1278,35 @@ func (s *PluginSuite) TestListParentIDEntries() {
 	}
 }
 
+func (s *PluginSuite) TestListExpiredEntries() {
+	now := time.Now().Unix()
+	expired := s.createRegistrationEntry(&common.RegistrationEntry{
+		ParentId:    "spiffe://example.org/node",
+		SpiffeId:    "spiffe://example.org/expired",
+		Selectors:   []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+		EntryExpiry: now - 60,
+	})
+	s.createRegistrationEntry(&common.RegistrationEntry{
+		ParentId:    "spiffe://example.org/node",
+		SpiffeId:    "spiffe://example.org/unexpired",
+		Selectors:   []*common.Selector{{Type: "unix", Value: "uid:1001"}},
+		EntryExpiry: now + 60,
+	})
+	s.createRegistrationEntry(&common.RegistrationEntry{
+		ParentId:  "spiffe://example.org/node",
+		SpiffeId:  "spiffe://example.org/neverexpires",
+		Selectors: []*common.Selector{{Type: "unix", Value: "uid:1002"}},
+	})
+
+	resp, err := s.ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{
+		ByExpiresBefore: &wrappers.Int64Value{
+			Value: now,
+		},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]*common.RegistrationEntry{expired}, resp.Entries)
+}
+
 func (s *PluginSuite) TestListSelectorEntries() {
 	allEntries := testutil.GetRegistrationEntries("entries.json")
 	tests := []struct {
@@ -1174,6 +1564,133 @@ func (s *PluginSuite) TestDeleteJoinToken() {
 	s.Equal(joinToken2, resp.JoinToken)
 }
 
+func (s *PluginSuite) TestListJoinTokens() {
+	resp, err := s.ds.ListJoinTokens(ctx, &datastore.ListJoinTokensRequest{})
+	s.Require().NoError(err)
+	s.Empty(resp.JoinTokens)
+
+	now := time.Now().Unix()
+	joinToken1 := &datastore.JoinToken{
+		Token:  "foobar",
+		Expiry: now,
+	}
+	joinToken2 := &datastore.JoinToken{
+		Token:   "batbaz",
+		Expiry:  now,
+		MaxUses: 3,
+	}
+	for _, joinToken := range []*datastore.JoinToken{joinToken1, joinToken2} {
+		_, err = s.ds.CreateJoinToken(ctx, &datastore.CreateJoinTokenRequest{
+			JoinToken: joinToken,
+		})
+		s.Require().NoError(err)
+	}
+
+	resp, err = s.ds.ListJoinTokens(ctx, &datastore.ListJoinTokensRequest{})
+	s.Require().NoError(err)
+	s.Equal([]*datastore.JoinToken{joinToken1, joinToken2}, resp.JoinTokens)
+}
+
+func (s *PluginSuite) TestUseJoinToken() {
+	now := time.Now().Unix()
+	singleUse := &datastore.JoinToken{
+		Token:  "foobar",
+		Expiry: now,
+	}
+	multiUse := &datastore.JoinToken{
+		Token:   "batbaz",
+		Expiry:  now,
+		MaxUses: 2,
+	}
+	for _, joinToken := range []*datastore.JoinToken{singleUse, multiUse} {
+		_, err := s.ds.CreateJoinToken(ctx, &datastore.CreateJoinTokenRequest{
+			JoinToken: joinToken,
+		})
+		s.Require().NoError(err)
+	}
+
+	// unknown tokens are not returned
+	resp, err := s.ds.UseJoinToken(ctx, &datastore.UseJoinTokenRequest{
+		Token: "unknown",
+	})
+	s.Require().NoError(err)
+	s.Nil(resp.JoinToken)
+
+	// a single use token is deleted after the first use
+	resp, err = s.ds.UseJoinToken(ctx, &datastore.UseJoinTokenRequest{
+		Token: singleUse.Token,
+	})
+	s.Require().NoError(err)
+	s.Equal(singleUse, resp.JoinToken)
+	resp, err = s.ds.UseJoinToken(ctx, &datastore.UseJoinTokenRequest{
+		Token: singleUse.Token,
+	})
+	s.Require().NoError(err)
+	s.Nil(resp.JoinToken)
+
+	// a multiple use token tracks uses until exhausted
+	resp, err = s.ds.UseJoinToken(ctx, &datastore.UseJoinTokenRequest{
+		Token: multiUse.Token,
+	})
+	s.Require().NoError(err)
+	s.Equal(multiUse, resp.JoinToken)
+
+	fetchResp, err := s.ds.FetchJoinToken(ctx, &datastore.FetchJoinTokenRequest{
+		Token: multiUse.Token,
+	})
+	s.Require().NoError(err)
+	s.Require().NotNil(fetchResp.JoinToken)
+	s.Equal(int32(1), fetchResp.JoinToken.Uses)
+
+	resp, err = s.ds.UseJoinToken(ctx, &datastore.UseJoinTokenRequest{
+		Token: multiUse.Token,
+	})
+	s.Require().NoError(err)
+	s.Require().NotNil(resp.JoinToken)
+	s.Equal(int32(1), resp.JoinToken.Uses)
+
+	fetchResp, err = s.ds.FetchJoinToken(ctx, &datastore.FetchJoinTokenRequest{
+		Token: multiUse.Token,
+	})
+	s.Require().NoError(err)
+	s.Nil(fetchResp.JoinToken)
+}
+
+func (s *PluginSuite) TestUseJoinTokenConcurrently() {
+	_, err := s.ds.CreateJoinToken(ctx, &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{
+			Token:   "foobar",
+			Expiry:  time.Now().Unix(),
+			MaxUses: 3,
+		},
+	})
+	s.Require().NoError(err)
+
+	// the token is handed out exactly as many times as it allows
+	var wg sync.WaitGroup
+	var uses int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := s.ds.UseJoinToken(ctx, &datastore.UseJoinTokenRequest{
+				Token: "foobar",
+			})
+			if s.NoError(err) && resp.JoinToken != nil {
+				atomic.AddInt32(&uses, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	s.Equal(int32(3), uses)
+
+	fetchResp, err := s.ds.FetchJoinToken(ctx, &datastore.FetchJoinTokenRequest{
+		Token: "foobar",
+	})
+	s.Require().NoError(err)
+	s.Nil(fetchResp.JoinToken)
+}
+
 func (s *PluginSuite) TestPruneJoinTokens() {
 	now := time.Now().Unix()
 	joinToken := &datastore.JoinToken{
@@ -1209,12 +1726,130 @@ func (s *PluginSuite) TestPruneJoinTokens() {
 	s.Nil(resp.JoinToken)
 }
 
+func (s *PluginSuite) TestJoinTokenEncryption() {
+	dbPath := filepath.Join(s.dir, "encryption.sqlite3")
+	keyPath := filepath.Join(s.dir, "encryption.key")
+	otherKeyPath := filepath.Join(s.dir, "encryption-other.key")
+	s.Require().NoError(ioutil.WriteFile(keyPath, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, keySize))), 0600))
+	s.Require().NoError(ioutil.WriteFile(otherKeyPath, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, keySize))), 0600))
+
+	p := newPlugin()
+	configure := func(keyFile string) error {
+		_, err := p.Configure(ctx, &spi.ConfigureRequest{
+			Configuration: fmt.Sprintf(`
+			database_type = "sqlite3"
+			connection_string = "%s"
+			encryption_key_file = "%s"
+			`, dbPath, keyFile),
+		})
+		return err
+	}
+	rawTokens := func() []JoinToken {
+		var models []JoinToken
+		s.Require().NoError(p.db.Order("id").Find(&models).Error)
+		return models
+	}
+	expiry := time.Now().Add(time.Hour).Unix()
+
+	// a token stored before encryption is configured is encrypted once it is
+	s.Require().NoError(configure(""))
+	_, err := p.CreateJoinToken(ctx, &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "before", Expiry: expiry},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal("before", rawTokens()[0].Token)
+
+	s.Require().NoError(configure(keyPath))
+	_, err = p.CreateJoinToken(ctx, &datastore.CreateJoinTokenRequest{
+		JoinToken: &datastore.JoinToken{Token: "after", Expiry: expiry, MaxUses: 2},
+	})
+	s.Require().NoError(err)
+
+	for _, model := range rawTokens() {
+		s.Require().True(strings.HasPrefix(model.Token, digestPrefix), "token %q is not a digest", model.Token)
+		s.Require().NotEmpty(model.SealedToken)
+		s.Require().False(bytes.Contains(model.SealedToken, []byte("before")))
+		s.Require().False(bytes.Contains(model.SealedToken, []byte("after")))
+	}
+
+	// tokens are decrypted when read
+	listResp, err := p.ListJoinTokens(ctx, &datastore.ListJoinTokensRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(listResp.JoinTokens, 2)
+	s.Require().Equal("before", listResp.JoinTokens[0].Token)
+	s.Require().Equal("after", listResp.JoinTokens[1].Token)
+
+	fetchResp, err := p.FetchJoinToken(ctx, &datastore.FetchJoinTokenRequest{Token: "before"})
+	s.Require().NoError(err)
+	s.Require().NotNil(fetchResp.JoinToken)
+	s.Require().Equal("before", fetchResp.JoinToken.Token)
+
+	useResp, err := p.UseJoinToken(ctx, &datastore.UseJoinTokenRequest{Token: "after"})
+	s.Require().NoError(err)
+	s.Require().NotNil(useResp.JoinToken)
+	s.Require().Equal("after", useResp.JoinToken.Token)
+
+	deleteResp, err := p.DeleteJoinToken(ctx, &datastore.DeleteJoinTokenRequest{Token: "before"})
+	s.Require().NoError(err)
+	s.Require().Equal("before", deleteResp.JoinToken.Token)
+
+	// the plugin cannot be configured with a different key, or without one,
+	// once tokens have been encrypted
+	s.Require().EqualError(configure(otherKeyPath), "datastore-sql: join tokens are encrypted with another key than the one in encryption_key_file")
+	s.Require().EqualError(configure(""), "datastore-sql: join tokens are encrypted but encryption_key_file is not set")
+
+	// the key is only required while encrypted tokens remain
+	_, err = p.DeleteJoinToken(ctx, &datastore.DeleteJoinTokenRequest{Token: "after"})
+	s.Require().NoError(err)
+	s.Require().NoError(configure(""))
+
+	// the key must be valid
+	s.Require().NoError(ioutil.WriteFile(keyPath, []byte("bm90IGEga2V5"), 0600))
+	s.Require().EqualError(configure(keyPath), "datastore-sql: encryption key must be 32 bytes; got 9")
+}
+
 func (s *PluginSuite) TestGetPluginInfo() {
 	resp, err := s.ds.GetPluginInfo(ctx, &spi.GetPluginInfoRequest{})
 	s.Require().NoError(err)
 	s.Require().NotNil(resp)
 }
 
+func (s *PluginSuite) TestDisableAutoMigration() {
+	dbPath := filepath.Join(s.dir, "disable-auto-migration.sqlite3")
+	s.Require().NoError(dumpDB(dbPath, migrationDump(codeVersion-1)))
+	config := fmt.Sprintf(`
+		database_type = "sqlite3"
+		connection_string = "file://%s"
+		disable_auto_migration = true
+	`, dbPath)
+
+	_, err := s.ds.Configure(ctx, &spi.ConfigureRequest{Configuration: config})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), fmt.Sprintf("database schema version %d is behind the code version %d", codeVersion-1, codeVersion))
+
+	status, err := GetSchemaStatus(config)
+	s.Require().NoError(err)
+	s.Require().Equal(&SchemaStatus{
+		Initialized: true,
+		Version:     codeVersion - 1,
+		CodeVersion: codeVersion,
+		Pending:     []string{fmt.Sprintf("v%d: %s", codeVersion, migrationDescriptions[codeVersion])},
+	}, status)
+	s.Require().False(status.Compatible())
+
+	_, err = MigrateSchema(config)
+	s.Require().NoError(err)
+
+	_, err = s.ds.Configure(ctx, &spi.ConfigureRequest{Configuration: config})
+	s.Require().NoError(err)
+}
+
+func (s *PluginSuite) TestMigrationDescriptions() {
+	for version := 1; version <= codeVersion; version++ {
+		s.Require().NotEmpty(migrationDescriptions[version], "no migration description for version %d", version)
+	}
+}
+
 func (s *PluginSuite) TestMigration() {
 	for i := 0; i < codeVersion; i++ {
 		dbName := fmt.Sprintf("v%d.sqlite3", i)
@@ -1324,6 +1959,51 @@ func (s *PluginSuite) TestMigration() {
 			s.Require().NoError(err)
 			s.Require().Len(resp.Entries, 1)
 			s.Require().True(resp.Entries[0].Downstream)
+		case 6:
+			// join tokens should gain the max_uses and uses columns
+			resp, err := s.ds.ListJoinTokens(context.Background(), &datastore.ListJoinTokensRequest{})
+			s.Require().NoError(err)
+			s.Require().Len(resp.JoinTokens, 1)
+			s.Require().Equal(int32(0), resp.JoinTokens[0].MaxUses)
+
+			_, err = s.ds.CreateJoinToken(context.Background(), &datastore.CreateJoinTokenRequest{
+				JoinToken: &datastore.JoinToken{
+					Token:   "reusable",
+					Expiry:  time.Now().Unix(),
+					MaxUses: 2,
+				},
+			})
+			s.Require().NoError(err)
+			useResp, err := s.ds.UseJoinToken(context.Background(), &datastore.UseJoinTokenRequest{
+				Token: "reusable",
+			})
+			s.Require().NoError(err)
+			s.Require().NotNil(useResp.JoinToken)
+			s.Require().Equal(int32(2), useResp.JoinToken.MaxUses)
+		case 7:
+			// changes should be recorded in the new change events table
+			s.createBundle("spiffe://otherdomain.org")
+			resp, err := s.ds.ListChangeEvents(context.Background(), &datastore.ListChangeEventsRequest{})
+			s.Require().NoError(err)
+			s.Require().Len(resp.Events, 1)
+			s.Require().Equal("spiffe://otherdomain.org", resp.Events[0].Key)
+		case 8:
+			// join tokens should gain the sealed_token column, and existing
+			// tokens should be readable
+			resp, err := s.ds.FetchJoinToken(context.Background(), &datastore.FetchJoinTokenRequest{
+				Token: "c4ad9d41-e0a5-4c64-9e4a-6b3e4b3ff2a7",
+			})
+			s.Require().NoError(err)
+			s.Require().NotNil(resp.JoinToken)
+		case 9:
+			// registration entries should gain the entry_expiry column, and
+			// existing entries should never expire
+			resp, err := s.ds.FetchRegistrationEntry(context.Background(), &datastore.FetchRegistrationEntryRequest{
+				EntryId: "f0373f87-a0f3-4c94-aa6a-a2f948bfc15a",
+			})
+			s.Require().NoError(err)
+			s.Require().NotNil(resp.Entry)
+			s.Require().Equal(int64(0), resp.Entry.EntryExpiry)
 		default:
 			s.T().Fatalf("no migration test added for version %d", i)
 		}
@@ -0,0 +1,604 @@
+package azurekeyvault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/v7.0/keyvault"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/hcl"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/base"
+	"github.com/zeebo/errs"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+)
+
+const (
+	defaultKeyPrefix = "spire-"
+	vaultResource    = "https://vault.azure.net"
+
+	// tagKeyID records the SPIRE key id on each key, since key names are
+	// restricted to alphanumerics and dashes.
+	tagKeyID = "spire-key-id"
+)
+
+var (
+	kvError = errs.Class("keymanager(azure_key_vault)")
+
+	reInvalidKeyNameChars = regexp.MustCompile(`[^0-9a-zA-Z-]`)
+)
+
+type configuration struct {
+	// VaultURL is the URL of the key vault (e.g. https://NAME.vault.azure.net/)
+	VaultURL string `hcl:"vault_url"`
+
+	// KeyPrefix is prepended to SPIRE key ids to form key names
+	KeyPrefix string `hcl:"key_prefix"`
+
+	// UseHSM creates HSM-protected keys (requires a premium vault or managed HSM)
+	UseHSM bool `hcl:"use_hsm"`
+
+	// UseMSI authenticates using the managed service identity
+	UseMSI bool `hcl:"use_msi"`
+
+	// TenantID, AppID and AppSecret are used to authenticate as a registered
+	// application when not using MSI.
+	TenantID  string `hcl:"tenant_id"`
+	AppID     string `hcl:"app_id"`
+	AppSecret string `hcl:"app_secret"`
+}
+
+type keyEntry struct {
+	name      string
+	version   string
+	publicKey *keymanager.PublicKey
+}
+
+type KeyManager struct {
+	// log is set by the catalog before the plugin is configured
+	log logrus.FieldLogger
+
+	// generateMu serializes key generation so that rotations of the same
+	// key do not race each other
+	generateMu sync.Mutex
+
+	mu      sync.RWMutex
+	client  vaultClient
+	config  *configuration
+	entries map[string]*keyEntry
+
+	hooks struct {
+		newClient func(config *configuration) (vaultClient, error)
+	}
+}
+
+var _ keymanager.Plugin = (*KeyManager)(nil)
+
+func New() *KeyManager {
+	p := &KeyManager{
+		log:     logrus.StandardLogger(),
+		entries: make(map[string]*keyEntry),
+	}
+	p.hooks.newClient = newClient
+	return p
+}
+
+func (p *KeyManager) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, kvError.New("unable to decode configuration: %v", err)
+	}
+
+	if config.VaultURL == "" {
+		return nil, kvError.New("vault_url is required")
+	}
+	if u, err := url.Parse(config.VaultURL); err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, kvError.New("vault_url %q must be an https URL", config.VaultURL)
+	}
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = defaultKeyPrefix
+	}
+	if reInvalidKeyNameChars.MatchString(config.KeyPrefix) {
+		return nil, kvError.New("key_prefix %q may only contain alphanumerics and dashes", config.KeyPrefix)
+	}
+
+	if config.UseMSI {
+		if config.TenantID != "" || config.AppID != "" || config.AppSecret != "" {
+			return nil, kvError.New("configuration cannot have app credentials when using MSI")
+		}
+	} else {
+		switch {
+		case config.TenantID == "":
+			return nil, kvError.New("tenant_id is required when not using MSI")
+		case config.AppID == "":
+			return nil, kvError.New("app_id is required when not using MSI")
+		case config.AppSecret == "":
+			return nil, kvError.New("app_secret is required when not using MSI")
+		}
+	}
+
+	client, err := p.hooks.newClient(config)
+	if err != nil {
+		return nil, kvError.Wrap(err)
+	}
+
+	entries, err := loadEntries(ctx, client, config)
+	if err != nil {
+		return nil, err
+	}
+
+	p.generateMu.Lock()
+	defer p.generateMu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.config = config
+	p.client = client
+	p.entries = entries
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+// SetLogger sets the logger the plugin logs to
+func (p *KeyManager) SetLogger(log logrus.FieldLogger) {
+	p.log = log
+}
+
+func (p *KeyManager) GetPluginInfo(ctx context.Context, req *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *KeyManager) GenerateKey(ctx context.Context, req *keymanager.GenerateKeyRequest) (*keymanager.GenerateKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, kvError.New("key id is required")
+	}
+	if req.KeyType == keymanager.KeyType_UNSPECIFIED_KEY_TYPE {
+		return nil, kvError.New("key type is required")
+	}
+
+	p.generateMu.Lock()
+	defer p.generateMu.Unlock()
+
+	config, client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := keyCreateParameters(req.KeyId, req.KeyType, config.UseHSM)
+	if err != nil {
+		return nil, err
+	}
+
+	name := keyName(config.KeyPrefix, req.KeyId)
+
+	oldEntry := p.getEntry(req.KeyId)
+	if oldEntry == nil {
+		// Make sure a key that does not belong to this SPIRE key id (or to
+		// SPIRE at all) is not hijacked by adding a new version to it.
+		existing, err := client.GetKey(ctx, name, "")
+		switch {
+		case err == nil:
+			if keyID := tagValue(existing.Tags, tagKeyID); keyID != req.KeyId {
+				return nil, kvError.New("key %q already exists and is not owned by SPIRE key id %q", name, req.KeyId)
+			}
+		case isNotFound(err):
+		default:
+			return nil, kvError.New("unable to get key %q: %v", name, err)
+		}
+	}
+
+	bundle, err := client.CreateKey(ctx, name, params)
+	if err != nil {
+		return nil, kvError.New("unable to create key %q: %v", name, err)
+	}
+
+	newEntry, err := makeKeyEntry(req.KeyId, bundle)
+	if err != nil {
+		return nil, err
+	}
+	if newEntry.publicKey.Type != req.KeyType {
+		return nil, kvError.New("created key %q has type %s; expected %s", name, newEntry.publicKey.Type, req.KeyType)
+	}
+
+	p.mu.Lock()
+	p.entries[req.KeyId] = newEntry
+	p.mu.Unlock()
+
+	// Creating a key with an existing name adds a new version. The previous
+	// version is disabled since it is no longer used. Failure to do so is not
+	// fatal since the new version is already in use.
+	if oldEntry != nil && oldEntry.version != newEntry.version {
+		if err := client.DisableKeyVersion(ctx, oldEntry.name, oldEntry.version); err != nil {
+			p.log.Warnf("Unable to disable version %q of key %q: %v", oldEntry.version, oldEntry.name, err)
+		}
+	}
+
+	return &keymanager.GenerateKeyResponse{
+		PublicKey: clonePublicKey(newEntry.publicKey),
+	}, nil
+}
+
+func (p *KeyManager) GetPublicKey(ctx context.Context, req *keymanager.GetPublicKeyRequest) (*keymanager.GetPublicKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, kvError.New("key id is required")
+	}
+
+	resp := new(keymanager.GetPublicKeyResponse)
+	if entry := p.getEntry(req.KeyId); entry != nil {
+		resp.PublicKey = clonePublicKey(entry.publicKey)
+	}
+	return resp, nil
+}
+
+func (p *KeyManager) GetPublicKeys(ctx context.Context, req *keymanager.GetPublicKeysRequest) (*keymanager.GetPublicKeysResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	resp := new(keymanager.GetPublicKeysResponse)
+	for _, entry := range p.entries {
+		resp.PublicKeys = append(resp.PublicKeys, clonePublicKey(entry.publicKey))
+	}
+	sort.Slice(resp.PublicKeys, func(i, j int) bool {
+		return resp.PublicKeys[i].Id < resp.PublicKeys[j].Id
+	})
+	return resp, nil
+}
+
+func (p *KeyManager) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error) {
+	if req.KeyId == "" {
+		return nil, kvError.New("key id is required")
+	}
+	if req.SignerOpts == nil {
+		return nil, kvError.New("signer opts is required")
+	}
+
+	var hashAlgorithm keymanager.HashAlgorithm
+	var pss bool
+	switch opts := req.SignerOpts.(type) {
+	case *keymanager.SignDataRequest_HashAlgorithm:
+		hashAlgorithm = opts.HashAlgorithm
+	case *keymanager.SignDataRequest_PssOptions:
+		if opts.PssOptions == nil {
+			return nil, kvError.New("PSS options are nil")
+		}
+		hashAlgorithm = opts.PssOptions.HashAlgorithm
+		// Key Vault always uses a salt length equal to the hash length
+		saltLength := int(opts.PssOptions.SaltLength)
+		if hash := crypto.Hash(hashAlgorithm); saltLength != rsa.PSSSaltLengthEqualsHash && (!hash.Available() || saltLength != hash.Size()) {
+			return nil, kvError.New("PSS salt length must match the hash length")
+		}
+		pss = true
+	default:
+		return nil, kvError.New("unsupported signer opts type %T", opts)
+	}
+	if hashAlgorithm == keymanager.HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM {
+		return nil, kvError.New("hash algorithm is required")
+	}
+
+	_, client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := p.getEntry(req.KeyId)
+	if entry == nil {
+		return nil, kvError.New("no such key %q", req.KeyId)
+	}
+
+	algorithm, err := signatureAlgorithm(entry.publicKey.Type, hashAlgorithm, pss)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := client.Sign(ctx, entry.name, entry.version, algorithm, req.Data)
+	if err != nil {
+		return nil, kvError.New("keypair %q signing operation failed: %v", req.KeyId, err)
+	}
+
+	if isECKeyType(entry.publicKey.Type) {
+		// Key Vault returns ECDSA signatures in the JWS (R || S) format
+		signature, err = ecdsaSignatureToASN1(signature)
+		if err != nil {
+			return nil, kvError.New("keypair %q returned malformed signature: %v", req.KeyId, err)
+		}
+	}
+
+	return &keymanager.SignDataResponse{
+		Signature: signature,
+	}, nil
+}
+
+// SignDataBatch signs each request in turn since Key Vault signs a single digest per call.
+func (p *KeyManager) SignDataBatch(ctx context.Context, req *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	return base.SignDataEach(ctx, req, p.SignData)
+}
+
+func (p *KeyManager) getClient() (*configuration, vaultClient, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.client == nil {
+		return nil, nil, kvError.New("not configured")
+	}
+	return p.config, p.client, nil
+}
+
+func (p *KeyManager) getEntry(keyID string) *keyEntry {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.entries[keyID]
+}
+
+func loadEntries(ctx context.Context, client vaultClient, config *configuration) (map[string]*keyEntry, error) {
+	items, err := client.ListKeys(ctx)
+	if err != nil {
+		return nil, kvError.New("unable to list keys: %v", err)
+	}
+
+	entries := make(map[string]*keyEntry)
+	for _, item := range items {
+		if item.Kid == nil {
+			continue
+		}
+		name, _ := parseKeyID(*item.Kid)
+		keyID := tagValue(item.Tags, tagKeyID)
+		if keyID == "" || !strings.HasPrefix(name, config.KeyPrefix) {
+			continue
+		}
+		if item.Attributes != nil && item.Attributes.Enabled != nil && !*item.Attributes.Enabled {
+			continue
+		}
+
+		bundle, err := client.GetKey(ctx, name, "")
+		if err != nil {
+			return nil, kvError.New("unable to get key %q: %v", name, err)
+		}
+		entry, err := makeKeyEntry(keyID, bundle)
+		if err != nil {
+			return nil, err
+		}
+		entries[keyID] = entry
+	}
+	return entries, nil
+}
+
+func makeKeyEntry(keyID string, bundle *keyvault.KeyBundle) (*keyEntry, error) {
+	if bundle.Key == nil || bundle.Key.Kid == nil {
+		return nil, kvError.New("key bundle for %q missing key", keyID)
+	}
+	name, version := parseKeyID(*bundle.Key.Kid)
+	if name == "" || version == "" {
+		return nil, kvError.New("malformed key identifier %q", *bundle.Key.Kid)
+	}
+
+	keyType, publicKey, err := publicKeyFromJWK(bundle.Key)
+	if err != nil {
+		return nil, kvError.New("unable to parse public key for %q: %v", name, err)
+	}
+	pkixData, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, kvError.New("unable to marshal public key for %q: %v", name, err)
+	}
+
+	return &keyEntry{
+		name:    name,
+		version: version,
+		publicKey: &keymanager.PublicKey{
+			Id:       keyID,
+			Type:     keyType,
+			PkixData: pkixData,
+		},
+	}, nil
+}
+
+func keyCreateParameters(keyID string, keyType keymanager.KeyType, useHSM bool) (keyvault.KeyCreateParameters, error) {
+	params := keyvault.KeyCreateParameters{
+		KeyOps: &[]keyvault.JSONWebKeyOperation{keyvault.Sign, keyvault.Verify},
+		Tags: map[string]*string{
+			tagKeyID: &keyID,
+		},
+	}
+
+	var keySize int32
+	switch keyType {
+	case keymanager.KeyType_EC_P256:
+		params.Kty, params.Curve = keyvault.EC, keyvault.P256
+	case keymanager.KeyType_EC_P384:
+		params.Kty, params.Curve = keyvault.EC, keyvault.P384
+	case keymanager.KeyType_RSA_2048:
+		params.Kty, keySize = keyvault.RSA, 2048
+	case keymanager.KeyType_RSA_4096:
+		params.Kty, keySize = keyvault.RSA, 4096
+	default:
+		return params, kvError.New("unsupported key type %q", keyType)
+	}
+	if keySize != 0 {
+		params.KeySize = &keySize
+	}
+
+	if useHSM {
+		switch params.Kty {
+		case keyvault.EC:
+			params.Kty = keyvault.ECHSM
+		case keyvault.RSA:
+			params.Kty = keyvault.RSAHSM
+		}
+	}
+	return params, nil
+}
+
+func signatureAlgorithm(keyType keymanager.KeyType, hashAlgorithm keymanager.HashAlgorithm, pss bool) (keyvault.JSONWebKeySignatureAlgorithm, error) {
+	switch {
+	case keyType == keymanager.KeyType_EC_P256 && hashAlgorithm == keymanager.HashAlgorithm_SHA256:
+		return keyvault.ES256, nil
+	case keyType == keymanager.KeyType_EC_P384 && hashAlgorithm == keymanager.HashAlgorithm_SHA384:
+		return keyvault.ES384, nil
+	case isECKeyType(keyType):
+		return "", kvError.New("hash algorithm %s is not supported with key type %s", hashAlgorithm, keyType)
+	}
+
+	switch hashAlgorithm {
+	case keymanager.HashAlgorithm_SHA256:
+		if pss {
+			return keyvault.PS256, nil
+		}
+		return keyvault.RS256, nil
+	case keymanager.HashAlgorithm_SHA384:
+		if pss {
+			return keyvault.PS384, nil
+		}
+		return keyvault.RS384, nil
+	case keymanager.HashAlgorithm_SHA512:
+		if pss {
+			return keyvault.PS512, nil
+		}
+		return keyvault.RS512, nil
+	default:
+		return "", kvError.New("hash algorithm %s is not supported with key type %s", hashAlgorithm, keyType)
+	}
+}
+
+func isECKeyType(keyType keymanager.KeyType) bool {
+	return keyType == keymanager.KeyType_EC_P256 || keyType == keymanager.KeyType_EC_P384
+}
+
+func publicKeyFromJWK(jwk *keyvault.JSONWebKey) (keymanager.KeyType, crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case keyvault.EC, keyvault.ECHSM:
+		if jwk.X == nil || jwk.Y == nil {
+			return 0, nil, errs.New("EC key missing coordinates")
+		}
+		x, err := decodeBase64URL(*jwk.X)
+		if err != nil {
+			return 0, nil, err
+		}
+		y, err := decodeBase64URL(*jwk.Y)
+		if err != nil {
+			return 0, nil, err
+		}
+		var keyType keymanager.KeyType
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case keyvault.P256:
+			keyType, curve = keymanager.KeyType_EC_P256, elliptic.P256()
+		case keyvault.P384:
+			keyType, curve = keymanager.KeyType_EC_P384, elliptic.P384()
+		default:
+			return 0, nil, errs.New("unsupported curve %q", jwk.Crv)
+		}
+		return keyType, &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	case keyvault.RSA, keyvault.RSAHSM:
+		if jwk.N == nil || jwk.E == nil {
+			return 0, nil, errs.New("RSA key missing modulus or exponent")
+		}
+		n, err := decodeBase64URL(*jwk.N)
+		if err != nil {
+			return 0, nil, err
+		}
+		e, err := decodeBase64URL(*jwk.E)
+		if err != nil {
+			return 0, nil, err
+		}
+		publicKey := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		switch bits := publicKey.N.BitLen(); bits {
+		case 2048:
+			return keymanager.KeyType_RSA_2048, publicKey, nil
+		case 4096:
+			return keymanager.KeyType_RSA_4096, publicKey, nil
+		default:
+			return 0, nil, errs.New("unsupported RSA key size %d", bits)
+		}
+	default:
+		return 0, nil, errs.New("unsupported key type %q", jwk.Kty)
+	}
+}
+
+func ecdsaSignatureToASN1(signature []byte) ([]byte, error) {
+	if len(signature) == 0 || len(signature)%2 != 0 {
+		return nil, errs.New("unexpected signature length %d", len(signature))
+	}
+	half := len(signature) / 2
+	return asn1.Marshal(struct {
+		R, S *big.Int
+	}{
+		R: new(big.Int).SetBytes(signature[:half]),
+		S: new(big.Int).SetBytes(signature[half:]),
+	})
+}
+
+// parseKeyID splits a key identifier of the form
+// https://VAULT/keys/NAME/VERSION into its name and version.
+func parseKeyID(kid string) (name, version string) {
+	u, err := url.Parse(kid)
+	if err != nil {
+		return "", ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "keys" {
+		return "", ""
+	}
+	name = parts[1]
+	if len(parts) > 2 {
+		version = parts[2]
+	}
+	return name, version
+}
+
+func keyName(prefix, keyID string) string {
+	return prefix + reInvalidKeyNameChars.ReplaceAllString(keyID, "-")
+}
+
+func tagValue(tags map[string]*string, key string) string {
+	if value := tags[key]; value != nil {
+		return *value
+	}
+	return ""
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func newClient(config *configuration) (vaultClient, error) {
+	var authorizer autorest.Authorizer
+	var err error
+	if config.UseMSI {
+		msiConfig := auth.NewMSIConfig()
+		msiConfig.Resource = vaultResource
+		authorizer, err = msiConfig.Authorizer()
+	} else {
+		credsConfig := auth.NewClientCredentialsConfig(config.AppID, config.AppSecret, config.TenantID)
+		credsConfig.Resource = vaultResource
+		authorizer, err = credsConfig.Authorizer()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newAzureClient(config.VaultURL, authorizer), nil
+}
+
+func clonePublicKey(publicKey *keymanager.PublicKey) *keymanager.PublicKey {
+	return proto.Clone(publicKey).(*keymanager.PublicKey)
+}
@@ -0,0 +1,525 @@
+package azurekeyvault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/v7.0/keyvault"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/spiffe/spire/pkg/common/x509util"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+	"github.com/stretchr/testify/suite"
+	"github.com/zeebo/errs"
+)
+
+const (
+	testVaultURL = "https://vault.test/"
+)
+
+var (
+	ctx = context.Background()
+
+	testConfig = fmt.Sprintf(`
+vault_url = %q
+use_msi = true
+`, testVaultURL)
+)
+
+func TestKeyManager(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	client *fakeClient
+	m      *keymanager.BuiltIn
+}
+
+func (s *Suite) SetupTest() {
+	s.client = newFakeClient()
+	s.m = s.newKeyManager(testConfig)
+}
+
+func (s *Suite) newKeyManager(config string) *keymanager.BuiltIn {
+	p := New()
+	p.hooks.newClient = func(*configuration) (vaultClient, error) {
+		return s.client, nil
+	}
+	resp, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: config,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&spi.ConfigureResponse{}, resp)
+	return keymanager.NewBuiltIn(p)
+}
+
+func (s *Suite) TestConfigureErrors() {
+	for _, tt := range []struct {
+		config string
+		err    string
+	}{
+		{
+			config: `use_msi = true`,
+			err:    "keymanager(azure_key_vault): vault_url is required",
+		},
+		{
+			config: `vault_url = "http://vault.test" use_msi = true`,
+			err:    `keymanager(azure_key_vault): vault_url "http://vault.test" must be an https URL`,
+		},
+		{
+			config: `vault_url = "https://vault.test" use_msi = true key_prefix = "spire_"`,
+			err:    `keymanager(azure_key_vault): key_prefix "spire_" may only contain alphanumerics and dashes`,
+		},
+		{
+			config: `vault_url = "https://vault.test" use_msi = true tenant_id = "TENANT"`,
+			err:    "keymanager(azure_key_vault): configuration cannot have app credentials when using MSI",
+		},
+		{
+			config: `vault_url = "https://vault.test" app_id = "APP" app_secret = "SECRET"`,
+			err:    "keymanager(azure_key_vault): tenant_id is required when not using MSI",
+		},
+		{
+			config: `vault_url = "https://vault.test" tenant_id = "TENANT" app_secret = "SECRET"`,
+			err:    "keymanager(azure_key_vault): app_id is required when not using MSI",
+		},
+		{
+			config: `vault_url = "https://vault.test" tenant_id = "TENANT" app_id = "APP"`,
+			err:    "keymanager(azure_key_vault): app_secret is required when not using MSI",
+		},
+	} {
+		p := New()
+		_, err := p.Configure(ctx, &spi.ConfigureRequest{
+			Configuration: tt.config,
+		})
+		s.Require().EqualError(err, tt.err, "config: %s", tt.config)
+	}
+}
+
+func (s *Suite) TestGenerateKeyBeforeConfigure() {
+	p := New()
+	resp, err := p.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, "keymanager(azure_key_vault): not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestGenerateKeyUnsupportedKeyType() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_RSA_1024,
+	})
+	s.Require().EqualError(err, `keymanager(azure_key_vault): unsupported key type "RSA_1024"`)
+
+	_, err = s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_ED25519,
+	})
+	s.Require().EqualError(err, `keymanager(azure_key_vault): unsupported key type "ED25519"`)
+}
+
+func (s *Suite) TestGenerateKey() {
+	for _, keyType := range []keymanager.KeyType{
+		keymanager.KeyType_EC_P256,
+		keymanager.KeyType_EC_P384,
+		keymanager.KeyType_RSA_2048,
+	} {
+		resp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+			KeyId:   keyType.String(),
+			KeyType: keyType,
+		})
+		s.Require().NoError(err)
+		s.Require().Equal(keyType.String(), resp.PublicKey.Id)
+		s.Require().Equal(keyType, resp.PublicKey.Type)
+		_, err = x509.ParsePKIXPublicKey(resp.PublicKey.PkixData)
+		s.Require().NoError(err)
+
+		getResp, err := s.m.GetPublicKey(ctx, &keymanager.GetPublicKeyRequest{
+			KeyId: keyType.String(),
+		})
+		s.Require().NoError(err)
+		s.Require().Equal(resp.PublicKey, getResp.PublicKey)
+	}
+
+	// key ids are mapped onto valid key names and recorded in a tag
+	key := s.client.latest("spire-EC-P256")
+	s.Require().NotNil(key)
+	s.Require().Equal("EC_P256", tagValue(key.bundle.Tags, tagKeyID))
+}
+
+func (s *Suite) TestGenerateKeyUsesHSM() {
+	m := s.newKeyManager(testConfig + `use_hsm = true`)
+	_, err := m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(keyvault.ECHSM, s.client.latest("spire-KEY").bundle.Key.Kty)
+}
+
+func (s *Suite) TestGenerateKeyRotatesAndDisablesOldVersion() {
+	resp1, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	resp2, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_RSA_2048,
+	})
+	s.Require().NoError(err)
+	s.Require().NotEqual(resp1.PublicKey.PkixData, resp2.PublicKey.PkixData)
+	s.Require().Equal(keymanager.KeyType_RSA_2048, resp2.PublicKey.Type)
+
+	versions := s.client.keys["spire-KEY"]
+	s.Require().Len(versions, 2)
+	s.Require().False(versions[0].enabled)
+	s.Require().True(versions[1].enabled)
+}
+
+func (s *Suite) TestGenerateKeyRefusesForeignKey() {
+	s.client.createKey("spire-KEY", keyvault.KeyCreateParameters{
+		Kty:   keyvault.EC,
+		Curve: keyvault.P256,
+	})
+
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, `keymanager(azure_key_vault): key "spire-KEY" already exists and is not owned by SPIRE key id "KEY"`)
+}
+
+func (s *Suite) TestGetPublicKeys() {
+	z, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "Z",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	a, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "A",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	resp, err := s.m.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal([]*keymanager.PublicKey{a.PublicKey, z.PublicKey}, resp.PublicKeys)
+}
+
+func (s *Suite) TestKeysAreLoadedOnConfigure() {
+	resp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "x509-CA-A",
+		KeyType: keymanager.KeyType_EC_P384,
+	})
+	s.Require().NoError(err)
+
+	// keys outside of the prefix are ignored
+	_, err = s.newKeyManager(testConfig+`key_prefix = "other-"`).GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	// keys without the key id tag are ignored
+	s.client.createKey("spire-untagged", keyvault.KeyCreateParameters{
+		Kty:   keyvault.EC,
+		Curve: keyvault.P256,
+	})
+
+	m := s.newKeyManager(testConfig)
+	getResp, err := m.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal([]*keymanager.PublicKey{resp.PublicKey}, getResp.PublicKeys)
+}
+
+func (s *Suite) TestSignData() {
+	s.testSignData(keymanager.KeyType_EC_P256, x509.ECDSAWithSHA256)
+	s.testSignData(keymanager.KeyType_EC_P384, x509.ECDSAWithSHA384)
+	s.testSignData(keymanager.KeyType_RSA_2048, x509.SHA256WithRSA)
+	s.testSignData(keymanager.KeyType_RSA_2048, x509.SHA384WithRSAPSS)
+}
+
+func (s *Suite) TestSignDataRejectsMismatchedHash() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P384,
+	})
+	s.Require().NoError(err)
+
+	_, err = s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		Data:  make([]byte, 32),
+		SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+		},
+	})
+	s.Require().EqualError(err, "keymanager(azure_key_vault): hash algorithm SHA256 is not supported with key type EC_P384")
+}
+
+func (s *Suite) TestSignDataRejectsPSSSaltLength() {
+	_, err := s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		SignerOpts: &keymanager.SignDataRequest_PssOptions{
+			PssOptions: &keymanager.PSSOptions{
+				HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+				SaltLength:    20,
+			},
+		},
+	})
+	s.Require().EqualError(err, "keymanager(azure_key_vault): PSS salt length must match the hash length")
+}
+
+func (s *Suite) TestSignDataNoKey() {
+	_, err := s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+		},
+	})
+	s.Require().EqualError(err, `keymanager(azure_key_vault): no such key "KEY"`)
+}
+
+func (s *Suite) testSignData(keyType keymanager.KeyType, signatureAlgorithm x509.SignatureAlgorithm) {
+	generateResp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keyType,
+	})
+	s.Require().NoError(err)
+
+	publicKey, err := x509.ParsePKIXPublicKey(generateResp.PublicKey.PkixData)
+	s.Require().NoError(err)
+
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(1),
+		NotAfter:           time.Now().Add(time.Minute),
+		SignatureAlgorithm: signatureAlgorithm,
+	}
+
+	cert, err := x509util.CreateCertificate(ctx, s.m, template, template, "KEY", publicKey)
+	s.Require().NoError(err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots: roots,
+	})
+	s.Require().NoError(err)
+}
+
+type fakeKeyVersion struct {
+	bundle     keyvault.KeyBundle
+	privateKey crypto.Signer
+	enabled    bool
+}
+
+type fakeClient struct {
+	mu      sync.Mutex
+	keys    map[string][]*fakeKeyVersion
+	version int
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		keys: make(map[string][]*fakeKeyVersion),
+	}
+}
+
+func (c *fakeClient) ListKeys(ctx context.Context) ([]keyvault.KeyItem, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var items []keyvault.KeyItem
+	for name, versions := range c.keys {
+		kid := testVaultURL + "keys/" + name
+		latest := versions[len(versions)-1]
+		enabled := latest.enabled
+		items = append(items, keyvault.KeyItem{
+			Kid:        &kid,
+			Tags:       latest.bundle.Tags,
+			Attributes: &keyvault.KeyAttributes{Enabled: &enabled},
+		})
+	}
+	return items, nil
+}
+
+func (c *fakeClient) GetKey(ctx context.Context, name, version string) (*keyvault.KeyBundle, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := c.findVersion(name, version)
+	if v == nil {
+		return nil, notFoundError()
+	}
+	bundle := v.bundle
+	return &bundle, nil
+}
+
+func (c *fakeClient) CreateKey(ctx context.Context, name string, params keyvault.KeyCreateParameters) (*keyvault.KeyBundle, error) {
+	return c.createKey(name, params)
+}
+
+func (c *fakeClient) DisableKeyVersion(ctx context.Context, name, version string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := c.findVersion(name, version)
+	if v == nil {
+		return notFoundError()
+	}
+	v.enabled = false
+	return nil
+}
+
+func (c *fakeClient) Sign(ctx context.Context, name, version string, algorithm keyvault.JSONWebKeySignatureAlgorithm, digest []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := c.findVersion(name, version)
+	if v == nil {
+		return nil, notFoundError()
+	}
+	if !v.enabled {
+		return nil, errs.New("key %q version %q is disabled", name, version)
+	}
+
+	switch algorithm {
+	case keyvault.ES256, keyvault.ES384:
+		der, err := v.privateKey.Sign(rand.Reader, digest, nil)
+		if err != nil {
+			return nil, err
+		}
+		var sig struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(der, &sig); err != nil {
+			return nil, err
+		}
+		size := (v.privateKey.Public().(*ecdsa.PublicKey).Curve.Params().BitSize + 7) / 8
+		out := make([]byte, size*2)
+		r, s := sig.R.Bytes(), sig.S.Bytes()
+		copy(out[size-len(r):size], r)
+		copy(out[2*size-len(s):], s)
+		return out, nil
+	case keyvault.RS256:
+		return v.privateKey.Sign(rand.Reader, digest, crypto.SHA256)
+	case keyvault.RS384:
+		return v.privateKey.Sign(rand.Reader, digest, crypto.SHA384)
+	case keyvault.RS512:
+		return v.privateKey.Sign(rand.Reader, digest, crypto.SHA512)
+	case keyvault.PS256:
+		return v.privateKey.Sign(rand.Reader, digest, &rsa.PSSOptions{Hash: crypto.SHA256, SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case keyvault.PS384:
+		return v.privateKey.Sign(rand.Reader, digest, &rsa.PSSOptions{Hash: crypto.SHA384, SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case keyvault.PS512:
+		return v.privateKey.Sign(rand.Reader, digest, &rsa.PSSOptions{Hash: crypto.SHA512, SaltLength: rsa.PSSSaltLengthEqualsHash})
+	default:
+		return nil, errs.New("unsupported algorithm %q", algorithm)
+	}
+}
+
+func (c *fakeClient) createKey(name string, params keyvault.KeyCreateParameters) (*keyvault.KeyBundle, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jwk := &keyvault.JSONWebKey{
+		Kty: params.Kty,
+		Crv: params.Curve,
+	}
+
+	var privateKey crypto.Signer
+	var err error
+	switch params.Kty {
+	case keyvault.EC, keyvault.ECHSM:
+		var curve elliptic.Curve
+		switch params.Curve {
+		case keyvault.P256:
+			curve = elliptic.P256()
+		case keyvault.P384:
+			curve = elliptic.P384()
+		default:
+			return nil, errs.New("unsupported curve %q", params.Curve)
+		}
+		var ecKey *ecdsa.PrivateKey
+		ecKey, err = ecdsa.GenerateKey(curve, rand.Reader)
+		if err == nil {
+			jwk.X = encodeBase64URL(ecKey.X.Bytes())
+			jwk.Y = encodeBase64URL(ecKey.Y.Bytes())
+			privateKey = ecKey
+		}
+	case keyvault.RSA, keyvault.RSAHSM:
+		var rsaKey *rsa.PrivateKey
+		rsaKey, err = rsa.GenerateKey(rand.Reader, int(*params.KeySize))
+		if err == nil {
+			jwk.N = encodeBase64URL(rsaKey.N.Bytes())
+			jwk.E = encodeBase64URL(big.NewInt(int64(rsaKey.E)).Bytes())
+			privateKey = rsaKey
+		}
+	default:
+		return nil, errs.New("unsupported key type %q", params.Kty)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.version++
+	kid := fmt.Sprintf("%skeys/%s/%d", testVaultURL, name, c.version)
+	jwk.Kid = &kid
+
+	v := &fakeKeyVersion{
+		bundle: keyvault.KeyBundle{
+			Key:  jwk,
+			Tags: params.Tags,
+		},
+		privateKey: privateKey,
+		enabled:    true,
+	}
+	c.keys[name] = append(c.keys[name], v)
+	bundle := v.bundle
+	return &bundle, nil
+}
+
+func (c *fakeClient) latest(name string) *fakeKeyVersion {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.findVersion(name, "")
+}
+
+func (c *fakeClient) findVersion(name, version string) *fakeKeyVersion {
+	versions := c.keys[name]
+	if len(versions) == 0 {
+		return nil
+	}
+	if version == "" {
+		return versions[len(versions)-1]
+	}
+	for _, v := range versions {
+		if _, vv := parseKeyID(*v.bundle.Key.Kid); vv == version {
+			return v
+		}
+	}
+	return nil
+}
+
+func encodeBase64URL(b []byte) *string {
+	s := base64.RawURLEncoding.EncodeToString(b)
+	return &s
+}
+
+func notFoundError() error {
+	return errs.Wrap(autorest.DetailedError{
+		StatusCode: http.StatusNotFound,
+		Message:    "not found",
+	})
+}
@@ -0,0 +1,99 @@
+package azurekeyvault
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/v7.0/keyvault"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/zeebo/errs"
+)
+
+// vaultClient is an interface representing all of the Key Vault API methods
+// the key manager needs to do its job.
+type vaultClient interface {
+	ListKeys(ctx context.Context) ([]keyvault.KeyItem, error)
+	GetKey(ctx context.Context, name, version string) (*keyvault.KeyBundle, error)
+	CreateKey(ctx context.Context, name string, params keyvault.KeyCreateParameters) (*keyvault.KeyBundle, error)
+	DisableKeyVersion(ctx context.Context, name, version string) error
+	Sign(ctx context.Context, name, version string, algorithm keyvault.JSONWebKeySignatureAlgorithm, digest []byte) ([]byte, error)
+}
+
+// azureClient implements vaultClient using the Azure SDK data plane client
+type azureClient struct {
+	vaultURL string
+	c        keyvault.BaseClient
+}
+
+func newAzureClient(vaultURL string, authorizer autorest.Authorizer) vaultClient {
+	c := keyvault.New()
+	c.Authorizer = authorizer
+	return &azureClient{
+		vaultURL: vaultURL,
+		c:        c,
+	}
+}
+
+func (c *azureClient) ListKeys(ctx context.Context) ([]keyvault.KeyItem, error) {
+	page, err := c.c.GetKeys(ctx, c.vaultURL, nil)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	var items []keyvault.KeyItem
+	for page.NotDone() {
+		items = append(items, page.Values()...)
+		if err := page.Next(); err != nil {
+			return nil, errs.Wrap(err)
+		}
+	}
+	return items, nil
+}
+
+func (c *azureClient) GetKey(ctx context.Context, name, version string) (*keyvault.KeyBundle, error) {
+	key, err := c.c.GetKey(ctx, c.vaultURL, name, version)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return &key, nil
+}
+
+func (c *azureClient) CreateKey(ctx context.Context, name string, params keyvault.KeyCreateParameters) (*keyvault.KeyBundle, error) {
+	key, err := c.c.CreateKey(ctx, c.vaultURL, name, params)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return &key, nil
+}
+
+func (c *azureClient) DisableKeyVersion(ctx context.Context, name, version string) error {
+	enabled := false
+	_, err := c.c.UpdateKey(ctx, c.vaultURL, name, version, keyvault.KeyUpdateParameters{
+		KeyAttributes: &keyvault.KeyAttributes{
+			Enabled: &enabled,
+		},
+	})
+	return errs.Wrap(err)
+}
+
+func (c *azureClient) Sign(ctx context.Context, name, version string, algorithm keyvault.JSONWebKeySignatureAlgorithm, digest []byte) ([]byte, error) {
+	value := base64.RawURLEncoding.EncodeToString(digest)
+	result, err := c.c.Sign(ctx, c.vaultURL, name, version, keyvault.KeySignParameters{
+		Algorithm: algorithm,
+		Value:     &value,
+	})
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if result.Result == nil {
+		return nil, errs.New("sign response missing signature")
+	}
+	return decodeBase64URL(*result.Result)
+}
+
+func isNotFound(err error) bool {
+	if err, ok := errs.Unwrap(err).(autorest.DetailedError); ok {
+		return err.StatusCode == 404
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package base
+
+import (
+	"context"
+
+	"github.com/spiffe/spire/proto/server/keymanager"
+)
+
+// SignDataFn signs data for a single request
+type SignDataFn func(ctx context.Context, req *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error)
+
+// SignDataEach implements SignDataBatch for key managers that cannot sign a
+// batch natively by signing each request in turn. The batch fails on the
+// first request that fails.
+func SignDataEach(ctx context.Context, req *keymanager.SignDataBatchRequest, signData SignDataFn) (*keymanager.SignDataBatchResponse, error) {
+	resp := &keymanager.SignDataBatchResponse{
+		Responses: make([]*keymanager.SignDataResponse, 0, len(req.Requests)),
+	}
+	for _, signReq := range req.Requests {
+		signResp, err := signData(ctx, signReq)
+		if err != nil {
+			return nil, err
+		}
+		resp.Responses = append(resp.Responses, signResp)
+	}
+	return resp, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -115,6 +116,35 @@ func (m *Base) GetPublicKeys(ctx context.Context, req *keymanager.GetPublicKeysR
 }
 
 func (m *Base) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error) {
+	return m.signData(req, m.getPrivateKey(req.KeyId))
+}
+
+func (m *Base) SignDataBatch(ctx context.Context, req *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	// look up all of the keys at once so the entries are only locked once
+	// for the batch
+	privateKeys := make([]crypto.PrivateKey, len(req.Requests))
+	m.mu.RLock()
+	for i, signReq := range req.Requests {
+		if entry := m.entries[signReq.KeyId]; entry != nil {
+			privateKeys[i] = entry.PrivateKey
+		}
+	}
+	m.mu.RUnlock()
+
+	resp := &keymanager.SignDataBatchResponse{
+		Responses: make([]*keymanager.SignDataResponse, 0, len(req.Requests)),
+	}
+	for i, signReq := range req.Requests {
+		signResp, err := m.signData(signReq, privateKeys[i])
+		if err != nil {
+			return nil, err
+		}
+		resp.Responses = append(resp.Responses, signResp)
+	}
+	return resp, nil
+}
+
+func (m *Base) signData(req *keymanager.SignDataRequest, privateKey crypto.PrivateKey) (*keymanager.SignDataResponse, error) {
 	if req.KeyId == "" {
 		return nil, m.newError("key id is required")
 	}
@@ -125,7 +155,9 @@ func (m *Base) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*
 	var signerOpts crypto.SignerOpts
 	switch opts := req.SignerOpts.(type) {
 	case *keymanager.SignDataRequest_HashAlgorithm:
-		if opts.HashAlgorithm == keymanager.HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM {
+		// Ed25519 signs the message itself, so there is no hash to specify
+		_, isEd25519 := privateKey.(ed25519.PrivateKey)
+		if opts.HashAlgorithm == keymanager.HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM && !isEd25519 {
 			return nil, m.newError("hash algorithm is required")
 		}
 		signerOpts = crypto.Hash(opts.HashAlgorithm)
@@ -144,7 +176,6 @@ func (m *Base) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*
 		return nil, m.newError("unsupported signer opts type %T", opts)
 	}
 
-	privateKey := m.getPrivateKey(req.KeyId)
 	if privateKey == nil {
 		return nil, m.newError("no such key %q", req.KeyId)
 	}
@@ -187,6 +218,8 @@ func (m *Base) generateKeyEntry(keyId string, keyType keymanager.KeyType) (e *Ke
 		privateKey, publicKey, err = generateRSAKey(2048)
 	case keymanager.KeyType_RSA_4096:
 		privateKey, publicKey, err = generateRSAKey(4096)
+	case keymanager.KeyType_ED25519:
+		publicKey, privateKey, err = ed25519.GenerateKey(rand.Reader)
 	default:
 		return nil, m.newError("unknown key type %q", keyType)
 	}
@@ -236,6 +269,8 @@ func MakeKeyEntryFromKey(id string, privateKey crypto.PrivateKey) (*KeyEntry, er
 			return nil, err
 		}
 		return makeKeyEntry(id, keyType, privateKey, privateKey.Public())
+	case ed25519.PrivateKey:
+		return makeKeyEntry(id, keymanager.KeyType_ED25519, privateKey, privateKey.Public())
 	default:
 		return nil, fmt.Errorf("unexpected private key type %T", privateKey)
 	}
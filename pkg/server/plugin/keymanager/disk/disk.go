@@ -17,17 +17,32 @@ import (
 
 type configuration struct {
 	KeysPath string `hcl:"keys_path"`
+
+	// Encryption of the keys file. At most one of Passphrase, KeyFile and
+	// AWSKMSKeyID can be set.
+	Passphrase         string `hcl:"passphrase"`
+	KeyFile            string `hcl:"key_file"`
+	AWSKMSKeyID        string `hcl:"aws_kms_key_id"`
+	AWSRegion          string `hcl:"aws_region"`
+	AWSAccessKeyID     string `hcl:"aws_access_key_id"`
+	AWSSecretAccessKey string `hcl:"aws_secret_access_key"`
 }
 
 type KeyManager struct {
 	*base.Base
 
-	mu     sync.Mutex
-	config *configuration
+	mu       sync.Mutex
+	config   *configuration
+	provider dataKeyProvider
+
+	hooks struct {
+		newKMSClient func(config *configuration) (kmsClient, error)
+	}
 }
 
 func New() *KeyManager {
 	m := &KeyManager{}
+	m.hooks.newKMSClient = newKMSClient
 	m.Base = base.New(base.Impl{
 		ErrorFn: newError,
 		WriteFn: m.saveEntries,
@@ -45,27 +60,39 @@ func (m *KeyManager) Configure(ctx context.Context, req *plugin.ConfigureRequest
 		return nil, newError("keys_path is required")
 	}
 
+	provider, err := newDataKeyProvider(config, m.hooks.newKMSClient)
+	if err != nil {
+		return nil, err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if err := m.configure(config); err != nil {
+	if err := m.configure(ctx, config, provider); err != nil {
 		return nil, err
 	}
 
 	return &plugin.ConfigureResponse{}, nil
 }
 
-func (m *KeyManager) configure(config *configuration) error {
+func (m *KeyManager) configure(ctx context.Context, config *configuration, provider dataKeyProvider) error {
 	// only load entry information on first configure
 	if m.config == nil {
-		entries, err := loadEntries(config.KeysPath)
+		entries, encrypted, err := loadEntries(ctx, config.KeysPath, provider)
 		if err != nil {
 			return err
 		}
+		// encrypt keys previously written in the clear
+		if provider != nil && !encrypted && len(entries) > 0 {
+			if err := writeEntries(ctx, config.KeysPath, entries, provider); err != nil {
+				return err
+			}
+		}
 		m.Base.SetEntries(entries)
 	}
 
 	m.config = config
+	m.provider = provider
 	return nil
 }
 
@@ -76,49 +103,65 @@ func (m *KeyManager) GetPluginInfo(ctx context.Context, req *plugin.GetPluginInf
 func (m *KeyManager) saveEntries(ctx context.Context, entries []*base.KeyEntry) error {
 	m.mu.Lock()
 	config := m.config
+	provider := m.provider
 	m.mu.Unlock()
 
 	if config == nil {
 		return newError("not configured")
 	}
 
-	return writeEntries(config.KeysPath, entries)
+	return writeEntries(ctx, config.KeysPath, entries, provider)
 }
 
 type entriesData struct {
-	Keys map[string][]byte `json:"keys"`
+	Keys      map[string][]byte `json:"keys,omitempty"`
+	Encrypted *encryptedData    `json:"encrypted,omitempty"`
 }
 
-func loadEntries(path string) ([]*base.KeyEntry, error) {
+func loadEntries(ctx context.Context, path string, provider dataKeyProvider) (entries []*base.KeyEntry, encrypted bool, err error) {
 	jsonBytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil
+			return nil, false, nil
 		}
-		return nil, err
+		return nil, false, err
 	}
 
 	data := new(entriesData)
 	if err := json.Unmarshal(jsonBytes, data); err != nil {
-		return nil, newError("unable to decode keys JSON: %v", err)
+		return nil, false, newError("unable to decode keys JSON: %v", err)
+	}
+
+	if data.Encrypted != nil {
+		if provider == nil {
+			return nil, false, newError("keys are encrypted but no encryption is configured")
+		}
+		jsonBytes, err := decrypt(ctx, provider, data.Encrypted)
+		if err != nil {
+			return nil, false, err
+		}
+		data = new(entriesData)
+		if err := json.Unmarshal(jsonBytes, data); err != nil {
+			return nil, false, newError("unable to decode decrypted keys JSON: %v", err)
+		}
+		encrypted = true
 	}
 
-	var entries []*base.KeyEntry
 	for id, keyBytes := range data.Keys {
 		key, err := x509.ParsePKCS8PrivateKey(keyBytes)
 		if err != nil {
-			return nil, newError("unable to parse key %q: %v", id, err)
+			return nil, false, newError("unable to parse key %q: %v", id, err)
 		}
 		entry, err := base.MakeKeyEntryFromKey(id, key)
 		if err != nil {
-			return nil, newError("unable to make entry %q: %v", id, err)
+			return nil, false, newError("unable to make entry %q: %v", id, err)
 		}
 		entries = append(entries, entry)
 	}
-	return entries, nil
+	return entries, encrypted, nil
 }
 
-func writeEntries(path string, entries []*base.KeyEntry) error {
+func writeEntries(ctx context.Context, path string, entries []*base.KeyEntry, provider dataKeyProvider) error {
 	data := &entriesData{
 		Keys: make(map[string][]byte),
 	}
@@ -135,6 +178,17 @@ func writeEntries(path string, entries []*base.KeyEntry) error {
 		return newError("unable to marshal entries: %v", err)
 	}
 
+	if provider != nil {
+		encrypted, err := encrypt(ctx, provider, jsonBytes)
+		if err != nil {
+			return err
+		}
+		jsonBytes, err = json.MarshalIndent(&entriesData{Encrypted: encrypted}, "", "\t")
+		if err != nil {
+			return newError("unable to marshal encrypted entries: %v", err)
+		}
+	}
+
 	if err := diskutil.AtomicWriteFile(path, jsonBytes, 0644); err != nil {
 		return newError("unable to write entries: %v", err)
 	}
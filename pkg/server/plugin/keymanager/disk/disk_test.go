@@ -1,13 +1,19 @@
 package disk
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager/base"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager/test"
 	"github.com/spiffe/spire/proto/common/plugin"
@@ -77,6 +83,36 @@ func (s *Suite) TestGeneralFunctionality() {
 	})
 }
 
+func (s *Suite) TestEd25519() {
+	test.RunEd25519(s.T(), func(t *testing.T) keymanager.Plugin {
+		caseDir, err := ioutil.TempDir(s.tmpDir, "testcase-")
+		require.NoError(t, err)
+
+		m := New()
+		resp, err := m.Configure(context.Background(), &plugin.ConfigureRequest{
+			Configuration: fmt.Sprintf("keys_path = %q", filepath.Join(caseDir, "keys.json")),
+		})
+		require.NoError(t, err)
+		require.Equal(t, &plugin.ConfigureResponse{}, resp)
+		return m
+	})
+}
+
+func (s *Suite) TestEd25519Persistence() {
+	resp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_ED25519,
+	})
+	s.Require().NoError(err)
+
+	s.createManager()
+	getResp, err := s.m.GetPublicKey(ctx, &keymanager.GetPublicKeyRequest{
+		KeyId: "KEY",
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp.PublicKey, getResp.PublicKey)
+}
+
 func (s *Suite) TestConfigureMissingPath() {
 	m := New()
 	resp, err := m.Configure(ctx, &keymanager.ConfigureRequest{})
@@ -151,7 +187,7 @@ func (s *Suite) TestGenerateKeyPersistence() {
 	s.Require().NoError(err)
 
 	// make sure keys have been saved
-	entries, err := loadEntries(s.keysPath())
+	entries, _, err := loadEntries(ctx, s.keysPath(), nil)
 	s.Require().NoError(err)
 	base.SortKeyEntries(entries)
 	s.Require().Len(entries, 2)
@@ -172,3 +208,213 @@ func (s *Suite) TestGetPluginInfo() {
 	s.Require().NoError(err)
 	s.Require().Equal(&plugin.GetPluginInfoResponse{}, resp)
 }
+
+func (s *Suite) TestConfigureEncryptionErrors() {
+	shortKeyFile := filepath.Join(s.tmpDir, "short.key")
+	s.Require().NoError(ioutil.WriteFile(shortKeyFile, []byte("too short"), 0600))
+
+	for _, tt := range []struct {
+		config string
+		err    string
+	}{
+		{
+			config: `passphrase = "secret" aws_kms_key_id = "alias/spire"`,
+			err:    "keymanager(disk): only one of passphrase, key_file or aws_kms_key_id can be set",
+		},
+		{
+			config: fmt.Sprintf("key_file = %q", shortKeyFile),
+			err:    "keymanager(disk): key file must contain exactly 32 bytes; got 9",
+		},
+		{
+			config: `aws_kms_key_id = "alias/spire"`,
+			err:    "keymanager(disk): aws_region is required with aws_kms_key_id",
+		},
+	} {
+		m := New()
+		_, err := m.Configure(ctx, &plugin.ConfigureRequest{
+			Configuration: fmt.Sprintf("keys_path = %q\n%s", s.keysPath(), tt.config),
+		})
+		s.Require().EqualError(err, tt.err, "config: %s", tt.config)
+	}
+}
+
+func (s *Suite) TestGeneralFunctionalityWithKeyFile() {
+	keyFile := s.writeKeyFile()
+	test.Run(s.T(), func(t *testing.T) keymanager.Plugin {
+		caseDir, err := ioutil.TempDir(s.tmpDir, "testcase-")
+		require.NoError(t, err)
+
+		m := New()
+		resp, err := m.Configure(context.Background(), &plugin.ConfigureRequest{
+			Configuration: fmt.Sprintf("keys_path = %q\nkey_file = %q", filepath.Join(caseDir, "keys.json"), keyFile),
+		})
+		require.NoError(t, err)
+		require.Equal(t, &plugin.ConfigureResponse{}, resp)
+		return m
+	})
+}
+
+func (s *Suite) TestEncryptionWithPassphrase() {
+	s.testEncryption(`passphrase = "secret"`, func() string { return `passphrase = "wrong"` }, "keymanager(disk): unable to decrypt keys")
+}
+
+func (s *Suite) TestEncryptionWithKeyFile() {
+	keyFile := s.writeKeyFile()
+	otherKeyFile := s.writeKeyFile()
+	s.testEncryption(fmt.Sprintf("key_file = %q", keyFile), func() string { return fmt.Sprintf("key_file = %q", otherKeyFile) }, "keymanager(disk): unable to decrypt keys")
+}
+
+func (s *Suite) TestEncryptionWithAWSKMS() {
+	client := newFakeKMSClient()
+	config := `aws_kms_key_id = "alias/spire" aws_region = "us-east-1"`
+	s.testEncryptionWithHook(config, func() string {
+		s.Require().Equal([]string{"alias/spire"}, client.keyIDs)
+		client.denied = true
+		return config
+	}, "keymanager(disk): unable to recover data key: access denied", client)
+}
+
+func (s *Suite) TestEncryptionMethodMismatch() {
+	s.createManagerWithConfig(`passphrase = "secret"`)
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	_, err = s.configureManager(New(), fmt.Sprintf("key_file = %q", s.writeKeyFile()))
+	s.Require().EqualError(err, `keymanager(disk): keys are encrypted with "passphrase" but "key_file" is configured`)
+}
+
+func (s *Suite) TestPlaintextKeysAreEncryptedOnConfigure() {
+	resp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	s.createManagerWithConfig(fmt.Sprintf("key_file = %q", s.writeKeyFile()))
+	s.requireKeysFileEncrypted()
+
+	getResp, err := s.m.GetPublicKey(ctx, &keymanager.GetPublicKeyRequest{
+		KeyId: "KEY",
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp.PublicKey, getResp.PublicKey)
+}
+
+func (s *Suite) testEncryption(config string, wrongConfig func() string, wrongErr string) {
+	s.testEncryptionWithHook(config, wrongConfig, wrongErr, nil)
+}
+
+func (s *Suite) testEncryptionWithHook(config string, wrongConfig func() string, wrongErr string, client kmsClient) {
+	newManager := func() *KeyManager {
+		m := New()
+		m.hooks.newKMSClient = func(*configuration) (kmsClient, error) {
+			return client, nil
+		}
+		return m
+	}
+
+	m := newManager()
+	_, err := s.configureManager(m, config)
+	s.Require().NoError(err)
+
+	resp, err := m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	s.requireKeysFileEncrypted()
+
+	// keys can be loaded with the same configuration
+	m = newManager()
+	_, err = s.configureManager(m, config)
+	s.Require().NoError(err)
+	getResp, err := m.GetPublicKey(ctx, &keymanager.GetPublicKeyRequest{
+		KeyId: "KEY",
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp.PublicKey, getResp.PublicKey)
+
+	// but not with the wrong secret
+	_, err = s.configureManager(newManager(), wrongConfig())
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), wrongErr)
+
+	// or without encryption
+	_, err = s.configureManager(newManager(), "")
+	s.Require().EqualError(err, "keymanager(disk): keys are encrypted but no encryption is configured")
+}
+
+func (s *Suite) createManagerWithConfig(config string) {
+	s.m = New()
+	resp, err := s.configureManager(s.m, config)
+	s.Require().NoError(err)
+	s.Require().Equal(&plugin.ConfigureResponse{}, resp)
+}
+
+func (s *Suite) configureManager(m *KeyManager, config string) (*plugin.ConfigureResponse, error) {
+	return m.Configure(ctx, &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("keys_path = %q\n%s", s.keysPath(), config),
+	})
+}
+
+func (s *Suite) writeKeyFile() string {
+	f, err := ioutil.TempFile(s.tmpDir, "data-key-")
+	s.Require().NoError(err)
+	defer f.Close()
+	key := make([]byte, 32)
+	_, err = rand.Read(key)
+	s.Require().NoError(err)
+	_, err = f.Write(key)
+	s.Require().NoError(err)
+	return f.Name()
+}
+
+func (s *Suite) requireKeysFileEncrypted() {
+	jsonBytes, err := ioutil.ReadFile(s.keysPath())
+	s.Require().NoError(err)
+	s.Require().Contains(string(jsonBytes), `"encrypted"`)
+	s.Require().NotContains(string(jsonBytes), `"keys"`)
+}
+
+// fakeKMSClient "wraps" data keys by prefixing them with the key id
+type fakeKMSClient struct {
+	keyIDs []string
+	denied bool
+}
+
+func newFakeKMSClient() *fakeKMSClient {
+	return &fakeKMSClient{}
+}
+
+func (c *fakeKMSClient) GenerateDataKeyWithContext(ctx aws.Context, input *kms.GenerateDataKeyInput, opts ...request.Option) (*kms.GenerateDataKeyOutput, error) {
+	keyID := aws.StringValue(input.KeyId)
+	c.keyIDs = append(c.keyIDs, keyID)
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &kms.GenerateDataKeyOutput{
+		KeyId:          input.KeyId,
+		Plaintext:      key,
+		CiphertextBlob: append([]byte(keyID+":"), key...),
+	}, nil
+}
+
+func (c *fakeKMSClient) DecryptWithContext(ctx aws.Context, input *kms.DecryptInput, opts ...request.Option) (*kms.DecryptOutput, error) {
+	if c.denied {
+		return nil, errors.New("access denied")
+	}
+	for _, keyID := range c.keyIDs {
+		prefix := []byte(keyID + ":")
+		if bytes.HasPrefix(input.CiphertextBlob, prefix) {
+			return &kms.DecryptOutput{
+				KeyId:     aws.String(keyID),
+				Plaintext: input.CiphertextBlob[len(prefix):],
+			}, nil
+		}
+	}
+	return nil, errors.New("invalid ciphertext")
+}
@@ -0,0 +1,244 @@
+package disk
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	encryptionMethodPassphrase = "passphrase"
+	encryptionMethodKeyFile    = "key_file"
+	encryptionMethodAWSKMS     = "aws_kms"
+
+	// dataKeySize is the size of the AES-256 key used to encrypt the keys
+	dataKeySize = 32
+
+	// scrypt parameters recommended for interactive logins. Keys are only
+	// written when rotated, so the cost is not a concern.
+	scryptN    = 32768
+	scryptR    = 8
+	scryptP    = 1
+	scryptSalt = 16
+)
+
+// encryptedData is the encrypted form of the keys. Along with the AES-GCM
+// nonce and ciphertext it holds what is needed to recover the data key for
+// the encryption method.
+type encryptedData struct {
+	Method     string `json:"method"`
+	Salt       []byte `json:"salt,omitempty"`
+	WrappedKey []byte `json:"wrapped_key,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// dataKeyProvider provides the key used to encrypt the keys on disk
+type dataKeyProvider interface {
+	Method() string
+
+	// NewDataKey returns a key for encrypting the keys and records in data
+	// whatever is needed to recover it.
+	NewDataKey(ctx context.Context, data *encryptedData) ([]byte, error)
+
+	// RecoverDataKey returns the key the data was encrypted with
+	RecoverDataKey(ctx context.Context, data *encryptedData) ([]byte, error)
+}
+
+// kmsClient is the subset of the AWS KMS API used to wrap data keys
+type kmsClient interface {
+	GenerateDataKeyWithContext(aws.Context, *kms.GenerateDataKeyInput, ...request.Option) (*kms.GenerateDataKeyOutput, error)
+	DecryptWithContext(aws.Context, *kms.DecryptInput, ...request.Option) (*kms.DecryptOutput, error)
+}
+
+func newDataKeyProvider(config *configuration, newKMSClient func(*configuration) (kmsClient, error)) (dataKeyProvider, error) {
+	methods := 0
+	for _, value := range []string{config.Passphrase, config.KeyFile, config.AWSKMSKeyID} {
+		if value != "" {
+			methods++
+		}
+	}
+	switch {
+	case methods > 1:
+		return nil, newError("only one of passphrase, key_file or aws_kms_key_id can be set")
+	case config.Passphrase != "":
+		return passphraseProvider{passphrase: []byte(config.Passphrase)}, nil
+	case config.KeyFile != "":
+		key, err := ioutil.ReadFile(config.KeyFile)
+		if err != nil {
+			return nil, newError("unable to read key file: %v", err)
+		}
+		if len(key) != dataKeySize {
+			return nil, newError("key file must contain exactly %d bytes; got %d", dataKeySize, len(key))
+		}
+		return keyFileProvider{key: key}, nil
+	case config.AWSKMSKeyID != "":
+		if config.AWSRegion == "" {
+			return nil, newError("aws_region is required with aws_kms_key_id")
+		}
+		client, err := newKMSClient(config)
+		if err != nil {
+			return nil, newError("unable to create KMS client: %v", err)
+		}
+		return awsKMSProvider{client: client, keyID: config.AWSKMSKeyID}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func newKMSClient(config *configuration) (kmsClient, error) {
+	conf := aws.NewConfig()
+	if config.AWSAccessKeyID != "" || config.AWSSecretAccessKey != "" {
+		conf.Credentials = credentials.NewStaticCredentials(config.AWSAccessKeyID, config.AWSSecretAccessKey, "")
+	}
+	conf.Region = aws.String(config.AWSRegion)
+
+	sess, err := session.NewSession(conf)
+	if err != nil {
+		return nil, err
+	}
+	return kms.New(sess), nil
+}
+
+func encrypt(ctx context.Context, provider dataKeyProvider, plaintext []byte) (*encryptedData, error) {
+	data := &encryptedData{
+		Method: provider.Method(),
+	}
+	key, err := provider.NewDataKey(ctx, data)
+	if err != nil {
+		return nil, newError("unable to obtain data key: %v", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	data.Nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, data.Nonce); err != nil {
+		return nil, newError("unable to generate nonce: %v", err)
+	}
+	data.Ciphertext = aead.Seal(nil, data.Nonce, plaintext, []byte(data.Method))
+	return data, nil
+}
+
+func decrypt(ctx context.Context, provider dataKeyProvider, data *encryptedData) ([]byte, error) {
+	if data.Method != provider.Method() {
+		return nil, newError("keys are encrypted with %q but %q is configured", data.Method, provider.Method())
+	}
+	key, err := provider.RecoverDataKey(ctx, data)
+	if err != nil {
+		return nil, newError("unable to recover data key: %v", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.Nonce) != aead.NonceSize() {
+		return nil, newError("invalid nonce size %d", len(data.Nonce))
+	}
+	plaintext, err := aead.Open(nil, data.Nonce, data.Ciphertext, []byte(data.Method))
+	if err != nil {
+		return nil, newError("unable to decrypt keys: %v", err)
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, newError("invalid data key: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, newError("unable to create cipher: %v", err)
+	}
+	return aead, nil
+}
+
+// passphraseProvider derives the data key from a passphrase using scrypt and
+// a random salt.
+type passphraseProvider struct {
+	passphrase []byte
+}
+
+func (passphraseProvider) Method() string {
+	return encryptionMethodPassphrase
+}
+
+func (p passphraseProvider) NewDataKey(ctx context.Context, data *encryptedData) ([]byte, error) {
+	data.Salt = make([]byte, scryptSalt)
+	if _, err := io.ReadFull(rand.Reader, data.Salt); err != nil {
+		return nil, err
+	}
+	return p.RecoverDataKey(ctx, data)
+}
+
+func (p passphraseProvider) RecoverDataKey(ctx context.Context, data *encryptedData) ([]byte, error) {
+	if len(data.Salt) == 0 {
+		return nil, errors.New("salt is missing")
+	}
+	return scrypt.Key(p.passphrase, data.Salt, scryptN, scryptR, scryptP, dataKeySize)
+}
+
+// keyFileProvider uses the contents of a key file as the data key
+type keyFileProvider struct {
+	key []byte
+}
+
+func (keyFileProvider) Method() string {
+	return encryptionMethodKeyFile
+}
+
+func (p keyFileProvider) NewDataKey(ctx context.Context, data *encryptedData) ([]byte, error) {
+	return p.key, nil
+}
+
+func (p keyFileProvider) RecoverDataKey(ctx context.Context, data *encryptedData) ([]byte, error) {
+	return p.key, nil
+}
+
+// awsKMSProvider generates a data key for each write, stored alongside the
+// keys wrapped by an AWS KMS customer master key (i.e. envelope encryption).
+type awsKMSProvider struct {
+	client kmsClient
+	keyID  string
+}
+
+func (awsKMSProvider) Method() string {
+	return encryptionMethodAWSKMS
+}
+
+func (p awsKMSProvider) NewDataKey(ctx context.Context, data *encryptedData) ([]byte, error) {
+	resp, err := p.client.GenerateDataKeyWithContext(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(p.keyID),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return nil, err
+	}
+	data.WrappedKey = resp.CiphertextBlob
+	return resp.Plaintext, nil
+}
+
+func (p awsKMSProvider) RecoverDataKey(ctx context.Context, data *encryptedData) ([]byte, error) {
+	if len(data.WrappedKey) == 0 {
+		return nil, errors.New("wrapped key is missing")
+	}
+	resp, err := p.client.DecryptWithContext(ctx, &kms.DecryptInput{
+		CiphertextBlob: data.WrappedKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
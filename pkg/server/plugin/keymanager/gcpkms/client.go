@@ -0,0 +1,297 @@
+package gcpkms
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/spiffe/spire/pkg/common/plugin/gcp"
+	"github.com/zeebo/errs"
+)
+
+const (
+	defaultEndpoint = "https://cloudkms.googleapis.com"
+
+	purposeAsymmetricSign = "ASYMMETRIC_SIGN"
+
+	stateEnabled           = "ENABLED"
+	statePendingGeneration = "PENDING_GENERATION"
+)
+
+type cryptoKey struct {
+	Name            string            `json:"name"`
+	Purpose         string            `json:"purpose,omitempty"`
+	VersionTemplate *versionTemplate  `json:"versionTemplate,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+}
+
+type versionTemplate struct {
+	Algorithm       string `json:"algorithm"`
+	ProtectionLevel string `json:"protectionLevel,omitempty"`
+}
+
+type cryptoKeyVersion struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Algorithm string `json:"algorithm"`
+}
+
+// kmsClient is an interface representing all of the Cloud KMS API methods
+// the key manager needs to do its job.
+type kmsClient interface {
+	ListCryptoKeys(ctx context.Context, keyRing string) ([]*cryptoKey, error)
+	CreateCryptoKey(ctx context.Context, keyRing, cryptoKeyID string, key *cryptoKey) (*cryptoKey, error)
+	UpdateCryptoKeyAlgorithm(ctx context.Context, name, algorithm string) error
+	ListCryptoKeyVersions(ctx context.Context, cryptoKeyName string) ([]*cryptoKeyVersion, error)
+	CreateCryptoKeyVersion(ctx context.Context, cryptoKeyName string) (*cryptoKeyVersion, error)
+	GetCryptoKeyVersion(ctx context.Context, name string) (*cryptoKeyVersion, error)
+	DestroyCryptoKeyVersion(ctx context.Context, name string) error
+	GetPublicKey(ctx context.Context, versionName string) (string, error)
+	AsymmetricSign(ctx context.Context, versionName string, hashAlg crypto.Hash, digest []byte) ([]byte, error)
+}
+
+// restClient implements kmsClient against the Cloud KMS v1 REST API
+type restClient struct {
+	endpoint string
+	tokens   gcp.TokenSource
+	http     *http.Client
+}
+
+func newRESTClient(endpoint string, tokens gcp.TokenSource) *restClient {
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	return &restClient{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		tokens:   tokens,
+		http:     http.DefaultClient,
+	}
+}
+
+func (c *restClient) ListCryptoKeys(ctx context.Context, keyRing string) ([]*cryptoKey, error) {
+	var keys []*cryptoKey
+	pageToken := ""
+	for {
+		var resp struct {
+			CryptoKeys    []*cryptoKey `json:"cryptoKeys"`
+			NextPageToken string       `json:"nextPageToken"`
+		}
+		query := url.Values{}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		if err := c.do(ctx, "GET", keyRing+"/cryptoKeys", query, nil, &resp); err != nil {
+			return nil, err
+		}
+		keys = append(keys, resp.CryptoKeys...)
+		if resp.NextPageToken == "" {
+			return keys, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+func (c *restClient) CreateCryptoKey(ctx context.Context, keyRing, cryptoKeyID string, key *cryptoKey) (*cryptoKey, error) {
+	query := url.Values{}
+	query.Set("cryptoKeyId", cryptoKeyID)
+	resp := new(cryptoKey)
+	if err := c.do(ctx, "POST", keyRing+"/cryptoKeys", query, key, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *restClient) UpdateCryptoKeyAlgorithm(ctx context.Context, name, algorithm string) error {
+	query := url.Values{}
+	query.Set("updateMask", "versionTemplate.algorithm")
+	body := &cryptoKey{
+		VersionTemplate: &versionTemplate{Algorithm: algorithm},
+	}
+	return c.do(ctx, "PATCH", name, query, body, nil)
+}
+
+func (c *restClient) ListCryptoKeyVersions(ctx context.Context, cryptoKeyName string) ([]*cryptoKeyVersion, error) {
+	var versions []*cryptoKeyVersion
+	pageToken := ""
+	for {
+		var resp struct {
+			CryptoKeyVersions []*cryptoKeyVersion `json:"cryptoKeyVersions"`
+			NextPageToken     string              `json:"nextPageToken"`
+		}
+		query := url.Values{}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		if err := c.do(ctx, "GET", cryptoKeyName+"/cryptoKeyVersions", query, nil, &resp); err != nil {
+			return nil, err
+		}
+		versions = append(versions, resp.CryptoKeyVersions...)
+		if resp.NextPageToken == "" {
+			return versions, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+func (c *restClient) CreateCryptoKeyVersion(ctx context.Context, cryptoKeyName string) (*cryptoKeyVersion, error) {
+	resp := new(cryptoKeyVersion)
+	if err := c.do(ctx, "POST", cryptoKeyName+"/cryptoKeyVersions", nil, struct{}{}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *restClient) GetCryptoKeyVersion(ctx context.Context, name string) (*cryptoKeyVersion, error) {
+	resp := new(cryptoKeyVersion)
+	if err := c.do(ctx, "GET", name, nil, nil, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *restClient) DestroyCryptoKeyVersion(ctx context.Context, name string) error {
+	return c.do(ctx, "POST", name+":destroy", nil, struct{}{}, nil)
+}
+
+func (c *restClient) GetPublicKey(ctx context.Context, versionName string) (string, error) {
+	var resp struct {
+		Pem string `json:"pem"`
+	}
+	if err := c.do(ctx, "GET", versionName+"/publicKey", nil, nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Pem, nil
+}
+
+func (c *restClient) AsymmetricSign(ctx context.Context, versionName string, hashAlg crypto.Hash, digest []byte) ([]byte, error) {
+	var digestField string
+	switch hashAlg {
+	case crypto.SHA256:
+		digestField = "sha256"
+	case crypto.SHA384:
+		digestField = "sha384"
+	case crypto.SHA512:
+		digestField = "sha512"
+	default:
+		return nil, errs.New("unsupported digest hash algorithm %v", hashAlg)
+	}
+
+	req := map[string]interface{}{
+		"digest": map[string]string{
+			digestField: base64.StdEncoding.EncodeToString(digest),
+		},
+	}
+	var resp struct {
+		Signature string `json:"signature"`
+	}
+	if err := c.do(ctx, "POST", versionName+":asymmetricSign", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	signature, err := base64.StdEncoding.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, errs.New("unable to decode signature: %v", err)
+	}
+	return signature, nil
+}
+
+func (c *restClient) do(ctx context.Context, method, resource string, query url.Values, in, out interface{}) error {
+	u := fmt.Sprintf("%s/v1/%s", c.endpoint, resource)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		inBytes, err := json.Marshal(in)
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		body = bytes.NewReader(inBytes)
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return errs.New("unable to obtain access token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(method, resource, resp.StatusCode, respBytes)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(respBytes, out); err != nil {
+			return errs.New("unable to decode %s response: %v", resource, err)
+		}
+	}
+	return nil
+}
+
+// apiError is returned when the Cloud KMS API responds with a non-200
+// status code.
+type apiError struct {
+	Method     string
+	Resource   string
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s %s failed: %s: %s", e.Method, e.Resource, e.Status, e.Message)
+	}
+	return fmt.Sprintf("%s %s failed: unexpected status code %d", e.Method, e.Resource, e.StatusCode)
+}
+
+func newAPIError(method, resource string, statusCode int, body []byte) error {
+	e := &apiError{
+		Method:     method,
+		Resource:   resource,
+		StatusCode: statusCode,
+	}
+	var errBody struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errBody); err == nil {
+		e.Status = errBody.Error.Status
+		e.Message = errBody.Error.Message
+	}
+	return e
+}
+
+func isAlreadyExists(err error) bool {
+	e, ok := err.(*apiError)
+	return ok && (e.Status == "ALREADY_EXISTS" || e.StatusCode == http.StatusConflict)
+}
@@ -0,0 +1,78 @@
+package gcpkms
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type staticTokenSource string
+
+func (s staticTokenSource) Token(context.Context) (string, error) {
+	return string(s), nil
+}
+
+func TestRESTClientAsymmetricSign(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "POST", req.Method)
+		require.Equal(t, "/v1/projects/P/locations/L/keyRings/R/cryptoKeys/K/cryptoKeyVersions/1:asymmetricSign", req.URL.Path)
+		require.Equal(t, "Bearer TOKEN", req.Header.Get("Authorization"))
+
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"digest":{"sha384":"AQID"}}`, string(body))
+
+		w.Write([]byte(`{"signature":"` + base64.StdEncoding.EncodeToString([]byte("SIGNATURE")) + `"}`))
+	}))
+	defer server.Close()
+
+	client := newRESTClient(server.URL, staticTokenSource("TOKEN"))
+	signature, err := client.AsymmetricSign(context.Background(), "projects/P/locations/L/keyRings/R/cryptoKeys/K/cryptoKeyVersions/1", crypto.SHA384, []byte{1, 2, 3})
+	require.NoError(t, err)
+	require.Equal(t, []byte("SIGNATURE"), signature)
+}
+
+func TestRESTClientListCryptoKeysPaginates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "/v1/projects/P/locations/L/keyRings/R/cryptoKeys", req.URL.Path)
+		var resp interface{}
+		switch req.URL.Query().Get("pageToken") {
+		case "":
+			resp = map[string]interface{}{
+				"cryptoKeys":    []*cryptoKey{{Name: "A"}},
+				"nextPageToken": "NEXT",
+			}
+		case "NEXT":
+			resp = map[string]interface{}{
+				"cryptoKeys": []*cryptoKey{{Name: "B"}},
+			}
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	defer server.Close()
+
+	client := newRESTClient(server.URL, staticTokenSource("TOKEN"))
+	keys, err := client.ListCryptoKeys(context.Background(), "projects/P/locations/L/keyRings/R")
+	require.NoError(t, err)
+	require.Equal(t, []*cryptoKey{{Name: "A"}, {Name: "B"}}, keys)
+}
+
+func TestRESTClientAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":{"code":409,"message":"key exists","status":"ALREADY_EXISTS"}}`))
+	}))
+	defer server.Close()
+
+	client := newRESTClient(server.URL, staticTokenSource("TOKEN"))
+	_, err := client.CreateCryptoKey(context.Background(), "projects/P/locations/L/keyRings/R", "K", &cryptoKey{})
+	require.EqualError(t, err, "POST projects/P/locations/L/keyRings/R/cryptoKeys failed: ALREADY_EXISTS: key exists")
+	require.True(t, isAlreadyExists(err))
+}
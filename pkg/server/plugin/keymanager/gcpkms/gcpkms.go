@@ -0,0 +1,518 @@
+package gcpkms
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/hcl"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/plugin/gcp"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/base"
+	"github.com/zeebo/errs"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+)
+
+const (
+	cloudKMSScope = "https://www.googleapis.com/auth/cloudkms"
+
+	// labelKey marks crypto keys created by this plugin
+	labelKey   = "spire-key-manager"
+	labelValue = "gcpkms"
+)
+
+var (
+	kmsError = errs.Class("keymanager(gcpkms)")
+
+	reKeyRing     = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+$`)
+	reCryptoKeyID = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,63}$`)
+
+	// reInvalidKeyIDChars matches the characters not allowed in crypto key ids
+	reInvalidKeyIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+)
+
+type configuration struct {
+	// KeyRing is the full resource name of the key ring the keys are
+	// created in (i.e. projects/PROJECT/locations/LOCATION/keyRings/RING)
+	KeyRing string `hcl:"key_ring"`
+
+	// KeyPrefix is prepended to SPIRE key ids to form crypto key ids.
+	// Defaults to a prefix derived from the trust domain.
+	KeyPrefix string `hcl:"key_prefix"`
+
+	// ServiceAccountFile is an optional path to a service account JSON key
+	// file. If unset, credentials are obtained from the metadata server.
+	ServiceAccountFile string `hcl:"service_account_file"`
+
+	// Endpoint optionally overrides the Cloud KMS API endpoint
+	Endpoint string `hcl:"endpoint"`
+}
+
+type keyEntry struct {
+	cryptoKeyName string
+	versionName   string
+	hashAlgorithm crypto.Hash
+	publicKey     *keymanager.PublicKey
+}
+
+type KeyManager struct {
+	// generateMu serializes key generation so that rotations of the same
+	// key do not race each other
+	generateMu sync.Mutex
+
+	// log is set by the catalog before the plugin is configured
+	log logrus.FieldLogger
+
+	mu      sync.RWMutex
+	config  *configuration
+	client  kmsClient
+	entries map[string]*keyEntry
+
+	hooks struct {
+		newClient    func(config *configuration) (kmsClient, error)
+		pollInterval time.Duration
+	}
+}
+
+var _ keymanager.Plugin = (*KeyManager)(nil)
+
+func New() *KeyManager {
+	p := &KeyManager{
+		log:     logrus.StandardLogger(),
+		entries: make(map[string]*keyEntry),
+	}
+	p.hooks.newClient = newClient
+	p.hooks.pollInterval = time.Second
+	return p
+}
+
+func (p *KeyManager) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, kmsError.New("unable to decode configuration: %v", err)
+	}
+
+	if config.KeyRing == "" {
+		return nil, kmsError.New("key_ring is required")
+	}
+	if !reKeyRing.MatchString(config.KeyRing) {
+		return nil, kmsError.New("key_ring %q is not a valid key ring resource name", config.KeyRing)
+	}
+	if config.KeyPrefix == "" {
+		trustDomain := req.GlobalConfig.GetTrustDomain()
+		if trustDomain == "" {
+			return nil, kmsError.New("trust domain is required to derive the default key_prefix")
+		}
+		config.KeyPrefix = defaultKeyPrefix(trustDomain)
+	}
+
+	client, err := p.hooks.newClient(config)
+	if err != nil {
+		return nil, kmsError.Wrap(err)
+	}
+
+	entries, err := loadEntries(ctx, p.log, client, config)
+	if err != nil {
+		return nil, err
+	}
+
+	p.generateMu.Lock()
+	defer p.generateMu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.config = config
+	p.client = client
+	p.entries = entries
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+// SetLogger sets the logger the plugin logs to
+func (p *KeyManager) SetLogger(log logrus.FieldLogger) {
+	p.log = log
+}
+
+func (p *KeyManager) GetPluginInfo(ctx context.Context, req *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *KeyManager) GenerateKey(ctx context.Context, req *keymanager.GenerateKeyRequest) (*keymanager.GenerateKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, kmsError.New("key id is required")
+	}
+	if req.KeyType == keymanager.KeyType_UNSPECIFIED_KEY_TYPE {
+		return nil, kmsError.New("key type is required")
+	}
+	algorithm, hashAlgorithm, err := algorithmFromKeyType(req.KeyType)
+	if err != nil {
+		return nil, err
+	}
+
+	p.generateMu.Lock()
+	defer p.generateMu.Unlock()
+
+	config, client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	cryptoKeyID := config.KeyPrefix + req.KeyId
+	if !reCryptoKeyID.MatchString(cryptoKeyID) {
+		return nil, kmsError.New("key id %q cannot be used to form a valid crypto key id", req.KeyId)
+	}
+
+	oldEntry := p.getEntry(req.KeyId)
+
+	var version *cryptoKeyVersion
+	if oldEntry != nil {
+		version, err = rotateCryptoKey(ctx, client, oldEntry.cryptoKeyName, algorithm)
+	} else {
+		version, err = createCryptoKey(ctx, client, config.KeyRing, cryptoKeyID, algorithm)
+	}
+	if err != nil {
+		return nil, kmsError.Wrap(err)
+	}
+
+	version, err = p.waitForVersion(ctx, client, version)
+	if err != nil {
+		return nil, kmsError.Wrap(err)
+	}
+
+	newEntry, err := makeKeyEntry(ctx, client, req.KeyId, req.KeyType, hashAlgorithm, version)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.entries[req.KeyId] = newEntry
+	p.mu.Unlock()
+
+	// The previous key material is no longer needed. Failure to destroy it is
+	// not fatal since the new key is already in use.
+	if oldEntry != nil && oldEntry.versionName != newEntry.versionName {
+		if err := client.DestroyCryptoKeyVersion(ctx, oldEntry.versionName); err != nil {
+			p.log.Warnf("Unable to destroy crypto key version %q: %v", oldEntry.versionName, err)
+		}
+	}
+
+	return &keymanager.GenerateKeyResponse{
+		PublicKey: clonePublicKey(newEntry.publicKey),
+	}, nil
+}
+
+func (p *KeyManager) GetPublicKey(ctx context.Context, req *keymanager.GetPublicKeyRequest) (*keymanager.GetPublicKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, kmsError.New("key id is required")
+	}
+
+	resp := new(keymanager.GetPublicKeyResponse)
+	if entry := p.getEntry(req.KeyId); entry != nil {
+		resp.PublicKey = clonePublicKey(entry.publicKey)
+	}
+	return resp, nil
+}
+
+func (p *KeyManager) GetPublicKeys(ctx context.Context, req *keymanager.GetPublicKeysRequest) (*keymanager.GetPublicKeysResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	resp := new(keymanager.GetPublicKeysResponse)
+	for _, entry := range p.entries {
+		resp.PublicKeys = append(resp.PublicKeys, clonePublicKey(entry.publicKey))
+	}
+	sort.Slice(resp.PublicKeys, func(i, j int) bool {
+		return resp.PublicKeys[i].Id < resp.PublicKeys[j].Id
+	})
+	return resp, nil
+}
+
+func (p *KeyManager) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error) {
+	if req.KeyId == "" {
+		return nil, kmsError.New("key id is required")
+	}
+	if req.SignerOpts == nil {
+		return nil, kmsError.New("signer opts is required")
+	}
+
+	var hashAlgorithm keymanager.HashAlgorithm
+	switch opts := req.SignerOpts.(type) {
+	case *keymanager.SignDataRequest_HashAlgorithm:
+		hashAlgorithm = opts.HashAlgorithm
+	case *keymanager.SignDataRequest_PssOptions:
+		return nil, kmsError.New("PSS signing is not supported")
+	default:
+		return nil, kmsError.New("unsupported signer opts type %T", opts)
+	}
+	if hashAlgorithm == keymanager.HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM {
+		return nil, kmsError.New("hash algorithm is required")
+	}
+
+	_, client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := p.getEntry(req.KeyId)
+	if entry == nil {
+		return nil, kmsError.New("no such key %q", req.KeyId)
+	}
+
+	if crypto.Hash(hashAlgorithm) != entry.hashAlgorithm {
+		return nil, kmsError.New("keypair %q requires hash algorithm %s; got %s", req.KeyId, keymanager.HashAlgorithm(entry.hashAlgorithm), hashAlgorithm)
+	}
+
+	signature, err := client.AsymmetricSign(ctx, entry.versionName, entry.hashAlgorithm, req.Data)
+	if err != nil {
+		return nil, kmsError.New("keypair %q signing operation failed: %v", req.KeyId, err)
+	}
+
+	return &keymanager.SignDataResponse{
+		Signature: signature,
+	}, nil
+}
+
+// SignDataBatch signs each request in turn since Cloud KMS has no batch signing operation.
+func (p *KeyManager) SignDataBatch(ctx context.Context, req *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	return base.SignDataEach(ctx, req, p.SignData)
+}
+
+func (p *KeyManager) getClient() (*configuration, kmsClient, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.client == nil {
+		return nil, nil, kmsError.New("not configured")
+	}
+	return p.config, p.client, nil
+}
+
+func (p *KeyManager) getEntry(keyID string) *keyEntry {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.entries[keyID]
+}
+
+// waitForVersion polls the crypto key version until the key material has
+// been generated. Asymmetric keys are not usable until then.
+func (p *KeyManager) waitForVersion(ctx context.Context, client kmsClient, version *cryptoKeyVersion) (*cryptoKeyVersion, error) {
+	for {
+		switch version.State {
+		case stateEnabled:
+			return version, nil
+		case statePendingGeneration:
+		default:
+			return nil, errs.New("crypto key version %q is in unexpected state %q", version.Name, version.State)
+		}
+
+		select {
+		case <-time.After(p.hooks.pollInterval):
+		case <-ctx.Done():
+			return nil, errs.New("timed out waiting for crypto key version %q: %v", version.Name, ctx.Err())
+		}
+
+		var err error
+		version, err = client.GetCryptoKeyVersion(ctx, version.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func createCryptoKey(ctx context.Context, client kmsClient, keyRing, cryptoKeyID, algorithm string) (*cryptoKeyVersion, error) {
+	key, err := client.CreateCryptoKey(ctx, keyRing, cryptoKeyID, &cryptoKey{
+		Purpose: purposeAsymmetricSign,
+		VersionTemplate: &versionTemplate{
+			Algorithm: algorithm,
+		},
+		Labels: map[string]string{
+			labelKey: labelValue,
+		},
+	})
+	switch {
+	case err == nil:
+		// Creating an asymmetric crypto key also creates its first version.
+		versions, err := client.ListCryptoKeyVersions(ctx, key.Name)
+		if err != nil {
+			return nil, err
+		}
+		version := latestVersion(versions, "")
+		if version == nil {
+			return nil, errs.New("crypto key %q has no versions", key.Name)
+		}
+		return version, nil
+	case isAlreadyExists(err):
+		// The crypto key exists (e.g. it has no enabled versions and was not
+		// loaded during configuration). Rotate it instead.
+		return rotateCryptoKey(ctx, client, keyRing+"/cryptoKeys/"+cryptoKeyID, algorithm)
+	default:
+		return nil, err
+	}
+}
+
+func rotateCryptoKey(ctx context.Context, client kmsClient, cryptoKeyName, algorithm string) (*cryptoKeyVersion, error) {
+	// The algorithm of new versions is determined by the version template,
+	// which is updated in case the requested key type changed.
+	if err := client.UpdateCryptoKeyAlgorithm(ctx, cryptoKeyName, algorithm); err != nil {
+		return nil, err
+	}
+	return client.CreateCryptoKeyVersion(ctx, cryptoKeyName)
+}
+
+func loadEntries(ctx context.Context, log logrus.FieldLogger, client kmsClient, config *configuration) (map[string]*keyEntry, error) {
+	cryptoKeys, err := client.ListCryptoKeys(ctx, config.KeyRing)
+	if err != nil {
+		return nil, kmsError.New("unable to list crypto keys: %v", err)
+	}
+
+	entries := make(map[string]*keyEntry)
+	for _, key := range cryptoKeys {
+		cryptoKeyID := key.Name[strings.LastIndex(key.Name, "/")+1:]
+		if !strings.HasPrefix(cryptoKeyID, config.KeyPrefix) || key.Purpose != purposeAsymmetricSign {
+			continue
+		}
+		keyID := strings.TrimPrefix(cryptoKeyID, config.KeyPrefix)
+
+		versions, err := client.ListCryptoKeyVersions(ctx, key.Name)
+		if err != nil {
+			return nil, kmsError.New("unable to list versions of crypto key %q: %v", key.Name, err)
+		}
+		version := latestVersion(versions, stateEnabled)
+		if version == nil {
+			continue
+		}
+
+		// Crypto keys created outside of SPIRE may use algorithms SPIRE has
+		// no key type for. They are left alone rather than failing startup.
+		keyType, hashAlgorithm, err := keyTypeFromAlgorithm(version.Algorithm)
+		if err != nil {
+			log.Warnf("Ignoring crypto key %q: %v", key.Name, err)
+			continue
+		}
+
+		entry, err := makeKeyEntry(ctx, client, keyID, keyType, hashAlgorithm, version)
+		if err != nil {
+			return nil, err
+		}
+		entries[keyID] = entry
+	}
+	return entries, nil
+}
+
+// defaultKeyPrefix derives the key prefix from the trust domain, so that the
+// servers of different trust domains sharing a key ring don't adopt each
+// other's keys. Trust domains too long to leave room for the SPIRE key id are
+// replaced by a digest.
+func defaultKeyPrefix(trustDomain string) string {
+	name := reInvalidKeyIDChars.ReplaceAllString(trustDomain, "-")
+	if len(name) > 32 {
+		sum := sha256.Sum256([]byte(trustDomain))
+		name = hex.EncodeToString(sum[:8])
+	}
+	return "spire-" + name + "-"
+}
+
+func makeKeyEntry(ctx context.Context, client kmsClient, keyID string, keyType keymanager.KeyType, hashAlgorithm crypto.Hash, version *cryptoKeyVersion) (*keyEntry, error) {
+	pemData, err := client.GetPublicKey(ctx, version.Name)
+	if err != nil {
+		return nil, kmsError.New("unable to get public key for %q: %v", version.Name, err)
+	}
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, kmsError.New("unable to decode public key for %q: malformed PEM", version.Name)
+	}
+	if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		return nil, kmsError.New("unable to parse public key for %q: %v", version.Name, err)
+	}
+
+	return &keyEntry{
+		cryptoKeyName: version.Name[:strings.Index(version.Name, "/cryptoKeyVersions/")],
+		versionName:   version.Name,
+		hashAlgorithm: hashAlgorithm,
+		publicKey: &keymanager.PublicKey{
+			Id:       keyID,
+			Type:     keyType,
+			PkixData: block.Bytes,
+		},
+	}, nil
+}
+
+// latestVersion returns the most recently created version in the given
+// state, or in any state if state is empty.
+func latestVersion(versions []*cryptoKeyVersion, state string) *cryptoKeyVersion {
+	var latest *cryptoKeyVersion
+	latestNumber := -1
+	for _, version := range versions {
+		if state != "" && version.State != state {
+			continue
+		}
+		number, err := strconv.Atoi(version.Name[strings.LastIndex(version.Name, "/")+1:])
+		if err != nil {
+			continue
+		}
+		if number > latestNumber {
+			latest = version
+			latestNumber = number
+		}
+	}
+	return latest
+}
+
+func algorithmFromKeyType(keyType keymanager.KeyType) (string, crypto.Hash, error) {
+	switch keyType {
+	case keymanager.KeyType_EC_P256:
+		return "EC_SIGN_P256_SHA256", crypto.SHA256, nil
+	case keymanager.KeyType_EC_P384:
+		return "EC_SIGN_P384_SHA384", crypto.SHA384, nil
+	case keymanager.KeyType_RSA_2048:
+		return "RSA_SIGN_PKCS1_2048_SHA256", crypto.SHA256, nil
+	case keymanager.KeyType_RSA_4096:
+		return "RSA_SIGN_PKCS1_4096_SHA256", crypto.SHA256, nil
+	default:
+		return "", 0, kmsError.New("unsupported key type %q", keyType)
+	}
+}
+
+func keyTypeFromAlgorithm(algorithm string) (keymanager.KeyType, crypto.Hash, error) {
+	switch algorithm {
+	case "EC_SIGN_P256_SHA256":
+		return keymanager.KeyType_EC_P256, crypto.SHA256, nil
+	case "EC_SIGN_P384_SHA384":
+		return keymanager.KeyType_EC_P384, crypto.SHA384, nil
+	case "RSA_SIGN_PKCS1_2048_SHA256":
+		return keymanager.KeyType_RSA_2048, crypto.SHA256, nil
+	case "RSA_SIGN_PKCS1_4096_SHA256":
+		return keymanager.KeyType_RSA_4096, crypto.SHA256, nil
+	default:
+		return keymanager.KeyType_UNSPECIFIED_KEY_TYPE, 0, kmsError.New("unsupported crypto key algorithm %q", algorithm)
+	}
+}
+
+func newClient(config *configuration) (kmsClient, error) {
+	var tokens gcp.TokenSource
+	if config.ServiceAccountFile != "" {
+		var err error
+		tokens, err = gcp.NewServiceAccountTokenSource(config.ServiceAccountFile, cloudKMSScope)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		tokens = gcp.NewMetadataTokenSource(gcp.DefaultMetadataTokenURL)
+	}
+	return newRESTClient(config.Endpoint, tokens), nil
+}
+
+func clonePublicKey(publicKey *keymanager.PublicKey) *keymanager.PublicKey {
+	return proto.Clone(publicKey).(*keymanager.PublicKey)
+}
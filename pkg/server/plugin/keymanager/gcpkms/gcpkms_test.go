@@ -0,0 +1,504 @@
+package gcpkms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/pkg/common/x509util"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+	"github.com/stretchr/testify/suite"
+	"github.com/zeebo/errs"
+)
+
+const (
+	testKeyRing   = "projects/PROJECT/locations/global/keyRings/RING"
+	testKeyPrefix = "spire-example-org-"
+)
+
+var (
+	ctx = context.Background()
+
+	testConfig = fmt.Sprintf(`key_ring = %q`, testKeyRing)
+)
+
+func TestKeyManager(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	client  *fakeClient
+	logHook *test.Hook
+	m       *keymanager.BuiltIn
+}
+
+func (s *Suite) SetupTest() {
+	s.client = newFakeClient()
+	s.m = s.newKeyManager(testConfig)
+}
+
+func (s *Suite) newKeyManager(config string) *keymanager.BuiltIn {
+	p := New()
+	p.hooks.newClient = func(*configuration) (kmsClient, error) {
+		return s.client, nil
+	}
+	p.hooks.pollInterval = time.Millisecond
+	log, logHook := test.NewNullLogger()
+	s.logHook = logHook
+	p.SetLogger(log)
+	resp, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: config,
+		GlobalConfig:  &spi.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&spi.ConfigureResponse{}, resp)
+	return keymanager.NewBuiltIn(p)
+}
+
+func (s *Suite) TestConfigureRequiresKeyRing() {
+	p := New()
+	_, err := p.Configure(ctx, &spi.ConfigureRequest{})
+	s.Require().EqualError(err, "keymanager(gcpkms): key_ring is required")
+}
+
+func (s *Suite) TestConfigureRejectsMalformedKeyRing() {
+	p := New()
+	_, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: `key_ring = "projects/PROJECT/keyRings/RING"`,
+	})
+	s.Require().EqualError(err, `keymanager(gcpkms): key_ring "projects/PROJECT/keyRings/RING" is not a valid key ring resource name`)
+}
+
+func (s *Suite) TestConfigureRequiresTrustDomainWithoutKeyPrefix() {
+	p := New()
+	_, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: testConfig,
+	})
+	s.Require().EqualError(err, "keymanager(gcpkms): trust domain is required to derive the default key_prefix")
+}
+
+func (s *Suite) TestDefaultKeyPrefix() {
+	s.Require().Equal("spire-example-org-", defaultKeyPrefix("example.org"))
+	s.Require().Equal("spire-other_domain-org-", defaultKeyPrefix("other_domain.org"))
+
+	// long trust domains are replaced by a digest to leave room for key ids
+	prefix := defaultKeyPrefix("a-very-long-trust-domain-name.example.org")
+	s.Require().Regexp(`^spire-[0-9a-f]{16}-$`, prefix)
+	s.Require().NotEqual(prefix, defaultKeyPrefix("a-very-long-trust-domain-name.example.com"))
+}
+
+func (s *Suite) TestGenerateKeyBeforeConfigure() {
+	p := New()
+	resp, err := p.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, "keymanager(gcpkms): not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestGenerateKeyRequiresKeyIDAndType() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, "keymanager(gcpkms): key id is required")
+
+	_, err = s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId: "KEY",
+	})
+	s.Require().EqualError(err, "keymanager(gcpkms): key type is required")
+}
+
+func (s *Suite) TestGenerateKeyUnsupportedKeyType() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_RSA_1024,
+	})
+	s.Require().EqualError(err, `keymanager(gcpkms): unsupported key type "RSA_1024"`)
+
+	_, err = s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_ED25519,
+	})
+	s.Require().EqualError(err, `keymanager(gcpkms): unsupported key type "ED25519"`)
+}
+
+func (s *Suite) TestGenerateKey() {
+	for _, keyType := range []keymanager.KeyType{
+		keymanager.KeyType_EC_P256,
+		keymanager.KeyType_EC_P384,
+		keymanager.KeyType_RSA_2048,
+	} {
+		resp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+			KeyId:   keyType.String(),
+			KeyType: keyType,
+		})
+		s.Require().NoError(err)
+		s.Require().Equal(keyType.String(), resp.PublicKey.Id)
+		s.Require().Equal(keyType, resp.PublicKey.Type)
+		_, err = x509.ParsePKIXPublicKey(resp.PublicKey.PkixData)
+		s.Require().NoError(err)
+
+		getResp, err := s.m.GetPublicKey(ctx, &keymanager.GetPublicKeyRequest{
+			KeyId: keyType.String(),
+		})
+		s.Require().NoError(err)
+		s.Require().Equal(resp.PublicKey, getResp.PublicKey)
+	}
+
+	s.Require().Contains(s.client.keys, testKeyRing+"/cryptoKeys/"+testKeyPrefix+"EC_P256")
+	s.Require().Equal(map[string]string{labelKey: labelValue}, s.client.keys[testKeyRing+"/cryptoKeys/"+testKeyPrefix+"EC_P256"].Labels)
+}
+
+func (s *Suite) TestGenerateKeyRotatesAndDestroysOldVersion() {
+	resp1, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	resp2, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P384,
+	})
+	s.Require().NoError(err)
+	s.Require().NotEqual(resp1.PublicKey.PkixData, resp2.PublicKey.PkixData)
+	s.Require().Equal(keymanager.KeyType_EC_P384, resp2.PublicKey.Type)
+
+	versions := s.client.versions[testKeyRing+"/cryptoKeys/"+testKeyPrefix+"KEY"]
+	s.Require().Len(versions, 2)
+	s.Require().Equal("DESTROY_SCHEDULED", versions[0].State)
+	s.Require().Equal(stateEnabled, versions[1].State)
+}
+
+func (s *Suite) TestGetPublicKeys() {
+	z, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "Z",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	a, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "A",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	resp, err := s.m.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal([]*keymanager.PublicKey{a.PublicKey, z.PublicKey}, resp.PublicKeys)
+}
+
+func (s *Suite) TestKeysAreLoadedOnConfigure() {
+	resp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	// keys outside of the prefix are ignored
+	_, err = s.newKeyManager(testConfig+"\nkey_prefix = \"other-\"").GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	m := s.newKeyManager(testConfig)
+	getResp, err := m.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal([]*keymanager.PublicKey{resp.PublicKey}, getResp.PublicKeys)
+}
+
+func (s *Suite) TestKeysWithUnsupportedAlgorithmsAreIgnored() {
+	resp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	// a crypto key under the prefix with an algorithm SPIRE has no key type for
+	name := testKeyRing + "/cryptoKeys/" + testKeyPrefix + "SECP256K1"
+	s.client.keys[name] = &cryptoKey{
+		Name:            name,
+		Purpose:         purposeAsymmetricSign,
+		VersionTemplate: &versionTemplate{Algorithm: "EC_SIGN_SECP256K1_SHA256"},
+	}
+	s.client.versions[name] = []*fakeVersion{{
+		cryptoKeyVersion: cryptoKeyVersion{
+			Name:      name + "/cryptoKeyVersions/1",
+			State:     stateEnabled,
+			Algorithm: "EC_SIGN_SECP256K1_SHA256",
+		},
+	}}
+
+	m := s.newKeyManager(testConfig)
+	getResp, err := m.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal([]*keymanager.PublicKey{resp.PublicKey}, getResp.PublicKeys)
+
+	entry := s.logHook.LastEntry()
+	s.Require().NotNil(entry)
+	s.Require().Equal(logrus.WarnLevel, entry.Level)
+	s.Require().Equal(fmt.Sprintf(`Ignoring crypto key %q: keymanager(gcpkms): unsupported crypto key algorithm "EC_SIGN_SECP256K1_SHA256"`, name), entry.Message)
+}
+
+func (s *Suite) TestSignData() {
+	s.testSignData(keymanager.KeyType_EC_P256, x509.ECDSAWithSHA256)
+	s.testSignData(keymanager.KeyType_EC_P384, x509.ECDSAWithSHA384)
+	s.testSignData(keymanager.KeyType_RSA_2048, x509.SHA256WithRSA)
+}
+
+func (s *Suite) TestSignDataRejectsMismatchedHash() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P384,
+	})
+	s.Require().NoError(err)
+
+	_, err = s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		Data:  make([]byte, 32),
+		SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+		},
+	})
+	s.Require().EqualError(err, `keymanager(gcpkms): keypair "KEY" requires hash algorithm SHA384; got SHA256`)
+}
+
+func (s *Suite) TestSignDataRejectsPSS() {
+	_, err := s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		SignerOpts: &keymanager.SignDataRequest_PssOptions{
+			PssOptions: &keymanager.PSSOptions{
+				HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+			},
+		},
+	})
+	s.Require().EqualError(err, "keymanager(gcpkms): PSS signing is not supported")
+}
+
+func (s *Suite) TestSignDataNoKey() {
+	_, err := s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+		},
+	})
+	s.Require().EqualError(err, `keymanager(gcpkms): no such key "KEY"`)
+}
+
+func (s *Suite) testSignData(keyType keymanager.KeyType, signatureAlgorithm x509.SignatureAlgorithm) {
+	generateResp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keyType,
+	})
+	s.Require().NoError(err)
+
+	publicKey, err := x509.ParsePKIXPublicKey(generateResp.PublicKey.PkixData)
+	s.Require().NoError(err)
+
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(1),
+		NotAfter:           time.Now().Add(time.Minute),
+		SignatureAlgorithm: signatureAlgorithm,
+	}
+
+	cert, err := x509util.CreateCertificate(ctx, s.m, template, template, "KEY", publicKey)
+	s.Require().NoError(err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots: roots,
+	})
+	s.Require().NoError(err)
+}
+
+type fakeVersion struct {
+	cryptoKeyVersion
+	privateKey crypto.Signer
+}
+
+type fakeClient struct {
+	mu       sync.Mutex
+	keys     map[string]*cryptoKey
+	versions map[string][]*fakeVersion
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		keys:     make(map[string]*cryptoKey),
+		versions: make(map[string][]*fakeVersion),
+	}
+}
+
+func (c *fakeClient) ListCryptoKeys(ctx context.Context, keyRing string) ([]*cryptoKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var keys []*cryptoKey
+	for name, key := range c.keys {
+		if strings.HasPrefix(name, keyRing+"/cryptoKeys/") {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (c *fakeClient) CreateCryptoKey(ctx context.Context, keyRing, cryptoKeyID string, key *cryptoKey) (*cryptoKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name := keyRing + "/cryptoKeys/" + cryptoKeyID
+	if _, ok := c.keys[name]; ok {
+		return nil, &apiError{StatusCode: http.StatusConflict, Status: "ALREADY_EXISTS"}
+	}
+	created := &cryptoKey{
+		Name:            name,
+		Purpose:         key.Purpose,
+		VersionTemplate: &versionTemplate{Algorithm: key.VersionTemplate.Algorithm},
+		Labels:          key.Labels,
+	}
+	c.keys[name] = created
+	if _, err := c.createVersion(name); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (c *fakeClient) UpdateCryptoKeyAlgorithm(ctx context.Context, name, algorithm string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key, ok := c.keys[name]
+	if !ok {
+		return &apiError{StatusCode: http.StatusNotFound, Status: "NOT_FOUND"}
+	}
+	key.VersionTemplate.Algorithm = algorithm
+	return nil
+}
+
+func (c *fakeClient) ListCryptoKeyVersions(ctx context.Context, cryptoKeyName string) ([]*cryptoKeyVersion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var versions []*cryptoKeyVersion
+	for _, version := range c.versions[cryptoKeyName] {
+		v := version.cryptoKeyVersion
+		versions = append(versions, &v)
+	}
+	return versions, nil
+}
+
+func (c *fakeClient) CreateCryptoKeyVersion(ctx context.Context, cryptoKeyName string) (*cryptoKeyVersion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.createVersion(cryptoKeyName)
+}
+
+func (c *fakeClient) GetCryptoKeyVersion(ctx context.Context, name string) (*cryptoKeyVersion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	version, err := c.findVersion(name)
+	if err != nil {
+		return nil, err
+	}
+	// key generation completes the first time the version is polled
+	if version.State == statePendingGeneration {
+		version.State = stateEnabled
+	}
+	v := version.cryptoKeyVersion
+	return &v, nil
+}
+
+func (c *fakeClient) DestroyCryptoKeyVersion(ctx context.Context, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	version, err := c.findVersion(name)
+	if err != nil {
+		return err
+	}
+	version.State = "DESTROY_SCHEDULED"
+	return nil
+}
+
+func (c *fakeClient) GetPublicKey(ctx context.Context, versionName string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	version, err := c.findVersion(versionName)
+	if err != nil {
+		return "", err
+	}
+	pkixData, err := x509.MarshalPKIXPublicKey(version.privateKey.Public())
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkixData})), nil
+}
+
+func (c *fakeClient) AsymmetricSign(ctx context.Context, versionName string, hashAlg crypto.Hash, digest []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	version, err := c.findVersion(versionName)
+	if err != nil {
+		return nil, err
+	}
+	if version.State != stateEnabled {
+		return nil, errs.New("version %q is not enabled", versionName)
+	}
+	return version.privateKey.Sign(rand.Reader, digest, hashAlg)
+}
+
+func (c *fakeClient) createVersion(cryptoKeyName string) (*cryptoKeyVersion, error) {
+	key := c.keys[cryptoKeyName]
+
+	var privateKey crypto.Signer
+	var err error
+	switch key.VersionTemplate.Algorithm {
+	case "EC_SIGN_P256_SHA256":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EC_SIGN_P384_SHA384":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "RSA_SIGN_PKCS1_2048_SHA256":
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, errs.New("unexpected algorithm %q", key.VersionTemplate.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	version := &fakeVersion{
+		cryptoKeyVersion: cryptoKeyVersion{
+			Name:      fmt.Sprintf("%s/cryptoKeyVersions/%d", cryptoKeyName, len(c.versions[cryptoKeyName])+1),
+			State:     statePendingGeneration,
+			Algorithm: key.VersionTemplate.Algorithm,
+		},
+		privateKey: privateKey,
+	}
+	c.versions[cryptoKeyName] = append(c.versions[cryptoKeyName], version)
+	v := version.cryptoKeyVersion
+	return &v, nil
+}
+
+func (c *fakeClient) findVersion(name string) (*fakeVersion, error) {
+	cryptoKeyName := name[:strings.Index(name, "/cryptoKeyVersions/")]
+	for _, version := range c.versions[cryptoKeyName] {
+		if version.Name == name {
+			return version, nil
+		}
+	}
+	return nil, &apiError{StatusCode: http.StatusNotFound, Status: "NOT_FOUND"}
+}
@@ -0,0 +1,330 @@
+package kmip
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"github.com/spiffe/spire/proto/server/keymanager"
+)
+
+type protocolVersion struct {
+	major int32
+	minor int32
+}
+
+func parseProtocolVersion(s string) (protocolVersion, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) == 2 {
+		major, majorErr := strconv.Atoi(parts[0])
+		minor, minorErr := strconv.Atoi(parts[1])
+		if majorErr == nil && minorErr == nil {
+			version := protocolVersion{major: int32(major), minor: int32(minor)}
+			// Sign was introduced in KMIP 1.2
+			if (version.major == 1 && version.minor >= 2 && version.minor <= 4) || (version.major == 2 && version.minor == 0) {
+				return version, nil
+			}
+		}
+	}
+	return protocolVersion{}, errs.New("unsupported protocol version %q; must be one of 1.2, 1.3, 1.4 or 2.0", s)
+}
+
+// client sends KMIP requests over a single connection, which is
+// re-established after any failure.
+type client struct {
+	dial     func() (net.Conn, error)
+	version  protocolVersion
+	username string
+	password string
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// CreateKeyPair creates a signing key pair, returning the unique identifiers
+// of the private and public keys
+func (c *client) CreateKeyPair(ctx context.Context, keyType keymanager.KeyType, name string) (string, string, error) {
+	algorithm, length, curve, err := keyParameters(keyType)
+	if err != nil {
+		return "", "", err
+	}
+
+	common := []item{
+		enumeration(tagCryptographicAlgorithm, algorithm),
+		integer(tagCryptographicLength, length),
+	}
+	if curve != 0 {
+		common = append(common, structure(tagCryptographicDomainParameters,
+			enumeration(tagRecommendedCurve, curve)))
+	}
+	private := []item{
+		keyName(name),
+		integer(tagCryptographicUsageMask, usageMaskSign),
+	}
+	public := []item{
+		keyName(name + "-public"),
+		integer(tagCryptographicUsageMask, usageMaskVerify),
+	}
+
+	var payload []item
+	if c.version.major >= 2 {
+		payload = []item{
+			structure(tagCommonAttributes, common...),
+			structure(tagPrivateKeyAttributes, private...),
+			structure(tagPublicKeyAttributes, public...),
+		}
+	} else {
+		payload = []item{
+			templateAttribute(tagCommonTemplateAttribute, common),
+			templateAttribute(tagPrivateKeyTemplateAttribute, private),
+			templateAttribute(tagPublicKeyTemplateAttribute, public),
+		}
+	}
+
+	resp, err := c.do(ctx, operationCreateKeyPair, payload...)
+	if err != nil {
+		return "", "", err
+	}
+	privateKeyID, err := resp.childText(tagPrivateKeyUniqueIdentifier)
+	if err != nil {
+		return "", "", err
+	}
+	publicKeyID, err := resp.childText(tagPublicKeyUniqueIdentifier)
+	if err != nil {
+		return "", "", err
+	}
+	return privateKeyID, publicKeyID, nil
+}
+
+// Activate activates an object so that it can be used for signing
+func (c *client) Activate(ctx context.Context, id string) error {
+	_, err := c.do(ctx, operationActivate, textString(tagUniqueIdentifier, id))
+	return err
+}
+
+// GetPublicKey returns the ASN.1 DER encoded PKIX public key of a public key
+// object
+func (c *client) GetPublicKey(ctx context.Context, id string) ([]byte, error) {
+	resp, err := c.do(ctx, operationGet,
+		textString(tagUniqueIdentifier, id),
+		enumeration(tagKeyFormatType, keyFormatTypeX509))
+	if err != nil {
+		return nil, err
+	}
+
+	objectType, err := resp.childEnum(tagObjectType)
+	if err != nil {
+		return nil, err
+	}
+	if objectType != objectTypePublicKey {
+		return nil, errs.New("object %q is not a public key", id)
+	}
+	publicKey, ok := resp.child(tagPublicKey)
+	if !ok {
+		return nil, errs.New("object %q is missing the public key", id)
+	}
+	keyBlock, ok := publicKey.child(tagKeyBlock)
+	if !ok {
+		return nil, errs.New("object %q is missing the key block", id)
+	}
+	keyFormatType, err := keyBlock.childEnum(tagKeyFormatType)
+	if err != nil {
+		return nil, err
+	}
+	if keyFormatType != keyFormatTypeX509 {
+		return nil, errs.New("object %q was returned in key format %#x instead of X.509", id, keyFormatType)
+	}
+	keyValue, ok := keyBlock.child(tagKeyValue)
+	if !ok {
+		return nil, errs.New("object %q is missing the key value", id)
+	}
+	// The key value is a byte string when wrapped, which is not requested
+	if keyValue.Type != typeStructure {
+		return nil, errs.New("object %q has an unexpected key value", id)
+	}
+	return keyValue.childBytes(tagKeyMaterial)
+}
+
+// Sign signs data with a private key. The data is signed as-is (i.e. it
+// is not hashed by the server).
+func (c *client) Sign(ctx context.Context, id string, algorithm uint32, paddingMethod uint32, data []byte) ([]byte, error) {
+	var params []item
+	if paddingMethod != 0 {
+		params = append(params, enumeration(tagPaddingMethod, paddingMethod))
+	}
+	params = append(params, enumeration(tagCryptographicAlgorithm, algorithm))
+
+	resp, err := c.do(ctx, operationSign,
+		textString(tagUniqueIdentifier, id),
+		structure(tagCryptographicParameters, params...),
+		byteString(tagData, data))
+	if err != nil {
+		return nil, err
+	}
+	return resp.childBytes(tagSignatureData)
+}
+
+// Revoke revokes an object so that it can be destroyed
+func (c *client) Revoke(ctx context.Context, id string) error {
+	_, err := c.do(ctx, operationRevoke,
+		textString(tagUniqueIdentifier, id),
+		structure(tagRevocationReason,
+			enumeration(tagRevocationReasonCode, revocationReasonCessationOfOperation)))
+	return err
+}
+
+// Destroy destroys an object
+func (c *client) Destroy(ctx context.Context, id string) error {
+	_, err := c.do(ctx, operationDestroy, textString(tagUniqueIdentifier, id))
+	return err
+}
+
+func (c *client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// do sends a request with a single batch item and returns the response
+// payload
+func (c *client) do(ctx context.Context, operation uint32, payload ...item) (item, error) {
+	header := []item{
+		structure(tagProtocolVersion,
+			integer(tagProtocolVersionMajor, c.version.major),
+			integer(tagProtocolVersionMinor, c.version.minor)),
+	}
+	if c.username != "" {
+		header = append(header, structure(tagAuthentication,
+			structure(tagCredential,
+				enumeration(tagCredentialType, credentialTypeUsernameAndPassword),
+				structure(tagCredentialValue,
+					textString(tagUsername, c.username),
+					textString(tagPassword, c.password)))))
+	}
+	header = append(header, integer(tagBatchCount, 1))
+
+	request := structure(tagRequestMessage,
+		structure(tagRequestHeader, header...),
+		structure(tagBatchItem,
+			enumeration(tagOperation, operation),
+			structure(tagRequestPayload, payload...)))
+
+	response, err := c.roundTrip(ctx, request)
+	if err != nil {
+		return item{}, err
+	}
+	if response.Tag != tagResponseMessage {
+		return item{}, errs.New("unexpected response tag %#06x", response.Tag)
+	}
+	batchItem, ok := response.child(tagBatchItem)
+	if !ok {
+		return item{}, errs.New("response is missing the batch item")
+	}
+	resultStatus, err := batchItem.childEnum(tagResultStatus)
+	if err != nil {
+		return item{}, err
+	}
+	if resultStatus != resultStatusSuccess {
+		resultReason, _ := batchItem.childEnum(tagResultReason)
+		resultMessage, _ := batchItem.childText(tagResultMessage)
+		return item{}, errs.New("operation failed with status %#x and reason %#x: %s", resultStatus, resultReason, resultMessage)
+	}
+	responsePayload, _ := batchItem.child(tagResponsePayload)
+	return responsePayload, nil
+}
+
+func (c *client) roundTrip(ctx context.Context, request item) (item, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := c.dial()
+		if err != nil {
+			return item{}, errs.New("unable to connect: %v", err)
+		}
+		c.conn = conn
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout)
+	}
+	c.conn.SetDeadline(deadline)
+
+	response, err := func() (item, error) {
+		if _, err := c.conn.Write(request.Marshal()); err != nil {
+			return item{}, err
+		}
+		return readItem(c.conn)
+	}()
+	if err != nil {
+		// the connection is in an unknown state
+		c.conn.Close()
+		c.conn = nil
+		return item{}, errs.Wrap(err)
+	}
+	return response, nil
+}
+
+func keyParameters(keyType keymanager.KeyType) (algorithm uint32, length int32, curve uint32, err error) {
+	switch keyType {
+	case keymanager.KeyType_EC_P256:
+		return cryptographicAlgorithmECDSA, 256, recommendedCurveP256, nil
+	case keymanager.KeyType_EC_P384:
+		return cryptographicAlgorithmECDSA, 384, recommendedCurveP384, nil
+	case keymanager.KeyType_RSA_2048:
+		return cryptographicAlgorithmRSA, 2048, 0, nil
+	case keymanager.KeyType_RSA_4096:
+		return cryptographicAlgorithmRSA, 4096, 0, nil
+	default:
+		return 0, 0, 0, errs.New("unsupported key type %q", keyType)
+	}
+}
+
+func keyName(name string) item {
+	return structure(tagName,
+		textString(tagNameValue, name),
+		enumeration(tagNameType, nameTypeUninterpretedTextString))
+}
+
+// templateAttribute wraps attributes in a KMIP 1.x template-attribute, where
+// each attribute is identified by name
+func templateAttribute(tag uint32, attributes []item) item {
+	var children []item
+	for _, attribute := range attributes {
+		value := attribute
+		value.Tag = tagAttributeValue
+		children = append(children, structure(tagAttribute,
+			textString(tagAttributeName, attributeName(attribute.Tag)),
+			value))
+	}
+	return structure(tag, children...)
+}
+
+func attributeName(tag uint32) string {
+	switch tag {
+	case tagCryptographicAlgorithm:
+		return "Cryptographic Algorithm"
+	case tagCryptographicLength:
+		return "Cryptographic Length"
+	case tagCryptographicDomainParameters:
+		return "Cryptographic Domain Parameters"
+	case tagCryptographicUsageMask:
+		return "Cryptographic Usage Mask"
+	case tagName:
+		return "Name"
+	default:
+		panic(errs.New("no attribute name for tag %#06x", tag))
+	}
+}
@@ -0,0 +1,562 @@
+package kmip
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/hcl"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/diskutil"
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/base"
+	"github.com/zeebo/errs"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+)
+
+const (
+	defaultProtocolVersion = "1.4"
+	defaultKeyNamePrefix   = "spire-"
+	defaultTimeout         = 30 * time.Second
+)
+
+var (
+	kmipError = errs.Class("keymanager(kmip)")
+
+	// digestInfoPrefixes are the ASN.1 DER prefixes of the PKCS #1 v1.5
+	// DigestInfo structure for each hash (see RFC 8017, section 9.2)
+	digestInfoPrefixes = map[crypto.Hash][]byte{
+		crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+		crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+		crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+	}
+)
+
+type configuration struct {
+	// Address is the host:port of the KMIP server
+	Address string `hcl:"address"`
+
+	// ServerName optionally overrides the name used to verify the server
+	// certificate
+	ServerName string `hcl:"server_name"`
+
+	// CABundlePath is the path to the CA certificates used to verify the
+	// KMIP server. If unset, the system roots are used.
+	CABundlePath string `hcl:"ca_bundle_path"`
+
+	// CertPath and KeyPath are the client certificate and private key used
+	// to authenticate to the KMIP server
+	CertPath string `hcl:"cert_path"`
+	KeyPath  string `hcl:"key_path"`
+
+	// Username and Password are optional credentials sent with each request
+	Username string `hcl:"username"`
+	Password string `hcl:"password"`
+
+	// ProtocolVersion is the KMIP protocol version
+	ProtocolVersion string `hcl:"protocol_version"`
+
+	// KeysPath is where the mapping from key id to KMIP object identifiers
+	// is stored
+	KeysPath string `hcl:"keys_path"`
+
+	// KeyNamePrefix is prepended to SPIRE key ids to name the KMIP objects
+	KeyNamePrefix string `hcl:"key_name_prefix"`
+
+	// Timeout bounds each request to the KMIP server
+	Timeout string `hcl:"timeout"`
+}
+
+type keyEntry struct {
+	privateKeyID string
+	publicKeyID  string
+	publicKey    *keymanager.PublicKey
+}
+
+type KeyManager struct {
+	// log is set by the catalog before the plugin is configured
+	log logrus.FieldLogger
+
+	// generateMu serializes key generation so that rotations of the same
+	// key do not race each other
+	generateMu sync.Mutex
+
+	mu      sync.RWMutex
+	client  *client
+	config  *configuration
+	entries map[string]*keyEntry
+
+	hooks struct {
+		dial func(config *configuration, tlsConfig *tls.Config) (net.Conn, error)
+		now  func() time.Time
+	}
+}
+
+var _ keymanager.Plugin = (*KeyManager)(nil)
+
+func New() *KeyManager {
+	p := &KeyManager{
+		log:     logrus.StandardLogger(),
+		entries: make(map[string]*keyEntry),
+	}
+	p.hooks.dial = dial
+	p.hooks.now = time.Now
+	return p
+}
+
+func (p *KeyManager) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, kmipError.New("unable to decode configuration: %v", err)
+	}
+
+	if config.Address == "" {
+		return nil, kmipError.New("address is required")
+	}
+	if config.CertPath == "" || config.KeyPath == "" {
+		return nil, kmipError.New("cert_path and key_path are required")
+	}
+	if config.KeysPath == "" {
+		return nil, kmipError.New("keys_path is required")
+	}
+	if config.Password != "" && config.Username == "" {
+		return nil, kmipError.New("password requires username")
+	}
+	if config.ProtocolVersion == "" {
+		config.ProtocolVersion = defaultProtocolVersion
+	}
+	version, err := parseProtocolVersion(config.ProtocolVersion)
+	if err != nil {
+		return nil, kmipError.Wrap(err)
+	}
+	if config.KeyNamePrefix == "" {
+		config.KeyNamePrefix = defaultKeyNamePrefix
+	}
+	timeout := defaultTimeout
+	if config.Timeout != "" {
+		timeout, err = time.ParseDuration(config.Timeout)
+		if err != nil {
+			return nil, kmipError.New("invalid timeout: %v", err)
+		}
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{
+		dial: func() (net.Conn, error) {
+			return p.hooks.dial(config, tlsConfig)
+		},
+		version:  version,
+		username: config.Username,
+		password: config.Password,
+		timeout:  timeout,
+	}
+
+	entries, err := loadEntries(ctx, c, config.KeysPath)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	p.generateMu.Lock()
+	defer p.generateMu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client != nil {
+		p.client.Close()
+	}
+	p.client = c
+	p.config = config
+	p.entries = entries
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+// SetLogger sets the logger the plugin logs to
+func (p *KeyManager) SetLogger(log logrus.FieldLogger) {
+	p.log = log
+}
+
+func (p *KeyManager) GetPluginInfo(ctx context.Context, req *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *KeyManager) GenerateKey(ctx context.Context, req *keymanager.GenerateKeyRequest) (*keymanager.GenerateKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, kmipError.New("key id is required")
+	}
+	if req.KeyType == keymanager.KeyType_UNSPECIFIED_KEY_TYPE {
+		return nil, kmipError.New("key type is required")
+	}
+	if _, _, _, err := keyParameters(req.KeyType); err != nil {
+		return nil, kmipError.Wrap(err)
+	}
+
+	p.generateMu.Lock()
+	defer p.generateMu.Unlock()
+
+	c, config, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// Names carry the creation time so that they stay unique across
+	// rotations of the same key
+	name := fmt.Sprintf("%s%s-%d", config.KeyNamePrefix, req.KeyId, p.hooks.now().Unix())
+	privateKeyID, publicKeyID, err := c.CreateKeyPair(ctx, req.KeyType, name)
+	if err != nil {
+		return nil, kmipError.New("unable to generate key %q: %v", req.KeyId, err)
+	}
+
+	newEntry, err := func() (*keyEntry, error) {
+		if err := c.Activate(ctx, privateKeyID); err != nil {
+			return nil, kmipError.New("unable to activate key %q: %v", req.KeyId, err)
+		}
+		return makeKeyEntry(ctx, c, req.KeyId, privateKeyID, publicKeyID)
+	}()
+	if err != nil {
+		p.destroyKeyPair(ctx, c, req.KeyId, privateKeyID, publicKeyID)
+		return nil, err
+	}
+	if newEntry.publicKey.Type != req.KeyType {
+		p.destroyKeyPair(ctx, c, req.KeyId, privateKeyID, publicKeyID)
+		return nil, kmipError.New("KMIP server generated a %s key for %q; expected %s", newEntry.publicKey.Type, req.KeyId, req.KeyType)
+	}
+
+	p.mu.Lock()
+	oldEntry := p.entries[req.KeyId]
+	p.entries[req.KeyId] = newEntry
+	err = writeEntries(config.KeysPath, p.entries)
+	if err != nil {
+		if oldEntry != nil {
+			p.entries[req.KeyId] = oldEntry
+		} else {
+			delete(p.entries, req.KeyId)
+		}
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		p.destroyKeyPair(ctx, c, req.KeyId, privateKeyID, publicKeyID)
+		return nil, err
+	}
+
+	// The previous key is no longer used. Failure to destroy it is not fatal
+	// since the new key is already in use.
+	if oldEntry != nil {
+		p.destroyKeyPair(ctx, c, req.KeyId, oldEntry.privateKeyID, oldEntry.publicKeyID)
+	}
+
+	return &keymanager.GenerateKeyResponse{
+		PublicKey: clonePublicKey(newEntry.publicKey),
+	}, nil
+}
+
+func (p *KeyManager) GetPublicKey(ctx context.Context, req *keymanager.GetPublicKeyRequest) (*keymanager.GetPublicKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, kmipError.New("key id is required")
+	}
+
+	resp := new(keymanager.GetPublicKeyResponse)
+	if entry := p.getEntry(req.KeyId); entry != nil {
+		resp.PublicKey = clonePublicKey(entry.publicKey)
+	}
+	return resp, nil
+}
+
+func (p *KeyManager) GetPublicKeys(ctx context.Context, req *keymanager.GetPublicKeysRequest) (*keymanager.GetPublicKeysResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	resp := new(keymanager.GetPublicKeysResponse)
+	for _, entry := range p.entries {
+		resp.PublicKeys = append(resp.PublicKeys, clonePublicKey(entry.publicKey))
+	}
+	sort.Slice(resp.PublicKeys, func(i, j int) bool {
+		return resp.PublicKeys[i].Id < resp.PublicKeys[j].Id
+	})
+	return resp, nil
+}
+
+func (p *KeyManager) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error) {
+	if req.KeyId == "" {
+		return nil, kmipError.New("key id is required")
+	}
+	if req.SignerOpts == nil {
+		return nil, kmipError.New("signer opts is required")
+	}
+
+	var hashAlgorithm keymanager.HashAlgorithm
+	switch opts := req.SignerOpts.(type) {
+	case *keymanager.SignDataRequest_HashAlgorithm:
+		hashAlgorithm = opts.HashAlgorithm
+	case *keymanager.SignDataRequest_PssOptions:
+		// Signing a digest with PSS would require the server to support
+		// signing pre-hashed data, which KMIP does not provide for.
+		return nil, kmipError.New("PSS signing is not supported")
+	default:
+		return nil, kmipError.New("unsupported signer opts type %T", opts)
+	}
+	if hashAlgorithm == keymanager.HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM {
+		return nil, kmipError.New("hash algorithm is required")
+	}
+
+	c, _, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := p.getEntry(req.KeyId)
+	if entry == nil {
+		return nil, kmipError.New("no such key %q", req.KeyId)
+	}
+
+	hash := crypto.Hash(hashAlgorithm)
+	if len(req.Data) != hash.Size() {
+		return nil, kmipError.New("data length %d does not match %s digest size", len(req.Data), hashAlgorithm)
+	}
+
+	// The digest is signed as-is: no hashing algorithm is sent, so the
+	// server does not hash it again. RSA signatures require the DigestInfo
+	// to be formed here.
+	var algorithm, paddingMethod uint32
+	data := req.Data
+	if isECKeyType(entry.publicKey.Type) {
+		algorithm = cryptographicAlgorithmECDSA
+	} else {
+		prefix, ok := digestInfoPrefixes[hash]
+		if !ok {
+			return nil, kmipError.New("unsupported hash algorithm %s", hashAlgorithm)
+		}
+		algorithm = cryptographicAlgorithmRSA
+		paddingMethod = paddingMethodPKCS1v15
+		data = append(append([]byte(nil), prefix...), req.Data...)
+	}
+
+	signature, err := c.Sign(ctx, entry.privateKeyID, algorithm, paddingMethod, data)
+	if err != nil {
+		return nil, kmipError.New("keypair %q signing operation failed: %v", req.KeyId, err)
+	}
+
+	if algorithm == cryptographicAlgorithmECDSA {
+		signature, err = normalizeECDSASignature(entry.publicKey.Type, signature)
+		if err != nil {
+			return nil, kmipError.New("keypair %q returned an invalid signature: %v", req.KeyId, err)
+		}
+	}
+
+	return &keymanager.SignDataResponse{
+		Signature: signature,
+	}, nil
+}
+
+// SignDataBatch signs each request in turn since each request is sent to the KMIP server as its own Sign operation.
+func (p *KeyManager) SignDataBatch(ctx context.Context, req *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	return base.SignDataEach(ctx, req, p.SignData)
+}
+
+func (p *KeyManager) getClient() (*client, *configuration, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.client == nil {
+		return nil, nil, kmipError.New("not configured")
+	}
+	return p.client, p.config, nil
+}
+
+func (p *KeyManager) getEntry(keyID string) *keyEntry {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.entries[keyID]
+}
+
+func newTLSConfig(config *configuration) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(config.CertPath, config.KeyPath)
+	if err != nil {
+		return nil, kmipError.New("unable to load client certificate: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ServerName:   config.ServerName,
+		// KMIP requires TLS 1.2 or newer
+		MinVersion: tls.VersionTLS12,
+	}
+	if config.CABundlePath != "" {
+		roots, err := util.LoadCertPool(config.CABundlePath)
+		if err != nil {
+			return nil, kmipError.New("unable to load CA bundle: %v", err)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	return tlsConfig, nil
+}
+
+func dial(config *configuration, tlsConfig *tls.Config) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: defaultTimeout}
+	return tls.DialWithDialer(dialer, "tcp", config.Address, tlsConfig)
+}
+
+// destroyKeyPair revokes and destroys the objects of a key pair. Failures are
+// logged since the objects are no longer used.
+func (p *KeyManager) destroyKeyPair(ctx context.Context, c *client, keyID, privateKeyID, publicKeyID string) {
+	if err := c.Revoke(ctx, privateKeyID); err != nil {
+		p.log.Warnf("Unable to revoke private key %q of %q: %v", privateKeyID, keyID, err)
+	}
+	for _, id := range []string{privateKeyID, publicKeyID} {
+		if err := c.Destroy(ctx, id); err != nil {
+			p.log.Warnf("Unable to destroy object %q of %q: %v", id, keyID, err)
+		}
+	}
+}
+
+func makeKeyEntry(ctx context.Context, c *client, keyID, privateKeyID, publicKeyID string) (*keyEntry, error) {
+	pkixData, err := c.GetPublicKey(ctx, publicKeyID)
+	if err != nil {
+		return nil, kmipError.New("unable to get public key for %q: %v", keyID, err)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(pkixData)
+	if err != nil {
+		return nil, kmipError.New("unable to parse public key for %q: %v", keyID, err)
+	}
+	keyType, err := keyTypeFromPublicKey(publicKey)
+	if err != nil {
+		return nil, kmipError.New("unable to load key %q: %v", keyID, err)
+	}
+	return &keyEntry{
+		privateKeyID: privateKeyID,
+		publicKeyID:  publicKeyID,
+		publicKey: &keymanager.PublicKey{
+			Id:       keyID,
+			Type:     keyType,
+			PkixData: pkixData,
+		},
+	}, nil
+}
+
+type entryData struct {
+	PrivateKeyID string `json:"private_key_id"`
+	PublicKeyID  string `json:"public_key_id"`
+}
+
+type entriesData struct {
+	Keys map[string]entryData `json:"keys"`
+}
+
+func loadEntries(ctx context.Context, c *client, path string) (map[string]*keyEntry, error) {
+	entries := make(map[string]*keyEntry)
+
+	jsonBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, kmipError.New("unable to read keys: %v", err)
+	}
+
+	data := new(entriesData)
+	if err := json.Unmarshal(jsonBytes, data); err != nil {
+		return nil, kmipError.New("unable to decode keys JSON: %v", err)
+	}
+
+	for id, keyData := range data.Keys {
+		entry, err := makeKeyEntry(ctx, c, id, keyData.PrivateKeyID, keyData.PublicKeyID)
+		if err != nil {
+			return nil, err
+		}
+		entries[id] = entry
+	}
+	return entries, nil
+}
+
+func writeEntries(path string, entries map[string]*keyEntry) error {
+	data := &entriesData{
+		Keys: make(map[string]entryData),
+	}
+	for id, entry := range entries {
+		data.Keys[id] = entryData{
+			PrivateKeyID: entry.privateKeyID,
+			PublicKeyID:  entry.publicKeyID,
+		}
+	}
+
+	jsonBytes, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return kmipError.New("unable to marshal entries: %v", err)
+	}
+
+	if err := diskutil.AtomicWriteFile(path, jsonBytes, 0644); err != nil {
+		return kmipError.New("unable to write entries: %v", err)
+	}
+
+	return nil
+}
+
+// normalizeECDSASignature returns the signature ASN.1 DER encoded. Some
+// servers return the raw concatenation of r and s instead.
+func normalizeECDSASignature(keyType keymanager.KeyType, signature []byte) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+	if rest, err := asn1.Unmarshal(signature, &sig); err == nil && len(rest) == 0 {
+		return signature, nil
+	}
+
+	size := 32
+	if keyType == keymanager.KeyType_EC_P384 {
+		size = 48
+	}
+	if len(signature) != 2*size {
+		return nil, errs.New("signature is neither ASN.1 DER encoded nor %d bytes long", 2*size)
+	}
+	sig.R = new(big.Int).SetBytes(signature[:size])
+	sig.S = new(big.Int).SetBytes(signature[size:])
+	return asn1.Marshal(sig)
+}
+
+func keyTypeFromPublicKey(publicKey crypto.PublicKey) (keymanager.KeyType, error) {
+	switch publicKey := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		switch publicKey.Curve.Params().BitSize {
+		case 256:
+			return keymanager.KeyType_EC_P256, nil
+		case 384:
+			return keymanager.KeyType_EC_P384, nil
+		}
+	case *rsa.PublicKey:
+		switch publicKey.N.BitLen() {
+		case 2048:
+			return keymanager.KeyType_RSA_2048, nil
+		case 4096:
+			return keymanager.KeyType_RSA_4096, nil
+		}
+	}
+	return keymanager.KeyType_UNSPECIFIED_KEY_TYPE, errs.New("unsupported public key type %T", publicKey)
+}
+
+func isECKeyType(keyType keymanager.KeyType) bool {
+	return keyType == keymanager.KeyType_EC_P256 || keyType == keymanager.KeyType_EC_P384
+}
+
+func clonePublicKey(publicKey *keymanager.PublicKey) *keymanager.PublicKey {
+	return proto.Clone(publicKey).(*keymanager.PublicKey)
+}
@@ -0,0 +1,787 @@
+package kmip
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/x509util"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestKeyManager(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	dir      string
+	keysPath string
+	server   *fakeServer
+	m        *keymanager.BuiltIn
+}
+
+func (s *Suite) SetupTest() {
+	dir, err := ioutil.TempDir("", "keymanager-kmip-test")
+	s.Require().NoError(err)
+	s.dir = dir
+	s.keysPath = filepath.Join(dir, "keys.json")
+	writeClientCertificate(s.T(), dir)
+	s.server = newFakeServer(protocolVersion{major: 1, minor: 4})
+	s.m = s.newKeyManager("")
+}
+
+func (s *Suite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *Suite) newKeyManager(extraConfig string) *keymanager.BuiltIn {
+	p := New()
+	p.hooks.dial = func(*configuration, *tls.Config) (net.Conn, error) {
+		return s.server.connect(), nil
+	}
+	p.hooks.now = func() time.Time {
+		return time.Unix(1000, 0)
+	}
+	resp, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: s.config(extraConfig),
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&spi.ConfigureResponse{}, resp)
+	return keymanager.NewBuiltIn(p)
+}
+
+func (s *Suite) config(extraConfig string) string {
+	return fmt.Sprintf(`
+		address = "kmip.example.org:5696"
+		cert_path = %q
+		key_path = %q
+		keys_path = %q
+		%s
+	`, filepath.Join(s.dir, "client.pem"), filepath.Join(s.dir, "client.key"), s.keysPath, extraConfig)
+}
+
+func (s *Suite) TestConfigureErrors() {
+	certPath := filepath.Join(s.dir, "client.pem")
+	keyPath := filepath.Join(s.dir, "client.key")
+	for _, tt := range []struct {
+		config string
+		err    string
+	}{
+		{
+			config: ``,
+			err:    "keymanager(kmip): address is required",
+		},
+		{
+			config: `address = "kmip:5696" keys_path = "keys.json"`,
+			err:    "keymanager(kmip): cert_path and key_path are required",
+		},
+		{
+			config: fmt.Sprintf(`address = "kmip:5696" cert_path = %q key_path = %q`, certPath, keyPath),
+			err:    "keymanager(kmip): keys_path is required",
+		},
+		{
+			config: fmt.Sprintf(`address = "kmip:5696" cert_path = %q key_path = %q keys_path = "keys.json" password = "secret"`, certPath, keyPath),
+			err:    "keymanager(kmip): password requires username",
+		},
+		{
+			config: fmt.Sprintf(`address = "kmip:5696" cert_path = %q key_path = %q keys_path = "keys.json" protocol_version = "1.1"`, certPath, keyPath),
+			err:    `keymanager(kmip): unsupported protocol version "1.1"; must be one of 1.2, 1.3, 1.4 or 2.0`,
+		},
+		{
+			config: fmt.Sprintf(`address = "kmip:5696" cert_path = %q key_path = %q keys_path = "keys.json" timeout = "soon"`, certPath, keyPath),
+			err:    `keymanager(kmip): invalid timeout: time: invalid duration "soon"`,
+		},
+		{
+			config: `address = "kmip:5696" cert_path = "missing.pem" key_path = "missing.key" keys_path = "keys.json"`,
+			err:    "keymanager(kmip): unable to load client certificate: open missing.pem: no such file or directory",
+		},
+	} {
+		p := New()
+		_, err := p.Configure(ctx, &spi.ConfigureRequest{
+			Configuration: tt.config,
+		})
+		s.Require().EqualError(err, tt.err, "config: %s", tt.config)
+	}
+}
+
+func (s *Suite) TestGenerateKeyBeforeConfigure() {
+	p := New()
+	resp, err := p.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, "keymanager(kmip): not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestGenerateKeyUnsupportedKeyType() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_RSA_1024,
+	})
+	s.Require().EqualError(err, `keymanager(kmip): unsupported key type "RSA_1024"`)
+
+	_, err = s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_ED25519,
+	})
+	s.Require().EqualError(err, `keymanager(kmip): unsupported key type "ED25519"`)
+}
+
+func (s *Suite) TestGenerateKey() {
+	for _, keyType := range []keymanager.KeyType{
+		keymanager.KeyType_EC_P256,
+		keymanager.KeyType_EC_P384,
+		keymanager.KeyType_RSA_2048,
+		keymanager.KeyType_RSA_4096,
+	} {
+		resp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+			KeyId:   keyType.String(),
+			KeyType: keyType,
+		})
+		s.Require().NoError(err)
+		s.Require().Equal(keyType.String(), resp.PublicKey.Id)
+		s.Require().Equal(keyType, resp.PublicKey.Type)
+		_, err = x509.ParsePKIXPublicKey(resp.PublicKey.PkixData)
+		s.Require().NoError(err)
+
+		getResp, err := s.m.GetPublicKey(ctx, &keymanager.GetPublicKeyRequest{
+			KeyId: keyType.String(),
+		})
+		s.Require().NoError(err)
+		s.Require().Equal(resp.PublicKey, getResp.PublicKey)
+	}
+
+	s.Require().Equal([]string{
+		"spire-EC_P256-1000",
+		"spire-EC_P256-1000-public",
+		"spire-EC_P384-1000",
+		"spire-EC_P384-1000-public",
+		"spire-RSA_2048-1000",
+		"spire-RSA_2048-1000-public",
+		"spire-RSA_4096-1000",
+		"spire-RSA_4096-1000-public",
+	}, s.server.names())
+}
+
+func (s *Suite) TestGenerateKeyDestroysPreviousKey() {
+	resp1, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	s.Require().Len(s.server.names(), 2)
+
+	resp2, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	s.Require().NotEqual(resp1.PublicKey.PkixData, resp2.PublicKey.PkixData)
+	s.Require().Len(s.server.names(), 2)
+}
+
+func (s *Suite) TestGenerateKeyFailure() {
+	s.server.setFailure(operationCreateKeyPair)
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, `keymanager(kmip): unable to generate key "KEY": operation failed with status 0x1 and reason 0x100: injected failure`)
+}
+
+func (s *Suite) TestGenerateKeyCleansUpAfterActivationFailure() {
+	s.server.setFailure(operationActivate)
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, `keymanager(kmip): unable to activate key "KEY": operation failed with status 0x1 and reason 0x100: injected failure`)
+	s.Require().Empty(s.server.names())
+}
+
+func (s *Suite) TestKeysAreLoadedOnConfigure() {
+	a, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "A",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	b, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "B",
+		KeyType: keymanager.KeyType_RSA_2048,
+	})
+	s.Require().NoError(err)
+
+	m := s.newKeyManager("")
+	resp, err := m.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal([]*keymanager.PublicKey{a.PublicKey, b.PublicKey}, resp.PublicKeys)
+}
+
+func (s *Suite) TestConfigureFailsIfKeyIsMissing() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	s.server = newFakeServer(protocolVersion{major: 1, minor: 4})
+
+	p := New()
+	p.hooks.dial = func(*configuration, *tls.Config) (net.Conn, error) {
+		return s.server.connect(), nil
+	}
+	_, err = p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: s.config(""),
+	})
+	s.Require().EqualError(err, `keymanager(kmip): unable to get public key for "KEY": operation failed with status 0x1 and reason 0x1: object "2" not found`)
+}
+
+func (s *Suite) TestSignData() {
+	s.testSignData(s.m, keymanager.KeyType_EC_P256, x509.ECDSAWithSHA256)
+	s.testSignData(s.m, keymanager.KeyType_EC_P384, x509.ECDSAWithSHA384)
+	s.testSignData(s.m, keymanager.KeyType_RSA_2048, x509.SHA256WithRSA)
+	s.testSignData(s.m, keymanager.KeyType_RSA_4096, x509.SHA512WithRSA)
+}
+
+func (s *Suite) TestSignDataWithRawECDSASignatures() {
+	s.server.rawECDSA = true
+	s.testSignData(s.m, keymanager.KeyType_EC_P256, x509.ECDSAWithSHA256)
+	s.testSignData(s.m, keymanager.KeyType_EC_P384, x509.ECDSAWithSHA384)
+}
+
+func (s *Suite) TestSignDataWithCredentials() {
+	s.server.username = "spire"
+	s.server.password = "secret"
+
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, `keymanager(kmip): unable to generate key "KEY": operation failed with status 0x1 and reason 0x3: invalid credentials`)
+
+	m := s.newKeyManager(`username = "spire" password = "secret"`)
+	s.testSignData(m, keymanager.KeyType_EC_P256, x509.ECDSAWithSHA256)
+}
+
+func (s *Suite) TestSignDataWithKMIP20() {
+	s.server = newFakeServer(protocolVersion{major: 2, minor: 0})
+	m := s.newKeyManager(`protocol_version = "2.0"`)
+	s.testSignData(m, keymanager.KeyType_EC_P256, x509.ECDSAWithSHA256)
+	s.testSignData(m, keymanager.KeyType_RSA_2048, x509.SHA256WithRSA)
+}
+
+func (s *Suite) TestSignDataRejectsPSS() {
+	_, err := s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		SignerOpts: &keymanager.SignDataRequest_PssOptions{
+			PssOptions: &keymanager.PSSOptions{
+				HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+			},
+		},
+	})
+	s.Require().EqualError(err, "keymanager(kmip): PSS signing is not supported")
+}
+
+func (s *Suite) TestSignDataNoKey() {
+	_, err := s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+		},
+	})
+	s.Require().EqualError(err, `keymanager(kmip): no such key "KEY"`)
+}
+
+func (s *Suite) TestSignDataWrongDigestLength() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	_, err = s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		Data:  []byte("DATA"),
+		SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+		},
+	})
+	s.Require().EqualError(err, "keymanager(kmip): data length 4 does not match SHA256 digest size")
+}
+
+func (s *Suite) TestSignDataFailure() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	s.server.setFailure(operationSign)
+	_, err = s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		Data:  make([]byte, 32),
+		SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+		},
+	})
+	s.Require().EqualError(err, `keymanager(kmip): keypair "KEY" signing operation failed: operation failed with status 0x1 and reason 0x100: injected failure`)
+}
+
+func (s *Suite) testSignData(m *keymanager.BuiltIn, keyType keymanager.KeyType, signatureAlgorithm x509.SignatureAlgorithm) {
+	generateResp, err := m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keyType,
+	})
+	s.Require().NoError(err)
+	publicKey, err := x509.ParsePKIXPublicKey(generateResp.PublicKey.PkixData)
+	s.Require().NoError(err)
+
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(1),
+		NotAfter:           time.Now().Add(time.Minute),
+		SignatureAlgorithm: signatureAlgorithm,
+	}
+
+	cert, err := x509util.CreateCertificate(ctx, m, template, template, "KEY", publicKey)
+	s.Require().NoError(err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots: roots,
+	})
+	s.Require().NoError(err)
+}
+
+func TestTTLVEncoding(t *testing.T) {
+	// examples from the KMIP 1.4 specification, section 9.1.2
+	for _, tt := range []struct {
+		item    item
+		encoded string
+	}{
+		{
+			item:    integer(0x420020, 8),
+			encoded: "42002002000000040000000800000000",
+		},
+		{
+			item:    enumeration(0x420020, 255),
+			encoded: "4200200500000004000000FF00000000",
+		},
+		{
+			item:    textString(0x420020, "Hello World"),
+			encoded: "420020070000000B48656C6C6F20576F726C640000000000",
+		},
+		{
+			item:    byteString(0x420020, []byte{1, 2, 3}),
+			encoded: "42002008000000030102030000000000",
+		},
+		{
+			item:    structure(0x420020, enumeration(0x420004, 254), integer(0x420005, 255)),
+			encoded: "42002001000000204200040500000004000000FE000000004200050200000004000000FF00000000",
+		},
+	} {
+		encoded := fmt.Sprintf("%X", tt.item.Marshal())
+		require.Equal(t, tt.encoded, encoded)
+
+		decoded, rest, err := unmarshalItem(tt.item.Marshal())
+		require.NoError(t, err)
+		require.Empty(t, rest)
+		require.Equal(t, tt.item, decoded)
+	}
+}
+
+func TestTTLVDecodingErrors(t *testing.T) {
+	for _, tt := range []struct {
+		data []byte
+		err  string
+	}{
+		{
+			data: []byte{0x42, 0x00, 0x20, 0x02},
+			err:  "truncated TTLV header",
+		},
+		{
+			data: []byte{0x42, 0x00, 0x20, 0x02, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00},
+			err:  "truncated value for tag 0x420020",
+		},
+		{
+			data: []byte{0x42, 0x00, 0x20, 0x02, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			err:  "invalid length 3 for tag 0x420020",
+		},
+		{
+			data: []byte{0x42, 0x00, 0x20, 0x0F, 0x00, 0x00, 0x00, 0x00},
+			err:  "unknown type 0xf for tag 0x420020",
+		},
+	} {
+		_, _, err := unmarshalItem(tt.data)
+		require.EqualError(t, err, tt.err)
+	}
+}
+
+type fakeObject struct {
+	name    string
+	public  crypto.PublicKey
+	private crypto.Signer
+	active  bool
+}
+
+// fakeServer is an in-memory KMIP server supporting the operations used by
+// the key manager
+type fakeServer struct {
+	version  protocolVersion
+	username string
+	password string
+	rawECDSA bool
+
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	nextID  int
+	failure uint32
+}
+
+func newFakeServer(version protocolVersion) *fakeServer {
+	return &fakeServer{
+		version: version,
+		objects: make(map[string]*fakeObject),
+	}
+}
+
+func (s *fakeServer) connect() net.Conn {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		for {
+			request, err := readItem(server)
+			if err != nil {
+				return
+			}
+			if _, err := server.Write(s.handle(request).Marshal()); err != nil {
+				return
+			}
+		}
+	}()
+	return client
+}
+
+func (s *fakeServer) setFailure(operation uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failure = operation
+}
+
+func (s *fakeServer) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for _, object := range s.objects {
+		names = append(names, object.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *fakeServer) handle(request item) item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batchItem, _ := request.child(tagBatchItem)
+	operation, _ := batchItem.childEnum(tagOperation)
+	payload, err := s.handleOperation(request, operation)
+
+	var result []item
+	result = append(result, enumeration(tagOperation, operation))
+	if err != nil {
+		result = append(result,
+			enumeration(tagResultStatus, 1),
+			enumeration(tagResultReason, err.reason),
+			textString(tagResultMessage, err.message))
+	} else {
+		result = append(result,
+			enumeration(tagResultStatus, resultStatusSuccess),
+			structure(tagResponsePayload, payload...))
+	}
+
+	return structure(tagResponseMessage,
+		structure(tagResponseHeader,
+			structure(tagProtocolVersion,
+				integer(tagProtocolVersionMajor, s.version.major),
+				integer(tagProtocolVersionMinor, s.version.minor)),
+			item{Tag: tagTimeStamp, Type: typeDateTime, Value: time.Now()},
+			integer(tagBatchCount, 1)),
+		structure(tagBatchItem, result...))
+}
+
+type fakeError struct {
+	reason  uint32
+	message string
+}
+
+func fail(reason uint32, format string, args ...interface{}) *fakeError {
+	return &fakeError{reason: reason, message: fmt.Sprintf(format, args...)}
+}
+
+func (s *fakeServer) handleOperation(request item, operation uint32) ([]item, *fakeError) {
+	header, _ := request.child(tagRequestHeader)
+	version, _ := header.child(tagProtocolVersion)
+	if major, _ := version.child(tagProtocolVersionMajor); major.Value != s.version.major {
+		return nil, fail(0x04, "unsupported protocol version")
+	}
+	if minor, _ := version.child(tagProtocolVersionMinor); minor.Value != s.version.minor {
+		return nil, fail(0x04, "unsupported protocol version")
+	}
+	if s.username != "" {
+		authentication, _ := header.child(tagAuthentication)
+		credential, _ := authentication.child(tagCredential)
+		credentialValue, _ := credential.child(tagCredentialValue)
+		username, _ := credentialValue.childText(tagUsername)
+		password, _ := credentialValue.childText(tagPassword)
+		if username != s.username || password != s.password {
+			return nil, fail(0x03, "invalid credentials")
+		}
+	}
+
+	if operation == s.failure {
+		return nil, fail(0x100, "injected failure")
+	}
+
+	batchItem, _ := request.child(tagBatchItem)
+	payload, _ := batchItem.child(tagRequestPayload)
+	switch operation {
+	case operationCreateKeyPair:
+		return s.createKeyPair(payload)
+	case operationActivate:
+		object, id, err := s.object(payload)
+		if err != nil {
+			return nil, err
+		}
+		object.active = true
+		return []item{textString(tagUniqueIdentifier, id)}, nil
+	case operationGet:
+		return s.get(payload)
+	case operationSign:
+		return s.sign(payload)
+	case operationRevoke:
+		object, id, err := s.object(payload)
+		if err != nil {
+			return nil, err
+		}
+		object.active = false
+		return []item{textString(tagUniqueIdentifier, id)}, nil
+	case operationDestroy:
+		object, id, err := s.object(payload)
+		if err != nil {
+			return nil, err
+		}
+		if object.active {
+			return nil, fail(0x0B, "object %q is active", id)
+		}
+		delete(s.objects, id)
+		return []item{textString(tagUniqueIdentifier, id)}, nil
+	default:
+		return nil, fail(0x05, "operation %#x not supported", operation)
+	}
+}
+
+func (s *fakeServer) object(payload item) (*fakeObject, string, *fakeError) {
+	id, _ := payload.childText(tagUniqueIdentifier)
+	object, ok := s.objects[id]
+	if !ok {
+		return nil, "", fail(0x01, "object %q not found", id)
+	}
+	return object, id, nil
+}
+
+// attributes returns the attributes in a KMIP 1.x template-attribute or
+// KMIP 2.0 attributes structure, keyed by tag
+func (s *fakeServer) attributes(payload item, templateTag, attributesTag uint32) map[uint32]item {
+	attributes := make(map[uint32]item)
+	if s.version.major >= 2 {
+		container, _ := payload.child(attributesTag)
+		for _, attribute := range container.children() {
+			attributes[attribute.Tag] = attribute
+		}
+		return attributes
+	}
+
+	container, _ := payload.child(templateTag)
+	for _, attribute := range container.children() {
+		name, _ := attribute.childText(tagAttributeName)
+		value, _ := attribute.child(tagAttributeValue)
+		for _, tag := range []uint32{tagCryptographicAlgorithm, tagCryptographicLength, tagCryptographicDomainParameters, tagCryptographicUsageMask, tagName} {
+			if attributeName(tag) == name {
+				value.Tag = tag
+				attributes[tag] = value
+			}
+		}
+	}
+	return attributes
+}
+
+func (s *fakeServer) createKeyPair(payload item) ([]item, *fakeError) {
+	common := s.attributes(payload, tagCommonTemplateAttribute, tagCommonAttributes)
+	private := s.attributes(payload, tagPrivateKeyTemplateAttribute, tagPrivateKeyAttributes)
+	public := s.attributes(payload, tagPublicKeyTemplateAttribute, tagPublicKeyAttributes)
+
+	if private[tagCryptographicUsageMask].Value != int32(usageMaskSign) || public[tagCryptographicUsageMask].Value != int32(usageMaskVerify) {
+		return nil, fail(0x07, "unexpected usage masks")
+	}
+
+	var signer crypto.Signer
+	var err error
+	length, _ := common[tagCryptographicLength].Value.(int32)
+	switch common[tagCryptographicAlgorithm].Value {
+	case uint32(cryptographicAlgorithmECDSA):
+		curve, _ := common[tagCryptographicDomainParameters].childEnum(tagRecommendedCurve)
+		switch {
+		case curve == recommendedCurveP256 && length == 256:
+			signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		case curve == recommendedCurveP384 && length == 384:
+			signer, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		default:
+			return nil, fail(0x07, "unsupported curve %#x", curve)
+		}
+	case uint32(cryptographicAlgorithmRSA):
+		signer, err = rsa.GenerateKey(rand.Reader, int(length))
+	default:
+		return nil, fail(0x07, "unsupported algorithm")
+	}
+	if err != nil {
+		return nil, fail(0x0A, "%v", err)
+	}
+
+	privateKeyID := s.newObject(private, &fakeObject{public: signer.Public(), private: signer})
+	publicKeyID := s.newObject(public, &fakeObject{public: signer.Public()})
+	return []item{
+		textString(tagPrivateKeyUniqueIdentifier, privateKeyID),
+		textString(tagPublicKeyUniqueIdentifier, publicKeyID),
+	}, nil
+}
+
+func (s *fakeServer) newObject(attributes map[uint32]item, object *fakeObject) string {
+	object.name, _ = attributes[tagName].childText(tagNameValue)
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	s.objects[id] = object
+	return id
+}
+
+func (s *fakeServer) get(payload item) ([]item, *fakeError) {
+	object, id, err := s.object(payload)
+	if err != nil {
+		return nil, err
+	}
+	if object.private != nil {
+		return nil, fail(0x0C, "private keys cannot be retrieved")
+	}
+	if keyFormatType, _ := payload.childEnum(tagKeyFormatType); keyFormatType != keyFormatTypeX509 {
+		return nil, fail(0x08, "unsupported key format type")
+	}
+	pkixData, _ := x509.MarshalPKIXPublicKey(object.public)
+	return []item{
+		enumeration(tagObjectType, objectTypePublicKey),
+		textString(tagUniqueIdentifier, id),
+		structure(tagPublicKey,
+			structure(tagKeyBlock,
+				enumeration(tagKeyFormatType, keyFormatTypeX509),
+				structure(tagKeyValue,
+					byteString(tagKeyMaterial, pkixData)))),
+	}, nil
+}
+
+func (s *fakeServer) sign(payload item) ([]item, *fakeError) {
+	object, id, ferr := s.object(payload)
+	if ferr != nil {
+		return nil, ferr
+	}
+	if object.private == nil {
+		return nil, fail(0x0B, "object %q is not a private key", id)
+	}
+	if !object.active {
+		return nil, fail(0x0B, "object %q is not active", id)
+	}
+	params, _ := payload.child(tagCryptographicParameters)
+	algorithm, _ := params.childEnum(tagCryptographicAlgorithm)
+	data, _ := payload.childBytes(tagData)
+
+	var signature []byte
+	switch key := object.private.(type) {
+	case *ecdsa.PrivateKey:
+		if algorithm != cryptographicAlgorithmECDSA {
+			return nil, fail(0x07, "unexpected algorithm %#x", algorithm)
+		}
+		r, sig, err := ecdsa.Sign(rand.Reader, key, data)
+		if err != nil {
+			return nil, fail(0x0A, "%v", err)
+		}
+		if s.rawECDSA {
+			size := (key.Curve.Params().BitSize + 7) / 8
+			signature = make([]byte, 2*size)
+			r.FillBytes(signature[:size])
+			sig.FillBytes(signature[size:])
+		} else {
+			signature, _ = asn1.Marshal(struct{ R, S *big.Int }{R: r, S: sig})
+		}
+	case *rsa.PrivateKey:
+		paddingMethod, _ := params.childEnum(tagPaddingMethod)
+		if algorithm != cryptographicAlgorithmRSA || paddingMethod != paddingMethodPKCS1v15 {
+			return nil, fail(0x07, "unexpected algorithm %#x or padding %#x", algorithm, paddingMethod)
+		}
+		var err error
+		// a zero hash signs the DigestInfo formed by the client as-is
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, 0, data)
+		if err != nil {
+			return nil, fail(0x0A, "%v", err)
+		}
+	}
+	return []item{
+		textString(tagUniqueIdentifier, id),
+		byteString(tagSignatureData, signature),
+	}, nil
+}
+
+func writeClientCertificate(t *testing.T, dir string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "spire-server"},
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "client.pem"), certPEM, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "client.key"), keyPEM, 0600))
+}
@@ -0,0 +1,367 @@
+package kmip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+// KMIP messages are encoded as TTLV (tag, type, length, value) items. Only
+// the subset of tags, types and enumerations used by the key manager is
+// defined here.
+
+const (
+	tagAttribute                     = 0x420008
+	tagAttributeName                 = 0x42000A
+	tagAttributeValue                = 0x42000B
+	tagAuthentication                = 0x42000C
+	tagBatchCount                    = 0x42000D
+	tagBatchItem                     = 0x42000F
+	tagCommonTemplateAttribute       = 0x42001F
+	tagCredential                    = 0x420023
+	tagCredentialType                = 0x420024
+	tagCredentialValue               = 0x420025
+	tagCryptographicAlgorithm        = 0x420028
+	tagCryptographicDomainParameters = 0x420029
+	tagCryptographicLength           = 0x42002A
+	tagCryptographicParameters       = 0x42002B
+	tagCryptographicUsageMask        = 0x42002C
+	tagKeyBlock                      = 0x420040
+	tagKeyFormatType                 = 0x420042
+	tagKeyMaterial                   = 0x420043
+	tagKeyValue                      = 0x420045
+	tagName                          = 0x420053
+	tagNameType                      = 0x420054
+	tagNameValue                     = 0x420055
+	tagObjectType                    = 0x420057
+	tagOperation                     = 0x42005C
+	tagPaddingMethod                 = 0x42005F
+	tagPrivateKeyTemplateAttribute   = 0x420065
+	tagPrivateKeyUniqueIdentifier    = 0x420066
+	tagProtocolVersion               = 0x420069
+	tagProtocolVersionMajor          = 0x42006A
+	tagProtocolVersionMinor          = 0x42006B
+	tagPublicKey                     = 0x42006D
+	tagPublicKeyTemplateAttribute    = 0x42006E
+	tagPublicKeyUniqueIdentifier     = 0x42006F
+	tagRecommendedCurve              = 0x420075
+	tagRequestHeader                 = 0x420077
+	tagRequestMessage                = 0x420078
+	tagRequestPayload                = 0x420079
+	tagResponseHeader                = 0x42007A
+	tagResponseMessage               = 0x42007B
+	tagResponsePayload               = 0x42007C
+	tagResultMessage                 = 0x42007D
+	tagResultReason                  = 0x42007E
+	tagResultStatus                  = 0x42007F
+	tagRevocationReason              = 0x420081
+	tagRevocationReasonCode          = 0x420082
+	tagTimeStamp                     = 0x420092
+	tagUniqueIdentifier              = 0x420094
+	tagUsername                      = 0x420099
+	tagPassword                      = 0x4200A1
+	tagData                          = 0x4200C2
+	tagSignatureData                 = 0x4200C3
+
+	// KMIP 2.0 replaces template-attributes with structures holding the
+	// attributes directly
+	tagCommonAttributes     = 0x420126
+	tagPrivateKeyAttributes = 0x420127
+	tagPublicKeyAttributes  = 0x420128
+)
+
+const (
+	typeStructure   = 0x01
+	typeInteger     = 0x02
+	typeLongInteger = 0x03
+	typeBigInteger  = 0x04
+	typeEnumeration = 0x05
+	typeBoolean     = 0x06
+	typeTextString  = 0x07
+	typeByteString  = 0x08
+	typeDateTime    = 0x09
+	typeInterval    = 0x0A
+)
+
+const (
+	operationCreateKeyPair = 0x02
+	operationGet           = 0x0A
+	operationActivate      = 0x12
+	operationRevoke        = 0x13
+	operationDestroy       = 0x14
+	operationSign          = 0x21
+
+	resultStatusSuccess = 0x00
+
+	credentialTypeUsernameAndPassword = 0x01
+
+	objectTypePublicKey = 0x03
+
+	cryptographicAlgorithmRSA   = 0x04
+	cryptographicAlgorithmECDSA = 0x06
+
+	recommendedCurveP256 = 0x07
+	recommendedCurveP384 = 0x0A
+
+	usageMaskSign   = 0x01
+	usageMaskVerify = 0x02
+
+	keyFormatTypeX509 = 0x05
+
+	paddingMethodPKCS1v15 = 0x08
+
+	nameTypeUninterpretedTextString = 0x01
+
+	revocationReasonCessationOfOperation = 0x06
+)
+
+// maxItemSize bounds the size of a decoded message
+const maxItemSize = 1 << 20
+
+// item is a TTLV item. The value is []item for structures, int32 for
+// integers, int64 for long integers, uint32 for enumerations, bool for
+// booleans, string for text strings, []byte for byte strings and big
+// integers, and time.Time for date-times.
+type item struct {
+	Tag   uint32
+	Type  byte
+	Value interface{}
+}
+
+func structure(tag uint32, children ...item) item {
+	return item{Tag: tag, Type: typeStructure, Value: children}
+}
+
+func integer(tag uint32, value int32) item {
+	return item{Tag: tag, Type: typeInteger, Value: value}
+}
+
+func enumeration(tag uint32, value uint32) item {
+	return item{Tag: tag, Type: typeEnumeration, Value: value}
+}
+
+func textString(tag uint32, value string) item {
+	return item{Tag: tag, Type: typeTextString, Value: value}
+}
+
+func byteString(tag uint32, value []byte) item {
+	return item{Tag: tag, Type: typeByteString, Value: value}
+}
+
+// child returns the first child of a structure with the given tag
+func (i item) child(tag uint32) (item, bool) {
+	for _, c := range i.children() {
+		if c.Tag == tag {
+			return c, true
+		}
+	}
+	return item{}, false
+}
+
+func (i item) children() []item {
+	children, _ := i.Value.([]item)
+	return children
+}
+
+func (i item) childText(tag uint32) (string, error) {
+	c, ok := i.child(tag)
+	if !ok {
+		return "", errs.New("missing %s", describeTag(tag))
+	}
+	value, ok := c.Value.(string)
+	if !ok {
+		return "", errs.New("%s is not a text string", describeTag(tag))
+	}
+	return value, nil
+}
+
+func (i item) childBytes(tag uint32) ([]byte, error) {
+	c, ok := i.child(tag)
+	if !ok {
+		return nil, errs.New("missing %s", describeTag(tag))
+	}
+	value, ok := c.Value.([]byte)
+	if !ok {
+		return nil, errs.New("%s is not a byte string", describeTag(tag))
+	}
+	return value, nil
+}
+
+func (i item) childEnum(tag uint32) (uint32, error) {
+	c, ok := i.child(tag)
+	if !ok {
+		return 0, errs.New("missing %s", describeTag(tag))
+	}
+	value, ok := c.Value.(uint32)
+	if !ok {
+		return 0, errs.New("%s is not an enumeration", describeTag(tag))
+	}
+	return value, nil
+}
+
+func (i item) Marshal() []byte {
+	var value []byte
+	switch v := i.Value.(type) {
+	case []item:
+		for _, c := range v {
+			value = append(value, c.Marshal()...)
+		}
+	case int32:
+		value = make([]byte, 4)
+		binary.BigEndian.PutUint32(value, uint32(v))
+	case uint32:
+		value = make([]byte, 4)
+		binary.BigEndian.PutUint32(value, v)
+	case int64:
+		value = make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(v))
+	case bool:
+		value = make([]byte, 8)
+		if v {
+			value[7] = 1
+		}
+	case string:
+		value = []byte(v)
+	case []byte:
+		value = v
+	case time.Time:
+		value = make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(v.Unix()))
+	default:
+		panic(fmt.Sprintf("unsupported TTLV value type %T", v))
+	}
+
+	out := make([]byte, 8, 8+len(value)+7)
+	out[0] = byte(i.Tag >> 16)
+	out[1] = byte(i.Tag >> 8)
+	out[2] = byte(i.Tag)
+	out[3] = i.Type
+	binary.BigEndian.PutUint32(out[4:], uint32(len(value)))
+	out = append(out, value...)
+	if pad := len(out) % 8; pad != 0 {
+		out = append(out, make([]byte, 8-pad)...)
+	}
+	return out
+}
+
+// readItem reads a single (top level) item from the reader
+func readItem(r io.Reader) (item, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return item{}, err
+	}
+	length := binary.BigEndian.Uint32(header[4:])
+	if length > maxItemSize {
+		return item{}, errs.New("message length %d exceeds the maximum of %d", length, maxItemSize)
+	}
+	buf := make([]byte, 8+padded(int(length)))
+	copy(buf, header)
+	if _, err := io.ReadFull(r, buf[8:]); err != nil {
+		return item{}, err
+	}
+	i, _, err := unmarshalItem(buf)
+	return i, err
+}
+
+func unmarshalItem(data []byte) (item, []byte, error) {
+	if len(data) < 8 {
+		return item{}, nil, errs.New("truncated TTLV header")
+	}
+	i := item{
+		Tag:  uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2]),
+		Type: data[3],
+	}
+	length := int(binary.BigEndian.Uint32(data[4:]))
+	data = data[8:]
+	if length > len(data) {
+		return item{}, nil, errs.New("truncated value for %s", describeTag(i.Tag))
+	}
+	value := data[:length]
+	rest := data[length:]
+	if n := padded(length) - length; n > 0 {
+		if n > len(rest) {
+			return item{}, nil, errs.New("truncated padding for %s", describeTag(i.Tag))
+		}
+		rest = rest[n:]
+	}
+
+	fixedLength := func(n int) error {
+		if length != n {
+			return errs.New("invalid length %d for %s", length, describeTag(i.Tag))
+		}
+		return nil
+	}
+
+	switch i.Type {
+	case typeStructure:
+		var children []item
+		for len(value) > 0 {
+			var child item
+			var err error
+			child, value, err = unmarshalItem(value)
+			if err != nil {
+				return item{}, nil, err
+			}
+			children = append(children, child)
+		}
+		i.Value = children
+	case typeInteger, typeInterval:
+		if err := fixedLength(4); err != nil {
+			return item{}, nil, err
+		}
+		i.Value = int32(binary.BigEndian.Uint32(value))
+	case typeEnumeration:
+		if err := fixedLength(4); err != nil {
+			return item{}, nil, err
+		}
+		i.Value = binary.BigEndian.Uint32(value)
+	case typeLongInteger:
+		if err := fixedLength(8); err != nil {
+			return item{}, nil, err
+		}
+		i.Value = int64(binary.BigEndian.Uint64(value))
+	case typeBoolean:
+		if err := fixedLength(8); err != nil {
+			return item{}, nil, err
+		}
+		i.Value = binary.BigEndian.Uint64(value) != 0
+	case typeDateTime:
+		if err := fixedLength(8); err != nil {
+			return item{}, nil, err
+		}
+		i.Value = time.Unix(int64(binary.BigEndian.Uint64(value)), 0).UTC()
+	case typeTextString:
+		i.Value = string(value)
+	case typeByteString, typeBigInteger:
+		i.Value = append([]byte(nil), value...)
+	default:
+		return item{}, nil, errs.New("unknown type %#x for %s", i.Type, describeTag(i.Tag))
+	}
+	return i, rest, nil
+}
+
+func padded(n int) int {
+	return (n + 7) &^ 7
+}
+
+func describeTag(tag uint32) string {
+	switch tag {
+	case tagUniqueIdentifier:
+		return "unique identifier"
+	case tagPrivateKeyUniqueIdentifier:
+		return "private key unique identifier"
+	case tagPublicKeyUniqueIdentifier:
+		return "public key unique identifier"
+	case tagSignatureData:
+		return "signature data"
+	case tagResponsePayload:
+		return "response payload"
+	case tagResultStatus:
+		return "result status"
+	default:
+		return fmt.Sprintf("tag %#06x", tag)
+	}
+}
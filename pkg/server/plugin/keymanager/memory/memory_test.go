@@ -21,3 +21,7 @@ func makeKeyManager(t *testing.T) keymanager.Plugin {
 	require.Equal(t, &plugin.ConfigureResponse{}, resp)
 	return m
 }
+
+func TestKeyManagerEd25519(t *testing.T) {
+	test.RunEd25519(t, makeKeyManager)
+}
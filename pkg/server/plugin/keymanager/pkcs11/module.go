@@ -0,0 +1,160 @@
+// +build cgo
+
+package pkcs11
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	p11 "github.com/miekg/pkcs11"
+	"github.com/zeebo/errs"
+)
+
+// module is the subset of the PKCS#11 API used by the key manager. It is
+// satisfied by *p11.Ctx.
+type module interface {
+	Initialize() error
+	Finalize() error
+	Destroy()
+	GetSlotList(tokenPresent bool) ([]uint, error)
+	GetTokenInfo(slotID uint) (p11.TokenInfo, error)
+	OpenSession(slotID uint, flags uint) (p11.SessionHandle, error)
+	CloseSession(sh p11.SessionHandle) error
+	Login(sh p11.SessionHandle, userType uint, pin string) error
+	FindObjectsInit(sh p11.SessionHandle, temp []*p11.Attribute) error
+	FindObjects(sh p11.SessionHandle, max int) ([]p11.ObjectHandle, bool, error)
+	FindObjectsFinal(sh p11.SessionHandle) error
+	GenerateKeyPair(sh p11.SessionHandle, m []*p11.Mechanism, public, private []*p11.Attribute) (p11.ObjectHandle, p11.ObjectHandle, error)
+	GetAttributeValue(sh p11.SessionHandle, o p11.ObjectHandle, a []*p11.Attribute) ([]*p11.Attribute, error)
+	DestroyObject(sh p11.SessionHandle, oh p11.ObjectHandle) error
+	SignInit(sh p11.SessionHandle, m []*p11.Mechanism, o p11.ObjectHandle) error
+	Sign(sh p11.SessionHandle, message []byte) ([]byte, error)
+}
+
+func openModule(path string) (module, error) {
+	ctx := p11.New(path)
+	if ctx == nil {
+		return nil, errs.New("unable to load PKCS#11 module %q", path)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, errs.New("unable to initialize PKCS#11 module %q: %v", path, err)
+	}
+	return ctx, nil
+}
+
+func closeModule(m module) {
+	m.Finalize()
+	m.Destroy()
+}
+
+// findSlot returns the slot holding the token with the given label
+func findSlot(m module, tokenLabel string) (uint, error) {
+	slots, err := m.GetSlotList(true)
+	if err != nil {
+		return 0, errs.New("unable to list slots: %v", err)
+	}
+	for _, slot := range slots {
+		info, err := m.GetTokenInfo(slot)
+		if err != nil {
+			return 0, errs.New("unable to get token info for slot %d: %v", slot, err)
+		}
+		// token labels are padded with spaces to 32 characters
+		if strings.TrimRight(info.Label, " \x00") == tokenLabel {
+			return slot, nil
+		}
+	}
+	return 0, errs.New("no token with label %q", tokenLabel)
+}
+
+// sessionPool hands out logged in sessions to a single token. A PKCS#11
+// session can only run one operation at a time (e.g. a SignInit/Sign pair),
+// so concurrent callers each need their own session.
+type sessionPool struct {
+	m    module
+	slot uint
+	pin  string
+
+	// tokens limits the number of sessions that can be open at once
+	tokens chan struct{}
+
+	mu       sync.Mutex
+	idle     []p11.SessionHandle
+	loggedIn bool
+	closed   bool
+}
+
+func newSessionPool(m module, slot uint, pin string, size int) *sessionPool {
+	return &sessionPool{
+		m:      m,
+		slot:   slot,
+		pin:    pin,
+		tokens: make(chan struct{}, size),
+	}
+}
+
+// Get returns an idle session, opening a new one if the pool has not reached
+// its size. It blocks until a session is available or the context is done.
+func (p *sessionPool) Get(ctx context.Context) (p11.SessionHandle, error) {
+	select {
+	case p.tokens <- struct{}{}:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		<-p.tokens
+		return 0, errs.New("session pool is closed")
+	}
+	if n := len(p.idle); n > 0 {
+		sh := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		return sh, nil
+	}
+
+	sh, err := p.m.OpenSession(p.slot, p11.CKF_SERIAL_SESSION|p11.CKF_RW_SESSION)
+	if err != nil {
+		<-p.tokens
+		return 0, errs.New("unable to open session: %v", err)
+	}
+	// Login state is shared by all sessions of the application, so only the
+	// first session needs to log in.
+	if !p.loggedIn && p.pin != "" {
+		if err := p.m.Login(sh, p11.CKU_USER, p.pin); err != nil && err != p11.Error(p11.CKR_USER_ALREADY_LOGGED_IN) {
+			p.m.CloseSession(sh)
+			<-p.tokens
+			return 0, errs.New("unable to log in: %v", err)
+		}
+		p.loggedIn = true
+	}
+	return sh, nil
+}
+
+// Put returns a session obtained from Get to the pool. Sessions that failed
+// an operation should be returned with a non-nil error so they are closed
+// instead of reused, since they may be left in an unusable state.
+func (p *sessionPool) Put(sh p11.SessionHandle, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil || p.closed {
+		p.m.CloseSession(sh)
+	} else {
+		p.idle = append(p.idle, sh)
+	}
+	<-p.tokens
+}
+
+// Close closes all idle sessions. Sessions still in use are closed when they
+// are returned.
+func (p *sessionPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, sh := range p.idle {
+		p.m.CloseSession(sh)
+	}
+	p.idle = nil
+	p.closed = true
+}
@@ -0,0 +1,791 @@
+//go:build cgo
+// +build cgo
+
+package pkcs11
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/hcl"
+	p11 "github.com/miekg/pkcs11"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/base"
+	"github.com/zeebo/errs"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+)
+
+const (
+	defaultKeyLabelPrefix  = "spire-"
+	defaultSessionPoolSize = 4
+	findObjectsBatchSize   = 64
+
+	// PKCS#11 v3.0 values for EdDSA keys, which the pkcs11 package predates
+	ckkECEdwards           = 0x00000040
+	ckmECEdwardsKeyPairGen = 0x00001055
+	ckmEdDSA               = 0x00001057
+)
+
+var (
+	pkcs11Error = errs.Class("keymanager(pkcs11)")
+
+	oidNamedCurveP256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	oidNamedCurveP384 = asn1.ObjectIdentifier{1, 3, 132, 0, 34}
+	oidEd25519        = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+	// digestInfoPrefixes are the DER encoded DigestInfo prefixes that have to
+	// be prepended to the digest for PKCS#1 v1.5 signatures, since
+	// CKM_RSA_PKCS does not hash or encode the data it signs.
+	digestInfoPrefixes = map[crypto.Hash][]byte{
+		crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+		crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+		crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+	}
+)
+
+type configuration struct {
+	// ModulePath is the path to the PKCS#11 module (shared library)
+	ModulePath string `hcl:"module_path"`
+
+	// TokenLabel selects the token by label. Either TokenLabel or SlotID
+	// must be set.
+	TokenLabel string `hcl:"token_label"`
+	SlotID     *int   `hcl:"slot_id"`
+
+	// UserPIN is the PIN used to log into the token
+	UserPIN string `hcl:"user_pin"`
+
+	// KeyLabelPrefix is prepended to SPIRE key ids to form object labels
+	KeyLabelPrefix string `hcl:"key_label_prefix"`
+
+	// SessionPoolSize is the maximum number of concurrent sessions
+	SessionPoolSize int `hcl:"session_pool_size"`
+}
+
+type keyEntry struct {
+	privateKey p11.ObjectHandle
+	publicKey  p11.ObjectHandle
+	hsmID      []byte
+	publicData *keymanager.PublicKey
+}
+
+type KeyManager struct {
+	// log is set by the catalog before the plugin is configured
+	log logrus.FieldLogger
+
+	// generateMu serializes key generation so that rotations of the same
+	// key do not race each other
+	generateMu sync.Mutex
+
+	// mu guards the module, which must not be released while an operation
+	// is using it
+	mu     sync.RWMutex
+	module module
+	pool   *sessionPool
+	config *configuration
+
+	entriesMu sync.RWMutex
+	entries   map[string]*keyEntry
+
+	hooks struct {
+		openModule func(path string) (module, error)
+		now        func() time.Time
+	}
+}
+
+var _ keymanager.Plugin = (*KeyManager)(nil)
+
+func New() *KeyManager {
+	p := &KeyManager{
+		log:     logrus.StandardLogger(),
+		entries: make(map[string]*keyEntry),
+	}
+	p.hooks.openModule = openModule
+	p.hooks.now = time.Now
+	return p
+}
+
+func (p *KeyManager) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, pkcs11Error.New("unable to decode configuration: %v", err)
+	}
+
+	if config.ModulePath == "" {
+		return nil, pkcs11Error.New("module_path is required")
+	}
+	switch {
+	case config.TokenLabel == "" && config.SlotID == nil:
+		return nil, pkcs11Error.New("one of token_label or slot_id is required")
+	case config.TokenLabel != "" && config.SlotID != nil:
+		return nil, pkcs11Error.New("token_label and slot_id are mutually exclusive")
+	case config.SlotID != nil && *config.SlotID < 0:
+		return nil, pkcs11Error.New("slot_id must not be negative")
+	}
+	if config.KeyLabelPrefix == "" {
+		config.KeyLabelPrefix = defaultKeyLabelPrefix
+	}
+	if config.SessionPoolSize < 0 {
+		return nil, pkcs11Error.New("session_pool_size must not be negative")
+	}
+	if config.SessionPoolSize == 0 {
+		config.SessionPoolSize = defaultSessionPoolSize
+	}
+
+	p.generateMu.Lock()
+	defer p.generateMu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Most modules can only be initialized once per process, so the existing
+	// module (if any) is released before the new one is loaded.
+	p.closeModule()
+
+	m, err := p.hooks.openModule(config.ModulePath)
+	if err != nil {
+		return nil, pkcs11Error.Wrap(err)
+	}
+
+	var slot uint
+	if config.SlotID != nil {
+		slot = uint(*config.SlotID)
+	} else {
+		slot, err = findSlot(m, config.TokenLabel)
+		if err != nil {
+			closeModule(m)
+			return nil, pkcs11Error.Wrap(err)
+		}
+	}
+
+	pool := newSessionPool(m, slot, config.UserPIN, config.SessionPoolSize)
+	entries, err := loadEntries(ctx, p.log, pool, config.KeyLabelPrefix)
+	if err != nil {
+		pool.Close()
+		closeModule(m)
+		return nil, err
+	}
+
+	p.module = m
+	p.pool = pool
+	p.config = config
+	p.setEntries(entries)
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+// SetLogger sets the logger the plugin logs to
+func (p *KeyManager) SetLogger(log logrus.FieldLogger) {
+	p.log = log
+}
+
+func (p *KeyManager) GetPluginInfo(ctx context.Context, req *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *KeyManager) GenerateKey(ctx context.Context, req *keymanager.GenerateKeyRequest) (*keymanager.GenerateKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, pkcs11Error.New("key id is required")
+	}
+	if req.KeyType == keymanager.KeyType_UNSPECIFIED_KEY_TYPE {
+		return nil, pkcs11Error.New("key type is required")
+	}
+
+	p.generateMu.Lock()
+	defer p.generateMu.Unlock()
+
+	// the read lock is held for the duration of the operation so that the
+	// module is not released out from under it by a reconfiguration
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.pool == nil {
+		return nil, pkcs11Error.New("not configured")
+	}
+
+	mech, publicTemplate, err := keyPairTemplate(req.KeyType)
+	if err != nil {
+		return nil, err
+	}
+
+	label := p.config.KeyLabelPrefix + req.KeyId
+	// The object id records when the key was generated so the latest key
+	// wins if a rotation is interrupted before the old key is destroyed.
+	hsmID := make([]byte, 8)
+	binary.BigEndian.PutUint64(hsmID, uint64(p.hooks.now().UnixNano()))
+
+	keyType := keyTypeAttribute(req.KeyType)
+	publicTemplate = append(publicTemplate,
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PUBLIC_KEY),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, keyType),
+		p11.NewAttribute(p11.CKA_TOKEN, true),
+		p11.NewAttribute(p11.CKA_VERIFY, true),
+		p11.NewAttribute(p11.CKA_LABEL, label),
+		p11.NewAttribute(p11.CKA_ID, hsmID),
+	)
+	privateTemplate := []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PRIVATE_KEY),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, keyType),
+		p11.NewAttribute(p11.CKA_TOKEN, true),
+		p11.NewAttribute(p11.CKA_PRIVATE, true),
+		p11.NewAttribute(p11.CKA_SENSITIVE, true),
+		p11.NewAttribute(p11.CKA_EXTRACTABLE, false),
+		p11.NewAttribute(p11.CKA_SIGN, true),
+		p11.NewAttribute(p11.CKA_LABEL, label),
+		p11.NewAttribute(p11.CKA_ID, hsmID),
+	}
+
+	sh, err := p.pool.Get(ctx)
+	if err != nil {
+		return nil, pkcs11Error.Wrap(err)
+	}
+	newEntry, err := func() (*keyEntry, error) {
+		publicKey, privateKey, err := p.module.GenerateKeyPair(sh, []*p11.Mechanism{p11.NewMechanism(mech, nil)}, publicTemplate, privateTemplate)
+		if err != nil {
+			return nil, err
+		}
+		publicData, err := readPublicKey(p.module, sh, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		publicData.Id = req.KeyId
+		return &keyEntry{
+			privateKey: privateKey,
+			publicKey:  publicKey,
+			hsmID:      hsmID,
+			publicData: publicData,
+		}, nil
+	}()
+	p.pool.Put(sh, err)
+	if err != nil {
+		return nil, pkcs11Error.New("unable to generate key %q: %v", req.KeyId, err)
+	}
+
+	p.entriesMu.Lock()
+	oldEntry := p.entries[req.KeyId]
+	p.entries[req.KeyId] = newEntry
+	p.entriesMu.Unlock()
+
+	// The previous key pair is no longer used. Failure to destroy it is not
+	// fatal since the new key pair is already in use.
+	if oldEntry != nil {
+		if err := p.destroyKeyPair(ctx, oldEntry); err != nil {
+			p.log.Warnf("Unable to destroy previous key pair for %q: %v", req.KeyId, err)
+		}
+	}
+
+	return &keymanager.GenerateKeyResponse{
+		PublicKey: clonePublicKey(newEntry.publicData),
+	}, nil
+}
+
+func (p *KeyManager) GetPublicKey(ctx context.Context, req *keymanager.GetPublicKeyRequest) (*keymanager.GetPublicKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, pkcs11Error.New("key id is required")
+	}
+
+	resp := new(keymanager.GetPublicKeyResponse)
+	if entry := p.getEntry(req.KeyId); entry != nil {
+		resp.PublicKey = clonePublicKey(entry.publicData)
+	}
+	return resp, nil
+}
+
+func (p *KeyManager) GetPublicKeys(ctx context.Context, req *keymanager.GetPublicKeysRequest) (*keymanager.GetPublicKeysResponse, error) {
+	p.entriesMu.RLock()
+	defer p.entriesMu.RUnlock()
+
+	resp := new(keymanager.GetPublicKeysResponse)
+	for _, entry := range p.entries {
+		resp.PublicKeys = append(resp.PublicKeys, clonePublicKey(entry.publicData))
+	}
+	sort.Slice(resp.PublicKeys, func(i, j int) bool {
+		return resp.PublicKeys[i].Id < resp.PublicKeys[j].Id
+	})
+	return resp, nil
+}
+
+func (p *KeyManager) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error) {
+	if req.KeyId == "" {
+		return nil, pkcs11Error.New("key id is required")
+	}
+	if req.SignerOpts == nil {
+		return nil, pkcs11Error.New("signer opts is required")
+	}
+
+	var hashAlgorithm keymanager.HashAlgorithm
+	var pssOptions *keymanager.PSSOptions
+	switch opts := req.SignerOpts.(type) {
+	case *keymanager.SignDataRequest_HashAlgorithm:
+		hashAlgorithm = opts.HashAlgorithm
+	case *keymanager.SignDataRequest_PssOptions:
+		if opts.PssOptions == nil {
+			return nil, pkcs11Error.New("PSS options are nil")
+		}
+		pssOptions = opts.PssOptions
+		hashAlgorithm = opts.PssOptions.HashAlgorithm
+	default:
+		return nil, pkcs11Error.New("unsupported signer opts type %T", opts)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.pool == nil {
+		return nil, pkcs11Error.New("not configured")
+	}
+
+	// Ed25519 signs the message itself and so does not use a hash algorithm
+	entry := p.getEntry(req.KeyId)
+	isEd25519 := entry != nil && entry.publicData.Type == keymanager.KeyType_ED25519
+	if isEd25519 && (hashAlgorithm != keymanager.HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM || pssOptions != nil) {
+		return nil, pkcs11Error.New("keypair %q signing operation failed: Ed25519 keys do not use a hash algorithm", req.KeyId)
+	}
+	if hashAlgorithm == keymanager.HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM && !isEd25519 {
+		return nil, pkcs11Error.New("hash algorithm is required")
+	}
+	hash := crypto.Hash(hashAlgorithm)
+	if !isEd25519 && !hash.Available() {
+		return nil, pkcs11Error.New("unsupported hash algorithm %s", hashAlgorithm)
+	}
+
+	if entry == nil {
+		return nil, pkcs11Error.New("no such key %q", req.KeyId)
+	}
+	if !isEd25519 && len(req.Data) != hash.Size() {
+		return nil, pkcs11Error.New("data length %d does not match %s digest size", len(req.Data), hashAlgorithm)
+	}
+
+	mech, data, err := signMechanism(entry.publicData.Type, hash, pssOptions, req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	sh, err := p.pool.Get(ctx)
+	if err != nil {
+		return nil, pkcs11Error.Wrap(err)
+	}
+	signature, err := func() ([]byte, error) {
+		if err := p.module.SignInit(sh, []*p11.Mechanism{mech}, entry.privateKey); err != nil {
+			return nil, err
+		}
+		return p.module.Sign(sh, data)
+	}()
+	p.pool.Put(sh, err)
+	if err != nil {
+		return nil, pkcs11Error.New("keypair %q signing operation failed: %v", req.KeyId, err)
+	}
+
+	if isECKeyType(entry.publicData.Type) {
+		// CKM_ECDSA produces signatures in the raw (R || S) format
+		signature, err = ecdsaSignatureToASN1(signature)
+		if err != nil {
+			return nil, pkcs11Error.New("keypair %q returned malformed signature: %v", req.KeyId, err)
+		}
+	}
+
+	return &keymanager.SignDataResponse{
+		Signature: signature,
+	}, nil
+}
+
+// SignDataBatch signs each request in turn since the token signs a single digest per operation.
+func (p *KeyManager) SignDataBatch(ctx context.Context, req *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	return base.SignDataEach(ctx, req, p.SignData)
+}
+
+func (p *KeyManager) destroyKeyPair(ctx context.Context, entry *keyEntry) (err error) {
+	sh, err := p.pool.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		p.pool.Put(sh, err)
+	}()
+	if err := p.module.DestroyObject(sh, entry.privateKey); err != nil {
+		return err
+	}
+	return p.module.DestroyObject(sh, entry.publicKey)
+}
+
+// closeModule releases the current module, if any. The caller must hold the
+// write lock.
+func (p *KeyManager) closeModule() {
+	if p.module == nil {
+		return
+	}
+	p.pool.Close()
+	closeModule(p.module)
+	p.module = nil
+	p.pool = nil
+	p.setEntries(make(map[string]*keyEntry))
+}
+
+func (p *KeyManager) getEntry(keyID string) *keyEntry {
+	p.entriesMu.RLock()
+	defer p.entriesMu.RUnlock()
+	return p.entries[keyID]
+}
+
+func (p *KeyManager) setEntries(entries map[string]*keyEntry) {
+	p.entriesMu.Lock()
+	defer p.entriesMu.Unlock()
+	p.entries = entries
+}
+
+func loadEntries(ctx context.Context, log logrus.FieldLogger, pool *sessionPool, prefix string) (_ map[string]*keyEntry, err error) {
+	sh, err := pool.Get(ctx)
+	if err != nil {
+		return nil, pkcs11Error.Wrap(err)
+	}
+	defer func() {
+		pool.Put(sh, err)
+	}()
+
+	privateKeys, err := findObjects(pool.m, sh, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PRIVATE_KEY),
+		p11.NewAttribute(p11.CKA_TOKEN, true),
+	})
+	if err != nil {
+		return nil, pkcs11Error.New("unable to find private keys: %v", err)
+	}
+
+	entries := make(map[string]*keyEntry)
+	for _, privateKey := range privateKeys {
+		attrs, err := pool.m.GetAttributeValue(sh, privateKey, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_LABEL, nil),
+			p11.NewAttribute(p11.CKA_ID, nil),
+		})
+		if err != nil {
+			return nil, pkcs11Error.New("unable to get private key attributes: %v", err)
+		}
+		label, hsmID := string(attrs[0].Value), attrs[1].Value
+		if !strings.HasPrefix(label, prefix) {
+			continue
+		}
+		keyID := strings.TrimPrefix(label, prefix)
+
+		if existing, ok := entries[keyID]; ok {
+			log.Warnf("Found multiple key pairs labeled %q; using the most recent one", label)
+			if bytes.Compare(existing.hsmID, hsmID) > 0 {
+				continue
+			}
+		}
+
+		publicKeys, err := findObjects(pool.m, sh, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PUBLIC_KEY),
+			p11.NewAttribute(p11.CKA_LABEL, label),
+			p11.NewAttribute(p11.CKA_ID, hsmID),
+		})
+		if err != nil {
+			return nil, pkcs11Error.New("unable to find public key for %q: %v", label, err)
+		}
+		if len(publicKeys) != 1 {
+			return nil, pkcs11Error.New("expected one public key for %q; found %d", label, len(publicKeys))
+		}
+
+		publicData, err := readPublicKey(pool.m, sh, publicKeys[0])
+		if err != nil {
+			return nil, pkcs11Error.New("unable to read public key for %q: %v", label, err)
+		}
+		publicData.Id = keyID
+
+		entries[keyID] = &keyEntry{
+			privateKey: privateKey,
+			publicKey:  publicKeys[0],
+			hsmID:      hsmID,
+			publicData: publicData,
+		}
+	}
+	return entries, nil
+}
+
+func findObjects(m module, sh p11.SessionHandle, template []*p11.Attribute) ([]p11.ObjectHandle, error) {
+	if err := m.FindObjectsInit(sh, template); err != nil {
+		return nil, err
+	}
+
+	var handles []p11.ObjectHandle
+	for {
+		batch, _, err := m.FindObjects(sh, findObjectsBatchSize)
+		if err != nil {
+			m.FindObjectsFinal(sh)
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		handles = append(handles, batch...)
+	}
+
+	if err := m.FindObjectsFinal(sh); err != nil {
+		return nil, err
+	}
+	return handles, nil
+}
+
+// readPublicKey reads the public key object and returns it without an id
+func readPublicKey(m module, sh p11.SessionHandle, o p11.ObjectHandle) (*keymanager.PublicKey, error) {
+	attrs, err := m.GetAttributeValue(sh, o, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_KEY_TYPE, nil),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var keyType keymanager.KeyType
+	var publicKey crypto.PublicKey
+	switch {
+	case bytes.Equal(attrs[0].Value, p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_EC).Value):
+		attrs, err := m.GetAttributeValue(sh, o, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_EC_PARAMS, nil),
+			p11.NewAttribute(p11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return nil, err
+		}
+		keyType, publicKey, err = parseECPublicKey(attrs[0].Value, attrs[1].Value)
+		if err != nil {
+			return nil, err
+		}
+	case bytes.Equal(attrs[0].Value, p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_RSA).Value):
+		attrs, err := m.GetAttributeValue(sh, o, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_MODULUS, nil),
+			p11.NewAttribute(p11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return nil, err
+		}
+		keyType, publicKey, err = parseRSAPublicKey(attrs[0].Value, attrs[1].Value)
+		if err != nil {
+			return nil, err
+		}
+	case bytes.Equal(attrs[0].Value, p11.NewAttribute(p11.CKA_KEY_TYPE, ckkECEdwards).Value):
+		attrs, err := m.GetAttributeValue(sh, o, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_EC_PARAMS, nil),
+			p11.NewAttribute(p11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return nil, err
+		}
+		keyType, publicKey, err = parseEdwardsPublicKey(attrs[0].Value, attrs[1].Value)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errs.New("unsupported key type")
+	}
+
+	pkixData, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &keymanager.PublicKey{
+		Type:     keyType,
+		PkixData: pkixData,
+	}, nil
+}
+
+func parseECPublicKey(ecParams, ecPoint []byte) (keymanager.KeyType, crypto.PublicKey, error) {
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(ecParams, &oid); err != nil {
+		return 0, nil, errs.New("unable to parse EC parameters: %v", err)
+	}
+
+	var keyType keymanager.KeyType
+	var curve elliptic.Curve
+	switch {
+	case oid.Equal(oidNamedCurveP256):
+		keyType, curve = keymanager.KeyType_EC_P256, elliptic.P256()
+	case oid.Equal(oidNamedCurveP384):
+		keyType, curve = keymanager.KeyType_EC_P384, elliptic.P384()
+	default:
+		return 0, nil, errs.New("unsupported curve %s", oid)
+	}
+
+	// CKA_EC_POINT is a DER encoded OCTET STRING, although some modules
+	// return the raw point
+	point := ecPoint
+	var octets []byte
+	if rest, err := asn1.Unmarshal(ecPoint, &octets); err == nil && len(rest) == 0 {
+		point = octets
+	}
+	x, y := elliptic.Unmarshal(curve, point)
+	if x == nil {
+		return 0, nil, errs.New("malformed EC point")
+	}
+	return keyType, &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func parseEdwardsPublicKey(ecParams, ecPoint []byte) (keymanager.KeyType, crypto.PublicKey, error) {
+	// the curve is identified either by OID or by name
+	var oid asn1.ObjectIdentifier
+	var name string
+	if _, err := asn1.Unmarshal(ecParams, &oid); err == nil {
+		if !oid.Equal(oidEd25519) {
+			return 0, nil, errs.New("unsupported curve %s", oid)
+		}
+	} else if _, err := asn1.Unmarshal(ecParams, &name); err == nil {
+		if name != "edwards25519" {
+			return 0, nil, errs.New("unsupported curve %q", name)
+		}
+	} else {
+		return 0, nil, errs.New("unable to parse EC parameters: %v", err)
+	}
+
+	// CKA_EC_POINT is a DER encoded OCTET STRING, although some modules
+	// return the raw point
+	point := ecPoint
+	var octets []byte
+	if rest, err := asn1.Unmarshal(ecPoint, &octets); err == nil && len(rest) == 0 {
+		point = octets
+	}
+	if len(point) != ed25519.PublicKeySize {
+		return 0, nil, errs.New("malformed Ed25519 point")
+	}
+	return keymanager.KeyType_ED25519, ed25519.PublicKey(point), nil
+}
+
+func parseRSAPublicKey(modulus, exponent []byte) (keymanager.KeyType, crypto.PublicKey, error) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}
+	switch bits := publicKey.N.BitLen(); bits {
+	case 1024:
+		return keymanager.KeyType_RSA_1024, publicKey, nil
+	case 2048:
+		return keymanager.KeyType_RSA_2048, publicKey, nil
+	case 4096:
+		return keymanager.KeyType_RSA_4096, publicKey, nil
+	default:
+		return 0, nil, errs.New("unsupported RSA key size %d", bits)
+	}
+}
+
+func keyPairTemplate(keyType keymanager.KeyType) (uint, []*p11.Attribute, error) {
+	switch keyType {
+	case keymanager.KeyType_EC_P256:
+		return ecKeyPairTemplate(oidNamedCurveP256)
+	case keymanager.KeyType_EC_P384:
+		return ecKeyPairTemplate(oidNamedCurveP384)
+	case keymanager.KeyType_RSA_1024:
+		return rsaKeyPairTemplate(1024)
+	case keymanager.KeyType_RSA_2048:
+		return rsaKeyPairTemplate(2048)
+	case keymanager.KeyType_RSA_4096:
+		return rsaKeyPairTemplate(4096)
+	case keymanager.KeyType_ED25519:
+		ecParams, err := asn1.Marshal(oidEd25519)
+		if err != nil {
+			return 0, nil, pkcs11Error.Wrap(err)
+		}
+		return ckmECEdwardsKeyPairGen, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_EC_PARAMS, ecParams),
+		}, nil
+	default:
+		return 0, nil, pkcs11Error.New("unsupported key type %q", keyType)
+	}
+}
+
+func ecKeyPairTemplate(oid asn1.ObjectIdentifier) (uint, []*p11.Attribute, error) {
+	ecParams, err := asn1.Marshal(oid)
+	if err != nil {
+		return 0, nil, pkcs11Error.Wrap(err)
+	}
+	return p11.CKM_EC_KEY_PAIR_GEN, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_EC_PARAMS, ecParams),
+	}, nil
+}
+
+func rsaKeyPairTemplate(bits int) (uint, []*p11.Attribute, error) {
+	return p11.CKM_RSA_PKCS_KEY_PAIR_GEN, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_MODULUS_BITS, bits),
+		p11.NewAttribute(p11.CKA_PUBLIC_EXPONENT, []byte{1, 0, 1}),
+	}, nil
+}
+
+func keyTypeAttribute(keyType keymanager.KeyType) uint {
+	if keyType == keymanager.KeyType_ED25519 {
+		return ckkECEdwards
+	}
+	if isECKeyType(keyType) {
+		return p11.CKK_EC
+	}
+	return p11.CKK_RSA
+}
+
+func signMechanism(keyType keymanager.KeyType, hash crypto.Hash, pssOptions *keymanager.PSSOptions, digest []byte) (*p11.Mechanism, []byte, error) {
+	if keyType == keymanager.KeyType_ED25519 {
+		// CKM_EDDSA without parameters is PureEdDSA over the message
+		return p11.NewMechanism(ckmEdDSA, nil), digest, nil
+	}
+	if isECKeyType(keyType) {
+		if pssOptions != nil {
+			return nil, nil, pkcs11Error.New("PSS options are only valid with RSA keys")
+		}
+		return p11.NewMechanism(p11.CKM_ECDSA, nil), digest, nil
+	}
+
+	if pssOptions != nil {
+		var hashMech, mgf uint
+		switch hash {
+		case crypto.SHA256:
+			hashMech, mgf = p11.CKM_SHA256, p11.CKG_MGF1_SHA256
+		case crypto.SHA384:
+			hashMech, mgf = p11.CKM_SHA384, p11.CKG_MGF1_SHA384
+		case crypto.SHA512:
+			hashMech, mgf = p11.CKM_SHA512, p11.CKG_MGF1_SHA512
+		default:
+			return nil, nil, pkcs11Error.New("hash algorithm %s is not supported with PSS", keymanager.HashAlgorithm(hash))
+		}
+		saltLength := int(pssOptions.SaltLength)
+		if saltLength <= 0 {
+			// both rsa.PSSSaltLengthAuto and rsa.PSSSaltLengthEqualsHash
+			// result in a salt as long as the hash
+			saltLength = hash.Size()
+		}
+		params := p11.NewPSSParams(hashMech, mgf, uint(saltLength))
+		return p11.NewMechanism(p11.CKM_RSA_PKCS_PSS, params), digest, nil
+	}
+
+	prefix, ok := digestInfoPrefixes[hash]
+	if !ok {
+		return nil, nil, pkcs11Error.New("hash algorithm %s is not supported with RSA keys", keymanager.HashAlgorithm(hash))
+	}
+	return p11.NewMechanism(p11.CKM_RSA_PKCS, nil), append(append([]byte{}, prefix...), digest...), nil
+}
+
+func isECKeyType(keyType keymanager.KeyType) bool {
+	return keyType == keymanager.KeyType_EC_P256 || keyType == keymanager.KeyType_EC_P384
+}
+
+func ecdsaSignatureToASN1(signature []byte) ([]byte, error) {
+	if len(signature) == 0 || len(signature)%2 != 0 {
+		return nil, errs.New("unexpected signature length %d", len(signature))
+	}
+	half := len(signature) / 2
+	return asn1.Marshal(struct {
+		R, S *big.Int
+	}{
+		R: new(big.Int).SetBytes(signature[:half]),
+		S: new(big.Int).SetBytes(signature[half:]),
+	})
+}
+
+func clonePublicKey(publicKey *keymanager.PublicKey) *keymanager.PublicKey {
+	return proto.Clone(publicKey).(*keymanager.PublicKey)
+}
@@ -0,0 +1,54 @@
+// +build !cgo
+
+package pkcs11
+
+import (
+	"context"
+
+	"github.com/zeebo/errs"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+)
+
+var (
+	pkcs11Error = errs.Class("keymanager(pkcs11)")
+)
+
+// KeyManager is a stub for builds without cgo, which the PKCS#11 bindings
+// require. It fails to configure.
+type KeyManager struct{}
+
+var _ keymanager.Plugin = (*KeyManager)(nil)
+
+func New() *KeyManager {
+	return &KeyManager{}
+}
+
+func (p *KeyManager) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	return nil, pkcs11Error.New("pkcs11 not supported in this build")
+}
+
+func (p *KeyManager) GetPluginInfo(ctx context.Context, req *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *KeyManager) GenerateKey(ctx context.Context, req *keymanager.GenerateKeyRequest) (*keymanager.GenerateKeyResponse, error) {
+	return nil, pkcs11Error.New("not configured")
+}
+
+func (p *KeyManager) GetPublicKey(ctx context.Context, req *keymanager.GetPublicKeyRequest) (*keymanager.GetPublicKeyResponse, error) {
+	return nil, pkcs11Error.New("not configured")
+}
+
+func (p *KeyManager) GetPublicKeys(ctx context.Context, req *keymanager.GetPublicKeysRequest) (*keymanager.GetPublicKeysResponse, error) {
+	return nil, pkcs11Error.New("not configured")
+}
+
+func (p *KeyManager) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error) {
+	return nil, pkcs11Error.New("not configured")
+}
+
+func (p *KeyManager) SignDataBatch(ctx context.Context, req *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	return nil, pkcs11Error.New("not configured")
+}
@@ -0,0 +1,459 @@
+// +build cgo
+
+package pkcs11
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"sync"
+	"testing"
+	"time"
+
+	p11 "github.com/miekg/pkcs11"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/test"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
+)
+
+const (
+	testConfig = `
+module_path = "/usr/lib/softhsm/libsofthsm2.so"
+token_label = "SPIRE"
+user_pin = "1234"
+`
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestKeyManager(t *testing.T) {
+	test.Run(t, func(t *testing.T) keymanager.Plugin {
+		return newKeyManager(t, newFakeModule(), testConfig)
+	})
+}
+
+func TestKeyManagerEd25519(t *testing.T) {
+	test.RunEd25519(t, func(t *testing.T) keymanager.Plugin {
+		return newKeyManager(t, newFakeModule(), testConfig)
+	})
+}
+
+func TestConfigureErrors(t *testing.T) {
+	for _, tt := range []struct {
+		config string
+		err    string
+	}{
+		{
+			config: `token_label = "SPIRE"`,
+			err:    "keymanager(pkcs11): module_path is required",
+		},
+		{
+			config: `module_path = "/lib.so"`,
+			err:    "keymanager(pkcs11): one of token_label or slot_id is required",
+		},
+		{
+			config: `module_path = "/lib.so" token_label = "SPIRE" slot_id = 1`,
+			err:    "keymanager(pkcs11): token_label and slot_id are mutually exclusive",
+		},
+		{
+			config: `module_path = "/lib.so" slot_id = -1`,
+			err:    "keymanager(pkcs11): slot_id must not be negative",
+		},
+		{
+			config: `module_path = "/lib.so" token_label = "OTHER"`,
+			err:    `keymanager(pkcs11): no token with label "OTHER"`,
+		},
+	} {
+		p := New()
+		p.hooks.openModule = func(string) (module, error) {
+			return newFakeModule(), nil
+		}
+		_, err := p.Configure(ctx, &spi.ConfigureRequest{
+			Configuration: tt.config,
+		})
+		require.EqualError(t, err, tt.err, "config: %s", tt.config)
+	}
+}
+
+func TestConfigureWithSlotID(t *testing.T) {
+	m := newFakeModule()
+	newKeyManager(t, m, `
+module_path = "/lib.so"
+slot_id = 1
+`)
+	require.Equal(t, uint(1), m.sessionSlot)
+	require.Equal(t, 0, m.logins)
+}
+
+func TestKeysAreLoadedOnConfigure(t *testing.T) {
+	m := newFakeModule()
+
+	p := keymanager.NewBuiltIn(newKeyManager(t, m, testConfig))
+	resp, err := p.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "x509-CA-A",
+		KeyType: keymanager.KeyType_EC_P384,
+	})
+	require.NoError(t, err)
+
+	// keys outside of the prefix are ignored
+	other := keymanager.NewBuiltIn(newKeyManager(t, m, testConfig+`key_label_prefix = "other-"`))
+	_, err = other.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	require.NoError(t, err)
+
+	p = keymanager.NewBuiltIn(newKeyManager(t, m, testConfig))
+	getResp, err := p.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []*keymanager.PublicKey{resp.PublicKey}, getResp.PublicKeys)
+}
+
+func TestGenerateKeyDestroysPreviousKeyPair(t *testing.T) {
+	m := newFakeModule()
+	p := keymanager.NewBuiltIn(newKeyManager(t, m, testConfig))
+
+	for i := 0; i < 2; i++ {
+		_, err := p.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+			KeyId:   "KEY",
+			KeyType: keymanager.KeyType_EC_P256,
+		})
+		require.NoError(t, err)
+	}
+	require.Len(t, m.objects, 2)
+}
+
+func TestSessionPoolLimitsSessions(t *testing.T) {
+	m := newFakeModule()
+	pool := newSessionPool(m, 0, "1234", 1)
+
+	sh, err := pool.Get(ctx)
+	require.NoError(t, err)
+
+	// the pool is exhausted so Get blocks until the context is done
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = pool.Get(timeoutCtx)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	// returned sessions are reused
+	pool.Put(sh, nil)
+	sh2, err := pool.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, sh, sh2)
+
+	// sessions returned with an error are closed
+	pool.Put(sh2, errs.New("oh no"))
+	require.Empty(t, m.sessions)
+	require.Equal(t, 1, m.logins)
+}
+
+func newKeyManager(t *testing.T, m *fakeModule, config string) *KeyManager {
+	p := New()
+	p.hooks.openModule = func(string) (module, error) {
+		return m, nil
+	}
+	resp, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: config,
+	})
+	require.NoError(t, err)
+	require.Equal(t, &spi.ConfigureResponse{}, resp)
+	return p
+}
+
+type fakeObject struct {
+	attrs  []*p11.Attribute
+	signer crypto.Signer
+}
+
+func (o *fakeObject) attr(typ uint) *p11.Attribute {
+	for _, attr := range o.attrs {
+		if attr.Type == typ {
+			return attr
+		}
+	}
+	return nil
+}
+
+type fakeSession struct {
+	found    []p11.ObjectHandle
+	signKey  *fakeObject
+	signMech *p11.Mechanism
+}
+
+// fakeModule is an in-memory PKCS#11 module with a single token labeled
+// "SPIRE" in slot 0.
+type fakeModule struct {
+	mu          sync.Mutex
+	nextHandle  uint
+	objects     map[p11.ObjectHandle]*fakeObject
+	sessions    map[p11.SessionHandle]*fakeSession
+	sessionSlot uint
+	logins      int
+}
+
+func newFakeModule() *fakeModule {
+	return &fakeModule{
+		objects:  make(map[p11.ObjectHandle]*fakeObject),
+		sessions: make(map[p11.SessionHandle]*fakeSession),
+	}
+}
+
+func (m *fakeModule) Initialize() error { return nil }
+func (m *fakeModule) Finalize() error   { return nil }
+func (m *fakeModule) Destroy()          {}
+
+func (m *fakeModule) GetSlotList(tokenPresent bool) ([]uint, error) {
+	return []uint{0, 1}, nil
+}
+
+func (m *fakeModule) GetTokenInfo(slotID uint) (p11.TokenInfo, error) {
+	if slotID == 0 {
+		return p11.TokenInfo{Label: "SPIRE                           "}, nil
+	}
+	return p11.TokenInfo{Label: "EMPTY"}, nil
+}
+
+func (m *fakeModule) OpenSession(slotID uint, flags uint) (p11.SessionHandle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextHandle++
+	sh := p11.SessionHandle(m.nextHandle)
+	m.sessions[sh] = &fakeSession{}
+	m.sessionSlot = slotID
+	return sh, nil
+}
+
+func (m *fakeModule) CloseSession(sh p11.SessionHandle) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, sh)
+	return nil
+}
+
+func (m *fakeModule) Login(sh p11.SessionHandle, userType uint, pin string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pin != "1234" {
+		return p11.Error(p11.CKR_PIN_INCORRECT)
+	}
+	m.logins++
+	return nil
+}
+
+func (m *fakeModule) FindObjectsInit(sh p11.SessionHandle, temp []*p11.Attribute) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, err := m.session(sh)
+	if err != nil {
+		return err
+	}
+	session.found = nil
+next:
+	for oh, o := range m.objects {
+		for _, want := range temp {
+			if got := o.attr(want.Type); got == nil || !bytes.Equal(got.Value, want.Value) {
+				continue next
+			}
+		}
+		session.found = append(session.found, oh)
+	}
+	return nil
+}
+
+func (m *fakeModule) FindObjects(sh p11.SessionHandle, max int) ([]p11.ObjectHandle, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, err := m.session(sh)
+	if err != nil {
+		return nil, false, err
+	}
+	n := len(session.found)
+	if n > max {
+		n = max
+	}
+	found := session.found[:n]
+	session.found = session.found[n:]
+	return found, len(session.found) > 0, nil
+}
+
+func (m *fakeModule) FindObjectsFinal(sh p11.SessionHandle) error {
+	return nil
+}
+
+func (m *fakeModule) GenerateKeyPair(sh p11.SessionHandle, mech []*p11.Mechanism, public, private []*p11.Attribute) (p11.ObjectHandle, p11.ObjectHandle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	publicKey := &fakeObject{attrs: append([]*p11.Attribute{}, public...)}
+	var signer crypto.Signer
+	switch mech[0].Mechanism {
+	case p11.CKM_EC_KEY_PAIR_GEN:
+		var oid asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(publicKey.attr(p11.CKA_EC_PARAMS).Value, &oid); err != nil {
+			return 0, 0, err
+		}
+		curve := elliptic.P256()
+		if oid.Equal(oidNamedCurveP384) {
+			curve = elliptic.P384()
+		}
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return 0, 0, err
+		}
+		point, err := asn1.Marshal(elliptic.Marshal(curve, key.X, key.Y))
+		if err != nil {
+			return 0, 0, err
+		}
+		publicKey.attrs = append(publicKey.attrs, p11.NewAttribute(p11.CKA_EC_POINT, point))
+		signer = key
+	case p11.CKM_RSA_PKCS_KEY_PAIR_GEN:
+		bits := 0
+		for _, b := range []int{1024, 2048, 4096} {
+			if bytes.Equal(publicKey.attr(p11.CKA_MODULUS_BITS).Value, p11.NewAttribute(p11.CKA_MODULUS_BITS, b).Value) {
+				bits = b
+			}
+		}
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return 0, 0, err
+		}
+		publicKey.attrs = append(publicKey.attrs, p11.NewAttribute(p11.CKA_MODULUS, key.N.Bytes()))
+		signer = key
+	case ckmECEdwardsKeyPairGen:
+		publicKeyBytes, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return 0, 0, err
+		}
+		point, err := asn1.Marshal([]byte(publicKeyBytes))
+		if err != nil {
+			return 0, 0, err
+		}
+		publicKey.attrs = append(publicKey.attrs, p11.NewAttribute(p11.CKA_EC_POINT, point))
+		signer = key
+	default:
+		return 0, 0, p11.Error(p11.CKR_MECHANISM_INVALID)
+	}
+
+	m.nextHandle++
+	publicHandle := p11.ObjectHandle(m.nextHandle)
+	m.objects[publicHandle] = publicKey
+	m.nextHandle++
+	privateHandle := p11.ObjectHandle(m.nextHandle)
+	m.objects[privateHandle] = &fakeObject{
+		attrs:  append([]*p11.Attribute{}, private...),
+		signer: signer,
+	}
+	return publicHandle, privateHandle, nil
+}
+
+func (m *fakeModule) GetAttributeValue(sh p11.SessionHandle, oh p11.ObjectHandle, a []*p11.Attribute) ([]*p11.Attribute, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.objects[oh]
+	if !ok {
+		return nil, p11.Error(p11.CKR_OBJECT_HANDLE_INVALID)
+	}
+	var attrs []*p11.Attribute
+	for _, want := range a {
+		attr := o.attr(want.Type)
+		if attr == nil {
+			return nil, p11.Error(p11.CKR_ATTRIBUTE_TYPE_INVALID)
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+func (m *fakeModule) DestroyObject(sh p11.SessionHandle, oh p11.ObjectHandle) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.objects[oh]; !ok {
+		return p11.Error(p11.CKR_OBJECT_HANDLE_INVALID)
+	}
+	delete(m.objects, oh)
+	return nil
+}
+
+func (m *fakeModule) SignInit(sh p11.SessionHandle, mech []*p11.Mechanism, oh p11.ObjectHandle) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, err := m.session(sh)
+	if err != nil {
+		return err
+	}
+	o, ok := m.objects[oh]
+	if !ok || o.signer == nil {
+		return p11.Error(p11.CKR_KEY_HANDLE_INVALID)
+	}
+	session.signKey = o
+	session.signMech = mech[0]
+	return nil
+}
+
+func (m *fakeModule) Sign(sh p11.SessionHandle, message []byte) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, err := m.session(sh)
+	if err != nil {
+		return nil, err
+	}
+	if session.signKey == nil {
+		return nil, p11.Error(p11.CKR_OPERATION_NOT_INITIALIZED)
+	}
+	key, mech := session.signKey, session.signMech
+	session.signKey, session.signMech = nil, nil
+
+	switch mech.Mechanism {
+	case p11.CKM_ECDSA:
+		privateKey := key.signer.(*ecdsa.PrivateKey)
+		r, s, err := ecdsa.Sign(rand.Reader, privateKey, message)
+		if err != nil {
+			return nil, err
+		}
+		size := (privateKey.Curve.Params().BitSize + 7) / 8
+		out := make([]byte, size*2)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(out[size-len(rb):size], rb)
+		copy(out[2*size-len(sb):], sb)
+		return out, nil
+	case ckmEdDSA:
+		return ed25519.Sign(key.signer.(ed25519.PrivateKey), message), nil
+	case p11.CKM_RSA_PKCS:
+		// the message is already a DigestInfo
+		return rsa.SignPKCS1v15(rand.Reader, key.signer.(*rsa.PrivateKey), 0, message)
+	case p11.CKM_RSA_PKCS_PSS:
+		var hash crypto.Hash
+		switch len(message) {
+		case 32:
+			hash = crypto.SHA256
+		case 48:
+			hash = crypto.SHA384
+		case 64:
+			hash = crypto.SHA512
+		}
+		return rsa.SignPSS(rand.Reader, key.signer.(*rsa.PrivateKey), hash, message, &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+		})
+	default:
+		return nil, p11.Error(p11.CKR_MECHANISM_INVALID)
+	}
+}
+
+func (m *fakeModule) session(sh p11.SessionHandle) (*fakeSession, error) {
+	session, ok := m.sessions[sh]
+	if !ok {
+		return nil, p11.Error(p11.CKR_SESSION_HANDLE_INVALID)
+	}
+	return session, nil
+}
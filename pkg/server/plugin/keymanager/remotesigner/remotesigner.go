@@ -0,0 +1,359 @@
+package remotesigner
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/hcl"
+	"github.com/zeebo/errs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/base"
+	signerapi "github.com/spiffe/spire/proto/api/remotesigner"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+)
+
+const (
+	unixPrefix = "unix://"
+
+	defaultTimeout = 10 * time.Second
+)
+
+var (
+	signerError = errs.Class("keymanager(remote_signer)")
+)
+
+type configuration struct {
+	// Address is the address of the remote signer, either host:port or
+	// unix:///path/to/socket
+	Address string `hcl:"address"`
+
+	// CABundlePath is the path to the CA certificates used to verify the
+	// remote signer. If unset, the system roots are used.
+	CABundlePath string `hcl:"ca_bundle_path"`
+
+	// CertPath and KeyPath are the certificate and private key presented to
+	// the remote signer for mutual TLS
+	CertPath string `hcl:"cert_path"`
+	KeyPath  string `hcl:"key_path"`
+
+	// Insecure disables TLS for host:port addresses. TLS is never used for
+	// unix domain sockets.
+	Insecure bool `hcl:"insecure"`
+
+	// Timeout bounds each call to the remote signer
+	Timeout string `hcl:"timeout"`
+}
+
+type KeyManager struct {
+	mu      sync.RWMutex
+	conn    *grpc.ClientConn
+	client  signerapi.RemoteSignerClient
+	timeout time.Duration
+	keys    map[string]*keymanager.PublicKey
+}
+
+var _ keymanager.Plugin = (*KeyManager)(nil)
+
+func New() *KeyManager {
+	return &KeyManager{
+		keys: make(map[string]*keymanager.PublicKey),
+	}
+}
+
+func (p *KeyManager) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, signerError.New("unable to decode configuration: %v", err)
+	}
+
+	if config.Address == "" {
+		return nil, signerError.New("address is required")
+	}
+	if (config.CertPath == "") != (config.KeyPath == "") {
+		return nil, signerError.New("cert_path and key_path must be set together")
+	}
+	isUnix := strings.HasPrefix(config.Address, unixPrefix)
+	if (isUnix || config.Insecure) && (config.CABundlePath != "" || config.CertPath != "") {
+		return nil, signerError.New("TLS options cannot be used without TLS")
+	}
+
+	timeout := defaultTimeout
+	if config.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(config.Timeout)
+		if err != nil {
+			return nil, signerError.New("invalid timeout: %v", err)
+		}
+	}
+
+	conn, err := dial(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	client := signerapi.NewRemoteSignerClient(conn)
+
+	keys, err := loadKeys(ctx, client, timeout)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn = conn
+	p.client = client
+	p.timeout = timeout
+	p.keys = keys
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *KeyManager) GetPluginInfo(ctx context.Context, req *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *KeyManager) GenerateKey(ctx context.Context, req *keymanager.GenerateKeyRequest) (*keymanager.GenerateKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, signerError.New("key id is required")
+	}
+	if req.KeyType == keymanager.KeyType_UNSPECIFIED_KEY_TYPE {
+		return nil, signerError.New("key type is required")
+	}
+
+	client, timeout, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := client.GenerateKey(ctx, &signerapi.GenerateKeyRequest{
+		KeyId:   req.KeyId,
+		KeyType: signerapi.KeyType(req.KeyType),
+	})
+	if err != nil {
+		return nil, signerError.New("unable to generate key %q: %v", req.KeyId, err)
+	}
+
+	publicKey, err := convertPublicKey(resp.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if publicKey.Id != req.KeyId || publicKey.Type != req.KeyType {
+		return nil, signerError.New("remote signer returned key %q of type %s; expected key %q of type %s", publicKey.Id, publicKey.Type, req.KeyId, req.KeyType)
+	}
+
+	p.mu.Lock()
+	p.keys[req.KeyId] = publicKey
+	p.mu.Unlock()
+
+	return &keymanager.GenerateKeyResponse{
+		PublicKey: clonePublicKey(publicKey),
+	}, nil
+}
+
+func (p *KeyManager) GetPublicKey(ctx context.Context, req *keymanager.GetPublicKeyRequest) (*keymanager.GetPublicKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, signerError.New("key id is required")
+	}
+
+	resp := new(keymanager.GetPublicKeyResponse)
+	if publicKey := p.getKey(req.KeyId); publicKey != nil {
+		resp.PublicKey = clonePublicKey(publicKey)
+	}
+	return resp, nil
+}
+
+func (p *KeyManager) GetPublicKeys(ctx context.Context, req *keymanager.GetPublicKeysRequest) (*keymanager.GetPublicKeysResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	resp := new(keymanager.GetPublicKeysResponse)
+	for _, publicKey := range p.keys {
+		resp.PublicKeys = append(resp.PublicKeys, clonePublicKey(publicKey))
+	}
+	sort.Slice(resp.PublicKeys, func(i, j int) bool {
+		return resp.PublicKeys[i].Id < resp.PublicKeys[j].Id
+	})
+	return resp, nil
+}
+
+func (p *KeyManager) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error) {
+	if req.KeyId == "" {
+		return nil, signerError.New("key id is required")
+	}
+	if req.SignerOpts == nil {
+		return nil, signerError.New("signer opts is required")
+	}
+
+	signReq := &signerapi.SignDataRequest{
+		KeyId: req.KeyId,
+		Data:  req.Data,
+	}
+	switch opts := req.SignerOpts.(type) {
+	case *keymanager.SignDataRequest_HashAlgorithm:
+		signReq.HashAlgorithm = signerapi.HashAlgorithm(opts.HashAlgorithm)
+	case *keymanager.SignDataRequest_PssOptions:
+		if opts.PssOptions == nil {
+			return nil, signerError.New("PSS options are nil")
+		}
+		signReq.HashAlgorithm = signerapi.HashAlgorithm(opts.PssOptions.HashAlgorithm)
+		signReq.Pss = true
+		signReq.PssSaltLength = opts.PssOptions.SaltLength
+	default:
+		return nil, signerError.New("unsupported signer opts type %T", opts)
+	}
+
+	publicKey := p.getKey(req.KeyId)
+
+	// Ed25519 signs the message itself and so does not use a hash algorithm
+	if signReq.HashAlgorithm == signerapi.HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM &&
+		(publicKey == nil || publicKey.Type != keymanager.KeyType_ED25519) {
+		return nil, signerError.New("hash algorithm is required")
+	}
+	if publicKey == nil {
+		return nil, signerError.New("no such key %q", req.KeyId)
+	}
+
+	client, timeout, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := client.SignData(ctx, signReq)
+	if err != nil {
+		return nil, signerError.New("keypair %q signing operation failed: %v", req.KeyId, err)
+	}
+
+	return &keymanager.SignDataResponse{
+		Signature: resp.Signature,
+	}, nil
+}
+
+// SignDataBatch signs each request in turn since the Remote Signer API has no batch operation.
+func (p *KeyManager) SignDataBatch(ctx context.Context, req *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	return base.SignDataEach(ctx, req, p.SignData)
+}
+
+func (p *KeyManager) getClient() (signerapi.RemoteSignerClient, time.Duration, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.client == nil {
+		return nil, 0, signerError.New("not configured")
+	}
+	return p.client, p.timeout, nil
+}
+
+func (p *KeyManager) getKey(keyID string) *keymanager.PublicKey {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.keys[keyID]
+}
+
+func dial(ctx context.Context, config *configuration) (*grpc.ClientConn, error) {
+	if strings.HasPrefix(config.Address, unixPrefix) {
+		conn, err := grpc.DialContext(ctx, strings.TrimPrefix(config.Address, unixPrefix),
+			grpc.WithInsecure(),
+			grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+				return net.DialTimeout("unix", addr, timeout)
+			}))
+		if err != nil {
+			return nil, signerError.New("unable to dial remote signer: %v", err)
+		}
+		return conn, nil
+	}
+
+	var creds grpc.DialOption
+	if config.Insecure {
+		creds = grpc.WithInsecure()
+	} else {
+		tlsConfig := new(tls.Config)
+		if config.CABundlePath != "" {
+			roots, err := util.LoadCertPool(config.CABundlePath)
+			if err != nil {
+				return nil, signerError.New("unable to load CA bundle: %v", err)
+			}
+			tlsConfig.RootCAs = roots
+		}
+		if config.CertPath != "" {
+			cert, err := tls.LoadX509KeyPair(config.CertPath, config.KeyPath)
+			if err != nil {
+				return nil, signerError.New("unable to load client certificate: %v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+
+	conn, err := grpc.DialContext(ctx, config.Address, creds)
+	if err != nil {
+		return nil, signerError.New("unable to dial remote signer: %v", err)
+	}
+	return conn, nil
+}
+
+func loadKeys(ctx context.Context, client signerapi.RemoteSignerClient, timeout time.Duration) (map[string]*keymanager.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := client.ListPublicKeys(ctx, &signerapi.ListPublicKeysRequest{})
+	if err != nil {
+		return nil, signerError.New("unable to list public keys: %v", err)
+	}
+
+	keys := make(map[string]*keymanager.PublicKey)
+	for _, signerKey := range resp.PublicKeys {
+		publicKey, err := convertPublicKey(signerKey)
+		if err != nil {
+			return nil, err
+		}
+		keys[publicKey.Id] = publicKey
+	}
+	return keys, nil
+}
+
+// convertPublicKey validates a public key returned by the remote signer and
+// converts it to a KeyManager public key
+func convertPublicKey(signerKey *signerapi.PublicKey) (*keymanager.PublicKey, error) {
+	if signerKey == nil {
+		return nil, signerError.New("remote signer returned no public key")
+	}
+	if signerKey.Id == "" {
+		return nil, signerError.New("remote signer returned a public key without an id")
+	}
+	keyType := keymanager.KeyType(signerKey.Type)
+	if _, ok := keymanager.KeyType_name[int32(keyType)]; !ok || keyType == keymanager.KeyType_UNSPECIFIED_KEY_TYPE {
+		return nil, signerError.New("remote signer returned key %q with unsupported type %d", signerKey.Id, signerKey.Type)
+	}
+	if _, err := x509.ParsePKIXPublicKey(signerKey.PkixData); err != nil {
+		return nil, signerError.New("remote signer returned key %q with malformed public key: %v", signerKey.Id, err)
+	}
+	return &keymanager.PublicKey{
+		Id:       signerKey.Id,
+		Type:     keyType,
+		PkixData: signerKey.PkixData,
+	}, nil
+}
+
+func clonePublicKey(publicKey *keymanager.PublicKey) *keymanager.PublicKey {
+	return proto.Clone(publicKey).(*keymanager.PublicKey)
+}
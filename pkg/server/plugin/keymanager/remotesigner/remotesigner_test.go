@@ -0,0 +1,411 @@
+package remotesigner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/memory"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/test"
+	signerapi "github.com/spiffe/spire/proto/api/remotesigner"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestKeyManager(t *testing.T) {
+	signer := startFakeSigner(t)
+	defer signer.Stop()
+
+	test.Run(t, signer.makeKeyManager)
+}
+
+func TestKeyManagerEd25519(t *testing.T) {
+	signer := startFakeSigner(t)
+	defer signer.Stop()
+
+	test.RunEd25519(t, signer.makeKeyManager)
+}
+
+func TestRemoteSigner(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	signer *fakeSigner
+}
+
+func (s *Suite) SetupTest() {
+	s.signer = startFakeSigner(s.T())
+}
+
+func (s *Suite) TearDownTest() {
+	s.signer.Stop()
+}
+
+func (s *Suite) TestConfigureErrors() {
+	for _, tt := range []struct {
+		config string
+		err    string
+	}{
+		{
+			config: ``,
+			err:    "keymanager(remote_signer): address is required",
+		},
+		{
+			config: `address = "localhost:8443" cert_path = "cert.pem"`,
+			err:    "keymanager(remote_signer): cert_path and key_path must be set together",
+		},
+		{
+			config: `address = "unix:///tmp/signer.sock" ca_bundle_path = "ca.pem"`,
+			err:    "keymanager(remote_signer): TLS options cannot be used without TLS",
+		},
+		{
+			config: `address = "localhost:8443" insecure = true cert_path = "cert.pem" key_path = "key.pem"`,
+			err:    "keymanager(remote_signer): TLS options cannot be used without TLS",
+		},
+		{
+			config: `address = "localhost:8443" timeout = "soon"`,
+			err:    `keymanager(remote_signer): invalid timeout: time: invalid duration "soon"`,
+		},
+		{
+			config: `address = "localhost:8443" ca_bundle_path = "/does/not/exist.pem"`,
+			err:    "keymanager(remote_signer): unable to load CA bundle: open /does/not/exist.pem: no such file or directory",
+		},
+	} {
+		p := New()
+		_, err := p.Configure(ctx, &spi.ConfigureRequest{
+			Configuration: tt.config,
+		})
+		s.Require().EqualError(err, tt.err, "config: %s", tt.config)
+	}
+}
+
+func (s *Suite) TestGenerateKeyBeforeConfigure() {
+	p := New()
+	resp, err := p.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, "keymanager(remote_signer): not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestKeysAreLoadedOnConfigure() {
+	m := keymanager.NewBuiltIn(s.signer.makeKeyManager(s.T()))
+	a, err := m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "A",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	// configure a new key manager against the same signer
+	p := s.signer.newKeyManager(s.T(), "")
+	resp, err := p.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal([]*keymanager.PublicKey{a.PublicKey}, resp.PublicKeys)
+}
+
+func (s *Suite) TestConfigureFailsWhenSignerIsUnavailable() {
+	s.signer.Stop()
+
+	p := New()
+	_, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`address = "unix://%s" timeout = "100ms"`, s.signer.socketPath),
+	})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "keymanager(remote_signer): unable to list public keys:")
+}
+
+func (s *Suite) TestGenerateKeyFails() {
+	m := keymanager.NewBuiltIn(s.signer.makeKeyManager(s.T()))
+
+	s.signer.setFailure(fmt.Errorf("HSM unavailable"))
+	_, err := m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, `keymanager(remote_signer): unable to generate key "KEY": rpc error: code = Unknown desc = HSM unavailable`)
+}
+
+func (s *Suite) TestGenerateKeyRejectsMismatchedKey() {
+	m := keymanager.NewBuiltIn(s.signer.makeKeyManager(s.T()))
+
+	s.signer.setKeyTypeOverride(signerapi.KeyType_EC_P384)
+	_, err := m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, `keymanager(remote_signer): remote signer returned key "KEY" of type EC_P384; expected key "KEY" of type EC_P256`)
+}
+
+func (s *Suite) TestSignDataOverTCP() {
+	m := keymanager.NewBuiltIn(s.signer.makeKeyManager(s.T()))
+	_, err := m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	// serve the same signer over mutual TLS
+	dir := s.signer.dir
+	caKey, caCert := createCertificate(s.T(), nil, nil, "CA")
+	serverKey, serverCert := createCertificate(s.T(), caKey, caCert, "localhost")
+	clientKey, clientCert := createCertificate(s.T(), caKey, caCert, "spire-server")
+	writeCertificate(s.T(), filepath.Join(dir, "ca.pem"), caCert)
+	writeCertificate(s.T(), filepath.Join(dir, "client.pem"), clientCert)
+	writePrivateKey(s.T(), filepath.Join(dir, "client.key"), clientKey)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	listener, err := net.Listen("tcp", "localhost:0")
+	s.Require().NoError(err)
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    roots,
+	})))
+	signerapi.RegisterRemoteSignerServer(server, s.signer)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	s.Require().NoError(err)
+
+	p := New()
+	_, err = p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+			address = "localhost:%s"
+			ca_bundle_path = %q
+			cert_path = %q
+			key_path = %q
+		`, port, filepath.Join(dir, "ca.pem"), filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")),
+	})
+	s.Require().NoError(err)
+
+	resp, err := p.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		Data:  make([]byte, 32),
+		SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+		},
+	})
+	s.Require().NoError(err)
+	s.Require().NotEmpty(resp.Signature)
+}
+
+// fakeSigner implements the remote signer API on top of the memory key
+// manager
+type fakeSigner struct {
+	dir        string
+	socketPath string
+	server     *grpc.Server
+
+	mu              sync.Mutex
+	km              *memory.KeyManager
+	failure         error
+	keyTypeOverride signerapi.KeyType
+}
+
+func startFakeSigner(t *testing.T) *fakeSigner {
+	dir, err := ioutil.TempDir("", "keymanager-remotesigner-test")
+	require.NoError(t, err)
+
+	s := &fakeSigner{
+		dir:        dir,
+		socketPath: filepath.Join(dir, "signer.sock"),
+		server:     grpc.NewServer(),
+	}
+	s.reset()
+
+	listener, err := net.Listen("unix", s.socketPath)
+	require.NoError(t, err)
+	signerapi.RegisterRemoteSignerServer(s.server, s)
+	go s.server.Serve(listener)
+	return s
+}
+
+func (s *fakeSigner) Stop() {
+	s.server.Stop()
+	os.RemoveAll(s.dir)
+}
+
+// makeKeyManager resets the signer and returns a key manager configured to
+// use it
+func (s *fakeSigner) makeKeyManager(t *testing.T) keymanager.Plugin {
+	s.reset()
+	return s.newKeyManager(t, "")
+}
+
+func (s *fakeSigner) newKeyManager(t *testing.T, extraConfig string) *KeyManager {
+	p := New()
+	resp, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf("address = \"unix://%s\"\n%s", s.socketPath, extraConfig),
+	})
+	require.NoError(t, err)
+	require.Equal(t, &spi.ConfigureResponse{}, resp)
+	return p
+}
+
+func (s *fakeSigner) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.km = memory.New()
+	s.failure = nil
+	s.keyTypeOverride = signerapi.KeyType_UNSPECIFIED_KEY_TYPE
+}
+
+func (s *fakeSigner) setFailure(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failure = err
+}
+
+func (s *fakeSigner) setKeyTypeOverride(keyType signerapi.KeyType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyTypeOverride = keyType
+}
+
+func (s *fakeSigner) state() (*memory.KeyManager, signerapi.KeyType, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.km, s.keyTypeOverride, s.failure
+}
+
+func (s *fakeSigner) GenerateKey(ctx context.Context, req *signerapi.GenerateKeyRequest) (*signerapi.GenerateKeyResponse, error) {
+	km, keyTypeOverride, err := s.state()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := km.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   req.KeyId,
+		KeyType: keymanager.KeyType(req.KeyType),
+	})
+	if err != nil {
+		return nil, err
+	}
+	publicKey := convertToSignerKey(resp.PublicKey)
+	if keyTypeOverride != signerapi.KeyType_UNSPECIFIED_KEY_TYPE {
+		publicKey.Type = keyTypeOverride
+	}
+	return &signerapi.GenerateKeyResponse{
+		PublicKey: publicKey,
+	}, nil
+}
+
+func (s *fakeSigner) ListPublicKeys(ctx context.Context, req *signerapi.ListPublicKeysRequest) (*signerapi.ListPublicKeysResponse, error) {
+	km, _, err := s.state()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := km.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	if err != nil {
+		return nil, err
+	}
+	out := new(signerapi.ListPublicKeysResponse)
+	for _, publicKey := range resp.PublicKeys {
+		out.PublicKeys = append(out.PublicKeys, convertToSignerKey(publicKey))
+	}
+	return out, nil
+}
+
+func (s *fakeSigner) SignData(ctx context.Context, req *signerapi.SignDataRequest) (*signerapi.SignDataResponse, error) {
+	km, _, err := s.state()
+	if err != nil {
+		return nil, err
+	}
+	signReq := &keymanager.SignDataRequest{
+		KeyId: req.KeyId,
+		Data:  req.Data,
+	}
+	if req.Pss {
+		signReq.SignerOpts = &keymanager.SignDataRequest_PssOptions{
+			PssOptions: &keymanager.PSSOptions{
+				HashAlgorithm: keymanager.HashAlgorithm(req.HashAlgorithm),
+				SaltLength:    req.PssSaltLength,
+			},
+		}
+	} else {
+		signReq.SignerOpts = &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm(req.HashAlgorithm),
+		}
+	}
+	resp, err := km.SignData(ctx, signReq)
+	if err != nil {
+		return nil, err
+	}
+	return &signerapi.SignDataResponse{
+		Signature: resp.Signature,
+	}, nil
+}
+
+func convertToSignerKey(publicKey *keymanager.PublicKey) *signerapi.PublicKey {
+	return &signerapi.PublicKey{
+		Id:       publicKey.Id,
+		Type:     signerapi.KeyType(publicKey.Type),
+		PkixData: publicKey.PkixData,
+	}
+}
+
+func createCertificate(t *testing.T, parentKey *ecdsa.PrivateKey, parent *x509.Certificate, name string) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	} else {
+		tmpl.DNSNames = []string{name}
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+	return key, cert
+}
+
+func writeCertificate(t *testing.T, path string, cert *x509.Certificate) {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	require.NoError(t, ioutil.WriteFile(path, certPEM, 0600))
+}
+
+func writePrivateKey(t *testing.T, path string, key *ecdsa.PrivateKey) {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	require.NoError(t, ioutil.WriteFile(path, keyPEM, 0600))
+}
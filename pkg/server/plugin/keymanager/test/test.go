@@ -2,9 +2,13 @@ package test
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"math/big"
 	"testing"
@@ -24,17 +28,28 @@ type Maker func(t *testing.T) keymanager.Plugin
 // the maker function is called. the returned key manager is expected to be
 // already configured.
 func Run(t *testing.T, maker Maker) {
-	suite.Run(t, &baseSuite{maker: maker})
+	suite.Run(t, &baseSuite{keyManagerSuite: keyManagerSuite{maker: maker}})
 }
 
-type baseSuite struct {
+// RunEd25519 runs tests for Ed25519 keys, which only some key managers
+// support.
+func RunEd25519(t *testing.T, maker Maker) {
+	suite.Run(t, &ed25519Suite{keyManagerSuite: keyManagerSuite{maker: maker}})
+}
+
+// keyManagerSuite holds the setup and helpers shared by the suites
+type keyManagerSuite struct {
 	suite.Suite
 
 	maker Maker
 	m     *keymanager.BuiltIn
 }
 
-func (s *baseSuite) SetupTest() {
+type baseSuite struct {
+	keyManagerSuite
+}
+
+func (s *keyManagerSuite) SetupTest() {
 	s.m = keymanager.NewBuiltIn(s.maker(s.T()))
 }
 
@@ -210,7 +225,7 @@ func (s *baseSuite) TestSignDataRSAPSS() {
 	s.testSignData(keymanager.KeyType_RSA_1024, x509.SHA256WithRSAPSS)
 }
 
-func (s *baseSuite) testSignData(keyType keymanager.KeyType, signatureAlgorithm x509.SignatureAlgorithm) {
+func (s *keyManagerSuite) testSignData(keyType keymanager.KeyType, signatureAlgorithm x509.SignatureAlgorithm) {
 	// create a new key
 	generateResp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
 		KeyId:   "KEY",
@@ -279,7 +294,174 @@ func (s *baseSuite) TestSignDataNoKey() {
 	s.Require().Nil(resp)
 }
 
-func (s *baseSuite) requireErrorContains(err error, contains string) {
+func (s *baseSuite) TestSignDataBatch() {
+	ecResp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "EC",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	ecKey, err := x509.ParsePKIXPublicKey(ecResp.PublicKey.PkixData)
+	s.Require().NoError(err)
+	rsaResp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "RSA",
+		KeyType: keymanager.KeyType_RSA_1024,
+	})
+	s.Require().NoError(err)
+	rsaKey, err := x509.ParsePKIXPublicKey(rsaResp.PublicKey.PkixData)
+	s.Require().NoError(err)
+
+	digests := [][]byte{
+		sha256Digest("A"),
+		sha256Digest("B"),
+		sha256Digest("C"),
+	}
+	signReq := func(keyID string, digest []byte) *keymanager.SignDataRequest {
+		return &keymanager.SignDataRequest{
+			KeyId: keyID,
+			Data:  digest,
+			SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+				HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+			},
+		}
+	}
+
+	resp, err := s.m.SignDataBatch(ctx, &keymanager.SignDataBatchRequest{
+		Requests: []*keymanager.SignDataRequest{
+			signReq("EC", digests[0]),
+			signReq("RSA", digests[1]),
+			signReq("EC", digests[2]),
+		},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(resp.Responses, 3)
+
+	// the signatures are returned in request order
+	s.Require().True(ecdsa.VerifyASN1(ecKey.(*ecdsa.PublicKey), digests[0], resp.Responses[0].Signature))
+	s.Require().NoError(rsa.VerifyPKCS1v15(rsaKey.(*rsa.PublicKey), crypto.SHA256, digests[1], resp.Responses[1].Signature))
+	s.Require().True(ecdsa.VerifyASN1(ecKey.(*ecdsa.PublicKey), digests[2], resp.Responses[2].Signature))
+}
+
+func (s *baseSuite) TestSignDataBatchCertificates() {
+	generateResp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	publicKey, err := x509.ParsePKIXPublicKey(generateResp.PublicKey.PkixData)
+	s.Require().NoError(err)
+
+	parentTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotAfter:              time.Now().Add(time.Minute),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	parent, err := x509util.CreateCertificate(ctx, s.m, parentTemplate, parentTemplate, "KEY", publicKey)
+	s.Require().NoError(err)
+
+	var templates []*x509.Certificate
+	for i := 0; i < 3; i++ {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		s.Require().NoError(err)
+		templates = append(templates, &x509.Certificate{
+			SerialNumber: big.NewInt(int64(i + 2)),
+			NotAfter:     time.Now().Add(time.Minute),
+			PublicKey:    key.Public(),
+		})
+	}
+
+	certs, err := x509util.CreateCertificates(ctx, s.m, templates, parent, "KEY")
+	s.Require().NoError(err)
+	s.Require().Len(certs, 3)
+	for i, cert := range certs {
+		s.Require().Equal(templates[i].SerialNumber, cert.SerialNumber)
+		s.Require().NoError(cert.CheckSignatureFrom(parent))
+	}
+}
+
+func (s *baseSuite) TestSignDataBatchEmpty() {
+	resp, err := s.m.SignDataBatch(ctx, &keymanager.SignDataBatchRequest{})
+	s.Require().NoError(err)
+	s.Require().Empty(resp.Responses)
+}
+
+func (s *baseSuite) TestSignDataBatchNoKey() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	resp, err := s.m.SignDataBatch(ctx, &keymanager.SignDataBatchRequest{
+		Requests: []*keymanager.SignDataRequest{
+			{
+				KeyId: "KEY",
+				Data:  sha256Digest("A"),
+				SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+					HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+				},
+			},
+			{
+				KeyId: "MISSING",
+				Data:  sha256Digest("B"),
+				SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+					HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+				},
+			},
+		},
+	})
+	s.requireErrorContains(err, `no such key "MISSING"`)
+	s.Require().Nil(resp)
+}
+
+func sha256Digest(s string) []byte {
+	sum := sha256.Sum256([]byte(s))
+	return sum[:]
+}
+
+func (s *keyManagerSuite) requireErrorContains(err error, contains string) {
 	s.Require().Error(err)
 	s.Require().Contains(err.Error(), contains)
 }
+
+type ed25519Suite struct {
+	keyManagerSuite
+}
+
+func (s *ed25519Suite) TestGenerateKeyEd25519() {
+	resp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_ED25519,
+	})
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().NotNil(resp.PublicKey)
+	s.Require().Equal(resp.PublicKey.Id, "KEY")
+	s.Require().Equal(resp.PublicKey.Type, keymanager.KeyType_ED25519)
+	publicKey, err := x509.ParsePKIXPublicKey(resp.PublicKey.PkixData)
+	s.Require().NoError(err)
+	_, ok := publicKey.(ed25519.PublicKey)
+	s.Require().True(ok)
+}
+
+func (s *ed25519Suite) TestSignDataEd25519() {
+	s.testSignData(keymanager.KeyType_ED25519, x509.PureEd25519)
+}
+
+func (s *ed25519Suite) TestSignDataEd25519WithHash() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_ED25519,
+	})
+	s.Require().NoError(err)
+
+	resp, err := s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		Data:  []byte("DATA"),
+		SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+		},
+	})
+	s.requireErrorContains(err, "signing operation failed")
+	s.Require().Nil(resp)
+}
@@ -0,0 +1,232 @@
+package tpm
+
+import (
+	"bytes"
+	"crypto"
+	"io"
+	"sync"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/zeebo/errs"
+)
+
+const (
+	cmdSign tpmutil.Command = 0x0000015D
+
+	// maxHandles is the number of handles requested when listing the
+	// persistent handles in use
+	maxHandles = 256
+)
+
+var (
+	// srkTemplate is the template of the storage root key under which
+	// signing keys are created. It follows the TCG guidance for an ECC SRK
+	// so it matches the SRK created by other tools.
+	srkTemplate = tpm2.Public{
+		Type:       tpm2.AlgECC,
+		NameAlg:    tpm2.AlgSHA256,
+		Attributes: tpm2.FlagStorageDefault | tpm2.FlagNoDA,
+		ECCParameters: &tpm2.ECCParams{
+			Symmetric: &tpm2.SymScheme{
+				Alg:     tpm2.AlgAES,
+				KeyBits: 128,
+				Mode:    tpm2.AlgCFB,
+			},
+			CurveID: tpm2.CurveNISTP256,
+		},
+	}
+)
+
+// device is the set of TPM operations the key manager needs
+type device interface {
+	// CreateKey creates a key from the template under the storage root key
+	// and persists it at the given handle.
+	CreateKey(template tpm2.Public, handle tpmutil.Handle) (crypto.PublicKey, error)
+
+	// ReadPublicKey returns the public key of the persistent key at the
+	// given handle.
+	ReadPublicKey(handle tpmutil.Handle) (crypto.PublicKey, error)
+
+	// EvictKey removes the persistent key at the given handle
+	EvictKey(handle tpmutil.Handle) error
+
+	// PersistentHandles returns the persistent handles that are in use
+	PersistentHandles() ([]tpmutil.Handle, error)
+
+	// PolicyDigest returns the digest of a policy that requires the PCRs in
+	// the selection to have their current values.
+	PolicyDigest(sel tpm2.PCRSelection) ([]byte, error)
+
+	// Sign signs the digest with the persistent key at the given handle. If
+	// sel is not nil, the key is authorized with a PCR policy session for
+	// the selection instead of an empty password.
+	Sign(handle tpmutil.Handle, digest []byte, scheme tpm2.SigScheme, sel *tpm2.PCRSelection) (*tpm2.Signature, error)
+
+	Close() error
+}
+
+// tpmDevice implements device for a TPM 2.0 character device (or resource
+// manager).
+type tpmDevice struct {
+	// mu serializes commands, since the TPM processes one at a time
+	mu            sync.Mutex
+	rw            io.ReadWriteCloser
+	ownerPassword string
+}
+
+func openDevice(path, ownerPassword string) (device, error) {
+	rw, err := openTPM(path)
+	if err != nil {
+		return nil, errs.New("unable to open TPM at %q: %v", path, err)
+	}
+	return &tpmDevice{
+		rw:            rw,
+		ownerPassword: ownerPassword,
+	}, nil
+}
+
+func (d *tpmDevice) CreateKey(template tpm2.Public, handle tpmutil.Handle) (crypto.PublicKey, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	srk, _, err := tpm2.CreatePrimary(d.rw, tpm2.HandleOwner, tpm2.PCRSelection{}, d.ownerPassword, "", srkTemplate)
+	if err != nil {
+		return nil, errs.New("unable to create storage root key: %v", err)
+	}
+	defer tpm2.FlushContext(d.rw, srk)
+
+	private, public, _, _, _, err := tpm2.CreateKey(d.rw, srk, tpm2.PCRSelection{}, "", "", template)
+	if err != nil {
+		return nil, errs.New("unable to create key: %v", err)
+	}
+
+	key, _, err := tpm2.Load(d.rw, srk, "", public, private)
+	if err != nil {
+		return nil, errs.New("unable to load key: %v", err)
+	}
+	defer tpm2.FlushContext(d.rw, key)
+
+	if err := tpm2.EvictControl(d.rw, d.ownerPassword, tpm2.HandleOwner, key, handle); err != nil {
+		return nil, errs.New("unable to persist key at handle %#x: %v", uint32(handle), err)
+	}
+
+	return d.readPublicKey(handle)
+}
+
+func (d *tpmDevice) ReadPublicKey(handle tpmutil.Handle) (crypto.PublicKey, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.readPublicKey(handle)
+}
+
+func (d *tpmDevice) readPublicKey(handle tpmutil.Handle) (crypto.PublicKey, error) {
+	public, _, _, err := tpm2.ReadPublic(d.rw, handle)
+	if err != nil {
+		return nil, errs.New("unable to read public area of handle %#x: %v", uint32(handle), err)
+	}
+	return public.Key()
+}
+
+func (d *tpmDevice) EvictKey(handle tpmutil.Handle) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// evicting a persistent handle removes the object
+	return tpm2.EvictControl(d.rw, d.ownerPassword, tpm2.HandleOwner, handle, handle)
+}
+
+func (d *tpmDevice) PersistentHandles() ([]tpmutil.Handle, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	vals, _, err := tpm2.GetCapability(d.rw, tpm2.CapabilityHandles, maxHandles, uint32(tpm2.PersistentFirst))
+	if err != nil {
+		return nil, errs.New("unable to list persistent handles: %v", err)
+	}
+	var handles []tpmutil.Handle
+	for _, val := range vals {
+		if handle, ok := val.(tpmutil.Handle); ok {
+			handles = append(handles, handle)
+		}
+	}
+	return handles, nil
+}
+
+func (d *tpmDevice) PolicyDigest(sel tpm2.PCRSelection) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	session, err := d.startPCRPolicySession(tpm2.SessionTrial, sel)
+	if err != nil {
+		return nil, err
+	}
+	defer tpm2.FlushContext(d.rw, session)
+
+	return tpm2.PolicyGetDigest(d.rw, session)
+}
+
+func (d *tpmDevice) Sign(handle tpmutil.Handle, digest []byte, scheme tpm2.SigScheme, sel *tpm2.PCRSelection) (*tpm2.Signature, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	auth := tpm2.AuthCommand{
+		Session:    tpm2.HandlePasswordSession,
+		Attributes: tpm2.AttrContinueSession,
+	}
+	if sel != nil {
+		session, err := d.startPCRPolicySession(tpm2.SessionPolicy, *sel)
+		if err != nil {
+			return nil, err
+		}
+		defer tpm2.FlushContext(d.rw, session)
+		auth.Session = session
+	}
+
+	return sign(d.rw, handle, auth, digest, scheme)
+}
+
+func (d *tpmDevice) Close() error {
+	return d.rw.Close()
+}
+
+func (d *tpmDevice) startPCRPolicySession(sessionType tpm2.SessionType, sel tpm2.PCRSelection) (tpmutil.Handle, error) {
+	session, _, err := tpm2.StartAuthSession(d.rw, tpm2.HandleNull, tpm2.HandleNull, make([]byte, 16), nil, sessionType, tpm2.AlgNull, tpm2.AlgSHA256)
+	if err != nil {
+		return 0, errs.New("unable to start policy session: %v", err)
+	}
+	if err := tpm2.PolicyPCR(d.rw, session, nil, sel); err != nil {
+		tpm2.FlushContext(d.rw, session)
+		return 0, errs.New("unable to apply PCR policy: %v", err)
+	}
+	return session, nil
+}
+
+// sign runs TPM2_Sign with the given authorization. tpm2.Sign only supports
+// password authorization, which rules out keys bound to a PCR policy.
+func sign(rw io.ReadWriter, handle tpmutil.Handle, auth tpm2.AuthCommand, digest []byte, scheme tpm2.SigScheme) (*tpm2.Signature, error) {
+	authArea, err := tpmutil.Pack(auth)
+	if err != nil {
+		return nil, err
+	}
+	resp, code, err := tpmutil.RunCommand(rw, tpm2.TagSessions, cmdSign,
+		handle,
+		tpmutil.U32Bytes(authArea),
+		tpmutil.U16Bytes(digest),
+		scheme.Alg, scheme.Hash,
+		// null validation ticket, since the key is not restricted
+		tpm2.TagHashCheck, tpm2.HandleNull, tpmutil.U16Bytes(nil),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if code != tpmutil.RCSuccess {
+		return nil, errs.New("TPM2_Sign failed with response code %#x", uint32(code))
+	}
+
+	var paramSize uint32
+	read, err := tpmutil.Unpack(resp, &paramSize)
+	if err != nil {
+		return nil, err
+	}
+	return tpm2.DecodeSignature(bytes.NewBuffer(resp[read:]))
+}
@@ -0,0 +1,535 @@
+package tpm
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/hashicorp/hcl"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/diskutil"
+	"github.com/spiffe/spire/pkg/server/plugin/keymanager/base"
+	"github.com/zeebo/errs"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+)
+
+const (
+	defaultDevicePath           = "/dev/tpmrm0"
+	defaultPersistentHandleBase = 0x81008000
+
+	// persistentHandleRange is the number of persistent handles, starting
+	// at the base, that the key manager allocates from
+	persistentHandleRange = 0x100
+)
+
+var (
+	tpmError = errs.Class("keymanager(tpm)")
+)
+
+type configuration struct {
+	// DevicePath is the path to the TPM device or resource manager
+	DevicePath string `hcl:"device_path"`
+
+	// KeysPath is where the mapping from key id to persistent handle is
+	// stored
+	KeysPath string `hcl:"keys_path"`
+
+	// OwnerPassword is the authorization value of the owner hierarchy
+	OwnerPassword string `hcl:"owner_password"`
+
+	// PersistentHandleBase is the first persistent handle used for keys
+	PersistentHandleBase int64 `hcl:"persistent_handle_base"`
+
+	// PCRs, if set, binds newly generated keys to a policy that requires
+	// the listed SHA-256 PCRs to hold the values they had when the key was
+	// generated.
+	PCRs []int `hcl:"pcrs"`
+}
+
+type keyEntry struct {
+	handle    tpmutil.Handle
+	pcrs      []int
+	publicKey *keymanager.PublicKey
+}
+
+type KeyManager struct {
+	// log is set by the catalog before the plugin is configured
+	log logrus.FieldLogger
+
+	// generateMu serializes key generation so that concurrent generations do
+	// not allocate the same handle
+	generateMu sync.Mutex
+
+	mu      sync.RWMutex
+	device  device
+	config  *configuration
+	entries map[string]*keyEntry
+
+	hooks struct {
+		openDevice func(path, ownerPassword string) (device, error)
+	}
+}
+
+var _ keymanager.Plugin = (*KeyManager)(nil)
+
+func New() *KeyManager {
+	p := &KeyManager{
+		log:     logrus.StandardLogger(),
+		entries: make(map[string]*keyEntry),
+	}
+	p.hooks.openDevice = openDevice
+	return p
+}
+
+func (p *KeyManager) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, tpmError.New("unable to decode configuration: %v", err)
+	}
+
+	if config.KeysPath == "" {
+		return nil, tpmError.New("keys_path is required")
+	}
+	if config.DevicePath == "" {
+		config.DevicePath = defaultDevicePath
+	}
+	if config.PersistentHandleBase == 0 {
+		config.PersistentHandleBase = defaultPersistentHandleBase
+	}
+	if first, last := config.PersistentHandleBase, config.PersistentHandleBase+persistentHandleRange-1; first < int64(tpm2.PersistentFirst) || last > 0x817FFFFF {
+		return nil, tpmError.New("persistent_handle_base %#x is outside of the owner persistent handle range", config.PersistentHandleBase)
+	}
+	for _, pcr := range config.PCRs {
+		if pcr < 0 || pcr > 23 {
+			return nil, tpmError.New("invalid PCR index %d", pcr)
+		}
+	}
+
+	p.generateMu.Lock()
+	defer p.generateMu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.device != nil {
+		p.device.Close()
+		p.device = nil
+		p.entries = make(map[string]*keyEntry)
+	}
+
+	d, err := p.hooks.openDevice(config.DevicePath, config.OwnerPassword)
+	if err != nil {
+		return nil, tpmError.Wrap(err)
+	}
+
+	entries, err := loadEntries(p.log, d, config.KeysPath)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	p.device = d
+	p.config = config
+	p.entries = entries
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+// SetLogger sets the logger the plugin logs to
+func (p *KeyManager) SetLogger(log logrus.FieldLogger) {
+	p.log = log
+}
+
+func (p *KeyManager) GetPluginInfo(ctx context.Context, req *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *KeyManager) GenerateKey(ctx context.Context, req *keymanager.GenerateKeyRequest) (*keymanager.GenerateKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, tpmError.New("key id is required")
+	}
+	if req.KeyType == keymanager.KeyType_UNSPECIFIED_KEY_TYPE {
+		return nil, tpmError.New("key type is required")
+	}
+
+	p.generateMu.Lock()
+	defer p.generateMu.Unlock()
+
+	d, config, err := p.getDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := keyTemplate(req.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.PCRs) > 0 {
+		// The key can only be used through the PCR policy. Clearing
+		// userWithAuth prevents use with the (empty) password.
+		template.Attributes &^= tpm2.FlagUserWithAuth
+		template.AuthPolicy, err = d.PolicyDigest(pcrSelection(config.PCRs))
+		if err != nil {
+			return nil, tpmError.New("unable to compute PCR policy: %v", err)
+		}
+	}
+
+	handle, err := p.allocateHandle(d, config)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := d.CreateKey(template, handle)
+	if err != nil {
+		return nil, tpmError.New("unable to generate key %q: %v", req.KeyId, err)
+	}
+	pkixData, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		d.EvictKey(handle)
+		return nil, tpmError.New("unable to marshal public key for %q: %v", req.KeyId, err)
+	}
+
+	newEntry := &keyEntry{
+		handle: handle,
+		pcrs:   append([]int(nil), config.PCRs...),
+		publicKey: &keymanager.PublicKey{
+			Id:       req.KeyId,
+			Type:     req.KeyType,
+			PkixData: pkixData,
+		},
+	}
+
+	p.mu.Lock()
+	oldEntry := p.entries[req.KeyId]
+	p.entries[req.KeyId] = newEntry
+	err = writeEntries(config.KeysPath, p.entries)
+	if err != nil {
+		if oldEntry != nil {
+			p.entries[req.KeyId] = oldEntry
+		} else {
+			delete(p.entries, req.KeyId)
+		}
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		d.EvictKey(handle)
+		return nil, err
+	}
+
+	// The previous key is no longer used. Failure to evict it is not fatal
+	// since the new key is already in use.
+	if oldEntry != nil {
+		if err := d.EvictKey(oldEntry.handle); err != nil {
+			p.log.Warnf("Unable to evict previous key for %q at handle %#x: %v", req.KeyId, uint32(oldEntry.handle), err)
+		}
+	}
+
+	return &keymanager.GenerateKeyResponse{
+		PublicKey: clonePublicKey(newEntry.publicKey),
+	}, nil
+}
+
+func (p *KeyManager) GetPublicKey(ctx context.Context, req *keymanager.GetPublicKeyRequest) (*keymanager.GetPublicKeyResponse, error) {
+	if req.KeyId == "" {
+		return nil, tpmError.New("key id is required")
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	resp := new(keymanager.GetPublicKeyResponse)
+	if entry := p.entries[req.KeyId]; entry != nil {
+		resp.PublicKey = clonePublicKey(entry.publicKey)
+	}
+	return resp, nil
+}
+
+func (p *KeyManager) GetPublicKeys(ctx context.Context, req *keymanager.GetPublicKeysRequest) (*keymanager.GetPublicKeysResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	resp := new(keymanager.GetPublicKeysResponse)
+	for _, entry := range p.entries {
+		resp.PublicKeys = append(resp.PublicKeys, clonePublicKey(entry.publicKey))
+	}
+	sort.Slice(resp.PublicKeys, func(i, j int) bool {
+		return resp.PublicKeys[i].Id < resp.PublicKeys[j].Id
+	})
+	return resp, nil
+}
+
+func (p *KeyManager) SignData(ctx context.Context, req *keymanager.SignDataRequest) (*keymanager.SignDataResponse, error) {
+	if req.KeyId == "" {
+		return nil, tpmError.New("key id is required")
+	}
+	if req.SignerOpts == nil {
+		return nil, tpmError.New("signer opts is required")
+	}
+
+	var hashAlgorithm keymanager.HashAlgorithm
+	switch opts := req.SignerOpts.(type) {
+	case *keymanager.SignDataRequest_HashAlgorithm:
+		hashAlgorithm = opts.HashAlgorithm
+	case *keymanager.SignDataRequest_PssOptions:
+		// TPMs disagree on the PSS salt length (older revisions of the
+		// specification use the maximum length), so PSS is not offered.
+		return nil, tpmError.New("PSS signing is not supported")
+	default:
+		return nil, tpmError.New("unsupported signer opts type %T", opts)
+	}
+	if hashAlgorithm == keymanager.HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM {
+		return nil, tpmError.New("hash algorithm is required")
+	}
+
+	d, _, err := p.getDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	entry := p.entries[req.KeyId]
+	p.mu.RUnlock()
+	if entry == nil {
+		return nil, tpmError.New("no such key %q", req.KeyId)
+	}
+
+	hashAlg, err := tpmHashAlgorithm(hashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	scheme := tpm2.SigScheme{Alg: tpm2.AlgECDSA, Hash: hashAlg}
+	if !isECKeyType(entry.publicKey.Type) {
+		scheme.Alg = tpm2.AlgRSASSA
+	}
+
+	var sel *tpm2.PCRSelection
+	if len(entry.pcrs) > 0 {
+		s := pcrSelection(entry.pcrs)
+		sel = &s
+	}
+
+	signature, err := d.Sign(entry.handle, req.Data, scheme, sel)
+	if err != nil {
+		return nil, tpmError.New("keypair %q signing operation failed: %v", req.KeyId, err)
+	}
+
+	var signatureBytes []byte
+	switch {
+	case signature.ECC != nil:
+		signatureBytes, err = asn1.Marshal(struct {
+			R, S *big.Int
+		}{
+			R: signature.ECC.R,
+			S: signature.ECC.S,
+		})
+		if err != nil {
+			return nil, tpmError.New("unable to marshal signature: %v", err)
+		}
+	case signature.RSA != nil:
+		signatureBytes = signature.RSA.Signature
+	default:
+		return nil, tpmError.New("keypair %q returned unexpected signature algorithm %#x", req.KeyId, uint16(signature.Alg))
+	}
+
+	return &keymanager.SignDataResponse{
+		Signature: signatureBytes,
+	}, nil
+}
+
+// SignDataBatch signs each request in turn since the TPM signs a single digest per command.
+func (p *KeyManager) SignDataBatch(ctx context.Context, req *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	return base.SignDataEach(ctx, req, p.SignData)
+}
+
+func (p *KeyManager) getDevice() (device, *configuration, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.device == nil {
+		return nil, nil, tpmError.New("not configured")
+	}
+	return p.device, p.config, nil
+}
+
+// allocateHandle returns the first handle in the configured range that is
+// neither persisted on the TPM nor referenced by an entry.
+func (p *KeyManager) allocateHandle(d device, config *configuration) (tpmutil.Handle, error) {
+	handles, err := d.PersistentHandles()
+	if err != nil {
+		return 0, tpmError.Wrap(err)
+	}
+	inUse := make(map[tpmutil.Handle]bool)
+	for _, handle := range handles {
+		inUse[handle] = true
+	}
+	p.mu.RLock()
+	for _, entry := range p.entries {
+		inUse[entry.handle] = true
+	}
+	p.mu.RUnlock()
+
+	for i := int64(0); i < persistentHandleRange; i++ {
+		handle := tpmutil.Handle(config.PersistentHandleBase + i)
+		if !inUse[handle] {
+			return handle, nil
+		}
+	}
+	return 0, tpmError.New("no free persistent handles starting at %#x", config.PersistentHandleBase)
+}
+
+type entryData struct {
+	Handle uint32 `json:"handle"`
+	PCRs   []int  `json:"pcrs,omitempty"`
+}
+
+type entriesData struct {
+	Keys map[string]entryData `json:"keys"`
+}
+
+func loadEntries(log logrus.FieldLogger, d device, path string) (map[string]*keyEntry, error) {
+	entries := make(map[string]*keyEntry)
+
+	jsonBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, tpmError.New("unable to read keys: %v", err)
+	}
+
+	data := new(entriesData)
+	if err := json.Unmarshal(jsonBytes, data); err != nil {
+		return nil, tpmError.New("unable to decode keys JSON: %v", err)
+	}
+
+	for id, keyData := range data.Keys {
+		handle := tpmutil.Handle(keyData.Handle)
+		publicKey, err := d.ReadPublicKey(handle)
+		if err != nil {
+			// The key may be gone if the TPM was cleared. It is dropped so
+			// that SPIRE generates a new one.
+			log.Warnf("Unable to load key %q at handle %#x: %v", id, keyData.Handle, err)
+			continue
+		}
+		keyType, err := keyTypeFromPublicKey(publicKey)
+		if err != nil {
+			return nil, tpmError.New("unable to load key %q: %v", id, err)
+		}
+		pkixData, err := x509.MarshalPKIXPublicKey(publicKey)
+		if err != nil {
+			return nil, tpmError.New("unable to marshal public key for %q: %v", id, err)
+		}
+		entries[id] = &keyEntry{
+			handle: handle,
+			pcrs:   keyData.PCRs,
+			publicKey: &keymanager.PublicKey{
+				Id:       id,
+				Type:     keyType,
+				PkixData: pkixData,
+			},
+		}
+	}
+	return entries, nil
+}
+
+func writeEntries(path string, entries map[string]*keyEntry) error {
+	data := &entriesData{
+		Keys: make(map[string]entryData),
+	}
+	for id, entry := range entries {
+		data.Keys[id] = entryData{
+			Handle: uint32(entry.handle),
+			PCRs:   entry.pcrs,
+		}
+	}
+
+	jsonBytes, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return tpmError.New("unable to marshal entries: %v", err)
+	}
+
+	if err := diskutil.AtomicWriteFile(path, jsonBytes, 0644); err != nil {
+		return tpmError.New("unable to write entries: %v", err)
+	}
+
+	return nil
+}
+
+func keyTemplate(keyType keymanager.KeyType) (tpm2.Public, error) {
+	template := tpm2.Public{
+		NameAlg:    tpm2.AlgSHA256,
+		Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+	}
+	switch keyType {
+	case keymanager.KeyType_EC_P256:
+		template.Type = tpm2.AlgECC
+		template.ECCParameters = &tpm2.ECCParams{CurveID: tpm2.CurveNISTP256}
+	case keymanager.KeyType_EC_P384:
+		template.Type = tpm2.AlgECC
+		template.ECCParameters = &tpm2.ECCParams{CurveID: tpm2.CurveNISTP384}
+	case keymanager.KeyType_RSA_2048:
+		template.Type = tpm2.AlgRSA
+		template.RSAParameters = &tpm2.RSAParams{KeyBits: 2048}
+	default:
+		return tpm2.Public{}, tpmError.New("unsupported key type %q", keyType)
+	}
+	return template, nil
+}
+
+func keyTypeFromPublicKey(publicKey crypto.PublicKey) (keymanager.KeyType, error) {
+	switch publicKey := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		switch publicKey.Curve.Params().BitSize {
+		case 256:
+			return keymanager.KeyType_EC_P256, nil
+		case 384:
+			return keymanager.KeyType_EC_P384, nil
+		}
+	case *rsa.PublicKey:
+		if publicKey.N.BitLen() == 2048 {
+			return keymanager.KeyType_RSA_2048, nil
+		}
+	}
+	return keymanager.KeyType_UNSPECIFIED_KEY_TYPE, errs.New("unsupported public key type %T", publicKey)
+}
+
+func tpmHashAlgorithm(hashAlgorithm keymanager.HashAlgorithm) (tpm2.Algorithm, error) {
+	switch hashAlgorithm {
+	case keymanager.HashAlgorithm_SHA256:
+		return tpm2.AlgSHA256, nil
+	case keymanager.HashAlgorithm_SHA384:
+		return tpm2.AlgSHA384, nil
+	case keymanager.HashAlgorithm_SHA512:
+		return tpm2.AlgSHA512, nil
+	default:
+		return tpm2.AlgNull, tpmError.New("unsupported hash algorithm %s", hashAlgorithm)
+	}
+}
+
+func pcrSelection(pcrs []int) tpm2.PCRSelection {
+	return tpm2.PCRSelection{
+		Hash: tpm2.AlgSHA256,
+		PCRs: pcrs,
+	}
+}
+
+func isECKeyType(keyType keymanager.KeyType) bool {
+	return keyType == keymanager.KeyType_EC_P256 || keyType == keymanager.KeyType_EC_P384
+}
+
+func clonePublicKey(publicKey *keymanager.PublicKey) *keymanager.PublicKey {
+	return proto.Clone(publicKey).(*keymanager.PublicKey)
+}
@@ -0,0 +1,451 @@
+package tpm
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/spiffe/spire/pkg/common/x509util"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/keymanager"
+	"github.com/stretchr/testify/suite"
+	"github.com/zeebo/errs"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestKeyManager(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	dir      string
+	keysPath string
+	device   *fakeDevice
+	m        *keymanager.BuiltIn
+}
+
+func (s *Suite) SetupTest() {
+	dir, err := ioutil.TempDir("", "keymanager-tpm-test")
+	s.Require().NoError(err)
+	s.dir = dir
+	s.keysPath = filepath.Join(dir, "keys.json")
+	s.device = newFakeDevice()
+	s.m = s.newKeyManager("")
+}
+
+func (s *Suite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *Suite) newKeyManager(extraConfig string) *keymanager.BuiltIn {
+	p := New()
+	p.hooks.openDevice = func(path, ownerPassword string) (device, error) {
+		return s.device, nil
+	}
+	resp, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf("keys_path = %q\n%s", s.keysPath, extraConfig),
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&spi.ConfigureResponse{}, resp)
+	return keymanager.NewBuiltIn(p)
+}
+
+func (s *Suite) TestConfigureErrors() {
+	for _, tt := range []struct {
+		config string
+		err    string
+	}{
+		{
+			config: ``,
+			err:    "keymanager(tpm): keys_path is required",
+		},
+		{
+			config: `keys_path = "keys.json" persistent_handle_base = 0x80000000`,
+			err:    "keymanager(tpm): persistent_handle_base 0x80000000 is outside of the owner persistent handle range",
+		},
+		{
+			config: `keys_path = "keys.json" pcrs = [7, 24]`,
+			err:    "keymanager(tpm): invalid PCR index 24",
+		},
+	} {
+		p := New()
+		_, err := p.Configure(ctx, &spi.ConfigureRequest{
+			Configuration: tt.config,
+		})
+		s.Require().EqualError(err, tt.err, "config: %s", tt.config)
+	}
+}
+
+func (s *Suite) TestGenerateKeyBeforeConfigure() {
+	p := New()
+	resp, err := p.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().EqualError(err, "keymanager(tpm): not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *Suite) TestGenerateKeyUnsupportedKeyType() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_RSA_4096,
+	})
+	s.Require().EqualError(err, `keymanager(tpm): unsupported key type "RSA_4096"`)
+
+	_, err = s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_ED25519,
+	})
+	s.Require().EqualError(err, `keymanager(tpm): unsupported key type "ED25519"`)
+}
+
+func (s *Suite) TestGenerateKey() {
+	for _, keyType := range []keymanager.KeyType{
+		keymanager.KeyType_EC_P256,
+		keymanager.KeyType_EC_P384,
+		keymanager.KeyType_RSA_2048,
+	} {
+		resp, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+			KeyId:   keyType.String(),
+			KeyType: keyType,
+		})
+		s.Require().NoError(err)
+		s.Require().Equal(keyType.String(), resp.PublicKey.Id)
+		s.Require().Equal(keyType, resp.PublicKey.Type)
+		_, err = x509.ParsePKIXPublicKey(resp.PublicKey.PkixData)
+		s.Require().NoError(err)
+
+		getResp, err := s.m.GetPublicKey(ctx, &keymanager.GetPublicKeyRequest{
+			KeyId: keyType.String(),
+		})
+		s.Require().NoError(err)
+		s.Require().Equal(resp.PublicKey, getResp.PublicKey)
+	}
+
+	s.Require().Equal([]tpmutil.Handle{0x81008000, 0x81008001, 0x81008002}, s.device.handles())
+}
+
+func (s *Suite) TestGenerateKeySkipsHandlesInUse() {
+	// a key persisted by someone else
+	_, err := s.device.CreateKey(tpm2.Public{Type: tpm2.AlgECC, ECCParameters: &tpm2.ECCParams{CurveID: tpm2.CurveNISTP256}}, 0x81008000)
+	s.Require().NoError(err)
+
+	_, err = s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]tpmutil.Handle{0x81008000, 0x81008001}, s.device.handles())
+}
+
+func (s *Suite) TestGenerateKeyEvictsPreviousKey() {
+	resp1, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	resp2, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	s.Require().NotEqual(resp1.PublicKey.PkixData, resp2.PublicKey.PkixData)
+	s.Require().Equal([]tpmutil.Handle{0x81008001}, s.device.handles())
+}
+
+func (s *Suite) TestKeysAreLoadedOnConfigure() {
+	a, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "A",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+	b, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "B",
+		KeyType: keymanager.KeyType_RSA_2048,
+	})
+	s.Require().NoError(err)
+
+	m := s.newKeyManager("")
+	resp, err := m.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal([]*keymanager.PublicKey{a.PublicKey, b.PublicKey}, resp.PublicKeys)
+}
+
+func (s *Suite) TestKeysMissingFromTPMAreDropped() {
+	_, err := s.m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+		KeyId:   "KEY",
+		KeyType: keymanager.KeyType_EC_P256,
+	})
+	s.Require().NoError(err)
+
+	// simulate the TPM being cleared
+	s.device = newFakeDevice()
+
+	m := s.newKeyManager("")
+	resp, err := m.GetPublicKeys(ctx, &keymanager.GetPublicKeysRequest{})
+	s.Require().NoError(err)
+	s.Require().Empty(resp.PublicKeys)
+}
+
+func (s *Suite) TestSignData() {
+	s.testSignData(s.m, keymanager.KeyType_EC_P256, x509.ECDSAWithSHA256)
+	s.testSignData(s.m, keymanager.KeyType_EC_P384, x509.ECDSAWithSHA384)
+	s.testSignData(s.m, keymanager.KeyType_RSA_2048, x509.SHA256WithRSA)
+}
+
+func (s *Suite) TestSignDataWithPCRPolicy() {
+	m := s.newKeyManager(`pcrs = [0, 7]`)
+	s.testSignData(m, keymanager.KeyType_EC_P256, x509.ECDSAWithSHA256)
+
+	// the key can no longer be used once the PCRs change
+	s.device.pcrsChanged = true
+	_, err := m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		Data:  make([]byte, 32),
+		SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+		},
+	})
+	s.Require().EqualError(err, `keymanager(tpm): keypair "KEY" signing operation failed: policy check failed`)
+
+	// the PCRs are remembered across restarts
+	s.device.pcrsChanged = false
+	m = s.newKeyManager("")
+	s.testSignData(m, keymanager.KeyType_EC_P256, x509.ECDSAWithSHA256)
+}
+
+func (s *Suite) TestSignDataRejectsPSS() {
+	_, err := s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		SignerOpts: &keymanager.SignDataRequest_PssOptions{
+			PssOptions: &keymanager.PSSOptions{
+				HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+			},
+		},
+	})
+	s.Require().EqualError(err, "keymanager(tpm): PSS signing is not supported")
+}
+
+func (s *Suite) TestSignDataNoKey() {
+	_, err := s.m.SignData(ctx, &keymanager.SignDataRequest{
+		KeyId: "KEY",
+		SignerOpts: &keymanager.SignDataRequest_HashAlgorithm{
+			HashAlgorithm: keymanager.HashAlgorithm_SHA256,
+		},
+	})
+	s.Require().EqualError(err, `keymanager(tpm): no such key "KEY"`)
+}
+
+func (s *Suite) testSignData(m *keymanager.BuiltIn, keyType keymanager.KeyType, signatureAlgorithm x509.SignatureAlgorithm) {
+	var publicKey crypto.PublicKey
+	getResp, err := m.GetPublicKey(ctx, &keymanager.GetPublicKeyRequest{
+		KeyId: "KEY",
+	})
+	s.Require().NoError(err)
+	if getResp.PublicKey != nil && getResp.PublicKey.Type == keyType {
+		publicKey, err = x509.ParsePKIXPublicKey(getResp.PublicKey.PkixData)
+		s.Require().NoError(err)
+	} else {
+		generateResp, err := m.GenerateKey(ctx, &keymanager.GenerateKeyRequest{
+			KeyId:   "KEY",
+			KeyType: keyType,
+		})
+		s.Require().NoError(err)
+		publicKey, err = x509.ParsePKIXPublicKey(generateResp.PublicKey.PkixData)
+		s.Require().NoError(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(1),
+		NotAfter:           time.Now().Add(time.Minute),
+		SignatureAlgorithm: signatureAlgorithm,
+	}
+
+	cert, err := x509util.CreateCertificate(ctx, m, template, template, "KEY", publicKey)
+	s.Require().NoError(err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots: roots,
+	})
+	s.Require().NoError(err)
+}
+
+type fakeKey struct {
+	template tpm2.Public
+	signer   crypto.Signer
+}
+
+// fakeDevice is an in-memory TPM. Policy digests are derived from the PCR
+// selection and whether the PCRs have "changed", which is enough to tell
+// whether a policy session would be satisfied.
+type fakeDevice struct {
+	mu          sync.Mutex
+	keys        map[tpmutil.Handle]*fakeKey
+	pcrsChanged bool
+}
+
+func newFakeDevice() *fakeDevice {
+	return &fakeDevice{
+		keys: make(map[tpmutil.Handle]*fakeKey),
+	}
+}
+
+func (d *fakeDevice) CreateKey(template tpm2.Public, handle tpmutil.Handle) (crypto.PublicKey, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.keys[handle]; ok {
+		return nil, errs.New("handle %#x in use", uint32(handle))
+	}
+
+	var signer crypto.Signer
+	var err error
+	switch template.Type {
+	case tpm2.AlgECC:
+		curve := elliptic.P256()
+		if template.ECCParameters.CurveID == tpm2.CurveNISTP384 {
+			curve = elliptic.P384()
+		}
+		signer, err = ecdsa.GenerateKey(curve, rand.Reader)
+	case tpm2.AlgRSA:
+		signer, err = rsa.GenerateKey(rand.Reader, int(template.RSAParameters.KeyBits))
+	default:
+		err = errs.New("unsupported type %#x", uint16(template.Type))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d.keys[handle] = &fakeKey{
+		template: template,
+		signer:   signer,
+	}
+	return signer.Public(), nil
+}
+
+func (d *fakeDevice) ReadPublicKey(handle tpmutil.Handle) (crypto.PublicKey, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key, ok := d.keys[handle]
+	if !ok {
+		return nil, errs.New("handle %#x not found", uint32(handle))
+	}
+	return key.signer.Public(), nil
+}
+
+func (d *fakeDevice) EvictKey(handle tpmutil.Handle) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.keys[handle]; !ok {
+		return errs.New("handle %#x not found", uint32(handle))
+	}
+	delete(d.keys, handle)
+	return nil
+}
+
+func (d *fakeDevice) PersistentHandles() ([]tpmutil.Handle, error) {
+	return d.handles(), nil
+}
+
+func (d *fakeDevice) PolicyDigest(sel tpm2.PCRSelection) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.policyDigest(sel), nil
+}
+
+func (d *fakeDevice) Sign(handle tpmutil.Handle, digest []byte, scheme tpm2.SigScheme, sel *tpm2.PCRSelection) (*tpm2.Signature, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key, ok := d.keys[handle]
+	if !ok {
+		return nil, errs.New("handle %#x not found", uint32(handle))
+	}
+
+	if sel != nil {
+		if !bytes.Equal(key.template.AuthPolicy, d.policyDigest(*sel)) {
+			return nil, errs.New("policy check failed")
+		}
+	} else if key.template.Attributes&tpm2.FlagUserWithAuth == 0 {
+		return nil, errs.New("key requires policy authorization")
+	}
+
+	var hash crypto.Hash
+	switch scheme.Hash {
+	case tpm2.AlgSHA256:
+		hash = crypto.SHA256
+	case tpm2.AlgSHA384:
+		hash = crypto.SHA384
+	case tpm2.AlgSHA512:
+		hash = crypto.SHA512
+	default:
+		return nil, errs.New("unsupported hash %#x", uint16(scheme.Hash))
+	}
+
+	switch scheme.Alg {
+	case tpm2.AlgECDSA:
+		r, s, err := ecdsa.Sign(rand.Reader, key.signer.(*ecdsa.PrivateKey), digest)
+		if err != nil {
+			return nil, err
+		}
+		return &tpm2.Signature{
+			Alg: tpm2.AlgECDSA,
+			ECC: &tpm2.SignatureECC{HashAlg: scheme.Hash, R: r, S: s},
+		}, nil
+	case tpm2.AlgRSASSA:
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key.signer.(*rsa.PrivateKey), hash, digest)
+		if err != nil {
+			return nil, err
+		}
+		return &tpm2.Signature{
+			Alg: tpm2.AlgRSASSA,
+			RSA: &tpm2.SignatureRSA{HashAlg: scheme.Hash, Signature: signature},
+		}, nil
+	default:
+		return nil, errs.New("unsupported scheme %#x", uint16(scheme.Alg))
+	}
+}
+
+func (d *fakeDevice) Close() error {
+	return nil
+}
+
+func (d *fakeDevice) handles() []tpmutil.Handle {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var handles []tpmutil.Handle
+	for handle := range d.keys {
+		handles = append(handles, handle)
+	}
+	sort.Slice(handles, func(i, j int) bool {
+		return handles[i] < handles[j]
+	})
+	return handles
+}
+
+func (d *fakeDevice) policyDigest(sel tpm2.PCRSelection) []byte {
+	return []byte(fmt.Sprintf("%v/%v", sel.PCRs, d.pcrsChanged))
+}
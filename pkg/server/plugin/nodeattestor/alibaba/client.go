@@ -0,0 +1,163 @@
+package alibaba
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/plugin/alibaba"
+	"github.com/zeebo/errs"
+)
+
+const (
+	ecsAPIVersion = "2014-05-26"
+)
+
+// apiClient is an interface representing all of the API methods the
+// attestor needs to do its job.
+type apiClient interface {
+	// GetInstanceRAMRole returns the name of the RAM role attached to the
+	// instance, or an empty string if there is none.
+	GetInstanceRAMRole(ctx context.Context, region, instanceID string) (string, error)
+}
+
+// ecsClient implements apiClient using the ECS RPC API, signing requests
+// with an AccessKey pair.
+type ecsClient struct {
+	httpClient      alibaba.HTTPClient
+	accessKeyID     string
+	accessKeySecret string
+	now             func() time.Time
+
+	// endpoint returns the ECS API endpoint for the region
+	endpoint func(region string) string
+}
+
+func newECSClient(accessKeyID, accessKeySecret string) apiClient {
+	return &ecsClient{
+		httpClient:      http.DefaultClient,
+		accessKeyID:     accessKeyID,
+		accessKeySecret: accessKeySecret,
+		now:             time.Now,
+		endpoint: func(region string) string {
+			return "https://ecs." + region + ".aliyuncs.com"
+		},
+	}
+}
+
+func (c *ecsClient) GetInstanceRAMRole(ctx context.Context, region, instanceID string) (string, error) {
+	instanceIDs, err := json.Marshal([]string{instanceID})
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+
+	r := struct {
+		InstanceRamRoleSets struct {
+			InstanceRamRoleSet []struct {
+				InstanceID  string `json:"InstanceId"`
+				RamRoleName string `json:"RamRoleName"`
+			} `json:"InstanceRamRoleSet"`
+		} `json:"InstanceRamRoleSets"`
+	}{}
+	if err := c.do(ctx, region, url.Values{
+		"Action":      {"DescribeInstanceRamRole"},
+		"RegionId":    {region},
+		"InstanceIds": {string(instanceIDs)},
+	}, &r); err != nil {
+		return "", err
+	}
+
+	for _, role := range r.InstanceRamRoleSets.InstanceRamRoleSet {
+		if role.InstanceID == instanceID {
+			return role.RamRoleName, nil
+		}
+	}
+	return "", nil
+}
+
+func (c *ecsClient) do(ctx context.Context, region string, params url.Values, out interface{}) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	params.Set("Format", "JSON")
+	params.Set("Version", ecsAPIVersion)
+	params.Set("AccessKeyId", c.accessKeyID)
+	params.Set("SignatureMethod", "HMAC-SHA1")
+	params.Set("SignatureVersion", "1.0")
+	params.Set("SignatureNonce", nonce)
+	params.Set("Timestamp", c.now().UTC().Format("2006-01-02T15:04:05Z"))
+	params.Set("Signature", signParams("GET", params, c.accessKeySecret))
+
+	req, err := http.NewRequest("GET", c.endpoint(region)+"/?"+params.Encode(), nil)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errs.New("unable to decode response: %v", err)
+	}
+	return nil
+}
+
+// signParams computes the RPC API signature (version 1.0) over the
+// request parameters.
+func signParams(method string, params url.Values, secret string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, percentEncode(key)+"="+percentEncode(params.Get(key)))
+	}
+	stringToSign := method + "&" + percentEncode("/") + "&" + percentEncode(strings.Join(pairs, "&"))
+
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func percentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.Replace(s, "+", "%20", -1)
+	s = strings.Replace(s, "*", "%2A", -1)
+	s = strings.Replace(s, "%7E", "~", -1)
+	return s
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errs.Wrap(err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
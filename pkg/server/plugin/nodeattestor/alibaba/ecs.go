@@ -0,0 +1,251 @@
+package alibaba
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/alibaba"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+)
+
+const (
+	accessKeyIDEnv     = "ALIBABA_CLOUD_ACCESS_KEY_ID"
+	accessKeySecretEnv = "ALIBABA_CLOUD_ACCESS_KEY_SECRET"
+)
+
+var (
+	ecsError = errs.Class("alibaba-ecs")
+
+	// regionRE restricts regions to values safe to use in the API endpoint
+	regionRE = regexp.MustCompile(`^[a-z0-9-]+$`)
+)
+
+type ECSAttestorConfig struct {
+	// SigningCertPath is the path to the PEM encoded Alibaba Cloud
+	// certificate(s) used to verify instance identity documents
+	SigningCertPath string `hcl:"signing_cert_path"`
+
+	AccountIDWhitelist []string `hcl:"account_id_whitelist"`
+
+	// AccessKeyID and AccessKeySecret are used to look up the RAM role
+	// attached to the instance. If unset, they are read from the
+	// ALIBABA_CLOUD_ACCESS_KEY_ID and ALIBABA_CLOUD_ACCESS_KEY_SECRET
+	// environment variables. The lookup is skipped if neither is set.
+	AccessKeyID     string `hcl:"access_key_id"`
+	AccessKeySecret string `hcl:"access_key_secret"`
+}
+
+type ecsAttestorConfig struct {
+	trustDomain  string
+	signingCerts []*x509.Certificate
+	accounts     map[string]bool
+	client       apiClient
+}
+
+type ECSAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *ecsAttestorConfig
+
+	hooks struct {
+		getenv    func(string) string
+		newClient func(accessKeyID, accessKeySecret string) apiClient
+	}
+}
+
+var _ nodeattestor.Plugin = (*ECSAttestorPlugin)(nil)
+
+func New() *ECSAttestorPlugin {
+	p := &ECSAttestorPlugin{}
+	p.hooks.getenv = os.Getenv
+	p.hooks.newClient = newECSClient
+	return p
+}
+
+func (p *ECSAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return ecsError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	// the identity document is not bound to the attestation, so as with
+	// other instance identity document attestors the first agent to
+	// present it claims the instance
+	if req.AttestedBefore {
+		return ecsError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return ecsError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != alibaba.PluginName {
+		return ecsError.New("unexpected attestation data type %q", dataType)
+	}
+
+	attestationData := new(alibaba.AttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return ecsError.New("unable to unmarshal attestation data: %v", err)
+	}
+
+	if attestationData.Document == "" {
+		return ecsError.New("missing document from attestation data")
+	}
+	if attestationData.Signature == "" {
+		return ecsError.New("missing signature from attestation data")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(attestationData.Signature)
+	if err != nil {
+		return ecsError.New("unable to decode signature: %v", err)
+	}
+
+	if err := alibaba.VerifyPKCS7(signature, []byte(attestationData.Document), config.signingCerts); err != nil {
+		return ecsError.New("unable to verify identity document: %v", err)
+	}
+
+	doc := new(alibaba.InstanceIdentityDocument)
+	if err := json.Unmarshal([]byte(attestationData.Document), doc); err != nil {
+		return ecsError.New("unable to unmarshal identity document: %v", err)
+	}
+
+	switch {
+	case doc.AccountID == "":
+		return ecsError.New("identity document missing account ID")
+	case doc.InstanceID == "":
+		return ecsError.New("identity document missing instance ID")
+	case !regionRE.MatchString(doc.RegionID):
+		return ecsError.New("identity document has invalid region %q", doc.RegionID)
+	}
+
+	if len(config.accounts) > 0 && !config.accounts[doc.AccountID] {
+		return ecsError.New("account %q is not whitelisted", doc.AccountID)
+	}
+
+	// the RAM role reported by the agent is not covered by the signature
+	// and is only trusted once confirmed with the ECS API
+	var ramRole string
+	if config.client != nil {
+		ramRole, err = config.client.GetInstanceRAMRole(stream.Context(), doc.RegionID, doc.InstanceID)
+		if err != nil {
+			return ecsError.New("unable to look up instance RAM role: %v", err)
+		}
+		if ramRole != attestationData.RAMRole {
+			return ecsError.New("RAM role mismatch: instance has %q; agent reported %q", ramRole, attestationData.RAMRole)
+		}
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: alibaba.AgentID(config.trustDomain, doc),
+		Selectors:    buildSelectors(doc, ramRole),
+	})
+}
+
+func (p *ECSAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(ECSAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, ecsError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, ecsError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, ecsError.New("global configuration missing trust domain")
+	}
+
+	if hclConfig.SigningCertPath == "" {
+		return nil, ecsError.New("signing_cert_path is required")
+	}
+	signingCerts, err := pemutil.LoadCertificates(hclConfig.SigningCertPath)
+	if err != nil {
+		return nil, ecsError.New("unable to load signing certificates: %v", err)
+	}
+
+	accessKeyID := hclConfig.AccessKeyID
+	if accessKeyID == "" {
+		accessKeyID = p.hooks.getenv(accessKeyIDEnv)
+	}
+	accessKeySecret := hclConfig.AccessKeySecret
+	if accessKeySecret == "" {
+		accessKeySecret = p.hooks.getenv(accessKeySecretEnv)
+	}
+	if (accessKeyID == "") != (accessKeySecret == "") {
+		return nil, ecsError.New("access_key_id and access_key_secret must be set together")
+	}
+
+	config := &ecsAttestorConfig{
+		trustDomain:  req.GlobalConfig.TrustDomain,
+		signingCerts: signingCerts,
+		accounts:     make(map[string]bool),
+	}
+	for _, account := range hclConfig.AccountIDWhitelist {
+		config.accounts[account] = true
+	}
+	if accessKeyID != "" {
+		config.client = p.hooks.newClient(accessKeyID, accessKeySecret)
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *ECSAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *ECSAttestorPlugin) getConfig() (*ecsAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, ecsError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *ECSAttestorPlugin) setConfig(config *ecsAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func buildSelectors(doc *alibaba.InstanceIdentityDocument, ramRole string) []*common.Selector {
+	selectors := []*common.Selector{
+		makeSelector("region", doc.RegionID),
+	}
+	if doc.ZoneID != "" {
+		selectors = append(selectors, makeSelector("zone", doc.ZoneID))
+	}
+	if doc.ImageID != "" {
+		selectors = append(selectors, makeSelector("image", doc.ImageID))
+	}
+	if doc.InstanceType != "" {
+		selectors = append(selectors, makeSelector("instance_type", doc.InstanceType))
+	}
+	if ramRole != "" {
+		selectors = append(selectors, makeSelector("ram_role", ramRole))
+	}
+	return selectors
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  alibaba.PluginName,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
@@ -0,0 +1,435 @@
+package alibaba
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/alibaba"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/spiffe/spire/test/fakes/fakealibaba"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testDocument = `{"account-id":"ACCOUNT","instance-id":"i-INSTANCE","region-id":"cn-hangzhou","zone-id":"cn-hangzhou-h","image-id":"ubuntu_18_04_64_20G_alibase_20190624.vhd","instance-type":"ecs.g6.large"}`
+)
+
+func TestECSAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(ECSAttestorSuite))
+}
+
+type ECSAttestorSuite struct {
+	suite.Suite
+
+	dir      string
+	signer   *fakealibaba.Signer
+	attestor *nodeattestor.BuiltIn
+
+	env       map[string]string
+	accessKey string
+	ramRole   string
+	apiErr    error
+}
+
+func (s *ECSAttestorSuite) SetupSuite() {
+	dir, err := ioutil.TempDir("", "alibaba-ecs-attestor-")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	s.signer = fakealibaba.New(s.T())
+	s.writeFile("signing.pem", pemutil.EncodeCertificate(s.signer.Certificate))
+}
+
+func (s *ECSAttestorSuite) TearDownSuite() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *ECSAttestorSuite) SetupTest() {
+	s.env = map[string]string{}
+	s.accessKey = ""
+	s.ramRole = ""
+	s.apiErr = nil
+
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	attestor := s.newAttestor()
+	resp, err := s.doAttestOnAttestor(attestor, s.signAttestRequest(testDocument))
+	s.Require().EqualError(err, "alibaba-ecs: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWhenAttestedBefore() {
+	req := s.signAttestRequest(testDocument)
+	req.AttestedBefore = true
+	s.requireAttestError(req, "alibaba-ecs: node has already attested")
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWithNoAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"alibaba-ecs: missing attestation data")
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWithWrongAttestationDataType() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "blah",
+		},
+	}, `alibaba-ecs: unexpected attestation data type "blah"`)
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWithMalformedAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "alibaba_ecs",
+			Data: []byte("{"),
+		},
+	}, "alibaba-ecs: unable to unmarshal attestation data")
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWithMissingFields() {
+	s.requireAttestError(makeAttestRequest(&alibaba.AttestationData{Signature: "SIGNATURE"}),
+		"alibaba-ecs: missing document from attestation data")
+	s.requireAttestError(makeAttestRequest(&alibaba.AttestationData{Document: testDocument}),
+		"alibaba-ecs: missing signature from attestation data")
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWithMalformedSignature() {
+	s.requireAttestError(makeAttestRequest(&alibaba.AttestationData{
+		Document:  testDocument,
+		Signature: "%%%",
+	}), "alibaba-ecs: unable to decode signature")
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWhenDocumentTamperedWith() {
+	req := makeAttestRequest(&alibaba.AttestationData{
+		Document:  `{"account-id":"ACCOUNT","instance-id":"i-OTHER","region-id":"cn-hangzhou"}`,
+		Signature: s.signer.Sign([]byte(testDocument)),
+	})
+	s.requireAttestError(req, "alibaba-ecs: unable to verify identity document: message digest mismatch")
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWhenSignedByUnknownSigner() {
+	other := fakealibaba.New(s.T())
+	req := makeAttestRequest(&alibaba.AttestationData{
+		Document:  testDocument,
+		Signature: other.Sign([]byte(testDocument)),
+	})
+	s.requireAttestError(req, "alibaba-ecs: unable to verify identity document: signing certificate not found")
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWithInvalidDocument() {
+	s.requireAttestError(s.signAttestRequest("{"),
+		"alibaba-ecs: unable to unmarshal identity document")
+	s.requireAttestError(s.signAttestRequest(`{"instance-id":"i-INSTANCE","region-id":"cn-hangzhou"}`),
+		"alibaba-ecs: identity document missing account ID")
+	s.requireAttestError(s.signAttestRequest(`{"account-id":"ACCOUNT","region-id":"cn-hangzhou"}`),
+		"alibaba-ecs: identity document missing instance ID")
+	s.requireAttestError(s.signAttestRequest(`{"account-id":"ACCOUNT","instance-id":"i-INSTANCE","region-id":"evil.example.com/"}`),
+		`alibaba-ecs: identity document has invalid region "evil.example.com/"`)
+}
+
+func (s *ECSAttestorSuite) TestAttestAccountIDWhitelist() {
+	s.configureAttestor(`account_id_whitelist = ["OTHER"]`)
+	s.requireAttestError(s.signAttestRequest(testDocument),
+		`alibaba-ecs: account "ACCOUNT" is not whitelisted`)
+
+	s.configureAttestor(`account_id_whitelist = ["OTHER", "ACCOUNT"]`)
+	_, err := s.doAttest(s.signAttestRequest(testDocument))
+	s.Require().NoError(err)
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWhenRAMRoleLookupFails() {
+	s.configureAttestor("access_key_id = \"ID\"\naccess_key_secret = \"SECRET\"")
+	s.apiErr = errors.New("oh no")
+	s.requireAttestError(s.signAttestRequest(testDocument),
+		"alibaba-ecs: unable to look up instance RAM role: oh no")
+}
+
+func (s *ECSAttestorSuite) TestAttestFailsWithRAMRoleMismatch() {
+	s.configureAttestor("access_key_id = \"ID\"\naccess_key_secret = \"SECRET\"")
+	s.ramRole = "ROLE"
+	req := makeAttestRequest(&alibaba.AttestationData{
+		Document:  testDocument,
+		Signature: s.signer.Sign([]byte(testDocument)),
+		RAMRole:   "ADMIN",
+	})
+	s.requireAttestError(req, `alibaba-ecs: RAM role mismatch: instance has "ROLE"; agent reported "ADMIN"`)
+}
+
+func (s *ECSAttestorSuite) TestAttestSuccess() {
+	// without API credentials the reported RAM role is ignored
+	req := makeAttestRequest(&alibaba.AttestationData{
+		Document:  testDocument,
+		Signature: s.signer.Sign([]byte(testDocument)),
+		RAMRole:   "ROLE",
+	})
+	resp, err := s.doAttest(req)
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/alibaba_ecs/ACCOUNT/cn-hangzhou/i-INSTANCE", resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "alibaba_ecs", Value: "region:cn-hangzhou"},
+		{Type: "alibaba_ecs", Value: "zone:cn-hangzhou-h"},
+		{Type: "alibaba_ecs", Value: "image:ubuntu_18_04_64_20G_alibase_20190624.vhd"},
+		{Type: "alibaba_ecs", Value: "instance_type:ecs.g6.large"},
+	}, resp.Selectors)
+}
+
+func (s *ECSAttestorSuite) TestAttestSuccessWithRAMRole() {
+	s.configureAttestor("access_key_id = \"ID\"\naccess_key_secret = \"SECRET\"")
+	s.ramRole = "ROLE"
+	req := makeAttestRequest(&alibaba.AttestationData{
+		Document:  testDocument,
+		Signature: s.signer.Sign([]byte(testDocument)),
+		RAMRole:   "ROLE",
+	})
+	resp, err := s.doAttest(req)
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().True(resp.Valid)
+	s.Require().Equal([]*common.Selector{
+		{Type: "alibaba_ecs", Value: "region:cn-hangzhou"},
+		{Type: "alibaba_ecs", Value: "zone:cn-hangzhou-h"},
+		{Type: "alibaba_ecs", Value: "image:ubuntu_18_04_64_20G_alibase_20190624.vhd"},
+		{Type: "alibaba_ecs", Value: "instance_type:ecs.g6.large"},
+		{Type: "alibaba_ecs", Value: "ram_role:ROLE"},
+	}, resp.Selectors)
+}
+
+func (s *ECSAttestorSuite) TestConfigure() {
+	globalConfig := &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"}
+
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+	})
+	s.requireErrorContains(err, "alibaba-ecs: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	// missing global configuration
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "alibaba-ecs: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "alibaba-ecs: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// missing signing certificates
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: globalConfig,
+	})
+	s.Require().EqualError(err, "alibaba-ecs: signing_cert_path is required")
+	s.Require().Nil(resp)
+
+	// signing certificates do not exist
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("signing_cert_path = %q", s.path("missing.pem")),
+		GlobalConfig:  globalConfig,
+	})
+	s.requireErrorContains(err, "alibaba-ecs: unable to load signing certificates")
+	s.Require().Nil(resp)
+
+	// access key pair is incomplete
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("signing_cert_path = %q\naccess_key_id = \"ID\"", s.path("signing.pem")),
+		GlobalConfig:  globalConfig,
+	})
+	s.Require().EqualError(err, "alibaba-ecs: access_key_id and access_key_secret must be set together")
+	s.Require().Nil(resp)
+
+	// access key pair from the environment
+	s.env["ALIBABA_CLOUD_ACCESS_KEY_ID"] = "ENVID"
+	s.env["ALIBABA_CLOUD_ACCESS_KEY_SECRET"] = "ENVSECRET"
+	s.configureAttestor("")
+	s.Require().Equal("ENVID:ENVSECRET", s.accessKey)
+
+	// configured access key pair takes precedence
+	s.configureAttestor("access_key_id = \"ID\"\naccess_key_secret = \"SECRET\"")
+	s.Require().Equal("ID:SECRET", s.accessKey)
+}
+
+func (s *ECSAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *ECSAttestorSuite) signAttestRequest(document string) *nodeattestor.AttestRequest {
+	return makeAttestRequest(&alibaba.AttestationData{
+		Document:  document,
+		Signature: s.signer.Sign([]byte(document)),
+	})
+}
+
+func (s *ECSAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.getenv = func(key string) string {
+		return s.env[key]
+	}
+	attestor.hooks.newClient = func(accessKeyID, accessKeySecret string) apiClient {
+		s.accessKey = accessKeyID + ":" + accessKeySecret
+		return &fakeAPIClient{s: s}
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *ECSAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("signing_cert_path = %q\n%s", s.path("signing.pem"), config),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *ECSAttestorSuite) doAttest(req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	return s.doAttestOnAttestor(s.attestor, req)
+}
+
+func (s *ECSAttestorSuite) doAttestOnAttestor(attestor *nodeattestor.BuiltIn, req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	stream, err := attestor.Attest(context.Background())
+	s.Require().NoError(err)
+
+	err = stream.Send(req)
+	s.Require().NoError(err)
+
+	err = stream.CloseSend()
+	s.Require().NoError(err)
+
+	return stream.Recv()
+}
+
+func (s *ECSAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	resp, err := s.doAttest(req)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *ECSAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func (s *ECSAttestorSuite) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+func (s *ECSAttestorSuite) writeFile(name string, data []byte) {
+	s.Require().NoError(ioutil.WriteFile(s.path(name), data, 0600))
+}
+
+func TestSignParams(t *testing.T) {
+	// example from the ECS API signature documentation
+	params := url.Values{
+		"Timestamp":        {"2016-02-23T12:46:24Z"},
+		"Format":           {"XML"},
+		"AccessKeyId":      {"testid"},
+		"Action":           {"DescribeRegions"},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureNonce":   {"3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf"},
+		"Version":          {"2014-05-26"},
+		"SignatureVersion": {"1.0"},
+	}
+	require.Equal(t, "OLeaidS1JvxuMvnyHOwuJ+uX5qY=", signParams("GET", params, "testsecret"))
+}
+
+func TestGetInstanceRAMRole(t *testing.T) {
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		params := req.URL.Query()
+		signature := params.Get("Signature")
+		params.Del("Signature")
+		if params.Get("AccessKeyId") != "ID" || signature != signParams("GET", params, "SECRET") {
+			http.Error(w, `{"Code":"SignatureDoesNotMatch"}`, http.StatusBadRequest)
+			return
+		}
+		if params.Get("Action") != "DescribeInstanceRamRole" || params.Get("Version") != "2014-05-26" ||
+			params.Get("Timestamp") != "2019-06-01T12:00:00Z" || params.Get("SignatureNonce") == "" {
+			http.Error(w, `{"Code":"InvalidParameter"}`, http.StatusBadRequest)
+			return
+		}
+		var instanceIDs []string
+		if err := json.Unmarshal([]byte(params.Get("InstanceIds")), &instanceIDs); err != nil || len(instanceIDs) != 1 {
+			http.Error(w, `{"Code":"InvalidInstanceIds.Malformed"}`, http.StatusBadRequest)
+			return
+		}
+		if instanceIDs[0] != "i-INSTANCE" {
+			fmt.Fprint(w, `{"InstanceRamRoleSets":{"InstanceRamRoleSet":[]},"TotalCount":0}`)
+			return
+		}
+		fmt.Fprint(w, `{"InstanceRamRoleSets":{"InstanceRamRoleSet":[{"InstanceId":"i-INSTANCE","RamRoleName":"ROLE"}]},"TotalCount":1}`)
+	}))
+	defer server.Close()
+
+	newClient := func(accessKeySecret string) *ecsClient {
+		client := newECSClient("ID", accessKeySecret).(*ecsClient)
+		client.now = func() time.Time { return now }
+		client.endpoint = func(region string) string {
+			require.Equal(t, "cn-hangzhou", region)
+			return server.URL
+		}
+		return client
+	}
+
+	ctx := context.Background()
+	client := newClient("SECRET")
+
+	role, err := client.GetInstanceRAMRole(ctx, "cn-hangzhou", "i-INSTANCE")
+	require.NoError(t, err)
+	require.Equal(t, "ROLE", role)
+
+	role, err = client.GetInstanceRAMRole(ctx, "cn-hangzhou", "i-NOROLE")
+	require.NoError(t, err)
+	require.Equal(t, "", role)
+
+	_, err = newClient("BAD").GetInstanceRAMRole(ctx, "cn-hangzhou", "i-INSTANCE")
+	require.EqualError(t, err, "unexpected status code 400: {\"Code\":\"SignatureDoesNotMatch\"}\n")
+}
+
+type fakeAPIClient struct {
+	s *ECSAttestorSuite
+}
+
+func (c *fakeAPIClient) GetInstanceRAMRole(ctx context.Context, region, instanceID string) (string, error) {
+	c.s.Require().Equal("cn-hangzhou", region)
+	c.s.Require().Equal("i-INSTANCE", instanceID)
+	if c.s.apiErr != nil {
+		return "", c.s.apiErr
+	}
+	return c.s.ramRole, nil
+}
+
+func makeAttestRequest(attestationData *alibaba.AttestationData) *nodeattestor.AttestRequest {
+	data, err := json.Marshal(attestationData)
+	if err != nil {
+		panic(err)
+	}
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "alibaba_ecs",
+			Data: data,
+		},
+	}
+}
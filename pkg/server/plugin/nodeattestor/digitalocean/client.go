@@ -0,0 +1,96 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/plugin/digitalocean"
+	"github.com/zeebo/errs"
+)
+
+const (
+	defaultAPIURL = "https://api.digitalocean.com"
+)
+
+// Droplet holds the droplet details returned by the DigitalOcean API that
+// are relevant to node attestation
+type Droplet struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	SizeSlug  string    `json:"size_slug"`
+	Tags      []string  `json:"tags"`
+	VPCUUID   string    `json:"vpc_uuid"`
+	Region    struct {
+		Slug string `json:"slug"`
+	} `json:"region"`
+	Image struct {
+		Slug string `json:"slug"`
+	} `json:"image"`
+}
+
+// apiClient is an interface representing all of the API methods the
+// attestor needs to do its job.
+type apiClient interface {
+	GetDroplet(ctx context.Context, dropletID int64) (*Droplet, error)
+}
+
+// doClient implements apiClient using the DigitalOcean v2 REST API
+type doClient struct {
+	httpClient digitalocean.HTTPClient
+	apiURL     string
+	token      string
+}
+
+func newDOClient(apiURL, token string) apiClient {
+	return &doClient{
+		httpClient: http.DefaultClient,
+		apiURL:     apiURL,
+		token:      token,
+	}
+}
+
+func (c *doClient) GetDroplet(ctx context.Context, dropletID int64) (*Droplet, error) {
+	req, err := http.NewRequest("GET", c.apiURL+"/v2/droplets/"+strconv.FormatInt(dropletID, 10), nil)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errs.New("droplet %d not found", dropletID)
+	default:
+		return nil, errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	r := struct {
+		Droplet *Droplet `json:"droplet"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, errs.New("unable to decode response: %v", err)
+	}
+	if r.Droplet == nil {
+		return nil, errs.New("response missing droplet")
+	}
+	return r.Droplet, nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
@@ -0,0 +1,240 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/digitalocean"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+)
+
+const (
+	apiTokenEnv = "DIGITALOCEAN_TOKEN"
+
+	dropletStatusActive = "active"
+)
+
+var (
+	dropletError = errs.Class("digitalocean-droplet")
+)
+
+type DropletAttestorConfig struct {
+	// APIToken is a DigitalOcean API token with read access to droplets.
+	// If unset, the token is read from the DIGITALOCEAN_TOKEN environment
+	// variable.
+	APIToken        string   `hcl:"api_token"`
+	RegionWhitelist []string `hcl:"region_whitelist"`
+	TagWhitelist    []string `hcl:"tag_whitelist"`
+
+	// MaxDropletAge, if set, is the maximum time since the droplet was
+	// created that it is allowed to attest
+	MaxDropletAge string `hcl:"max_droplet_age"`
+}
+
+type dropletAttestorConfig struct {
+	trustDomain   string
+	client        apiClient
+	regions       map[string]bool
+	tags          map[string]bool
+	maxDropletAge time.Duration
+}
+
+type DropletAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *dropletAttestorConfig
+
+	hooks struct {
+		now       func() time.Time
+		getenv    func(string) string
+		newClient func(apiURL, token string) apiClient
+	}
+}
+
+var _ nodeattestor.Plugin = (*DropletAttestorPlugin)(nil)
+
+func New() *DropletAttestorPlugin {
+	p := &DropletAttestorPlugin{}
+	p.hooks.now = time.Now
+	p.hooks.getenv = os.Getenv
+	p.hooks.newClient = newDOClient
+	return p
+}
+
+func (p *DropletAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return dropletError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestedBefore {
+		return dropletError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return dropletError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != digitalocean.PluginName {
+		return dropletError.New("unexpected attestation data type %q", dataType)
+	}
+
+	attestationData := new(digitalocean.AttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return dropletError.New("unable to unmarshal attestation data: %v", err)
+	}
+
+	if attestationData.DropletID <= 0 {
+		return dropletError.New("missing droplet ID from attestation data")
+	}
+
+	droplet, err := config.client.GetDroplet(stream.Context(), attestationData.DropletID)
+	if err != nil {
+		return dropletError.New("unable to look up droplet: %v", err)
+	}
+
+	if droplet.ID != attestationData.DropletID {
+		return dropletError.New("droplet ID mismatch: expected %d; got %d", attestationData.DropletID, droplet.ID)
+	}
+
+	if droplet.Status != dropletStatusActive {
+		return dropletError.New("droplet %d is not active (status %q)", droplet.ID, droplet.Status)
+	}
+
+	if config.maxDropletAge > 0 {
+		if age := p.hooks.now().Sub(droplet.CreatedAt); age > config.maxDropletAge {
+			return dropletError.New("droplet %d was created %s ago; exceeds max_droplet_age", droplet.ID, age.Round(time.Second))
+		}
+	}
+
+	if len(config.regions) > 0 && !config.regions[droplet.Region.Slug] {
+		return dropletError.New("region %q is not whitelisted", droplet.Region.Slug)
+	}
+
+	if len(config.tags) > 0 && !hasWhitelistedTag(config.tags, droplet.Tags) {
+		return dropletError.New("droplet %d has no whitelisted tag", droplet.ID)
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: digitalocean.AgentID(config.trustDomain, droplet.ID),
+		Selectors:    buildSelectors(droplet),
+	})
+}
+
+func (p *DropletAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(DropletAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, dropletError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, dropletError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, dropletError.New("global configuration missing trust domain")
+	}
+
+	apiToken := hclConfig.APIToken
+	if apiToken == "" {
+		apiToken = p.hooks.getenv(apiTokenEnv)
+	}
+	if apiToken == "" {
+		return nil, dropletError.New("api_token or the %s environment variable is required", apiTokenEnv)
+	}
+
+	var maxDropletAge time.Duration
+	if hclConfig.MaxDropletAge != "" {
+		var err error
+		maxDropletAge, err = time.ParseDuration(hclConfig.MaxDropletAge)
+		if err != nil {
+			return nil, dropletError.New("invalid max_droplet_age: %v", err)
+		}
+	}
+
+	config := &dropletAttestorConfig{
+		trustDomain:   req.GlobalConfig.TrustDomain,
+		client:        p.hooks.newClient(defaultAPIURL, apiToken),
+		regions:       make(map[string]bool),
+		tags:          make(map[string]bool),
+		maxDropletAge: maxDropletAge,
+	}
+	for _, region := range hclConfig.RegionWhitelist {
+		config.regions[region] = true
+	}
+	for _, tag := range hclConfig.TagWhitelist {
+		config.tags[tag] = true
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *DropletAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *DropletAttestorPlugin) getConfig() (*dropletAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, dropletError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *DropletAttestorPlugin) setConfig(config *dropletAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func hasWhitelistedTag(whitelist map[string]bool, tags []string) bool {
+	for _, tag := range tags {
+		if whitelist[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+func buildSelectors(droplet *Droplet) []*common.Selector {
+	selectors := []*common.Selector{
+		makeSelector("region", droplet.Region.Slug),
+	}
+	if droplet.SizeSlug != "" {
+		selectors = append(selectors, makeSelector("size", droplet.SizeSlug))
+	}
+	if droplet.Image.Slug != "" {
+		selectors = append(selectors, makeSelector("image", droplet.Image.Slug))
+	}
+	if droplet.VPCUUID != "" {
+		selectors = append(selectors, makeSelector("vpc", droplet.VPCUUID))
+	}
+
+	tags := append([]string(nil), droplet.Tags...)
+	sort.Strings(tags)
+	for _, tag := range tags {
+		selectors = append(selectors, makeSelector("tag", tag))
+	}
+	return selectors
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  digitalocean.PluginName,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
@@ -0,0 +1,330 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestDropletAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(DropletAttestorSuite))
+}
+
+type DropletAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+	now      time.Time
+	env      map[string]string
+	apiToken string
+	droplet  *Droplet
+	apiErr   error
+}
+
+func (s *DropletAttestorSuite) SetupTest() {
+	s.now = time.Now()
+	s.env = map[string]string{}
+	s.apiToken = ""
+	s.apiErr = nil
+	s.droplet = &Droplet{
+		ID:        12345,
+		Name:      "web-1",
+		Status:    "active",
+		CreatedAt: s.now.Add(-time.Minute),
+		SizeSlug:  "s-1vcpu-1gb",
+		Tags:      []string{"web", "prod"},
+		VPCUUID:   "VPCUUID",
+	}
+	s.droplet.Region.Slug = "nyc3"
+	s.droplet.Image.Slug = "ubuntu-20-04-x64"
+
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *DropletAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	resp, err := s.doAttestOnAttestor(s.newAttestor(), &nodeattestor.AttestRequest{})
+	s.Require().EqualError(err, "digitalocean-droplet: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *DropletAttestorSuite) TestAttestFailsWhenAttestedBefore() {
+	s.requireAttestError(&nodeattestor.AttestRequest{AttestedBefore: true},
+		"digitalocean-droplet: node has already attested")
+}
+
+func (s *DropletAttestorSuite) TestAttestFailsWithNoAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"digitalocean-droplet: missing attestation data")
+}
+
+func (s *DropletAttestorSuite) TestAttestFailsWithWrongAttestationDataType() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "blah",
+		},
+	}, `digitalocean-droplet: unexpected attestation data type "blah"`)
+}
+
+func (s *DropletAttestorSuite) TestAttestFailsWithMalformedAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "digitalocean_droplet",
+			Data: []byte("{"),
+		},
+	}, "digitalocean-droplet: unable to unmarshal attestation data")
+}
+
+func (s *DropletAttestorSuite) TestAttestFailsWithNoDropletID() {
+	s.requireAttestError(makeAttestRequest(0),
+		"digitalocean-droplet: missing droplet ID from attestation data")
+}
+
+func (s *DropletAttestorSuite) TestAttestFailsWhenLookupFails() {
+	s.apiErr = errors.New("oh no")
+	s.requireAttestError(makeAttestRequest(12345),
+		"digitalocean-droplet: unable to look up droplet: oh no")
+}
+
+func (s *DropletAttestorSuite) TestAttestFailsWithDropletIDMismatch() {
+	s.droplet.ID = 54321
+	s.requireAttestError(makeAttestRequest(12345),
+		"digitalocean-droplet: droplet ID mismatch: expected 12345; got 54321")
+}
+
+func (s *DropletAttestorSuite) TestAttestFailsWhenDropletNotActive() {
+	s.droplet.Status = "off"
+	s.requireAttestError(makeAttestRequest(12345),
+		`digitalocean-droplet: droplet 12345 is not active (status "off")`)
+}
+
+func (s *DropletAttestorSuite) TestAttestMaxDropletAge() {
+	s.configureAttestor(`max_droplet_age = "10m"`)
+
+	s.droplet.CreatedAt = s.now.Add(-10 * time.Minute)
+	_, err := s.doAttest(makeAttestRequest(12345))
+	s.Require().NoError(err)
+
+	s.droplet.CreatedAt = s.now.Add(-11 * time.Minute)
+	s.requireAttestError(makeAttestRequest(12345),
+		"digitalocean-droplet: droplet 12345 was created 11m0s ago; exceeds max_droplet_age")
+}
+
+func (s *DropletAttestorSuite) TestAttestRegionWhitelist() {
+	s.configureAttestor(`region_whitelist = ["sfo3"]`)
+	s.requireAttestError(makeAttestRequest(12345),
+		`digitalocean-droplet: region "nyc3" is not whitelisted`)
+
+	s.configureAttestor(`region_whitelist = ["sfo3", "nyc3"]`)
+	_, err := s.doAttest(makeAttestRequest(12345))
+	s.Require().NoError(err)
+}
+
+func (s *DropletAttestorSuite) TestAttestTagWhitelist() {
+	s.configureAttestor(`tag_whitelist = ["db"]`)
+	s.requireAttestError(makeAttestRequest(12345),
+		"digitalocean-droplet: droplet 12345 has no whitelisted tag")
+
+	s.configureAttestor(`tag_whitelist = ["db", "web"]`)
+	_, err := s.doAttest(makeAttestRequest(12345))
+	s.Require().NoError(err)
+}
+
+func (s *DropletAttestorSuite) TestAttestSuccess() {
+	resp, err := s.doAttest(makeAttestRequest(12345))
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/digitalocean_droplet/12345", resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "digitalocean_droplet", Value: "region:nyc3"},
+		{Type: "digitalocean_droplet", Value: "size:s-1vcpu-1gb"},
+		{Type: "digitalocean_droplet", Value: "image:ubuntu-20-04-x64"},
+		{Type: "digitalocean_droplet", Value: "vpc:VPCUUID"},
+		{Type: "digitalocean_droplet", Value: "tag:prod"},
+		{Type: "digitalocean_droplet", Value: "tag:web"},
+	}, resp.Selectors)
+}
+
+func (s *DropletAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+	})
+	s.requireErrorContains(err, "digitalocean-droplet: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	// missing global configuration
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "digitalocean-droplet: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "digitalocean-droplet: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// missing API token
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "digitalocean-droplet: api_token or the DIGITALOCEAN_TOKEN environment variable is required")
+	s.Require().Nil(resp)
+
+	// invalid max droplet age
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `
+		api_token = "TOKEN"
+		max_droplet_age = "blah"
+		`,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.requireErrorContains(err, "digitalocean-droplet: invalid max_droplet_age")
+	s.Require().Nil(resp)
+
+	// API token from the environment
+	s.env["DIGITALOCEAN_TOKEN"] = "ENVTOKEN"
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&plugin.ConfigureResponse{}, resp)
+	s.Require().Equal("ENVTOKEN", s.apiToken)
+
+	// configured API token takes precedence
+	s.configureAttestor("")
+	s.Require().Equal("TOKEN", s.apiToken)
+}
+
+func (s *DropletAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *DropletAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.now = func() time.Time {
+		return s.now
+	}
+	attestor.hooks.getenv = func(key string) string {
+		return s.env[key]
+	}
+	attestor.hooks.newClient = func(apiURL, token string) apiClient {
+		s.Require().Equal("https://api.digitalocean.com", apiURL)
+		s.apiToken = token
+		return fakeAPIClient(func(ctx context.Context, dropletID int64) (*Droplet, error) {
+			if s.apiErr != nil {
+				return nil, s.apiErr
+			}
+			return s.droplet, nil
+		})
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *DropletAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "api_token = \"TOKEN\"\n" + config,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *DropletAttestorSuite) doAttest(req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	return s.doAttestOnAttestor(s.attestor, req)
+}
+
+func (s *DropletAttestorSuite) doAttestOnAttestor(attestor *nodeattestor.BuiltIn, req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	stream, err := attestor.Attest(context.Background())
+	s.Require().NoError(err)
+
+	err = stream.Send(req)
+	s.Require().NoError(err)
+
+	err = stream.CloseSend()
+	s.Require().NoError(err)
+
+	return stream.Recv()
+}
+
+func (s *DropletAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	resp, err := s.doAttest(req)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *DropletAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func TestGetDroplet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if v := req.Header.Get("Authorization"); v != "Bearer TOKEN" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch req.URL.Path {
+		case "/v2/droplets/12345":
+			fmt.Fprint(w, `{"droplet": {"id": 12345, "status": "active", "created_at": "2019-06-01T12:00:00Z", "region": {"slug": "nyc3"}, "tags": ["web"]}}`)
+		case "/v2/droplets/2":
+			fmt.Fprint(w, `{}`)
+		case "/v2/droplets/3":
+			fmt.Fprint(w, `{`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := newDOClient(server.URL, "TOKEN")
+
+	droplet, err := client.GetDroplet(ctx, 12345)
+	require.NoError(t, err)
+	require.Equal(t, int64(12345), droplet.ID)
+	require.Equal(t, "active", droplet.Status)
+	require.Equal(t, time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC), droplet.CreatedAt)
+	require.Equal(t, "nyc3", droplet.Region.Slug)
+	require.Equal(t, []string{"web"}, droplet.Tags)
+
+	_, err = client.GetDroplet(ctx, 1)
+	require.EqualError(t, err, "droplet 1 not found")
+
+	_, err = client.GetDroplet(ctx, 2)
+	require.EqualError(t, err, "response missing droplet")
+
+	_, err = client.GetDroplet(ctx, 3)
+	require.EqualError(t, err, "unable to decode response: unexpected EOF")
+
+	_, err = newDOClient(server.URL, "BAD").GetDroplet(ctx, 12345)
+	require.EqualError(t, err, "unexpected status code 401: unauthorized\n")
+}
+
+type fakeAPIClient func(ctx context.Context, dropletID int64) (*Droplet, error)
+
+func (fn fakeAPIClient) GetDroplet(ctx context.Context, dropletID int64) (*Droplet, error) {
+	return fn(ctx, dropletID)
+}
+
+func makeAttestRequest(dropletID int64) *nodeattestor.AttestRequest {
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "digitalocean_droplet",
+			Data: []byte(fmt.Sprintf(`{"droplet_id": %d}`, dropletID)),
+		},
+	}
+}
@@ -0,0 +1,105 @@
+package equinix
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/plugin/equinix"
+	"github.com/zeebo/errs"
+)
+
+const (
+	defaultAPIURL = "https://api.equinix.com/metal/v1"
+)
+
+// Device holds the device details returned by the Equinix Metal API that
+// are relevant to node attestation
+type Device struct {
+	ID        string    `json:"id"`
+	Hostname  string    `json:"hostname"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+	Tags      []string  `json:"tags"`
+	Facility  struct {
+		Code string `json:"code"`
+	} `json:"facility"`
+	Metro struct {
+		Code string `json:"code"`
+	} `json:"metro"`
+	Plan struct {
+		Slug string `json:"slug"`
+	} `json:"plan"`
+	Project struct {
+		Href string `json:"href"`
+	} `json:"project"`
+}
+
+// ProjectID returns the ID of the project the device belongs to. The API
+// only references the project by its URL.
+func (d *Device) ProjectID() string {
+	if d.Project.Href == "" {
+		return ""
+	}
+	return path.Base(d.Project.Href)
+}
+
+// apiClient is an interface representing all of the API methods the
+// attestor needs to do its job.
+type apiClient interface {
+	GetDevice(ctx context.Context, deviceID string) (*Device, error)
+}
+
+// metalClient implements apiClient using the Equinix Metal REST API
+type metalClient struct {
+	httpClient equinix.HTTPClient
+	apiURL     string
+	token      string
+}
+
+func newMetalClient(apiURL, token string) apiClient {
+	return &metalClient{
+		httpClient: http.DefaultClient,
+		apiURL:     apiURL,
+		token:      token,
+	}
+}
+
+func (c *metalClient) GetDevice(ctx context.Context, deviceID string) (*Device, error) {
+	req, err := http.NewRequest("GET", c.apiURL+"/devices/"+url.PathEscape(deviceID), nil)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("X-Auth-Token", c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errs.New("device %q not found", deviceID)
+	default:
+		return nil, errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	device := new(Device)
+	if err := json.NewDecoder(resp.Body).Decode(device); err != nil {
+		return nil, errs.New("unable to decode response: %v", err)
+	}
+	return device, nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
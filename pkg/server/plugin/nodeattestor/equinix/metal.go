@@ -0,0 +1,235 @@
+package equinix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/equinix"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+)
+
+const (
+	apiTokenEnv = "METAL_AUTH_TOKEN"
+
+	deviceStateActive = "active"
+)
+
+var (
+	metalError = errs.Class("equinix-metal")
+)
+
+type MetalAttestorConfig struct {
+	// APIToken is an Equinix Metal API token with read access to the
+	// devices of the whitelisted projects. If unset, the token is read from
+	// the METAL_AUTH_TOKEN environment variable.
+	APIToken           string   `hcl:"api_token"`
+	ProjectIDWhitelist []string `hcl:"project_id_whitelist"`
+	FacilityWhitelist  []string `hcl:"facility_whitelist"`
+
+	// MaxDeviceAge, if set, is the maximum time since the device was
+	// created that it is allowed to attest
+	MaxDeviceAge string `hcl:"max_device_age"`
+}
+
+type metalAttestorConfig struct {
+	trustDomain  string
+	client       apiClient
+	projectIDs   map[string]bool
+	facilities   map[string]bool
+	maxDeviceAge time.Duration
+}
+
+type MetalAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *metalAttestorConfig
+
+	hooks struct {
+		now       func() time.Time
+		getenv    func(string) string
+		newClient func(apiURL, token string) apiClient
+	}
+}
+
+var _ nodeattestor.Plugin = (*MetalAttestorPlugin)(nil)
+
+func New() *MetalAttestorPlugin {
+	p := &MetalAttestorPlugin{}
+	p.hooks.now = time.Now
+	p.hooks.getenv = os.Getenv
+	p.hooks.newClient = newMetalClient
+	return p
+}
+
+func (p *MetalAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return metalError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestedBefore {
+		return metalError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return metalError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != equinix.PluginName {
+		return metalError.New("unexpected attestation data type %q", dataType)
+	}
+
+	attestationData := new(equinix.AttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return metalError.New("unable to unmarshal attestation data: %v", err)
+	}
+
+	if attestationData.DeviceID == "" {
+		return metalError.New("missing device ID from attestation data")
+	}
+
+	device, err := config.client.GetDevice(stream.Context(), attestationData.DeviceID)
+	if err != nil {
+		return metalError.New("unable to look up device: %v", err)
+	}
+
+	if device.ID != attestationData.DeviceID {
+		return metalError.New("device ID mismatch: expected %q; got %q", attestationData.DeviceID, device.ID)
+	}
+
+	if device.State != deviceStateActive {
+		return metalError.New("device %q is not active (state %q)", device.ID, device.State)
+	}
+
+	if config.maxDeviceAge > 0 {
+		if age := p.hooks.now().Sub(device.CreatedAt); age > config.maxDeviceAge {
+			return metalError.New("device %q was created %s ago; exceeds max_device_age", device.ID, age.Round(time.Second))
+		}
+	}
+
+	if !config.projectIDs[device.ProjectID()] {
+		return metalError.New("project ID %q is not whitelisted", device.ProjectID())
+	}
+
+	if len(config.facilities) > 0 && !config.facilities[device.Facility.Code] {
+		return metalError.New("facility %q is not whitelisted", device.Facility.Code)
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: equinix.AgentID(config.trustDomain, device.ID),
+		Selectors:    buildSelectors(device),
+	})
+}
+
+func (p *MetalAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(MetalAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, metalError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, metalError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, metalError.New("global configuration missing trust domain")
+	}
+
+	apiToken := hclConfig.APIToken
+	if apiToken == "" {
+		apiToken = p.hooks.getenv(apiTokenEnv)
+	}
+	if apiToken == "" {
+		return nil, metalError.New("api_token or the %s environment variable is required", apiTokenEnv)
+	}
+
+	if len(hclConfig.ProjectIDWhitelist) == 0 {
+		return nil, metalError.New("configuration must have at least one project ID whitelisted")
+	}
+
+	var maxDeviceAge time.Duration
+	if hclConfig.MaxDeviceAge != "" {
+		var err error
+		maxDeviceAge, err = time.ParseDuration(hclConfig.MaxDeviceAge)
+		if err != nil {
+			return nil, metalError.New("invalid max_device_age: %v", err)
+		}
+	}
+
+	config := &metalAttestorConfig{
+		trustDomain:  req.GlobalConfig.TrustDomain,
+		client:       p.hooks.newClient(defaultAPIURL, apiToken),
+		projectIDs:   make(map[string]bool),
+		facilities:   make(map[string]bool),
+		maxDeviceAge: maxDeviceAge,
+	}
+	for _, projectID := range hclConfig.ProjectIDWhitelist {
+		config.projectIDs[projectID] = true
+	}
+	for _, facility := range hclConfig.FacilityWhitelist {
+		config.facilities[facility] = true
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *MetalAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *MetalAttestorPlugin) getConfig() (*metalAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, metalError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *MetalAttestorPlugin) setConfig(config *metalAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func buildSelectors(device *Device) []*common.Selector {
+	selectors := []*common.Selector{
+		makeSelector("project", device.ProjectID()),
+	}
+	if device.Facility.Code != "" {
+		selectors = append(selectors, makeSelector("facility", device.Facility.Code))
+	}
+	if device.Metro.Code != "" {
+		selectors = append(selectors, makeSelector("metro", device.Metro.Code))
+	}
+	if device.Plan.Slug != "" {
+		selectors = append(selectors, makeSelector("plan", device.Plan.Slug))
+	}
+
+	tags := append([]string(nil), device.Tags...)
+	sort.Strings(tags)
+	for _, tag := range tags {
+		selectors = append(selectors, makeSelector("tag", tag))
+	}
+	return selectors
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  equinix.PluginName,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
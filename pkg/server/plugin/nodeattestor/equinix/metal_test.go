@@ -0,0 +1,331 @@
+package equinix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMetalAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(MetalAttestorSuite))
+}
+
+type MetalAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+	now      time.Time
+	env      map[string]string
+	apiToken string
+	device   *Device
+	apiErr   error
+}
+
+func (s *MetalAttestorSuite) SetupTest() {
+	s.now = time.Now()
+	s.env = map[string]string{}
+	s.apiToken = ""
+	s.apiErr = nil
+	s.device = &Device{
+		ID:        "DEVICEID",
+		Hostname:  "node-1",
+		State:     "active",
+		CreatedAt: s.now.Add(-time.Minute),
+		Tags:      []string{"k8s", "prod"},
+	}
+	s.device.Facility.Code = "sv15"
+	s.device.Metro.Code = "sv"
+	s.device.Plan.Slug = "c3.small.x86"
+	s.device.Project.Href = "/metal/v1/projects/PROJECTID"
+
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *MetalAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	resp, err := s.doAttestOnAttestor(s.newAttestor(), &nodeattestor.AttestRequest{})
+	s.Require().EqualError(err, "equinix-metal: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *MetalAttestorSuite) TestAttestFailsWhenAttestedBefore() {
+	s.requireAttestError(&nodeattestor.AttestRequest{AttestedBefore: true},
+		"equinix-metal: node has already attested")
+}
+
+func (s *MetalAttestorSuite) TestAttestFailsWithNoAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"equinix-metal: missing attestation data")
+}
+
+func (s *MetalAttestorSuite) TestAttestFailsWithWrongAttestationDataType() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "blah",
+		},
+	}, `equinix-metal: unexpected attestation data type "blah"`)
+}
+
+func (s *MetalAttestorSuite) TestAttestFailsWithMalformedAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "equinix_metal",
+			Data: []byte("{"),
+		},
+	}, "equinix-metal: unable to unmarshal attestation data")
+}
+
+func (s *MetalAttestorSuite) TestAttestFailsWithNoDeviceID() {
+	s.requireAttestError(makeAttestRequest(""),
+		"equinix-metal: missing device ID from attestation data")
+}
+
+func (s *MetalAttestorSuite) TestAttestFailsWhenLookupFails() {
+	s.apiErr = errors.New("oh no")
+	s.requireAttestError(makeAttestRequest("DEVICEID"),
+		"equinix-metal: unable to look up device: oh no")
+}
+
+func (s *MetalAttestorSuite) TestAttestFailsWithDeviceIDMismatch() {
+	s.device.ID = "OTHERID"
+	s.requireAttestError(makeAttestRequest("DEVICEID"),
+		`equinix-metal: device ID mismatch: expected "DEVICEID"; got "OTHERID"`)
+}
+
+func (s *MetalAttestorSuite) TestAttestFailsWhenDeviceNotActive() {
+	s.device.State = "provisioning"
+	s.requireAttestError(makeAttestRequest("DEVICEID"),
+		`equinix-metal: device "DEVICEID" is not active (state "provisioning")`)
+}
+
+func (s *MetalAttestorSuite) TestAttestFailsWhenProjectNotWhitelisted() {
+	s.device.Project.Href = "/metal/v1/projects/OTHERPROJECT"
+	s.requireAttestError(makeAttestRequest("DEVICEID"),
+		`equinix-metal: project ID "OTHERPROJECT" is not whitelisted`)
+}
+
+func (s *MetalAttestorSuite) TestAttestMaxDeviceAge() {
+	s.configureAttestor(`max_device_age = "1h"`)
+
+	s.device.CreatedAt = s.now.Add(-time.Hour)
+	_, err := s.doAttest(makeAttestRequest("DEVICEID"))
+	s.Require().NoError(err)
+
+	s.device.CreatedAt = s.now.Add(-time.Hour - time.Minute)
+	s.requireAttestError(makeAttestRequest("DEVICEID"),
+		`equinix-metal: device "DEVICEID" was created 1h1m0s ago; exceeds max_device_age`)
+}
+
+func (s *MetalAttestorSuite) TestAttestFacilityWhitelist() {
+	s.configureAttestor(`facility_whitelist = ["da11"]`)
+	s.requireAttestError(makeAttestRequest("DEVICEID"),
+		`equinix-metal: facility "sv15" is not whitelisted`)
+
+	s.configureAttestor(`facility_whitelist = ["da11", "sv15"]`)
+	_, err := s.doAttest(makeAttestRequest("DEVICEID"))
+	s.Require().NoError(err)
+}
+
+func (s *MetalAttestorSuite) TestAttestSuccess() {
+	resp, err := s.doAttest(makeAttestRequest("DEVICEID"))
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/equinix_metal/DEVICEID", resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "equinix_metal", Value: "project:PROJECTID"},
+		{Type: "equinix_metal", Value: "facility:sv15"},
+		{Type: "equinix_metal", Value: "metro:sv"},
+		{Type: "equinix_metal", Value: "plan:c3.small.x86"},
+		{Type: "equinix_metal", Value: "tag:k8s"},
+		{Type: "equinix_metal", Value: "tag:prod"},
+	}, resp.Selectors)
+}
+
+func (s *MetalAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+	})
+	s.requireErrorContains(err, "equinix-metal: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	// missing global configuration
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "equinix-metal: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "equinix-metal: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// missing API token
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "equinix-metal: api_token or the METAL_AUTH_TOKEN environment variable is required")
+	s.Require().Nil(resp)
+
+	// missing project whitelist
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `api_token = "TOKEN"`,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "equinix-metal: configuration must have at least one project ID whitelisted")
+	s.Require().Nil(resp)
+
+	// invalid max device age
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `
+		api_token = "TOKEN"
+		project_id_whitelist = ["PROJECTID"]
+		max_device_age = "blah"
+		`,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.requireErrorContains(err, "equinix-metal: invalid max_device_age")
+	s.Require().Nil(resp)
+
+	// API token from the environment
+	s.env["METAL_AUTH_TOKEN"] = "ENVTOKEN"
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `project_id_whitelist = ["PROJECTID"]`,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&plugin.ConfigureResponse{}, resp)
+	s.Require().Equal("ENVTOKEN", s.apiToken)
+
+	// configured API token takes precedence
+	s.configureAttestor("")
+	s.Require().Equal("TOKEN", s.apiToken)
+}
+
+func (s *MetalAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *MetalAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.now = func() time.Time {
+		return s.now
+	}
+	attestor.hooks.getenv = func(key string) string {
+		return s.env[key]
+	}
+	attestor.hooks.newClient = func(apiURL, token string) apiClient {
+		s.Require().Equal("https://api.equinix.com/metal/v1", apiURL)
+		s.apiToken = token
+		return fakeAPIClient(func(ctx context.Context, deviceID string) (*Device, error) {
+			if s.apiErr != nil {
+				return nil, s.apiErr
+			}
+			return s.device, nil
+		})
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *MetalAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "api_token = \"TOKEN\"\nproject_id_whitelist = [\"PROJECTID\"]\n" + config,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *MetalAttestorSuite) doAttest(req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	return s.doAttestOnAttestor(s.attestor, req)
+}
+
+func (s *MetalAttestorSuite) doAttestOnAttestor(attestor *nodeattestor.BuiltIn, req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	stream, err := attestor.Attest(context.Background())
+	s.Require().NoError(err)
+
+	err = stream.Send(req)
+	s.Require().NoError(err)
+
+	err = stream.CloseSend()
+	s.Require().NoError(err)
+
+	return stream.Recv()
+}
+
+func (s *MetalAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	resp, err := s.doAttest(req)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *MetalAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func TestGetDevice(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if v := req.Header.Get("X-Auth-Token"); v != "TOKEN" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch req.URL.Path {
+		case "/devices/DEVICEID":
+			fmt.Fprint(w, `{"id": "DEVICEID", "state": "active", "created_at": "2019-06-01T12:00:00Z", "facility": {"code": "sv15"}, "project": {"href": "/metal/v1/projects/PROJECTID"}}`)
+		case "/devices/MALFORMED":
+			fmt.Fprint(w, `{`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := newMetalClient(server.URL, "TOKEN")
+
+	device, err := client.GetDevice(ctx, "DEVICEID")
+	require.NoError(t, err)
+	require.Equal(t, "DEVICEID", device.ID)
+	require.Equal(t, "active", device.State)
+	require.Equal(t, time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC), device.CreatedAt)
+	require.Equal(t, "sv15", device.Facility.Code)
+	require.Equal(t, "PROJECTID", device.ProjectID())
+
+	_, err = client.GetDevice(ctx, "MISSING")
+	require.EqualError(t, err, `device "MISSING" not found`)
+
+	_, err = client.GetDevice(ctx, "MALFORMED")
+	require.EqualError(t, err, "unable to decode response: unexpected EOF")
+
+	_, err = newMetalClient(server.URL, "BAD").GetDevice(ctx, "DEVICEID")
+	require.EqualError(t, err, "unexpected status code 401: unauthorized\n")
+}
+
+type fakeAPIClient func(ctx context.Context, deviceID string) (*Device, error)
+
+func (fn fakeAPIClient) GetDevice(ctx context.Context, deviceID string) (*Device, error) {
+	return fn(ctx, deviceID)
+}
+
+func makeAttestRequest(deviceID string) *nodeattestor.AttestRequest {
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "equinix_metal",
+			Data: []byte(fmt.Sprintf(`{"device_id": %q}`, deviceID)),
+		},
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"text/template"
 
@@ -11,6 +12,7 @@ import (
 
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/spiffe/spire/pkg/common/plugin/gcp"
+	"github.com/spiffe/spire/proto/common"
 	spi "github.com/spiffe/spire/proto/common/plugin"
 	"github.com/spiffe/spire/proto/server/nodeattestor"
 )
@@ -77,6 +79,7 @@ func (p *IITAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) erro
 	resp := &nodeattestor.AttestResponse{
 		Valid:        true,
 		BaseSPIFFEID: id.String(),
+		Selectors:    buildSelectors(identityMetadata),
 	}
 
 	if err := stream.Send(resp); err != nil {
@@ -172,6 +175,28 @@ func validateAttestationAndExtractIdentityMetadata(stream nodeattestor.Attest_Pl
 	return identityToken.Google.ComputeEngine, nil
 }
 
+// buildSelectors returns the integrity selectors for the instance. Instances
+// that are not Shielded VMs report false for all of the integrity checks.
+func buildSelectors(computeEngine gcp.ComputeEngine) []*common.Selector {
+	var shielded gcp.ShieldedInstance
+	if computeEngine.ShieldedInstance != nil {
+		shielded = *computeEngine.ShieldedInstance
+	}
+	return []*common.Selector{
+		makeSelector("secure-boot", shielded.SecureBoot),
+		makeSelector("vtpm", shielded.VTPM),
+		makeSelector("integrity-validated", shielded.IntegrityValidated),
+		makeSelector("confidential-compute", computeEngine.InstanceConfidentiality == 1),
+	}
+}
+
+func makeSelector(kind string, value bool) *common.Selector {
+	return &common.Selector{
+		Type:  gcp.PluginName,
+		Value: kind + ":" + strconv.FormatBool(value),
+	}
+}
+
 func newError(msg string) error {
 	return errors.New("gcp-iit: " + msg)
 }
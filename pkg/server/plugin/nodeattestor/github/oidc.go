@@ -0,0 +1,247 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/jwtutil"
+	"github.com/spiffe/spire/pkg/common/plugin/github"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	pluginName = "github_oidc"
+
+	// GitHub OIDC tokens are short lived, so only a small leeway is given to
+	// account for clock differences between GitHub and the server.
+	tokenLeeway = time.Minute
+
+	keySetRefreshInterval = time.Hour
+)
+
+var (
+	oidcError = errs.Class("github-oidc")
+)
+
+type OIDCAttestorConfig struct {
+	Issuer                   string   `hcl:"issuer"`
+	Audience                 string   `hcl:"audience"`
+	RepositoryOwnerWhitelist []string `hcl:"repository_owner_whitelist"`
+}
+
+type oidcAttestorConfig struct {
+	trustDomain      string
+	issuer           string
+	audience         string
+	repositoryOwners map[string]bool
+	keySetProvider   jwtutil.KeySetProvider
+}
+
+type OIDCAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *oidcAttestorConfig
+
+	hooks struct {
+		now               func() time.Time
+		newKeySetProvider func(issuer string) jwtutil.KeySetProvider
+	}
+}
+
+var _ nodeattestor.Plugin = (*OIDCAttestorPlugin)(nil)
+
+func NewOIDCAttestorPlugin() *OIDCAttestorPlugin {
+	p := &OIDCAttestorPlugin{}
+	p.hooks.now = time.Now
+	p.hooks.newKeySetProvider = func(issuer string) jwtutil.KeySetProvider {
+		return jwtutil.NewCachingKeySetProvider(jwtutil.OIDCIssuer(issuer), keySetRefreshInterval)
+	}
+	return p
+}
+
+func (p *OIDCAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return oidcError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestedBefore {
+		return oidcError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return oidcError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != pluginName {
+		return oidcError.New("unexpected attestation data type %q", dataType)
+	}
+
+	if req.AttestationData.Data == nil {
+		return oidcError.New("missing attestation data payload")
+	}
+
+	attestationData := new(github.OIDCAttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return oidcError.New("failed to unmarshal data payload: %v", err)
+	}
+
+	if attestationData.Token == "" {
+		return oidcError.New("missing token from attestation data")
+	}
+
+	keySet, err := config.keySetProvider.GetKeySet(stream.Context())
+	if err != nil {
+		return oidcError.New("unable to obtain JWKS: %v", err)
+	}
+
+	token, err := jwt.ParseSigned(attestationData.Token)
+	if err != nil {
+		return oidcError.New("unable to parse token: %v", err)
+	}
+
+	keyID, ok := getTokenKeyID(token)
+	if !ok {
+		return oidcError.New("token missing key id")
+	}
+
+	keys := keySet.Key(keyID)
+	if len(keys) == 0 {
+		return oidcError.New("key id %q not found", keyID)
+	}
+
+	claims := new(github.OIDCTokenClaims)
+	if err := token.Claims(&keys[0], claims); err != nil {
+		return oidcError.New("unable to verify token: %v", err)
+	}
+
+	if err := claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:   config.issuer,
+		Audience: []string{config.audience},
+		Time:     p.hooks.now(),
+	}, tokenLeeway); err != nil {
+		return oidcError.New("unable to validate token claims: %v", err)
+	}
+
+	switch {
+	case claims.Repository == "":
+		return oidcError.New("token missing repository claim")
+	case claims.RepositoryOwner == "":
+		return oidcError.New("token missing repository owner claim")
+	case claims.RunID == "":
+		return oidcError.New("token missing run ID claim")
+	case claims.ID == "":
+		return oidcError.New("token missing token ID claim")
+	}
+
+	if !config.repositoryOwners[claims.RepositoryOwner] {
+		return oidcError.New("repository owner %q is not whitelisted", claims.RepositoryOwner)
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: claims.AgentID(config.trustDomain),
+		Selectors:    buildSelectors(claims),
+	})
+}
+
+func (p *OIDCAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(OIDCAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, oidcError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, oidcError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, oidcError.New("global configuration missing trust domain")
+	}
+
+	if len(hclConfig.RepositoryOwnerWhitelist) == 0 {
+		return nil, oidcError.New("configuration must have at least one repository owner whitelisted")
+	}
+
+	config := &oidcAttestorConfig{
+		trustDomain:      req.GlobalConfig.TrustDomain,
+		issuer:           hclConfig.Issuer,
+		audience:         hclConfig.Audience,
+		repositoryOwners: make(map[string]bool),
+	}
+	if config.issuer == "" {
+		config.issuer = github.DefaultOIDCIssuer
+	}
+	if config.audience == "" {
+		config.audience = github.DefaultAudience
+	}
+	for _, owner := range hclConfig.RepositoryOwnerWhitelist {
+		config.repositoryOwners[owner] = true
+	}
+	config.keySetProvider = p.hooks.newKeySetProvider(config.issuer)
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *OIDCAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *OIDCAttestorPlugin) getConfig() (*oidcAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, oidcError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *OIDCAttestorPlugin) setConfig(config *oidcAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func buildSelectors(claims *github.OIDCTokenClaims) []*common.Selector {
+	selectors := []*common.Selector{
+		makeSelector("repository", claims.Repository),
+		makeSelector("repository_owner", claims.RepositoryOwner),
+	}
+	if claims.Ref != "" {
+		selectors = append(selectors, makeSelector("ref", claims.Ref))
+	}
+	if claims.Environment != "" {
+		selectors = append(selectors, makeSelector("environment", claims.Environment))
+	}
+	if claims.Workflow != "" {
+		selectors = append(selectors, makeSelector("workflow", claims.Workflow))
+	}
+	return selectors
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  pluginName,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
+
+func getTokenKeyID(token *jwt.JSONWebToken) (string, bool) {
+	for _, h := range token.Headers {
+		if h.KeyID != "" {
+			return h.KeyID, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,378 @@
+package github
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/jwtutil"
+	"github.com/spiffe/spire/pkg/common/plugin/github"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/stretchr/testify/suite"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestOIDCAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(OIDCAttestorSuite))
+}
+
+type OIDCAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+	key      *ecdsa.PrivateKey
+	jwks     *jose.JSONWebKeySet
+	now      time.Time
+	issuer   string
+}
+
+func (s *OIDCAttestorSuite) SetupTest() {
+	var err error
+	s.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	s.jwks = new(jose.JSONWebKeySet)
+	// JWT numeric dates have second granularity
+	s.now = time.Now().Truncate(time.Second)
+	s.issuer = ""
+
+	s.attestor = s.newAttestor()
+	s.configureAttestor()
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	resp, err := s.doAttestOnAttestor(s.newAttestor(), &nodeattestor.AttestRequest{})
+	s.Require().EqualError(err, "github-oidc: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWhenAttestedBefore() {
+	s.requireAttestError(&nodeattestor.AttestRequest{AttestedBefore: true},
+		"github-oidc: node has already attested")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithNoAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"github-oidc: missing attestation data")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithWrongAttestationDataType() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "blah",
+		},
+	}, `github-oidc: unexpected attestation data type "blah"`)
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithNoAttestationDataPayload() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "github_oidc",
+		},
+	}, "github-oidc: missing attestation data payload")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithMalformedAttestationDataPayload() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "github_oidc",
+			Data: []byte("{"),
+		},
+	}, "github-oidc: failed to unmarshal data payload")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithNoToken() {
+	s.requireAttestError(makeAttestRequest(""),
+		"github-oidc: missing token from attestation data")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithMalformedToken() {
+	s.requireAttestError(makeAttestRequest("blah"),
+		"github-oidc: unable to parse token")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsIfTokenKeyIDMissing() {
+	s.requireAttestError(s.signAttestRequest("", s.validClaims()),
+		"github-oidc: token missing key id")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsIfTokenKeyIDNotFound() {
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		`github-oidc: key id "KEYID" not found`)
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithBadSignature() {
+	s.addKey("KEYID")
+
+	// sign a token and replace the signature
+	token := s.signToken("KEYID", s.validClaims())
+	parts := strings.Split(token, ".")
+	s.Require().Len(parts, 3)
+	parts[2] = "aaaa"
+	token = strings.Join(parts, ".")
+
+	s.requireAttestError(makeAttestRequest(token),
+		"unable to verify token")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsClaimValidation() {
+	s.addKey("KEYID")
+
+	// wrong issuer
+	claims := s.validClaims()
+	claims.Issuer = "https://example.org"
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"invalid issuer claim")
+
+	// wrong audience
+	claims = s.validClaims()
+	claims.Audience = []string{"FOO"}
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"invalid audience claim")
+
+	// missing repository
+	claims = s.validClaims()
+	claims.Repository = ""
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"github-oidc: token missing repository claim")
+
+	// missing repository owner
+	claims = s.validClaims()
+	claims.RepositoryOwner = ""
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"github-oidc: token missing repository owner claim")
+
+	// missing run id
+	claims = s.validClaims()
+	claims.RunID = ""
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"github-oidc: token missing run ID claim")
+
+	// missing token id
+	claims = s.validClaims()
+	claims.ID = ""
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"github-oidc: token missing token ID claim")
+
+	// repository owner not whitelisted
+	claims = s.validClaims()
+	claims.RepositoryOwner = "evil-org"
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		`github-oidc: repository owner "evil-org" is not whitelisted`)
+}
+
+func (s *OIDCAttestorSuite) TestAttestTokenExpiration() {
+	s.addKey("KEYID")
+	req := s.signAttestRequest("KEYID", s.validClaims())
+
+	// within the 1m leeway (token expires at 5m + 1m leeway = 6m)
+	s.adjustTime(6 * time.Minute)
+	_, err := s.doAttest(req)
+	s.Require().NoError(err)
+
+	// just after the 1m leeway
+	s.adjustTime(time.Second)
+	s.requireAttestError(req, "token is expired")
+}
+
+func (s *OIDCAttestorSuite) TestAttestSuccess() {
+	s.addKey("KEYID")
+
+	resp, err := s.doAttest(s.signAttestRequest("KEYID", s.validClaims()))
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/github_oidc/octo-org/octo-repo/42/TOKENID", resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "github_oidc", Value: "repository:octo-org/octo-repo"},
+		{Type: "github_oidc", Value: "repository_owner:octo-org"},
+		{Type: "github_oidc", Value: "ref:refs/heads/main"},
+		{Type: "github_oidc", Value: "environment:production"},
+		{Type: "github_oidc", Value: "workflow:deploy"},
+	}, resp.Selectors)
+
+	// optional claims are left out of the selectors
+	claims := s.validClaims()
+	claims.Environment = ""
+	resp, err = s.doAttest(s.signAttestRequest("KEYID", claims))
+	s.Require().NoError(err)
+	s.Require().Equal([]*common.Selector{
+		{Type: "github_oidc", Value: "repository:octo-org/octo-repo"},
+		{Type: "github_oidc", Value: "repository_owner:octo-org"},
+		{Type: "github_oidc", Value: "ref:refs/heads/main"},
+		{Type: "github_oidc", Value: "workflow:deploy"},
+	}, resp.Selectors)
+}
+
+func (s *OIDCAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+	})
+	s.requireErrorContains(err, "github-oidc: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	// missing global configuration
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "github-oidc: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "github-oidc: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// missing repository owners
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: ``,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "github-oidc: configuration must have at least one repository owner whitelisted")
+	s.Require().Nil(resp)
+
+	// success with a custom issuer and audience
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `
+		issuer = "https://ghe.example.org/_services/token"
+		audience = "AUDIENCE"
+		repository_owner_whitelist = ["octo-org"]
+		`,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&plugin.ConfigureResponse{}, resp)
+	s.Require().Equal("https://ghe.example.org/_services/token", s.issuer)
+
+	s.addKey("KEYID")
+	claims := s.validClaims()
+	claims.Issuer = "https://ghe.example.org/_services/token"
+	claims.Audience = []string{"AUDIENCE"}
+	_, err = s.doAttest(s.signAttestRequest("KEYID", claims))
+	s.Require().NoError(err)
+}
+
+func (s *OIDCAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *OIDCAttestorSuite) adjustTime(d time.Duration) {
+	s.now = s.now.Add(d)
+}
+
+func (s *OIDCAttestorSuite) validClaims() *github.OIDCTokenClaims {
+	return &github.OIDCTokenClaims{
+		Claims: jwt.Claims{
+			ID:        "TOKENID",
+			Issuer:    github.DefaultOIDCIssuer,
+			Subject:   "repo:octo-org/octo-repo:environment:production",
+			Audience:  []string{github.DefaultAudience},
+			NotBefore: jwt.NewNumericDate(s.now),
+			Expiry:    jwt.NewNumericDate(s.now.Add(5 * time.Minute)),
+		},
+		Repository:      "octo-org/octo-repo",
+		RepositoryOwner: "octo-org",
+		Ref:             "refs/heads/main",
+		Environment:     "production",
+		Workflow:        "deploy",
+		RunID:           "42",
+	}
+}
+
+func (s *OIDCAttestorSuite) signToken(keyID string, claims *github.OIDCTokenClaims) string {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key: jose.JSONWebKey{
+			Key:   s.key,
+			KeyID: keyID,
+		},
+	}, nil)
+	s.Require().NoError(err)
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *OIDCAttestorSuite) signAttestRequest(keyID string, claims *github.OIDCTokenClaims) *nodeattestor.AttestRequest {
+	return makeAttestRequest(s.signToken(keyID, claims))
+}
+
+func (s *OIDCAttestorSuite) addKey(keyID string) {
+	s.jwks.Keys = append(s.jwks.Keys, jose.JSONWebKey{
+		Key:   s.key.Public(),
+		KeyID: keyID,
+	})
+}
+
+func (s *OIDCAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := NewOIDCAttestorPlugin()
+	attestor.hooks.now = func() time.Time {
+		return s.now
+	}
+	attestor.hooks.newKeySetProvider = func(issuer string) jwtutil.KeySetProvider {
+		s.issuer = issuer
+		return jwtutil.KeySetProviderFunc(func(ctx context.Context) (*jose.JSONWebKeySet, error) {
+			return s.jwks, nil
+		})
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *OIDCAttestorSuite) configureAttestor() {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `
+		repository_owner_whitelist = ["octo-org"]
+		`,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+	s.Require().Equal(github.DefaultOIDCIssuer, s.issuer)
+}
+
+func (s *OIDCAttestorSuite) doAttest(req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	return s.doAttestOnAttestor(s.attestor, req)
+}
+
+func (s *OIDCAttestorSuite) doAttestOnAttestor(attestor *nodeattestor.BuiltIn, req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	stream, err := attestor.Attest(context.Background())
+	s.Require().NoError(err)
+
+	err = stream.Send(req)
+	s.Require().NoError(err)
+
+	err = stream.CloseSend()
+	s.Require().NoError(err)
+
+	return stream.Recv()
+}
+
+func (s *OIDCAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	resp, err := s.doAttest(req)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *OIDCAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func makeAttestRequest(token string) *nodeattestor.AttestRequest {
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "github_oidc",
+			Data: []byte(fmt.Sprintf(`{"token": %q}`, token)),
+		},
+	}
+}
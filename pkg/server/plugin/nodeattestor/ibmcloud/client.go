@@ -0,0 +1,198 @@
+package ibmcloud
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/plugin/ibmcloud"
+	"github.com/zeebo/errs"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	defaultIAMURL = "https://iam.cloud.ibm.com"
+
+	vpcAPIVersion = "2022-03-01"
+
+	// keySetRefreshInterval limits how often the IAM signing keys are
+	// refetched when a token signed by an unknown key is presented
+	keySetRefreshInterval = time.Minute
+
+	// accessTokenRefreshMargin is how long before expiry the API access
+	// token is renewed
+	accessTokenRefreshMargin = time.Minute
+)
+
+// Instance holds the instance details returned by the VPC API that are
+// relevant to node attestation
+type Instance struct {
+	ID      string `json:"id"`
+	CRN     string `json:"crn"`
+	Status  string `json:"status"`
+	Profile struct {
+		Name string `json:"name"`
+	} `json:"profile"`
+	VPC struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"vpc"`
+	Zone struct {
+		Name string `json:"name"`
+	} `json:"zone"`
+	ResourceGroup struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"resource_group"`
+}
+
+// apiClient is an interface representing all of the API methods the
+// attestor needs to do its job.
+type apiClient interface {
+	// GetSigningKey returns the IAM public key with the given key ID
+	GetSigningKey(ctx context.Context, keyID string) (*jose.JSONWebKey, error)
+
+	GetInstance(ctx context.Context, region, instanceID string) (*Instance, error)
+}
+
+// ibmClient implements apiClient using the IAM and VPC REST APIs. The API
+// key is exchanged for an IAM access token to call the VPC API.
+type ibmClient struct {
+	httpClient ibmcloud.HTTPClient
+	iamURL     string
+	apiKey     string
+	now        func() time.Time
+
+	// vpcEndpoint returns the VPC API endpoint for the region
+	vpcEndpoint func(region string) string
+
+	mu                sync.Mutex
+	keySet            *jose.JSONWebKeySet
+	keySetFetchedAt   time.Time
+	accessToken       string
+	accessTokenExpiry time.Time
+}
+
+func newIBMClient(iamURL, apiKey string) apiClient {
+	return &ibmClient{
+		httpClient: http.DefaultClient,
+		iamURL:     iamURL,
+		apiKey:     apiKey,
+		now:        time.Now,
+		vpcEndpoint: func(region string) string {
+			return "https://" + region + ".iaas.cloud.ibm.com"
+		},
+	}
+}
+
+func (c *ibmClient) GetSigningKey(ctx context.Context, keyID string) (*jose.JSONWebKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keySet != nil {
+		if keys := c.keySet.Key(keyID); len(keys) > 0 {
+			return &keys[0], nil
+		}
+		if c.now().Sub(c.keySetFetchedAt) < keySetRefreshInterval {
+			return nil, errs.New("key id %q not found", keyID)
+		}
+	}
+
+	keySet := new(jose.JSONWebKeySet)
+	if err := c.do(ctx, "GET", c.iamURL+"/identity/keys", "", nil, keySet); err != nil {
+		return nil, errs.New("unable to fetch IAM keys: %v", err)
+	}
+	c.keySet = keySet
+	c.keySetFetchedAt = c.now()
+
+	if keys := c.keySet.Key(keyID); len(keys) > 0 {
+		return &keys[0], nil
+	}
+	return nil, errs.New("key id %q not found", keyID)
+}
+
+func (c *ibmClient) GetInstance(ctx context.Context, region, instanceID string) (*Instance, error) {
+	accessToken, err := c.getAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := new(Instance)
+	instanceURL := c.vpcEndpoint(region) + "/v1/instances/" + url.PathEscape(instanceID) + "?version=" + vpcAPIVersion + "&generation=2"
+	if err := c.do(ctx, "GET", instanceURL, accessToken, nil, instance); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+func (c *ibmClient) getAccessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.accessToken != "" && c.now().Before(c.accessTokenExpiry.Add(-accessTokenRefreshMargin)) {
+		return c.accessToken, nil
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ibm:params:oauth:grant-type:apikey"},
+		"apikey":     {c.apiKey},
+	}
+	r := struct {
+		AccessToken string `json:"access_token"`
+		Expiration  int64  `json:"expiration"`
+	}{}
+	if err := c.do(ctx, "POST", c.iamURL+"/identity/token", "", form, &r); err != nil {
+		return "", errs.New("unable to obtain API access token: %v", err)
+	}
+	if r.AccessToken == "" {
+		return "", errs.New("unable to obtain API access token: response missing access token")
+	}
+
+	c.accessToken = r.AccessToken
+	c.accessTokenExpiry = time.Unix(r.Expiration, 0)
+	return c.accessToken, nil
+}
+
+func (c *ibmClient) do(ctx context.Context, method, reqURL, accessToken string, form url.Values, out interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, reqURL, body)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errs.New("unable to decode response: %v", err)
+	}
+	return nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
@@ -0,0 +1,277 @@
+package ibmcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/ibmcloud"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	apiKeyEnv = "IBMCLOUD_API_KEY"
+
+	instanceStatusRunning = "running"
+
+	// Leeway given to account for clock differences between IAM and the
+	// server
+	tokenLeeway = time.Minute
+)
+
+var (
+	vpcError = errs.Class("ibmcloud-vpc")
+)
+
+type VPCAttestorConfig struct {
+	AccountWhitelist []string `hcl:"account_whitelist"`
+
+	// APIKey is an IBM Cloud API key with read access to VPC instances. If
+	// unset, the key is read from the IBMCLOUD_API_KEY environment
+	// variable.
+	APIKey string `hcl:"api_key"`
+
+	VPCWhitelist           []string `hcl:"vpc_whitelist"`
+	ResourceGroupWhitelist []string `hcl:"resource_group_whitelist"`
+}
+
+type vpcAttestorConfig struct {
+	trustDomain    string
+	client         apiClient
+	accounts       map[string]bool
+	vpcs           map[string]bool
+	resourceGroups map[string]bool
+}
+
+type VPCAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *vpcAttestorConfig
+
+	hooks struct {
+		now       func() time.Time
+		getenv    func(string) string
+		newClient func(iamURL, apiKey string) apiClient
+	}
+}
+
+var _ nodeattestor.Plugin = (*VPCAttestorPlugin)(nil)
+
+func New() *VPCAttestorPlugin {
+	p := &VPCAttestorPlugin{}
+	p.hooks.now = time.Now
+	p.hooks.getenv = os.Getenv
+	p.hooks.newClient = newIBMClient
+	return p
+}
+
+func (p *VPCAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return vpcError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestedBefore {
+		return vpcError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return vpcError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != ibmcloud.PluginName {
+		return vpcError.New("unexpected attestation data type %q", dataType)
+	}
+
+	attestationData := new(ibmcloud.AttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return vpcError.New("unable to unmarshal attestation data: %v", err)
+	}
+
+	if attestationData.Token == "" {
+		return vpcError.New("missing token from attestation data")
+	}
+
+	token, err := jwt.ParseSigned(attestationData.Token)
+	if err != nil {
+		return vpcError.New("unable to parse token: %v", err)
+	}
+
+	keyID, ok := getTokenKeyID(token)
+	if !ok {
+		return vpcError.New("token missing key id")
+	}
+
+	key, err := config.client.GetSigningKey(stream.Context(), keyID)
+	if err != nil {
+		return vpcError.New("unable to get token signing key: %v", err)
+	}
+
+	claims := new(ibmcloud.IAMClaims)
+	if err := token.Claims(key, claims); err != nil {
+		return vpcError.New("unable to verify token: %v", err)
+	}
+
+	if err := claims.ValidateWithLeeway(jwt.Expected{
+		Issuer: defaultIAMURL + "/identity",
+		Time:   p.hooks.now(),
+	}, tokenLeeway); err != nil {
+		return vpcError.New("unable to validate token claims: %v", err)
+	}
+
+	if claims.Authn.SubType != ibmcloud.SubTypeComputeResource {
+		return vpcError.New("token was not issued to a compute resource (subject type %q)", claims.Authn.SubType)
+	}
+
+	crn, err := ibmcloud.ParseInstanceCRN(claims.Authn.Sub)
+	if err != nil {
+		return vpcError.Wrap(err)
+	}
+
+	if crn.AccountID != claims.Account.BSS {
+		return vpcError.New("instance account %q does not match token account %q", crn.AccountID, claims.Account.BSS)
+	}
+
+	if !config.accounts[crn.AccountID] {
+		return vpcError.New("account %q is not whitelisted", crn.AccountID)
+	}
+
+	instance, err := config.client.GetInstance(stream.Context(), crn.Region(), crn.InstanceID)
+	if err != nil {
+		return vpcError.New("unable to look up instance: %v", err)
+	}
+
+	if instance.CRN != crn.CRN {
+		return vpcError.New("instance CRN mismatch: expected %q; got %q", crn.CRN, instance.CRN)
+	}
+
+	if instance.Status != instanceStatusRunning {
+		return vpcError.New("instance %q is not running (status %q)", instance.ID, instance.Status)
+	}
+
+	if len(config.vpcs) > 0 && !config.vpcs[instance.VPC.ID] {
+		return vpcError.New("VPC %q is not whitelisted", instance.VPC.ID)
+	}
+
+	if len(config.resourceGroups) > 0 && !config.resourceGroups[instance.ResourceGroup.ID] {
+		return vpcError.New("resource group %q is not whitelisted", instance.ResourceGroup.ID)
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: ibmcloud.AgentID(config.trustDomain, crn),
+		Selectors:    buildSelectors(crn, instance),
+	})
+}
+
+func (p *VPCAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(VPCAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, vpcError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, vpcError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, vpcError.New("global configuration missing trust domain")
+	}
+
+	if len(hclConfig.AccountWhitelist) == 0 {
+		return nil, vpcError.New("account_whitelist is required")
+	}
+
+	apiKey := hclConfig.APIKey
+	if apiKey == "" {
+		apiKey = p.hooks.getenv(apiKeyEnv)
+	}
+	if apiKey == "" {
+		return nil, vpcError.New("api_key or the %s environment variable is required", apiKeyEnv)
+	}
+
+	config := &vpcAttestorConfig{
+		trustDomain:    req.GlobalConfig.TrustDomain,
+		client:         p.hooks.newClient(defaultIAMURL, apiKey),
+		accounts:       make(map[string]bool),
+		vpcs:           make(map[string]bool),
+		resourceGroups: make(map[string]bool),
+	}
+	for _, account := range hclConfig.AccountWhitelist {
+		config.accounts[account] = true
+	}
+	for _, vpc := range hclConfig.VPCWhitelist {
+		config.vpcs[vpc] = true
+	}
+	for _, resourceGroup := range hclConfig.ResourceGroupWhitelist {
+		config.resourceGroups[resourceGroup] = true
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *VPCAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *VPCAttestorPlugin) getConfig() (*vpcAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, vpcError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *VPCAttestorPlugin) setConfig(config *vpcAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func buildSelectors(crn *ibmcloud.InstanceCRN, instance *Instance) []*common.Selector {
+	selectors := []*common.Selector{
+		makeSelector("account", crn.AccountID),
+		makeSelector("region", crn.Region()),
+		makeSelector("zone", instance.Zone.Name),
+		makeSelector("vpc", instance.VPC.ID),
+		makeSelector("resource_group", instance.ResourceGroup.ID),
+	}
+	if instance.VPC.Name != "" {
+		selectors = append(selectors, makeSelector("vpc_name", instance.VPC.Name))
+	}
+	if instance.ResourceGroup.Name != "" {
+		selectors = append(selectors, makeSelector("resource_group_name", instance.ResourceGroup.Name))
+	}
+	if instance.Profile.Name != "" {
+		selectors = append(selectors, makeSelector("profile", instance.Profile.Name))
+	}
+	return selectors
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  ibmcloud.PluginName,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
+
+func getTokenKeyID(token *jwt.JSONWebToken) (string, bool) {
+	for _, h := range token.Headers {
+		if h.KeyID != "" {
+			return h.KeyID, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,512 @@
+package ibmcloud
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/plugin/ibmcloud"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	testCRN = "crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::instance:0717_INSTANCE"
+)
+
+func TestVPCAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(VPCAttestorSuite))
+}
+
+type VPCAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+	key      *ecdsa.PrivateKey
+	now      time.Time
+	env      map[string]string
+	apiKey   string
+	instance *Instance
+	apiErr   error
+}
+
+func (s *VPCAttestorSuite) SetupTest() {
+	var err error
+	s.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+
+	// JWT numeric dates have second granularity
+	s.now = time.Now().Truncate(time.Second)
+	s.env = map[string]string{}
+	s.apiKey = ""
+	s.apiErr = nil
+	s.instance = &Instance{
+		ID:     "0717_INSTANCE",
+		CRN:    testCRN,
+		Status: "running",
+	}
+	s.instance.Profile.Name = "bx2-2x8"
+	s.instance.VPC.ID = "r006-VPC"
+	s.instance.VPC.Name = "prod-vpc"
+	s.instance.Zone.Name = "us-south-1"
+	s.instance.ResourceGroup.ID = "RESOURCEGROUP"
+	s.instance.ResourceGroup.Name = "prod"
+
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	resp, err := s.doAttestOnAttestor(s.newAttestor(), &nodeattestor.AttestRequest{})
+	s.Require().EqualError(err, "ibmcloud-vpc: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsWhenAttestedBefore() {
+	s.requireAttestError(&nodeattestor.AttestRequest{AttestedBefore: true},
+		"ibmcloud-vpc: node has already attested")
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsWithNoAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"ibmcloud-vpc: missing attestation data")
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsWithWrongAttestationDataType() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "blah",
+		},
+	}, `ibmcloud-vpc: unexpected attestation data type "blah"`)
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsWithMalformedAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "ibmcloud_vpc",
+			Data: []byte("{"),
+		},
+	}, "ibmcloud-vpc: unable to unmarshal attestation data")
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsWithNoToken() {
+	s.requireAttestError(makeAttestRequest(""),
+		"ibmcloud-vpc: missing token from attestation data")
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsWithMalformedToken() {
+	s.requireAttestError(makeAttestRequest("blah"),
+		"ibmcloud-vpc: unable to parse token")
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsIfTokenKeyIDMissing() {
+	s.requireAttestError(s.signAttestRequest("", s.validClaims()),
+		"ibmcloud-vpc: token missing key id")
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsIfTokenKeyIDNotFound() {
+	s.requireAttestError(s.signAttestRequest("OTHER", s.validClaims()),
+		`ibmcloud-vpc: unable to get token signing key: key id "OTHER" not found`)
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsWithBadSignature() {
+	// sign a token and replace the signature
+	token := s.signToken("KEYID", s.validClaims())
+	parts := strings.Split(token, ".")
+	s.Require().Len(parts, 3)
+	parts[2] = "aaaa"
+	token = strings.Join(parts, ".")
+
+	s.requireAttestError(makeAttestRequest(token),
+		"ibmcloud-vpc: unable to verify token")
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsClaimValidation() {
+	// wrong issuer
+	claims := s.validClaims()
+	claims.Issuer = "https://iam.example.org/identity"
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"invalid issuer claim")
+
+	// not a compute resource
+	claims = s.validClaims()
+	claims.Authn.SubType = "ServiceId"
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		`ibmcloud-vpc: token was not issued to a compute resource (subject type "ServiceId")`)
+
+	// not an instance
+	claims = s.validClaims()
+	claims.Authn.Sub = "crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::vpc:VPC"
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"is not a VPC instance")
+
+	// account mismatch
+	claims = s.validClaims()
+	claims.Account.BSS = "OTHER"
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		`ibmcloud-vpc: instance account "ACCOUNT" does not match token account "OTHER"`)
+}
+
+func (s *VPCAttestorSuite) TestAttestTokenExpiration() {
+	req := s.signAttestRequest("KEYID", s.validClaims())
+
+	// within the 1m leeway (token expires at 5m + 1m leeway = 6m)
+	s.adjustTime(6 * time.Minute)
+	_, err := s.doAttest(req)
+	s.Require().NoError(err)
+
+	// just after the 1m leeway
+	s.adjustTime(time.Second)
+	s.requireAttestError(req, "token is expired")
+}
+
+func (s *VPCAttestorSuite) TestAttestAccountWhitelist() {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `
+		account_whitelist = ["OTHER"]
+		api_key = "APIKEY"
+		`,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&plugin.ConfigureResponse{}, resp)
+
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		`ibmcloud-vpc: account "ACCOUNT" is not whitelisted`)
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsWhenLookupFails() {
+	s.apiErr = errors.New("oh no")
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		"ibmcloud-vpc: unable to look up instance: oh no")
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsWithCRNMismatch() {
+	s.instance.CRN = "crn:v1:bluemix:public:is:us-south-1:a/ACCOUNT::instance:OTHER"
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		"ibmcloud-vpc: instance CRN mismatch")
+}
+
+func (s *VPCAttestorSuite) TestAttestFailsWhenInstanceNotRunning() {
+	s.instance.Status = "stopped"
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		`ibmcloud-vpc: instance "0717_INSTANCE" is not running (status "stopped")`)
+}
+
+func (s *VPCAttestorSuite) TestAttestVPCWhitelist() {
+	s.configureAttestor(`vpc_whitelist = ["r006-OTHER"]`)
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		`ibmcloud-vpc: VPC "r006-VPC" is not whitelisted`)
+
+	s.configureAttestor(`vpc_whitelist = ["r006-OTHER", "r006-VPC"]`)
+	_, err := s.doAttest(s.signAttestRequest("KEYID", s.validClaims()))
+	s.Require().NoError(err)
+}
+
+func (s *VPCAttestorSuite) TestAttestResourceGroupWhitelist() {
+	s.configureAttestor(`resource_group_whitelist = ["OTHER"]`)
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		`ibmcloud-vpc: resource group "RESOURCEGROUP" is not whitelisted`)
+
+	s.configureAttestor(`resource_group_whitelist = ["RESOURCEGROUP"]`)
+	_, err := s.doAttest(s.signAttestRequest("KEYID", s.validClaims()))
+	s.Require().NoError(err)
+}
+
+func (s *VPCAttestorSuite) TestAttestSuccess() {
+	resp, err := s.doAttest(s.signAttestRequest("KEYID", s.validClaims()))
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/ibmcloud_vpc/ACCOUNT/0717_INSTANCE", resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "ibmcloud_vpc", Value: "account:ACCOUNT"},
+		{Type: "ibmcloud_vpc", Value: "region:us-south"},
+		{Type: "ibmcloud_vpc", Value: "zone:us-south-1"},
+		{Type: "ibmcloud_vpc", Value: "vpc:r006-VPC"},
+		{Type: "ibmcloud_vpc", Value: "resource_group:RESOURCEGROUP"},
+		{Type: "ibmcloud_vpc", Value: "vpc_name:prod-vpc"},
+		{Type: "ibmcloud_vpc", Value: "resource_group_name:prod"},
+		{Type: "ibmcloud_vpc", Value: "profile:bx2-2x8"},
+	}, resp.Selectors)
+}
+
+func (s *VPCAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+	})
+	s.requireErrorContains(err, "ibmcloud-vpc: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	// missing global configuration
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "ibmcloud-vpc: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "ibmcloud-vpc: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// missing account whitelist
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "ibmcloud-vpc: account_whitelist is required")
+	s.Require().Nil(resp)
+
+	// missing API key
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `account_whitelist = ["ACCOUNT"]`,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "ibmcloud-vpc: api_key or the IBMCLOUD_API_KEY environment variable is required")
+	s.Require().Nil(resp)
+
+	// API key from the environment
+	s.env["IBMCLOUD_API_KEY"] = "ENVAPIKEY"
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `account_whitelist = ["ACCOUNT"]`,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&plugin.ConfigureResponse{}, resp)
+	s.Require().Equal("ENVAPIKEY", s.apiKey)
+
+	// configured API key takes precedence
+	s.configureAttestor("")
+	s.Require().Equal("APIKEY", s.apiKey)
+}
+
+func (s *VPCAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *VPCAttestorSuite) adjustTime(d time.Duration) {
+	s.now = s.now.Add(d)
+}
+
+func (s *VPCAttestorSuite) validClaims() *ibmcloud.IAMClaims {
+	claims := &ibmcloud.IAMClaims{
+		Claims: jwt.Claims{
+			Issuer:    "https://iam.cloud.ibm.com/identity",
+			Subject:   "Profile-1234",
+			IssuedAt:  jwt.NewNumericDate(s.now),
+			Expiry:    jwt.NewNumericDate(s.now.Add(5 * time.Minute)),
+			NotBefore: jwt.NewNumericDate(s.now),
+		},
+	}
+	claims.Account.BSS = "ACCOUNT"
+	claims.Authn.Sub = testCRN
+	claims.Authn.SubType = "ComputeResource"
+	return claims
+}
+
+func (s *VPCAttestorSuite) signToken(keyID string, claims *ibmcloud.IAMClaims) string {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key: jose.JSONWebKey{
+			Key:   s.key,
+			KeyID: keyID,
+		},
+	}, nil)
+	s.Require().NoError(err)
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *VPCAttestorSuite) signAttestRequest(keyID string, claims *ibmcloud.IAMClaims) *nodeattestor.AttestRequest {
+	return makeAttestRequest(s.signToken(keyID, claims))
+}
+
+func (s *VPCAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.now = func() time.Time {
+		return s.now
+	}
+	attestor.hooks.getenv = func(key string) string {
+		return s.env[key]
+	}
+	attestor.hooks.newClient = func(iamURL, apiKey string) apiClient {
+		s.Require().Equal("https://iam.cloud.ibm.com", iamURL)
+		s.apiKey = apiKey
+		return &fakeAPIClient{s: s}
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *VPCAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "account_whitelist = [\"ACCOUNT\"]\napi_key = \"APIKEY\"\n" + config,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *VPCAttestorSuite) doAttest(req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	return s.doAttestOnAttestor(s.attestor, req)
+}
+
+func (s *VPCAttestorSuite) doAttestOnAttestor(attestor *nodeattestor.BuiltIn, req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	stream, err := attestor.Attest(context.Background())
+	s.Require().NoError(err)
+
+	err = stream.Send(req)
+	s.Require().NoError(err)
+
+	err = stream.CloseSend()
+	s.Require().NoError(err)
+
+	return stream.Recv()
+}
+
+func (s *VPCAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	resp, err := s.doAttest(req)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *VPCAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func TestIBMClient(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	keyFetches := 0
+	tokenFetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/identity/keys":
+			keyFetches++
+			json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+				Keys: []jose.JSONWebKey{{Key: key.Public(), KeyID: "KEYID", Algorithm: "ES256"}},
+			})
+		case "/identity/token":
+			if req.PostFormValue("grant_type") != "urn:ibm:params:oauth:grant-type:apikey" || req.PostFormValue("apikey") != "APIKEY" {
+				http.Error(w, "bad api key", http.StatusBadRequest)
+				return
+			}
+			tokenFetches++
+			fmt.Fprintf(w, `{"access_token": "ACCESSTOKEN", "expiration": %d}`, now.Add(time.Hour).Unix())
+		case "/v1/instances/0717_INSTANCE":
+			if req.Header.Get("Authorization") != "Bearer ACCESSTOKEN" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if req.URL.Query().Get("version") != "2022-03-01" || req.URL.Query().Get("generation") != "2" {
+				http.Error(w, "bad version", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"id": "0717_INSTANCE", "crn": %q, "status": "running", "vpc": {"id": "r006-VPC"}, "zone": {"name": "us-south-1"}}`, testCRN)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	newClient := func(apiKey string) *ibmClient {
+		client := newIBMClient(server.URL, apiKey).(*ibmClient)
+		client.now = func() time.Time { return now }
+		client.vpcEndpoint = func(region string) string {
+			require.Equal(t, "us-south", region)
+			return server.URL
+		}
+		return client
+	}
+
+	ctx := context.Background()
+	client := newClient("APIKEY")
+
+	// keys are fetched once and cached
+	jwk, err := client.GetSigningKey(ctx, "KEYID")
+	require.NoError(t, err)
+	require.Equal(t, "KEYID", jwk.KeyID)
+	_, err = client.GetSigningKey(ctx, "KEYID")
+	require.NoError(t, err)
+	require.Equal(t, 1, keyFetches)
+
+	// unknown keys only trigger a refetch after the refresh interval
+	_, err = client.GetSigningKey(ctx, "OTHER")
+	require.EqualError(t, err, `key id "OTHER" not found`)
+	require.Equal(t, 1, keyFetches)
+	now = now.Add(time.Minute)
+	_, err = client.GetSigningKey(ctx, "OTHER")
+	require.EqualError(t, err, `key id "OTHER" not found`)
+	require.Equal(t, 2, keyFetches)
+
+	// access token is fetched once and reused until close to expiry
+	instance, err := client.GetInstance(ctx, "us-south", "0717_INSTANCE")
+	require.NoError(t, err)
+	require.Equal(t, "0717_INSTANCE", instance.ID)
+	require.Equal(t, testCRN, instance.CRN)
+	require.Equal(t, "running", instance.Status)
+	require.Equal(t, "r006-VPC", instance.VPC.ID)
+	require.Equal(t, "us-south-1", instance.Zone.Name)
+	_, err = client.GetInstance(ctx, "us-south", "0717_INSTANCE")
+	require.NoError(t, err)
+	require.Equal(t, 1, tokenFetches)
+	now = now.Add(59 * time.Minute)
+	_, err = client.GetInstance(ctx, "us-south", "0717_INSTANCE")
+	require.NoError(t, err)
+	require.Equal(t, 2, tokenFetches)
+
+	_, err = client.GetInstance(ctx, "us-south", "MISSING")
+	require.EqualError(t, err, "unexpected status code 404: 404 page not found\n")
+
+	_, err = newClient("BAD").GetInstance(ctx, "us-south", "0717_INSTANCE")
+	require.EqualError(t, err, "unable to obtain API access token: unexpected status code 400: bad api key\n")
+}
+
+type fakeAPIClient struct {
+	s *VPCAttestorSuite
+}
+
+func (c *fakeAPIClient) GetSigningKey(ctx context.Context, keyID string) (*jose.JSONWebKey, error) {
+	if keyID != "KEYID" {
+		return nil, fmt.Errorf("key id %q not found", keyID)
+	}
+	return &jose.JSONWebKey{Key: c.s.key.Public(), KeyID: keyID}, nil
+}
+
+func (c *fakeAPIClient) GetInstance(ctx context.Context, region, instanceID string) (*Instance, error) {
+	c.s.Require().Equal("us-south", region)
+	c.s.Require().Equal("0717_INSTANCE", instanceID)
+	if c.s.apiErr != nil {
+		return nil, c.s.apiErr
+	}
+	return c.s.instance, nil
+}
+
+func makeAttestRequest(token string) *nodeattestor.AttestRequest {
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "ibmcloud_vpc",
+			Data: []byte(fmt.Sprintf(`{"token": %q}`, token)),
+		},
+	}
+}
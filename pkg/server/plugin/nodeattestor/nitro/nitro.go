@@ -0,0 +1,228 @@
+package nitro
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/nitro"
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+)
+
+var (
+	nitroError = errs.Class("aws-nitro")
+)
+
+type NitroAttestorConfig struct {
+	// CABundlePath is the path to the Nitro Enclaves root certificate(s)
+	CABundlePath string `hcl:"ca_bundle_path"`
+
+	// PCRWhitelist maps a PCR index to the measurements allowed for it.
+	// Enclaves with a measurement not in the list are rejected.
+	PCRWhitelist map[string][]string `hcl:"pcr_whitelist"`
+}
+
+type nitroAttestorConfig struct {
+	trustDomain  string
+	roots        *x509.CertPool
+	pcrWhitelist map[int][][]byte
+}
+
+type NitroAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *nitroAttestorConfig
+
+	hooks struct {
+		now               func() time.Time
+		generateChallenge func() (*nitro.Challenge, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*NitroAttestorPlugin)(nil)
+
+func New() *NitroAttestorPlugin {
+	p := &NitroAttestorPlugin{}
+	p.hooks.now = time.Now
+	p.hooks.generateChallenge = nitro.GenerateChallenge
+	return p
+}
+
+func (p *NitroAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return nitroError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestedBefore {
+		return nitroError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return nitroError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != nitro.PluginName {
+		return nitroError.New("unexpected attestation data type %q", dataType)
+	}
+
+	attestationData := new(nitro.AttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return nitroError.New("failed to unmarshal data payload: %v", err)
+	}
+
+	if attestationData.ModuleID == "" {
+		return nitroError.New("missing module ID from attestation data")
+	}
+
+	// challenge the agent to produce a fresh attestation document
+	challenge, err := p.hooks.generateChallenge()
+	if err != nil {
+		return nitroError.New("unable to generate challenge: %v", err)
+	}
+
+	challengeBytes, err := json.Marshal(challenge)
+	if err != nil {
+		return nitroError.New("unable to marshal challenge: %v", err)
+	}
+
+	if err := stream.Send(&nodeattestor.AttestResponse{
+		Challenge: challengeBytes,
+	}); err != nil {
+		return err
+	}
+
+	responseReq, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	response := new(nitro.Response)
+	if err := json.Unmarshal(responseReq.Response, response); err != nil {
+		return nitroError.New("unable to unmarshal challenge response: %v", err)
+	}
+
+	doc, err := nitro.VerifyAttestationDocument(response.Document, config.roots, p.hooks.now())
+	if err != nil {
+		return nitroError.New("attestation document verification failed: %v", err)
+	}
+
+	if !bytes.Equal(doc.Nonce, challenge.Nonce) {
+		return nitroError.New("attestation document nonce does not match challenge")
+	}
+
+	if doc.ModuleID != attestationData.ModuleID {
+		return nitroError.New("attestation document module ID %q does not match %q", doc.ModuleID, attestationData.ModuleID)
+	}
+
+	for index, allowed := range config.pcrWhitelist {
+		if !containsPCR(allowed, doc.PCRs[index]) {
+			return nitroError.New("PCR%d measurement %x is not whitelisted", index, doc.PCRs[index])
+		}
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: nitro.AgentID(config.trustDomain, doc.ModuleID),
+		Selectors:    buildSelectors(doc),
+	})
+}
+
+func (p *NitroAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(NitroAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, nitroError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, nitroError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, nitroError.New("global configuration missing trust domain")
+	}
+
+	if hclConfig.CABundlePath == "" {
+		return nil, nitroError.New("ca_bundle_path is required")
+	}
+	roots, err := util.LoadCertPool(hclConfig.CABundlePath)
+	if err != nil {
+		return nil, nitroError.New("unable to load trust bundle: %v", err)
+	}
+
+	pcrWhitelist := make(map[int][][]byte)
+	for indexStr, measurements := range hclConfig.PCRWhitelist {
+		index, err := strconv.Atoi(indexStr)
+		if err != nil || index < 0 {
+			return nil, nitroError.New("invalid PCR index %q", indexStr)
+		}
+		if len(measurements) == 0 {
+			return nil, nitroError.New("PCR%d whitelist must have at least one measurement", index)
+		}
+		for _, measurement := range measurements {
+			pcr, err := hex.DecodeString(measurement)
+			if err != nil {
+				return nil, nitroError.New("invalid PCR%d measurement %q: %v", index, measurement, err)
+			}
+			pcrWhitelist[index] = append(pcrWhitelist[index], pcr)
+		}
+	}
+
+	p.setConfig(&nitroAttestorConfig{
+		trustDomain:  req.GlobalConfig.TrustDomain,
+		roots:        roots,
+		pcrWhitelist: pcrWhitelist,
+	})
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *NitroAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *NitroAttestorPlugin) getConfig() (*nitroAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, nitroError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *NitroAttestorPlugin) setConfig(config *nitroAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func buildSelectors(doc *nitro.AttestationDocument) []*common.Selector {
+	var selectors []*common.Selector
+	for _, value := range doc.SelectorValues() {
+		selectors = append(selectors, &common.Selector{
+			Type:  nitro.PluginName,
+			Value: value,
+		})
+	}
+	return selectors
+}
+
+func containsPCR(allowed [][]byte, pcr []byte) bool {
+	for _, measurement := range allowed {
+		if bytes.Equal(measurement, pcr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package nitro
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/nitro"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/spiffe/spire/test/fakes/fakenitro"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	pcr0 = bytes.Repeat([]byte{0x01}, 48)
+	pcr1 = bytes.Repeat([]byte{0x02}, 48)
+	pcr2 = bytes.Repeat([]byte{0x03}, 48)
+	zero = make([]byte, 48)
+)
+
+func TestNitroAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(NitroAttestorSuite))
+}
+
+type NitroAttestorSuite struct {
+	suite.Suite
+
+	dir        string
+	hypervisor *fakenitro.Hypervisor
+	attestor   *nodeattestor.BuiltIn
+	now        time.Time
+	nonce      []byte
+}
+
+func (s *NitroAttestorSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "nitro-test")
+	s.Require().NoError(err)
+
+	s.hypervisor = fakenitro.New(s.T())
+	s.writeFile("root.pem", pemutil.EncodeCertificate(s.hypervisor.Root))
+
+	s.now = time.Now()
+	s.nonce = []byte("NONCE")
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *NitroAttestorSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *NitroAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	stream, err := s.newAttestor().Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{}))
+	_, err = stream.Recv()
+	s.Require().EqualError(err, "aws-nitro: not configured")
+}
+
+func (s *NitroAttestorSuite) TestAttestFailsWithBadAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{AttestedBefore: true},
+		"aws-nitro: node has already attested")
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"aws-nitro: missing attestation data")
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{Type: "blah"},
+	}, `aws-nitro: unexpected attestation data type "blah"`)
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{Type: "aws_nitro", Data: []byte("{")},
+	}, "aws-nitro: failed to unmarshal data payload")
+	s.requireAttestError(makeAttestRequest(""),
+		"aws-nitro: missing module ID from attestation data")
+}
+
+func (s *NitroAttestorSuite) TestAttestFailsWithBadResponse() {
+	// malformed challenge response
+	s.requireChallengeResponseError([]byte("{"),
+		"aws-nitro: unable to unmarshal challenge response")
+
+	// malformed document
+	s.requireChallengeResponseError(makeResponse([]byte("blah")),
+		"aws-nitro: attestation document verification failed: unable to decode COSE_Sign1")
+
+	// untrusted signer
+	untrusted := fakenitro.New(s.T())
+	s.requireChallengeResponseError(makeResponse(untrusted.Attest(s.document())),
+		"aws-nitro: attestation document verification failed: certificate verification failed")
+
+	// bad signature
+	doc := s.hypervisor.Attest(s.document())
+	doc[len(doc)-1] ^= 0xff
+	s.requireChallengeResponseError(makeResponse(doc),
+		"aws-nitro: attestation document verification failed: signature verification failed")
+
+	// expired signing certificate
+	s.now = s.now.Add(2 * time.Hour)
+	s.requireChallengeResponseError(makeResponse(s.hypervisor.Attest(s.document())),
+		"aws-nitro: attestation document verification failed: certificate verification failed")
+	s.now = time.Now()
+
+	// nonce mismatch
+	document := s.document()
+	document.Nonce = []byte("OTHER")
+	s.requireChallengeResponseError(makeResponse(s.hypervisor.Attest(document)),
+		"aws-nitro: attestation document nonce does not match challenge")
+
+	// module ID mismatch
+	document = s.document()
+	document.ModuleID = "i-0000-enc0000"
+	s.requireChallengeResponseError(makeResponse(s.hypervisor.Attest(document)),
+		`aws-nitro: attestation document module ID "i-0000-enc0000" does not match "i-1234-enc5678"`)
+}
+
+func (s *NitroAttestorSuite) TestAttestFailsWithPCRNotWhitelisted() {
+	s.configureAttestor(fmt.Sprintf(`
+	pcr_whitelist = {
+		"0" = ["%x"]
+		"2" = ["%x"]
+	}`, pcr0, pcr0))
+
+	s.requireChallengeResponseError(makeResponse(s.hypervisor.Attest(s.document())),
+		fmt.Sprintf("aws-nitro: PCR2 measurement %x is not whitelisted", pcr2))
+}
+
+func (s *NitroAttestorSuite) TestAttestSuccess() {
+	s.configureAttestor(fmt.Sprintf(`
+	pcr_whitelist = {
+		"0" = ["%x", "%x"]
+	}`, pcr1, pcr0))
+
+	resp, err := s.doAttest(makeResponse(s.hypervisor.Attest(s.document())))
+	s.Require().NoError(err)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/aws_nitro/i-1234-enc5678", resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "aws_nitro", Value: fmt.Sprintf("pcr0:%x", pcr0)},
+		{Type: "aws_nitro", Value: fmt.Sprintf("pcr1:%x", pcr1)},
+		{Type: "aws_nitro", Value: fmt.Sprintf("pcr2:%x", pcr2)},
+	}, resp.Selectors)
+}
+
+func (s *NitroAttestorSuite) TestConfigure() {
+	configure := func(config string, globalConfig *plugin.ConfigureRequest_GlobalConfig) error {
+		resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+			Configuration: config,
+			GlobalConfig:  globalConfig,
+		})
+		if err != nil {
+			s.Require().Nil(resp)
+		}
+		return err
+	}
+	globalConfig := &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"}
+	caBundlePath := fmt.Sprintf("ca_bundle_path = %q\n", filepath.Join(s.dir, "root.pem"))
+
+	err := configure("blah", globalConfig)
+	s.requireErrorContains(err, "aws-nitro: unable to decode configuration")
+
+	err = configure(caBundlePath, nil)
+	s.Require().EqualError(err, "aws-nitro: global configuration is required")
+
+	err = configure(caBundlePath, &plugin.ConfigureRequest_GlobalConfig{})
+	s.Require().EqualError(err, "aws-nitro: global configuration missing trust domain")
+
+	err = configure("", globalConfig)
+	s.Require().EqualError(err, "aws-nitro: ca_bundle_path is required")
+
+	err = configure(`ca_bundle_path = "blah"`, globalConfig)
+	s.requireErrorContains(err, "aws-nitro: unable to load trust bundle")
+
+	err = configure(caBundlePath+`pcr_whitelist = { "x" = ["00"] }`, globalConfig)
+	s.Require().EqualError(err, `aws-nitro: invalid PCR index "x"`)
+
+	err = configure(caBundlePath+`pcr_whitelist = { "0" = [] }`, globalConfig)
+	s.Require().EqualError(err, "aws-nitro: PCR0 whitelist must have at least one measurement")
+
+	err = configure(caBundlePath+`pcr_whitelist = { "0" = ["zz"] }`, globalConfig)
+	s.requireErrorContains(err, `aws-nitro: invalid PCR0 measurement "zz"`)
+
+	err = configure(caBundlePath, globalConfig)
+	s.Require().NoError(err)
+}
+
+func (s *NitroAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *NitroAttestorSuite) document() fakenitro.Document {
+	return fakenitro.Document{
+		ModuleID:  "i-1234-enc5678",
+		Timestamp: s.now,
+		PCRs: map[int][]byte{
+			0: pcr0,
+			1: pcr1,
+			2: pcr2,
+			3: zero,
+			4: zero,
+		},
+		Nonce: s.nonce,
+	}
+}
+
+func (s *NitroAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.now = func() time.Time {
+		return s.now
+	}
+	attestor.hooks.generateChallenge = func() (*nitro.Challenge, error) {
+		return &nitro.Challenge{Nonce: s.nonce}, nil
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *NitroAttestorSuite) configureAttestor(extra string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("ca_bundle_path = %q\n%s", filepath.Join(s.dir, "root.pem"), extra),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+// doAttest performs the challenge/response exchange, answering the
+// challenge with the given response
+func (s *NitroAttestorSuite) doAttest(response []byte) (*nodeattestor.AttestResponse, error) {
+	stream, err := s.attestor.Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+
+	s.Require().NoError(stream.Send(makeAttestRequest("i-1234-enc5678")))
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	challenge := new(nitro.Challenge)
+	s.Require().NoError(json.Unmarshal(resp.Challenge, challenge))
+	s.Require().Equal(s.nonce, challenge.Nonce)
+
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{
+		Response: response,
+	}))
+	return stream.Recv()
+}
+
+func (s *NitroAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	stream, err := s.attestor.Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+
+	s.Require().NoError(stream.Send(req))
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *NitroAttestorSuite) requireChallengeResponseError(response []byte, contains string) {
+	resp, err := s.doAttest(response)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *NitroAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func (s *NitroAttestorSuite) writeFile(name string, data []byte) {
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, name), data, 0644))
+}
+
+func makeAttestRequest(moduleID string) *nodeattestor.AttestRequest {
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "aws_nitro",
+			Data: []byte(fmt.Sprintf(`{"module_id": %q}`, moduleID)),
+		},
+	}
+}
+
+func makeResponse(document []byte) []byte {
+	data, _ := json.Marshal(nitro.Response{Document: document})
+	return data
+}
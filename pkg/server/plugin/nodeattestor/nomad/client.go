@@ -0,0 +1,126 @@
+package nomad
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/zeebo/errs"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// Allocation holds the allocation details returned by the Nomad API that
+// are relevant to node attestation
+type Allocation struct {
+	ID           string `json:"ID"`
+	NodeID       string `json:"NodeID"`
+	Namespace    string `json:"Namespace"`
+	JobID        string `json:"JobID"`
+	ClientStatus string `json:"ClientStatus"`
+}
+
+// Node holds the client node details returned by the Nomad API that are
+// relevant to node attestation
+type Node struct {
+	ID         string `json:"ID"`
+	Name       string `json:"Name"`
+	Datacenter string `json:"Datacenter"`
+	NodeClass  string `json:"NodeClass"`
+	NodePool   string `json:"NodePool"`
+	Status     string `json:"Status"`
+}
+
+// apiClient is an interface representing all of the API methods the
+// attestor needs to do its job.
+type apiClient interface {
+	// GetKeySet returns the keys used to sign workload identities
+	GetKeySet(ctx context.Context) (*jose.JSONWebKeySet, error)
+
+	GetAllocation(ctx context.Context, allocationID string) (*Allocation, error)
+	GetNode(ctx context.Context, nodeID string) (*Node, error)
+}
+
+// nomadClient implements apiClient using the Nomad HTTP API
+type nomadClient struct {
+	httpClient *http.Client
+	address    string
+	token      string
+}
+
+func newNomadClient(address, token string, roots *x509.CertPool) apiClient {
+	httpClient := http.DefaultClient
+	if roots != nil {
+		httpClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					RootCAs: roots,
+				},
+			},
+		}
+	}
+	return &nomadClient{
+		httpClient: httpClient,
+		address:    address,
+		token:      token,
+	}
+}
+
+func (c *nomadClient) GetKeySet(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	keySet := new(jose.JSONWebKeySet)
+	if err := c.get(ctx, "/.well-known/jwks.json", keySet); err != nil {
+		return nil, err
+	}
+	return keySet, nil
+}
+
+func (c *nomadClient) GetAllocation(ctx context.Context, allocationID string) (*Allocation, error) {
+	allocation := new(Allocation)
+	if err := c.get(ctx, "/v1/allocation/"+url.PathEscape(allocationID), allocation); err != nil {
+		return nil, err
+	}
+	return allocation, nil
+}
+
+func (c *nomadClient) GetNode(ctx context.Context, nodeID string) (*Node, error) {
+	node := new(Node)
+	if err := c.get(ctx, "/v1/node/"+url.PathEscape(nodeID), node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (c *nomadClient) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequest("GET", c.address+path, nil)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	if c.token != "" {
+		req.Header.Set("X-Nomad-Token", c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errs.New("unable to decode response: %v", err)
+	}
+	return nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
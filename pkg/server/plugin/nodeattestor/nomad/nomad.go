@@ -0,0 +1,322 @@
+package nomad
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/jwtutil"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/nomad"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	allocationStatusRunning = "running"
+	nodeStatusReady         = "ready"
+
+	// Leeway given to account for clock differences between Nomad and the
+	// server
+	tokenLeeway = time.Minute
+
+	keySetRefreshInterval = time.Hour
+)
+
+var (
+	nomadError = errs.Class("nomad")
+)
+
+type ClusterConfig struct {
+	// Address is the address of the Nomad HTTP API
+	Address string `hcl:"address"`
+
+	// Token is a Nomad ACL token able to read allocations and nodes
+	Token string `hcl:"token"`
+
+	// CACertPath is the path to the CA certificates used to verify the
+	// Nomad API server certificate. Defaults to the system roots.
+	CACertPath string `hcl:"ca_cert_path"`
+
+	// Audience is the audience of the workload identity used by the agent
+	Audience string `hcl:"audience"`
+
+	JobWhitelist []string `hcl:"job_whitelist"`
+}
+
+type NomadAttestorConfig struct {
+	Clusters map[string]*ClusterConfig `hcl:"clusters"`
+}
+
+type clusterConfig struct {
+	audience       string
+	jobs           map[string]bool
+	client         apiClient
+	keySetProvider jwtutil.KeySetProvider
+}
+
+type nomadAttestorConfig struct {
+	trustDomain string
+	clusters    map[string]*clusterConfig
+}
+
+type NomadAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *nomadAttestorConfig
+
+	hooks struct {
+		now       func() time.Time
+		newClient func(address, token string, roots *x509.CertPool) apiClient
+	}
+}
+
+var _ nodeattestor.Plugin = (*NomadAttestorPlugin)(nil)
+
+func New() *NomadAttestorPlugin {
+	p := &NomadAttestorPlugin{}
+	p.hooks.now = time.Now
+	p.hooks.newClient = newNomadClient
+	return p
+}
+
+func (p *NomadAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return nomadError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	// Re-attestation is allowed since the allocation the token was issued
+	// to is confirmed to be running with the Nomad API.
+
+	if req.AttestationData == nil {
+		return nomadError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != nomad.PluginName {
+		return nomadError.New("unexpected attestation data type %q", dataType)
+	}
+
+	attestationData := new(nomad.AttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return nomadError.New("unable to unmarshal attestation data: %v", err)
+	}
+
+	if attestationData.Cluster == "" {
+		return nomadError.New("missing cluster in attestation data")
+	}
+
+	if attestationData.Token == "" {
+		return nomadError.New("missing token in attestation data")
+	}
+
+	cluster := config.clusters[attestationData.Cluster]
+	if cluster == nil {
+		return nomadError.New("not configured for cluster %q", attestationData.Cluster)
+	}
+
+	claims, err := p.verifyToken(stream.Context(), cluster, attestationData.Token)
+	if err != nil {
+		return err
+	}
+
+	jobName := fmt.Sprintf("%s:%s", claims.Namespace, claims.JobID)
+	if !cluster.jobs[jobName] {
+		return nomadError.New("%q is not a whitelisted job", jobName)
+	}
+
+	allocation, err := cluster.client.GetAllocation(stream.Context(), claims.AllocationID)
+	if err != nil {
+		return nomadError.New("unable to look up allocation: %v", err)
+	}
+
+	if allocation.Namespace != claims.Namespace || allocation.JobID != claims.JobID {
+		return nomadError.New("allocation %q does not belong to job %q", claims.AllocationID, jobName)
+	}
+
+	if allocation.ClientStatus != allocationStatusRunning {
+		return nomadError.New("allocation %q is not running (status %q)", claims.AllocationID, allocation.ClientStatus)
+	}
+
+	node, err := cluster.client.GetNode(stream.Context(), allocation.NodeID)
+	if err != nil {
+		return nomadError.New("unable to look up node: %v", err)
+	}
+
+	if node.Status != nodeStatusReady {
+		return nomadError.New("node %q is not ready (status %q)", node.ID, node.Status)
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: nomad.AgentID(config.trustDomain, attestationData.Cluster, claims.AllocationID),
+		Selectors:    buildSelectors(attestationData.Cluster, claims, node),
+	})
+}
+
+func (p *NomadAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(NomadAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, nomadError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, nomadError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, nomadError.New("global configuration missing trust domain")
+	}
+
+	if len(hclConfig.Clusters) == 0 {
+		return nil, nomadError.New("configuration must have at least one cluster")
+	}
+
+	config := &nomadAttestorConfig{
+		trustDomain: req.GlobalConfig.TrustDomain,
+		clusters:    make(map[string]*clusterConfig),
+	}
+	for name, cluster := range hclConfig.Clusters {
+		if cluster.Address == "" {
+			return nil, nomadError.New("cluster %q configuration missing address", name)
+		}
+		if len(cluster.JobWhitelist) == 0 {
+			return nil, nomadError.New("cluster %q configuration must have at least one job whitelisted", name)
+		}
+
+		var roots *x509.CertPool
+		if cluster.CACertPath != "" {
+			certs, err := pemutil.LoadCertificates(cluster.CACertPath)
+			if err != nil {
+				return nil, nomadError.New("failed to load cluster %q CA certificates from %q: %v", name, cluster.CACertPath, err)
+			}
+			roots = x509.NewCertPool()
+			for _, cert := range certs {
+				roots.AddCert(cert)
+			}
+		}
+
+		client := p.hooks.newClient(cluster.Address, cluster.Token, roots)
+		c := &clusterConfig{
+			audience:       cluster.Audience,
+			jobs:           make(map[string]bool),
+			client:         client,
+			keySetProvider: jwtutil.NewCachingKeySetProvider(jwtutil.KeySetProviderFunc(client.GetKeySet), keySetRefreshInterval),
+		}
+		if c.audience == "" {
+			c.audience = nomad.DefaultAudience
+		}
+		for _, job := range cluster.JobWhitelist {
+			c.jobs[job] = true
+		}
+		config.clusters[name] = c
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *NomadAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *NomadAttestorPlugin) getConfig() (*nomadAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, nomadError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *NomadAttestorPlugin) setConfig(config *nomadAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func (p *NomadAttestorPlugin) verifyToken(ctx context.Context, cluster *clusterConfig, rawToken string) (*nomad.WorkloadIdentityClaims, error) {
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return nil, nomadError.New("unable to parse token: %v", err)
+	}
+
+	keyID, ok := getTokenKeyID(token)
+	if !ok {
+		return nil, nomadError.New("token missing key id")
+	}
+
+	keySet, err := cluster.keySetProvider.GetKeySet(ctx)
+	if err != nil {
+		return nil, nomadError.New("unable to obtain JWKS: %v", err)
+	}
+
+	keys := keySet.Key(keyID)
+	if len(keys) == 0 {
+		return nil, nomadError.New("key id %q not found", keyID)
+	}
+
+	claims := new(nomad.WorkloadIdentityClaims)
+	if err := token.Claims(&keys[0], claims); err != nil {
+		return nil, nomadError.New("unable to verify token: %v", err)
+	}
+
+	if err := claims.ValidateWithLeeway(jwt.Expected{
+		Audience: []string{cluster.audience},
+		Time:     p.hooks.now(),
+	}, tokenLeeway); err != nil {
+		return nil, nomadError.New("unable to validate token claims: %v", err)
+	}
+
+	switch {
+	case claims.Namespace == "":
+		return nil, nomadError.New("token missing namespace claim")
+	case claims.JobID == "":
+		return nil, nomadError.New("token missing job ID claim")
+	case claims.AllocationID == "":
+		return nil, nomadError.New("token missing allocation ID claim")
+	}
+
+	return claims, nil
+}
+
+func buildSelectors(cluster string, claims *nomad.WorkloadIdentityClaims, node *Node) []*common.Selector {
+	selectors := []*common.Selector{
+		makeSelector("cluster", cluster),
+		makeSelector("agent_ns", claims.Namespace),
+		makeSelector("agent_job", claims.JobID),
+		makeSelector("datacenter", node.Datacenter),
+	}
+	if node.NodeClass != "" {
+		selectors = append(selectors, makeSelector("node_class", node.NodeClass))
+	}
+	if node.NodePool != "" {
+		selectors = append(selectors, makeSelector("node_pool", node.NodePool))
+	}
+	return selectors
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  nomad.PluginName,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
+
+func getTokenKeyID(token *jwt.JSONWebToken) (string, bool) {
+	for _, h := range token.Headers {
+		if h.KeyID != "" {
+			return h.KeyID, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,566 @@
+package nomad
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/nomad"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	testAllocationID = "5c3b2bbe-4a2b-e0a4-ae6c-3a5d1ee0a4a1"
+	testNodeID       = "f7476465-4d6e-c0de-26d0-e383c49be941"
+)
+
+func TestNomadAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(NomadAttestorSuite))
+}
+
+type NomadAttestorSuite struct {
+	suite.Suite
+
+	dir      string
+	attestor *nodeattestor.BuiltIn
+	key      *ecdsa.PrivateKey
+	now      time.Time
+
+	clientAddress string
+	clientToken   string
+	clientRoots   *x509.CertPool
+
+	allocation    *Allocation
+	allocationErr error
+	node          *Node
+	nodeErr       error
+}
+
+func (s *NomadAttestorSuite) SetupSuite() {
+	dir, err := ioutil.TempDir("", "nomad-attestor-")
+	s.Require().NoError(err)
+	s.dir = dir
+}
+
+func (s *NomadAttestorSuite) TearDownSuite() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *NomadAttestorSuite) SetupTest() {
+	var err error
+	s.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+
+	// JWT numeric dates have second granularity
+	s.now = time.Now().Truncate(time.Second)
+
+	s.allocation = &Allocation{
+		ID:           testAllocationID,
+		NodeID:       testNodeID,
+		Namespace:    "default",
+		JobID:        "spire-agent",
+		ClientStatus: "running",
+	}
+	s.allocationErr = nil
+	s.node = &Node{
+		ID:         testNodeID,
+		Name:       "client-1",
+		Datacenter: "dc1",
+		NodeClass:  "compute",
+		NodePool:   "prod",
+		Status:     "ready",
+	}
+	s.nodeErr = nil
+
+	s.attestor = s.newAttestor()
+	s.configureAttestor()
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	resp, err := s.doAttestOnAttestor(s.newAttestor(), &nodeattestor.AttestRequest{})
+	s.Require().EqualError(err, "nomad: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWithNoAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"nomad: missing attestation data")
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWithWrongAttestationDataType() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "blah",
+		},
+	}, `nomad: unexpected attestation data type "blah"`)
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWithMalformedAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "nomad",
+			Data: []byte("{"),
+		},
+	}, "nomad: unable to unmarshal attestation data")
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWithMissingFields() {
+	s.requireAttestError(makeAttestRequest("", "TOKEN"),
+		"nomad: missing cluster in attestation data")
+	s.requireAttestError(makeAttestRequest("FOO", ""),
+		"nomad: missing token in attestation data")
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWithUnknownCluster() {
+	s.requireAttestError(makeAttestRequest("CORGE", "TOKEN"),
+		`nomad: not configured for cluster "CORGE"`)
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWithMalformedToken() {
+	s.requireAttestError(makeAttestRequest("FOO", "blah"),
+		"nomad: unable to parse token")
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsIfTokenKeyIDMissing() {
+	s.requireAttestError(s.signAttestRequest("", s.validClaims()),
+		"nomad: token missing key id")
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsIfTokenKeyIDNotFound() {
+	s.requireAttestError(s.signAttestRequest("OTHER", s.validClaims()),
+		`nomad: key id "OTHER" not found`)
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWithBadSignature() {
+	// sign a token and replace the signature
+	token := s.signToken("KEYID", s.validClaims())
+	parts := strings.Split(token, ".")
+	s.Require().Len(parts, 3)
+	parts[2] = "aaaa"
+	token = strings.Join(parts, ".")
+
+	s.requireAttestError(makeAttestRequest("FOO", token),
+		"nomad: unable to verify token")
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsClaimValidation() {
+	// wrong audience
+	claims := s.validClaims()
+	claims.Audience = []string{"FOO"}
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"invalid audience claim")
+
+	// expired
+	claims = s.validClaims()
+	claims.Expiry = jwt.NewNumericDate(s.now.Add(-2 * time.Minute))
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"token is expired")
+
+	// missing namespace
+	claims = s.validClaims()
+	claims.Namespace = ""
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"nomad: token missing namespace claim")
+
+	// missing job
+	claims = s.validClaims()
+	claims.JobID = ""
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"nomad: token missing job ID claim")
+
+	// missing allocation
+	claims = s.validClaims()
+	claims.AllocationID = ""
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"nomad: token missing allocation ID claim")
+
+	// job not whitelisted
+	claims = s.validClaims()
+	claims.JobID = "web"
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		`nomad: "default:web" is not a whitelisted job`)
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWhenAllocationLookupFails() {
+	s.allocationErr = errors.New("oh no")
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		"nomad: unable to look up allocation: oh no")
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWhenAllocationBelongsToOtherJob() {
+	s.allocation.JobID = "web"
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		`nomad: allocation "5c3b2bbe-4a2b-e0a4-ae6c-3a5d1ee0a4a1" does not belong to job "default:spire-agent"`)
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWhenAllocationNotRunning() {
+	s.allocation.ClientStatus = "complete"
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		`nomad: allocation "5c3b2bbe-4a2b-e0a4-ae6c-3a5d1ee0a4a1" is not running (status "complete")`)
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWhenNodeLookupFails() {
+	s.nodeErr = errors.New("oh no")
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		"nomad: unable to look up node: oh no")
+}
+
+func (s *NomadAttestorSuite) TestAttestFailsWhenNodeNotReady() {
+	s.node.Status = "down"
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		`nomad: node "f7476465-4d6e-c0de-26d0-e383c49be941" is not ready (status "down")`)
+}
+
+func (s *NomadAttestorSuite) TestAttestSuccess() {
+	resp, err := s.doAttest(s.signAttestRequest("KEYID", s.validClaims()))
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/nomad/FOO/5c3b2bbe-4a2b-e0a4-ae6c-3a5d1ee0a4a1", resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "nomad", Value: "cluster:FOO"},
+		{Type: "nomad", Value: "agent_ns:default"},
+		{Type: "nomad", Value: "agent_job:spire-agent"},
+		{Type: "nomad", Value: "datacenter:dc1"},
+		{Type: "nomad", Value: "node_class:compute"},
+		{Type: "nomad", Value: "node_pool:prod"},
+	}, resp.Selectors)
+
+	// the allocation is checked with the API, so the agent can attest again
+	req := s.signAttestRequest("KEYID", s.validClaims())
+	req.AttestedBefore = true
+	_, err = s.doAttest(req)
+	s.Require().NoError(err)
+
+	// optional node properties are left out of the selectors
+	s.node.NodeClass = ""
+	s.node.NodePool = ""
+	resp, err = s.doAttest(s.signAttestRequest("KEYID", s.validClaims()))
+	s.Require().NoError(err)
+	s.Require().Equal([]*common.Selector{
+		{Type: "nomad", Value: "cluster:FOO"},
+		{Type: "nomad", Value: "agent_ns:default"},
+		{Type: "nomad", Value: "agent_job:spire-agent"},
+		{Type: "nomad", Value: "datacenter:dc1"},
+	}, resp.Selectors)
+}
+
+func (s *NomadAttestorSuite) TestConfigure() {
+	globalConfig := &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"}
+
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+	})
+	s.requireErrorContains(err, "nomad: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	// missing global configuration
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "nomad: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "nomad: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// no clusters
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: globalConfig,
+	})
+	s.Require().EqualError(err, "nomad: configuration must have at least one cluster")
+	s.Require().Nil(resp)
+
+	// missing address
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `clusters = {
+			"FOO" = {
+				job_whitelist = ["default:spire-agent"]
+			}
+		}`,
+		GlobalConfig: globalConfig,
+	})
+	s.Require().EqualError(err, `nomad: cluster "FOO" configuration missing address`)
+	s.Require().Nil(resp)
+
+	// no jobs whitelisted
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `clusters = {
+			"FOO" = {
+				address = "https://nomad.example.org:4646"
+			}
+		}`,
+		GlobalConfig: globalConfig,
+	})
+	s.Require().EqualError(err, `nomad: cluster "FOO" configuration must have at least one job whitelisted`)
+	s.Require().Nil(resp)
+
+	// CA certificates cannot be loaded
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf(`clusters = {
+			"FOO" = {
+				address = "https://nomad.example.org:4646"
+				ca_cert_path = %q
+				job_whitelist = ["default:spire-agent"]
+			}
+		}`, filepath.Join(s.dir, "missing.pem")),
+		GlobalConfig: globalConfig,
+	})
+	s.requireErrorContains(err, `nomad: failed to load cluster "FOO" CA certificates`)
+	s.Require().Nil(resp)
+
+	// success with a CA certificate and token
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotAfter:     s.now.Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	s.Require().NoError(err)
+	cert, err := x509.ParseCertificate(certDER)
+	s.Require().NoError(err)
+	caPath := filepath.Join(s.dir, "ca.pem")
+	s.Require().NoError(ioutil.WriteFile(caPath, pemutil.EncodeCertificate(cert), 0600))
+
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf(`clusters = {
+			"FOO" = {
+				address = "https://nomad.example.org:4646"
+				token = "SECRET"
+				ca_cert_path = %q
+				job_whitelist = ["default:spire-agent"]
+			}
+		}`, caPath),
+		GlobalConfig: globalConfig,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&plugin.ConfigureResponse{}, resp)
+	s.Require().Equal("https://nomad.example.org:4646", s.clientAddress)
+	s.Require().Equal("SECRET", s.clientToken)
+	s.Require().NotNil(s.clientRoots)
+	s.Require().Len(s.clientRoots.Subjects(), 1)
+}
+
+func (s *NomadAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *NomadAttestorSuite) validClaims() *nomad.WorkloadIdentityClaims {
+	return &nomad.WorkloadIdentityClaims{
+		Claims: jwt.Claims{
+			Subject:  "global:default:spire-agent:agent:agent:default",
+			Audience: []string{"spire-server"},
+			IssuedAt: jwt.NewNumericDate(s.now),
+			Expiry:   jwt.NewNumericDate(s.now.Add(time.Hour)),
+		},
+		Namespace:    "default",
+		JobID:        "spire-agent",
+		AllocationID: testAllocationID,
+		Task:         "agent",
+	}
+}
+
+func (s *NomadAttestorSuite) signToken(keyID string, claims *nomad.WorkloadIdentityClaims) string {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key: jose.JSONWebKey{
+			Key:   s.key,
+			KeyID: keyID,
+		},
+	}, nil)
+	s.Require().NoError(err)
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *NomadAttestorSuite) signAttestRequest(keyID string, claims *nomad.WorkloadIdentityClaims) *nodeattestor.AttestRequest {
+	return makeAttestRequest("FOO", s.signToken(keyID, claims))
+}
+
+func (s *NomadAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.now = func() time.Time {
+		return s.now
+	}
+	attestor.hooks.newClient = func(address, token string, roots *x509.CertPool) apiClient {
+		s.clientAddress = address
+		s.clientToken = token
+		s.clientRoots = roots
+		return &fakeAPIClient{s: s}
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *NomadAttestorSuite) configureAttestor() {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `clusters = {
+			"FOO" = {
+				address = "https://nomad.example.org:4646"
+				audience = "spire-server"
+				job_whitelist = ["default:spire-agent"]
+			}
+		}`,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *NomadAttestorSuite) doAttest(req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	return s.doAttestOnAttestor(s.attestor, req)
+}
+
+func (s *NomadAttestorSuite) doAttestOnAttestor(attestor *nodeattestor.BuiltIn, req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	stream, err := attestor.Attest(context.Background())
+	s.Require().NoError(err)
+
+	err = stream.Send(req)
+	s.Require().NoError(err)
+
+	err = stream.CloseSend()
+	s.Require().NoError(err)
+
+	return stream.Recv()
+}
+
+func (s *NomadAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	resp, err := s.doAttest(req)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *NomadAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func TestNomadClient(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/.well-known/jwks.json" {
+			json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+				Keys: []jose.JSONWebKey{{Key: key.Public(), KeyID: "KEYID", Algorithm: "ES256"}},
+			})
+			return
+		}
+		if req.Header.Get("X-Nomad-Token") != "SECRET" {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		switch req.URL.Path {
+		case "/v1/allocation/" + testAllocationID:
+			fmt.Fprintf(w, `{"ID": %q, "NodeID": %q, "Namespace": "default", "JobID": "spire-agent", "ClientStatus": "running"}`, testAllocationID, testNodeID)
+		case "/v1/node/" + testNodeID:
+			fmt.Fprintf(w, `{"ID": %q, "Name": "client-1", "Datacenter": "dc1", "NodeClass": "compute", "NodePool": "prod", "Status": "ready"}`, testNodeID)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	ctx := context.Background()
+	client := newNomadClient(server.URL, "SECRET", roots)
+
+	keySet, err := client.GetKeySet(ctx)
+	require.NoError(t, err)
+	require.Len(t, keySet.Key("KEYID"), 1)
+
+	allocation, err := client.GetAllocation(ctx, testAllocationID)
+	require.NoError(t, err)
+	require.Equal(t, &Allocation{
+		ID:           testAllocationID,
+		NodeID:       testNodeID,
+		Namespace:    "default",
+		JobID:        "spire-agent",
+		ClientStatus: "running",
+	}, allocation)
+
+	node, err := client.GetNode(ctx, testNodeID)
+	require.NoError(t, err)
+	require.Equal(t, &Node{
+		ID:         testNodeID,
+		Name:       "client-1",
+		Datacenter: "dc1",
+		NodeClass:  "compute",
+		NodePool:   "prod",
+		Status:     "ready",
+	}, node)
+
+	_, err = client.GetNode(ctx, "MISSING")
+	require.EqualError(t, err, "unexpected status code 404: 404 page not found\n")
+
+	_, err = newNomadClient(server.URL, "BAD", roots).GetNode(ctx, testNodeID)
+	require.EqualError(t, err, "unexpected status code 403: Permission denied\n")
+
+	// the server certificate is not trusted by the system roots
+	_, err = newNomadClient(server.URL, "SECRET", nil).GetNode(ctx, testNodeID)
+	require.Error(t, err)
+}
+
+type fakeAPIClient struct {
+	s *NomadAttestorSuite
+}
+
+func (c *fakeAPIClient) GetKeySet(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	return &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{{Key: c.s.key.Public(), KeyID: "KEYID"}},
+	}, nil
+}
+
+func (c *fakeAPIClient) GetAllocation(ctx context.Context, allocationID string) (*Allocation, error) {
+	c.s.Require().Equal(testAllocationID, allocationID)
+	if c.s.allocationErr != nil {
+		return nil, c.s.allocationErr
+	}
+	return c.s.allocation, nil
+}
+
+func (c *fakeAPIClient) GetNode(ctx context.Context, nodeID string) (*Node, error) {
+	c.s.Require().Equal(testNodeID, nodeID)
+	if c.s.nodeErr != nil {
+		return nil, c.s.nodeErr
+	}
+	return c.s.node, nil
+}
+
+func makeAttestRequest(cluster, token string) *nodeattestor.AttestRequest {
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "nomad",
+			Data: []byte(fmt.Sprintf(`{"cluster": %q, "token": %q}`, cluster, token)),
+		},
+	}
+}
@@ -0,0 +1,142 @@
+package oci
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/plugin/oci"
+	"github.com/zeebo/errs"
+)
+
+var (
+	// reRegion validates the region reported by the agent before it is
+	// used to build the API endpoint
+	reRegion = regexp.MustCompile(`^[a-z0-9-]+$`)
+)
+
+// Instance holds the instance details returned by the OCI compute API that
+// are relevant to node attestation
+type Instance struct {
+	ID                 string                            `json:"id"`
+	CompartmentID      string                            `json:"compartmentId"`
+	LifecycleState     string                            `json:"lifecycleState"`
+	Shape              string                            `json:"shape"`
+	AvailabilityDomain string                            `json:"availabilityDomain"`
+	Region             string                            `json:"region"`
+	DefinedTags        map[string]map[string]interface{} `json:"definedTags"`
+}
+
+// apiClient is an interface representing all of the API methods the
+// attestor needs to do its job.
+type apiClient interface {
+	GetInstance(ctx context.Context, region, instanceID string) (*Instance, error)
+}
+
+// apiCredentials identify the API signing key of an OCI user
+type apiCredentials struct {
+	TenancyID   string
+	UserID      string
+	Fingerprint string
+	PrivateKey  *rsa.PrivateKey
+}
+
+func (c apiCredentials) keyID() string {
+	return c.TenancyID + "/" + c.UserID + "/" + c.Fingerprint
+}
+
+// ociClient implements apiClient using the OCI core services REST API,
+// signing requests with the configured API key
+type ociClient struct {
+	httpClient oci.HTTPClient
+	creds      apiCredentials
+	now        func() time.Time
+
+	// endpoint returns the API endpoint for the region
+	endpoint func(region string) string
+}
+
+func newOCIClient(creds apiCredentials) apiClient {
+	return &ociClient{
+		httpClient: http.DefaultClient,
+		creds:      creds,
+		now:        time.Now,
+		endpoint: func(region string) string {
+			return "https://iaas." + region + ".oraclecloud.com"
+		},
+	}
+}
+
+func (c *ociClient) GetInstance(ctx context.Context, region, instanceID string) (*Instance, error) {
+	if !reRegion.MatchString(region) {
+		return nil, errs.New("invalid region %q", region)
+	}
+
+	req, err := http.NewRequest("GET", c.endpoint(region)+"/20160918/instances/"+url.PathEscape(instanceID), nil)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if err := c.signRequest(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errs.New("instance %q not found", instanceID)
+	default:
+		return nil, errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+
+	instance := new(Instance)
+	if err := json.NewDecoder(resp.Body).Decode(instance); err != nil {
+		return nil, errs.New("unable to decode response: %v", err)
+	}
+	return instance, nil
+}
+
+// signRequest signs the request using the draft-cavage HTTP signature
+// scheme required by the OCI APIs
+func (c *ociClient) signRequest(req *http.Request) error {
+	req.Header.Set("Date", c.now().UTC().Format(http.TimeFormat))
+
+	signingString := strings.Join([]string{
+		"date: " + req.Header.Get("Date"),
+		fmt.Sprintf("(request-target): %s %s", strings.ToLower(req.Method), req.URL.RequestURI()),
+		"host: " + req.URL.Host,
+	}, "\n")
+
+	hashed := sha256.Sum256([]byte(signingString))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.creds.PrivateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return errs.New("unable to sign request: %v", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf(`Signature version="1",keyId=%q,algorithm="rsa-sha256",headers="date (request-target) host",signature=%q`,
+		c.creds.keyID(), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
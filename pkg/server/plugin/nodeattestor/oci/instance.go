@@ -0,0 +1,306 @@
+package oci
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/oci"
+	"github.com/spiffe/spire/pkg/common/plugin/x509pop"
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+)
+
+const (
+	instanceStateRunning = "RUNNING"
+)
+
+var (
+	ociError = errs.Class("oci-instance-principal")
+)
+
+type InstanceAttestorConfig struct {
+	// CABundlePath is the path to the roots of the OCI instance principal
+	// certificates
+	CABundlePath         string   `hcl:"ca_bundle_path"`
+	TenancyWhitelist     []string `hcl:"tenancy_whitelist"`
+	CompartmentWhitelist []string `hcl:"compartment_whitelist"`
+
+	// The API key settings are optional. When set, the instance is looked
+	// up with the compute API to confirm it is running and to produce
+	// additional selectors.
+	APITenancyID      string `hcl:"api_tenancy_ocid"`
+	APIUserID         string `hcl:"api_user_ocid"`
+	APIFingerprint    string `hcl:"api_fingerprint"`
+	APIPrivateKeyPath string `hcl:"api_private_key_path"`
+}
+
+type instanceAttestorConfig struct {
+	trustDomain  string
+	trustBundle  *x509.CertPool
+	tenancies    map[string]bool
+	compartments map[string]bool
+	client       apiClient
+}
+
+type InstanceAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *instanceAttestorConfig
+
+	hooks struct {
+		newClient func(creds apiCredentials) apiClient
+	}
+}
+
+var _ nodeattestor.Plugin = (*InstanceAttestorPlugin)(nil)
+
+func New() *InstanceAttestorPlugin {
+	p := &InstanceAttestorPlugin{}
+	p.hooks.newClient = newOCIClient
+	return p
+}
+
+func (p *InstanceAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return ociError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestationData == nil {
+		return ociError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != oci.PluginName {
+		return ociError.New("unexpected attestation data type %q", dataType)
+	}
+
+	attestationData := new(oci.AttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return ociError.New("unable to unmarshal attestation data: %v", err)
+	}
+
+	if len(attestationData.Certificates) == 0 {
+		return ociError.New("no certificate to attest")
+	}
+	leaf, err := x509.ParseCertificate(attestationData.Certificates[0])
+	if err != nil {
+		return ociError.New("unable to parse instance certificate: %v", err)
+	}
+	intermediates := x509.NewCertPool()
+	for i, intermediateBytes := range attestationData.Certificates[1:] {
+		intermediate, err := x509.ParseCertificate(intermediateBytes)
+		if err != nil {
+			return ociError.New("unable to parse intermediate certificate %d: %v", i, err)
+		}
+		intermediates.AddCert(intermediate)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         config.trustBundle,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return ociError.New("certificate verification failed: %v", err)
+	}
+
+	identity, err := oci.ParseInstanceIdentity(leaf)
+	if err != nil {
+		return ociError.Wrap(err)
+	}
+
+	if !config.tenancies[identity.TenancyID] {
+		return ociError.New("tenancy %q is not whitelisted", identity.TenancyID)
+	}
+	if len(config.compartments) > 0 && !config.compartments[identity.CompartmentID] {
+		return ociError.New("compartment %q is not whitelisted", identity.CompartmentID)
+	}
+
+	// the certificate is trusted; have the node prove possession of the
+	// instance principal private key
+	if err := p.challengeNode(stream, leaf); err != nil {
+		return err
+	}
+
+	selectors := []*common.Selector{
+		makeSelector("tenancy", identity.TenancyID),
+		makeSelector("compartment", identity.CompartmentID),
+	}
+
+	if config.client != nil {
+		instance, err := config.client.GetInstance(stream.Context(), attestationData.Region, identity.InstanceID)
+		if err != nil {
+			return ociError.New("unable to look up instance: %v", err)
+		}
+		if instance.ID != identity.InstanceID {
+			return ociError.New("instance ID mismatch: expected %q; got %q", identity.InstanceID, instance.ID)
+		}
+		if instance.CompartmentID != identity.CompartmentID {
+			return ociError.New("instance compartment mismatch: expected %q; got %q", identity.CompartmentID, instance.CompartmentID)
+		}
+		if instance.LifecycleState != instanceStateRunning {
+			return ociError.New("instance %q is not running (state %q)", instance.ID, instance.LifecycleState)
+		}
+		selectors = append(selectors, buildInstanceSelectors(instance)...)
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: oci.AgentID(config.trustDomain, identity),
+		Selectors:    selectors,
+	})
+}
+
+func (p *InstanceAttestorPlugin) challengeNode(stream nodeattestor.Attest_PluginStream, leaf *x509.Certificate) error {
+	challenge, err := x509pop.GenerateChallenge(leaf)
+	if err != nil {
+		return ociError.New("unable to generate challenge: %v", err)
+	}
+
+	challengeBytes, err := json.Marshal(challenge)
+	if err != nil {
+		return ociError.New("unable to marshal challenge: %v", err)
+	}
+
+	if err := stream.Send(&nodeattestor.AttestResponse{
+		Challenge: challengeBytes,
+	}); err != nil {
+		return ociError.Wrap(err)
+	}
+
+	responseReq, err := stream.Recv()
+	if err != nil {
+		return ociError.Wrap(err)
+	}
+
+	response := new(x509pop.Response)
+	if err := json.Unmarshal(responseReq.Response, response); err != nil {
+		return ociError.New("unable to unmarshal challenge response: %v", err)
+	}
+
+	if err := x509pop.VerifyChallengeResponse(leaf.PublicKey, challenge, response); err != nil {
+		return ociError.New("challenge response verification failed: %v", err)
+	}
+	return nil
+}
+
+func (p *InstanceAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(InstanceAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, ociError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, ociError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, ociError.New("global configuration missing trust domain")
+	}
+
+	if hclConfig.CABundlePath == "" {
+		return nil, ociError.New("ca_bundle_path is required")
+	}
+	if len(hclConfig.TenancyWhitelist) == 0 {
+		return nil, ociError.New("tenancy_whitelist is required")
+	}
+
+	trustBundle, err := util.LoadCertPool(hclConfig.CABundlePath)
+	if err != nil {
+		return nil, ociError.New("unable to load trust bundle: %v", err)
+	}
+
+	config := &instanceAttestorConfig{
+		trustDomain:  req.GlobalConfig.TrustDomain,
+		trustBundle:  trustBundle,
+		tenancies:    make(map[string]bool),
+		compartments: make(map[string]bool),
+	}
+	for _, tenancy := range hclConfig.TenancyWhitelist {
+		config.tenancies[tenancy] = true
+	}
+	for _, compartment := range hclConfig.CompartmentWhitelist {
+		config.compartments[compartment] = true
+	}
+
+	switch {
+	case hclConfig.APITenancyID == "" && hclConfig.APIUserID == "" && hclConfig.APIFingerprint == "" && hclConfig.APIPrivateKeyPath == "":
+	case hclConfig.APITenancyID == "" || hclConfig.APIUserID == "" || hclConfig.APIFingerprint == "" || hclConfig.APIPrivateKeyPath == "":
+		return nil, ociError.New("api_tenancy_ocid, api_user_ocid, api_fingerprint and api_private_key_path must be configured together")
+	default:
+		privateKey, err := pemutil.LoadRSAPrivateKey(hclConfig.APIPrivateKeyPath)
+		if err != nil {
+			return nil, ociError.New("unable to load API private key: %v", err)
+		}
+		config.client = p.hooks.newClient(apiCredentials{
+			TenancyID:   hclConfig.APITenancyID,
+			UserID:      hclConfig.APIUserID,
+			Fingerprint: hclConfig.APIFingerprint,
+			PrivateKey:  privateKey,
+		})
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *InstanceAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *InstanceAttestorPlugin) getConfig() (*instanceAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, ociError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *InstanceAttestorPlugin) setConfig(config *instanceAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func buildInstanceSelectors(instance *Instance) []*common.Selector {
+	var selectors []*common.Selector
+	if instance.Shape != "" {
+		selectors = append(selectors, makeSelector("shape", instance.Shape))
+	}
+	if instance.AvailabilityDomain != "" {
+		selectors = append(selectors, makeSelector("availability_domain", instance.AvailabilityDomain))
+	}
+	if instance.Region != "" {
+		selectors = append(selectors, makeSelector("region", instance.Region))
+	}
+
+	var definedTags []string
+	for namespace, tags := range instance.DefinedTags {
+		for key, value := range tags {
+			definedTags = append(definedTags, fmt.Sprintf("%s.%s:%v", namespace, key, value))
+		}
+	}
+	sort.Strings(definedTags)
+	for _, definedTag := range definedTags {
+		selectors = append(selectors, makeSelector("defined_tag", definedTag))
+	}
+	return selectors
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  oci.PluginName,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
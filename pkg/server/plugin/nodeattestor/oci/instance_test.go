@@ -0,0 +1,522 @@
+package oci
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/x509pop"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/spiffe/spire/test/fakes/fakeoci"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testTenancy     = "ocid1.tenancy.oc1..tenancy"
+	testCompartment = "ocid1.compartment.oc1..compartment"
+	testInstance    = "ocid1.instance.oc1.iad.instance"
+)
+
+func TestInstanceAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(InstanceAttestorSuite))
+}
+
+type InstanceAttestorSuite struct {
+	suite.Suite
+
+	dir      string
+	ca       *fakeoci.CA
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	attestor *nodeattestor.BuiltIn
+
+	apiCreds *apiCredentials
+	apiKey   *rsa.PrivateKey
+	instance *Instance
+	apiErr   error
+}
+
+func (s *InstanceAttestorSuite) SetupSuite() {
+	dir, err := ioutil.TempDir("", "oci-instance-attestor-")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	s.ca = fakeoci.New(s.T())
+	s.writeFile("roots.pem", pemutil.EncodeCertificate(s.ca.Root))
+
+	s.apiKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+	keyBytes, err := pemutil.EncodePKCS8PrivateKey(s.apiKey)
+	s.Require().NoError(err)
+	s.writeFile("api-key.pem", keyBytes)
+}
+
+func (s *InstanceAttestorSuite) TearDownSuite() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *InstanceAttestorSuite) SetupTest() {
+	s.cert, s.key = s.ca.IssueInstanceCertificate(
+		"opc-certtype:instance",
+		"opc-instance:"+testInstance,
+		"opc-compartment:"+testCompartment,
+		"opc-tenant:"+testTenancy,
+	)
+	s.apiCreds = nil
+	s.apiErr = nil
+	s.instance = &Instance{
+		ID:                 testInstance,
+		CompartmentID:      testCompartment,
+		LifecycleState:     "RUNNING",
+		Shape:              "VM.Standard2.1",
+		AvailabilityDomain: "Uocm:US-ASHBURN-AD-1",
+		Region:             "iad",
+		DefinedTags: map[string]map[string]interface{}{
+			"Operations": {"CostCenter": "42", "Env": "prod"},
+		},
+	}
+
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *InstanceAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	resp, err := s.doAttestOnAttestor(s.newAttestor(), &nodeattestor.AttestRequest{})
+	s.Require().EqualError(err, "oci-instance-principal: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *InstanceAttestorSuite) TestAttestFailsWithNoAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"oci-instance-principal: missing attestation data")
+}
+
+func (s *InstanceAttestorSuite) TestAttestFailsWithWrongAttestationDataType() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "blah",
+		},
+	}, `oci-instance-principal: unexpected attestation data type "blah"`)
+}
+
+func (s *InstanceAttestorSuite) TestAttestFailsWithMalformedAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "oci_instance_principal",
+			Data: []byte("{"),
+		},
+	}, "oci-instance-principal: unable to unmarshal attestation data")
+}
+
+func (s *InstanceAttestorSuite) TestAttestFailsWithNoCertificate() {
+	s.requireAttestError(makeAttestRequest(nil),
+		"oci-instance-principal: no certificate to attest")
+}
+
+func (s *InstanceAttestorSuite) TestAttestFailsWithMalformedCertificate() {
+	s.requireAttestError(makeAttestRequest([][]byte{[]byte("blah")}),
+		"oci-instance-principal: unable to parse instance certificate")
+	s.requireAttestError(makeAttestRequest([][]byte{s.cert.Raw, []byte("blah")}),
+		"oci-instance-principal: unable to parse intermediate certificate 0")
+}
+
+func (s *InstanceAttestorSuite) TestAttestFailsWithUntrustedCertificate() {
+	// without the intermediate the chain cannot be built
+	s.requireAttestError(makeAttestRequest([][]byte{s.cert.Raw}),
+		"oci-instance-principal: certificate verification failed")
+
+	other := fakeoci.New(s.T())
+	cert, _ := other.IssueInstanceCertificate("opc-instance:"+testInstance, "opc-compartment:"+testCompartment, "opc-tenant:"+testTenancy)
+	s.requireAttestError(makeAttestRequest([][]byte{cert.Raw, other.Intermediate.Raw}),
+		"oci-instance-principal: certificate verification failed")
+}
+
+func (s *InstanceAttestorSuite) TestAttestFailsWithIncompleteIdentity() {
+	cert, _ := s.ca.IssueInstanceCertificate("opc-instance:"+testInstance, "opc-tenant:"+testTenancy)
+	s.requireAttestError(makeAttestRequest([][]byte{cert.Raw, s.ca.Intermediate.Raw}),
+		"oci-instance-principal: certificate missing compartment ID")
+}
+
+func (s *InstanceAttestorSuite) TestAttestTenancyWhitelist() {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("ca_bundle_path = %q\ntenancy_whitelist = [\"ocid1.tenancy.oc1..other\"]", s.path("roots.pem")),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+
+	s.requireAttestError(s.makeAttestRequest(),
+		`oci-instance-principal: tenancy "ocid1.tenancy.oc1..tenancy" is not whitelisted`)
+}
+
+func (s *InstanceAttestorSuite) TestAttestCompartmentWhitelist() {
+	s.configureAttestor(`compartment_whitelist = ["ocid1.compartment.oc1..other"]`)
+	s.requireAttestError(s.makeAttestRequest(),
+		`oci-instance-principal: compartment "ocid1.compartment.oc1..compartment" is not whitelisted`)
+
+	s.configureAttestor(`compartment_whitelist = ["ocid1.compartment.oc1..compartment"]`)
+	_, err := s.doAttest(s.makeAttestRequest())
+	s.Require().NoError(err)
+}
+
+func (s *InstanceAttestorSuite) TestAttestFailsWithBadChallengeResponse() {
+	stream, err := s.attestor.Attest(context.Background())
+	s.Require().NoError(err)
+	s.Require().NoError(stream.Send(s.makeAttestRequest()))
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Require().NotNil(resp.Challenge)
+
+	// respond with a signature from the wrong key
+	_, wrongKey := s.ca.IssueInstanceCertificate()
+	challenge := new(x509pop.Challenge)
+	s.Require().NoError(json.Unmarshal(resp.Challenge, challenge))
+	response, err := x509pop.CalculateResponse(wrongKey, challenge)
+	s.Require().NoError(err)
+	responseBytes, err := json.Marshal(response)
+	s.Require().NoError(err)
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{Response: responseBytes}))
+	s.Require().NoError(stream.CloseSend())
+
+	resp, err = stream.Recv()
+	s.requireErrorContains(err, "oci-instance-principal: challenge response verification failed")
+	s.Require().Nil(resp)
+}
+
+func (s *InstanceAttestorSuite) TestAttestSuccess() {
+	resp, err := s.doAttest(s.makeAttestRequest())
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/oci_instance_principal/"+testTenancy+"/"+testInstance, resp.BaseSPIFFEID)
+	s.Require().Equal([]*common.Selector{
+		{Type: "oci_instance_principal", Value: "tenancy:" + testTenancy},
+		{Type: "oci_instance_principal", Value: "compartment:" + testCompartment},
+	}, resp.Selectors)
+}
+
+func (s *InstanceAttestorSuite) TestAttestWithAPI() {
+	s.configureAttestor(s.apiConfig())
+	s.Require().NotNil(s.apiCreds)
+	s.Require().Equal("ocid1.tenancy.oc1..api/ocid1.user.oc1..api/aa:bb", s.apiCreds.keyID())
+	s.Require().Equal(s.apiKey, s.apiCreds.PrivateKey)
+
+	resp, err := s.doAttest(s.makeAttestRequest())
+	s.Require().NoError(err)
+	s.Require().Equal([]*common.Selector{
+		{Type: "oci_instance_principal", Value: "tenancy:" + testTenancy},
+		{Type: "oci_instance_principal", Value: "compartment:" + testCompartment},
+		{Type: "oci_instance_principal", Value: "shape:VM.Standard2.1"},
+		{Type: "oci_instance_principal", Value: "availability_domain:Uocm:US-ASHBURN-AD-1"},
+		{Type: "oci_instance_principal", Value: "region:iad"},
+		{Type: "oci_instance_principal", Value: "defined_tag:Operations.CostCenter:42"},
+		{Type: "oci_instance_principal", Value: "defined_tag:Operations.Env:prod"},
+	}, resp.Selectors)
+}
+
+func (s *InstanceAttestorSuite) TestAttestWithAPIFailures() {
+	s.configureAttestor(s.apiConfig())
+
+	s.apiErr = errors.New("oh no")
+	s.requireAttestError(s.makeAttestRequest(),
+		"oci-instance-principal: unable to look up instance: oh no")
+	s.apiErr = nil
+
+	s.instance.ID = "ocid1.instance.oc1.iad.other"
+	s.requireAttestError(s.makeAttestRequest(),
+		"oci-instance-principal: instance ID mismatch")
+	s.instance.ID = testInstance
+
+	s.instance.CompartmentID = "ocid1.compartment.oc1..other"
+	s.requireAttestError(s.makeAttestRequest(),
+		"oci-instance-principal: instance compartment mismatch")
+	s.instance.CompartmentID = testCompartment
+
+	s.instance.LifecycleState = "STOPPED"
+	s.requireAttestError(s.makeAttestRequest(),
+		`oci-instance-principal: instance "ocid1.instance.oc1.iad.instance" is not running (state "STOPPED")`)
+}
+
+func (s *InstanceAttestorSuite) TestConfigure() {
+	globalConfig := &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"}
+
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+	})
+	s.requireErrorContains(err, "oci-instance-principal: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	// missing global configuration
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "oci-instance-principal: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "oci-instance-principal: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// missing CA bundle
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: globalConfig,
+	})
+	s.Require().EqualError(err, "oci-instance-principal: ca_bundle_path is required")
+	s.Require().Nil(resp)
+
+	// missing tenancy whitelist
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("ca_bundle_path = %q", s.path("roots.pem")),
+		GlobalConfig:  globalConfig,
+	})
+	s.Require().EqualError(err, "oci-instance-principal: tenancy_whitelist is required")
+	s.Require().Nil(resp)
+
+	// unloadable CA bundle
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("ca_bundle_path = %q\ntenancy_whitelist = [%q]", s.path("missing.pem"), testTenancy),
+		GlobalConfig:  globalConfig,
+	})
+	s.requireErrorContains(err, "oci-instance-principal: unable to load trust bundle")
+	s.Require().Nil(resp)
+
+	// partial API configuration
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: s.baseConfig() + `api_user_ocid = "ocid1.user.oc1..api"`,
+		GlobalConfig:  globalConfig,
+	})
+	s.Require().EqualError(err, "oci-instance-principal: api_tenancy_ocid, api_user_ocid, api_fingerprint and api_private_key_path must be configured together")
+	s.Require().Nil(resp)
+
+	// unloadable API key
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: s.baseConfig() + strings.Replace(s.apiConfig(), "api-key.pem", "roots.pem", 1),
+		GlobalConfig:  globalConfig,
+	})
+	s.requireErrorContains(err, "oci-instance-principal: unable to load API private key")
+	s.Require().Nil(resp)
+}
+
+func (s *InstanceAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *InstanceAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.newClient = func(creds apiCredentials) apiClient {
+		s.apiCreds = &creds
+		return fakeAPIClient(func(ctx context.Context, region, instanceID string) (*Instance, error) {
+			s.Require().Equal("us-ashburn-1", region)
+			s.Require().Equal(testInstance, instanceID)
+			if s.apiErr != nil {
+				return nil, s.apiErr
+			}
+			return s.instance, nil
+		})
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *InstanceAttestorSuite) baseConfig() string {
+	return fmt.Sprintf("ca_bundle_path = %q\ntenancy_whitelist = [%q]\n", s.path("roots.pem"), testTenancy)
+}
+
+func (s *InstanceAttestorSuite) apiConfig() string {
+	return fmt.Sprintf(`
+		api_tenancy_ocid = "ocid1.tenancy.oc1..api"
+		api_user_ocid = "ocid1.user.oc1..api"
+		api_fingerprint = "aa:bb"
+		api_private_key_path = %q
+		`, s.path("api-key.pem"))
+}
+
+func (s *InstanceAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: s.baseConfig() + config,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *InstanceAttestorSuite) doAttest(req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	return s.doAttestOnAttestor(s.attestor, req)
+}
+
+// doAttestOnAttestor sends the request and, if challenged, answers the
+// challenge using the instance principal key
+func (s *InstanceAttestorSuite) doAttestOnAttestor(attestor *nodeattestor.BuiltIn, req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	stream, err := attestor.Attest(context.Background())
+	s.Require().NoError(err)
+
+	err = stream.Send(req)
+	s.Require().NoError(err)
+
+	resp, err := stream.Recv()
+	if err != nil || resp.Challenge == nil {
+		s.Require().NoError(stream.CloseSend())
+		return resp, err
+	}
+
+	challenge := new(x509pop.Challenge)
+	s.Require().NoError(json.Unmarshal(resp.Challenge, challenge))
+	response, err := x509pop.CalculateResponse(s.key, challenge)
+	s.Require().NoError(err)
+	responseBytes, err := json.Marshal(response)
+	s.Require().NoError(err)
+
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{Response: responseBytes}))
+	s.Require().NoError(stream.CloseSend())
+
+	return stream.Recv()
+}
+
+func (s *InstanceAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	resp, err := s.doAttest(req)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *InstanceAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func (s *InstanceAttestorSuite) makeAttestRequest() *nodeattestor.AttestRequest {
+	return makeAttestRequest([][]byte{s.cert.Raw, s.ca.Intermediate.Raw})
+}
+
+func (s *InstanceAttestorSuite) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+func (s *InstanceAttestorSuite) writeFile(name string, data []byte) {
+	s.Require().NoError(ioutil.WriteFile(s.path(name), data, 0600))
+}
+
+func TestGetInstance(t *testing.T) {
+	apiKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	reAuthorization := regexp.MustCompile(`^Signature version="1",keyId="([^"]+)",algorithm="rsa-sha256",headers="date \(request-target\) host",signature="([^"]+)"$`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		m := reAuthorization.FindStringSubmatch(req.Header.Get("Authorization"))
+		if m == nil || m[1] != "tenancy/user/fingerprint" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if req.Header.Get("Date") != "Sat, 01 Jun 2019 12:00:00 GMT" {
+			http.Error(w, "bad date", http.StatusUnauthorized)
+			return
+		}
+		signature, err := base64.StdEncoding.DecodeString(m[2])
+		if err != nil {
+			http.Error(w, "malformed signature", http.StatusUnauthorized)
+			return
+		}
+		hashed := sha256.Sum256([]byte(fmt.Sprintf("date: %s\n(request-target): get %s\nhost: %s",
+			req.Header.Get("Date"), req.URL.RequestURI(), req.Host)))
+		if err := rsa.VerifyPKCS1v15(&apiKey.PublicKey, crypto.SHA256, hashed[:], signature); err != nil {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+
+		switch req.URL.Path {
+		case "/20160918/instances/ocid1.instance.oc1.iad.instance":
+			fmt.Fprint(w, `{"id": "ocid1.instance.oc1.iad.instance", "compartmentId": "ocid1.compartment.oc1..compartment", "lifecycleState": "RUNNING", "definedTags": {"Operations": {"Env": "prod"}}}`)
+		case "/20160918/instances/malformed":
+			fmt.Fprint(w, `{`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	newClient := func(key *rsa.PrivateKey) apiClient {
+		client := newOCIClient(apiCredentials{
+			TenancyID:   "tenancy",
+			UserID:      "user",
+			Fingerprint: "fingerprint",
+			PrivateKey:  key,
+		}).(*ociClient)
+		client.now = func() time.Time { return now }
+		client.endpoint = func(region string) string {
+			require.Equal(t, "us-ashburn-1", region)
+			return server.URL
+		}
+		return client
+	}
+
+	ctx := context.Background()
+	client := newClient(apiKey)
+
+	instance, err := client.GetInstance(ctx, "us-ashburn-1", "ocid1.instance.oc1.iad.instance")
+	require.NoError(t, err)
+	require.Equal(t, &Instance{
+		ID:             "ocid1.instance.oc1.iad.instance",
+		CompartmentID:  "ocid1.compartment.oc1..compartment",
+		LifecycleState: "RUNNING",
+		DefinedTags: map[string]map[string]interface{}{
+			"Operations": {"Env": "prod"},
+		},
+	}, instance)
+
+	_, err = client.GetInstance(ctx, "us-ashburn-1", "missing")
+	require.EqualError(t, err, `instance "missing" not found`)
+
+	_, err = client.GetInstance(ctx, "us-ashburn-1", "malformed")
+	require.EqualError(t, err, "unable to decode response: unexpected EOF")
+
+	_, err = client.GetInstance(ctx, "evil.example.com/", "ocid1.instance.oc1.iad.instance")
+	require.EqualError(t, err, `invalid region "evil.example.com/"`)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, err = newClient(otherKey).GetInstance(ctx, "us-ashburn-1", "ocid1.instance.oc1.iad.instance")
+	require.EqualError(t, err, "unexpected status code 401: bad signature\n")
+}
+
+type fakeAPIClient func(ctx context.Context, region, instanceID string) (*Instance, error)
+
+func (fn fakeAPIClient) GetInstance(ctx context.Context, region, instanceID string) (*Instance, error) {
+	return fn(ctx, region, instanceID)
+}
+
+func makeAttestRequest(certificates [][]byte) *nodeattestor.AttestRequest {
+	data, _ := json.Marshal(map[string]interface{}{
+		"certificates": certificates,
+		"region":       "us-ashburn-1",
+	})
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "oci_instance_principal",
+			Data: data,
+		},
+	}
+}
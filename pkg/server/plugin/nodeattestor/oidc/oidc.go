@@ -0,0 +1,286 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/jwtutil"
+	"github.com/spiffe/spire/pkg/common/plugin/oidc"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// Leeway given to account for clock differences between the issuer and
+	// the server
+	tokenLeeway = time.Minute
+
+	keySetRefreshInterval = time.Hour
+)
+
+var (
+	oidcError = errs.Class("oidc")
+)
+
+type OIDCAttestorConfig struct {
+	Issuer string `hcl:"issuer"`
+
+	// JWKSURL is the URL of the issuer signing keys. If unset, it is
+	// discovered from the issuer OpenID configuration.
+	JWKSURL string `hcl:"jwks_url"`
+
+	Audience string `hcl:"audience"`
+
+	// AllowedClaims maps claim names to the values allowed for them. Tokens
+	// must carry every listed claim with one of its allowed values.
+	AllowedClaims map[string][]string `hcl:"allowed_claims"`
+
+	// SelectorClaims lists the claims turned into selectors. Defaults to
+	// the subject.
+	SelectorClaims []string `hcl:"selector_claims"`
+}
+
+type oidcAttestorConfig struct {
+	trustDomain    string
+	issuer         string
+	audience       string
+	allowedClaims  map[string]map[string]bool
+	selectorClaims []string
+	keySetProvider jwtutil.KeySetProvider
+}
+
+type OIDCAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *oidcAttestorConfig
+
+	hooks struct {
+		now               func() time.Time
+		newKeySetProvider func(issuer, jwksURL string) jwtutil.KeySetProvider
+	}
+}
+
+var _ nodeattestor.Plugin = (*OIDCAttestorPlugin)(nil)
+
+func New() *OIDCAttestorPlugin {
+	p := &OIDCAttestorPlugin{}
+	p.hooks.now = time.Now
+	p.hooks.newKeySetProvider = newKeySetProvider
+	return p
+}
+
+func (p *OIDCAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return oidcError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestedBefore {
+		return oidcError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return oidcError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != oidc.PluginName {
+		return oidcError.New("unexpected attestation data type %q", dataType)
+	}
+
+	attestationData := new(oidc.AttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return oidcError.New("unable to unmarshal attestation data: %v", err)
+	}
+
+	if attestationData.Token == "" {
+		return oidcError.New("missing token from attestation data")
+	}
+
+	token, err := jwt.ParseSigned(attestationData.Token)
+	if err != nil {
+		return oidcError.New("unable to parse token: %v", err)
+	}
+
+	keyID, ok := getTokenKeyID(token)
+	if !ok {
+		return oidcError.New("token missing key id")
+	}
+
+	keySet, err := config.keySetProvider.GetKeySet(stream.Context())
+	if err != nil {
+		return oidcError.New("unable to obtain JWKS: %v", err)
+	}
+
+	keys := keySet.Key(keyID)
+	if len(keys) == 0 {
+		return oidcError.New("key id %q not found", keyID)
+	}
+
+	claims := new(jwt.Claims)
+	allClaims := make(map[string]interface{})
+	if err := token.Claims(&keys[0], claims, &allClaims); err != nil {
+		return oidcError.New("unable to verify token: %v", err)
+	}
+
+	if err := claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:   config.issuer,
+		Audience: []string{config.audience},
+		Time:     p.hooks.now(),
+	}, tokenLeeway); err != nil {
+		return oidcError.New("unable to validate token claims: %v", err)
+	}
+
+	if err := checkAllowedClaims(config.allowedClaims, allClaims); err != nil {
+		return err
+	}
+
+	agentID, err := oidc.AgentID(config.trustDomain, claims.Subject, claims.ID)
+	if err != nil {
+		return oidcError.Wrap(err)
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: agentID,
+		Selectors:    buildSelectors(config.selectorClaims, allClaims),
+	})
+}
+
+func (p *OIDCAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(OIDCAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, oidcError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, oidcError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, oidcError.New("global configuration missing trust domain")
+	}
+
+	if hclConfig.Issuer == "" {
+		return nil, oidcError.New("issuer is required")
+	}
+	if hclConfig.Audience == "" {
+		return nil, oidcError.New("audience is required")
+	}
+
+	config := &oidcAttestorConfig{
+		trustDomain:    req.GlobalConfig.TrustDomain,
+		issuer:         hclConfig.Issuer,
+		audience:       hclConfig.Audience,
+		allowedClaims:  make(map[string]map[string]bool),
+		selectorClaims: hclConfig.SelectorClaims,
+		keySetProvider: p.hooks.newKeySetProvider(hclConfig.Issuer, hclConfig.JWKSURL),
+	}
+	for claim, values := range hclConfig.AllowedClaims {
+		if len(values) == 0 {
+			return nil, oidcError.New("allowed_claims entry for %q has no values", claim)
+		}
+		config.allowedClaims[claim] = make(map[string]bool)
+		for _, value := range values {
+			config.allowedClaims[claim][value] = true
+		}
+	}
+	if len(config.selectorClaims) == 0 {
+		config.selectorClaims = []string{"sub"}
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *OIDCAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *OIDCAttestorPlugin) getConfig() (*oidcAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, oidcError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *OIDCAttestorPlugin) setConfig(config *oidcAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func newKeySetProvider(issuer, jwksURL string) jwtutil.KeySetProvider {
+	var provider jwtutil.KeySetProvider = jwtutil.OIDCIssuer(issuer)
+	if jwksURL != "" {
+		provider = jwtutil.KeySetProviderFunc(func(ctx context.Context) (*jose.JSONWebKeySet, error) {
+			return jwtutil.FetchKeySet(ctx, jwksURL)
+		})
+	}
+	return jwtutil.NewCachingKeySetProvider(provider, keySetRefreshInterval)
+}
+
+func checkAllowedClaims(allowedClaims map[string]map[string]bool, claims map[string]interface{}) error {
+	// iterate in a stable order so the reported claim is deterministic
+	names := make([]string, 0, len(allowedClaims))
+	for name := range allowedClaims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values := oidc.ClaimValues(claims[name])
+		if len(values) == 0 {
+			return oidcError.New("token missing %q claim", name)
+		}
+		allowed := false
+		for _, value := range values {
+			if allowedClaims[name][value] {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return oidcError.New("token %q claim is not allowed", name)
+		}
+	}
+	return nil
+}
+
+func buildSelectors(selectorClaims []string, claims map[string]interface{}) []*common.Selector {
+	var selectors []*common.Selector
+	for _, name := range selectorClaims {
+		for _, value := range oidc.ClaimValues(claims[name]) {
+			selectors = append(selectors, makeSelector(name, value))
+		}
+	}
+	return selectors
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  oidc.PluginName,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
+
+func getTokenKeyID(token *jwt.JSONWebToken) (string, bool) {
+	for _, h := range token.Headers {
+		if h.KeyID != "" {
+			return h.KeyID, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,383 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/jwtutil"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/stretchr/testify/suite"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	testIssuer = "https://oidc.example.org"
+)
+
+func TestOIDCAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(OIDCAttestorSuite))
+}
+
+type OIDCAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+	key      *ecdsa.PrivateKey
+	jwks     *jose.JSONWebKeySet
+	now      time.Time
+	issuer   string
+	jwksURL  string
+}
+
+func (s *OIDCAttestorSuite) SetupTest() {
+	var err error
+	s.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	s.jwks = new(jose.JSONWebKeySet)
+
+	// JWT numeric dates have second granularity
+	s.now = time.Now().Truncate(time.Second)
+	s.issuer = ""
+	s.jwksURL = ""
+
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	resp, err := s.doAttestOnAttestor(s.newAttestor(), &nodeattestor.AttestRequest{})
+	s.Require().EqualError(err, "oidc: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWhenAttestedBefore() {
+	s.requireAttestError(&nodeattestor.AttestRequest{AttestedBefore: true},
+		"oidc: node has already attested")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithNoAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"oidc: missing attestation data")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithWrongAttestationDataType() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "blah",
+		},
+	}, `oidc: unexpected attestation data type "blah"`)
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithMalformedAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "oidc",
+			Data: []byte("{"),
+		},
+	}, "oidc: unable to unmarshal attestation data")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithNoToken() {
+	s.requireAttestError(makeAttestRequest(""),
+		"oidc: missing token from attestation data")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithMalformedToken() {
+	s.requireAttestError(makeAttestRequest("blah"),
+		"oidc: unable to parse token")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsIfTokenKeyIDMissing() {
+	s.requireAttestError(s.signAttestRequest("", s.validClaims()),
+		"oidc: token missing key id")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsIfTokenKeyIDNotFound() {
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		`oidc: key id "KEYID" not found`)
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsWithBadSignature() {
+	s.addKey("KEYID")
+
+	// sign a token and replace the signature
+	token := s.signToken("KEYID", s.validClaims())
+	parts := strings.Split(token, ".")
+	s.Require().Len(parts, 3)
+	parts[2] = "aaaa"
+	token = strings.Join(parts, ".")
+
+	s.requireAttestError(makeAttestRequest(token),
+		"oidc: unable to verify token")
+}
+
+func (s *OIDCAttestorSuite) TestAttestFailsClaimValidation() {
+	s.addKey("KEYID")
+
+	// wrong issuer
+	claims := s.validClaims()
+	claims["iss"] = "https://evil.example.org"
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"invalid issuer claim")
+
+	// wrong audience
+	claims = s.validClaims()
+	claims["aud"] = "FOO"
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"invalid audience claim")
+
+	// subject that would escape the agent ID path
+	claims = s.validClaims()
+	claims["sub"] = "../../server"
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		`oidc: subject "../../server" cannot be used in an agent ID`)
+}
+
+func (s *OIDCAttestorSuite) TestAttestTokenExpiration() {
+	s.addKey("KEYID")
+	req := s.signAttestRequest("KEYID", s.validClaims())
+
+	// within the 1m leeway (token expires at 5m + 1m leeway = 6m)
+	s.adjustTime(6 * time.Minute)
+	_, err := s.doAttest(req)
+	s.Require().NoError(err)
+
+	// just after the 1m leeway
+	s.adjustTime(time.Second)
+	s.requireAttestError(req, "token is expired")
+}
+
+func (s *OIDCAttestorSuite) TestAttestAllowedClaims() {
+	s.addKey("KEYID")
+	s.configureAttestor(`
+	allowed_claims = {
+		repository_owner = ["octo-org", "other-org"]
+		groups = ["admins"]
+	}
+	`)
+
+	// allowed
+	_, err := s.doAttest(s.signAttestRequest("KEYID", s.validClaims()))
+	s.Require().NoError(err)
+
+	// claim missing
+	claims := s.validClaims()
+	delete(claims, "groups")
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		`oidc: token missing "groups" claim`)
+
+	// value not allowed
+	claims = s.validClaims()
+	claims["repository_owner"] = "evil-org"
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		`oidc: token "repository_owner" claim is not allowed`)
+
+	// no value of an array claim is allowed
+	claims = s.validClaims()
+	claims["groups"] = []string{"devs"}
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		`oidc: token "groups" claim is not allowed`)
+}
+
+func (s *OIDCAttestorSuite) TestAttestSuccess() {
+	s.addKey("KEYID")
+
+	resp, err := s.doAttest(s.signAttestRequest("KEYID", s.validClaims()))
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/oidc/repo:octo-org/octo-repo:ref:refs/heads/main/TOKENID", resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "oidc", Value: "sub:repo:octo-org/octo-repo:ref:refs/heads/main"},
+	}, resp.Selectors)
+
+	// without a token ID
+	claims := s.validClaims()
+	delete(claims, "jti")
+	resp, err = s.doAttest(s.signAttestRequest("KEYID", claims))
+	s.Require().NoError(err)
+	s.Require().Equal("spiffe://example.org/spire/agent/oidc/repo:octo-org/octo-repo:ref:refs/heads/main", resp.BaseSPIFFEID)
+}
+
+func (s *OIDCAttestorSuite) TestAttestSuccessWithSelectorClaims() {
+	s.addKey("KEYID")
+	s.configureAttestor(`selector_claims = ["repository_owner", "groups", "run_attempt", "missing"]`)
+
+	resp, err := s.doAttest(s.signAttestRequest("KEYID", s.validClaims()))
+	s.Require().NoError(err)
+	s.Require().Equal([]*common.Selector{
+		{Type: "oidc", Value: "repository_owner:octo-org"},
+		{Type: "oidc", Value: "groups:admins"},
+		{Type: "oidc", Value: "groups:devs"},
+		{Type: "oidc", Value: "run_attempt:2"},
+	}, resp.Selectors)
+}
+
+func (s *OIDCAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+	})
+	s.requireErrorContains(err, "oidc: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	// missing global configuration
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "oidc: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "oidc: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// missing issuer
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `audience = "spire-server"`,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "oidc: issuer is required")
+	s.Require().Nil(resp)
+
+	// missing audience
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("issuer = %q", testIssuer),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "oidc: audience is required")
+	s.Require().Nil(resp)
+
+	// allowed claim without values
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("issuer = %q\naudience = \"spire-server\"\nallowed_claims = { repository_owner = [] }", testIssuer),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, `oidc: allowed_claims entry for "repository_owner" has no values`)
+	s.Require().Nil(resp)
+
+	// explicit JWKS URL
+	s.configureAttestor(`jwks_url = "https://keys.example.org/jwks"`)
+	s.Require().Equal("https://keys.example.org/jwks", s.jwksURL)
+}
+
+func (s *OIDCAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *OIDCAttestorSuite) adjustTime(d time.Duration) {
+	s.now = s.now.Add(d)
+}
+
+func (s *OIDCAttestorSuite) validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":              testIssuer,
+		"sub":              "repo:octo-org/octo-repo:ref:refs/heads/main",
+		"aud":              "spire-server",
+		"jti":              "TOKENID",
+		"nbf":              s.now.Unix(),
+		"exp":              s.now.Add(5 * time.Minute).Unix(),
+		"repository_owner": "octo-org",
+		"groups":           []string{"devs", "admins"},
+		"run_attempt":      2,
+	}
+}
+
+func (s *OIDCAttestorSuite) signToken(keyID string, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key: jose.JSONWebKey{
+			Key:   s.key,
+			KeyID: keyID,
+		},
+	}, nil)
+	s.Require().NoError(err)
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *OIDCAttestorSuite) signAttestRequest(keyID string, claims map[string]interface{}) *nodeattestor.AttestRequest {
+	return makeAttestRequest(s.signToken(keyID, claims))
+}
+
+func (s *OIDCAttestorSuite) addKey(keyID string) {
+	s.jwks.Keys = append(s.jwks.Keys, jose.JSONWebKey{
+		Key:   s.key.Public(),
+		KeyID: keyID,
+	})
+}
+
+func (s *OIDCAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.now = func() time.Time {
+		return s.now
+	}
+	attestor.hooks.newKeySetProvider = func(issuer, jwksURL string) jwtutil.KeySetProvider {
+		s.issuer = issuer
+		s.jwksURL = jwksURL
+		return jwtutil.KeySetProviderFunc(func(ctx context.Context) (*jose.JSONWebKeySet, error) {
+			return s.jwks, nil
+		})
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *OIDCAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("issuer = %q\naudience = \"spire-server\"\n%s", testIssuer, config),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+	s.Require().Equal(testIssuer, s.issuer)
+}
+
+func (s *OIDCAttestorSuite) doAttest(req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	return s.doAttestOnAttestor(s.attestor, req)
+}
+
+func (s *OIDCAttestorSuite) doAttestOnAttestor(attestor *nodeattestor.BuiltIn, req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	stream, err := attestor.Attest(context.Background())
+	s.Require().NoError(err)
+
+	err = stream.Send(req)
+	s.Require().NoError(err)
+
+	err = stream.CloseSend()
+	s.Require().NoError(err)
+
+	return stream.Recv()
+}
+
+func (s *OIDCAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	resp, err := s.doAttest(req)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *OIDCAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func makeAttestRequest(token string) *nodeattestor.AttestRequest {
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "oidc",
+			Data: []byte(fmt.Sprintf(`{"token": %q}`, token)),
+		},
+	}
+}
@@ -0,0 +1,240 @@
+package openstack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/openstack"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// Leeway given to account for clock differences between the vendordata
+	// service and the server
+	tokenLeeway = time.Minute
+)
+
+var (
+	openstackError = errs.Class("openstack")
+)
+
+type VendordataAttestorConfig struct {
+	// VendordataJWKSPath is the path to a JWKS document holding the public
+	// keys of the vendordata service
+	VendordataJWKSPath string   `hcl:"vendordata_jwks_path"`
+	Issuer             string   `hcl:"issuer"`
+	Audience           string   `hcl:"audience"`
+	ProjectIDWhitelist []string `hcl:"project_id_whitelist"`
+}
+
+type vendordataAttestorConfig struct {
+	trustDomain string
+	keySet      *jose.JSONWebKeySet
+	issuer      string
+	audience    string
+	projectIDs  map[string]bool
+}
+
+type VendordataAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *vendordataAttestorConfig
+
+	hooks struct {
+		now func() time.Time
+	}
+}
+
+var _ nodeattestor.Plugin = (*VendordataAttestorPlugin)(nil)
+
+func New() *VendordataAttestorPlugin {
+	p := &VendordataAttestorPlugin{}
+	p.hooks.now = time.Now
+	return p
+}
+
+func (p *VendordataAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return openstackError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestedBefore {
+		return openstackError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return openstackError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != openstack.PluginName {
+		return openstackError.New("unexpected attestation data type %q", dataType)
+	}
+
+	attestationData := new(openstack.AttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return openstackError.New("unable to unmarshal attestation data: %v", err)
+	}
+
+	if attestationData.Token == "" {
+		return openstackError.New("missing token from attestation data")
+	}
+
+	token, err := jwt.ParseSigned(attestationData.Token)
+	if err != nil {
+		return openstackError.New("unable to parse token: %v", err)
+	}
+
+	keyID, ok := getTokenKeyID(token)
+	if !ok {
+		return openstackError.New("token missing key id")
+	}
+
+	keys := config.keySet.Key(keyID)
+	if len(keys) == 0 {
+		return openstackError.New("key id %q not found", keyID)
+	}
+
+	claims := new(openstack.VendordataClaims)
+	if err := token.Claims(&keys[0], claims); err != nil {
+		return openstackError.New("unable to verify token: %v", err)
+	}
+
+	if err := claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:   config.issuer,
+		Audience: []string{config.audience},
+		Time:     p.hooks.now(),
+	}, tokenLeeway); err != nil {
+		return openstackError.New("unable to validate token claims: %v", err)
+	}
+
+	switch {
+	case claims.ProjectID == "":
+		return openstackError.New("token missing project ID claim")
+	case claims.InstanceID == "":
+		return openstackError.New("token missing instance ID claim")
+	}
+
+	if len(config.projectIDs) > 0 && !config.projectIDs[claims.ProjectID] {
+		return openstackError.New("project ID %q is not whitelisted", claims.ProjectID)
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: claims.AgentID(config.trustDomain),
+		Selectors:    buildSelectors(claims),
+	})
+}
+
+func (p *VendordataAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(VendordataAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, openstackError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, openstackError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, openstackError.New("global configuration missing trust domain")
+	}
+
+	if hclConfig.VendordataJWKSPath == "" {
+		return nil, openstackError.New("vendordata_jwks_path is required")
+	}
+	keySet, err := loadKeySet(hclConfig.VendordataJWKSPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &vendordataAttestorConfig{
+		trustDomain: req.GlobalConfig.TrustDomain,
+		keySet:      keySet,
+		issuer:      hclConfig.Issuer,
+		audience:    hclConfig.Audience,
+		projectIDs:  make(map[string]bool),
+	}
+	if config.audience == "" {
+		config.audience = openstack.DefaultAudience
+	}
+	for _, projectID := range hclConfig.ProjectIDWhitelist {
+		config.projectIDs[projectID] = true
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *VendordataAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *VendordataAttestorPlugin) getConfig() (*vendordataAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, openstackError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *VendordataAttestorPlugin) setConfig(config *vendordataAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func loadKeySet(path string) (*jose.JSONWebKeySet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, openstackError.New("unable to read vendordata JWKS: %v", err)
+	}
+	keySet := new(jose.JSONWebKeySet)
+	if err := json.Unmarshal(data, keySet); err != nil {
+		return nil, openstackError.New("unable to parse vendordata JWKS: %v", err)
+	}
+	if len(keySet.Keys) == 0 {
+		return nil, openstackError.New("vendordata JWKS has no keys")
+	}
+	return keySet, nil
+}
+
+func buildSelectors(claims *openstack.VendordataClaims) []*common.Selector {
+	selectors := []*common.Selector{
+		makeSelector("project_id", claims.ProjectID),
+		makeSelector("instance_id", claims.InstanceID),
+	}
+	if claims.Flavor != "" {
+		selectors = append(selectors, makeSelector("flavor", claims.Flavor))
+	}
+	return selectors
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  openstack.PluginName,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
+
+func getTokenKeyID(token *jwt.JSONWebToken) (string, bool) {
+	for _, h := range token.Headers {
+		if h.KeyID != "" {
+			return h.KeyID, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,370 @@
+package openstack
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/plugin/openstack"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/stretchr/testify/suite"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestVendordataAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(VendordataAttestorSuite))
+}
+
+type VendordataAttestorSuite struct {
+	suite.Suite
+
+	dir      string
+	jwksPath string
+	attestor *nodeattestor.BuiltIn
+	key      *ecdsa.PrivateKey
+	now      time.Time
+}
+
+func (s *VendordataAttestorSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "openstack-test")
+	s.Require().NoError(err)
+
+	s.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	s.jwksPath = s.writeKeySet(jose.JSONWebKey{
+		Key:   s.key.Public(),
+		KeyID: "KEYID",
+	})
+
+	// JWT numeric dates have second granularity
+	s.now = time.Now().Truncate(time.Second)
+
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *VendordataAttestorSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *VendordataAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	resp, err := s.doAttestOnAttestor(s.newAttestor(), &nodeattestor.AttestRequest{})
+	s.Require().EqualError(err, "openstack: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *VendordataAttestorSuite) TestAttestFailsWhenAttestedBefore() {
+	s.requireAttestError(&nodeattestor.AttestRequest{AttestedBefore: true},
+		"openstack: node has already attested")
+}
+
+func (s *VendordataAttestorSuite) TestAttestFailsWithNoAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"openstack: missing attestation data")
+}
+
+func (s *VendordataAttestorSuite) TestAttestFailsWithWrongAttestationDataType() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "blah",
+		},
+	}, `openstack: unexpected attestation data type "blah"`)
+}
+
+func (s *VendordataAttestorSuite) TestAttestFailsWithMalformedAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "openstack",
+			Data: []byte("{"),
+		},
+	}, "openstack: unable to unmarshal attestation data")
+}
+
+func (s *VendordataAttestorSuite) TestAttestFailsWithNoToken() {
+	s.requireAttestError(makeAttestRequest(""),
+		"openstack: missing token from attestation data")
+}
+
+func (s *VendordataAttestorSuite) TestAttestFailsWithMalformedToken() {
+	s.requireAttestError(makeAttestRequest("blah"),
+		"openstack: unable to parse token")
+}
+
+func (s *VendordataAttestorSuite) TestAttestFailsIfTokenKeyIDMissing() {
+	s.requireAttestError(s.signAttestRequest("", s.validClaims()),
+		"openstack: token missing key id")
+}
+
+func (s *VendordataAttestorSuite) TestAttestFailsIfTokenKeyIDNotFound() {
+	s.requireAttestError(s.signAttestRequest("OTHER", s.validClaims()),
+		`openstack: key id "OTHER" not found`)
+}
+
+func (s *VendordataAttestorSuite) TestAttestFailsWithBadSignature() {
+	// sign a token and replace the signature
+	token := s.signToken("KEYID", s.validClaims())
+	parts := strings.Split(token, ".")
+	s.Require().Len(parts, 3)
+	parts[2] = "aaaa"
+	token = strings.Join(parts, ".")
+
+	s.requireAttestError(makeAttestRequest(token),
+		"openstack: unable to verify token")
+}
+
+func (s *VendordataAttestorSuite) TestAttestFailsClaimValidation() {
+	// wrong audience
+	claims := s.validClaims()
+	claims.Audience = []string{"FOO"}
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"invalid audience claim")
+
+	// missing project id
+	claims = s.validClaims()
+	claims.ProjectID = ""
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"openstack: token missing project ID claim")
+
+	// missing instance id
+	claims = s.validClaims()
+	claims.InstanceID = ""
+	s.requireAttestError(s.signAttestRequest("KEYID", claims),
+		"openstack: token missing instance ID claim")
+}
+
+func (s *VendordataAttestorSuite) TestAttestTokenExpiration() {
+	req := s.signAttestRequest("KEYID", s.validClaims())
+
+	// within the 1m leeway (token expires at 5m + 1m leeway = 6m)
+	s.adjustTime(6 * time.Minute)
+	_, err := s.doAttest(req)
+	s.Require().NoError(err)
+
+	// just after the 1m leeway
+	s.adjustTime(time.Second)
+	s.requireAttestError(req, "token is expired")
+}
+
+func (s *VendordataAttestorSuite) TestAttestProjectIDWhitelist() {
+	s.configureAttestor(`project_id_whitelist = ["OTHERPROJECT"]`)
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		`openstack: project ID "PROJECTID" is not whitelisted`)
+
+	s.configureAttestor(`project_id_whitelist = ["OTHERPROJECT", "PROJECTID"]`)
+	_, err := s.doAttest(s.signAttestRequest("KEYID", s.validClaims()))
+	s.Require().NoError(err)
+}
+
+func (s *VendordataAttestorSuite) TestAttestIssuer() {
+	s.configureAttestor(`issuer = "https://vendordata.example.org"`)
+	s.requireAttestError(s.signAttestRequest("KEYID", s.validClaims()),
+		"invalid issuer claim")
+
+	claims := s.validClaims()
+	claims.Issuer = "https://vendordata.example.org"
+	_, err := s.doAttest(s.signAttestRequest("KEYID", claims))
+	s.Require().NoError(err)
+}
+
+func (s *VendordataAttestorSuite) TestAttestSuccess() {
+	resp, err := s.doAttest(s.signAttestRequest("KEYID", s.validClaims()))
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/openstack/PROJECTID/INSTANCEID", resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "openstack", Value: "project_id:PROJECTID"},
+		{Type: "openstack", Value: "instance_id:INSTANCEID"},
+		{Type: "openstack", Value: "flavor:m1.small"},
+	}, resp.Selectors)
+
+	// the flavor is left out of the selectors when not provided
+	claims := s.validClaims()
+	claims.Flavor = ""
+	resp, err = s.doAttest(s.signAttestRequest("KEYID", claims))
+	s.Require().NoError(err)
+	s.Require().Equal([]*common.Selector{
+		{Type: "openstack", Value: "project_id:PROJECTID"},
+		{Type: "openstack", Value: "instance_id:INSTANCEID"},
+	}, resp.Selectors)
+}
+
+func (s *VendordataAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+	})
+	s.requireErrorContains(err, "openstack: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	// missing global configuration
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "openstack: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "openstack: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// missing JWKS path
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "openstack: vendordata_jwks_path is required")
+	s.Require().Nil(resp)
+
+	// JWKS does not exist
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("vendordata_jwks_path = %q", filepath.Join(s.dir, "missing.json")),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.requireErrorContains(err, "openstack: unable to read vendordata JWKS")
+	s.Require().Nil(resp)
+
+	// malformed JWKS
+	malformedPath := filepath.Join(s.dir, "malformed.json")
+	s.Require().NoError(ioutil.WriteFile(malformedPath, []byte("{"), 0644))
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("vendordata_jwks_path = %q", malformedPath),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.requireErrorContains(err, "openstack: unable to parse vendordata JWKS")
+	s.Require().Nil(resp)
+
+	// empty JWKS
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("vendordata_jwks_path = %q", s.writeKeySet()),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().EqualError(err, "openstack: vendordata JWKS has no keys")
+	s.Require().Nil(resp)
+
+	// success with a custom audience
+	s.configureAttestor(`audience = "AUDIENCE"`)
+	claims := s.validClaims()
+	claims.Audience = []string{"AUDIENCE"}
+	_, err = s.doAttest(s.signAttestRequest("KEYID", claims))
+	s.Require().NoError(err)
+}
+
+func (s *VendordataAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *VendordataAttestorSuite) adjustTime(d time.Duration) {
+	s.now = s.now.Add(d)
+}
+
+func (s *VendordataAttestorSuite) validClaims() *openstack.VendordataClaims {
+	return &openstack.VendordataClaims{
+		Claims: jwt.Claims{
+			Audience:  []string{openstack.DefaultAudience},
+			NotBefore: jwt.NewNumericDate(s.now),
+			Expiry:    jwt.NewNumericDate(s.now.Add(5 * time.Minute)),
+		},
+		ProjectID:  "PROJECTID",
+		InstanceID: "INSTANCEID",
+		Flavor:     "m1.small",
+	}
+}
+
+func (s *VendordataAttestorSuite) signToken(keyID string, claims *openstack.VendordataClaims) string {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key: jose.JSONWebKey{
+			Key:   s.key,
+			KeyID: keyID,
+		},
+	}, nil)
+	s.Require().NoError(err)
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *VendordataAttestorSuite) signAttestRequest(keyID string, claims *openstack.VendordataClaims) *nodeattestor.AttestRequest {
+	return makeAttestRequest(s.signToken(keyID, claims))
+}
+
+func (s *VendordataAttestorSuite) writeKeySet(keys ...jose.JSONWebKey) string {
+	data, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
+	s.Require().NoError(err)
+	f, err := ioutil.TempFile(s.dir, "jwks")
+	s.Require().NoError(err)
+	defer f.Close()
+	_, err = f.Write(data)
+	s.Require().NoError(err)
+	return f.Name()
+}
+
+func (s *VendordataAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.now = func() time.Time {
+		return s.now
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *VendordataAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("vendordata_jwks_path = %q\n%s", s.jwksPath, config),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *VendordataAttestorSuite) doAttest(req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	return s.doAttestOnAttestor(s.attestor, req)
+}
+
+func (s *VendordataAttestorSuite) doAttestOnAttestor(attestor *nodeattestor.BuiltIn, req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	stream, err := attestor.Attest(context.Background())
+	s.Require().NoError(err)
+
+	err = stream.Send(req)
+	s.Require().NoError(err)
+
+	err = stream.CloseSend()
+	s.Require().NoError(err)
+
+	return stream.Recv()
+}
+
+func (s *VendordataAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	resp, err := s.doAttest(req)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *VendordataAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func makeAttestRequest(token string) *nodeattestor.AttestRequest {
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "openstack",
+			Data: []byte(fmt.Sprintf(`{"token": %q}`, token)),
+		},
+	}
+}
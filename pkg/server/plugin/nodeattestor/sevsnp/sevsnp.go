@@ -0,0 +1,224 @@
+package sevsnp
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/sevsnp"
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+)
+
+var (
+	sevsnpError = errs.Class("sev-snp")
+)
+
+type SEVSNPAttestorConfig struct {
+	// CABundlePath is the path to the AMD root (ARK) certificate(s)
+	CABundlePath string `hcl:"ca_bundle_path"`
+
+	// AllowDebug allows guests whose policy permits debugging to attest
+	AllowDebug bool `hcl:"allow_debug"`
+
+	// MeasurementWhitelist and ChipIDWhitelist, if set, restrict
+	// attestation to guests with the listed launch measurements running on
+	// the listed chips
+	MeasurementWhitelist []string `hcl:"measurement_whitelist"`
+	ChipIDWhitelist      []string `hcl:"chip_id_whitelist"`
+}
+
+type sevsnpAttestorConfig struct {
+	trustDomain  string
+	roots        *x509.CertPool
+	allowDebug   bool
+	measurements map[string]bool
+	chipIDs      map[string]bool
+}
+
+type SEVSNPAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *sevsnpAttestorConfig
+
+	hooks struct {
+		now               func() time.Time
+		generateChallenge func() (*sevsnp.Challenge, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*SEVSNPAttestorPlugin)(nil)
+
+func New() *SEVSNPAttestorPlugin {
+	p := &SEVSNPAttestorPlugin{}
+	p.hooks.now = time.Now
+	p.hooks.generateChallenge = sevsnp.GenerateChallenge
+	return p
+}
+
+func (p *SEVSNPAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return sevsnpError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestedBefore {
+		return sevsnpError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return sevsnpError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != sevsnp.PluginName {
+		return sevsnpError.New("unexpected attestation data type %q", dataType)
+	}
+
+	// challenge the agent to produce a fresh report
+	challenge, err := p.hooks.generateChallenge()
+	if err != nil {
+		return sevsnpError.New("unable to generate challenge: %v", err)
+	}
+
+	challengeBytes, err := json.Marshal(challenge)
+	if err != nil {
+		return sevsnpError.New("unable to marshal challenge: %v", err)
+	}
+
+	if err := stream.Send(&nodeattestor.AttestResponse{
+		Challenge: challengeBytes,
+	}); err != nil {
+		return err
+	}
+
+	responseReq, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	response := new(sevsnp.Response)
+	if err := json.Unmarshal(responseReq.Response, response); err != nil {
+		return sevsnpError.New("unable to unmarshal challenge response: %v", err)
+	}
+
+	report, err := sevsnp.VerifyReport(response.Report, response.Certificates, config.roots, p.hooks.now())
+	if err != nil {
+		return sevsnpError.New("report verification failed: %v", err)
+	}
+
+	if report.ReportData != sevsnp.ReportDataForNonce(challenge.Nonce) {
+		return sevsnpError.New("report data does not match challenge")
+	}
+
+	if report.Debug() && !config.allowDebug {
+		return sevsnpError.New("guest policy allows debugging")
+	}
+
+	if len(config.measurements) > 0 && !config.measurements[hex.EncodeToString(report.Measurement[:])] {
+		return sevsnpError.New("measurement %x is not whitelisted", report.Measurement)
+	}
+
+	if len(config.chipIDs) > 0 && !config.chipIDs[hex.EncodeToString(report.ChipID[:])] {
+		return sevsnpError.New("chip ID %x is not whitelisted", report.ChipID)
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: sevsnp.AgentID(config.trustDomain, report),
+		Selectors:    buildSelectors(report),
+	})
+}
+
+func (p *SEVSNPAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(SEVSNPAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, sevsnpError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, sevsnpError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, sevsnpError.New("global configuration missing trust domain")
+	}
+
+	if hclConfig.CABundlePath == "" {
+		return nil, sevsnpError.New("ca_bundle_path is required")
+	}
+	roots, err := util.LoadCertPool(hclConfig.CABundlePath)
+	if err != nil {
+		return nil, sevsnpError.New("unable to load trust bundle: %v", err)
+	}
+
+	measurements, err := parseHexValues("measurement", hclConfig.MeasurementWhitelist, 48)
+	if err != nil {
+		return nil, err
+	}
+	chipIDs, err := parseHexValues("chip ID", hclConfig.ChipIDWhitelist, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	p.setConfig(&sevsnpAttestorConfig{
+		trustDomain:  req.GlobalConfig.TrustDomain,
+		roots:        roots,
+		allowDebug:   hclConfig.AllowDebug,
+		measurements: measurements,
+		chipIDs:      chipIDs,
+	})
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *SEVSNPAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *SEVSNPAttestorPlugin) getConfig() (*sevsnpAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, sevsnpError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *SEVSNPAttestorPlugin) setConfig(config *sevsnpAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+// parseHexValues parses the hex encoded values, returning them normalized
+// to lower case
+func parseHexValues(kind string, values []string, size int) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, value := range values {
+		b, err := hex.DecodeString(value)
+		if err != nil || len(b) != size {
+			return nil, sevsnpError.New("invalid %s %q: expected %d hex encoded bytes", kind, value, size)
+		}
+		set[hex.EncodeToString(b)] = true
+	}
+	return set, nil
+}
+
+func buildSelectors(report *sevsnp.Report) []*common.Selector {
+	var selectors []*common.Selector
+	for _, value := range report.SelectorValues() {
+		selectors = append(selectors, &common.Selector{
+			Type:  sevsnp.PluginName,
+			Value: value,
+		})
+	}
+	return selectors
+}
@@ -0,0 +1,300 @@
+package sevsnp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/sevsnp"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/spiffe/spire/test/fakes/fakesevsnp"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	measurement = bytes.Repeat([]byte{0x01}, 48)
+	reportID    = bytes.Repeat([]byte{0x02}, 32)
+)
+
+func TestSEVSNPAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(SEVSNPAttestorSuite))
+}
+
+type SEVSNPAttestorSuite struct {
+	suite.Suite
+
+	dir      string
+	sp       *fakesevsnp.SecureProcessor
+	attestor *nodeattestor.BuiltIn
+	now      time.Time
+	nonce    []byte
+}
+
+func (s *SEVSNPAttestorSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "sevsnp-test")
+	s.Require().NoError(err)
+
+	s.sp = fakesevsnp.New(s.T())
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, "ark.pem"), pemutil.EncodeCertificate(s.sp.ARK), 0644))
+
+	s.now = time.Now()
+	s.nonce = []byte("NONCE")
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *SEVSNPAttestorSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *SEVSNPAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	stream, err := s.newAttestor().Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{}))
+	_, err = stream.Recv()
+	s.Require().EqualError(err, "sev-snp: not configured")
+}
+
+func (s *SEVSNPAttestorSuite) TestAttestFailsWithBadAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{AttestedBefore: true},
+		"sev-snp: node has already attested")
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"sev-snp: missing attestation data")
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{Type: "blah"},
+	}, `sev-snp: unexpected attestation data type "blah"`)
+}
+
+func (s *SEVSNPAttestorSuite) TestAttestFailsWithBadResponse() {
+	// malformed challenge response
+	s.requireChallengeResponseError([]byte("{"),
+		"sev-snp: unable to unmarshal challenge response")
+
+	// malformed report
+	s.requireChallengeResponseError(s.makeResponse([]byte("blah"), s.sp.Certificates()),
+		"sev-snp: report verification failed: unexpected report size 4")
+
+	// missing certificates
+	s.requireChallengeResponseError(s.makeResponse(s.sp.Report(s.report()), nil),
+		"sev-snp: report verification failed: missing VCEK certificate")
+
+	// untrusted VCEK
+	untrusted := fakesevsnp.New(s.T())
+	s.requireChallengeResponseError(s.makeResponse(untrusted.Report(s.report()), untrusted.Certificates()),
+		"sev-snp: report verification failed: VCEK certificate verification failed")
+
+	// expired VCEK
+	s.now = s.now.Add(2 * time.Hour)
+	s.requireChallengeResponseError(s.makeResponse(s.sp.Report(s.report()), s.sp.Certificates()),
+		"sev-snp: report verification failed: VCEK certificate verification failed")
+	s.now = time.Now()
+
+	// VCEK issued for another chip
+	report := s.report()
+	report.ChipID[0] ^= 0xff
+	s.requireChallengeResponseError(s.makeResponse(s.sp.Report(report), s.sp.Certificates()),
+		"sev-snp: report verification failed: VCEK certificate was not issued for chip")
+
+	// tampered report
+	reportBytes := s.sp.Report(s.report())
+	reportBytes[0x90] ^= 0xff
+	s.requireChallengeResponseError(s.makeResponse(reportBytes, s.sp.Certificates()),
+		"sev-snp: report verification failed: report signature verification failed")
+
+	// report data does not bind the nonce
+	report = s.report()
+	report.ReportData = sevsnp.ReportDataForNonce([]byte("OTHER"))
+	s.requireChallengeResponseError(s.makeResponse(s.sp.Report(report), s.sp.Certificates()),
+		"sev-snp: report data does not match challenge")
+
+	// debuggable guest
+	report = s.report()
+	report.Policy |= 1 << 19
+	s.requireChallengeResponseError(s.makeResponse(s.sp.Report(report), s.sp.Certificates()),
+		"sev-snp: guest policy allows debugging")
+}
+
+func (s *SEVSNPAttestorSuite) TestAttestWithWhitelists() {
+	s.configureAttestor(fmt.Sprintf(`measurement_whitelist = ["%x"]`, bytes.Repeat([]byte{0xff}, 48)))
+	s.requireChallengeResponseError(s.makeResponse(s.sp.Report(s.report()), s.sp.Certificates()),
+		fmt.Sprintf("sev-snp: measurement %x is not whitelisted", measurement))
+
+	s.configureAttestor(fmt.Sprintf(`chip_id_whitelist = ["%x"]`, bytes.Repeat([]byte{0xff}, 64)))
+	s.requireChallengeResponseError(s.makeResponse(s.sp.Report(s.report()), s.sp.Certificates()),
+		fmt.Sprintf("sev-snp: chip ID %x is not whitelisted", s.sp.ChipID))
+
+	s.configureAttestor(fmt.Sprintf(`
+	measurement_whitelist = ["%X"]
+	chip_id_whitelist = ["%x"]
+	`, measurement, s.sp.ChipID))
+	resp, err := s.doAttest(s.makeResponse(s.sp.Report(s.report()), s.sp.Certificates()))
+	s.Require().NoError(err)
+	s.Require().True(resp.Valid)
+}
+
+func (s *SEVSNPAttestorSuite) TestAttestSuccess() {
+	resp, err := s.doAttest(s.makeResponse(s.sp.Report(s.report()), s.sp.Certificates()))
+	s.Require().NoError(err)
+	s.Require().True(resp.Valid)
+	s.Require().Equal(fmt.Sprintf("spiffe://example.org/spire/agent/sev_snp/%x", reportID), resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "sev_snp", Value: fmt.Sprintf("measurement:%x", measurement)},
+		{Type: "sev_snp", Value: "policy:0000000000030000"},
+		{Type: "sev_snp", Value: fmt.Sprintf("chip_id:%x", s.sp.ChipID)},
+		{Type: "sev_snp", Value: "guest_svn:4"},
+		{Type: "sev_snp", Value: "vmpl:0"},
+	}, resp.Selectors)
+
+	// debuggable guests are accepted when allowed
+	s.configureAttestor("allow_debug = true")
+	report := s.report()
+	report.Policy |= 1 << 19
+	resp, err = s.doAttest(s.makeResponse(s.sp.Report(report), s.sp.Certificates()))
+	s.Require().NoError(err)
+	s.Require().True(resp.Valid)
+}
+
+func (s *SEVSNPAttestorSuite) TestConfigure() {
+	configure := func(config string, globalConfig *plugin.ConfigureRequest_GlobalConfig) error {
+		resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+			Configuration: config,
+			GlobalConfig:  globalConfig,
+		})
+		if err != nil {
+			s.Require().Nil(resp)
+		}
+		return err
+	}
+	globalConfig := &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"}
+	caBundlePath := fmt.Sprintf("ca_bundle_path = %q\n", filepath.Join(s.dir, "ark.pem"))
+
+	err := configure("blah", globalConfig)
+	s.requireErrorContains(err, "sev-snp: unable to decode configuration")
+
+	err = configure(caBundlePath, nil)
+	s.Require().EqualError(err, "sev-snp: global configuration is required")
+
+	err = configure(caBundlePath, &plugin.ConfigureRequest_GlobalConfig{})
+	s.Require().EqualError(err, "sev-snp: global configuration missing trust domain")
+
+	err = configure("", globalConfig)
+	s.Require().EqualError(err, "sev-snp: ca_bundle_path is required")
+
+	err = configure(`ca_bundle_path = "blah"`, globalConfig)
+	s.requireErrorContains(err, "sev-snp: unable to load trust bundle")
+
+	err = configure(caBundlePath+`measurement_whitelist = ["00"]`, globalConfig)
+	s.Require().EqualError(err, `sev-snp: invalid measurement "00": expected 48 hex encoded bytes`)
+
+	err = configure(caBundlePath+`chip_id_whitelist = ["zz"]`, globalConfig)
+	s.Require().EqualError(err, `sev-snp: invalid chip ID "zz": expected 64 hex encoded bytes`)
+
+	err = configure(caBundlePath, globalConfig)
+	s.Require().NoError(err)
+}
+
+func (s *SEVSNPAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *SEVSNPAttestorSuite) report() sevsnp.Report {
+	report := sevsnp.Report{
+		GuestSVN:   4,
+		Policy:     0x30000,
+		ReportData: sevsnp.ReportDataForNonce(s.nonce),
+	}
+	copy(report.Measurement[:], measurement)
+	copy(report.ReportID[:], reportID)
+	return report
+}
+
+func (s *SEVSNPAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.now = func() time.Time {
+		return s.now
+	}
+	attestor.hooks.generateChallenge = func() (*sevsnp.Challenge, error) {
+		return &sevsnp.Challenge{Nonce: s.nonce}, nil
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *SEVSNPAttestorSuite) configureAttestor(extra string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("ca_bundle_path = %q\n%s", filepath.Join(s.dir, "ark.pem"), extra),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+// doAttest performs the challenge/response exchange, answering the
+// challenge with the given response
+func (s *SEVSNPAttestorSuite) doAttest(response []byte) (*nodeattestor.AttestResponse, error) {
+	stream, err := s.attestor.Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "sev_snp",
+			Data: []byte("{}"),
+		},
+	}))
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	challenge := new(sevsnp.Challenge)
+	s.Require().NoError(json.Unmarshal(resp.Challenge, challenge))
+	s.Require().Equal(s.nonce, challenge.Nonce)
+
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{
+		Response: response,
+	}))
+	return stream.Recv()
+}
+
+func (s *SEVSNPAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	stream, err := s.attestor.Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+
+	s.Require().NoError(stream.Send(req))
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *SEVSNPAttestorSuite) requireChallengeResponseError(response []byte, contains string) {
+	resp, err := s.doAttest(response)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *SEVSNPAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func (s *SEVSNPAttestorSuite) makeResponse(report []byte, certificates [][]byte) []byte {
+	data, err := json.Marshal(sevsnp.Response{
+		Report:       report,
+		Certificates: certificates,
+	})
+	s.Require().NoError(err)
+	return data
+}
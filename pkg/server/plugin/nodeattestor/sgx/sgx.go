@@ -0,0 +1,223 @@
+package sgx
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/sgx"
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+)
+
+var (
+	sgxError = errs.Class("sgx-dcap")
+)
+
+type SGXAttestorConfig struct {
+	// CABundlePath is the path to the Intel SGX root CA certificate(s)
+	CABundlePath string `hcl:"ca_bundle_path"`
+
+	// AllowDebug allows enclaves launched in debug mode to attest
+	AllowDebug bool `hcl:"allow_debug"`
+
+	// MRENCLAVEWhitelist and MRSIGNERWhitelist, if set, restrict
+	// attestation to enclaves with the listed measurements
+	MRENCLAVEWhitelist []string `hcl:"mrenclave_whitelist"`
+	MRSIGNERWhitelist  []string `hcl:"mrsigner_whitelist"`
+}
+
+type sgxAttestorConfig struct {
+	trustDomain string
+	roots       *x509.CertPool
+	allowDebug  bool
+	mrenclaves  map[[32]byte]bool
+	mrsigners   map[[32]byte]bool
+}
+
+type SGXAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *sgxAttestorConfig
+
+	hooks struct {
+		now               func() time.Time
+		generateChallenge func() (*sgx.Challenge, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*SGXAttestorPlugin)(nil)
+
+func New() *SGXAttestorPlugin {
+	p := &SGXAttestorPlugin{}
+	p.hooks.now = time.Now
+	p.hooks.generateChallenge = sgx.GenerateChallenge
+	return p
+}
+
+func (p *SGXAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return sgxError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestedBefore {
+		return sgxError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return sgxError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != sgx.PluginName {
+		return sgxError.New("unexpected attestation data type %q", dataType)
+	}
+
+	// challenge the agent to produce a fresh quote
+	challenge, err := p.hooks.generateChallenge()
+	if err != nil {
+		return sgxError.New("unable to generate challenge: %v", err)
+	}
+
+	challengeBytes, err := json.Marshal(challenge)
+	if err != nil {
+		return sgxError.New("unable to marshal challenge: %v", err)
+	}
+
+	if err := stream.Send(&nodeattestor.AttestResponse{
+		Challenge: challengeBytes,
+	}); err != nil {
+		return err
+	}
+
+	responseReq, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	response := new(sgx.Response)
+	if err := json.Unmarshal(responseReq.Response, response); err != nil {
+		return sgxError.New("unable to unmarshal challenge response: %v", err)
+	}
+
+	quote, err := sgx.VerifyQuote(response.Quote, config.roots, p.hooks.now())
+	if err != nil {
+		return sgxError.New("quote verification failed: %v", err)
+	}
+
+	if quote.Report.ReportData != sgx.ReportDataForNonce(challenge.Nonce) {
+		return sgxError.New("quote report data does not match challenge")
+	}
+
+	if quote.Report.Debug() && !config.allowDebug {
+		return sgxError.New("debug enclaves are not allowed")
+	}
+
+	if len(config.mrenclaves) > 0 && !config.mrenclaves[quote.Report.MRENCLAVE] {
+		return sgxError.New("MRENCLAVE %x is not whitelisted", quote.Report.MRENCLAVE)
+	}
+
+	if len(config.mrsigners) > 0 && !config.mrsigners[quote.Report.MRSIGNER] {
+		return sgxError.New("MRSIGNER %x is not whitelisted", quote.Report.MRSIGNER)
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: sgx.AgentID(config.trustDomain, quote),
+		Selectors:    buildSelectors(&quote.Report),
+	})
+}
+
+func (p *SGXAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(SGXAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, sgxError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, sgxError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, sgxError.New("global configuration missing trust domain")
+	}
+
+	if hclConfig.CABundlePath == "" {
+		return nil, sgxError.New("ca_bundle_path is required")
+	}
+	roots, err := util.LoadCertPool(hclConfig.CABundlePath)
+	if err != nil {
+		return nil, sgxError.New("unable to load trust bundle: %v", err)
+	}
+
+	mrenclaves, err := parseMeasurements("MRENCLAVE", hclConfig.MRENCLAVEWhitelist)
+	if err != nil {
+		return nil, err
+	}
+	mrsigners, err := parseMeasurements("MRSIGNER", hclConfig.MRSIGNERWhitelist)
+	if err != nil {
+		return nil, err
+	}
+
+	p.setConfig(&sgxAttestorConfig{
+		trustDomain: req.GlobalConfig.TrustDomain,
+		roots:       roots,
+		allowDebug:  hclConfig.AllowDebug,
+		mrenclaves:  mrenclaves,
+		mrsigners:   mrsigners,
+	})
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *SGXAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *SGXAttestorPlugin) getConfig() (*sgxAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, sgxError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *SGXAttestorPlugin) setConfig(config *sgxAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func parseMeasurements(kind string, values []string) (map[[32]byte]bool, error) {
+	measurements := make(map[[32]byte]bool)
+	for _, value := range values {
+		b, err := hex.DecodeString(value)
+		if err != nil || len(b) != 32 {
+			return nil, sgxError.New("invalid %s %q: expected 32 hex encoded bytes", kind, value)
+		}
+		var measurement [32]byte
+		copy(measurement[:], b)
+		measurements[measurement] = true
+	}
+	return measurements, nil
+}
+
+func buildSelectors(report *sgx.ReportBody) []*common.Selector {
+	var selectors []*common.Selector
+	for _, value := range report.SelectorValues() {
+		selectors = append(selectors, &common.Selector{
+			Type:  sgx.PluginName,
+			Value: value,
+		})
+	}
+	return selectors
+}
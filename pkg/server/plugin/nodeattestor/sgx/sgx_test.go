@@ -0,0 +1,299 @@
+package sgx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/sgx"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/spiffe/spire/test/fakes/fakesgx"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	mrenclave = bytes.Repeat([]byte{0x01}, 32)
+	mrsigner  = bytes.Repeat([]byte{0x02}, 32)
+)
+
+func TestSGXAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(SGXAttestorSuite))
+}
+
+type SGXAttestorSuite struct {
+	suite.Suite
+
+	dir      string
+	qe       *fakesgx.QuotingEnclave
+	attestor *nodeattestor.BuiltIn
+	now      time.Time
+	nonce    []byte
+}
+
+func (s *SGXAttestorSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "sgx-test")
+	s.Require().NoError(err)
+
+	s.qe = fakesgx.New(s.T())
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, "root.pem"), pemutil.EncodeCertificate(s.qe.Root), 0644))
+
+	s.now = time.Now()
+	s.nonce = []byte("NONCE")
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *SGXAttestorSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *SGXAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	stream, err := s.newAttestor().Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{}))
+	_, err = stream.Recv()
+	s.Require().EqualError(err, "sgx-dcap: not configured")
+}
+
+func (s *SGXAttestorSuite) TestAttestFailsWithBadAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{AttestedBefore: true},
+		"sgx-dcap: node has already attested")
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"sgx-dcap: missing attestation data")
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{Type: "blah"},
+	}, `sgx-dcap: unexpected attestation data type "blah"`)
+}
+
+func (s *SGXAttestorSuite) TestAttestFailsWithBadResponse() {
+	// malformed challenge response
+	s.requireChallengeResponseError([]byte("{"),
+		"sgx-dcap: unable to unmarshal challenge response")
+
+	// malformed quote
+	s.requireChallengeResponseError(makeResponse([]byte("blah")),
+		"sgx-dcap: quote verification failed: quote too short")
+
+	// untrusted PCK certificate
+	untrusted := fakesgx.New(s.T())
+	s.requireChallengeResponseError(makeResponse(untrusted.Quote(s.report())),
+		"sgx-dcap: quote verification failed: PCK certificate verification failed")
+
+	// expired PCK certificate
+	s.now = s.now.Add(2 * time.Hour)
+	s.requireChallengeResponseError(makeResponse(s.qe.Quote(s.report())),
+		"sgx-dcap: quote verification failed: PCK certificate verification failed")
+	s.now = time.Now()
+
+	// tampered enclave report
+	quote := s.qe.Quote(s.report())
+	quote[sgx.HeaderSize+64] ^= 0xff
+	s.requireChallengeResponseError(makeResponse(quote),
+		"sgx-dcap: quote verification failed: quote signature verification failed")
+
+	// tampered QE report
+	quote = s.qe.Quote(s.report())
+	quote[sgx.HeaderSize+sgx.ReportBodySize+4+sgx.SignatureSize+sgx.PublicKeySize] ^= 0xff
+	s.requireChallengeResponseError(makeResponse(quote),
+		"sgx-dcap: quote verification failed: QE report signature verification failed")
+
+	// tampered attestation key
+	quote = s.qe.Quote(s.report())
+	quote[sgx.HeaderSize+sgx.ReportBodySize+4+sgx.SignatureSize] ^= 0xff
+	s.requireChallengeResponseError(makeResponse(quote),
+		"sgx-dcap: quote verification failed: QE report does not bind the attestation key")
+
+	// report data does not bind the nonce
+	report := s.report()
+	report.ReportData = sgx.ReportDataForNonce([]byte("OTHER"))
+	s.requireChallengeResponseError(makeResponse(s.qe.Quote(report)),
+		"sgx-dcap: quote report data does not match challenge")
+
+	// debug enclave
+	report = s.report()
+	report.Attributes[0] |= 0x02
+	s.requireChallengeResponseError(makeResponse(s.qe.Quote(report)),
+		"sgx-dcap: debug enclaves are not allowed")
+}
+
+func (s *SGXAttestorSuite) TestAttestWithWhitelists() {
+	other := bytes.Repeat([]byte{0xff}, 32)
+
+	s.configureAttestor(fmt.Sprintf(`mrenclave_whitelist = ["%x"]`, other))
+	s.requireChallengeResponseError(makeResponse(s.qe.Quote(s.report())),
+		fmt.Sprintf("sgx-dcap: MRENCLAVE %x is not whitelisted", mrenclave))
+
+	s.configureAttestor(fmt.Sprintf(`mrsigner_whitelist = ["%x"]`, other))
+	s.requireChallengeResponseError(makeResponse(s.qe.Quote(s.report())),
+		fmt.Sprintf("sgx-dcap: MRSIGNER %x is not whitelisted", mrsigner))
+
+	s.configureAttestor(fmt.Sprintf(`
+	mrenclave_whitelist = ["%x", "%x"]
+	mrsigner_whitelist = ["%x"]
+	`, other, mrenclave, mrsigner))
+	resp, err := s.doAttest(makeResponse(s.qe.Quote(s.report())))
+	s.Require().NoError(err)
+	s.Require().True(resp.Valid)
+}
+
+func (s *SGXAttestorSuite) TestAttestSuccess() {
+	resp, err := s.doAttest(makeResponse(s.qe.Quote(s.report())))
+	s.Require().NoError(err)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/sgx_dcap/"+sgx.AttestationKeyFingerprint(s.qe.AttestationKey()), resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "sgx_dcap", Value: fmt.Sprintf("mrenclave:%x", mrenclave)},
+		{Type: "sgx_dcap", Value: fmt.Sprintf("mrsigner:%x", mrsigner)},
+		{Type: "sgx_dcap", Value: "isv_prod_id:7"},
+		{Type: "sgx_dcap", Value: "isv_svn:3"},
+	}, resp.Selectors)
+
+	// debug enclaves are accepted when allowed
+	s.configureAttestor("allow_debug = true")
+	report := s.report()
+	report.Attributes[0] |= 0x02
+	resp, err = s.doAttest(makeResponse(s.qe.Quote(report)))
+	s.Require().NoError(err)
+	s.Require().True(resp.Valid)
+}
+
+func (s *SGXAttestorSuite) TestConfigure() {
+	configure := func(config string, globalConfig *plugin.ConfigureRequest_GlobalConfig) error {
+		resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+			Configuration: config,
+			GlobalConfig:  globalConfig,
+		})
+		if err != nil {
+			s.Require().Nil(resp)
+		}
+		return err
+	}
+	globalConfig := &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"}
+	caBundlePath := fmt.Sprintf("ca_bundle_path = %q\n", filepath.Join(s.dir, "root.pem"))
+
+	err := configure("blah", globalConfig)
+	s.requireErrorContains(err, "sgx-dcap: unable to decode configuration")
+
+	err = configure(caBundlePath, nil)
+	s.Require().EqualError(err, "sgx-dcap: global configuration is required")
+
+	err = configure(caBundlePath, &plugin.ConfigureRequest_GlobalConfig{})
+	s.Require().EqualError(err, "sgx-dcap: global configuration missing trust domain")
+
+	err = configure("", globalConfig)
+	s.Require().EqualError(err, "sgx-dcap: ca_bundle_path is required")
+
+	err = configure(`ca_bundle_path = "blah"`, globalConfig)
+	s.requireErrorContains(err, "sgx-dcap: unable to load trust bundle")
+
+	err = configure(caBundlePath+`mrenclave_whitelist = ["00"]`, globalConfig)
+	s.Require().EqualError(err, `sgx-dcap: invalid MRENCLAVE "00": expected 32 hex encoded bytes`)
+
+	err = configure(caBundlePath+`mrsigner_whitelist = ["zz"]`, globalConfig)
+	s.Require().EqualError(err, `sgx-dcap: invalid MRSIGNER "zz": expected 32 hex encoded bytes`)
+
+	err = configure(caBundlePath, globalConfig)
+	s.Require().NoError(err)
+}
+
+func (s *SGXAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *SGXAttestorSuite) report() sgx.ReportBody {
+	report := sgx.ReportBody{
+		ISVProdID:  7,
+		ISVSVN:     3,
+		ReportData: sgx.ReportDataForNonce(s.nonce),
+	}
+	copy(report.MRENCLAVE[:], mrenclave)
+	copy(report.MRSIGNER[:], mrsigner)
+	return report
+}
+
+func (s *SGXAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.now = func() time.Time {
+		return s.now
+	}
+	attestor.hooks.generateChallenge = func() (*sgx.Challenge, error) {
+		return &sgx.Challenge{Nonce: s.nonce}, nil
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *SGXAttestorSuite) configureAttestor(extra string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf("ca_bundle_path = %q\n%s", filepath.Join(s.dir, "root.pem"), extra),
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+// doAttest performs the challenge/response exchange, answering the
+// challenge with the given response
+func (s *SGXAttestorSuite) doAttest(response []byte) (*nodeattestor.AttestResponse, error) {
+	stream, err := s.attestor.Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "sgx_dcap",
+			Data: []byte("{}"),
+		},
+	}))
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	challenge := new(sgx.Challenge)
+	s.Require().NoError(json.Unmarshal(resp.Challenge, challenge))
+	s.Require().Equal(s.nonce, challenge.Nonce)
+
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{
+		Response: response,
+	}))
+	return stream.Recv()
+}
+
+func (s *SGXAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	stream, err := s.attestor.Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+
+	s.Require().NoError(stream.Send(req))
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *SGXAttestorSuite) requireChallengeResponseError(response []byte, contains string) {
+	resp, err := s.doAttest(response)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *SGXAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func makeResponse(quote []byte) []byte {
+	data, _ := json.Marshal(sgx.Response{Quote: quote})
+	return data
+}
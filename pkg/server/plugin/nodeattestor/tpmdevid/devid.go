@@ -0,0 +1,237 @@
+package tpmdevid
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/tpmdevid"
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+)
+
+var (
+	devidError = errs.Class("tpm-devid")
+)
+
+type DevIDAttestorConfig struct {
+	// DevIDCAPath is the path to the CA certificate(s) trusted to issue
+	// DevID certificates
+	DevIDCAPath string `hcl:"devid_ca_path"`
+
+	// PCRSelectors enables the tpm_devid:pcr selectors. They are opt-in
+	// since they are only trustworthy if the DevID key is restricted.
+	PCRSelectors bool `hcl:"pcr_selectors"`
+}
+
+type devidAttestorConfig struct {
+	trustDomain  string
+	roots        *x509.CertPool
+	pcrSelectors bool
+}
+
+type DevIDAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *devidAttestorConfig
+
+	hooks struct {
+		now               func() time.Time
+		generateChallenge func() (*tpmdevid.Challenge, error)
+	}
+}
+
+var _ nodeattestor.Plugin = (*DevIDAttestorPlugin)(nil)
+
+func New() *DevIDAttestorPlugin {
+	p := &DevIDAttestorPlugin{}
+	p.hooks.now = time.Now
+	p.hooks.generateChallenge = tpmdevid.GenerateChallenge
+	return p
+}
+
+func (p *DevIDAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return devidError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestedBefore {
+		return devidError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return devidError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != tpmdevid.PluginName {
+		return devidError.New("unexpected attestation data type %q", dataType)
+	}
+
+	attestationData := new(tpmdevid.AttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return devidError.New("unable to unmarshal attestation data: %v", err)
+	}
+
+	// build up the DevID certificate and list of intermediates
+	if len(attestationData.Certificates) == 0 {
+		return devidError.New("missing DevID certificate")
+	}
+	devID, err := x509.ParseCertificate(attestationData.Certificates[0])
+	if err != nil {
+		return devidError.New("unable to parse DevID certificate: %v", err)
+	}
+	intermediates := x509.NewCertPool()
+	for i, intermediateBytes := range attestationData.Certificates[1:] {
+		intermediate, err := x509.ParseCertificate(intermediateBytes)
+		if err != nil {
+			return devidError.New("unable to parse intermediate certificate %d: %v", i, err)
+		}
+		intermediates.AddCert(intermediate)
+	}
+
+	chains, err := devID.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         config.roots,
+		CurrentTime:   p.hooks.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return devidError.New("DevID certificate verification failed: %v", err)
+	}
+
+	// now that the DevID certificate is trusted, challenge the node to
+	// quote its PCRs with the DevID key
+	challenge, err := p.hooks.generateChallenge()
+	if err != nil {
+		return devidError.New("unable to generate challenge: %v", err)
+	}
+
+	challengeBytes, err := json.Marshal(challenge)
+	if err != nil {
+		return devidError.New("unable to marshal challenge: %v", err)
+	}
+
+	if err := stream.Send(&nodeattestor.AttestResponse{
+		Challenge: challengeBytes,
+	}); err != nil {
+		return err
+	}
+
+	responseReq, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	response := new(tpmdevid.Response)
+	if err := json.Unmarshal(responseReq.Response, response); err != nil {
+		return devidError.New("unable to unmarshal challenge response: %v", err)
+	}
+
+	if err := tpmdevid.VerifyQuote(devID.PublicKey, response, challenge.Nonce); err != nil {
+		return devidError.New("quote verification failed: %v", err)
+	}
+
+	var pcrs map[int][]byte
+	if config.pcrSelectors {
+		pcrs = response.PCRs
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: tpmdevid.AgentID(config.trustDomain, devID),
+		Selectors:    buildSelectors(devID, chains, pcrs),
+	})
+}
+
+func (p *DevIDAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(DevIDAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, devidError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, devidError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, devidError.New("global configuration missing trust domain")
+	}
+
+	if hclConfig.DevIDCAPath == "" {
+		return nil, devidError.New("devid_ca_path is required")
+	}
+	roots, err := util.LoadCertPool(hclConfig.DevIDCAPath)
+	if err != nil {
+		return nil, devidError.New("unable to load DevID CA certificates: %v", err)
+	}
+
+	p.setConfig(&devidAttestorConfig{
+		trustDomain:  req.GlobalConfig.TrustDomain,
+		roots:        roots,
+		pcrSelectors: hclConfig.PCRSelectors,
+	})
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *DevIDAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *DevIDAttestorPlugin) getConfig() (*devidAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, devidError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *DevIDAttestorPlugin) setConfig(config *devidAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func buildSelectors(devID *x509.Certificate, chains [][]*x509.Certificate, pcrs map[int][]byte) []*common.Selector {
+	selectors := []*common.Selector{}
+
+	if devID.Subject.CommonName != "" {
+		selectors = append(selectors, &common.Selector{
+			Type: tpmdevid.PluginName, Value: "subject:cn:" + devID.Subject.CommonName,
+		})
+	}
+
+	// Used to avoid duplicating selectors.
+	fingerprints := map[string]bool{}
+	for _, chain := range chains {
+		// skip the DevID certificate at the 0 index
+		for _, cert := range chain[1:] {
+			fp := tpmdevid.Fingerprint(cert)
+			if fingerprints[fp] {
+				continue
+			}
+			fingerprints[fp] = true
+
+			selectors = append(selectors, &common.Selector{
+				Type: tpmdevid.PluginName, Value: "ca:fingerprint:" + fp,
+			})
+		}
+	}
+
+	for _, value := range tpmdevid.PCRSelectorValues(pcrs) {
+		selectors = append(selectors, &common.Selector{
+			Type: tpmdevid.PluginName, Value: value,
+		})
+	}
+
+	return selectors
+}
@@ -0,0 +1,290 @@
+package tpmdevid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/tpmdevid"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/spiffe/spire/test/fakes/fakedevid"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	pcrs = map[int][]byte{
+		0: bytes.Repeat([]byte{0x00}, 32),
+		7: bytes.Repeat([]byte{0x07}, 32),
+	}
+)
+
+func TestDevIDAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(DevIDAttestorSuite))
+}
+
+type DevIDAttestorSuite struct {
+	suite.Suite
+
+	dir      string
+	tpm      *fakedevid.TPM
+	attestor *nodeattestor.BuiltIn
+	now      time.Time
+	nonce    []byte
+}
+
+func (s *DevIDAttestorSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "tpmdevid-test")
+	s.Require().NoError(err)
+
+	s.tpm = fakedevid.New(s.T())
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, "ca.pem"), pemutil.EncodeCertificate(s.tpm.Root), 0644))
+
+	s.now = time.Now()
+	s.nonce = []byte("NONCE")
+	s.attestor = s.newAttestor()
+	s.configureAttestor()
+}
+
+func (s *DevIDAttestorSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *DevIDAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	stream, err := s.newAttestor().Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{}))
+	_, err = stream.Recv()
+	s.Require().EqualError(err, "tpm-devid: not configured")
+}
+
+func (s *DevIDAttestorSuite) TestAttestFailsWithBadAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{AttestedBefore: true},
+		"tpm-devid: node has already attested")
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"tpm-devid: missing attestation data")
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{Type: "blah"},
+	}, `tpm-devid: unexpected attestation data type "blah"`)
+	s.requireAttestError(s.attestRequest([]byte("{")),
+		"tpm-devid: unable to unmarshal attestation data")
+	s.requireAttestError(s.attestRequest(s.marshal(tpmdevid.AttestationData{})),
+		"tpm-devid: missing DevID certificate")
+	s.requireAttestError(s.attestRequest(s.marshal(tpmdevid.AttestationData{
+		Certificates: [][]byte{[]byte("blah")},
+	})), "tpm-devid: unable to parse DevID certificate")
+	s.requireAttestError(s.attestRequest(s.marshal(tpmdevid.AttestationData{
+		Certificates: [][]byte{s.tpm.DevID.Raw, []byte("blah")},
+	})), "tpm-devid: unable to parse intermediate certificate 0")
+
+	// missing intermediate
+	s.requireAttestError(s.attestRequest(s.marshal(tpmdevid.AttestationData{
+		Certificates: [][]byte{s.tpm.DevID.Raw},
+	})), "tpm-devid: DevID certificate verification failed")
+
+	// untrusted
+	untrusted := fakedevid.New(s.T())
+	s.requireAttestError(s.attestRequest(s.marshal(tpmdevid.AttestationData{
+		Certificates: untrusted.Certificates(),
+	})), "tpm-devid: DevID certificate verification failed")
+
+	// expired
+	s.now = s.now.Add(2 * time.Hour)
+	s.requireAttestError(s.attestRequest(s.marshal(tpmdevid.AttestationData{
+		Certificates: s.tpm.Certificates(),
+	})), "tpm-devid: DevID certificate verification failed")
+}
+
+func (s *DevIDAttestorSuite) TestAttestFailsWithBadResponse() {
+	s.requireChallengeResponseError([]byte("{"),
+		"tpm-devid: unable to unmarshal challenge response")
+
+	// quote does not include the nonce
+	quote, sig := s.tpm.Quote([]byte("OTHER"), pcrs)
+	s.requireChallengeResponseError(s.makeResponse(quote, s.encodeSignature(sig), pcrs),
+		"tpm-devid: quote verification failed: quote does not include the challenge nonce")
+
+	// quote signed by another key
+	other := fakedevid.New(s.T())
+	quote, sig = other.Quote(s.nonce, pcrs)
+	s.requireChallengeResponseError(s.makeResponse(quote, s.encodeSignature(sig), pcrs),
+		"tpm-devid: quote verification failed: quote signature verification failed")
+}
+
+func (s *DevIDAttestorSuite) TestAttestSuccess() {
+	quote, sig := s.tpm.Quote(s.nonce, pcrs)
+	resp, err := s.doAttest(s.makeResponse(quote, s.encodeSignature(sig), pcrs))
+	s.Require().NoError(err)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/tpm_devid/"+tpmdevid.Fingerprint(s.tpm.DevID), resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "tpm_devid", Value: "subject:cn:FAKEDEVID"},
+		{Type: "tpm_devid", Value: "ca:fingerprint:" + tpmdevid.Fingerprint(s.tpm.Intermediate)},
+		{Type: "tpm_devid", Value: "ca:fingerprint:" + tpmdevid.Fingerprint(s.tpm.Root)},
+	}, resp.Selectors)
+}
+
+func (s *DevIDAttestorSuite) TestAttestSuccessWithPCRSelectors() {
+	s.configureAttestor("pcr_selectors = true")
+
+	quote, sig := s.tpm.Quote(s.nonce, pcrs)
+	resp, err := s.doAttest(s.makeResponse(quote, s.encodeSignature(sig), pcrs))
+	s.Require().NoError(err)
+	s.Require().True(resp.Valid)
+	s.Require().Equal([]*common.Selector{
+		{Type: "tpm_devid", Value: "subject:cn:FAKEDEVID"},
+		{Type: "tpm_devid", Value: "ca:fingerprint:" + tpmdevid.Fingerprint(s.tpm.Intermediate)},
+		{Type: "tpm_devid", Value: "ca:fingerprint:" + tpmdevid.Fingerprint(s.tpm.Root)},
+		{Type: "tpm_devid", Value: fmt.Sprintf("pcr:0:%x", pcrs[0])},
+		{Type: "tpm_devid", Value: fmt.Sprintf("pcr:7:%x", pcrs[7])},
+	}, resp.Selectors)
+}
+
+func (s *DevIDAttestorSuite) TestConfigure() {
+	configure := func(config string, globalConfig *plugin.ConfigureRequest_GlobalConfig) error {
+		resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+			Configuration: config,
+			GlobalConfig:  globalConfig,
+		})
+		if err != nil {
+			s.Require().Nil(resp)
+		}
+		return err
+	}
+	globalConfig := &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"}
+	devIDCAPath := fmt.Sprintf("devid_ca_path = %q", filepath.Join(s.dir, "ca.pem"))
+
+	err := configure("blah", globalConfig)
+	s.requireErrorContains(err, "tpm-devid: unable to decode configuration")
+
+	err = configure(devIDCAPath, nil)
+	s.Require().EqualError(err, "tpm-devid: global configuration is required")
+
+	err = configure(devIDCAPath, &plugin.ConfigureRequest_GlobalConfig{})
+	s.Require().EqualError(err, "tpm-devid: global configuration missing trust domain")
+
+	err = configure("", globalConfig)
+	s.Require().EqualError(err, "tpm-devid: devid_ca_path is required")
+
+	err = configure(`devid_ca_path = "blah"`, globalConfig)
+	s.requireErrorContains(err, "tpm-devid: unable to load DevID CA certificates")
+
+	err = configure(devIDCAPath, globalConfig)
+	s.Require().NoError(err)
+}
+
+func (s *DevIDAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *DevIDAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.now = func() time.Time {
+		return s.now
+	}
+	attestor.hooks.generateChallenge = func() (*tpmdevid.Challenge, error) {
+		return &tpmdevid.Challenge{Nonce: s.nonce}, nil
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *DevIDAttestorSuite) configureAttestor(extra ...string) {
+	config := fmt.Sprintf("devid_ca_path = %q", filepath.Join(s.dir, "ca.pem"))
+	for _, line := range extra {
+		config += "\n" + line
+	}
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: config,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *DevIDAttestorSuite) attestRequest(data []byte) *nodeattestor.AttestRequest {
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "tpm_devid",
+			Data: data,
+		},
+	}
+}
+
+// doAttest performs the challenge/response exchange, answering the
+// challenge with the given response
+func (s *DevIDAttestorSuite) doAttest(response []byte) (*nodeattestor.AttestResponse, error) {
+	stream, err := s.attestor.Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+
+	s.Require().NoError(stream.Send(s.attestRequest(s.marshal(tpmdevid.AttestationData{
+		Certificates: s.tpm.Certificates(),
+	}))))
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	challenge := new(tpmdevid.Challenge)
+	s.Require().NoError(json.Unmarshal(resp.Challenge, challenge))
+	s.Require().Equal(s.nonce, challenge.Nonce)
+
+	s.Require().NoError(stream.Send(&nodeattestor.AttestRequest{
+		Response: response,
+	}))
+	return stream.Recv()
+}
+
+func (s *DevIDAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	stream, err := s.attestor.Attest(context.Background())
+	s.Require().NoError(err)
+	defer stream.CloseSend()
+
+	s.Require().NoError(stream.Send(req))
+	resp, err := stream.Recv()
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *DevIDAttestorSuite) requireChallengeResponseError(response []byte, contains string) {
+	resp, err := s.doAttest(response)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *DevIDAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func (s *DevIDAttestorSuite) makeResponse(quote, signature []byte, pcrs map[int][]byte) []byte {
+	return s.marshal(tpmdevid.Response{
+		Quote:     quote,
+		Signature: signature,
+		PCRs:      pcrs,
+	})
+}
+
+func (s *DevIDAttestorSuite) encodeSignature(sig *tpm2.Signature) []byte {
+	signature, err := tpmdevid.EncodeSignature(sig)
+	s.Require().NoError(err)
+	return signature
+}
+
+func (s *DevIDAttestorSuite) marshal(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	s.Require().NoError(err)
+	return data
+}
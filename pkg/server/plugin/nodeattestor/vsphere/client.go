@@ -0,0 +1,257 @@
+package vsphere
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/spiffe/spire/pkg/common/plugin/vsphere"
+	"github.com/zeebo/errs"
+)
+
+const (
+	powerStateOn = "POWERED_ON"
+)
+
+// VM holds the virtual machine details obtained from vCenter that are
+// relevant to node attestation
+type VM struct {
+	ID         string
+	Name       string
+	BIOSUUID   string
+	PowerState string
+	// ResourcePools and Folders hold the names of the resource pools and
+	// folders containing the VM
+	ResourcePools []string
+	Folders       []string
+	// Tags holds the attached tags in "<category>:<tag>" form
+	Tags []string
+}
+
+// apiClient is an interface representing all of the API methods the
+// attestor needs to do its job.
+type apiClient interface {
+	FindVM(ctx context.Context, biosUUID string) (*VM, error)
+}
+
+// vcenterClient implements apiClient using the vSphere Automation REST API
+type vcenterClient struct {
+	httpClient *http.Client
+	url        string
+	username   string
+	password   string
+}
+
+func newVCenterClient(vcenterURL, username, password string, roots *x509.CertPool) apiClient {
+	return &vcenterClient{
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					RootCAs: roots,
+				},
+			},
+		},
+		url:      strings.TrimSuffix(vcenterURL, "/"),
+		username: username,
+		password: password,
+	}
+}
+
+// FindVM looks up the powered on virtual machine with the given BIOS UUID.
+// The API cannot filter virtual machines by UUID, so the details of each
+// powered on virtual machine are inspected until a match is found.
+func (c *vcenterClient) FindVM(ctx context.Context, biosUUID string) (*VM, error) {
+	session, err := c.createSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.deleteSession(ctx, session)
+
+	var summaries []struct {
+		VM   string `json:"vm"`
+		Name string `json:"name"`
+	}
+	if err := c.do(ctx, session, "GET", "/api/vcenter/vm?power_states="+powerStateOn, nil, &summaries); err != nil {
+		return nil, errs.New("unable to list virtual machines: %v", err)
+	}
+
+	for _, summary := range summaries {
+		var info struct {
+			Name       string `json:"name"`
+			PowerState string `json:"power_state"`
+			Identity   struct {
+				BIOSUUID string `json:"bios_uuid"`
+			} `json:"identity"`
+		}
+		if err := c.do(ctx, session, "GET", "/api/vcenter/vm/"+url.PathEscape(summary.VM), nil, &info); err != nil {
+			return nil, errs.New("unable to get virtual machine %q: %v", summary.VM, err)
+		}
+		uuid, err := vsphere.NormalizeUUID(info.Identity.BIOSUUID)
+		if err != nil || uuid != biosUUID {
+			continue
+		}
+
+		vm := &VM{
+			ID:         summary.VM,
+			Name:       info.Name,
+			BIOSUUID:   uuid,
+			PowerState: info.PowerState,
+		}
+		if vm.ResourcePools, err = c.findContainers(ctx, session, vm.ID, "/api/vcenter/resource-pool", "resource_pool", "resource_pools"); err != nil {
+			return nil, errs.New("unable to determine resource pools: %v", err)
+		}
+		if vm.Folders, err = c.findContainers(ctx, session, vm.ID, "/api/vcenter/folder?type=VIRTUAL_MACHINE", "folder", "folders"); err != nil {
+			return nil, errs.New("unable to determine folders: %v", err)
+		}
+		if vm.Tags, err = c.listAttachedTags(ctx, session, vm.ID); err != nil {
+			return nil, errs.New("unable to list tags: %v", err)
+		}
+		return vm, nil
+	}
+
+	return nil, errs.New("no powered on virtual machine with BIOS UUID %q", biosUUID)
+}
+
+// findContainers returns the names of the resource pools or folders
+// holding the virtual machine. The VM details do not reference either, so
+// each container is probed by listing its VMs, filtered by the VM.
+func (c *vcenterClient) findContainers(ctx context.Context, session, vmID, listPath, idField, filter string) ([]string, error) {
+	var containers []map[string]interface{}
+	if err := c.do(ctx, session, "GET", listPath, nil, &containers); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, container := range containers {
+		id, _ := container[idField].(string)
+		if id == "" {
+			continue
+		}
+		var vms []struct {
+			VM string `json:"vm"`
+		}
+		query := url.Values{}
+		query.Set("vms", vmID)
+		query.Set(filter, id)
+		if err := c.do(ctx, session, "GET", "/api/vcenter/vm?"+query.Encode(), nil, &vms); err != nil {
+			return nil, err
+		}
+		if name, _ := container["name"].(string); len(vms) > 0 && name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (c *vcenterClient) listAttachedTags(ctx context.Context, session, vmID string) ([]string, error) {
+	body := map[string]interface{}{
+		"object_id": map[string]string{
+			"id":   vmID,
+			"type": "VirtualMachine",
+		},
+	}
+	var tagIDs []string
+	if err := c.do(ctx, session, "POST", "/api/cis/tagging/tag-association?action=list-attached-tags", body, &tagIDs); err != nil {
+		return nil, err
+	}
+
+	categories := make(map[string]string)
+	var tags []string
+	for _, tagID := range tagIDs {
+		var tag struct {
+			Name       string `json:"name"`
+			CategoryID string `json:"category_id"`
+		}
+		if err := c.do(ctx, session, "GET", "/api/cis/tagging/tag/"+url.PathEscape(tagID), nil, &tag); err != nil {
+			return nil, err
+		}
+		category, ok := categories[tag.CategoryID]
+		if !ok {
+			var info struct {
+				Name string `json:"name"`
+			}
+			if err := c.do(ctx, session, "GET", "/api/cis/tagging/category/"+url.PathEscape(tag.CategoryID), nil, &info); err != nil {
+				return nil, err
+			}
+			category = info.Name
+			categories[tag.CategoryID] = category
+		}
+		tags = append(tags, category+":"+tag.Name)
+	}
+	return tags, nil
+}
+
+func (c *vcenterClient) createSession(ctx context.Context) (string, error) {
+	req, err := http.NewRequest("POST", c.url+"/api/session", nil)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.SetBasicAuth(c.username, c.password)
+
+	var session string
+	if err := c.doRequest(req, &session); err != nil {
+		return "", errs.New("unable to create session: %v", err)
+	}
+	return session, nil
+}
+
+func (c *vcenterClient) deleteSession(ctx context.Context, session string) {
+	// failing to delete the session is not fatal; vCenter expires idle
+	// sessions on its own
+	_ = c.do(ctx, session, "DELETE", "/api/session", nil, nil)
+}
+
+func (c *vcenterClient) do(ctx context.Context, session, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.url+path, body)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("vmware-api-session-id", session)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.doRequest(req, out)
+}
+
+func (c *vcenterClient) doRequest(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errs.New("unexpected status code %d: %s", resp.StatusCode, tryRead(resp.Body))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errs.New("unable to decode response: %v", err)
+	}
+	return nil
+}
+
+func tryRead(r io.Reader) string {
+	b := make([]byte, 1024)
+	n, _ := r.Read(b)
+	return string(b[:n])
+}
@@ -0,0 +1,233 @@
+package vsphere
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/plugin/vsphere"
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/proto/common"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/zeebo/errs"
+)
+
+const (
+	passwordEnv = "VSPHERE_PASSWORD"
+)
+
+var (
+	vsphereError = errs.Class("vsphere")
+)
+
+type VMAttestorConfig struct {
+	VCenterURL string `hcl:"vcenter_url"`
+	Username   string `hcl:"username"`
+	// Password for the vCenter user. If unset, the password is read from
+	// the VSPHERE_PASSWORD environment variable.
+	Password string `hcl:"password"`
+
+	// CABundlePath is the path to the CA certificates used to verify the
+	// vCenter server certificate. If unset, the system roots are used.
+	CABundlePath string `hcl:"ca_bundle_path"`
+
+	ResourcePoolWhitelist []string `hcl:"resource_pool_whitelist"`
+	FolderWhitelist       []string `hcl:"folder_whitelist"`
+}
+
+type vmAttestorConfig struct {
+	trustDomain   string
+	client        apiClient
+	resourcePools map[string]bool
+	folders       map[string]bool
+}
+
+type VMAttestorPlugin struct {
+	mu     sync.RWMutex
+	config *vmAttestorConfig
+
+	hooks struct {
+		getenv    func(string) string
+		newClient func(vcenterURL, username, password string, roots *x509.CertPool) apiClient
+	}
+}
+
+var _ nodeattestor.Plugin = (*VMAttestorPlugin)(nil)
+
+func New() *VMAttestorPlugin {
+	p := &VMAttestorPlugin{}
+	p.hooks.getenv = os.Getenv
+	p.hooks.newClient = newVCenterClient
+	return p
+}
+
+func (p *VMAttestorPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return vsphereError.Wrap(err)
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if req.AttestedBefore {
+		return vsphereError.New("node has already attested")
+	}
+
+	if req.AttestationData == nil {
+		return vsphereError.New("missing attestation data")
+	}
+
+	if dataType := req.AttestationData.Type; dataType != vsphere.PluginName {
+		return vsphereError.New("unexpected attestation data type %q", dataType)
+	}
+
+	attestationData := new(vsphere.AttestationData)
+	if err := json.Unmarshal(req.AttestationData.Data, attestationData); err != nil {
+		return vsphereError.New("unable to unmarshal attestation data: %v", err)
+	}
+
+	biosUUID, err := vsphere.NormalizeUUID(attestationData.BIOSUUID)
+	if err != nil {
+		return vsphereError.New("invalid BIOS UUID: %v", err)
+	}
+
+	vm, err := config.client.FindVM(stream.Context(), biosUUID)
+	if err != nil {
+		return vsphereError.New("unable to look up virtual machine: %v", err)
+	}
+
+	if len(config.resourcePools) > 0 && !containsAny(config.resourcePools, vm.ResourcePools) {
+		return vsphereError.New("virtual machine %q is not in a whitelisted resource pool", vm.Name)
+	}
+
+	if len(config.folders) > 0 && !containsAny(config.folders, vm.Folders) {
+		return vsphereError.New("virtual machine %q is not in a whitelisted folder", vm.Name)
+	}
+
+	return stream.Send(&nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: vsphere.AgentID(config.trustDomain, biosUUID),
+		Selectors:    buildSelectors(vm),
+	})
+}
+
+func (p *VMAttestorPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	hclConfig := new(VMAttestorConfig)
+	if err := hcl.Decode(hclConfig, req.Configuration); err != nil {
+		return nil, vsphereError.New("unable to decode configuration: %v", err)
+	}
+	if req.GlobalConfig == nil {
+		return nil, vsphereError.New("global configuration is required")
+	}
+	if req.GlobalConfig.TrustDomain == "" {
+		return nil, vsphereError.New("global configuration missing trust domain")
+	}
+
+	if hclConfig.VCenterURL == "" {
+		return nil, vsphereError.New("vcenter_url is required")
+	}
+	if hclConfig.Username == "" {
+		return nil, vsphereError.New("username is required")
+	}
+	password := hclConfig.Password
+	if password == "" {
+		password = p.hooks.getenv(passwordEnv)
+	}
+	if password == "" {
+		return nil, vsphereError.New("password or the %s environment variable is required", passwordEnv)
+	}
+
+	var roots *x509.CertPool
+	if hclConfig.CABundlePath != "" {
+		var err error
+		roots, err = util.LoadCertPool(hclConfig.CABundlePath)
+		if err != nil {
+			return nil, vsphereError.New("unable to load CA bundle: %v", err)
+		}
+	}
+
+	config := &vmAttestorConfig{
+		trustDomain:   req.GlobalConfig.TrustDomain,
+		client:        p.hooks.newClient(hclConfig.VCenterURL, hclConfig.Username, password, roots),
+		resourcePools: make(map[string]bool),
+		folders:       make(map[string]bool),
+	}
+	for _, resourcePool := range hclConfig.ResourcePoolWhitelist {
+		config.resourcePools[resourcePool] = true
+	}
+	for _, folder := range hclConfig.FolderWhitelist {
+		config.folders[folder] = true
+	}
+
+	p.setConfig(config)
+	return &spi.ConfigureResponse{}, nil
+}
+
+func (p *VMAttestorPlugin) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+func (p *VMAttestorPlugin) getConfig() (*vmAttestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, vsphereError.New("not configured")
+	}
+	return p.config, nil
+}
+
+func (p *VMAttestorPlugin) setConfig(config *vmAttestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func containsAny(set map[string]bool, values []string) bool {
+	for _, value := range values {
+		if set[value] {
+			return true
+		}
+	}
+	return false
+}
+
+func buildSelectors(vm *VM) []*common.Selector {
+	selectors := []*common.Selector{
+		makeSelector("vm_uuid", vm.BIOSUUID),
+	}
+	if vm.Name != "" {
+		selectors = append(selectors, makeSelector("vm_name", vm.Name))
+	}
+	for _, resourcePool := range sorted(vm.ResourcePools) {
+		selectors = append(selectors, makeSelector("resource_pool", resourcePool))
+	}
+	for _, folder := range sorted(vm.Folders) {
+		selectors = append(selectors, makeSelector("folder", folder))
+	}
+	for _, tag := range sorted(vm.Tags) {
+		selectors = append(selectors, makeSelector("tag", tag))
+	}
+	return selectors
+}
+
+func sorted(values []string) []string {
+	values = append([]string(nil), values...)
+	sort.Strings(values)
+	return values
+}
+
+func makeSelector(kind, value string) *common.Selector {
+	return &common.Selector{
+		Type:  vsphere.PluginName,
+		Value: fmt.Sprintf("%s:%s", kind, value),
+	}
+}
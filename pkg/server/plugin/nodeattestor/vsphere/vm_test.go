@@ -0,0 +1,381 @@
+package vsphere
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	biosUUID = "4210b1a2-3c4d-5e6f-7a8b-9c0d1e2f3a4b"
+)
+
+func TestVMAttestorPlugin(t *testing.T) {
+	suite.Run(t, new(VMAttestorSuite))
+}
+
+type VMAttestorSuite struct {
+	suite.Suite
+
+	attestor *nodeattestor.BuiltIn
+	env      map[string]string
+	password string
+	vm       *VM
+	apiErr   error
+}
+
+func (s *VMAttestorSuite) SetupTest() {
+	s.env = map[string]string{}
+	s.password = ""
+	s.apiErr = nil
+	s.vm = &VM{
+		ID:            "vm-42",
+		Name:          "k8s-node-1",
+		BIOSUUID:      biosUUID,
+		PowerState:    "POWERED_ON",
+		ResourcePools: []string{"Resources", "k8s"},
+		Folders:       []string{"vm", "nodes"},
+		Tags:          []string{"env:prod", "app:k8s"},
+	}
+
+	s.attestor = s.newAttestor()
+	s.configureAttestor("")
+}
+
+func (s *VMAttestorSuite) TestAttestFailsWhenNotConfigured() {
+	resp, err := s.doAttestOnAttestor(s.newAttestor(), &nodeattestor.AttestRequest{})
+	s.Require().EqualError(err, "vsphere: not configured")
+	s.Require().Nil(resp)
+}
+
+func (s *VMAttestorSuite) TestAttestFailsWhenAttestedBefore() {
+	s.requireAttestError(&nodeattestor.AttestRequest{AttestedBefore: true},
+		"vsphere: node has already attested")
+}
+
+func (s *VMAttestorSuite) TestAttestFailsWithNoAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{},
+		"vsphere: missing attestation data")
+}
+
+func (s *VMAttestorSuite) TestAttestFailsWithWrongAttestationDataType() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "blah",
+		},
+	}, `vsphere: unexpected attestation data type "blah"`)
+}
+
+func (s *VMAttestorSuite) TestAttestFailsWithMalformedAttestationData() {
+	s.requireAttestError(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "vsphere",
+			Data: []byte("{"),
+		},
+	}, "vsphere: unable to unmarshal attestation data")
+}
+
+func (s *VMAttestorSuite) TestAttestFailsWithInvalidBIOSUUID() {
+	s.requireAttestError(makeAttestRequest("blah"),
+		`vsphere: invalid BIOS UUID: malformed UUID "blah"`)
+}
+
+func (s *VMAttestorSuite) TestAttestFailsWhenLookupFails() {
+	s.apiErr = errors.New("oh no")
+	s.requireAttestError(makeAttestRequest(biosUUID),
+		"vsphere: unable to look up virtual machine: oh no")
+}
+
+func (s *VMAttestorSuite) TestAttestResourcePoolWhitelist() {
+	s.configureAttestor(`resource_pool_whitelist = ["db"]`)
+	s.requireAttestError(makeAttestRequest(biosUUID),
+		`vsphere: virtual machine "k8s-node-1" is not in a whitelisted resource pool`)
+
+	s.configureAttestor(`resource_pool_whitelist = ["db", "k8s"]`)
+	_, err := s.doAttest(makeAttestRequest(biosUUID))
+	s.Require().NoError(err)
+}
+
+func (s *VMAttestorSuite) TestAttestFolderWhitelist() {
+	s.configureAttestor(`folder_whitelist = ["templates"]`)
+	s.requireAttestError(makeAttestRequest(biosUUID),
+		`vsphere: virtual machine "k8s-node-1" is not in a whitelisted folder`)
+
+	s.configureAttestor(`folder_whitelist = ["templates", "nodes"]`)
+	_, err := s.doAttest(makeAttestRequest(biosUUID))
+	s.Require().NoError(err)
+}
+
+func (s *VMAttestorSuite) TestAttestSuccess() {
+	// the UUID reported by the guest is normalized
+	resp, err := s.doAttest(makeAttestRequest("4210B1A2-3C4D-5E6F-7A8B-9C0D1E2F3A4B"))
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Require().True(resp.Valid)
+	s.Require().Equal("spiffe://example.org/spire/agent/vsphere/"+biosUUID, resp.BaseSPIFFEID)
+	s.Require().Nil(resp.Challenge)
+	s.Require().Equal([]*common.Selector{
+		{Type: "vsphere", Value: "vm_uuid:" + biosUUID},
+		{Type: "vsphere", Value: "vm_name:k8s-node-1"},
+		{Type: "vsphere", Value: "resource_pool:Resources"},
+		{Type: "vsphere", Value: "resource_pool:k8s"},
+		{Type: "vsphere", Value: "folder:nodes"},
+		{Type: "vsphere", Value: "folder:vm"},
+		{Type: "vsphere", Value: "tag:app:k8s"},
+		{Type: "vsphere", Value: "tag:env:prod"},
+	}, resp.Selectors)
+}
+
+func (s *VMAttestorSuite) TestConfigure() {
+	// malformed configuration
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: "blah",
+	})
+	s.requireErrorContains(err, "vsphere: unable to decode configuration")
+	s.Require().Nil(resp)
+
+	// missing global configuration
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{})
+	s.Require().EqualError(err, "vsphere: global configuration is required")
+	s.Require().Nil(resp)
+
+	// missing trust domain
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{}})
+	s.Require().EqualError(err, "vsphere: global configuration missing trust domain")
+	s.Require().Nil(resp)
+
+	// missing vCenter URL
+	s.requireConfigureError(``, "vsphere: vcenter_url is required")
+
+	// missing username
+	s.requireConfigureError(`vcenter_url = "https://vcenter.example.org"`,
+		"vsphere: username is required")
+
+	// missing password
+	s.requireConfigureError(`
+		vcenter_url = "https://vcenter.example.org"
+		username = "spire@vsphere.local"
+	`, "vsphere: password or the VSPHERE_PASSWORD environment variable is required")
+
+	// CA bundle does not exist
+	s.requireConfigureError(`
+		vcenter_url = "https://vcenter.example.org"
+		username = "spire@vsphere.local"
+		password = "PASSWORD"
+		ca_bundle_path = "/does/not/exist"
+	`, "vsphere: unable to load CA bundle")
+
+	// password from the environment
+	s.env["VSPHERE_PASSWORD"] = "ENVPASSWORD"
+	resp, err = s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `
+		vcenter_url = "https://vcenter.example.org"
+		username = "spire@vsphere.local"
+		`,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&plugin.ConfigureResponse{}, resp)
+	s.Require().Equal("ENVPASSWORD", s.password)
+
+	// configured password takes precedence
+	s.configureAttestor("")
+	s.Require().Equal("PASSWORD", s.password)
+}
+
+func (s *VMAttestorSuite) TestGetPluginInfo() {
+	resp, err := s.attestor.GetPluginInfo(context.Background(), &plugin.GetPluginInfoRequest{})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.GetPluginInfoResponse{})
+}
+
+func (s *VMAttestorSuite) newAttestor() *nodeattestor.BuiltIn {
+	attestor := New()
+	attestor.hooks.getenv = func(key string) string {
+		return s.env[key]
+	}
+	attestor.hooks.newClient = func(vcenterURL, username, password string, roots *x509.CertPool) apiClient {
+		s.Require().Equal("https://vcenter.example.org", vcenterURL)
+		s.Require().Equal("spire@vsphere.local", username)
+		s.password = password
+		return fakeAPIClient(func(ctx context.Context, uuid string) (*VM, error) {
+			if s.apiErr != nil {
+				return nil, s.apiErr
+			}
+			if uuid != s.vm.BIOSUUID {
+				return nil, fmt.Errorf("unexpected BIOS UUID %q", uuid)
+			}
+			return s.vm, nil
+		})
+	}
+	return nodeattestor.NewBuiltIn(attestor)
+}
+
+func (s *VMAttestorSuite) configureAttestor(config string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: `
+		vcenter_url = "https://vcenter.example.org"
+		username = "spire@vsphere.local"
+		password = "PASSWORD"
+		` + config,
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *VMAttestorSuite) requireConfigureError(config string, contains string) {
+	resp, err := s.attestor.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: config,
+		GlobalConfig:  &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *VMAttestorSuite) doAttest(req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	return s.doAttestOnAttestor(s.attestor, req)
+}
+
+func (s *VMAttestorSuite) doAttestOnAttestor(attestor *nodeattestor.BuiltIn, req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	stream, err := attestor.Attest(context.Background())
+	s.Require().NoError(err)
+
+	err = stream.Send(req)
+	s.Require().NoError(err)
+
+	err = stream.CloseSend()
+	s.Require().NoError(err)
+
+	return stream.Recv()
+}
+
+func (s *VMAttestorSuite) requireAttestError(req *nodeattestor.AttestRequest, contains string) {
+	resp, err := s.doAttest(req)
+	s.requireErrorContains(err, contains)
+	s.Require().Nil(resp)
+}
+
+func (s *VMAttestorSuite) requireErrorContains(err error, contains string) {
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), contains)
+}
+
+func TestFindVM(t *testing.T) {
+	sessionDeleted := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/api/session" && req.Method == "POST" {
+			if username, password, _ := req.BasicAuth(); username != "USER" || password != "PASSWORD" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `"SESSION"`)
+			return
+		}
+		if req.Header.Get("vmware-api-session-id") != "SESSION" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		query := req.URL.Query()
+		switch {
+		case req.URL.Path == "/api/session" && req.Method == "DELETE":
+			sessionDeleted = true
+		case req.URL.Path == "/api/vcenter/vm" && query.Get("power_states") == "POWERED_ON":
+			fmt.Fprint(w, `[{"vm": "vm-1", "name": "other"}, {"vm": "vm-42", "name": "k8s-node-1"}]`)
+		case req.URL.Path == "/api/vcenter/vm/vm-1":
+			fmt.Fprint(w, `{"name": "other", "power_state": "POWERED_ON", "identity": {"bios_uuid": "00000000-0000-0000-0000-000000000000"}}`)
+		case req.URL.Path == "/api/vcenter/vm/vm-42":
+			fmt.Fprint(w, `{"name": "k8s-node-1", "power_state": "POWERED_ON", "identity": {"bios_uuid": "4210B1A2-3C4D-5E6F-7A8B-9C0D1E2F3A4B"}}`)
+		case req.URL.Path == "/api/vcenter/resource-pool":
+			fmt.Fprint(w, `[{"resource_pool": "resgroup-1", "name": "Resources"}, {"resource_pool": "resgroup-2", "name": "db"}]`)
+		case req.URL.Path == "/api/vcenter/folder" && query.Get("type") == "VIRTUAL_MACHINE":
+			fmt.Fprint(w, `[{"folder": "group-1", "name": "nodes", "type": "VIRTUAL_MACHINE"}]`)
+		case req.URL.Path == "/api/vcenter/vm" && query.Get("vms") == "vm-42":
+			if query.Get("resource_pools") == "resgroup-1" || query.Get("folders") == "group-1" {
+				fmt.Fprint(w, `[{"vm": "vm-42"}]`)
+			} else {
+				fmt.Fprint(w, `[]`)
+			}
+		case req.URL.Path == "/api/cis/tagging/tag-association" && req.Method == "POST":
+			body := struct {
+				ObjectID struct {
+					ID   string `json:"id"`
+					Type string `json:"type"`
+				} `json:"object_id"`
+			}{}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.ObjectID.ID != "vm-42" || body.ObjectID.Type != "VirtualMachine" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `["tag-1", "tag-2"]`)
+		case req.URL.Path == "/api/cis/tagging/tag/tag-1":
+			fmt.Fprint(w, `{"name": "prod", "category_id": "category-1"}`)
+		case req.URL.Path == "/api/cis/tagging/tag/tag-2":
+			fmt.Fprint(w, `{"name": "staging", "category_id": "category-1"}`)
+		case req.URL.Path == "/api/cis/tagging/category/category-1":
+			fmt.Fprint(w, `{"name": "env"}`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	ctx := context.Background()
+	client := newVCenterClient(server.URL+"/", "USER", "PASSWORD", roots)
+
+	vm, err := client.FindVM(ctx, biosUUID)
+	require.NoError(t, err)
+	require.Equal(t, &VM{
+		ID:            "vm-42",
+		Name:          "k8s-node-1",
+		BIOSUUID:      biosUUID,
+		PowerState:    "POWERED_ON",
+		ResourcePools: []string{"Resources"},
+		Folders:       []string{"nodes"},
+		Tags:          []string{"env:prod", "env:staging"},
+	}, vm)
+	require.True(t, sessionDeleted)
+
+	// no match
+	_, err = client.FindVM(ctx, "11111111-1111-1111-1111-111111111111")
+	require.EqualError(t, err, `no powered on virtual machine with BIOS UUID "11111111-1111-1111-1111-111111111111"`)
+
+	// bad credentials
+	_, err = newVCenterClient(server.URL, "USER", "BAD", roots).FindVM(ctx, biosUUID)
+	require.EqualError(t, err, "unable to create session: unexpected status code 401: unauthorized\n")
+
+	// untrusted server certificate
+	_, err = newVCenterClient(server.URL, "USER", "PASSWORD", x509.NewCertPool()).FindVM(ctx, biosUUID)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to create session")
+}
+
+type fakeAPIClient func(ctx context.Context, biosUUID string) (*VM, error)
+
+func (fn fakeAPIClient) FindVM(ctx context.Context, biosUUID string) (*VM, error) {
+	return fn(ctx, biosUUID)
+}
+
+func makeAttestRequest(biosUUID string) *nodeattestor.AttestRequest {
+	return &nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "vsphere",
+			Data: []byte(fmt.Sprintf(`{"bios_uuid": %q}`, biosUUID)),
+		},
+	}
+}
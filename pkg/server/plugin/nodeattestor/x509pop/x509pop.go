@@ -1,11 +1,16 @@
 package x509pop
 
 import (
+	"bytes"
 	"context"
 	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/hcl"
 	"github.com/spiffe/spire/pkg/common/plugin/x509pop"
@@ -22,10 +27,21 @@ const (
 type configuration struct {
 	trustDomain string
 	trustBundle *x509.CertPool
+	crls        *crlFile
+	requireCRL  bool
 }
 
 type X509PoPConfig struct {
 	CABundlePath string `hcl:"ca_bundle_path"`
+
+	// CRLPath is the path to the certificate revocation lists, PEM or
+	// DER encoded, used to check the certificates presented by agents. The
+	// file is reloaded when it changes.
+	CRLPath string `hcl:"crl_path"`
+
+	// RequireCRL, if true, rejects certificates whose issuer has no CRL in
+	// CRLPath. By default such certificates are not checked for revocation.
+	RequireCRL bool `hcl:"require_crl"`
 }
 
 type X509PoPPlugin struct {
@@ -84,6 +100,18 @@ func (p *X509PoPPlugin) Attest(stream nodeattestor.Attest_PluginStream) error {
 		return newError("certificate verification failed: %v", err)
 	}
 
+	// only chains without revoked certificates are trusted
+	if c.crls != nil {
+		crls, err := c.crls.load()
+		if err != nil {
+			return newError("unable to load CRLs: %v", err)
+		}
+		chains, err = filterRevokedChains(chains, crls, c.requireCRL, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
 	// now that the leaf certificate is trusted, issue a challenge to the node
 	// to prove possession of the private key.
 	challenge, err := x509pop.GenerateChallenge(leaf)
@@ -153,9 +181,21 @@ func (p *X509PoPPlugin) Configure(ctx context.Context, req *spi.ConfigureRequest
 		return nil, newError("unable to load trust bundle: %v", err)
 	}
 
+	var crls *crlFile
+	if config.CRLPath != "" {
+		crls = &crlFile{path: config.CRLPath}
+		if _, err := crls.load(); err != nil {
+			return nil, newError("unable to load CRLs: %v", err)
+		}
+	} else if config.RequireCRL {
+		return nil, newError("require_crl requires crl_path")
+	}
+
 	p.setConfiguration(&configuration{
 		trustDomain: req.GlobalConfig.TrustDomain,
 		trustBundle: trustBundle,
+		crls:        crls,
+		requireCRL:  config.RequireCRL,
 	})
 
 	return &spi.ConfigureResponse{}, nil
@@ -190,6 +230,24 @@ func buildSelectors(leaf *x509.Certificate, chains [][]*x509.Certificate) []*com
 		})
 	}
 
+	for _, ou := range leaf.Subject.OrganizationalUnit {
+		selectors = append(selectors, &common.Selector{
+			Type: "x509pop", Value: "subject:ou:" + ou,
+		})
+	}
+
+	for _, uri := range leaf.URIs {
+		selectors = append(selectors, &common.Selector{
+			Type: "x509pop", Value: "san:uri:" + uri.String(),
+		})
+	}
+
+	for _, policy := range leaf.PolicyIdentifiers {
+		selectors = append(selectors, &common.Selector{
+			Type: "x509pop", Value: "policy:" + policy.String(),
+		})
+	}
+
 	// Used to avoid duplicating selectors.
 	fingerprints := map[string]*x509.Certificate{}
 	for _, chain := range chains {
@@ -211,3 +269,125 @@ func buildSelectors(leaf *x509.Certificate, chains [][]*x509.Certificate) []*com
 
 	return selectors
 }
+
+// filterRevokedChains returns the chains in which no certificate has been
+// revoked by its issuer. An error is returned if no chain can be trusted.
+func filterRevokedChains(chains [][]*x509.Certificate, crls []*x509.RevocationList, requireCRL bool, now time.Time) ([][]*x509.Certificate, error) {
+	var lastErr error
+	var trusted [][]*x509.Certificate
+	for _, chain := range chains {
+		if err := checkRevocation(chain, crls, requireCRL, now); err != nil {
+			lastErr = err
+			continue
+		}
+		trusted = append(trusted, chain)
+	}
+	if len(trusted) == 0 {
+		return nil, lastErr
+	}
+	return trusted, nil
+}
+
+// checkRevocation checks every certificate in the chain but the root against
+// the CRLs signed by its issuer. A certificate fails the check if a CRL lists
+// it, if a CRL is past its NextUpdate, or if there is no CRL and requireCRL
+// is set.
+func checkRevocation(chain []*x509.Certificate, crls []*x509.RevocationList, requireCRL bool, now time.Time) error {
+	for i := 0; i < len(chain)-1; i++ {
+		cert, issuer := chain[i], chain[i+1]
+		found := false
+		for _, crl := range crls {
+			if !bytes.Equal(crl.RawIssuer, cert.RawIssuer) {
+				continue
+			}
+			if err := crl.CheckSignatureFrom(issuer); err != nil {
+				continue
+			}
+			found = true
+			if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+				return newError("CRL issued by %q expired at %s", cert.Issuer, crl.NextUpdate.Format(time.RFC3339))
+			}
+			for _, entry := range crl.RevokedCertificateEntries {
+				if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+					return newError("certificate with serial %s issued by %q has been revoked", cert.SerialNumber, cert.Issuer)
+				}
+			}
+		}
+		if !found && requireCRL {
+			return newError("no CRL issued by %q for certificate with serial %s", cert.Issuer, cert.SerialNumber)
+		}
+	}
+	return nil
+}
+
+// crlFile caches the CRLs loaded from a file, reloading them whenever the
+// file modification time or size changes.
+type crlFile struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	crls    []*x509.RevocationList
+}
+
+// load returns the CRLs in the file, reloading them if the file has changed
+// since they were last loaded. If the file cannot be read or parsed an error
+// is returned and the previously loaded CRLs are not used.
+func (f *crlFile) load() ([]*x509.RevocationList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, err
+	}
+	if f.crls != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.crls, nil
+	}
+
+	crls, err := loadCRLs(f.path)
+	if err != nil {
+		return nil, err
+	}
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	f.crls = crls
+	return crls, nil
+}
+
+// loadCRLs loads the certificate revocation lists at path. The file may
+// contain one or more PEM encoded CRLs or a single DER encoded CRL.
+func loadCRLs(path string) ([]*x509.RevocationList, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var crls []*x509.RevocationList
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("unexpected PEM block type %q", block.Type)
+		}
+		crl, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		crls = append(crls, crl)
+	}
+
+	if len(crls) == 0 {
+		crl, err := x509.ParseRevocationList(data)
+		if err != nil {
+			return nil, err
+		}
+		crls = append(crls, crl)
+	}
+	return crls, nil
+}
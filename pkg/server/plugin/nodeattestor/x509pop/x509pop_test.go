@@ -3,11 +3,22 @@ package x509pop
 import (
 	"context"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spiffe/spire/pkg/common/plugin/x509pop"
 	"github.com/spiffe/spire/proto/common"
@@ -111,6 +122,126 @@ func (s *Suite) TestAttestSuccess() {
 	}, resp.Selectors)
 }
 
+func (s *Suite) TestAttestWithExtendedSelectors() {
+	require := s.Require()
+
+	pki := s.newTestPKI()
+	leafKey, leafCert := pki.issueLeaf(&x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject: pkix.Name{
+			CommonName:         "device-1",
+			OrganizationalUnit: []string{"edge", "fleet-a"},
+		},
+		URIs:     []*url.URL{{Scheme: "urn", Opaque: "device:1234"}},
+		Policies: []x509.OID{s.oid(1, 3, 6, 1, 4, 1, 55555, 1)},
+	})
+	s.configureWithPKI(pki, "")
+
+	resp, err := s.attestWithKey(leafKey, leafCert, pki.intermediate)
+	require.NoError(err)
+	s.True(resp.Valid)
+	require.EqualValues([]*common.Selector{
+		{Type: "x509pop", Value: "subject:cn:device-1"},
+		{Type: "x509pop", Value: "subject:ou:edge"},
+		{Type: "x509pop", Value: "subject:ou:fleet-a"},
+		{Type: "x509pop", Value: "san:uri:urn:device:1234"},
+		{Type: "x509pop", Value: "policy:1.3.6.1.4.1.55555.1"},
+		{Type: "x509pop", Value: "ca:fingerprint:" + x509pop.Fingerprint(pki.intermediate)},
+		{Type: "x509pop", Value: "ca:fingerprint:" + x509pop.Fingerprint(pki.root)},
+	}, resp.Selectors)
+}
+
+func (s *Suite) TestAttestWithRevocation() {
+	require := s.Require()
+
+	pki := s.newTestPKI()
+	leafKey, leafCert := pki.issueLeaf(&x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "device-1"},
+	})
+	otherKey, otherCert := pki.issueLeaf(&x509.Certificate{
+		SerialNumber: big.NewInt(4),
+		Subject:      pkix.Name{CommonName: "device-2"},
+	})
+
+	// leaf revoked by the intermediate
+	s.configureWithPKI(pki, s.writeCRLs(pki.intermediateCRL(leafCert.SerialNumber)))
+	_, err := s.attestWithKey(leafKey, leafCert, pki.intermediate)
+	require.EqualError(err, `x509pop: certificate with serial 3 issued by "CN=INTERMEDIATE" has been revoked`)
+	_, err = s.attestWithKey(otherKey, otherCert, pki.intermediate)
+	require.NoError(err)
+
+	// intermediate revoked by the root
+	s.configureWithPKI(pki, s.writeCRLs(pki.rootCRL(pki.intermediate.SerialNumber)))
+	_, err = s.attestWithKey(otherKey, otherCert, pki.intermediate)
+	require.EqualError(err, `x509pop: certificate with serial 2 issued by "CN=ROOT" has been revoked`)
+
+	// CRLs not signed by the issuer are ignored
+	impostor := s.newTestPKI()
+	s.configureWithPKI(pki, s.writeCRLs(impostor.intermediateCRL(leafCert.SerialNumber)))
+	_, err = s.attestWithKey(leafKey, leafCert, pki.intermediate)
+	require.NoError(err)
+
+	// CRLs past their next update reject the certificates they cover
+	expired := pki.crl(pki.intermediate, pki.intermediateKey, nil, time.Now().Add(-time.Minute))
+	s.configureWithPKI(pki, s.writeCRLs(expired))
+	_, err = s.attestWithKey(otherKey, otherCert, pki.intermediate)
+	require.Error(err)
+	require.Contains(err.Error(), `x509pop: CRL issued by "CN=INTERMEDIATE" expired at`)
+}
+
+func (s *Suite) TestAttestReloadsCRLs() {
+	require := s.Require()
+
+	pki := s.newTestPKI()
+	leafKey, leafCert := pki.issueLeaf(&x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "device-1"},
+	})
+
+	crlPath := s.writeCRLs(pki.intermediateCRL())
+	s.configureWithPKI(pki, crlPath)
+	_, err := s.attestWithKey(leafKey, leafCert, pki.intermediate)
+	require.NoError(err)
+
+	// the leaf is revoked once the file is updated
+	require.NoError(ioutil.WriteFile(crlPath, pem.EncodeToMemory(&pem.Block{
+		Type:  "X509 CRL",
+		Bytes: pki.intermediateCRL(leafCert.SerialNumber),
+	}), 0644))
+	_, err = s.attestWithKey(leafKey, leafCert, pki.intermediate)
+	require.EqualError(err, `x509pop: certificate with serial 3 issued by "CN=INTERMEDIATE" has been revoked`)
+
+	// attestation fails closed if the file can no longer be loaded
+	require.NoError(os.Remove(crlPath))
+	_, err = s.attestWithKey(leafKey, leafCert, pki.intermediate)
+	require.Error(err)
+	require.Contains(err.Error(), "x509pop: unable to load CRLs:")
+}
+
+func (s *Suite) TestAttestRequireCRL() {
+	require := s.Require()
+
+	pki := s.newTestPKI()
+	leafKey, leafCert := pki.issueLeaf(&x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "device-1"},
+	})
+
+	// by default, certificates without a CRL from their issuer are accepted
+	s.configureWithPKI(pki, s.writeCRLs(pki.rootCRL()))
+	_, err := s.attestWithKey(leafKey, leafCert, pki.intermediate)
+	require.NoError(err)
+
+	s.configureWithPKIConfig(pki, fmt.Sprintf("crl_path = %q\nrequire_crl = true", s.writeCRLs(pki.rootCRL())))
+	_, err = s.attestWithKey(leafKey, leafCert, pki.intermediate)
+	require.EqualError(err, `x509pop: no CRL issued by "CN=INTERMEDIATE" for certificate with serial 3`)
+
+	s.configureWithPKIConfig(pki, fmt.Sprintf("crl_path = %q\nrequire_crl = true", s.writeCRLs(pki.rootCRL(), pki.intermediateCRL())))
+	_, err = s.attestWithKey(leafKey, leafCert, pki.intermediate)
+	require.NoError(err)
+}
+
 func (s *Suite) TestAttestFailure() {
 	require := s.Require()
 
@@ -246,6 +377,38 @@ func (s *Suite) TestConfigure() {
 	s.errorContains(err, "x509pop: unable to load trust bundle")
 	require.Nil(resp)
 
+	// bad CRLs
+	resp, err = p.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+		ca_bundle_path = %q
+		crl_path = "blah"
+		`, fixture.Join("nodeattestor", "x509pop", "root-crt.pem")),
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.errorContains(err, "x509pop: unable to load CRLs")
+	require.Nil(resp)
+
+	// CRL file containing a certificate
+	resp, err = p.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+		ca_bundle_path = %q
+		crl_path = %q
+		`, fixture.Join("nodeattestor", "x509pop", "root-crt.pem"), fixture.Join("nodeattestor", "x509pop", "root-crt.pem")),
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	require.EqualError(err, `x509pop: unable to load CRLs: unexpected PEM block type "CERTIFICATE"`)
+	require.Nil(resp)
+
+	// CRLs required without a CRL file
+	resp, err = p.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+		ca_bundle_path = %q
+		require_crl = true
+		`, fixture.Join("nodeattestor", "x509pop", "root-crt.pem")),
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	require.EqualError(err, "x509pop: require_crl requires crl_path")
+	require.Nil(resp)
 }
 
 func (s *Suite) TestGetPluginInfo() {
@@ -258,6 +421,165 @@ func (s *Suite) TestGetPluginInfo() {
 	require.Equal(resp, &plugin.GetPluginInfoResponse{})
 }
 
+// attestWithKey runs a full attestation for the leaf and intermediate,
+// answering the challenge with the given key
+func (s *Suite) attestWithKey(key crypto.PrivateKey, leaf, intermediate *x509.Certificate) (*nodeattestor.AttestResponse, error) {
+	require := s.Require()
+
+	stream, done := s.attest()
+	defer done()
+
+	require.NoError(stream.Send(&nodeattestor.AttestRequest{
+		AttestationData: &common.AttestationData{
+			Type: "x509pop",
+			Data: s.marshal(&x509pop.AttestationData{
+				Certificates: [][]byte{leaf.Raw, intermediate.Raw},
+			}),
+		},
+	}))
+
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := new(x509pop.Challenge)
+	s.unmarshal(resp.Challenge, challenge)
+	response, err := x509pop.CalculateResponse(key, challenge)
+	require.NoError(err)
+	require.NoError(stream.Send(&nodeattestor.AttestRequest{
+		Response: s.marshal(response),
+	}))
+
+	return stream.Recv()
+}
+
+func (s *Suite) configureWithPKI(pki *testPKI, crlPath string) {
+	s.configureWithPKIConfig(pki, fmt.Sprintf("crl_path = %q", crlPath))
+}
+
+func (s *Suite) configureWithPKIConfig(pki *testPKI, extra string) {
+	dir := s.tempDir()
+	rootPath := filepath.Join(dir, "root.pem")
+	s.Require().NoError(ioutil.WriteFile(rootPath, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: pki.root.Raw,
+	}), 0644))
+
+	resp, err := s.p.Configure(context.Background(), &plugin.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+ca_bundle_path = %q
+%s`, rootPath, extra),
+		GlobalConfig: &plugin.ConfigureRequest_GlobalConfig{TrustDomain: "example.org"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(resp, &plugin.ConfigureResponse{})
+}
+
+func (s *Suite) writeCRLs(crls ...[]byte) string {
+	var data []byte
+	for _, crl := range crls {
+		data = append(data, pem.EncodeToMemory(&pem.Block{
+			Type:  "X509 CRL",
+			Bytes: crl,
+		})...)
+	}
+	path := filepath.Join(s.tempDir(), "crl.pem")
+	s.Require().NoError(ioutil.WriteFile(path, data, 0644))
+	return path
+}
+
+func (s *Suite) oid(ids ...uint64) x509.OID {
+	oid, err := x509.OIDFromInts(ids)
+	s.Require().NoError(err)
+	return oid
+}
+
+func (s *Suite) tempDir() string {
+	dir, err := ioutil.TempDir("", "x509pop-")
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// testPKI is a root and intermediate CA with ECDSA keys, independent of the
+// fixtures on disk
+type testPKI struct {
+	s               *Suite
+	root            *x509.Certificate
+	rootKey         *ecdsa.PrivateKey
+	intermediate    *x509.Certificate
+	intermediateKey *ecdsa.PrivateKey
+}
+
+func (s *Suite) newTestPKI() *testPKI {
+	pki := &testPKI{s: s}
+	pki.rootKey, pki.root = pki.issue(&x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ROOT"},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}, nil, nil)
+	pki.intermediateKey, pki.intermediate = pki.issue(&x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "INTERMEDIATE"},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}, pki.root, pki.rootKey)
+	return pki
+}
+
+func (pki *testPKI) issueLeaf(tmpl *x509.Certificate) (*ecdsa.PrivateKey, *x509.Certificate) {
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	return pki.issue(tmpl, pki.intermediate, pki.intermediateKey)
+}
+
+func (pki *testPKI) issue(tmpl, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate) {
+	require := pki.s.Require()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	tmpl.NotBefore = time.Now().Add(-time.Minute)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	require.NoError(err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(err)
+	return key, cert
+}
+
+func (pki *testPKI) rootCRL(serials ...*big.Int) []byte {
+	return pki.crl(pki.root, pki.rootKey, serials, time.Now().Add(time.Hour))
+}
+
+func (pki *testPKI) intermediateCRL(serials ...*big.Int) []byte {
+	return pki.crl(pki.intermediate, pki.intermediateKey, serials, time.Now().Add(time.Hour))
+}
+
+func (pki *testPKI) crl(issuer *x509.Certificate, key *ecdsa.PrivateKey, serials []*big.Int, nextUpdate time.Time) []byte {
+	var entries []x509.RevocationListEntry
+	for _, serial := range serials {
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: time.Now(),
+		})
+	}
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                nextUpdate.Add(-2 * time.Hour),
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: entries,
+	}, issuer, key)
+	pki.s.Require().NoError(err)
+	return crl
+}
+
 func (s *Suite) attest() (nodeattestor.Attest_Stream, func()) {
 	stream, err := s.p.Attest(context.Background())
 	s.Require().NoError(err)
@@ -15,8 +15,9 @@ import (
 // needs to do its job.
 type apiClient interface {
 	SubscriptionID() string
-	GetVirtualMachineResourceID(ctx context.Context, principalID string) (string, error)
+	GetResourceID(ctx context.Context, principalID string) (string, error)
 	GetVirtualMachine(ctx context.Context, resourceGroup string, name string) (*compute.VirtualMachine, error)
+	GetVirtualMachineScaleSet(ctx context.Context, resourceGroup string, name string) (*compute.VirtualMachineScaleSet, error)
 	GetNetworkInterface(ctx context.Context, resourceGroup string, name string) (*network.Interface, error)
 }
 
@@ -25,6 +26,7 @@ type azureClient struct {
 	subscriptionID string
 	r              resources.Client
 	v              compute.VirtualMachinesClient
+	s              compute.VirtualMachineScaleSetsClient
 	n              network.InterfacesClient
 }
 
@@ -35,6 +37,9 @@ func newAzureClient(subscriptionID string, authorizer autorest.Authorizer) apiCl
 	v := compute.NewVirtualMachinesClient(subscriptionID)
 	v.Authorizer = authorizer
 
+	s := compute.NewVirtualMachineScaleSetsClient(subscriptionID)
+	s.Authorizer = authorizer
+
 	n := network.NewInterfacesClient(subscriptionID)
 	n.Authorizer = authorizer
 
@@ -42,6 +47,7 @@ func newAzureClient(subscriptionID string, authorizer autorest.Authorizer) apiCl
 		subscriptionID: subscriptionID,
 		r:              r,
 		v:              v,
+		s:              s,
 		n:              n,
 	}
 }
@@ -50,8 +56,10 @@ func (c *azureClient) SubscriptionID() string {
 	return c.subscriptionID
 }
 
-func (c *azureClient) GetVirtualMachineResourceID(ctx context.Context, principalID string) (string, error) {
-	filter := fmt.Sprintf("resourceType eq 'Microsoft.Compute/virtualMachines' and identity/principalId eq '%s'", principalID)
+// GetResourceID returns the ID of the virtual machine or virtual machine
+// scale set the principal belongs to.
+func (c *azureClient) GetResourceID(ctx context.Context, principalID string) (string, error) {
+	filter := fmt.Sprintf("(resourceType eq 'Microsoft.Compute/virtualMachines' or resourceType eq 'Microsoft.Compute/virtualMachineScaleSets') and identity/principalId eq '%s'", principalID)
 	result, err := c.r.List(ctx, filter, "", nil)
 	if err != nil {
 		return "", errs.Wrap(err)
@@ -69,7 +77,6 @@ func (c *azureClient) GetVirtualMachineResourceID(ctx context.Context, principal
 	}
 
 	return *values[0].ID, nil
-
 }
 
 func (c *azureClient) GetVirtualMachine(ctx context.Context, resourceGroup string, name string) (*compute.VirtualMachine, error) {
@@ -80,6 +87,14 @@ func (c *azureClient) GetVirtualMachine(ctx context.Context, resourceGroup strin
 	return &vm, nil
 }
 
+func (c *azureClient) GetVirtualMachineScaleSet(ctx context.Context, resourceGroup string, name string) (*compute.VirtualMachineScaleSet, error) {
+	vmss, err := c.s.Get(ctx, resourceGroup, name)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return &vmss, nil
+}
+
 func (c *azureClient) GetNetworkInterface(ctx context.Context, resourceGroup string, name string) (*network.Interface, error) {
 	ni, err := c.n.Get(ctx, resourceGroup, name, "")
 	if err != nil {
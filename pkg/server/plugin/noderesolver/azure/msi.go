@@ -31,11 +31,12 @@ const (
 var (
 	msiError = errs.Class("azure-msi")
 
-	reAgentIDPath            = regexp.MustCompile(`^/spire/agent/azure_msi/([^/]+)/([^/]+)`)
-	reVirtualMachineID       = regexp.MustCompile(`^/subscriptions/[^/]+/resourceGroups/([^/]+)/providers/Microsoft.Compute/virtualMachines/([^/]+)$`)
-	reNetworkSecurityGroupID = regexp.MustCompile(`^/subscriptions/[^/]+/resourceGroups/([^/]+)/providers/Microsoft.Network/networkSecurityGroups/([^/]+)$`)
-	reNetworkInterfaceID     = regexp.MustCompile(`^/subscriptions/[^/]+/resourceGroups/([^/]+)/providers/Microsoft.Network/networkInterfaces/([^/]+)$`)
-	reVirtualNetworkSubnetID = regexp.MustCompile(`^/subscriptions/[^/]+/resourceGroups/([^/]+)/providers/Microsoft.Network/virtualNetworks/([^/]+)/subnets/([^/]+)$`)
+	reAgentIDPath              = regexp.MustCompile(`^/spire/agent/azure_msi/([^/]+)/([^/]+)`)
+	reVirtualMachineID         = regexp.MustCompile(`^/subscriptions/[^/]+/resourceGroups/([^/]+)/providers/Microsoft.Compute/virtualMachines/([^/]+)$`)
+	reVirtualMachineScaleSetID = regexp.MustCompile(`^/subscriptions/[^/]+/resourceGroups/([^/]+)/providers/Microsoft.Compute/virtualMachineScaleSets/([^/]+)$`)
+	reNetworkSecurityGroupID   = regexp.MustCompile(`^/subscriptions/[^/]+/resourceGroups/([^/]+)/providers/Microsoft.Network/networkSecurityGroups/([^/]+)$`)
+	reNetworkInterfaceID       = regexp.MustCompile(`^/subscriptions/[^/]+/resourceGroups/([^/]+)/providers/Microsoft.Network/networkInterfaces/([^/]+)$`)
+	reVirtualNetworkSubnetID   = regexp.MustCompile(`^/subscriptions/[^/]+/resourceGroups/([^/]+)/providers/Microsoft.Network/virtualNetworks/([^/]+)/subnets/([^/]+)$`)
 )
 
 type TenantConfig struct {
@@ -182,14 +183,19 @@ func (p *MSIResolverPlugin) resolveSpiffeID(ctx context.Context, spiffeID string
 		return nil, err
 	}
 
-	// Retrieve the resource belonging to the principal id.
-	vmResourceID, err := client.GetVirtualMachineResourceID(ctx, principalID)
+	// Retrieve the resource belonging to the principal id. The identity
+	// either belongs to a virtual machine or to the scale set it is part of.
+	resourceID, err := client.GetResourceID(ctx, principalID)
 	if err != nil {
 		return nil, msiError.New("unable to get resource for principal %q: %v", principalID, err)
 	}
 
-	// parse out the resource group and vm name from the resource ID
-	vmResourceGroup, vmName, err := parseVirtualMachineID(vmResourceID)
+	var resourceSelectors []string
+	if vmssResourceGroup, vmssName, ok := parseVirtualMachineScaleSetID(resourceID); ok {
+		resourceSelectors, err = getVirtualMachineScaleSetSelectors(ctx, client, vmssResourceGroup, vmssName)
+	} else {
+		resourceSelectors, err = getVirtualMachineSelectors(ctx, client, resourceID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -198,25 +204,9 @@ func (p *MSIResolverPlugin) resolveSpiffeID(ctx context.Context, spiffeID string
 	// individual selectors (e.g. the virtual network for each interface)
 	selectorMap := map[string]bool{
 		selectorValue("subscription-id", client.SubscriptionID()): true,
-		selectorValue("vm-name", vmResourceGroup, vmName):         true,
-	}
-	addSelectors := func(values []string) {
-		for _, value := range values {
-			selectorMap[value] = true
-		}
-	}
-
-	// pull the VM information and gather selectors
-	vm, err := client.GetVirtualMachine(ctx, vmResourceGroup, vmName)
-	if err != nil {
-		return nil, msiError.New("unable to get virtual machine %q: %v", resourceGroupName(vmResourceGroup, vmName), err)
 	}
-	if vm.NetworkProfile != nil {
-		networkProfileSelectors, err := getNetworkProfileSelectors(ctx, client, vm.NetworkProfile)
-		if err != nil {
-			return nil, err
-		}
-		addSelectors(networkProfileSelectors)
+	for _, value := range resourceSelectors {
+		selectorMap[value] = true
 	}
 
 	// sort and return selectors
@@ -237,6 +227,109 @@ func (p *MSIResolverPlugin) resolveSpiffeID(ctx context.Context, spiffeID string
 	return selectors, nil
 }
 
+func getVirtualMachineSelectors(ctx context.Context, client apiClient, vmResourceID string) ([]string, error) {
+	// parse out the resource group and vm name from the resource ID
+	vmResourceGroup, vmName, err := parseVirtualMachineID(vmResourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	selectors := []string{
+		selectorValue("resource-group", vmResourceGroup),
+		selectorValue("vm-name", vmResourceGroup, vmName),
+	}
+
+	// pull the VM information and gather selectors
+	vm, err := client.GetVirtualMachine(ctx, vmResourceGroup, vmName)
+	if err != nil {
+		return nil, msiError.New("unable to get virtual machine %q: %v", resourceGroupName(vmResourceGroup, vmName), err)
+	}
+	selectors = append(selectors, getTagSelectors(vm.Tags)...)
+	if vm.VirtualMachineProperties != nil && vm.NetworkProfile != nil {
+		networkProfileSelectors, err := getNetworkProfileSelectors(ctx, client, vm.NetworkProfile)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, networkProfileSelectors...)
+	}
+
+	return selectors, nil
+}
+
+func getVirtualMachineScaleSetSelectors(ctx context.Context, client apiClient, vmssResourceGroup, vmssName string) ([]string, error) {
+	selectors := []string{
+		selectorValue("resource-group", vmssResourceGroup),
+		selectorValue("vm-scale-set", vmssResourceGroup, vmssName),
+	}
+
+	vmss, err := client.GetVirtualMachineScaleSet(ctx, vmssResourceGroup, vmssName)
+	if err != nil {
+		return nil, msiError.New("unable to get virtual machine scale set %q: %v", resourceGroupName(vmssResourceGroup, vmssName), err)
+	}
+	selectors = append(selectors, getTagSelectors(vmss.Tags)...)
+
+	// the scale set network configuration references the security group
+	// and subnets directly, so the network interfaces need not be fetched
+	if props := vmss.VirtualMachineScaleSetProperties; props != nil &&
+		props.VirtualMachineProfile != nil &&
+		props.VirtualMachineProfile.NetworkProfile != nil &&
+		props.VirtualMachineProfile.NetworkProfile.NetworkInterfaceConfigurations != nil {
+		for _, config := range *props.VirtualMachineProfile.NetworkProfile.NetworkInterfaceConfigurations {
+			configSelectors, err := getScaleSetNetworkConfigurationSelectors(config.VirtualMachineScaleSetNetworkConfigurationProperties)
+			if err != nil {
+				return nil, err
+			}
+			selectors = append(selectors, configSelectors...)
+		}
+	}
+
+	return selectors, nil
+}
+
+func getScaleSetNetworkConfigurationSelectors(props *compute.VirtualMachineScaleSetNetworkConfigurationProperties) ([]string, error) {
+	if props == nil {
+		return nil, nil
+	}
+
+	var selectors []string
+	if nsg := props.NetworkSecurityGroup; nsg != nil && nsg.ID != nil {
+		nsgResourceGroup, nsgName, err := parseNetworkSecurityGroupID(*nsg.ID)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selectorValue("network-security-group", nsgResourceGroup, nsgName))
+	}
+
+	if ipcs := props.IPConfigurations; ipcs != nil {
+		for _, ipc := range *ipcs {
+			if ipcProps := ipc.VirtualMachineScaleSetIPConfigurationProperties; ipcProps != nil {
+				if subnet := ipcProps.Subnet; subnet != nil && subnet.ID != nil {
+					subResourceGroup, subVirtualNetwork, subName, err := parseVirtualNetworkSubnetID(*subnet.ID)
+					if err != nil {
+						return nil, err
+					}
+					selectors = append(selectors, selectorValue("virtual-network", subResourceGroup, subVirtualNetwork))
+					selectors = append(selectors, selectorValue("virtual-network-subnet", subResourceGroup, subVirtualNetwork, subName))
+				}
+			}
+		}
+	}
+
+	return selectors, nil
+}
+
+func getTagSelectors(tags map[string]*string) []string {
+	var selectors []string
+	for name, value := range tags {
+		tagValue := ""
+		if value != nil {
+			tagValue = *value
+		}
+		selectors = append(selectors, selectorValue("tag", name, tagValue))
+	}
+	return selectors
+}
+
 func getNetworkProfileSelectors(ctx context.Context, client apiClient, networkProfile *compute.NetworkProfile) ([]string, error) {
 	if networkProfile.NetworkInterfaces == nil {
 		return nil, nil
@@ -311,6 +404,14 @@ func parseVirtualMachineID(id string) (resourceGroup, name string, err error) {
 	return m[1], m[2], nil
 }
 
+func parseVirtualMachineScaleSetID(id string) (resourceGroup, name string, ok bool) {
+	m := reVirtualMachineScaleSetID.FindStringSubmatch(id)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
 func parseNetworkSecurityGroupID(id string) (resourceGroup, name string, err error) {
 	m := reNetworkSecurityGroupID.FindStringSubmatch(id)
 	if m == nil {
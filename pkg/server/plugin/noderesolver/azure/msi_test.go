@@ -17,8 +17,9 @@ import (
 )
 
 const (
-	azureAgentID = "spiffe://example.org/spire/agent/azure_msi/TENANT/PRINCIPAL"
-	vmResourceID = "/subscriptions/SUBSCRIPTIONID/resourceGroups/RESOURCEGROUP/providers/Microsoft.Compute/virtualMachines/VIRTUALMACHINE"
+	azureAgentID   = "spiffe://example.org/spire/agent/azure_msi/TENANT/PRINCIPAL"
+	vmResourceID   = "/subscriptions/SUBSCRIPTIONID/resourceGroups/RESOURCEGROUP/providers/Microsoft.Compute/virtualMachines/VIRTUALMACHINE"
+	vmssResourceID = "/subscriptions/SUBSCRIPTIONID/resourceGroups/RESOURCEGROUP/providers/Microsoft.Compute/virtualMachineScaleSets/SCALESET"
 )
 
 var (
@@ -30,9 +31,19 @@ var (
 
 	// these are expected selectors
 	vmSelectors = []string{
+		"resource-group:RESOURCEGROUP",
 		"subscription-id:SUBSCRIPTION",
 		"vm-name:RESOURCEGROUP:VIRTUALMACHINE",
 	}
+	vmssSelectors = []string{
+		"resource-group:RESOURCEGROUP",
+		"subscription-id:SUBSCRIPTION",
+		"vm-scale-set:RESOURCEGROUP:SCALESET",
+	}
+	tagSelectors = []string{
+		"tag:EMPTY:",
+		"tag:TEAM:BLOG",
+	}
 	niSelectors = []string{
 		"network-security-group:NSGRESOURCEGROUP:NETWORKSECURITYGROUP",
 		"virtual-network:NETRESOURCEGROUP:VIRTUALNETWORK",
@@ -99,19 +110,19 @@ func (s *MSIResolverSuite) TestResolveWithUnrecognizedTenant() {
 }
 
 func (s *MSIResolverSuite) TestResolveWithNoVirtualMachineResource() {
-	s.api.SetVirtualMachineResourceID("PRINCIPAL", "")
+	s.api.SetResourceID("PRINCIPAL", "")
 	s.assertResolveFailure(azureAgentID,
 		`azure-msi: unable to get resource for principal "PRINCIPAL": not found`)
 }
 
 func (s *MSIResolverSuite) TestResolveWithMalformedResourceID() {
-	s.api.SetVirtualMachineResourceID("PRINCIPAL", malformedResourceID)
+	s.api.SetResourceID("PRINCIPAL", malformedResourceID)
 	s.assertResolveFailure(azureAgentID,
 		`azure-msi: malformed virtual machine ID "MALFORMEDRESOURCEID"`)
 }
 
 func (s *MSIResolverSuite) TestResolveWithNoVirtualMachineInfo() {
-	s.api.SetVirtualMachineResourceID("PRINCIPAL", vmResourceID)
+	s.api.SetResourceID("PRINCIPAL", vmResourceID)
 	s.assertResolveFailure(azureAgentID,
 		`azure-msi: unable to get virtual machine "RESOURCEGROUP:VIRTUALMACHINE"`)
 }
@@ -182,6 +193,64 @@ func (s *MSIResolverSuite) TestResolveVirtualMachine() {
 	ni.NetworkSecurityGroup = &network.SecurityGroup{ID: &nsgResourceID}
 	props.Subnet.ID = &subnetResourceID
 	s.assertResolveSuccess(vmSelectors, niSelectors)
+
+	// virtual machine with tags
+	vm.Tags = makeTags()
+	s.assertResolveSuccess(vmSelectors, niSelectors, tagSelectors)
+}
+
+func (s *MSIResolverSuite) TestResolveWithNoVirtualMachineScaleSetInfo() {
+	s.api.SetResourceID("PRINCIPAL", vmssResourceID)
+	s.assertResolveFailure(azureAgentID,
+		`azure-msi: unable to get virtual machine scale set "RESOURCEGROUP:SCALESET"`)
+}
+
+func (s *MSIResolverSuite) TestResolveVirtualMachineScaleSet() {
+	vmss := &compute.VirtualMachineScaleSet{}
+	s.setVirtualMachineScaleSet(vmss)
+
+	// no properties
+	s.assertResolveSuccess(vmssSelectors)
+
+	// network profile with no interface configurations
+	vmss.VirtualMachineScaleSetProperties = &compute.VirtualMachineScaleSetProperties{
+		VirtualMachineProfile: &compute.VirtualMachineScaleSetVMProfile{
+			NetworkProfile: &compute.VirtualMachineScaleSetNetworkProfile{},
+		},
+	}
+	s.assertResolveSuccess(vmssSelectors)
+
+	// network profile with empty interface configuration
+	props := new(compute.VirtualMachineScaleSetNetworkConfigurationProperties)
+	vmss.VirtualMachineProfile.NetworkProfile.NetworkInterfaceConfigurations = &[]compute.VirtualMachineScaleSetNetworkConfiguration{
+		{VirtualMachineScaleSetNetworkConfigurationProperties: props},
+	}
+	s.assertResolveSuccess(vmssSelectors)
+
+	// interface configuration with malformed security group
+	props.NetworkSecurityGroup = &compute.SubResource{ID: &malformedResourceID}
+	s.assertResolveFailure(azureAgentID,
+		`azure-msi: malformed network security group ID "MALFORMEDRESOURCEID"`)
+
+	// interface configuration with malformed subnet
+	props.NetworkSecurityGroup = &compute.SubResource{ID: &nsgResourceID}
+	props.IPConfigurations = &[]compute.VirtualMachineScaleSetIPConfiguration{
+		{
+			VirtualMachineScaleSetIPConfigurationProperties: &compute.VirtualMachineScaleSetIPConfigurationProperties{
+				Subnet: &compute.APIEntityReference{ID: &malformedResourceID},
+			},
+		},
+	}
+	s.assertResolveFailure(azureAgentID,
+		`azure-msi: malformed virtual network subnet ID "MALFORMEDRESOURCEID"`)
+
+	// interface configuration with good subnet and security group
+	(*props.IPConfigurations)[0].Subnet.ID = &subnetResourceID
+	s.assertResolveSuccess(vmssSelectors, niSelectors)
+
+	// scale set with tags
+	vmss.Tags = makeTags()
+	s.assertResolveSuccess(vmssSelectors, niSelectors, tagSelectors)
 }
 
 func (s *MSIResolverSuite) TestConfigure() {
@@ -331,10 +400,15 @@ func (s *MSIResolverSuite) addVirtualMachine() {
 }
 
 func (s *MSIResolverSuite) setVirtualMachine(vm *compute.VirtualMachine) {
-	s.api.SetVirtualMachineResourceID("PRINCIPAL", vmResourceID)
+	s.api.SetResourceID("PRINCIPAL", vmResourceID)
 	s.api.SetVirtualMachine("RESOURCEGROUP", "VIRTUALMACHINE", vm)
 }
 
+func (s *MSIResolverSuite) setVirtualMachineScaleSet(vmss *compute.VirtualMachineScaleSet) {
+	s.api.SetResourceID("PRINCIPAL", vmssResourceID)
+	s.api.SetVirtualMachineScaleSet("RESOURCEGROUP", "SCALESET", vmss)
+}
+
 func (s *MSIResolverSuite) setNetworkInterface(ni *network.Interface) {
 	s.api.SetNetworkInterface("RESOURCEGROUP", "NETWORKINTERFACE", ni)
 }
@@ -344,6 +418,7 @@ type fakeAPIClient struct {
 
 	vmResourceIDs     map[string]string
 	virtualMachines   map[string]*compute.VirtualMachine
+	scaleSets         map[string]*compute.VirtualMachineScaleSet
 	networkInterfaces map[string]*network.Interface
 }
 
@@ -352,6 +427,7 @@ func newFakeAPIClient(t testing.TB) *fakeAPIClient {
 		t:                 t,
 		vmResourceIDs:     make(map[string]string),
 		virtualMachines:   make(map[string]*compute.VirtualMachine),
+		scaleSets:         make(map[string]*compute.VirtualMachineScaleSet),
 		networkInterfaces: make(map[string]*network.Interface),
 	}
 }
@@ -360,11 +436,11 @@ func (c *fakeAPIClient) SubscriptionID() string {
 	return "SUBSCRIPTION"
 }
 
-func (c *fakeAPIClient) SetVirtualMachineResourceID(principalID, resourceID string) {
+func (c *fakeAPIClient) SetResourceID(principalID, resourceID string) {
 	c.vmResourceIDs[principalID] = resourceID
 }
 
-func (c *fakeAPIClient) GetVirtualMachineResourceID(ctx context.Context, principalID string) (string, error) {
+func (c *fakeAPIClient) GetResourceID(ctx context.Context, principalID string) (string, error) {
 	id := c.vmResourceIDs[principalID]
 	if id == "" {
 		return "", errors.New("not found")
@@ -384,6 +460,18 @@ func (c *fakeAPIClient) GetVirtualMachine(ctx context.Context, resourceGroup str
 	return vm, nil
 }
 
+func (c *fakeAPIClient) SetVirtualMachineScaleSet(resourceGroup string, name string, vmss *compute.VirtualMachineScaleSet) {
+	c.scaleSets[resourceGroupName(resourceGroup, name)] = vmss
+}
+
+func (c *fakeAPIClient) GetVirtualMachineScaleSet(ctx context.Context, resourceGroup string, name string) (*compute.VirtualMachineScaleSet, error) {
+	vmss := c.scaleSets[resourceGroupName(resourceGroup, name)]
+	if vmss == nil {
+		return nil, errors.New("not found")
+	}
+	return vmss, nil
+}
+
 func (c *fakeAPIClient) SetNetworkInterface(resourceGroup string, name string, ni *network.Interface) {
 	c.networkInterfaces[resourceGroupName(resourceGroup, name)] = ni
 }
@@ -395,3 +483,11 @@ func (c *fakeAPIClient) GetNetworkInterface(ctx context.Context, resourceGroup s
 	}
 	return ni, nil
 }
+
+func makeTags() map[string]*string {
+	team := "BLOG"
+	return map[string]*string{
+		"TEAM":  &team,
+		"EMPTY": nil,
+	}
+}
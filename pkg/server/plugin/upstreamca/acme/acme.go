@@ -0,0 +1,475 @@
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/diskutil"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/x509util"
+	"github.com/zeebo/errs"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/upstreamca"
+)
+
+const (
+	defaultHTTP01Address   = ":80"
+	defaultIssuanceTimeout = 5 * time.Minute
+)
+
+var (
+	acmeErr = errs.Class("upstreamca(acme)")
+)
+
+type configuration struct {
+	// DirectoryURL is the URL of the ACME directory of the CA
+	DirectoryURL string `hcl:"directory_url"`
+
+	// Email is the optional contact address of the ACME account
+	Email string `hcl:"email"`
+
+	// AgreeToTermsOfService indicates agreement to the terms of service of
+	// the CA when the account is created
+	AgreeToTermsOfService bool `hcl:"agree_to_terms_of_service"`
+
+	// AccountKeyFile is the path to the PEM encoded P-256 account key. It is
+	// generated if it does not exist. If unset, a new account is created
+	// every time the plugin is configured.
+	AccountKeyFile string `hcl:"account_key_file"`
+
+	// EABKeyID and EABHMACKey are the external account binding credentials
+	// provided by the CA, if it requires them. The HMAC key is base64url
+	// encoded.
+	EABKeyID   string `hcl:"eab_key_id"`
+	EABHMACKey string `hcl:"eab_hmac_key"`
+
+	// HTTP01Address is the address the http-01 challenge responder listens
+	// on while authorizations are pending
+	HTTP01Address string `hcl:"http01_address"`
+
+	// TrustBundleFile is the optional path to the PEM encoded roots of the
+	// CA, which ACME servers do not return
+	TrustBundleFile string `hcl:"trust_bundle_file"`
+
+	// CAFile is the optional path to the CA certificates used to
+	// authenticate the ACME server
+	CAFile string `hcl:"ca_file"`
+
+	// IssuanceTimeout is how long to wait for the authorizations to be
+	// validated and the certificate to be issued
+	IssuanceTimeout string `hcl:"issuance_timeout"`
+
+	issuanceTimeout time.Duration
+	eab             *externalAccountBinding
+}
+
+type UpstreamCA struct {
+	// log is set by the catalog before the plugin is configured
+	log logrus.FieldLogger
+
+	mu        sync.RWMutex
+	config    *configuration
+	client    *acmeClient
+	responder *http01Responder
+
+	// registerMu serializes the account registration
+	registerMu sync.Mutex
+
+	hooks struct {
+		listen       func(network, address string) (net.Listener, error)
+		pollInterval time.Duration
+	}
+}
+
+var _ upstreamca.Plugin = (*UpstreamCA)(nil)
+
+func New() *UpstreamCA {
+	p := &UpstreamCA{
+		log:       logrus.StandardLogger(),
+		responder: newHTTP01Responder(),
+	}
+	p.hooks.listen = net.Listen
+	p.hooks.pollInterval = time.Second
+	return p
+}
+
+func (p *UpstreamCA) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, acmeErr.New("unable to decode configuration: %v", err)
+	}
+
+	if config.DirectoryURL == "" {
+		return nil, acmeErr.New("directory_url is required")
+	}
+	if u, err := url.Parse(config.DirectoryURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, acmeErr.New("directory_url %q is not a valid URL", config.DirectoryURL)
+	}
+	switch {
+	case config.EABKeyID != "" && config.EABHMACKey != "":
+		hmacKey, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(config.EABHMACKey, "="))
+		if err != nil {
+			return nil, acmeErr.New("unable to decode eab_hmac_key: %v", err)
+		}
+		config.eab = &externalAccountBinding{
+			KeyID:   config.EABKeyID,
+			HMACKey: hmacKey,
+		}
+	case config.EABKeyID != "" || config.EABHMACKey != "":
+		return nil, acmeErr.New("eab_key_id and eab_hmac_key must be set together")
+	}
+	if config.HTTP01Address == "" {
+		config.HTTP01Address = defaultHTTP01Address
+	}
+	config.issuanceTimeout = defaultIssuanceTimeout
+	if config.IssuanceTimeout != "" {
+		timeout, err := time.ParseDuration(config.IssuanceTimeout)
+		if err != nil {
+			return nil, acmeErr.New("invalid issuance_timeout value: %v", err)
+		}
+		config.issuanceTimeout = timeout
+	}
+	if config.TrustBundleFile != "" {
+		if _, err := pemutil.LoadCertificates(config.TrustBundleFile); err != nil {
+			return nil, acmeErr.New("unable to load trust bundle: %v", err)
+		}
+	}
+
+	key, err := loadAccountKey(config.AccountKeyFile)
+	if err != nil {
+		return nil, acmeErr.Wrap(err)
+	}
+	client, err := newACMEClient(config.DirectoryURL, key, config.CAFile)
+	if err != nil {
+		return nil, acmeErr.Wrap(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	p.client = client
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+// SetLogger sets the logger the plugin logs to
+func (p *UpstreamCA) SetLogger(log logrus.FieldLogger) {
+	p.log = log
+}
+
+func (p *UpstreamCA) GetPluginInfo(ctx context.Context, req *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+// SubmitCSR orders a certificate for the names in the CSR from the ACME
+// server, answering http-01 challenges for pending authorizations. The server
+// CA manager submits a new CSR ahead of the expiry of the current
+// certificate, so every call places a new order.
+func (p *UpstreamCA) SubmitCSR(ctx context.Context, req *upstreamca.SubmitCSRRequest) (*upstreamca.SubmitCSRResponse, error) {
+	config, client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	csr, err := x509.ParseCertificateRequest(req.Csr)
+	if err != nil {
+		return nil, acmeErr.New("unable to parse CSR: %v", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, acmeErr.New("CSR signature check failed: %v", err)
+	}
+	identifiers := identifiersFromCSR(csr)
+	if len(identifiers) == 0 {
+		return nil, acmeErr.New("CSR has no DNS names, IP addresses or common name to order a certificate for; set the common name of the server ca_subject")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.issuanceTimeout)
+	defer cancel()
+
+	if err := p.register(ctx, config, client); err != nil {
+		return nil, err
+	}
+
+	o, err := client.CreateOrder(ctx, identifiers)
+	if err != nil {
+		return nil, acmeErr.New("unable to create order: %v", err)
+	}
+
+	var stopResponder func()
+	defer func() {
+		if stopResponder != nil {
+			stopResponder()
+		}
+	}()
+	for _, authzURL := range o.Authorizations {
+		authz, err := client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return nil, acmeErr.New("unable to get authorization: %v", err)
+		}
+		switch authz.Status {
+		case statusValid:
+			continue
+		case statusPending:
+		default:
+			return nil, acmeErr.New("authorization for %q is %s", authz.Identifier.Value, authz.Status)
+		}
+
+		if stopResponder == nil {
+			listener, err := p.hooks.listen("tcp", config.HTTP01Address)
+			if err != nil {
+				return nil, acmeErr.New("unable to listen for http-01 challenges: %v", err)
+			}
+			stopResponder = p.responder.serve(listener)
+		}
+		if err := p.solveHTTP01(ctx, client, authzURL, authz); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.poll(ctx, "order to be ready", func() (bool, error) {
+		if o.Status != statusPending {
+			return true, nil
+		}
+		o, err = client.GetOrder(ctx, o.URL)
+		return false, err
+	}); err != nil {
+		return nil, err
+	}
+	if o.Status != statusReady {
+		return nil, orderError(o)
+	}
+
+	finalized, err := client.FinalizeOrder(ctx, o.Finalize, req.Csr)
+	if err != nil {
+		return nil, acmeErr.New("unable to finalize order: %v", err)
+	}
+	o.Status, o.Certificate, o.Error = finalized.Status, finalized.Certificate, finalized.Error
+	if err := p.poll(ctx, "certificate to be issued", func() (bool, error) {
+		if o.Status != statusProcessing && o.Status != statusReady {
+			return true, nil
+		}
+		o, err = client.GetOrder(ctx, o.URL)
+		return false, err
+	}); err != nil {
+		return nil, err
+	}
+	if o.Status != statusValid {
+		return nil, orderError(o)
+	}
+
+	chainPEM, err := client.FetchCertificate(ctx, o.Certificate)
+	if err != nil {
+		return nil, acmeErr.New("unable to fetch certificate: %v", err)
+	}
+	certs, err := pemutil.ParseCertificates(chainPEM)
+	if err != nil {
+		return nil, acmeErr.New("unable to parse issued certificate chain: %v", err)
+	}
+	intermediates, roots := x509util.SplitRoots(certs[1:])
+
+	if config.TrustBundleFile != "" {
+		bundleCerts, err := pemutil.LoadCertificates(config.TrustBundleFile)
+		if err != nil {
+			return nil, acmeErr.New("unable to load trust bundle: %v", err)
+		}
+		roots = x509util.AppendUniqueCertificates(roots, bundleCerts...)
+	}
+	if len(roots) == 0 {
+		return nil, acmeErr.New("no root certificates found in the issued chain; configure trust_bundle_file")
+	}
+
+	p.log.Infof("Issued certificate for %s expiring at %s", identifierValues(identifiers), certs[0].NotAfter.UTC().Format(time.RFC3339))
+
+	return &upstreamca.SubmitCSRResponse{
+		SignedCertificate: &upstreamca.SignedCertificate{
+			CertChain: x509util.DERFromCertificates(append([]*x509.Certificate{certs[0]}, intermediates...)),
+			Bundle:    x509util.DERFromCertificates(roots),
+		},
+	}, nil
+}
+
+// register creates or looks up the ACME account, once per configuration
+func (p *UpstreamCA) register(ctx context.Context, config *configuration, client *acmeClient) error {
+	p.registerMu.Lock()
+	defer p.registerMu.Unlock()
+
+	if client.AccountURL() != "" {
+		return nil
+	}
+
+	var contact []string
+	if config.Email != "" {
+		contact = append(contact, "mailto:"+config.Email)
+	}
+	if err := client.Register(ctx, contact, config.AgreeToTermsOfService, config.eab); err != nil {
+		return acmeErr.New("unable to register ACME account: %v", err)
+	}
+	p.log.Infof("Using ACME account %s", client.AccountURL())
+	return nil
+}
+
+// solveHTTP01 serves the key authorization of the http-01 challenge of the
+// authorization and waits for the server to validate it
+func (p *UpstreamCA) solveHTTP01(ctx context.Context, client *acmeClient, authzURL string, authz *authorization) error {
+	var chal *challenge
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == challengeHTTP01 {
+			chal = &authz.Challenges[i]
+			break
+		}
+	}
+	if chal == nil {
+		return acmeErr.New("no http-01 challenge offered for %q", authz.Identifier.Value)
+	}
+
+	keyAuthorization, err := client.KeyAuthorization(chal.Token)
+	if err != nil {
+		return acmeErr.Wrap(err)
+	}
+	p.responder.Set(chal.Token, keyAuthorization)
+	defer p.responder.Remove(chal.Token)
+
+	if err := client.AcceptChallenge(ctx, chal.URL); err != nil {
+		return acmeErr.New("unable to accept http-01 challenge for %q: %v", authz.Identifier.Value, err)
+	}
+
+	if err := p.poll(ctx, "authorization of "+authz.Identifier.Value, func() (bool, error) {
+		if authz.Status != statusPending {
+			return true, nil
+		}
+		authz, err = client.GetAuthorization(ctx, authzURL)
+		return false, err
+	}); err != nil {
+		return err
+	}
+	if authz.Status != statusValid {
+		for _, c := range authz.Challenges {
+			if c.Error != nil {
+				return acmeErr.New("authorization for %q is %s: %s: %s", authz.Identifier.Value, authz.Status, c.Error.Type, c.Error.Detail)
+			}
+		}
+		return acmeErr.New("authorization for %q is %s", authz.Identifier.Value, authz.Status)
+	}
+	return nil
+}
+
+// poll calls fn every poll interval until it reports it is done or fails
+func (p *UpstreamCA) poll(ctx context.Context, what string, fn func() (bool, error)) error {
+	ticker := time.NewTicker(p.hooks.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		done, err := fn()
+		switch {
+		case err != nil:
+			return acmeErr.New("unable to wait for %s: %v", what, err)
+		case done:
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return acmeErr.New("timed out waiting for %s: %v", what, ctx.Err())
+		}
+	}
+}
+
+func (p *UpstreamCA) getClient() (*configuration, *acmeClient, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.client == nil {
+		return nil, nil, acmeErr.New("not configured")
+	}
+	return p.config, p.client, nil
+}
+
+// identifiersFromCSR returns the identifiers to order a certificate for. ACME
+// servers check the names in the CSR, including its common name, against
+// the order.
+func identifiersFromCSR(csr *x509.CertificateRequest) []identifier {
+	var identifiers []identifier
+	seen := make(map[identifier]bool)
+	add := func(id identifier) {
+		if !seen[id] {
+			seen[id] = true
+			identifiers = append(identifiers, id)
+		}
+	}
+
+	if cn := csr.Subject.CommonName; cn != "" {
+		if ip := net.ParseIP(cn); ip != nil {
+			add(identifier{Type: "ip", Value: ip.String()})
+		} else {
+			add(identifier{Type: "dns", Value: strings.ToLower(cn)})
+		}
+	}
+	for _, name := range csr.DNSNames {
+		add(identifier{Type: "dns", Value: strings.ToLower(name)})
+	}
+	for _, ip := range csr.IPAddresses {
+		add(identifier{Type: "ip", Value: ip.String()})
+	}
+	return identifiers
+}
+
+func identifierValues(identifiers []identifier) string {
+	var values []string
+	for _, id := range identifiers {
+		values = append(values, id.Value)
+	}
+	return strings.Join(values, ",")
+}
+
+func orderError(o *order) error {
+	if o.Error != nil {
+		return acmeErr.New("order is %s: %s: %s", o.Status, o.Error.Type, o.Error.Detail)
+	}
+	return acmeErr.New("order is %s", o.Status)
+}
+
+// loadAccountKey loads the account key from the path, generating and storing
+// it if it does not exist yet. An ephemeral key is generated if the path is
+// empty.
+func loadAccountKey(path string) (*ecdsa.PrivateKey, error) {
+	if path != "" {
+		key, err := pemutil.LoadECPrivateKey(path)
+		switch {
+		case err == nil:
+			if key.Curve != elliptic.P256() {
+				return nil, errs.New("account key %q is not a P-256 key", path)
+			}
+			return key, nil
+		case !os.IsNotExist(err):
+			return nil, errs.New("unable to load account key: %v", err)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if path != "" {
+		keyPEM, err := pemutil.EncodePKCS8PrivateKey(key)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		if err := diskutil.AtomicWriteFile(path, keyPEM, 0600); err != nil {
+			return nil, errs.New("unable to write account key: %v", err)
+		}
+	}
+	return key, nil
+}
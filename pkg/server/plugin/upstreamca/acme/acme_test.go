@@ -0,0 +1,588 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/x509util"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/upstreamca"
+	"github.com/stretchr/testify/suite"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+var (
+	ctx = context.Background()
+
+	testEABHMACKey = []byte("0123456789abcdef0123456789abcdef")
+)
+
+func TestUpstreamCA(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	dir    string
+	server *fakeACMEServer
+}
+
+func (s *Suite) SetupTest() {
+	dir, err := ioutil.TempDir("", "upstreamca-acme")
+	s.Require().NoError(err)
+	s.dir = dir
+	s.server = s.newFakeACMEServer()
+	s.writeFile("root.pem", pemutil.EncodeCertificate(s.server.root))
+}
+
+func (s *Suite) TearDownTest() {
+	s.server.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *Suite) newUpstreamCA(config string) *UpstreamCA {
+	p := New()
+	p.hooks.listen = func(network, address string) (net.Listener, error) {
+		s.Require().Equal(":80", address)
+		listener, err := net.Listen(network, "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+		s.server.setChallengeAddr(listener.Addr().String())
+		return listener, nil
+	}
+	p.hooks.pollInterval = time.Millisecond
+	resp, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`directory_url = %q
+			%s`, s.server.URL+"/directory", config),
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&spi.ConfigureResponse{}, resp)
+	return p
+}
+
+func (s *Suite) TestConfigureRequiresDirectoryURL() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{})
+	s.Require().EqualError(err, "upstreamca(acme): directory_url is required")
+}
+
+func (s *Suite) TestConfigureRequiresBothEABOptions() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{
+		Configuration: `
+			directory_url = "https://acme.example.org/directory"
+			eab_key_id = "KID"
+		`,
+	})
+	s.Require().EqualError(err, "upstreamca(acme): eab_key_id and eab_hmac_key must be set together")
+}
+
+func (s *Suite) TestConfigureRejectsMissingTrustBundle() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+			directory_url = "https://acme.example.org/directory"
+			trust_bundle_file = %q
+		`, filepath.Join(s.dir, "missing.pem")),
+	})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "upstreamca(acme): unable to load trust bundle")
+}
+
+func (s *Suite) TestSubmitCSRNotConfigured() {
+	_, err := New().SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire.example.org")})
+	s.Require().EqualError(err, "upstreamca(acme): not configured")
+}
+
+func (s *Suite) TestSubmitCSRRequiresCommonName() {
+	p := s.newUpstreamCA("")
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("")})
+	s.Require().EqualError(err, "upstreamca(acme): CSR has no DNS names, IP addresses or common name to order a certificate for; set the common name of the server ca_subject")
+}
+
+func (s *Suite) TestSubmitCSR() {
+	accountKeyFile := filepath.Join(s.dir, "account.key")
+	p := s.newUpstreamCA(fmt.Sprintf(`
+		email = "admin@example.org"
+		account_key_file = %q
+		trust_bundle_file = %q
+	`, accountKeyFile, filepath.Join(s.dir, "root.pem")))
+
+	csr := s.makeCSR("spire.example.org")
+	resp, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: csr})
+	s.Require().NoError(err)
+
+	// the order is placed for the common name and its http-01 challenge was
+	// validated against the responder
+	s.Require().Equal([]identifier{{Type: "dns", Value: "spire.example.org"}}, s.server.lastOrder.Identifiers)
+	s.Require().True(s.server.validated)
+	s.Require().Equal([]string{"mailto:admin@example.org"}, s.server.lastContact)
+
+	chain, err := x509.ParseCertificates(resp.SignedCertificate.CertChain)
+	s.Require().NoError(err)
+	s.Require().Len(chain, 2)
+	s.Require().Equal("spire.example.org", chain[0].Subject.CommonName)
+	s.Require().True(chain[1].Equal(s.server.intermediate))
+
+	bundle, err := x509.ParseCertificates(resp.SignedCertificate.Bundle)
+	s.Require().NoError(err)
+	s.Require().Len(bundle, 1)
+	s.Require().True(bundle[0].Equal(s.server.root))
+
+	// renewing places a new order with the same account
+	resp, err = p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire.example.org")})
+	s.Require().NoError(err)
+	renewed, err := x509.ParseCertificates(resp.SignedCertificate.CertChain)
+	s.Require().NoError(err)
+	s.Require().NotEqual(chain[0].SerialNumber, renewed[0].SerialNumber)
+	s.Require().Equal(2, s.server.orderCount)
+	s.Require().Len(s.server.accounts, 1)
+
+	// the account key is persisted and reused after reconfiguration
+	_, err = os.Stat(accountKeyFile)
+	s.Require().NoError(err)
+	p = s.newUpstreamCA(fmt.Sprintf(`
+		account_key_file = %q
+		trust_bundle_file = %q
+	`, accountKeyFile, filepath.Join(s.dir, "root.pem")))
+	_, err = p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire.example.org")})
+	s.Require().NoError(err)
+	s.Require().Len(s.server.accounts, 1)
+}
+
+func (s *Suite) TestSubmitCSRWithPreauthorizedIdentifiers() {
+	p := s.newUpstreamCA("")
+	p.hooks.listen = func(network, address string) (net.Listener, error) {
+		s.FailNow("unexpected http-01 listener")
+		return nil, nil
+	}
+	s.server.preauthorized = true
+	s.server.includeRoot = true
+
+	resp, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire.example.org")})
+	s.Require().NoError(err)
+
+	// the root is taken from the issued chain
+	chain, err := x509.ParseCertificates(resp.SignedCertificate.CertChain)
+	s.Require().NoError(err)
+	s.Require().Len(chain, 2)
+	bundle, err := x509.ParseCertificates(resp.SignedCertificate.Bundle)
+	s.Require().NoError(err)
+	s.Require().Len(bundle, 1)
+	s.Require().True(bundle[0].Equal(s.server.root))
+}
+
+func (s *Suite) TestSubmitCSRWithExternalAccountBinding() {
+	s.server.requireEAB = true
+
+	p := s.newUpstreamCA(fmt.Sprintf(`trust_bundle_file = %q`, filepath.Join(s.dir, "root.pem")))
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire.example.org")})
+	s.Require().EqualError(err, "upstreamca(acme): unable to register ACME account: the ACME server requires an external account binding")
+
+	p = s.newUpstreamCA(fmt.Sprintf(`
+		eab_key_id = "KID"
+		eab_hmac_key = %q
+		trust_bundle_file = %q
+	`, base64.RawURLEncoding.EncodeToString(testEABHMACKey), filepath.Join(s.dir, "root.pem")))
+	_, err = p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire.example.org")})
+	s.Require().NoError(err)
+}
+
+func (s *Suite) TestSubmitCSRFailsValidation() {
+	p := s.newUpstreamCA("")
+	s.server.failValidation = true
+
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire.example.org")})
+	s.Require().EqualError(err, `upstreamca(acme): authorization for "spire.example.org" is invalid: urn:ietf:params:acme:error:unauthorized: key authorization mismatch`)
+}
+
+func (s *Suite) TestSubmitCSRRequiresRoots() {
+	p := s.newUpstreamCA("")
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire.example.org")})
+	s.Require().EqualError(err, "upstreamca(acme): no root certificates found in the issued chain; configure trust_bundle_file")
+}
+
+func (s *Suite) TestSubmitCSRRetriesBadNonce() {
+	p := s.newUpstreamCA(fmt.Sprintf(`trust_bundle_file = %q`, filepath.Join(s.dir, "root.pem")))
+	s.server.badNonces = 1
+
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire.example.org")})
+	s.Require().NoError(err)
+	s.Require().Equal(0, s.server.badNonces)
+}
+
+func (s *Suite) makeCSR(commonName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName},
+		URIs:    []*url.URL{{Scheme: "spiffe", Host: "example.org"}},
+	}, key)
+	s.Require().NoError(err)
+	return csr
+}
+
+func (s *Suite) writeFile(name string, data []byte) {
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, name), data, 0600))
+}
+
+// fakeACMEServer implements enough of RFC 8555 to issue certificates from an
+// intermediate CA under a root, validating http-01 challenges against the
+// challenge address.
+type fakeACMEServer struct {
+	*httptest.Server
+	s *Suite
+
+	root            *x509.Certificate
+	intermediate    *x509.Certificate
+	intermediateKey *ecdsa.PrivateKey
+	serialNumber    x509util.SerialNumber
+
+	// preauthorized creates authorizations that are already valid
+	preauthorized bool
+	// requireEAB requires an external account binding
+	requireEAB bool
+	// failValidation fails the http-01 validations
+	failValidation bool
+	// includeRoot includes the root in the issued chain
+	includeRoot bool
+	// badNonces is the number of requests to reject with a badNonce error
+	badNonces int
+
+	mu            sync.Mutex
+	challengeAddr string
+	nextNonce     int
+	nonces        map[string]bool
+	accounts      map[string]*jose.JSONWebKey
+	orders        map[string]*order
+	authzs        map[string]*authorization
+	certs         map[string]*x509.Certificate
+	orderCount    int
+	lastOrder     *order
+	lastContact   []string
+	validated     bool
+}
+
+func (s *Suite) newFakeACMEServer() *fakeACMEServer {
+	f := &fakeACMEServer{
+		s:            s,
+		serialNumber: x509util.NewSerialNumber(),
+		nonces:       make(map[string]bool),
+		accounts:     make(map[string]*jose.JSONWebKey),
+		orders:       make(map[string]*order),
+		authzs:       make(map[string]*authorization),
+		certs:        make(map[string]*x509.Certificate),
+	}
+	var rootKey *ecdsa.PrivateKey
+	f.root, rootKey = f.createCA("root", nil, nil)
+	f.intermediate, f.intermediateKey = f.createCA("intermediate", f.root, rootKey)
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	return f
+}
+
+func (f *fakeACMEServer) setChallengeAddr(addr string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.challengeAddr = addr
+}
+
+func (f *fakeACMEServer) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case req.Method == "GET" && req.URL.Path == "/directory":
+		dir := directory{
+			NewNonce:   f.URL + "/new-nonce",
+			NewAccount: f.URL + "/new-account",
+			NewOrder:   f.URL + "/new-order",
+		}
+		dir.Meta.ExternalAccountRequired = f.requireEAB
+		f.writeJSON(w, http.StatusOK, dir)
+		return
+	case req.Method == "HEAD" && req.URL.Path == "/new-nonce":
+		w.Header().Set("Replay-Nonce", f.newNonce())
+		return
+	case req.Method != "POST":
+		http.NotFound(w, req)
+		return
+	}
+
+	w.Header().Set("Replay-Nonce", f.newNonce())
+	payload, key, prob := f.verify(req)
+	if prob != nil {
+		f.writeJSON(w, http.StatusBadRequest, prob)
+		return
+	}
+
+	switch path := req.URL.Path; {
+	case path == "/new-account":
+		f.newAccount(w, payload, key)
+	case path == "/new-order":
+		f.newOrder(w, payload)
+	case strings.HasPrefix(path, "/order/"):
+		f.writeJSON(w, http.StatusOK, f.orders[path])
+	case strings.HasPrefix(path, "/authz/"):
+		f.writeJSON(w, http.StatusOK, f.authzs[path])
+	case strings.HasPrefix(path, "/chall/"):
+		f.validate(w, strings.Replace(path, "/chall/", "/authz/", 1), key)
+	case strings.HasPrefix(path, "/finalize/"):
+		f.finalize(w, strings.Replace(path, "/finalize/", "/order/", 1), payload)
+	case strings.HasPrefix(path, "/cert/"):
+		f.certificate(w, strings.Replace(path, "/cert/", "/order/", 1))
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// verify checks the nonce, url and signature of the JWS request, returning
+// its payload and the account key
+func (f *fakeACMEServer) verify(req *http.Request) ([]byte, *jose.JSONWebKey, *problem) {
+	body, err := ioutil.ReadAll(req.Body)
+	f.s.Require().NoError(err)
+	f.s.Require().Equal(contentTypeJOSE, req.Header.Get("Content-Type"))
+
+	jws, err := jose.ParseSigned(string(body))
+	f.s.Require().NoError(err)
+	header := jws.Signatures[0].Protected
+	f.s.Require().Equal(f.URL+req.URL.Path, header.ExtraHeaders["url"])
+
+	if !f.nonces[header.Nonce] {
+		return nil, nil, &problem{Type: errBadNonce, Detail: "unknown nonce"}
+	}
+	delete(f.nonces, header.Nonce)
+	if f.badNonces > 0 {
+		f.badNonces--
+		return nil, nil, &problem{Type: errBadNonce, Detail: "nonce rejected"}
+	}
+
+	key := header.JSONWebKey
+	if req.URL.Path == "/new-account" {
+		f.s.Require().NotNil(key)
+	} else {
+		f.s.Require().Nil(key)
+		key = f.accounts[header.KeyID]
+		f.s.Require().NotNil(key, "unknown account %q", header.KeyID)
+	}
+	payload, err := jws.Verify(key)
+	f.s.Require().NoError(err)
+	return payload, key, nil
+}
+
+func (f *fakeACMEServer) newAccount(w http.ResponseWriter, payload []byte, key *jose.JSONWebKey) {
+	var req struct {
+		Contact                []string        `json:"contact"`
+		ExternalAccountBinding json.RawMessage `json:"externalAccountBinding"`
+	}
+	f.s.Require().NoError(json.Unmarshal(payload, &req))
+
+	if f.requireEAB {
+		jws, err := jose.ParseSigned(string(req.ExternalAccountBinding))
+		f.s.Require().NoError(err)
+		f.s.Require().Equal("KID", jws.Signatures[0].Protected.KeyID)
+		f.s.Require().Equal(f.URL+"/new-account", jws.Signatures[0].Protected.ExtraHeaders["url"])
+		boundKey, err := jws.Verify(testEABHMACKey)
+		f.s.Require().NoError(err)
+		jwk := new(jose.JSONWebKey)
+		f.s.Require().NoError(jwk.UnmarshalJSON(boundKey))
+		f.s.Require().Equal(thumbprint(f.s, key), thumbprint(f.s, jwk))
+	}
+
+	accountURL := f.URL + "/account/" + thumbprint(f.s, key)
+	status := http.StatusOK
+	if _, ok := f.accounts[accountURL]; !ok {
+		f.accounts[accountURL] = key
+		status = http.StatusCreated
+	}
+	f.lastContact = req.Contact
+	w.Header().Set("Location", accountURL)
+	f.writeJSON(w, status, struct {
+		Status string `json:"status"`
+	}{Status: statusValid})
+}
+
+func (f *fakeACMEServer) newOrder(w http.ResponseWriter, payload []byte) {
+	o := new(order)
+	f.s.Require().NoError(json.Unmarshal(payload, o))
+	f.orderCount++
+	id := fmt.Sprint(f.orderCount)
+
+	o.Status = statusPending
+	if f.preauthorized {
+		o.Status = statusReady
+	}
+	for i, identifier := range o.Identifiers {
+		authzID := fmt.Sprintf("%s-%d", id, i)
+		authz := &authorization{
+			Status:     statusPending,
+			Identifier: identifier,
+			Challenges: []challenge{
+				{Type: "dns-01", URL: f.URL + "/chall/dns-" + authzID, Status: statusPending, Token: "dns-token"},
+				{Type: challengeHTTP01, URL: f.URL + "/chall/" + authzID, Status: statusPending, Token: "token-" + authzID},
+			},
+		}
+		if f.preauthorized {
+			authz.Status = statusValid
+		}
+		f.authzs["/authz/"+authzID] = authz
+		o.Authorizations = append(o.Authorizations, f.URL+"/authz/"+authzID)
+	}
+	o.Finalize = f.URL + "/finalize/" + id
+	f.orders["/order/"+id] = o
+	f.lastOrder = o
+
+	w.Header().Set("Location", f.URL+"/order/"+id)
+	f.writeJSON(w, http.StatusCreated, o)
+}
+
+// validate fetches the key authorization from the http-01 responder and
+// updates the authorization and its order
+func (f *fakeACMEServer) validate(w http.ResponseWriter, authzPath string, key *jose.JSONWebKey) {
+	authz, ok := f.authzs[authzPath]
+	f.s.Require().True(ok, "unknown authorization %q", authzPath)
+	chal := &authz.Challenges[1]
+
+	resp, err := http.Get("http://" + f.challengeAddr + http01PathPrefix + chal.Token)
+	f.s.Require().NoError(err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	f.s.Require().NoError(err)
+
+	expected := chal.Token + "." + thumbprint(f.s, key)
+	if f.failValidation {
+		expected = "bogus"
+	}
+	if string(body) == expected {
+		chal.Status, authz.Status = statusValid, statusValid
+		f.validated = true
+	} else {
+		chal.Status, authz.Status = statusInvalid, statusInvalid
+		chal.Error = &problem{Type: "urn:ietf:params:acme:error:unauthorized", Detail: "key authorization mismatch"}
+	}
+
+	orderPath := "/order/" + strings.SplitN(strings.TrimPrefix(authzPath, "/authz/"), "-", 2)[0]
+	o := f.orders[orderPath]
+	o.Status = statusReady
+	for _, authzURL := range o.Authorizations {
+		if status := f.authzs[strings.TrimPrefix(authzURL, f.URL)].Status; status != statusValid {
+			o.Status = statusPending
+			if status == statusInvalid {
+				o.Status = statusInvalid
+			}
+		}
+	}
+	f.writeJSON(w, http.StatusOK, chal)
+}
+
+func (f *fakeACMEServer) finalize(w http.ResponseWriter, orderPath string, payload []byte) {
+	o, ok := f.orders[orderPath]
+	f.s.Require().True(ok, "unknown order %q", orderPath)
+	f.s.Require().Equal(statusReady, o.Status)
+
+	var req struct {
+		CSR string `json:"csr"`
+	}
+	f.s.Require().NoError(json.Unmarshal(payload, &req))
+	csrDER, err := base64.RawURLEncoding.DecodeString(req.CSR)
+	f.s.Require().NoError(err)
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	f.s.Require().NoError(err)
+	f.s.Require().Equal(o.Identifiers[0].Value, csr.Subject.CommonName)
+
+	// the certificate is issued asynchronously
+	o.Status = statusProcessing
+	o.Certificate = f.URL + strings.Replace(orderPath, "/order/", "/cert/", 1)
+	f.writeJSON(w, http.StatusOK, o)
+	o.Status = statusValid
+	f.certs[orderPath] = f.createCertificate(&x509.Certificate{
+		Subject:               csr.Subject,
+		URIs:                  csr.URIs,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, csr.PublicKey, f.intermediate, f.intermediateKey)
+}
+
+func (f *fakeACMEServer) certificate(w http.ResponseWriter, orderPath string) {
+	cert, ok := f.certs[orderPath]
+	f.s.Require().True(ok, "no certificate for order %q", orderPath)
+
+	chain := []*x509.Certificate{cert, f.intermediate}
+	if f.includeRoot {
+		chain = append(chain, f.root)
+	}
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	w.Write(pemutil.EncodeCertificates(chain))
+}
+
+func (f *fakeACMEServer) newNonce() string {
+	f.nextNonce++
+	nonce := fmt.Sprintf("nonce-%d", f.nextNonce)
+	f.nonces[nonce] = true
+	return nonce
+}
+
+func (f *fakeACMEServer) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	if _, ok := v.(*problem); ok {
+		w.Header().Set("Content-Type", "application/problem+json")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	f.s.Require().NoError(json.NewEncoder(w).Encode(v))
+}
+
+func (f *fakeACMEServer) createCA(commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	f.s.Require().NoError(err)
+	if parent == nil {
+		parentKey = key
+	}
+	cert := f.createCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, &key.PublicKey, parent, parentKey)
+	return cert, key
+}
+
+func (f *fakeACMEServer) createCertificate(tmpl *x509.Certificate, publicKey interface{}, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	serialNumber, err := f.serialNumber.NextNumber(ctx)
+	f.s.Require().NoError(err)
+	tmpl.SerialNumber = serialNumber
+	tmpl.NotBefore = time.Now().Add(-time.Minute)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent = tmpl
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, parent, publicKey, parentKey)
+	f.s.Require().NoError(err)
+	cert, err := x509.ParseCertificate(certDER)
+	f.s.Require().NoError(err)
+	return cert
+}
+
+func thumbprint(s *Suite, key *jose.JSONWebKey) string {
+	t, err := key.Thumbprint(crypto.SHA256)
+	s.Require().NoError(err)
+	return base64.RawURLEncoding.EncodeToString(t)
+}
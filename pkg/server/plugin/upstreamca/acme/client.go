@@ -0,0 +1,454 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/zeebo/errs"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	statusPending    = "pending"
+	statusProcessing = "processing"
+	statusReady      = "ready"
+	statusValid      = "valid"
+	statusInvalid    = "invalid"
+
+	challengeHTTP01 = "http-01"
+
+	errBadNonce = "urn:ietf:params:acme:error:badNonce"
+
+	contentTypeJOSE = "application/jose+json"
+)
+
+type directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+	Meta       struct {
+		ExternalAccountRequired bool `json:"externalAccountRequired"`
+	} `json:"meta"`
+}
+
+type identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type problem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+}
+
+type order struct {
+	Status         string       `json:"status"`
+	Identifiers    []identifier `json:"identifiers"`
+	Authorizations []string     `json:"authorizations"`
+	Finalize       string       `json:"finalize"`
+	Certificate    string       `json:"certificate,omitempty"`
+	Error          *problem     `json:"error,omitempty"`
+
+	// URL is the location of the order, from the Location header
+	URL string `json:"-"`
+}
+
+type authorization struct {
+	Status     string      `json:"status"`
+	Identifier identifier  `json:"identifier"`
+	Challenges []challenge `json:"challenges"`
+}
+
+type challenge struct {
+	Type   string   `json:"type"`
+	URL    string   `json:"url"`
+	Status string   `json:"status"`
+	Token  string   `json:"token"`
+	Error  *problem `json:"error,omitempty"`
+}
+
+// externalAccountBinding holds the credentials binding a new ACME account to
+// an account the CA already knows about (RFC 8555 section 7.3.4)
+type externalAccountBinding struct {
+	KeyID   string
+	HMACKey []byte
+}
+
+// acmeError is returned when the ACME server answers with a problem document
+type acmeError struct {
+	method  string
+	url     string
+	problem problem
+}
+
+func (e *acmeError) Error() string {
+	return e.method + " " + e.url + " failed: " + e.problem.Type + ": " + e.problem.Detail
+}
+
+// acmeClient implements the subset of the RFC 8555 protocol needed to order
+// certificates with an existing account key.
+type acmeClient struct {
+	directoryURL string
+	key          *ecdsa.PrivateKey
+	http         *http.Client
+
+	mu        sync.Mutex
+	dir       *directory
+	nonces    []string
+	accountID string
+}
+
+func newACMEClient(directoryURL string, key *ecdsa.PrivateKey, caFile string) (*acmeClient, error) {
+	client := &http.Client{}
+	if caFile != "" {
+		caPEM, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errs.New("unable to read CA file: %v", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caPEM) {
+			return nil, errs.New("no certificates found in CA file %q", caFile)
+		}
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots},
+		}
+	}
+	return &acmeClient{
+		directoryURL: directoryURL,
+		key:          key,
+		http:         client,
+	}, nil
+}
+
+// Register creates the account for the client key, or looks up the existing
+// one, and remembers its URL for subsequent requests.
+func (c *acmeClient) Register(ctx context.Context, contact []string, agreeToTOS bool, eab *externalAccountBinding) error {
+	dir, err := c.directory(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := struct {
+		Contact                []string         `json:"contact,omitempty"`
+		TermsOfServiceAgreed   bool             `json:"termsOfServiceAgreed,omitempty"`
+		ExternalAccountBinding *json.RawMessage `json:"externalAccountBinding,omitempty"`
+	}{
+		Contact:              contact,
+		TermsOfServiceAgreed: agreeToTOS,
+	}
+	if eab != nil {
+		binding, err := c.signExternalAccountBinding(dir.NewAccount, eab)
+		if err != nil {
+			return err
+		}
+		req.ExternalAccountBinding = &binding
+	} else if dir.Meta.ExternalAccountRequired {
+		return errs.New("the ACME server requires an external account binding")
+	}
+
+	resp, err := c.post(ctx, dir.NewAccount, req, nil, true)
+	if err != nil {
+		return err
+	}
+	accountID := resp.Header.Get("Location")
+	if accountID == "" {
+		return errs.New("ACME server did not return the account URL")
+	}
+
+	c.mu.Lock()
+	c.accountID = accountID
+	c.mu.Unlock()
+	return nil
+}
+
+// AccountURL returns the URL of the registered account, if any
+func (c *acmeClient) AccountURL() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.accountID
+}
+
+func (c *acmeClient) CreateOrder(ctx context.Context, identifiers []identifier) (*order, error) {
+	dir, err := c.directory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := struct {
+		Identifiers []identifier `json:"identifiers"`
+	}{
+		Identifiers: identifiers,
+	}
+	o := new(order)
+	resp, err := c.post(ctx, dir.NewOrder, req, o, false)
+	if err != nil {
+		return nil, err
+	}
+	o.URL = resp.Header.Get("Location")
+	return o, nil
+}
+
+func (c *acmeClient) GetOrder(ctx context.Context, url string) (*order, error) {
+	o := new(order)
+	if _, err := c.post(ctx, url, nil, o, false); err != nil {
+		return nil, err
+	}
+	o.URL = url
+	return o, nil
+}
+
+func (c *acmeClient) GetAuthorization(ctx context.Context, url string) (*authorization, error) {
+	authz := new(authorization)
+	if _, err := c.post(ctx, url, nil, authz, false); err != nil {
+		return nil, err
+	}
+	return authz, nil
+}
+
+// AcceptChallenge tells the server the challenge is ready to be validated
+func (c *acmeClient) AcceptChallenge(ctx context.Context, url string) error {
+	_, err := c.post(ctx, url, struct{}{}, new(challenge), false)
+	return err
+}
+
+func (c *acmeClient) FinalizeOrder(ctx context.Context, url string, csr []byte) (*order, error) {
+	req := struct {
+		CSR string `json:"csr"`
+	}{
+		CSR: base64.RawURLEncoding.EncodeToString(csr),
+	}
+	o := new(order)
+	if _, err := c.post(ctx, url, req, o, false); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// FetchCertificate downloads the PEM encoded certificate chain of a valid
+// order
+func (c *acmeClient) FetchCertificate(ctx context.Context, url string) ([]byte, error) {
+	resp, err := c.post(ctx, url, nil, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// KeyAuthorization returns the key authorization for a challenge token
+// (RFC 8555 section 8.1)
+func (c *acmeClient) KeyAuthorization(token string) (string, error) {
+	jwk := jose.JSONWebKey{Key: c.key.Public()}
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	return token + "." + base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// Nonce implements jose.NonceSource, handing out the nonces collected from
+// previous responses and fetching a new one when none is left.
+func (c *acmeClient) Nonce() (string, error) {
+	c.mu.Lock()
+	if n := len(c.nonces); n > 0 {
+		nonce := c.nonces[n-1]
+		c.nonces = c.nonces[:n-1]
+		c.mu.Unlock()
+		return nonce, nil
+	}
+	dir := c.dir
+	c.mu.Unlock()
+
+	if dir == nil {
+		return "", errs.New("ACME directory has not been fetched")
+	}
+	resp, err := c.http.Head(dir.NewNonce)
+	if err != nil {
+		return "", errs.New("unable to fetch nonce: %v", err)
+	}
+	resp.Body.Close()
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errs.New("ACME server did not return a nonce")
+	}
+	return nonce, nil
+}
+
+func (c *acmeClient) directory(ctx context.Context) (*directory, error) {
+	c.mu.Lock()
+	dir := c.dir
+	c.mu.Unlock()
+	if dir != nil {
+		return dir, nil
+	}
+
+	req, err := http.NewRequest("GET", c.directoryURL, nil)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errs.New("GET %s failed: unexpected status code %d", c.directoryURL, resp.StatusCode)
+	}
+
+	dir = new(directory)
+	if err := json.NewDecoder(resp.Body).Decode(dir); err != nil {
+		return nil, errs.New("unable to decode ACME directory: %v", err)
+	}
+	if dir.NewNonce == "" || dir.NewAccount == "" || dir.NewOrder == "" {
+		return nil, errs.New("ACME directory is missing required resources")
+	}
+
+	c.mu.Lock()
+	c.dir = dir
+	c.mu.Unlock()
+	return dir, nil
+}
+
+type response struct {
+	Header http.Header
+	Body   []byte
+}
+
+// post sends a JWS signed request to the ACME server. A nil payload sends a
+// POST-as-GET request. Requests rejected because of a bad nonce are retried
+// once, as recommended by RFC 8555 section 6.5.
+func (c *acmeClient) post(ctx context.Context, url string, payload, out interface{}, embedJWK bool) (*response, error) {
+	resp, err := c.postOnce(ctx, url, payload, out, embedJWK)
+	if e, ok := err.(*acmeError); ok && e.problem.Type == errBadNonce {
+		resp, err = c.postOnce(ctx, url, payload, out, embedJWK)
+	}
+	return resp, err
+}
+
+func (c *acmeClient) postOnce(ctx context.Context, url string, payload, out interface{}, embedJWK bool) (*response, error) {
+	// POST-as-GET requests carry an empty payload, which must still be
+	// serialized
+	payloadBytes := []byte{}
+	if payload != nil {
+		var err error
+		payloadBytes, err = json.Marshal(payload)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+	}
+
+	body, err := c.sign(url, payloadBytes, embedJWK)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", url, strings.NewReader(body))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	req.Header.Set("Content-Type", contentTypeJOSE)
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	if nonce := resp.Header.Get("Replay-Nonce"); nonce != "" {
+		c.mu.Lock()
+		c.nonces = append(c.nonces, nonce)
+		c.mu.Unlock()
+	}
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e := &acmeError{method: "POST", url: url}
+		if err := json.Unmarshal(respBytes, &e.problem); err != nil || e.problem.Type == "" {
+			return nil, errs.New("POST %s failed: unexpected status code %d", url, resp.StatusCode)
+		}
+		return nil, e
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(respBytes, out); err != nil {
+			return nil, errs.New("unable to decode %s response: %v", url, err)
+		}
+	}
+	return &response{
+		Header: resp.Header,
+		Body:   respBytes,
+	}, nil
+}
+
+// sign returns the flattened JWS of the payload. Requests are identified by
+// the account URL, except account creation, which embeds the public key.
+func (c *acmeClient) sign(url string, payload []byte, embedJWK bool) (string, error) {
+	key := jose.JSONWebKey{Key: c.key}
+	if !embedJWK {
+		c.mu.Lock()
+		key.KeyID = c.accountID
+		c.mu.Unlock()
+		if key.KeyID == "" {
+			return "", errs.New("ACME account is not registered")
+		}
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key:       key,
+	}, &jose.SignerOptions{
+		NonceSource: c,
+		EmbedJWK:    embedJWK,
+		ExtraHeaders: map[jose.HeaderKey]interface{}{
+			"url": url,
+		},
+	})
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return "", errs.New("unable to sign ACME request: %v", err)
+	}
+	return jws.FullSerialize(), nil
+}
+
+// signExternalAccountBinding returns the JWS binding the account key to the
+// external account, MACed with the key provided by the CA
+func (c *acmeClient) signExternalAccountBinding(url string, eab *externalAccountBinding) (json.RawMessage, error) {
+	jwk, err := json.Marshal(jose.JSONWebKey{Key: c.key.Public()})
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.HS256,
+		Key:       eab.HMACKey,
+	}, &jose.SignerOptions{
+		ExtraHeaders: map[jose.HeaderKey]interface{}{
+			"kid": eab.KeyID,
+			"url": url,
+		},
+	})
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	jws, err := signer.Sign(jwk)
+	if err != nil {
+		return nil, errs.New("unable to sign external account binding: %v", err)
+	}
+	return json.RawMessage(jws.FullSerialize()), nil
+}
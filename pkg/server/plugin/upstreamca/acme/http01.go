@@ -0,0 +1,65 @@
+package acme
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	http01PathPrefix = "/.well-known/acme-challenge/"
+)
+
+// http01Responder answers http-01 challenges (RFC 8555 section 8.3) with the
+// key authorizations of the pending tokens
+type http01Responder struct {
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+func newHTTP01Responder() *http01Responder {
+	return &http01Responder{
+		tokens: make(map[string]string),
+	}
+}
+
+func (r *http01Responder) Set(token, keyAuthorization string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[token] = keyAuthorization
+}
+
+func (r *http01Responder) Remove(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tokens, token)
+}
+
+func (r *http01Responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" || !strings.HasPrefix(req.URL.Path, http01PathPrefix) {
+		http.NotFound(w, req)
+		return
+	}
+
+	r.mu.RLock()
+	keyAuthorization, ok := r.tokens[strings.TrimPrefix(req.URL.Path, http01PathPrefix)]
+	r.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write([]byte(keyAuthorization))
+}
+
+// serve serves the responder on the listener until the returned function is
+// called
+func (r *http01Responder) serve(listener net.Listener) (stop func()) {
+	server := &http.Server{Handler: r}
+	go server.Serve(listener)
+	return func() {
+		server.Close()
+	}
+}
@@ -0,0 +1,100 @@
+package ejbca
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	enrollPath = "/ejbca/ejbca-rest-api/v1/certificate/pkcs10enroll"
+)
+
+type enrollRequest struct {
+	CertificateRequest       string `json:"certificate_request"`
+	CertificateProfileName   string `json:"certificate_profile_name"`
+	EndEntityProfileName     string `json:"end_entity_profile_name"`
+	CertificateAuthorityName string `json:"certificate_authority_name"`
+	Username                 string `json:"username"`
+	Password                 string `json:"password"`
+	AccountBindingID         string `json:"account_binding_id,omitempty"`
+	IncludeChain             bool   `json:"include_chain"`
+}
+
+type enrollResponse struct {
+	Certificate      string   `json:"certificate"`
+	SerialNumber     string   `json:"serial_number"`
+	ResponseFormat   string   `json:"response_format"`
+	CertificateChain []string `json:"certificate_chain"`
+}
+
+// ejbcaClient is an interface representing all of the EJBCA REST API methods
+// the upstream CA needs to do its job.
+type ejbcaClient interface {
+	EnrollPKCS10(ctx context.Context, req *enrollRequest) (*enrollResponse, error)
+}
+
+// restClient implements ejbcaClient against the EJBCA REST API, which
+// authenticates clients with their TLS certificate
+type restClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+func newRESTClient(baseURL string, tlsConfig *tls.Config) *restClient {
+	return &restClient{
+		baseURL: baseURL,
+		http: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}
+}
+
+func (c *restClient) EnrollPKCS10(ctx context.Context, req *enrollRequest) (*enrollResponse, error) {
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	httpReq, err := http.NewRequest("POST", c.baseURL+enrollPath, bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			ErrorCode    int    `json:"error_code"`
+			ErrorMessage string `json:"error_message"`
+		}
+		if err := json.Unmarshal(respBytes, &apiErr); err == nil && apiErr.ErrorMessage != "" {
+			return nil, errs.New("POST %s failed: %d: %s", enrollPath, apiErr.ErrorCode, apiErr.ErrorMessage)
+		}
+		return nil, errs.New("POST %s failed: unexpected status code %d", enrollPath, resp.StatusCode)
+	}
+
+	out := new(enrollResponse)
+	if err := json.Unmarshal(respBytes, out); err != nil {
+		return nil, errs.New("unable to decode %s response: %v", enrollPath, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package ejbca
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRESTClientEnrollPKCS10(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "POST", req.Method)
+		require.Equal(t, enrollPath, req.URL.Path)
+		require.Len(t, req.TLS.PeerCertificates, 1, "client certificate expected")
+
+		enroll := new(enrollRequest)
+		require.NoError(t, json.NewDecoder(req.Body).Decode(enroll))
+		require.Equal(t, "spire", enroll.Username)
+		require.Equal(t, "SubCA", enroll.CertificateProfileName)
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"certificate":"CERT","serial_number":"01","response_format":"DER","certificate_chain":["CHAIN"]}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	client := newRESTClient(server.URL, clientTLSConfig(server))
+	resp, err := client.EnrollPKCS10(context.Background(), &enrollRequest{
+		Username:               "spire",
+		CertificateProfileName: "SubCA",
+	})
+	require.NoError(t, err)
+	require.Equal(t, &enrollResponse{
+		Certificate:      "CERT",
+		SerialNumber:     "01",
+		ResponseFormat:   "DER",
+		CertificateChain: []string{"CHAIN"},
+	}, resp)
+}
+
+func TestRESTClientAPIError(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error_code":403,"error_message":"Not authorized to resource /administrator"}`))
+	}))
+	defer server.Close()
+
+	client := newRESTClient(server.URL, clientTLSConfig(server))
+	_, err := client.EnrollPKCS10(context.Background(), &enrollRequest{})
+	require.EqualError(t, err, "POST /ejbca/ejbca-rest-api/v1/certificate/pkcs10enroll failed: 403: Not authorized to resource /administrator")
+}
+
+// clientTLSConfig trusts the test server and presents its certificate as
+// the client certificate
+func clientTLSConfig(server *httptest.Server) *tls.Config {
+	tlsConfig := server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	tlsConfig.Certificates = server.TLS.Certificates
+	return tlsConfig
+}
@@ -0,0 +1,289 @@
+package ejbca
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/x509util"
+	"github.com/zeebo/errs"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/upstreamca"
+)
+
+var (
+	ejbcaErr = errs.Class("upstreamca(ejbca)")
+)
+
+type configuration struct {
+	// Hostname is the host (and optional port) of the EJBCA server
+	Hostname string `hcl:"hostname"`
+
+	// CACertPath is the optional path to the CA certificates used to
+	// authenticate the EJBCA server. If unset, the system roots are used.
+	CACertPath string `hcl:"ca_cert_path"`
+
+	// ClientCertPath and ClientCertKeyPath are the paths to the client
+	// certificate and key used to authenticate to the EJBCA REST API
+	ClientCertPath    string `hcl:"client_cert_path"`
+	ClientCertKeyPath string `hcl:"client_cert_key_path"`
+
+	// CAName is the name of the EJBCA CA that signs the certificates
+	CAName string `hcl:"ca_name"`
+
+	// EndEntityProfileName and CertificateProfileName are the names of the
+	// EJBCA profiles used to issue the certificates
+	EndEntityProfileName   string `hcl:"end_entity_profile_name"`
+	CertificateProfileName string `hcl:"certificate_profile_name"`
+
+	// EndEntityName determines the name of the end entity the certificates
+	// are enrolled for. It is either one of cn, dns, uri or ip, taking the
+	// name from the corresponding field of the CSR, or a literal name.
+	// Defaults to the common name, falling back to the SPIFFE ID.
+	EndEntityName string `hcl:"end_entity_name"`
+
+	// AccountBindingID is the optional account binding id sent with the
+	// enrollments
+	AccountBindingID string `hcl:"account_binding_id"`
+}
+
+type UpstreamCA struct {
+	// log is set by the catalog before the plugin is configured
+	log logrus.FieldLogger
+
+	mu     sync.RWMutex
+	config *configuration
+	client ejbcaClient
+
+	hooks struct {
+		newClient func(config *configuration) (ejbcaClient, error)
+	}
+}
+
+var _ upstreamca.Plugin = (*UpstreamCA)(nil)
+
+func New() *UpstreamCA {
+	p := &UpstreamCA{
+		log: logrus.StandardLogger(),
+	}
+	p.hooks.newClient = newClient
+	return p
+}
+
+func (p *UpstreamCA) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, ejbcaErr.New("unable to decode configuration: %v", err)
+	}
+
+	switch {
+	case config.Hostname == "":
+		return nil, ejbcaErr.New("hostname is required")
+	case config.ClientCertPath == "":
+		return nil, ejbcaErr.New("client_cert_path is required")
+	case config.ClientCertKeyPath == "":
+		return nil, ejbcaErr.New("client_cert_key_path is required")
+	case config.CAName == "":
+		return nil, ejbcaErr.New("ca_name is required")
+	case config.EndEntityProfileName == "":
+		return nil, ejbcaErr.New("end_entity_profile_name is required")
+	case config.CertificateProfileName == "":
+		return nil, ejbcaErr.New("certificate_profile_name is required")
+	}
+
+	client, err := p.hooks.newClient(config)
+	if err != nil {
+		return nil, ejbcaErr.Wrap(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	p.client = client
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+// SetLogger sets the logger the plugin logs to
+func (p *UpstreamCA) SetLogger(log logrus.FieldLogger) {
+	p.log = log
+}
+
+func (p *UpstreamCA) GetPluginInfo(ctx context.Context, req *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+// SubmitCSR enrolls the CSR with EJBCA using the configured profiles and CA.
+// EJBCA returns the issued certificate along with the chain of its CA, whose
+// root forms the upstream bundle.
+func (p *UpstreamCA) SubmitCSR(ctx context.Context, req *upstreamca.SubmitCSRRequest) (*upstreamca.SubmitCSRResponse, error) {
+	config, client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	csr, err := x509.ParseCertificateRequest(req.Csr)
+	if err != nil {
+		return nil, ejbcaErr.New("unable to parse CSR: %v", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, ejbcaErr.New("CSR signature check failed: %v", err)
+	}
+
+	username, err := endEntityName(config.EndEntityName, csr)
+	if err != nil {
+		return nil, err
+	}
+	// the end entity password is only used for this enrollment
+	password, err := newPassword()
+	if err != nil {
+		return nil, ejbcaErr.Wrap(err)
+	}
+
+	resp, err := client.EnrollPKCS10(ctx, &enrollRequest{
+		CertificateRequest: string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE REQUEST",
+			Bytes: req.Csr,
+		})),
+		CertificateProfileName:   config.CertificateProfileName,
+		EndEntityProfileName:     config.EndEntityProfileName,
+		CertificateAuthorityName: config.CAName,
+		Username:                 username,
+		Password:                 password,
+		AccountBindingID:         config.AccountBindingID,
+		IncludeChain:             true,
+	})
+	if err != nil {
+		return nil, ejbcaErr.New("unable to enroll CSR: %v", err)
+	}
+
+	cert, err := parseCertificate(resp.Certificate)
+	if err != nil {
+		return nil, ejbcaErr.New("unable to parse issued certificate: %v", err)
+	}
+	var chain []*x509.Certificate
+	for _, encoded := range resp.CertificateChain {
+		chainCert, err := parseCertificate(encoded)
+		if err != nil {
+			return nil, ejbcaErr.New("unable to parse certificate chain: %v", err)
+		}
+		// the chain may or may not start with the issued certificate
+		if !chainCert.Equal(cert) {
+			chain = append(chain, chainCert)
+		}
+	}
+	intermediates, roots := x509util.SplitRoots(chain)
+	if len(roots) == 0 {
+		return nil, ejbcaErr.New("no root certificates found in the certificate chain of CA %q", config.CAName)
+	}
+
+	p.log.Infof("Issued certificate %s for end entity %q expiring at %s", resp.SerialNumber, username, cert.NotAfter.UTC().Format(time.RFC3339))
+
+	return &upstreamca.SubmitCSRResponse{
+		SignedCertificate: &upstreamca.SignedCertificate{
+			CertChain: x509util.DERFromCertificates(append([]*x509.Certificate{cert}, intermediates...)),
+			Bundle:    x509util.DERFromCertificates(roots),
+		},
+	}, nil
+}
+
+func (p *UpstreamCA) getClient() (*configuration, ejbcaClient, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.client == nil {
+		return nil, nil, ejbcaErr.New("not configured")
+	}
+	return p.config, p.client, nil
+}
+
+// endEntityName returns the name of the end entity to enroll the CSR for
+func endEntityName(option string, csr *x509.CertificateRequest) (string, error) {
+	switch option {
+	case "":
+		if csr.Subject.CommonName != "" {
+			return csr.Subject.CommonName, nil
+		}
+		if len(csr.URIs) > 0 {
+			return csr.URIs[0].String(), nil
+		}
+		return "", ejbcaErr.New("CSR has no common name or URI SAN to name the end entity after")
+	case "cn":
+		if csr.Subject.CommonName != "" {
+			return csr.Subject.CommonName, nil
+		}
+	case "dns":
+		if len(csr.DNSNames) > 0 {
+			return csr.DNSNames[0], nil
+		}
+	case "uri":
+		if len(csr.URIs) > 0 {
+			return csr.URIs[0].String(), nil
+		}
+	case "ip":
+		if len(csr.IPAddresses) > 0 {
+			return csr.IPAddresses[0].String(), nil
+		}
+	default:
+		return option, nil
+	}
+	return "", ejbcaErr.New("CSR has no %s to name the end entity after", option)
+}
+
+// parseCertificate parses a certificate returned by EJBCA, which is either
+// PEM or base64 encoded DER or PEM, depending on the response format
+func parseCertificate(encoded string) (*x509.Certificate, error) {
+	data := []byte(encoded)
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		var err error
+		data, err = base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		return pemutil.ParseCertificate(data)
+	}
+	return x509.ParseCertificate(data)
+}
+
+func newPassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func newClient(config *configuration) (ejbcaClient, error) {
+	clientCert, err := tls.LoadX509KeyPair(config.ClientCertPath, config.ClientCertKeyPath)
+	if err != nil {
+		return nil, errs.New("unable to load client certificate: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+	}
+	if config.CACertPath != "" {
+		caPEM, err := ioutil.ReadFile(config.CACertPath)
+		if err != nil {
+			return nil, errs.New("unable to read CA certificates: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, errs.New("no certificates found in %q", config.CACertPath)
+		}
+	}
+	return newRESTClient("https://"+strings.TrimSuffix(config.Hostname, "/"), tlsConfig), nil
+}
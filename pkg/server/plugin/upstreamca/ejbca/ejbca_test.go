@@ -0,0 +1,286 @@
+package ejbca
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/x509util"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/upstreamca"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testConfig = `
+		hostname = "ejbca.example.org"
+		client_cert_path = "client.pem"
+		client_cert_key_path = "client.key"
+		ca_name = "SPIRE Intermediate"
+		end_entity_profile_name = "SPIRE"
+		certificate_profile_name = "SubCA"
+	`
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestUpstreamCA(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	client *fakeClient
+}
+
+func (s *Suite) SetupTest() {
+	s.client = s.newFakeClient()
+}
+
+func (s *Suite) newUpstreamCA(config string) *UpstreamCA {
+	p := New()
+	p.hooks.newClient = func(*configuration) (ejbcaClient, error) {
+		return s.client, nil
+	}
+	resp, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: config,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&spi.ConfigureResponse{}, resp)
+	return p
+}
+
+func (s *Suite) TestConfigureRequiresHostname() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{})
+	s.Require().EqualError(err, "upstreamca(ejbca): hostname is required")
+}
+
+func (s *Suite) TestConfigureRequiresProfiles() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{
+		Configuration: `
+			hostname = "ejbca.example.org"
+			client_cert_path = "client.pem"
+			client_cert_key_path = "client.key"
+			ca_name = "SPIRE Intermediate"
+		`,
+	})
+	s.Require().EqualError(err, "upstreamca(ejbca): end_entity_profile_name is required")
+}
+
+func (s *Suite) TestConfigureFailsToLoadClientCertificate() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{
+		Configuration: testConfig,
+	})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "upstreamca(ejbca): unable to load client certificate")
+}
+
+func (s *Suite) TestSubmitCSRNotConfigured() {
+	_, err := New().SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire")})
+	s.Require().EqualError(err, "upstreamca(ejbca): not configured")
+}
+
+func (s *Suite) TestSubmitCSR() {
+	p := s.newUpstreamCA(testConfig + `account_binding_id = "binding"`)
+
+	csr := s.makeCSR("spire")
+	resp, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: csr})
+	s.Require().NoError(err)
+
+	// the enrollment is built from the configuration
+	req := s.client.lastRequest
+	s.Require().Equal("SPIRE Intermediate", req.CertificateAuthorityName)
+	s.Require().Equal("SPIRE", req.EndEntityProfileName)
+	s.Require().Equal("SubCA", req.CertificateProfileName)
+	s.Require().Equal("spire", req.Username)
+	s.Require().Len(req.Password, 32)
+	s.Require().Equal("binding", req.AccountBindingID)
+	s.Require().True(req.IncludeChain)
+	parsedCSR, err := pemutil.ParseCertificateRequest([]byte(req.CertificateRequest))
+	s.Require().NoError(err)
+	s.Require().Equal(csr, parsedCSR.Raw)
+
+	chain, err := x509.ParseCertificates(resp.SignedCertificate.CertChain)
+	s.Require().NoError(err)
+	s.Require().Len(chain, 2)
+	s.Require().Equal("spire", chain[0].Subject.CommonName)
+	s.Require().True(chain[1].Equal(s.client.intermediate))
+
+	bundle, err := x509.ParseCertificates(resp.SignedCertificate.Bundle)
+	s.Require().NoError(err)
+	s.Require().Len(bundle, 1)
+	s.Require().True(bundle[0].Equal(s.client.root))
+}
+
+func (s *Suite) TestSubmitCSRWithPEMResponse() {
+	p := s.newUpstreamCA(testConfig)
+	s.client.pem = true
+
+	resp, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire")})
+	s.Require().NoError(err)
+	chain, err := x509.ParseCertificates(resp.SignedCertificate.CertChain)
+	s.Require().NoError(err)
+	s.Require().Len(chain, 2)
+}
+
+func (s *Suite) TestSubmitCSREndEntityName() {
+	// the SPIFFE ID is used when the CSR has no common name
+	p := s.newUpstreamCA(testConfig)
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("")})
+	s.Require().NoError(err)
+	s.Require().Equal("spiffe://example.org", s.client.lastRequest.Username)
+
+	p = s.newUpstreamCA(testConfig + `end_entity_name = "uri"`)
+	_, err = p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire")})
+	s.Require().NoError(err)
+	s.Require().Equal("spiffe://example.org", s.client.lastRequest.Username)
+
+	p = s.newUpstreamCA(testConfig + `end_entity_name = "spire-server"`)
+	_, err = p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire")})
+	s.Require().NoError(err)
+	s.Require().Equal("spire-server", s.client.lastRequest.Username)
+
+	p = s.newUpstreamCA(testConfig + `end_entity_name = "dns"`)
+	_, err = p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire")})
+	s.Require().EqualError(err, "upstreamca(ejbca): CSR has no dns to name the end entity after")
+}
+
+func (s *Suite) TestSubmitCSRRequiresRoot() {
+	p := s.newUpstreamCA(testConfig)
+	s.client.omitRoot = true
+
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire")})
+	s.Require().EqualError(err, `upstreamca(ejbca): no root certificates found in the certificate chain of CA "SPIRE Intermediate"`)
+}
+
+func (s *Suite) TestSubmitCSRFailsOnAPIError() {
+	p := s.newUpstreamCA(testConfig)
+	s.client.enrollErr = errors.New("POST /ejbca/ejbca-rest-api/v1/certificate/pkcs10enroll failed: 403: Not authorized")
+
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR("spire")})
+	s.Require().EqualError(err, "upstreamca(ejbca): unable to enroll CSR: POST /ejbca/ejbca-rest-api/v1/certificate/pkcs10enroll failed: 403: Not authorized")
+}
+
+func (s *Suite) makeCSR(commonName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName},
+		URIs:    []*url.URL{{Scheme: "spiffe", Host: "example.org"}},
+	}, key)
+	s.Require().NoError(err)
+	return csr
+}
+
+// fakeClient issues certificates from an intermediate CA under a root
+type fakeClient struct {
+	s *Suite
+
+	root            *x509.Certificate
+	intermediate    *x509.Certificate
+	intermediateKey *ecdsa.PrivateKey
+	serialNumber    x509util.SerialNumber
+	lastRequest     *enrollRequest
+	enrollErr       error
+
+	// pem returns the certificates in the PEM response format
+	pem bool
+	// omitRoot omits the root from the returned chain
+	omitRoot bool
+}
+
+func (s *Suite) newFakeClient() *fakeClient {
+	c := &fakeClient{
+		s:            s,
+		serialNumber: x509util.NewSerialNumber(),
+	}
+	var rootKey *ecdsa.PrivateKey
+	c.root, rootKey = c.createCA("root", nil, nil)
+	c.intermediate, c.intermediateKey = c.createCA("intermediate", c.root, rootKey)
+	return c
+}
+
+func (c *fakeClient) EnrollPKCS10(ctx context.Context, req *enrollRequest) (*enrollResponse, error) {
+	c.lastRequest = req
+	if c.enrollErr != nil {
+		return nil, c.enrollErr
+	}
+
+	csr, err := pemutil.ParseCertificateRequest([]byte(req.CertificateRequest))
+	if err != nil {
+		return nil, err
+	}
+	cert := c.createCertificate(&x509.Certificate{
+		Subject:               csr.Subject,
+		URIs:                  csr.URIs,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, csr.PublicKey, c.intermediate, c.intermediateKey)
+
+	chain := []*x509.Certificate{c.intermediate}
+	if !c.omitRoot {
+		chain = append(chain, c.root)
+	}
+
+	encode := func(cert *x509.Certificate) string {
+		if c.pem {
+			return string(pemutil.EncodeCertificate(cert))
+		}
+		return base64.StdEncoding.EncodeToString(cert.Raw)
+	}
+	resp := &enrollResponse{
+		Certificate:    encode(cert),
+		SerialNumber:   cert.SerialNumber.Text(16),
+		ResponseFormat: "DER",
+	}
+	if c.pem {
+		resp.ResponseFormat = "PEM"
+	}
+	for _, chainCert := range chain {
+		resp.CertificateChain = append(resp.CertificateChain, encode(chainCert))
+	}
+	return resp, nil
+}
+
+func (c *fakeClient) createCA(commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.s.Require().NoError(err)
+	if parent == nil {
+		parentKey = key
+	}
+	cert := c.createCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, &key.PublicKey, parent, parentKey)
+	return cert, key
+}
+
+func (c *fakeClient) createCertificate(tmpl *x509.Certificate, publicKey interface{}, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	serialNumber, err := c.serialNumber.NextNumber(ctx)
+	c.s.Require().NoError(err)
+	tmpl.SerialNumber = serialNumber
+	tmpl.NotBefore = time.Now().Add(-time.Minute)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent = tmpl
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, parent, publicKey, parentKey)
+	c.s.Require().NoError(err)
+	cert, err := x509.ParseCertificate(certDER)
+	c.s.Require().NoError(err)
+	return cert
+}
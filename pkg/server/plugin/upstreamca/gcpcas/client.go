@@ -0,0 +1,162 @@
+package gcpcas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/spiffe/spire/pkg/common/plugin/gcp"
+	"github.com/zeebo/errs"
+)
+
+const (
+	defaultEndpoint = "https://privateca.googleapis.com"
+)
+
+type certificate struct {
+	Name                string   `json:"name,omitempty"`
+	PemCSR              string   `json:"pemCsr,omitempty"`
+	Lifetime            string   `json:"lifetime,omitempty"`
+	CertificateTemplate string   `json:"certificateTemplate,omitempty"`
+	PemCertificate      string   `json:"pemCertificate,omitempty"`
+	PemCertificateChain []string `json:"pemCertificateChain,omitempty"`
+}
+
+type createCertificateRequest struct {
+	CAPool                        string
+	CertificateID                 string
+	RequestID                     string
+	IssuingCertificateAuthorityID string
+	Certificate                   *certificate
+}
+
+// casClient is an interface representing all of the Certificate Authority
+// Service API methods the upstream CA needs to do its job.
+type casClient interface {
+	CreateCertificate(ctx context.Context, req *createCertificateRequest) (*certificate, error)
+	FetchCACerts(ctx context.Context, caPool string) ([][]string, error)
+}
+
+// restClient implements casClient against the Certificate Authority Service
+// v1 REST API
+type restClient struct {
+	endpoint string
+	tokens   gcp.TokenSource
+	http     *http.Client
+}
+
+func newRESTClient(endpoint string, tokens gcp.TokenSource) *restClient {
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	return &restClient{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		tokens:   tokens,
+		http:     http.DefaultClient,
+	}
+}
+
+func (c *restClient) CreateCertificate(ctx context.Context, req *createCertificateRequest) (*certificate, error) {
+	query := url.Values{}
+	query.Set("certificateId", req.CertificateID)
+	query.Set("requestId", req.RequestID)
+	if req.IssuingCertificateAuthorityID != "" {
+		query.Set("issuingCertificateAuthorityId", req.IssuingCertificateAuthorityID)
+	}
+	resp := new(certificate)
+	if err := c.do(ctx, "POST", req.CAPool+"/certificates", query, req.Certificate, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// FetchCACerts returns the PEM encoded certificate chains of the CAs in the
+// pool, each ordered from the CA up to its root.
+func (c *restClient) FetchCACerts(ctx context.Context, caPool string) ([][]string, error) {
+	var resp struct {
+		CACerts []struct {
+			Certificates []string `json:"certificates"`
+		} `json:"caCerts"`
+	}
+	if err := c.do(ctx, "POST", caPool+":fetchCaCerts", nil, struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+
+	var chains [][]string
+	for _, caCert := range resp.CACerts {
+		chains = append(chains, caCert.Certificates)
+	}
+	return chains, nil
+}
+
+func (c *restClient) do(ctx context.Context, method, resource string, query url.Values, in, out interface{}) error {
+	u := fmt.Sprintf("%s/v1/%s", c.endpoint, resource)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		inBytes, err := json.Marshal(in)
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		body = bytes.NewReader(inBytes)
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return errs.New("unable to obtain access token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(method, resource, resp.StatusCode, respBytes)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(respBytes, out); err != nil {
+			return errs.New("unable to decode %s response: %v", resource, err)
+		}
+	}
+	return nil
+}
+
+func newAPIError(method, resource string, statusCode int, body []byte) error {
+	var errBody struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errBody); err == nil && errBody.Error.Message != "" {
+		return errs.New("%s %s failed: %s: %s", method, resource, errBody.Error.Status, errBody.Error.Message)
+	}
+	return errs.New("%s %s failed: unexpected status code %d", method, resource, statusCode)
+}
@@ -0,0 +1,79 @@
+package gcpcas
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type staticTokenSource string
+
+func (s staticTokenSource) Token(context.Context) (string, error) {
+	return string(s), nil
+}
+
+func TestRESTClientCreateCertificate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "POST", req.Method)
+		require.Equal(t, "/v1/projects/P/locations/L/caPools/POOL/certificates", req.URL.Path)
+		require.Equal(t, "spire-ID", req.URL.Query().Get("certificateId"))
+		require.Equal(t, "REQUEST", req.URL.Query().Get("requestId"))
+		require.Equal(t, "CA", req.URL.Query().Get("issuingCertificateAuthorityId"))
+		require.Equal(t, "Bearer TOKEN", req.Header.Get("Authorization"))
+
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"pemCsr":"CSR","lifetime":"3600s"}`, string(body))
+
+		w.Write([]byte(`{"name":"NAME","pemCertificate":"CERT","pemCertificateChain":["INTERMEDIATE","ROOT"]}`))
+	}))
+	defer server.Close()
+
+	client := newRESTClient(server.URL, staticTokenSource("TOKEN"))
+	cert, err := client.CreateCertificate(context.Background(), &createCertificateRequest{
+		CAPool:                        "projects/P/locations/L/caPools/POOL",
+		CertificateID:                 "spire-ID",
+		RequestID:                     "REQUEST",
+		IssuingCertificateAuthorityID: "CA",
+		Certificate: &certificate{
+			PemCSR:   "CSR",
+			Lifetime: "3600s",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &certificate{
+		Name:                "NAME",
+		PemCertificate:      "CERT",
+		PemCertificateChain: []string{"INTERMEDIATE", "ROOT"},
+	}, cert)
+}
+
+func TestRESTClientFetchCACerts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "POST", req.Method)
+		require.Equal(t, "/v1/projects/P/locations/L/caPools/POOL:fetchCaCerts", req.URL.Path)
+		w.Write([]byte(`{"caCerts":[{"certificates":["A","ROOT"]},{"certificates":["B"]}]}`))
+	}))
+	defer server.Close()
+
+	client := newRESTClient(server.URL, staticTokenSource("TOKEN"))
+	chains, err := client.FetchCACerts(context.Background(), "projects/P/locations/L/caPools/POOL")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"A", "ROOT"}, {"B"}}, chains)
+}
+
+func TestRESTClientAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"code":403,"message":"denied","status":"PERMISSION_DENIED"}}`))
+	}))
+	defer server.Close()
+
+	client := newRESTClient(server.URL, staticTokenSource("TOKEN"))
+	_, err := client.FetchCACerts(context.Background(), "projects/P/locations/L/caPools/POOL")
+	require.EqualError(t, err, "POST projects/P/locations/L/caPools/POOL:fetchCaCerts failed: PERMISSION_DENIED: denied")
+}
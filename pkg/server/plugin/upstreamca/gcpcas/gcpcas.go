@@ -0,0 +1,257 @@
+package gcpcas
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/hashicorp/hcl"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/gcp"
+	"github.com/spiffe/spire/pkg/common/x509util"
+	"github.com/zeebo/errs"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/upstreamca"
+)
+
+const (
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+var (
+	casError = errs.Class("upstreamca(gcp_cas)")
+
+	reCAPool              = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/caPools/[^/]+$`)
+	reCertificateTemplate = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/certificateTemplates/[^/]+$`)
+)
+
+type configuration struct {
+	// CAPool is the full resource name of the CA pool the certificates are
+	// requested from (i.e. projects/PROJECT/locations/LOCATION/caPools/POOL)
+	CAPool string `hcl:"ca_pool"`
+
+	// CertificateAuthority optionally pins the id of the CA in the pool that
+	// issues the certificates. If unset, the pool picks one.
+	CertificateAuthority string `hcl:"certificate_authority"`
+
+	// CertificateTemplate is the optional full resource name of the
+	// certificate template applied to the issued certificates
+	CertificateTemplate string `hcl:"certificate_template"`
+
+	// TTL is the optional lifetime requested for the issued certificates. If
+	// unset, the lifetime is determined by the pool and template.
+	TTL string `hcl:"ttl"`
+
+	// ServiceAccountFile is an optional path to a service account JSON key
+	// file. If unset, credentials are obtained from the metadata server.
+	ServiceAccountFile string `hcl:"service_account_file"`
+
+	// Endpoint optionally overrides the Certificate Authority Service API
+	// endpoint
+	Endpoint string `hcl:"endpoint"`
+
+	ttl time.Duration
+}
+
+type UpstreamCA struct {
+	// log is set by the catalog before the plugin is configured
+	log logrus.FieldLogger
+
+	mu     sync.RWMutex
+	config *configuration
+	client casClient
+
+	hooks struct {
+		newClient func(config *configuration) (casClient, error)
+		newID     func() (string, error)
+	}
+}
+
+var _ upstreamca.Plugin = (*UpstreamCA)(nil)
+
+func New() *UpstreamCA {
+	p := &UpstreamCA{
+		log: logrus.StandardLogger(),
+	}
+	p.hooks.newClient = newClient
+	p.hooks.newID = newID
+	return p
+}
+
+func (p *UpstreamCA) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, casError.New("unable to decode configuration: %v", err)
+	}
+
+	if config.CAPool == "" {
+		return nil, casError.New("ca_pool is required")
+	}
+	if !reCAPool.MatchString(config.CAPool) {
+		return nil, casError.New("ca_pool %q is not a valid CA pool resource name", config.CAPool)
+	}
+	if config.CertificateTemplate != "" && !reCertificateTemplate.MatchString(config.CertificateTemplate) {
+		return nil, casError.New("certificate_template %q is not a valid certificate template resource name", config.CertificateTemplate)
+	}
+	if config.TTL != "" {
+		ttl, err := time.ParseDuration(config.TTL)
+		if err != nil {
+			return nil, casError.New("invalid ttl value: %v", err)
+		}
+		if ttl < time.Second {
+			return nil, casError.New("ttl must be at least one second")
+		}
+		config.ttl = ttl
+	}
+
+	client, err := p.hooks.newClient(config)
+	if err != nil {
+		return nil, casError.Wrap(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	p.client = client
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+// SetLogger sets the logger the plugin logs to
+func (p *UpstreamCA) SetLogger(log logrus.FieldLogger) {
+	p.log = log
+}
+
+func (p *UpstreamCA) GetPluginInfo(ctx context.Context, req *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+// SubmitCSR requests a certificate for the CSR from the CA pool. The issued
+// certificate is returned along with the intermediates chaining it to the
+// roots of the pool, which form the upstream bundle.
+func (p *UpstreamCA) SubmitCSR(ctx context.Context, req *upstreamca.SubmitCSRRequest) (*upstreamca.SubmitCSRResponse, error) {
+	config, client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	csr, err := x509.ParseCertificateRequest(req.Csr)
+	if err != nil {
+		return nil, casError.New("unable to parse CSR: %v", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, casError.New("CSR signature check failed: %v", err)
+	}
+
+	certificateID, err := p.hooks.newID()
+	if err != nil {
+		return nil, casError.Wrap(err)
+	}
+	requestID, err := p.hooks.newID()
+	if err != nil {
+		return nil, casError.Wrap(err)
+	}
+
+	cert := &certificate{
+		PemCSR: string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE REQUEST",
+			Bytes: req.Csr,
+		})),
+		CertificateTemplate: config.CertificateTemplate,
+	}
+	if config.ttl > 0 {
+		cert.Lifetime = fmt.Sprintf("%ds", int64(config.ttl/time.Second))
+	}
+
+	issued, err := client.CreateCertificate(ctx, &createCertificateRequest{
+		CAPool:                        config.CAPool,
+		CertificateID:                 "spire-" + certificateID,
+		RequestID:                     requestID,
+		IssuingCertificateAuthorityID: config.CertificateAuthority,
+		Certificate:                   cert,
+	})
+	if err != nil {
+		return nil, casError.New("unable to create certificate: %v", err)
+	}
+
+	leaf, err := pemutil.ParseCertificate([]byte(issued.PemCertificate))
+	if err != nil {
+		return nil, casError.New("unable to parse issued certificate: %v", err)
+	}
+	chain, err := parseCertificates(issued.PemCertificateChain)
+	if err != nil {
+		return nil, casError.New("unable to parse issued certificate chain: %v", err)
+	}
+	intermediates, roots := x509util.SplitRoots(chain)
+
+	// The roots of every CA in the pool are trusted so that certificates
+	// issued by any of them, e.g. after the pool rotates its CAs, chain
+	// back to the bundle.
+	caChains, err := client.FetchCACerts(ctx, config.CAPool)
+	if err != nil {
+		return nil, casError.New("unable to fetch CA certificates: %v", err)
+	}
+	for _, caChain := range caChains {
+		caCerts, err := parseCertificates(caChain)
+		if err != nil {
+			return nil, casError.New("unable to parse CA certificates: %v", err)
+		}
+		_, caRoots := x509util.SplitRoots(caCerts)
+		roots = x509util.AppendUniqueCertificates(roots, caRoots...)
+	}
+	if len(roots) == 0 {
+		return nil, casError.New("no root certificates found for CA pool %q", config.CAPool)
+	}
+
+	p.log.Infof("Issued certificate %q expiring at %s", issued.Name, leaf.NotAfter.UTC().Format(time.RFC3339))
+
+	return &upstreamca.SubmitCSRResponse{
+		SignedCertificate: &upstreamca.SignedCertificate{
+			CertChain: x509util.DERFromCertificates(append([]*x509.Certificate{leaf}, intermediates...)),
+			Bundle:    x509util.DERFromCertificates(roots),
+		},
+	}, nil
+}
+
+func (p *UpstreamCA) getClient() (*configuration, casClient, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.client == nil {
+		return nil, nil, casError.New("not configured")
+	}
+	return p.config, p.client, nil
+}
+
+func parseCertificates(pemCerts []string) ([]*x509.Certificate, error) {
+	return pemutil.ParseCertificates([]byte(strings.Join(pemCerts, "\n")))
+}
+
+func newClient(config *configuration) (casClient, error) {
+	var tokens gcp.TokenSource
+	if config.ServiceAccountFile != "" {
+		var err error
+		tokens, err = gcp.NewServiceAccountTokenSource(config.ServiceAccountFile, cloudPlatformScope)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		tokens = gcp.NewMetadataTokenSource(gcp.DefaultMetadataTokenURL)
+	}
+	return newRESTClient(config.Endpoint, tokens), nil
+}
+
+func newID() (string, error) {
+	u, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
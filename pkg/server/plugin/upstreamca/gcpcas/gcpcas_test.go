@@ -0,0 +1,266 @@
+package gcpcas
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/x509util"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/upstreamca"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testCAPool   = "projects/PROJECT/locations/us-west1/caPools/POOL"
+	testTemplate = "projects/PROJECT/locations/us-west1/certificateTemplates/spire"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestUpstreamCA(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	client *fakeClient
+	p      *UpstreamCA
+}
+
+func (s *Suite) SetupTest() {
+	s.client = s.newFakeClient()
+	s.p = s.newUpstreamCA(fmt.Sprintf(`
+		ca_pool = %q
+		certificate_authority = "CA"
+		certificate_template = %q
+		ttl = "1h"
+	`, testCAPool, testTemplate))
+}
+
+func (s *Suite) newUpstreamCA(config string) *UpstreamCA {
+	p := New()
+	p.hooks.newClient = func(*configuration) (casClient, error) {
+		return s.client, nil
+	}
+	p.hooks.newID = func() (string, error) {
+		return "ID", nil
+	}
+	resp, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: config,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&spi.ConfigureResponse{}, resp)
+	return p
+}
+
+func (s *Suite) TestConfigureRequiresCAPool() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{})
+	s.Require().EqualError(err, "upstreamca(gcp_cas): ca_pool is required")
+}
+
+func (s *Suite) TestConfigureRejectsMalformedCAPool() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{
+		Configuration: `ca_pool = "projects/PROJECT/caPools/POOL"`,
+	})
+	s.Require().EqualError(err, `upstreamca(gcp_cas): ca_pool "projects/PROJECT/caPools/POOL" is not a valid CA pool resource name`)
+}
+
+func (s *Suite) TestConfigureRejectsMalformedCertificateTemplate() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+			ca_pool = %q
+			certificate_template = "spire"
+		`, testCAPool),
+	})
+	s.Require().EqualError(err, `upstreamca(gcp_cas): certificate_template "spire" is not a valid certificate template resource name`)
+}
+
+func (s *Suite) TestConfigureRejectsInvalidTTL() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{
+		Configuration: fmt.Sprintf(`
+			ca_pool = %q
+			ttl = "1ms"
+		`, testCAPool),
+	})
+	s.Require().EqualError(err, "upstreamca(gcp_cas): ttl must be at least one second")
+}
+
+func (s *Suite) TestSubmitCSRNotConfigured() {
+	_, err := New().SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR()})
+	s.Require().EqualError(err, "upstreamca(gcp_cas): not configured")
+}
+
+func (s *Suite) TestSubmitCSRRejectsMalformedCSR() {
+	_, err := s.p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: []byte("malformed")})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "upstreamca(gcp_cas): unable to parse CSR")
+}
+
+func (s *Suite) TestSubmitCSR() {
+	csr := s.makeCSR()
+	resp, err := s.p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: csr})
+	s.Require().NoError(err)
+
+	// the request is built from the configuration
+	req := s.client.lastRequest
+	s.Require().Equal(testCAPool, req.CAPool)
+	s.Require().Equal("spire-ID", req.CertificateID)
+	s.Require().Equal("ID", req.RequestID)
+	s.Require().Equal("CA", req.IssuingCertificateAuthorityID)
+	s.Require().Equal(testTemplate, req.Certificate.CertificateTemplate)
+	s.Require().Equal("3600s", req.Certificate.Lifetime)
+	parsedCSR, err := pemutil.ParseCertificateRequest([]byte(req.Certificate.PemCSR))
+	s.Require().NoError(err)
+	s.Require().Equal(csr, parsedCSR.Raw)
+
+	// the chain holds the issued certificate and the intermediate, while the
+	// bundle holds the roots of every CA in the pool
+	chain, err := x509.ParseCertificates(resp.SignedCertificate.CertChain)
+	s.Require().NoError(err)
+	s.Require().Len(chain, 2)
+	s.Require().Equal("spire", chain[0].Subject.CommonName)
+	s.Require().True(chain[1].Equal(s.client.intermediate))
+
+	bundle, err := x509.ParseCertificates(resp.SignedCertificate.Bundle)
+	s.Require().NoError(err)
+	s.Require().Len(bundle, 2)
+	s.Require().True(bundle[0].Equal(s.client.root))
+	s.Require().True(bundle[1].Equal(s.client.otherRoot))
+}
+
+func (s *Suite) TestSubmitCSROmitsUnsetOptions() {
+	p := s.newUpstreamCA(fmt.Sprintf(`ca_pool = %q`, testCAPool))
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR()})
+	s.Require().NoError(err)
+
+	req := s.client.lastRequest
+	s.Require().Empty(req.IssuingCertificateAuthorityID)
+	s.Require().Empty(req.Certificate.CertificateTemplate)
+	s.Require().Empty(req.Certificate.Lifetime)
+}
+
+func (s *Suite) TestSubmitCSRFailsOnAPIError() {
+	s.client.createErr = errors.New("POST certificates failed: PERMISSION_DENIED: denied")
+	_, err := s.p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR()})
+	s.Require().EqualError(err, "upstreamca(gcp_cas): unable to create certificate: POST certificates failed: PERMISSION_DENIED: denied")
+}
+
+func (s *Suite) makeCSR() []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "spire"},
+		URIs:    []*url.URL{{Scheme: "spiffe", Host: "example.org"}},
+	}, key)
+	s.Require().NoError(err)
+	return csr
+}
+
+// fakeClient issues certificates from an intermediate CA under a root. The
+// pool has a second CA under another root.
+type fakeClient struct {
+	s *Suite
+
+	root            *x509.Certificate
+	otherRoot       *x509.Certificate
+	intermediate    *x509.Certificate
+	intermediateKey *ecdsa.PrivateKey
+	serialNumber    x509util.SerialNumber
+	lastRequest     *createCertificateRequest
+	createErr       error
+}
+
+func (s *Suite) newFakeClient() *fakeClient {
+	c := &fakeClient{
+		s:            s,
+		serialNumber: x509util.NewSerialNumber(),
+	}
+	var rootKey *ecdsa.PrivateKey
+	c.root, rootKey = c.createCA("root", nil, nil)
+	c.otherRoot, _ = c.createCA("other root", nil, nil)
+	c.intermediate, c.intermediateKey = c.createCA("intermediate", c.root, rootKey)
+	return c
+}
+
+func (c *fakeClient) CreateCertificate(ctx context.Context, req *createCertificateRequest) (*certificate, error) {
+	c.lastRequest = req
+	if c.createErr != nil {
+		return nil, c.createErr
+	}
+
+	csr, err := pemutil.ParseCertificateRequest([]byte(req.Certificate.PemCSR))
+	if err != nil {
+		return nil, err
+	}
+	cert := c.createCertificate(&x509.Certificate{
+		Subject:               csr.Subject,
+		URIs:                  csr.URIs,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, csr.PublicKey, c.intermediate, c.intermediateKey)
+
+	return &certificate{
+		Name:           req.CAPool + "/certificates/" + req.CertificateID,
+		PemCertificate: string(pemutil.EncodeCertificate(cert)),
+		PemCertificateChain: []string{
+			string(pemutil.EncodeCertificate(c.intermediate)),
+			string(pemutil.EncodeCertificate(c.root)),
+		},
+	}, nil
+}
+
+func (c *fakeClient) FetchCACerts(ctx context.Context, caPool string) ([][]string, error) {
+	return [][]string{
+		{
+			string(pemutil.EncodeCertificate(c.intermediate)),
+			string(pemutil.EncodeCertificate(c.root)),
+		},
+		{
+			string(pemutil.EncodeCertificate(c.otherRoot)),
+		},
+	}, nil
+}
+
+func (c *fakeClient) createCA(commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.s.Require().NoError(err)
+	if parent == nil {
+		parentKey = key
+	}
+	cert := c.createCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, &key.PublicKey, parent, parentKey)
+	return cert, key
+}
+
+func (c *fakeClient) createCertificate(tmpl *x509.Certificate, publicKey interface{}, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	serialNumber, err := c.serialNumber.NextNumber(ctx)
+	c.s.Require().NoError(err)
+	tmpl.SerialNumber = serialNumber
+	tmpl.NotBefore = time.Now().Add(-time.Minute)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent = tmpl
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, parent, publicKey, parentKey)
+	c.s.Require().NoError(err)
+	cert, err := x509.ParseCertificate(certDER)
+	c.s.Require().NoError(err)
+	return cert
+}
@@ -0,0 +1,206 @@
+package k8scsr
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/zeebo/errs"
+)
+
+const (
+	inClusterTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	inClusterCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+
+	csrPath = "/apis/certificates.k8s.io/v1/certificatesigningrequests"
+)
+
+type objectMeta struct {
+	Name            string            `json:"name,omitempty"`
+	GenerateName    string            `json:"generateName,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+type csrCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type certificateSigningRequest struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   objectMeta `json:"metadata"`
+	Spec       struct {
+		Request           []byte   `json:"request"`
+		SignerName        string   `json:"signerName"`
+		ExpirationSeconds *int32   `json:"expirationSeconds,omitempty"`
+		Usages            []string `json:"usages,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Conditions  []csrCondition `json:"conditions,omitempty"`
+		Certificate []byte         `json:"certificate,omitempty"`
+	} `json:"status"`
+}
+
+type configMap struct {
+	Data map[string]string `json:"data"`
+}
+
+// kubeClient is an interface representing all of the Kubernetes API methods
+// the upstream CA needs to do its job.
+type kubeClient interface {
+	CreateCSR(ctx context.Context, csr *certificateSigningRequest) (*certificateSigningRequest, error)
+	GetCSR(ctx context.Context, name string) (*certificateSigningRequest, error)
+	ApproveCSR(ctx context.Context, csr *certificateSigningRequest) error
+	GetConfigMap(ctx context.Context, namespace, name string) (*configMap, error)
+}
+
+// restClient implements kubeClient against the Kubernetes REST API
+type restClient struct {
+	apiServer string
+	tokenFile string
+	http      *http.Client
+}
+
+func newRESTClient(apiServer, tokenFile, caFile string) (*restClient, error) {
+	if apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errs.New("api_server is required when not running in a Kubernetes cluster")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+		if tokenFile == "" {
+			tokenFile = inClusterTokenFile
+		}
+		if caFile == "" {
+			caFile = inClusterCAFile
+		}
+	}
+
+	client := &http.Client{}
+	if caFile != "" {
+		caPEM, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errs.New("unable to read CA file: %v", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caPEM) {
+			return nil, errs.New("no certificates found in CA file %q", caFile)
+		}
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots},
+		}
+	}
+
+	return &restClient{
+		apiServer: strings.TrimSuffix(apiServer, "/"),
+		tokenFile: tokenFile,
+		http:      client,
+	}, nil
+}
+
+func (c *restClient) CreateCSR(ctx context.Context, csr *certificateSigningRequest) (*certificateSigningRequest, error) {
+	resp := new(certificateSigningRequest)
+	if err := c.do(ctx, "POST", csrPath, csr, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *restClient) GetCSR(ctx context.Context, name string) (*certificateSigningRequest, error) {
+	resp := new(certificateSigningRequest)
+	if err := c.do(ctx, "GET", csrPath+"/"+name, nil, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *restClient) ApproveCSR(ctx context.Context, csr *certificateSigningRequest) error {
+	return c.do(ctx, "PUT", csrPath+"/"+csr.Metadata.Name+"/approval", csr, nil)
+}
+
+func (c *restClient) GetConfigMap(ctx context.Context, namespace, name string) (*configMap, error) {
+	resp := new(configMap)
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/namespaces/%s/configmaps/%s", namespace, name), nil, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *restClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		inBytes, err := json.Marshal(in)
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		body = bytes.NewReader(inBytes)
+	}
+
+	req, err := http.NewRequest(method, c.apiServer+path, body)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	// the token is read on every request since projected service account
+	// tokens are rotated by the kubelet
+	if c.tokenFile != "" {
+		token, err := ioutil.ReadFile(c.tokenFile)
+		if err != nil {
+			return errs.New("unable to read token file: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(method, path, resp.StatusCode, respBytes)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(respBytes, out); err != nil {
+			return errs.New("unable to decode %s response: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// newAPIError returns an error holding the message of the Status object
+// returned by the API server, if any.
+func newAPIError(method, path string, statusCode int, body []byte) error {
+	var status struct {
+		Message string `json:"message"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.Unmarshal(body, &status); err == nil && status.Message != "" {
+		return errs.New("%s %s failed: %s: %s", method, path, status.Reason, status.Message)
+	}
+	return errs.New("%s %s failed: unexpected status code %d", method, path, statusCode)
+}
@@ -0,0 +1,88 @@
+package k8scsr
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRESTClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k8scsr-client")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("TOKEN\n"), 0600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "Bearer TOKEN", req.Header.Get("Authorization"))
+		switch req.Method + " " + req.URL.Path {
+		case "POST /apis/certificates.k8s.io/v1/certificatesigningrequests":
+			csr := new(certificateSigningRequest)
+			require.NoError(t, json.NewDecoder(req.Body).Decode(csr))
+			require.Equal(t, "example.com/signer", csr.Spec.SignerName)
+			require.Equal(t, []byte("CSR"), csr.Spec.Request)
+			csr.Metadata.Name = csr.Metadata.GenerateName + "abcde"
+			w.WriteHeader(http.StatusCreated)
+			require.NoError(t, json.NewEncoder(w).Encode(csr))
+		case "PUT /apis/certificates.k8s.io/v1/certificatesigningrequests/spire-server-abcde/approval":
+			w.Write([]byte(`{}`))
+		case "GET /apis/certificates.k8s.io/v1/certificatesigningrequests/spire-server-abcde":
+			w.Write([]byte(`{"metadata":{"name":"spire-server-abcde"},"status":{"certificate":"Q0VSVA=="}}`))
+		case "GET /api/v1/namespaces/spire/configmaps/bundle":
+			w.Write([]byte(`{"data":{"ca.crt":"ROOT"}}`))
+		default:
+			t.Fatalf("unexpected request %s %s", req.Method, req.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := newRESTClient(server.URL, tokenFile, "")
+	require.NoError(t, err)
+
+	csr := &certificateSigningRequest{
+		Metadata: objectMeta{GenerateName: "spire-server-"},
+	}
+	csr.Spec.SignerName = "example.com/signer"
+	csr.Spec.Request = []byte("CSR")
+	created, err := client.CreateCSR(context.Background(), csr)
+	require.NoError(t, err)
+	require.Equal(t, "spire-server-abcde", created.Metadata.Name)
+
+	require.NoError(t, client.ApproveCSR(context.Background(), created))
+
+	issued, err := client.GetCSR(context.Background(), "spire-server-abcde")
+	require.NoError(t, err)
+	require.Equal(t, []byte("CERT"), issued.Status.Certificate)
+
+	configMap, err := client.GetConfigMap(context.Background(), "spire", "bundle")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"ca.crt": "ROOT"}, configMap.Data)
+}
+
+func TestRESTClientAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"kind":"Status","status":"Failure","message":"cannot create certificatesigningrequests","reason":"Forbidden","code":403}`))
+	}))
+	defer server.Close()
+
+	client, err := newRESTClient(server.URL, "", "")
+	require.NoError(t, err)
+	_, err = client.CreateCSR(context.Background(), &certificateSigningRequest{})
+	require.EqualError(t, err, "POST /apis/certificates.k8s.io/v1/certificatesigningrequests failed: Forbidden: cannot create certificatesigningrequests")
+}
+
+func TestRESTClientRequiresAPIServerOutsideCluster(t *testing.T) {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		t.Skip("running in a Kubernetes cluster")
+	}
+	_, err := newRESTClient("", "", "")
+	require.EqualError(t, err, "api_server is required when not running in a Kubernetes cluster")
+}
@@ -0,0 +1,302 @@
+package k8scsr
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/x509util"
+	"github.com/zeebo/errs"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/upstreamca"
+)
+
+const (
+	defaultIssuanceTimeout = 5 * time.Minute
+	defaultTrustBundleKey  = "ca.crt"
+
+	// minimum expiration accepted by the Kubernetes API
+	minTTL = 10 * time.Minute
+
+	conditionApproved = "Approved"
+	conditionDenied   = "Denied"
+	conditionFailed   = "Failed"
+)
+
+var (
+	csrError = errs.Class("upstreamca(k8s_csr)")
+)
+
+type configuration struct {
+	// SignerName is the name of the Kubernetes signer the CSR is addressed
+	// to (e.g. issuers.cert-manager.io/NAMESPACE.ISSUER)
+	SignerName string `hcl:"signer_name"`
+
+	// TTL is the optional lifetime requested for the issued certificates.
+	// Signers may ignore it.
+	TTL string `hcl:"ttl"`
+
+	// AutoApprove approves the CSR on behalf of the signer. The server must
+	// be granted the approve verb on the signer.
+	AutoApprove bool `hcl:"auto_approve"`
+
+	// CSRAnnotations are added to the CSR objects, e.g. to pass options to
+	// the signer
+	CSRAnnotations map[string]string `hcl:"csr_annotations"`
+
+	// TrustBundleConfigMap optionally names a config map, as
+	// NAMESPACE/NAME, holding the root certificates of the signer
+	TrustBundleConfigMap string `hcl:"trust_bundle_configmap"`
+
+	// TrustBundleKey is the key of the config map holding the roots
+	TrustBundleKey string `hcl:"trust_bundle_key"`
+
+	// IssuanceTimeout is how long to wait for the CSR to be approved and
+	// the certificate to be issued
+	IssuanceTimeout string `hcl:"issuance_timeout"`
+
+	// APIServer is the URL of the Kubernetes API server. If unset, the
+	// in-cluster configuration is used.
+	APIServer string `hcl:"api_server"`
+
+	// TokenFile is the path to the bearer token used to authenticate to the
+	// API server
+	TokenFile string `hcl:"token_file"`
+
+	// CAFile is the path to the CA certificates of the API server
+	CAFile string `hcl:"ca_file"`
+
+	ttl                  time.Duration
+	issuanceTimeout      time.Duration
+	trustBundleNamespace string
+	trustBundleName      string
+}
+
+type UpstreamCA struct {
+	// log is set by the catalog before the plugin is configured
+	log logrus.FieldLogger
+
+	mu     sync.RWMutex
+	config *configuration
+	client kubeClient
+
+	hooks struct {
+		newClient    func(config *configuration) (kubeClient, error)
+		pollInterval time.Duration
+	}
+}
+
+var _ upstreamca.Plugin = (*UpstreamCA)(nil)
+
+func New() *UpstreamCA {
+	p := &UpstreamCA{
+		log: logrus.StandardLogger(),
+	}
+	p.hooks.newClient = newClient
+	p.hooks.pollInterval = time.Second
+	return p
+}
+
+func (p *UpstreamCA) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	config := new(configuration)
+	if err := hcl.Decode(config, req.Configuration); err != nil {
+		return nil, csrError.New("unable to decode configuration: %v", err)
+	}
+
+	if config.SignerName == "" {
+		return nil, csrError.New("signer_name is required")
+	}
+	if config.TTL != "" {
+		ttl, err := time.ParseDuration(config.TTL)
+		if err != nil {
+			return nil, csrError.New("invalid ttl value: %v", err)
+		}
+		if ttl < minTTL {
+			return nil, csrError.New("ttl must be at least %s", minTTL)
+		}
+		config.ttl = ttl
+	}
+	config.issuanceTimeout = defaultIssuanceTimeout
+	if config.IssuanceTimeout != "" {
+		timeout, err := time.ParseDuration(config.IssuanceTimeout)
+		if err != nil {
+			return nil, csrError.New("invalid issuance_timeout value: %v", err)
+		}
+		config.issuanceTimeout = timeout
+	}
+	if config.TrustBundleConfigMap != "" {
+		parts := strings.Split(config.TrustBundleConfigMap, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, csrError.New("trust_bundle_configmap %q must be in the form NAMESPACE/NAME", config.TrustBundleConfigMap)
+		}
+		config.trustBundleNamespace, config.trustBundleName = parts[0], parts[1]
+	}
+	if config.TrustBundleKey == "" {
+		config.TrustBundleKey = defaultTrustBundleKey
+	}
+
+	client, err := p.hooks.newClient(config)
+	if err != nil {
+		return nil, csrError.Wrap(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	p.client = client
+
+	return &spi.ConfigureResponse{}, nil
+}
+
+// SetLogger sets the logger the plugin logs to
+func (p *UpstreamCA) SetLogger(log logrus.FieldLogger) {
+	p.log = log
+}
+
+func (p *UpstreamCA) GetPluginInfo(ctx context.Context, req *spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
+	return &spi.GetPluginInfoResponse{}, nil
+}
+
+// SubmitCSR creates a Kubernetes CSR object for the CSR and waits for the
+// signer to issue the certificate. The roots found in the issued chain and in
+// the trust bundle config map form the upstream bundle.
+func (p *UpstreamCA) SubmitCSR(ctx context.Context, req *upstreamca.SubmitCSRRequest) (*upstreamca.SubmitCSRResponse, error) {
+	config, client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	csr, err := x509.ParseCertificateRequest(req.Csr)
+	if err != nil {
+		return nil, csrError.New("unable to parse CSR: %v", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, csrError.New("CSR signature check failed: %v", err)
+	}
+
+	object := &certificateSigningRequest{
+		APIVersion: "certificates.k8s.io/v1",
+		Kind:       "CertificateSigningRequest",
+		Metadata: objectMeta{
+			GenerateName: "spire-server-",
+			Annotations:  config.CSRAnnotations,
+		},
+	}
+	object.Spec.Request = pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE REQUEST",
+		Bytes: req.Csr,
+	})
+	object.Spec.SignerName = config.SignerName
+	object.Spec.Usages = []string{"digital signature", "cert sign", "crl sign"}
+	if config.ttl > 0 {
+		expirationSeconds := int32(config.ttl / time.Second)
+		object.Spec.ExpirationSeconds = &expirationSeconds
+	}
+
+	created, err := client.CreateCSR(ctx, object)
+	if err != nil {
+		return nil, csrError.New("unable to create CSR: %v", err)
+	}
+	p.log.Infof("Created CSR %q for signer %q", created.Metadata.Name, config.SignerName)
+
+	if config.AutoApprove {
+		created.Status.Conditions = append(created.Status.Conditions, csrCondition{
+			Type:    conditionApproved,
+			Status:  "True",
+			Reason:  "SPIREAutoApproved",
+			Message: "Approved by the SPIRE server",
+		})
+		if err := client.ApproveCSR(ctx, created); err != nil {
+			return nil, csrError.New("unable to approve CSR %q: %v", created.Metadata.Name, err)
+		}
+	}
+
+	certs, err := p.waitForCertificate(ctx, client, created.Metadata.Name, config.issuanceTimeout)
+	if err != nil {
+		return nil, err
+	}
+	intermediates, roots := x509util.SplitRoots(certs[1:])
+
+	if config.trustBundleName != "" {
+		configMap, err := client.GetConfigMap(ctx, config.trustBundleNamespace, config.trustBundleName)
+		if err != nil {
+			return nil, csrError.New("unable to get trust bundle config map: %v", err)
+		}
+		bundleCerts, err := pemutil.ParseCertificates([]byte(configMap.Data[config.TrustBundleKey]))
+		if err != nil {
+			return nil, csrError.New("unable to parse trust bundle config map key %q: %v", config.TrustBundleKey, err)
+		}
+		roots = x509util.AppendUniqueCertificates(roots, bundleCerts...)
+	}
+	if len(roots) == 0 {
+		return nil, csrError.New("no root certificates found in the issued chain; configure trust_bundle_configmap")
+	}
+
+	return &upstreamca.SubmitCSRResponse{
+		SignedCertificate: &upstreamca.SignedCertificate{
+			CertChain: x509util.DERFromCertificates(append([]*x509.Certificate{certs[0]}, intermediates...)),
+			Bundle:    x509util.DERFromCertificates(roots),
+		},
+	}, nil
+}
+
+// waitForCertificate polls the CSR object until the certificate is issued,
+// returning the issued chain, or the CSR is denied or fails.
+func (p *UpstreamCA) waitForCertificate(ctx context.Context, client kubeClient, name string, timeout time.Duration) ([]*x509.Certificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(p.hooks.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		object, err := client.GetCSR(ctx, name)
+		if err != nil {
+			return nil, csrError.New("unable to get CSR %q: %v", name, err)
+		}
+
+		for _, condition := range object.Status.Conditions {
+			if condition.Status != "True" {
+				continue
+			}
+			switch condition.Type {
+			case conditionDenied, conditionFailed:
+				return nil, csrError.New("CSR %q was %s: %s: %s", name, strings.ToLower(condition.Type), condition.Reason, condition.Message)
+			}
+		}
+
+		if len(object.Status.Certificate) > 0 {
+			certs, err := pemutil.ParseCertificates(object.Status.Certificate)
+			if err != nil {
+				return nil, csrError.New("unable to parse certificate issued for CSR %q: %v", name, err)
+			}
+			return certs, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, csrError.New("timed out waiting for CSR %q to be issued: %v", name, ctx.Err())
+		}
+	}
+}
+
+func (p *UpstreamCA) getClient() (*configuration, kubeClient, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.client == nil {
+		return nil, nil, csrError.New("not configured")
+	}
+	return p.config, p.client, nil
+}
+
+func newClient(config *configuration) (kubeClient, error) {
+	return newRESTClient(config.APIServer, config.TokenFile, config.CAFile)
+}
@@ -0,0 +1,324 @@
+package k8scsr
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/x509util"
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/spiffe/spire/proto/server/upstreamca"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testSigner = "issuers.cert-manager.io/spire.ca"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestUpstreamCA(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+
+	client *fakeClient
+}
+
+func (s *Suite) SetupTest() {
+	s.client = s.newFakeClient()
+}
+
+func (s *Suite) newUpstreamCA(config string) *UpstreamCA {
+	p := New()
+	p.hooks.newClient = func(*configuration) (kubeClient, error) {
+		return s.client, nil
+	}
+	p.hooks.pollInterval = time.Millisecond
+	resp, err := p.Configure(ctx, &spi.ConfigureRequest{
+		Configuration: config,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(&spi.ConfigureResponse{}, resp)
+	return p
+}
+
+func (s *Suite) TestConfigureRequiresSignerName() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{})
+	s.Require().EqualError(err, "upstreamca(k8s_csr): signer_name is required")
+}
+
+func (s *Suite) TestConfigureRejectsShortTTL() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{
+		Configuration: `
+			signer_name = "example.com/signer"
+			ttl = "1m"
+		`,
+	})
+	s.Require().EqualError(err, "upstreamca(k8s_csr): ttl must be at least 10m0s")
+}
+
+func (s *Suite) TestConfigureRejectsMalformedTrustBundleConfigMap() {
+	_, err := New().Configure(ctx, &spi.ConfigureRequest{
+		Configuration: `
+			signer_name = "example.com/signer"
+			trust_bundle_configmap = "bundle"
+		`,
+	})
+	s.Require().EqualError(err, `upstreamca(k8s_csr): trust_bundle_configmap "bundle" must be in the form NAMESPACE/NAME`)
+}
+
+func (s *Suite) TestSubmitCSRNotConfigured() {
+	_, err := New().SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR()})
+	s.Require().EqualError(err, "upstreamca(k8s_csr): not configured")
+}
+
+func (s *Suite) TestSubmitCSR() {
+	p := s.newUpstreamCA(`
+		signer_name = "issuers.cert-manager.io/spire.ca"
+		ttl = "24h"
+		auto_approve = true
+		csr_annotations = {
+			"experimental.cert-manager.io/request-is-ca" = "true"
+		}
+	`)
+	s.client.issueAfterPolls = 2
+
+	csr := s.makeCSR()
+	resp, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: csr})
+	s.Require().NoError(err)
+
+	// the CSR object is built from the configuration and approved
+	object := s.client.created
+	s.Require().Equal("spire-server-", object.Metadata.GenerateName)
+	s.Require().Equal(map[string]string{"experimental.cert-manager.io/request-is-ca": "true"}, object.Metadata.Annotations)
+	s.Require().Equal(testSigner, object.Spec.SignerName)
+	s.Require().Equal(int32(86400), *object.Spec.ExpirationSeconds)
+	parsedCSR, err := pemutil.ParseCertificateRequest(object.Spec.Request)
+	s.Require().NoError(err)
+	s.Require().Equal(csr, parsedCSR.Raw)
+	s.Require().True(s.client.approved)
+
+	chain, err := x509.ParseCertificates(resp.SignedCertificate.CertChain)
+	s.Require().NoError(err)
+	s.Require().Len(chain, 2)
+	s.Require().Equal("spire", chain[0].Subject.CommonName)
+	s.Require().True(chain[1].Equal(s.client.intermediate))
+
+	bundle, err := x509.ParseCertificates(resp.SignedCertificate.Bundle)
+	s.Require().NoError(err)
+	s.Require().Len(bundle, 1)
+	s.Require().True(bundle[0].Equal(s.client.root))
+}
+
+func (s *Suite) TestSubmitCSRWithTrustBundleConfigMap() {
+	p := s.newUpstreamCA(`
+		signer_name = "issuers.cert-manager.io/spire.ca"
+		trust_bundle_configmap = "spire/bundle"
+	`)
+	s.client.leafOnly = true
+	s.client.configMaps["spire/bundle"] = &configMap{
+		Data: map[string]string{
+			"ca.crt": string(pemutil.EncodeCertificate(s.client.root)),
+		},
+	}
+
+	resp, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR()})
+	s.Require().NoError(err)
+	s.Require().False(s.client.approved)
+
+	chain, err := x509.ParseCertificates(resp.SignedCertificate.CertChain)
+	s.Require().NoError(err)
+	s.Require().Len(chain, 1)
+
+	bundle, err := x509.ParseCertificates(resp.SignedCertificate.Bundle)
+	s.Require().NoError(err)
+	s.Require().Len(bundle, 1)
+	s.Require().True(bundle[0].Equal(s.client.root))
+}
+
+func (s *Suite) TestSubmitCSRRequiresRoots() {
+	p := s.newUpstreamCA(`signer_name = "issuers.cert-manager.io/spire.ca"`)
+	s.client.leafOnly = true
+
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR()})
+	s.Require().EqualError(err, "upstreamca(k8s_csr): no root certificates found in the issued chain; configure trust_bundle_configmap")
+}
+
+func (s *Suite) TestSubmitCSRDenied() {
+	p := s.newUpstreamCA(`signer_name = "issuers.cert-manager.io/spire.ca"`)
+	s.client.deny = true
+
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR()})
+	s.Require().EqualError(err, `upstreamca(k8s_csr): CSR "spire-server-1" was denied: PolicyDenied: not allowed`)
+}
+
+func (s *Suite) TestSubmitCSRTimesOut() {
+	p := s.newUpstreamCA(`
+		signer_name = "issuers.cert-manager.io/spire.ca"
+		issuance_timeout = "20ms"
+	`)
+	s.client.issueAfterPolls = -1
+
+	_, err := p.SubmitCSR(ctx, &upstreamca.SubmitCSRRequest{Csr: s.makeCSR()})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), `upstreamca(k8s_csr): timed out waiting for CSR "spire-server-1" to be issued`)
+}
+
+func (s *Suite) makeCSR() []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "spire"},
+		URIs:    []*url.URL{{Scheme: "spiffe", Host: "example.org"}},
+	}, key)
+	s.Require().NoError(err)
+	return csr
+}
+
+// fakeClient acts as a signer issuing certificates from an intermediate CA
+// under a root.
+type fakeClient struct {
+	s *Suite
+
+	root            *x509.Certificate
+	intermediate    *x509.Certificate
+	intermediateKey *ecdsa.PrivateKey
+	serialNumber    x509util.SerialNumber
+
+	// issueAfterPolls is the number of polls after which the certificate is
+	// issued. It is never issued if negative.
+	issueAfterPolls int
+	// leafOnly issues the certificate without its chain
+	leafOnly bool
+	// deny denies the CSR
+	deny bool
+
+	mu         sync.Mutex
+	created    *certificateSigningRequest
+	approved   bool
+	polls      int
+	configMaps map[string]*configMap
+}
+
+func (s *Suite) newFakeClient() *fakeClient {
+	c := &fakeClient{
+		s:            s,
+		serialNumber: x509util.NewSerialNumber(),
+		configMaps:   make(map[string]*configMap),
+	}
+	var rootKey *ecdsa.PrivateKey
+	c.root, rootKey = c.createCA("root", nil, nil)
+	c.intermediate, c.intermediateKey = c.createCA("intermediate", c.root, rootKey)
+	return c
+}
+
+func (c *fakeClient) CreateCSR(ctx context.Context, csr *certificateSigningRequest) (*certificateSigningRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.created = csr
+	created := *csr
+	created.Metadata.Name = csr.Metadata.GenerateName + "1"
+	return &created, nil
+}
+
+func (c *fakeClient) ApproveCSR(ctx context.Context, csr *certificateSigningRequest) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.s.Require().Equal("spire-server-1", csr.Metadata.Name)
+	c.s.Require().Len(csr.Status.Conditions, 1)
+	c.s.Require().Equal(conditionApproved, csr.Status.Conditions[0].Type)
+	c.approved = true
+	return nil
+}
+
+func (c *fakeClient) GetCSR(ctx context.Context, name string) (*certificateSigningRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	object := *c.created
+	object.Metadata.Name = name
+	if c.deny {
+		object.Status.Conditions = []csrCondition{{
+			Type:    conditionDenied,
+			Status:  "True",
+			Reason:  "PolicyDenied",
+			Message: "not allowed",
+		}}
+		return &object, nil
+	}
+
+	c.polls++
+	if c.issueAfterPolls < 0 || c.polls <= c.issueAfterPolls {
+		return &object, nil
+	}
+
+	csr, err := pemutil.ParseCertificateRequest(object.Spec.Request)
+	if err != nil {
+		return nil, err
+	}
+	cert := c.createCertificate(&x509.Certificate{
+		Subject:               csr.Subject,
+		URIs:                  csr.URIs,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, csr.PublicKey, c.intermediate, c.intermediateKey)
+
+	chain := []*x509.Certificate{cert}
+	if !c.leafOnly {
+		chain = append(chain, c.intermediate, c.root)
+	}
+	object.Status.Certificate = pemutil.EncodeCertificates(chain)
+	return &object, nil
+}
+
+func (c *fakeClient) GetConfigMap(ctx context.Context, namespace, name string) (*configMap, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	configMap, ok := c.configMaps[namespace+"/"+name]
+	c.s.Require().True(ok, "no config map %s/%s", namespace, name)
+	return configMap, nil
+}
+
+func (c *fakeClient) createCA(commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.s.Require().NoError(err)
+	if parent == nil {
+		parentKey = key
+	}
+	cert := c.createCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, &key.PublicKey, parent, parentKey)
+	return cert, key
+}
+
+func (c *fakeClient) createCertificate(tmpl *x509.Certificate, publicKey interface{}, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	serialNumber, err := c.serialNumber.NextNumber(ctx)
+	c.s.Require().NoError(err)
+	tmpl.SerialNumber = serialNumber
+	tmpl.NotBefore = time.Now().Add(-time.Minute)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent = tmpl
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, parent, publicKey, parentKey)
+	c.s.Require().NoError(err)
+	cert, err := x509.ParseCertificate(certDER)
+	c.s.Require().NoError(err)
+	return cert
+}
@@ -0,0 +1,76 @@
+package pruner
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/proto/server/datastore"
+)
+
+type ChangeEventConfig struct {
+	DataStore datastore.DataStore
+	Log       logrus.FieldLogger
+	Metrics   telemetry.Metrics
+
+	// How long to wait between pruning runs. Defaults to an hour.
+	Interval time.Duration
+
+	// How long change events are kept. Defaults to a day.
+	Retention time.Duration
+}
+
+// NewChangeEventPruner returns a pruner that deletes old change events. Every
+// write to the datastore records a change event, so it runs whether or not
+// the datastore is otherwise pruned, and is not affected by dry-run mode.
+func NewChangeEventPruner(c *ChangeEventConfig) *changeEventPruner {
+	if c.Interval == 0 {
+		c.Interval = time.Hour
+	}
+	if c.Retention == 0 {
+		c.Retention = 24 * time.Hour
+	}
+
+	p := &changeEventPruner{
+		c: c,
+	}
+	p.hooks.now = time.Now
+	return p
+}
+
+type changeEventPruner struct {
+	c *ChangeEventConfig
+
+	hooks struct {
+		now func() time.Time
+	}
+}
+
+// Run prunes the change events every interval until the context is canceled.
+func (p *changeEventPruner) Run(ctx context.Context) error {
+	t := time.NewTicker(p.c.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.c.Log.Debug("Stopping change event pruner")
+			return nil
+		case <-t.C:
+			if err := p.prune(ctx); err != nil {
+				p.c.Log.Errorf("Could not prune change events: %v", err)
+			}
+		}
+	}
+}
+
+// prune deletes the change events created before the retention period.
+func (p *changeEventPruner) prune(ctx context.Context) (err error) {
+	defer telemetry.CountCall(p.c.Metrics, "pruner", "change_events", "prune")(&err)
+
+	_, err = p.c.DataStore.PruneChangeEvents(ctx, &datastore.PruneChangeEventsRequest{
+		CreatedBefore: p.hooks.now().Add(-p.c.Retention).Unix(),
+	})
+	return err
+}
@@ -0,0 +1,53 @@
+package pruner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/spiffe/spire/test/fakes/fakedatastore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneChangeEvents(t *testing.T) {
+	ctx := context.Background()
+	ds := fakedatastore.New()
+	metrics := newFakeMetrics()
+	now := time.Now()
+
+	createEntry := func(spiffeID string) {
+		_, err := ds.CreateRegistrationEntry(ctx, &datastore.CreateRegistrationEntryRequest{
+			Entry: &common.RegistrationEntry{
+				ParentId:  "spiffe://example.org/node",
+				SpiffeId:  spiffeID,
+				Selectors: []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	createEntry("spiffe://example.org/old")
+	now = now.Add(48 * time.Hour)
+
+	// the most recent event is always kept, so the pruned events can only
+	// be told apart once another one has been recorded
+	createEntry("spiffe://example.org/new")
+
+	log, _ := test.NewNullLogger()
+	p := NewChangeEventPruner(&ChangeEventConfig{
+		DataStore: ds,
+		Log:       log,
+		Metrics:   metrics,
+	})
+	p.hooks.now = func() time.Time {
+		return now
+	}
+	require.NoError(t, p.prune(ctx))
+
+	resp, err := ds.ListChangeEvents(ctx, &datastore.ListChangeEventsRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 1)
+}
@@ -0,0 +1,164 @@
+package pruner
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/proto/server/datastore"
+)
+
+const (
+	// deleteBatchSize is the number of registration entries deleted per
+	// datastore call
+	deleteBatchSize = 500
+)
+
+type Pruner interface {
+	Run(ctx context.Context) error
+}
+
+type pruner struct {
+	c *Config
+
+	hooks struct {
+		now func() time.Time
+	}
+}
+
+// Run prunes the datastore every interval until the context is canceled.
+func (p *pruner) Run(ctx context.Context) error {
+	t := time.NewTicker(p.c.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.c.Log.Debug("Stopping pruner")
+			return nil
+		case <-t.C:
+			p.prune(ctx)
+		}
+	}
+}
+
+func (p *pruner) prune(ctx context.Context) {
+	now := p.hooks.now()
+
+	if err := p.pruneEntries(ctx, now); err != nil {
+		p.c.Log.Errorf("Could not prune expired registration entries: %v", err)
+	}
+
+	if p.c.StaleAgentThreshold > 0 {
+		if err := p.pruneAgents(ctx, now.Add(-p.c.StaleAgentThreshold)); err != nil {
+			p.c.Log.Errorf("Could not prune stale agents: %v", err)
+		}
+	}
+}
+
+// pruneEntries deletes the registration entries that expired before now.
+func (p *pruner) pruneEntries(ctx context.Context, now time.Time) (err error) {
+	defer telemetry.CountCall(p.c.Metrics, "pruner", "entries", "prune")(&err)
+
+	resp, err := p.c.DataStore.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{
+		ByExpiresBefore: &wrappers.Int64Value{
+			Value: now.Unix(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(resp.Entries))
+	for _, entry := range resp.Entries {
+		p.c.Log.Infof("%s expired registration entry %s (%s)", p.verb(), entry.EntryId, entry.SpiffeId)
+		ids = append(ids, entry.EntryId)
+	}
+
+	if p.c.DryRun {
+		p.c.Metrics.IncrCounter([]string{"pruner", "entries", "prunable"}, float32(len(ids)))
+		return nil
+	}
+
+	pruned := 0
+	defer func() {
+		p.c.Metrics.IncrCounter([]string{"pruner", "entries", "pruned"}, float32(pruned))
+	}()
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > deleteBatchSize {
+			n = deleteBatchSize
+		}
+		if _, err := p.c.DataStore.BatchDeleteRegistrationEntries(ctx, &datastore.BatchDeleteRegistrationEntriesRequest{
+			EntryIds: ids[:n],
+		}); err != nil {
+			return err
+		}
+		pruned += n
+		ids = ids[n:]
+	}
+	return nil
+}
+
+// pruneAgents deletes the attested nodes, along with their selectors, whose
+// SVID expired before the given time without being renewed.
+func (p *pruner) pruneAgents(ctx context.Context, expiredBefore time.Time) (err error) {
+	defer telemetry.CountCall(p.c.Metrics, "pruner", "agents", "prune")(&err)
+
+	resp, err := p.c.DataStore.ListAttestedNodes(ctx, &datastore.ListAttestedNodesRequest{
+		ByExpiresBefore: &wrappers.Int64Value{
+			Value: expiredBefore.Unix(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if p.c.DryRun {
+		for _, node := range resp.Nodes {
+			p.c.Log.Infof("%s stale agent %s", p.verb(), node.SpiffeId)
+		}
+		p.c.Metrics.IncrCounter([]string{"pruner", "agents", "prunable"}, float32(len(resp.Nodes)))
+		return nil
+	}
+
+	pruned := 0
+	defer func() {
+		p.c.Metrics.IncrCounter([]string{"pruner", "agents", "pruned"}, float32(pruned))
+	}()
+	for _, node := range resp.Nodes {
+		// the agent may have renewed its SVID since it was listed, in which
+		// case it has another serial number and is not deleted
+		deleted, err := p.c.DataStore.DeleteAttestedNode(ctx, &datastore.DeleteAttestedNodeRequest{
+			SpiffeId: node.SpiffeId,
+			ByCertSerialNumber: &wrappers.StringValue{
+				Value: node.CertSerialNumber,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if deleted.Node == nil {
+			p.c.Log.Debugf("Agent %s was renewed, not pruning it", node.SpiffeId)
+			continue
+		}
+		p.c.Log.Infof("%s stale agent %s", p.verb(), node.SpiffeId)
+		if _, err := p.c.DataStore.SetNodeSelectors(ctx, &datastore.SetNodeSelectorsRequest{
+			Selectors: &datastore.NodeSelectors{
+				SpiffeId: node.SpiffeId,
+			},
+		}); err != nil {
+			return err
+		}
+		pruned++
+	}
+	return nil
+}
+
+func (p *pruner) verb() string {
+	if p.c.DryRun {
+		return "Would prune"
+	}
+	return "Pruning"
+}
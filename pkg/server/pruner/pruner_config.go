@@ -0,0 +1,37 @@
+package pruner
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/proto/server/datastore"
+)
+
+type Config struct {
+	DataStore datastore.DataStore
+	Log       logrus.FieldLogger
+	Metrics   telemetry.Metrics
+
+	// How long to wait between pruning runs
+	Interval time.Duration
+
+	// How long after its SVID expired an agent that hasn't renewed is
+	// pruned. Agents are not pruned if zero.
+	StaleAgentThreshold time.Duration
+
+	// If true, records are logged and counted but not deleted
+	DryRun bool
+}
+
+func New(c *Config) *pruner {
+	if c.Interval == 0 {
+		c.Interval = time.Hour
+	}
+
+	p := &pruner{
+		c: c,
+	}
+	p.hooks.now = time.Now
+	return p
+}
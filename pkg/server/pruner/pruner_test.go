@@ -0,0 +1,258 @@
+package pruner
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/spiffe/spire/test/fakes/fakedatastore"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestPruner(t *testing.T) {
+	suite.Run(t, new(PrunerTestSuite))
+}
+
+type PrunerTestSuite struct {
+	suite.Suite
+
+	ds      *fakedatastore.DataStore
+	metrics *fakeMetrics
+	now     time.Time
+}
+
+func (s *PrunerTestSuite) SetupTest() {
+	s.ds = fakedatastore.New()
+	s.metrics = newFakeMetrics()
+	s.now = time.Now()
+}
+
+func (s *PrunerTestSuite) TestPruneExpiredEntries() {
+	s.createEntry("spiffe://example.org/expired", s.now.Add(-time.Minute))
+	s.createEntry("spiffe://example.org/unexpired", s.now.Add(time.Minute))
+	s.createEntry("spiffe://example.org/neverexpires", time.Time{})
+
+	s.newPruner(0, false).prune(context.Background())
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/neverexpires",
+		"spiffe://example.org/unexpired",
+	}, s.listEntries())
+	s.Require().Equal(float32(1), s.metrics.counter("pruner.entries.pruned"))
+}
+
+func (s *PrunerTestSuite) TestPruneStaleAgents() {
+	s.createAgent("spiffe://example.org/spire/agent/stale", s.now.Add(-2*time.Hour))
+	s.createAgent("spiffe://example.org/spire/agent/expired", s.now.Add(-30*time.Minute))
+	s.createAgent("spiffe://example.org/spire/agent/active", s.now.Add(time.Hour))
+
+	s.newPruner(time.Hour, false).prune(context.Background())
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/spire/agent/active",
+		"spiffe://example.org/spire/agent/expired",
+	}, s.listAgents())
+	s.Require().Equal(float32(1), s.metrics.counter("pruner.agents.pruned"))
+
+	// the selectors of the stale agent are deleted with it
+	resp, err := s.ds.GetNodeSelectors(context.Background(), &datastore.GetNodeSelectorsRequest{
+		SpiffeId: "spiffe://example.org/spire/agent/stale",
+	})
+	s.Require().NoError(err)
+	s.Require().Empty(resp.Selectors.Selectors)
+}
+
+func (s *PrunerTestSuite) TestAgentsRenewedWhilePruningAreKept() {
+	s.createAgent("spiffe://example.org/spire/agent/renewed", s.now.Add(-2*time.Hour))
+
+	p := s.newPruner(time.Hour, false)
+	p.c.DataStore = renewingDataStore{
+		DataStore: s.ds,
+		notAfter:  s.now.Add(time.Hour),
+	}
+	p.prune(context.Background())
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/spire/agent/renewed",
+	}, s.listAgents())
+	s.Require().Equal(float32(0), s.metrics.counter("pruner.agents.pruned"))
+
+	// the selectors are kept along with the agent
+	resp, err := s.ds.GetNodeSelectors(context.Background(), &datastore.GetNodeSelectorsRequest{
+		SpiffeId: "spiffe://example.org/spire/agent/renewed",
+	})
+	s.Require().NoError(err)
+	s.Require().Len(resp.Selectors.Selectors, 1)
+}
+
+func (s *PrunerTestSuite) TestAgentsAreNotPrunedWithoutThreshold() {
+	s.createAgent("spiffe://example.org/spire/agent/stale", s.now.Add(-2*time.Hour))
+
+	s.newPruner(0, false).prune(context.Background())
+
+	s.Require().Equal([]string{
+		"spiffe://example.org/spire/agent/stale",
+	}, s.listAgents())
+}
+
+func (s *PrunerTestSuite) TestDryRun() {
+	s.createEntry("spiffe://example.org/expired", s.now.Add(-time.Minute))
+	s.createAgent("spiffe://example.org/spire/agent/stale", s.now.Add(-2*time.Hour))
+
+	s.newPruner(time.Hour, true).prune(context.Background())
+
+	s.Require().Equal([]string{"spiffe://example.org/expired"}, s.listEntries())
+	s.Require().Equal([]string{"spiffe://example.org/spire/agent/stale"}, s.listAgents())
+	s.Require().Equal(float32(1), s.metrics.counter("pruner.entries.prunable"))
+	s.Require().Equal(float32(1), s.metrics.counter("pruner.agents.prunable"))
+	s.Require().Equal(float32(0), s.metrics.counter("pruner.entries.pruned"))
+	s.Require().Equal(float32(0), s.metrics.counter("pruner.agents.pruned"))
+}
+
+func (s *PrunerTestSuite) TestRun() {
+	s.createEntry("spiffe://example.org/expired", s.now.Add(-time.Minute))
+
+	p := s.newPruner(0, false)
+	p.c.Interval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	for i := 0; i < 100 && len(s.listEntries()) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Require().Empty(s.listEntries())
+}
+
+func (s *PrunerTestSuite) newPruner(staleAgentThreshold time.Duration, dryRun bool) *pruner {
+	log, _ := test.NewNullLogger()
+	p := New(&Config{
+		DataStore:           s.ds,
+		Log:                 log,
+		Metrics:             s.metrics,
+		StaleAgentThreshold: staleAgentThreshold,
+		DryRun:              dryRun,
+	})
+	p.hooks.now = func() time.Time {
+		return s.now
+	}
+	return p
+}
+
+func (s *PrunerTestSuite) createEntry(spiffeID string, expiry time.Time) {
+	entry := &common.RegistrationEntry{
+		ParentId:  "spiffe://example.org/node",
+		SpiffeId:  spiffeID,
+		Selectors: []*common.Selector{{Type: "unix", Value: "uid:1000"}},
+	}
+	if !expiry.IsZero() {
+		entry.EntryExpiry = expiry.Unix()
+	}
+	_, err := s.ds.CreateRegistrationEntry(context.Background(), &datastore.CreateRegistrationEntryRequest{
+		Entry: entry,
+	})
+	s.Require().NoError(err)
+}
+
+func (s *PrunerTestSuite) createAgent(spiffeID string, notAfter time.Time) {
+	_, err := s.ds.CreateAttestedNode(context.Background(), &datastore.CreateAttestedNodeRequest{
+		Node: &common.AttestedNode{
+			SpiffeId:            spiffeID,
+			AttestationDataType: "test",
+			CertSerialNumber:    "1234",
+			CertNotAfter:        notAfter.Unix(),
+		},
+	})
+	s.Require().NoError(err)
+	_, err = s.ds.SetNodeSelectors(context.Background(), &datastore.SetNodeSelectorsRequest{
+		Selectors: &datastore.NodeSelectors{
+			SpiffeId:  spiffeID,
+			Selectors: []*common.Selector{{Type: "test", Value: "foo"}},
+		},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *PrunerTestSuite) listEntries() []string {
+	resp, err := s.ds.ListRegistrationEntries(context.Background(), &datastore.ListRegistrationEntriesRequest{})
+	s.Require().NoError(err)
+	var ids []string
+	for _, entry := range resp.Entries {
+		ids = append(ids, entry.SpiffeId)
+	}
+	return ids
+}
+
+func (s *PrunerTestSuite) listAgents() []string {
+	resp, err := s.ds.ListAttestedNodes(context.Background(), &datastore.ListAttestedNodesRequest{})
+	s.Require().NoError(err)
+	var ids []string
+	for _, node := range resp.Nodes {
+		ids = append(ids, node.SpiffeId)
+	}
+	return ids
+}
+
+// renewingDataStore renews the SVID of the listed agents right after they
+// are listed, as agents renewing while the pruner runs would
+type renewingDataStore struct {
+	*fakedatastore.DataStore
+	notAfter time.Time
+}
+
+func (ds renewingDataStore) ListAttestedNodes(ctx context.Context, req *datastore.ListAttestedNodesRequest) (*datastore.ListAttestedNodesResponse, error) {
+	resp, err := ds.DataStore.ListAttestedNodes(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range resp.Nodes {
+		if _, err := ds.UpdateAttestedNode(ctx, &datastore.UpdateAttestedNodeRequest{
+			SpiffeId:         node.SpiffeId,
+			CertSerialNumber: "5678",
+			CertNotAfter:     ds.notAfter.Unix(),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+type fakeMetrics struct {
+	telemetry.Blackhole
+
+	mu       sync.Mutex
+	counters map[string]float32
+}
+
+func newFakeMetrics() *fakeMetrics {
+	return &fakeMetrics{
+		counters: make(map[string]float32),
+	}
+}
+
+func (m *fakeMetrics) IncrCounter(key []string, val float32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[strings.Join(key, ".")] += val
+}
+
+func (m *fakeMetrics) counter(key string) float32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counters[key]
+}
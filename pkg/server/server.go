@@ -17,13 +17,16 @@ import (
 	"github.com/sirupsen/logrus"
 	common "github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/common/profiling"
+	"github.com/spiffe/spire/pkg/common/selector"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/pkg/common/util"
 	"github.com/spiffe/spire/pkg/server/ca"
 	"github.com/spiffe/spire/pkg/server/catalog"
 	"github.com/spiffe/spire/pkg/server/endpoints"
+	"github.com/spiffe/spire/pkg/server/pruner"
 	"github.com/spiffe/spire/pkg/server/svid"
 	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/spiffe/spire/proto/server/keymanager"
 	"google.golang.org/grpc"
 )
 
@@ -80,6 +83,36 @@ type Config struct {
 
 	// CASubject is the subject used in the CA certificate
 	CASubject pkix.Name
+
+	// CAKeyType is the key type used for the X509 CA signing key
+	CAKeyType keymanager.KeyType
+
+	// JWTKeyType is the key type used for the JWT signing key
+	JWTKeyType keymanager.KeyType
+
+	// If set, post-processes the selectors of agents
+	SelectorHook *selector.Hook
+
+	// If set, expired registration entries and stale agents are pruned from
+	// the datastore
+	Pruning *PruningConfig
+
+	// How long the change events recorded by the datastore are kept. They
+	// are pruned whether or not Pruning is set. Defaults to a day.
+	ChangeEventRetention time.Duration
+}
+
+// PruningConfig configures the pruning of the datastore
+type PruningConfig struct {
+	// How often the datastore is pruned
+	Interval time.Duration
+
+	// How long after its SVID expired an agent that hasn't renewed is
+	// pruned. Agents are not pruned if zero.
+	StaleAgentThreshold time.Duration
+
+	// If true, the records that would be pruned are only logged
+	DryRun bool
 }
 
 type Server struct {
@@ -127,7 +160,7 @@ func (s *Server) run(ctx context.Context) (err error) {
 	})
 	defer metrics.Stop()
 
-	cat := s.newCatalog()
+	cat := s.newCatalog(metrics)
 	defer cat.Stop()
 
 	if err := cat.Run(ctx); err != nil {
@@ -156,11 +189,17 @@ func (s *Server) run(ctx context.Context) (err error) {
 
 	endpointsServer := s.newEndpointsServer(cat, svidRotator, serverCA, metrics)
 
-	err = util.RunTasks(ctx,
+	tasks := []func(context.Context) error{
 		caManager.Run,
 		svidRotator.Run,
 		endpointsServer.ListenAndServe,
-	)
+		s.newChangeEventPruner(cat, metrics).Run,
+	}
+	if s.config.Pruning != nil {
+		tasks = append(tasks, s.newPruner(cat, metrics).Run)
+	}
+
+	err = util.RunTasks(ctx, tasks...)
 	if err == context.Canceled {
 		err = nil
 	}
@@ -221,11 +260,12 @@ func (s *Server) setupProfiling(ctx context.Context) (stop func()) {
 	}
 }
 
-func (s *Server) newCatalog() *catalog.ServerCatalog {
+func (s *Server) newCatalog(metrics telemetry.Metrics) *catalog.ServerCatalog {
 	return catalog.New(&catalog.Config{
 		GlobalConfig:  s.config.GlobalConfig(),
 		PluginConfigs: s.config.PluginConfigs,
 		Log:           s.config.Log.WithField("subsystem_name", "catalog"),
+		Metrics:       metrics,
 	})
 }
 
@@ -239,6 +279,8 @@ func (s *Server) newCAManager(ctx context.Context, catalog catalog.Catalog, metr
 		SVIDTTL:        s.config.SVIDTTL,
 		CATTL:          s.config.CATTL,
 		CASubject:      s.config.CASubject,
+		CAKeyType:      s.config.CAKeyType,
+		JWTKeyType:     s.config.JWTKeyType,
 		CertsPath:      s.caCertsPath(),
 	})
 	if err := caManager.Initialize(ctx); err != nil {
@@ -270,6 +312,28 @@ func (s *Server) newEndpointsServer(catalog catalog.Catalog, svidRotator svid.Ro
 		ServerCA:    serverCA,
 		Log:         s.config.Log.WithField("subsystem_name", "endpoints"),
 		Metrics:     metrics,
+
+		SelectorHook: s.config.SelectorHook,
+	})
+}
+
+func (s *Server) newPruner(catalog catalog.Catalog, metrics telemetry.Metrics) pruner.Pruner {
+	return pruner.New(&pruner.Config{
+		DataStore:           catalog.DataStores()[0],
+		Log:                 s.config.Log.WithField("subsystem_name", "pruner"),
+		Metrics:             metrics,
+		Interval:            s.config.Pruning.Interval,
+		StaleAgentThreshold: s.config.Pruning.StaleAgentThreshold,
+		DryRun:              s.config.Pruning.DryRun,
+	})
+}
+
+func (s *Server) newChangeEventPruner(catalog catalog.Catalog, metrics telemetry.Metrics) pruner.Pruner {
+	return pruner.NewChangeEventPruner(&pruner.ChangeEventConfig{
+		DataStore: catalog.DataStores()[0],
+		Log:       s.config.Log.WithField("subsystem_name", "change_event_pruner"),
+		Metrics:   metrics,
+		Retention: s.config.ChangeEventRetention,
 	})
 }
 
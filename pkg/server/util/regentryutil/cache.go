@@ -0,0 +1,274 @@
+package regentryutil
+
+import (
+	"context"
+	"sync"
+
+	"github.com/spiffe/spire/pkg/server/changefeed"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/server/datastore"
+)
+
+// Cache keeps the registration entries in memory so that the entries an agent
+// is authorized for can be worked out without listing entries from the
+// datastore. The entries are loaded once the change feed is in sync. After
+// that, only the entries the feed reports as changed are fetched again, on the
+// next read. The datastore is read instead while the feed is not in sync.
+//
+// Like FetchStaleRegistrationEntries, the cache may be slightly out of date and
+// must not be used to authorize a request.
+type Cache struct {
+	ds datastore.DataStore
+
+	// refreshMu serializes the reads from the datastore that bring the cache
+	// up to date, so that concurrent callers don't all load the entries
+	refreshMu sync.Mutex
+
+	mu         sync.RWMutex
+	synced     bool
+	loaded     bool
+	entries    map[string]*common.RegistrationEntry
+	byParentID map[string]idSet
+	bySelector map[selectorKey]idSet
+
+	// dirty holds the IDs of the entries changed since they were last read,
+	// along with the sequence number of their last change
+	dirty map[string]uint64
+	seq   uint64
+
+	// generation is bumped whenever the cached entries are dropped, so that
+	// a read racing with a resync doesn't cache what it read before
+	generation uint64
+}
+
+type idSet map[string]bool
+
+type selectorKey struct {
+	Type  string
+	Value string
+}
+
+var _ changefeed.Handler = (*Cache)(nil)
+
+func NewCache(ds datastore.DataStore) *Cache {
+	c := &Cache{
+		ds: ds,
+	}
+	c.drop()
+	return c
+}
+
+// FetchRegistrationEntries is like FetchStaleRegistrationEntries, but reads
+// the registration entries from the cache while it is in sync.
+func (c *Cache) FetchRegistrationEntries(ctx context.Context, spiffeID string) ([]*common.RegistrationEntry, error) {
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	fetcher := newRegistrationEntryFetcher(c.ds, true)
+	fetcher.cache = c
+	return fetcher.Fetch(ctx, spiffeID)
+}
+
+func (c *Cache) Sync() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.synced = true
+	c.drop()
+}
+
+func (c *Cache) Unsync() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.synced = false
+	c.drop()
+}
+
+func (c *Cache) Changed(event *datastore.ChangeEvent) {
+	if event.Kind != datastore.ChangeEvent_REGISTRATION_ENTRY {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.synced {
+		return
+	}
+	c.seq++
+	c.dirty[event.Key] = c.seq
+}
+
+// refresh loads the entries if they are not loaded yet, or else fetches the
+// entries that changed since they were read.
+func (c *Cache) refresh(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.mu.RLock()
+	synced, loaded := c.synced, c.loaded
+	generation, seq := c.generation, c.seq
+	dirty := make(map[string]uint64, len(c.dirty))
+	for id, changeSeq := range c.dirty {
+		dirty[id] = changeSeq
+	}
+	c.mu.RUnlock()
+
+	if !synced {
+		return nil
+	}
+
+	// The entries are read from the primary, since the change feed may
+	// already have reported changes a replica has not caught up with.
+	if !loaded {
+		resp, err := c.ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{})
+		if err != nil {
+			return err
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.generation != generation {
+			return nil
+		}
+		for _, entry := range resp.Entries {
+			c.setEntry(entry)
+		}
+		// the entries changed before they were listed are up to date
+		for id, changeSeq := range c.dirty {
+			if changeSeq <= seq {
+				delete(c.dirty, id)
+			}
+		}
+		c.loaded = true
+		return nil
+	}
+
+	for id, changeSeq := range dirty {
+		resp, err := c.ds.FetchRegistrationEntry(ctx, &datastore.FetchRegistrationEntryRequest{
+			EntryId: id,
+		})
+		if err != nil {
+			return err
+		}
+
+		c.mu.Lock()
+		if c.generation != generation {
+			c.mu.Unlock()
+			return nil
+		}
+		c.removeEntry(id)
+		if resp.Entry != nil {
+			c.setEntry(resp.Entry)
+		}
+		// the entry has to be read again if it changed in the meantime
+		if c.dirty[id] == changeSeq {
+			delete(c.dirty, id)
+		}
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+// childEntries returns the entries with the given parent ID. It returns
+// false if the entries are not loaded.
+func (c *Cache) childEntries(parentID string) ([]*common.RegistrationEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.loaded {
+		return nil, false
+	}
+
+	var entries []*common.RegistrationEntry
+	for id := range c.byParentID[parentID] {
+		entries = append(entries, c.entries[id])
+	}
+	return entries, true
+}
+
+// entriesMatchingSubset returns the entries whose selectors are a subset of
+// the given selectors. It returns false if the entries are not loaded.
+func (c *Cache) entriesMatchingSubset(selectors []*common.Selector) ([]*common.RegistrationEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.loaded {
+		return nil, false
+	}
+
+	set := make(map[selectorKey]bool, len(selectors))
+	for _, s := range selectors {
+		set[selectorKey{Type: s.Type, Value: s.Value}] = true
+	}
+
+	var entries []*common.RegistrationEntry
+	checked := make(map[string]bool)
+	for key := range set {
+		for id := range c.bySelector[key] {
+			if checked[id] {
+				continue
+			}
+			checked[id] = true
+
+			entry := c.entries[id]
+			if isSubset(entry.Selectors, set) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries, true
+}
+
+// setEntry adds the entry to the cache. Must be called with the lock held.
+func (c *Cache) setEntry(entry *common.RegistrationEntry) {
+	c.entries[entry.EntryId] = entry
+	if c.byParentID[entry.ParentId] == nil {
+		c.byParentID[entry.ParentId] = make(idSet)
+	}
+	c.byParentID[entry.ParentId][entry.EntryId] = true
+	for _, s := range entry.Selectors {
+		key := selectorKey{Type: s.Type, Value: s.Value}
+		if c.bySelector[key] == nil {
+			c.bySelector[key] = make(idSet)
+		}
+		c.bySelector[key][entry.EntryId] = true
+	}
+}
+
+// removeEntry removes the entry from the cache, if there. Must be called with
+// the lock held.
+func (c *Cache) removeEntry(id string) {
+	entry, ok := c.entries[id]
+	if !ok {
+		return
+	}
+	delete(c.entries, id)
+	delete(c.byParentID[entry.ParentId], id)
+	if len(c.byParentID[entry.ParentId]) == 0 {
+		delete(c.byParentID, entry.ParentId)
+	}
+	for _, s := range entry.Selectors {
+		key := selectorKey{Type: s.Type, Value: s.Value}
+		delete(c.bySelector[key], id)
+		if len(c.bySelector[key]) == 0 {
+			delete(c.bySelector, key)
+		}
+	}
+}
+
+// drop drops the cached entries. Must be called with the lock held.
+func (c *Cache) drop() {
+	c.loaded = false
+	c.entries = make(map[string]*common.RegistrationEntry)
+	c.byParentID = make(map[string]idSet)
+	c.bySelector = make(map[selectorKey]idSet)
+	c.dirty = make(map[string]uint64)
+	c.generation++
+}
+
+func isSubset(selectors []*common.Selector, set map[selectorKey]bool) bool {
+	for _, s := range selectors {
+		if !set[selectorKey{Type: s.Type, Value: s.Value}] {
+			return false
+		}
+	}
+	return true
+}
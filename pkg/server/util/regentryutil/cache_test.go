@@ -0,0 +1,111 @@
+package regentryutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/spiffe/spire/test/fakes/fakedatastore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheFetchRegistrationEntries(t *testing.T) {
+	require := require.New(t)
+	dataStore := &listCountingDataStore{DataStore: fakedatastore.New()}
+
+	createRegistrationEntry := func(entry *common.RegistrationEntry) *common.RegistrationEntry {
+		resp, err := dataStore.CreateRegistrationEntry(ctx, &datastore.CreateRegistrationEntryRequest{
+			Entry: entry,
+		})
+		require.NoError(err)
+		return resp.Entry
+	}
+
+	rootID := "spiffe://example.org/root"
+	a1 := &common.Selector{Type: "a", Value: "1"}
+	b2 := &common.Selector{Type: "b", Value: "2"}
+
+	createRegistrationEntry(&common.RegistrationEntry{
+		ParentId: rootID,
+		SpiffeId: "spiffe://example.org/1",
+	})
+	twoEntry := createRegistrationEntry(&common.RegistrationEntry{
+		ParentId: rootID,
+		SpiffeId: "spiffe://example.org/2",
+	})
+	createRegistrationEntry(&common.RegistrationEntry{
+		SpiffeId:  "spiffe://example.org/3",
+		Selectors: []*common.Selector{a1},
+	})
+	createRegistrationEntry(&common.RegistrationEntry{
+		SpiffeId:  "spiffe://example.org/4",
+		Selectors: []*common.Selector{a1, {Type: "c", Value: "3"}},
+	})
+	_, err := dataStore.SetNodeSelectors(ctx, &datastore.SetNodeSelectorsRequest{
+		Selectors: &datastore.NodeSelectors{
+			SpiffeId:  twoEntry.SpiffeId,
+			Selectors: []*common.Selector{a1, b2},
+		},
+	})
+	require.NoError(err)
+
+	cache := NewCache(dataStore)
+	requireEntries := func() {
+		expected, err := FetchRegistrationEntries(ctx, dataStore.DataStore, rootID)
+		require.NoError(err)
+		actual, err := cache.FetchRegistrationEntries(ctx, rootID)
+		require.NoError(err)
+		require.Equal(expected, actual)
+	}
+
+	// the datastore is read until the change feed is in sync
+	requireEntries()
+	require.NotZero(dataStore.lists)
+
+	// the entries are listed once the feed is in sync, and then read from
+	// the cache
+	cache.Sync()
+	dataStore.lists = 0
+	requireEntries()
+	requireEntries()
+	require.Equal(1, dataStore.lists)
+
+	// changed entries are fetched again
+	sixEntry := createRegistrationEntry(&common.RegistrationEntry{
+		ParentId: rootID,
+		SpiffeId: "spiffe://example.org/6",
+	})
+	cache.Changed(&datastore.ChangeEvent{
+		Kind: datastore.ChangeEvent_REGISTRATION_ENTRY,
+		Op:   datastore.ChangeEvent_CREATED,
+		Key:  sixEntry.EntryId,
+	})
+	_, err = dataStore.DeleteRegistrationEntry(ctx, &datastore.DeleteRegistrationEntryRequest{
+		EntryId: twoEntry.EntryId,
+	})
+	require.NoError(err)
+	cache.Changed(&datastore.ChangeEvent{
+		Kind: datastore.ChangeEvent_REGISTRATION_ENTRY,
+		Op:   datastore.ChangeEvent_DELETED,
+		Key:  twoEntry.EntryId,
+	})
+	requireEntries()
+	require.Equal(1, dataStore.lists)
+
+	// the datastore is read again once the feed is out of sync
+	cache.Unsync()
+	requireEntries()
+	require.True(dataStore.lists > 1)
+}
+
+type listCountingDataStore struct {
+	datastore.DataStore
+
+	lists int
+}
+
+func (ds *listCountingDataStore) ListRegistrationEntries(ctx context.Context, req *datastore.ListRegistrationEntriesRequest) (*datastore.ListRegistrationEntriesResponse, error) {
+	ds.lists++
+	return ds.DataStore.ListRegistrationEntries(ctx, req)
+}
@@ -14,17 +14,34 @@ func FetchRegistrationEntries(ctx context.Context,
 	dataStore datastore.DataStore, spiffeID string) (
 	entries []*common.RegistrationEntry, err error) {
 
-	fetcher := newRegistrationEntryFetcher(dataStore)
+	fetcher := newRegistrationEntryFetcher(dataStore, false)
+	return fetcher.Fetch(ctx, spiffeID)
+}
+
+// FetchStaleRegistrationEntries is like FetchRegistrationEntries, but lets the
+// datastore answer from data that is slightly out of date, e.g. from a
+// read-only replica. The entries must not be used to authorize a request.
+func FetchStaleRegistrationEntries(ctx context.Context,
+	dataStore datastore.DataStore, spiffeID string) (
+	entries []*common.RegistrationEntry, err error) {
+
+	fetcher := newRegistrationEntryFetcher(dataStore, true)
 	return fetcher.Fetch(ctx, spiffeID)
 }
 
 type registrationEntryFetcher struct {
-	dataStore datastore.DataStore
+	dataStore     datastore.DataStore
+	tolerateStale bool
+
+	// cache, if set, is used instead of listing entries from the datastore
+	// while it has the entries loaded
+	cache *Cache
 }
 
-func newRegistrationEntryFetcher(dataStore datastore.DataStore) *registrationEntryFetcher {
+func newRegistrationEntryFetcher(dataStore datastore.DataStore, tolerateStale bool) *registrationEntryFetcher {
 	return &registrationEntryFetcher{
-		dataStore: dataStore,
+		dataStore:     dataStore,
+		tolerateStale: tolerateStale,
 	}
 }
 
@@ -78,11 +95,18 @@ func (f *registrationEntryFetcher) directEntries(ctx context.Context, id string)
 // childEntries returns all registration entries for which the given ID is
 // defined as a parent.
 func (f *registrationEntryFetcher) childEntries(ctx context.Context, clientID string) ([]*common.RegistrationEntry, error) {
+	if f.cache != nil {
+		if entries, ok := f.cache.childEntries(clientID); ok {
+			return entries, nil
+		}
+	}
+
 	resp, err := f.dataStore.ListRegistrationEntries(ctx,
 		&datastore.ListRegistrationEntriesRequest{
 			ByParentId: &wrappers.StringValue{
 				Value: clientID,
 			},
+			TolerateStale: f.tolerateStale,
 		})
 	if err != nil {
 		return nil, err
@@ -96,7 +120,8 @@ func (f *registrationEntryFetcher) childEntries(ctx context.Context, clientID st
 func (f *registrationEntryFetcher) mappedEntries(ctx context.Context, clientID string) ([]*common.RegistrationEntry, error) {
 	selectorsResp, err := f.dataStore.GetNodeSelectors(ctx,
 		&datastore.GetNodeSelectorsRequest{
-			SpiffeId: clientID,
+			SpiffeId:      clientID,
+			TolerateStale: f.tolerateStale,
 		})
 	if err != nil {
 		return nil, err
@@ -111,6 +136,12 @@ func (f *registrationEntryFetcher) mappedEntries(ctx context.Context, clientID s
 		return nil, nil
 	}
 
+	if f.cache != nil {
+		if entries, ok := f.cache.entriesMatchingSubset(selectors); ok {
+			return entries, nil
+		}
+	}
+
 	// list all registration entries with a combination of the selectors
 	listResp, err := f.dataStore.ListRegistrationEntries(ctx,
 		&datastore.ListRegistrationEntriesRequest{
@@ -118,6 +149,7 @@ func (f *registrationEntryFetcher) mappedEntries(ctx context.Context, clientID s
 				Selectors: selectors,
 				Match:     datastore.BySelectors_MATCH_SUBSET,
 			},
+			TolerateStale: f.tolerateStale,
 		})
 	if err != nil {
 		return nil, err
@@ -6,6 +6,7 @@ import (
 
 	"github.com/golang/protobuf/ptypes/empty"
 	go_plugin "github.com/hashicorp/go-plugin"
+	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/proto/common/plugin"
 	"google.golang.org/grpc"
 )
@@ -38,6 +39,13 @@ func NewBuiltIn(plugin Plugin) *BuiltIn {
 	}
 }
 
+// SetLogger hands the logger to the plugin, if the plugin logs
+func (b BuiltIn) SetLogger(log logrus.FieldLogger) {
+	if p, ok := b.plugin.(interface{ SetLogger(logrus.FieldLogger) }); ok {
+		p.SetLogger(log)
+	}
+}
+
 func (b BuiltIn) GenerateKeyPair(ctx context.Context, req *GenerateKeyPairRequest) (*GenerateKeyPairResponse, error) {
 	resp, err := b.plugin.GenerateKeyPair(ctx, req)
 	if err != nil {
@@ -52,7 +52,7 @@ func (x DeleteFederatedBundleRequest_Mode) String() string {
 	return proto.EnumName(DeleteFederatedBundleRequest_Mode_name, int32(x))
 }
 func (DeleteFederatedBundleRequest_Mode) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{6, 0}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{6, 0}
 }
 
 // A type that represents the id of an entry.
@@ -68,7 +68,7 @@ func (m *RegistrationEntryID) Reset()         { *m = RegistrationEntryID{} }
 func (m *RegistrationEntryID) String() string { return proto.CompactTextString(m) }
 func (*RegistrationEntryID) ProtoMessage()    {}
 func (*RegistrationEntryID) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{0}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{0}
 }
 func (m *RegistrationEntryID) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RegistrationEntryID.Unmarshal(m, b)
@@ -108,7 +108,7 @@ func (m *ParentID) Reset()         { *m = ParentID{} }
 func (m *ParentID) String() string { return proto.CompactTextString(m) }
 func (*ParentID) ProtoMessage()    {}
 func (*ParentID) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{1}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{1}
 }
 func (m *ParentID) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ParentID.Unmarshal(m, b)
@@ -148,7 +148,7 @@ func (m *SpiffeID) Reset()         { *m = SpiffeID{} }
 func (m *SpiffeID) String() string { return proto.CompactTextString(m) }
 func (*SpiffeID) ProtoMessage()    {}
 func (*SpiffeID) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{2}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{2}
 }
 func (m *SpiffeID) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SpiffeID.Unmarshal(m, b)
@@ -188,7 +188,7 @@ func (m *UpdateEntryRequest) Reset()         { *m = UpdateEntryRequest{} }
 func (m *UpdateEntryRequest) String() string { return proto.CompactTextString(m) }
 func (*UpdateEntryRequest) ProtoMessage()    {}
 func (*UpdateEntryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{3}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{3}
 }
 func (m *UpdateEntryRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateEntryRequest.Unmarshal(m, b)
@@ -232,7 +232,7 @@ func (m *FederatedBundle) Reset()         { *m = FederatedBundle{} }
 func (m *FederatedBundle) String() string { return proto.CompactTextString(m) }
 func (*FederatedBundle) ProtoMessage()    {}
 func (*FederatedBundle) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{4}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{4}
 }
 func (m *FederatedBundle) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FederatedBundle.Unmarshal(m, b)
@@ -286,7 +286,7 @@ func (m *FederatedBundleID) Reset()         { *m = FederatedBundleID{} }
 func (m *FederatedBundleID) String() string { return proto.CompactTextString(m) }
 func (*FederatedBundleID) ProtoMessage()    {}
 func (*FederatedBundleID) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{5}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{5}
 }
 func (m *FederatedBundleID) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FederatedBundleID.Unmarshal(m, b)
@@ -325,7 +325,7 @@ func (m *DeleteFederatedBundleRequest) Reset()         { *m = DeleteFederatedBun
 func (m *DeleteFederatedBundleRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteFederatedBundleRequest) ProtoMessage()    {}
 func (*DeleteFederatedBundleRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{6}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{6}
 }
 func (m *DeleteFederatedBundleRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DeleteFederatedBundleRequest.Unmarshal(m, b)
@@ -364,7 +364,16 @@ type JoinToken struct {
 	// The join token. If not set, one will be generated
 	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
 	// TTL in seconds
-	Ttl                  int32    `protobuf:"varint,2,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Ttl int32 `protobuf:"varint,2,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// Number of times the token can be used. If not set, the token can be
+	// used once.
+	MaxUses int32 `protobuf:"varint,3,opt,name=max_uses,json=maxUses,proto3" json:"max_uses,omitempty"`
+	// Number of times the token has been used. Only set when listing
+	// tokens.
+	Uses int32 `protobuf:"varint,4,opt,name=uses,proto3" json:"uses,omitempty"`
+	// Expiration in seconds since unix epoch. Only set when listing
+	// tokens.
+	Expiry               int64    `protobuf:"varint,5,opt,name=expiry,proto3" json:"expiry,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -374,7 +383,7 @@ func (m *JoinToken) Reset()         { *m = JoinToken{} }
 func (m *JoinToken) String() string { return proto.CompactTextString(m) }
 func (*JoinToken) ProtoMessage()    {}
 func (*JoinToken) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{7}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{7}
 }
 func (m *JoinToken) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_JoinToken.Unmarshal(m, b)
@@ -408,6 +417,27 @@ func (m *JoinToken) GetTtl() int32 {
 	return 0
 }
 
+func (m *JoinToken) GetMaxUses() int32 {
+	if m != nil {
+		return m.MaxUses
+	}
+	return 0
+}
+
+func (m *JoinToken) GetUses() int32 {
+	if m != nil {
+		return m.Uses
+	}
+	return 0
+}
+
+func (m *JoinToken) GetExpiry() int64 {
+	if m != nil {
+		return m.Expiry
+	}
+	return 0
+}
+
 // CA Bundle of the server
 type Bundle struct {
 	// ASN.1 DER data of the bundle (deprecated).
@@ -423,7 +453,7 @@ func (m *Bundle) Reset()         { *m = Bundle{} }
 func (m *Bundle) String() string { return proto.CompactTextString(m) }
 func (*Bundle) ProtoMessage()    {}
 func (*Bundle) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{8}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{8}
 }
 func (m *Bundle) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Bundle.Unmarshal(m, b)
@@ -457,18 +487,401 @@ func (m *Bundle) GetBundle() *common.Bundle {
 	return nil
 }
 
-// Represents a ListAgents request
-type ListAgentsRequest struct {
+// Pagination of a listing. The token is a cursor into the listing, which
+// stays valid as items are added and removed.
+type Pagination struct {
+	// Token returned with the previous page. Empty for the first page, and
+	// in the response to the last page.
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// Maximum number of items per page. If zero, the listing is not
+	// paginated.
+	PageSize             int32    `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Pagination) Reset()         { *m = Pagination{} }
+func (m *Pagination) String() string { return proto.CompactTextString(m) }
+func (*Pagination) ProtoMessage()    {}
+func (*Pagination) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{9}
+}
+func (m *Pagination) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Pagination.Unmarshal(m, b)
+}
+func (m *Pagination) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Pagination.Marshal(b, m, deterministic)
+}
+func (dst *Pagination) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Pagination.Merge(dst, src)
+}
+func (m *Pagination) XXX_Size() int {
+	return xxx_messageInfo_Pagination.Size(m)
+}
+func (m *Pagination) XXX_DiscardUnknown() {
+	xxx_messageInfo_Pagination.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Pagination proto.InternalMessageInfo
+
+func (m *Pagination) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *Pagination) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+// Represents a ListEntries request
+type ListEntriesRequest struct {
+	// Page of entries to list
+	Pagination           *Pagination `protobuf:"bytes,1,opt,name=pagination,proto3" json:"pagination,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *ListEntriesRequest) Reset()         { *m = ListEntriesRequest{} }
+func (m *ListEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListEntriesRequest) ProtoMessage()    {}
+func (*ListEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{10}
+}
+func (m *ListEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListEntriesRequest.Unmarshal(m, b)
+}
+func (m *ListEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListEntriesRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListEntriesRequest.Merge(dst, src)
+}
+func (m *ListEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListEntriesRequest.Size(m)
+}
+func (m *ListEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListEntriesRequest proto.InternalMessageInfo
+
+func (m *ListEntriesRequest) GetPagination() *Pagination {
+	if m != nil {
+		return m.Pagination
+	}
+	return nil
+}
+
+// Represents a ListEntries response
+type ListEntriesResponse struct {
+	// Page of registration entries
+	Entries []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// Pagination to request the next page with
+	Pagination           *Pagination `protobuf:"bytes,2,opt,name=pagination,proto3" json:"pagination,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *ListEntriesResponse) Reset()         { *m = ListEntriesResponse{} }
+func (m *ListEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListEntriesResponse) ProtoMessage()    {}
+func (*ListEntriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{11}
+}
+func (m *ListEntriesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListEntriesResponse.Unmarshal(m, b)
+}
+func (m *ListEntriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListEntriesResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListEntriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListEntriesResponse.Merge(dst, src)
+}
+func (m *ListEntriesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListEntriesResponse.Size(m)
+}
+func (m *ListEntriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListEntriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListEntriesResponse proto.InternalMessageInfo
+
+func (m *ListEntriesResponse) GetEntries() []*common.RegistrationEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *ListEntriesResponse) GetPagination() *Pagination {
+	if m != nil {
+		return m.Pagination
+	}
+	return nil
+}
+
+// Represents a CreateEntries request
+type CreateEntriesRequest struct {
+	// Registration entries to create
+	Entries              []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *CreateEntriesRequest) Reset()         { *m = CreateEntriesRequest{} }
+func (m *CreateEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateEntriesRequest) ProtoMessage()    {}
+func (*CreateEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{12}
+}
+func (m *CreateEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateEntriesRequest.Unmarshal(m, b)
+}
+func (m *CreateEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateEntriesRequest.Marshal(b, m, deterministic)
+}
+func (dst *CreateEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateEntriesRequest.Merge(dst, src)
+}
+func (m *CreateEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateEntriesRequest.Size(m)
+}
+func (m *CreateEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateEntriesRequest proto.InternalMessageInfo
+
+func (m *CreateEntriesRequest) GetEntries() []*common.RegistrationEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+// Represents a CreateEntries response
+type CreateEntriesResponse struct {
+	// The created registration entries, in the same order as in the request
+	Entries              []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *CreateEntriesResponse) Reset()         { *m = CreateEntriesResponse{} }
+func (m *CreateEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*CreateEntriesResponse) ProtoMessage()    {}
+func (*CreateEntriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{13}
+}
+func (m *CreateEntriesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateEntriesResponse.Unmarshal(m, b)
+}
+func (m *CreateEntriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateEntriesResponse.Marshal(b, m, deterministic)
+}
+func (dst *CreateEntriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateEntriesResponse.Merge(dst, src)
+}
+func (m *CreateEntriesResponse) XXX_Size() int {
+	return xxx_messageInfo_CreateEntriesResponse.Size(m)
+}
+func (m *CreateEntriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateEntriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateEntriesResponse proto.InternalMessageInfo
+
+func (m *CreateEntriesResponse) GetEntries() []*common.RegistrationEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+// Represents an UpdateEntries request
+type UpdateEntriesRequest struct {
+	// Registration entries to update
+	Entries              []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *UpdateEntriesRequest) Reset()         { *m = UpdateEntriesRequest{} }
+func (m *UpdateEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateEntriesRequest) ProtoMessage()    {}
+func (*UpdateEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{14}
+}
+func (m *UpdateEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateEntriesRequest.Unmarshal(m, b)
+}
+func (m *UpdateEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateEntriesRequest.Marshal(b, m, deterministic)
+}
+func (dst *UpdateEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateEntriesRequest.Merge(dst, src)
+}
+func (m *UpdateEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateEntriesRequest.Size(m)
+}
+func (m *UpdateEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateEntriesRequest proto.InternalMessageInfo
+
+func (m *UpdateEntriesRequest) GetEntries() []*common.RegistrationEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+// Represents an UpdateEntries response
+type UpdateEntriesResponse struct {
+	// The updated registration entries, in the same order as in the request
+	Entries              []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *UpdateEntriesResponse) Reset()         { *m = UpdateEntriesResponse{} }
+func (m *UpdateEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*UpdateEntriesResponse) ProtoMessage()    {}
+func (*UpdateEntriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{15}
+}
+func (m *UpdateEntriesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateEntriesResponse.Unmarshal(m, b)
+}
+func (m *UpdateEntriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateEntriesResponse.Marshal(b, m, deterministic)
+}
+func (dst *UpdateEntriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateEntriesResponse.Merge(dst, src)
+}
+func (m *UpdateEntriesResponse) XXX_Size() int {
+	return xxx_messageInfo_UpdateEntriesResponse.Size(m)
+}
+func (m *UpdateEntriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateEntriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateEntriesResponse proto.InternalMessageInfo
+
+func (m *UpdateEntriesResponse) GetEntries() []*common.RegistrationEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+// Represents a DeleteEntries request
+type DeleteEntriesRequest struct {
+	// IDs of the registration entries to delete
+	Ids                  []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
+func (m *DeleteEntriesRequest) Reset()         { *m = DeleteEntriesRequest{} }
+func (m *DeleteEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteEntriesRequest) ProtoMessage()    {}
+func (*DeleteEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{16}
+}
+func (m *DeleteEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteEntriesRequest.Unmarshal(m, b)
+}
+func (m *DeleteEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteEntriesRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteEntriesRequest.Merge(dst, src)
+}
+func (m *DeleteEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteEntriesRequest.Size(m)
+}
+func (m *DeleteEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteEntriesRequest proto.InternalMessageInfo
+
+func (m *DeleteEntriesRequest) GetIds() []string {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+// Represents a DeleteEntries response
+type DeleteEntriesResponse struct {
+	// The deleted registration entries, in the same order as in the request
+	Entries              []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *DeleteEntriesResponse) Reset()         { *m = DeleteEntriesResponse{} }
+func (m *DeleteEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteEntriesResponse) ProtoMessage()    {}
+func (*DeleteEntriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{17}
+}
+func (m *DeleteEntriesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteEntriesResponse.Unmarshal(m, b)
+}
+func (m *DeleteEntriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteEntriesResponse.Marshal(b, m, deterministic)
+}
+func (dst *DeleteEntriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteEntriesResponse.Merge(dst, src)
+}
+func (m *DeleteEntriesResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteEntriesResponse.Size(m)
+}
+func (m *DeleteEntriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteEntriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteEntriesResponse proto.InternalMessageInfo
+
+func (m *DeleteEntriesResponse) GetEntries() []*common.RegistrationEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+// Represents a ListAgents request
+type ListAgentsRequest struct {
+	// Page of agents to list
+	Pagination           *Pagination `protobuf:"bytes,1,opt,name=pagination,proto3" json:"pagination,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
 func (m *ListAgentsRequest) Reset()         { *m = ListAgentsRequest{} }
 func (m *ListAgentsRequest) String() string { return proto.CompactTextString(m) }
 func (*ListAgentsRequest) ProtoMessage()    {}
 func (*ListAgentsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{9}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{18}
 }
 func (m *ListAgentsRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListAgentsRequest.Unmarshal(m, b)
@@ -488,20 +901,29 @@ func (m *ListAgentsRequest) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_ListAgentsRequest proto.InternalMessageInfo
 
+func (m *ListAgentsRequest) GetPagination() *Pagination {
+	if m != nil {
+		return m.Pagination
+	}
+	return nil
+}
+
 // Represents a ListAgents response
 type ListAgentsResponse struct {
 	// List of all attested agents
-	Nodes                []*common.AttestedNode `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
-	XXX_unrecognized     []byte                 `json:"-"`
-	XXX_sizecache        int32                  `json:"-"`
+	Nodes []*common.AttestedNode `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	// Pagination to request the next page with
+	Pagination           *Pagination `protobuf:"bytes,2,opt,name=pagination,proto3" json:"pagination,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
 }
 
 func (m *ListAgentsResponse) Reset()         { *m = ListAgentsResponse{} }
 func (m *ListAgentsResponse) String() string { return proto.CompactTextString(m) }
 func (*ListAgentsResponse) ProtoMessage()    {}
 func (*ListAgentsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{10}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{19}
 }
 func (m *ListAgentsResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListAgentsResponse.Unmarshal(m, b)
@@ -528,6 +950,13 @@ func (m *ListAgentsResponse) GetNodes() []*common.AttestedNode {
 	return nil
 }
 
+func (m *ListAgentsResponse) GetPagination() *Pagination {
+	if m != nil {
+		return m.Pagination
+	}
+	return nil
+}
+
 // Represents an evict request
 type EvictAgentRequest struct {
 	// Agent identity of the node to be evicted.
@@ -542,7 +971,7 @@ func (m *EvictAgentRequest) Reset()         { *m = EvictAgentRequest{} }
 func (m *EvictAgentRequest) String() string { return proto.CompactTextString(m) }
 func (*EvictAgentRequest) ProtoMessage()    {}
 func (*EvictAgentRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{11}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{20}
 }
 func (m *EvictAgentRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_EvictAgentRequest.Unmarshal(m, b)
@@ -582,7 +1011,7 @@ func (m *EvictAgentResponse) Reset()         { *m = EvictAgentResponse{} }
 func (m *EvictAgentResponse) String() string { return proto.CompactTextString(m) }
 func (*EvictAgentResponse) ProtoMessage()    {}
 func (*EvictAgentResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_registration_f1c410388c141894, []int{12}
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{21}
 }
 func (m *EvictAgentResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_EvictAgentResponse.Unmarshal(m, b)
@@ -609,6 +1038,157 @@ func (m *EvictAgentResponse) GetNode() *common.AttestedNode {
 	return nil
 }
 
+// Represents a ListJoinTokens request
+type ListJoinTokensRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListJoinTokensRequest) Reset()         { *m = ListJoinTokensRequest{} }
+func (m *ListJoinTokensRequest) String() string { return proto.CompactTextString(m) }
+func (*ListJoinTokensRequest) ProtoMessage()    {}
+func (*ListJoinTokensRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{22}
+}
+func (m *ListJoinTokensRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListJoinTokensRequest.Unmarshal(m, b)
+}
+func (m *ListJoinTokensRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListJoinTokensRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListJoinTokensRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListJoinTokensRequest.Merge(dst, src)
+}
+func (m *ListJoinTokensRequest) XXX_Size() int {
+	return xxx_messageInfo_ListJoinTokensRequest.Size(m)
+}
+func (m *ListJoinTokensRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListJoinTokensRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListJoinTokensRequest proto.InternalMessageInfo
+
+// Represents a ListJoinTokens response
+type ListJoinTokensResponse struct {
+	// List of all outstanding join tokens
+	JoinTokens           []*JoinToken `protobuf:"bytes,1,rep,name=join_tokens,json=joinTokens,proto3" json:"join_tokens,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *ListJoinTokensResponse) Reset()         { *m = ListJoinTokensResponse{} }
+func (m *ListJoinTokensResponse) String() string { return proto.CompactTextString(m) }
+func (*ListJoinTokensResponse) ProtoMessage()    {}
+func (*ListJoinTokensResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{23}
+}
+func (m *ListJoinTokensResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListJoinTokensResponse.Unmarshal(m, b)
+}
+func (m *ListJoinTokensResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListJoinTokensResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListJoinTokensResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListJoinTokensResponse.Merge(dst, src)
+}
+func (m *ListJoinTokensResponse) XXX_Size() int {
+	return xxx_messageInfo_ListJoinTokensResponse.Size(m)
+}
+func (m *ListJoinTokensResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListJoinTokensResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListJoinTokensResponse proto.InternalMessageInfo
+
+func (m *ListJoinTokensResponse) GetJoinTokens() []*JoinToken {
+	if m != nil {
+		return m.JoinTokens
+	}
+	return nil
+}
+
+// Represents a RevokeJoinToken request
+type RevokeJoinTokenRequest struct {
+	// The join token to revoke
+	Token                string   `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevokeJoinTokenRequest) Reset()         { *m = RevokeJoinTokenRequest{} }
+func (m *RevokeJoinTokenRequest) String() string { return proto.CompactTextString(m) }
+func (*RevokeJoinTokenRequest) ProtoMessage()    {}
+func (*RevokeJoinTokenRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{24}
+}
+func (m *RevokeJoinTokenRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeJoinTokenRequest.Unmarshal(m, b)
+}
+func (m *RevokeJoinTokenRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeJoinTokenRequest.Marshal(b, m, deterministic)
+}
+func (dst *RevokeJoinTokenRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeJoinTokenRequest.Merge(dst, src)
+}
+func (m *RevokeJoinTokenRequest) XXX_Size() int {
+	return xxx_messageInfo_RevokeJoinTokenRequest.Size(m)
+}
+func (m *RevokeJoinTokenRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeJoinTokenRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeJoinTokenRequest proto.InternalMessageInfo
+
+func (m *RevokeJoinTokenRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+// Represents a RevokeJoinToken response
+type RevokeJoinTokenResponse struct {
+	// The revoked join token
+	JoinToken            *JoinToken `protobuf:"bytes,1,opt,name=join_token,json=joinToken,proto3" json:"join_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *RevokeJoinTokenResponse) Reset()         { *m = RevokeJoinTokenResponse{} }
+func (m *RevokeJoinTokenResponse) String() string { return proto.CompactTextString(m) }
+func (*RevokeJoinTokenResponse) ProtoMessage()    {}
+func (*RevokeJoinTokenResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_registration_2a5d4f5e425d888f, []int{25}
+}
+func (m *RevokeJoinTokenResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeJoinTokenResponse.Unmarshal(m, b)
+}
+func (m *RevokeJoinTokenResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeJoinTokenResponse.Marshal(b, m, deterministic)
+}
+func (dst *RevokeJoinTokenResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeJoinTokenResponse.Merge(dst, src)
+}
+func (m *RevokeJoinTokenResponse) XXX_Size() int {
+	return xxx_messageInfo_RevokeJoinTokenResponse.Size(m)
+}
+func (m *RevokeJoinTokenResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeJoinTokenResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeJoinTokenResponse proto.InternalMessageInfo
+
+func (m *RevokeJoinTokenResponse) GetJoinToken() *JoinToken {
+	if m != nil {
+		return m.JoinToken
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*RegistrationEntryID)(nil), "spire.api.registration.RegistrationEntryID")
 	proto.RegisterType((*ParentID)(nil), "spire.api.registration.ParentID")
@@ -619,10 +1199,23 @@ func init() {
 	proto.RegisterType((*DeleteFederatedBundleRequest)(nil), "spire.api.registration.DeleteFederatedBundleRequest")
 	proto.RegisterType((*JoinToken)(nil), "spire.api.registration.JoinToken")
 	proto.RegisterType((*Bundle)(nil), "spire.api.registration.Bundle")
+	proto.RegisterType((*Pagination)(nil), "spire.api.registration.Pagination")
+	proto.RegisterType((*ListEntriesRequest)(nil), "spire.api.registration.ListEntriesRequest")
+	proto.RegisterType((*ListEntriesResponse)(nil), "spire.api.registration.ListEntriesResponse")
+	proto.RegisterType((*CreateEntriesRequest)(nil), "spire.api.registration.CreateEntriesRequest")
+	proto.RegisterType((*CreateEntriesResponse)(nil), "spire.api.registration.CreateEntriesResponse")
+	proto.RegisterType((*UpdateEntriesRequest)(nil), "spire.api.registration.UpdateEntriesRequest")
+	proto.RegisterType((*UpdateEntriesResponse)(nil), "spire.api.registration.UpdateEntriesResponse")
+	proto.RegisterType((*DeleteEntriesRequest)(nil), "spire.api.registration.DeleteEntriesRequest")
+	proto.RegisterType((*DeleteEntriesResponse)(nil), "spire.api.registration.DeleteEntriesResponse")
 	proto.RegisterType((*ListAgentsRequest)(nil), "spire.api.registration.ListAgentsRequest")
 	proto.RegisterType((*ListAgentsResponse)(nil), "spire.api.registration.ListAgentsResponse")
 	proto.RegisterType((*EvictAgentRequest)(nil), "spire.api.registration.EvictAgentRequest")
 	proto.RegisterType((*EvictAgentResponse)(nil), "spire.api.registration.EvictAgentResponse")
+	proto.RegisterType((*ListJoinTokensRequest)(nil), "spire.api.registration.ListJoinTokensRequest")
+	proto.RegisterType((*ListJoinTokensResponse)(nil), "spire.api.registration.ListJoinTokensResponse")
+	proto.RegisterType((*RevokeJoinTokenRequest)(nil), "spire.api.registration.RevokeJoinTokenRequest")
+	proto.RegisterType((*RevokeJoinTokenResponse)(nil), "spire.api.registration.RevokeJoinTokenResponse")
 	proto.RegisterEnum("spire.api.registration.DeleteFederatedBundleRequest_Mode", DeleteFederatedBundleRequest_Mode_name, DeleteFederatedBundleRequest_Mode_value)
 }
 
@@ -646,6 +1239,15 @@ type RegistrationClient interface {
 	FetchEntry(ctx context.Context, in *RegistrationEntryID, opts ...grpc.CallOption) (*common.RegistrationEntry, error)
 	// Retrieve all registered entries.
 	FetchEntries(ctx context.Context, in *common.Empty, opts ...grpc.CallOption) (*common.RegistrationEntries, error)
+	// Retrieve registered entries a page at a time.
+	ListEntries(ctx context.Context, in *ListEntriesRequest, opts ...grpc.CallOption) (*ListEntriesResponse, error)
+	// Creates several entries at once. The call fails without creating any
+	// entry if one of them is invalid or already exists.
+	CreateEntries(ctx context.Context, in *CreateEntriesRequest, opts ...grpc.CallOption) (*CreateEntriesResponse, error)
+	// Updates several entries at once.
+	UpdateEntries(ctx context.Context, in *UpdateEntriesRequest, opts ...grpc.CallOption) (*UpdateEntriesResponse, error)
+	// Deletes several entries at once and returns the deleted entries.
+	DeleteEntries(ctx context.Context, in *DeleteEntriesRequest, opts ...grpc.CallOption) (*DeleteEntriesResponse, error)
 	// Updates a specific registered entry.
 	UpdateEntry(ctx context.Context, in *UpdateEntryRequest, opts ...grpc.CallOption) (*common.RegistrationEntry, error)
 	// Returns all the Entries associated with the ParentID value.
@@ -666,6 +1268,10 @@ type RegistrationClient interface {
 	DeleteFederatedBundle(ctx context.Context, in *DeleteFederatedBundleRequest, opts ...grpc.CallOption) (*common.Empty, error)
 	// Create a new join token
 	CreateJoinToken(ctx context.Context, in *JoinToken, opts ...grpc.CallOption) (*JoinToken, error)
+	// ListJoinTokens will list all outstanding join tokens
+	ListJoinTokens(ctx context.Context, in *ListJoinTokensRequest, opts ...grpc.CallOption) (*ListJoinTokensResponse, error)
+	// RevokeJoinToken deletes a join token so it can no longer be used
+	RevokeJoinToken(ctx context.Context, in *RevokeJoinTokenRequest, opts ...grpc.CallOption) (*RevokeJoinTokenResponse, error)
 	// Retrieves the CA bundle.
 	FetchBundle(ctx context.Context, in *common.Empty, opts ...grpc.CallOption) (*Bundle, error)
 	// EvictAgent removes an attestation entry from the attested nodes store
@@ -718,6 +1324,42 @@ func (c *registrationClient) FetchEntries(ctx context.Context, in *common.Empty,
 	return out, nil
 }
 
+func (c *registrationClient) ListEntries(ctx context.Context, in *ListEntriesRequest, opts ...grpc.CallOption) (*ListEntriesResponse, error) {
+	out := new(ListEntriesResponse)
+	err := c.cc.Invoke(ctx, "/spire.api.registration.Registration/ListEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registrationClient) CreateEntries(ctx context.Context, in *CreateEntriesRequest, opts ...grpc.CallOption) (*CreateEntriesResponse, error) {
+	out := new(CreateEntriesResponse)
+	err := c.cc.Invoke(ctx, "/spire.api.registration.Registration/CreateEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registrationClient) UpdateEntries(ctx context.Context, in *UpdateEntriesRequest, opts ...grpc.CallOption) (*UpdateEntriesResponse, error) {
+	out := new(UpdateEntriesResponse)
+	err := c.cc.Invoke(ctx, "/spire.api.registration.Registration/UpdateEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registrationClient) DeleteEntries(ctx context.Context, in *DeleteEntriesRequest, opts ...grpc.CallOption) (*DeleteEntriesResponse, error) {
+	out := new(DeleteEntriesResponse)
+	err := c.cc.Invoke(ctx, "/spire.api.registration.Registration/DeleteEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *registrationClient) UpdateEntry(ctx context.Context, in *UpdateEntryRequest, opts ...grpc.CallOption) (*common.RegistrationEntry, error) {
 	out := new(common.RegistrationEntry)
 	err := c.cc.Invoke(ctx, "/spire.api.registration.Registration/UpdateEntry", in, out, opts...)
@@ -831,6 +1473,24 @@ func (c *registrationClient) CreateJoinToken(ctx context.Context, in *JoinToken,
 	return out, nil
 }
 
+func (c *registrationClient) ListJoinTokens(ctx context.Context, in *ListJoinTokensRequest, opts ...grpc.CallOption) (*ListJoinTokensResponse, error) {
+	out := new(ListJoinTokensResponse)
+	err := c.cc.Invoke(ctx, "/spire.api.registration.Registration/ListJoinTokens", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registrationClient) RevokeJoinToken(ctx context.Context, in *RevokeJoinTokenRequest, opts ...grpc.CallOption) (*RevokeJoinTokenResponse, error) {
+	out := new(RevokeJoinTokenResponse)
+	err := c.cc.Invoke(ctx, "/spire.api.registration.Registration/RevokeJoinToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *registrationClient) FetchBundle(ctx context.Context, in *common.Empty, opts ...grpc.CallOption) (*Bundle, error) {
 	out := new(Bundle)
 	err := c.cc.Invoke(ctx, "/spire.api.registration.Registration/FetchBundle", in, out, opts...)
@@ -868,6 +1528,15 @@ type RegistrationServer interface {
 	FetchEntry(context.Context, *RegistrationEntryID) (*common.RegistrationEntry, error)
 	// Retrieve all registered entries.
 	FetchEntries(context.Context, *common.Empty) (*common.RegistrationEntries, error)
+	// Retrieve registered entries a page at a time.
+	ListEntries(context.Context, *ListEntriesRequest) (*ListEntriesResponse, error)
+	// Creates several entries at once. The call fails without creating any
+	// entry if one of them is invalid or already exists.
+	CreateEntries(context.Context, *CreateEntriesRequest) (*CreateEntriesResponse, error)
+	// Updates several entries at once.
+	UpdateEntries(context.Context, *UpdateEntriesRequest) (*UpdateEntriesResponse, error)
+	// Deletes several entries at once and returns the deleted entries.
+	DeleteEntries(context.Context, *DeleteEntriesRequest) (*DeleteEntriesResponse, error)
 	// Updates a specific registered entry.
 	UpdateEntry(context.Context, *UpdateEntryRequest) (*common.RegistrationEntry, error)
 	// Returns all the Entries associated with the ParentID value.
@@ -888,6 +1557,10 @@ type RegistrationServer interface {
 	DeleteFederatedBundle(context.Context, *DeleteFederatedBundleRequest) (*common.Empty, error)
 	// Create a new join token
 	CreateJoinToken(context.Context, *JoinToken) (*JoinToken, error)
+	// ListJoinTokens will list all outstanding join tokens
+	ListJoinTokens(context.Context, *ListJoinTokensRequest) (*ListJoinTokensResponse, error)
+	// RevokeJoinToken deletes a join token so it can no longer be used
+	RevokeJoinToken(context.Context, *RevokeJoinTokenRequest) (*RevokeJoinTokenResponse, error)
 	// Retrieves the CA bundle.
 	FetchBundle(context.Context, *common.Empty) (*Bundle, error)
 	// EvictAgent removes an attestation entry from the attested nodes store
@@ -972,6 +1645,78 @@ func _Registration_FetchEntries_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Registration_ListEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationServer).ListEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.api.registration.Registration/ListEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationServer).ListEntries(ctx, req.(*ListEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registration_CreateEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationServer).CreateEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.api.registration.Registration/CreateEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationServer).CreateEntries(ctx, req.(*CreateEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registration_UpdateEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationServer).UpdateEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.api.registration.Registration/UpdateEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationServer).UpdateEntries(ctx, req.(*UpdateEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registration_DeleteEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationServer).DeleteEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.api.registration.Registration/DeleteEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationServer).DeleteEntries(ctx, req.(*DeleteEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Registration_UpdateEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UpdateEntryRequest)
 	if err := dec(in); err != nil {
@@ -1155,6 +1900,42 @@ func _Registration_CreateJoinToken_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Registration_ListJoinTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJoinTokensRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationServer).ListJoinTokens(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.api.registration.Registration/ListJoinTokens",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationServer).ListJoinTokens(ctx, req.(*ListJoinTokensRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registration_RevokeJoinToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeJoinTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationServer).RevokeJoinToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.api.registration.Registration/RevokeJoinToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationServer).RevokeJoinToken(ctx, req.(*RevokeJoinTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Registration_FetchBundle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(common.Empty)
 	if err := dec(in); err != nil {
@@ -1229,6 +2010,22 @@ var _Registration_serviceDesc = grpc.ServiceDesc{
 			MethodName: "FetchEntries",
 			Handler:    _Registration_FetchEntries_Handler,
 		},
+		{
+			MethodName: "ListEntries",
+			Handler:    _Registration_ListEntries_Handler,
+		},
+		{
+			MethodName: "CreateEntries",
+			Handler:    _Registration_CreateEntries_Handler,
+		},
+		{
+			MethodName: "UpdateEntries",
+			Handler:    _Registration_UpdateEntries_Handler,
+		},
+		{
+			MethodName: "DeleteEntries",
+			Handler:    _Registration_DeleteEntries_Handler,
+		},
 		{
 			MethodName: "UpdateEntry",
 			Handler:    _Registration_UpdateEntry_Handler,
@@ -1265,6 +2062,14 @@ var _Registration_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CreateJoinToken",
 			Handler:    _Registration_CreateJoinToken_Handler,
 		},
+		{
+			MethodName: "ListJoinTokens",
+			Handler:    _Registration_ListJoinTokens_Handler,
+		},
+		{
+			MethodName: "RevokeJoinToken",
+			Handler:    _Registration_RevokeJoinToken_Handler,
+		},
 		{
 			MethodName: "FetchBundle",
 			Handler:    _Registration_FetchBundle_Handler,
@@ -1288,56 +2093,78 @@ var _Registration_serviceDesc = grpc.ServiceDesc{
 	Metadata: "registration.proto",
 }
 
-func init() { proto.RegisterFile("registration.proto", fileDescriptor_registration_f1c410388c141894) }
-
-var fileDescriptor_registration_f1c410388c141894 = []byte{
-	// 758 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0x51, 0x6f, 0xda, 0x48,
-	0x10, 0x3e, 0x93, 0x80, 0x92, 0x01, 0x11, 0x58, 0x48, 0x14, 0x59, 0xa7, 0xbb, 0xc4, 0xa7, 0x53,
-	0xd3, 0xb4, 0x32, 0x29, 0x69, 0x1f, 0xfa, 0x08, 0xd8, 0x48, 0x34, 0x49, 0x1b, 0x19, 0xd2, 0x4a,
-	0xc9, 0x43, 0x04, 0xf6, 0x24, 0x71, 0x0b, 0xb6, 0x6b, 0x6f, 0x2a, 0xe5, 0xad, 0x7f, 0xa5, 0xbf,
-	0xa0, 0x7f, 0xb1, 0xf2, 0x7a, 0x6d, 0x8c, 0xb1, 0x83, 0x5b, 0xb5, 0x4f, 0x78, 0x3d, 0xdf, 0x7c,
-	0xf3, 0x79, 0x66, 0x76, 0x06, 0x20, 0x2e, 0xde, 0x9a, 0x1e, 0x75, 0xc7, 0xd4, 0xb4, 0x2d, 0xd9,
-	0x71, 0x6d, 0x6a, 0x93, 0x1d, 0xcf, 0x31, 0x5d, 0x94, 0xc7, 0x8e, 0x29, 0xc7, 0xad, 0xe2, 0x8b,
-	0x5b, 0x93, 0xde, 0xdd, 0x4f, 0x64, 0xdd, 0x9e, 0xb5, 0x3c, 0xc7, 0xbc, 0xb9, 0xc1, 0x16, 0x43,
-	0xb6, 0x98, 0x5b, 0x4b, 0xb7, 0x67, 0x33, 0xdb, 0xe2, 0x3f, 0x01, 0x95, 0xf4, 0x3f, 0x34, 0xb4,
-	0x18, 0x85, 0x6a, 0x51, 0xf7, 0x61, 0xa0, 0x90, 0x2a, 0x14, 0x4c, 0x63, 0x57, 0xd8, 0x13, 0x0e,
-	0x36, 0xb5, 0x82, 0x69, 0x48, 0x22, 0x6c, 0x9c, 0x8f, 0x5d, 0xb4, 0x68, 0xba, 0x6d, 0xc8, 0x82,
-	0xa5, 0xd8, 0x4e, 0x80, 0x5c, 0x38, 0xc6, 0x98, 0x22, 0x23, 0xd6, 0xf0, 0xf3, 0x3d, 0x7a, 0x94,
-	0xbc, 0x82, 0x22, 0xfa, 0x67, 0x06, 0x2c, 0xb7, 0xff, 0x95, 0x83, 0xef, 0xe1, 0xc2, 0x96, 0xf4,
-	0x68, 0x01, 0x5a, 0xfa, 0x26, 0xc0, 0x56, 0x1f, 0x0d, 0x74, 0xc7, 0x14, 0x8d, 0xee, 0xbd, 0x65,
-	0x4c, 0x91, 0x1c, 0x41, 0x53, 0x51, 0xcf, 0x35, 0xb5, 0xd7, 0x19, 0xa9, 0xca, 0x75, 0xf0, 0xd1,
-	0xd7, 0x91, 0x04, 0x32, 0xb7, 0x71, 0x89, 0x06, 0x91, 0xa1, 0x11, 0xf3, 0xd0, 0xc7, 0xd7, 0x3a,
-	0xba, 0xd4, 0xdb, 0x2d, 0xec, 0x09, 0x07, 0x15, 0xad, 0x3e, 0x37, 0xf5, 0xc6, 0x3d, 0xdf, 0x40,
-	0x9e, 0x43, 0x69, 0xc2, 0x62, 0xed, 0xae, 0x31, 0xb5, 0xcd, 0x45, 0xb5, 0x81, 0x0e, 0x8d, 0x63,
-	0xa4, 0xff, 0xa0, 0x9e, 0x90, 0x98, 0x92, 0x95, 0xef, 0x02, 0xfc, 0xad, 0xe0, 0x14, 0x29, 0x26,
-	0xb0, 0x61, 0x82, 0x12, 0x0e, 0xe4, 0x0c, 0xd6, 0x67, 0xb6, 0x81, 0x4c, 0x64, 0xb5, 0xfd, 0x5a,
-	0x4e, 0xaf, 0xbf, 0xfc, 0x18, 0xa7, 0x7c, 0x66, 0x1b, 0xa8, 0x31, 0x1a, 0xe9, 0x08, 0xd6, 0xfd,
-	0x13, 0xa9, 0xc0, 0x86, 0xa6, 0x0e, 0x47, 0xda, 0xa0, 0x37, 0xaa, 0xfd, 0x45, 0x00, 0x4a, 0x8a,
-	0x7a, 0xaa, 0x8e, 0xd4, 0x9a, 0x40, 0xaa, 0x00, 0xca, 0x60, 0x38, 0x7c, 0xd7, 0x1b, 0x74, 0x46,
-	0x6a, 0xad, 0x20, 0x1d, 0xc3, 0xe6, 0x1b, 0xdb, 0xb4, 0x46, 0xf6, 0x27, 0xb4, 0x48, 0x13, 0x8a,
-	0xd4, 0x7f, 0xe0, 0x02, 0x83, 0x03, 0xa9, 0xc1, 0x1a, 0xa5, 0x53, 0x26, 0xb1, 0xa8, 0xf9, 0x8f,
-	0xd2, 0x0d, 0x94, 0x78, 0x95, 0x32, 0x72, 0x2e, 0xac, 0xce, 0x79, 0x21, 0x47, 0xce, 0x1b, 0x50,
-	0x3f, 0x35, 0x3d, 0xda, 0xb9, 0x45, 0x8b, 0x7a, 0xfc, 0x73, 0xa5, 0x3e, 0x90, 0xf8, 0x4b, 0xcf,
-	0xb1, 0x2d, 0xcf, 0x6f, 0x97, 0xa2, 0x65, 0x1b, 0xe8, 0x87, 0x5e, 0x3b, 0x28, 0xb7, 0xc5, 0x45,
-	0xde, 0x0e, 0xa5, 0xe8, 0x51, 0x34, 0xde, 0xfa, 0xa9, 0x0a, 0x80, 0x52, 0x0b, 0xea, 0xea, 0x17,
-	0x53, 0x0f, 0x88, 0xc2, 0xfa, 0x88, 0xb0, 0xe1, 0xf1, 0x96, 0xe7, 0x49, 0x88, 0xce, 0x92, 0x02,
-	0x24, 0xee, 0xc0, 0x03, 0xcb, 0xb0, 0xee, 0xf3, 0xf1, 0x8e, 0x7f, 0x2c, 0x2e, 0xc3, 0xb5, 0xbf,
-	0x56, 0xa0, 0x12, 0xbf, 0x08, 0xe4, 0x0a, 0xca, 0x3d, 0x17, 0xc3, 0x9b, 0x44, 0x56, 0xdd, 0x19,
-	0xf1, 0x59, 0x56, 0x93, 0xa4, 0x5d, 0xf7, 0x2b, 0x28, 0x07, 0xbd, 0x13, 0x90, 0xff, 0x8c, 0xaf,
-	0xb8, 0x4a, 0x09, 0xb9, 0x04, 0xe8, 0x23, 0xd5, 0xef, 0xfe, 0x04, 0x77, 0x1f, 0x2a, 0x11, 0xb7,
-	0x89, 0x1e, 0x69, 0x2c, 0x3a, 0xa8, 0x33, 0x87, 0x3e, 0x88, 0xfb, 0x8f, 0xb3, 0xf8, 0x7e, 0x97,
-	0x50, 0x8e, 0xcd, 0x29, 0x72, 0x98, 0x25, 0x72, 0x79, 0x98, 0xad, 0xd6, 0x78, 0x01, 0x55, 0xbf,
-	0x13, 0xbb, 0x0f, 0xd1, 0x04, 0xdd, 0xcb, 0xa2, 0x0f, 0x11, 0x79, 0x24, 0x9f, 0x84, 0xb4, 0x43,
-	0x9c, 0xa2, 0x4e, 0x6d, 0x97, 0xec, 0x2c, 0x3a, 0x85, 0xef, 0xf3, 0x90, 0x45, 0x1a, 0xa3, 0x49,
-	0x9e, 0xa9, 0x31, 0x44, 0xe4, 0xa3, 0xdd, 0x0e, 0x9a, 0x36, 0x39, 0xb6, 0x9f, 0x64, 0xb1, 0x27,
-	0x80, 0x62, 0x5a, 0x41, 0xc9, 0x47, 0x68, 0xb2, 0xaa, 0x27, 0x59, 0x9f, 0xe6, 0x64, 0x1d, 0x28,
-	0x62, 0x5e, 0x01, 0xe4, 0x3d, 0x34, 0xfd, 0xcc, 0x24, 0x5e, 0x67, 0x74, 0x5a, 0x5e, 0xd6, 0x23,
-	0xc1, 0x4f, 0x4d, 0xd0, 0x4c, 0xbf, 0x37, 0x35, 0x13, 0xd8, 0x4e, 0xdd, 0x02, 0xe4, 0xe5, 0xaf,
-	0x2c, 0x8d, 0xf4, 0x18, 0x1f, 0x60, 0x2b, 0xa8, 0xea, 0x7c, 0x25, 0xec, 0x67, 0xb1, 0x47, 0x10,
-	0x71, 0x35, 0x84, 0x74, 0xa1, 0xcc, 0xea, 0xca, 0x25, 0xa7, 0xa6, 0xf8, 0x9f, 0x2c, 0x1a, 0xee,
-	0xa4, 0x03, 0xcc, 0xc7, 0x6f, 0x76, 0x47, 0x2c, 0xcd, 0x74, 0xf1, 0x30, 0x0f, 0x94, 0x4f, 0x73,
-	0x1d, 0x60, 0xbe, 0x5c, 0xb2, 0x83, 0x2c, 0x6d, 0xa5, 0xec, 0x20, 0xcb, 0xbb, 0xaa, 0x5b, 0xbd,
-	0xac, 0xc4, 0x21, 0x93, 0x12, 0xfb, 0xc7, 0x76, 0xfc, 0x23, 0x00, 0x00, 0xff, 0xff, 0x44, 0xf4,
-	0x42, 0xb5, 0x12, 0x0a, 0x00, 0x00,
+func init() { proto.RegisterFile("registration.proto", fileDescriptor_registration_2a5d4f5e425d888f) }
+
+var fileDescriptor_registration_2a5d4f5e425d888f = []byte{
+	// 1109 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0xdf, 0x72, 0xda, 0xc6,
+	0x17, 0xfe, 0x09, 0x1b, 0x02, 0x07, 0x7e, 0x18, 0xd6, 0x84, 0x50, 0xb5, 0xd3, 0x12, 0x75, 0x3a,
+	0x75, 0x9d, 0x58, 0xb8, 0x6e, 0x7b, 0x91, 0xab, 0xd6, 0x80, 0x3c, 0x43, 0x93, 0xb4, 0xae, 0xc0,
+	0x4d, 0xc7, 0xee, 0x0c, 0x23, 0xa3, 0x35, 0x91, 0x03, 0x92, 0x2a, 0xad, 0x33, 0xc6, 0x8f, 0xd0,
+	0xeb, 0xce, 0xf4, 0xba, 0x4f, 0xd0, 0x57, 0xec, 0xec, 0x6a, 0xf5, 0x07, 0x21, 0x19, 0xd5, 0x76,
+	0xaf, 0x90, 0x76, 0xbf, 0xfd, 0xce, 0xb7, 0xe7, 0x9c, 0x3d, 0x7b, 0x04, 0x20, 0x07, 0x4f, 0x0d,
+	0x97, 0x38, 0x1a, 0x31, 0x2c, 0x53, 0xb6, 0x1d, 0x8b, 0x58, 0xa8, 0xe9, 0xda, 0x86, 0x83, 0x65,
+	0xcd, 0x36, 0xe4, 0xe8, 0xac, 0xf8, 0xe5, 0xd4, 0x20, 0x6f, 0xaf, 0xce, 0xe5, 0x89, 0x35, 0xef,
+	0xb8, 0xb6, 0x71, 0x71, 0x81, 0x3b, 0x0c, 0xd9, 0x61, 0xcb, 0x3a, 0x13, 0x6b, 0x3e, 0xb7, 0x4c,
+	0xfe, 0xe3, 0x51, 0x49, 0x9f, 0xc1, 0xb6, 0x1a, 0xa1, 0x50, 0x4c, 0xe2, 0x2c, 0x06, 0x7d, 0x54,
+	0x85, 0x9c, 0xa1, 0xb7, 0x84, 0xb6, 0xb0, 0x53, 0x52, 0x73, 0x86, 0x2e, 0x89, 0x50, 0x3c, 0xd6,
+	0x1c, 0x6c, 0x92, 0xe4, 0xb9, 0x21, 0x33, 0x96, 0x30, 0xf7, 0x12, 0xd0, 0x89, 0xad, 0x6b, 0x04,
+	0x33, 0x62, 0x15, 0xff, 0x76, 0x85, 0x5d, 0x82, 0xbe, 0x81, 0x3c, 0xa6, 0xef, 0x0c, 0x58, 0x3e,
+	0xf8, 0x44, 0xf6, 0xf6, 0xc3, 0x85, 0xad, 0xe8, 0x51, 0x3d, 0xb4, 0xf4, 0x97, 0x00, 0x5b, 0x47,
+	0x58, 0xc7, 0x8e, 0x46, 0xb0, 0xde, 0xbd, 0x32, 0xf5, 0x19, 0x46, 0xfb, 0xd0, 0xe8, 0x2b, 0xc7,
+	0xaa, 0xd2, 0x3b, 0x1c, 0x29, 0xfd, 0xb1, 0xb7, 0xe9, 0x71, 0x20, 0x01, 0x85, 0x73, 0x5c, 0xa2,
+	0x8e, 0x64, 0xd8, 0x8e, 0xac, 0x98, 0x68, 0xe3, 0x09, 0x76, 0x88, 0xdb, 0xca, 0xb5, 0x85, 0x9d,
+	0x8a, 0x5a, 0x0f, 0xa7, 0x7a, 0x5a, 0x8f, 0x4e, 0xa0, 0xe7, 0x50, 0x38, 0x67, 0xb6, 0x5a, 0x1b,
+	0x4c, 0x6d, 0x63, 0x59, 0xad, 0xa7, 0x43, 0xe5, 0x18, 0xe9, 0x53, 0xa8, 0xc7, 0x24, 0x26, 0x78,
+	0xe5, 0x6f, 0x01, 0x3e, 0xea, 0xe3, 0x19, 0x26, 0x38, 0x86, 0xf5, 0x1d, 0x14, 0x5b, 0x80, 0x5e,
+	0xc3, 0xe6, 0xdc, 0xd2, 0x31, 0x13, 0x59, 0x3d, 0x78, 0x21, 0x27, 0xc7, 0x5f, 0xbe, 0x8d, 0x53,
+	0x7e, 0x6d, 0xe9, 0x58, 0x65, 0x34, 0xd2, 0x3e, 0x6c, 0xd2, 0x37, 0x54, 0x81, 0xa2, 0xaa, 0x0c,
+	0x47, 0xea, 0xa0, 0x37, 0xaa, 0xfd, 0x0f, 0x01, 0x14, 0xfa, 0xca, 0x2b, 0x65, 0xa4, 0xd4, 0x04,
+	0x54, 0x05, 0xe8, 0x0f, 0x86, 0xc3, 0x1f, 0x7b, 0x83, 0xc3, 0x91, 0x52, 0xcb, 0x49, 0x37, 0x50,
+	0xfa, 0xde, 0x32, 0xcc, 0x91, 0xf5, 0x0e, 0x9b, 0xa8, 0x01, 0x79, 0x42, 0x1f, 0xb8, 0x40, 0xef,
+	0x05, 0xd5, 0x60, 0x83, 0x90, 0x19, 0x93, 0x98, 0x57, 0xe9, 0x23, 0xfa, 0x00, 0x8a, 0x73, 0xed,
+	0x7a, 0x7c, 0xe5, 0x62, 0x97, 0xf9, 0x2e, 0xaf, 0x3e, 0x9a, 0x6b, 0xd7, 0x27, 0x2e, 0x76, 0x11,
+	0x82, 0x4d, 0x36, 0xbc, 0xc9, 0x86, 0xd9, 0x33, 0x6a, 0x42, 0x01, 0x5f, 0xdb, 0x86, 0xb3, 0x68,
+	0xe5, 0xdb, 0xc2, 0xce, 0x86, 0xca, 0xdf, 0xa4, 0x0b, 0x28, 0xf0, 0x60, 0xa7, 0x84, 0x4e, 0x58,
+	0x1f, 0xba, 0x5c, 0x86, 0xd0, 0x7d, 0x0b, 0x70, 0xac, 0x4d, 0x0d, 0x93, 0xf9, 0x32, 0x65, 0x93,
+	0x1f, 0x42, 0xc9, 0xd6, 0xa6, 0x78, 0xec, 0x1a, 0x37, 0x98, 0x6f, 0xb5, 0x48, 0x07, 0x86, 0xc6,
+	0x0d, 0x96, 0x7e, 0x01, 0xf4, 0xca, 0x70, 0x09, 0xcd, 0x59, 0x03, 0xbb, 0x7e, 0x2c, 0xbb, 0x00,
+	0x76, 0x40, 0xcb, 0x33, 0x5e, 0x4a, 0x8b, 0x60, 0x28, 0x40, 0x8d, 0xac, 0x92, 0xfe, 0x10, 0x60,
+	0x7b, 0x89, 0xda, 0xb5, 0x2d, 0xd3, 0xc5, 0xe8, 0x05, 0x3c, 0xc2, 0xde, 0x50, 0x4b, 0x68, 0x6f,
+	0x64, 0x39, 0x4a, 0x3e, 0x3e, 0x26, 0x2b, 0x77, 0x27, 0x59, 0x3f, 0x41, 0xa3, 0xe7, 0x60, 0x7e,
+	0xba, 0x23, 0x5b, 0xbe, 0xbb, 0x2c, 0x49, 0x85, 0xc7, 0x31, 0xca, 0x7b, 0x6f, 0x95, 0xca, 0x0c,
+	0x8b, 0xd0, 0x83, 0xc9, 0x8c, 0x51, 0xde, 0x5f, 0xe6, 0x0e, 0x34, 0xbc, 0x03, 0x1c, 0x93, 0x59,
+	0x83, 0x0d, 0x43, 0xf7, 0xe8, 0x4a, 0x2a, 0x7d, 0xa4, 0xd6, 0x63, 0xc8, 0xfb, 0x5b, 0x7f, 0x03,
+	0x75, 0x9a, 0x61, 0x87, 0x53, 0x6c, 0x92, 0x07, 0xcd, 0xdd, 0xdf, 0x05, 0x40, 0x51, 0x66, 0x2e,
+	0x75, 0x1f, 0xf2, 0xa6, 0xa5, 0x07, 0x42, 0xc5, 0x65, 0xa1, 0x87, 0x84, 0x60, 0x97, 0x60, 0xfd,
+	0x07, 0x5a, 0xb4, 0x3c, 0xe0, 0x83, 0x64, 0x6c, 0x07, 0xea, 0xca, 0x7b, 0x63, 0xe2, 0x89, 0xf1,
+	0x77, 0x29, 0x42, 0xd1, 0xe5, 0x17, 0x18, 0x3f, 0xed, 0xc1, 0xbb, 0xd4, 0x07, 0x14, 0x5d, 0xc0,
+	0xc5, 0xcb, 0xb0, 0x49, 0x35, 0x71, 0x8f, 0xdc, 0xa6, 0x9d, 0xe1, 0xa4, 0x27, 0xf0, 0x98, 0xba,
+	0x20, 0x28, 0xa1, 0xbe, 0x83, 0xa5, 0x5f, 0xa1, 0x19, 0x9f, 0xe0, 0x26, 0xba, 0x50, 0xbe, 0xb4,
+	0x0c, 0x73, 0xcc, 0xea, 0x8e, 0xef, 0xa5, 0xa7, 0x69, 0xdb, 0x0d, 0x08, 0x54, 0xb8, 0x0c, 0xb8,
+	0x24, 0x19, 0x9a, 0x2a, 0x7e, 0x6f, 0xbd, 0xc3, 0xe1, 0x34, 0xdf, 0x72, 0x62, 0x75, 0x93, 0xce,
+	0xe0, 0xc9, 0x0a, 0x9e, 0xcb, 0xf9, 0x0e, 0x20, 0x94, 0xc3, 0xf7, 0x9d, 0x41, 0x4d, 0x29, 0x50,
+	0x73, 0xf0, 0x67, 0x1d, 0x2a, 0xd1, 0xfc, 0x43, 0x67, 0x50, 0x0e, 0x8f, 0xfa, 0x02, 0xad, 0x4b,
+	0x55, 0xf1, 0x59, 0x9a, 0xb9, 0xa4, 0x06, 0xe6, 0x0c, 0xca, 0xe1, 0x11, 0x59, 0xa0, 0x7f, 0xb3,
+	0x56, 0x5c, 0xa7, 0x04, 0x9d, 0x02, 0x1c, 0x61, 0x32, 0x79, 0xfb, 0x5f, 0x70, 0x1f, 0x41, 0x25,
+	0xe0, 0xa6, 0x75, 0x7a, 0x7b, 0x79, 0x81, 0x32, 0xb7, 0xc9, 0x42, 0x7c, 0x7a, 0x3b, 0x0b, 0x5d,
+	0x77, 0x01, 0xe5, 0xc8, 0x8d, 0x81, 0x76, 0xd3, 0x44, 0xae, 0xde, 0x58, 0xe2, 0xb3, 0x4c, 0x58,
+	0x9e, 0x18, 0x33, 0xf8, 0xff, 0x52, 0xc1, 0x46, 0xcf, 0xd3, 0x56, 0x27, 0x5d, 0x15, 0xe2, 0x5e,
+	0x46, 0x74, 0x68, 0x6d, 0xa9, 0xee, 0xa6, 0x5b, 0x4b, 0xaa, 0xf8, 0xe2, 0x5e, 0x46, 0x74, 0x68,
+	0x6d, 0xa9, 0xce, 0xa6, 0x5b, 0x4b, 0x2a, 0xdc, 0xe2, 0x5e, 0x46, 0x34, 0xb7, 0x76, 0x0a, 0xe5,
+	0x48, 0xaf, 0x9c, 0x1e, 0xb1, 0xd5, 0x86, 0x7a, 0x7d, 0x56, 0x9d, 0x40, 0x95, 0x06, 0xaf, 0xbb,
+	0x08, 0xba, 0xf8, 0x76, 0x7a, 0xe5, 0xf4, 0x10, 0x59, 0x92, 0xec, 0xa5, 0x4f, 0x3b, 0xc4, 0x33,
+	0x3c, 0x21, 0x96, 0x83, 0x9a, 0xcb, 0x8b, 0xfc, 0xf1, 0x2c, 0x64, 0x81, 0xc6, 0xe0, 0x6b, 0x22,
+	0x55, 0xa3, 0x8f, 0xc8, 0x46, 0xcb, 0x3b, 0x8a, 0xf8, 0xa7, 0xc3, 0xe7, 0x69, 0xec, 0x31, 0xa0,
+	0x98, 0x74, 0x04, 0xd1, 0x25, 0x34, 0xd8, 0x39, 0x8d, 0xb3, 0x7e, 0x91, 0x91, 0x75, 0xd0, 0x17,
+	0xb3, 0x0a, 0x40, 0x3f, 0x43, 0x83, 0x7a, 0x26, 0x36, 0x9c, 0x52, 0x1b, 0xb2, 0xb2, 0xee, 0x0b,
+	0xd4, 0x35, 0x5e, 0x32, 0x3d, 0xac, 0x6b, 0xce, 0xfd, 0xf6, 0x24, 0x4e, 0xfb, 0xf5, 0x5d, 0x3e,
+	0x5c, 0x92, 0x6d, 0xbc, 0x81, 0x2d, 0x2f, 0xaa, 0xe1, 0x67, 0xc9, 0xfa, 0xeb, 0x48, 0x5c, 0x0f,
+	0x41, 0x96, 0x97, 0x85, 0xc1, 0x80, 0x8b, 0xf6, 0x6e, 0x2b, 0x87, 0x2b, 0x57, 0xba, 0x28, 0x67,
+	0x85, 0xf3, 0x63, 0xef, 0xc0, 0x56, 0xec, 0xd2, 0x45, 0x72, 0xfa, 0x8d, 0x92, 0x74, 0x9b, 0x8b,
+	0x9d, 0xcc, 0xf8, 0xb0, 0xb9, 0x60, 0xc9, 0xcb, 0xe3, 0x92, 0x98, 0x47, 0x1f, 0xa7, 0x91, 0xf2,
+	0x45, 0x13, 0x80, 0xb0, 0x33, 0x4a, 0x4f, 0xfb, 0x95, 0x76, 0x4b, 0xdc, 0xcd, 0x02, 0xe5, 0x42,
+	0x27, 0x00, 0x61, 0xef, 0x98, 0x6e, 0x64, 0xa5, 0x73, 0x15, 0x77, 0xb3, 0x40, 0x3d, 0x23, 0xdd,
+	0xea, 0x69, 0x25, 0x0a, 0x39, 0x2f, 0xb0, 0xbf, 0x46, 0xbe, 0xfa, 0x67, 0x00, 0x5f, 0x24, 0x43,
+	0x21, 0x7b, 0x11, 0x00, 0x00,
 }
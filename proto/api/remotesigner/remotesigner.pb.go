@@ -0,0 +1,652 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: remotesigner.proto
+
+package remotesigner
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// Type of a key. Values line up with the KeyManager key types.
+type KeyType int32
+
+const (
+	KeyType_UNSPECIFIED_KEY_TYPE KeyType = 0
+	KeyType_EC_P256              KeyType = 1
+	KeyType_EC_P384              KeyType = 2
+	KeyType_RSA_1024             KeyType = 3
+	KeyType_RSA_2048             KeyType = 4
+	KeyType_RSA_4096             KeyType = 5
+	KeyType_ED25519              KeyType = 6
+)
+
+var KeyType_name = map[int32]string{
+	0: "UNSPECIFIED_KEY_TYPE",
+	1: "EC_P256",
+	2: "EC_P384",
+	3: "RSA_1024",
+	4: "RSA_2048",
+	5: "RSA_4096",
+	6: "ED25519",
+}
+var KeyType_value = map[string]int32{
+	"UNSPECIFIED_KEY_TYPE": 0,
+	"EC_P256":              1,
+	"EC_P384":              2,
+	"RSA_1024":             3,
+	"RSA_2048":             4,
+	"RSA_4096":             5,
+	"ED25519":              6,
+}
+
+func (x KeyType) String() string {
+	return proto.EnumName(KeyType_name, int32(x))
+}
+func (KeyType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_remotesigner_6d4ed685c5f1f4be, []int{0}
+}
+
+// Hash algorithm used to produce the data to be signed. Values line up with
+// the go crypto.Hash constants.
+type HashAlgorithm int32
+
+const (
+	HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM HashAlgorithm = 0
+	HashAlgorithm_SHA224                     HashAlgorithm = 4
+	HashAlgorithm_SHA256                     HashAlgorithm = 5
+	HashAlgorithm_SHA384                     HashAlgorithm = 6
+	HashAlgorithm_SHA512                     HashAlgorithm = 7
+	HashAlgorithm_SHA3_224                   HashAlgorithm = 10
+	HashAlgorithm_SHA3_256                   HashAlgorithm = 11
+	HashAlgorithm_SHA3_384                   HashAlgorithm = 12
+	HashAlgorithm_SHA3_512                   HashAlgorithm = 13
+	HashAlgorithm_SHA512_224                 HashAlgorithm = 14
+	HashAlgorithm_SHA512_256                 HashAlgorithm = 15
+)
+
+var HashAlgorithm_name = map[int32]string{
+	0:  "UNSPECIFIED_HASH_ALGORITHM",
+	4:  "SHA224",
+	5:  "SHA256",
+	6:  "SHA384",
+	7:  "SHA512",
+	10: "SHA3_224",
+	11: "SHA3_256",
+	12: "SHA3_384",
+	13: "SHA3_512",
+	14: "SHA512_224",
+	15: "SHA512_256",
+}
+var HashAlgorithm_value = map[string]int32{
+	"UNSPECIFIED_HASH_ALGORITHM": 0,
+	"SHA224":                     4,
+	"SHA256":                     5,
+	"SHA384":                     6,
+	"SHA512":                     7,
+	"SHA3_224":                   10,
+	"SHA3_256":                   11,
+	"SHA3_384":                   12,
+	"SHA3_512":                   13,
+	"SHA512_224":                 14,
+	"SHA512_256":                 15,
+}
+
+func (x HashAlgorithm) String() string {
+	return proto.EnumName(HashAlgorithm_name, int32(x))
+}
+func (HashAlgorithm) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_remotesigner_6d4ed685c5f1f4be, []int{1}
+}
+
+// A public key held by the signer
+type PublicKey struct {
+	// key identifier
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// type of the key
+	Type KeyType `protobuf:"varint,2,opt,name=type,proto3,enum=spire.api.remotesigner.KeyType" json:"type,omitempty"`
+	// ASN.1 DER encoded PKIX public key
+	PkixData             []byte   `protobuf:"bytes,3,opt,name=pkix_data,json=pkixData,proto3" json:"pkix_data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PublicKey) Reset()         { *m = PublicKey{} }
+func (m *PublicKey) String() string { return proto.CompactTextString(m) }
+func (*PublicKey) ProtoMessage()    {}
+func (*PublicKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_remotesigner_6d4ed685c5f1f4be, []int{0}
+}
+func (m *PublicKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PublicKey.Unmarshal(m, b)
+}
+func (m *PublicKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PublicKey.Marshal(b, m, deterministic)
+}
+func (dst *PublicKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PublicKey.Merge(dst, src)
+}
+func (m *PublicKey) XXX_Size() int {
+	return xxx_messageInfo_PublicKey.Size(m)
+}
+func (m *PublicKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_PublicKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PublicKey proto.InternalMessageInfo
+
+func (m *PublicKey) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *PublicKey) GetType() KeyType {
+	if m != nil {
+		return m.Type
+	}
+	return KeyType_UNSPECIFIED_KEY_TYPE
+}
+
+func (m *PublicKey) GetPkixData() []byte {
+	if m != nil {
+		return m.PkixData
+	}
+	return nil
+}
+
+// Represents a request to generate a key
+type GenerateKeyRequest struct {
+	// key identifier. If a key with the same id already exists it is
+	// replaced by the new key.
+	KeyId string `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	// type of key to generate
+	KeyType              KeyType  `protobuf:"varint,2,opt,name=key_type,json=keyType,proto3,enum=spire.api.remotesigner.KeyType" json:"key_type,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GenerateKeyRequest) Reset()         { *m = GenerateKeyRequest{} }
+func (m *GenerateKeyRequest) String() string { return proto.CompactTextString(m) }
+func (*GenerateKeyRequest) ProtoMessage()    {}
+func (*GenerateKeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_remotesigner_6d4ed685c5f1f4be, []int{1}
+}
+func (m *GenerateKeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GenerateKeyRequest.Unmarshal(m, b)
+}
+func (m *GenerateKeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GenerateKeyRequest.Marshal(b, m, deterministic)
+}
+func (dst *GenerateKeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GenerateKeyRequest.Merge(dst, src)
+}
+func (m *GenerateKeyRequest) XXX_Size() int {
+	return xxx_messageInfo_GenerateKeyRequest.Size(m)
+}
+func (m *GenerateKeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GenerateKeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GenerateKeyRequest proto.InternalMessageInfo
+
+func (m *GenerateKeyRequest) GetKeyId() string {
+	if m != nil {
+		return m.KeyId
+	}
+	return ""
+}
+
+func (m *GenerateKeyRequest) GetKeyType() KeyType {
+	if m != nil {
+		return m.KeyType
+	}
+	return KeyType_UNSPECIFIED_KEY_TYPE
+}
+
+// Represents the generated key
+type GenerateKeyResponse struct {
+	PublicKey            *PublicKey `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *GenerateKeyResponse) Reset()         { *m = GenerateKeyResponse{} }
+func (m *GenerateKeyResponse) String() string { return proto.CompactTextString(m) }
+func (*GenerateKeyResponse) ProtoMessage()    {}
+func (*GenerateKeyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_remotesigner_6d4ed685c5f1f4be, []int{2}
+}
+func (m *GenerateKeyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GenerateKeyResponse.Unmarshal(m, b)
+}
+func (m *GenerateKeyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GenerateKeyResponse.Marshal(b, m, deterministic)
+}
+func (dst *GenerateKeyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GenerateKeyResponse.Merge(dst, src)
+}
+func (m *GenerateKeyResponse) XXX_Size() int {
+	return xxx_messageInfo_GenerateKeyResponse.Size(m)
+}
+func (m *GenerateKeyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GenerateKeyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GenerateKeyResponse proto.InternalMessageInfo
+
+func (m *GenerateKeyResponse) GetPublicKey() *PublicKey {
+	if m != nil {
+		return m.PublicKey
+	}
+	return nil
+}
+
+// Represents an empty request
+type ListPublicKeysRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListPublicKeysRequest) Reset()         { *m = ListPublicKeysRequest{} }
+func (m *ListPublicKeysRequest) String() string { return proto.CompactTextString(m) }
+func (*ListPublicKeysRequest) ProtoMessage()    {}
+func (*ListPublicKeysRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_remotesigner_6d4ed685c5f1f4be, []int{3}
+}
+func (m *ListPublicKeysRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListPublicKeysRequest.Unmarshal(m, b)
+}
+func (m *ListPublicKeysRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListPublicKeysRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListPublicKeysRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListPublicKeysRequest.Merge(dst, src)
+}
+func (m *ListPublicKeysRequest) XXX_Size() int {
+	return xxx_messageInfo_ListPublicKeysRequest.Size(m)
+}
+func (m *ListPublicKeysRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListPublicKeysRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListPublicKeysRequest proto.InternalMessageInfo
+
+// Represents the public keys held by the signer
+type ListPublicKeysResponse struct {
+	PublicKeys           []*PublicKey `protobuf:"bytes,1,rep,name=public_keys,json=publicKeys,proto3" json:"public_keys,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *ListPublicKeysResponse) Reset()         { *m = ListPublicKeysResponse{} }
+func (m *ListPublicKeysResponse) String() string { return proto.CompactTextString(m) }
+func (*ListPublicKeysResponse) ProtoMessage()    {}
+func (*ListPublicKeysResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_remotesigner_6d4ed685c5f1f4be, []int{4}
+}
+func (m *ListPublicKeysResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListPublicKeysResponse.Unmarshal(m, b)
+}
+func (m *ListPublicKeysResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListPublicKeysResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListPublicKeysResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListPublicKeysResponse.Merge(dst, src)
+}
+func (m *ListPublicKeysResponse) XXX_Size() int {
+	return xxx_messageInfo_ListPublicKeysResponse.Size(m)
+}
+func (m *ListPublicKeysResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListPublicKeysResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListPublicKeysResponse proto.InternalMessageInfo
+
+func (m *ListPublicKeysResponse) GetPublicKeys() []*PublicKey {
+	if m != nil {
+		return m.PublicKeys
+	}
+	return nil
+}
+
+// Represents a request to sign data
+type SignDataRequest struct {
+	// identifier of the key to sign with
+	KeyId string `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	// data to sign. This is the digest produced by hash_algorithm, or the
+	// message itself for ED25519 keys.
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	// hash algorithm used to produce the data. Unspecified for ED25519 keys.
+	HashAlgorithm HashAlgorithm `protobuf:"varint,3,opt,name=hash_algorithm,json=hashAlgorithm,proto3,enum=spire.api.remotesigner.HashAlgorithm" json:"hash_algorithm,omitempty"`
+	// if true, RSA keys sign using RSASSA-PSS instead of PKCS #1 v1.5
+	Pss bool `protobuf:"varint,4,opt,name=pss,proto3" json:"pss,omitempty"`
+	// PSS salt length (0 means as large as possible, -1 means equal to the
+	// hash length)
+	PssSaltLength        int32    `protobuf:"varint,5,opt,name=pss_salt_length,json=pssSaltLength,proto3" json:"pss_salt_length,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SignDataRequest) Reset()         { *m = SignDataRequest{} }
+func (m *SignDataRequest) String() string { return proto.CompactTextString(m) }
+func (*SignDataRequest) ProtoMessage()    {}
+func (*SignDataRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_remotesigner_6d4ed685c5f1f4be, []int{5}
+}
+func (m *SignDataRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignDataRequest.Unmarshal(m, b)
+}
+func (m *SignDataRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignDataRequest.Marshal(b, m, deterministic)
+}
+func (dst *SignDataRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignDataRequest.Merge(dst, src)
+}
+func (m *SignDataRequest) XXX_Size() int {
+	return xxx_messageInfo_SignDataRequest.Size(m)
+}
+func (m *SignDataRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignDataRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignDataRequest proto.InternalMessageInfo
+
+func (m *SignDataRequest) GetKeyId() string {
+	if m != nil {
+		return m.KeyId
+	}
+	return ""
+}
+
+func (m *SignDataRequest) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *SignDataRequest) GetHashAlgorithm() HashAlgorithm {
+	if m != nil {
+		return m.HashAlgorithm
+	}
+	return HashAlgorithm_UNSPECIFIED_HASH_ALGORITHM
+}
+
+func (m *SignDataRequest) GetPss() bool {
+	if m != nil {
+		return m.Pss
+	}
+	return false
+}
+
+func (m *SignDataRequest) GetPssSaltLength() int32 {
+	if m != nil {
+		return m.PssSaltLength
+	}
+	return 0
+}
+
+// Represents the signature
+type SignDataResponse struct {
+	// ASN.1 DER encoded signature for EC keys; raw signature otherwise
+	Signature            []byte   `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SignDataResponse) Reset()         { *m = SignDataResponse{} }
+func (m *SignDataResponse) String() string { return proto.CompactTextString(m) }
+func (*SignDataResponse) ProtoMessage()    {}
+func (*SignDataResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_remotesigner_6d4ed685c5f1f4be, []int{6}
+}
+func (m *SignDataResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignDataResponse.Unmarshal(m, b)
+}
+func (m *SignDataResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignDataResponse.Marshal(b, m, deterministic)
+}
+func (dst *SignDataResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignDataResponse.Merge(dst, src)
+}
+func (m *SignDataResponse) XXX_Size() int {
+	return xxx_messageInfo_SignDataResponse.Size(m)
+}
+func (m *SignDataResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignDataResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignDataResponse proto.InternalMessageInfo
+
+func (m *SignDataResponse) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*PublicKey)(nil), "spire.api.remotesigner.PublicKey")
+	proto.RegisterType((*GenerateKeyRequest)(nil), "spire.api.remotesigner.GenerateKeyRequest")
+	proto.RegisterType((*GenerateKeyResponse)(nil), "spire.api.remotesigner.GenerateKeyResponse")
+	proto.RegisterType((*ListPublicKeysRequest)(nil), "spire.api.remotesigner.ListPublicKeysRequest")
+	proto.RegisterType((*ListPublicKeysResponse)(nil), "spire.api.remotesigner.ListPublicKeysResponse")
+	proto.RegisterType((*SignDataRequest)(nil), "spire.api.remotesigner.SignDataRequest")
+	proto.RegisterType((*SignDataResponse)(nil), "spire.api.remotesigner.SignDataResponse")
+	proto.RegisterEnum("spire.api.remotesigner.KeyType", KeyType_name, KeyType_value)
+	proto.RegisterEnum("spire.api.remotesigner.HashAlgorithm", HashAlgorithm_name, HashAlgorithm_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// RemoteSignerClient is the client API for RemoteSigner service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type RemoteSignerClient interface {
+	// Generates a new key, replacing any existing key with the same id
+	GenerateKey(ctx context.Context, in *GenerateKeyRequest, opts ...grpc.CallOption) (*GenerateKeyResponse, error)
+	// Lists the public keys held by the signer
+	ListPublicKeys(ctx context.Context, in *ListPublicKeysRequest, opts ...grpc.CallOption) (*ListPublicKeysResponse, error)
+	// Signs data with a private key
+	SignData(ctx context.Context, in *SignDataRequest, opts ...grpc.CallOption) (*SignDataResponse, error)
+}
+
+type remoteSignerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewRemoteSignerClient(cc *grpc.ClientConn) RemoteSignerClient {
+	return &remoteSignerClient{cc}
+}
+
+func (c *remoteSignerClient) GenerateKey(ctx context.Context, in *GenerateKeyRequest, opts ...grpc.CallOption) (*GenerateKeyResponse, error) {
+	out := new(GenerateKeyResponse)
+	err := c.cc.Invoke(ctx, "/spire.api.remotesigner.RemoteSigner/GenerateKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteSignerClient) ListPublicKeys(ctx context.Context, in *ListPublicKeysRequest, opts ...grpc.CallOption) (*ListPublicKeysResponse, error) {
+	out := new(ListPublicKeysResponse)
+	err := c.cc.Invoke(ctx, "/spire.api.remotesigner.RemoteSigner/ListPublicKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteSignerClient) SignData(ctx context.Context, in *SignDataRequest, opts ...grpc.CallOption) (*SignDataResponse, error) {
+	out := new(SignDataResponse)
+	err := c.cc.Invoke(ctx, "/spire.api.remotesigner.RemoteSigner/SignData", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RemoteSignerServer is the server API for RemoteSigner service.
+type RemoteSignerServer interface {
+	// Generates a new key, replacing any existing key with the same id
+	GenerateKey(context.Context, *GenerateKeyRequest) (*GenerateKeyResponse, error)
+	// Lists the public keys held by the signer
+	ListPublicKeys(context.Context, *ListPublicKeysRequest) (*ListPublicKeysResponse, error)
+	// Signs data with a private key
+	SignData(context.Context, *SignDataRequest) (*SignDataResponse, error)
+}
+
+func RegisterRemoteSignerServer(s *grpc.Server, srv RemoteSignerServer) {
+	s.RegisterService(&_RemoteSigner_serviceDesc, srv)
+}
+
+func _RemoteSigner_GenerateKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteSignerServer).GenerateKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.api.remotesigner.RemoteSigner/GenerateKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteSignerServer).GenerateKey(ctx, req.(*GenerateKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RemoteSigner_ListPublicKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPublicKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteSignerServer).ListPublicKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.api.remotesigner.RemoteSigner/ListPublicKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteSignerServer).ListPublicKeys(ctx, req.(*ListPublicKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RemoteSigner_SignData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteSignerServer).SignData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.api.remotesigner.RemoteSigner/SignData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteSignerServer).SignData(ctx, req.(*SignDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _RemoteSigner_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "spire.api.remotesigner.RemoteSigner",
+	HandlerType: (*RemoteSignerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GenerateKey",
+			Handler:    _RemoteSigner_GenerateKey_Handler,
+		},
+		{
+			MethodName: "ListPublicKeys",
+			Handler:    _RemoteSigner_ListPublicKeys_Handler,
+		},
+		{
+			MethodName: "SignData",
+			Handler:    _RemoteSigner_SignData_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "remotesigner.proto",
+}
+
+func init() { proto.RegisterFile("remotesigner.proto", fileDescriptor_remotesigner_6d4ed685c5f1f4be) }
+
+var fileDescriptor_remotesigner_6d4ed685c5f1f4be = []byte{
+	// 626 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xff, 0x6f, 0xd2, 0x40,
+	0x14, 0x5f, 0xf9, 0xce, 0x03, 0xba, 0xcb, 0xe9, 0x66, 0x83, 0x46, 0xb1, 0x89, 0x4a, 0x66, 0x24,
+	0xac, 0xa3, 0x64, 0xf3, 0x27, 0xd9, 0x86, 0x83, 0x80, 0x4a, 0xda, 0x19, 0x33, 0xa3, 0x69, 0x3a,
+	0x39, 0xa1, 0x81, 0xc1, 0xd9, 0xbb, 0x25, 0xf6, 0x2f, 0xf3, 0x1f, 0x30, 0xfe, 0x5d, 0xe6, 0x8e,
+	0x16, 0xba, 0x39, 0xb2, 0xf9, 0xdb, 0xfb, 0xbc, 0x7b, 0x9f, 0xf7, 0xb9, 0xfb, 0xbc, 0xbe, 0x02,
+	0xf6, 0xc9, 0xc5, 0x9c, 0x13, 0xe6, 0x8d, 0x66, 0xc4, 0xaf, 0x51, 0x7f, 0xce, 0xe7, 0x78, 0x9b,
+	0x51, 0xcf, 0x27, 0x35, 0x97, 0x7a, 0xb5, 0xf8, 0xa9, 0x7e, 0x01, 0xf9, 0xc1, 0xe5, 0xf9, 0xd4,
+	0xfb, 0xd6, 0x23, 0x01, 0x56, 0x21, 0xe1, 0x0d, 0x35, 0xa5, 0xa2, 0x54, 0xf3, 0x56, 0xc2, 0x1b,
+	0xe2, 0x3d, 0x48, 0xf1, 0x80, 0x12, 0x2d, 0x51, 0x51, 0xaa, 0xaa, 0xf1, 0xa4, 0x76, 0x73, 0x8f,
+	0x5a, 0x8f, 0x04, 0xa7, 0x01, 0x25, 0x96, 0x2c, 0xc6, 0x0f, 0x21, 0x4f, 0x27, 0xde, 0x4f, 0x67,
+	0xe8, 0x72, 0x57, 0x4b, 0x56, 0x94, 0x6a, 0xd1, 0xca, 0x89, 0xc4, 0xb1, 0xcb, 0x5d, 0x7d, 0x04,
+	0xf8, 0x84, 0xcc, 0x88, 0xef, 0x72, 0xd2, 0x23, 0x81, 0x45, 0x7e, 0x5c, 0x12, 0xc6, 0xf1, 0x16,
+	0x64, 0x26, 0x24, 0x70, 0x96, 0xda, 0xe9, 0x09, 0x09, 0xba, 0x43, 0xfc, 0x1a, 0x72, 0x22, 0xfd,
+	0x3f, 0x57, 0xc8, 0x4e, 0x16, 0x81, 0xfe, 0x09, 0xee, 0x5d, 0x11, 0x62, 0x74, 0x3e, 0x63, 0x04,
+	0xbf, 0x01, 0xa0, 0xf2, 0xb9, 0xce, 0x84, 0x04, 0x52, 0xad, 0x60, 0x3c, 0x5d, 0xd7, 0x74, 0x69,
+	0x8c, 0x95, 0xa7, 0x51, 0xa8, 0x3f, 0x80, 0xad, 0xbe, 0xc7, 0xf8, 0xf2, 0x8c, 0x85, 0x8f, 0xd0,
+	0xbf, 0xc0, 0xf6, 0xf5, 0x83, 0x50, 0xf4, 0x10, 0x0a, 0x2b, 0x51, 0xa6, 0x29, 0x95, 0xe4, 0xdd,
+	0x54, 0x61, 0xa9, 0xca, 0xf4, 0xdf, 0x0a, 0x6c, 0xda, 0xde, 0x68, 0x26, 0x5c, 0xbc, 0xc5, 0x36,
+	0x0c, 0x29, 0xe9, 0x7d, 0x42, 0x7a, 0x2f, 0x63, 0xdc, 0x07, 0x75, 0xec, 0xb2, 0xb1, 0xe3, 0x4e,
+	0x47, 0x73, 0xdf, 0xe3, 0xe3, 0x0b, 0x39, 0x19, 0xd5, 0x78, 0xb6, 0xee, 0x16, 0x1d, 0x97, 0x8d,
+	0x5b, 0x51, 0xb1, 0x55, 0x1a, 0xc7, 0x21, 0x46, 0x90, 0xa4, 0x8c, 0x69, 0xa9, 0x8a, 0x52, 0xcd,
+	0x59, 0x22, 0xc4, 0xcf, 0x61, 0x93, 0x32, 0xe6, 0x30, 0x77, 0xca, 0x9d, 0x29, 0x99, 0x8d, 0xf8,
+	0x58, 0x4b, 0x57, 0x94, 0x6a, 0xda, 0x2a, 0x51, 0xc6, 0x6c, 0x77, 0xca, 0xfb, 0x32, 0xa9, 0xd7,
+	0x01, 0xad, 0x5e, 0x11, 0xda, 0xf3, 0x08, 0xf2, 0x42, 0xd4, 0xe5, 0x97, 0x3e, 0x91, 0x2f, 0x29,
+	0x5a, 0xab, 0xc4, 0x0e, 0x87, 0x6c, 0x38, 0x5c, 0xac, 0xc1, 0xfd, 0x8f, 0xef, 0xed, 0x41, 0xfb,
+	0xa8, 0xfb, 0xb6, 0xdb, 0x3e, 0x76, 0x7a, 0xed, 0x33, 0xe7, 0xf4, 0x6c, 0xd0, 0x46, 0x1b, 0xb8,
+	0x00, 0xd9, 0xf6, 0x91, 0x33, 0x30, 0xcc, 0x26, 0x52, 0x22, 0xb0, 0xb7, 0xdf, 0x40, 0x09, 0x5c,
+	0x84, 0x9c, 0x65, 0xb7, 0x9c, 0xdd, 0xba, 0xd1, 0x40, 0xc9, 0x08, 0x19, 0xf5, 0xc6, 0x3e, 0x4a,
+	0x45, 0xa8, 0x51, 0x3f, 0x68, 0xa2, 0xb4, 0xa4, 0x1d, 0x1b, 0xa6, 0xb9, 0x7b, 0x80, 0x32, 0x3b,
+	0xbf, 0x14, 0x28, 0x5d, 0xb1, 0x00, 0x3f, 0x86, 0x72, 0x5c, 0xbc, 0xd3, 0xb2, 0x3b, 0x4e, 0xab,
+	0x7f, 0xf2, 0xc1, 0xea, 0x9e, 0x76, 0xde, 0xa1, 0x0d, 0x0c, 0x90, 0xb1, 0x3b, 0x2d, 0xc3, 0x68,
+	0xa0, 0x54, 0x14, 0x9b, 0xa2, 0xed, 0x22, 0x16, 0x97, 0xc9, 0x84, 0xb1, 0xb9, 0x6b, 0xa0, 0xac,
+	0x10, 0x17, 0x79, 0x47, 0x30, 0x60, 0x85, 0xcc, 0x26, 0x2a, 0x2c, 0x91, 0x60, 0x15, 0x97, 0x48,
+	0xf0, 0x4a, 0x58, 0x05, 0x58, 0xf4, 0x90, 0x4c, 0x35, 0x8e, 0xcd, 0x26, 0xda, 0x34, 0xfe, 0x24,
+	0xa0, 0x68, 0xc9, 0x49, 0xda, 0x72, 0x92, 0xf8, 0x3b, 0x14, 0x62, 0x9b, 0x80, 0x77, 0xd6, 0x4d,
+	0xfc, 0xdf, 0xbd, 0x2c, 0xbf, 0xbc, 0x53, 0x6d, 0x38, 0xc6, 0x39, 0xa8, 0x57, 0xbf, 0x7f, 0xfc,
+	0x6a, 0x1d, 0xfd, 0xc6, 0x05, 0x2a, 0xd7, 0xee, 0x5a, 0x1e, 0x0a, 0x7e, 0x85, 0x5c, 0xf4, 0x2d,
+	0xe1, 0x17, 0xeb, 0xb8, 0xd7, 0x76, 0xa6, 0x5c, 0xbd, 0xbd, 0x70, 0xd1, 0xfe, 0x50, 0xfd, 0x5c,
+	0x8c, 0x17, 0x9c, 0x67, 0xe4, 0x8f, 0x74, 0xef, 0xef, 0x00, 0x9d, 0xa8, 0xea, 0x99, 0x5e, 0x05,
+	0x00, 0x00,
+}
@@ -29,7 +29,7 @@ func (m *Empty) Reset()         { *m = Empty{} }
 func (m *Empty) String() string { return proto.CompactTextString(m) }
 func (*Empty) ProtoMessage()    {}
 func (*Empty) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_cc2c2acbe08ae165, []int{0}
+	return fileDescriptor_common_d62b9f59401ae247, []int{0}
 }
 func (m *Empty) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Empty.Unmarshal(m, b)
@@ -64,7 +64,7 @@ func (m *AttestationData) Reset()         { *m = AttestationData{} }
 func (m *AttestationData) String() string { return proto.CompactTextString(m) }
 func (*AttestationData) ProtoMessage()    {}
 func (*AttestationData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_cc2c2acbe08ae165, []int{1}
+	return fileDescriptor_common_d62b9f59401ae247, []int{1}
 }
 func (m *AttestationData) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_AttestationData.Unmarshal(m, b)
@@ -115,7 +115,7 @@ func (m *Selector) Reset()         { *m = Selector{} }
 func (m *Selector) String() string { return proto.CompactTextString(m) }
 func (*Selector) ProtoMessage()    {}
 func (*Selector) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_cc2c2acbe08ae165, []int{2}
+	return fileDescriptor_common_d62b9f59401ae247, []int{2}
 }
 func (m *Selector) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Selector.Unmarshal(m, b)
@@ -162,7 +162,7 @@ func (m *Selectors) Reset()         { *m = Selectors{} }
 func (m *Selectors) String() string { return proto.CompactTextString(m) }
 func (*Selectors) ProtoMessage()    {}
 func (*Selectors) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_cc2c2acbe08ae165, []int{3}
+	return fileDescriptor_common_d62b9f59401ae247, []int{3}
 }
 func (m *Selectors) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Selectors.Unmarshal(m, b)
@@ -208,7 +208,7 @@ func (m *AttestedNode) Reset()         { *m = AttestedNode{} }
 func (m *AttestedNode) String() string { return proto.CompactTextString(m) }
 func (*AttestedNode) ProtoMessage()    {}
 func (*AttestedNode) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_cc2c2acbe08ae165, []int{4}
+	return fileDescriptor_common_d62b9f59401ae247, []int{4}
 }
 func (m *AttestedNode) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_AttestedNode.Unmarshal(m, b)
@@ -279,7 +279,10 @@ type RegistrationEntry struct {
 	// example.
 	Admin bool `protobuf:"varint,7,opt,name=admin,proto3" json:"admin,omitempty"`
 	// * To enable signing CA CSR in upstream spire server
-	Downstream           bool     `protobuf:"varint,8,opt,name=downstream,proto3" json:"downstream,omitempty"`
+	Downstream bool `protobuf:"varint,8,opt,name=downstream,proto3" json:"downstream,omitempty"`
+	// * Expiration of the entry, in seconds since the Unix epoch. Expired
+	// entries are pruned by the server. Zero means the entry never expires.
+	EntryExpiry          int64    `protobuf:"varint,9,opt,name=entry_expiry,json=entryExpiry,proto3" json:"entry_expiry,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -289,7 +292,7 @@ func (m *RegistrationEntry) Reset()         { *m = RegistrationEntry{} }
 func (m *RegistrationEntry) String() string { return proto.CompactTextString(m) }
 func (*RegistrationEntry) ProtoMessage()    {}
 func (*RegistrationEntry) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_cc2c2acbe08ae165, []int{5}
+	return fileDescriptor_common_d62b9f59401ae247, []int{5}
 }
 func (m *RegistrationEntry) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RegistrationEntry.Unmarshal(m, b)
@@ -365,6 +368,13 @@ func (m *RegistrationEntry) GetDownstream() bool {
 	return false
 }
 
+func (m *RegistrationEntry) GetEntryExpiry() int64 {
+	if m != nil {
+		return m.EntryExpiry
+	}
+	return 0
+}
+
 // * A list of registration entries.
 type RegistrationEntries struct {
 	// * A list of RegistrationEntry.
@@ -378,7 +388,7 @@ func (m *RegistrationEntries) Reset()         { *m = RegistrationEntries{} }
 func (m *RegistrationEntries) String() string { return proto.CompactTextString(m) }
 func (*RegistrationEntries) ProtoMessage()    {}
 func (*RegistrationEntries) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_cc2c2acbe08ae165, []int{6}
+	return fileDescriptor_common_d62b9f59401ae247, []int{6}
 }
 func (m *RegistrationEntries) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RegistrationEntries.Unmarshal(m, b)
@@ -417,7 +427,7 @@ func (m *Certificate) Reset()         { *m = Certificate{} }
 func (m *Certificate) String() string { return proto.CompactTextString(m) }
 func (*Certificate) ProtoMessage()    {}
 func (*Certificate) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_cc2c2acbe08ae165, []int{7}
+	return fileDescriptor_common_d62b9f59401ae247, []int{7}
 }
 func (m *Certificate) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Certificate.Unmarshal(m, b)
@@ -461,7 +471,7 @@ func (m *PublicKey) Reset()         { *m = PublicKey{} }
 func (m *PublicKey) String() string { return proto.CompactTextString(m) }
 func (*PublicKey) ProtoMessage()    {}
 func (*PublicKey) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_cc2c2acbe08ae165, []int{8}
+	return fileDescriptor_common_d62b9f59401ae247, []int{8}
 }
 func (m *PublicKey) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PublicKey.Unmarshal(m, b)
@@ -518,7 +528,7 @@ func (m *Bundle) Reset()         { *m = Bundle{} }
 func (m *Bundle) String() string { return proto.CompactTextString(m) }
 func (*Bundle) ProtoMessage()    {}
 func (*Bundle) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_cc2c2acbe08ae165, []int{9}
+	return fileDescriptor_common_d62b9f59401ae247, []int{9}
 }
 func (m *Bundle) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Bundle.Unmarshal(m, b)
@@ -572,45 +582,46 @@ func init() {
 	proto.RegisterType((*Bundle)(nil), "spire.common.Bundle")
 }
 
-func init() { proto.RegisterFile("common.proto", fileDescriptor_common_cc2c2acbe08ae165) }
+func init() { proto.RegisterFile("common.proto", fileDescriptor_common_d62b9f59401ae247) }
 
-var fileDescriptor_common_cc2c2acbe08ae165 = []byte{
-	// 591 bytes of a gzipped FileDescriptorProto
+var fileDescriptor_common_d62b9f59401ae247 = []byte{
+	// 605 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x54, 0x4f, 0x6f, 0xd3, 0x4e,
-	0x10, 0x95, 0xeb, 0x26, 0xb1, 0xa7, 0xe9, 0x9f, 0xdf, 0xf6, 0x07, 0xb8, 0x42, 0x40, 0x64, 0x01,
-	0x8a, 0x10, 0x8a, 0x50, 0xe9, 0xa5, 0x07, 0x0e, 0xfd, 0x77, 0x88, 0x2a, 0x55, 0x95, 0x8b, 0x84,
-	0xe0, 0x62, 0x6d, 0xb2, 0x93, 0x76, 0xdb, 0x78, 0xd7, 0xda, 0x9d, 0x10, 0xfc, 0x21, 0xf8, 0x20,
-	0x9c, 0xf9, 0x82, 0x68, 0xd7, 0xa4, 0xa9, 0x03, 0x12, 0xb7, 0xdd, 0xb7, 0x6f, 0xc6, 0xef, 0xbd,
-	0x19, 0x19, 0xba, 0x63, 0x5d, 0x14, 0x5a, 0x0d, 0x4a, 0xa3, 0x49, 0xb3, 0xae, 0x2d, 0xa5, 0xc1,
-	0x41, 0x8d, 0xa5, 0x1d, 0x68, 0x9d, 0x15, 0x25, 0x55, 0xe9, 0x21, 0x6c, 0x1f, 0x11, 0xa1, 0x25,
-	0x4e, 0x52, 0xab, 0x53, 0x4e, 0x9c, 0x31, 0x58, 0xa7, 0xaa, 0xc4, 0x24, 0xe8, 0x05, 0xfd, 0x38,
-	0xf3, 0x67, 0x87, 0x09, 0x4e, 0x3c, 0x59, 0xeb, 0x05, 0xfd, 0x6e, 0xe6, 0xcf, 0xe9, 0x01, 0x44,
-	0x57, 0x38, 0xc5, 0x31, 0x69, 0xf3, 0xd7, 0x9a, 0xff, 0xa1, 0xf5, 0x95, 0x4f, 0x67, 0xe8, 0x8b,
-	0xe2, 0xac, 0xbe, 0xa4, 0x1f, 0x20, 0x5e, 0x54, 0x59, 0xf6, 0x0e, 0x3a, 0xa8, 0xc8, 0x48, 0xb4,
-	0x49, 0xd0, 0x0b, 0xfb, 0x1b, 0xfb, 0x8f, 0x07, 0x0f, 0x65, 0x0e, 0x16, 0xcc, 0x6c, 0x41, 0x4b,
-	0x7f, 0x06, 0xd0, 0xad, 0x05, 0xa3, 0xb8, 0xd0, 0x02, 0xd9, 0x53, 0x88, 0x6d, 0x29, 0x27, 0x13,
-	0xcc, 0xa5, 0xf8, 0xfd, 0xf9, 0xa8, 0x06, 0x86, 0x82, 0xed, 0xc3, 0x23, 0xbe, 0x74, 0x97, 0x3b,
-	0xd9, 0xb9, 0xd7, 0x59, 0x4b, 0xda, 0xe5, 0x4d, 0xeb, 0x1f, 0x9d, 0xec, 0xb7, 0xc0, 0xc6, 0x68,
-	0x28, 0xb7, 0x68, 0x24, 0x9f, 0xe6, 0x6a, 0x56, 0x8c, 0xd0, 0x24, 0xa1, 0x2f, 0xd8, 0x71, 0x2f,
-	0x57, 0xfe, 0xe1, 0xc2, 0xe3, 0xec, 0x25, 0x6c, 0x79, 0xb6, 0xd2, 0x94, 0xf3, 0x09, 0xa1, 0x49,
-	0xd6, 0x7b, 0x41, 0x3f, 0xcc, 0xba, 0x0e, 0xbd, 0xd0, 0x74, 0xe4, 0xb0, 0xf4, 0xfb, 0x1a, 0xfc,
-	0x97, 0xe1, 0xb5, 0xb4, 0x64, 0xfc, 0xc7, 0xce, 0x14, 0x99, 0x8a, 0x1d, 0x40, 0x6c, 0x17, 0x51,
-	0xfc, 0xc3, 0xff, 0x92, 0xe8, 0x0c, 0x97, 0xdc, 0xa0, 0x22, 0x67, 0xb8, 0xf6, 0x11, 0xd5, 0xc0,
-	0x50, 0x34, 0xd3, 0x08, 0x57, 0xd2, 0xd8, 0x81, 0x90, 0x68, 0xea, 0x05, 0xb6, 0x32, 0x77, 0x64,
-	0xaf, 0x60, 0x6b, 0x82, 0x02, 0x0d, 0x27, 0xb4, 0xf9, 0x5c, 0xd2, 0x4d, 0xd2, 0xea, 0x85, 0xfd,
-	0x38, 0xdb, 0xbc, 0x47, 0x3f, 0x49, 0xba, 0x61, 0x7b, 0x10, 0xb9, 0xfc, 0x2b, 0xd7, 0xb4, 0xed,
-	0x9b, 0xfa, 0x79, 0x54, 0x43, 0xe1, 0x86, 0xcc, 0x45, 0x21, 0x55, 0xd2, 0xe9, 0x05, 0xfd, 0x28,
-	0xab, 0x2f, 0xec, 0x39, 0x80, 0xd0, 0x73, 0x65, 0xc9, 0x20, 0x2f, 0x92, 0xc8, 0x3f, 0x3d, 0x40,
-	0xd2, 0x4b, 0xd8, 0x5d, 0x8d, 0x43, 0xa2, 0x65, 0x87, 0xab, 0xeb, 0xf0, 0xa2, 0x19, 0xc7, 0x1f,
-	0x11, 0x2e, 0xf7, 0xe2, 0x0d, 0x6c, 0x9c, 0xa0, 0x21, 0x39, 0x91, 0x63, 0x4e, 0x7e, 0x2b, 0x04,
-	0x9a, 0x7c, 0x54, 0x91, 0xef, 0xe5, 0x96, 0x36, 0x12, 0x68, 0x8e, 0xdd, 0x3d, 0xfd, 0x0c, 0xf1,
-	0xe5, 0x6c, 0x34, 0x95, 0xe3, 0x73, 0xac, 0xd8, 0x33, 0x80, 0xf2, 0x4e, 0x7e, 0x6b, 0x50, 0x63,
-	0x87, 0x78, 0xae, 0xcb, 0xec, 0xee, 0x3e, 0x67, 0x77, 0x74, 0xad, 0x97, 0xc3, 0x0e, 0xfd, 0xb0,
-	0x23, 0xb5, 0x18, 0xf4, 0x8f, 0x00, 0xda, 0xc7, 0x33, 0x25, 0xa6, 0xc8, 0x5e, 0xc3, 0x36, 0x99,
-	0x99, 0xa5, 0x5c, 0xe8, 0x82, 0x4b, 0xb5, 0x5c, 0xcf, 0x4d, 0x0f, 0x9f, 0x7a, 0x74, 0x28, 0xd8,
-	0x01, 0x44, 0x46, 0x6b, 0xca, 0xc7, 0xdc, 0x26, 0x6b, 0xde, 0xf5, 0x5e, 0xd3, 0xf5, 0x03, 0x5f,
-	0x59, 0xc7, 0x51, 0x4f, 0xb8, 0x65, 0x47, 0xb0, 0x73, 0x3b, 0xa7, 0xdc, 0xca, 0x6b, 0x25, 0xd5,
-	0x75, 0x7e, 0x87, 0x95, 0x4d, 0x42, 0x5f, 0xfd, 0xa4, 0x59, 0x7d, 0xef, 0x34, 0xdb, 0xba, 0x9d,
-	0xd3, 0x55, 0xcd, 0x3f, 0xc7, 0xca, 0x1e, 0x47, 0x5f, 0xda, 0x35, 0x67, 0xd4, 0xf6, 0xbf, 0x88,
-	0xf7, 0xbf, 0x02, 0x00, 0x00, 0xff, 0xff, 0x56, 0x58, 0xbb, 0x7c, 0x32, 0x04, 0x00, 0x00,
+	0x10, 0x95, 0xeb, 0x26, 0xb1, 0xa7, 0xe9, 0x9f, 0xdf, 0xf6, 0x07, 0xb8, 0x42, 0x40, 0xb0, 0x00,
+	0x45, 0x08, 0x45, 0xa8, 0xf4, 0xd2, 0x03, 0x87, 0xfe, 0x3b, 0x44, 0x95, 0xaa, 0xca, 0x45, 0x42,
+	0x70, 0xb1, 0x36, 0xd9, 0x49, 0xbb, 0x6d, 0xbc, 0x6b, 0xed, 0x4e, 0x48, 0xfd, 0x91, 0xb8, 0x21,
+	0xf1, 0x05, 0xd1, 0xae, 0x49, 0x53, 0x17, 0x24, 0x6e, 0xb3, 0x6f, 0xdf, 0x8c, 0xe7, 0xbd, 0x7d,
+	0x32, 0x74, 0xc7, 0xba, 0x28, 0xb4, 0x1a, 0x94, 0x46, 0x93, 0x66, 0x5d, 0x5b, 0x4a, 0x83, 0x83,
+	0x1a, 0x4b, 0x3b, 0xd0, 0x3a, 0x29, 0x4a, 0xaa, 0xd2, 0x7d, 0xd8, 0x3c, 0x20, 0x42, 0x4b, 0x9c,
+	0xa4, 0x56, 0xc7, 0x9c, 0x38, 0x63, 0xb0, 0x4a, 0x55, 0x89, 0x49, 0xd0, 0x0b, 0xfa, 0x71, 0xe6,
+	0x6b, 0x87, 0x09, 0x4e, 0x3c, 0x59, 0xe9, 0x05, 0xfd, 0x6e, 0xe6, 0xeb, 0x74, 0x0f, 0xa2, 0x0b,
+	0x9c, 0xe2, 0x98, 0xb4, 0xf9, 0x6b, 0xcf, 0xff, 0xd0, 0xfa, 0xc6, 0xa7, 0x33, 0xf4, 0x4d, 0x71,
+	0x56, 0x1f, 0xd2, 0x8f, 0x10, 0x2f, 0xba, 0x2c, 0x7b, 0x0f, 0x1d, 0x54, 0x64, 0x24, 0xda, 0x24,
+	0xe8, 0x85, 0xfd, 0xb5, 0xdd, 0xc7, 0x83, 0xfb, 0x6b, 0x0e, 0x16, 0xcc, 0x6c, 0x41, 0x4b, 0x7f,
+	0x06, 0xd0, 0xad, 0x17, 0x46, 0x71, 0xa6, 0x05, 0xb2, 0xa7, 0x10, 0xdb, 0x52, 0x4e, 0x26, 0x98,
+	0x4b, 0xf1, 0xfb, 0xf3, 0x51, 0x0d, 0x0c, 0x05, 0xdb, 0x85, 0x47, 0x7c, 0xa9, 0x2e, 0x77, 0x6b,
+	0xe7, 0x7e, 0xcf, 0x7a, 0xa5, 0x6d, 0xde, 0x94, 0xfe, 0xc9, 0xad, 0xfd, 0x0e, 0xd8, 0x18, 0x0d,
+	0xe5, 0x16, 0x8d, 0xe4, 0xd3, 0x5c, 0xcd, 0x8a, 0x11, 0x9a, 0x24, 0xf4, 0x0d, 0x5b, 0xee, 0xe6,
+	0xc2, 0x5f, 0x9c, 0x79, 0x9c, 0xbd, 0x82, 0x0d, 0xcf, 0x56, 0x9a, 0x72, 0x3e, 0x21, 0x34, 0xc9,
+	0x6a, 0x2f, 0xe8, 0x87, 0x59, 0xd7, 0xa1, 0x67, 0x9a, 0x0e, 0x1c, 0x96, 0xfe, 0x58, 0x81, 0xff,
+	0x32, 0xbc, 0x94, 0x96, 0x8c, 0xff, 0xd8, 0x89, 0x22, 0x53, 0xb1, 0x3d, 0x88, 0xed, 0xc2, 0x8a,
+	0x7f, 0xe8, 0x5f, 0x12, 0x9d, 0xe0, 0x92, 0x1b, 0x54, 0xe4, 0x04, 0xd7, 0x3a, 0xa2, 0x1a, 0x18,
+	0x8a, 0xa6, 0x1b, 0xe1, 0x03, 0x37, 0xb6, 0x20, 0x24, 0x9a, 0xfa, 0x05, 0x5b, 0x99, 0x2b, 0xd9,
+	0x6b, 0xd8, 0x98, 0xa0, 0x40, 0xc3, 0x09, 0x6d, 0x3e, 0x97, 0x74, 0x95, 0xb4, 0x7a, 0x61, 0x3f,
+	0xce, 0xd6, 0xef, 0xd0, 0xcf, 0x92, 0xae, 0xd8, 0x0e, 0x44, 0xce, 0xff, 0xca, 0x0d, 0x6d, 0xfb,
+	0xa1, 0xfe, 0x3d, 0xaa, 0xa1, 0x70, 0x8f, 0xcc, 0x45, 0x21, 0x55, 0xd2, 0xe9, 0x05, 0xfd, 0x28,
+	0xab, 0x0f, 0xec, 0x39, 0x80, 0xd0, 0x73, 0x65, 0xc9, 0x20, 0x2f, 0x92, 0xc8, 0x5f, 0xdd, 0x43,
+	0xd8, 0x4b, 0xe8, 0xd6, 0x03, 0xf1, 0xb6, 0x94, 0xa6, 0x4a, 0x62, 0xef, 0xd9, 0x9a, 0xc7, 0x4e,
+	0x3c, 0x94, 0x9e, 0xc3, 0xf6, 0x43, 0xc7, 0x24, 0x5a, 0xb6, 0xff, 0x30, 0x31, 0x2f, 0x9a, 0x8e,
+	0xfd, 0xe1, 0xf2, 0x32, 0x3a, 0x6f, 0x61, 0xed, 0x08, 0x0d, 0xc9, 0x89, 0x1c, 0x73, 0xf2, 0xc1,
+	0x11, 0x68, 0xf2, 0x51, 0x45, 0x7e, 0x96, 0xcb, 0x75, 0x24, 0xd0, 0x1c, 0xba, 0x73, 0xfa, 0x05,
+	0xe2, 0xf3, 0xd9, 0x68, 0x2a, 0xc7, 0xa7, 0x58, 0xb1, 0x67, 0x00, 0xe5, 0x8d, 0xbc, 0x6d, 0x50,
+	0x63, 0x87, 0x78, 0xae, 0xb3, 0xf5, 0xe6, 0xee, 0x29, 0x5c, 0xe9, 0x46, 0x2f, 0xf3, 0x10, 0x7a,
+	0x6d, 0x91, 0x5a, 0x64, 0xe1, 0x7b, 0x00, 0xed, 0xc3, 0x99, 0x12, 0x53, 0x64, 0x6f, 0x60, 0x93,
+	0xcc, 0xcc, 0x52, 0x2e, 0x74, 0xc1, 0xa5, 0x5a, 0x26, 0x78, 0xdd, 0xc3, 0xc7, 0x1e, 0x1d, 0x0a,
+	0xb6, 0x07, 0x91, 0xd1, 0x9a, 0xf2, 0x31, 0xb7, 0xc9, 0x8a, 0x57, 0xbd, 0xd3, 0x54, 0x7d, 0x4f,
+	0x57, 0xd6, 0x71, 0xd4, 0x23, 0x6e, 0xd9, 0x01, 0x6c, 0x5d, 0xcf, 0x29, 0xb7, 0xf2, 0x52, 0x49,
+	0x75, 0x99, 0xdf, 0x60, 0x65, 0x93, 0xd0, 0x77, 0x3f, 0x69, 0x76, 0xdf, 0x29, 0xcd, 0x36, 0xae,
+	0xe7, 0x74, 0x51, 0xf3, 0x4f, 0xb1, 0xb2, 0x87, 0xd1, 0xd7, 0x76, 0xcd, 0x19, 0xb5, 0xfd, 0x5f,
+	0xe4, 0xc3, 0xaf, 0x01, 0x00, 0x4f, 0xf4, 0x01, 0x2d, 0x55, 0x04, 0x00, 0x00,
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/golang/protobuf/ptypes/empty"
 	go_plugin "github.com/hashicorp/go-plugin"
+	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/proto/common/plugin"
 	"google.golang.org/grpc"
 )
@@ -30,10 +31,17 @@ type DataStore interface {
 	ListRegistrationEntries(context.Context, *ListRegistrationEntriesRequest) (*ListRegistrationEntriesResponse, error)
 	UpdateRegistrationEntry(context.Context, *UpdateRegistrationEntryRequest) (*UpdateRegistrationEntryResponse, error)
 	DeleteRegistrationEntry(context.Context, *DeleteRegistrationEntryRequest) (*DeleteRegistrationEntryResponse, error)
+	BatchCreateRegistrationEntries(context.Context, *BatchCreateRegistrationEntriesRequest) (*BatchCreateRegistrationEntriesResponse, error)
+	BatchUpdateRegistrationEntries(context.Context, *BatchUpdateRegistrationEntriesRequest) (*BatchUpdateRegistrationEntriesResponse, error)
+	BatchDeleteRegistrationEntries(context.Context, *BatchDeleteRegistrationEntriesRequest) (*BatchDeleteRegistrationEntriesResponse, error)
 	CreateJoinToken(context.Context, *CreateJoinTokenRequest) (*CreateJoinTokenResponse, error)
 	FetchJoinToken(context.Context, *FetchJoinTokenRequest) (*FetchJoinTokenResponse, error)
+	ListJoinTokens(context.Context, *ListJoinTokensRequest) (*ListJoinTokensResponse, error)
+	UseJoinToken(context.Context, *UseJoinTokenRequest) (*UseJoinTokenResponse, error)
 	DeleteJoinToken(context.Context, *DeleteJoinTokenRequest) (*DeleteJoinTokenResponse, error)
 	PruneJoinTokens(context.Context, *PruneJoinTokensRequest) (*PruneJoinTokensResponse, error)
+	ListChangeEvents(context.Context, *ListChangeEventsRequest) (*ListChangeEventsResponse, error)
+	PruneChangeEvents(context.Context, *PruneChangeEventsRequest) (*PruneChangeEventsResponse, error)
 }
 
 // Plugin is the interface implemented by plugin implementations
@@ -56,10 +64,17 @@ type Plugin interface {
 	ListRegistrationEntries(context.Context, *ListRegistrationEntriesRequest) (*ListRegistrationEntriesResponse, error)
 	UpdateRegistrationEntry(context.Context, *UpdateRegistrationEntryRequest) (*UpdateRegistrationEntryResponse, error)
 	DeleteRegistrationEntry(context.Context, *DeleteRegistrationEntryRequest) (*DeleteRegistrationEntryResponse, error)
+	BatchCreateRegistrationEntries(context.Context, *BatchCreateRegistrationEntriesRequest) (*BatchCreateRegistrationEntriesResponse, error)
+	BatchUpdateRegistrationEntries(context.Context, *BatchUpdateRegistrationEntriesRequest) (*BatchUpdateRegistrationEntriesResponse, error)
+	BatchDeleteRegistrationEntries(context.Context, *BatchDeleteRegistrationEntriesRequest) (*BatchDeleteRegistrationEntriesResponse, error)
 	CreateJoinToken(context.Context, *CreateJoinTokenRequest) (*CreateJoinTokenResponse, error)
 	FetchJoinToken(context.Context, *FetchJoinTokenRequest) (*FetchJoinTokenResponse, error)
+	ListJoinTokens(context.Context, *ListJoinTokensRequest) (*ListJoinTokensResponse, error)
+	UseJoinToken(context.Context, *UseJoinTokenRequest) (*UseJoinTokenResponse, error)
 	DeleteJoinToken(context.Context, *DeleteJoinTokenRequest) (*DeleteJoinTokenResponse, error)
 	PruneJoinTokens(context.Context, *PruneJoinTokensRequest) (*PruneJoinTokensResponse, error)
+	ListChangeEvents(context.Context, *ListChangeEventsRequest) (*ListChangeEventsResponse, error)
+	PruneChangeEvents(context.Context, *PruneChangeEventsRequest) (*PruneChangeEventsResponse, error)
 	Configure(context.Context, *plugin.ConfigureRequest) (*plugin.ConfigureResponse, error)
 	GetPluginInfo(context.Context, *plugin.GetPluginInfoRequest) (*plugin.GetPluginInfoResponse, error)
 }
@@ -76,6 +91,13 @@ func NewBuiltIn(plugin Plugin) *BuiltIn {
 	}
 }
 
+// SetLogger hands the logger to the plugin, if the plugin logs
+func (b BuiltIn) SetLogger(log logrus.FieldLogger) {
+	if p, ok := b.plugin.(interface{ SetLogger(logrus.FieldLogger) }); ok {
+		p.SetLogger(log)
+	}
+}
+
 func (b BuiltIn) CreateBundle(ctx context.Context, req *CreateBundleRequest) (*CreateBundleResponse, error) {
 	resp, err := b.plugin.CreateBundle(ctx, req)
 	if err != nil {
@@ -220,6 +242,30 @@ func (b BuiltIn) DeleteRegistrationEntry(ctx context.Context, req *DeleteRegistr
 	return resp, nil
 }
 
+func (b BuiltIn) BatchCreateRegistrationEntries(ctx context.Context, req *BatchCreateRegistrationEntriesRequest) (*BatchCreateRegistrationEntriesResponse, error) {
+	resp, err := b.plugin.BatchCreateRegistrationEntries(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (b BuiltIn) BatchUpdateRegistrationEntries(ctx context.Context, req *BatchUpdateRegistrationEntriesRequest) (*BatchUpdateRegistrationEntriesResponse, error) {
+	resp, err := b.plugin.BatchUpdateRegistrationEntries(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (b BuiltIn) BatchDeleteRegistrationEntries(ctx context.Context, req *BatchDeleteRegistrationEntriesRequest) (*BatchDeleteRegistrationEntriesResponse, error) {
+	resp, err := b.plugin.BatchDeleteRegistrationEntries(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (b BuiltIn) CreateJoinToken(ctx context.Context, req *CreateJoinTokenRequest) (*CreateJoinTokenResponse, error) {
 	resp, err := b.plugin.CreateJoinToken(ctx, req)
 	if err != nil {
@@ -236,6 +282,22 @@ func (b BuiltIn) FetchJoinToken(ctx context.Context, req *FetchJoinTokenRequest)
 	return resp, nil
 }
 
+func (b BuiltIn) ListJoinTokens(ctx context.Context, req *ListJoinTokensRequest) (*ListJoinTokensResponse, error) {
+	resp, err := b.plugin.ListJoinTokens(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (b BuiltIn) UseJoinToken(ctx context.Context, req *UseJoinTokenRequest) (*UseJoinTokenResponse, error) {
+	resp, err := b.plugin.UseJoinToken(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (b BuiltIn) DeleteJoinToken(ctx context.Context, req *DeleteJoinTokenRequest) (*DeleteJoinTokenResponse, error) {
 	resp, err := b.plugin.DeleteJoinToken(ctx, req)
 	if err != nil {
@@ -252,6 +314,22 @@ func (b BuiltIn) PruneJoinTokens(ctx context.Context, req *PruneJoinTokensReques
 	return resp, nil
 }
 
+func (b BuiltIn) ListChangeEvents(ctx context.Context, req *ListChangeEventsRequest) (*ListChangeEventsResponse, error) {
+	resp, err := b.plugin.ListChangeEvents(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (b BuiltIn) PruneChangeEvents(ctx context.Context, req *PruneChangeEventsRequest) (*PruneChangeEventsResponse, error) {
+	resp, err := b.plugin.PruneChangeEvents(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (b BuiltIn) Configure(ctx context.Context, req *plugin.ConfigureRequest) (*plugin.ConfigureResponse, error) {
 	resp, err := b.plugin.Configure(ctx, req)
 	if err != nil {
@@ -353,18 +431,39 @@ func (s *GRPCServer) UpdateRegistrationEntry(ctx context.Context, req *UpdateReg
 func (s *GRPCServer) DeleteRegistrationEntry(ctx context.Context, req *DeleteRegistrationEntryRequest) (*DeleteRegistrationEntryResponse, error) {
 	return s.Plugin.DeleteRegistrationEntry(ctx, req)
 }
+func (s *GRPCServer) BatchCreateRegistrationEntries(ctx context.Context, req *BatchCreateRegistrationEntriesRequest) (*BatchCreateRegistrationEntriesResponse, error) {
+	return s.Plugin.BatchCreateRegistrationEntries(ctx, req)
+}
+func (s *GRPCServer) BatchUpdateRegistrationEntries(ctx context.Context, req *BatchUpdateRegistrationEntriesRequest) (*BatchUpdateRegistrationEntriesResponse, error) {
+	return s.Plugin.BatchUpdateRegistrationEntries(ctx, req)
+}
+func (s *GRPCServer) BatchDeleteRegistrationEntries(ctx context.Context, req *BatchDeleteRegistrationEntriesRequest) (*BatchDeleteRegistrationEntriesResponse, error) {
+	return s.Plugin.BatchDeleteRegistrationEntries(ctx, req)
+}
 func (s *GRPCServer) CreateJoinToken(ctx context.Context, req *CreateJoinTokenRequest) (*CreateJoinTokenResponse, error) {
 	return s.Plugin.CreateJoinToken(ctx, req)
 }
 func (s *GRPCServer) FetchJoinToken(ctx context.Context, req *FetchJoinTokenRequest) (*FetchJoinTokenResponse, error) {
 	return s.Plugin.FetchJoinToken(ctx, req)
 }
+func (s *GRPCServer) ListJoinTokens(ctx context.Context, req *ListJoinTokensRequest) (*ListJoinTokensResponse, error) {
+	return s.Plugin.ListJoinTokens(ctx, req)
+}
+func (s *GRPCServer) UseJoinToken(ctx context.Context, req *UseJoinTokenRequest) (*UseJoinTokenResponse, error) {
+	return s.Plugin.UseJoinToken(ctx, req)
+}
 func (s *GRPCServer) DeleteJoinToken(ctx context.Context, req *DeleteJoinTokenRequest) (*DeleteJoinTokenResponse, error) {
 	return s.Plugin.DeleteJoinToken(ctx, req)
 }
 func (s *GRPCServer) PruneJoinTokens(ctx context.Context, req *PruneJoinTokensRequest) (*PruneJoinTokensResponse, error) {
 	return s.Plugin.PruneJoinTokens(ctx, req)
 }
+func (s *GRPCServer) ListChangeEvents(ctx context.Context, req *ListChangeEventsRequest) (*ListChangeEventsResponse, error) {
+	return s.Plugin.ListChangeEvents(ctx, req)
+}
+func (s *GRPCServer) PruneChangeEvents(ctx context.Context, req *PruneChangeEventsRequest) (*PruneChangeEventsResponse, error) {
+	return s.Plugin.PruneChangeEvents(ctx, req)
+}
 func (s *GRPCServer) Configure(ctx context.Context, req *plugin.ConfigureRequest) (*plugin.ConfigureResponse, error) {
 	return s.Plugin.Configure(ctx, req)
 }
@@ -430,18 +529,39 @@ func (c *GRPCClient) UpdateRegistrationEntry(ctx context.Context, req *UpdateReg
 func (c *GRPCClient) DeleteRegistrationEntry(ctx context.Context, req *DeleteRegistrationEntryRequest) (*DeleteRegistrationEntryResponse, error) {
 	return c.client.DeleteRegistrationEntry(ctx, req)
 }
+func (c *GRPCClient) BatchCreateRegistrationEntries(ctx context.Context, req *BatchCreateRegistrationEntriesRequest) (*BatchCreateRegistrationEntriesResponse, error) {
+	return c.client.BatchCreateRegistrationEntries(ctx, req)
+}
+func (c *GRPCClient) BatchUpdateRegistrationEntries(ctx context.Context, req *BatchUpdateRegistrationEntriesRequest) (*BatchUpdateRegistrationEntriesResponse, error) {
+	return c.client.BatchUpdateRegistrationEntries(ctx, req)
+}
+func (c *GRPCClient) BatchDeleteRegistrationEntries(ctx context.Context, req *BatchDeleteRegistrationEntriesRequest) (*BatchDeleteRegistrationEntriesResponse, error) {
+	return c.client.BatchDeleteRegistrationEntries(ctx, req)
+}
 func (c *GRPCClient) CreateJoinToken(ctx context.Context, req *CreateJoinTokenRequest) (*CreateJoinTokenResponse, error) {
 	return c.client.CreateJoinToken(ctx, req)
 }
 func (c *GRPCClient) FetchJoinToken(ctx context.Context, req *FetchJoinTokenRequest) (*FetchJoinTokenResponse, error) {
 	return c.client.FetchJoinToken(ctx, req)
 }
+func (c *GRPCClient) ListJoinTokens(ctx context.Context, req *ListJoinTokensRequest) (*ListJoinTokensResponse, error) {
+	return c.client.ListJoinTokens(ctx, req)
+}
+func (c *GRPCClient) UseJoinToken(ctx context.Context, req *UseJoinTokenRequest) (*UseJoinTokenResponse, error) {
+	return c.client.UseJoinToken(ctx, req)
+}
 func (c *GRPCClient) DeleteJoinToken(ctx context.Context, req *DeleteJoinTokenRequest) (*DeleteJoinTokenResponse, error) {
 	return c.client.DeleteJoinToken(ctx, req)
 }
 func (c *GRPCClient) PruneJoinTokens(ctx context.Context, req *PruneJoinTokensRequest) (*PruneJoinTokensResponse, error) {
 	return c.client.PruneJoinTokens(ctx, req)
 }
+func (c *GRPCClient) ListChangeEvents(ctx context.Context, req *ListChangeEventsRequest) (*ListChangeEventsResponse, error) {
+	return c.client.ListChangeEvents(ctx, req)
+}
+func (c *GRPCClient) PruneChangeEvents(ctx context.Context, req *PruneChangeEventsRequest) (*PruneChangeEventsResponse, error) {
+	return c.client.PruneChangeEvents(ctx, req)
+}
 func (c *GRPCClient) Configure(ctx context.Context, req *plugin.ConfigureRequest) (*plugin.ConfigureResponse, error) {
 	return c.client.Configure(ctx, req)
 }
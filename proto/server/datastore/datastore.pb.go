@@ -126,7 +126,7 @@ func (x DeleteBundleRequest_Mode) String() string {
 	return proto.EnumName(DeleteBundleRequest_Mode_name, int32(x))
 }
 func (DeleteBundleRequest_Mode) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{10, 0}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{10, 0}
 }
 
 type BySelectors_MatchBehavior int32
@@ -149,7 +149,56 @@ func (x BySelectors_MatchBehavior) String() string {
 	return proto.EnumName(BySelectors_MatchBehavior_name, int32(x))
 }
 func (BySelectors_MatchBehavior) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{31, 0}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{31, 0}
+}
+
+type ChangeEvent_Kind int32
+
+const (
+	ChangeEvent_REGISTRATION_ENTRY ChangeEvent_Kind = 0
+	ChangeEvent_BUNDLE             ChangeEvent_Kind = 1
+)
+
+var ChangeEvent_Kind_name = map[int32]string{
+	0: "REGISTRATION_ENTRY",
+	1: "BUNDLE",
+}
+var ChangeEvent_Kind_value = map[string]int32{
+	"REGISTRATION_ENTRY": 0,
+	"BUNDLE":             1,
+}
+
+func (x ChangeEvent_Kind) String() string {
+	return proto.EnumName(ChangeEvent_Kind_name, int32(x))
+}
+func (ChangeEvent_Kind) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{58, 0}
+}
+
+type ChangeEvent_Op int32
+
+const (
+	ChangeEvent_CREATED ChangeEvent_Op = 0
+	ChangeEvent_UPDATED ChangeEvent_Op = 1
+	ChangeEvent_DELETED ChangeEvent_Op = 2
+)
+
+var ChangeEvent_Op_name = map[int32]string{
+	0: "CREATED",
+	1: "UPDATED",
+	2: "DELETED",
+}
+var ChangeEvent_Op_value = map[string]int32{
+	"CREATED": 0,
+	"UPDATED": 1,
+	"DELETED": 2,
+}
+
+func (x ChangeEvent_Op) String() string {
+	return proto.EnumName(ChangeEvent_Op_name, int32(x))
+}
+func (ChangeEvent_Op) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{58, 1}
 }
 
 type CreateBundleRequest struct {
@@ -163,7 +212,7 @@ func (m *CreateBundleRequest) Reset()         { *m = CreateBundleRequest{} }
 func (m *CreateBundleRequest) String() string { return proto.CompactTextString(m) }
 func (*CreateBundleRequest) ProtoMessage()    {}
 func (*CreateBundleRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{0}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{0}
 }
 func (m *CreateBundleRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateBundleRequest.Unmarshal(m, b)
@@ -201,7 +250,7 @@ func (m *CreateBundleResponse) Reset()         { *m = CreateBundleResponse{} }
 func (m *CreateBundleResponse) String() string { return proto.CompactTextString(m) }
 func (*CreateBundleResponse) ProtoMessage()    {}
 func (*CreateBundleResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{1}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{1}
 }
 func (m *CreateBundleResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateBundleResponse.Unmarshal(m, b)
@@ -229,7 +278,11 @@ func (m *CreateBundleResponse) GetBundle() *common.Bundle {
 }
 
 type FetchBundleRequest struct {
-	TrustDomainId        string   `protobuf:"bytes,1,opt,name=trust_domain_id,json=trustDomainId,proto3" json:"trust_domain_id,omitempty"`
+	TrustDomainId string `protobuf:"bytes,1,opt,name=trust_domain_id,json=trustDomainId,proto3" json:"trust_domain_id,omitempty"`
+	// If true, the datastore may answer from data that is slightly out of
+	// date, e.g. from a read-only replica. Must not be set when the answer
+	// is used to authorize a request or follows a write that it must see.
+	TolerateStale        bool     `protobuf:"varint,2,opt,name=tolerate_stale,json=tolerateStale,proto3" json:"tolerate_stale,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -239,7 +292,7 @@ func (m *FetchBundleRequest) Reset()         { *m = FetchBundleRequest{} }
 func (m *FetchBundleRequest) String() string { return proto.CompactTextString(m) }
 func (*FetchBundleRequest) ProtoMessage()    {}
 func (*FetchBundleRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{2}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{2}
 }
 func (m *FetchBundleRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FetchBundleRequest.Unmarshal(m, b)
@@ -266,6 +319,13 @@ func (m *FetchBundleRequest) GetTrustDomainId() string {
 	return ""
 }
 
+func (m *FetchBundleRequest) GetTolerateStale() bool {
+	if m != nil {
+		return m.TolerateStale
+	}
+	return false
+}
+
 type FetchBundleResponse struct {
 	Bundle               *common.Bundle `protobuf:"bytes,1,opt,name=bundle,proto3" json:"bundle,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
@@ -277,7 +337,7 @@ func (m *FetchBundleResponse) Reset()         { *m = FetchBundleResponse{} }
 func (m *FetchBundleResponse) String() string { return proto.CompactTextString(m) }
 func (*FetchBundleResponse) ProtoMessage()    {}
 func (*FetchBundleResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{3}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{3}
 }
 func (m *FetchBundleResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FetchBundleResponse.Unmarshal(m, b)
@@ -314,7 +374,7 @@ func (m *ListBundlesRequest) Reset()         { *m = ListBundlesRequest{} }
 func (m *ListBundlesRequest) String() string { return proto.CompactTextString(m) }
 func (*ListBundlesRequest) ProtoMessage()    {}
 func (*ListBundlesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{4}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{4}
 }
 func (m *ListBundlesRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListBundlesRequest.Unmarshal(m, b)
@@ -345,7 +405,7 @@ func (m *ListBundlesResponse) Reset()         { *m = ListBundlesResponse{} }
 func (m *ListBundlesResponse) String() string { return proto.CompactTextString(m) }
 func (*ListBundlesResponse) ProtoMessage()    {}
 func (*ListBundlesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{5}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{5}
 }
 func (m *ListBundlesResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListBundlesResponse.Unmarshal(m, b)
@@ -383,7 +443,7 @@ func (m *UpdateBundleRequest) Reset()         { *m = UpdateBundleRequest{} }
 func (m *UpdateBundleRequest) String() string { return proto.CompactTextString(m) }
 func (*UpdateBundleRequest) ProtoMessage()    {}
 func (*UpdateBundleRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{6}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{6}
 }
 func (m *UpdateBundleRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateBundleRequest.Unmarshal(m, b)
@@ -421,7 +481,7 @@ func (m *UpdateBundleResponse) Reset()         { *m = UpdateBundleResponse{} }
 func (m *UpdateBundleResponse) String() string { return proto.CompactTextString(m) }
 func (*UpdateBundleResponse) ProtoMessage()    {}
 func (*UpdateBundleResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{7}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{7}
 }
 func (m *UpdateBundleResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateBundleResponse.Unmarshal(m, b)
@@ -459,7 +519,7 @@ func (m *AppendBundleRequest) Reset()         { *m = AppendBundleRequest{} }
 func (m *AppendBundleRequest) String() string { return proto.CompactTextString(m) }
 func (*AppendBundleRequest) ProtoMessage()    {}
 func (*AppendBundleRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{8}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{8}
 }
 func (m *AppendBundleRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_AppendBundleRequest.Unmarshal(m, b)
@@ -497,7 +557,7 @@ func (m *AppendBundleResponse) Reset()         { *m = AppendBundleResponse{} }
 func (m *AppendBundleResponse) String() string { return proto.CompactTextString(m) }
 func (*AppendBundleResponse) ProtoMessage()    {}
 func (*AppendBundleResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{9}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{9}
 }
 func (m *AppendBundleResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_AppendBundleResponse.Unmarshal(m, b)
@@ -536,7 +596,7 @@ func (m *DeleteBundleRequest) Reset()         { *m = DeleteBundleRequest{} }
 func (m *DeleteBundleRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteBundleRequest) ProtoMessage()    {}
 func (*DeleteBundleRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{10}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{10}
 }
 func (m *DeleteBundleRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DeleteBundleRequest.Unmarshal(m, b)
@@ -581,7 +641,7 @@ func (m *DeleteBundleResponse) Reset()         { *m = DeleteBundleResponse{} }
 func (m *DeleteBundleResponse) String() string { return proto.CompactTextString(m) }
 func (*DeleteBundleResponse) ProtoMessage()    {}
 func (*DeleteBundleResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{11}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{11}
 }
 func (m *DeleteBundleResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DeleteBundleResponse.Unmarshal(m, b)
@@ -622,7 +682,7 @@ func (m *NodeSelectors) Reset()         { *m = NodeSelectors{} }
 func (m *NodeSelectors) String() string { return proto.CompactTextString(m) }
 func (*NodeSelectors) ProtoMessage()    {}
 func (*NodeSelectors) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{12}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{12}
 }
 func (m *NodeSelectors) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_NodeSelectors.Unmarshal(m, b)
@@ -667,7 +727,7 @@ func (m *SetNodeSelectorsRequest) Reset()         { *m = SetNodeSelectorsRequest
 func (m *SetNodeSelectorsRequest) String() string { return proto.CompactTextString(m) }
 func (*SetNodeSelectorsRequest) ProtoMessage()    {}
 func (*SetNodeSelectorsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{13}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{13}
 }
 func (m *SetNodeSelectorsRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SetNodeSelectorsRequest.Unmarshal(m, b)
@@ -704,7 +764,7 @@ func (m *SetNodeSelectorsResponse) Reset()         { *m = SetNodeSelectorsRespon
 func (m *SetNodeSelectorsResponse) String() string { return proto.CompactTextString(m) }
 func (*SetNodeSelectorsResponse) ProtoMessage()    {}
 func (*SetNodeSelectorsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{14}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{14}
 }
 func (m *SetNodeSelectorsResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SetNodeSelectorsResponse.Unmarshal(m, b)
@@ -725,7 +785,9 @@ func (m *SetNodeSelectorsResponse) XXX_DiscardUnknown() {
 var xxx_messageInfo_SetNodeSelectorsResponse proto.InternalMessageInfo
 
 type GetNodeSelectorsRequest struct {
-	SpiffeId             string   `protobuf:"bytes,1,opt,name=spiffe_id,json=spiffeId,proto3" json:"spiffe_id,omitempty"`
+	SpiffeId string `protobuf:"bytes,1,opt,name=spiffe_id,json=spiffeId,proto3" json:"spiffe_id,omitempty"`
+	// See FetchBundleRequest.tolerate_stale
+	TolerateStale        bool     `protobuf:"varint,2,opt,name=tolerate_stale,json=tolerateStale,proto3" json:"tolerate_stale,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -735,7 +797,7 @@ func (m *GetNodeSelectorsRequest) Reset()         { *m = GetNodeSelectorsRequest
 func (m *GetNodeSelectorsRequest) String() string { return proto.CompactTextString(m) }
 func (*GetNodeSelectorsRequest) ProtoMessage()    {}
 func (*GetNodeSelectorsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{15}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{15}
 }
 func (m *GetNodeSelectorsRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetNodeSelectorsRequest.Unmarshal(m, b)
@@ -762,6 +824,13 @@ func (m *GetNodeSelectorsRequest) GetSpiffeId() string {
 	return ""
 }
 
+func (m *GetNodeSelectorsRequest) GetTolerateStale() bool {
+	if m != nil {
+		return m.TolerateStale
+	}
+	return false
+}
+
 type GetNodeSelectorsResponse struct {
 	Selectors            *NodeSelectors `protobuf:"bytes,1,opt,name=selectors,proto3" json:"selectors,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
@@ -773,7 +842,7 @@ func (m *GetNodeSelectorsResponse) Reset()         { *m = GetNodeSelectorsRespon
 func (m *GetNodeSelectorsResponse) String() string { return proto.CompactTextString(m) }
 func (*GetNodeSelectorsResponse) ProtoMessage()    {}
 func (*GetNodeSelectorsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{16}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{16}
 }
 func (m *GetNodeSelectorsResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetNodeSelectorsResponse.Unmarshal(m, b)
@@ -811,7 +880,7 @@ func (m *CreateAttestedNodeRequest) Reset()         { *m = CreateAttestedNodeReq
 func (m *CreateAttestedNodeRequest) String() string { return proto.CompactTextString(m) }
 func (*CreateAttestedNodeRequest) ProtoMessage()    {}
 func (*CreateAttestedNodeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{17}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{17}
 }
 func (m *CreateAttestedNodeRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateAttestedNodeRequest.Unmarshal(m, b)
@@ -849,7 +918,7 @@ func (m *CreateAttestedNodeResponse) Reset()         { *m = CreateAttestedNodeRe
 func (m *CreateAttestedNodeResponse) String() string { return proto.CompactTextString(m) }
 func (*CreateAttestedNodeResponse) ProtoMessage()    {}
 func (*CreateAttestedNodeResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{18}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{18}
 }
 func (m *CreateAttestedNodeResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateAttestedNodeResponse.Unmarshal(m, b)
@@ -887,7 +956,7 @@ func (m *FetchAttestedNodeRequest) Reset()         { *m = FetchAttestedNodeReque
 func (m *FetchAttestedNodeRequest) String() string { return proto.CompactTextString(m) }
 func (*FetchAttestedNodeRequest) ProtoMessage()    {}
 func (*FetchAttestedNodeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{19}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{19}
 }
 func (m *FetchAttestedNodeRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FetchAttestedNodeRequest.Unmarshal(m, b)
@@ -925,7 +994,7 @@ func (m *FetchAttestedNodeResponse) Reset()         { *m = FetchAttestedNodeResp
 func (m *FetchAttestedNodeResponse) String() string { return proto.CompactTextString(m) }
 func (*FetchAttestedNodeResponse) ProtoMessage()    {}
 func (*FetchAttestedNodeResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{20}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{20}
 }
 func (m *FetchAttestedNodeResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FetchAttestedNodeResponse.Unmarshal(m, b)
@@ -964,7 +1033,7 @@ func (m *ListAttestedNodesRequest) Reset()         { *m = ListAttestedNodesReque
 func (m *ListAttestedNodesRequest) String() string { return proto.CompactTextString(m) }
 func (*ListAttestedNodesRequest) ProtoMessage()    {}
 func (*ListAttestedNodesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{21}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{21}
 }
 func (m *ListAttestedNodesRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListAttestedNodesRequest.Unmarshal(m, b)
@@ -1010,7 +1079,7 @@ func (m *ListAttestedNodesResponse) Reset()         { *m = ListAttestedNodesResp
 func (m *ListAttestedNodesResponse) String() string { return proto.CompactTextString(m) }
 func (*ListAttestedNodesResponse) ProtoMessage()    {}
 func (*ListAttestedNodesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{22}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{22}
 }
 func (m *ListAttestedNodesResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListAttestedNodesResponse.Unmarshal(m, b)
@@ -1057,7 +1126,7 @@ func (m *UpdateAttestedNodeRequest) Reset()         { *m = UpdateAttestedNodeReq
 func (m *UpdateAttestedNodeRequest) String() string { return proto.CompactTextString(m) }
 func (*UpdateAttestedNodeRequest) ProtoMessage()    {}
 func (*UpdateAttestedNodeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{23}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{23}
 }
 func (m *UpdateAttestedNodeRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateAttestedNodeRequest.Unmarshal(m, b)
@@ -1109,7 +1178,7 @@ func (m *UpdateAttestedNodeResponse) Reset()         { *m = UpdateAttestedNodeRe
 func (m *UpdateAttestedNodeResponse) String() string { return proto.CompactTextString(m) }
 func (*UpdateAttestedNodeResponse) ProtoMessage()    {}
 func (*UpdateAttestedNodeResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{24}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{24}
 }
 func (m *UpdateAttestedNodeResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateAttestedNodeResponse.Unmarshal(m, b)
@@ -1137,17 +1206,21 @@ func (m *UpdateAttestedNodeResponse) GetNode() *common.AttestedNode {
 }
 
 type DeleteAttestedNodeRequest struct {
-	SpiffeId             string   `protobuf:"bytes,1,opt,name=spiffe_id,json=spiffeId,proto3" json:"spiffe_id,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	SpiffeId string `protobuf:"bytes,1,opt,name=spiffe_id,json=spiffeId,proto3" json:"spiffe_id,omitempty"`
+	// If set, the node is only deleted if its certificate serial number is
+	// still this one. Otherwise nothing is deleted and the response holds no
+	// node.
+	ByCertSerialNumber   *wrappers.StringValue `protobuf:"bytes,2,opt,name=by_cert_serial_number,json=byCertSerialNumber,proto3" json:"by_cert_serial_number,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
 }
 
 func (m *DeleteAttestedNodeRequest) Reset()         { *m = DeleteAttestedNodeRequest{} }
 func (m *DeleteAttestedNodeRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteAttestedNodeRequest) ProtoMessage()    {}
 func (*DeleteAttestedNodeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{25}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{25}
 }
 func (m *DeleteAttestedNodeRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DeleteAttestedNodeRequest.Unmarshal(m, b)
@@ -1174,6 +1247,13 @@ func (m *DeleteAttestedNodeRequest) GetSpiffeId() string {
 	return ""
 }
 
+func (m *DeleteAttestedNodeRequest) GetByCertSerialNumber() *wrappers.StringValue {
+	if m != nil {
+		return m.ByCertSerialNumber
+	}
+	return nil
+}
+
 type DeleteAttestedNodeResponse struct {
 	Node                 *common.AttestedNode `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
@@ -1185,7 +1265,7 @@ func (m *DeleteAttestedNodeResponse) Reset()         { *m = DeleteAttestedNodeRe
 func (m *DeleteAttestedNodeResponse) String() string { return proto.CompactTextString(m) }
 func (*DeleteAttestedNodeResponse) ProtoMessage()    {}
 func (*DeleteAttestedNodeResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{26}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{26}
 }
 func (m *DeleteAttestedNodeResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DeleteAttestedNodeResponse.Unmarshal(m, b)
@@ -1223,7 +1303,7 @@ func (m *CreateRegistrationEntryRequest) Reset()         { *m = CreateRegistrati
 func (m *CreateRegistrationEntryRequest) String() string { return proto.CompactTextString(m) }
 func (*CreateRegistrationEntryRequest) ProtoMessage()    {}
 func (*CreateRegistrationEntryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{27}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{27}
 }
 func (m *CreateRegistrationEntryRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateRegistrationEntryRequest.Unmarshal(m, b)
@@ -1261,7 +1341,7 @@ func (m *CreateRegistrationEntryResponse) Reset()         { *m = CreateRegistrat
 func (m *CreateRegistrationEntryResponse) String() string { return proto.CompactTextString(m) }
 func (*CreateRegistrationEntryResponse) ProtoMessage()    {}
 func (*CreateRegistrationEntryResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{28}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{28}
 }
 func (m *CreateRegistrationEntryResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateRegistrationEntryResponse.Unmarshal(m, b)
@@ -1299,7 +1379,7 @@ func (m *FetchRegistrationEntryRequest) Reset()         { *m = FetchRegistration
 func (m *FetchRegistrationEntryRequest) String() string { return proto.CompactTextString(m) }
 func (*FetchRegistrationEntryRequest) ProtoMessage()    {}
 func (*FetchRegistrationEntryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{29}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{29}
 }
 func (m *FetchRegistrationEntryRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FetchRegistrationEntryRequest.Unmarshal(m, b)
@@ -1337,7 +1417,7 @@ func (m *FetchRegistrationEntryResponse) Reset()         { *m = FetchRegistratio
 func (m *FetchRegistrationEntryResponse) String() string { return proto.CompactTextString(m) }
 func (*FetchRegistrationEntryResponse) ProtoMessage()    {}
 func (*FetchRegistrationEntryResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{30}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{30}
 }
 func (m *FetchRegistrationEntryResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FetchRegistrationEntryResponse.Unmarshal(m, b)
@@ -1376,7 +1456,7 @@ func (m *BySelectors) Reset()         { *m = BySelectors{} }
 func (m *BySelectors) String() string { return proto.CompactTextString(m) }
 func (*BySelectors) ProtoMessage()    {}
 func (*BySelectors) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{31}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{31}
 }
 func (m *BySelectors) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BySelectors.Unmarshal(m, b)
@@ -1422,7 +1502,7 @@ func (m *Pagination) Reset()         { *m = Pagination{} }
 func (m *Pagination) String() string { return proto.CompactTextString(m) }
 func (*Pagination) ProtoMessage()    {}
 func (*Pagination) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{32}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{32}
 }
 func (m *Pagination) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Pagination.Unmarshal(m, b)
@@ -1457,20 +1537,23 @@ func (m *Pagination) GetPageSize() int32 {
 }
 
 type ListRegistrationEntriesRequest struct {
-	ByParentId           *wrappers.StringValue `protobuf:"bytes,1,opt,name=by_parent_id,json=byParentId,proto3" json:"by_parent_id,omitempty"`
-	BySelectors          *BySelectors          `protobuf:"bytes,2,opt,name=by_selectors,json=bySelectors,proto3" json:"by_selectors,omitempty"`
-	BySpiffeId           *wrappers.StringValue `protobuf:"bytes,3,opt,name=by_spiffe_id,json=bySpiffeId,proto3" json:"by_spiffe_id,omitempty"`
-	Pagination           *Pagination           `protobuf:"bytes,4,opt,name=pagination,proto3" json:"pagination,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
-	XXX_unrecognized     []byte                `json:"-"`
-	XXX_sizecache        int32                 `json:"-"`
+	ByParentId      *wrappers.StringValue `protobuf:"bytes,1,opt,name=by_parent_id,json=byParentId,proto3" json:"by_parent_id,omitempty"`
+	BySelectors     *BySelectors          `protobuf:"bytes,2,opt,name=by_selectors,json=bySelectors,proto3" json:"by_selectors,omitempty"`
+	BySpiffeId      *wrappers.StringValue `protobuf:"bytes,3,opt,name=by_spiffe_id,json=bySpiffeId,proto3" json:"by_spiffe_id,omitempty"`
+	Pagination      *Pagination           `protobuf:"bytes,4,opt,name=pagination,proto3" json:"pagination,omitempty"`
+	ByExpiresBefore *wrappers.Int64Value  `protobuf:"bytes,5,opt,name=by_expires_before,json=byExpiresBefore,proto3" json:"by_expires_before,omitempty"`
+	// See FetchBundleRequest.tolerate_stale
+	TolerateStale        bool     `protobuf:"varint,6,opt,name=tolerate_stale,json=tolerateStale,proto3" json:"tolerate_stale,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *ListRegistrationEntriesRequest) Reset()         { *m = ListRegistrationEntriesRequest{} }
 func (m *ListRegistrationEntriesRequest) String() string { return proto.CompactTextString(m) }
 func (*ListRegistrationEntriesRequest) ProtoMessage()    {}
 func (*ListRegistrationEntriesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{33}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{33}
 }
 func (m *ListRegistrationEntriesRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListRegistrationEntriesRequest.Unmarshal(m, b)
@@ -1518,6 +1601,20 @@ func (m *ListRegistrationEntriesRequest) GetPagination() *Pagination {
 	return nil
 }
 
+func (m *ListRegistrationEntriesRequest) GetByExpiresBefore() *wrappers.Int64Value {
+	if m != nil {
+		return m.ByExpiresBefore
+	}
+	return nil
+}
+
+func (m *ListRegistrationEntriesRequest) GetTolerateStale() bool {
+	if m != nil {
+		return m.TolerateStale
+	}
+	return false
+}
+
 type ListRegistrationEntriesResponse struct {
 	Entries              []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
 	Pagination           *Pagination                 `protobuf:"bytes,2,opt,name=pagination,proto3" json:"pagination,omitempty"`
@@ -1530,7 +1627,7 @@ func (m *ListRegistrationEntriesResponse) Reset()         { *m = ListRegistratio
 func (m *ListRegistrationEntriesResponse) String() string { return proto.CompactTextString(m) }
 func (*ListRegistrationEntriesResponse) ProtoMessage()    {}
 func (*ListRegistrationEntriesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{34}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{34}
 }
 func (m *ListRegistrationEntriesResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListRegistrationEntriesResponse.Unmarshal(m, b)
@@ -1575,7 +1672,7 @@ func (m *UpdateRegistrationEntryRequest) Reset()         { *m = UpdateRegistrati
 func (m *UpdateRegistrationEntryRequest) String() string { return proto.CompactTextString(m) }
 func (*UpdateRegistrationEntryRequest) ProtoMessage()    {}
 func (*UpdateRegistrationEntryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{35}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{35}
 }
 func (m *UpdateRegistrationEntryRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateRegistrationEntryRequest.Unmarshal(m, b)
@@ -1613,7 +1710,7 @@ func (m *UpdateRegistrationEntryResponse) Reset()         { *m = UpdateRegistrat
 func (m *UpdateRegistrationEntryResponse) String() string { return proto.CompactTextString(m) }
 func (*UpdateRegistrationEntryResponse) ProtoMessage()    {}
 func (*UpdateRegistrationEntryResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{36}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{36}
 }
 func (m *UpdateRegistrationEntryResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateRegistrationEntryResponse.Unmarshal(m, b)
@@ -1651,7 +1748,7 @@ func (m *DeleteRegistrationEntryRequest) Reset()         { *m = DeleteRegistrati
 func (m *DeleteRegistrationEntryRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteRegistrationEntryRequest) ProtoMessage()    {}
 func (*DeleteRegistrationEntryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{37}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{37}
 }
 func (m *DeleteRegistrationEntryRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DeleteRegistrationEntryRequest.Unmarshal(m, b)
@@ -1689,7 +1786,7 @@ func (m *DeleteRegistrationEntryResponse) Reset()         { *m = DeleteRegistrat
 func (m *DeleteRegistrationEntryResponse) String() string { return proto.CompactTextString(m) }
 func (*DeleteRegistrationEntryResponse) ProtoMessage()    {}
 func (*DeleteRegistrationEntryResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{38}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{38}
 }
 func (m *DeleteRegistrationEntryResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DeleteRegistrationEntryResponse.Unmarshal(m, b)
@@ -1716,11 +1813,253 @@ func (m *DeleteRegistrationEntryResponse) GetEntry() *common.RegistrationEntry {
 	return nil
 }
 
+type BatchCreateRegistrationEntriesRequest struct {
+	Entries              []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *BatchCreateRegistrationEntriesRequest) Reset()         { *m = BatchCreateRegistrationEntriesRequest{} }
+func (m *BatchCreateRegistrationEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchCreateRegistrationEntriesRequest) ProtoMessage()    {}
+func (*BatchCreateRegistrationEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{39}
+}
+func (m *BatchCreateRegistrationEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchCreateRegistrationEntriesRequest.Unmarshal(m, b)
+}
+func (m *BatchCreateRegistrationEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchCreateRegistrationEntriesRequest.Marshal(b, m, deterministic)
+}
+func (dst *BatchCreateRegistrationEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchCreateRegistrationEntriesRequest.Merge(dst, src)
+}
+func (m *BatchCreateRegistrationEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchCreateRegistrationEntriesRequest.Size(m)
+}
+func (m *BatchCreateRegistrationEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchCreateRegistrationEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchCreateRegistrationEntriesRequest proto.InternalMessageInfo
+
+func (m *BatchCreateRegistrationEntriesRequest) GetEntries() []*common.RegistrationEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type BatchCreateRegistrationEntriesResponse struct {
+	// The created entries, in the same order as in the request
+	Entries              []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *BatchCreateRegistrationEntriesResponse) Reset() {
+	*m = BatchCreateRegistrationEntriesResponse{}
+}
+func (m *BatchCreateRegistrationEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchCreateRegistrationEntriesResponse) ProtoMessage()    {}
+func (*BatchCreateRegistrationEntriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{40}
+}
+func (m *BatchCreateRegistrationEntriesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchCreateRegistrationEntriesResponse.Unmarshal(m, b)
+}
+func (m *BatchCreateRegistrationEntriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchCreateRegistrationEntriesResponse.Marshal(b, m, deterministic)
+}
+func (dst *BatchCreateRegistrationEntriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchCreateRegistrationEntriesResponse.Merge(dst, src)
+}
+func (m *BatchCreateRegistrationEntriesResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchCreateRegistrationEntriesResponse.Size(m)
+}
+func (m *BatchCreateRegistrationEntriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchCreateRegistrationEntriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchCreateRegistrationEntriesResponse proto.InternalMessageInfo
+
+func (m *BatchCreateRegistrationEntriesResponse) GetEntries() []*common.RegistrationEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type BatchUpdateRegistrationEntriesRequest struct {
+	Entries              []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *BatchUpdateRegistrationEntriesRequest) Reset()         { *m = BatchUpdateRegistrationEntriesRequest{} }
+func (m *BatchUpdateRegistrationEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchUpdateRegistrationEntriesRequest) ProtoMessage()    {}
+func (*BatchUpdateRegistrationEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{41}
+}
+func (m *BatchUpdateRegistrationEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchUpdateRegistrationEntriesRequest.Unmarshal(m, b)
+}
+func (m *BatchUpdateRegistrationEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchUpdateRegistrationEntriesRequest.Marshal(b, m, deterministic)
+}
+func (dst *BatchUpdateRegistrationEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchUpdateRegistrationEntriesRequest.Merge(dst, src)
+}
+func (m *BatchUpdateRegistrationEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchUpdateRegistrationEntriesRequest.Size(m)
+}
+func (m *BatchUpdateRegistrationEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchUpdateRegistrationEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchUpdateRegistrationEntriesRequest proto.InternalMessageInfo
+
+func (m *BatchUpdateRegistrationEntriesRequest) GetEntries() []*common.RegistrationEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type BatchUpdateRegistrationEntriesResponse struct {
+	// The updated entries, in the same order as in the request
+	Entries              []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *BatchUpdateRegistrationEntriesResponse) Reset() {
+	*m = BatchUpdateRegistrationEntriesResponse{}
+}
+func (m *BatchUpdateRegistrationEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchUpdateRegistrationEntriesResponse) ProtoMessage()    {}
+func (*BatchUpdateRegistrationEntriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{42}
+}
+func (m *BatchUpdateRegistrationEntriesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchUpdateRegistrationEntriesResponse.Unmarshal(m, b)
+}
+func (m *BatchUpdateRegistrationEntriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchUpdateRegistrationEntriesResponse.Marshal(b, m, deterministic)
+}
+func (dst *BatchUpdateRegistrationEntriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchUpdateRegistrationEntriesResponse.Merge(dst, src)
+}
+func (m *BatchUpdateRegistrationEntriesResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchUpdateRegistrationEntriesResponse.Size(m)
+}
+func (m *BatchUpdateRegistrationEntriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchUpdateRegistrationEntriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchUpdateRegistrationEntriesResponse proto.InternalMessageInfo
+
+func (m *BatchUpdateRegistrationEntriesResponse) GetEntries() []*common.RegistrationEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type BatchDeleteRegistrationEntriesRequest struct {
+	EntryIds             []string `protobuf:"bytes,1,rep,name=entry_ids,json=entryIds,proto3" json:"entry_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchDeleteRegistrationEntriesRequest) Reset()         { *m = BatchDeleteRegistrationEntriesRequest{} }
+func (m *BatchDeleteRegistrationEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchDeleteRegistrationEntriesRequest) ProtoMessage()    {}
+func (*BatchDeleteRegistrationEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{43}
+}
+func (m *BatchDeleteRegistrationEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchDeleteRegistrationEntriesRequest.Unmarshal(m, b)
+}
+func (m *BatchDeleteRegistrationEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchDeleteRegistrationEntriesRequest.Marshal(b, m, deterministic)
+}
+func (dst *BatchDeleteRegistrationEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchDeleteRegistrationEntriesRequest.Merge(dst, src)
+}
+func (m *BatchDeleteRegistrationEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchDeleteRegistrationEntriesRequest.Size(m)
+}
+func (m *BatchDeleteRegistrationEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchDeleteRegistrationEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchDeleteRegistrationEntriesRequest proto.InternalMessageInfo
+
+func (m *BatchDeleteRegistrationEntriesRequest) GetEntryIds() []string {
+	if m != nil {
+		return m.EntryIds
+	}
+	return nil
+}
+
+type BatchDeleteRegistrationEntriesResponse struct {
+	// The deleted entries, in the same order as in the request
+	Entries              []*common.RegistrationEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *BatchDeleteRegistrationEntriesResponse) Reset() {
+	*m = BatchDeleteRegistrationEntriesResponse{}
+}
+func (m *BatchDeleteRegistrationEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchDeleteRegistrationEntriesResponse) ProtoMessage()    {}
+func (*BatchDeleteRegistrationEntriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{44}
+}
+func (m *BatchDeleteRegistrationEntriesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchDeleteRegistrationEntriesResponse.Unmarshal(m, b)
+}
+func (m *BatchDeleteRegistrationEntriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchDeleteRegistrationEntriesResponse.Marshal(b, m, deterministic)
+}
+func (dst *BatchDeleteRegistrationEntriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchDeleteRegistrationEntriesResponse.Merge(dst, src)
+}
+func (m *BatchDeleteRegistrationEntriesResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchDeleteRegistrationEntriesResponse.Size(m)
+}
+func (m *BatchDeleteRegistrationEntriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchDeleteRegistrationEntriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchDeleteRegistrationEntriesResponse proto.InternalMessageInfo
+
+func (m *BatchDeleteRegistrationEntriesResponse) GetEntries() []*common.RegistrationEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
 type JoinToken struct {
 	// Token value
 	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
 	// Expiration in seconds since unix epoch
-	Expiry               int64    `protobuf:"varint,2,opt,name=expiry,proto3" json:"expiry,omitempty"`
+	Expiry int64 `protobuf:"varint,2,opt,name=expiry,proto3" json:"expiry,omitempty"`
+	// Number of times the token can be used. Zero means the token can
+	// be used once.
+	MaxUses int32 `protobuf:"varint,3,opt,name=max_uses,json=maxUses,proto3" json:"max_uses,omitempty"`
+	// Number of times the token has been used
+	Uses                 int32    `protobuf:"varint,4,opt,name=uses,proto3" json:"uses,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1730,7 +2069,7 @@ func (m *JoinToken) Reset()         { *m = JoinToken{} }
 func (m *JoinToken) String() string { return proto.CompactTextString(m) }
 func (*JoinToken) ProtoMessage()    {}
 func (*JoinToken) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{39}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{45}
 }
 func (m *JoinToken) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_JoinToken.Unmarshal(m, b)
@@ -1764,6 +2103,20 @@ func (m *JoinToken) GetExpiry() int64 {
 	return 0
 }
 
+func (m *JoinToken) GetMaxUses() int32 {
+	if m != nil {
+		return m.MaxUses
+	}
+	return 0
+}
+
+func (m *JoinToken) GetUses() int32 {
+	if m != nil {
+		return m.Uses
+	}
+	return 0
+}
+
 type CreateJoinTokenRequest struct {
 	JoinToken            *JoinToken `protobuf:"bytes,1,opt,name=join_token,json=joinToken,proto3" json:"join_token,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
@@ -1775,7 +2128,7 @@ func (m *CreateJoinTokenRequest) Reset()         { *m = CreateJoinTokenRequest{}
 func (m *CreateJoinTokenRequest) String() string { return proto.CompactTextString(m) }
 func (*CreateJoinTokenRequest) ProtoMessage()    {}
 func (*CreateJoinTokenRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{40}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{46}
 }
 func (m *CreateJoinTokenRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateJoinTokenRequest.Unmarshal(m, b)
@@ -1813,7 +2166,7 @@ func (m *CreateJoinTokenResponse) Reset()         { *m = CreateJoinTokenResponse
 func (m *CreateJoinTokenResponse) String() string { return proto.CompactTextString(m) }
 func (*CreateJoinTokenResponse) ProtoMessage()    {}
 func (*CreateJoinTokenResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{41}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{47}
 }
 func (m *CreateJoinTokenResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateJoinTokenResponse.Unmarshal(m, b)
@@ -1851,7 +2204,7 @@ func (m *FetchJoinTokenRequest) Reset()         { *m = FetchJoinTokenRequest{} }
 func (m *FetchJoinTokenRequest) String() string { return proto.CompactTextString(m) }
 func (*FetchJoinTokenRequest) ProtoMessage()    {}
 func (*FetchJoinTokenRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{42}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{48}
 }
 func (m *FetchJoinTokenRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FetchJoinTokenRequest.Unmarshal(m, b)
@@ -1889,7 +2242,7 @@ func (m *FetchJoinTokenResponse) Reset()         { *m = FetchJoinTokenResponse{}
 func (m *FetchJoinTokenResponse) String() string { return proto.CompactTextString(m) }
 func (*FetchJoinTokenResponse) ProtoMessage()    {}
 func (*FetchJoinTokenResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{43}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{49}
 }
 func (m *FetchJoinTokenResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FetchJoinTokenResponse.Unmarshal(m, b)
@@ -1916,84 +2269,230 @@ func (m *FetchJoinTokenResponse) GetJoinToken() *JoinToken {
 	return nil
 }
 
-type DeleteJoinTokenRequest struct {
+type ListJoinTokensRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListJoinTokensRequest) Reset()         { *m = ListJoinTokensRequest{} }
+func (m *ListJoinTokensRequest) String() string { return proto.CompactTextString(m) }
+func (*ListJoinTokensRequest) ProtoMessage()    {}
+func (*ListJoinTokensRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{50}
+}
+func (m *ListJoinTokensRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListJoinTokensRequest.Unmarshal(m, b)
+}
+func (m *ListJoinTokensRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListJoinTokensRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListJoinTokensRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListJoinTokensRequest.Merge(dst, src)
+}
+func (m *ListJoinTokensRequest) XXX_Size() int {
+	return xxx_messageInfo_ListJoinTokensRequest.Size(m)
+}
+func (m *ListJoinTokensRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListJoinTokensRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListJoinTokensRequest proto.InternalMessageInfo
+
+type ListJoinTokensResponse struct {
+	JoinTokens           []*JoinToken `protobuf:"bytes,1,rep,name=join_tokens,json=joinTokens,proto3" json:"join_tokens,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *ListJoinTokensResponse) Reset()         { *m = ListJoinTokensResponse{} }
+func (m *ListJoinTokensResponse) String() string { return proto.CompactTextString(m) }
+func (*ListJoinTokensResponse) ProtoMessage()    {}
+func (*ListJoinTokensResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{51}
+}
+func (m *ListJoinTokensResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListJoinTokensResponse.Unmarshal(m, b)
+}
+func (m *ListJoinTokensResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListJoinTokensResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListJoinTokensResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListJoinTokensResponse.Merge(dst, src)
+}
+func (m *ListJoinTokensResponse) XXX_Size() int {
+	return xxx_messageInfo_ListJoinTokensResponse.Size(m)
+}
+func (m *ListJoinTokensResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListJoinTokensResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListJoinTokensResponse proto.InternalMessageInfo
+
+func (m *ListJoinTokensResponse) GetJoinTokens() []*JoinToken {
+	if m != nil {
+		return m.JoinTokens
+	}
+	return nil
+}
+
+type UseJoinTokenRequest struct {
 	Token                string   `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *DeleteJoinTokenRequest) Reset()         { *m = DeleteJoinTokenRequest{} }
-func (m *DeleteJoinTokenRequest) String() string { return proto.CompactTextString(m) }
-func (*DeleteJoinTokenRequest) ProtoMessage()    {}
-func (*DeleteJoinTokenRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{44}
+func (m *UseJoinTokenRequest) Reset()         { *m = UseJoinTokenRequest{} }
+func (m *UseJoinTokenRequest) String() string { return proto.CompactTextString(m) }
+func (*UseJoinTokenRequest) ProtoMessage()    {}
+func (*UseJoinTokenRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{52}
 }
-func (m *DeleteJoinTokenRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_DeleteJoinTokenRequest.Unmarshal(m, b)
+func (m *UseJoinTokenRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UseJoinTokenRequest.Unmarshal(m, b)
 }
-func (m *DeleteJoinTokenRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_DeleteJoinTokenRequest.Marshal(b, m, deterministic)
+func (m *UseJoinTokenRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UseJoinTokenRequest.Marshal(b, m, deterministic)
 }
-func (dst *DeleteJoinTokenRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_DeleteJoinTokenRequest.Merge(dst, src)
+func (dst *UseJoinTokenRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UseJoinTokenRequest.Merge(dst, src)
 }
-func (m *DeleteJoinTokenRequest) XXX_Size() int {
-	return xxx_messageInfo_DeleteJoinTokenRequest.Size(m)
+func (m *UseJoinTokenRequest) XXX_Size() int {
+	return xxx_messageInfo_UseJoinTokenRequest.Size(m)
 }
-func (m *DeleteJoinTokenRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_DeleteJoinTokenRequest.DiscardUnknown(m)
+func (m *UseJoinTokenRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UseJoinTokenRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_DeleteJoinTokenRequest proto.InternalMessageInfo
+var xxx_messageInfo_UseJoinTokenRequest proto.InternalMessageInfo
 
-func (m *DeleteJoinTokenRequest) GetToken() string {
+func (m *UseJoinTokenRequest) GetToken() string {
 	if m != nil {
 		return m.Token
 	}
 	return ""
 }
 
-type DeleteJoinTokenResponse struct {
+type UseJoinTokenResponse struct {
+	// The join token as it was before being used. Unset if the token
+	// does not exist.
 	JoinToken            *JoinToken `protobuf:"bytes,1,opt,name=join_token,json=joinToken,proto3" json:"join_token,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
 	XXX_unrecognized     []byte     `json:"-"`
 	XXX_sizecache        int32      `json:"-"`
 }
 
-func (m *DeleteJoinTokenResponse) Reset()         { *m = DeleteJoinTokenResponse{} }
-func (m *DeleteJoinTokenResponse) String() string { return proto.CompactTextString(m) }
-func (*DeleteJoinTokenResponse) ProtoMessage()    {}
-func (*DeleteJoinTokenResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{45}
+func (m *UseJoinTokenResponse) Reset()         { *m = UseJoinTokenResponse{} }
+func (m *UseJoinTokenResponse) String() string { return proto.CompactTextString(m) }
+func (*UseJoinTokenResponse) ProtoMessage()    {}
+func (*UseJoinTokenResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{53}
 }
-func (m *DeleteJoinTokenResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_DeleteJoinTokenResponse.Unmarshal(m, b)
+func (m *UseJoinTokenResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UseJoinTokenResponse.Unmarshal(m, b)
 }
-func (m *DeleteJoinTokenResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_DeleteJoinTokenResponse.Marshal(b, m, deterministic)
+func (m *UseJoinTokenResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UseJoinTokenResponse.Marshal(b, m, deterministic)
 }
-func (dst *DeleteJoinTokenResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_DeleteJoinTokenResponse.Merge(dst, src)
+func (dst *UseJoinTokenResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UseJoinTokenResponse.Merge(dst, src)
 }
-func (m *DeleteJoinTokenResponse) XXX_Size() int {
-	return xxx_messageInfo_DeleteJoinTokenResponse.Size(m)
+func (m *UseJoinTokenResponse) XXX_Size() int {
+	return xxx_messageInfo_UseJoinTokenResponse.Size(m)
 }
-func (m *DeleteJoinTokenResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_DeleteJoinTokenResponse.DiscardUnknown(m)
+func (m *UseJoinTokenResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_UseJoinTokenResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_DeleteJoinTokenResponse proto.InternalMessageInfo
+var xxx_messageInfo_UseJoinTokenResponse proto.InternalMessageInfo
 
-func (m *DeleteJoinTokenResponse) GetJoinToken() *JoinToken {
+func (m *UseJoinTokenResponse) GetJoinToken() *JoinToken {
 	if m != nil {
 		return m.JoinToken
 	}
 	return nil
 }
 
-type PruneJoinTokensRequest struct {
-	ExpiresBefore        int64    `protobuf:"varint,1,opt,name=expires_before,json=expiresBefore,proto3" json:"expires_before,omitempty"`
+type DeleteJoinTokenRequest struct {
+	Token                string   `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteJoinTokenRequest) Reset()         { *m = DeleteJoinTokenRequest{} }
+func (m *DeleteJoinTokenRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteJoinTokenRequest) ProtoMessage()    {}
+func (*DeleteJoinTokenRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{54}
+}
+func (m *DeleteJoinTokenRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteJoinTokenRequest.Unmarshal(m, b)
+}
+func (m *DeleteJoinTokenRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteJoinTokenRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteJoinTokenRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteJoinTokenRequest.Merge(dst, src)
+}
+func (m *DeleteJoinTokenRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteJoinTokenRequest.Size(m)
+}
+func (m *DeleteJoinTokenRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteJoinTokenRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteJoinTokenRequest proto.InternalMessageInfo
+
+func (m *DeleteJoinTokenRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+type DeleteJoinTokenResponse struct {
+	JoinToken            *JoinToken `protobuf:"bytes,1,opt,name=join_token,json=joinToken,proto3" json:"join_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *DeleteJoinTokenResponse) Reset()         { *m = DeleteJoinTokenResponse{} }
+func (m *DeleteJoinTokenResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteJoinTokenResponse) ProtoMessage()    {}
+func (*DeleteJoinTokenResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{55}
+}
+func (m *DeleteJoinTokenResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteJoinTokenResponse.Unmarshal(m, b)
+}
+func (m *DeleteJoinTokenResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteJoinTokenResponse.Marshal(b, m, deterministic)
+}
+func (dst *DeleteJoinTokenResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteJoinTokenResponse.Merge(dst, src)
+}
+func (m *DeleteJoinTokenResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteJoinTokenResponse.Size(m)
+}
+func (m *DeleteJoinTokenResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteJoinTokenResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteJoinTokenResponse proto.InternalMessageInfo
+
+func (m *DeleteJoinTokenResponse) GetJoinToken() *JoinToken {
+	if m != nil {
+		return m.JoinToken
+	}
+	return nil
+}
+
+type PruneJoinTokensRequest struct {
+	ExpiresBefore        int64    `protobuf:"varint,1,opt,name=expires_before,json=expiresBefore,proto3" json:"expires_before,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -2003,7 +2502,7 @@ func (m *PruneJoinTokensRequest) Reset()         { *m = PruneJoinTokensRequest{}
 func (m *PruneJoinTokensRequest) String() string { return proto.CompactTextString(m) }
 func (*PruneJoinTokensRequest) ProtoMessage()    {}
 func (*PruneJoinTokensRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{46}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{56}
 }
 func (m *PruneJoinTokensRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PruneJoinTokensRequest.Unmarshal(m, b)
@@ -2040,7 +2539,7 @@ func (m *PruneJoinTokensResponse) Reset()         { *m = PruneJoinTokensResponse
 func (m *PruneJoinTokensResponse) String() string { return proto.CompactTextString(m) }
 func (*PruneJoinTokensResponse) ProtoMessage()    {}
 func (*PruneJoinTokensResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_datastore_2844d8face5c3336, []int{47}
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{57}
 }
 func (m *PruneJoinTokensResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PruneJoinTokensResponse.Unmarshal(m, b)
@@ -2060,6 +2559,258 @@ func (m *PruneJoinTokensResponse) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_PruneJoinTokensResponse proto.InternalMessageInfo
 
+// Describes a change to a registration entry or a bundle
+type ChangeEvent struct {
+	// Identifies the event. Event IDs increase with each change, but changes
+	// made concurrently may become visible out of ID order.
+	Id uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The kind of record that changed
+	Kind ChangeEvent_Kind `protobuf:"varint,2,opt,name=kind,proto3,enum=spire.server.datastore.ChangeEvent_Kind" json:"kind,omitempty"`
+	// The change made to the record
+	Op ChangeEvent_Op `protobuf:"varint,3,opt,name=op,proto3,enum=spire.server.datastore.ChangeEvent_Op" json:"op,omitempty"`
+	// Entry ID of the registration entry or trust domain ID of the bundle
+	Key string `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`
+	// Time of the change, in seconds since the epoch
+	CreatedAt            int64    `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChangeEvent) Reset()         { *m = ChangeEvent{} }
+func (m *ChangeEvent) String() string { return proto.CompactTextString(m) }
+func (*ChangeEvent) ProtoMessage()    {}
+func (*ChangeEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{58}
+}
+func (m *ChangeEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChangeEvent.Unmarshal(m, b)
+}
+func (m *ChangeEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChangeEvent.Marshal(b, m, deterministic)
+}
+func (dst *ChangeEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChangeEvent.Merge(dst, src)
+}
+func (m *ChangeEvent) XXX_Size() int {
+	return xxx_messageInfo_ChangeEvent.Size(m)
+}
+func (m *ChangeEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChangeEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChangeEvent proto.InternalMessageInfo
+
+func (m *ChangeEvent) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *ChangeEvent) GetKind() ChangeEvent_Kind {
+	if m != nil {
+		return m.Kind
+	}
+	return ChangeEvent_REGISTRATION_ENTRY
+}
+
+func (m *ChangeEvent) GetOp() ChangeEvent_Op {
+	if m != nil {
+		return m.Op
+	}
+	return ChangeEvent_CREATED
+}
+
+func (m *ChangeEvent) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *ChangeEvent) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+type ListChangeEventsRequest struct {
+	// Only events with an ID greater than after_id are returned
+	AfterId uint64 `protobuf:"varint,1,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"`
+	// Maximum number of events returned. Zero means no limit.
+	Limit                int32    `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListChangeEventsRequest) Reset()         { *m = ListChangeEventsRequest{} }
+func (m *ListChangeEventsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListChangeEventsRequest) ProtoMessage()    {}
+func (*ListChangeEventsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{59}
+}
+func (m *ListChangeEventsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListChangeEventsRequest.Unmarshal(m, b)
+}
+func (m *ListChangeEventsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListChangeEventsRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListChangeEventsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListChangeEventsRequest.Merge(dst, src)
+}
+func (m *ListChangeEventsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListChangeEventsRequest.Size(m)
+}
+func (m *ListChangeEventsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListChangeEventsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListChangeEventsRequest proto.InternalMessageInfo
+
+func (m *ListChangeEventsRequest) GetAfterId() uint64 {
+	if m != nil {
+		return m.AfterId
+	}
+	return 0
+}
+
+func (m *ListChangeEventsRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type ListChangeEventsResponse struct {
+	// Events following after_id, in ID order
+	Events []*ChangeEvent `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	// ID of the most recent event known to the datastore
+	LatestId uint64 `protobuf:"varint,2,opt,name=latest_id,json=latestId,proto3" json:"latest_id,omitempty"`
+	// Set when events following after_id may have been pruned. The caller
+	// should reload the entries and bundles it tracks.
+	ResyncRequired       bool     `protobuf:"varint,3,opt,name=resync_required,json=resyncRequired,proto3" json:"resync_required,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListChangeEventsResponse) Reset()         { *m = ListChangeEventsResponse{} }
+func (m *ListChangeEventsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListChangeEventsResponse) ProtoMessage()    {}
+func (*ListChangeEventsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{60}
+}
+func (m *ListChangeEventsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListChangeEventsResponse.Unmarshal(m, b)
+}
+func (m *ListChangeEventsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListChangeEventsResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListChangeEventsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListChangeEventsResponse.Merge(dst, src)
+}
+func (m *ListChangeEventsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListChangeEventsResponse.Size(m)
+}
+func (m *ListChangeEventsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListChangeEventsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListChangeEventsResponse proto.InternalMessageInfo
+
+func (m *ListChangeEventsResponse) GetEvents() []*ChangeEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *ListChangeEventsResponse) GetLatestId() uint64 {
+	if m != nil {
+		return m.LatestId
+	}
+	return 0
+}
+
+func (m *ListChangeEventsResponse) GetResyncRequired() bool {
+	if m != nil {
+		return m.ResyncRequired
+	}
+	return false
+}
+
+type PruneChangeEventsRequest struct {
+	// Events created before this time, in seconds since the epoch, are pruned
+	CreatedBefore        int64    `protobuf:"varint,1,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PruneChangeEventsRequest) Reset()         { *m = PruneChangeEventsRequest{} }
+func (m *PruneChangeEventsRequest) String() string { return proto.CompactTextString(m) }
+func (*PruneChangeEventsRequest) ProtoMessage()    {}
+func (*PruneChangeEventsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{61}
+}
+func (m *PruneChangeEventsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PruneChangeEventsRequest.Unmarshal(m, b)
+}
+func (m *PruneChangeEventsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PruneChangeEventsRequest.Marshal(b, m, deterministic)
+}
+func (dst *PruneChangeEventsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PruneChangeEventsRequest.Merge(dst, src)
+}
+func (m *PruneChangeEventsRequest) XXX_Size() int {
+	return xxx_messageInfo_PruneChangeEventsRequest.Size(m)
+}
+func (m *PruneChangeEventsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PruneChangeEventsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PruneChangeEventsRequest proto.InternalMessageInfo
+
+func (m *PruneChangeEventsRequest) GetCreatedBefore() int64 {
+	if m != nil {
+		return m.CreatedBefore
+	}
+	return 0
+}
+
+type PruneChangeEventsResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PruneChangeEventsResponse) Reset()         { *m = PruneChangeEventsResponse{} }
+func (m *PruneChangeEventsResponse) String() string { return proto.CompactTextString(m) }
+func (*PruneChangeEventsResponse) ProtoMessage()    {}
+func (*PruneChangeEventsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_datastore_3748d7ab0cc36356, []int{62}
+}
+func (m *PruneChangeEventsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PruneChangeEventsResponse.Unmarshal(m, b)
+}
+func (m *PruneChangeEventsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PruneChangeEventsResponse.Marshal(b, m, deterministic)
+}
+func (dst *PruneChangeEventsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PruneChangeEventsResponse.Merge(dst, src)
+}
+func (m *PruneChangeEventsResponse) XXX_Size() int {
+	return xxx_messageInfo_PruneChangeEventsResponse.Size(m)
+}
+func (m *PruneChangeEventsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PruneChangeEventsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PruneChangeEventsResponse proto.InternalMessageInfo
+
 func init() {
 	proto.RegisterType((*CreateBundleRequest)(nil), "spire.server.datastore.CreateBundleRequest")
 	proto.RegisterType((*CreateBundleResponse)(nil), "spire.server.datastore.CreateBundleResponse")
@@ -2100,17 +2851,34 @@ func init() {
 	proto.RegisterType((*UpdateRegistrationEntryResponse)(nil), "spire.server.datastore.UpdateRegistrationEntryResponse")
 	proto.RegisterType((*DeleteRegistrationEntryRequest)(nil), "spire.server.datastore.DeleteRegistrationEntryRequest")
 	proto.RegisterType((*DeleteRegistrationEntryResponse)(nil), "spire.server.datastore.DeleteRegistrationEntryResponse")
+	proto.RegisterType((*BatchCreateRegistrationEntriesRequest)(nil), "spire.server.datastore.BatchCreateRegistrationEntriesRequest")
+	proto.RegisterType((*BatchCreateRegistrationEntriesResponse)(nil), "spire.server.datastore.BatchCreateRegistrationEntriesResponse")
+	proto.RegisterType((*BatchUpdateRegistrationEntriesRequest)(nil), "spire.server.datastore.BatchUpdateRegistrationEntriesRequest")
+	proto.RegisterType((*BatchUpdateRegistrationEntriesResponse)(nil), "spire.server.datastore.BatchUpdateRegistrationEntriesResponse")
+	proto.RegisterType((*BatchDeleteRegistrationEntriesRequest)(nil), "spire.server.datastore.BatchDeleteRegistrationEntriesRequest")
+	proto.RegisterType((*BatchDeleteRegistrationEntriesResponse)(nil), "spire.server.datastore.BatchDeleteRegistrationEntriesResponse")
 	proto.RegisterType((*JoinToken)(nil), "spire.server.datastore.JoinToken")
 	proto.RegisterType((*CreateJoinTokenRequest)(nil), "spire.server.datastore.CreateJoinTokenRequest")
 	proto.RegisterType((*CreateJoinTokenResponse)(nil), "spire.server.datastore.CreateJoinTokenResponse")
 	proto.RegisterType((*FetchJoinTokenRequest)(nil), "spire.server.datastore.FetchJoinTokenRequest")
 	proto.RegisterType((*FetchJoinTokenResponse)(nil), "spire.server.datastore.FetchJoinTokenResponse")
+	proto.RegisterType((*ListJoinTokensRequest)(nil), "spire.server.datastore.ListJoinTokensRequest")
+	proto.RegisterType((*ListJoinTokensResponse)(nil), "spire.server.datastore.ListJoinTokensResponse")
+	proto.RegisterType((*UseJoinTokenRequest)(nil), "spire.server.datastore.UseJoinTokenRequest")
+	proto.RegisterType((*UseJoinTokenResponse)(nil), "spire.server.datastore.UseJoinTokenResponse")
 	proto.RegisterType((*DeleteJoinTokenRequest)(nil), "spire.server.datastore.DeleteJoinTokenRequest")
 	proto.RegisterType((*DeleteJoinTokenResponse)(nil), "spire.server.datastore.DeleteJoinTokenResponse")
 	proto.RegisterType((*PruneJoinTokensRequest)(nil), "spire.server.datastore.PruneJoinTokensRequest")
 	proto.RegisterType((*PruneJoinTokensResponse)(nil), "spire.server.datastore.PruneJoinTokensResponse")
+	proto.RegisterType((*ChangeEvent)(nil), "spire.server.datastore.ChangeEvent")
+	proto.RegisterType((*ListChangeEventsRequest)(nil), "spire.server.datastore.ListChangeEventsRequest")
+	proto.RegisterType((*ListChangeEventsResponse)(nil), "spire.server.datastore.ListChangeEventsResponse")
+	proto.RegisterType((*PruneChangeEventsRequest)(nil), "spire.server.datastore.PruneChangeEventsRequest")
+	proto.RegisterType((*PruneChangeEventsResponse)(nil), "spire.server.datastore.PruneChangeEventsResponse")
 	proto.RegisterEnum("spire.server.datastore.DeleteBundleRequest_Mode", DeleteBundleRequest_Mode_name, DeleteBundleRequest_Mode_value)
 	proto.RegisterEnum("spire.server.datastore.BySelectors_MatchBehavior", BySelectors_MatchBehavior_name, BySelectors_MatchBehavior_value)
+	proto.RegisterEnum("spire.server.datastore.ChangeEvent_Kind", ChangeEvent_Kind_name, ChangeEvent_Kind_value)
+	proto.RegisterEnum("spire.server.datastore.ChangeEvent_Op", ChangeEvent_Op_name, ChangeEvent_Op_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -2161,14 +2929,33 @@ type DataStoreClient interface {
 	UpdateRegistrationEntry(ctx context.Context, in *UpdateRegistrationEntryRequest, opts ...grpc.CallOption) (*UpdateRegistrationEntryResponse, error)
 	// Deletes a specific registration entry
 	DeleteRegistrationEntry(ctx context.Context, in *DeleteRegistrationEntryRequest, opts ...grpc.CallOption) (*DeleteRegistrationEntryResponse, error)
+	// Creates several registration entries. Either all or none of the
+	// entries are created, if the datastore supports it.
+	BatchCreateRegistrationEntries(ctx context.Context, in *BatchCreateRegistrationEntriesRequest, opts ...grpc.CallOption) (*BatchCreateRegistrationEntriesResponse, error)
+	// Updates several registration entries. Either all or none of the
+	// entries are updated, if the datastore supports it.
+	BatchUpdateRegistrationEntries(ctx context.Context, in *BatchUpdateRegistrationEntriesRequest, opts ...grpc.CallOption) (*BatchUpdateRegistrationEntriesResponse, error)
+	// Deletes several registration entries. Either all or none of the
+	// entries are deleted, if the datastore supports it.
+	BatchDeleteRegistrationEntries(ctx context.Context, in *BatchDeleteRegistrationEntriesRequest, opts ...grpc.CallOption) (*BatchDeleteRegistrationEntriesResponse, error)
 	// Creates a join token
 	CreateJoinToken(ctx context.Context, in *CreateJoinTokenRequest, opts ...grpc.CallOption) (*CreateJoinTokenResponse, error)
 	// Fetches a specific join token
 	FetchJoinToken(ctx context.Context, in *FetchJoinTokenRequest, opts ...grpc.CallOption) (*FetchJoinTokenResponse, error)
+	// Lists join tokens
+	ListJoinTokens(ctx context.Context, in *ListJoinTokensRequest, opts ...grpc.CallOption) (*ListJoinTokensResponse, error)
+	// Records a use of a specific join token, deleting the token once it
+	// has been used as many times as allowed
+	UseJoinToken(ctx context.Context, in *UseJoinTokenRequest, opts ...grpc.CallOption) (*UseJoinTokenResponse, error)
 	// Delete a specific join token
 	DeleteJoinToken(ctx context.Context, in *DeleteJoinTokenRequest, opts ...grpc.CallOption) (*DeleteJoinTokenResponse, error)
 	// Prunes all join tokens that expire before the specified timestamp
 	PruneJoinTokens(ctx context.Context, in *PruneJoinTokensRequest, opts ...grpc.CallOption) (*PruneJoinTokensResponse, error)
+	// Lists registration entry and bundle change events following a given event
+	ListChangeEvents(ctx context.Context, in *ListChangeEventsRequest, opts ...grpc.CallOption) (*ListChangeEventsResponse, error)
+	// Prunes change events created before a given time. The most recent event
+	// is always kept.
+	PruneChangeEvents(ctx context.Context, in *PruneChangeEventsRequest, opts ...grpc.CallOption) (*PruneChangeEventsResponse, error)
 	// Applies the plugin configuration
 	Configure(ctx context.Context, in *plugin.ConfigureRequest, opts ...grpc.CallOption) (*plugin.ConfigureResponse, error)
 	// Returns the version and related metadata of the installed plugin
@@ -2345,6 +3132,33 @@ func (c *dataStoreClient) DeleteRegistrationEntry(ctx context.Context, in *Delet
 	return out, nil
 }
 
+func (c *dataStoreClient) BatchCreateRegistrationEntries(ctx context.Context, in *BatchCreateRegistrationEntriesRequest, opts ...grpc.CallOption) (*BatchCreateRegistrationEntriesResponse, error) {
+	out := new(BatchCreateRegistrationEntriesResponse)
+	err := c.cc.Invoke(ctx, "/spire.server.datastore.DataStore/BatchCreateRegistrationEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataStoreClient) BatchUpdateRegistrationEntries(ctx context.Context, in *BatchUpdateRegistrationEntriesRequest, opts ...grpc.CallOption) (*BatchUpdateRegistrationEntriesResponse, error) {
+	out := new(BatchUpdateRegistrationEntriesResponse)
+	err := c.cc.Invoke(ctx, "/spire.server.datastore.DataStore/BatchUpdateRegistrationEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataStoreClient) BatchDeleteRegistrationEntries(ctx context.Context, in *BatchDeleteRegistrationEntriesRequest, opts ...grpc.CallOption) (*BatchDeleteRegistrationEntriesResponse, error) {
+	out := new(BatchDeleteRegistrationEntriesResponse)
+	err := c.cc.Invoke(ctx, "/spire.server.datastore.DataStore/BatchDeleteRegistrationEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dataStoreClient) CreateJoinToken(ctx context.Context, in *CreateJoinTokenRequest, opts ...grpc.CallOption) (*CreateJoinTokenResponse, error) {
 	out := new(CreateJoinTokenResponse)
 	err := c.cc.Invoke(ctx, "/spire.server.datastore.DataStore/CreateJoinToken", in, out, opts...)
@@ -2363,6 +3177,24 @@ func (c *dataStoreClient) FetchJoinToken(ctx context.Context, in *FetchJoinToken
 	return out, nil
 }
 
+func (c *dataStoreClient) ListJoinTokens(ctx context.Context, in *ListJoinTokensRequest, opts ...grpc.CallOption) (*ListJoinTokensResponse, error) {
+	out := new(ListJoinTokensResponse)
+	err := c.cc.Invoke(ctx, "/spire.server.datastore.DataStore/ListJoinTokens", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataStoreClient) UseJoinToken(ctx context.Context, in *UseJoinTokenRequest, opts ...grpc.CallOption) (*UseJoinTokenResponse, error) {
+	out := new(UseJoinTokenResponse)
+	err := c.cc.Invoke(ctx, "/spire.server.datastore.DataStore/UseJoinToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dataStoreClient) DeleteJoinToken(ctx context.Context, in *DeleteJoinTokenRequest, opts ...grpc.CallOption) (*DeleteJoinTokenResponse, error) {
 	out := new(DeleteJoinTokenResponse)
 	err := c.cc.Invoke(ctx, "/spire.server.datastore.DataStore/DeleteJoinToken", in, out, opts...)
@@ -2381,6 +3213,24 @@ func (c *dataStoreClient) PruneJoinTokens(ctx context.Context, in *PruneJoinToke
 	return out, nil
 }
 
+func (c *dataStoreClient) ListChangeEvents(ctx context.Context, in *ListChangeEventsRequest, opts ...grpc.CallOption) (*ListChangeEventsResponse, error) {
+	out := new(ListChangeEventsResponse)
+	err := c.cc.Invoke(ctx, "/spire.server.datastore.DataStore/ListChangeEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataStoreClient) PruneChangeEvents(ctx context.Context, in *PruneChangeEventsRequest, opts ...grpc.CallOption) (*PruneChangeEventsResponse, error) {
+	out := new(PruneChangeEventsResponse)
+	err := c.cc.Invoke(ctx, "/spire.server.datastore.DataStore/PruneChangeEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dataStoreClient) Configure(ctx context.Context, in *plugin.ConfigureRequest, opts ...grpc.CallOption) (*plugin.ConfigureResponse, error) {
 	out := new(plugin.ConfigureResponse)
 	err := c.cc.Invoke(ctx, "/spire.server.datastore.DataStore/Configure", in, out, opts...)
@@ -2437,14 +3287,33 @@ type DataStoreServer interface {
 	UpdateRegistrationEntry(context.Context, *UpdateRegistrationEntryRequest) (*UpdateRegistrationEntryResponse, error)
 	// Deletes a specific registration entry
 	DeleteRegistrationEntry(context.Context, *DeleteRegistrationEntryRequest) (*DeleteRegistrationEntryResponse, error)
+	// Creates several registration entries. Either all or none of the
+	// entries are created, if the datastore supports it.
+	BatchCreateRegistrationEntries(context.Context, *BatchCreateRegistrationEntriesRequest) (*BatchCreateRegistrationEntriesResponse, error)
+	// Updates several registration entries. Either all or none of the
+	// entries are updated, if the datastore supports it.
+	BatchUpdateRegistrationEntries(context.Context, *BatchUpdateRegistrationEntriesRequest) (*BatchUpdateRegistrationEntriesResponse, error)
+	// Deletes several registration entries. Either all or none of the
+	// entries are deleted, if the datastore supports it.
+	BatchDeleteRegistrationEntries(context.Context, *BatchDeleteRegistrationEntriesRequest) (*BatchDeleteRegistrationEntriesResponse, error)
 	// Creates a join token
 	CreateJoinToken(context.Context, *CreateJoinTokenRequest) (*CreateJoinTokenResponse, error)
 	// Fetches a specific join token
 	FetchJoinToken(context.Context, *FetchJoinTokenRequest) (*FetchJoinTokenResponse, error)
+	// Lists join tokens
+	ListJoinTokens(context.Context, *ListJoinTokensRequest) (*ListJoinTokensResponse, error)
+	// Records a use of a specific join token, deleting the token once it
+	// has been used as many times as allowed
+	UseJoinToken(context.Context, *UseJoinTokenRequest) (*UseJoinTokenResponse, error)
 	// Delete a specific join token
 	DeleteJoinToken(context.Context, *DeleteJoinTokenRequest) (*DeleteJoinTokenResponse, error)
 	// Prunes all join tokens that expire before the specified timestamp
 	PruneJoinTokens(context.Context, *PruneJoinTokensRequest) (*PruneJoinTokensResponse, error)
+	// Lists registration entry and bundle change events following a given event
+	ListChangeEvents(context.Context, *ListChangeEventsRequest) (*ListChangeEventsResponse, error)
+	// Prunes change events created before a given time. The most recent event
+	// is always kept.
+	PruneChangeEvents(context.Context, *PruneChangeEventsRequest) (*PruneChangeEventsResponse, error)
 	// Applies the plugin configuration
 	Configure(context.Context, *plugin.ConfigureRequest) (*plugin.ConfigureResponse, error)
 	// Returns the version and related metadata of the installed plugin
@@ -2779,6 +3648,60 @@ func _DataStore_DeleteRegistrationEntry_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DataStore_BatchCreateRegistrationEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCreateRegistrationEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataStoreServer).BatchCreateRegistrationEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.server.datastore.DataStore/BatchCreateRegistrationEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataStoreServer).BatchCreateRegistrationEntries(ctx, req.(*BatchCreateRegistrationEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataStore_BatchUpdateRegistrationEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchUpdateRegistrationEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataStoreServer).BatchUpdateRegistrationEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.server.datastore.DataStore/BatchUpdateRegistrationEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataStoreServer).BatchUpdateRegistrationEntries(ctx, req.(*BatchUpdateRegistrationEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataStore_BatchDeleteRegistrationEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchDeleteRegistrationEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataStoreServer).BatchDeleteRegistrationEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.server.datastore.DataStore/BatchDeleteRegistrationEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataStoreServer).BatchDeleteRegistrationEntries(ctx, req.(*BatchDeleteRegistrationEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DataStore_CreateJoinToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CreateJoinTokenRequest)
 	if err := dec(in); err != nil {
@@ -2815,6 +3738,42 @@ func _DataStore_FetchJoinToken_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DataStore_ListJoinTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJoinTokensRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataStoreServer).ListJoinTokens(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.server.datastore.DataStore/ListJoinTokens",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataStoreServer).ListJoinTokens(ctx, req.(*ListJoinTokensRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataStore_UseJoinToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UseJoinTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataStoreServer).UseJoinToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.server.datastore.DataStore/UseJoinToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataStoreServer).UseJoinToken(ctx, req.(*UseJoinTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DataStore_DeleteJoinToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteJoinTokenRequest)
 	if err := dec(in); err != nil {
@@ -2851,6 +3810,42 @@ func _DataStore_PruneJoinTokens_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DataStore_ListChangeEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListChangeEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataStoreServer).ListChangeEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.server.datastore.DataStore/ListChangeEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataStoreServer).ListChangeEvents(ctx, req.(*ListChangeEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataStore_PruneChangeEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PruneChangeEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataStoreServer).PruneChangeEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.server.datastore.DataStore/PruneChangeEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataStoreServer).PruneChangeEvents(ctx, req.(*PruneChangeEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DataStore_Configure_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(plugin.ConfigureRequest)
 	if err := dec(in); err != nil {
@@ -2963,6 +3958,18 @@ var _DataStore_serviceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteRegistrationEntry",
 			Handler:    _DataStore_DeleteRegistrationEntry_Handler,
 		},
+		{
+			MethodName: "BatchCreateRegistrationEntries",
+			Handler:    _DataStore_BatchCreateRegistrationEntries_Handler,
+		},
+		{
+			MethodName: "BatchUpdateRegistrationEntries",
+			Handler:    _DataStore_BatchUpdateRegistrationEntries_Handler,
+		},
+		{
+			MethodName: "BatchDeleteRegistrationEntries",
+			Handler:    _DataStore_BatchDeleteRegistrationEntries_Handler,
+		},
 		{
 			MethodName: "CreateJoinToken",
 			Handler:    _DataStore_CreateJoinToken_Handler,
@@ -2971,6 +3978,14 @@ var _DataStore_serviceDesc = grpc.ServiceDesc{
 			MethodName: "FetchJoinToken",
 			Handler:    _DataStore_FetchJoinToken_Handler,
 		},
+		{
+			MethodName: "ListJoinTokens",
+			Handler:    _DataStore_ListJoinTokens_Handler,
+		},
+		{
+			MethodName: "UseJoinToken",
+			Handler:    _DataStore_UseJoinToken_Handler,
+		},
 		{
 			MethodName: "DeleteJoinToken",
 			Handler:    _DataStore_DeleteJoinToken_Handler,
@@ -2979,6 +3994,14 @@ var _DataStore_serviceDesc = grpc.ServiceDesc{
 			MethodName: "PruneJoinTokens",
 			Handler:    _DataStore_PruneJoinTokens_Handler,
 		},
+		{
+			MethodName: "ListChangeEvents",
+			Handler:    _DataStore_ListChangeEvents_Handler,
+		},
+		{
+			MethodName: "PruneChangeEvents",
+			Handler:    _DataStore_PruneChangeEvents_Handler,
+		},
 		{
 			MethodName: "Configure",
 			Handler:    _DataStore_Configure_Handler,
@@ -2992,107 +4015,142 @@ var _DataStore_serviceDesc = grpc.ServiceDesc{
 	Metadata: "datastore.proto",
 }
 
-func init() { proto.RegisterFile("datastore.proto", fileDescriptor_datastore_2844d8face5c3336) }
-
-var fileDescriptor_datastore_2844d8face5c3336 = []byte{
-	// 1582 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x59, 0x5b, 0x6f, 0xdb, 0xc6,
-	0x12, 0x0e, 0x7d, 0x8b, 0x35, 0x92, 0x2f, 0x59, 0xfb, 0xd8, 0x92, 0x72, 0x8e, 0xed, 0xc3, 0x73,
-	0x12, 0xa4, 0xb9, 0x50, 0xb6, 0x9a, 0x38, 0x49, 0x5b, 0x34, 0xb5, 0x2e, 0x51, 0xd5, 0x38, 0xa9,
-	0x40, 0x29, 0x4d, 0x90, 0x00, 0x15, 0x28, 0x73, 0x25, 0x33, 0x95, 0x48, 0x95, 0x5c, 0xa5, 0x51,
-	0xf2, 0x03, 0x0a, 0x14, 0x7d, 0xe9, 0x3f, 0xe8, 0x5b, 0x5f, 0x0a, 0xf4, 0xa9, 0xef, 0xfd, 0x69,
-	0x05, 0x77, 0x49, 0x91, 0x14, 0xb9, 0xb4, 0x24, 0xbb, 0x4f, 0x8e, 0x96, 0x33, 0xdf, 0x7c, 0x33,
-	0xbb, 0x3b, 0x3b, 0x33, 0x81, 0x35, 0x55, 0x21, 0x8a, 0x45, 0x0c, 0x13, 0x4b, 0x7d, 0xd3, 0x20,
-	0x06, 0xda, 0xb2, 0xfa, 0x9a, 0x89, 0x25, 0x0b, 0x9b, 0x6f, 0xb1, 0x29, 0x8d, 0xbe, 0x66, 0x77,
-	0x3a, 0x86, 0xd1, 0xe9, 0xe2, 0x1c, 0x95, 0x6a, 0x0d, 0xda, 0xb9, 0x1f, 0x4c, 0xa5, 0xdf, 0xc7,
-	0xa6, 0xc5, 0xf4, 0xb2, 0x0f, 0x3a, 0x1a, 0x39, 0x1d, 0xb4, 0xa4, 0x13, 0xa3, 0x97, 0xb3, 0xfa,
-	0x5a, 0xbb, 0x8d, 0x73, 0x14, 0x89, 0x29, 0xe4, 0x4e, 0x8c, 0x5e, 0xcf, 0xd0, 0x73, 0xfd, 0xee,
-	0xa0, 0xa3, 0xb9, 0x7f, 0x1c, 0xcd, 0x83, 0x89, 0x34, 0xd9, 0x1f, 0xa6, 0x22, 0x16, 0x61, 0xa3,
-	0x68, 0x62, 0x85, 0xe0, 0xc2, 0x40, 0x57, 0xbb, 0x58, 0xc6, 0xdf, 0x0f, 0xb0, 0x45, 0xd0, 0x6d,
-	0x58, 0x6a, 0xd1, 0x85, 0xb4, 0xb0, 0x27, 0xdc, 0x48, 0xe6, 0x37, 0x25, 0xe6, 0x8c, 0xa3, 0xeb,
-	0x08, 0x3b, 0x32, 0x62, 0x09, 0x36, 0x83, 0x20, 0x56, 0xdf, 0xd0, 0x2d, 0x3c, 0x25, 0xca, 0x67,
-	0x80, 0x1e, 0x63, 0x72, 0x72, 0x1a, 0x64, 0x72, 0x1d, 0xd6, 0x88, 0x39, 0xb0, 0x48, 0x53, 0x35,
-	0x7a, 0x8a, 0xa6, 0x37, 0x35, 0x95, 0x82, 0x25, 0xe4, 0x15, 0xba, 0x5c, 0xa2, 0xab, 0x55, 0xd5,
-	0x76, 0x24, 0xa0, 0x3d, 0x13, 0x85, 0x4d, 0x40, 0xc7, 0x9a, 0x45, 0xd8, 0xaa, 0xe5, 0x50, 0x10,
-	0xcb, 0xb0, 0x11, 0x58, 0x75, 0xa0, 0x25, 0xb8, 0xcc, 0xd4, 0xac, 0xb4, 0xb0, 0x37, 0xcf, 0xc5,
-	0x76, 0x85, 0x6c, 0x86, 0xcf, 0xfb, 0xea, 0xf9, 0x43, 0x1d, 0x04, 0x99, 0xc9, 0xcf, 0x22, 0x6c,
-	0x1c, 0xf5, 0xfb, 0x58, 0x57, 0xcf, 0x49, 0x25, 0x08, 0x32, 0x13, 0x95, 0x3f, 0x05, 0xd8, 0x28,
-	0xe1, 0x2e, 0x1e, 0x0f, 0xcb, 0x84, 0xfb, 0x8e, 0x4a, 0xb0, 0xd0, 0x33, 0x54, 0x9c, 0x9e, 0xdb,
-	0x13, 0x6e, 0xac, 0xe6, 0xf7, 0xa5, 0xe8, 0x4b, 0x27, 0x45, 0x98, 0x90, 0x9e, 0x1a, 0x2a, 0x96,
-	0xa9, 0xb6, 0xb8, 0x0f, 0x0b, 0xf6, 0x2f, 0x94, 0x82, 0x65, 0xb9, 0x5c, 0x6f, 0xc8, 0xd5, 0x62,
-	0x63, 0xfd, 0x12, 0x02, 0x58, 0x2a, 0x95, 0x8f, 0xcb, 0x8d, 0xf2, 0xba, 0x80, 0x56, 0x01, 0x4a,
-	0xd5, 0x7a, 0xfd, 0xeb, 0x62, 0xf5, 0xa8, 0x51, 0x5e, 0x9f, 0xb3, 0xbd, 0x0f, 0x62, 0xce, 0xe4,
-	0x7d, 0x0b, 0x56, 0x9e, 0x19, 0x2a, 0xae, 0xe3, 0x2e, 0x3e, 0x21, 0x86, 0x69, 0xa1, 0xab, 0x90,
-	0x60, 0x37, 0xd7, 0x73, 0x78, 0x99, 0x2d, 0x54, 0x55, 0x74, 0x17, 0x12, 0x96, 0x2b, 0x99, 0x9e,
-	0xa3, 0x67, 0x6e, 0x2b, 0x08, 0xef, 0x02, 0xc9, 0x9e, 0xa0, 0xf8, 0x2d, 0x6c, 0xd7, 0x31, 0x09,
-	0x98, 0x71, 0x83, 0x5c, 0xf4, 0x03, 0x32, 0xbe, 0xd7, 0x78, 0x11, 0x0c, 0x02, 0xf8, 0xf0, 0xb3,
-	0x90, 0x0e, 0xe3, 0xb3, 0x68, 0x88, 0x87, 0xb0, 0x5d, 0xe1, 0xd8, 0x8e, 0xf3, 0x54, 0x6c, 0x42,
-	0xba, 0xc2, 0xc1, 0xbc, 0x18, 0xd2, 0x4f, 0x20, 0xc3, 0x52, 0xd6, 0x11, 0x21, 0xd8, 0x22, 0x58,
-	0xb5, 0x25, 0x5d, 0x6a, 0x12, 0x2c, 0xe8, 0xf6, 0x99, 0x62, 0xe0, 0xd9, 0x60, 0x88, 0x03, 0x0a,
-	0x54, 0x4e, 0x3c, 0x86, 0x6c, 0x14, 0xd8, 0x28, 0x4f, 0x4c, 0x87, 0x76, 0x1f, 0xd2, 0x34, 0x93,
-	0x45, 0x31, 0x8b, 0x0d, 0xda, 0x13, 0xc8, 0x44, 0x28, 0xce, 0xc8, 0xe2, 0x37, 0x01, 0xd2, 0x76,
-	0xd6, 0xf3, 0x7f, 0x1a, 0xed, 0x5d, 0x05, 0xae, 0xb4, 0x86, 0x4d, 0xfc, 0xce, 0xc6, 0xb0, 0x9a,
-	0x2d, 0xdc, 0x36, 0x4c, 0x17, 0xf9, 0xaa, 0xc4, 0x9e, 0x37, 0xc9, 0x7d, 0xde, 0xa4, 0xaa, 0x4e,
-	0x0e, 0xef, 0x7e, 0xa3, 0x74, 0x07, 0x58, 0x5e, 0x6b, 0x0d, 0xcb, 0x4c, 0xa9, 0x40, 0x75, 0x50,
-	0x01, 0xa0, 0xaf, 0x74, 0x34, 0x5d, 0x21, 0x9a, 0xa1, 0xd3, 0x3b, 0x9c, 0xcc, 0x8b, 0xbc, 0xcd,
-	0xac, 0x8d, 0x24, 0x65, 0x9f, 0x96, 0xf8, 0x8b, 0x00, 0x99, 0x08, 0xa6, 0x8e, 0xdf, 0xfb, 0xb0,
-	0x68, 0xfb, 0xe3, 0xe6, 0xe8, 0x38, 0xc7, 0x99, 0xe0, 0x85, 0x70, 0xfa, 0x59, 0x80, 0x0c, 0xcb,
-	0xd3, 0xd3, 0xee, 0x22, 0xba, 0x0d, 0xe8, 0x04, 0x9b, 0xa4, 0x69, 0x61, 0x53, 0x53, 0xba, 0x4d,
-	0x7d, 0xd0, 0x6b, 0x61, 0x93, 0xd2, 0x48, 0xc8, 0xeb, 0xf6, 0x97, 0x3a, 0xfd, 0xf0, 0x8c, 0xae,
-	0xa3, 0xff, 0xc3, 0x2a, 0x95, 0xd6, 0x0d, 0xd2, 0x54, 0xda, 0x04, 0x9b, 0xe9, 0xf9, 0x3d, 0xe1,
-	0xc6, 0xbc, 0x9c, 0xb2, 0x57, 0x9f, 0x19, 0xe4, 0xc8, 0x5e, 0xb3, 0x0f, 0x68, 0x14, 0x9b, 0x19,
-	0x8f, 0xc6, 0x03, 0xc8, 0xb0, 0xd4, 0x37, 0xf5, 0x09, 0x3d, 0x86, 0x6c, 0x94, 0xe6, 0x8c, 0x3c,
-	0x5e, 0xc0, 0x0e, 0xbb, 0x76, 0x32, 0xee, 0x68, 0x16, 0x31, 0x69, 0xe8, 0xcb, 0x3a, 0x31, 0x87,
-	0x2e, 0x99, 0x7b, 0xb0, 0x88, 0xed, 0xdf, 0x0e, 0xe4, 0x6e, 0x10, 0x32, 0xac, 0xc6, 0xa4, 0xc5,
-	0x97, 0xb0, 0xcb, 0x05, 0x76, 0xb8, 0xce, 0x88, 0xfc, 0x09, 0xfc, 0x87, 0x5e, 0x51, 0x2e, 0xe3,
-	0x0c, 0x2c, 0x53, 0x49, 0x2f, 0x7a, 0x97, 0xe9, 0xef, 0xaa, 0x6a, 0xbb, 0xcb, 0xd3, 0x3d, 0x1f,
-	0xa9, 0xbf, 0x04, 0x48, 0x16, 0x86, 0xde, 0x1b, 0x74, 0x37, 0x98, 0x60, 0x27, 0x7b, 0x66, 0x50,
-	0x05, 0x16, 0x7b, 0x0a, 0x39, 0x39, 0x75, 0x5e, 0xe2, 0x03, 0xde, 0x8d, 0xf1, 0x59, 0x92, 0x9e,
-	0xda, 0x0a, 0x05, 0x7c, 0xaa, 0xbc, 0xd5, 0x0c, 0x53, 0x66, 0xfa, 0x62, 0x1e, 0x56, 0x02, 0xeb,
-	0x68, 0x0d, 0x92, 0x4f, 0x8f, 0x1a, 0xc5, 0x2f, 0x9b, 0xe5, 0x97, 0x47, 0xf4, 0x5d, 0x5e, 0x87,
-	0x14, 0x5b, 0xa8, 0x3f, 0x2f, 0xd4, 0xcb, 0x8d, 0x75, 0x41, 0x7c, 0x04, 0xe0, 0xdd, 0x44, 0xb4,
-	0x09, 0x8b, 0xc4, 0xf8, 0x0e, 0xeb, 0x4e, 0x04, 0xd9, 0x0f, 0xfb, 0x64, 0xf6, 0x95, 0x0e, 0x6e,
-	0x5a, 0xda, 0x7b, 0x56, 0x2e, 0x2c, 0xca, 0xcb, 0xf6, 0x42, 0x5d, 0x7b, 0x8f, 0xc5, 0xdf, 0xe7,
-	0x60, 0xc7, 0x4e, 0x22, 0xe3, 0x41, 0xd2, 0xbc, 0xa4, 0xf7, 0x39, 0xa4, 0x5a, 0xc3, 0x66, 0x5f,
-	0x31, 0xb1, 0x4e, 0xdc, 0xed, 0x49, 0xe6, 0xff, 0x1d, 0xca, 0x77, 0x75, 0x62, 0x6a, 0x7a, 0x87,
-	0x25, 0x3c, 0x68, 0x0d, 0x6b, 0x54, 0xa1, 0xaa, 0xa2, 0xc7, 0x54, 0xdf, 0xff, 0x80, 0xdb, 0xfa,
-	0xff, 0x9b, 0x20, 0x4e, 0x72, 0xb2, 0xe5, 0xdb, 0x1e, 0xc6, 0xc3, 0xbb, 0x64, 0xf3, 0x93, 0xf1,
-	0xa8, 0xbb, 0x09, 0x26, 0x98, 0xdf, 0x16, 0x66, 0xca, 0x6f, 0xbf, 0x0a, 0xb0, 0xcb, 0x0d, 0x97,
-	0x73, 0x1a, 0x1f, 0x02, 0x3d, 0xba, 0xda, 0x28, 0xf7, 0x9e, 0x79, 0x1e, 0x5d, 0xf9, 0x0b, 0x49,
-	0xc1, 0x2f, 0x60, 0x87, 0xe5, 0xbc, 0x7f, 0x20, 0x3b, 0x70, 0x81, 0xcf, 0x77, 0x11, 0x3f, 0x85,
-	0x1d, 0x96, 0x1e, 0x67, 0x49, 0x0f, 0x2f, 0x61, 0x97, 0xab, 0x7c, 0x3e, 0x5a, 0x0f, 0x21, 0xf1,
-	0x95, 0xa1, 0xe9, 0x0d, 0x7a, 0x8b, 0xa2, 0xef, 0xd6, 0x16, 0x2c, 0xd1, 0x6a, 0x60, 0x48, 0x37,
-	0x6b, 0x5e, 0x76, 0x7e, 0x89, 0xaf, 0x60, 0x8b, 0x65, 0xd2, 0x11, 0x80, 0xeb, 0xc9, 0x17, 0x00,
-	0x6f, 0x0c, 0x4d, 0x6f, 0x7a, 0x60, 0xc9, 0xfc, 0x7f, 0x79, 0x5b, 0xec, 0x69, 0x27, 0xde, 0xb8,
-	0xff, 0x14, 0x5f, 0xc3, 0x76, 0x08, 0xdb, 0x71, 0xf4, 0xfc, 0xe0, 0x77, 0xe0, 0x5f, 0x34, 0xd9,
-	0x86, 0x78, 0x47, 0xfa, 0x6f, 0xfb, 0x39, 0x2e, 0x7e, 0x61, 0x54, 0x24, 0xd8, 0x62, 0x1b, 0x3b,
-	0x21, 0x97, 0xd7, 0xb0, 0x1d, 0x92, 0xbf, 0x30, 0x32, 0x8f, 0x60, 0xab, 0x66, 0x0e, 0x74, 0x0f,
-	0x7b, 0x94, 0x1e, 0xaf, 0xc1, 0x6a, 0x44, 0x41, 0x38, 0x2f, 0xaf, 0x60, 0x7f, 0xc5, 0x27, 0x66,
-	0x60, 0x3b, 0x04, 0xc0, 0xd8, 0xe5, 0xff, 0xd8, 0x82, 0x44, 0x49, 0x21, 0x4a, 0xdd, 0x36, 0x8f,
-	0x34, 0x48, 0xf9, 0x87, 0x0a, 0xe8, 0x16, 0x8f, 0x67, 0xc4, 0xfc, 0x22, 0x7b, 0x7b, 0x32, 0x61,
-	0x27, 0x2c, 0x6d, 0x48, 0xfa, 0x66, 0x07, 0xe8, 0x26, 0x4f, 0x39, 0x3c, 0x9e, 0xc8, 0xde, 0x9a,
-	0x48, 0xd6, 0xb3, 0xe3, 0x1b, 0x24, 0xf0, 0xed, 0x84, 0x67, 0x10, 0x7c, 0x3b, 0x51, 0x93, 0x09,
-	0x0d, 0x52, 0xfe, 0x21, 0x01, 0x3f, 0x74, 0x11, 0xf3, 0x08, 0x7e, 0xe8, 0x22, 0xe7, 0x0e, 0x1a,
-	0xa4, 0xfc, 0x43, 0x00, 0xbe, 0xa9, 0x88, 0x79, 0x03, 0xdf, 0x54, 0xe4, 0x5c, 0x41, 0x83, 0x94,
-	0xbf, 0xe3, 0xe6, 0x9b, 0x8a, 0xe8, 0xf5, 0xf9, 0xa6, 0x22, 0x9b, 0xf8, 0x0f, 0x80, 0xc2, 0x0d,
-	0x1d, 0x3a, 0x88, 0x3f, 0x54, 0x11, 0xd5, 0x70, 0x36, 0x3f, 0x8d, 0x8a, 0x63, 0xfc, 0x1d, 0x5c,
-	0x09, 0xb5, 0x71, 0x68, 0x3f, 0xf6, 0x9c, 0x45, 0x99, 0x3e, 0x98, 0x42, 0xc3, 0xb3, 0x1c, 0x6a,
-	0xa4, 0xf8, 0x96, 0x79, 0xdd, 0x21, 0xdf, 0x32, 0xbf, 0x4b, 0xfb, 0x00, 0x28, 0xdc, 0xa0, 0xf0,
-	0x03, 0xce, 0x6d, 0xad, 0xf8, 0x01, 0x8f, 0xe9, 0x7f, 0x3e, 0x00, 0x0a, 0x77, 0x25, 0x7c, 0xe3,
-	0xdc, 0xde, 0x87, 0x6f, 0x3c, 0xa6, 0xe9, 0x19, 0xc0, 0xfa, 0xf8, 0xf4, 0x04, 0xe5, 0x78, 0x38,
-	0x9c, 0x39, 0x4e, 0x76, 0x7f, 0x72, 0x05, 0xcf, 0x6c, 0x65, 0x62, 0xb3, 0x95, 0x69, 0xcd, 0x72,
-	0x67, 0x37, 0x3f, 0x09, 0xee, 0xa3, 0x1d, 0xaa, 0x36, 0xd0, 0x61, 0xfc, 0x5d, 0xe1, 0xd5, 0x44,
-	0xd9, 0xfb, 0x53, 0xeb, 0x39, 0x64, 0x7e, 0x14, 0x9c, 0x57, 0x3b, 0xcc, 0xe5, 0x5e, 0xec, 0xe5,
-	0xe1, 0x52, 0x39, 0x9c, 0x56, 0xcd, 0x17, 0x16, 0x4e, 0x39, 0xcd, 0x0f, 0x4b, 0x7c, 0xbb, 0xc2,
-	0x0f, 0xcb, 0x59, 0x75, 0xbb, 0x4d, 0x86, 0x53, 0xe0, 0xf2, 0xc9, 0xc4, 0x97, 0xda, 0x7c, 0x32,
-	0x67, 0x55, 0xd2, 0x36, 0x19, 0x4e, 0x59, 0xcb, 0x27, 0x13, 0x5f, 0x44, 0xf3, 0xc9, 0x9c, 0x55,
-	0x3f, 0x9b, 0xb0, 0x36, 0x56, 0x71, 0x22, 0x29, 0xfe, 0xf0, 0x8d, 0x97, 0x6c, 0xd9, 0xdc, 0xc4,
-	0xf2, 0x8e, 0x4d, 0x03, 0x56, 0x83, 0x95, 0x25, 0xba, 0x13, 0x7b, 0xc8, 0x42, 0x16, 0xa5, 0x49,
-	0xc5, 0x3d, 0x27, 0xc7, 0xca, 0x47, 0xbe, 0x93, 0xd1, 0x75, 0x29, 0xdf, 0x49, 0x5e, 0x5d, 0x6a,
-	0xc2, 0xda, 0x58, 0x51, 0xc8, 0xb7, 0x19, 0x5d, 0x7e, 0xf2, 0x6d, 0x72, 0xaa, 0x4d, 0xf4, 0x0a,
-	0x12, 0x45, 0x43, 0x6f, 0x6b, 0x9d, 0x81, 0x89, 0xd1, 0xb5, 0x60, 0x2b, 0xe4, 0xfc, 0xaf, 0xda,
-	0xe8, 0xbb, 0x6b, 0xe4, 0xfa, 0x59, 0x62, 0xa3, 0x42, 0x6f, 0xa5, 0x82, 0x49, 0x8d, 0x7e, 0xae,
-	0xea, 0x6d, 0x03, 0x7d, 0x14, 0xa9, 0x18, 0x90, 0x71, 0x6d, 0xdc, 0x9c, 0x44, 0x94, 0xd9, 0x29,
-	0x24, 0x5f, 0x25, 0x46, 0x8e, 0xd6, 0x2e, 0xd5, 0x84, 0xda, 0x5c, 0x6b, 0x89, 0xf6, 0xff, 0x1f,
-	0xff, 0x1d, 0x00, 0x00, 0xff, 0xff, 0xae, 0x80, 0xda, 0x59, 0x8f, 0x1c, 0x00, 0x00,
+func init() { proto.RegisterFile("datastore.proto", fileDescriptor_datastore_3748d7ab0cc36356) }
+
+var fileDescriptor_datastore_3748d7ab0cc36356 = []byte{
+	// 2144 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x5a, 0x5f, 0x77, 0xdb, 0x48,
+	0x15, 0xaf, 0x9c, 0x3f, 0x8d, 0xaf, 0xf3, 0xc7, 0x3b, 0x4d, 0x13, 0x47, 0xa5, 0x69, 0xd1, 0xd2,
+	0x52, 0x9a, 0xac, 0x9d, 0x98, 0x6e, 0x03, 0x2c, 0x74, 0xf1, 0xbf, 0x0d, 0xde, 0x6d, 0x93, 0x1c,
+	0xd9, 0x61, 0x4b, 0x17, 0xf0, 0x91, 0xad, 0x89, 0xa3, 0xad, 0x2d, 0x79, 0xa5, 0x71, 0xa9, 0x97,
+	0x0f, 0xc0, 0x81, 0xc3, 0x0b, 0xef, 0xec, 0x81, 0x37, 0x3e, 0x01, 0xef, 0x7c, 0x16, 0x3e, 0x09,
+	0x47, 0x33, 0x23, 0x4b, 0xb2, 0x34, 0x8e, 0xec, 0x84, 0xa7, 0x66, 0xae, 0xee, 0xef, 0xfe, 0xee,
+	0x9d, 0xb9, 0x73, 0x67, 0xe6, 0xba, 0xb0, 0xa1, 0x6b, 0x44, 0x73, 0x88, 0x65, 0xe3, 0xfc, 0xc0,
+	0xb6, 0x88, 0x85, 0xb6, 0x9c, 0x81, 0x61, 0xe3, 0xbc, 0x83, 0xed, 0x77, 0xd8, 0xce, 0x8f, 0xbf,
+	0xca, 0xbb, 0x5d, 0xcb, 0xea, 0xf6, 0x70, 0x81, 0x6a, 0xb5, 0x87, 0x17, 0x85, 0x3f, 0xd8, 0xda,
+	0x60, 0x80, 0x6d, 0x87, 0xe1, 0xe4, 0x9f, 0x74, 0x0d, 0x72, 0x39, 0x6c, 0xe7, 0x3b, 0x56, 0xbf,
+	0xe0, 0x0c, 0x8c, 0x8b, 0x0b, 0x5c, 0xa0, 0x96, 0x18, 0xa0, 0xd0, 0xb1, 0xfa, 0x7d, 0xcb, 0x2c,
+	0x0c, 0x7a, 0xc3, 0xae, 0xe1, 0xfd, 0xc3, 0x91, 0x87, 0x89, 0x90, 0xec, 0x1f, 0x06, 0x51, 0x2a,
+	0x70, 0xa7, 0x62, 0x63, 0x8d, 0xe0, 0xf2, 0xd0, 0xd4, 0x7b, 0x58, 0xc5, 0xdf, 0x0c, 0xb1, 0x43,
+	0xd0, 0x3e, 0x2c, 0xb7, 0xa9, 0x20, 0x27, 0x3d, 0x94, 0x9e, 0x64, 0x8a, 0x9b, 0x79, 0x16, 0x0c,
+	0xc7, 0x72, 0x65, 0xae, 0xa3, 0x54, 0x61, 0x33, 0x6c, 0xc4, 0x19, 0x58, 0xa6, 0x83, 0x67, 0xb4,
+	0xd2, 0x01, 0xf4, 0x19, 0x26, 0x9d, 0xcb, 0xb0, 0x27, 0x8f, 0x61, 0x83, 0xd8, 0x43, 0x87, 0xb4,
+	0x74, 0xab, 0xaf, 0x19, 0x66, 0xcb, 0xd0, 0xa9, 0xb1, 0xb4, 0xba, 0x46, 0xc5, 0x55, 0x2a, 0xad,
+	0xeb, 0xe8, 0x11, 0xac, 0x13, 0xab, 0x87, 0x6d, 0x8d, 0xe0, 0x96, 0x43, 0xb4, 0x1e, 0xce, 0xa5,
+	0x1e, 0x4a, 0x4f, 0x56, 0xd4, 0x35, 0x4f, 0xda, 0x70, 0x85, 0x6e, 0xbc, 0x21, 0x92, 0xb9, 0x3c,
+	0xdd, 0x04, 0xf4, 0xd2, 0x70, 0x08, 0x93, 0x3a, 0xdc, 0x53, 0xa5, 0x06, 0x77, 0x42, 0x52, 0x6e,
+	0x3a, 0x0f, 0xb7, 0x19, 0xcc, 0xc9, 0x49, 0x0f, 0x17, 0x84, 0xb6, 0x3d, 0x25, 0xd7, 0xc3, 0xf3,
+	0x81, 0x7e, 0xfd, 0x15, 0x09, 0x1b, 0x99, 0x2b, 0xce, 0x0a, 0xdc, 0x29, 0x0d, 0x06, 0xd8, 0xd4,
+	0xaf, 0xe9, 0x4a, 0xd8, 0xc8, 0x5c, 0xae, 0xfc, 0x5b, 0x82, 0x3b, 0x55, 0xdc, 0xc3, 0x04, 0xcf,
+	0x97, 0x1e, 0x55, 0x58, 0xec, 0x5b, 0x3a, 0x4b, 0x8a, 0xf5, 0xe2, 0x41, 0x3e, 0x7e, 0x6f, 0xe6,
+	0x63, 0x28, 0xf2, 0xaf, 0x2c, 0x1d, 0xab, 0x14, 0xad, 0x1c, 0xc0, 0xa2, 0x3b, 0x42, 0xab, 0xb0,
+	0xa2, 0xd6, 0x1a, 0x4d, 0xb5, 0x5e, 0x69, 0x66, 0x6f, 0x21, 0x80, 0xe5, 0x6a, 0xed, 0x65, 0xad,
+	0x59, 0xcb, 0x4a, 0x68, 0x1d, 0xa0, 0x5a, 0x6f, 0x34, 0x4e, 0x2b, 0xf5, 0x52, 0xb3, 0x96, 0x4d,
+	0xb9, 0xd1, 0x87, 0x6d, 0xce, 0x15, 0x7d, 0x1b, 0xd6, 0x4e, 0x2c, 0x1d, 0x37, 0x70, 0x0f, 0x77,
+	0x88, 0x65, 0x3b, 0xe8, 0x1e, 0xa4, 0xd9, 0x06, 0xf7, 0x03, 0x5e, 0x61, 0x82, 0xba, 0x8e, 0x9e,
+	0x41, 0xda, 0xf1, 0x34, 0x73, 0x29, 0x9a, 0x73, 0x5b, 0x61, 0xf3, 0x9e, 0x21, 0xd5, 0x57, 0x54,
+	0x7e, 0x0f, 0xdb, 0x0d, 0x4c, 0x42, 0x34, 0xde, 0x24, 0x57, 0x82, 0x06, 0x99, 0xbf, 0x8f, 0x44,
+	0x33, 0x18, 0x36, 0x10, 0xb0, 0x2f, 0x43, 0x2e, 0x6a, 0x9f, 0xcd, 0x86, 0xf2, 0x3b, 0xd8, 0x3e,
+	0x16, 0x70, 0x4f, 0x8d, 0x34, 0xe1, 0xa6, 0x6f, 0x41, 0xee, 0x58, 0x40, 0x7d, 0x33, 0xb1, 0x7d,
+	0x01, 0x3b, 0xac, 0x00, 0x96, 0x08, 0xc1, 0x0e, 0xc1, 0xba, 0xab, 0xe9, 0x45, 0x90, 0x87, 0x45,
+	0xd3, 0x4d, 0x3d, 0x66, 0x5c, 0x0e, 0xaf, 0x44, 0x08, 0x40, 0xf5, 0x94, 0x97, 0x20, 0xc7, 0x19,
+	0x1b, 0x97, 0x93, 0xd9, 0xac, 0x1d, 0x41, 0x8e, 0x16, 0xbc, 0x38, 0xcf, 0xa6, 0xcd, 0xad, 0x1b,
+	0x53, 0x0c, 0x70, 0x4e, 0x2f, 0xfe, 0x25, 0x41, 0xce, 0x2d, 0x8e, 0xc1, 0x4f, 0xe3, 0x25, 0x3e,
+	0x86, 0x0f, 0xda, 0xa3, 0x16, 0x7e, 0xef, 0xda, 0x70, 0x5a, 0x6d, 0x7c, 0x61, 0xd9, 0x9e, 0xe5,
+	0x7b, 0x79, 0x76, 0x58, 0xe6, 0xbd, 0xc3, 0x32, 0x5f, 0x37, 0xc9, 0xf3, 0x67, 0xbf, 0xd6, 0x7a,
+	0x43, 0xac, 0x6e, 0xb4, 0x47, 0x35, 0x06, 0x2a, 0x53, 0x0c, 0x2a, 0x03, 0x0c, 0xb4, 0xae, 0x61,
+	0x6a, 0xc4, 0xb0, 0x4c, 0x9a, 0x0a, 0x99, 0xa2, 0x22, 0x5a, 0xcc, 0xb3, 0xb1, 0xa6, 0x1a, 0x40,
+	0x29, 0x7f, 0x93, 0x60, 0x27, 0xc6, 0x53, 0x1e, 0xf7, 0x01, 0x2c, 0xb9, 0xf1, 0x78, 0xa5, 0x7c,
+	0x5a, 0xe0, 0x4c, 0xf1, 0x46, 0x7c, 0xfa, 0xab, 0x04, 0x3b, 0xac, 0x9c, 0xcf, 0xba, 0x8a, 0x68,
+	0x1f, 0x50, 0x07, 0xdb, 0xa4, 0xe5, 0x60, 0xdb, 0xd0, 0x7a, 0x2d, 0x73, 0xd8, 0x6f, 0x63, 0x9b,
+	0xba, 0x91, 0x56, 0xb3, 0xee, 0x97, 0x06, 0xfd, 0x70, 0x42, 0xe5, 0xe8, 0x07, 0xb0, 0x4e, 0xb5,
+	0x4d, 0x8b, 0xb4, 0xb4, 0x0b, 0x82, 0xed, 0xdc, 0xc2, 0x43, 0xe9, 0xc9, 0x82, 0xba, 0xea, 0x4a,
+	0x4f, 0x2c, 0x52, 0x72, 0x65, 0x6e, 0x82, 0xc6, 0x79, 0x33, 0x67, 0x6a, 0xfc, 0x59, 0x82, 0x1d,
+	0x56, 0x22, 0x67, 0x0e, 0xee, 0x14, 0xee, 0xb6, 0x47, 0x2d, 0x41, 0x7c, 0x99, 0xe2, 0xf7, 0x22,
+	0xc9, 0xd3, 0x20, 0xb6, 0x61, 0x76, 0x59, 0xf6, 0xa0, 0xf6, 0xa8, 0x32, 0x11, 0xbf, 0x1b, 0x59,
+	0x9c, 0x2b, 0x73, 0x46, 0xf6, 0x25, 0xec, 0xb2, 0x8d, 0xac, 0xe2, 0xae, 0xe1, 0x10, 0x9b, 0x2e,
+	0x66, 0xcd, 0x24, 0xf6, 0xc8, 0x8b, 0xee, 0x63, 0x58, 0xc2, 0xee, 0x98, 0x9b, 0x7c, 0x10, 0x36,
+	0x19, 0x85, 0x31, 0x6d, 0xe5, 0x35, 0x3c, 0x10, 0x1a, 0xe6, 0xbe, 0xce, 0x69, 0xf9, 0x67, 0x70,
+	0x9f, 0x6e, 0x7a, 0xa1, 0xc7, 0x3b, 0xb0, 0x42, 0x35, 0xfd, 0xe5, 0xb8, 0x4d, 0xc7, 0x75, 0xdd,
+	0x0d, 0x57, 0x84, 0xbd, 0x9e, 0x53, 0xff, 0x91, 0x20, 0x53, 0x1e, 0xf9, 0x87, 0xdf, 0xb3, 0x70,
+	0xc9, 0x4e, 0x76, 0xbe, 0xa1, 0x63, 0x58, 0xea, 0x6b, 0xa4, 0x73, 0xc9, 0xaf, 0x00, 0x87, 0xa2,
+	0x3d, 0x18, 0x60, 0xca, 0xbf, 0x72, 0x01, 0x65, 0x7c, 0xa9, 0xbd, 0x33, 0x2c, 0x5b, 0x65, 0x78,
+	0xa5, 0x08, 0x6b, 0x21, 0x39, 0xda, 0x80, 0xcc, 0xab, 0x52, 0xb3, 0xf2, 0xab, 0x56, 0xed, 0x75,
+	0x89, 0x5e, 0x08, 0xb2, 0xb0, 0xca, 0x04, 0x8d, 0xf3, 0x72, 0xa3, 0xd6, 0xcc, 0x4a, 0xca, 0xa7,
+	0x00, 0xfe, 0xde, 0x46, 0x9b, 0xb0, 0x44, 0xac, 0xb7, 0xd8, 0xe4, 0x33, 0xc8, 0x06, 0x6e, 0xaa,
+	0x0f, 0xb4, 0x2e, 0x6e, 0x39, 0xc6, 0xb7, 0xec, 0x1c, 0x5b, 0x52, 0x57, 0x5c, 0x41, 0xc3, 0xf8,
+	0x16, 0x2b, 0x7f, 0x5f, 0x80, 0x5d, 0xb7, 0x2c, 0x4d, 0x4e, 0x92, 0xe1, 0x97, 0xd1, 0x17, 0xb0,
+	0xda, 0x1e, 0xb5, 0x06, 0x9a, 0x8d, 0x4d, 0xe2, 0x2d, 0xcf, 0x55, 0x9b, 0x00, 0xda, 0xa3, 0x33,
+	0x0a, 0xa8, 0xeb, 0xe8, 0x33, 0x8a, 0x0f, 0xde, 0x1c, 0x5c, 0xfc, 0x87, 0x09, 0xe6, 0x49, 0xcd,
+	0xb4, 0xfd, 0x01, 0xf7, 0xc3, 0xdf, 0xb5, 0x0b, 0xc9, 0xfc, 0x68, 0x78, 0xbb, 0x3a, 0x5c, 0x31,
+	0x17, 0xe7, 0xa9, 0x98, 0xf1, 0x47, 0xca, 0xd2, 0x1c, 0x47, 0x4a, 0xf4, 0x86, 0xb1, 0x1c, 0x77,
+	0xc3, 0xf8, 0xa7, 0x04, 0x0f, 0x84, 0xcb, 0xc3, 0xb3, 0xff, 0xa7, 0x40, 0xb7, 0x8a, 0x31, 0x3e,
+	0x3d, 0xae, 0xcc, 0x7f, 0x4f, 0xff, 0x46, 0x0e, 0x91, 0x2f, 0x61, 0x97, 0x55, 0xed, 0xff, 0x43,
+	0x35, 0x12, 0x1a, 0xbe, 0xde, 0xc6, 0xff, 0x04, 0x76, 0x59, 0x39, 0x9e, 0xa7, 0x1c, 0xbd, 0x86,
+	0x07, 0x42, 0xf0, 0xf5, 0xdc, 0x6a, 0xc3, 0xa3, 0xb2, 0x5b, 0x00, 0xe2, 0x6b, 0x70, 0x60, 0x47,
+	0xce, 0xbf, 0xe2, 0x4a, 0x07, 0x1e, 0x5f, 0xc5, 0x71, 0xed, 0xb4, 0x1a, 0x07, 0x12, 0xbf, 0x7c,
+	0x37, 0x1b, 0xc8, 0x14, 0x8e, 0xeb, 0x07, 0x52, 0xe5, 0x81, 0xc4, 0x2f, 0x78, 0x20, 0x90, 0x7b,
+	0x90, 0xf6, 0xf2, 0x85, 0xb1, 0xa4, 0xd5, 0x15, 0x9e, 0x30, 0xbe, 0xab, 0x53, 0xac, 0x5c, 0xdf,
+	0xd5, 0x4b, 0x48, 0x7f, 0x6e, 0x19, 0x66, 0x93, 0x96, 0xfc, 0xf8, 0x83, 0x60, 0x0b, 0x96, 0x69,
+	0xe5, 0x1a, 0xd1, 0x9d, 0xbe, 0xa0, 0xf2, 0x91, 0x9b, 0xec, 0x7d, 0xed, 0x7d, 0x6b, 0xe8, 0x60,
+	0x87, 0x16, 0xd5, 0x25, 0xf5, 0x76, 0x5f, 0x7b, 0x7f, 0xee, 0x60, 0x07, 0x21, 0x58, 0xa4, 0xe2,
+	0x45, 0x2a, 0xa6, 0x7f, 0x2b, 0x6f, 0x60, 0x8b, 0x65, 0xcf, 0x98, 0xcf, 0x9b, 0x85, 0x5f, 0x02,
+	0x7c, 0x6d, 0x19, 0x66, 0xcb, 0xe7, 0xce, 0x14, 0xbf, 0x2f, 0x2a, 0x27, 0x3e, 0x3a, 0xfd, 0xb5,
+	0xf7, 0xa7, 0xf2, 0x15, 0x6c, 0x47, 0x6c, 0xf3, 0xb9, 0xb9, 0xbe, 0xf1, 0x8f, 0xe0, 0x2e, 0xbd,
+	0x48, 0x44, 0xfc, 0x8e, 0x9d, 0x2e, 0x37, 0xce, 0x49, 0xf5, 0x1b, 0x73, 0x65, 0x1b, 0xee, 0xba,
+	0x65, 0x7d, 0xfc, 0x6d, 0xdc, 0xec, 0xf9, 0x2d, 0x6c, 0x4d, 0x7e, 0xe0, 0xa4, 0x65, 0xc8, 0xf8,
+	0xa4, 0x5e, 0x7e, 0x24, 0x60, 0x85, 0x31, 0xab, 0xa3, 0xec, 0xc1, 0x9d, 0x73, 0x07, 0x27, 0x8c,
+	0xff, 0x35, 0x6c, 0x86, 0x95, 0x6f, 0x2c, 0xfa, 0x3c, 0x6c, 0xb1, 0xbd, 0x90, 0xd0, 0x93, 0xaf,
+	0x60, 0x3b, 0xa2, 0x7f, 0x63, 0xce, 0x7c, 0x0a, 0x5b, 0x67, 0xf6, 0xd0, 0xc4, 0x91, 0xb5, 0x70,
+	0xcf, 0xe8, 0x98, 0xc7, 0xe3, 0x82, 0xba, 0x86, 0x83, 0x47, 0xb9, 0xb2, 0x03, 0xdb, 0x11, 0x03,
+	0xbc, 0xff, 0xf0, 0x5d, 0x0a, 0x32, 0x95, 0x4b, 0xcd, 0xec, 0xe2, 0xda, 0x3b, 0x6c, 0x12, 0xb4,
+	0x0e, 0x29, 0x7e, 0xa0, 0x2c, 0xaa, 0x29, 0x43, 0x47, 0x3f, 0x87, 0xc5, 0xb7, 0x86, 0xa9, 0xf3,
+	0xab, 0xe3, 0x13, 0x91, 0xdf, 0x01, 0x13, 0xf9, 0x2f, 0x0c, 0x53, 0x57, 0x29, 0x0a, 0x3d, 0x87,
+	0x94, 0x35, 0xa0, 0x3b, 0x76, 0xbd, 0xf8, 0x38, 0x09, 0xf6, 0x74, 0xa0, 0xa6, 0xac, 0x01, 0xca,
+	0xc2, 0xc2, 0x5b, 0x3c, 0xa2, 0x7b, 0x3a, 0xad, 0xba, 0x7f, 0xa2, 0xfb, 0x00, 0x1d, 0xba, 0xed,
+	0xf4, 0x96, 0x46, 0xe8, 0x7d, 0x66, 0x41, 0x4d, 0x73, 0x49, 0x89, 0x28, 0x4f, 0x61, 0xd1, 0xa5,
+	0x45, 0x5b, 0x80, 0xd4, 0xda, 0x71, 0xbd, 0xd1, 0x54, 0x4b, 0xcd, 0xfa, 0xe9, 0x49, 0xab, 0x76,
+	0xd2, 0x54, 0x7f, 0xc3, 0x1a, 0x55, 0xe5, 0xf3, 0x93, 0xea, 0xcb, 0x5a, 0x56, 0x52, 0xf6, 0x20,
+	0x75, 0x3a, 0x40, 0x19, 0xb8, 0x5d, 0x51, 0x6b, 0xa5, 0x66, 0xad, 0x9a, 0xbd, 0xe5, 0x0e, 0xce,
+	0xcf, 0xaa, 0x74, 0x20, 0xb9, 0x03, 0xd6, 0xd4, 0xaa, 0x66, 0x53, 0xca, 0xe7, 0xb0, 0xed, 0x66,
+	0x7b, 0xc0, 0x47, 0x27, 0x70, 0x02, 0xd3, 0x97, 0x62, 0x6b, 0x3c, 0x61, 0xb7, 0xe9, 0xb8, 0xae,
+	0xbb, 0x49, 0xd2, 0x33, 0xfa, 0x06, 0xe1, 0x97, 0x59, 0x36, 0x50, 0xbe, 0xe3, 0xad, 0x80, 0xb0,
+	0x31, 0x9e, 0x26, 0x9f, 0xc0, 0x32, 0xa6, 0x12, 0xbe, 0x6f, 0x3e, 0x4c, 0x30, 0x5d, 0x2a, 0x87,
+	0xb8, 0xc5, 0xbd, 0xa7, 0x11, 0xec, 0xd0, 0xdb, 0x6f, 0x8a, 0xfa, 0xb2, 0xc2, 0x04, 0x75, 0x1d,
+	0xfd, 0x10, 0x36, 0x6c, 0xec, 0x8c, 0xcc, 0x4e, 0xcb, 0xc6, 0xdf, 0x0c, 0x0d, 0x1b, 0xb3, 0x8b,
+	0xe9, 0x8a, 0xba, 0xce, 0xc4, 0x2a, 0x97, 0x2a, 0x25, 0xc8, 0xd1, 0x34, 0x89, 0x0b, 0xf6, 0x11,
+	0xac, 0x7b, 0xf3, 0x1f, 0xce, 0x34, 0x2e, 0xe5, 0x99, 0x76, 0x0f, 0x76, 0x62, 0x4c, 0xb0, 0x10,
+	0x8b, 0xff, 0xbd, 0x0f, 0xe9, 0xaa, 0x46, 0xb4, 0x86, 0x1b, 0x07, 0x32, 0x60, 0x35, 0xd8, 0x3a,
+	0x47, 0x7b, 0xc2, 0x80, 0xa3, 0x5d, 0x7a, 0x79, 0x3f, 0x99, 0x32, 0x9f, 0xdb, 0x0b, 0xc8, 0x04,
+	0x5a, 0xdf, 0xe8, 0xa9, 0x08, 0x1c, 0x6d, 0xc2, 0xcb, 0x7b, 0x89, 0x74, 0x7d, 0x9e, 0x40, 0x1f,
+	0x5c, 0xcc, 0x13, 0x6d, 0xa1, 0xcb, 0x7b, 0x89, 0x74, 0x39, 0x8f, 0x01, 0xab, 0xc1, 0x1e, 0xb7,
+	0x78, 0xea, 0x62, 0xda, 0xe9, 0xf2, 0x7e, 0x32, 0x65, 0x9f, 0x2a, 0xd8, 0xc3, 0x16, 0x53, 0xc5,
+	0xb4, 0xcb, 0xe5, 0xfd, 0x64, 0xca, 0x3e, 0x55, 0xb0, 0x61, 0x2c, 0xa6, 0x8a, 0x69, 0x55, 0xcb,
+	0xfb, 0xc9, 0x94, 0x39, 0xd5, 0x1f, 0x01, 0x45, 0x1b, 0x8d, 0xe8, 0x70, 0x7a, 0x52, 0xc5, 0x34,
+	0x69, 0xe4, 0xe2, 0x2c, 0x10, 0x4e, 0xfe, 0x1e, 0x3e, 0x88, 0xb4, 0x17, 0xd1, 0xc1, 0xd4, 0x3c,
+	0x8b, 0xa3, 0x3e, 0x9c, 0x01, 0xe1, 0x33, 0x47, 0x1a, 0x7c, 0x62, 0x66, 0x51, 0xd7, 0x52, 0x3e,
+	0x9c, 0x01, 0xe1, 0x4f, 0x78, 0xb4, 0x71, 0x26, 0x9e, 0x70, 0x61, 0xcb, 0x4f, 0x2e, 0xce, 0x02,
+	0xf1, 0xc9, 0xa3, 0xbd, 0x2d, 0x31, 0xb9, 0xb0, 0x25, 0x27, 0x17, 0x67, 0x81, 0x70, 0xf2, 0x21,
+	0x64, 0x27, 0x9b, 0xff, 0xa8, 0x20, 0xb2, 0x23, 0xf8, 0x19, 0x42, 0x3e, 0x48, 0x0e, 0xf0, 0x69,
+	0x8f, 0x13, 0xd3, 0x1e, 0xcf, 0x4a, 0x2b, 0xfc, 0x4d, 0xe1, 0x2f, 0x92, 0x77, 0x3d, 0x8e, 0x3c,
+	0x04, 0xd0, 0xf3, 0xe9, 0x7b, 0x45, 0xf4, 0xd2, 0x95, 0x8f, 0x66, 0xc6, 0x71, 0x67, 0xfe, 0x24,
+	0xf1, 0xfb, 0x71, 0xd4, 0x97, 0x8f, 0xa7, 0x6e, 0x1e, 0xa1, 0x2b, 0xcf, 0x67, 0x85, 0x05, 0xa6,
+	0x45, 0xd0, 0x24, 0x11, 0x4f, 0xcb, 0xf4, 0xa6, 0x97, 0x7c, 0x34, 0x33, 0x2e, 0xe0, 0x8c, 0xa0,
+	0x6d, 0x21, 0x76, 0x66, 0x7a, 0x03, 0x45, 0x3e, 0x9a, 0x19, 0x17, 0x70, 0x46, 0xd0, 0xac, 0x10,
+	0x3b, 0x33, 0xbd, 0x35, 0x22, 0x1f, 0xcd, 0x8c, 0xe3, 0xce, 0xfc, 0x43, 0x82, 0xdd, 0xe9, 0xbd,
+	0x07, 0xf4, 0x0b, 0x61, 0x53, 0x30, 0x49, 0x5f, 0x44, 0x7e, 0x31, 0x2f, 0x7c, 0xd2, 0x43, 0x61,
+	0x53, 0xe1, 0x0a, 0x0f, 0xaf, 0x6a, 0x78, 0xc8, 0x2f, 0xe6, 0x85, 0x4f, 0x7a, 0x28, 0xec, 0x25,
+	0x5c, 0xe1, 0xe1, 0x55, 0x9d, 0x0c, 0xf9, 0xc5, 0xbc, 0x70, 0xee, 0xa1, 0x0d, 0x1b, 0x13, 0x2f,
+	0x78, 0x94, 0x9f, 0x5e, 0x62, 0x26, 0x1f, 0x81, 0x72, 0x21, 0xb1, 0x3e, 0xe7, 0xb4, 0x60, 0x3d,
+	0xfc, 0x52, 0x47, 0x1f, 0x4d, 0x2d, 0x25, 0x11, 0xc6, 0x7c, 0x52, 0x75, 0x9f, 0x30, 0xfc, 0x4a,
+	0x17, 0x13, 0xc6, 0x3e, 0xf3, 0xe5, 0x7c, 0x52, 0xf5, 0xc0, 0x9d, 0x34, 0xf0, 0x16, 0x9f, 0x72,
+	0x27, 0x8d, 0x3e, 0xef, 0xe5, 0xfd, 0x64, 0xca, 0xfe, 0x02, 0x4e, 0x3c, 0xb6, 0xc5, 0x0b, 0x18,
+	0xff, 0x8a, 0x97, 0x0b, 0x89, 0xf5, 0x7d, 0xce, 0x89, 0x27, 0xb4, 0x98, 0x33, 0xfe, 0xb1, 0x2e,
+	0x17, 0x12, 0xeb, 0xfb, 0x67, 0xf8, 0xe4, 0x73, 0x51, 0x7c, 0x86, 0x0b, 0x5e, 0xa9, 0xf2, 0x41,
+	0x72, 0x80, 0x7f, 0x4b, 0x8c, 0xbc, 0xe1, 0xc4, 0xb7, 0x44, 0xd1, 0x8b, 0x51, 0x3e, 0x9c, 0x01,
+	0xc1, 0x99, 0xdf, 0x40, 0xba, 0x62, 0x99, 0x17, 0x46, 0x77, 0xe8, 0xfe, 0xfe, 0x10, 0x6e, 0x2c,
+	0xf2, 0xff, 0xee, 0x35, 0xfe, 0xee, 0xd1, 0x3c, 0xbe, 0x4a, 0x6d, 0xfc, 0x36, 0x5b, 0x3b, 0xc6,
+	0xe4, 0x8c, 0x7e, 0xae, 0x9b, 0x17, 0x16, 0xfa, 0x51, 0x2c, 0x30, 0xa4, 0xe3, 0x71, 0x3c, 0x4d,
+	0xa2, 0xca, 0x78, 0xca, 0x99, 0x37, 0xe9, 0x71, 0xa8, 0x67, 0xb7, 0xce, 0xa4, 0xb3, 0x54, 0x7b,
+	0x99, 0xfe, 0xde, 0xf2, 0xe3, 0xff, 0x0d, 0x00, 0xb4, 0xe7, 0x67, 0x14, 0x28, 0x27, 0x00, 0x00,
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/golang/protobuf/ptypes/empty"
 	go_plugin "github.com/hashicorp/go-plugin"
+	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/proto/common/plugin"
 	"google.golang.org/grpc"
 )
@@ -16,6 +17,7 @@ type KeyManager interface {
 	GetPublicKey(context.Context, *GetPublicKeyRequest) (*GetPublicKeyResponse, error)
 	GetPublicKeys(context.Context, *GetPublicKeysRequest) (*GetPublicKeysResponse, error)
 	SignData(context.Context, *SignDataRequest) (*SignDataResponse, error)
+	SignDataBatch(context.Context, *SignDataBatchRequest) (*SignDataBatchResponse, error)
 }
 
 // Plugin is the interface implemented by plugin implementations
@@ -24,6 +26,7 @@ type Plugin interface {
 	GetPublicKey(context.Context, *GetPublicKeyRequest) (*GetPublicKeyResponse, error)
 	GetPublicKeys(context.Context, *GetPublicKeysRequest) (*GetPublicKeysResponse, error)
 	SignData(context.Context, *SignDataRequest) (*SignDataResponse, error)
+	SignDataBatch(context.Context, *SignDataBatchRequest) (*SignDataBatchResponse, error)
 	Configure(context.Context, *plugin.ConfigureRequest) (*plugin.ConfigureResponse, error)
 	GetPluginInfo(context.Context, *plugin.GetPluginInfoRequest) (*plugin.GetPluginInfoResponse, error)
 }
@@ -40,6 +43,13 @@ func NewBuiltIn(plugin Plugin) *BuiltIn {
 	}
 }
 
+// SetLogger hands the logger to the plugin, if the plugin logs
+func (b BuiltIn) SetLogger(log logrus.FieldLogger) {
+	if p, ok := b.plugin.(interface{ SetLogger(logrus.FieldLogger) }); ok {
+		p.SetLogger(log)
+	}
+}
+
 func (b BuiltIn) GenerateKey(ctx context.Context, req *GenerateKeyRequest) (*GenerateKeyResponse, error) {
 	resp, err := b.plugin.GenerateKey(ctx, req)
 	if err != nil {
@@ -72,6 +82,14 @@ func (b BuiltIn) SignData(ctx context.Context, req *SignDataRequest) (*SignDataR
 	return resp, nil
 }
 
+func (b BuiltIn) SignDataBatch(ctx context.Context, req *SignDataBatchRequest) (*SignDataBatchResponse, error) {
+	resp, err := b.plugin.SignDataBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (b BuiltIn) Configure(ctx context.Context, req *plugin.ConfigureRequest) (*plugin.ConfigureResponse, error) {
 	resp, err := b.plugin.Configure(ctx, req)
 	if err != nil {
@@ -131,6 +149,9 @@ func (s *GRPCServer) GetPublicKeys(ctx context.Context, req *GetPublicKeysReques
 func (s *GRPCServer) SignData(ctx context.Context, req *SignDataRequest) (*SignDataResponse, error) {
 	return s.Plugin.SignData(ctx, req)
 }
+func (s *GRPCServer) SignDataBatch(ctx context.Context, req *SignDataBatchRequest) (*SignDataBatchResponse, error) {
+	return s.Plugin.SignDataBatch(ctx, req)
+}
 func (s *GRPCServer) Configure(ctx context.Context, req *plugin.ConfigureRequest) (*plugin.ConfigureResponse, error) {
 	return s.Plugin.Configure(ctx, req)
 }
@@ -154,6 +175,9 @@ func (c *GRPCClient) GetPublicKeys(ctx context.Context, req *GetPublicKeysReques
 func (c *GRPCClient) SignData(ctx context.Context, req *SignDataRequest) (*SignDataResponse, error) {
 	return c.client.SignData(ctx, req)
 }
+func (c *GRPCClient) SignDataBatch(ctx context.Context, req *SignDataBatchRequest) (*SignDataBatchResponse, error) {
+	return c.client.SignDataBatch(ctx, req)
+}
 func (c *GRPCClient) Configure(ctx context.Context, req *plugin.ConfigureRequest) (*plugin.ConfigureResponse, error) {
 	return c.client.Configure(ctx, req)
 }
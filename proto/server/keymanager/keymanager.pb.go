@@ -48,6 +48,7 @@ const (
 	KeyType_RSA_1024             KeyType = 3
 	KeyType_RSA_2048             KeyType = 4
 	KeyType_RSA_4096             KeyType = 5
+	KeyType_ED25519              KeyType = 6
 )
 
 var KeyType_name = map[int32]string{
@@ -57,6 +58,7 @@ var KeyType_name = map[int32]string{
 	3: "RSA_1024",
 	4: "RSA_2048",
 	5: "RSA_4096",
+	6: "ED25519",
 }
 var KeyType_value = map[string]int32{
 	"UNSPECIFIED_KEY_TYPE": 0,
@@ -65,13 +67,14 @@ var KeyType_value = map[string]int32{
 	"RSA_1024":             3,
 	"RSA_2048":             4,
 	"RSA_4096":             5,
+	"ED25519":              6,
 }
 
 func (x KeyType) String() string {
 	return proto.EnumName(KeyType_name, int32(x))
 }
 func (KeyType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{0}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{0}
 }
 
 type HashAlgorithm int32
@@ -123,7 +126,7 @@ func (x HashAlgorithm) String() string {
 	return proto.EnumName(HashAlgorithm_name, int32(x))
 }
 func (HashAlgorithm) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{1}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{1}
 }
 
 type PublicKey struct {
@@ -139,7 +142,7 @@ func (m *PublicKey) Reset()         { *m = PublicKey{} }
 func (m *PublicKey) String() string { return proto.CompactTextString(m) }
 func (*PublicKey) ProtoMessage()    {}
 func (*PublicKey) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{0}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{0}
 }
 func (m *PublicKey) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PublicKey.Unmarshal(m, b)
@@ -192,7 +195,7 @@ func (m *GenerateKeyRequest) Reset()         { *m = GenerateKeyRequest{} }
 func (m *GenerateKeyRequest) String() string { return proto.CompactTextString(m) }
 func (*GenerateKeyRequest) ProtoMessage()    {}
 func (*GenerateKeyRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{1}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{1}
 }
 func (m *GenerateKeyRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GenerateKeyRequest.Unmarshal(m, b)
@@ -237,7 +240,7 @@ func (m *GenerateKeyResponse) Reset()         { *m = GenerateKeyResponse{} }
 func (m *GenerateKeyResponse) String() string { return proto.CompactTextString(m) }
 func (*GenerateKeyResponse) ProtoMessage()    {}
 func (*GenerateKeyResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{2}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{2}
 }
 func (m *GenerateKeyResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GenerateKeyResponse.Unmarshal(m, b)
@@ -275,7 +278,7 @@ func (m *GetPublicKeyRequest) Reset()         { *m = GetPublicKeyRequest{} }
 func (m *GetPublicKeyRequest) String() string { return proto.CompactTextString(m) }
 func (*GetPublicKeyRequest) ProtoMessage()    {}
 func (*GetPublicKeyRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{3}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{3}
 }
 func (m *GetPublicKeyRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetPublicKeyRequest.Unmarshal(m, b)
@@ -313,7 +316,7 @@ func (m *GetPublicKeyResponse) Reset()         { *m = GetPublicKeyResponse{} }
 func (m *GetPublicKeyResponse) String() string { return proto.CompactTextString(m) }
 func (*GetPublicKeyResponse) ProtoMessage()    {}
 func (*GetPublicKeyResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{4}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{4}
 }
 func (m *GetPublicKeyResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetPublicKeyResponse.Unmarshal(m, b)
@@ -350,7 +353,7 @@ func (m *GetPublicKeysRequest) Reset()         { *m = GetPublicKeysRequest{} }
 func (m *GetPublicKeysRequest) String() string { return proto.CompactTextString(m) }
 func (*GetPublicKeysRequest) ProtoMessage()    {}
 func (*GetPublicKeysRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{5}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{5}
 }
 func (m *GetPublicKeysRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetPublicKeysRequest.Unmarshal(m, b)
@@ -381,7 +384,7 @@ func (m *GetPublicKeysResponse) Reset()         { *m = GetPublicKeysResponse{} }
 func (m *GetPublicKeysResponse) String() string { return proto.CompactTextString(m) }
 func (*GetPublicKeysResponse) ProtoMessage()    {}
 func (*GetPublicKeysResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{6}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{6}
 }
 func (m *GetPublicKeysResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetPublicKeysResponse.Unmarshal(m, b)
@@ -420,7 +423,7 @@ func (m *PSSOptions) Reset()         { *m = PSSOptions{} }
 func (m *PSSOptions) String() string { return proto.CompactTextString(m) }
 func (*PSSOptions) ProtoMessage()    {}
 func (*PSSOptions) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{7}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{7}
 }
 func (m *PSSOptions) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PSSOptions.Unmarshal(m, b)
@@ -470,7 +473,7 @@ func (m *SignDataRequest) Reset()         { *m = SignDataRequest{} }
 func (m *SignDataRequest) String() string { return proto.CompactTextString(m) }
 func (*SignDataRequest) ProtoMessage()    {}
 func (*SignDataRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{8}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{8}
 }
 func (m *SignDataRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SignDataRequest.Unmarshal(m, b)
@@ -621,7 +624,7 @@ func (m *SignDataResponse) Reset()         { *m = SignDataResponse{} }
 func (m *SignDataResponse) String() string { return proto.CompactTextString(m) }
 func (*SignDataResponse) ProtoMessage()    {}
 func (*SignDataResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keymanager_6ff2c2b415389f2b, []int{9}
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{9}
 }
 func (m *SignDataResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SignDataResponse.Unmarshal(m, b)
@@ -648,6 +651,83 @@ func (m *SignDataResponse) GetSignature() []byte {
 	return nil
 }
 
+type SignDataBatchRequest struct {
+	Requests             []*SignDataRequest `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *SignDataBatchRequest) Reset()         { *m = SignDataBatchRequest{} }
+func (m *SignDataBatchRequest) String() string { return proto.CompactTextString(m) }
+func (*SignDataBatchRequest) ProtoMessage()    {}
+func (*SignDataBatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{10}
+}
+func (m *SignDataBatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignDataBatchRequest.Unmarshal(m, b)
+}
+func (m *SignDataBatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignDataBatchRequest.Marshal(b, m, deterministic)
+}
+func (dst *SignDataBatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignDataBatchRequest.Merge(dst, src)
+}
+func (m *SignDataBatchRequest) XXX_Size() int {
+	return xxx_messageInfo_SignDataBatchRequest.Size(m)
+}
+func (m *SignDataBatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignDataBatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignDataBatchRequest proto.InternalMessageInfo
+
+func (m *SignDataBatchRequest) GetRequests() []*SignDataRequest {
+	if m != nil {
+		return m.Requests
+	}
+	return nil
+}
+
+type SignDataBatchResponse struct {
+	// One response for each request, in the same order
+	Responses            []*SignDataResponse `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *SignDataBatchResponse) Reset()         { *m = SignDataBatchResponse{} }
+func (m *SignDataBatchResponse) String() string { return proto.CompactTextString(m) }
+func (*SignDataBatchResponse) ProtoMessage()    {}
+func (*SignDataBatchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_keymanager_2311d18674b5a326, []int{11}
+}
+func (m *SignDataBatchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignDataBatchResponse.Unmarshal(m, b)
+}
+func (m *SignDataBatchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignDataBatchResponse.Marshal(b, m, deterministic)
+}
+func (dst *SignDataBatchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignDataBatchResponse.Merge(dst, src)
+}
+func (m *SignDataBatchResponse) XXX_Size() int {
+	return xxx_messageInfo_SignDataBatchResponse.Size(m)
+}
+func (m *SignDataBatchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignDataBatchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignDataBatchResponse proto.InternalMessageInfo
+
+func (m *SignDataBatchResponse) GetResponses() []*SignDataResponse {
+	if m != nil {
+		return m.Responses
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*PublicKey)(nil), "spire.server.keymanager.PublicKey")
 	proto.RegisterType((*GenerateKeyRequest)(nil), "spire.server.keymanager.GenerateKeyRequest")
@@ -659,6 +739,8 @@ func init() {
 	proto.RegisterType((*PSSOptions)(nil), "spire.server.keymanager.PSSOptions")
 	proto.RegisterType((*SignDataRequest)(nil), "spire.server.keymanager.SignDataRequest")
 	proto.RegisterType((*SignDataResponse)(nil), "spire.server.keymanager.SignDataResponse")
+	proto.RegisterType((*SignDataBatchRequest)(nil), "spire.server.keymanager.SignDataBatchRequest")
+	proto.RegisterType((*SignDataBatchResponse)(nil), "spire.server.keymanager.SignDataBatchResponse")
 	proto.RegisterEnum("spire.server.keymanager.KeyType", KeyType_name, KeyType_value)
 	proto.RegisterEnum("spire.server.keymanager.HashAlgorithm", HashAlgorithm_name, HashAlgorithm_value)
 }
@@ -683,6 +765,10 @@ type KeyManagerClient interface {
 	GetPublicKeys(ctx context.Context, in *GetPublicKeysRequest, opts ...grpc.CallOption) (*GetPublicKeysResponse, error)
 	// Signs data with private key
 	SignData(ctx context.Context, in *SignDataRequest, opts ...grpc.CallOption) (*SignDataResponse, error)
+	// Signs data for several requests at once. The batch fails if any
+	// request fails. The server signs each request with SignData instead
+	// if the plugin does not implement it.
+	SignDataBatch(ctx context.Context, in *SignDataBatchRequest, opts ...grpc.CallOption) (*SignDataBatchResponse, error)
 	// Applies the plugin configuration
 	Configure(ctx context.Context, in *plugin.ConfigureRequest, opts ...grpc.CallOption) (*plugin.ConfigureResponse, error)
 	// Returns the version and related metadata of the installed plugin
@@ -733,6 +819,15 @@ func (c *keyManagerClient) SignData(ctx context.Context, in *SignDataRequest, op
 	return out, nil
 }
 
+func (c *keyManagerClient) SignDataBatch(ctx context.Context, in *SignDataBatchRequest, opts ...grpc.CallOption) (*SignDataBatchResponse, error) {
+	out := new(SignDataBatchResponse)
+	err := c.cc.Invoke(ctx, "/spire.server.keymanager.KeyManager/SignDataBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *keyManagerClient) Configure(ctx context.Context, in *plugin.ConfigureRequest, opts ...grpc.CallOption) (*plugin.ConfigureResponse, error) {
 	out := new(plugin.ConfigureResponse)
 	err := c.cc.Invoke(ctx, "/spire.server.keymanager.KeyManager/Configure", in, out, opts...)
@@ -761,6 +856,10 @@ type KeyManagerServer interface {
 	GetPublicKeys(context.Context, *GetPublicKeysRequest) (*GetPublicKeysResponse, error)
 	// Signs data with private key
 	SignData(context.Context, *SignDataRequest) (*SignDataResponse, error)
+	// Signs data for several requests at once. The batch fails if any
+	// request fails. The server signs each request with SignData instead
+	// if the plugin does not implement it.
+	SignDataBatch(context.Context, *SignDataBatchRequest) (*SignDataBatchResponse, error)
 	// Applies the plugin configuration
 	Configure(context.Context, *plugin.ConfigureRequest) (*plugin.ConfigureResponse, error)
 	// Returns the version and related metadata of the installed plugin
@@ -843,6 +942,24 @@ func _KeyManager_SignData_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KeyManager_SignDataBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignDataBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyManagerServer).SignDataBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/spire.server.keymanager.KeyManager/SignDataBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyManagerServer).SignDataBatch(ctx, req.(*SignDataBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _KeyManager_Configure_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(plugin.ConfigureRequest)
 	if err := dec(in); err != nil {
@@ -899,6 +1016,10 @@ var _KeyManager_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SignData",
 			Handler:    _KeyManager_SignData_Handler,
 		},
+		{
+			MethodName: "SignDataBatch",
+			Handler:    _KeyManager_SignDataBatch_Handler,
+		},
 		{
 			MethodName: "Configure",
 			Handler:    _KeyManager_Configure_Handler,
@@ -912,57 +1033,61 @@ var _KeyManager_serviceDesc = grpc.ServiceDesc{
 	Metadata: "keymanager.proto",
 }
 
-func init() { proto.RegisterFile("keymanager.proto", fileDescriptor_keymanager_6ff2c2b415389f2b) }
-
-var fileDescriptor_keymanager_6ff2c2b415389f2b = []byte{
-	// 771 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x55, 0x6d, 0x6f, 0xda, 0x48,
-	0x10, 0xc6, 0x84, 0xd7, 0x31, 0x10, 0x6b, 0x2f, 0xb9, 0x43, 0xdc, 0xe9, 0x8a, 0x5c, 0x35, 0x22,
-	0x69, 0x6a, 0x88, 0x03, 0x28, 0x55, 0x3f, 0x11, 0x42, 0x02, 0x22, 0x69, 0x90, 0x49, 0xa5, 0x26,
-	0xaa, 0x64, 0x39, 0x61, 0xb1, 0x2d, 0xc0, 0x76, 0xbd, 0xa6, 0xaa, 0xa5, 0xfe, 0xaf, 0xfe, 0xa1,
-	0xfe, 0x88, 0x7e, 0xac, 0x6c, 0xaf, 0x79, 0x49, 0x4a, 0x42, 0xd5, 0x7e, 0xf2, 0xcc, 0xec, 0xf3,
-	0xcc, 0xb3, 0x33, 0xb3, 0xbb, 0x06, 0x6e, 0x84, 0xdd, 0x89, 0x62, 0x28, 0x2a, 0xb6, 0x05, 0xcb,
-	0x36, 0x1d, 0x13, 0xfd, 0x43, 0x2c, 0xdd, 0xc6, 0x02, 0xc1, 0xf6, 0x27, 0x6c, 0x0b, 0xf3, 0xe5,
-	0xc2, 0x91, 0xaa, 0x3b, 0xda, 0xf4, 0x56, 0xb8, 0x33, 0x27, 0x65, 0x62, 0xe9, 0xc3, 0x21, 0x2e,
-	0xfb, 0xd0, 0xb2, 0xcf, 0x2b, 0xdf, 0x99, 0x93, 0x89, 0x69, 0x94, 0xad, 0xf1, 0x54, 0xd5, 0xc3,
-	0x4f, 0x90, 0x92, 0x37, 0x20, 0xdd, 0x9b, 0xde, 0x8e, 0xf5, 0xbb, 0x2e, 0x76, 0x51, 0x0e, 0xa2,
-	0xfa, 0x20, 0xcf, 0x14, 0x99, 0x52, 0x5a, 0x8a, 0xea, 0x03, 0x54, 0x85, 0x98, 0xe3, 0x5a, 0x38,
-	0x1f, 0x2d, 0x32, 0xa5, 0x9c, 0x58, 0x14, 0x56, 0xc8, 0x0b, 0x5d, 0xec, 0x5e, 0xb9, 0x16, 0x96,
-	0x7c, 0x34, 0xfa, 0x17, 0xd2, 0xd6, 0x48, 0xff, 0x2c, 0x0f, 0x14, 0x47, 0xc9, 0x6f, 0x14, 0x99,
-	0x52, 0x46, 0x4a, 0x79, 0x81, 0x13, 0xc5, 0x51, 0x78, 0x0d, 0xd0, 0x19, 0x36, 0xb0, 0xad, 0x38,
-	0xb8, 0x8b, 0x5d, 0x09, 0x7f, 0x9c, 0x62, 0xe2, 0xa0, 0x6d, 0x48, 0x8c, 0xb0, 0x2b, 0xcf, 0xc4,
-	0xe3, 0x23, 0xec, 0x76, 0x06, 0xe8, 0x0d, 0xa4, 0xbc, 0xf0, 0x2f, 0xed, 0x21, 0x39, 0x0a, 0x0c,
-	0xfe, 0x3d, 0xfc, 0xb5, 0xa4, 0x44, 0x2c, 0xd3, 0x20, 0x18, 0x35, 0x00, 0x2c, 0xbf, 0x60, 0x79,
-	0x84, 0x5d, 0x5f, 0x8e, 0x15, 0xf9, 0x95, 0x59, 0x67, 0xbd, 0x91, 0xd2, 0x56, 0x68, 0xf2, 0xfb,
-	0x5e, 0x66, 0x67, 0xbe, 0xf4, 0x68, 0x11, 0xfc, 0x35, 0x6c, 0x2d, 0xa3, 0xff, 0xdc, 0x46, 0xfe,
-	0x5e, 0x4e, 0x4d, 0xe8, 0x4e, 0xf8, 0x0f, 0xb0, 0x7d, 0x2f, 0x4e, 0x35, 0x9b, 0xc0, 0xce, 0x35,
-	0x49, 0x9e, 0x29, 0x6e, 0xac, 0x29, 0x0a, 0x33, 0x51, 0xc2, 0x7f, 0x01, 0xe8, 0xf5, 0xfb, 0x97,
-	0x96, 0xa3, 0x9b, 0x06, 0x41, 0xcf, 0x80, 0x25, 0xca, 0xd8, 0x91, 0xc7, 0xd8, 0x50, 0x1d, 0xcd,
-	0xaf, 0x23, 0x2e, 0x81, 0x17, 0x3a, 0xf7, 0x23, 0xe8, 0x02, 0x72, 0x9a, 0x42, 0x34, 0x59, 0x19,
-	0xab, 0xa6, 0xad, 0x3b, 0xda, 0x84, 0x8e, 0x72, 0x67, 0xa5, 0x6c, 0x5b, 0x21, 0x5a, 0x23, 0x44,
-	0x4b, 0x59, 0x6d, 0xd1, 0xe5, 0xbf, 0x31, 0xb0, 0xd9, 0xd7, 0x55, 0xc3, 0x3b, 0x4d, 0x4f, 0x1c,
-	0x1f, 0x04, 0xb1, 0x85, 0x33, 0xe8, 0xdb, 0xe8, 0xf2, 0xf7, 0x76, 0xd3, 0x8e, 0xdc, 0xdb, 0x0f,
-	0x3a, 0x05, 0xd6, 0x22, 0x44, 0x36, 0x83, 0x76, 0xe4, 0x63, 0xfe, 0x1c, 0x9f, 0xaf, 0x6e, 0xe9,
-	0xac, 0x73, 0xed, 0x88, 0x04, 0x16, 0x21, 0xd4, 0x3b, 0xce, 0x02, 0x4b, 0x74, 0xd5, 0xc0, 0xb6,
-	0x97, 0x8a, 0xf0, 0x15, 0xe0, 0xe6, 0x55, 0xd2, 0xe9, 0xfd, 0x07, 0x69, 0x0f, 0xa2, 0x38, 0x53,
-	0x1b, 0xfb, 0x95, 0x66, 0xa4, 0x79, 0x60, 0x4f, 0x85, 0x24, 0xbd, 0x03, 0x28, 0x0f, 0x5b, 0xef,
-	0xde, 0xf6, 0x7b, 0xad, 0x66, 0xe7, 0xb4, 0xd3, 0x3a, 0x91, 0xbb, 0xad, 0x6b, 0xf9, 0xea, 0xba,
-	0xd7, 0xe2, 0x22, 0x88, 0x85, 0x64, 0xab, 0x29, 0xf7, 0xc4, 0x5a, 0x9d, 0x63, 0x42, 0xe7, 0xf0,
-	0xa8, 0xca, 0x45, 0x51, 0x06, 0x52, 0x52, 0xbf, 0x21, 0x1f, 0x54, 0xc4, 0x2a, 0xb7, 0x11, 0x7a,
-	0x62, 0xa5, 0x7a, 0xc4, 0xc5, 0x42, 0xaf, 0x5a, 0x79, 0x5d, 0xe7, 0xe2, 0x7b, 0x5f, 0x19, 0xc8,
-	0x2e, 0x35, 0x05, 0xfd, 0x0f, 0x85, 0x45, 0xbd, 0x76, 0xa3, 0xdf, 0x96, 0x1b, 0xe7, 0x67, 0x97,
-	0x52, 0xe7, 0xaa, 0x7d, 0xc1, 0x45, 0x10, 0x40, 0xa2, 0xdf, 0x6e, 0x88, 0x62, 0x95, 0x8b, 0x85,
-	0x76, 0xad, 0xce, 0xc5, 0xa9, 0xed, 0xe9, 0x27, 0xa8, 0x5d, 0x3b, 0x10, 0xb9, 0xa4, 0xa7, 0xe7,
-	0xc5, 0x65, 0x8f, 0x01, 0x73, 0xaf, 0x56, 0xe7, 0xd8, 0x99, 0xe7, 0xb1, 0x32, 0x33, 0xcf, 0xe3,
-	0x65, 0x51, 0x0e, 0x20, 0xc8, 0xe1, 0x33, 0x73, 0x8b, 0x7e, 0xad, 0xce, 0x6d, 0x8a, 0xdf, 0x63,
-	0x00, 0x5d, 0xec, 0x5e, 0x04, 0xb3, 0x40, 0x1a, 0xb0, 0x0b, 0x2f, 0x04, 0x7a, 0xb9, 0x72, 0x68,
-	0x0f, 0x5f, 0xac, 0xc2, 0xfe, 0x7a, 0x60, 0x3a, 0xb9, 0x11, 0x64, 0x16, 0x2f, 0x24, 0x7a, 0x8c,
-	0xfd, 0xe0, 0x61, 0x29, 0xbc, 0x5a, 0x13, 0x4d, 0xc5, 0x0c, 0xc8, 0x2e, 0xdd, 0x7e, 0xb4, 0x1e,
-	0x3f, 0x7c, 0x3d, 0x0a, 0xc2, 0xba, 0x70, 0xaa, 0x27, 0x43, 0x2a, 0x3c, 0xaa, 0xa8, 0xb4, 0x92,
-	0x7b, 0xef, 0xce, 0x16, 0x76, 0xd7, 0x40, 0x52, 0x81, 0x1b, 0x48, 0x37, 0x4d, 0x63, 0xa8, 0xab,
-	0x53, 0x1b, 0xa3, 0x17, 0x94, 0x17, 0xfc, 0xd3, 0x04, 0xfa, 0x33, 0x9b, 0xad, 0x87, 0xe9, 0x77,
-	0x9e, 0x82, 0xd1, 0xdc, 0xc3, 0xa0, 0x59, 0xfe, 0x72, 0xc7, 0x18, 0x9a, 0x68, 0xf7, 0xa7, 0xc4,
-	0x25, 0x4c, 0xa8, 0xb1, 0xb7, 0x0e, 0x34, 0xd0, 0x39, 0xce, 0xdc, 0xc0, 0xbc, 0xc4, 0x5e, 0xe4,
-	0x36, 0xe1, 0xff, 0x7e, 0x0f, 0x7f, 0x04, 0x00, 0x00, 0xff, 0xff, 0xc6, 0xd3, 0xf0, 0x11, 0xe5,
-	0x07, 0x00, 0x00,
+func init() { proto.RegisterFile("keymanager.proto", fileDescriptor_keymanager_2311d18674b5a326) }
+
+var fileDescriptor_keymanager_2311d18674b5a326 = []byte{
+	// 837 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x6d, 0x6f, 0xe2, 0x46,
+	0x10, 0xc6, 0x84, 0xd7, 0x31, 0x70, 0xd6, 0x36, 0x69, 0x11, 0xad, 0x5a, 0xe4, 0xaa, 0x27, 0x2e,
+	0xbd, 0x1a, 0xe2, 0x03, 0x94, 0x53, 0x3f, 0x11, 0xe0, 0x02, 0xe2, 0xd2, 0x20, 0x93, 0x4a, 0xcd,
+	0xe9, 0x24, 0xd7, 0x81, 0xc5, 0xb6, 0x00, 0xdb, 0xf5, 0x9a, 0xaa, 0x96, 0xfa, 0xbf, 0xfa, 0x87,
+	0xfa, 0xa1, 0x3f, 0xa3, 0xb2, 0xbd, 0xb6, 0x81, 0x3b, 0x12, 0x57, 0xbd, 0x4f, 0x9e, 0x99, 0x7d,
+	0x9e, 0x79, 0x66, 0x76, 0x76, 0x57, 0x06, 0x6e, 0x85, 0xdd, 0x8d, 0x62, 0x28, 0x2a, 0xb6, 0x05,
+	0xcb, 0x36, 0x1d, 0x13, 0x7d, 0x41, 0x2c, 0xdd, 0xc6, 0x02, 0xc1, 0xf6, 0xef, 0xd8, 0x16, 0xe2,
+	0xe5, 0xda, 0xa5, 0xaa, 0x3b, 0xda, 0xf6, 0x41, 0x98, 0x9b, 0x9b, 0x26, 0xb1, 0xf4, 0xe5, 0x12,
+	0x37, 0x7d, 0x68, 0xd3, 0xe7, 0x35, 0xe7, 0xe6, 0x66, 0x63, 0x1a, 0x4d, 0x6b, 0xbd, 0x55, 0xf5,
+	0xf0, 0x13, 0xa4, 0xe4, 0x0d, 0x28, 0x4e, 0xb7, 0x0f, 0x6b, 0x7d, 0x3e, 0xc1, 0x2e, 0xaa, 0x40,
+	0x5a, 0x5f, 0x54, 0x99, 0x3a, 0xd3, 0x28, 0x4a, 0x69, 0x7d, 0x81, 0xda, 0x90, 0x71, 0x5c, 0x0b,
+	0x57, 0xd3, 0x75, 0xa6, 0x51, 0x11, 0xeb, 0xc2, 0x11, 0x79, 0x61, 0x82, 0xdd, 0x3b, 0xd7, 0xc2,
+	0x92, 0x8f, 0x46, 0x5f, 0x42, 0xd1, 0x5a, 0xe9, 0x7f, 0xc8, 0x0b, 0xc5, 0x51, 0xaa, 0x27, 0x75,
+	0xa6, 0x51, 0x92, 0x0a, 0x5e, 0x60, 0xa0, 0x38, 0x0a, 0xaf, 0x01, 0xba, 0xc6, 0x06, 0xb6, 0x15,
+	0x07, 0x4f, 0xb0, 0x2b, 0xe1, 0xdf, 0xb6, 0x98, 0x38, 0xe8, 0x0c, 0x72, 0x2b, 0xec, 0xca, 0x91,
+	0x78, 0x76, 0x85, 0xdd, 0xf1, 0x02, 0xfd, 0x08, 0x05, 0x2f, 0xfc, 0x9f, 0x6a, 0xc8, 0xaf, 0x02,
+	0x83, 0xff, 0x05, 0x3e, 0xdb, 0x53, 0x22, 0x96, 0x69, 0x10, 0x8c, 0x7a, 0x00, 0x96, 0xdf, 0xb0,
+	0xbc, 0xc2, 0xae, 0x2f, 0xc7, 0x8a, 0xfc, 0xd1, 0xac, 0xd1, 0xde, 0x48, 0x45, 0x2b, 0x34, 0xf9,
+	0x97, 0x5e, 0x66, 0x27, 0x5e, 0x7a, 0xb4, 0x09, 0xfe, 0x1e, 0x4e, 0xf7, 0xd1, 0x9f, 0xae, 0x90,
+	0xcf, 0xf7, 0x53, 0x13, 0x5a, 0x09, 0xff, 0x1e, 0xce, 0x0e, 0xe2, 0x54, 0xb3, 0x0f, 0x6c, 0xac,
+	0x49, 0xaa, 0x4c, 0xfd, 0x24, 0xa1, 0x28, 0x44, 0xa2, 0x84, 0xff, 0x13, 0x60, 0x3a, 0x9b, 0xdd,
+	0x5a, 0x8e, 0x6e, 0x1a, 0x04, 0x7d, 0x03, 0x2c, 0x51, 0xd6, 0x8e, 0xbc, 0xc6, 0x86, 0xea, 0x68,
+	0x7e, 0x1f, 0x59, 0x09, 0xbc, 0xd0, 0x5b, 0x3f, 0x82, 0x6e, 0xa0, 0xa2, 0x29, 0x44, 0x93, 0x95,
+	0xb5, 0x6a, 0xda, 0xba, 0xa3, 0x6d, 0xe8, 0x28, 0x9f, 0x1f, 0x95, 0x1d, 0x29, 0x44, 0xeb, 0x85,
+	0x68, 0xa9, 0xac, 0xed, 0xba, 0xfc, 0xdf, 0x0c, 0x3c, 0x9b, 0xe9, 0xaa, 0xe1, 0x9d, 0xa6, 0x27,
+	0x8e, 0x0f, 0x82, 0xcc, 0xce, 0x19, 0xf4, 0x6d, 0x74, 0xfb, 0xff, 0xaa, 0x19, 0xa5, 0x0e, 0xea,
+	0x41, 0x6f, 0x80, 0xb5, 0x08, 0x91, 0xcd, 0x60, 0x3b, 0xaa, 0x19, 0x7f, 0x8e, 0xdf, 0x1e, 0xdf,
+	0xd2, 0x68, 0xe7, 0x46, 0x29, 0x09, 0x2c, 0x42, 0xa8, 0x77, 0x55, 0x06, 0x96, 0xe8, 0xaa, 0x81,
+	0x6d, 0x2f, 0x15, 0xe1, 0x5b, 0xc0, 0xc5, 0x5d, 0xd2, 0xe9, 0x7d, 0x05, 0x45, 0x0f, 0xa2, 0x38,
+	0x5b, 0x1b, 0xfb, 0x9d, 0x96, 0xa4, 0x38, 0xc0, 0xbf, 0x87, 0xd3, 0x90, 0x71, 0xa5, 0x38, 0x73,
+	0x2d, 0xdc, 0x9c, 0x01, 0x14, 0xec, 0xc0, 0x0c, 0x07, 0xde, 0x38, 0x5a, 0xdd, 0xc1, 0xc6, 0x4a,
+	0x11, 0x93, 0xff, 0x15, 0xce, 0x0e, 0xb2, 0xd3, 0xa2, 0xae, 0xa1, 0x68, 0x53, 0x3b, 0xcc, 0xff,
+	0x22, 0x41, 0xfe, 0x80, 0x21, 0xc5, 0xdc, 0x73, 0x07, 0xf2, 0xf4, 0x0e, 0xa3, 0x2a, 0x9c, 0xfe,
+	0xfc, 0xd3, 0x6c, 0x3a, 0xec, 0x8f, 0xdf, 0x8c, 0x87, 0x03, 0x79, 0x32, 0xbc, 0x97, 0xef, 0xee,
+	0xa7, 0x43, 0x2e, 0x85, 0x58, 0xc8, 0x0f, 0xfb, 0xf2, 0x54, 0xec, 0x74, 0x39, 0x26, 0x74, 0x5e,
+	0x5d, 0xb6, 0xb9, 0x34, 0x2a, 0x41, 0x41, 0x9a, 0xf5, 0xe4, 0x8b, 0x96, 0xd8, 0xe6, 0x4e, 0x42,
+	0x4f, 0x6c, 0xb5, 0x2f, 0xb9, 0x4c, 0xe8, 0xb5, 0x5b, 0xaf, 0xbb, 0x5c, 0xd6, 0xa7, 0x0d, 0xc4,
+	0x4e, 0xe7, 0xe2, 0x35, 0x97, 0x3b, 0xff, 0x8b, 0x81, 0xf2, 0xde, 0x84, 0xd1, 0xd7, 0x50, 0xdb,
+	0x15, 0x1f, 0xf5, 0x66, 0x23, 0xb9, 0xf7, 0xf6, 0xfa, 0x56, 0x1a, 0xdf, 0x8d, 0x6e, 0xb8, 0x14,
+	0x02, 0xc8, 0xcd, 0x46, 0x3d, 0x51, 0x6c, 0x73, 0x99, 0xd0, 0xee, 0x78, 0x69, 0x03, 0xdb, 0x2b,
+	0x26, 0x47, 0xed, 0xce, 0x85, 0xc8, 0xe5, 0x3d, 0x71, 0x2f, 0x2e, 0x7b, 0x0c, 0x88, 0xbd, 0x4e,
+	0x97, 0x63, 0x23, 0xcf, 0x63, 0x95, 0x22, 0xcf, 0xe3, 0x95, 0x51, 0x05, 0x20, 0xc8, 0xe1, 0x33,
+	0x2b, 0xbb, 0x7e, 0xa7, 0xcb, 0x3d, 0x13, 0xff, 0xc9, 0x02, 0x4c, 0xb0, 0x7b, 0x13, 0x6c, 0x2d,
+	0xd2, 0x80, 0xdd, 0x79, 0xee, 0xd0, 0xf7, 0x47, 0x67, 0xf0, 0xe1, 0xf3, 0x5b, 0x7b, 0x99, 0x0c,
+	0x4c, 0x27, 0xbe, 0x82, 0xd2, 0xee, 0xeb, 0x82, 0x1e, 0x63, 0x7f, 0xf0, 0x4a, 0xd6, 0x7e, 0x48,
+	0x88, 0xa6, 0x62, 0x06, 0x94, 0x77, 0xe3, 0x04, 0x25, 0xe3, 0x87, 0x4f, 0x61, 0x4d, 0x48, 0x0a,
+	0xa7, 0x7a, 0x32, 0x14, 0xc2, 0x43, 0x8a, 0x12, 0xdf, 0x93, 0x5a, 0xf2, 0x13, 0xef, 0x35, 0xb4,
+	0x77, 0x91, 0x1e, 0x69, 0xe8, 0x63, 0xd7, 0xb9, 0x26, 0x24, 0x85, 0x53, 0xbd, 0x77, 0x50, 0xec,
+	0x9b, 0xc6, 0x52, 0x57, 0xb7, 0x36, 0x46, 0xdf, 0x51, 0x72, 0xf0, 0x43, 0x20, 0xd0, 0x3f, 0x81,
+	0x68, 0x3d, 0xd4, 0x78, 0xfe, 0x14, 0x8c, 0xe6, 0x5e, 0x06, 0xc3, 0xf1, 0x97, 0xc7, 0xc6, 0xd2,
+	0x44, 0x2f, 0x3e, 0x4a, 0xdc, 0xc3, 0x84, 0x1a, 0xe7, 0x49, 0xa0, 0x81, 0xce, 0x55, 0xe9, 0x1d,
+	0xc4, 0x7d, 0x4e, 0x53, 0x0f, 0x39, 0xff, 0xdf, 0xe5, 0xd5, 0xbf, 0x03, 0x00, 0xe0, 0x81, 0x71,
+	0xc2, 0x22, 0x09, 0x00, 0x00,
 }
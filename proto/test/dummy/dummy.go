@@ -6,6 +6,7 @@ import (
 
 	"github.com/golang/protobuf/ptypes/empty"
 	go_plugin "github.com/hashicorp/go-plugin"
+	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/proto/builtin"
 	"github.com/spiffe/spire/proto/common/plugin"
 	"google.golang.org/grpc"
@@ -188,6 +189,13 @@ func NewBuiltIn(plugin Plugin) *BuiltIn {
 	}
 }
 
+// SetLogger hands the logger to the plugin, if the plugin logs
+func (b BuiltIn) SetLogger(log logrus.FieldLogger) {
+	if p, ok := b.plugin.(interface{ SetLogger(logrus.FieldLogger) }); ok {
+		p.SetLogger(log)
+	}
+}
+
 func (b BuiltIn) NoStream(ctx context.Context, req *NoStreamRequest) (*NoStreamResponse, error) {
 	resp, err := b.plugin.NoStream(ctx, req)
 	if err != nil {
@@ -0,0 +1,157 @@
+package fakealibaba
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidDigestSHA256  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+)
+
+// Signer signs instance identity documents the way the ECS metadata
+// service does, producing a detached PKCS#7 signature.
+type Signer struct {
+	t *testing.T
+
+	Certificate *x509.Certificate
+	key         *rsa.PrivateKey
+}
+
+func New(t *testing.T) *Signer {
+	now := time.Now()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// a random serial number keeps signers distinguishable since they
+	// share a subject
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "FAKEALIBABA"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+
+	return &Signer{
+		t:           t,
+		Certificate: cert,
+		key:         key,
+	}
+}
+
+// Sign returns the base64 encoded PKCS#7 signature over content, with the
+// content digest carried in the authenticated attributes.
+func (s *Signer) Sign(content []byte) string {
+	contentDigest := sha256.Sum256(content)
+
+	attributes := []attribute{
+		{Type: oidContentType, Value: s.marshalSet(oidData)},
+		{Type: oidMessageDigest, Value: s.marshalSet(contentDigest[:])},
+	}
+	attributesDER, err := asn1.MarshalWithParams(attributes, "set")
+	require.NoError(s.t, err)
+
+	attributesDigest := sha256.Sum256(attributesDER)
+	encryptedDigest, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, attributesDigest[:])
+	require.NoError(s.t, err)
+
+	// within the signer info the attributes are implicitly tagged
+	authenticatedAttributes := append([]byte(nil), attributesDER...)
+	authenticatedAttributes[0] = 0xa0
+
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidDigestSHA256}},
+		ContentInfo:      contentInfo{ContentType: oidData},
+		SignerInfos: []signerInfo{
+			{
+				Version: 1,
+				IssuerAndSerialNumber: issuerAndSerialNumber{
+					Issuer:       asn1.RawValue{FullBytes: s.Certificate.RawIssuer},
+					SerialNumber: s.Certificate.SerialNumber,
+				},
+				DigestAlgorithm:           pkix.AlgorithmIdentifier{Algorithm: oidDigestSHA256},
+				AuthenticatedAttributes:   asn1.RawValue{FullBytes: authenticatedAttributes},
+				DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption},
+				EncryptedDigest:           encryptedDigest,
+			},
+		},
+	}
+	sdDER, err := asn1.Marshal(sd)
+	require.NoError(s.t, err)
+
+	der, err := asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{FullBytes: s.explicit(sdDER)},
+	})
+	require.NoError(s.t, err)
+
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func (s *Signer) marshalSet(v interface{}) asn1.RawValue {
+	der, err := asn1.Marshal(v)
+	require.NoError(s.t, err)
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: der}
+}
+
+func (s *Signer) explicit(der []byte) []byte {
+	wrapped, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der})
+	require.NoError(s.t, err)
+	return wrapped
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	SignerInfos      []signerInfo `asn1:"set"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+type attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
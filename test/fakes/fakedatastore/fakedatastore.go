@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/golang/protobuf/proto"
@@ -43,6 +44,14 @@ type DataStore struct {
 
 	// relates bundles with entries that federate with them
 	bundleEntries map[string]map[string]bool
+
+	// held by pointer since some methods have value receivers
+	changeEvents *changeEventLog
+}
+
+type changeEventLog struct {
+	events []*datastore.ChangeEvent
+	lastID uint64
 }
 
 var _ datastore.DataStore = (*DataStore)(nil)
@@ -55,6 +64,7 @@ func New() *DataStore {
 		registrationEntries: make(map[string]*datastore.RegistrationEntry),
 		tokens:              make(map[string]*datastore.JoinToken),
 		bundleEntries:       make(map[string]map[string]bool),
+		changeEvents:        new(changeEventLog),
 	}
 }
 
@@ -70,6 +80,7 @@ func (s *DataStore) CreateBundle(ctx context.Context, req *datastore.CreateBundl
 	}
 
 	s.bundles[bundle.TrustDomainId] = cloneBundle(bundle)
+	s.recordChangeEvent(datastore.ChangeEvent_BUNDLE, datastore.ChangeEvent_CREATED, bundle.TrustDomainId)
 
 	return &datastore.CreateBundleResponse{
 		Bundle: cloneBundle(bundle),
@@ -89,6 +100,7 @@ func (s *DataStore) UpdateBundle(ctx context.Context, req *datastore.UpdateBundl
 	}
 
 	s.bundles[bundle.TrustDomainId] = cloneBundle(bundle)
+	s.recordChangeEvent(datastore.ChangeEvent_BUNDLE, datastore.ChangeEvent_UPDATED, bundle.TrustDomainId)
 
 	return &datastore.UpdateBundleResponse{
 		Bundle: cloneBundle(bundle),
@@ -103,11 +115,17 @@ func (s *DataStore) AppendBundle(ctx context.Context, req *datastore.AppendBundl
 
 	bundle := req.Bundle
 
+	op := datastore.ChangeEvent_CREATED
+	changed := true
 	if existingBundle, ok := s.bundles[bundle.TrustDomainId]; ok {
-		bundle, _ = bundleutil.MergeBundles(existingBundle, bundle)
+		op = datastore.ChangeEvent_UPDATED
+		bundle, changed = bundleutil.MergeBundles(existingBundle, bundle)
 	}
 
 	s.bundles[bundle.TrustDomainId] = cloneBundle(bundle)
+	if changed {
+		s.recordChangeEvent(datastore.ChangeEvent_BUNDLE, op, bundle.TrustDomainId)
+	}
 
 	return &datastore.AppendBundleResponse{
 		Bundle: cloneBundle(bundle),
@@ -129,11 +147,13 @@ func (s *DataStore) DeleteBundle(ctx context.Context, req *datastore.DeleteBundl
 		case datastore.DeleteBundleRequest_DELETE:
 			for entryID := range bundleEntries {
 				delete(s.registrationEntries, entryID)
+				s.recordChangeEvent(datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_DELETED, entryID)
 			}
 		case datastore.DeleteBundleRequest_DISSOCIATE:
 			for entryID := range bundleEntries {
 				if entry := s.registrationEntries[entryID]; entry != nil {
 					entry.FederatesWith = removeString(entry.FederatesWith, req.TrustDomainId)
+					s.recordChangeEvent(datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_UPDATED, entryID)
 				}
 			}
 		default:
@@ -141,6 +161,7 @@ func (s *DataStore) DeleteBundle(ctx context.Context, req *datastore.DeleteBundl
 		}
 	}
 	delete(s.bundles, req.TrustDomainId)
+	s.recordChangeEvent(datastore.ChangeEvent_BUNDLE, datastore.ChangeEvent_DELETED, req.TrustDomainId)
 
 	return &datastore.DeleteBundleResponse{
 		Bundle: cloneBundle(bundle),
@@ -229,8 +250,20 @@ func (s *DataStore) ListAttestedNodes(ctx context.Context,
 	}
 	sort.Strings(keys)
 
-	resp := new(datastore.ListAttestedNodesResponse)
+	// the token is the SPIFFE ID of the last node of the previous page
+	p := req.Pagination
+	paginated := p != nil && p.PageSize > 0
+
+	resp := &datastore.ListAttestedNodesResponse{
+		Pagination: p,
+	}
 	for _, key := range keys {
+		if paginated && len(resp.Nodes) == int(p.PageSize) {
+			break
+		}
+		if paginated && key <= p.Token {
+			continue
+		}
 		attestedNodeEntry := s.attestedNodes[key]
 		if req.ByExpiresBefore != nil {
 			if attestedNodeEntry.CertNotAfter >= req.ByExpiresBefore.Value {
@@ -239,6 +272,9 @@ func (s *DataStore) ListAttestedNodes(ctx context.Context,
 		}
 		resp.Nodes = append(resp.Nodes, cloneAttestedNode(attestedNodeEntry))
 	}
+	if paginated && len(resp.Nodes) > 0 {
+		p.Token = resp.Nodes[len(resp.Nodes)-1].SpiffeId
+	}
 
 	return resp, nil
 }
@@ -271,6 +307,9 @@ func (s *DataStore) DeleteAttestedNode(ctx context.Context,
 	if !ok {
 		return nil, ErrNoSuchAttestedNode
 	}
+	if req.ByCertSerialNumber != nil && node.CertSerialNumber != req.ByCertSerialNumber.Value {
+		return &datastore.DeleteAttestedNodeResponse{}, nil
+	}
 	delete(s.attestedNodes, req.SpiffeId)
 
 	return &datastore.DeleteAttestedNodeResponse{
@@ -322,6 +361,7 @@ func (s *DataStore) CreateRegistrationEntry(ctx context.Context,
 	if err := s.addBundleLinks(entryID, req.Entry.FederatesWith); err != nil {
 		return nil, err
 	}
+	s.recordChangeEvent(datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_CREATED, entryID)
 
 	return &datastore.CreateRegistrationEntryResponse{
 		Entry: cloneRegistrationEntry(entry),
@@ -359,6 +399,9 @@ func (s *DataStore) ListRegistrationEntries(ctx context.Context,
 		if req.BySpiffeId != nil && entry.SpiffeId != req.BySpiffeId.Value {
 			continue
 		}
+		if req.ByExpiresBefore != nil && (entry.EntryExpiry == 0 || entry.EntryExpiry >= req.ByExpiresBefore.Value) {
+			continue
+		}
 
 		entriesSet[entry.EntryId] = entry
 	}
@@ -469,6 +512,7 @@ func (s DataStore) UpdateRegistrationEntry(ctx context.Context,
 	if err := s.addBundleLinks(entry.EntryId, req.Entry.FederatesWith); err != nil {
 		return nil, err
 	}
+	s.recordChangeEvent(datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_UPDATED, entry.EntryId)
 
 	return &datastore.UpdateRegistrationEntryResponse{
 		Entry: cloneRegistrationEntry(entry),
@@ -488,12 +532,61 @@ func (s *DataStore) DeleteRegistrationEntry(ctx context.Context,
 	delete(s.registrationEntries, req.EntryId)
 
 	s.removeBundleLinks(req.EntryId, registrationEntry.FederatesWith)
+	s.recordChangeEvent(datastore.ChangeEvent_REGISTRATION_ENTRY, datastore.ChangeEvent_DELETED, req.EntryId)
 
 	return &datastore.DeleteRegistrationEntryResponse{
 		Entry: cloneRegistrationEntry(registrationEntry),
 	}, nil
 }
 
+func (s *DataStore) BatchCreateRegistrationEntries(ctx context.Context,
+	req *datastore.BatchCreateRegistrationEntriesRequest) (*datastore.BatchCreateRegistrationEntriesResponse, error) {
+
+	resp := new(datastore.BatchCreateRegistrationEntriesResponse)
+	for _, entry := range req.Entries {
+		createResp, err := s.CreateRegistrationEntry(ctx, &datastore.CreateRegistrationEntryRequest{
+			Entry: entry,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, createResp.Entry)
+	}
+	return resp, nil
+}
+
+func (s *DataStore) BatchUpdateRegistrationEntries(ctx context.Context,
+	req *datastore.BatchUpdateRegistrationEntriesRequest) (*datastore.BatchUpdateRegistrationEntriesResponse, error) {
+
+	resp := new(datastore.BatchUpdateRegistrationEntriesResponse)
+	for _, entry := range req.Entries {
+		updateResp, err := s.UpdateRegistrationEntry(ctx, &datastore.UpdateRegistrationEntryRequest{
+			Entry: entry,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, updateResp.Entry)
+	}
+	return resp, nil
+}
+
+func (s *DataStore) BatchDeleteRegistrationEntries(ctx context.Context,
+	req *datastore.BatchDeleteRegistrationEntriesRequest) (*datastore.BatchDeleteRegistrationEntriesResponse, error) {
+
+	resp := new(datastore.BatchDeleteRegistrationEntriesResponse)
+	for _, entryID := range req.EntryIds {
+		deleteResp, err := s.DeleteRegistrationEntry(ctx, &datastore.DeleteRegistrationEntryRequest{
+			EntryId: entryID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, deleteResp.Entry)
+	}
+	return resp, nil
+}
+
 // CreateJoinToken takes a Token message and stores it
 func (s *DataStore) CreateJoinToken(ctx context.Context, req *datastore.CreateJoinTokenRequest) (*datastore.CreateJoinTokenResponse, error) {
 	s.mu.Lock()
@@ -540,6 +633,41 @@ func (s *DataStore) DeleteJoinToken(ctx context.Context, req *datastore.DeleteJo
 	}, nil
 }
 
+func (s *DataStore) ListJoinTokens(ctx context.Context, req *datastore.ListJoinTokensRequest) (*datastore.ListJoinTokensResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := new(datastore.ListJoinTokensResponse)
+	for _, joinToken := range s.tokens {
+		resp.JoinTokens = append(resp.JoinTokens, cloneJoinToken(joinToken))
+	}
+	sort.Slice(resp.JoinTokens, func(i, j int) bool {
+		return resp.JoinTokens[i].Token < resp.JoinTokens[j].Token
+	})
+
+	return resp, nil
+}
+
+func (s *DataStore) UseJoinToken(ctx context.Context, req *datastore.UseJoinTokenRequest) (*datastore.UseJoinTokenResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	joinToken, ok := s.tokens[req.Token]
+	if !ok {
+		return &datastore.UseJoinTokenResponse{}, nil
+	}
+	resp := &datastore.UseJoinTokenResponse{
+		JoinToken: cloneJoinToken(joinToken),
+	}
+
+	joinToken.Uses++
+	if joinToken.Uses >= joinToken.MaxUses {
+		delete(s.tokens, req.Token)
+	}
+
+	return resp, nil
+}
+
 func (s *DataStore) PruneJoinTokens(ctx context.Context, req *datastore.PruneJoinTokensRequest) (*datastore.PruneJoinTokensResponse, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -553,6 +681,48 @@ func (s *DataStore) PruneJoinTokens(ctx context.Context, req *datastore.PruneJoi
 	return &datastore.PruneJoinTokensResponse{}, nil
 }
 
+func (s *DataStore) ListChangeEvents(ctx context.Context, req *datastore.ListChangeEventsRequest) (*datastore.ListChangeEventsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := s.changeEvents
+	resp := new(datastore.ListChangeEventsResponse)
+	if len(log.events) == 0 {
+		return resp, nil
+	}
+	resp.LatestId = log.lastID
+
+	oldestID := log.events[0].Id
+	if req.AfterId > 0 && (req.AfterId+1 < oldestID || req.AfterId > log.lastID) {
+		resp.ResyncRequired = true
+		return resp, nil
+	}
+
+	for _, event := range log.events {
+		if event.Id <= req.AfterId {
+			continue
+		}
+		if req.Limit > 0 && len(resp.Events) == int(req.Limit) {
+			break
+		}
+		resp.Events = append(resp.Events, proto.Clone(event).(*datastore.ChangeEvent))
+	}
+	return resp, nil
+}
+
+func (s *DataStore) PruneChangeEvents(ctx context.Context, req *datastore.PruneChangeEventsRequest) (*datastore.PruneChangeEventsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the most recent event is always kept
+	log := s.changeEvents
+	for len(log.events) > 1 && log.events[0].CreatedAt < req.CreatedBefore {
+		log.events = log.events[1:]
+	}
+
+	return &datastore.PruneChangeEventsResponse{}, nil
+}
+
 func (s *DataStore) Configure(ctx context.Context, req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
 	return &spi.ConfigureResponse{}, nil
 }
@@ -561,6 +731,18 @@ func (DataStore) GetPluginInfo(context.Context, *spi.GetPluginInfoRequest) (*spi
 	return &spi.GetPluginInfoResponse{}, nil
 }
 
+func (s *DataStore) recordChangeEvent(kind datastore.ChangeEvent_Kind, op datastore.ChangeEvent_Op, key string) {
+	log := s.changeEvents
+	log.lastID++
+	log.events = append(log.events, &datastore.ChangeEvent{
+		Id:        log.lastID,
+		Kind:      kind,
+		Op:        op,
+		Key:       key,
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
 func (s *DataStore) addBundleLinks(entryID string, bundleIDs []string) error {
 	for _, bundleID := range bundleIDs {
 		if _, ok := s.bundles[bundleID]; !ok {
@@ -619,7 +801,7 @@ nextSelector:
 	for _, candidate := range subset {
 		for _, selector := range selectors {
 			if candidate.Type == selector.Type && candidate.Value == selector.Value {
-				break nextSelector
+				continue nextSelector
 			}
 		}
 		return false
@@ -0,0 +1,152 @@
+package fakedevid
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TPM produces quotes signed by a DevID key the way a TPM does, with a DevID
+// certificate issued by an intermediate chaining back to its own root.
+type TPM struct {
+	t *testing.T
+
+	Root         *x509.Certificate
+	Intermediate *x509.Certificate
+	DevID        *x509.Certificate
+
+	devIDKey *ecdsa.PrivateKey
+}
+
+func New(t *testing.T) *TPM {
+	now := time.Now()
+
+	rootKey := generateKey(t)
+	root := createCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "FAKEDEVIDROOT"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, nil, rootKey, rootKey)
+
+	intermediateKey := generateKey(t)
+	intermediate := createCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "FAKEDEVIDINTERMEDIATE"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, root, intermediateKey, rootKey)
+
+	devIDKey := generateKey(t)
+	devID := createCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "FAKEDEVID"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+	}, intermediate, devIDKey, intermediateKey)
+
+	return &TPM{
+		t:            t,
+		Root:         root,
+		Intermediate: intermediate,
+		DevID:        devID,
+		devIDKey:     devIDKey,
+	}
+}
+
+// Roots returns a pool containing the DevID root
+func (tpm *TPM) Roots() *x509.CertPool {
+	roots := x509.NewCertPool()
+	roots.AddCert(tpm.Root)
+	return roots
+}
+
+// Certificates returns the DER encoded DevID and intermediate certificates
+func (tpm *TPM) Certificates() [][]byte {
+	return [][]byte{tpm.DevID.Raw, tpm.Intermediate.Raw}
+}
+
+// Quote returns a TPMS_ATTEST quote structure over the SHA-256 PCR values,
+// including the nonce, and its signature by the DevID key.
+func (tpm *TPM) Quote(nonce []byte, pcrs map[int][]byte) ([]byte, *tpm2.Signature) {
+	quote := MarshalQuote(0xff544347, tpm2.TagAttestQuote, nonce, tpm2.AlgSHA256, pcrs)
+	return quote, tpm.Sign(quote)
+}
+
+// Sign signs the data with the DevID key, as TPM2_Sign would
+func (tpm *TPM) Sign(data []byte) *tpm2.Signature {
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, tpm.devIDKey, digest[:])
+	require.NoError(tpm.t, err)
+	return &tpm2.Signature{
+		Alg: tpm2.AlgECDSA,
+		ECC: &tpm2.SignatureECC{
+			HashAlg: tpm2.AlgSHA256,
+			R:       r,
+			S:       s,
+		},
+	}
+}
+
+// MarshalQuote encodes a TPMS_ATTEST quote structure
+func MarshalQuote(magic uint32, attestType tpmutil.Tag, nonce []byte, bank tpm2.Algorithm, pcrs map[int][]byte) []byte {
+	var indices []int
+	for pcr := range pcrs {
+		indices = append(indices, pcr)
+	}
+	sort.Ints(indices)
+
+	mask := make([]byte, 3)
+	h := sha256.New()
+	for _, pcr := range indices {
+		mask[pcr/8] |= 1 << uint(pcr%8)
+		h.Write(pcrs[pcr])
+	}
+
+	quote, err := tpmutil.Pack(
+		magic,
+		attestType,
+		tpmutil.U16Bytes(nil), // qualified signer
+		tpmutil.U16Bytes(nonce),
+		tpm2.ClockInfo{},
+		uint64(0), // firmware version
+		uint32(1), bank, byte(len(mask)), tpmutil.RawBytes(mask),
+		tpmutil.U16Bytes(h.Sum(nil)),
+	)
+	if err != nil {
+		panic(err)
+	}
+	return quote
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func createCertificate(t *testing.T, template, parent *x509.Certificate, key, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	if parent == nil {
+		parent = template
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+	return cert
+}
@@ -0,0 +1,156 @@
+package fakenitro
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/plugin/nitro"
+	"github.com/stretchr/testify/require"
+)
+
+// Hypervisor produces attestation documents signed the way the Nitro
+// hypervisor does, with a certificate chaining back to its own root.
+type Hypervisor struct {
+	t *testing.T
+
+	Root         *x509.Certificate
+	Intermediate *x509.Certificate
+	Leaf         *x509.Certificate
+	leafKey      *ecdsa.PrivateKey
+}
+
+// Document holds the attestation document fields
+type Document struct {
+	ModuleID  string
+	Timestamp time.Time
+	PCRs      map[int][]byte
+	Nonce     []byte
+	UserData  []byte
+	PublicKey []byte
+}
+
+func New(t *testing.T) *Hypervisor {
+	now := time.Now()
+
+	rootKey := generateKey(t)
+	root := createCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "FAKENITROROOT"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, nil, rootKey, rootKey)
+
+	intermediateKey := generateKey(t)
+	intermediate := createCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "FAKENITROINTERMEDIATE"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, root, intermediateKey, rootKey)
+
+	leafKey := generateKey(t)
+	leaf := createCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "FAKENITROLEAF"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+	}, intermediate, leafKey, intermediateKey)
+
+	return &Hypervisor{
+		t:            t,
+		Root:         root,
+		Intermediate: intermediate,
+		Leaf:         leaf,
+		leafKey:      leafKey,
+	}
+}
+
+// Roots returns a pool containing the hypervisor root
+func (h *Hypervisor) Roots() *x509.CertPool {
+	roots := x509.NewCertPool()
+	roots.AddCert(h.Root)
+	return roots
+}
+
+// Attest returns a COSE_Sign1 encoded attestation document
+func (h *Hypervisor) Attest(doc Document) []byte {
+	pcrs := make(map[interface{}]interface{})
+	for i, pcr := range doc.PCRs {
+		pcrs[uint64(i)] = pcr
+	}
+	payload := h.encode(map[string]interface{}{
+		"module_id":   doc.ModuleID,
+		"digest":      "SHA384",
+		"timestamp":   uint64(doc.Timestamp.UnixNano() / int64(time.Millisecond)),
+		"pcrs":        pcrs,
+		"certificate": h.Leaf.Raw,
+		"cabundle":    []interface{}{h.Root.Raw, h.Intermediate.Raw},
+		"public_key":  nilIfEmpty(doc.PublicKey),
+		"user_data":   nilIfEmpty(doc.UserData),
+		"nonce":       nilIfEmpty(doc.Nonce),
+	})
+	return h.Sign(payload)
+}
+
+// Sign wraps the payload in a COSE_Sign1 message signed with ES384
+func (h *Hypervisor) Sign(payload []byte) []byte {
+	protected := h.encode(map[interface{}]interface{}{
+		int64(1): int64(-35),
+	})
+	toBeSigned := h.encode([]interface{}{"Signature1", protected, []byte{}, payload})
+	digest := sha512.Sum384(toBeSigned)
+	r, s, err := ecdsa.Sign(rand.Reader, h.leafKey, digest[:])
+	require.NoError(h.t, err)
+	signature := make([]byte, 96)
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	copy(signature[48-len(rBytes):48], rBytes)
+	copy(signature[96-len(sBytes):], sBytes)
+
+	return h.encode([]interface{}{
+		protected,
+		map[interface{}]interface{}{},
+		payload,
+		signature,
+	})
+}
+
+func (h *Hypervisor) encode(v interface{}) []byte {
+	data, err := nitro.EncodeCBOR(v)
+	require.NoError(h.t, err)
+	return data
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func createCertificate(t *testing.T, template, parent *x509.Certificate, key, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	if parent == nil {
+		parent = template
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+	return cert
+}
+
+func nilIfEmpty(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,105 @@
+package fakeoci
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// CA issues instance principal certificates the way the OCI identity
+// service does, from an intermediate chaining back to its own root.
+type CA struct {
+	t *testing.T
+
+	Root         *x509.Certificate
+	Intermediate *x509.Certificate
+
+	intermediateKey *ecdsa.PrivateKey
+	serial          int64
+}
+
+func New(t *testing.T) *CA {
+	now := time.Now()
+
+	rootKey := generateKey(t)
+	root := createCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "FAKEOCIROOT"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, rootKey, rootKey)
+
+	intermediateKey := generateKey(t)
+	intermediate := createCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "FAKEOCIINTERMEDIATE"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, root, intermediateKey, rootKey)
+
+	return &CA{
+		t:               t,
+		Root:            root,
+		Intermediate:    intermediate,
+		intermediateKey: intermediateKey,
+		serial:          2,
+	}
+}
+
+// Roots returns a pool containing the root
+func (ca *CA) Roots() *x509.CertPool {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Root)
+	return roots
+}
+
+// IssueInstanceCertificate issues an instance principal certificate whose
+// subject carries the given organizational units (e.g.
+// "opc-instance:ocid1.instance..."). It returns the certificate and its key.
+func (ca *CA) IssueInstanceCertificate(ous ...string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	now := time.Now()
+	ca.serial++
+
+	key := generateKey(ca.t)
+	cert := createCertificate(ca.t, &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject: pkix.Name{
+			CommonName:         "FAKEOCIINSTANCE",
+			OrganizationalUnit: ous,
+		},
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.Add(time.Hour),
+		KeyUsage:  x509.KeyUsageDigitalSignature,
+	}, ca.Intermediate, key, ca.intermediateKey)
+	return cert, key
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func createCertificate(t *testing.T, tmpl, parent *x509.Certificate, key, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	if parent == nil {
+		parent = tmpl
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+	return cert
+}
@@ -114,6 +114,18 @@ func (c *ServerCA) SignX509SVID(ctx context.Context, csrDER []byte, ttl time.Dur
 	return append([]*x509.Certificate{cert}, c.certs...), nil
 }
 
+func (c *ServerCA) SignX509SVIDs(ctx context.Context, params []ca.X509SVIDParams) ([][]*x509.Certificate, error) {
+	var svids [][]*x509.Certificate
+	for _, p := range params {
+		svid, err := c.SignX509SVID(ctx, p.CSR, p.TTL)
+		if err != nil {
+			return nil, err
+		}
+		svids = append(svids, svid)
+	}
+	return svids, nil
+}
+
 func (c *ServerCA) SignX509CASVID(ctx context.Context, csrDER []byte, ttl time.Duration) ([]*x509.Certificate, error) {
 	if ttl <= 0 {
 		ttl = c.options.DefaultTTL
@@ -90,14 +90,19 @@ func (c *Catalog) UpstreamCAs() []*catalog.ManagedUpstreamCA {
 
 func (c *Catalog) SetKeyManagers(keyManagers ...keymanager.KeyManager) {
 	c.keyManagers = nil
-	for i, keyManager := range keyManagers {
-		c.keyManagers = append(c.keyManagers, catalog.NewManagedKeyManager(
-			keyManager, common.PluginConfig{
-				PluginName: pluginName("keymanager", i),
-			}))
+	for _, keyManager := range keyManagers {
+		c.AddKeyManagerWithPurposes(keyManager)
 	}
 }
 
+func (c *Catalog) AddKeyManagerWithPurposes(keyManager keymanager.KeyManager, keyPurposes ...string) {
+	c.keyManagers = append(c.keyManagers, catalog.NewManagedKeyManager(
+		keyManager, common.PluginConfig{
+			PluginName:  pluginName("keymanager", len(c.keyManagers)),
+			KeyPurposes: keyPurposes,
+		}))
+}
+
 func (c *Catalog) KeyManagers() []*catalog.ManagedKeyManager {
 	return c.keyManagers
 }
@@ -0,0 +1,164 @@
+package fakesevsnp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/plugin/sevsnp"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oidHardwareID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 4}
+)
+
+// SecureProcessor produces SEV-SNP attestation reports the way the AMD
+// secure processor does, signed by a VCEK chaining back to its own ARK.
+type SecureProcessor struct {
+	t *testing.T
+
+	ChipID [64]byte
+	ARK    *x509.Certificate
+	ASK    *x509.Certificate
+	VCEK   *x509.Certificate
+
+	vcekKey *ecdsa.PrivateKey
+}
+
+func New(t *testing.T) *SecureProcessor {
+	var chipID [64]byte
+	_, err := rand.Read(chipID[:])
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	arkKey := generateKey(t)
+	ark := createCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "FAKEARK"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, nil, arkKey, arkKey)
+
+	askKey := generateKey(t)
+	ask := createCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "FAKEASK"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, ark, askKey, arkKey)
+
+	hwID, err := asn1.Marshal(chipID[:])
+	require.NoError(t, err)
+	vcekKey := generateKey(t)
+	vcek := createCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "FAKEVCEK"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidHardwareID, Value: hwID},
+		},
+	}, ask, vcekKey, askKey)
+
+	return &SecureProcessor{
+		t:       t,
+		ChipID:  chipID,
+		ARK:     ark,
+		ASK:     ask,
+		VCEK:    vcek,
+		vcekKey: vcekKey,
+	}
+}
+
+// Roots returns a pool containing the ARK
+func (sp *SecureProcessor) Roots() *x509.CertPool {
+	roots := x509.NewCertPool()
+	roots.AddCert(sp.ARK)
+	return roots
+}
+
+// Certificates returns the DER encoded VCEK and ASK certificates
+func (sp *SecureProcessor) Certificates() [][]byte {
+	return [][]byte{sp.VCEK.Raw, sp.ASK.Raw}
+}
+
+// Report returns a signed attestation report. The version, signature
+// algorithm and chip ID are filled in if unset.
+func (sp *SecureProcessor) Report(r sevsnp.Report) []byte {
+	if r.Version == 0 {
+		r.Version = sevsnp.MinReportVersion
+	}
+	if r.SignatureAlgo == 0 {
+		r.SignatureAlgo = sevsnp.SignatureAlgoECDSAP384SHA384
+	}
+	if r.ChipID == [64]byte{} {
+		r.ChipID = sp.ChipID
+	}
+
+	le := binary.LittleEndian
+	data := make([]byte, sevsnp.ReportSize)
+	le.PutUint32(data[0x00:], r.Version)
+	le.PutUint32(data[0x04:], r.GuestSVN)
+	le.PutUint64(data[0x08:], r.Policy)
+	copy(data[0x10:], r.FamilyID[:])
+	copy(data[0x20:], r.ImageID[:])
+	le.PutUint32(data[0x30:], r.VMPL)
+	le.PutUint32(data[0x34:], r.SignatureAlgo)
+	le.PutUint64(data[0x38:], r.CurrentTCB)
+	le.PutUint64(data[0x40:], r.PlatformInfo)
+	copy(data[0x50:], r.ReportData[:])
+	copy(data[0x90:], r.Measurement[:])
+	copy(data[0xC0:], r.HostData[:])
+	copy(data[0xE0:], r.IDKeyDigest[:])
+	copy(data[0x110:], r.AuthorKeyDigest[:])
+	copy(data[0x140:], r.ReportID[:])
+	le.PutUint64(data[0x180:], r.ReportedTCB)
+	copy(data[0x1A0:], r.ChipID[:])
+
+	digest := sha512.Sum384(data[:0x2A0])
+	rInt, sInt, err := ecdsa.Sign(rand.Reader, sp.vcekKey, digest[:])
+	require.NoError(sp.t, err)
+	copy(data[0x2A0:], littleEndian(rInt, 72))
+	copy(data[0x2A0+72:], littleEndian(sInt, 72))
+	return data
+}
+
+func littleEndian(n *big.Int, size int) []byte {
+	be := n.Bytes()
+	out := make([]byte, size)
+	for i := range be {
+		out[i] = be[len(be)-1-i]
+	}
+	return out
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func createCertificate(t *testing.T, template, parent *x509.Certificate, key, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	if parent == nil {
+		parent = template
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+	return cert
+}
@@ -0,0 +1,171 @@
+package fakesgx
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/sgx"
+	"github.com/stretchr/testify/require"
+)
+
+// QuotingEnclave produces SGX ECDSA quotes the way the DCAP quoting enclave
+// does, with a PCK certificate chaining back to its own root.
+type QuotingEnclave struct {
+	t *testing.T
+
+	Root           *x509.Certificate
+	Intermediate   *x509.Certificate
+	PCK            *x509.Certificate
+	pckKey         *ecdsa.PrivateKey
+	attestationKey *ecdsa.PrivateKey
+}
+
+func New(t *testing.T) *QuotingEnclave {
+	now := time.Now()
+
+	rootKey := generateKey(t)
+	root := createCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "FAKESGXROOT"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, nil, rootKey, rootKey)
+
+	intermediateKey := generateKey(t)
+	intermediate := createCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "FAKESGXPCKCA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, root, intermediateKey, rootKey)
+
+	pckKey := generateKey(t)
+	pck := createCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "FAKESGXPCK"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+	}, intermediate, pckKey, intermediateKey)
+
+	return &QuotingEnclave{
+		t:              t,
+		Root:           root,
+		Intermediate:   intermediate,
+		PCK:            pck,
+		pckKey:         pckKey,
+		attestationKey: generateKey(t),
+	}
+}
+
+// Roots returns a pool containing the quoting enclave root
+func (qe *QuotingEnclave) Roots() *x509.CertPool {
+	roots := x509.NewCertPool()
+	roots.AddCert(qe.Root)
+	return roots
+}
+
+// AttestationKey returns the raw attestation public key embedded in quotes
+func (qe *QuotingEnclave) AttestationKey() []byte {
+	return rawPublicKey(&qe.attestationKey.PublicKey)
+}
+
+// Quote returns a signed quote for the enclave report
+func (qe *QuotingEnclave) Quote(report sgx.ReportBody) []byte {
+	header := make([]byte, sgx.HeaderSize)
+	binary.LittleEndian.PutUint16(header[0:], sgx.QuoteVersion)
+	binary.LittleEndian.PutUint16(header[2:], sgx.AttestationKeyTypeECDSAP256)
+
+	signedData := append(header, marshalReportBody(report)...)
+	attestationKey := qe.AttestationKey()
+	qeAuthData := []byte("QEAUTHDATA")
+
+	// the QE report binds the attestation key
+	h := sha256.New()
+	h.Write(attestationKey)
+	h.Write(qeAuthData)
+	var qeReport sgx.ReportBody
+	copy(qeReport.ReportData[:], h.Sum(nil))
+	qeReportData := marshalReportBody(qeReport)
+
+	certificationData := pemutil.EncodeCertificates([]*x509.Certificate{qe.PCK, qe.Intermediate, qe.Root})
+
+	signatureData := new(bytes.Buffer)
+	signatureData.Write(qe.sign(qe.attestationKey, signedData))
+	signatureData.Write(attestationKey)
+	signatureData.Write(qeReportData)
+	signatureData.Write(qe.sign(qe.pckKey, qeReportData))
+	binary.Write(signatureData, binary.LittleEndian, uint16(len(qeAuthData)))
+	signatureData.Write(qeAuthData)
+	binary.Write(signatureData, binary.LittleEndian, uint16(sgx.CertificationDataTypePCKCertChain))
+	binary.Write(signatureData, binary.LittleEndian, uint32(len(certificationData)))
+	signatureData.Write(certificationData)
+
+	quote := new(bytes.Buffer)
+	quote.Write(signedData)
+	binary.Write(quote, binary.LittleEndian, uint32(signatureData.Len()))
+	quote.Write(signatureData.Bytes())
+	return quote.Bytes()
+}
+
+func (qe *QuotingEnclave) sign(key *ecdsa.PrivateKey, data []byte) []byte {
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(qe.t, err)
+	signature := make([]byte, 64)
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	copy(signature[32-len(rBytes):32], rBytes)
+	copy(signature[64-len(sBytes):], sBytes)
+	return signature
+}
+
+func marshalReportBody(r sgx.ReportBody) []byte {
+	b := make([]byte, sgx.ReportBodySize)
+	copy(b[0:16], r.CPUSVN[:])
+	binary.LittleEndian.PutUint32(b[16:], r.MiscSelect)
+	copy(b[48:64], r.Attributes[:])
+	copy(b[64:96], r.MRENCLAVE[:])
+	copy(b[128:160], r.MRSIGNER[:])
+	binary.LittleEndian.PutUint16(b[256:], r.ISVProdID)
+	binary.LittleEndian.PutUint16(b[258:], r.ISVSVN)
+	copy(b[320:384], r.ReportData[:])
+	return b
+}
+
+func rawPublicKey(key *ecdsa.PublicKey) []byte {
+	raw := make([]byte, sgx.PublicKeySize)
+	x, y := key.X.Bytes(), key.Y.Bytes()
+	copy(raw[32-len(x):32], x)
+	copy(raw[64-len(y):], y)
+	return raw
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func createCertificate(t *testing.T, template, parent *x509.Certificate, key, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	if parent == nil {
+		parent = template
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+	return cert
+}
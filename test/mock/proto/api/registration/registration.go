@@ -36,6 +36,24 @@ func (m *MockRegistrationClient) EXPECT() *MockRegistrationClientMockRecorder {
 	return m.recorder
 }
 
+// CreateEntries mocks base method
+func (m *MockRegistrationClient) CreateEntries(arg0 context.Context, arg1 *registration.CreateEntriesRequest, arg2 ...grpc.CallOption) (*registration.CreateEntriesResponse, error) {
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateEntries", varargs...)
+	ret0, _ := ret[0].(*registration.CreateEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateEntries indicates an expected call of CreateEntries
+func (mr *MockRegistrationClientMockRecorder) CreateEntries(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEntries", reflect.TypeOf((*MockRegistrationClient)(nil).CreateEntries), varargs...)
+}
+
 // CreateEntry mocks base method
 func (m *MockRegistrationClient) CreateEntry(arg0 context.Context, arg1 *common.RegistrationEntry, arg2 ...grpc.CallOption) (*registration.RegistrationEntryID, error) {
 	varargs := []interface{}{arg0, arg1}
@@ -90,6 +108,60 @@ func (mr *MockRegistrationClientMockRecorder) CreateJoinToken(arg0, arg1 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJoinToken", reflect.TypeOf((*MockRegistrationClient)(nil).CreateJoinToken), varargs...)
 }
 
+// ListJoinTokens mocks base method
+func (m *MockRegistrationClient) ListJoinTokens(arg0 context.Context, arg1 *registration.ListJoinTokensRequest, arg2 ...grpc.CallOption) (*registration.ListJoinTokensResponse, error) {
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListJoinTokens", varargs...)
+	ret0, _ := ret[0].(*registration.ListJoinTokensResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListJoinTokens indicates an expected call of ListJoinTokens
+func (mr *MockRegistrationClientMockRecorder) ListJoinTokens(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListJoinTokens", reflect.TypeOf((*MockRegistrationClient)(nil).ListJoinTokens), varargs...)
+}
+
+// RevokeJoinToken mocks base method
+func (m *MockRegistrationClient) RevokeJoinToken(arg0 context.Context, arg1 *registration.RevokeJoinTokenRequest, arg2 ...grpc.CallOption) (*registration.RevokeJoinTokenResponse, error) {
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RevokeJoinToken", varargs...)
+	ret0, _ := ret[0].(*registration.RevokeJoinTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeJoinToken indicates an expected call of RevokeJoinToken
+func (mr *MockRegistrationClientMockRecorder) RevokeJoinToken(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeJoinToken", reflect.TypeOf((*MockRegistrationClient)(nil).RevokeJoinToken), varargs...)
+}
+
+// DeleteEntries mocks base method
+func (m *MockRegistrationClient) DeleteEntries(arg0 context.Context, arg1 *registration.DeleteEntriesRequest, arg2 ...grpc.CallOption) (*registration.DeleteEntriesResponse, error) {
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteEntries", varargs...)
+	ret0, _ := ret[0].(*registration.DeleteEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteEntries indicates an expected call of DeleteEntries
+func (mr *MockRegistrationClientMockRecorder) DeleteEntries(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEntries", reflect.TypeOf((*MockRegistrationClient)(nil).DeleteEntries), varargs...)
+}
+
 // DeleteEntry mocks base method
 func (m *MockRegistrationClient) DeleteEntry(arg0 context.Context, arg1 *registration.RegistrationEntryID, arg2 ...grpc.CallOption) (*common.RegistrationEntry, error) {
 	varargs := []interface{}{arg0, arg1}
@@ -288,6 +360,24 @@ func (mr *MockRegistrationClientMockRecorder) ListBySpiffeID(arg0, arg1 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBySpiffeID", reflect.TypeOf((*MockRegistrationClient)(nil).ListBySpiffeID), varargs...)
 }
 
+// ListEntries mocks base method
+func (m *MockRegistrationClient) ListEntries(arg0 context.Context, arg1 *registration.ListEntriesRequest, arg2 ...grpc.CallOption) (*registration.ListEntriesResponse, error) {
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListEntries", varargs...)
+	ret0, _ := ret[0].(*registration.ListEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEntries indicates an expected call of ListEntries
+func (mr *MockRegistrationClientMockRecorder) ListEntries(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEntries", reflect.TypeOf((*MockRegistrationClient)(nil).ListEntries), varargs...)
+}
+
 // ListFederatedBundles mocks base method
 func (m *MockRegistrationClient) ListFederatedBundles(arg0 context.Context, arg1 *common.Empty, arg2 ...grpc.CallOption) (registration.Registration_ListFederatedBundlesClient, error) {
 	varargs := []interface{}{arg0, arg1}
@@ -306,6 +396,24 @@ func (mr *MockRegistrationClientMockRecorder) ListFederatedBundles(arg0, arg1 in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFederatedBundles", reflect.TypeOf((*MockRegistrationClient)(nil).ListFederatedBundles), varargs...)
 }
 
+// UpdateEntries mocks base method
+func (m *MockRegistrationClient) UpdateEntries(arg0 context.Context, arg1 *registration.UpdateEntriesRequest, arg2 ...grpc.CallOption) (*registration.UpdateEntriesResponse, error) {
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateEntries", varargs...)
+	ret0, _ := ret[0].(*registration.UpdateEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateEntries indicates an expected call of UpdateEntries
+func (mr *MockRegistrationClientMockRecorder) UpdateEntries(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEntries", reflect.TypeOf((*MockRegistrationClient)(nil).UpdateEntries), varargs...)
+}
+
 // UpdateEntry mocks base method
 func (m *MockRegistrationClient) UpdateEntry(arg0 context.Context, arg1 *registration.UpdateEntryRequest, arg2 ...grpc.CallOption) (*common.RegistrationEntry, error) {
 	varargs := []interface{}{arg0, arg1}
@@ -365,6 +473,19 @@ func (m *MockRegistrationServer) EXPECT() *MockRegistrationServerMockRecorder {
 	return m.recorder
 }
 
+// CreateEntries mocks base method
+func (m *MockRegistrationServer) CreateEntries(arg0 context.Context, arg1 *registration.CreateEntriesRequest) (*registration.CreateEntriesResponse, error) {
+	ret := m.ctrl.Call(m, "CreateEntries", arg0, arg1)
+	ret0, _ := ret[0].(*registration.CreateEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateEntries indicates an expected call of CreateEntries
+func (mr *MockRegistrationServerMockRecorder) CreateEntries(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEntries", reflect.TypeOf((*MockRegistrationServer)(nil).CreateEntries), arg0, arg1)
+}
+
 // CreateEntry mocks base method
 func (m *MockRegistrationServer) CreateEntry(arg0 context.Context, arg1 *common.RegistrationEntry) (*registration.RegistrationEntryID, error) {
 	ret := m.ctrl.Call(m, "CreateEntry", arg0, arg1)
@@ -404,6 +525,45 @@ func (mr *MockRegistrationServerMockRecorder) CreateJoinToken(arg0, arg1 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJoinToken", reflect.TypeOf((*MockRegistrationServer)(nil).CreateJoinToken), arg0, arg1)
 }
 
+// ListJoinTokens mocks base method
+func (m *MockRegistrationServer) ListJoinTokens(arg0 context.Context, arg1 *registration.ListJoinTokensRequest) (*registration.ListJoinTokensResponse, error) {
+	ret := m.ctrl.Call(m, "ListJoinTokens", arg0, arg1)
+	ret0, _ := ret[0].(*registration.ListJoinTokensResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListJoinTokens indicates an expected call of ListJoinTokens
+func (mr *MockRegistrationServerMockRecorder) ListJoinTokens(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListJoinTokens", reflect.TypeOf((*MockRegistrationServer)(nil).ListJoinTokens), arg0, arg1)
+}
+
+// RevokeJoinToken mocks base method
+func (m *MockRegistrationServer) RevokeJoinToken(arg0 context.Context, arg1 *registration.RevokeJoinTokenRequest) (*registration.RevokeJoinTokenResponse, error) {
+	ret := m.ctrl.Call(m, "RevokeJoinToken", arg0, arg1)
+	ret0, _ := ret[0].(*registration.RevokeJoinTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeJoinToken indicates an expected call of RevokeJoinToken
+func (mr *MockRegistrationServerMockRecorder) RevokeJoinToken(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeJoinToken", reflect.TypeOf((*MockRegistrationServer)(nil).RevokeJoinToken), arg0, arg1)
+}
+
+// DeleteEntries mocks base method
+func (m *MockRegistrationServer) DeleteEntries(arg0 context.Context, arg1 *registration.DeleteEntriesRequest) (*registration.DeleteEntriesResponse, error) {
+	ret := m.ctrl.Call(m, "DeleteEntries", arg0, arg1)
+	ret0, _ := ret[0].(*registration.DeleteEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteEntries indicates an expected call of DeleteEntries
+func (mr *MockRegistrationServerMockRecorder) DeleteEntries(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEntries", reflect.TypeOf((*MockRegistrationServer)(nil).DeleteEntries), arg0, arg1)
+}
+
 // DeleteEntry mocks base method
 func (m *MockRegistrationServer) DeleteEntry(arg0 context.Context, arg1 *registration.RegistrationEntryID) (*common.RegistrationEntry, error) {
 	ret := m.ctrl.Call(m, "DeleteEntry", arg0, arg1)
@@ -547,6 +707,19 @@ func (mr *MockRegistrationServerMockRecorder) ListBySpiffeID(arg0, arg1 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBySpiffeID", reflect.TypeOf((*MockRegistrationServer)(nil).ListBySpiffeID), arg0, arg1)
 }
 
+// ListEntries mocks base method
+func (m *MockRegistrationServer) ListEntries(arg0 context.Context, arg1 *registration.ListEntriesRequest) (*registration.ListEntriesResponse, error) {
+	ret := m.ctrl.Call(m, "ListEntries", arg0, arg1)
+	ret0, _ := ret[0].(*registration.ListEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEntries indicates an expected call of ListEntries
+func (mr *MockRegistrationServerMockRecorder) ListEntries(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEntries", reflect.TypeOf((*MockRegistrationServer)(nil).ListEntries), arg0, arg1)
+}
+
 // ListFederatedBundles mocks base method
 func (m *MockRegistrationServer) ListFederatedBundles(arg0 *common.Empty, arg1 registration.Registration_ListFederatedBundlesServer) error {
 	ret := m.ctrl.Call(m, "ListFederatedBundles", arg0, arg1)
@@ -559,6 +732,19 @@ func (mr *MockRegistrationServerMockRecorder) ListFederatedBundles(arg0, arg1 in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFederatedBundles", reflect.TypeOf((*MockRegistrationServer)(nil).ListFederatedBundles), arg0, arg1)
 }
 
+// UpdateEntries mocks base method
+func (m *MockRegistrationServer) UpdateEntries(arg0 context.Context, arg1 *registration.UpdateEntriesRequest) (*registration.UpdateEntriesResponse, error) {
+	ret := m.ctrl.Call(m, "UpdateEntries", arg0, arg1)
+	ret0, _ := ret[0].(*registration.UpdateEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateEntries indicates an expected call of UpdateEntries
+func (mr *MockRegistrationServerMockRecorder) UpdateEntries(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEntries", reflect.TypeOf((*MockRegistrationServer)(nil).UpdateEntries), arg0, arg1)
+}
+
 // UpdateEntry mocks base method
 func (m *MockRegistrationServer) UpdateEntry(arg0 context.Context, arg1 *registration.UpdateEntryRequest) (*common.RegistrationEntry, error) {
 	ret := m.ctrl.Call(m, "UpdateEntry", arg0, arg1)
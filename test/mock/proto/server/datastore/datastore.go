@@ -48,6 +48,45 @@ func (mr *MockDataStoreMockRecorder) AppendBundle(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendBundle", reflect.TypeOf((*MockDataStore)(nil).AppendBundle), arg0, arg1)
 }
 
+// BatchCreateRegistrationEntries mocks base method
+func (m *MockDataStore) BatchCreateRegistrationEntries(arg0 context.Context, arg1 *datastore.BatchCreateRegistrationEntriesRequest) (*datastore.BatchCreateRegistrationEntriesResponse, error) {
+	ret := m.ctrl.Call(m, "BatchCreateRegistrationEntries", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.BatchCreateRegistrationEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchCreateRegistrationEntries indicates an expected call of BatchCreateRegistrationEntries
+func (mr *MockDataStoreMockRecorder) BatchCreateRegistrationEntries(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchCreateRegistrationEntries", reflect.TypeOf((*MockDataStore)(nil).BatchCreateRegistrationEntries), arg0, arg1)
+}
+
+// BatchDeleteRegistrationEntries mocks base method
+func (m *MockDataStore) BatchDeleteRegistrationEntries(arg0 context.Context, arg1 *datastore.BatchDeleteRegistrationEntriesRequest) (*datastore.BatchDeleteRegistrationEntriesResponse, error) {
+	ret := m.ctrl.Call(m, "BatchDeleteRegistrationEntries", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.BatchDeleteRegistrationEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchDeleteRegistrationEntries indicates an expected call of BatchDeleteRegistrationEntries
+func (mr *MockDataStoreMockRecorder) BatchDeleteRegistrationEntries(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchDeleteRegistrationEntries", reflect.TypeOf((*MockDataStore)(nil).BatchDeleteRegistrationEntries), arg0, arg1)
+}
+
+// BatchUpdateRegistrationEntries mocks base method
+func (m *MockDataStore) BatchUpdateRegistrationEntries(arg0 context.Context, arg1 *datastore.BatchUpdateRegistrationEntriesRequest) (*datastore.BatchUpdateRegistrationEntriesResponse, error) {
+	ret := m.ctrl.Call(m, "BatchUpdateRegistrationEntries", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.BatchUpdateRegistrationEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchUpdateRegistrationEntries indicates an expected call of BatchUpdateRegistrationEntries
+func (mr *MockDataStoreMockRecorder) BatchUpdateRegistrationEntries(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchUpdateRegistrationEntries", reflect.TypeOf((*MockDataStore)(nil).BatchUpdateRegistrationEntries), arg0, arg1)
+}
+
 // CreateAttestedNode mocks base method
 func (m *MockDataStore) CreateAttestedNode(arg0 context.Context, arg1 *datastore.CreateAttestedNodeRequest) (*datastore.CreateAttestedNodeResponse, error) {
 	ret := m.ctrl.Call(m, "CreateAttestedNode", arg0, arg1)
@@ -191,6 +230,32 @@ func (mr *MockDataStoreMockRecorder) FetchJoinToken(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchJoinToken", reflect.TypeOf((*MockDataStore)(nil).FetchJoinToken), arg0, arg1)
 }
 
+// ListJoinTokens mocks base method
+func (m *MockDataStore) ListJoinTokens(arg0 context.Context, arg1 *datastore.ListJoinTokensRequest) (*datastore.ListJoinTokensResponse, error) {
+	ret := m.ctrl.Call(m, "ListJoinTokens", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.ListJoinTokensResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListJoinTokens indicates an expected call of ListJoinTokens
+func (mr *MockDataStoreMockRecorder) ListJoinTokens(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListJoinTokens", reflect.TypeOf((*MockDataStore)(nil).ListJoinTokens), arg0, arg1)
+}
+
+// UseJoinToken mocks base method
+func (m *MockDataStore) UseJoinToken(arg0 context.Context, arg1 *datastore.UseJoinTokenRequest) (*datastore.UseJoinTokenResponse, error) {
+	ret := m.ctrl.Call(m, "UseJoinToken", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.UseJoinTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseJoinToken indicates an expected call of UseJoinToken
+func (mr *MockDataStoreMockRecorder) UseJoinToken(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseJoinToken", reflect.TypeOf((*MockDataStore)(nil).UseJoinToken), arg0, arg1)
+}
+
 // FetchRegistrationEntry mocks base method
 func (m *MockDataStore) FetchRegistrationEntry(arg0 context.Context, arg1 *datastore.FetchRegistrationEntryRequest) (*datastore.FetchRegistrationEntryResponse, error) {
 	ret := m.ctrl.Call(m, "FetchRegistrationEntry", arg0, arg1)
@@ -243,6 +308,19 @@ func (mr *MockDataStoreMockRecorder) ListBundles(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBundles", reflect.TypeOf((*MockDataStore)(nil).ListBundles), arg0, arg1)
 }
 
+// ListChangeEvents mocks base method
+func (m *MockDataStore) ListChangeEvents(arg0 context.Context, arg1 *datastore.ListChangeEventsRequest) (*datastore.ListChangeEventsResponse, error) {
+	ret := m.ctrl.Call(m, "ListChangeEvents", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.ListChangeEventsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListChangeEvents indicates an expected call of ListChangeEvents
+func (mr *MockDataStoreMockRecorder) ListChangeEvents(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListChangeEvents", reflect.TypeOf((*MockDataStore)(nil).ListChangeEvents), arg0, arg1)
+}
+
 // ListRegistrationEntries mocks base method
 func (m *MockDataStore) ListRegistrationEntries(arg0 context.Context, arg1 *datastore.ListRegistrationEntriesRequest) (*datastore.ListRegistrationEntriesResponse, error) {
 	ret := m.ctrl.Call(m, "ListRegistrationEntries", arg0, arg1)
@@ -256,6 +334,19 @@ func (mr *MockDataStoreMockRecorder) ListRegistrationEntries(arg0, arg1 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRegistrationEntries", reflect.TypeOf((*MockDataStore)(nil).ListRegistrationEntries), arg0, arg1)
 }
 
+// PruneChangeEvents mocks base method
+func (m *MockDataStore) PruneChangeEvents(arg0 context.Context, arg1 *datastore.PruneChangeEventsRequest) (*datastore.PruneChangeEventsResponse, error) {
+	ret := m.ctrl.Call(m, "PruneChangeEvents", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.PruneChangeEventsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneChangeEvents indicates an expected call of PruneChangeEvents
+func (mr *MockDataStoreMockRecorder) PruneChangeEvents(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneChangeEvents", reflect.TypeOf((*MockDataStore)(nil).PruneChangeEvents), arg0, arg1)
+}
+
 // PruneJoinTokens mocks base method
 func (m *MockDataStore) PruneJoinTokens(arg0 context.Context, arg1 *datastore.PruneJoinTokensRequest) (*datastore.PruneJoinTokensResponse, error) {
 	ret := m.ctrl.Call(m, "PruneJoinTokens", arg0, arg1)
@@ -370,6 +461,45 @@ func (mr *MockPluginMockRecorder) Configure(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Configure", reflect.TypeOf((*MockPlugin)(nil).Configure), arg0, arg1)
 }
 
+// BatchCreateRegistrationEntries mocks base method
+func (m *MockPlugin) BatchCreateRegistrationEntries(arg0 context.Context, arg1 *datastore.BatchCreateRegistrationEntriesRequest) (*datastore.BatchCreateRegistrationEntriesResponse, error) {
+	ret := m.ctrl.Call(m, "BatchCreateRegistrationEntries", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.BatchCreateRegistrationEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchCreateRegistrationEntries indicates an expected call of BatchCreateRegistrationEntries
+func (mr *MockPluginMockRecorder) BatchCreateRegistrationEntries(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchCreateRegistrationEntries", reflect.TypeOf((*MockPlugin)(nil).BatchCreateRegistrationEntries), arg0, arg1)
+}
+
+// BatchDeleteRegistrationEntries mocks base method
+func (m *MockPlugin) BatchDeleteRegistrationEntries(arg0 context.Context, arg1 *datastore.BatchDeleteRegistrationEntriesRequest) (*datastore.BatchDeleteRegistrationEntriesResponse, error) {
+	ret := m.ctrl.Call(m, "BatchDeleteRegistrationEntries", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.BatchDeleteRegistrationEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchDeleteRegistrationEntries indicates an expected call of BatchDeleteRegistrationEntries
+func (mr *MockPluginMockRecorder) BatchDeleteRegistrationEntries(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchDeleteRegistrationEntries", reflect.TypeOf((*MockPlugin)(nil).BatchDeleteRegistrationEntries), arg0, arg1)
+}
+
+// BatchUpdateRegistrationEntries mocks base method
+func (m *MockPlugin) BatchUpdateRegistrationEntries(arg0 context.Context, arg1 *datastore.BatchUpdateRegistrationEntriesRequest) (*datastore.BatchUpdateRegistrationEntriesResponse, error) {
+	ret := m.ctrl.Call(m, "BatchUpdateRegistrationEntries", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.BatchUpdateRegistrationEntriesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchUpdateRegistrationEntries indicates an expected call of BatchUpdateRegistrationEntries
+func (mr *MockPluginMockRecorder) BatchUpdateRegistrationEntries(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchUpdateRegistrationEntries", reflect.TypeOf((*MockPlugin)(nil).BatchUpdateRegistrationEntries), arg0, arg1)
+}
+
 // CreateAttestedNode mocks base method
 func (m *MockPlugin) CreateAttestedNode(arg0 context.Context, arg1 *datastore.CreateAttestedNodeRequest) (*datastore.CreateAttestedNodeResponse, error) {
 	ret := m.ctrl.Call(m, "CreateAttestedNode", arg0, arg1)
@@ -513,6 +643,32 @@ func (mr *MockPluginMockRecorder) FetchJoinToken(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchJoinToken", reflect.TypeOf((*MockPlugin)(nil).FetchJoinToken), arg0, arg1)
 }
 
+// ListJoinTokens mocks base method
+func (m *MockPlugin) ListJoinTokens(arg0 context.Context, arg1 *datastore.ListJoinTokensRequest) (*datastore.ListJoinTokensResponse, error) {
+	ret := m.ctrl.Call(m, "ListJoinTokens", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.ListJoinTokensResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListJoinTokens indicates an expected call of ListJoinTokens
+func (mr *MockPluginMockRecorder) ListJoinTokens(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListJoinTokens", reflect.TypeOf((*MockPlugin)(nil).ListJoinTokens), arg0, arg1)
+}
+
+// UseJoinToken mocks base method
+func (m *MockPlugin) UseJoinToken(arg0 context.Context, arg1 *datastore.UseJoinTokenRequest) (*datastore.UseJoinTokenResponse, error) {
+	ret := m.ctrl.Call(m, "UseJoinToken", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.UseJoinTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseJoinToken indicates an expected call of UseJoinToken
+func (mr *MockPluginMockRecorder) UseJoinToken(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseJoinToken", reflect.TypeOf((*MockPlugin)(nil).UseJoinToken), arg0, arg1)
+}
+
 // FetchRegistrationEntry mocks base method
 func (m *MockPlugin) FetchRegistrationEntry(arg0 context.Context, arg1 *datastore.FetchRegistrationEntryRequest) (*datastore.FetchRegistrationEntryResponse, error) {
 	ret := m.ctrl.Call(m, "FetchRegistrationEntry", arg0, arg1)
@@ -578,6 +734,19 @@ func (mr *MockPluginMockRecorder) ListBundles(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBundles", reflect.TypeOf((*MockPlugin)(nil).ListBundles), arg0, arg1)
 }
 
+// ListChangeEvents mocks base method
+func (m *MockPlugin) ListChangeEvents(arg0 context.Context, arg1 *datastore.ListChangeEventsRequest) (*datastore.ListChangeEventsResponse, error) {
+	ret := m.ctrl.Call(m, "ListChangeEvents", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.ListChangeEventsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListChangeEvents indicates an expected call of ListChangeEvents
+func (mr *MockPluginMockRecorder) ListChangeEvents(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListChangeEvents", reflect.TypeOf((*MockPlugin)(nil).ListChangeEvents), arg0, arg1)
+}
+
 // ListRegistrationEntries mocks base method
 func (m *MockPlugin) ListRegistrationEntries(arg0 context.Context, arg1 *datastore.ListRegistrationEntriesRequest) (*datastore.ListRegistrationEntriesResponse, error) {
 	ret := m.ctrl.Call(m, "ListRegistrationEntries", arg0, arg1)
@@ -591,6 +760,19 @@ func (mr *MockPluginMockRecorder) ListRegistrationEntries(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRegistrationEntries", reflect.TypeOf((*MockPlugin)(nil).ListRegistrationEntries), arg0, arg1)
 }
 
+// PruneChangeEvents mocks base method
+func (m *MockPlugin) PruneChangeEvents(arg0 context.Context, arg1 *datastore.PruneChangeEventsRequest) (*datastore.PruneChangeEventsResponse, error) {
+	ret := m.ctrl.Call(m, "PruneChangeEvents", arg0, arg1)
+	ret0, _ := ret[0].(*datastore.PruneChangeEventsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneChangeEvents indicates an expected call of PruneChangeEvents
+func (mr *MockPluginMockRecorder) PruneChangeEvents(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneChangeEvents", reflect.TypeOf((*MockPlugin)(nil).PruneChangeEvents), arg0, arg1)
+}
+
 // PruneJoinTokens mocks base method
 func (m *MockPlugin) PruneJoinTokens(arg0 context.Context, arg1 *datastore.PruneJoinTokensRequest) (*datastore.PruneJoinTokensResponse, error) {
 	ret := m.ctrl.Call(m, "PruneJoinTokens", arg0, arg1)
@@ -87,6 +87,19 @@ func (mr *MockKeyManagerMockRecorder) SignData(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignData", reflect.TypeOf((*MockKeyManager)(nil).SignData), arg0, arg1)
 }
 
+// SignDataBatch mocks base method
+func (m *MockKeyManager) SignDataBatch(arg0 context.Context, arg1 *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	ret := m.ctrl.Call(m, "SignDataBatch", arg0, arg1)
+	ret0, _ := ret[0].(*keymanager.SignDataBatchResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignDataBatch indicates an expected call of SignDataBatch
+func (mr *MockKeyManagerMockRecorder) SignDataBatch(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignDataBatch", reflect.TypeOf((*MockKeyManager)(nil).SignDataBatch), arg0, arg1)
+}
+
 // MockPlugin is a mock of Plugin interface
 type MockPlugin struct {
 	ctrl     *gomock.Controller
@@ -187,3 +200,16 @@ func (m *MockPlugin) SignData(arg0 context.Context, arg1 *keymanager.SignDataReq
 func (mr *MockPluginMockRecorder) SignData(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignData", reflect.TypeOf((*MockPlugin)(nil).SignData), arg0, arg1)
 }
+
+// SignDataBatch mocks base method
+func (m *MockPlugin) SignDataBatch(arg0 context.Context, arg1 *keymanager.SignDataBatchRequest) (*keymanager.SignDataBatchResponse, error) {
+	ret := m.ctrl.Call(m, "SignDataBatch", arg0, arg1)
+	ret0, _ := ret[0].(*keymanager.SignDataBatchResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignDataBatch indicates an expected call of SignDataBatch
+func (mr *MockPluginMockRecorder) SignDataBatch(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignDataBatch", reflect.TypeOf((*MockPlugin)(nil).SignDataBatch), arg0, arg1)
+}
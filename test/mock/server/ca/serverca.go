@@ -8,6 +8,7 @@ import (
 	context "context"
 	x509 "crypto/x509"
 	gomock "github.com/golang/mock/gomock"
+	ca "github.com/spiffe/spire/pkg/server/ca"
 	node "github.com/spiffe/spire/proto/api/node"
 	reflect "reflect"
 	time "time"
@@ -74,3 +75,16 @@ func (m *MockServerCA) SignX509SVID(arg0 context.Context, arg1 []byte, arg2 time
 func (mr *MockServerCAMockRecorder) SignX509SVID(arg0, arg1, arg2 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignX509SVID", reflect.TypeOf((*MockServerCA)(nil).SignX509SVID), arg0, arg1, arg2)
 }
+
+// SignX509SVIDs mocks base method
+func (m *MockServerCA) SignX509SVIDs(arg0 context.Context, arg1 []ca.X509SVIDParams) ([][]*x509.Certificate, error) {
+	ret := m.ctrl.Call(m, "SignX509SVIDs", arg0, arg1)
+	ret0, _ := ret[0].([][]*x509.Certificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignX509SVIDs indicates an expected call of SignX509SVIDs
+func (mr *MockServerCAMockRecorder) SignX509SVIDs(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignX509SVIDs", reflect.TypeOf((*MockServerCA)(nil).SignX509SVIDs), arg0, arg1)
+}
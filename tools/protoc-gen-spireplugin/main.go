@@ -285,6 +285,7 @@ import (
 
 	"github.com/golang/protobuf/ptypes/empty"
 	go_plugin "github.com/hashicorp/go-plugin"
+	"github.com/sirupsen/logrus"
 {{- if .UsesStreams }}
 	"github.com/spiffe/spire/proto/builtin"
 {{- end }}
@@ -521,6 +522,13 @@ func NewBuiltIn(plugin Plugin) *BuiltIn {
 	}
 }
 
+// SetLogger hands the logger to the plugin, if the plugin logs
+func (b BuiltIn) SetLogger(log logrus.FieldLogger) {
+	if p, ok := b.plugin.(interface{ SetLogger(logrus.FieldLogger) }); ok {
+		p.SetLogger(log)
+	}
+}
+
 {{- range .Methods }}
 {{- $m := . }}
 {{ $clientintf := printf "%s_Stream" $m.Name }}